@@ -0,0 +1,284 @@
+// Package api serves the notes tree as a JSON REST API, for web frontends
+// and scripts such as mobile shortcuts.
+//
+// Entries are addressed like on the command line, by path, ID, file stem
+// or slug:
+//
+//	GET    /api/entries?q=&sort=&reverse=&limit=&drafts=  list entries matching a query
+//	POST   /api/entries                                   create an entry
+//	GET    /api/entries/{ref}                             an entry with its markdown
+//	PUT    /api/entries/{ref}                             replace an entry's markdown
+//	PATCH  /api/entries/{ref}                             change its title, tags or draft flag
+//	GET    /api/html/{ref}                                an entry rendered to HTML
+//	GET    /api/search?q=&limit=                          full-text search
+//	GET    /api/tags                                      tags with their entry counts
+//	POST   /api/tags/rename, /api/tags/merge              rewrite tags across entries
+//	POST   /api/graphql, GET /api/graphql?query=          a GraphQL query
+//	GET    /api/graphql/schema                            the GraphQL schema, in SDL
+//
+// The GraphQL schema exposes entries, tags, categories and the link graph
+// between entries, with filters on every list of entries and cursor
+// pagination in the style of Relay connections:
+//
+//	{ entries(tag: ["go"], first: 10) { totalCount nodes { title backlinks { nodes { path } } } pageInfo { endCursor } } }
+//
+// Errors are returned as {"error": "..."} with a matching status code.
+// Private entries are not served.
+//
+// With Web set, the server is also a small web app at /, where people sign
+// in with a password or GitHub to browse, write and edit entries in the
+// browser:
+//
+//	GET    /?q=                   entries matching a query
+//	GET    /new, POST /new        write a new entry
+//	GET    /edit/{ref}, POST ...  edit an entry's markdown
+//	POST   /preview?from=         render posted markdown to HTML
+//	GET    /login, POST /login    sign in with a user name and password
+//	GET    /auth/github           sign in with GitHub
+//	POST   /logout                sign out
+//
+// Signed-in users may call the API too, sending the CSRF token of their
+// session as X-CSRF-Token with changes.
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/canhta/til/go/internal/graphql"
+	"github.com/canhta/til/go/internal/notes"
+)
+
+// Server serves the API for a notes tree.
+type Server struct {
+	Addr string
+	Tree *notes.Tree
+	// Token, if set, must be sent as "Authorization: Bearer <token>" with
+	// every request.
+	Token string
+	// Origins are the browser origins allowed to call the API; "*" allows
+	// any.
+	Origins []string
+	// Collections are the saved queries usable as @name.
+	Collections map[string]string
+	// Lang is the language of entries that name none, matched by lang:.
+	Lang string
+	// Scaffold renders a new entry from its category's template, returning
+	// its path and content.
+	Scaffold func(category, title, slug string, tags []string) (string, []byte, error)
+	// Commit records a change to the entry at rel and the files in also, as
+	// the git configuration directs.
+	Commit func(ctx context.Context, rel, verb string, also ...string) error
+	// Web, if set, serves the web app and lets its users call the API.
+	Web *Web
+	Log *log.Logger
+
+	sessions   *sessions
+	schemaOnce sync.Once
+	gql        *graphql.Schema
+}
+
+// Run serves the API until ctx is cancelled. Without a Token or the web
+// app, whose users sign in, it refuses to listen on anything but a
+// loopback address.
+func (s *Server) Run(ctx context.Context) error {
+	if s.Log == nil {
+		s.Log = log.New(os.Stderr, "", log.LstdFlags)
+	}
+	if s.Web != nil && len(s.Web.Users) == 0 && (s.Web.GitHub == nil || s.Web.GitHub.ClientID == "") {
+		return errors.New("nobody can sign in to the web app; configure [api.web] users or [api.web.github]")
+	}
+	ln, err := net.Listen("tcp", s.Addr)
+	if err != nil {
+		return err
+	}
+	if s.Token == "" && s.Web == nil {
+		if ip := ln.Addr().(*net.TCPAddr).IP; !ip.IsLoopback() {
+			ln.Close()
+			return fmt.Errorf("refusing to serve on %s without a token; configure [api] token_env", ln.Addr())
+		}
+	}
+	srv := &http.Server{Handler: s.Handler(), ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		srv.Shutdown(shutdown)
+	}()
+	s.Log.Printf("serving the API on http://%s/api/", ln.Addr())
+	if s.Web != nil {
+		s.Log.Printf("serving the web app on http://%s/", ln.Addr())
+	}
+	if err := srv.Serve(ln); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// Handler returns the API's HTTP handler, with the web app's if Web is
+// set.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/entries", s.listEntries)
+	mux.HandleFunc("POST /api/entries", s.createEntry)
+	mux.HandleFunc("GET /api/entries/{ref...}", s.getEntry)
+	mux.HandleFunc("PUT /api/entries/{ref...}", s.putEntry)
+	mux.HandleFunc("PATCH /api/entries/{ref...}", s.patchEntry)
+	mux.HandleFunc("GET /api/html/{ref...}", s.getHTML)
+	mux.HandleFunc("GET /api/search", s.search)
+	mux.HandleFunc("GET /api/tags", s.listTags)
+	mux.HandleFunc("POST /api/tags/rename", s.renameTag)
+	mux.HandleFunc("POST /api/tags/merge", s.mergeTags)
+	mux.HandleFunc("GET /api/graphql", s.graphQL)
+	mux.HandleFunc("POST /api/graphql", s.graphQL)
+	mux.HandleFunc("GET /api/graphql/schema", s.graphQLSchema)
+	api := s.cors(s.auth(mux))
+	if s.Web == nil {
+		return api
+	}
+	if s.sessions == nil {
+		s.sessions = newSessions(s.Web.SessionKey)
+	}
+	root := http.NewServeMux()
+	root.Handle("/api/", api)
+	s.webRoutes(root)
+	return root
+}
+
+// auth rejects requests without the configured token or, with the web
+// app, a session.
+func (s *Server) auth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.Token == "" && s.Web == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok && s.Token != "" {
+			if subtle.ConstantTimeCompare([]byte(got), []byte(s.Token)) == 1 {
+				next.ServeHTTP(w, r)
+				return
+			}
+		} else if s.Web != nil {
+			if _, _, ok := s.sessions.user(r); ok {
+				if r.Method != http.MethodGet && r.Method != http.MethodHead && !s.sessions.checkCSRF(r, r.Header.Get(csrfHeader)) {
+					writeError(w, http.StatusForbidden, fmt.Errorf("missing or wrong %s header", csrfHeader))
+					return
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+		w.Header().Set("WWW-Authenticate", `Bearer realm="til"`)
+		writeError(w, http.StatusUnauthorized, errors.New("missing or wrong token"))
+	})
+}
+
+// cors adds CORS headers for allowed origins and answers preflight
+// requests.
+func (s *Server) cors(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		allowed := origin != "" && (slices.Contains(s.Origins, "*") || slices.Contains(s.Origins, origin))
+		if allowed {
+			h := w.Header()
+			h.Set("Access-Control-Allow-Origin", origin)
+			h.Add("Vary", "Origin")
+			h.Set("Access-Control-Expose-Headers", "ETag, Location")
+		}
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			if !allowed {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			h := w.Header()
+			h.Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH")
+			h.Set("Access-Control-Allow-Headers", "Authorization, Content-Type, If-Match")
+			h.Set("Access-Control-Max-Age", "600")
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// httpError is an error carrying the status to answer it with.
+type httpError struct {
+	status int
+	err    error
+}
+
+func (e *httpError) Error() string { return e.err.Error() }
+func (e *httpError) Unwrap() error { return e.err }
+
+func badRequest(format string, args ...any) error {
+	return &httpError{http.StatusBadRequest, fmt.Errorf(format, args...)}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// fail answers a request that failed with err.
+func (s *Server) fail(w http.ResponseWriter, r *http.Request, err error) {
+	status := http.StatusInternalServerError
+	var he *httpError
+	switch {
+	case errors.As(err, &he):
+		status = he.status
+	case errors.Is(err, notes.ErrNotFound), errors.Is(err, os.ErrNotExist):
+		status = http.StatusNotFound
+	}
+	if status == http.StatusInternalServerError {
+		s.Log.Printf("%s %s: %v", r.Method, r.URL.Path, err)
+	}
+	writeError(w, status, err)
+}
+
+// decode reads the JSON request body into v.
+func decode(r *http.Request, v any) error {
+	dec := json.NewDecoder(http.MaxBytesReader(nil, r.Body, 4<<20))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return badRequest("invalid request body: %v", err)
+	}
+	return nil
+}
+
+// intParam parses the query parameter name, returning def when it is
+// absent.
+func intParam(r *http.Request, name string, def int) (int, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		return 0, badRequest("%s: want a non-negative number, got %q", name, v)
+	}
+	return n, nil
+}
+
+func boolParam(r *http.Request, name string) bool {
+	v, _ := strconv.ParseBool(r.URL.Query().Get(name))
+	return v
+}
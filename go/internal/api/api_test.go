@@ -0,0 +1,256 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/canhta/til/go/internal/notes"
+)
+
+// apiServer returns a server for a tree of files.
+func apiServer(t *testing.T, files map[string]string) *Server {
+	t.Helper()
+	tree := notes.Open(t.TempDir())
+	for p, data := range files {
+		if err := tree.Write(p, []byte(data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return &Server{Tree: tree, Scaffold: scaffold, Log: log.New(io.Discard, "", 0)}
+}
+
+// do sends a request to h, with headers given as name, value pairs.
+func do(h http.Handler, method, target, body string, headers ...string) *httptest.ResponseRecorder {
+	var rd io.Reader
+	if body != "" {
+		rd = strings.NewReader(body)
+	}
+	req := httptest.NewRequest(method, target, rd)
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	return w
+}
+
+func TestAuth(t *testing.T) {
+	s := apiServer(t, map[string]string{"go/a.md": "# A\n"})
+	s.Token = "s3cret"
+	h := s.Handler()
+	for _, hdr := range [][]string{nil, {"Authorization", "Bearer wrong"}, {"Authorization", "s3cret"}} {
+		w := do(h, "GET", "/api/entries", "", hdr...)
+		if w.Code != http.StatusUnauthorized || w.Header().Get("WWW-Authenticate") != `Bearer realm="til"` {
+			t.Errorf("GET with %q = %d %s", hdr, w.Code, w.Body)
+		}
+		if !strings.Contains(w.Body.String(), `"error": "missing or wrong token"`) {
+			t.Errorf("error body = %s", w.Body)
+		}
+	}
+	if w := do(h, "GET", "/api/entries", "", "Authorization", "Bearer s3cret"); w.Code != http.StatusOK {
+		t.Errorf("GET with the token = %d %s", w.Code, w.Body)
+	}
+}
+
+func TestCORS(t *testing.T) {
+	s := apiServer(t, map[string]string{"go/a.md": "# A\n"})
+	s.Origins = []string{"https://app.example"}
+	h := s.Handler()
+
+	w := do(h, "GET", "/api/entries", "", "Origin", "https://app.example")
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "https://app.example" || w.Code != http.StatusOK {
+		t.Errorf("allowed origin: %d, Allow-Origin %q", w.Code, got)
+	}
+	if got := w.Header().Get("Access-Control-Expose-Headers"); got != "ETag, Location" {
+		t.Errorf("Expose-Headers = %q", got)
+	}
+	if w := do(h, "GET", "/api/entries", "", "Origin", "https://evil.example"); w.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Error("another origin was allowed")
+	}
+
+	w = do(h, "OPTIONS", "/api/entries", "", "Origin", "https://app.example", "Access-Control-Request-Method", "PUT")
+	if w.Code != http.StatusNoContent || !strings.Contains(w.Header().Get("Access-Control-Allow-Methods"), "PUT") ||
+		!strings.Contains(w.Header().Get("Access-Control-Allow-Headers"), "If-Match") {
+		t.Errorf("preflight = %d %v", w.Code, w.Header())
+	}
+	if w := do(h, "OPTIONS", "/api/entries", "", "Origin", "https://evil.example", "Access-Control-Request-Method", "PUT"); w.Code != http.StatusForbidden {
+		t.Errorf("preflight from another origin = %d", w.Code)
+	}
+
+	// Preflights carry no credentials, so they pass before auth.
+	s.Token, s.Origins = "t", []string{"*"}
+	h = s.Handler()
+	if w := do(h, "OPTIONS", "/api/entries", "", "Origin", "https://any.example", "Access-Control-Request-Method", "GET"); w.Code != http.StatusNoContent {
+		t.Errorf("preflight with a token configured = %d", w.Code)
+	}
+}
+
+var apiFiles = map[string]string{
+	"go/slices.md":  "---\ntitle: Slices\ndate: 2024-06-01\ntags: [go]\n---\n\nSlices share arrays. See [[maps]].\n",
+	"go/maps.md":    "---\ntitle: Maps\ndate: 2024-05-01\ntags: [go, maps]\n---\n\nMaps are unordered.\n",
+	"git/rebase.md": "---\ntitle: Rebase\ndate: 2023-01-10\ntags: [git]\n---\n\nRebase onto main.\n",
+	"git/draft.md":  "---\ntitle: Draft\ndraft: true\n---\n",
+	"git/secret.md": "---\ntitle: Secret\nprivate: true\n---\n",
+}
+
+func decodeJSON[T any](t *testing.T, w *httptest.ResponseRecorder) T {
+	t.Helper()
+	var v T
+	if err := json.Unmarshal(w.Body.Bytes(), &v); err != nil {
+		t.Fatalf("%d %s: %v", w.Code, w.Body, err)
+	}
+	return v
+}
+
+func TestListEntries(t *testing.T) {
+	h := apiServer(t, apiFiles).Handler()
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"", []string{"go/slices.md", "go/maps.md", "git/rebase.md"}},
+		{"?q=tag:go&reverse=true", []string{"go/maps.md", "go/slices.md"}},
+		{"?sort=title", []string{"go/maps.md", "git/rebase.md", "go/slices.md"}},
+		{"?limit=1", []string{"go/slices.md"}},
+		{"?drafts=true&q=category:git", []string{"git/rebase.md", "git/draft.md"}},
+	}
+	for _, tt := range tests {
+		w := do(h, "GET", "/api/entries"+tt.query, "")
+		var paths []string
+		for _, s := range decodeJSON[[]detail](t, w) {
+			paths = append(paths, s.Path)
+		}
+		if !slices.Equal(paths, tt.want) {
+			t.Errorf("GET /api/entries%s = %q, want %q", tt.query, paths, tt.want)
+		}
+	}
+	for _, q := range []string{"?limit=-1", "?sort=size", "?q=nofield:x"} {
+		if w := do(h, "GET", "/api/entries"+q, ""); w.Code != http.StatusBadRequest {
+			t.Errorf("GET /api/entries%s = %d", q, w.Code)
+		}
+	}
+}
+
+func TestEntryLifecycle(t *testing.T) {
+	s := apiServer(t, apiFiles)
+	var commits []string
+	s.Commit = func(_ context.Context, rel, verb string, also ...string) error {
+		commits = append(commits, verb+" "+rel)
+		return nil
+	}
+	h := s.Handler()
+
+	w := do(h, "GET", "/api/entries/slices", "")
+	d := decodeJSON[detail](t, w)
+	if d.Path != "go/slices.md" || d.Title != "Slices" || !strings.HasPrefix(d.Content, "---\ntitle: Slices\n") {
+		t.Errorf("GET slices = %+v", d)
+	}
+	tag := w.Header().Get("ETag")
+	if tag == "" {
+		t.Fatal("no ETag")
+	}
+	if w := do(h, "GET", "/api/entries/secret", ""); w.Code != http.StatusNotFound {
+		t.Errorf("GET secret = %d %s", w.Code, w.Body)
+	}
+	if w := do(h, "GET", "/api/entries/nowhere", ""); w.Code != http.StatusNotFound {
+		t.Errorf("GET nowhere = %d", w.Code)
+	}
+
+	// Writes are checked against If-Match.
+	if w := do(h, "PUT", "/api/entries/slices", `{"content":"# Slices\n\nNew.\n"}`, "If-Match", `"stale"`); w.Code != http.StatusPreconditionFailed {
+		t.Errorf("PUT with a stale ETag = %d %s", w.Code, w.Body)
+	}
+	w = do(h, "PUT", "/api/entries/slices", `{"content":"---\ntitle: Slices\ntags: [go]\n---\n\nNew.\n"}`, "If-Match", tag)
+	if d := decodeJSON[detail](t, w); w.Code != http.StatusOK || !strings.HasSuffix(d.Content, "New.\n") || w.Header().Get("ETag") == tag {
+		t.Errorf("PUT = %d %+v", w.Code, d)
+	}
+	if w := do(h, "PUT", "/api/entries/slices", `{"content":"---\nprivate: true\n---\n"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("PUT making the entry private = %d", w.Code)
+	}
+	if w := do(h, "PUT", "/api/entries/slices", `{"content":"x","extra":1}`); w.Code != http.StatusBadRequest {
+		t.Errorf("PUT with an unknown field = %d", w.Code)
+	}
+
+	w = do(h, "PATCH", "/api/entries/maps", `{"title":"Maps in Go","tags":["go"],"draft":true}`)
+	if d := decodeJSON[detail](t, w); d.Title != "Maps in Go" || !slices.Equal(d.Tags, []string{"go"}) || !d.Draft {
+		t.Errorf("PATCH = %+v", d)
+	}
+	w = do(h, "PATCH", "/api/entries/maps", `{"draft":false}`)
+	if d := decodeJSON[detail](t, w); d.Draft || d.Content != "---\ntitle: Maps in Go\ndate: 2024-05-01\ntags: [go]\n---\n\nMaps are unordered.\n" {
+		t.Errorf("PATCH draft false = %+v", d)
+	}
+
+	w = do(h, "POST", "/api/entries", `{"category":"rust","title":"Ownership","body":"Moves.","draft":true}`)
+	if w.Code != http.StatusCreated || w.Header().Get("Location") != "/api/entries/rust/ownership.md" {
+		t.Fatalf("POST = %d %s %v", w.Code, w.Body, w.Header())
+	}
+	if d := decodeJSON[detail](t, w); !d.Draft || !strings.HasSuffix(d.Content, "# Ownership\n\nMoves.\n") {
+		t.Errorf("POST content = %q", d.Content)
+	}
+	if w := do(h, "POST", "/api/entries", `{"category":"rust","title":"Ownership"}`); w.Code != http.StatusConflict {
+		t.Errorf("POST of an existing entry = %d", w.Code)
+	}
+	if w := do(h, "POST", "/api/entries", `{"title":"No category"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("POST without a category = %d", w.Code)
+	}
+
+	want := []string{"update go/slices.md", "update go/maps.md", "update go/maps.md", "add rust/ownership.md"}
+	if !slices.Equal(commits, want) {
+		t.Errorf("commits = %q, want %q", commits, want)
+	}
+}
+
+func TestHTMLAndSearch(t *testing.T) {
+	h := apiServer(t, apiFiles).Handler()
+	w := do(h, "GET", "/api/html/slices", "")
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/html") {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	if !strings.Contains(w.Body.String(), `href="/api/html/go/maps.md">Maps</a>`) {
+		t.Errorf("GET /api/html/slices =\n%s", w.Body)
+	}
+
+	results := decodeJSON[[]struct{ Path, Title string }](t, do(h, "GET", "/api/search?q=unordered", ""))
+	if len(results) != 1 || results[0].Path != "go/maps.md" {
+		t.Errorf("search unordered = %+v", results)
+	}
+	if got := do(h, "GET", "/api/search?q=zzzz", "").Body.String(); strings.TrimSpace(got) != "[]" {
+		t.Errorf("search with no results = %s", got)
+	}
+}
+
+func TestTags(t *testing.T) {
+	s := apiServer(t, apiFiles)
+	h := s.Handler()
+	type count struct {
+		Tag   string
+		Count int
+	}
+	if got := decodeJSON[[]count](t, do(h, "GET", "/api/tags", "")); !slices.Equal(got, []count{{"go", 2}, {"git", 1}, {"maps", 1}}) {
+		t.Errorf("GET /api/tags = %+v", got)
+	}
+	if w := do(h, "POST", "/api/tags/rename", `{"from":"maps","to":"go"}`); w.Code != http.StatusConflict {
+		t.Errorf("rename onto a used tag = %d %s", w.Code, w.Body)
+	}
+	if w := do(h, "POST", "/api/tags/rename", `{"from":"maps"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("rename without to = %d", w.Code)
+	}
+	w := do(h, "POST", "/api/tags/merge", `{"from":["maps","git"],"to":"go"}`)
+	changes := decodeJSON[[]struct {
+		Path     string
+		Old, New []string
+	}](t, w)
+	if len(changes) != 2 || changes[0].Path != "git/rebase.md" || !slices.Equal(changes[0].New, []string{"go"}) {
+		t.Errorf("merge = %+v", changes)
+	}
+	if data, _ := s.Tree.Read("go/maps.md"); !strings.Contains(string(data), "tags: [go]\n") {
+		t.Errorf("go/maps.md after merge:\n%s", data)
+	}
+}
@@ -0,0 +1,424 @@
+package api
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/canhta/til/go/internal/include"
+	"github.com/canhta/til/go/internal/links"
+	"github.com/canhta/til/go/internal/notes"
+	"github.com/canhta/til/go/internal/query"
+	"github.com/canhta/til/go/internal/search"
+	"github.com/canhta/til/go/internal/tags"
+	"github.com/canhta/til/go/pkg/entry"
+	"github.com/canhta/til/go/pkg/render"
+)
+
+// detail is the JSON form of a single entry.
+type detail struct {
+	entry.Summary
+	Draft bool `json:"draft,omitempty"`
+	// Content is the whole file, frontmatter included.
+	Content string `json:"content"`
+}
+
+func (s *Server) listEntries(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	limit, err := intParam(r, "limit", 0)
+	if err != nil {
+		s.fail(w, r, err)
+		return
+	}
+	x, err := query.Parser{Now: time.Now(), Collections: s.Collections, Lang: s.Lang}.Parse(q.Get("q"))
+	if err != nil {
+		s.fail(w, r, badRequest("q: %v", err))
+		return
+	}
+	entries, err := s.entries(boolParam(r, "drafts"))
+	if err != nil {
+		s.fail(w, r, err)
+		return
+	}
+	entries = query.Filter(entries, x)
+	key := q.Get("sort")
+	if key == "" {
+		key = "created"
+	}
+	if err := query.Sort(entries, key, boolParam(r, "reverse")); err != nil {
+		s.fail(w, r, badRequest("sort: %v", err))
+		return
+	}
+	if limit > 0 && len(entries) > limit {
+		entries = entries[:limit]
+	}
+	out := make([]entry.Summary, len(entries))
+	for i, e := range entries {
+		out[i] = entry.Summarize(e)
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+// entries loads the entries served, leaving out private ones and, unless
+// drafts is set, drafts.
+func (s *Server) entries(drafts bool) ([]*entry.Entry, error) {
+	entries, err := s.Tree.Entries()
+	if err != nil {
+		return nil, err
+	}
+	return slices.DeleteFunc(entries, func(e *entry.Entry) bool {
+		return e.Meta.Private || e.Meta.Draft && !drafts
+	}), nil
+}
+
+// resolve finds the entry named by the {ref} path parameter. Only refs
+// inside the tree are accepted, and only entries are found: a path naming
+// another file, such as one in .git, is not.
+func (s *Server) resolve(r *http.Request) (*entry.Entry, error) {
+	ref := r.PathValue("ref")
+	if ref == "" || path.IsAbs(ref) || slices.Contains(strings.Split(ref, "/"), "..") {
+		return nil, badRequest("invalid entry %q", ref)
+	}
+	e, err := s.Tree.Resolve(path.Clean(ref))
+	if err != nil {
+		return nil, err
+	}
+	if e.Meta.Private {
+		return nil, &httpError{http.StatusNotFound, fmt.Errorf("%q: %w", ref, errPrivate)}
+	}
+	return e, nil
+}
+
+var errPrivate = errors.New("private entries are not served")
+
+func etag(data []byte) string {
+	sum := sha256.Sum256(data)
+	return `"` + hex.EncodeToString(sum[:8]) + `"`
+}
+
+// writeDetail answers with the entry at rel as it is stored.
+func (s *Server) writeDetail(w http.ResponseWriter, r *http.Request, status int, rel string) {
+	data, err := s.Tree.Read(rel)
+	if err != nil {
+		s.fail(w, r, err)
+		return
+	}
+	e, err := s.Tree.Load(rel)
+	if err != nil {
+		s.fail(w, r, err)
+		return
+	}
+	w.Header().Set("ETag", etag(data))
+	writeJSON(w, status, detail{Summary: entry.Summarize(e), Draft: e.Meta.Draft, Content: string(data)})
+}
+
+func (s *Server) getEntry(w http.ResponseWriter, r *http.Request) {
+	e, err := s.resolve(r)
+	if err != nil {
+		s.fail(w, r, err)
+		return
+	}
+	s.writeDetail(w, r, http.StatusOK, e.Path)
+}
+
+func (s *Server) getHTML(w http.ResponseWriter, r *http.Request) {
+	e, err := s.resolve(r)
+	if err != nil {
+		s.fail(w, r, err)
+		return
+	}
+	html, err := s.renderEntry(e, "/api/html/", r.URL.Query().Get("style"))
+	if err != nil {
+		s.fail(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(html)
+}
+
+// renderEntry renders e to HTML with its includes expanded, linking
+// [[links]] to the entries they name under prefix.
+func (s *Server) renderEntry(e *entry.Entry, prefix, style string) ([]byte, error) {
+	entries, err := s.entries(true)
+	if err != nil {
+		return nil, err
+	}
+	return renderIndexed(e, links.NewIndex(entries), prefix, style)
+}
+
+// renderIndexed is renderEntry with the index of entries at hand.
+func renderIndexed(e *entry.Entry, ix *links.Index, prefix, style string) ([]byte, error) {
+	rd := render.New(render.Options{
+		Highlight: style,
+		ResolveWiki: func(from, target string) (string, string, bool) {
+			to, res := ix.Resolve(from, links.Link{Target: target, Wiki: true})
+			if res != links.Resolved {
+				return "", "", false
+			}
+			return prefix + to.Path, to.Meta.Title, true
+		},
+	})
+	return rd.RenderFrom(include.Expand(e, ix).Body, e.Path)
+}
+
+// newEntry is the request body of POST /api/entries.
+type newEntry struct {
+	Category string   `json:"category"`
+	Title    string   `json:"title"`
+	Slug     string   `json:"slug"`
+	Tags     []string `json:"tags"`
+	// Body is markdown appended to the template's content.
+	Body  string `json:"body"`
+	Draft bool   `json:"draft"`
+}
+
+func (s *Server) createEntry(w http.ResponseWriter, r *http.Request) {
+	var req newEntry
+	if err := decode(r, &req); err != nil {
+		s.fail(w, r, err)
+		return
+	}
+	// Requests made by the web app are attributed to whoever is signed in.
+	var author string
+	if s.sessions != nil {
+		if u, _, ok := s.sessions.user(r); ok {
+			author = u.Name
+		}
+	}
+	rel, err := s.create(req, author)
+	if err != nil {
+		s.fail(w, r, err)
+		return
+	}
+	s.commit(r, rel, "add")
+	w.Header().Set("Location", "/api/entries/"+rel)
+	s.writeDetail(w, r, http.StatusCreated, rel)
+}
+
+// create scaffolds and saves the entry req asks for, attributed to author
+// if set, returning its path. Only entry files are written.
+func (s *Server) create(req newEntry, author string) (string, error) {
+	if req.Category == "" || req.Title == "" {
+		return "", badRequest("category and title are required")
+	}
+	if req.Slug != "" && !entry.ValidSlug(req.Slug) {
+		return "", badRequest("invalid slug %q", req.Slug)
+	}
+	rel, content, err := s.Scaffold(req.Category, req.Title, req.Slug, req.Tags)
+	if err != nil {
+		return "", badRequest("%v", err)
+	}
+	if !isEntryPath(rel) {
+		return "", badRequest("%s is not an entry", rel)
+	}
+	if req.Body != "" {
+		content = append(bytes.TrimRight(content, "\n"), "\n\n"+strings.TrimRight(req.Body, "\n")+"\n"...)
+	}
+	if req.Draft || author != "" {
+		content, err = entry.Rewrite(content, func(f *entry.Front) error {
+			if author != "" {
+				if err := f.Set("author", author); err != nil {
+					return err
+				}
+			}
+			if req.Draft {
+				return f.Set("draft", true)
+			}
+			return nil
+		})
+		if err != nil {
+			return "", err
+		}
+	}
+	if ok, err := s.Tree.Exists(rel); err != nil || ok {
+		if err == nil {
+			err = &httpError{http.StatusConflict, fmt.Errorf("%s already exists", rel)}
+		}
+		return "", err
+	}
+	return rel, s.Tree.Create(rel, content)
+}
+
+// commit commits a change, logging rather than failing: the file itself
+// was saved either way.
+func (s *Server) commit(r *http.Request, rel, verb string, also ...string) {
+	if s.Commit == nil {
+		return
+	}
+	if err := s.Commit(r.Context(), rel, verb, also...); err != nil {
+		s.Log.Printf("commit %s: %v", rel, err)
+	}
+}
+
+// checkMatch enforces an If-Match precondition against the stored file.
+func (s *Server) checkMatch(r *http.Request, rel string) error {
+	want := r.Header.Get("If-Match")
+	if want == "" {
+		return nil
+	}
+	data, err := s.Tree.Read(rel)
+	if err != nil {
+		return err
+	}
+	if want != "*" && want != etag(data) {
+		return &httpError{http.StatusPreconditionFailed, fmt.Errorf("%s changed since it was read", rel)}
+	}
+	return nil
+}
+
+func (s *Server) putEntry(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Content string `json:"content"`
+	}
+	if err := decode(r, &req); err != nil {
+		s.fail(w, r, err)
+		return
+	}
+	e, err := s.resolve(r)
+	if err == nil {
+		err = s.checkMatch(r, e.Path)
+	}
+	if err != nil {
+		s.fail(w, r, err)
+		return
+	}
+	if err := s.update(e.Path, []byte(req.Content)); err != nil {
+		s.fail(w, r, err)
+		return
+	}
+	s.commit(r, e.Path, "update")
+	s.writeDetail(w, r, http.StatusOK, e.Path)
+}
+
+// isEntryPath reports whether rel can be the path of an entry file of the
+// tree.
+func isEntryPath(rel string) bool {
+	return notes.InTree(rel) && path.Ext(rel) == ".md"
+}
+
+// update replaces the entry at rel with content, which must parse and
+// must not make it private. Only entry files are written.
+func (s *Server) update(rel string, content []byte) error {
+	if !isEntryPath(rel) {
+		return badRequest("%s is not an entry", rel)
+	}
+	next, err := entry.Parse(rel, content)
+	if err != nil {
+		return badRequest("%v", err)
+	}
+	if next.Meta.Private {
+		return badRequest("entries can only be made private with til edit")
+	}
+	return s.Tree.Write(rel, content)
+}
+
+// patch is the request body of PATCH /api/entries/{ref}; fields left out
+// are kept.
+type patch struct {
+	Title *string   `json:"title"`
+	Tags  *[]string `json:"tags"`
+	Draft *bool     `json:"draft"`
+}
+
+func (s *Server) patchEntry(w http.ResponseWriter, r *http.Request) {
+	var req patch
+	if err := decode(r, &req); err != nil {
+		s.fail(w, r, err)
+		return
+	}
+	e, err := s.resolve(r)
+	if err == nil {
+		err = s.checkMatch(r, e.Path)
+	}
+	if err != nil {
+		s.fail(w, r, err)
+		return
+	}
+	if req.Tags != nil {
+		allow, err := tags.LoadAllowlist(s.Tree)
+		if err != nil {
+			s.fail(w, r, err)
+			return
+		}
+		if u := allow.Unknown(*req.Tags); len(u) > 0 {
+			s.fail(w, r, badRequest("tags not in the allowlist: %s", strings.Join(u, ", ")))
+			return
+		}
+	}
+	data, err := s.Tree.Read(e.Path)
+	if err != nil {
+		s.fail(w, r, err)
+		return
+	}
+	updated, err := entry.Rewrite(data, func(f *entry.Front) error {
+		if req.Title != nil {
+			if err := f.Set("title", *req.Title); err != nil {
+				return err
+			}
+		}
+		if req.Tags != nil {
+			if err := f.Set("tags", *req.Tags); err != nil {
+				return err
+			}
+		}
+		if req.Draft != nil {
+			if *req.Draft {
+				return f.Set("draft", true)
+			}
+			_, err := f.Delete("draft")
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		s.fail(w, r, err)
+		return
+	}
+	if !bytes.Equal(updated, data) {
+		if err := s.Tree.Write(e.Path, updated); err != nil {
+			s.fail(w, r, err)
+			return
+		}
+		s.commit(r, e.Path, "update")
+	}
+	s.writeDetail(w, r, http.StatusOK, e.Path)
+}
+
+func (s *Server) search(w http.ResponseWriter, r *http.Request) {
+	limit, err := intParam(r, "limit", 20)
+	if err != nil {
+		s.fail(w, r, err)
+		return
+	}
+	x, err := query.Parser{Now: time.Now(), Collections: s.Collections, Lang: s.Lang}.Parse(r.URL.Query().Get("q"))
+	if err != nil {
+		s.fail(w, r, badRequest("q: %v", err))
+		return
+	}
+	ix, err := search.Open(s.Tree)
+	if err != nil {
+		s.fail(w, r, err)
+		return
+	}
+	defer ix.Close()
+	if _, err := ix.Sync(r.Context()); err != nil {
+		s.fail(w, r, err)
+		return
+	}
+	results, err := ix.Query(r.Context(), x, search.Options{Limit: limit})
+	if err != nil {
+		s.fail(w, r, err)
+		return
+	}
+	if results == nil {
+		results = []search.Result{}
+	}
+	writeJSON(w, http.StatusOK, results)
+}
@@ -0,0 +1,174 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// GitHubURL and GitHubAPI are the addresses of github.com and its REST
+// API.
+const (
+	GitHubURL = "https://github.com"
+	GitHubAPI = "https://api.github.com"
+)
+
+const oauthCookie = "til_oauth"
+
+// GitHub signs users in to the web app with a GitHub OAuth app, whose
+// callback URL is /auth/github/callback on the server.
+type GitHub struct {
+	ClientID     string
+	ClientSecret string
+	// Allow lists the logins allowed to sign in.
+	Allow []string
+	// URL is the address of a GitHub Enterprise server, whose API is then
+	// at /api/v3. Defaults to GitHubURL.
+	URL    string
+	Client *http.Client
+}
+
+func (g *GitHub) web() string {
+	if g.URL == "" {
+		return GitHubURL
+	}
+	return strings.TrimSuffix(g.URL, "/")
+}
+
+func (g *GitHub) api() string {
+	if g.URL == "" {
+		return GitHubAPI
+	}
+	return g.web() + "/api/v3"
+}
+
+func (g *GitHub) allowed(login string) bool {
+	return slices.ContainsFunc(g.Allow, func(a string) bool { return strings.EqualFold(a, login) })
+}
+
+// callbackURL is where GitHub sends users back to after r.
+func callbackURL(r *http.Request) string {
+	scheme := "http"
+	if secure(r) {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host + "/auth/github/callback"
+}
+
+// githubLogin sends the user to GitHub to sign in, remembering the state
+// to expect back and the page to return to.
+func (s *Server) githubLogin(w http.ResponseWriter, r *http.Request) {
+	g := s.Web.GitHub
+	state := randomToken()
+	http.SetCookie(w, &http.Cookie{
+		Name: oauthCookie, Value: state + "|" + localPath(r.URL.Query().Get("next")), Path: "/auth/github/",
+		MaxAge: 600, HttpOnly: true, Secure: secure(r), SameSite: http.SameSiteLaxMode,
+	})
+	q := url.Values{"client_id": {g.ClientID}, "state": {state}, "redirect_uri": {callbackURL(r)}}
+	http.Redirect(w, r, g.web()+"/login/oauth/authorize?"+q.Encode(), http.StatusSeeOther)
+}
+
+// githubCallback signs in the GitHub user coming back with a code.
+func (s *Server) githubCallback(w http.ResponseWriter, r *http.Request) {
+	g := s.Web.GitHub
+	var state, next string
+	if c, err := r.Cookie(oauthCookie); err == nil {
+		state, next, _ = strings.Cut(c.Value, "|")
+	}
+	if state == "" || r.URL.Query().Get("state") != state {
+		s.loginPage(w, r, http.StatusBadRequest, "The GitHub sign-in expired; try again.")
+		return
+	}
+	http.SetCookie(w, &http.Cookie{Name: oauthCookie, Path: "/auth/github/", MaxAge: -1})
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+	token, err := g.exchange(ctx, r.URL.Query().Get("code"), callbackURL(r))
+	if err != nil {
+		s.Log.Printf("github sign-in: %v", err)
+		s.loginPage(w, r, http.StatusBadGateway, "GitHub did not sign you in.")
+		return
+	}
+	login, name, err := g.user(ctx, token)
+	if err != nil {
+		s.Log.Printf("github sign-in: %v", err)
+		s.loginPage(w, r, http.StatusBadGateway, "GitHub did not say who you are.")
+		return
+	}
+	if !g.allowed(login) {
+		s.loginPage(w, r, http.StatusForbidden, fmt.Sprintf("%s is not allowed to sign in.", login))
+		return
+	}
+	if name == "" {
+		name = login
+	}
+	s.sessions.start(w, r, user{Login: login, Name: name})
+	http.Redirect(w, r, next, http.StatusSeeOther)
+}
+
+// exchange trades an OAuth code for an access token.
+func (g *GitHub) exchange(ctx context.Context, code, redirect string) (string, error) {
+	form := url.Values{"client_id": {g.ClientID}, "client_secret": {g.ClientSecret}, "code": {code}, "redirect_uri": {redirect}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, g.web()+"/login/oauth/access_token", strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	var out struct {
+		Token string `json:"access_token"`
+		Error string `json:"error_description"`
+	}
+	if err := g.do(req, &out); err != nil {
+		return "", err
+	}
+	if out.Token == "" {
+		return "", fmt.Errorf("no access token: %s", out.Error)
+	}
+	return out.Token, nil
+}
+
+// user returns the login and name of the user token belongs to.
+func (g *GitHub) user(ctx context.Context, token string) (string, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, g.api()+"/user", nil)
+	if err != nil {
+		return "", "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	var out struct {
+		Login string `json:"login"`
+		Name  string `json:"name"`
+	}
+	if err := g.do(req, &out); err != nil {
+		return "", "", err
+	}
+	if out.Login == "" {
+		return "", "", fmt.Errorf("no login in the answer of %s", req.URL)
+	}
+	return out.Login, out.Name, nil
+}
+
+func (g *GitHub) do(req *http.Request, v any) error {
+	req.Header.Set("Accept", "application/json")
+	client := g.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("%s: %s", req.URL.Path, resp.Status)
+	}
+	return json.Unmarshal(data, v)
+}
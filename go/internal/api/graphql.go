@@ -0,0 +1,407 @@
+package api
+
+import (
+	"cmp"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/canhta/til/go/internal/graphql"
+	"github.com/canhta/til/go/internal/links"
+	"github.com/canhta/til/go/internal/notes"
+	"github.com/canhta/til/go/internal/query"
+	"github.com/canhta/til/go/internal/tags"
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+// Connections return 50 entries unless asked for up to MaxPage.
+const (
+	DefaultPage = 50
+	MaxPage     = 500
+)
+
+// graph is what a GraphQL request reads, loaded once per request.
+type graph struct {
+	entries []*entry.Entry
+	byPath  map[string]*entry.Entry
+	once    sync.Once
+	links   *links.Graph
+	ixOnce  sync.Once
+	ix      *links.Index
+}
+
+type graphKey struct{}
+
+func graphFrom(ctx context.Context) *graph { return ctx.Value(graphKey{}).(*graph) }
+
+// linkGraph builds the link graph on first use.
+func (g *graph) linkGraph() *links.Graph {
+	g.once.Do(func() { g.links = links.NewGraph(g.entries) })
+	return g.links
+}
+
+// index indexes the entries for resolving links on first use.
+func (g *graph) index() *links.Index {
+	g.ixOnce.Do(func() { g.ix = links.NewIndex(g.entries) })
+	return g.ix
+}
+
+func (g *graph) paths(ps []string) []*entry.Entry {
+	out := make([]*entry.Entry, 0, len(ps))
+	for _, p := range ps {
+		out = append(out, g.byPath[p])
+	}
+	return out
+}
+
+// connection is a page of entries.
+type connection struct {
+	page             []*entry.Entry
+	total            int
+	hasNext, hasPrev bool
+}
+
+type tagValue struct {
+	name  string
+	count int
+}
+
+type linkValue struct{ from, to *entry.Entry }
+
+// connArgs filter and page the entries of a connection.
+var connArgs = []graphql.Arg{
+	{Name: "query", Type: "String", Doc: "A query in the language of til search, such as \"tag:go AND created:>2024\"."},
+	{Name: "category", Type: "String"},
+	{Name: "tag", Type: "[String!]", Doc: "Only entries with all of these tags."},
+	{Name: "author", Type: "String"},
+	{Name: "lang", Type: "String", Doc: "Only entries in this language, such as \"vi\"."},
+	{Name: "drafts", Type: "Boolean", Default: false},
+	{Name: "sort", Type: "String", Doc: "One of " + strings.Join(query.SortKeys, ", ") + "."},
+	{Name: "reverse", Type: "Boolean", Default: false},
+	{Name: "first", Type: "Int", Doc: fmt.Sprintf("How many entries to return; %d unless set, at most %d.", DefaultPage, MaxPage)},
+	{Name: "after", Type: "String", Doc: "The cursor of the entry to start after."},
+}
+
+// withSort returns connArgs sorting by key unless told otherwise.
+func withSort(key string) []graphql.Arg {
+	args := slices.Clone(connArgs)
+	for i := range args {
+		if args[i].Name == "sort" {
+			args[i].Default = key
+		}
+	}
+	return args
+}
+
+// connect filters, sorts and pages entries as args ask.
+func (s *Server) connect(entries []*entry.Entry, args map[string]any) (*connection, error) {
+	var x query.And
+	if q, _ := args["query"].(string); q != "" {
+		e, err := query.Parser{Now: time.Now(), Collections: s.Collections, Lang: s.Lang}.Parse(q)
+		if err != nil {
+			return nil, fmt.Errorf("query: %v", err)
+		}
+		x = append(x, e)
+	}
+	if c, _ := args["category"].(string); c != "" {
+		x = append(x, query.Category(c))
+	}
+	if ts, _ := args["tag"].([]any); ts != nil {
+		for _, t := range ts {
+			x = append(x, query.Tag(t.(string)))
+		}
+	}
+	if a, _ := args["author"].(string); a != "" {
+		x = append(x, query.Author(a))
+	}
+	if l, _ := args["lang"].(string); l != "" {
+		x = append(x, query.Lang{Tag: l, Default: s.Lang})
+	}
+	drafts, _ := args["drafts"].(bool)
+	var out []*entry.Entry
+	for _, e := range entries {
+		if (drafts || !e.Meta.Draft) && x.Match(e) {
+			out = append(out, e)
+		}
+	}
+	if key, _ := args["sort"].(string); key != "" {
+		reverse, _ := args["reverse"].(bool)
+		if err := query.Sort(out, key, reverse); err != nil {
+			return nil, err
+		}
+	}
+	c := &connection{total: len(out)}
+	if after, _ := args["after"].(string); after != "" {
+		p, err := base64.RawURLEncoding.DecodeString(after)
+		i := slices.IndexFunc(out, func(e *entry.Entry) bool { return e.Path == string(p) })
+		if err != nil || i < 0 {
+			return nil, fmt.Errorf("after: %q is not the cursor of an entry here", after)
+		}
+		out, c.hasPrev = out[i+1:], true
+	}
+	first := DefaultPage
+	if n, ok := args["first"].(int); ok {
+		if n < 0 || n > MaxPage {
+			return nil, fmt.Errorf("first: want 0 to %d, got %d", MaxPage, n)
+		}
+		first = n
+	}
+	if len(out) > first {
+		out, c.hasNext = out[:first], true
+	}
+	c.page = out
+	return c, nil
+}
+
+func cursor(e *entry.Entry) string { return base64.RawURLEncoding.EncodeToString([]byte(e.Path)) }
+
+// resolver adapts a function of the parent's type to a field resolver.
+func resolver[T any](fn func(ctx context.Context, v T, args map[string]any) (any, error)) func(context.Context, any, map[string]any) (any, error) {
+	return func(ctx context.Context, parent any, args map[string]any) (any, error) {
+		return fn(ctx, parent.(T), args)
+	}
+}
+
+// plain adapts a function of the parent's type that cannot fail.
+func plain[T any](fn func(T) any) func(context.Context, any, map[string]any) (any, error) {
+	return func(_ context.Context, parent any, _ map[string]any) (any, error) { return fn(parent.(T)), nil }
+}
+
+// orNil returns s, or nil for the empty string, for nullable fields.
+func orNil(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// schema returns the GraphQL schema of the API.
+func (s *Server) schema() *graphql.Schema {
+	entries := func(ctx context.Context, parent any, args map[string]any) (any, error) {
+		return s.connect(graphFrom(ctx).entries, args)
+	}
+	g := &graphql.Schema{Query: "Query", Types: []*graphql.Object{
+		{Name: "Query", Fields: []*graphql.Field{
+			{Name: "entries", Type: "EntryConnection!", Args: withSort("created"), Resolve: entries,
+				Doc: "Entries, newest first unless sorted otherwise."},
+			{Name: "entry", Type: "Entry", Args: []graphql.Arg{{Name: "ref", Type: "String!", Doc: "A path, ID, file stem or slug."}},
+				Resolve: func(ctx context.Context, _ any, args map[string]any) (any, error) {
+					ref := args["ref"].(string)
+					if path.IsAbs(ref) || slices.Contains(strings.Split(ref, "/"), "..") {
+						return nil, fmt.Errorf("invalid entry %q", ref)
+					}
+					e, err := s.Tree.Resolve(path.Clean(ref))
+					if errors.Is(err, notes.ErrNotFound) {
+						return nil, nil
+					}
+					if err != nil {
+						return nil, err
+					}
+					return graphFrom(ctx).byPath[e.Path], nil
+				}},
+			{Name: "tags", Type: "[Tag!]!", Args: []graphql.Arg{{Name: "drafts", Type: "Boolean", Default: false}},
+				Doc: "Tags by how many entries use them.",
+				Resolve: func(ctx context.Context, _ any, args map[string]any) (any, error) {
+					var out []tagValue
+					for _, c := range tags.Counts(withDrafts(graphFrom(ctx).entries, args)) {
+						out = append(out, tagValue{c.Tag, c.Count})
+					}
+					return orEmpty(out), nil
+				}},
+			{Name: "categories", Type: "[Category!]!", Args: []graphql.Arg{{Name: "drafts", Type: "Boolean", Default: false}},
+				Doc: "Categories by name.",
+				Resolve: func(ctx context.Context, _ any, args map[string]any) (any, error) {
+					counts := map[string]int{}
+					for _, e := range withDrafts(graphFrom(ctx).entries, args) {
+						counts[e.Meta.Category]++
+					}
+					var out []tagValue
+					for name, n := range counts {
+						out = append(out, tagValue{name, n})
+					}
+					slices.SortFunc(out, func(a, b tagValue) int { return strings.Compare(a.name, b.name) })
+					return orEmpty(out), nil
+				}},
+			{Name: "links", Type: "[Link!]!", Args: []graphql.Arg{{Name: "drafts", Type: "Boolean", Default: false}},
+				Doc: "Every link between two entries: the edges of the link graph.",
+				Resolve: func(ctx context.Context, _ any, args map[string]any) (any, error) {
+					g := graphFrom(ctx)
+					drafts, _ := args["drafts"].(bool)
+					out := []linkValue{}
+					for _, from := range g.entries {
+						for _, to := range g.paths(g.linkGraph().Out[from.Path]) {
+							if drafts || !from.Meta.Draft && !to.Meta.Draft {
+								out = append(out, linkValue{from, to})
+							}
+						}
+					}
+					return out, nil
+				}},
+		}},
+		{Name: "Entry", Fields: []*graphql.Field{
+			{Name: "id", Type: "ID!", Resolve: plain(func(e *entry.Entry) any { return e.ID() })},
+			{Name: "path", Type: "String!", Resolve: plain(func(e *entry.Entry) any { return e.Path })},
+			{Name: "title", Type: "String!", Resolve: plain(func(e *entry.Entry) any { return e.Meta.Title })},
+			{Name: "slug", Type: "String", Resolve: plain(func(e *entry.Entry) any { return orNil(e.Meta.Slug) })},
+			{Name: "category", Type: "String!", Resolve: plain(func(e *entry.Entry) any { return e.Meta.Category })},
+			{Name: "tags", Type: "[String!]!", Resolve: plain(func(e *entry.Entry) any { return orEmpty(e.Meta.Tags) })},
+			{Name: "author", Type: "String", Resolve: plain(func(e *entry.Entry) any { return orNil(e.Meta.Author) })},
+			{Name: "lang", Type: "String!", Doc: "The language the entry is written in.",
+				Resolve: plain(func(e *entry.Entry) any { return cmp.Or(e.Meta.Lang, s.Lang) })},
+			{Name: "created", Type: "String", Doc: "The creation date, as 2006-01-02.",
+				Resolve: plain(func(e *entry.Entry) any { return orNil(entry.Summarize(e).Created) })},
+			{Name: "updated", Type: "String", Doc: "When the entry last changed, in RFC 3339 format.",
+				Resolve: plain(func(e *entry.Entry) any { return orNil(entry.Summarize(e).Updated) })},
+			{Name: "draft", Type: "Boolean!", Resolve: plain(func(e *entry.Entry) any { return e.Meta.Draft })},
+			{Name: "words", Type: "Int!", Resolve: plain(func(e *entry.Entry) any { return e.Counts.Words })},
+			{Name: "codeLines", Type: "Int!", Resolve: plain(func(e *entry.Entry) any { return e.Counts.CodeLines })},
+			{Name: "readingMinutes", Type: "Int!", Resolve: plain(func(e *entry.Entry) any { return e.Counts.ReadingMinutes() })},
+			{Name: "body", Type: "String!", Doc: "The markdown after the frontmatter.",
+				Resolve: plain(func(e *entry.Entry) any { return string(e.Body) })},
+			{Name: "content", Type: "String!", Doc: "The whole file, frontmatter included.",
+				Resolve: resolver(func(_ context.Context, e *entry.Entry, _ map[string]any) (any, error) {
+					data, err := s.Tree.Read(e.Path)
+					return string(data), err
+				})},
+			{Name: "html", Type: "String!", Args: []graphql.Arg{{Name: "style", Type: "String", Doc: "A chroma style to highlight code with."}},
+				Doc: "The body rendered to HTML, [[links]] pointing at /api/html/.",
+				Resolve: resolver(func(ctx context.Context, e *entry.Entry, args map[string]any) (any, error) {
+					style, _ := args["style"].(string)
+					html, err := renderIndexed(e, graphFrom(ctx).index(), "/api/html/", style)
+					return string(html), err
+				})},
+			{Name: "links", Type: "EntryConnection!", Args: connArgs, Doc: "The entries this one links to, in order.",
+				Resolve: resolver(func(ctx context.Context, e *entry.Entry, args map[string]any) (any, error) {
+					g := graphFrom(ctx)
+					return s.connect(g.paths(g.linkGraph().Out[e.Path]), args)
+				})},
+			{Name: "backlinks", Type: "EntryConnection!", Args: connArgs, Doc: "The entries linking to this one, by path.",
+				Resolve: resolver(func(ctx context.Context, e *entry.Entry, args map[string]any) (any, error) {
+					g := graphFrom(ctx)
+					return s.connect(g.paths(g.linkGraph().In[e.Path]), args)
+				})},
+		}},
+		{Name: "EntryConnection", Doc: "A page of entries.", Fields: []*graphql.Field{
+			{Name: "totalCount", Type: "Int!", Doc: "How many entries match, on every page.",
+				Resolve: plain(func(c *connection) any { return c.total })},
+			{Name: "nodes", Type: "[Entry!]!", Resolve: plain(func(c *connection) any { return orEmpty(c.page) })},
+			{Name: "edges", Type: "[EntryEdge!]!", Resolve: plain(func(c *connection) any { return orEmpty(c.page) })},
+			{Name: "pageInfo", Type: "PageInfo!", Resolve: plain(func(c *connection) any { return c })},
+		}},
+		{Name: "EntryEdge", Fields: []*graphql.Field{
+			{Name: "cursor", Type: "String!", Resolve: plain(func(e *entry.Entry) any { return cursor(e) })},
+			{Name: "node", Type: "Entry!", Resolve: plain(func(e *entry.Entry) any { return e })},
+		}},
+		{Name: "PageInfo", Fields: []*graphql.Field{
+			{Name: "hasNextPage", Type: "Boolean!", Resolve: plain(func(c *connection) any { return c.hasNext })},
+			{Name: "hasPreviousPage", Type: "Boolean!", Resolve: plain(func(c *connection) any { return c.hasPrev })},
+			{Name: "startCursor", Type: "String", Resolve: plain(func(c *connection) any {
+				if len(c.page) == 0 {
+					return nil
+				}
+				return cursor(c.page[0])
+			})},
+			{Name: "endCursor", Type: "String", Doc: "Pass as after to get the next page.", Resolve: plain(func(c *connection) any {
+				if len(c.page) == 0 {
+					return nil
+				}
+				return cursor(c.page[len(c.page)-1])
+			})},
+		}},
+		{Name: "Tag", Fields: []*graphql.Field{
+			{Name: "name", Type: "String!", Resolve: plain(func(t tagValue) any { return t.name })},
+			{Name: "count", Type: "Int!", Resolve: plain(func(t tagValue) any { return t.count })},
+			{Name: "entries", Type: "EntryConnection!", Args: withSort("created"),
+				Resolve: resolver(func(ctx context.Context, t tagValue, args map[string]any) (any, error) {
+					return s.connect(query.Filter(slices.Clone(graphFrom(ctx).entries), query.Tag(t.name)), args)
+				})},
+		}},
+		{Name: "Category", Fields: []*graphql.Field{
+			{Name: "name", Type: "String!", Resolve: plain(func(t tagValue) any { return t.name })},
+			{Name: "count", Type: "Int!", Resolve: plain(func(t tagValue) any { return t.count })},
+			{Name: "entries", Type: "EntryConnection!", Args: withSort("created"),
+				Resolve: resolver(func(ctx context.Context, t tagValue, args map[string]any) (any, error) {
+					return s.connect(query.Filter(slices.Clone(graphFrom(ctx).entries), query.Category(t.name)), args)
+				})},
+		}},
+		{Name: "Link", Doc: "A link from one entry to another.", Fields: []*graphql.Field{
+			{Name: "from", Type: "Entry!", Resolve: plain(func(l linkValue) any { return l.from })},
+			{Name: "to", Type: "Entry!", Resolve: plain(func(l linkValue) any { return l.to })},
+		}},
+	}}
+	if err := g.Check(); err != nil {
+		panic(err)
+	}
+	return g
+}
+
+func withDrafts(entries []*entry.Entry, args map[string]any) []*entry.Entry {
+	if drafts, _ := args["drafts"].(bool); drafts {
+		return entries
+	}
+	return slices.DeleteFunc(slices.Clone(entries), func(e *entry.Entry) bool { return e.Meta.Draft })
+}
+
+// orEmpty turns a nil slice into an empty one, for non-null lists.
+func orEmpty[T any](s []T) []T {
+	if s == nil {
+		return []T{}
+	}
+	return s
+}
+
+func (s *Server) graphSchema() *graphql.Schema {
+	s.schemaOnce.Do(func() { s.gql = s.schema() })
+	return s.gql
+}
+
+// graphQL answers GraphQL queries posted as JSON, or sent as the query,
+// variables and operationName parameters of a GET request.
+func (s *Server) graphQL(w http.ResponseWriter, r *http.Request) {
+	var req graphql.Request
+	if r.Method == http.MethodGet {
+		q := r.URL.Query()
+		req.Query, req.OperationName = q.Get("query"), q.Get("operationName")
+		if v := q.Get("variables"); v != "" {
+			if err := json.Unmarshal([]byte(v), &req.Variables); err != nil {
+				s.fail(w, r, badRequest("variables: %v", err))
+				return
+			}
+		}
+	} else if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&req); err != nil {
+		s.fail(w, r, badRequest("invalid request body: %v", err))
+		return
+	}
+	if req.Query == "" {
+		s.fail(w, r, badRequest("no query; GET /api/graphql/schema describes the schema"))
+		return
+	}
+	entries, err := s.entries(true)
+	if err != nil {
+		s.fail(w, r, err)
+		return
+	}
+	g := &graph{entries: entries, byPath: map[string]*entry.Entry{}}
+	for _, e := range entries {
+		g.byPath[e.Path] = e
+	}
+	resp := s.graphSchema().Execute(context.WithValue(r.Context(), graphKey{}, g), req)
+	status := http.StatusOK
+	if !resp.Executed {
+		status = http.StatusBadRequest
+	}
+	writeJSON(w, status, resp)
+}
+
+func (s *Server) graphQLSchema(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprint(w, s.graphSchema().SDL())
+}
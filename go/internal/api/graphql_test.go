@@ -0,0 +1,137 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+var graphFiles = map[string]string{
+	"go/slices.md":  "---\ntitle: Slices\ndate: 2024-06-01\ntags: [go, slices]\nauthor: Ann\n---\n\nSee [[go/maps]] and [[git/rebase]].\n",
+	"go/maps.md":    "---\ntitle: Maps\ndate: 2024-05-01\ntags: [go]\n---\n\nBack to [[go/slices]].\n",
+	"git/rebase.md": "---\ntitle: Rebase\ndate: 2024-04-01\ntags: [git]\n---\n\nOnto *main*.\n",
+	"git/draft.md":  "---\ntitle: Draft\ndate: 2024-07-01\ndraft: true\n---\n\n[[go/maps]]\n",
+}
+
+// graphQL posts query with vars and returns the status and the JSON
+// answer, compacted.
+func graphQL(t *testing.T, s *Server, query string, vars map[string]any) (int, string) {
+	t.Helper()
+	body, _ := json.Marshal(map[string]any{"query": query, "variables": vars})
+	w := do(s.Handler(), "POST", "/api/graphql", string(body), "Content-Type", "application/json")
+	return w.Code, compact(t, w.Body.Bytes())
+}
+
+func compact(t *testing.T, data []byte) string {
+	t.Helper()
+	var b bytes.Buffer
+	if err := json.Compact(&b, data); err != nil {
+		t.Fatalf("%v: %s", err, data)
+	}
+	return b.String()
+}
+
+func TestGraphQL(t *testing.T) {
+	s := apiServer(t, graphFiles)
+	tests := []struct {
+		name  string
+		query string
+		vars  map[string]any
+		want  string
+	}{
+		{"entries newest first without drafts", `{ entries { totalCount nodes { path } } }`, nil,
+			`{"data":{"entries":{"totalCount":3,"nodes":[{"path":"go/slices.md"},{"path":"go/maps.md"},{"path":"git/rebase.md"}]}}}`},
+		{"filters", `{ a: entries(tag: ["go", "slices"]) { totalCount } b: entries(category: "git", drafts: true) { totalCount } c: entries(query: "author:ann") { totalCount } }`, nil,
+			`{"data":{"a":{"totalCount":1},"b":{"totalCount":2},"c":{"totalCount":1}}}`},
+		{"sort", `{ entries(sort: "title") { nodes { title } } }`, nil,
+			`{"data":{"entries":{"nodes":[{"title":"Maps"},{"title":"Rebase"},{"title":"Slices"}]}}}`},
+		{"entry fields", `query($ref: String!) { entry(ref: $ref) { id title slug category tags author created draft html } }`, map[string]any{"ref": "git/rebase"},
+			`{"data":{"entry":{"id":"git/rebase","title":"Rebase","slug":"rebase","category":"git","tags":["git"],"author":null,"created":"2024-04-01","draft":false,"html":"\u003cp\u003eOnto \u003cem\u003emain\u003c/em\u003e.\u003c/p\u003e\n"}}}`},
+		{"missing entry", `{ entry(ref: "go/nope") { title } }`, nil, `{"data":{"entry":null}}`},
+		{"links and backlinks", `{ entry(ref: "go/maps") { links { nodes { path } } backlinks { nodes { path } } all: backlinks(drafts: true) { totalCount } } }`, nil,
+			`{"data":{"entry":{"links":{"nodes":[{"path":"go/slices.md"}]},"backlinks":{"nodes":[{"path":"go/slices.md"}]},"all":{"totalCount":2}}}}`},
+		{"tags and categories", `{ tags { name count entries { totalCount } } categories { name count } }`, nil,
+			`{"data":{"tags":[{"name":"go","count":2,"entries":{"totalCount":2}},{"name":"git","count":1,"entries":{"totalCount":1}},{"name":"slices","count":1,"entries":{"totalCount":1}}],"categories":[{"name":"git","count":1},{"name":"go","count":2}]}}`},
+		// The draft's link to go/maps.md is left out.
+		{"link graph", `{ links { from { path } to { path } } }`, nil,
+			`{"data":{"links":[{"from":{"path":"go/maps.md"},"to":{"path":"go/slices.md"}},{"from":{"path":"go/slices.md"},"to":{"path":"go/maps.md"}},{"from":{"path":"go/slices.md"},"to":{"path":"git/rebase.md"}}]}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, got := graphQL(t, s, tt.query, tt.vars)
+			if code != http.StatusOK || got != tt.want {
+				t.Errorf("%d\ngot  %s\nwant %s", code, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGraphQLPages(t *testing.T) {
+	s := apiServer(t, graphFiles)
+	var after any
+	var paths []string
+	for range 3 {
+		_, body := graphQL(t, s, `query($after: String) { entries(first: 2, after: $after) { totalCount edges { node { path } } pageInfo { hasNextPage hasPreviousPage endCursor } } }`, map[string]any{"after": after})
+		var resp struct {
+			Data struct {
+				Entries struct {
+					TotalCount int
+					Edges      []struct{ Node struct{ Path string } }
+					PageInfo   struct {
+						HasNextPage, HasPreviousPage bool
+						EndCursor                    string
+					}
+				}
+			}
+		}
+		if err := json.Unmarshal([]byte(body), &resp); err != nil {
+			t.Fatal(err)
+		}
+		c := resp.Data.Entries
+		for _, e := range c.Edges {
+			paths = append(paths, e.Node.Path)
+		}
+		if c.TotalCount != 3 || c.PageInfo.HasPreviousPage != (after != nil) {
+			t.Errorf("page after %v = %s", after, body)
+		}
+		if !c.PageInfo.HasNextPage {
+			break
+		}
+		after = c.PageInfo.EndCursor
+	}
+	if got := strings.Join(paths, " "); got != "go/slices.md go/maps.md git/rebase.md" {
+		t.Errorf("pages = %s", got)
+	}
+
+	for _, q := range []string{`{ entries(after: "bm9wZQ") { totalCount } }`, `{ entries(first: 501) { totalCount } }`, `{ entries(sort: "size") { totalCount } }`} {
+		if code, body := graphQL(t, s, q, nil); code != http.StatusOK || !strings.HasPrefix(body, `{"data":null,"errors":[`) {
+			t.Errorf("%s = %d %s", q, code, body)
+		}
+	}
+}
+
+func TestGraphQLRequests(t *testing.T) {
+	s := apiServer(t, graphFiles)
+	h := s.Handler()
+	q := url.Values{"query": {`query($r: String!) { entry(ref: $r) { title } }`}, "variables": {`{"r": "go/maps"}`}}
+	if w := do(h, "GET", "/api/graphql?"+q.Encode(), ""); w.Code != http.StatusOK || compact(t, w.Body.Bytes()) != `{"data":{"entry":{"title":"Maps"}}}` {
+		t.Errorf("GET /api/graphql = %d %s", w.Code, w.Body)
+	}
+	for _, target := range []string{"/api/graphql", "/api/graphql?query=%7B+nope+%7D", "/api/graphql?query=x&variables=%7B"} {
+		if w := do(h, "GET", target, ""); w.Code != http.StatusBadRequest {
+			t.Errorf("GET %s = %d %s", target, w.Code, w.Body)
+		}
+	}
+	if w := do(h, "POST", "/api/graphql", "{"); w.Code != http.StatusBadRequest {
+		t.Errorf("POST of bad JSON = %d", w.Code)
+	}
+	if code, body := graphQL(t, s, `{ entry(ref: "../../etc/passwd") { title } }`, nil); code != http.StatusOK || !strings.Contains(body, "invalid entry") {
+		t.Errorf("entry outside the tree = %d %s", code, body)
+	}
+	if w := do(h, "GET", "/api/graphql/schema", ""); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "type EntryConnection {") {
+		t.Errorf("GET /api/graphql/schema = %d %s", w.Code, w.Body)
+	}
+}
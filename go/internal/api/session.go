@@ -0,0 +1,105 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// SessionTTL is how long a sign-in to the web app lasts.
+const SessionTTL = 7 * 24 * time.Hour
+
+const (
+	sessionCookie = "til_session"
+	// csrfHeader carries the CSRF token of the session with API requests
+	// made by the web app.
+	csrfHeader = "X-CSRF-Token"
+)
+
+// user is who a session is signed in as.
+type user struct {
+	// Login is the user name or GitHub login.
+	Login string `json:"login"`
+	// Name is the name entries are attributed to.
+	Name    string `json:"name"`
+	Expires int64  `json:"exp"`
+}
+
+// sessions signs and checks session cookies.
+type sessions struct {
+	key []byte
+}
+
+func newSessions(key []byte) *sessions {
+	if len(key) == 0 {
+		key = make([]byte, 32)
+		rand.Read(key)
+	}
+	return &sessions{key: key}
+}
+
+func (ss *sessions) sign(data string) string {
+	h := hmac.New(sha256.New, ss.key)
+	h.Write([]byte(data))
+	return base64.RawURLEncoding.EncodeToString(h.Sum(nil))
+}
+
+// start signs u in with a cookie on w.
+func (ss *sessions) start(w http.ResponseWriter, r *http.Request, u user) {
+	u.Expires = time.Now().Add(SessionTTL).Unix()
+	data, _ := json.Marshal(u)
+	value := base64.RawURLEncoding.EncodeToString(data)
+	http.SetCookie(w, &http.Cookie{
+		Name: sessionCookie, Value: value + "." + ss.sign(value), Path: "/",
+		MaxAge: int(SessionTTL.Seconds()), HttpOnly: true, Secure: secure(r), SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// end signs the user of r out.
+func (ss *sessions) end(w http.ResponseWriter, r *http.Request) {
+	http.SetCookie(w, &http.Cookie{Name: sessionCookie, Path: "/", MaxAge: -1, HttpOnly: true, Secure: secure(r)})
+}
+
+// user returns who r is signed in as, with the CSRF token of the session.
+func (ss *sessions) user(r *http.Request) (user, string, bool) {
+	c, err := r.Cookie(sessionCookie)
+	if err != nil {
+		return user{}, "", false
+	}
+	value, sig, ok := strings.Cut(c.Value, ".")
+	if !ok || subtle.ConstantTimeCompare([]byte(sig), []byte(ss.sign(value))) != 1 {
+		return user{}, "", false
+	}
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	var u user
+	if err != nil || json.Unmarshal(data, &u) != nil || time.Now().Unix() > u.Expires {
+		return user{}, "", false
+	}
+	return u, ss.sign("csrf." + c.Value), true
+}
+
+// checkCSRF reports whether token is the CSRF token of the session of r.
+func (ss *sessions) checkCSRF(r *http.Request, token string) bool {
+	_, want, ok := ss.user(r)
+	return ok && token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(want)) == 1
+}
+
+// secure reports whether r reached the server, or the proxy in front of it,
+// over HTTPS, so that cookies can be kept to it.
+func secure(r *http.Request) bool {
+	return r.TLS != nil || strings.EqualFold(r.Header.Get("X-Forwarded-Proto"), "https")
+}
+
+// randomToken returns a random hex string, for OAuth states.
+func randomToken() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
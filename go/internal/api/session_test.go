@@ -0,0 +1,117 @@
+package api
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// sessionCookieOf signs u in with ss and returns the cookie set.
+func sessionCookieOf(t *testing.T, ss *sessions, r *http.Request, u user) *http.Cookie {
+	t.Helper()
+	w := httptest.NewRecorder()
+	ss.start(w, r, u)
+	for _, c := range w.Result().Cookies() {
+		if c.Name == sessionCookie {
+			return c
+		}
+	}
+	t.Fatal("no session cookie set")
+	return nil
+}
+
+func withCookie(c *http.Cookie) *http.Request {
+	r := httptest.NewRequest("GET", "/", nil)
+	r.AddCookie(c)
+	return r
+}
+
+func TestSessions(t *testing.T) {
+	ss := newSessions([]byte("key"))
+	c := sessionCookieOf(t, ss, httptest.NewRequest("GET", "/", nil), user{Login: "ann", Name: "Ann"})
+	if !c.HttpOnly || c.Secure || c.SameSite != http.SameSiteLaxMode || c.MaxAge != int(SessionTTL.Seconds()) {
+		t.Errorf("cookie = %+v", c)
+	}
+	u, csrf, ok := ss.user(withCookie(c))
+	if !ok || u.Login != "ann" || u.Name != "Ann" || csrf == "" {
+		t.Fatalf("user = %+v, %q, %v", u, csrf, ok)
+	}
+	// The same key, as after a restart, keeps the session.
+	if _, again, ok := newSessions([]byte("key")).user(withCookie(c)); !ok || again != csrf {
+		t.Errorf("session lost with the same key: %v, %q", ok, again)
+	}
+	if !ss.checkCSRF(withCookie(c), csrf) {
+		t.Error("checkCSRF rejected the session's token")
+	}
+	other := sessionCookieOf(t, ss, httptest.NewRequest("GET", "/", nil), user{Login: "bob"})
+	_, otherCSRF, _ := ss.user(withCookie(other))
+	for _, token := range []string{"", otherCSRF, csrf + "x"} {
+		if ss.checkCSRF(withCookie(c), token) {
+			t.Errorf("checkCSRF accepted %q", token)
+		}
+	}
+	if ss.checkCSRF(httptest.NewRequest("POST", "/", nil), csrf) {
+		t.Error("checkCSRF accepted a token without a session")
+	}
+
+	value, sig, _ := strings.Cut(c.Value, ".")
+	forge := func(u user) string {
+		data, _ := json.Marshal(u)
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+	expired := forge(user{Login: "ann", Expires: time.Now().Add(-time.Minute).Unix()})
+	for name, v := range map[string]string{
+		"another user":    forge(user{Login: "root", Expires: time.Now().Add(time.Hour).Unix()}) + "." + sig,
+		"no signature":    value,
+		"bad signature":   value + ".x" + sig,
+		"other key":       value + "." + newSessions([]byte("other")).sign(value),
+		"expired":         expired + "." + ss.sign(expired),
+		"not base64":      "!!!." + ss.sign("!!!"),
+		"not json":        "bm90IGpzb24." + ss.sign("bm90IGpzb24"),
+		"empty":           "",
+		"signature alone": "." + ss.sign(""),
+	} {
+		if u, _, ok := ss.user(withCookie(&http.Cookie{Name: sessionCookie, Value: v})); ok {
+			t.Errorf("%s: signed in as %+v", name, u)
+		}
+	}
+
+	// Sessions started over HTTPS, directly or behind a proxy, keep their
+	// cookie to it.
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("X-Forwarded-Proto", "https")
+	if c := sessionCookieOf(t, ss, r, user{Login: "ann"}); !c.Secure {
+		t.Error("cookie of a session over HTTPS is not secure")
+	}
+	w := httptest.NewRecorder()
+	ss.end(w, r)
+	if c := w.Result().Cookies(); len(c) != 1 || c[0].Name != sessionCookie || c[0].MaxAge >= 0 {
+		t.Errorf("end set %+v", c)
+	}
+}
+
+func TestNewSessionsRandomKey(t *testing.T) {
+	a, b := newSessions(nil), newSessions(nil)
+	if len(a.key) != 32 || string(a.key) == string(b.key) {
+		t.Errorf("keys %x and %x, want distinct random keys", a.key, b.key)
+	}
+}
+
+func TestLocalPath(t *testing.T) {
+	for next, want := range map[string]string{
+		"/edit/go/a.md?x=1":    "/edit/go/a.md?x=1",
+		"":                     "/",
+		"https://evil.example": "/",
+		"//evil.example/":      "/",
+		`/\evil.example`:       "/",
+		"edit":                 "/",
+	} {
+		if got := localPath(next); got != want {
+			t.Errorf("localPath(%q) = %q, want %q", next, got, want)
+		}
+	}
+}
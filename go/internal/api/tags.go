@@ -0,0 +1,92 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/canhta/til/go/internal/tags"
+)
+
+func (s *Server) listTags(w http.ResponseWriter, r *http.Request) {
+	entries, err := s.entries(boolParam(r, "drafts"))
+	if err != nil {
+		s.fail(w, r, err)
+		return
+	}
+	counts := tags.Counts(entries)
+	if counts == nil {
+		counts = []tags.Count{}
+	}
+	writeJSON(w, http.StatusOK, counts)
+}
+
+func (s *Server) renameTag(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		From string `json:"from"`
+		To   string `json:"to"`
+	}
+	if err := decode(r, &req); err != nil {
+		s.fail(w, r, err)
+		return
+	}
+	if req.From == "" || req.To == "" {
+		s.fail(w, r, badRequest("from and to are required"))
+		return
+	}
+	entries, err := s.entries(true)
+	if err != nil {
+		s.fail(w, r, err)
+		return
+	}
+	for _, c := range tags.Counts(entries) {
+		if c.Tag == req.To {
+			s.fail(w, r, &httpError{http.StatusConflict, fmt.Errorf("tag %q is already used by %d entries; merge instead", req.To, c.Count)})
+			return
+		}
+	}
+	s.replaceTags(w, r, []string{req.From}, req.To)
+}
+
+func (s *Server) mergeTags(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		From []string `json:"from"`
+		To   string   `json:"to"`
+	}
+	if err := decode(r, &req); err != nil {
+		s.fail(w, r, err)
+		return
+	}
+	if len(req.From) == 0 || req.To == "" {
+		s.fail(w, r, badRequest("from and to are required"))
+		return
+	}
+	s.replaceTags(w, r, req.From, req.To)
+}
+
+// replaceTags replaces the tags from with to in every entry served,
+// answering with the changes made. Like til tags, it does not commit.
+func (s *Server) replaceTags(w http.ResponseWriter, r *http.Request, from []string, to string) {
+	allow, err := tags.LoadAllowlist(s.Tree)
+	if err != nil {
+		s.fail(w, r, err)
+		return
+	}
+	if u := allow.Unknown([]string{to}); len(u) > 0 {
+		s.fail(w, r, badRequest("tag %q is not in the allowlist", to))
+		return
+	}
+	entries, err := s.entries(true)
+	if err != nil {
+		s.fail(w, r, err)
+		return
+	}
+	changes, err := tags.Rewrite(s.Tree, entries, func(t []string) []string { return tags.Replace(t, from, to) })
+	if err != nil {
+		s.fail(w, r, err)
+		return
+	}
+	if changes == nil {
+		changes = []tags.Change{}
+	}
+	writeJSON(w, http.StatusOK, changes)
+}
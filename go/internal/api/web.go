@@ -0,0 +1,338 @@
+package api
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"html/template"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/canhta/til/go/internal/notes"
+	"github.com/canhta/til/go/internal/query"
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+//go:embed web
+var webFS embed.FS
+
+var webTemplates = template.Must(template.New("").Funcs(template.FuncMap{
+	"date": func(t time.Time) string {
+		if t.IsZero() {
+			return ""
+		}
+		return t.Format(entry.DateLayout)
+	},
+	"join": strings.Join,
+}).ParseFS(webFS, "web/*.html"))
+
+// WebLimit is the most entries the entry list of the web app shows.
+const WebLimit = 200
+
+// Web configures the web app served next to the API, in which people who
+// do not use the command line sign in to browse, write and edit entries.
+type Web struct {
+	// Users maps the names of users signing in with a password to bcrypt
+	// hashes of their passwords.
+	Users map[string]string
+	// GitHub, if set, signs users in with GitHub.
+	GitHub *GitHub
+	// SessionKey signs session cookies. A random key is made when it is
+	// empty, which signs everyone out when the server restarts.
+	SessionKey []byte
+}
+
+// webData is passed to the templates of the web app.
+type webData struct {
+	Title string
+	User  user
+	// CSRF is the token forms and scripts send back with changes.
+	CSRF  string
+	Error string
+	// Next is the page to go to after signing in.
+	Next   string
+	GitHub bool
+	Users  bool
+
+	Query      string
+	Entries    []*entry.Entry
+	More       bool
+	Entry      *entry.Entry
+	Categories []string
+	// Content is the markdown being edited, and ETag that of the file it
+	// was read from.
+	Content, ETag string
+	// Form holds the fields of the new entry form.
+	Form  newEntry
+	Saved bool
+}
+
+// webRoutes adds the pages of the web app to mux.
+func (s *Server) webRoutes(mux *http.ServeMux) {
+	static, _ := fs.Sub(webFS, "web/static")
+	mux.Handle("GET /static/", http.StripPrefix("/static/", http.FileServerFS(static)))
+	mux.HandleFunc("GET /login", func(w http.ResponseWriter, r *http.Request) { s.loginPage(w, r, http.StatusOK, "") })
+	mux.HandleFunc("POST /login", s.passwordLogin)
+	mux.HandleFunc("POST /logout", s.logout)
+	if s.Web.GitHub != nil {
+		mux.HandleFunc("GET /auth/github", s.githubLogin)
+		mux.HandleFunc("GET /auth/github/callback", s.githubCallback)
+	}
+	mux.Handle("GET /{$}", s.signedIn(s.indexPage))
+	mux.Handle("GET /new", s.signedIn(s.newPage))
+	mux.Handle("POST /new", s.signedIn(s.newSubmit))
+	mux.Handle("GET /edit/{ref...}", s.signedIn(s.editPage))
+	mux.Handle("POST /edit/{ref...}", s.signedIn(s.editSubmit))
+	mux.Handle("POST /preview", s.signedIn(s.preview))
+}
+
+// signedIn serves pages to signed-in users, sending others to the login
+// page, and checks the CSRF token of the forms they post.
+func (s *Server) signedIn(page func(http.ResponseWriter, *http.Request, webData)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		u, csrf, ok := s.sessions.user(r)
+		if !ok {
+			http.Redirect(w, r, "/login?next="+url.QueryEscape(r.URL.RequestURI()), http.StatusSeeOther)
+			return
+		}
+		if r.Method == http.MethodPost {
+			token := r.Header.Get(csrfHeader)
+			if token == "" {
+				token = r.PostFormValue("csrf")
+			}
+			if !s.sessions.checkCSRF(r, token) {
+				http.Error(w, "invalid CSRF token; reload the page", http.StatusForbidden)
+				return
+			}
+		}
+		page(w, r, webData{User: u, CSRF: csrf})
+	})
+}
+
+func (s *Server) render(w http.ResponseWriter, status int, name string, d webData) {
+	var buf bytes.Buffer
+	if err := webTemplates.ExecuteTemplate(&buf, name, d); err != nil {
+		s.Log.Printf("web %s: %v", name, err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	w.Write(buf.Bytes())
+}
+
+func (s *Server) loginPage(w http.ResponseWriter, r *http.Request, status int, msg string) {
+	s.render(w, status, "login.html", webData{
+		Title:  "Sign in",
+		Error:  msg,
+		Next:   localPath(r.FormValue("next")),
+		GitHub: s.Web.GitHub != nil,
+		Users:  len(s.Web.Users) > 0,
+	})
+}
+
+// dummyHash is compared against for unknown users, so that they take as
+// long to turn away as known ones. It is made on first use, hashing being
+// slow enough to delay every til command if done as the package loads.
+var dummyHash = sync.OnceValue(func() []byte {
+	h, _ := bcrypt.GenerateFromPassword([]byte("til"), bcrypt.DefaultCost)
+	return h
+})
+
+func (s *Server) passwordLogin(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimSpace(r.PostFormValue("user"))
+	hash, ok := s.Web.Users[name]
+	if !ok {
+		hash = string(dummyHash())
+	}
+	if bcrypt.CompareHashAndPassword([]byte(hash), []byte(r.PostFormValue("password"))) != nil || !ok {
+		s.loginPage(w, r, http.StatusUnauthorized, "Wrong user name or password.")
+		return
+	}
+	s.sessions.start(w, r, user{Login: name, Name: name})
+	http.Redirect(w, r, localPath(r.PostFormValue("next")), http.StatusSeeOther)
+}
+
+func (s *Server) logout(w http.ResponseWriter, r *http.Request) {
+	s.sessions.end(w, r)
+	http.Redirect(w, r, "/login", http.StatusSeeOther)
+}
+
+// localPath returns next if it is a path on this server, and "/"
+// otherwise, so that signing in cannot redirect elsewhere.
+func localPath(next string) string {
+	if !strings.HasPrefix(next, "/") || strings.HasPrefix(next, "//") || strings.HasPrefix(next, `/\`) {
+		return "/"
+	}
+	return next
+}
+
+func (s *Server) indexPage(w http.ResponseWriter, r *http.Request, d webData) {
+	d.Title = "Entries"
+	d.Query = r.URL.Query().Get("q")
+	x, err := query.Parser{Now: time.Now(), Collections: s.Collections, Lang: s.Lang}.Parse(d.Query)
+	if err != nil {
+		d.Error = err.Error()
+		x = query.All{}
+	}
+	entries, err := s.entries(true)
+	if err != nil {
+		s.webFail(w, r, err)
+		return
+	}
+	entries = query.Filter(entries, x)
+	query.Sort(entries, "updated", false)
+	if len(entries) > WebLimit {
+		entries, d.More = entries[:WebLimit], true
+	}
+	d.Entries = entries
+	s.render(w, http.StatusOK, "index.html", d)
+}
+
+func (s *Server) newPage(w http.ResponseWriter, r *http.Request, d webData) {
+	d.Title = "New entry"
+	d.Form.Category = r.URL.Query().Get("category")
+	s.newForm(w, r, http.StatusOK, d)
+}
+
+func (s *Server) newForm(w http.ResponseWriter, r *http.Request, status int, d webData) {
+	cats, err := s.Tree.Categories()
+	if err != nil {
+		s.webFail(w, r, err)
+		return
+	}
+	d.Categories = cats
+	s.render(w, status, "new.html", d)
+}
+
+func (s *Server) newSubmit(w http.ResponseWriter, r *http.Request, d webData) {
+	d.Title = "New entry"
+	d.Form = newEntry{
+		Category: strings.TrimSpace(r.PostFormValue("category")),
+		Title:    strings.TrimSpace(r.PostFormValue("title")),
+		Tags:     splitTags(r.PostFormValue("tags")),
+		Body:     r.PostFormValue("body"),
+		Draft:    r.PostFormValue("draft") != "",
+	}
+	rel, err := s.create(d.Form, d.User.Name)
+	if err != nil {
+		var he *httpError
+		if !errors.As(err, &he) {
+			s.webFail(w, r, err)
+			return
+		}
+		d.Error = err.Error()
+		s.newForm(w, r, he.status, d)
+		return
+	}
+	s.commit(r, rel, "add")
+	http.Redirect(w, r, "/edit/"+rel, http.StatusSeeOther)
+}
+
+// splitTags splits a comma- or space-separated list of tags.
+func splitTags(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ' ' })
+}
+
+func (s *Server) editPage(w http.ResponseWriter, r *http.Request, d webData) {
+	e, err := s.resolve(r)
+	if err != nil {
+		s.webFail(w, r, err)
+		return
+	}
+	data, err := s.Tree.Read(e.Path)
+	if err != nil {
+		s.webFail(w, r, err)
+		return
+	}
+	d.Title, d.Entry, d.Content, d.ETag = e.Meta.Title, e, string(data), etag(data)
+	d.Saved = r.URL.Query().Has("saved")
+	s.render(w, http.StatusOK, "edit.html", d)
+}
+
+func (s *Server) editSubmit(w http.ResponseWriter, r *http.Request, d webData) {
+	e, err := s.resolve(r)
+	if err != nil {
+		s.webFail(w, r, err)
+		return
+	}
+	// Browsers send textareas with CRLF line breaks.
+	content := strings.ReplaceAll(r.PostFormValue("content"), "\r\n", "\n")
+	d.Title, d.Entry, d.Content, d.ETag = e.Meta.Title, e, content, r.PostFormValue("etag")
+	data, err := s.Tree.Read(e.Path)
+	if err != nil {
+		s.webFail(w, r, err)
+		return
+	}
+	if etag(data) != d.ETag {
+		d.Error = "Someone else saved this entry since you opened it. Copy your changes, reload the page and make them again."
+		s.render(w, http.StatusConflict, "edit.html", d)
+		return
+	}
+	if err := s.update(e.Path, []byte(content)); err != nil {
+		var he *httpError
+		if !errors.As(err, &he) {
+			s.webFail(w, r, err)
+			return
+		}
+		d.Error = err.Error()
+		s.render(w, he.status, "edit.html", d)
+		return
+	}
+	if content != string(data) {
+		s.commit(r, e.Path, "update")
+	}
+	http.Redirect(w, r, "/edit/"+e.Path+"?saved=1", http.StatusSeeOther)
+}
+
+// preview renders the markdown posted as the content of the entry at the
+// path given by the from parameter.
+func (s *Server) preview(w http.ResponseWriter, r *http.Request, _ webData) {
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, 4<<20))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	from := r.URL.Query().Get("from")
+	if from == "" {
+		from = "preview.md"
+	}
+	e, err := entry.Parse(from, data)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	html, err := s.renderEntry(e, "/edit/", "")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(html)
+}
+
+// webFail answers a page request that failed with err.
+func (s *Server) webFail(w http.ResponseWriter, r *http.Request, err error) {
+	status := http.StatusInternalServerError
+	var he *httpError
+	switch {
+	case errors.As(err, &he):
+		status = he.status
+	case errors.Is(err, notes.ErrNotFound), errors.Is(err, fs.ErrNotExist):
+		status = http.StatusNotFound
+	}
+	msg := err.Error()
+	if status == http.StatusInternalServerError {
+		s.Log.Printf("%s %s: %v", r.Method, r.URL.Path, err)
+		msg = "Something went wrong; the server log has the details."
+	}
+	u, csrf, _ := s.sessions.user(r)
+	s.render(w, status, "error.html", webData{Title: http.StatusText(status), User: u, CSRF: csrf, Error: msg})
+}
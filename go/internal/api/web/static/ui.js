@@ -0,0 +1,37 @@
+// Renders the markdown of the editor's textarea into its preview pane as
+// the user types.
+(function () {
+  const form = document.querySelector("form.editor");
+  if (!form) return;
+  const text = form.querySelector("textarea");
+  const pane = form.querySelector(".preview");
+  const csrf = form.querySelector("input[name=csrf]").value;
+  const isNew = text.name === "body";
+  let timer, seq = 0;
+
+  async function update() {
+    const n = ++seq;
+    let content = text.value;
+    if (isNew) {
+      const title = form.querySelector("input[name=title]").value || "Untitled";
+      content = "---\ntitle: " + JSON.stringify(title) + "\n---\n\n" + content;
+    }
+    const resp = await fetch(form.dataset.preview, {
+      method: "POST",
+      headers: { "Content-Type": "text/markdown; charset=utf-8", "X-CSRF-Token": csrf },
+      body: content,
+    });
+    if (n !== seq) return;
+    if (resp.ok) {
+      pane.innerHTML = await resp.text();
+    } else {
+      pane.textContent = "Preview failed: " + (await resp.text());
+    }
+  }
+
+  text.addEventListener("input", function () {
+    clearTimeout(timer);
+    timer = setTimeout(update, 300);
+  });
+  update();
+})();
@@ -0,0 +1,319 @@
+package api
+
+import (
+	"html"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// newWebServer is newServer with the web app, which ann signs in to with
+// the password "secret".
+func newWebServer(t *testing.T) (*Server, string) {
+	t.Helper()
+	s, root := newServer(t)
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Web = &Web{Users: map[string]string{"ann": string(hash)}}
+	s.Scaffold = scaffold
+	return s, root
+}
+
+// client drives the web app, keeping its session cookie.
+type client struct {
+	t      *testing.T
+	h      http.Handler
+	cookie *http.Cookie
+}
+
+func (c *client) do(req *http.Request) *httptest.ResponseRecorder {
+	c.t.Helper()
+	if c.cookie != nil {
+		req.AddCookie(c.cookie)
+	}
+	w := httptest.NewRecorder()
+	c.h.ServeHTTP(w, req)
+	for _, ck := range w.Result().Cookies() {
+		if ck.Name == sessionCookie {
+			c.cookie = ck
+		}
+	}
+	return w
+}
+
+func (c *client) get(p string) *httptest.ResponseRecorder {
+	return c.do(httptest.NewRequest("GET", p, nil))
+}
+
+func (c *client) post(p string, form url.Values) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("POST", p, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return c.do(req)
+}
+
+var csrfField = regexp.MustCompile(`name="csrf" value="([^"]+)"`)
+
+// signIn signs ann in and returns the CSRF token of her session.
+func (c *client) signIn(password string) string {
+	c.t.Helper()
+	w := c.post("/login", url.Values{"user": {"ann"}, "password": {password}})
+	if w.Code != http.StatusSeeOther {
+		c.t.Fatalf("POST /login = %d %s", w.Code, w.Body)
+	}
+	m := csrfField.FindStringSubmatch(c.get("/new").Body.String())
+	if m == nil {
+		c.t.Fatal("no CSRF token on /new")
+	}
+	return m[1]
+}
+
+func TestNewWritesOnlyEntries(t *testing.T) {
+	s, root := newWebServer(t)
+	c := &client{t: t, h: s.Handler()}
+	csrf := c.signIn("secret")
+	for _, category := range []string{"../..", "../.til/templates", ".git/hooks", ""} {
+		w := c.post("/new", url.Values{"csrf": {csrf}, "category": {category}, "title": {"escaped"}})
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("POST /new in %q = %d", category, w.Code)
+		}
+	}
+	for _, p := range []string{filepath.Join("..", "..", "escaped.md"), filepath.Join(".til", "templates", "escaped.md"), filepath.Join(".git", "hooks", "escaped.md")} {
+		if _, err := os.Stat(filepath.Join(root, p)); err == nil {
+			t.Errorf("%s written", p)
+		}
+	}
+	if w := c.post("/new", url.Values{"csrf": {csrf}, "category": {"go"}, "title": {"Fine"}}); w.Code != http.StatusSeeOther || w.Header().Get("Location") != "/edit/go/fine.md" {
+		t.Errorf("POST /new = %d %s", w.Code, w.Header().Get("Location"))
+	}
+	if data, _ := os.ReadFile(filepath.Join(root, "go", "fine.md")); !strings.Contains(string(data), "author: ann") {
+		t.Errorf("go/fine.md = %q", data)
+	}
+}
+
+func TestCreateWritesOnlyEntries(t *testing.T) {
+	s, root := newServer(t)
+	s.Scaffold = scaffold
+	for _, req := range []newEntry{
+		{Category: "../..", Title: "escaped"},
+		{Category: ".git/hooks", Title: "pre-commit"},
+		{Category: "go/..", Title: "readme"},
+	} {
+		if rel, err := s.create(req, ""); err == nil {
+			t.Errorf("create(%+v) wrote %s", req, rel)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(root, "readme.md")); err == nil {
+		t.Error("readme.md written")
+	}
+}
+
+func TestWebSignIn(t *testing.T) {
+	s, _ := newWebServer(t)
+	c := &client{t: t, h: s.Handler()}
+
+	w := c.get("/edit/go/hello.md?x=1")
+	if w.Code != http.StatusSeeOther || w.Header().Get("Location") != "/login?next=%2Fedit%2Fgo%2Fhello.md%3Fx%3D1" {
+		t.Errorf("signed-out GET = %d %s", w.Code, w.Header().Get("Location"))
+	}
+	for _, form := range []url.Values{
+		{"user": {"ann"}, "password": {"wrong"}},
+		{"user": {"bob"}, "password": {"secret"}},
+		{"user": {"ann"}},
+	} {
+		if w := c.post("/login", form); w.Code != http.StatusUnauthorized || !strings.Contains(w.Body.String(), "Wrong user name or password.") {
+			t.Errorf("POST /login %v = %d", form, w.Code)
+		}
+	}
+	if c.cookie != nil {
+		t.Fatal("failed sign-in set a session")
+	}
+	w = c.post("/login", url.Values{"user": {" ann "}, "password": {"secret"}, "next": {"//evil.example/"}})
+	if w.Code != http.StatusSeeOther || w.Header().Get("Location") != "/" {
+		t.Errorf("POST /login with a foreign next = %d %s", w.Code, w.Header().Get("Location"))
+	}
+	if w := c.get("/"); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "go/hello.md") {
+		t.Errorf("GET / signed in = %d\n%s", w.Code, w.Body)
+	}
+	if w := c.post("/login", url.Values{"user": {"ann"}, "password": {"secret"}, "next": {"/edit/go/hello.md"}}); w.Header().Get("Location") != "/edit/go/hello.md" {
+		t.Errorf("POST /login with next = %s", w.Header().Get("Location"))
+	}
+
+	// Signing out needs no token, and ends the session.
+	if w := c.post("/logout", nil); w.Code != http.StatusSeeOther || c.cookie.MaxAge >= 0 {
+		t.Errorf("POST /logout = %d, cookie %+v", w.Code, c.cookie)
+	}
+	c.cookie = nil
+	if w := c.get("/"); w.Code != http.StatusSeeOther {
+		t.Errorf("GET / signed out = %d", w.Code)
+	}
+}
+
+func TestWebCSRF(t *testing.T) {
+	s, root := newWebServer(t)
+	h := s.Handler()
+	c := &client{t: t, h: h}
+	csrf := c.signIn("secret")
+
+	for _, token := range []string{"", "forged"} {
+		w := c.post("/new", url.Values{"csrf": {token}, "category": {"go"}, "title": {"Forged"}})
+		if w.Code != http.StatusForbidden {
+			t.Errorf("POST /new with token %q = %d", token, w.Code)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(root, "go", "forged.md")); err == nil {
+		t.Error("entry created without a valid CSRF token")
+	}
+
+	// The API takes the session too, with the token as a header.
+	api := func(method, p, body, token string) int {
+		req := httptest.NewRequest(method, p, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set(csrfHeader, token)
+		}
+		return c.do(req).Code
+	}
+	if code := api("GET", "/api/entries", "", ""); code != http.StatusOK {
+		t.Errorf("GET /api/entries with a session = %d", code)
+	}
+	body := `{"category":"go","title":"Via API"}`
+	if code := api("POST", "/api/entries", body, ""); code != http.StatusForbidden {
+		t.Errorf("POST /api/entries without the header = %d", code)
+	}
+	if code := api("POST", "/api/entries", body, csrf); code != http.StatusCreated {
+		t.Errorf("POST /api/entries with the header = %d", code)
+	}
+	c.cookie = nil
+	if code := api("GET", "/api/entries", "", ""); code != http.StatusUnauthorized {
+		t.Errorf("GET /api/entries signed out = %d", code)
+	}
+}
+
+func TestWebEdit(t *testing.T) {
+	s, root := newWebServer(t)
+	c := &client{t: t, h: s.Handler()}
+	c.signIn("secret")
+	page := c.get("/edit/go/hello.md").Body.String()
+	csrf := csrfField.FindStringSubmatch(page)[1]
+	etag := html.UnescapeString(regexp.MustCompile(`name="etag" value="([^"]+)"`).FindStringSubmatch(page)[1])
+
+	content := "---\r\ntitle: Hello\r\n---\r\n\r\nnew body\r\n"
+	w := c.post("/edit/go/hello.md", url.Values{"csrf": {csrf}, "etag": {etag}, "content": {content}})
+	if w.Code != http.StatusSeeOther || w.Header().Get("Location") != "/edit/go/hello.md?saved=1" {
+		t.Fatalf("POST /edit = %d %s", w.Code, w.Body)
+	}
+	if data, _ := os.ReadFile(filepath.Join(root, "go", "hello.md")); string(data) != "---\ntitle: Hello\n---\n\nnew body\n" {
+		t.Errorf("saved %q, want LF line breaks", data)
+	}
+	if !strings.Contains(c.get("/edit/go/hello.md?saved=1").Body.String(), "Saved.") {
+		t.Error("no notice after saving")
+	}
+
+	// The etag read before the save is stale now.
+	w = c.post("/edit/go/hello.md", url.Values{"csrf": {csrf}, "etag": {etag}, "content": {"lost"}})
+	if w.Code != http.StatusConflict || !strings.Contains(w.Body.String(), "Someone else saved this entry") || !strings.Contains(w.Body.String(), ">lost</textarea>") {
+		t.Errorf("POST /edit with a stale etag = %d\n%s", w.Code, w.Body)
+	}
+	if w := c.get("/edit/go/missing.md"); w.Code != http.StatusNotFound {
+		t.Errorf("GET /edit of a missing entry = %d", w.Code)
+	}
+
+	req := httptest.NewRequest("POST", "/preview?from=go/hello.md", strings.NewReader("# Hi\n\n*there*\n"))
+	req.Header.Set(csrfHeader, csrf)
+	if w := c.do(req); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "<em>there</em>") {
+		t.Errorf("POST /preview = %d %s", w.Code, w.Body)
+	}
+}
+
+func TestGitHubSignIn(t *testing.T) {
+	var exchanged url.Values
+	gh := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login/oauth/access_token":
+			r.ParseForm()
+			exchanged = r.PostForm
+			if r.PostForm.Get("code") == "bad" {
+				w.Write([]byte(`{"error_description":"bad code"}`))
+				return
+			}
+			w.Write([]byte(`{"access_token":"tok-` + r.PostForm.Get("code") + `"}`))
+		case "/api/v3/user":
+			switch r.Header.Get("Authorization") {
+			case "Bearer tok-ann":
+				w.Write([]byte(`{"login":"Ann","name":"Ann Lee"}`))
+			case "Bearer tok-eve":
+				w.Write([]byte(`{"login":"eve"}`))
+			default:
+				w.WriteHeader(http.StatusUnauthorized)
+			}
+		}
+	}))
+	defer gh.Close()
+	s, _ := newWebServer(t)
+	s.Log = log.New(io.Discard, "", 0)
+	s.Web.GitHub = &GitHub{ClientID: "id", ClientSecret: "shh", Allow: []string{"ann"}, URL: gh.URL + "/"}
+	h := s.Handler()
+
+	signIn := func(code string) (*client, *httptest.ResponseRecorder) {
+		c := &client{t: t, h: h}
+		w := c.get("/auth/github?next=/new")
+		loc, _ := url.Parse(w.Header().Get("Location"))
+		if w.Code != http.StatusSeeOther || loc.Path != "/login/oauth/authorize" || loc.Query().Get("client_id") != "id" ||
+			loc.Query().Get("redirect_uri") != "http://example.com/auth/github/callback" {
+			t.Fatalf("GET /auth/github = %d %s", w.Code, loc)
+		}
+		var state *http.Cookie
+		for _, ck := range w.Result().Cookies() {
+			if ck.Name == oauthCookie {
+				state = ck
+			}
+		}
+		req := httptest.NewRequest("GET", "/auth/github/callback?code="+code+"&state="+loc.Query().Get("state"), nil)
+		req.AddCookie(state)
+		return c, c.do(req)
+	}
+
+	c, w := signIn("ann")
+	if w.Code != http.StatusSeeOther || w.Header().Get("Location") != "/new" || c.cookie == nil {
+		t.Fatalf("callback = %d %s", w.Code, w.Body)
+	}
+	if exchanged.Get("client_secret") != "shh" || exchanged.Get("code") != "ann" {
+		t.Errorf("exchanged %v", exchanged)
+	}
+	if u, _, ok := s.sessions.user(withCookie(c.cookie)); !ok || u.Login != "Ann" || u.Name != "Ann Lee" {
+		t.Errorf("signed in as %+v", u)
+	}
+
+	tests := []struct {
+		code   string
+		status int
+		msg    string
+	}{
+		{"eve", http.StatusForbidden, "eve is not allowed to sign in."},
+		{"bad", http.StatusBadGateway, "GitHub did not sign you in."},
+		{"nobody", http.StatusBadGateway, "GitHub did not say who you are."},
+	}
+	for _, tt := range tests {
+		c, w := signIn(tt.code)
+		if w.Code != tt.status || !strings.Contains(w.Body.String(), tt.msg) || c.cookie != nil {
+			t.Errorf("callback with %s = %d\n%s", tt.code, w.Code, w.Body)
+		}
+	}
+
+	// A callback without the state it was sent with is turned away.
+	c = &client{t: t, h: h}
+	if w := c.get("/auth/github/callback?code=ann&state=guess"); w.Code != http.StatusBadRequest || c.cookie != nil {
+		t.Errorf("callback without the state cookie = %d", w.Code)
+	}
+}
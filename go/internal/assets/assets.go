@@ -0,0 +1,195 @@
+// Package assets manages the images and other files attached to entries.
+//
+// The files of an entry live in an assets directory next to it, one
+// subdirectory per entry: go/basic_syntax.md keeps them in
+// go/assets/basic_syntax/. Entries link to them with relative paths.
+package assets
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/canhta/til/go/internal/notes"
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+// Dir is the name of the assets directory inside a category.
+const Dir = "assets"
+
+// maxDownload caps the size of files attached from a URL.
+const maxDownload = 50 << 20
+
+// DirFor returns the slash-separated directory holding the assets of the
+// entry at p.
+func DirFor(p string) string {
+	stem := strings.TrimSuffix(path.Base(p), path.Ext(p))
+	return path.Join(path.Dir(p), Dir, stem)
+}
+
+// List returns the paths of all asset files in tree, sorted.
+func List(tree *notes.Tree) ([]string, error) {
+	files, err := tree.Files()
+	if err != nil {
+		return nil, err
+	}
+	var out []string
+	for _, f := range files {
+		parts := strings.Split(f.Path, "/")
+		if len(parts) > 2 && parts[1] == Dir && path.Ext(f.Path) != ".md" {
+			out = append(out, f.Path)
+		}
+	}
+	return out, nil
+}
+
+// Fingerprint returns a short content hash of data, used to give published
+// copies of assets names that change whenever their content does.
+func Fingerprint(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:5])
+}
+
+// Fingerprinted inserts the fingerprint of data before the extension of the
+// slash-separated path p: a/b.png becomes a/b.0123456789.png.
+func Fingerprinted(p string, data []byte) string {
+	ext := path.Ext(p)
+	return strings.TrimSuffix(p, ext) + "." + Fingerprint(data) + ext
+}
+
+// Source is a file to attach.
+type Source struct {
+	// Name is the file name to store it under.
+	Name string
+	Data []byte
+}
+
+// Read loads src, a local file or an http(s) URL.
+func Read(ctx context.Context, src string) (*Source, error) {
+	u, err := url.Parse(src)
+	if err != nil || u.Scheme != "http" && u.Scheme != "https" {
+		data, err := os.ReadFile(src)
+		if err != nil {
+			return nil, err
+		}
+		return &Source{Name: filepath.Base(src), Data: data}, nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, src, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "til (+https://github.com/canhta/til)")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch %s: %s", src, resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxDownload+1))
+	if err != nil {
+		return nil, fmt.Errorf("fetch %s: %w", src, err)
+	}
+	if len(data) > maxDownload {
+		return nil, fmt.Errorf("fetch %s: larger than %d MiB", src, maxDownload>>20)
+	}
+	name := path.Base(resp.Request.URL.Path)
+	if name == "/" || name == "." {
+		name = "file"
+	}
+	if path.Ext(name) == "" {
+		if mt, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type")); err == nil {
+			if exts, _ := mime.ExtensionsByType(mt); len(exts) > 0 {
+				name += exts[len(exts)-1]
+			}
+		}
+	}
+	return &Source{Name: name, Data: data}, nil
+}
+
+// Store writes data into the assets directory of the entry at entryPath of
+// tree under a name derived from name, and returns the path of the stored
+// file. A file with the same content is reused; a different one with the
+// same name gets a numeric suffix.
+func Store(tree *notes.Tree, entryPath, name string, data []byte) (string, error) {
+	dir := DirFor(entryPath)
+	ext := strings.ToLower(path.Ext(name))
+	base := entry.Slugify(strings.TrimSuffix(name, path.Ext(name)))
+	if base == "" {
+		base = "file"
+	}
+	for i := 1; ; i++ {
+		n := base
+		if i > 1 {
+			n = fmt.Sprintf("%s-%d", base, i)
+		}
+		rel := path.Join(dir, n+ext)
+		old, err := tree.Read(rel)
+		if errors.Is(err, fs.ErrNotExist) {
+			return rel, tree.Write(rel, data)
+		}
+		if err != nil {
+			return "", err
+		}
+		if bytes.Equal(old, data) {
+			return rel, nil
+		}
+	}
+}
+
+// IsImage reports whether the file name has an image extension.
+func IsImage(name string) bool {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".png", ".jpg", ".jpeg", ".gif", ".webp", ".svg", ".avif":
+		return true
+	}
+	return false
+}
+
+// Link rewrites body so links and images pointing at src point at dest
+// instead. If body mentions neither, a reference to dest is appended: an
+// image with alt text for images, a plain link otherwise. It reports
+// whether existing references were rewritten.
+func Link(body []byte, src, dest, alt string) ([]byte, bool) {
+	re := regexp.MustCompile(`(\]\()<?` + regexp.QuoteMeta(src) + `>?(\s+"[^"]*")?\)`)
+	if re.Match(body) {
+		return re.ReplaceAll(body, []byte("${1}"+escapeDest(dest)+"${2})")), true
+	}
+	if bytes.Contains(body, []byte("]("+escapeDest(dest))) {
+		return body, false
+	}
+	ref := "[" + alt + "](" + escapeDest(dest) + ")"
+	if IsImage(dest) {
+		ref = "!" + ref
+	}
+	body = bytes.TrimRight(body, "\n")
+	if len(body) > 0 {
+		body = append(body, "\n\n"...)
+	}
+	return append(body, ref+"\n"...), false
+}
+
+// escapeDest makes a link destination safe to write unbracketed.
+func escapeDest(d string) string {
+	if strings.ContainsAny(d, " ()<>") {
+		return "<" + d + ">"
+	}
+	return d
+}
@@ -0,0 +1,112 @@
+package assets
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/canhta/til/go/internal/notes"
+)
+
+func TestDirFor(t *testing.T) {
+	if got := DirFor("go/basic_syntax.md"); got != "go/assets/basic_syntax" {
+		t.Errorf("DirFor = %s", got)
+	}
+}
+
+func TestFingerprinted(t *testing.T) {
+	a, b := Fingerprinted("go/assets/x/a.png", []byte("one")), Fingerprinted("go/assets/x/a.png", []byte("two"))
+	if a == b || len(a) != len("go/assets/x/a.0123456789.png") || filepath.Ext(a) != ".png" {
+		t.Errorf("Fingerprinted = %s, %s", a, b)
+	}
+	if a != Fingerprinted("go/assets/x/a.png", []byte("one")) {
+		t.Error("Fingerprinted is not stable")
+	}
+}
+
+func TestStore(t *testing.T) {
+	tree := notes.Open(t.TempDir())
+	got, err := Store(tree, "go/slices.md", "Slice Header.PNG", []byte("one"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "go/assets/slices/slice-header.png" {
+		t.Errorf("Store = %s", got)
+	}
+	if again, _ := Store(tree, "go/slices.md", "slice-header.png", []byte("one")); again != got {
+		t.Errorf("Store of the same content = %s, want %s", again, got)
+	}
+	if other, _ := Store(tree, "go/slices.md", "slice-header.png", []byte("two")); other != "go/assets/slices/slice-header-2.png" {
+		t.Errorf("Store of other content = %s", other)
+	}
+	if data, _ := tree.Read(got); string(data) != "one" {
+		t.Errorf("%s = %q", got, data)
+	}
+	list, err := List(tree)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"go/assets/slices/slice-header-2.png", "go/assets/slices/slice-header.png"}; !slices.Equal(list, want) {
+		t.Errorf("List = %q, want %q", list, want)
+	}
+}
+
+func TestLink(t *testing.T) {
+	tests := []struct {
+		name, body, src, dest, alt, want string
+		rewrote                          bool
+	}{
+		{"rewrite", "See ![x](/tmp/a.png \"title\") and [a](</tmp/a.png>).\n", "/tmp/a.png", "assets/s/a.png", "A",
+			"See ![x](assets/s/a.png \"title\") and [a](assets/s/a.png).\n", true},
+		{"append image", "Body.\n\n", "/tmp/a.png", "assets/s/a.png", "A", "Body.\n\n![A](assets/s/a.png)\n", false},
+		{"append link", "", "x.pdf", "assets/s/x.pdf", "X", "[X](assets/s/x.pdf)\n", false},
+		{"escape", "Body\n", "a b.png", "assets/s/a b.png", "A", "Body\n\n![A](<assets/s/a b.png>)\n", false},
+		{"already linked", "![A](assets/s/a.png)\n", "/tmp/a.png", "assets/s/a.png", "A", "![A](assets/s/a.png)\n", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, rewrote := Link([]byte(tt.body), tt.src, tt.dest, tt.alt)
+			if string(got) != tt.want || rewrote != tt.rewrote {
+				t.Errorf("Link = %q, %v; want %q, %v", got, rewrote, tt.want, tt.rewrote)
+			}
+		})
+	}
+}
+
+func TestRead(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "diagram.svg")
+	if err := os.WriteFile(file, []byte("<svg/>"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	src, err := Read(context.Background(), file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if src.Name != "diagram.svg" || string(src.Data) != "<svg/>" {
+		t.Errorf("Read(file) = %+v", src)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "image/png")
+		w.Write([]byte("png"))
+	}))
+	defer srv.Close()
+	src, err = Read(context.Background(), srv.URL+"/images/header")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if src.Name != "header.png" || string(src.Data) != "png" {
+		t.Errorf("Read(url) = %+v", src)
+	}
+	if _, err := Read(context.Background(), srv.URL+"/missing"); err == nil {
+		t.Error("Read of a missing URL succeeded")
+	}
+}
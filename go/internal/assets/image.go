@@ -0,0 +1,94 @@
+package assets
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"path"
+	"strings"
+)
+
+// Shrink options. A zero value leaves the image as it is.
+type Shrink struct {
+	// MaxWidth scales images wider than this down to it.
+	MaxWidth int
+	// Compress re-encodes images when that makes them smaller.
+	Compress bool
+	// Quality is the JPEG quality used when re-encoding, 1 to 100.
+	Quality int
+}
+
+// Process applies opts to the PNG, JPEG or GIF image in data, named name,
+// and returns the result. Other files and images that cannot be decoded are
+// returned unchanged; a GIF is re-encoded as its first frame only when it
+// must be scaled.
+func Process(name string, data []byte, opts Shrink) ([]byte, error) {
+	if opts.MaxWidth <= 0 && !opts.Compress {
+		return data, nil
+	}
+	ext := strings.ToLower(path.Ext(name))
+	if ext != ".png" && ext != ".jpg" && ext != ".jpeg" && ext != ".gif" {
+		return data, nil
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return data, nil
+	}
+	scaled := false
+	if opts.MaxWidth > 0 && img.Bounds().Dx() > opts.MaxWidth {
+		img = scale(img, opts.MaxWidth)
+		scaled = true
+	}
+	if !scaled && (!opts.Compress || ext == ".gif") {
+		return data, nil
+	}
+	var buf bytes.Buffer
+	switch ext {
+	case ".png":
+		err = (&png.Encoder{CompressionLevel: png.BestCompression}).Encode(&buf, img)
+	case ".gif":
+		err = gif.Encode(&buf, img, nil)
+	default:
+		q := opts.Quality
+		if q <= 0 || q > 100 {
+			q = 85
+		}
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: q})
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !scaled && buf.Len() >= len(data) {
+		return data, nil
+	}
+	return buf.Bytes(), nil
+}
+
+// scale shrinks img to width w, averaging the source pixels under each
+// destination pixel.
+func scale(img image.Image, w int) image.Image {
+	b := img.Bounds()
+	h := max(1, b.Dy()*w/b.Dx())
+	dst := image.NewRGBA64(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		y0 := b.Min.Y + y*b.Dy()/h
+		y1 := max(y0+1, b.Min.Y+(y+1)*b.Dy()/h)
+		for x := 0; x < w; x++ {
+			x0 := b.Min.X + x*b.Dx()/w
+			x1 := max(x0+1, b.Min.X+(x+1)*b.Dx()/w)
+			var r, g, bl, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := img.At(sx, sy).RGBA()
+					r, g, bl, a = r+uint64(cr), g+uint64(cg), bl+uint64(cb), a+uint64(ca)
+					n++
+				}
+			}
+			dst.SetRGBA64(x, y, color.RGBA64{uint16(r / n), uint16(g / n), uint16(bl / n), uint16(a / n)})
+		}
+	}
+	return dst
+}
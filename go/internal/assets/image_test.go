@@ -0,0 +1,58 @@
+package assets
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+)
+
+func pngOf(t *testing.T, w, h int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := range h {
+		for x := range w {
+			img.Set(x, y, color.RGBA{uint8(x), uint8(y), 0, 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestProcess(t *testing.T) {
+	data := pngOf(t, 200, 100)
+	got, err := Process("a.png", data, Shrink{MaxWidth: 50})
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, _, err := image.Decode(bytes.NewReader(got))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := img.Bounds(); b.Dx() != 50 || b.Dy() != 25 {
+		t.Errorf("scaled to %v, want 50x25", b)
+	}
+
+	for _, tt := range []struct {
+		name string
+		data []byte
+		opts Shrink
+	}{
+		{"a.png", data, Shrink{}},
+		{"a.png", data, Shrink{MaxWidth: 400}},
+		{"a.svg", []byte("<svg/>"), Shrink{MaxWidth: 10, Compress: true}},
+		{"a.png", []byte("not a png"), Shrink{MaxWidth: 10}},
+	} {
+		got, err := Process(tt.name, tt.data, tt.opts)
+		if err != nil || !bytes.Equal(got, tt.data) {
+			t.Errorf("Process(%s, %+v) changed the file: %v", tt.name, tt.opts, err)
+		}
+	}
+	if got, _ := Process("a.png", data, Shrink{Compress: true}); len(got) > len(data) {
+		t.Errorf("Process --compress grew the file from %d to %d bytes", len(data), len(got))
+	}
+}
@@ -0,0 +1,272 @@
+// Package bench turns the competing Go snippets of an entry into
+// benchmarks and records their results in the entry.
+//
+// A Go block with the bench attribute is a case, named by the attribute's
+// value, its label attribute or its position:
+//
+//	```go {bench=append}
+//	dst = append(dst[:0], src...)
+//	```
+//
+// Its code is the body of the benchmark loop. A bench block made only of
+// declarations is instead shared by every case, for the inputs they work
+// on and the variables their results go to. The results table sits
+// between StartMarker and EndMarker.
+package bench
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/tools/imports"
+
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+const (
+	StartMarker = "<!-- til:bench:start -->"
+	EndMarker   = "<!-- til:bench:end -->"
+)
+
+// Attr is the code block attribute marking a bench block.
+const Attr = "bench"
+
+// ErrNoCases is returned by Parse for entries without bench cases.
+var ErrNoCases = errors.New("no ```go {bench} code blocks")
+
+// Case is a snippet benchmarked against the others.
+type Case struct {
+	Label string
+	Block entry.CodeBlock
+}
+
+// Suite is the bench blocks of an entry.
+type Suite struct {
+	// Shared holds the declaration blocks compiled alongside every case.
+	Shared []entry.CodeBlock
+	Cases  []Case
+	// EndLine is the 1-based body line of the last bench block's closing
+	// fence.
+	EndLine int
+}
+
+// Parse collects the bench blocks of body.
+func Parse(body []byte) (*Suite, error) {
+	s := &Suite{}
+	seen := map[string]bool{}
+	for _, b := range entry.CodeBlocks(body) {
+		if b.Lang != "go" || !b.Has(Attr) {
+			continue
+		}
+		s.EndLine = b.EndLine
+		if declarations(b.Code) {
+			s.Shared = append(s.Shared, b)
+			continue
+		}
+		label := b.Attrs[Attr]
+		if label == "" {
+			label = b.Attrs["label"]
+		}
+		if label == "" {
+			label = fmt.Sprintf("block %d", b.Index+1)
+		}
+		if seen[label] {
+			return nil, fmt.Errorf("block %d: duplicate bench label %q", b.Index+1, label)
+		}
+		seen[label] = true
+		s.Cases = append(s.Cases, Case{Label: label, Block: b})
+	}
+	if len(s.Cases) == 0 {
+		return nil, ErrNoCases
+	}
+	return s, nil
+}
+
+// declarations reports whether code parses as top-level Go declarations.
+func declarations(code string) bool {
+	_, err := parser.ParseFile(token.NewFileSet(), "shared.go", "package p\n\n"+code, 0)
+	return err == nil && strings.TrimSpace(code) != ""
+}
+
+// Source returns the test file benchmarking the cases, as sub-benchmarks
+// of BenchmarkTIL named by their index. Missing imports are added.
+func (s *Suite) Source() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteString("package bench\n\nimport \"testing\"\n\n")
+	for _, sh := range s.Shared {
+		b.WriteString(sh.Code + "\n")
+	}
+	b.WriteString("func BenchmarkTIL(b *testing.B) {\n")
+	for i, c := range s.Cases {
+		fmt.Fprintf(&b, "\tb.Run(%q, func(b *testing.B) {\n\t\tfor b.Loop() {\n%s\n\t\t}\n\t})\n", strconv.Itoa(i), c.Block.Code)
+	}
+	b.WriteString("}\n")
+	src, err := imports.Process("bench_test.go", b.Bytes(), &imports.Options{Comments: true, TabIndent: true, TabWidth: 8})
+	if err != nil {
+		return nil, fmt.Errorf("bench: %w", err)
+	}
+	return src, nil
+}
+
+// Result is the measurement of one case, averaged over the runs.
+type Result struct {
+	Label       string  `json:"label"`
+	Runs        int     `json:"runs"`
+	NsPerOp     float64 `json:"ns_per_op"`
+	BytesPerOp  float64 `json:"bytes_per_op"`
+	AllocsPerOp float64 `json:"allocs_per_op"`
+}
+
+// Report is the outcome of a benchmark run.
+type Report struct {
+	GOOS    string    `json:"goos,omitempty"`
+	GOARCH  string    `json:"goarch,omitempty"`
+	CPU     string    `json:"cpu,omitempty"`
+	Date    time.Time `json:"date"`
+	Results []Result  `json:"results"`
+}
+
+// ParseOutput reads the output of go test -bench -benchmem for s.
+func (s *Suite) ParseOutput(out []byte, date time.Time) (*Report, error) {
+	rep := &Report{Date: date, Results: make([]Result, len(s.Cases))}
+	for i, c := range s.Cases {
+		rep.Results[i].Label = c.Label
+	}
+	sc := bufio.NewScanner(bytes.NewReader(out))
+	for sc.Scan() {
+		line := sc.Text()
+		if k, v, ok := strings.Cut(line, ": "); ok {
+			switch k {
+			case "goos":
+				rep.GOOS = v
+			case "goarch":
+				rep.GOARCH = v
+			case "cpu":
+				rep.CPU = v
+			}
+			continue
+		}
+		name, rest, ok := strings.Cut(line, "\t")
+		if !ok || !strings.HasPrefix(name, "BenchmarkTIL/") {
+			continue
+		}
+		name = strings.TrimPrefix(name, "BenchmarkTIL/")
+		if i := strings.LastIndexByte(name, '-'); i >= 0 {
+			name = name[:i]
+		}
+		i, err := strconv.Atoi(strings.TrimSpace(name))
+		if err != nil || i < 0 || i >= len(rep.Results) {
+			continue
+		}
+		r := &rep.Results[i]
+		fields := strings.Fields(rest)
+		for j := 1; j+1 < len(fields); j += 2 {
+			v, err := strconv.ParseFloat(fields[j], 64)
+			if err != nil {
+				continue
+			}
+			switch fields[j+1] {
+			case "ns/op":
+				r.NsPerOp += v
+			case "B/op":
+				r.BytesPerOp += v
+			case "allocs/op":
+				r.AllocsPerOp += v
+			}
+		}
+		r.Runs++
+	}
+	for i := range rep.Results {
+		r := &rep.Results[i]
+		if r.Runs == 0 {
+			return nil, fmt.Errorf("bench: no result for %q", r.Label)
+		}
+		n := float64(r.Runs)
+		r.NsPerOp, r.BytesPerOp, r.AllocsPerOp = r.NsPerOp/n, r.BytesPerOp/n, r.AllocsPerOp/n
+	}
+	return rep, nil
+}
+
+// Table renders rep as a markdown table between the markers, the fastest
+// case listing 1.00× and the others how many times slower they are.
+func Table(rep *Report) []byte {
+	fastest := math.Inf(1)
+	for _, r := range rep.Results {
+		fastest = min(fastest, r.NsPerOp)
+	}
+	var b bytes.Buffer
+	b.WriteString(StartMarker + "\n\n")
+	b.WriteString("| Snippet | ns/op | B/op | allocs/op | |\n")
+	b.WriteString("| --- | ---: | ---: | ---: | ---: |\n")
+	for _, r := range rep.Results {
+		rel := "–"
+		if fastest > 0 {
+			rel = fmt.Sprintf("%.2f×", r.NsPerOp/fastest)
+		}
+		fmt.Fprintf(&b, "| %s | %s | %s | %s | %s |\n",
+			strings.ReplaceAll(r.Label, "|", `\|`), number(r.NsPerOp), number(r.BytesPerOp), number(r.AllocsPerOp), rel)
+	}
+	var env []string
+	if rep.GOOS != "" {
+		env = append(env, rep.GOOS+"/"+rep.GOARCH)
+	}
+	if rep.CPU != "" {
+		env = append(env, rep.CPU)
+	}
+	env = append(env, rep.Date.Format(entry.DateLayout))
+	fmt.Fprintf(&b, "\n_%s._\n\n%s\n", strings.Join(env, ", "), EndMarker)
+	return b.Bytes()
+}
+
+// number formats v with up to three significant decimals below 10 and
+// none above.
+func number(v float64) string {
+	if v >= 10 || v == math.Trunc(v) {
+		return strconv.FormatFloat(math.Round(v), 'f', -1, 64)
+	}
+	return strconv.FormatFloat(v, 'g', 3, 64)
+}
+
+// Splice replaces the marked section of body with table or, if body has
+// none, inserts it after the 1-based line after.
+func Splice(body, table []byte, after int) []byte {
+	start := bytes.Index(body, []byte(StartMarker))
+	end := bytes.Index(body, []byte(EndMarker))
+	if start >= 0 && end > start {
+		end += len(EndMarker)
+		if end < len(body) && body[end] == '\n' {
+			end++
+		}
+		out := append([]byte{}, body[:start]...)
+		out = append(out, table...)
+		return append(out, body[end:]...)
+	}
+	lines := strings.SplitAfter(string(body), "\n")
+	after = min(max(after, 0), len(lines))
+	head := strings.Join(lines[:after], "")
+	if head != "" && !strings.HasSuffix(head, "\n") {
+		head += "\n"
+	}
+	tail := strings.Join(lines[after:], "")
+	var out bytes.Buffer
+	out.WriteString(head)
+	if head != "" {
+		out.WriteString("\n")
+	}
+	out.Write(table)
+	if tail != "" {
+		if !strings.HasPrefix(tail, "\n") {
+			out.WriteString("\n")
+		}
+		out.WriteString(tail)
+	}
+	return out.Bytes()
+}
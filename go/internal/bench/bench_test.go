@@ -0,0 +1,138 @@
+package bench
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+const body = "# Append or copy\n\n```go {bench}\nvar src = make([]int, 1024)\nvar dst []int\n```\n\n```go {bench=append}\ndst = append(dst[:0], src...)\n```\n\n```go {bench label=\"copy\"}\ndst = make([]int, len(src))\ncopy(dst, src)\n```\n\n```go\nfmt.Println(\"not benchmarked\")\n```\n\n```go {bench}\nsort.Ints(dst)\n```\n\nAfter.\n"
+
+func TestParse(t *testing.T) {
+	s, err := Parse([]byte(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Shared) != 1 || !strings.Contains(s.Shared[0].Code, "var src") {
+		t.Errorf("Shared = %+v", s.Shared)
+	}
+	var labels []string
+	for _, c := range s.Cases {
+		labels = append(labels, c.Label)
+	}
+	if strings.Join(labels, ",") != "append,copy,block 5" {
+		t.Errorf("labels = %q", labels)
+	}
+	if s.EndLine != 23 {
+		t.Errorf("EndLine = %d, want the last bench block's", s.EndLine)
+	}
+
+	if _, err := Parse([]byte("```go\nx()\n```\n")); !errors.Is(err, ErrNoCases) {
+		t.Errorf("Parse(no cases) = %v", err)
+	}
+	if _, err := Parse([]byte("```go {bench}\nvar x int\n```\n")); !errors.Is(err, ErrNoCases) {
+		t.Errorf("Parse(only shared) = %v", err)
+	}
+	if _, err := Parse([]byte("```go {bench=a}\nx()\n```\n\n```go {bench=a}\ny()\n```\n")); err == nil || err.Error() != `block 2: duplicate bench label "a"` {
+		t.Errorf("Parse(duplicate) = %v", err)
+	}
+}
+
+func TestSource(t *testing.T) {
+	s, err := Parse([]byte(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	src, err := s.Source()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"package bench", `"sort"`, `"testing"`, "var src = make([]int, 1024)", `b.Run("0", func(b *testing.B) {`, `b.Run("2", `, "for b.Loop() {", "\t\t\tsort.Ints(dst)"} {
+		if !strings.Contains(string(src), want) {
+			t.Errorf("Source has no %q:\n%s", want, src)
+		}
+	}
+	s.Cases[0].Block.Code = "dst = append(dst[:0]"
+	if _, err := s.Source(); err == nil {
+		t.Error("Source of a broken case succeeded")
+	}
+}
+
+const output = `goos: linux
+goarch: amd64
+pkg: bench
+cpu: Example CPU @ 3.00GHz
+BenchmarkTIL/0-8         	 1000000	       100.0 ns/op	       0 B/op	       0 allocs/op
+BenchmarkTIL/1-8         	  500000	       400.0 ns/op	    8192 B/op	       1 allocs/op
+BenchmarkTIL/0-8         	 1000000	       120.0 ns/op	       0 B/op	       0 allocs/op
+BenchmarkTIL/1-8         	  500000	       420.0 ns/op	    8192 B/op	       1 allocs/op
+BenchmarkOther-8         	 1000000	         1.0 ns/op
+PASS
+`
+
+func TestParseOutput(t *testing.T) {
+	s := &Suite{Cases: []Case{{Label: "append"}, {Label: "copy"}}}
+	date := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	rep, err := s.ParseOutput([]byte(output), date)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rep.GOOS != "linux" || rep.GOARCH != "amd64" || rep.CPU != "Example CPU @ 3.00GHz" {
+		t.Errorf("report = %+v", rep)
+	}
+	want := []Result{
+		{Label: "append", Runs: 2, NsPerOp: 110},
+		{Label: "copy", Runs: 2, NsPerOp: 410, BytesPerOp: 8192, AllocsPerOp: 1},
+	}
+	for i, r := range rep.Results {
+		if r != want[i] {
+			t.Errorf("result %d = %+v, want %+v", i, r, want[i])
+		}
+	}
+
+	s.Cases = append(s.Cases, Case{Label: "third"})
+	if _, err := s.ParseOutput([]byte(output), date); err == nil || err.Error() != `bench: no result for "third"` {
+		t.Errorf("ParseOutput with a case missing = %v", err)
+	}
+}
+
+func TestTable(t *testing.T) {
+	rep := &Report{
+		GOOS: "linux", GOARCH: "amd64", CPU: "Example CPU",
+		Date: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+		Results: []Result{
+			{Label: "a|b", NsPerOp: 5.25, BytesPerOp: 0, AllocsPerOp: 0.5},
+			{Label: "copy", NsPerOp: 21, BytesPerOp: 8192, AllocsPerOp: 1},
+		},
+	}
+	want := StartMarker + "\n\n" +
+		"| Snippet | ns/op | B/op | allocs/op | |\n" +
+		"| --- | ---: | ---: | ---: | ---: |\n" +
+		"| a\\|b | 5.25 | 0 | 0.5 | 1.00× |\n" +
+		"| copy | 21 | 8192 | 1 | 4.00× |\n" +
+		"\n_linux/amd64, Example CPU, 2024-03-01._\n\n" + EndMarker + "\n"
+	if got := string(Table(rep)); got != want {
+		t.Errorf("Table =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestSplice(t *testing.T) {
+	table := StartMarker + "\nnew\n" + EndMarker + "\n"
+	tests := []struct {
+		body  string
+		after int
+		want  string
+	}{
+		{"a\nb\nc\n", 2, "a\nb\n\n" + table + "\nc\n"},
+		{"a\nb\n", 2, "a\nb\n\n" + table},
+		{"a", 1, "a\n\n" + table},
+		{"a\n\nb\n", 1, "a\n\n" + table + "\nb\n"},
+		{"a\n" + StartMarker + "\nold\n" + EndMarker + "\nb\n", 0, "a\n" + table + "b\n"},
+	}
+	for _, tt := range tests {
+		if got := string(Splice([]byte(tt.body), []byte(table), tt.after)); got != tt.want {
+			t.Errorf("Splice(%q, %d) =\n%q\nwant\n%q", tt.body, tt.after, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+// Package capture turns captured text into entry bodies.
+package capture
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxTitle is the length, in runes, of titles derived from captured text.
+const maxTitle = 60
+
+// Title derives an entry title from the first non-blank line of text,
+// dropping markdown heading marks and shell prompts.
+func Title(text string) string {
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		line = strings.TrimSpace(strings.TrimLeft(line, "#"))
+		line = strings.TrimPrefix(line, "$ ")
+		if line == "" {
+			continue
+		}
+		if utf8.RuneCountInString(line) > maxTitle {
+			r := []rune(line)[:maxTitle]
+			line = strings.TrimRightFunc(string(r), unicode.IsSpace) + "…"
+		}
+		return line
+	}
+	return ""
+}
+
+// Body returns text as markdown: unchanged if it reads as prose or already
+// contains fenced code, otherwise wrapped in a code fence tagged lang.
+func Body(text, lang string) string {
+	text = strings.Trim(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	if lang == "" && !LooksLikeCode(text) {
+		return text
+	}
+	if strings.Contains(text, "\n```") || strings.HasPrefix(text, "```") {
+		return text
+	}
+	return Fence(text, lang)
+}
+
+// Fence wraps text in a backtick fence longer than any backtick run inside
+// it.
+func Fence(text, lang string) string {
+	n := 3
+	run := 0
+	for _, r := range text {
+		if r == '`' {
+			run++
+			n = max(n, run+1)
+		} else {
+			run = 0
+		}
+	}
+	fence := strings.Repeat("`", n)
+	return fence + lang + "\n" + text + "\n" + fence
+}
+
+// LooksLikeCode guesses whether text is program source or command output
+// rather than prose: most of its lines are indented, start with a prompt,
+// or are dense with punctuation that prose seldom uses.
+func LooksLikeCode(text string) bool {
+	lines, code := 0, 0
+	for _, line := range strings.Split(text, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		lines++
+		if codeLine(line) {
+			code++
+		}
+	}
+	return lines > 0 && code*2 >= lines
+}
+
+func codeLine(line string) bool {
+	if strings.HasPrefix(line, "\t") || strings.HasPrefix(line, "    ") {
+		return true
+	}
+	t := strings.TrimSpace(line)
+	if strings.HasPrefix(t, "$ ") {
+		return true
+	}
+	switch t[len(t)-1] {
+	case '{', '}', ';', ')', '(', '[', ']':
+		return true
+	}
+	symbols, letters := 0, 0
+	for _, r := range t {
+		switch {
+		case strings.ContainsRune("{}[]()<>=;:$|&/\\*_#@%^~`\"'", r):
+			symbols++
+		case unicode.IsLetter(r):
+			letters++
+		}
+	}
+	return symbols*5 > letters
+}
@@ -0,0 +1,57 @@
+package capture
+
+import "testing"
+
+func TestTitle(t *testing.T) {
+	tests := []struct{ text, want string }{
+		{"\n\n  ## Slices share arrays\nmore", "Slices share arrays"},
+		{"$ go test ./...\nok", "go test ./..."},
+		{"   \n", ""},
+		{"A very long first line of captured text that goes on and on past the limit", "A very long first line of captured text that goes on and on…"},
+		{"héllo wörld", "héllo wörld"},
+	}
+	for _, tt := range tests {
+		if got := Title(tt.text); got != tt.want {
+			t.Errorf("Title(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestLooksLikeCode(t *testing.T) {
+	tests := []struct {
+		text string
+		want bool
+	}{
+		{"Slices share their backing array.\nCopy them to be safe.", false},
+		{"func main() {\n\tfmt.Println(1)\n}", true},
+		{"$ git rebase --onto main\nSuccessfully rebased.", true},
+		{"<div>\n  <p>x</p>\n</div>", true},
+		{"x := map[string]int{}\nThis is a sentence about maps.\nAnd another one.", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := LooksLikeCode(tt.text); got != tt.want {
+			t.Errorf("LooksLikeCode(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestBody(t *testing.T) {
+	tests := []struct{ text, lang, want string }{
+		{"\nJust prose.\r\n\n", "", "Just prose."},
+		{"func f() {\n}\n", "", "```\nfunc f() {\n}\n```"},
+		{"ls -l", "sh", "```sh\nls -l\n```"},
+		{"Notes\n\n```go\nx()\n```", "go", "Notes\n\n```go\nx()\n```"},
+	}
+	for _, tt := range tests {
+		if got := Body(tt.text, tt.lang); got != tt.want {
+			t.Errorf("Body(%q, %q) = %q, want %q", tt.text, tt.lang, got, tt.want)
+		}
+	}
+}
+
+func TestFence(t *testing.T) {
+	if got, want := Fence("a ```` b", "md"), "`````md\na ```` b\n`````"; got != want {
+		t.Errorf("Fence = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,367 @@
+package capture
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"golang.org/x/net/html/charset"
+)
+
+// Page is what was extracted from a web page.
+type Page struct {
+	URL       string
+	Title     string
+	Author    string
+	Published time.Time
+	// Excerpt holds the opening paragraphs of the main text.
+	Excerpt []string
+}
+
+// FetchOptions limits a fetch.
+type FetchOptions struct {
+	Timeout time.Duration
+	// MaxBytes caps how much of the page is read; the rest is ignored.
+	MaxBytes int64
+	// ExcerptWords is roughly how many words of text to keep.
+	ExcerptWords int
+}
+
+// ErrOffline is returned when the page could not be reached at all, as
+// opposed to the server answering with an error.
+var ErrOffline = errors.New("network unreachable")
+
+// Fetch downloads the HTML page at rawURL and extracts its metadata and an
+// excerpt of its text.
+func Fetch(ctx context.Context, rawURL string, opts FetchOptions) (*Page, error) {
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "til (+https://github.com/canhta/til)")
+	req.Header.Set("Accept", "text/html,application/xhtml+xml;q=0.9,*/*;q=0.1")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		var dns *net.DNSError
+		var op *net.OpError
+		if errors.As(err, &dns) || errors.As(err, &op) || errors.Is(err, context.DeadlineExceeded) {
+			return nil, fmt.Errorf("%w: %v", ErrOffline, err)
+		}
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch %s: %s", rawURL, resp.Status)
+	}
+	ctype := resp.Header.Get("Content-Type")
+	if mt, _, err := mime.ParseMediaType(ctype); err == nil && mt != "text/html" && mt != "application/xhtml+xml" {
+		return nil, fmt.Errorf("fetch %s: not an HTML page (%s)", rawURL, mt)
+	}
+	var body io.Reader = resp.Body
+	if opts.MaxBytes > 0 {
+		body = io.LimitReader(body, opts.MaxBytes)
+	}
+	if body, err = charset.NewReader(body, ctype); err != nil {
+		return nil, fmt.Errorf("fetch %s: %w", rawURL, err)
+	}
+	p, err := Extract(body, opts.ExcerptWords)
+	if err != nil {
+		return nil, fmt.Errorf("fetch %s: %w", rawURL, err)
+	}
+	p.URL = resp.Request.URL.String()
+	return p, nil
+}
+
+// Extract reads an HTML document. Metadata comes from, in order of
+// preference, JSON-LD, Open Graph and other meta tags, and the document
+// itself. The excerpt is taken from the element holding the most paragraph
+// text, in the manner of readability tools.
+func Extract(r io.Reader, words int) (*Page, error) {
+	doc, err := html.Parse(r)
+	if err != nil {
+		return nil, err
+	}
+	p := &Page{}
+	meta := map[string]string{}
+	var docTitle, h1 string
+	walk(doc, func(n *html.Node) bool {
+		switch n.DataAtom {
+		case atom.Meta:
+			key := strings.ToLower(attr(n, "property"))
+			if key == "" {
+				key = strings.ToLower(attr(n, "name"))
+			}
+			if key != "" && meta[key] == "" {
+				meta[key] = strings.TrimSpace(attr(n, "content"))
+			}
+		case atom.Title:
+			if docTitle == "" {
+				docTitle = text(n)
+			}
+		case atom.H1:
+			if h1 == "" {
+				h1 = text(n)
+			}
+		case atom.Script:
+			if strings.EqualFold(attr(n, "type"), "application/ld+json") {
+				ld(p, text(n))
+			}
+			return false
+		case atom.Time:
+			if meta["time"] == "" {
+				meta["time"] = attr(n, "datetime")
+			}
+		}
+		return true
+	})
+	p.Title = first(p.Title, meta["og:title"], meta["twitter:title"], docTitle, h1)
+	p.Author = first(p.Author, meta["author"], meta["article:author"], meta["twitter:creator"])
+	if strings.HasPrefix(p.Author, "http") {
+		p.Author = ""
+	}
+	if p.Published.IsZero() {
+		for _, k := range []string{"article:published_time", "date", "dc.date", "pubdate", "time"} {
+			if t, ok := parseTime(meta[k]); ok {
+				p.Published = t
+				break
+			}
+		}
+	}
+	if words <= 0 {
+		words = 150
+	}
+	p.Excerpt = excerpt(doc, words)
+	if len(p.Excerpt) == 0 {
+		if d := first(meta["og:description"], meta["description"]); d != "" {
+			p.Excerpt = []string{d}
+		}
+	}
+	return p, nil
+}
+
+// ld reads metadata from a JSON-LD block into p, keeping values already
+// set.
+func ld(p *Page, src string) {
+	var v any
+	if json.Unmarshal([]byte(src), &v) != nil {
+		return
+	}
+	var objs []map[string]any
+	var collect func(v any)
+	collect = func(v any) {
+		switch v := v.(type) {
+		case []any:
+			for _, x := range v {
+				collect(x)
+			}
+		case map[string]any:
+			objs = append(objs, v)
+			if g, ok := v["@graph"]; ok {
+				collect(g)
+			}
+		}
+	}
+	collect(v)
+	for _, o := range objs {
+		if _, ok := o["datePublished"]; !ok && o["headline"] == nil {
+			continue
+		}
+		if s, ok := o["headline"].(string); ok && p.Title == "" {
+			p.Title = strings.TrimSpace(s)
+		}
+		if p.Author == "" {
+			p.Author = names(o["author"])
+		}
+		if s, ok := o["datePublished"].(string); ok && p.Published.IsZero() {
+			if t, ok := parseTime(s); ok {
+				p.Published = t
+			}
+		}
+	}
+}
+
+// names joins the names in a JSON-LD author value.
+func names(v any) string {
+	switch v := v.(type) {
+	case string:
+		return strings.TrimSpace(v)
+	case map[string]any:
+		s, _ := v["name"].(string)
+		return strings.TrimSpace(s)
+	case []any:
+		var out []string
+		for _, x := range v {
+			if s := names(x); s != "" {
+				out = append(out, s)
+			}
+		}
+		return strings.Join(out, ", ")
+	}
+	return ""
+}
+
+var timeLayouts = []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02T15:04", "2006-01-02", time.RFC1123, time.RFC1123Z}
+
+func parseTime(s string) (time.Time, bool) {
+	s = strings.TrimSpace(s)
+	for _, l := range timeLayouts {
+		if t, err := time.Parse(l, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// boilerplate matches class and id values of page furniture.
+var boilerplate = regexp.MustCompile(`(?i)comment|sidebar|footer|masthead|menu|nav|share|social|promo|related|banner|cookie|subscribe|newsletter|\bads?\b`)
+
+// skip reports whether n cannot hold the main text.
+func skip(n *html.Node) bool {
+	switch n.DataAtom {
+	case atom.Script, atom.Style, atom.Noscript, atom.Nav, atom.Header, atom.Footer, atom.Aside, atom.Form, atom.Svg, atom.Iframe, atom.Button:
+		return true
+	}
+	return n.Type == html.ElementNode && boilerplate.MatchString(attr(n, "class")+" "+attr(n, "id"))
+}
+
+// excerpt picks the element whose paragraphs hold the most text and returns
+// its leading blocks, up to about words words, as markdown.
+func excerpt(doc *html.Node, words int) []string {
+	scores := map[*html.Node]float64{}
+	walk(doc, func(n *html.Node) bool {
+		if skip(n) {
+			return false
+		}
+		if n.DataAtom == atom.P || n.DataAtom == atom.Pre {
+			l := float64(len(text(n)))
+			if l < 25 {
+				return false
+			}
+			if p := n.Parent; p != nil {
+				scores[p] += l
+				if g := p.Parent; g != nil {
+					scores[g] += l / 2
+				}
+			}
+			return false
+		}
+		return true
+	})
+	var best *html.Node
+	for n, s := range scores {
+		if n.DataAtom == atom.Article || n.DataAtom == atom.Main {
+			s *= 1.25
+		}
+		if best == nil || s > scores[best] || s == scores[best] && n.Data < best.Data {
+			best = n
+		}
+	}
+	if best == nil {
+		return nil
+	}
+	var out []string
+	count := 0
+	walk(best, func(n *html.Node) bool {
+		if count >= words {
+			return false
+		}
+		if n != best && skip(n) {
+			return false
+		}
+		var block string
+		switch n.DataAtom {
+		case atom.P:
+			block = text(n)
+		case atom.H2, atom.H3, atom.H4:
+			block = "## " + text(n)
+		case atom.Pre:
+			block = Fence(strings.Trim(rawText(n), "\n"), "")
+		case atom.Li:
+			block = "- " + text(n)
+		default:
+			return true
+		}
+		if strings.TrimLeft(block, "#- ") != "" {
+			out = append(out, block)
+			count += len(strings.Fields(block))
+		}
+		return false
+	})
+	return out
+}
+
+func walk(n *html.Node, visit func(*html.Node) bool) {
+	if n.Type == html.ElementNode && !visit(n) {
+		return
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		walk(c, visit)
+	}
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// text returns the whitespace-collapsed text inside n.
+func text(n *html.Node) string {
+	return strings.Join(strings.Fields(rawText(n)), " ")
+}
+
+func rawText(n *html.Node) string {
+	var b strings.Builder
+	var rec func(*html.Node)
+	rec = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			b.WriteString(n.Data)
+		}
+		if n.DataAtom == atom.Br {
+			b.WriteByte('\n')
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			rec(c)
+		}
+	}
+	rec(n)
+	return b.String()
+}
+
+func first(vals ...string) string {
+	for _, v := range vals {
+		if v = strings.TrimSpace(v); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// FallbackTitle names a page that could not be fetched by its host and
+// path.
+func FallbackTitle(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return rawURL
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(u.Host, "www.")+u.Path, "/")
+}
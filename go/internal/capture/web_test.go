@@ -0,0 +1,136 @@
+package capture
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+const article = `<!DOCTYPE html>
+<html><head>
+<title>Slices | Go blog</title>
+<meta property="og:title" content="Go Slices: usage and internals">
+<meta name="author" content="Andrew Gerrand">
+<meta property="article:published_time" content="2011-01-05T10:00:00Z">
+<script type="application/ld+json">{"@graph":[{"@type":"WebSite","name":"Blog"}]}</script>
+</head><body>
+<nav class="menu"><p>Home, Blog, About and a long list of links nobody reads.</p></nav>
+<article>
+<h1>Go Slices</h1>
+<p>Go's slice type provides a convenient and efficient means of working with sequences.</p>
+<h2>Arrays</h2>
+<pre>var a [4]int
+a[0] = 1</pre>
+<ul><li>A slice is a descriptor of an array segment.</li></ul>
+<div class="share"><p>Share this on every social network you have ever heard of.</p></div>
+<p>Slicing does not copy the slice's data. It creates a new slice value that points to the original array.</p>
+</article>
+<footer><p>Copyright notice that is long enough to be counted as a paragraph.</p></footer>
+</body></html>`
+
+func TestExtract(t *testing.T) {
+	p, err := Extract(strings.NewReader(article), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Title != "Go Slices: usage and internals" || p.Author != "Andrew Gerrand" {
+		t.Errorf("title %q, author %q", p.Title, p.Author)
+	}
+	if want := time.Date(2011, 1, 5, 10, 0, 0, 0, time.UTC); !p.Published.Equal(want) {
+		t.Errorf("published %v, want %v", p.Published, want)
+	}
+	want := []string{
+		"Go's slice type provides a convenient and efficient means of working with sequences.",
+		"## Arrays",
+		"```\nvar a [4]int\na[0] = 1\n```",
+		"- A slice is a descriptor of an array segment.",
+		"Slicing does not copy the slice's data. It creates a new slice value that points to the original array.",
+	}
+	if !slices.Equal(p.Excerpt, want) {
+		t.Errorf("excerpt =\n%q\nwant\n%q", p.Excerpt, want)
+	}
+
+	if p, _ := Extract(strings.NewReader(article), 10); len(p.Excerpt) != 1 {
+		t.Errorf("10-word excerpt = %q", p.Excerpt)
+	}
+}
+
+func TestExtractJSONLD(t *testing.T) {
+	const doc = `<html><head><title>Doc title</title>
+<meta name="author" content="Meta Author">
+<meta name="description" content="What the page is about.">
+<script type="application/ld+json">[{"@type":"Article","headline":" LD headline ","datePublished":"2024-06-01",
+"author":[{"@type":"Person","name":"Ann"},{"name":"Bob"}]}]</script>
+</head><body><p>short</p></body></html>`
+	p, err := Extract(strings.NewReader(doc), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Title != "LD headline" || p.Author != "Ann, Bob" || p.Published.Format(time.DateOnly) != "2024-06-01" {
+		t.Errorf("Extract = %+v", p)
+	}
+	if !slices.Equal(p.Excerpt, []string{"What the page is about."}) {
+		t.Errorf("excerpt = %q, want the description", p.Excerpt)
+	}
+
+	p, _ = Extract(strings.NewReader(`<html><body><h1>Heading</h1><time datetime="2020-02-03">Feb 3</time><meta name="author" content="https://x.example/me"></body></html>`), 0)
+	if p.Title != "Heading" || p.Author != "" || p.Published.Format(time.DateOnly) != "2020-02-03" {
+		t.Errorf("Extract = %+v", p)
+	}
+}
+
+func TestFetch(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/old", func(w http.ResponseWriter, r *http.Request) { http.Redirect(w, r, "/post", http.StatusFound) })
+	mux.HandleFunc("/post", func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.UserAgent(), "til ") {
+			t.Errorf("User-Agent = %q", r.UserAgent())
+		}
+		w.Header().Set("Content-Type", "text/html; charset=iso-8859-1")
+		w.Write([]byte("<html><head><title>Caf\xe9</title></head><body></body></html>"))
+	})
+	mux.HandleFunc("/data.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte("{}"))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	ctx := context.Background()
+
+	p, err := Fetch(ctx, srv.URL+"/old", FetchOptions{Timeout: 5 * time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Title != "Café" || p.URL != srv.URL+"/post" {
+		t.Errorf("Fetch = %+v", p)
+	}
+	if _, err := Fetch(ctx, srv.URL+"/missing", FetchOptions{}); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("Fetch(missing) = %v", err)
+	}
+	if _, err := Fetch(ctx, srv.URL+"/data.json", FetchOptions{}); err == nil || !strings.Contains(err.Error(), "not an HTML page") {
+		t.Errorf("Fetch(json) = %v", err)
+	}
+
+	closed := httptest.NewServer(mux)
+	closed.Close()
+	if _, err := Fetch(ctx, closed.URL+"/post", FetchOptions{}); !errors.Is(err, ErrOffline) {
+		t.Errorf("Fetch(closed server) = %v, want ErrOffline", err)
+	}
+}
+
+func TestFallbackTitle(t *testing.T) {
+	for in, want := range map[string]string{
+		"https://www.go.dev/blog/slices/": "go.dev/blog/slices",
+		"https://example.com":             "example.com",
+		"not a url":                       "not a url",
+	} {
+		if got := FallbackTitle(in); got != want {
+			t.Errorf("FallbackTitle(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,272 @@
+// Package changelog summarizes what changed in a notes tree over periods
+// of time: the entries added and those substantially updated, for til
+// changelog and the changelog page of the site.
+//
+// An entry is added when it is created, on its date frontmatter, or when
+// it was first committed if that is later, as for entries imported with
+// old dates. It is updated in a period when its body gained or lost at
+// least Options.MinWords words over the period, comparing the versions git
+// history has at its start and end, or when its updated frontmatter falls
+// in the period. Outside git, only the frontmatter counts.
+package changelog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/canhta/til/go/internal/git"
+	"github.com/canhta/til/go/internal/gitdates"
+	"github.com/canhta/til/go/internal/notes"
+	"github.com/canhta/til/go/internal/wdiff"
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+// DefaultMinWords is the Options.MinWords used when it is not set.
+const DefaultMinWords = 50
+
+// Kind is the kind of a Change.
+type Kind string
+
+const (
+	Added   Kind = "added"
+	Updated Kind = "updated"
+)
+
+// Change is an entry added or updated in a period.
+type Change struct {
+	Kind  Kind      `json:"kind"`
+	Path  string    `json:"path"`
+	Title string    `json:"title"`
+	Date  time.Time `json:"date"`
+	// Words is the number of words an update added and removed, when git
+	// history has the entry at both ends of the period.
+	Words int          `json:"words,omitempty"`
+	Entry *entry.Entry `json:"-"`
+}
+
+// Period is a span of time, from From up to but excluding To, and the
+// changes made in it, newest first.
+type Period struct {
+	From    time.Time `json:"from"`
+	To      time.Time `json:"to"`
+	Changes []Change  `json:"changes"`
+}
+
+// Added returns the changes of p adding entries.
+func (p Period) Added() []Change { return p.kind(Added) }
+
+// Updated returns the changes of p updating entries.
+func (p Period) Updated() []Change { return p.kind(Updated) }
+
+func (p Period) kind(k Kind) []Change {
+	var out []Change
+	for _, c := range p.Changes {
+		if c.Kind == k {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// Months returns the periods of the n calendar months up to and including
+// that of now, newest first. The last ends at now.
+func Months(now time.Time, n int) []Period {
+	out := make([]Period, 0, n)
+	to := now
+	start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	for range n {
+		out = append(out, Period{From: start, To: to})
+		to, start = start, start.AddDate(0, -1, 0)
+	}
+	return out
+}
+
+// Options controls Compose.
+type Options struct {
+	// MinWords is how many words an update of the body must add or remove
+	// to count. Defaults to DefaultMinWords.
+	MinWords int
+	// Now is the time the tree is as it is on disk: periods ending then
+	// or later compare against the files rather than the last commit.
+	// Defaults to the current time.
+	Now time.Time
+}
+
+// Compose sets the changes made to entries in each of periods.
+func Compose(ctx context.Context, tree *notes.Tree, entries []*entry.Entry, periods []Period, opts Options) error {
+	if len(periods) == 0 {
+		return nil
+	}
+	if opts.MinWords <= 0 {
+		opts.MinWords = DefaultMinWords
+	}
+	if opts.Now.IsZero() {
+		opts.Now = time.Now()
+	}
+	earliest := periods[0].From
+	for _, p := range periods {
+		if p.From.Before(earliest) {
+			earliest = p.From
+		}
+	}
+	repo, err := git.Open(ctx, tree.Root)
+	if errors.Is(err, git.ErrNotRepo) {
+		repo = nil
+	} else if err != nil {
+		return err
+	}
+	var dates map[string]gitdates.Dates
+	if repo != nil {
+		if dates, err = gitdates.Load(ctx, tree); err != nil {
+			return err
+		}
+	}
+	for _, e := range entries {
+		d, committed := dates[e.Path]
+		added := e.Meta.Date
+		if committed && d.Created.After(added) {
+			added = d.Created
+		}
+		last := e.Meta.Updated
+		if committed && d.Updated.After(last) {
+			last = d.Updated
+		}
+		if added.Before(earliest) && last.Before(earliest) && !(committed && e.ModTime.After(d.Updated)) {
+			continue
+		}
+		h := &history{ctx: ctx, tree: tree, repo: repo, e: e, versions: map[string]string{}}
+		for i := range periods {
+			p := &periods[i]
+			if !added.IsZero() && !added.Before(p.From) && added.Before(p.To) {
+				p.Changes = append(p.Changes, Change{Kind: Added, Path: e.Path, Title: e.Meta.Title, Date: added, Entry: e})
+				continue
+			}
+			// An entry is not updated before it exists.
+			if !added.IsZero() && !added.Before(p.To) {
+				continue
+			}
+			c := Change{Kind: Updated, Path: e.Path, Title: e.Meta.Title, Entry: e}
+			front := inPeriod(e.Meta.Updated, *p)
+			if front {
+				c.Date = e.Meta.Updated
+			}
+			if committed {
+				words, date, err := h.changed(*p, opts.Now)
+				if err != nil {
+					return fmt.Errorf("%s: %w", e.Path, err)
+				}
+				c.Words = words
+				if words >= opts.MinWords && date.After(c.Date) {
+					c.Date = date
+				}
+				front = front || words >= opts.MinWords
+			}
+			if front {
+				p.Changes = append(p.Changes, c)
+			}
+		}
+	}
+	for i := range periods {
+		sort.SliceStable(periods[i].Changes, func(a, b int) bool {
+			return periods[i].Changes[a].Date.After(periods[i].Changes[b].Date)
+		})
+	}
+	return nil
+}
+
+func inPeriod(t time.Time, p Period) bool {
+	return !t.IsZero() && !t.Before(p.From) && t.Before(p.To)
+}
+
+// history reads the versions of one committed entry.
+type history struct {
+	ctx  context.Context
+	tree *notes.Tree
+	repo *git.Repo
+	e    *entry.Entry
+	revs []git.Revision
+	read bool
+	// versions are the bodies read, by commit, with "" for the file on
+	// disk.
+	versions map[string]string
+}
+
+// changed returns the words the body of the entry gained and lost over p,
+// and the time of the last change in it.
+func (h *history) changed(p Period, now time.Time) (int, time.Time, error) {
+	if !h.read {
+		var err error
+		if h.revs, err = h.repo.Log(h.ctx, h.tree.Abs(h.e.Path)); err != nil {
+			return 0, time.Time{}, err
+		}
+		h.read = true
+	}
+	// at returns the newest revision before t, of revs newest first.
+	at := func(t time.Time) (git.Revision, bool) {
+		for _, r := range h.revs {
+			if r.Date.Before(t) {
+				return r, true
+			}
+		}
+		return git.Revision{}, false
+	}
+	start, ok := at(p.From)
+	if !ok {
+		return 0, time.Time{}, nil
+	}
+	old, err := h.body(start)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	var cur string
+	var date time.Time
+	if p.To.Before(now) {
+		end, _ := at(p.To)
+		if end.Hash == start.Hash {
+			return 0, time.Time{}, nil
+		}
+		if cur, err = h.body(end); err != nil {
+			return 0, time.Time{}, err
+		}
+		date = end.Date
+	} else {
+		if cur, err = h.body(git.Revision{}); err != nil {
+			return 0, time.Time{}, err
+		}
+		date = h.e.ModTime
+		if len(h.revs) > 0 && h.revs[0].Date.After(p.From) && h.revs[0].Date.Before(date) {
+			date = h.revs[0].Date
+		}
+	}
+	n := 0
+	for _, c := range wdiff.Diff(old, cur) {
+		if c.Op != wdiff.Equal {
+			n += len(strings.Fields(c.Text))
+		}
+	}
+	return n, date, nil
+}
+
+// body returns the body of the entry as of r, or on disk for the zero r.
+func (h *history) body(r git.Revision) (string, error) {
+	if v, ok := h.versions[r.Hash]; ok {
+		return v, nil
+	}
+	var data []byte
+	var err error
+	if r.Hash == "" {
+		data, err = h.tree.Read(h.e.Path)
+	} else {
+		data, err = h.repo.Show(h.ctx, r.Hash, r.Path)
+	}
+	if err != nil {
+		return "", err
+	}
+	_, body, _ := entry.SplitFrontmatter(data)
+	h.versions[r.Hash] = string(body)
+	return string(body), nil
+}
@@ -0,0 +1,170 @@
+package changelog
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/canhta/til/go/internal/notes"
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+func day(s string) time.Time {
+	t, err := time.Parse(time.DateOnly, s)
+	if err != nil {
+		panic(err)
+	}
+	return t.Add(12 * time.Hour)
+}
+
+// summary formats the changes of each period on one line.
+func summary(ps []Period) []string {
+	var out []string
+	for _, p := range ps {
+		var cs []string
+		for _, c := range p.Changes {
+			s := fmt.Sprintf("%s %s %s", c.Kind, c.Path, c.Date.Format(time.DateOnly))
+			if c.Words > 0 {
+				s += fmt.Sprintf(" %dw", c.Words)
+			}
+			cs = append(cs, s)
+		}
+		out = append(out, p.From.Format("2006-01")+": "+strings.Join(cs, ", "))
+	}
+	return out
+}
+
+func TestMonths(t *testing.T) {
+	now := time.Date(2024, 6, 15, 10, 0, 0, 0, time.UTC)
+	ps := Months(now, 3)
+	want := []Period{
+		{From: time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC), To: now},
+		{From: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), To: time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)},
+		{From: time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC), To: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	if len(ps) != len(want) {
+		t.Fatalf("Months = %v", ps)
+	}
+	for i := range want {
+		if !ps[i].From.Equal(want[i].From) || !ps[i].To.Equal(want[i].To) {
+			t.Errorf("period %d = %v to %v, want %v to %v", i, ps[i].From, ps[i].To, want[i].From, want[i].To)
+		}
+	}
+	if ps := Months(time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC), 2); ps[1].From.Format(time.DateOnly) != "2023-12-01" {
+		t.Errorf("Months across a year = %v", ps)
+	}
+}
+
+// TestComposeFrontmatter checks a tree outside git, where only the date
+// and updated frontmatter tell of changes.
+func TestComposeFrontmatter(t *testing.T) {
+	var entries []*entry.Entry
+	for p, front := range map[string]string{
+		"go/new.md":     "date: 2024-06-02",
+		"go/old.md":     "date: 2024-04-10\nupdated: 2024-05-20",
+		"go/older.md":   "date: 2023-01-01\nupdated: 2023-02-01",
+		"go/undated.md": "updated: 2024-06-03",
+		"go/later.md":   "date: 2024-05-01\nupdated: 2024-04-20",
+	} {
+		e, err := entry.Parse(p, []byte("---\ntitle: T\n"+front+"\n---\n\nBody.\n"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		entries = append(entries, e)
+	}
+	now := time.Date(2024, 6, 15, 0, 0, 0, 0, time.UTC)
+	ps := Months(now, 3)
+	if err := Compose(context.Background(), notes.Open(t.TempDir()), entries, ps, Options{Now: now}); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"2024-06: updated go/undated.md 2024-06-03, added go/new.md 2024-06-02",
+		"2024-05: updated go/old.md 2024-05-20, added go/later.md 2024-05-01",
+		"2024-04: added go/old.md 2024-04-10",
+	}
+	if got := summary(ps); !slices.Equal(got, want) {
+		t.Errorf("Compose =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if got := ps[1].Added(); len(got) != 1 || got[0].Path != "go/later.md" || got[0].Title != "T" {
+		t.Errorf("Added = %v", got)
+	}
+	if got := ps[1].Updated(); len(got) != 1 || got[0].Path != "go/old.md" {
+		t.Errorf("Updated = %v", got)
+	}
+}
+
+func runGit(t *testing.T, dir, date string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GIT_AUTHOR_NAME=Ann", "GIT_AUTHOR_EMAIL=ann@example.com",
+		"GIT_COMMITTER_NAME=Ann", "GIT_COMMITTER_EMAIL=ann@example.com")
+	if date != "" {
+		cmd.Env = append(cmd.Env, "GIT_AUTHOR_DATE="+date+"T12:00:00Z", "GIT_COMMITTER_DATE="+date+"T12:00:00Z")
+	}
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+	}
+}
+
+// TestComposeGit checks a tree in git, whose history tells of entries
+// imported with old dates and of updates to their bodies.
+func TestComposeGit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	dir := t.TempDir()
+	runGit(t, dir, "", "init", "--quiet")
+	tree := notes.Open(dir)
+	commit := func(date string, files map[string]string) {
+		t.Helper()
+		for p, data := range files {
+			if err := tree.Write(p, []byte(data)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		runGit(t, dir, "", "add", "-A")
+		runGit(t, dir, date, "commit", "--quiet", "-m", date)
+	}
+	body := "---\ntitle: Slices\ndate: 2024-04-10\n---\n\nSlices share their backing arrays.\n"
+	commit("2024-04-10", map[string]string{"go/slices.md": body})
+	commit("2024-05-10", map[string]string{"go/slices.md": body + "\n" + strings.Repeat("more ", 60) + "\n"})
+	commit("2024-05-20", map[string]string{"go/maps.md": "---\ntitle: Maps\ndate: 2023-01-01\n---\n\nMaps.\n"})
+	commit("2024-06-05", map[string]string{"go/slices.md": body + "\n" + strings.Repeat("more ", 65) + "\n"})
+
+	now := time.Date(2024, 6, 15, 0, 0, 0, 0, time.UTC)
+	compose := func(opts Options) []string {
+		t.Helper()
+		entries, err := tree.Entries()
+		if err != nil {
+			t.Fatal(err)
+		}
+		ps := Months(now, 3)
+		opts.Now = now
+		if err := Compose(context.Background(), tree, entries, ps, opts); err != nil {
+			t.Fatal(err)
+		}
+		return summary(ps)
+	}
+	want := []string{
+		"2024-06: ",
+		"2024-05: added go/maps.md 2024-05-20, updated go/slices.md 2024-05-10 60w",
+		"2024-04: added go/slices.md 2024-04-10",
+	}
+	if got := compose(Options{}); !slices.Equal(got, want) {
+		t.Errorf("Compose =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	// Smaller updates count with a lower MinWords, those of the month up
+	// to now dated by their commit.
+	want[0] = "2024-06: updated go/slices.md 2024-06-05 5w"
+	if got := compose(Options{MinWords: 5}); !slices.Equal(got, want) {
+		t.Errorf("Compose with MinWords 5 =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
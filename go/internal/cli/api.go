@@ -0,0 +1,155 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/term"
+
+	"github.com/canhta/til/go/internal/api"
+	"github.com/canhta/til/go/internal/config"
+)
+
+func newAPICmd(a *app) *cobra.Command {
+	var (
+		addr         string
+		web, hashPwd bool
+	)
+	cmd := &cobra.Command{
+		Use:   "api",
+		Short: "Serve entries over a JSON REST API",
+		Long: `Serve entries over a JSON REST API for web frontends and scripts: list,
+search, read and render entries, create and update them, and rename or
+merge tags. Private entries are not served.
+
+Entries, tags, categories and the link graph can also be queried with
+GraphQL at /api/graphql, filtering and paging every list of entries;
+/api/graphql/schema prints the schema.
+
+Clients authenticate with "Authorization: Bearer <token>", the token being
+read from the environment variable named by [api] token_env. Without one,
+the API only listens on localhost. Browser origins allowed to call it are
+listed in [api] origins. Entries created or updated are committed when
+[git] commit is enabled.
+
+With --web, a small web app is served at / as well, for teammates who do
+not use the command line: they sign in, browse and search entries, and
+write or edit them in a textarea with a live preview. Entries they create
+are attributed to them. Users signing in with a password are listed with
+bcrypt hashes of their passwords, as printed by --hash-password, in
+[api.web.users]; [api.web.github] signs listed GitHub users in with an
+OAuth app whose callback URL is /auth/github/callback. Serve it behind
+HTTPS when it is reachable beyond localhost.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if hashPwd {
+				return hashPassword(cmd)
+			}
+			cfg := a.cfg.API
+			var token string
+			if cfg.TokenEnv != "" {
+				if token = os.Getenv(cfg.TokenEnv); token == "" {
+					return fmt.Errorf("[api] token_env names %s, which is not set", cfg.TokenEnv)
+				}
+			}
+			// Commits from concurrent requests would race for the git index.
+			var mu sync.Mutex
+			s := &api.Server{
+				Addr:        addr,
+				Tree:        a.tree,
+				Token:       token,
+				Origins:     cfg.Origins,
+				Collections: a.cfg.Collections,
+				Lang:        a.lang(),
+				Scaffold: func(category, title, slug string, tags []string) (string, []byte, error) {
+					return a.scaffold(category, title, slug, tags, nil)
+				},
+				Commit: func(ctx context.Context, rel, verb string, also ...string) error {
+					if a.noCommit {
+						return nil
+					}
+					mu.Lock()
+					defer mu.Unlock()
+					return a.commit(ctx, cmd.ErrOrStderr(), rel, verb, also...)
+				},
+			}
+			if web {
+				w, err := webConfig(cfg.Web)
+				if err != nil {
+					return err
+				}
+				s.Web = w
+			}
+			return s.Run(cmd.Context())
+		},
+	}
+	cmd.Flags().StringVar(&addr, "addr", "localhost:4001", "address to listen on")
+	cmd.Flags().BoolVar(&web, "web", false, "serve the web app at / too")
+	cmd.Flags().BoolVar(&hashPwd, "hash-password", false, "read a password and print its hash for [api.web.users]")
+	a.commitFlag(cmd)
+	return cmd
+}
+
+// webConfig sets up the web app as cfg configures it.
+func webConfig(cfg config.Web) (*api.Web, error) {
+	w := &api.Web{Users: cfg.Users}
+	if cfg.SessionKeyEnv != "" {
+		key := os.Getenv(cfg.SessionKeyEnv)
+		if key == "" {
+			return nil, fmt.Errorf("[api.web] session_key_env names %s, which is not set", cfg.SessionKeyEnv)
+		}
+		w.SessionKey = []byte(key)
+	}
+	if gh := cfg.GitHub; gh.ClientID != "" {
+		env := gh.ClientSecretEnv
+		if env == "" {
+			env = "GITHUB_CLIENT_SECRET"
+		}
+		secret := os.Getenv(env)
+		if secret == "" {
+			return nil, fmt.Errorf("[api.web.github] needs the client secret in %s", env)
+		}
+		if len(gh.Allow) == 0 {
+			return nil, errors.New("[api.web.github] allow lists nobody to sign in")
+		}
+		w.GitHub = &api.GitHub{ClientID: gh.ClientID, ClientSecret: secret, Allow: gh.Allow, URL: gh.URL}
+	}
+	return w, nil
+}
+
+// hashPassword reads a password, from the terminal without echoing it or
+// else a line of stdin, and prints its bcrypt hash.
+func hashPassword(cmd *cobra.Command) error {
+	var pwd []byte
+	if f, ok := cmd.InOrStdin().(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+		fmt.Fprint(cmd.ErrOrStderr(), "Password: ")
+		p, err := term.ReadPassword(int(f.Fd()))
+		fmt.Fprintln(cmd.ErrOrStderr())
+		if err != nil {
+			return err
+		}
+		pwd = p
+	} else {
+		line, err := bufio.NewReader(cmd.InOrStdin()).ReadString('\n')
+		if err != nil && line == "" {
+			return errors.New("no password on stdin")
+		}
+		pwd = []byte(strings.TrimRight(line, "\r\n"))
+	}
+	if len(pwd) == 0 {
+		return errors.New("empty password")
+	}
+	hash, err := bcrypt.GenerateFromPassword(pwd, bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(cmd.OutOrStdout(), string(hash))
+	return nil
+}
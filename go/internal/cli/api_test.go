@@ -0,0 +1,53 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/canhta/til/go/internal/config"
+)
+
+func TestAPIHashPassword(t *testing.T) {
+	root := newTree(t, nil)
+	out, err := runStdin(t, root, "hunter2\n", "api", "--hash-password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash := strings.TrimSpace(out)
+	if err := bcrypt.CompareHashAndPassword([]byte(hash), []byte("hunter2")); err != nil {
+		t.Errorf("hash %q does not match the password: %v", hash, err)
+	}
+	if _, err := runStdin(t, root, "\n", "api", "--hash-password"); err == nil {
+		t.Error("hashing an empty password succeeded")
+	}
+}
+
+func TestWebConfig(t *testing.T) {
+	t.Setenv("TIL_TEST_SESSION", "key")
+	t.Setenv("GITHUB_CLIENT_SECRET", "shh")
+	w, err := webConfig(config.Web{
+		Users:         map[string]string{"ann": "hash"},
+		SessionKeyEnv: "TIL_TEST_SESSION",
+		GitHub:        config.WebGitHub{ClientID: "id", Allow: []string{"ann"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(w.SessionKey) != "key" || w.Users["ann"] != "hash" || w.GitHub == nil || w.GitHub.ClientSecret != "shh" {
+		t.Errorf("web = %+v, github %+v", w, w.GitHub)
+	}
+	if w, err := webConfig(config.Web{}); err != nil || w.GitHub != nil || w.SessionKey != nil {
+		t.Errorf("webConfig of nothing = %+v, %v", w, err)
+	}
+	for name, cfg := range map[string]config.Web{
+		"unset session key": {SessionKeyEnv: "TIL_TEST_UNSET"},
+		"no secret":         {GitHub: config.WebGitHub{ClientID: "id", ClientSecretEnv: "TIL_TEST_UNSET", Allow: []string{"ann"}}},
+		"nobody allowed":    {GitHub: config.WebGitHub{ClientID: "id"}},
+	} {
+		if _, err := webConfig(cfg); err == nil {
+			t.Errorf("%s: webConfig succeeded", name)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+func newArchiveCmd(a *app) *cobra.Command {
+	var undo bool
+	cmd := &cobra.Command{
+		Use:   "archive <entry>...",
+		Short: "Hide entries from the site and default listings",
+		Long: `Archive sets archived: true in the frontmatter of entries. Archived
+entries stay in the tree, but are left out of the site, the README index
+and til list and til search, which show them with --archived. --undo
+unarchives them.`,
+		Example: `  til archive go/old-gopath
+  til list --archived --tag go
+  til archive --undo go/old-gopath`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			verb := "archive"
+			if undo {
+				verb = "unarchive"
+			}
+			files := map[string][]byte{}
+			var paths []string
+			out := cmd.OutOrStdout()
+			for _, ref := range args {
+				e, err := a.resolve(ref)
+				if err != nil {
+					return err
+				}
+				if e.Meta.Archived != undo {
+					fmt.Fprintf(out, "%s is already %sd\n", e.Path, verb)
+					continue
+				}
+				data, err := a.tree.Read(e.Path)
+				if err != nil {
+					return err
+				}
+				next, err := entry.Rewrite(data, func(f *entry.Front) error {
+					if undo {
+						_, err := f.Delete("archived")
+						return err
+					}
+					return f.Set("archived", true)
+				})
+				if err != nil {
+					return fmt.Errorf("%s: %w", e.Path, err)
+				}
+				if _, ok := files[e.Path]; !ok {
+					paths = append(paths, e.Path)
+				}
+				files[e.Path] = next
+			}
+			if len(paths) == 0 {
+				return nil
+			}
+			if err := a.tree.WriteAll(files); err != nil {
+				return err
+			}
+			for _, p := range paths {
+				fmt.Fprintf(out, "%sd %s\n", verb, p)
+			}
+			return a.commitEntry(cmd, paths[0], verb, paths[1:]...)
+		},
+	}
+	cmd.Flags().BoolVar(&undo, "undo", false, "unarchive the entries")
+	a.commitFlag(cmd)
+	return cmd
+}
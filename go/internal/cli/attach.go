@@ -0,0 +1,83 @@
+package cli
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/canhta/til/go/internal/assets"
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+func newAttachCmd(a *app) *cobra.Command {
+	var (
+		shrink assets.Shrink
+		alt    string
+	)
+	cmd := &cobra.Command{
+		Use:   "attach <entry> <file-or-url>",
+		Short: "Copy an image or file into an entry's assets and link it",
+		Long: `Attach stores a local file or a downloaded URL in the entry's assets
+directory (for go/basic_syntax.md, go/assets/basic_syntax/) and links it
+from the entry with a relative path.
+
+Links and images in the entry that already point at the file or URL as
+given are rewritten to the stored copy; otherwise an image, or a link for
+other files, is appended. Images can be scaled down with --max-width and
+re-encoded with --compress. til build publishes assets under
+fingerprinted names.`,
+		Example: `  til attach slices ~/Pictures/slice-header.png --max-width 1200
+  til attach go/concurrency https://go.dev/blog/pipelines/fanout.png`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			e, err := a.resolve(args[0])
+			if err != nil {
+				return err
+			}
+			src, err := assets.Read(cmd.Context(), args[1])
+			if err != nil {
+				return err
+			}
+			data, err := assets.Process(src.Name, src.Data, shrink)
+			if err != nil {
+				return fmt.Errorf("%s: %w", src.Name, err)
+			}
+			stored, err := assets.Store(a.tree, e.Path, src.Name, data)
+			if err != nil {
+				return err
+			}
+			content, err := a.tree.Read(e.Path)
+			if err != nil {
+				return err
+			}
+			if alt == "" {
+				alt = entry.Humanize(strings.TrimSuffix(src.Name, path.Ext(src.Name)))
+			}
+			dest := strings.TrimPrefix(stored, path.Dir(e.Path)+"/")
+			front := content[:len(content)-len(e.Body)]
+			body, rewrote := assets.Link(e.Body, args[1], dest, alt)
+			if err := a.tree.Write(e.Path, append(front[:len(front):len(front)], body...)); err != nil {
+				return err
+			}
+			out := cmd.OutOrStdout()
+			fmt.Fprintln(out, stored)
+			switch {
+			case rewrote:
+				fmt.Fprintf(out, "rewrote links to %s in %s\n", args[1], e.Path)
+			case len(body) == len(e.Body):
+				fmt.Fprintf(out, "already linked from %s\n", e.Path)
+			default:
+				fmt.Fprintf(out, "linked from %s\n", e.Path)
+			}
+			return a.commitEntry(cmd, e.Path, "attach to", stored)
+		},
+	}
+	cmd.Flags().IntVar(&shrink.MaxWidth, "max-width", 0, "scale images wider than this many pixels down")
+	cmd.Flags().BoolVar(&shrink.Compress, "compress", false, "re-encode images when that makes them smaller")
+	cmd.Flags().IntVar(&shrink.Quality, "quality", 85, "JPEG quality when scaling or compressing")
+	cmd.Flags().StringVar(&alt, "alt", "", "alt text of the appended image (default: from the file name)")
+	a.commitFlag(cmd)
+	return cmd
+}
@@ -0,0 +1,54 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestAttach(t *testing.T) {
+	dir := t.TempDir()
+	src, other := filepath.Join(dir, "header.svg"), filepath.Join(dir, "notes.pdf")
+	if err := os.WriteFile(src, []byte("<svg/>"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(other, []byte("%PDF"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	root := newTree(t, map[string]string{
+		"go/slices.md": "---\ntitle: Slices\ndate: 2024-06-01\n---\n\nThe header: ![old](" + src + ")\n",
+	})
+	out := mustRun(t, root, "attach", "slices", src)
+	if out != "go/assets/slices/header.svg\nrewrote links to "+src+" in go/slices.md\n" {
+		t.Errorf("attach printed %q", out)
+	}
+	if got := readFile(t, root, "go/assets/slices/header.svg"); got != "<svg/>" {
+		t.Errorf("stored asset = %q", got)
+	}
+	if got := readFile(t, root, "go/slices.md"); !strings.Contains(got, "The header: ![old](assets/slices/header.svg)\n") {
+		t.Errorf("go/slices.md:\n%s", got)
+	}
+	out = mustRun(t, root, "attach", "slices", other, "--alt", "The notes")
+	if !strings.Contains(out, "linked from go/slices.md") {
+		t.Errorf("attach printed %q", out)
+	}
+	got := readFile(t, root, "go/slices.md")
+	if !strings.HasSuffix(got, "\n\n[The notes](assets/slices/notes.pdf)\n") || !strings.HasPrefix(got, "---\ntitle: Slices\n") {
+		t.Errorf("go/slices.md:\n%s", got)
+	}
+	if out := mustRun(t, root, "attach", "slices", other); !strings.Contains(out, "already linked") {
+		t.Errorf("second attach printed %q", out)
+	}
+
+	mustRun(t, root, "build")
+	page := readFile(t, root, "public/go/slices/index.html")
+	m := regexp.MustCompile(`/go/assets/slices/header\.[0-9a-f]{10}\.svg`).FindString(page)
+	if m == "" {
+		t.Fatalf("the page does not link a fingerprinted asset:\n%s", page)
+	}
+	if got := readFile(t, root, "public"+m); got != "<svg/>" {
+		t.Errorf("public%s = %q", m, got)
+	}
+}
@@ -0,0 +1,53 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAuthors(t *testing.T) {
+	root := newTree(t, map[string]string{
+		"go/maps.md":    "---\ntitle: Maps\ndate: 2024-05-01\nauthor: Bob\n---\n",
+		"git/rebase.md": "---\ntitle: Rebase\ndate: 2024-04-01\nauthor: Jane Doe\n---\n",
+	})
+	gitconfig := filepath.Join(t.TempDir(), "gitconfig")
+	if err := os.WriteFile(gitconfig, []byte("[user]\n\tname = Jane Doe\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GIT_CONFIG_GLOBAL", gitconfig)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+
+	mustRun(t, root, "new", "go", "Slices", "--no-edit")
+	if got := readFile(t, root, "go/slices.md"); !strings.Contains(got, "\nauthor: Jane Doe\n") {
+		t.Errorf("new entry =\n%s", got)
+	}
+	// Templates of the tree that leave the author out get it added.
+	writeFile(t, root, ".til/templates/go.md", "---\ntitle: {{ .Title }}\n---\n")
+	mustRun(t, root, "new", "go", "Channels", "--no-edit")
+	if got := readFile(t, root, "go/channels.md"); got != "---\ntitle: Channels\nauthor: Jane Doe\n---\n" {
+		t.Errorf("entry from a template without an author =\n%s", got)
+	}
+
+	out := mustRun(t, root, "list", "--author", "jane doe")
+	if !strings.Contains(out, "git/rebase.md") || !strings.Contains(out, "go/slices.md") || strings.Contains(out, "go/maps.md") {
+		t.Errorf("list --author =\n%s", out)
+	}
+	if out := mustRun(t, root, "list", "-a", "bob", "-a", "nobody"); !strings.Contains(out, "go/maps.md") || strings.Contains(out, "rebase") {
+		t.Errorf("list -a bob -a nobody =\n%s", out)
+	}
+	if out := mustRun(t, root, "stats"); !strings.Contains(out, "PER AUTHOR") || !strings.Contains(out, "Jane Doe") {
+		t.Errorf("stats =\n%s", out)
+	}
+
+	if out := mustRun(t, root, "export", "book", "--format", "html", "--author", "bob"); !strings.Contains(out, `<p class="author">Bob</p>`) {
+		t.Errorf("book by one author lacks them on the cover:\n%s", out)
+	}
+	if out := mustRun(t, root, "export", "book", "--format", "html"); strings.Contains(out, `class="author"`) {
+		t.Errorf("book by several authors names one:\n%s", out)
+	}
+	if out := mustRun(t, root, "export", "book", "--format", "html", "--book-author", "The team"); !strings.Contains(out, `<p class="author">The team</p>`) {
+		t.Errorf("book --book-author lacks it:\n%s", out)
+	}
+}
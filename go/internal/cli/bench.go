@@ -0,0 +1,116 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/canhta/til/go/internal/bench"
+	"github.com/canhta/til/go/internal/runner"
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+func newBenchCmd(a *app) *cobra.Command {
+	var (
+		count     int
+		benchtime string
+		timeout   time.Duration
+		sandbox   bool
+		dryRun    bool
+	)
+	cmd := &cobra.Command{
+		Use:   "bench <entry>",
+		Short: "Benchmark the competing Go snippets of an entry",
+		Long: `Bench runs the Go code blocks of an entry tagged {bench} against each
+other as testing.B benchmarks and records a table of their time and
+allocations per operation in the entry. Each block is named by the value
+of the attribute, its label attribute or its position:
+
+  ` + "```go {bench=append}" + `
+  dst = append(dst[:0], src...)
+  ` + "```" + `
+
+A case's code is the body of the benchmark loop. A {bench} block made only
+of declarations is shared by every case instead, for the inputs they work
+on and the package-level variables their results go to. run and verify
+skip {bench} blocks.
+
+The table sits between ` + bench.StartMarker + ` and
+` + bench.EndMarker + ` markers, added after the last {bench} block the first
+time and replaced on later runs. The benchmark binary runs in the sandbox
+like til run's blocks.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			e, err := a.resolve(args[0])
+			if err != nil {
+				return err
+			}
+			suite, err := bench.Parse(e.Body)
+			if err != nil {
+				return fmt.Errorf("%s: %w", e.Path, err)
+			}
+			src, err := suite.Source()
+			if err != nil {
+				return fmt.Errorf("%s: %w", e.Path, err)
+			}
+			reg, err := a.registry(timeout, sandbox)
+			if err != nil {
+				return err
+			}
+			g, ok := reg.Lookup("go")
+			gr, isGo := g.(*runner.Go)
+			if !ok || !isGo {
+				gr = &runner.Go{Timeout: timeout}
+			}
+			sb, err := reg.Sandbox.For(e.Meta.Sandbox)
+			if err != nil {
+				return err
+			}
+			flags := []string{"-test.count", strconv.Itoa(count)}
+			if benchtime != "" {
+				flags = append(flags, "-test.benchtime", benchtime)
+			}
+			fmt.Fprintf(cmd.ErrOrStderr(), "benchmarking %s in %s\n", plural(len(suite.Cases), "snippet"), e.Path)
+			res := gr.Bench(cmd.Context(), src, flags, sb)
+			if res.Failed() {
+				cmd.ErrOrStderr().Write(res.Output)
+				return fmt.Errorf("%s: bench: %s: %w", e.Path, res.Phase, res.Err)
+			}
+			rep, err := suite.ParseOutput(res.Output, time.Now())
+			if err != nil {
+				cmd.ErrOrStderr().Write(res.Output)
+				return fmt.Errorf("%s: %w", e.Path, err)
+			}
+			table := bench.Table(rep)
+			if !dryRun {
+				data, err := a.tree.Read(e.Path)
+				if err != nil {
+					return err
+				}
+				_, body, _ := entry.SplitFrontmatter(data)
+				data = append(data[:len(data)-len(body):len(data)-len(body)], bench.Splice(body, table, suite.EndLine)...)
+				if err := a.tree.Write(e.Path, data); err != nil {
+					return err
+				}
+				if err := a.commitEntry(cmd, e.Path, "benchmark"); err != nil {
+					return err
+				}
+			}
+			return a.output(cmd, rep, func(w io.Writer) error {
+				_, err := w.Write(table)
+				return err
+			})
+		},
+	}
+	withJSON(cmd, "bench")
+	cmd.Flags().IntVar(&count, "count", 1, "run each benchmark this many times and average the results")
+	cmd.Flags().StringVar(&benchtime, "benchtime", "", "run each benchmark for this long or, as 100x, this many iterations (default 1s)")
+	cmd.Flags().DurationVar(&timeout, "timeout", 0, "limit for each build and run step (default from config, else 30s)")
+	sandboxFlag(cmd, &sandbox)
+	cmd.Flags().BoolVarP(&dryRun, "dry-run", "n", false, "print the table without writing it into the entry")
+	a.commitFlag(cmd)
+	return cmd
+}
@@ -0,0 +1,46 @@
+package cli
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/canhta/til/go/internal/bench"
+)
+
+func TestBench(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds Go programs")
+	}
+	root := newTree(t, map[string]string{
+		"go/copy.md": "---\ntitle: Append or copy\n---\n\n```go {bench}\nvar src = make([]int, 64)\nvar dst []int\n```\n\n```go {bench=append}\ndst = append(dst[:0], src...)\n```\n\n```go {bench=copy}\ndst = make([]int, len(src))\ncopy(dst, src)\n```\n\nAfter.\n",
+		"go/none.md": "# None\n\n```go\nfmt.Println()\n```\n",
+	})
+	out := mustRun(t, root, "bench", "copy", "--benchtime", "10x", "--timeout", "5m", "--count", "2")
+	if !strings.Contains(out, "benchmarking 2 snippets in go/copy.md") || !strings.Contains(out, "| append | ") || !strings.Contains(out, "| copy | ") {
+		t.Errorf("bench =\n%s", out)
+	}
+	got := readFile(t, root, "go/copy.md")
+	start := strings.Index(got, bench.StartMarker)
+	if start < 0 || !strings.HasSuffix(got, bench.EndMarker+"\n\nAfter.\n") || !strings.Contains(got[:start], "copy(dst, src)\n```\n\n") {
+		t.Errorf("entry after bench =\n%s", got)
+	}
+
+	before := readFile(t, root, "go/copy.md")
+	out = mustRun(t, root, "bench", "copy", "--benchtime", "10x", "--timeout", "5m", "--timeout", "5m", "--dry-run", "--json")
+	var rep struct{ Data bench.Report }
+	if err := json.Unmarshal([]byte(out[strings.Index(out, "{"):]), &rep); err != nil || len(rep.Data.Results) != 2 || rep.Data.Results[0].Runs != 1 {
+		t.Errorf("bench --json = %v\n%s", err, out)
+	}
+	if readFile(t, root, "go/copy.md") != before {
+		t.Error("bench --dry-run wrote the entry")
+	}
+
+	if _, err := run(t, root, "bench", "none"); err == nil || !strings.Contains(err.Error(), "go/none.md: no ```go {bench} code blocks") {
+		t.Errorf("bench none = %v", err)
+	}
+	writeFile(t, root, "go/broken.md", "# Broken\n\n```go {bench}\nundefined()\n```\n")
+	if _, err := run(t, root, "bench", "broken", "--benchtime", "1x", "--timeout", "5m"); err == nil || !strings.Contains(err.Error(), "go/broken.md: bench: build: ") {
+		t.Errorf("bench broken = %v", err)
+	}
+}
@@ -0,0 +1,167 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/canhta/til/go/internal/importer"
+	"github.com/canhta/til/go/internal/notes"
+	"github.com/canhta/til/go/internal/telegram"
+)
+
+func newBotCmd(a *app) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "bot",
+		Short: "Capture entries sent to a chat bot",
+	}
+	cmd.AddCommand(newBotTelegramCmd(a))
+	return cmd
+}
+
+// botWait is how long a request for Telegram updates waits for one.
+const botWait = 50 * time.Second
+
+func newBotTelegramCmd(a *app) *cobra.Command {
+	var category string
+	cmd := &cobra.Command{
+		Use:   "telegram",
+		Short: "Save the messages sent to a Telegram bot as entries",
+		Long: `Telegram runs until interrupted, saving each message sent to the bot as an
+entry and replying with its path, and its URL when [site] base_url is an
+absolute URL. The title is the message's first line; code blocks are kept
+as fenced code, #hashtags become tags, and a photo or file sent is saved
+as an asset, with its caption as the text.
+
+Create a bot with @BotFather and put its token in $TELEGRAM_BOT_TOKEN, or
+the variable [bot.telegram] token_env names. Only messages from the users
+listed in allow_users are saved; until it is set, the bot replies to each
+message with the ID of its sender:
+
+  [bot.telegram]
+  allow_users = [123456789]
+
+Each message is saved once even if Telegram delivers it again. On SIGINT
+or SIGTERM the message being saved is finished first.`,
+		Example: `  TELEGRAM_BOT_TOKEN=123:abc til bot telegram -c inbox`,
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg := a.cfg.Bot.Telegram
+			env := cfg.TokenEnv
+			if env == "" {
+				env = "TELEGRAM_BOT_TOKEN"
+			}
+			token := os.Getenv(env)
+			if token == "" {
+				return fmt.Errorf("$%s is not set; it needs the token @BotFather gave the bot", env)
+			}
+			if !cmd.Flags().Changed("category") && cfg.Category != "" {
+				category = cfg.Category
+			}
+			if notes.Skip(category) || strings.ContainsAny(category, `/\`) {
+				return fmt.Errorf("invalid category %q", category)
+			}
+			c := telegram.Client{Token: token, API: cfg.API}
+			ctx := cmd.Context()
+			stderr := cmd.ErrOrStderr()
+			if len(cfg.AllowUsers) == 0 {
+				fmt.Fprintln(stderr, "warning: [bot.telegram] allow_users is not set; no message will be saved")
+			}
+			fmt.Fprintln(stderr, "waiting for messages; interrupt to stop")
+			var offset int64
+			for ctx.Err() == nil {
+				updates, err := c.Updates(ctx, offset, botWait)
+				if err != nil {
+					if ctx.Err() != nil {
+						break
+					}
+					fmt.Fprintf(stderr, "warning: %v\n", err)
+					select {
+					case <-ctx.Done():
+					case <-time.After(5 * time.Second):
+					}
+					continue
+				}
+				for _, u := range updates {
+					offset = u.ID + 1
+					if u.Message != nil {
+						a.botMessage(cmd, c, u.Message, category)
+					}
+				}
+			}
+			if offset > 0 {
+				// Confirm the updates handled, so they are not delivered
+				// again on the next start.
+				ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+				defer cancel()
+				if _, err := c.Updates(ctx, offset, 0); err != nil {
+					fmt.Fprintf(stderr, "warning: %v\n", err)
+				}
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&category, "category", "c", importer.DefaultCategory, "category of the entries (default from [bot.telegram] category)")
+	a.commitFlag(cmd)
+	return cmd
+}
+
+// botMessage saves the message m sent to the bot and replies with where.
+// It is not interrupted, so a message is never half saved.
+func (a *app) botMessage(cmd *cobra.Command, c telegram.Client, m *telegram.Message, category string) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(cmd.Context()), 2*time.Minute)
+	defer cancel()
+	stderr := cmd.ErrOrStderr()
+	reply := func(text string) {
+		if err := c.Reply(ctx, m, text); err != nil {
+			fmt.Fprintf(stderr, "warning: %v\n", err)
+		}
+	}
+	cfg := a.cfg.Bot.Telegram
+	if m.From == nil {
+		return
+	}
+	if !slices.Contains(cfg.AllowUsers, m.From.ID) {
+		fmt.Fprintf(stderr, "ignoring message from user %d (@%s)\n", m.From.ID, m.From.Username)
+		if len(cfg.AllowUsers) == 0 {
+			reply(fmt.Sprintf("To save your messages, add your user ID to the til config:\n\n[bot.telegram]\nallow_users = [%d]", m.From.ID))
+		}
+		return
+	}
+	if command, _, _ := strings.Cut(m.Text, " "); strings.HasPrefix(command, "/") {
+		reply("Send me anything to save it as an entry: the first line is its title, #hashtags tag it.")
+		return
+	}
+	n, err := c.Convert(ctx, m, telegram.Options{Category: category})
+	if err == nil && strings.TrimSpace(n.Body) == "" && len(n.Assets) == 0 {
+		return
+	}
+	var rel string
+	if err == nil {
+		rel, err = a.importNote(cmd, n, "telegram", false)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "warning: message %d: %v\n", m.ID, err)
+		reply("Not saved: " + err.Error())
+		return
+	}
+	if rel == "" {
+		reply("Saved already as " + n.Existing)
+		return
+	}
+	fmt.Fprintln(cmd.OutOrStdout(), rel)
+	text := "Saved " + rel
+	if entries, err := a.tree.Entries(); err == nil {
+		if url := a.entryURLs(entries); url != nil {
+			if u := url(rel); u != "" {
+				text += "\n" + u
+			}
+		}
+	}
+	reply(text)
+}
@@ -0,0 +1,83 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestBotTelegram(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var (
+		mu      sync.Mutex
+		replies []string
+		offsets []float64
+	)
+	updates := `{"ok":true,"result":[
+{"update_id":10,"message":{"message_id":1,"from":{"id":99,"username":"other"},"chat":{"id":5},"text":"Spam"}},
+{"update_id":11,"message":{"message_id":2,"from":{"id":7,"username":"me"},"chat":{"id":5},"text":"/start"}},
+{"update_id":12,"message":{"message_id":3,"from":{"id":7,"username":"me"},"chat":{"id":5},"date":1709251200,"text":"Slices share arrays #go","entities":[{"type":"hashtag","offset":20,"length":3}]}},
+{"update_id":13,"message":{"message_id":3,"from":{"id":7,"username":"me"},"chat":{"id":5},"text":"Slices share arrays #go"}}]}`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var p struct {
+			Offset float64
+			Text   string
+		}
+		json.NewDecoder(r.Body).Decode(&p)
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/getUpdates"):
+			offsets = append(offsets, p.Offset)
+			if len(offsets) == 1 {
+				w.Write([]byte(updates))
+				return
+			}
+			// The second poll ends the run, as an interrupt would.
+			cancel()
+			w.Write([]byte(`{"ok":true,"result":[]}`))
+		case strings.HasSuffix(r.URL.Path, "/sendMessage"):
+			replies = append(replies, p.Text)
+			w.Write([]byte(`{"ok":true,"result":{}}`))
+		}
+	}))
+	defer srv.Close()
+	root := newTree(t, nil)
+	writeConfig(t, "[site]\nbase_url = \"https://til.example/\"\n\n[bot.telegram]\ntoken_env = \"TIL_TEST_BOT\"\nallow_users = [7]\ncategory = \"chat\"\napi = \""+srv.URL+"\"\n")
+
+	if _, err := run(t, root, "bot", "telegram"); err == nil || !strings.HasPrefix(err.Error(), "$TIL_TEST_BOT is not set") {
+		t.Errorf("bot telegram without a token = %v", err)
+	}
+	t.Setenv("TIL_TEST_BOT", "1:tok")
+	cmd := newRootCmd(&app{})
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(&out)
+	cmd.SetArgs([]string{"-C", root, "bot", "telegram"})
+	if err := cmd.ExecuteContext(ctx); err != nil {
+		t.Fatalf("bot telegram: %v\n%s", err, out.String())
+	}
+	if got := out.String(); !strings.Contains(got, "ignoring message from user 99 (@other)\n") || !strings.Contains(got, "chat/slices_share_arrays.md\n") {
+		t.Errorf("bot telegram =\n%s", got)
+	}
+	if got := readFile(t, root, "chat/slices_share_arrays.md"); !strings.Contains(got, "tags: [go]\n") || !strings.Contains(got, "imported: telegram:5/3\n") || !strings.HasSuffix(got, "\nSlices share arrays\n") {
+		t.Errorf("chat/slices_share_arrays.md =\n%s", got)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(replies) != 3 || !strings.HasPrefix(replies[0], "Send me anything") ||
+		replies[1] != "Saved chat/slices_share_arrays.md\nhttps://til.example/chat/slices-share-arrays/" ||
+		replies[2] != "Saved already as chat/slices_share_arrays.md" {
+		t.Errorf("replies = %q", replies)
+	}
+	// The last request confirms the updates handled.
+	if len(offsets) != 3 || offsets[0] != 0 || offsets[1] != 14 || offsets[2] != 14 {
+		t.Errorf("offsets = %v", offsets)
+	}
+}
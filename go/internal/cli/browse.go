@@ -0,0 +1,29 @@
+package cli
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/canhta/til/go/internal/tui"
+)
+
+func newBrowseCmd(a *app) *cobra.Command {
+	var opts tui.Options
+	cmd := &cobra.Command{
+		Use:     "browse",
+		Aliases: []string{"tui"},
+		Short:   "Browse entries in an interactive terminal UI",
+		Long: `Browse opens a full-screen browser with a filterable entry list and a
+preview pane. Keys:
+
+  ↑/↓ j/k    move              /        filter
+  e, enter   open in $EDITOR   t        edit tags
+  d          delete            y        copy a code block
+  pgup/pgdn  scroll preview    q        quit`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return tui.Run(a.tree, opts)
+		},
+	}
+	cmd.Flags().StringVar(&opts.Style, "style", "monokai", "chroma style for code in the preview")
+	return cmd
+}
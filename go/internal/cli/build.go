@@ -0,0 +1,190 @@
+package cli
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/canhta/til/go/internal/logging"
+	"github.com/canhta/til/go/internal/query"
+	"github.com/canhta/til/go/internal/site"
+	"github.com/canhta/til/go/internal/webmention"
+	"github.com/canhta/til/go/pkg/entry"
+	"github.com/canhta/til/go/pkg/hook"
+)
+
+func newBuildCmd(a *app) *cobra.Command {
+	var opts site.Options
+	var fetchMentions bool
+	cmd := &cobra.Command{
+		Use:   "build",
+		Short: "Generate the static site into ./public",
+		Long: `Build generates the static site into ./public.
+
+Entries are rendered only when their source, the entries they include, the
+pages their links point to or the rendering options changed since the last
+build; the others are taken from a cache in the state directory. --force
+renders them all. Entries are rendered and pages written in parallel, on as
+many goroutines as --jobs allows.
+
+Entry pages list the webmentions fetched by til webmention fetch, or with
+--webmentions or [site.webmention] fetch by the build itself; see til
+webmention.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if fetchMentions || a.cfg.Site.Webmention.Fetch {
+				ctx, end := logging.Start(cmd.Context(), "webmentions")
+				ms, err := a.fetchWebmentions(ctx)
+				end()
+				if err != nil {
+					fmt.Fprintf(cmd.ErrOrStderr(), "warning: %v; listing the webmentions fetched before\n", err)
+				}
+				opts.Mentions = ms
+			}
+			if err := a.siteOptions(&opts); err != nil {
+				return err
+			}
+			if err := a.hooks.Run(cmd.Context(), &hook.Payload{Event: hook.PreBuild, Out: opts.Out}); err != nil {
+				return err
+			}
+			s, err := site.Build(cmd.Context(), a.tree, opts)
+			if err != nil {
+				return err
+			}
+			printDangling(cmd.ErrOrStderr(), s.Dangling)
+			for _, err := range s.IncludeErrors {
+				fmt.Fprintf(cmd.ErrOrStderr(), "warning: %v\n", err)
+			}
+			for _, err := range s.TranslationErrors {
+				fmt.Fprintf(cmd.ErrOrStderr(), "warning: %v\n", err)
+			}
+			for _, err := range s.DiagramErrors {
+				fmt.Fprintf(cmd.ErrOrStderr(), "warning: %v; left for the browser to draw\n", err)
+			}
+			for _, err := range s.ImageErrors {
+				fmt.Fprintf(cmd.ErrOrStderr(), "warning: %v; published as SVG\n", err)
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "built %d entries (%d rendered) in %d categories into %s\n",
+				len(s.Pages), len(s.Rendered), len(s.Categories), opts.Out)
+			if s.Origin == "" {
+				fmt.Fprintln(cmd.ErrOrStderr(), "note: feeds and sitemap skipped; pass an absolute --base-url to generate them")
+			}
+			return nil
+		},
+	}
+	siteFlags(cmd.Flags(), &opts)
+	cmd.Flags().BoolVar(&opts.Force, "force", false, "render every entry anew instead of reusing those rendered by the last build")
+	cmd.Flags().BoolVar(&fetchMentions, "webmentions", false, "fetch the webmentions received before building")
+	return cmd
+}
+
+// siteFlags registers the flags shared by commands that build the site.
+func siteFlags(fs *pflag.FlagSet, opts *site.Options) {
+	fs.StringVarP(&opts.Out, "out", "o", "public", "output directory, relative to the notes root")
+	fs.StringVar(&opts.Title, "title", "TIL", "site title")
+	fs.StringVar(&opts.BaseURL, "base-url", "", "URL the site is published at, e.g. https://example.com/til/ (feeds and the sitemap need an absolute URL; default from [site] base_url, else /)")
+	fs.StringVar(&opts.Permalink, "permalink", "", "pattern of entry URLs, of :category, :slug, :year, :month and :day (default from [site] permalink, else "+site.DefaultPermalink+")")
+	fs.IntVar(&opts.FeedLimit, "feed-limit", 20, "number of entries per feed")
+	fs.IntVar(&opts.PerPage, "per-page", 0, "number of entries per page of category and month listings (default from [site] per_page, else 20)")
+	fs.BoolVar(&opts.GitDates, "git-dates", false, "date entries by their first and last commit")
+	fs.BoolVar(&opts.History, "history", false, "publish a page listing the commits of each entry")
+	fs.BoolVar(&opts.Changelog, "changelog", false, "publish a page summarizing the entries added and updated lately")
+	fs.IntVar(&opts.Related, "related", 5, "number of related entries listed on each entry page")
+	fs.BoolVar(&opts.CollectionPages, "collections", false, "render a page for each collection in the config file")
+	fs.BoolVar(&opts.Drafts, "drafts", false, "include draft entries")
+	fs.StringVar(&opts.Theme, "theme", "", "theme: "+strings.Join(site.Themes(), ", ")+" or a directory (default from [site] theme, else default)")
+	fs.StringVar(&opts.Highlight, "highlight", "", "chroma style for code blocks (default: the theme's)")
+	fs.BoolVar(&opts.LineNumbers, "line-numbers", false, "number the lines of code blocks")
+	fs.BoolVar(&opts.Math, "math", false, "typeset TeX between $ and $$")
+	fs.IntVarP(&opts.Jobs, "jobs", "j", 0, "entries rendered at once (default: number of CPUs)")
+}
+
+// siteOptions completes the options from the site flags: the output
+// directory is made absolute, relative to the notes root, and configured
+// defaults and collections are applied.
+func (a *app) siteOptions(opts *site.Options) error {
+	opts.GitDates = opts.GitDates || a.cfg.Git.Dates
+	opts.History = opts.History || a.cfg.Site.History
+	opts.Changelog = opts.Changelog || a.cfg.Site.Changelog
+	if opts.BaseURL == "" {
+		opts.BaseURL = a.cfg.Site.BaseURL
+	}
+	if opts.BaseURL == "" {
+		opts.BaseURL = "/"
+	}
+	if opts.Theme == "" {
+		opts.Theme = a.cfg.Site.Theme
+	}
+	if opts.Permalink == "" {
+		opts.Permalink = a.cfg.Site.Permalink
+	}
+	if opts.Highlight == "" {
+		opts.Highlight = a.cfg.Site.Highlight
+	}
+	opts.LineNumbers = opts.LineNumbers || a.cfg.Site.LineNumbers
+	if opts.PerPage <= 0 {
+		opts.PerPage = a.cfg.Site.PerPage
+	}
+	opts.Mermaid = a.cfg.Site.Mermaid
+	opts.Math = opts.Math || a.cfg.Site.Math
+	opts.KaTeX = a.cfg.Site.KaTeX
+	opts.OGImage = a.cfg.Site.OGImage
+	opts.Robots = a.cfg.Site.Robots
+	opts.Lang = a.lang()
+	opts.Webmention = a.cfg.Site.Webmention.Endpoint
+	if opts.Mentions == nil {
+		ms, err := webmention.LoadReceived(a.tree)
+		if err != nil {
+			return err
+		}
+		opts.Mentions = ms
+	}
+	if !filepath.IsAbs(opts.Out) {
+		opts.Out = filepath.Join(a.tree.Root, opts.Out)
+	}
+	if !opts.CollectionPages {
+		return nil
+	}
+	opts.Collections = map[string]query.Expr{}
+	for name := range a.cfg.Collections {
+		x, err := a.parseQuery("@"+name, time.Now())
+		if err != nil {
+			return err
+		}
+		opts.Collections[name] = x
+	}
+	return nil
+}
+
+// entryURLs returns the absolute site URL of each of entries by path, or
+// nil when [site] base_url is not an absolute URL.
+func (a *app) entryURLs(entries []*entry.Entry) func(path string) string {
+	s := site.New(entries, site.Options{BaseURL: a.cfg.Site.BaseURL, Permalink: a.cfg.Site.Permalink})
+	if s.Origin == "" {
+		return nil
+	}
+	return func(p string) string {
+		if pg := s.Page(p); pg != nil {
+			return s.AbsURL(pg.URL)
+		}
+		return ""
+	}
+}
+
+// parseQuery parses a query, resolving @name to the configured
+// collections.
+func (a *app) parseQuery(s string, now time.Time) (query.Expr, error) {
+	return query.Parser{Now: now, Collections: a.cfg.Collections, Lang: a.lang()}.Parse(s)
+}
+
+// lang returns the language of entries that name none.
+func (a *app) lang() string {
+	if a.cfg.Lang != "" {
+		return a.cfg.Lang
+	}
+	return "en"
+}
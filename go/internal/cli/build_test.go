@@ -0,0 +1,94 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBuild(t *testing.T) {
+	root := newTree(t, map[string]string{
+		"go/slices.md": "---\ntitle: Slices share arrays\ndate: 2024-06-01\n---\n\nSee [[nowhere]].\n",
+	})
+	out := mustRun(t, root, "build", "--title", "Notes")
+	if !strings.Contains(out, "built 1 entries (1 rendered) in 1 categories into ") || !strings.Contains(out, "[[nowhere]] names no entry") {
+		t.Errorf("build printed %q", out)
+	}
+	if got := readFile(t, root, "public/go/slices/index.html"); !strings.Contains(got, "Slices share arrays") {
+		t.Errorf("public/go/slices/index.html:\n%s", got)
+	}
+	if got := readFile(t, root, "public/index.html"); !strings.Contains(got, "<title>Notes</title>") {
+		t.Errorf("public/index.html:\n%s", got)
+	}
+	// Unchanged entries are not rendered again.
+	if out := mustRun(t, root, "build", "-o", "site"); !strings.Contains(out, "(0 rendered)") {
+		t.Errorf("second build printed %q", out)
+	}
+	if got := readFile(t, root, "site/index.html"); !strings.Contains(got, "/go/slices/") {
+		t.Errorf("site/index.html:\n%s", got)
+	}
+	if out := mustRun(t, root, "build", "--force"); !strings.Contains(out, "(1 rendered)") {
+		t.Errorf("build --force printed %q", out)
+	}
+}
+
+func TestBuildCollections(t *testing.T) {
+	root := newTree(t, map[string]string{
+		"go/slices.md":  "---\ntitle: Slices\ndate: 2024-06-01\ntags: [go]\n---\n",
+		"git/rebase.md": "---\ntitle: Rebase\ndate: 2024-05-01\ntags: [git]\n---\n",
+	})
+	writeConfig(t, "[collections]\ngolang = \"tag:go\"\nrecent = \"@golang OR tag:git\"\n")
+	if out := mustRun(t, root, "list", "--", "@golang"); !strings.Contains(out, "go/slices.md") || strings.Contains(out, "git/rebase.md") {
+		t.Errorf("list @golang =\n%s", out)
+	}
+	if out := mustRun(t, root, "search", "--", "@recent"); !strings.Contains(out, "go/slices.md") || !strings.Contains(out, "git/rebase.md") {
+		t.Errorf("search @recent =\n%s", out)
+	}
+	if _, err := run(t, root, "list", "--", "@nope"); err == nil {
+		t.Error("list @nope succeeded")
+	}
+	mustRun(t, root, "build", "--collections")
+	if got := readFile(t, root, "public/collections/golang/index.html"); !strings.Contains(got, "/go/slices/") || strings.Contains(got, "/git/rebase/") {
+		t.Errorf("public/collections/golang/index.html:\n%s", got)
+	}
+	if got := readFile(t, root, "public/collections/recent/index.html"); !strings.Contains(got, "/git/rebase/") {
+		t.Errorf("public/collections/recent/index.html:\n%s", got)
+	}
+}
+
+func TestBuildPerPage(t *testing.T) {
+	root := newTree(t, map[string]string{
+		"go/slices.md": "---\ntitle: Slices\ndate: 2024-06-01\n---\n",
+		"go/maps.md":   "---\ntitle: Maps\ndate: 2024-05-01\n---\n",
+		"go/iota.md":   "---\ntitle: Iota\ndate: 2024-05-02\n---\n",
+	})
+	writeConfig(t, "[site]\nper_page = 2\n")
+	mustRun(t, root, "build")
+	if got := readFile(t, root, "public/categories/go/page/2/index.html"); !strings.Contains(got, "/go/maps/") {
+		t.Errorf("public/categories/go/page/2/index.html:\n%s", got)
+	}
+	mustRun(t, root, "build", "--per-page", "1")
+	if got := readFile(t, root, "public/categories/go/page/3/index.html"); !strings.Contains(got, "Page 3 of 3") {
+		t.Errorf("public/categories/go/page/3/index.html:\n%s", got)
+	}
+	if got := readFile(t, root, "public/2024/05/index.html"); !strings.Contains(got, "/go/iota/") {
+		t.Errorf("public/2024/05/index.html:\n%s", got)
+	}
+}
+
+func TestBuildPermalink(t *testing.T) {
+	root := newTree(t, map[string]string{
+		"go/slices.md": "---\ntitle: Slices\ndate: 2024-06-01\n---\n",
+	})
+	writeConfig(t, "[site]\npermalink = \"/:year/:slug/\"\n")
+	mustRun(t, root, "build")
+	if got := readFile(t, root, "public/2024/slices/index.html"); !strings.Contains(got, "Slices") {
+		t.Errorf("public/2024/slices/index.html:\n%s", got)
+	}
+	mustRun(t, root, "build", "--permalink", "/til/:slug/")
+	if got := readFile(t, root, "public/2024/slices/index.html"); !strings.Contains(got, `url=/til/slices/"`) {
+		t.Errorf("public/2024/slices/index.html does not redirect:\n%s", got)
+	}
+	if _, err := run(t, root, "build", "--permalink", "/:title/"); err == nil || !strings.Contains(err.Error(), "unknown token :title") {
+		t.Errorf("build --permalink /:title/ = %v", err)
+	}
+}
@@ -0,0 +1,103 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/canhta/til/go/internal/notes"
+)
+
+// loadBudget is how long loading every entry through the cache should take,
+// for commands such as til list to start at once; til index doctor warns
+// beyond it.
+const loadBudget = 50 * time.Millisecond
+
+func newIndexRebuildCmd(a *app) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "rebuild",
+		Short: "Rebuild the cache of parsed entries",
+		Long: `Rebuild parses every entry anew into the entry cache, ` + notes.StateDir + `/` + notes.CacheFile + `.
+
+Commands load entries through the cache, parsing only the files whose size,
+modification time or content changed since it was written, so it needs no
+upkeep; rebuild it when til index doctor finds it corrupt.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			start := time.Now()
+			n, err := a.tree.RebuildCache()
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.ErrOrStderr(), "cached %d entries in %s\n", n, time.Since(start).Round(time.Millisecond))
+			return nil
+		},
+	}
+	return cmd
+}
+
+func newIndexDoctorCmd(a *app) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "doctor",
+		Short: "Check the cache of parsed entries against the files",
+		Long: `Doctor reads every entry file and compares it with the entry cache: entries
+changed since it was written, which the next command re-parses, entries
+missing from it or gone from the tree, and entries the cache would serve out
+of date because their file changed keeping its size and modification time.
+It then times loading every entry through the cache, which should take less
+than ` + loadBudget.String() + `.
+
+Doctor exits 1 when the cache is corrupt; til index rebuild repairs it.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			r, err := a.tree.CheckCache()
+			if err != nil {
+				return err
+			}
+			err = a.output(cmd, r, func(w io.Writer) error {
+				if r.Unreadable != "" {
+					fmt.Fprintf(w, "unreadable: %s\n", r.Unreadable)
+				}
+				fmt.Fprintf(w, "%d entries cached\n", r.Cached)
+				for _, l := range []struct {
+					label string
+					paths []string
+				}{
+					{"stale", r.Stale},
+					{"missing", r.Missing},
+					{"gone", r.Gone},
+					{"corrupt", r.Corrupt},
+				} {
+					switch n := len(l.paths); {
+					case n > 5:
+						fmt.Fprintf(w, "%s: %s and %d more\n", l.label, strings.Join(l.paths[:5], ", "), n-5)
+					case n > 0:
+						fmt.Fprintf(w, "%s: %s\n", l.label, strings.Join(l.paths, ", "))
+					}
+				}
+				if len(r.Corrupt) > 0 {
+					return nil
+				}
+				fmt.Fprintf(w, "loaded in %s, %d parsed anew", r.Load.Round(100*time.Microsecond), r.Parsed)
+				if r.Load > loadBudget {
+					fmt.Fprintf(w, ", over the %s budget", loadBudget)
+				}
+				fmt.Fprintln(w)
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			if len(r.Corrupt) > 0 {
+				return &exitError{code: 1, err: errors.New("the entry cache is corrupt; run til index rebuild")}
+			}
+			return nil
+		},
+	}
+	withJSON(cmd, "cache")
+	return cmd
+}
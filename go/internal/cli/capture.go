@@ -0,0 +1,192 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/canhta/til/go/internal/capture"
+	"github.com/canhta/til/go/internal/clipboard"
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+func newCaptureCmd(a *app) *cobra.Command {
+	var (
+		title    string
+		category string
+		tagList  []string
+		lang     string
+		fromClip bool
+		edit     bool
+		timeout  time.Duration
+	)
+	cmd := &cobra.Command{
+		Use:   "capture [url]",
+		Short: "Save text from stdin, the clipboard or a web page as a new entry",
+		Long: `Capture reads text from stdin, or from the clipboard with --clipboard, and
+saves it as a new entry dated today, printing its path.
+
+Text that looks like code or command output is wrapped in a code fence.
+Without --title, the title is taken from the first line of the text. When
+an entry with the same slug exists, a numeric suffix is added.
+
+Given a URL, capture fetches the page instead and saves its title, author
+and publication date in the frontmatter, with the URL as source, and an
+excerpt of its main text as the body. If the page cannot be fetched, the
+entry is still created with the URL, so links can be saved offline and
+filled in later.`,
+		Example: `  go test ./... 2>&1 | til capture --title "Go test output" --tag go
+  til capture --clipboard -c shell
+  til capture https://go.dev/blog/slices-intro -c go`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 1 {
+				rel, err := a.captureURL(cmd, args[0], category, title, tagList, timeout)
+				if err != nil {
+					return err
+				}
+				return a.finishCapture(cmd, rel, edit)
+			}
+			var text string
+			if fromClip {
+				var err error
+				if text, err = clipboard.Paste(); err != nil {
+					return err
+				}
+			} else {
+				b, err := io.ReadAll(cmd.InOrStdin())
+				if err != nil {
+					return err
+				}
+				text = string(b)
+			}
+			if strings.TrimSpace(text) == "" {
+				return fmt.Errorf("nothing to capture")
+			}
+			if title == "" {
+				title = capture.Title(text)
+			}
+			rel, err := a.writeCapture(category, title, tagList, capture.Body(text, lang), nil)
+			if err != nil {
+				return err
+			}
+			return a.finishCapture(cmd, rel, edit)
+		},
+	}
+	cmd.Flags().StringVar(&title, "title", "", "entry title (default: the first line of the text)")
+	cmd.Flags().StringVarP(&category, "category", "c", "inbox", "category to save the entry in")
+	cmd.Flags().StringSliceVarP(&tagList, "tag", "t", nil, "tag the entry (repeatable)")
+	cmd.Flags().StringVar(&lang, "lang", "", "always fence the text as code in this language")
+	cmd.Flags().BoolVar(&fromClip, "clipboard", false, "read the text from the clipboard instead of stdin")
+	cmd.Flags().BoolVar(&edit, "edit", false, "open the entry in $EDITOR after saving it")
+	cmd.Flags().DurationVar(&timeout, "timeout", 15*time.Second, "give up fetching a URL after this long")
+	a.commitFlag(cmd)
+	return cmd
+}
+
+// maxPage is the most of a web page capture reads.
+const maxPage = 5 << 20
+
+// captureURL saves the page at rawURL as a new entry and returns its path.
+func (a *app) captureURL(cmd *cobra.Command, rawURL, category, title string, tagList []string, timeout time.Duration) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return "", fmt.Errorf("capture: %q is not an http or https URL", rawURL)
+	}
+	page, err := capture.Fetch(cmd.Context(), rawURL, capture.FetchOptions{Timeout: timeout, MaxBytes: maxPage})
+	if err != nil {
+		if cmd.Context().Err() != nil {
+			return "", err
+		}
+		fmt.Fprintf(cmd.ErrOrStderr(), "til: %v; saving the link only\n", err)
+		page = &capture.Page{URL: rawURL}
+	}
+	if title == "" {
+		title = page.Title
+	}
+	if title == "" {
+		title = capture.FallbackTitle(page.URL)
+	}
+	body := quoteExcerpt(page.Excerpt) + fmt.Sprintf("Source: <%s>", page.URL)
+	return a.writeCapture(category, title, tagList, body, func(f *entry.Front) error {
+		if err := f.Set("source", page.URL); err != nil {
+			return err
+		}
+		if page.Author != "" {
+			if err := f.Set("author", page.Author); err != nil {
+				return err
+			}
+		}
+		if !page.Published.IsZero() {
+			d := page.Published
+			return f.Set("published", time.Date(d.Year(), d.Month(), d.Day(), 0, 0, 0, 0, time.UTC))
+		}
+		return nil
+	})
+}
+
+// quoteExcerpt formats the excerpt of a page as a block quote followed by a
+// blank line, or returns "" if there is none.
+func quoteExcerpt(excerpt []string) string {
+	var b strings.Builder
+	for i, block := range excerpt {
+		if i > 0 {
+			b.WriteString(">\n")
+		}
+		for _, line := range strings.Split(block, "\n") {
+			b.WriteString(strings.TrimRight("> "+line, " ") + "\n")
+		}
+	}
+	if b.Len() > 0 {
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// finishCapture reports a captured entry, opens it if asked and commits it.
+func (a *app) finishCapture(cmd *cobra.Command, rel string, edit bool) error {
+	fmt.Fprintln(cmd.OutOrStdout(), rel)
+	if edit {
+		if err := a.openEditor(rel); err != nil {
+			return err
+		}
+	}
+	return a.commitEntry(cmd, rel, "add")
+}
+
+// writeCapture scaffolds a new entry in category with body appended and
+// returns its path; meta, if not nil, adds frontmatter fields. The slug
+// derived from title gets a numeric suffix if it is taken.
+func (a *app) writeCapture(category, title string, tagList []string, body string, meta func(*entry.Front) error) (string, error) {
+	base := entry.Slugify(title)
+	if base == "" {
+		base = "capture"
+	}
+	slug := base
+	for i := 2; ; i++ {
+		taken, err := a.tree.Exists(path.Join(category, entry.FileName(slug)))
+		if err != nil {
+			return "", err
+		}
+		if !taken {
+			break
+		}
+		slug = fmt.Sprintf("%s-%d", base, i)
+	}
+	rel, content, err := a.scaffold(category, title, slug, tagList, nil)
+	if err != nil {
+		return "", err
+	}
+	content = append([]byte(strings.TrimRight(string(content), "\n")), "\n\n"+body+"\n"...)
+	if meta != nil {
+		if content, err = entry.Rewrite(content, meta); err != nil {
+			return "", err
+		}
+	}
+	return rel, a.tree.Create(rel, content)
+}
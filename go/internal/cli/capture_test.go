@@ -0,0 +1,103 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// runStdin is run with stdin reading from in.
+func runStdin(t *testing.T, dir, in string, args ...string) (string, error) {
+	t.Helper()
+	cmd := newRootCmd(&app{})
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(&out)
+	cmd.SetIn(strings.NewReader(in))
+	cmd.SetArgs(append([]string{"-C", dir}, args...))
+	err := cmd.ExecuteContext(context.Background())
+	return out.String(), err
+}
+
+func TestCaptureStdin(t *testing.T) {
+	root := newTree(t, nil)
+	out, err := runStdin(t, root, "$ go test ./...\nok  \tx\t0.01s\n", "capture", "-t", "go")
+	if err != nil {
+		t.Fatalf("capture: %v\n%s", err, out)
+	}
+	rel := strings.TrimSpace(out)
+	if !strings.HasPrefix(rel, "inbox/") {
+		t.Fatalf("capture printed %q", out)
+	}
+	got := readFile(t, root, rel)
+	for _, want := range []string{"title: go test ./...", "tags: [go]", "\n```\n$ go test ./...\nok  \tx\t0.01s\n```\n"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("%s lacks %q:\n%s", rel, want, got)
+		}
+	}
+
+	// The same title again gets a numeric suffix.
+	out, err = runStdin(t, root, "Prose about tests.\n", "capture", "--title", "go test ./...", "-c", "go")
+	if err != nil {
+		t.Fatalf("capture: %v\n%s", err, out)
+	}
+	out2, err := runStdin(t, root, "More prose.\n", "capture", "--title", "go test ./...", "-c", "go")
+	if err != nil {
+		t.Fatalf("capture: %v\n%s", err, out2)
+	}
+	first, second := strings.TrimSpace(out), strings.TrimSpace(out2)
+	if !strings.HasPrefix(first, "go/") || second == first || !strings.Contains(second, "2") {
+		t.Errorf("captures saved to %s and %s", first, second)
+	}
+	if got := readFile(t, root, first); !strings.HasSuffix(got, "\n\nProse about tests.\n") {
+		t.Errorf("%s:\n%s", first, got)
+	}
+
+	if _, err := runStdin(t, root, " \n\n", "capture"); err == nil || !strings.Contains(err.Error(), "nothing to capture") {
+		t.Errorf("capture of blank text = %v", err)
+	}
+}
+
+func TestCaptureURL(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<html><head><title>Slices intro</title><meta name="author" content="Rob">
+<meta name="date" content="2024-06-01"></head><body><article>
+<p>A slice is a descriptor of an array segment, with a length and a capacity.</p>
+</article></body></html>`))
+	}))
+	defer srv.Close()
+	root := newTree(t, nil)
+	out := mustRun(t, root, "capture", srv.URL+"/blog/slices", "-c", "go")
+	rel := strings.TrimSpace(out)
+	got := readFile(t, root, rel)
+	for _, want := range []string{
+		"title: Slices intro",
+		"source: " + srv.URL + "/blog/slices",
+		"author: Rob",
+		"published: 2024-06-01",
+		"\n> A slice is a descriptor of an array segment, with a length and a capacity.\n\nSource: <" + srv.URL + "/blog/slices>\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("%s lacks %q:\n%s", rel, want, got)
+		}
+	}
+
+	// An unreachable page is saved as a link.
+	srv.Close()
+	out = mustRun(t, root, "capture", srv.URL+"/offline", "-c", "go")
+	if !strings.Contains(out, "saving the link only") {
+		t.Errorf("capture of an unreachable page printed %q", out)
+	}
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	got = readFile(t, root, lines[len(lines)-1])
+	if !strings.Contains(got, "Source: <"+srv.URL+"/offline>") || !strings.Contains(got, "title: 127.0.0.1") {
+		t.Errorf("link-only capture:\n%s", got)
+	}
+
+	if _, err := run(t, root, "capture", "ftp://example.com/x"); err == nil {
+		t.Error("capture of an ftp URL succeeded")
+	}
+}
@@ -0,0 +1,109 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/canhta/til/go/internal/changelog"
+	"github.com/canhta/til/go/internal/query"
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+func newChangelogCmd(a *app) *cobra.Command {
+	var (
+		since    string
+		months   int
+		minWords int
+	)
+	cmd := &cobra.Command{
+		Use:   "changelog",
+		Short: "Summarize the entries added and updated lately",
+		Long: `Changelog lists, as markdown, the public entries added and substantially
+updated in each of the last --months months, newest first, or in one
+section since the date given with --since, for catching up on what's new.
+
+An entry is added on its date, or when it was first committed if later. It
+counts as updated when its updated frontmatter falls in the month, or when
+its body gained or lost at least --min-words words over the month, as told
+by git history. Entries link to their pages when [site] base_url is an
+absolute URL. til build publishes the same summary as a page with --changelog.`,
+		Example: `  til changelog
+  til changelog --since 2026-09-01
+  til changelog --months 1 --json`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			now := time.Now()
+			var periods []changelog.Period
+			if since != "" {
+				from, err := query.ParseDate(since, now)
+				if err != nil {
+					return fmt.Errorf("--since: %w", err)
+				}
+				periods = []changelog.Period{{From: from, To: now}}
+			} else {
+				if months < 1 {
+					return fmt.Errorf("--months must be at least 1")
+				}
+				periods = changelog.Months(now, months)
+			}
+			all, err := a.tree.Entries()
+			if err != nil {
+				return err
+			}
+			entries := entry.Public(all)
+			if err := changelog.Compose(cmd.Context(), a.tree, entries, periods, changelog.Options{MinWords: minWords, Now: now}); err != nil {
+				return err
+			}
+			url := a.entryURLs(entries)
+			return a.output(cmd, periods, func(w io.Writer) error {
+				for i, p := range periods {
+					if i > 0 {
+						fmt.Fprintln(w)
+					}
+					if since != "" {
+						fmt.Fprintf(w, "## Since %s\n", p.From.Format(entry.DateLayout))
+					} else {
+						fmt.Fprintf(w, "## %s\n", p.From.Format("January 2006"))
+					}
+					if len(p.Changes) == 0 {
+						fmt.Fprintln(w, "\nNothing new.")
+						continue
+					}
+					printChanges(w, "Added", p.Added(), url)
+					printChanges(w, "Updated", p.Updated(), url)
+				}
+				return nil
+			})
+		},
+	}
+	withJSON(cmd, "changelog")
+	cmd.Flags().StringVar(&since, "since", "", "summarize the changes since this date (YYYY-MM-DD, YYYY-MM, 7d, 2w, 3m, 1y)")
+	cmd.Flags().IntVar(&months, "months", 3, "the number of months to summarize")
+	cmd.Flags().IntVar(&minWords, "min-words", changelog.DefaultMinWords, "the words an update must change to be listed")
+	return cmd
+}
+
+// printChanges prints the section heading of changes and a list item for
+// each, linked to the entry's URL when url has one.
+func printChanges(w io.Writer, heading string, changes []changelog.Change, url func(string) string) {
+	if len(changes) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "\n### %s\n\n", heading)
+	for _, c := range changes {
+		dest := c.Path
+		if url != nil {
+			if u := url(c.Path); u != "" {
+				dest = u
+			}
+		}
+		fmt.Fprintf(w, "- [%s](%s), %s", c.Title, dest, c.Date.Format(entry.DateLayout))
+		if c.Kind == changelog.Updated && c.Words > 0 {
+			fmt.Fprintf(w, ", %d words changed", c.Words)
+		}
+		fmt.Fprintln(w)
+	}
+}
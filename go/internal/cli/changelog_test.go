@@ -0,0 +1,48 @@
+package cli
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestChangelog(t *testing.T) {
+	now := time.Now()
+	today := now.Format(time.DateOnly)
+	root := newTree(t, map[string]string{
+		"go/slices.md": "---\ntitle: Slices\ndate: " + today + "\n---\n\nSlices.\n",
+		"go/maps.md":   "---\ntitle: Maps\ndate: 2020-01-01\nupdated: " + today + "\n---\n\nMaps.\n",
+		"go/draft.md":  "---\ntitle: Draft\ndate: " + today + "\ndraft: true\n---\n\nDraft.\n",
+	})
+	out := mustRun(t, root, "changelog", "--months", "2")
+	want := "## " + now.Format("January 2006") + "\n\n### Added\n\n- [Slices](go/slices.md), " + today + "\n\n### Updated\n\n- [Maps](go/maps.md), " + today + "\n\n## " +
+		time.Date(now.Year(), now.Month()-1, 1, 0, 0, 0, 0, time.Local).Format("January 2006") + "\n\nNothing new.\n"
+	if out != want {
+		t.Errorf("changelog =\n%s\nwant\n%s", out, want)
+	}
+
+	writeConfig(t, "[site]\nbase_url = \"https://til.example/\"\n")
+	if out := mustRun(t, root, "changelog", "--since", "7d"); !strings.HasPrefix(out, "## Since ") || !strings.Contains(out, "- [Slices](https://til.example/go/slices/), ") {
+		t.Errorf("changelog --since 7d =\n%s", out)
+	}
+
+	var doc struct {
+		Kind string
+		Data []struct {
+			Changes []struct{ Kind, Path string }
+		}
+	}
+	if err := json.Unmarshal([]byte(mustRun(t, root, "changelog", "--months", "1", "--json")), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.Kind != "changelog" || len(doc.Data) != 1 || len(doc.Data[0].Changes) != 2 {
+		t.Errorf("changelog --json = %+v", doc)
+	}
+
+	for _, args := range [][]string{{"--months", "0"}, {"--since", "someday"}} {
+		if _, err := run(t, root, append([]string{"changelog"}, args...)...); err == nil {
+			t.Errorf("changelog %q succeeded", args)
+		}
+	}
+}
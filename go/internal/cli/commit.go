@@ -0,0 +1,72 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+
+	"github.com/canhta/til/go/internal/git"
+)
+
+// commitEntry commits the entry at rel, with the files at the relative
+// paths in also, with the message "til: <verb> <rel>" when [git] commit is
+// enabled. Situations where committing would be
+// unsafe, such as an unfinished merge, are reported and skipped rather than
+// failing the command: the file itself was saved either way.
+func (a *app) commitEntry(cmd *cobra.Command, rel, verb string, also ...string) error {
+	if a.noCommit {
+		return nil
+	}
+	return a.commit(cmd.Context(), cmd.ErrOrStderr(), rel, verb, also...)
+}
+
+// commit is commitEntry for callers without a command, such as the API
+// server, reporting to stderr.
+func (a *app) commit(ctx context.Context, stderr io.Writer, rel, verb string, also ...string) error {
+	if !a.cfg.Git.Commit {
+		return nil
+	}
+	repo, err := git.Open(ctx, a.tree.Root)
+	if errors.Is(err, git.ErrNotRepo) {
+		fmt.Fprintf(stderr, "not committing %s: %s is not in a git repository\n", rel, a.tree.Root)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	files := []string{a.tree.Abs(rel)}
+	for _, p := range also {
+		files = append(files, a.tree.Abs(p))
+	}
+	if busy, err := repo.Busy(ctx); err != nil {
+		return err
+	} else if busy != "" {
+		fmt.Fprintf(stderr, "not committing %s: %s\n", rel, busy)
+		return nil
+	}
+	changed, err := repo.Changed(ctx, files...)
+	if err != nil || !changed {
+		return err
+	}
+	msg := fmt.Sprintf("til: %s %s", verb, rel)
+	if err := repo.CommitFiles(ctx, msg, files...); err != nil {
+		return err
+	}
+	fmt.Fprintf(stderr, "committed %q\n", msg)
+	if !a.cfg.Git.Push {
+		return nil
+	}
+	if err := repo.Push(ctx); err != nil {
+		return fmt.Errorf("committed, but %w", err)
+	}
+	return nil
+}
+
+// commitFlag registers --no-commit, which turns off [git] commit for one
+// invocation.
+func (a *app) commitFlag(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&a.noCommit, "no-commit", false, "do not commit the entry even if [git] commit is enabled")
+}
@@ -0,0 +1,72 @@
+package cli
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+// initGit makes root a git repository with a first commit, isolated from
+// the user's git config.
+func initGit(t *testing.T, root string) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	for _, v := range []string{"GIT_AUTHOR", "GIT_COMMITTER"} {
+		t.Setenv(v+"_NAME", "Ann")
+		t.Setenv(v+"_EMAIL", "ann@example.com")
+	}
+	runGit(t, root, "init", "--quiet")
+	runGit(t, root, "add", ".")
+	runGit(t, root, "commit", "--quiet", "--allow-empty", "-m", "init")
+}
+
+func runGit(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+	}
+	return string(out)
+}
+
+func TestCommit(t *testing.T) {
+	root := newTree(t, map[string]string{"go/old.md": "# Old\n"})
+	initGit(t, root)
+	writeConfig(t, "[git]\ncommit = true\n")
+	writeFile(t, root, "go/dirty.md", "# Uncommitted\n")
+
+	out := mustRun(t, root, "new", "go", "Slices share arrays", "--no-edit")
+	if !strings.Contains(out, `committed "til: add go/slices_share_arrays.md"`) {
+		t.Errorf("new:\n%s", out)
+	}
+	if got := runGit(t, root, "show", "--name-only", "--format=%s", "HEAD"); got != "til: add go/slices_share_arrays.md\n\ngo/slices_share_arrays.md\n" {
+		t.Errorf("HEAD = %q", got)
+	}
+	if got := runGit(t, root, "status", "--porcelain"); got != "?? go/dirty.md\n" {
+		t.Errorf("status = %q, want the other changes left alone", got)
+	}
+
+	mustRun(t, root, "new", "go", "Maps", "--no-edit", "--no-commit")
+	if got := runGit(t, root, "status", "--porcelain"); !strings.Contains(got, "?? go/maps.md\n") {
+		t.Errorf("status = %q, want go/maps.md uncommitted", got)
+	}
+
+	writeConfig(t, "[git]\ncommit = false\n")
+	mustRun(t, root, "new", "go", "Channels", "--no-edit")
+	if got := runGit(t, root, "log", "-1", "--format=%s"); got != "til: add go/slices_share_arrays.md\n" {
+		t.Errorf("HEAD = %q, want no commit when [git] commit is off", got)
+	}
+}
+
+func TestCommitOutsideGit(t *testing.T) {
+	root := newTree(t, nil)
+	writeConfig(t, "[git]\ncommit = true\n")
+	if out := mustRun(t, root, "new", "go", "Maps", "--no-edit"); !strings.Contains(out, "not committing go/maps.md: ") {
+		t.Errorf("new:\n%s", out)
+	}
+}
@@ -0,0 +1,193 @@
+package cli
+
+import (
+	"maps"
+	"os"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/canhta/til/go/internal/config"
+	"github.com/canhta/til/go/internal/search"
+)
+
+// Kinds of values completed for arguments and flags.
+const (
+	completeEntry    = "entry"
+	completeCategory = "category"
+	completeTag      = "tag"
+)
+
+// argKinds maps argument placeholders in usage lines to what is completed
+// for them.
+var argKinds = map[string]string{
+	"entry":    completeEntry,
+	"category": completeCategory,
+	"tag":      completeTag,
+	"old":      completeTag,
+	"into":     completeTag,
+}
+
+// registerCompletions makes cmd and its subcommands complete entries,
+// categories and tags for the arguments their usage lines name so, and for
+// --tag and --category flags.
+func (a *app) registerCompletions(cmd *cobra.Command) {
+	for _, c := range cmd.Commands() {
+		a.registerCompletions(c)
+	}
+	if kinds, rest := usageKinds(cmd.Use); cmd.ValidArgsFunction == nil && (rest != "" || slices.ContainsFunc(kinds, func(k string) bool { return k != "" })) {
+		cmd.ValidArgsFunction = func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			kind := rest
+			if i := len(args); i < len(kinds) {
+				kind = kinds[i]
+			}
+			if kind == "" {
+				return nil, cobra.ShellCompDirectiveDefault
+			}
+			return a.complete(cmd, kind, toComplete)
+		}
+	}
+	for flag, kind := range map[string]string{"tag": completeTag, "category": completeCategory} {
+		if cmd.Flags().Lookup(flag) == nil {
+			continue
+		}
+		_ = cmd.RegisterFlagCompletionFunc(flag, func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return a.complete(cmd, kind, toComplete)
+		})
+	}
+}
+
+// completeProfiles completes --profile with the profiles of the config
+// file.
+func completeProfiles(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	file, err := config.Path()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+	names, err := config.Profiles(file)
+	if err != nil {
+		cobra.CompDebugln(err.Error(), true)
+		return nil, cobra.ShellCompDirectiveError
+	}
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeWorkspaces completes workspace names from the config file.
+func (a *app) completeWorkspaces(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	cfg, err := config.Load(a.profile)
+	if err != nil {
+		cobra.CompDebugln(err.Error(), true)
+		return nil, cobra.ShellCompDirectiveError
+	}
+	var names []string
+	for _, name := range slices.Sorted(maps.Keys(cfg.Workspaces)) {
+		names = append(names, name+"\t"+cfg.Workspaces[name])
+	}
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
+
+// usageKinds returns what is completed for each positional argument of a
+// usage line, "" where nothing is, and for the arguments after those. From
+// an argument that repeats on, all are completed as it is, since where the
+// following ones start is unknown.
+func usageKinds(use string) (kinds []string, rest string) {
+	for _, f := range strings.Fields(use)[1:] {
+		f = strings.Trim(f, "[]")
+		kind := argKinds[strings.Trim(strings.TrimSuffix(f, "..."), "<>")]
+		if strings.HasSuffix(f, "...") {
+			return kinds, kind
+		}
+		kinds = append(kinds, kind)
+	}
+	return kinds, ""
+}
+
+// complete returns the values of kind starting with toComplete. A tag is
+// completed after the commas of a list of them too.
+func (a *app) complete(cmd *cobra.Command, kind, toComplete string) ([]string, cobra.ShellCompDirective) {
+	// The root's PersistentPreRunE is skipped for completion.
+	if a.tree == nil {
+		if err := a.init(); err != nil {
+			cobra.CompDebugln(err.Error(), true)
+			return nil, cobra.ShellCompDirectiveError
+		}
+	}
+	entries, err := a.summaries(cmd)
+	if err != nil {
+		cobra.CompDebugln(err.Error(), true)
+		return nil, cobra.ShellCompDirectiveError
+	}
+	var out []string
+	seen := map[string]bool{}
+	add := func(v, desc string) {
+		if seen[v] {
+			return
+		}
+		seen[v] = true
+		if desc != "" {
+			v += "\t" + desc
+		}
+		out = append(out, v)
+	}
+	switch kind {
+	case completeEntry:
+		for _, e := range entries {
+			id := strings.TrimSuffix(e.Path, ".md")
+			if stem := path.Base(id); strings.HasPrefix(id, toComplete) {
+				add(id, e.Title)
+			} else if strings.HasPrefix(stem, toComplete) {
+				add(stem, e.Title)
+			}
+		}
+	case completeCategory:
+		for _, e := range entries {
+			if c, _, ok := strings.Cut(e.Path, "/"); ok && strings.HasPrefix(c, toComplete) {
+				add(c, "")
+			}
+		}
+	case completeTag:
+		var before string
+		var done []string
+		if i := strings.LastIndex(toComplete, ","); i >= 0 {
+			before, toComplete = toComplete[:i+1], toComplete[i+1:]
+			done = strings.Split(before, ",")
+		}
+		for _, e := range entries {
+			for _, t := range e.Tags {
+				if strings.HasPrefix(t, toComplete) && !slices.Contains(done, t) {
+					add(before+t, "")
+				}
+			}
+		}
+		slices.Sort(out)
+	}
+	return out, cobra.ShellCompDirectiveNoFileComp
+}
+
+// summaries returns the entries as the search index holds them, after an
+// incremental sync that reads only the files changed since the last one,
+// so completing stays fast in large trees. Without an index, which
+// completion does not create, the entries are read.
+func (a *app) summaries(cmd *cobra.Command) ([]search.Summary, error) {
+	if _, err := os.Stat(a.tree.StatePath(search.File)); err == nil {
+		ix, err := search.Open(a.tree)
+		if err == nil {
+			defer ix.Close()
+			if _, err = ix.Sync(cmd.Context()); err == nil {
+				return ix.Summaries(cmd.Context())
+			}
+		}
+		cobra.CompDebugln(err.Error(), true)
+	}
+	entries, err := a.tree.Entries()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]search.Summary, len(entries))
+	for i, e := range entries {
+		out[i] = search.Summary{Path: e.Path, Title: e.Meta.Title, Tags: e.Meta.Tags}
+	}
+	return out, nil
+}
@@ -0,0 +1,75 @@
+package cli
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestUsageKinds(t *testing.T) {
+	tests := []struct {
+		use   string
+		kinds []string
+		rest  string
+	}{
+		{"show <entry>", []string{"entry"}, ""},
+		{"new <category> <title>", []string{"category", ""}, ""},
+		{"grep <pattern> [entry...]", []string{""}, "entry"},
+		{"restore [entry...]", nil, "entry"},
+		{"merge <tag>... <into>", nil, "tag"},
+		{"tags", nil, ""},
+	}
+	for _, tt := range tests {
+		kinds, rest := usageKinds(tt.use)
+		if !slices.Equal(kinds, tt.kinds) || rest != tt.rest {
+			t.Errorf("usageKinds(%q) = %q, %q, want %q, %q", tt.use, kinds, rest, tt.kinds, tt.rest)
+		}
+	}
+}
+
+// complete returns what the shell is offered for args in root, less the
+// directive. The shell passes on -C as typed, after __complete.
+func complete(t *testing.T, root string, args ...string) []string {
+	t.Helper()
+	out := mustRun(t, root, append([]string{"__complete", "-C", root}, args...)...)
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	i := slices.IndexFunc(lines, func(l string) bool { return strings.HasPrefix(l, ":") })
+	if i < 0 {
+		t.Fatalf("__complete %s =\n%s", strings.Join(args, " "), out)
+	}
+	return lines[:i]
+}
+
+func TestComplete(t *testing.T) {
+	root := newTree(t, map[string]string{
+		"go/slices.md":  "---\ntitle: Slices\ntags: [go, slices]\n---\n",
+		"go/maps.md":    "---\ntitle: Maps\ntags: [go]\n---\n",
+		"git/rebase.md": "---\ntitle: Rebase\ntags: [git]\n---\n",
+	})
+	tests := []struct {
+		args []string
+		want []string
+	}{
+		{[]string{"show", "go/"}, []string{"go/maps\tMaps", "go/slices\tSlices"}},
+		{[]string{"show", "re"}, []string{"rebase\tRebase"}},
+		{[]string{"show", "go/maps", ""}, nil},
+		{[]string{"new", "g"}, []string{"git", "go"}},
+		{[]string{"new", "go", ""}, nil},
+		{[]string{"tags", "merge", "go", "g"}, []string{"git", "go"}},
+		{[]string{"list", "--tag", "s"}, []string{"slices"}},
+		{[]string{"list", "--tag", "go,"}, []string{"go,git", "go,slices"}},
+		{[]string{"list", "--category", ""}, []string{"git", "go"}},
+	}
+	for _, tt := range tests {
+		if got := complete(t, root, tt.args...); !slices.Equal(got, tt.want) {
+			t.Errorf("complete %q = %q, want %q", tt.args, got, tt.want)
+		}
+	}
+
+	// From the search index once there is one, kept in sync with edits.
+	mustRun(t, root, "search", "slices")
+	writeFile(t, root, "go/generics.md", "---\ntitle: Generics\ntags: [go, generics]\n---\n")
+	if got := complete(t, root, "list", "--tag", "ge"); !slices.Equal(got, []string{"generics"}) {
+		t.Errorf("complete after an edit = %q", got)
+	}
+}
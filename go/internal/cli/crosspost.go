@@ -0,0 +1,167 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/canhta/til/go/internal/crosspost"
+	"github.com/canhta/til/go/internal/links"
+	"github.com/canhta/til/go/internal/site"
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+func newCrosspostCmd(a *app) *cobra.Command {
+	var (
+		to     []string
+		dryRun bool
+		fresh  bool
+	)
+	cmd := &cobra.Command{
+		Use:   "crosspost <entry>",
+		Short: "Post an entry to dev.to or Hashnode",
+		Long: `Crosspost publishes an entry as an article on dev.to or Hashnode, with its
+title, tags and an excerpt as description, and its site page as the
+canonical URL. Links to other entries and their assets are made absolute
+site URLs, which needs [site] base_url.
+
+The article's ID is recorded under crosspost in the entry's frontmatter,
+so posting again updates the article. Drafts are posted as dev.to drafts.
+
+API keys are read from $DEVTO_API_KEY and $HASHNODE_TOKEN, or the
+variables named in [crosspost.devto] and [crosspost.hashnode], which also
+sets the Hashnode publication ID.`,
+		Example: `  til crosspost go/slices --to devto
+  til crosspost go/slices --to devto --to hashnode`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var targets []crosspost.Target
+			for _, name := range to {
+				t, err := a.crosspostTarget(name, dryRun)
+				if err != nil {
+					return err
+				}
+				targets = append(targets, t)
+			}
+			e, err := a.resolve(args[0])
+			if err != nil {
+				return err
+			}
+			if e.Meta.Private {
+				return fmt.Errorf("%s is private", e.Path)
+			}
+			entries, err := a.tree.Entries()
+			if err != nil {
+				return err
+			}
+			// Links resolve to the pages til build publishes.
+			published := slices.DeleteFunc(slices.Clone(entries), func(o *entry.Entry) bool {
+				return o.Meta.Private || o.Meta.Draft && o.Path != e.Path
+			})
+			s := site.New(published, site.Options{BaseURL: a.cfg.Site.BaseURL, Permalink: a.cfg.Site.Permalink})
+			if s.Origin == "" {
+				return errors.New("[site] base_url must be an absolute URL to link back to the site")
+			}
+			if err := s.LoadAssets(a.tree); err != nil {
+				return err
+			}
+			art, unresolved := crosspost.Prepare(e, s, links.NewIndex(entries))
+			for _, u := range unresolved {
+				fmt.Fprintf(cmd.ErrOrStderr(), "warning: %s: not resolved: %s\n", e.Path, u)
+			}
+			if dryRun {
+				return a.output(cmd, art, func(w io.Writer) error {
+					fmt.Fprintf(w, "title: %s\ntags: %s\ncanonical: %s\npublished: %t\n\n%s",
+						art.Title, strings.Join(art.Tags, ", "), art.Canonical, art.Published, art.Markdown)
+					return nil
+				})
+			}
+
+			remotes, err := crosspost.Remotes(e)
+			if err != nil {
+				return fmt.Errorf("%s: %w", e.Path, err)
+			}
+			var posted, failed int
+			for _, t := range targets {
+				var prev *crosspost.Remote
+				if r, ok := remotes[t.Name()]; ok && !fresh {
+					prev = &r
+				}
+				r, err := t.Post(cmd.Context(), art, prev)
+				if errors.Is(err, crosspost.ErrNotFound) && prev != nil {
+					err = fmt.Errorf("article %s: %w; pass --new to post it again", prev.ID, err)
+				}
+				if err != nil {
+					fmt.Fprintf(cmd.ErrOrStderr(), "%s: %v\n", t.Name(), err)
+					failed++
+					continue
+				}
+				data, err := a.tree.Read(e.Path)
+				if err != nil {
+					return err
+				}
+				if data, err = crosspost.Record(data, t.Name(), r); err != nil {
+					return fmt.Errorf("%s: %w", e.Path, err)
+				}
+				if err := a.tree.Write(e.Path, data); err != nil {
+					return err
+				}
+				verb := "posted"
+				if prev != nil {
+					verb = "updated"
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "%s %s on %s: %s\n", verb, e.Path, t.Name(), r.URL)
+				posted++
+			}
+			if posted > 0 {
+				if err := a.commitEntry(cmd, e.Path, "crosspost"); err != nil {
+					return err
+				}
+			}
+			if failed > 0 {
+				return fmt.Errorf("%s failed", plural(failed, "target"))
+			}
+			return nil
+		},
+	}
+	withJSON(cmd, "article")
+	cmd.Flags().StringSliceVar(&to, "to", nil, "platform to post to: devto or hashnode (repeatable)")
+	cmd.Flags().BoolVarP(&dryRun, "dry-run", "n", false, "print the article instead of posting it")
+	cmd.Flags().BoolVar(&fresh, "new", false, "post a new article even if one is recorded")
+	_ = cmd.MarkFlagRequired("to")
+	a.commitFlag(cmd)
+	return cmd
+}
+
+// crosspostTarget returns the named platform, with its API key unless
+// dryRun.
+func (a *app) crosspostTarget(name string, dryRun bool) (crosspost.Target, error) {
+	cfg := a.cfg.Crosspost
+	secret := func(env, def string) (string, error) {
+		if env == "" {
+			env = def
+		}
+		v := os.Getenv(env)
+		if v == "" && !dryRun {
+			return "", fmt.Errorf("%s: $%s is not set", name, env)
+		}
+		return v, nil
+	}
+	switch name {
+	case "devto":
+		key, err := secret(cfg.DevTo.KeyEnv, "DEVTO_API_KEY")
+		return crosspost.DevTo{Key: key, API: cfg.DevTo.API}, err
+	case "hashnode":
+		if cfg.Hashnode.Publication == "" {
+			return nil, errors.New("hashnode: set publication in [crosspost.hashnode]")
+		}
+		token, err := secret(cfg.Hashnode.TokenEnv, "HASHNODE_TOKEN")
+		return crosspost.Hashnode{Token: token, Publication: cfg.Hashnode.Publication, API: cfg.Hashnode.API}, err
+	}
+	return nil, fmt.Errorf("unknown platform %q: want devto or hashnode", name)
+}
@@ -0,0 +1,65 @@
+package cli
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCrosspost(t *testing.T) {
+	var requests []string
+	status := http.StatusOK
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		w.WriteHeader(status)
+		w.Write([]byte(`{"id":17,"url":"https://dev.to/me/slices"}`))
+	}))
+	defer srv.Close()
+	root := newTree(t, map[string]string{
+		"go/slices.md": "---\ntitle: Slices\ntags: [go]\n---\n\nSee [maps](maps.md) and [[nowhere]].\n",
+		"go/maps.md":   "---\ntitle: Maps\n---\n\nMaps.\n",
+		"go/secret.md": "---\ntitle: Secret\nprivate: true\n---\n",
+	})
+
+	if _, err := run(t, root, "crosspost", "slices", "--to", "devto", "-n"); err == nil || !strings.Contains(err.Error(), "[site] base_url") {
+		t.Errorf("crosspost without base_url = %v", err)
+	}
+	writeConfig(t, "[site]\nbase_url = \"https://til.example/\"\n\n[crosspost.devto]\nkey_env = \"TIL_TEST_DEVTO\"\napi = \""+srv.URL+"\"\n")
+	if _, err := run(t, root, "crosspost", "slices", "--to", "devto"); err == nil || err.Error() != "devto: $TIL_TEST_DEVTO is not set" {
+		t.Errorf("crosspost without a key = %v", err)
+	}
+	for _, args := range [][]string{{"secret", "--to", "devto", "-n"}, {"slices", "--to", "medium", "-n"}, {"slices", "--to", "hashnode", "-n"}} {
+		if _, err := run(t, root, append([]string{"crosspost"}, args...)...); err == nil {
+			t.Errorf("crosspost %s succeeded", strings.Join(args, " "))
+		}
+	}
+
+	want := "warning: go/slices.md: not resolved: [[nowhere]]\n" +
+		"title: Slices\ntags: go\ncanonical: https://til.example/go/slices/\npublished: true\n\n" +
+		"See [maps](https://til.example/go/maps/) and [[nowhere]].\n"
+	if out := mustRun(t, root, "crosspost", "slices", "--to", "devto", "-n"); out != want || len(requests) != 0 {
+		t.Errorf("crosspost -n =\n%s\nwant\n%s", out, want)
+	}
+
+	t.Setenv("TIL_TEST_DEVTO", "key")
+	if out := mustRun(t, root, "crosspost", "slices", "--to", "devto"); !strings.HasSuffix(out, "posted go/slices.md on devto: https://dev.to/me/slices\n") {
+		t.Errorf("crosspost =\n%s", out)
+	}
+	if got := readFile(t, root, "go/slices.md"); !strings.Contains(got, "crosspost:\n  devto:\n    id: \"17\"\n") {
+		t.Errorf("article not recorded:\n%s", got)
+	}
+	if out := mustRun(t, root, "crosspost", "slices", "--to", "devto"); !strings.HasSuffix(out, "updated go/slices.md on devto: https://dev.to/me/slices\n") {
+		t.Errorf("second crosspost =\n%s", out)
+	}
+	mustRun(t, root, "crosspost", "slices", "--to", "devto", "--new")
+	if want := "POST /articles,PUT /articles/17,POST /articles"; strings.Join(requests, ",") != want {
+		t.Errorf("requests = %q, want %s", requests, want)
+	}
+
+	status = http.StatusNotFound
+	out, err := run(t, root, "crosspost", "slices", "--to", "devto")
+	if err == nil || err.Error() != "1 target failed" || !strings.Contains(out, "devto: article 17: article not found; pass --new to post it again\n") {
+		t.Errorf("crosspost of a deleted article = %v\n%s", err, out)
+	}
+}
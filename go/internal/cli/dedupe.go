@@ -0,0 +1,132 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/canhta/til/go/internal/dedupe"
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+func newDedupeCmd(a *app) *cobra.Command {
+	var (
+		threshold float64
+		merge     bool
+	)
+	cmd := &cobra.Command{
+		Use:   "dedupe",
+		Short: "Find duplicate and near-duplicate entries",
+		Long: `Dedupe lists the pairs of entries whose bodies are identical or nearly so,
+most alike first, scored by the share of three-word runs they have in
+common: 1 for the same words in the same order, whatever the case or
+punctuation.
+
+With --merge, each pair is offered for merging in turn. Merging keeps the
+entry with the richer body, the one with more words, adds the other's tags
+and earlier creation date to it, and removes the other entry. Links to the
+removed entry are left for til lint to report.`,
+		Example: `  til dedupe
+  til dedupe --threshold 0.5
+  til dedupe --merge`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if threshold <= 0 || threshold > 1 {
+				return fmt.Errorf("--threshold %v: want a score above 0, up to 1", threshold)
+			}
+			entries, err := a.tree.Entries()
+			if err != nil {
+				return err
+			}
+			pairs := dedupe.Find(entries, threshold)
+			if merge {
+				return a.mergeDuplicates(cmd, pairs)
+			}
+			return a.output(cmd, pairs, func(w io.Writer) error {
+				if len(pairs) == 0 {
+					fmt.Fprintln(w, "No duplicates found.")
+					return nil
+				}
+				tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+				fmt.Fprintln(tw, "SCORE\tKIND\tENTRY\tDUPLICATE")
+				for _, p := range pairs {
+					kind := "similar"
+					if p.Identical {
+						kind = "identical"
+					}
+					fmt.Fprintf(tw, "%.2f\t%s\t%s\t%s\n", p.Score, kind, p.A, p.B)
+				}
+				return tw.Flush()
+			})
+		},
+	}
+	withJSON(cmd, "duplicates")
+	a.commitFlag(cmd)
+	cmd.Flags().Float64Var(&threshold, "threshold", dedupe.DefaultThreshold, "lowest score, from 0 to 1, of the pairs listed")
+	cmd.Flags().BoolVar(&merge, "merge", false, "offer to merge each pair in turn")
+	return cmd
+}
+
+// mergeDuplicates offers each pair for merging, skipping those with an
+// entry removed by an earlier merge.
+func (a *app) mergeDuplicates(cmd *cobra.Command, pairs []dedupe.Pair) error {
+	out := cmd.OutOrStdout()
+	if len(pairs) == 0 {
+		fmt.Fprintln(out, "No duplicates found.")
+		return nil
+	}
+	in := bufio.NewReader(cmd.InOrStdin())
+	removed := map[string]bool{}
+	merged := 0
+	for i, p := range pairs {
+		if removed[p.A] || removed[p.B] {
+			continue
+		}
+		// Reloaded, as an earlier merge may have changed them.
+		ea, err := a.tree.Load(p.A)
+		if err != nil {
+			return err
+		}
+		eb, err := a.tree.Load(p.B)
+		if err != nil {
+			return err
+		}
+		keep, drop := dedupe.Richer(ea, eb)
+		fmt.Fprintf(out, "\n[%d/%d] %.2f alike\n", i+1, len(pairs), p.Score)
+		for _, e := range []*entry.Entry{keep, drop} {
+			fmt.Fprintf(out, "  %s  (%s, %s)\n", e.Meta.Title, e.Path, plural(e.Counts.Words, "word"))
+		}
+		answer, err := prompt(out, in, fmt.Sprintf("m to merge into %s, s to skip, q to quit: ", keep.Path))
+		if err != nil || answer == "q" {
+			break
+		}
+		if answer != "m" {
+			continue
+		}
+		data, err := a.tree.Read(keep.Path)
+		if err != nil {
+			return err
+		}
+		data, err = dedupe.Merge(data, keep, drop)
+		if err != nil {
+			return fmt.Errorf("%s: %w", keep.Path, err)
+		}
+		if err := a.tree.Write(keep.Path, data); err != nil {
+			return err
+		}
+		if err := a.tree.Remove(drop.Path); err != nil {
+			return err
+		}
+		removed[drop.Path] = true
+		merged++
+		fmt.Fprintf(out, "merged %s into %s\n", drop.Path, keep.Path)
+		if err := a.commitEntry(cmd, keep.Path, "merge", drop.Path); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(out, "\nMerged %s.\n", plural(merged, "pair"))
+	return nil
+}
@@ -0,0 +1,44 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDedupe(t *testing.T) {
+	body := "Appending to a slice may reuse the backing array of another slice cut from the same array.\n"
+	root := newTree(t, map[string]string{
+		"go/slices.md": "---\ntitle: Slices\ndate: 2024-06-01\ntags: [go]\n---\n\n" + body + "\nCopy first.\n",
+		"go/shared.md": "---\ntitle: Shared arrays\ndate: 2023-01-02\ntags: [arrays]\n---\n\n" + body,
+		"go/maps.md":   "---\ntitle: Maps\n---\n\nMap iteration order is random.\n",
+	})
+	out := mustRun(t, root, "dedupe")
+	if !strings.HasPrefix(out, "SCORE  KIND     ENTRY         DUPLICATE\n0.") || !strings.Contains(out, "similar  go/shared.md  go/slices.md\n") || strings.Contains(out, "maps") {
+		t.Errorf("dedupe =\n%s", out)
+	}
+	if out := mustRun(t, root, "dedupe", "--threshold", "0.99"); out != "No duplicates found.\n" {
+		t.Errorf("dedupe --threshold 0.99 = %q", out)
+	}
+	if _, err := run(t, root, "dedupe", "--threshold", "0"); err == nil {
+		t.Error("dedupe --threshold 0 succeeded")
+	}
+
+	if out, err := runStdin(t, root, "s\n", "dedupe", "--merge"); err != nil || !strings.Contains(out, "Merged 0 pairs.") {
+		t.Errorf("dedupe --merge, skipping = %v\n%s", err, out)
+	}
+	out, err := runStdin(t, root, "m\n", "dedupe", "--merge")
+	if err != nil {
+		t.Fatalf("dedupe --merge: %v\n%s", err, out)
+	}
+	if !strings.Contains(out, "m to merge into go/slices.md") || !strings.Contains(out, "merged go/shared.md into go/slices.md\n") || !strings.Contains(out, "Merged 1 pair.") {
+		t.Errorf("dedupe --merge =\n%s", out)
+	}
+	if got := readFile(t, root, "go/slices.md"); !strings.HasPrefix(got, "---\ntitle: Slices\ndate: 2023-01-02\ntags: [go, arrays]\n---\n") {
+		t.Errorf("merged entry =\n%s", got)
+	}
+	if _, err := os.Stat(filepath.Join(root, "go", "shared.md")); !os.IsNotExist(err) {
+		t.Errorf("duplicate not removed: %v", err)
+	}
+}
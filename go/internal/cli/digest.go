@@ -0,0 +1,135 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/canhta/til/go/internal/digest"
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+func newDigestCmd(a *app) *cobra.Command {
+	var (
+		channels  []string
+		skipEmpty bool
+		day       string
+		gitDates  bool
+	)
+	cmd := &cobra.Command{
+		Use:   "digest",
+		Short: "Send a summary of due reviews, this day's entries and the streak",
+		Long: `Digest composes a summary of the entries due for review, those written on
+this day in earlier years, the current writing streak and progress on the
+[[goals]] of the config, and delivers it to the channels of the [digest]
+config section, or those given with --channel:
+
+  stdout   print it
+  email    mail it through [digest.email]
+  webhook  POST it as JSON to [digest] webhook
+
+It is meant to be run daily from cron or a systemd timer. Entries link to
+the site when [site] base_url is an absolute URL.`,
+		Example: `  til digest --channel email --skip-empty
+  0 8 * * * cd ~/til && til digest --channel webhook`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg := a.cfg.Digest
+			if !cmd.Flags().Changed("channel") {
+				channels = cfg.Channels
+			}
+			if len(channels) == 0 {
+				channels = []string{"stdout"}
+			}
+			senders := map[string]func(context.Context, *digest.Digest) error{}
+			for _, ch := range channels {
+				send, err := a.digestChannel(ch)
+				if err != nil {
+					return err
+				}
+				senders[ch] = send
+			}
+
+			now := time.Now()
+			if day != "" {
+				d, err := time.ParseInLocation(entry.DateLayout, day, now.Location())
+				if err != nil {
+					return fmt.Errorf("--date: want YYYY-MM-DD: %w", err)
+				}
+				now = d
+			}
+			entries, err := a.datedEntries(cmd.Context(), gitDates)
+			if err != nil {
+				return err
+			}
+			opts := digest.Options{Now: now, URL: a.entryURLs(entries), Limit: cfg.Limit, Goals: a.cfg.Goals}
+			d, err := digest.Compose(cmd.Context(), a.tree, entries, opts)
+			if err != nil {
+				return err
+			}
+			if d.Empty() && (skipEmpty || cfg.SkipEmpty) {
+				return nil
+			}
+
+			failed := 0
+			for _, ch := range channels {
+				if ch == "stdout" {
+					err = a.output(cmd, d, func(w io.Writer) error {
+						_, err := io.WriteString(w, d.Text())
+						return err
+					})
+				} else {
+					err = senders[ch](cmd.Context(), d)
+				}
+				if err != nil {
+					fmt.Fprintf(cmd.ErrOrStderr(), "%s: %v\n", ch, err)
+					failed++
+				}
+			}
+			if failed > 0 {
+				return fmt.Errorf("%s failed", plural(failed, "channel"))
+			}
+			return nil
+		},
+	}
+	withJSON(cmd, "digest")
+	cmd.Flags().StringSliceVar(&channels, "channel", nil, "deliver to this channel: stdout, email or webhook (repeatable; default from [digest] channels)")
+	cmd.Flags().BoolVar(&skipEmpty, "skip-empty", false, "send nothing when nothing is due or written on this day")
+	cmd.Flags().StringVar(&day, "date", "", "compose the digest of this date instead (YYYY-MM-DD)")
+	cmd.Flags().BoolVar(&gitDates, "git-dates", false, "date entries by their first and last commit")
+	return cmd
+}
+
+// digestChannel returns the sender of the named channel, checking its
+// configuration. stdout is written by the caller.
+func (a *app) digestChannel(name string) (func(context.Context, *digest.Digest) error, error) {
+	cfg := a.cfg.Digest
+	switch name {
+	case "stdout":
+		return nil, nil
+	case "email":
+		c := cfg.Email
+		if c.Host == "" || c.From == "" || len(c.To) == 0 {
+			return nil, errors.New("channel email: set host, from and to in [digest.email]")
+		}
+		m := digest.Email{Host: c.Host, Port: c.Port, Username: c.Username, From: c.From, To: c.To}
+		if c.PasswordEnv != "" {
+			m.Password = os.Getenv(c.PasswordEnv)
+			if m.Password == "" {
+				return nil, fmt.Errorf("channel email: $%s is not set", c.PasswordEnv)
+			}
+		}
+		return m.Send, nil
+	case "webhook":
+		if cfg.Webhook == "" {
+			return nil, errors.New("channel webhook: set webhook in [digest]")
+		}
+		return digest.Webhook{URL: cfg.Webhook}.Send, nil
+	}
+	return nil, fmt.Errorf("unknown channel %q: want stdout, email or webhook", name)
+}
@@ -0,0 +1,56 @@
+package cli
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDigest(t *testing.T) {
+	root := newTree(t, map[string]string{
+		"go/slices.md": "---\ntitle: Slices\ndate: 2022-06-15\n---\n",
+		"go/maps.md":   "---\ntitle: Maps\ndate: 2024-06-14\n---\n",
+	})
+	want := "TIL digest 2024-06-15: 1 from this day\n\nOn this day, before 2024:\n  - Slices [2022] (go/slices.md)\n\nWriting streak: 1 day.\n"
+	if out := mustRun(t, root, "digest", "--date", "2024-06-15"); out != want {
+		t.Errorf("digest =\n%s\nwant\n%s", out, want)
+	}
+	if out := mustRun(t, root, "digest", "--date", "2024-01-02", "--skip-empty"); out != "" {
+		t.Errorf("digest --skip-empty =\n%s", out)
+	}
+
+	var posted string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		posted = string(data)
+	}))
+	defer srv.Close()
+	writeConfig(t, "[site]\nbase_url = \"https://til.example/\"\n\n[digest]\nchannels = [\"webhook\"]\nwebhook = \""+srv.URL+"\"\n")
+	if out := mustRun(t, root, "digest", "--date", "2024-06-15"); out != "" {
+		t.Errorf("digest to a webhook printed\n%s", out)
+	}
+	if !strings.Contains(posted, `"text":"TIL digest 2024-06-15: 1 from this day`) || !strings.Contains(posted, `"url":"https://til.example/go/slices/"`) {
+		t.Errorf("posted %s", posted)
+	}
+
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"--channel", "email"}, "channel email: set host, from and to in [digest.email]"},
+		{[]string{"--channel", "pigeon"}, `unknown channel "pigeon"`},
+	}
+	for _, tt := range tests {
+		if _, err := run(t, root, append([]string{"digest"}, tt.args...)...); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("digest %s = %v, want %s", strings.Join(tt.args, " "), err, tt.want)
+		}
+	}
+
+	srv.Close()
+	out, err := run(t, root, "digest", "--channel", "stdout,webhook", "--date", "2024-06-15")
+	if err == nil || err.Error() != "1 channel failed" || !strings.HasPrefix(out, "TIL digest") || !strings.Contains(out, "webhook: ") {
+		t.Errorf("digest with a failing webhook = %v\n%s", err, out)
+	}
+}
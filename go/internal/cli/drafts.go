@@ -0,0 +1,121 @@
+package cli
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/canhta/til/go/internal/notify"
+	"github.com/canhta/til/go/internal/query"
+	"github.com/canhta/til/go/pkg/entry"
+	"github.com/canhta/til/go/pkg/hook"
+)
+
+func newDraftsCmd(a *app) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "drafts",
+		Short: "List draft entries",
+		Long: `Drafts lists the entries marked "draft: true" in their frontmatter. Drafts
+are left out of the generated site, its feeds and the README index, but
+are found by til search and til list. Publish one with til publish.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			entries, err := a.tree.Entries()
+			if err != nil {
+				return err
+			}
+			var drafts []*entry.Entry
+			for _, e := range entries {
+				if e.Meta.Draft {
+					drafts = append(drafts, e)
+				}
+			}
+			if err := query.Sort(drafts, "updated", false); err != nil {
+				return err
+			}
+			return a.outputEntries(cmd, drafts)
+		},
+	}
+	return withJSON(cmd, "entries")
+}
+
+func newPublishCmd(a *app) *cobra.Command {
+	var keepDate, noNotify bool
+	cmd := &cobra.Command{
+		Use:   "publish <entry>",
+		Short: "Publish a draft entry",
+		Long: `Publish removes the draft flag from an entry and sets its date to today,
+so it appears on the site as a new entry. With --keep-date the original
+date is kept. The entry is then announced on the webhooks configured for
+til notify, and with [site.webmention] send, the pages it links to are
+sent webmentions.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			e, err := a.resolve(args[0])
+			if err != nil {
+				return err
+			}
+			if !e.Meta.Draft {
+				return fmt.Errorf("%s is not a draft", e.Path)
+			}
+			var hooks []*notify.Webhook
+			if !noNotify && !a.cfg.Notify.Manual {
+				if hooks, err = a.webhooks(); err != nil {
+					return err
+				}
+			}
+			data, err := a.tree.Read(e.Path)
+			if err != nil {
+				return err
+			}
+			updated, err := entry.Rewrite(data, func(f *entry.Front) error {
+				if _, err := f.Delete("draft"); err != nil {
+					return err
+				}
+				if keepDate {
+					return nil
+				}
+				now := time.Now()
+				return f.Set("date", time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC))
+			})
+			if err != nil {
+				return fmt.Errorf("%s: %w", e.Path, err)
+			}
+			if err := a.tree.Write(e.Path, updated); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "published %s\n", e.Path)
+			if err := a.commitEntry(cmd, e.Path, "publish"); err != nil {
+				return err
+			}
+			if e, err = a.tree.Resolve(e.Path); err != nil {
+				return err
+			}
+			published := &hook.Payload{Event: hook.PostPublish, Path: e.Path}
+			if urls := a.entryURLs([]*entry.Entry{e}); urls != nil {
+				published.URL = urls(e.Path)
+			}
+			if err := a.hooks.Run(cmd.Context(), published); err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "warning: %v\n", err)
+			}
+			if a.cfg.Site.Webmention.Send && published.URL != "" {
+				urls := func(string) string { return published.URL }
+				if err := a.sendWebmentions(cmd.Context(), cmd.OutOrStdout(), cmd.ErrOrStderr(), []*entry.Entry{e}, urls, false, false); err != nil {
+					fmt.Fprintf(cmd.ErrOrStderr(), "warning: %v; retry with til webmention send %s\n", err, e.Path)
+				}
+			}
+			if len(hooks) == 0 {
+				return nil
+			}
+			if err := a.announce(cmd.Context(), cmd.OutOrStdout(), cmd.ErrOrStderr(), e, hooks); err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "warning: %v; retry with til notify %s\n", err, e.Path)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&keepDate, "keep-date", false, "keep the entry's date instead of setting it to today")
+	cmd.Flags().BoolVar(&noNotify, "no-notify", false, "do not announce the entry on the configured webhooks")
+	a.commitFlag(cmd)
+	return cmd
+}
@@ -0,0 +1,44 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDraftsPublish(t *testing.T) {
+	root := newTree(t, map[string]string{
+		"go/slices.md": "---\ntitle: Slices\ndate: 2024-06-01\n---\n",
+		"go/old.md":    "---\ntitle: Old draft\ndate: 2020-01-02\ndraft: true\n---\n",
+	})
+	mustRun(t, root, "new", "go", "Maps are unordered", "--draft", "--no-edit")
+	if got := readFile(t, root, "go/maps_are_unordered.md"); !strings.Contains(got, "\ndraft: true\n") {
+		t.Fatalf("new --draft wrote:\n%s", got)
+	}
+	out := mustRun(t, root, "drafts")
+	if !strings.Contains(out, "go/maps_are_unordered.md") || !strings.Contains(out, "go/old.md") || strings.Contains(out, "go/slices.md") {
+		t.Errorf("drafts =\n%s", out)
+	}
+	mustRun(t, root, "build")
+	if got := readFile(t, root, "public/index.html"); strings.Contains(got, "Old draft") || strings.Contains(got, "Maps are unordered") {
+		t.Errorf("the site lists drafts:\n%s", got)
+	}
+
+	if out := mustRun(t, root, "publish", "old"); out != "published go/old.md\n" {
+		t.Errorf("publish = %q", out)
+	}
+	today := time.Now().Format(time.DateOnly)
+	if got, want := readFile(t, root, "go/old.md"), "---\ntitle: Old draft\ndate: "+today+"\n---\n"; got != want {
+		t.Errorf("published go/old.md =\n%s\nwant\n%s", got, want)
+	}
+	mustRun(t, root, "publish", "maps_are_unordered", "--keep-date")
+	if got := readFile(t, root, "go/maps_are_unordered.md"); strings.Contains(got, "draft") {
+		t.Errorf("published go/maps_are_unordered.md keeps the draft flag:\n%s", got)
+	}
+	if out := mustRun(t, root, "drafts"); strings.Contains(out, ".md") {
+		t.Errorf("drafts after publishing =\n%s", out)
+	}
+	if _, err := run(t, root, "publish", "slices"); err == nil || !strings.Contains(err.Error(), "go/slices.md is not a draft") {
+		t.Errorf("publish of a published entry = %v", err)
+	}
+}
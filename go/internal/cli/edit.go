@@ -0,0 +1,133 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/canhta/til/go/internal/editor"
+	"github.com/canhta/til/go/internal/notes"
+	"github.com/canhta/til/go/internal/private"
+)
+
+func newEditCmd(a *app) *cobra.Command {
+	var first bool
+	cmd := &cobra.Command{
+		Use:   "edit [entry]",
+		Short: "Open an entry in $EDITOR",
+		Long: `Edit opens an entry, given by path, ID, file stem or slug, in the editor.
+With [git] commit enabled in the configuration, the change is committed
+when the editor exits.
+
+Anything else is matched fuzzily against titles and paths. A single match
+is opened; several are offered in a fuzzy finder, newest first, narrowed
+as you type: ↑/↓ to move, enter to open, esc to cancel. --first opens the
+best match without asking, and without an entry the finder lists them all.
+
+Encrypted private entries, given by path or file stem, are decrypted to a
+temporary file for editing and encrypted again afterwards. Setting
+"private: true" in an entry's frontmatter encrypts it when the editor
+exits; removing it from an encrypted entry stores it decrypted.`,
+		Example: `  til edit slice
+  til edit --first go/slices`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var ref string
+			if len(args) == 1 {
+				ref = args[0]
+			}
+			if ref != "" {
+				if _, err := a.resolve(ref); errors.Is(err, notes.ErrNotFound) {
+					if rel, perr := a.tree.ResolveEncrypted(ref); perr == nil {
+						return a.editPrivate(cmd, rel)
+					}
+				}
+			}
+			e, err := a.pickEntry(ref, first)
+			if err != nil {
+				return err
+			}
+			if err := a.openEditor(e.Path); err != nil {
+				return err
+			}
+			data, err := a.tree.Read(e.Path)
+			if err != nil {
+				return err
+			}
+			if !private.IsPrivate(data) {
+				return a.commitEntry(cmd, e.Path, "update")
+			}
+			rel, err := a.seal(cmd, e.Path)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.ErrOrStderr(), "note: versions of %s committed before remain readable in git history\n", e.Path)
+			return a.commitEntry(cmd, rel, "update", e.Path)
+		},
+	}
+	pickFlag(cmd, &first)
+	a.commitFlag(cmd)
+	return cmd
+}
+
+// openEditor opens the file at rel in the editor: in place if the store
+// keeps it locally, otherwise through a temporary copy that is written
+// back when it changed.
+func (a *app) openEditor(rel string) error {
+	if file, ok := a.tree.File(rel); ok {
+		before, err := a.tree.Read(rel)
+		if err != nil {
+			return err
+		}
+		if err := editor.Open(file); err != nil {
+			return err
+		}
+		return a.resave(rel, before)
+	}
+	data, err := a.tree.Read(rel)
+	if err != nil {
+		return err
+	}
+	edited, err := editTemp(path.Base(rel), data)
+	if err != nil || bytes.Equal(edited, data) {
+		return err
+	}
+	return a.tree.Write(rel, edited)
+}
+
+// resave writes the entry at rel again through the store if an editor
+// changed it in place from before, for the save hooks to see the change.
+func (a *app) resave(rel string, before []byte) error {
+	if a.hooks.Empty() {
+		return nil
+	}
+	data, err := a.tree.Read(rel)
+	if err != nil || bytes.Equal(data, before) {
+		return err
+	}
+	return a.tree.Write(rel, data)
+}
+
+// editTemp opens data in the editor as a private temporary file with the
+// given name and returns the edited content. The file is removed
+// afterwards.
+func editTemp(name string, data []byte) ([]byte, error) {
+	dir, err := os.MkdirTemp("", "til-edit-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	tmp := filepath.Join(dir, name)
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return nil, err
+	}
+	if err := editor.Open(tmp); err != nil {
+		return nil, err
+	}
+	return os.ReadFile(tmp)
+}
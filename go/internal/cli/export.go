@@ -0,0 +1,292 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/canhta/til/go/internal/export"
+	"github.com/canhta/til/go/internal/fsutil"
+	"github.com/canhta/til/go/internal/query"
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+func newExportCmd(a *app) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export entries for use in other tools",
+	}
+	cmd.AddCommand(
+		newExportAnkiCmd(a),
+		newExportSiteCmd(a, export.Hugo, "Hugo"),
+		newExportSiteCmd(a, export.Jekyll, "Jekyll"),
+		newExportBookCmd(a),
+		newExportDumpCmd(a, "json", export.JSON),
+		newExportDumpCmd(a, "csv", export.CSV),
+	)
+	return cmd
+}
+
+func newExportAnkiCmd(a *app) *cobra.Command {
+	var (
+		filter listFilter
+		opts   export.AnkiOptions
+		out    string
+	)
+	cmd := &cobra.Command{
+		Use:   "anki",
+		Short: "Export entries as Anki flashcards",
+		Long: `Export entries as an Anki text import file, one card per entry with the
+title on the front and the rendered body, code highlighted, on the back.
+Import it with File > Import in Anki. Cards are keyed by entry, so importing
+a newer export updates the cards instead of adding duplicates.`,
+		Example: `  til export anki --tag flashcard -o til.txt
+  til export anki --deck "Notes::{category}" > cards.txt`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			x, err := filter.expr(time.Now(), a.lang())
+			if err != nil {
+				return err
+			}
+			entries, err := a.tree.Entries()
+			if err != nil {
+				return err
+			}
+			entries = query.Filter(entries, x)
+			if err := query.Sort(entries, "path", false); err != nil {
+				return err
+			}
+			if out == "" || out == "-" {
+				return export.Anki(cmd.OutOrStdout(), entries, opts)
+			}
+			var buf bytes.Buffer
+			if err := export.Anki(&buf, entries, opts); err != nil {
+				return err
+			}
+			if err := fsutil.WriteFile(out, buf.Bytes(), 0o644); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.ErrOrStderr(), "wrote %d cards to %s\n", len(entries), out)
+			return nil
+		},
+	}
+	filter.register(cmd)
+	cmd.Flags().StringVar(&opts.Deck, "deck", export.DefaultDeck, `deck name; "{category}" is replaced by the entry's category`)
+	cmd.Flags().StringVar(&opts.Style, "style", "github", "chroma style for code blocks")
+	cmd.Flags().StringVarP(&out, "out", "o", "", "write to this file instead of stdout")
+	return cmd
+}
+
+func newExportSiteCmd(a *app, gen export.Generator, name string) *cobra.Command {
+	var (
+		filter listFilter
+		opts   = export.SiteOptions{Generator: gen}
+		out    string
+		drafts bool
+	)
+	cmd := &cobra.Command{
+		Use:   string(gen),
+		Short: "Export entries as " + name + " content",
+		Long: `Export entries as the content of a ` + name + ` site: each entry becomes a page
+at <category>/<slug>/ with its frontmatter mapped to ` + name + `'s fields and its
+assets next to it. Links between exported entries and to assets are
+rewritten to relative URLs.
+
+Exports are incremental: a manifest in the output directory records what
+was exported, so later exports write only what changed and remove entries
+that are gone, leaving files they did not create alone. Private entries are
+never exported.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			x, err := filter.expr(time.Now(), a.lang())
+			if err != nil {
+				return err
+			}
+			entries, err := a.tree.Entries()
+			if err != nil {
+				return err
+			}
+			entries = slices.DeleteFunc(query.Filter(entries, x), func(e *entry.Entry) bool {
+				return e.Meta.Private || e.Meta.Archived || e.Meta.Draft && !drafts
+			})
+			files, err := export.Site(a.tree, entries, opts)
+			if err != nil {
+				return err
+			}
+			st, err := export.Sync(out, files)
+			if err != nil {
+				return err
+			}
+			for _, p := range st.Removed {
+				fmt.Fprintf(cmd.ErrOrStderr(), "removed %s\n", p)
+			}
+			fmt.Fprintf(cmd.ErrOrStderr(), "exported %d entries to %s: %d files written, %d unchanged, %d removed\n",
+				len(entries), out, st.Written, st.Unchanged, len(st.Removed))
+			return nil
+		},
+	}
+	filter.register(cmd)
+	cmd.Flags().StringVarP(&out, "out", "o", "", "directory to export into (required)")
+	cmd.MarkFlagRequired("out")
+	cmd.Flags().BoolVar(&drafts, "drafts", false, "export drafts too, marked as drafts")
+	if gen == export.Jekyll {
+		cmd.Flags().StringVar(&opts.Prefix, "prefix", export.DefaultPrefix, "URL path the entries are served under")
+	}
+	return cmd
+}
+
+func newExportBookCmd(a *app) *cobra.Command {
+	var (
+		filter listFilter
+		opts   = export.BookOptions{Format: export.EPUB}
+		format string
+		out    string
+	)
+	cmd := &cobra.Command{
+		Use:   "book",
+		Short: "Export entries as an EPUB or printable HTML book",
+		Long: `Export entries as a single book with a cover page, a table of contents and
+one chapter per category, entries oldest first. Code is highlighted and
+attached images are embedded.
+
+The epub format suits e-readers. The html format is one self-contained
+page laid out for printing; print it to PDF from a browser. Drafts and
+private entries are left out. The cover names --book-author, else the
+author of the entries when they all have the same one.`,
+		Example: `  til export book --since 2026-01-01 -o til-2026.epub
+  til export book --format html --book-title "Go notes" -c go -o go.html
+  til export book --author "Jane Doe" -o jane.epub`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts.Format = export.BookFormat(format)
+			if !slices.Contains(export.BookFormats, opts.Format) {
+				return fmt.Errorf("unknown format %q (want one of %v)", format, export.BookFormats)
+			}
+			x, err := filter.expr(time.Now(), a.lang())
+			if err != nil {
+				return err
+			}
+			entries, err := a.tree.Entries()
+			if err != nil {
+				return err
+			}
+			entries = query.Filter(entry.Public(entries), x)
+			if len(entries) == 0 {
+				return errors.New("no entries to export")
+			}
+			if opts.Author == "" {
+				opts.Author = soleAuthor(entries)
+			}
+			if out == "" || out == "-" {
+				if opts.Format == export.EPUB && isTerminal(cmd.OutOrStdout()) {
+					return errors.New("not writing an EPUB to a terminal; pass -o")
+				}
+				return export.Book(cmd.OutOrStdout(), a.tree, entries, opts)
+			}
+			var buf bytes.Buffer
+			if err := export.Book(&buf, a.tree, entries, opts); err != nil {
+				return err
+			}
+			if err := fsutil.WriteFile(out, buf.Bytes(), 0o644); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.ErrOrStderr(), "wrote %d entries to %s\n", len(entries), out)
+			return nil
+		},
+	}
+	filter.register(cmd)
+	cmd.Flags().StringVar(&format, "format", string(export.EPUB), "epub or html")
+	cmd.Flags().StringVar(&opts.Title, "book-title", "TIL", "title on the cover")
+	cmd.Flags().StringVar(&opts.Author, "book-author", "", "author on the cover")
+	cmd.Flags().StringVar(&opts.Style, "style", "github", "chroma style for code blocks")
+	cmd.Flags().StringVarP(&out, "out", "o", "", "write to this file instead of stdout")
+	return cmd
+}
+
+func newExportDumpCmd(a *app, format string, dump func(io.Writer, []*entry.Entry, export.DumpOptions) error) *cobra.Command {
+	var (
+		filter listFilter
+		opts   export.DumpOptions
+		out    string
+	)
+	long := `Export every entry as one JSON document, for data analysis notebooks and
+external search systems:
+
+  {"schema": 1, "entries": [{"path": ..., "title": ..., "body": ...}]}
+
+Each entry has the fields til list --json prints, draft and archived, the
+frontmatter as written, with any custom fields, the markdown body and with
+--html the rendered body.`
+	if format == "csv" {
+		long = `Export every entry as a row of CSV, for data analysis notebooks and
+spreadsheets. The header row names the columns:
+
+  ` + strings.Join(export.CSVColumns, ",") + `
+
+Tags are separated by semicolons, the frontmatter is a JSON object of the
+fields as written, with any custom fields, and html holds the rendered body
+with --html.`
+	}
+	cmd := &cobra.Command{
+		Use:   format,
+		Short: "Export entries as " + strings.ToUpper(format) + ", with their metadata and body",
+		Long: long + `
+
+Entries are sorted by path. Drafts and archived entries are exported and
+marked as such; private entries are not. The schema is versioned: it only
+changes to remove or redefine a field, which raises the schema number, and
+may gain new fields otherwise.`,
+		Example: `  til export ` + format + ` -o til.` + format + `
+  til export ` + format + ` --tag go --html`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			x, err := filter.expr(time.Now(), a.lang())
+			if err != nil {
+				return err
+			}
+			entries, err := a.tree.Entries()
+			if err != nil {
+				return err
+			}
+			entries = slices.DeleteFunc(query.Filter(entries, x), func(e *entry.Entry) bool { return e.Meta.Private })
+			if err := query.Sort(entries, "path", false); err != nil {
+				return err
+			}
+			if out == "" || out == "-" {
+				return dump(cmd.OutOrStdout(), entries, opts)
+			}
+			var buf bytes.Buffer
+			if err := dump(&buf, entries, opts); err != nil {
+				return err
+			}
+			if err := fsutil.WriteFile(out, buf.Bytes(), 0o644); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.ErrOrStderr(), "wrote %d entries to %s\n", len(entries), out)
+			return nil
+		},
+	}
+	filter.register(cmd)
+	cmd.Flags().BoolVar(&opts.HTML, "html", false, "include the body rendered to HTML")
+	cmd.Flags().StringVar(&opts.Style, "style", "github", "chroma style for code blocks of the HTML")
+	cmd.Flags().StringVarP(&out, "out", "o", "", "write to this file instead of stdout")
+	return cmd
+}
+
+// soleAuthor returns the author of entries when they all name the same
+// one, and "" otherwise.
+func soleAuthor(entries []*entry.Entry) string {
+	author := entries[0].Meta.Author
+	for _, e := range entries[1:] {
+		if e.Meta.Author != author {
+			return ""
+		}
+	}
+	return author
+}
@@ -0,0 +1,85 @@
+package cli
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestExportHugo(t *testing.T) {
+	root := newTree(t, map[string]string{
+		"go/slices.md":  "---\ntitle: Slices\ndate: 2024-06-01\ntags: [go]\n---\n\nSee [[git/rebase]].\n",
+		"go/draft.md":   "---\ntitle: Draft\ndraft: true\n---\n",
+		"go/old.md":     "---\ntitle: Old\narchived: true\n---\n",
+		"git/rebase.md": "---\ntitle: Rebase\ndate: 2023-01-10\n---\n",
+	})
+	hugo := filepath.Join(root, "site", "content", "til")
+	out := mustRun(t, root, "export", "hugo", "-o", hugo)
+	if !strings.Contains(out, "exported 2 entries to "+hugo+": 2 files written, 0 unchanged, 0 removed") {
+		t.Errorf("export hugo printed %q", out)
+	}
+	if got := readFile(t, root, "site/content/til/go/slices/index.md"); !strings.Contains(got, "See [Rebase](../../git/rebase/).") {
+		t.Errorf("go/slices/index.md:\n%s", got)
+	}
+	if got := readFile(t, root, "site/content/til/go/draft/index.md"); !strings.HasPrefix(got, "<") {
+		t.Errorf("a draft was exported:\n%s", got)
+	}
+
+	out = mustRun(t, root, "export", "hugo", "-o", hugo, "--drafts", "--category", "go")
+	if !strings.Contains(out, "removed git/rebase/index.md") || !strings.Contains(out, "2 files written, 0 unchanged, 1 removed") {
+		t.Errorf("second export printed %q", out)
+	}
+	// The link to the entry no longer exported is now plain text.
+	if got := readFile(t, root, "site/content/til/go/slices/index.md"); !strings.Contains(got, "See git/rebase.") {
+		t.Errorf("go/slices/index.md:\n%s", got)
+	}
+	if got := readFile(t, root, "site/content/til/go/draft/index.md"); !strings.Contains(got, "draft: true") {
+		t.Errorf("go/draft/index.md:\n%s", got)
+	}
+
+	mustRun(t, root, "export", "jekyll", "-o", filepath.Join(root, "blog"), "--prefix", "notes")
+	if got := readFile(t, root, "blog/_posts/2024-06-01-go-slices.md"); !strings.Contains(got, "permalink: /notes/go/slices/") {
+		t.Errorf("blog/_posts/2024-06-01-go-slices.md:\n%s", got)
+	}
+	if _, err := run(t, root, "export", "hugo"); err == nil {
+		t.Error("export hugo without -o succeeded")
+	}
+}
+
+func TestExportDump(t *testing.T) {
+	root := newTree(t, map[string]string{
+		"go/slices.md":   "---\ntitle: Slices\ntags: [go]\n---\n\nSlices.\n",
+		"go/draft.md":    "---\ntitle: Draft\ndraft: true\n---\n",
+		"go/private.md":  "---\ntitle: Private\nprivate: true\n---\n",
+		"git/rebase.md":  "---\ntitle: Rebase\ntags: [git]\n---\n",
+		"git/archive.md": "---\ntitle: Old\narchived: true\n---\n",
+	})
+	var doc struct {
+		Schema  int
+		Entries []struct {
+			Path  string
+			Draft bool
+		}
+	}
+	if err := json.Unmarshal([]byte(mustRun(t, root, "export", "json")), &doc); err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	for _, e := range doc.Entries {
+		paths = append(paths, e.Path)
+	}
+	if want := []string{"git/archive.md", "git/rebase.md", "go/draft.md", "go/slices.md"}; doc.Schema != 1 || !slices.Equal(paths, want) {
+		t.Errorf("export json = schema %d, %q, want %q", doc.Schema, paths, want)
+	}
+
+	out := mustRun(t, root, "export", "csv", "--tag", "go", "-o", filepath.Join(root, "til.csv"))
+	if !strings.Contains(out, "wrote 1 entries to "+filepath.Join(root, "til.csv")) {
+		t.Errorf("export csv -o printed %q", out)
+	}
+	lines := strings.Split(readFile(t, root, "til.csv"), "\n")
+	if len(lines) != 5 || !strings.HasPrefix(lines[0], "path,title,category,") || !strings.HasPrefix(lines[1], "go/slices.md,Slices,go,") {
+		t.Errorf("til.csv =\n%s", strings.Join(lines, "\n"))
+	}
+}
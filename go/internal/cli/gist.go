@@ -0,0 +1,150 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+
+	"github.com/spf13/cobra"
+
+	"github.com/canhta/til/go/internal/gist"
+	"github.com/canhta/til/go/internal/include"
+	"github.com/canhta/til/go/internal/links"
+	"github.com/canhta/til/go/internal/site"
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+func newGistCmd(a *app) *cobra.Command {
+	var (
+		public bool
+		dryRun bool
+		fresh  bool
+	)
+	cmd := &cobra.Command{
+		Use:   "gist <entry>",
+		Short: "Share an entry as a GitHub Gist",
+		Long: `Gist creates a gist holding an entry's markdown, without frontmatter, and
+each of its code blocks as a file of its own, named after the block's file
+attribute or the entry's slug and the block's language. With [site]
+base_url set to an absolute URL, links to other entries point to the site.
+
+The gist ID is recorded as gist in the entry's frontmatter, so running
+til gist again updates the gist. Gists are secret unless --public or
+[gist] public is set. The GitHub token is read from $GITHUB_TOKEN, or the
+variable [gist] token_env names.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg := a.cfg.Gist
+			env := cfg.TokenEnv
+			if env == "" {
+				env = "GITHUB_TOKEN"
+			}
+			token := os.Getenv(env)
+			if token == "" && !dryRun {
+				return fmt.Errorf("$%s is not set; it needs a GitHub token with the gist scope", env)
+			}
+			e, err := a.resolve(args[0])
+			if err != nil {
+				return err
+			}
+			if e.Meta.Private {
+				return fmt.Errorf("%s is private", e.Path)
+			}
+			entries, err := a.tree.Entries()
+			if err != nil {
+				return err
+			}
+			x := include.Expand(e, links.NewIndex(entries))
+			for _, err := range x.Errors {
+				fmt.Fprintf(cmd.ErrOrStderr(), "warning: %v\n", err)
+			}
+			body := x.Body
+			published := slices.DeleteFunc(slices.Clone(entries), func(o *entry.Entry) bool {
+				return o.Meta.Private || o.Meta.Draft
+			})
+			if s := site.New(published, site.Options{BaseURL: a.cfg.Site.BaseURL, Permalink: a.cfg.Site.Permalink}); s.Origin != "" {
+				if err := s.LoadAssets(a.tree); err != nil {
+					return err
+				}
+				var unresolved []string
+				body, unresolved = s.AbsLinks(e.Path, body)
+				for _, u := range unresolved {
+					fmt.Fprintf(cmd.ErrOrStderr(), "warning: %s: not resolved: %s\n", e.Path, u)
+				}
+			}
+			files := gist.Files(e, body)
+			res := gistResult{}
+			for _, f := range files {
+				res.Files = append(res.Files, f.Name)
+			}
+			if e.Front != nil && !fresh {
+				f, err := entry.ParseFront(e.Front)
+				if err != nil {
+					return fmt.Errorf("%s: %w", e.Path, err)
+				}
+				if _, err := f.Get(gist.Field, &res.ID); err != nil {
+					return fmt.Errorf("%s: %s: %w", e.Path, gist.Field, err)
+				}
+			}
+			if dryRun {
+				return a.output(cmd, res, func(w io.Writer) error {
+					for _, n := range res.Files {
+						fmt.Fprintln(w, n)
+					}
+					return nil
+				})
+			}
+
+			id := res.ID
+			c := gist.Client{Token: token, API: cfg.API}
+			var g *gist.Gist
+			if id != "" {
+				g, err = c.Update(cmd.Context(), id, e.Meta.Title, files)
+				if errors.Is(err, gist.ErrNotFound) {
+					err = fmt.Errorf("gist %s: %w; pass --new to create another", id, err)
+				}
+			} else {
+				g, err = c.Create(cmd.Context(), e.Meta.Title, public || cfg.Public, files)
+			}
+			if err != nil {
+				return err
+			}
+			if g.ID != id {
+				data, err := a.tree.Read(e.Path)
+				if err != nil {
+					return err
+				}
+				data, err = entry.Rewrite(data, func(f *entry.Front) error { return f.Set(gist.Field, g.ID) })
+				if err != nil {
+					return fmt.Errorf("%s: %w", e.Path, err)
+				}
+				if err := a.tree.Write(e.Path, data); err != nil {
+					return err
+				}
+			}
+			if err := a.commitEntry(cmd, e.Path, "gist"); err != nil {
+				return err
+			}
+			res.ID, res.URL = g.ID, g.URL
+			return a.output(cmd, res, func(w io.Writer) error {
+				_, err := fmt.Fprintln(w, g.URL)
+				return err
+			})
+		},
+	}
+	withJSON(cmd, "gist")
+	cmd.Flags().BoolVar(&public, "public", false, "create a public gist instead of a secret one")
+	cmd.Flags().BoolVarP(&dryRun, "dry-run", "n", false, "list the gist's files instead of uploading them")
+	cmd.Flags().BoolVar(&fresh, "new", false, "create a new gist even if one is recorded")
+	a.commitFlag(cmd)
+	return cmd
+}
+
+// gistResult is the JSON output of til gist.
+type gistResult struct {
+	ID    string   `json:"id,omitempty"`
+	URL   string   `json:"url,omitempty"`
+	Files []string `json:"files"`
+}
@@ -0,0 +1,67 @@
+package cli
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGist(t *testing.T) {
+	var requests []string
+	var md string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		var req struct {
+			Files map[string]*struct{ Content string }
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		if f := req.Files["slices.md"]; f != nil {
+			md = f.Content
+		}
+		if r.URL.Path == "/gists/gone" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{"id":"g1","html_url":"https://gist.github.com/g1","files":{}}`))
+	}))
+	defer srv.Close()
+	root := newTree(t, map[string]string{
+		"go/slices.md": "---\ntitle: Slices\n---\n\nSee [maps](maps.md).\n\n```go\nfmt.Println(1)\n```\n",
+		"go/maps.md":   "---\ntitle: Maps\n---\n\nMaps.\n",
+		"go/secret.md": "---\ntitle: Secret\nprivate: true\n---\n",
+		"go/gone.md":   "---\ntitle: Gone\ngist: gone\n---\n",
+	})
+
+	t.Setenv("TIL_TEST_GITHUB", "")
+	writeConfig(t, "[site]\nbase_url = \"https://til.example/\"\n\n[gist]\ntoken_env = \"TIL_TEST_GITHUB\"\napi = \""+srv.URL+"\"\n")
+	if _, err := run(t, root, "gist", "slices"); err == nil || !strings.Contains(err.Error(), "$TIL_TEST_GITHUB is not set") {
+		t.Errorf("gist without a token = %v", err)
+	}
+	if out := mustRun(t, root, "gist", "slices", "-n"); out != "slices.md\nslices-1.go\n" || len(requests) != 0 {
+		t.Errorf("gist -n =\n%s", out)
+	}
+
+	t.Setenv("TIL_TEST_GITHUB", "tok")
+	if _, err := run(t, root, "gist", "secret"); err == nil || err.Error() != "go/secret.md is private" {
+		t.Errorf("gist of a private entry = %v", err)
+	}
+	if out := mustRun(t, root, "gist", "slices"); out != "https://gist.github.com/g1\n" {
+		t.Errorf("gist =\n%s", out)
+	}
+	if md != "\nSee [maps](https://til.example/go/maps/).\n\n```go\nfmt.Println(1)\n```\n" {
+		t.Errorf("markdown = %q, want no frontmatter and absolute links", md)
+	}
+	if got := readFile(t, root, "go/slices.md"); !strings.Contains(got, "gist: g1\n") {
+		t.Errorf("gist ID not recorded:\n%s", got)
+	}
+	mustRun(t, root, "gist", "slices")
+	mustRun(t, root, "gist", "slices", "--new")
+	if want := "POST /gists,GET /gists/g1,PATCH /gists/g1,POST /gists"; strings.Join(requests, ",") != want {
+		t.Errorf("requests = %q, want %s", requests, want)
+	}
+	if _, err := run(t, root, "gist", "gone"); err == nil || err.Error() != "gist gone: gist not found; pass --new to create another" {
+		t.Errorf("gist of a deleted gist = %v", err)
+	}
+}
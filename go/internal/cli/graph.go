@@ -0,0 +1,111 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+
+	"github.com/spf13/cobra"
+
+	"github.com/canhta/til/go/internal/links"
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+func newGraphCmd(a *app) *cobra.Command {
+	var format string
+	cmd := &cobra.Command{
+		Use:   "graph",
+		Short: "Print the link graph between entries",
+		Long: `Graph prints the links between entries, from [[wiki links]] and relative
+markdown links, as Graphviz dot or JSON. Links naming no entry are reported
+on stderr.`,
+		Example: `  til graph | dot -Tsvg > graph.svg
+  til graph --json`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			entries, err := a.tree.Entries()
+			if err != nil {
+				return err
+			}
+			g := links.NewGraph(entries)
+			printDangling(cmd.ErrOrStderr(), g.Dangling)
+			switch format {
+			case "dot":
+				return a.output(cmd, graphData(entries, g), func(w io.Writer) error { return writeDot(w, entries, g) })
+			case "json":
+				return writeJSON(cmd.OutOrStdout(), "graph", graphData(entries, g))
+			}
+			return fmt.Errorf("unknown format %q (want dot or json)", format)
+		},
+	}
+	cmd.Flags().StringVarP(&format, "format", "f", "dot", "output format: dot or json (the same as --json)")
+	return withJSON(cmd, "graph")
+}
+
+// printDangling warns about links that do not resolve to an entry.
+func printDangling(w io.Writer, ds []links.Dangling) {
+	for _, d := range ds {
+		why := "names no entry"
+		if d.Ambiguous {
+			why = "names several entries"
+		}
+		fmt.Fprintf(w, "warning: %s:%d: [[%s]] %s\n", d.From, d.Link.Line, d.Link.Target, why)
+	}
+}
+
+func writeDot(w io.Writer, entries []*entry.Entry, g *links.Graph) error {
+	q := strconv.Quote
+	fmt.Fprintln(w, "digraph til {")
+	fmt.Fprintln(w, "\tnode [shape=box, style=rounded];")
+	byCat := map[string][]*entry.Entry{}
+	var cats []string
+	for _, e := range entries {
+		if byCat[e.Meta.Category] == nil {
+			cats = append(cats, e.Meta.Category)
+		}
+		byCat[e.Meta.Category] = append(byCat[e.Meta.Category], e)
+	}
+	sort.Strings(cats)
+	for i, c := range cats {
+		fmt.Fprintf(w, "\tsubgraph cluster_%d {\n\t\tlabel=%s;\n", i, q(c))
+		for _, e := range byCat[c] {
+			fmt.Fprintf(w, "\t\t%s [label=%s];\n", q(e.Path), q(e.Meta.Title))
+		}
+		fmt.Fprintln(w, "\t}")
+	}
+	for _, e := range entries {
+		for _, to := range g.Out[e.Path] {
+			fmt.Fprintf(w, "\t%s -> %s;\n", q(e.Path), q(to))
+		}
+	}
+	_, err := fmt.Fprintln(w, "}")
+	return err
+}
+
+type graphJSON struct {
+	Nodes []graphNode `json:"nodes"`
+	Edges []graphEdge `json:"edges"`
+}
+
+type graphNode struct {
+	Path     string `json:"path"`
+	Title    string `json:"title"`
+	Category string `json:"category"`
+}
+
+type graphEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+func graphData(entries []*entry.Entry, g *links.Graph) graphJSON {
+	out := graphJSON{Nodes: []graphNode{}, Edges: []graphEdge{}}
+	for _, e := range entries {
+		out.Nodes = append(out.Nodes, graphNode{Path: e.Path, Title: e.Meta.Title, Category: e.Meta.Category})
+		for _, to := range g.Out[e.Path] {
+			out.Edges = append(out.Edges, graphEdge{From: e.Path, To: to})
+		}
+	}
+	return out
+}
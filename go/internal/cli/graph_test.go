@@ -0,0 +1,32 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGraph(t *testing.T) {
+	root := newTree(t, map[string]string{
+		"go/slices.md":  "# Slices\n\nSee [[maps]] and [[nowhere]].\n",
+		"go/maps.md":    "# Maps\n",
+		"git/rebase.md": "# Rebase \"onto\"\n\nSee [slices](../go/slices.md).\n",
+	})
+	want := "warning: go/slices.md:3: [[nowhere]] names no entry\n" +
+		"digraph til {\n" +
+		"\tnode [shape=box, style=rounded];\n" +
+		"\tsubgraph cluster_0 {\n\t\tlabel=\"git\";\n\t\t\"git/rebase.md\" [label=\"Rebase \\\"onto\\\"\"];\n\t}\n" +
+		"\tsubgraph cluster_1 {\n\t\tlabel=\"go\";\n\t\t\"go/maps.md\" [label=\"Maps\"];\n\t\t\"go/slices.md\" [label=\"Slices\"];\n\t}\n" +
+		"\t\"git/rebase.md\" -> \"go/slices.md\";\n" +
+		"\t\"go/slices.md\" -> \"go/maps.md\";\n" +
+		"}\n"
+	if out := mustRun(t, root, "graph"); out != want {
+		t.Errorf("graph =\n%s\nwant\n%s", out, want)
+	}
+	out := mustRun(t, root, "graph", "--format", "json")
+	if !strings.Contains(out, `"kind": "graph"`) || !strings.Contains(out, `"edges"`) {
+		t.Errorf("graph --format json =\n%s", out)
+	}
+	if _, err := run(t, root, "graph", "--format", "svg"); err == nil {
+		t.Error("graph --format svg succeeded")
+	}
+}
@@ -0,0 +1,173 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/canhta/til/go/internal/grep"
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+// grepCount is the number of matching lines of an entry, for -c and -l.
+type grepCount struct {
+	Path    string `json:"path"`
+	Matches int    `json:"matches"`
+}
+
+func newGrepCmd(a *app) *cobra.Command {
+	var (
+		opts                     grep.Options
+		ignoreCase, fixed, words bool
+		context                  int
+		files, count             bool
+	)
+	cmd := &cobra.Command{
+		Use:   "grep <pattern> [entry...]",
+		Short: "Find the lines of entries matching a regular expression",
+		Long: `Grep prints the lines of entries matching a regular expression, in RE2
+syntax, as path:line:text, with context lines as path-line-text and "--"
+between groups. It searches every entry, drafts included, or just those
+given.
+
+--in code searches only the lines inside fenced code blocks, and --lang
+only the blocks of a language, so as to find the snippets using a function
+without the prose mentioning it; --in prose searches the rest of the body.
+The whole file, frontmatter included, is searched by default. Grep exits
+with status 1 when no line matches.`,
+		Example: `  til grep 'copy\(' --in code --lang go
+  til grep -i --context 2 'rebase --onto'
+  til grep -l '^tags:.*\bgo\b'`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(opts.Langs) > 0 && !cmd.Flags().Changed("in") {
+				opts.In = grep.Code
+			}
+			if err := opts.Check(); err != nil {
+				return fmt.Errorf("--in: %w", err)
+			}
+			if !cmd.Flags().Changed("before") {
+				opts.Before = context
+			}
+			if !cmd.Flags().Changed("after") {
+				opts.After = context
+			}
+			pattern := args[0]
+			if fixed {
+				pattern = regexp.QuoteMeta(pattern)
+			}
+			if words {
+				pattern = `\b(?:` + pattern + `)\b`
+			}
+			if ignoreCase {
+				pattern = "(?i)" + pattern
+			}
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return err
+			}
+			entries, err := a.grepEntries(args[1:])
+			if err != nil {
+				return err
+			}
+			var lines []grep.Line
+			var counts []grepCount
+			for _, e := range entries {
+				ls := grep.Entry(e, re, opts)
+				if len(ls) == 0 {
+					continue
+				}
+				lines = append(lines, ls...)
+				n := 0
+				for _, l := range ls {
+					if l.Match {
+						n++
+					}
+				}
+				counts = append(counts, grepCount{Path: e.Path, Matches: n})
+			}
+			if len(counts) == 0 {
+				return &exitError{code: 1, err: errors.New("no matches")}
+			}
+			if files || count {
+				return a.output(cmd, counts, func(w io.Writer) error {
+					for _, c := range counts {
+						if count {
+							fmt.Fprintf(w, "%s:%d\n", c.Path, c.Matches)
+						} else {
+							fmt.Fprintln(w, c.Path)
+						}
+					}
+					return nil
+				})
+			}
+			mark := func(s string) string { return s }
+			if isTerminal(cmd.OutOrStdout()) {
+				mark = func(s string) string { return "\x1b[1;33m" + s + "\x1b[0m" }
+			}
+			return a.output(cmd, lines, func(w io.Writer) error {
+				printGrep(w, lines, opts.Before > 0 || opts.After > 0, mark)
+				return nil
+			})
+		},
+	}
+	withJSON(cmd, "grep")
+	fs := cmd.Flags()
+	fs.StringVar(&opts.In, "in", grep.All, "where to search: all, code or prose")
+	fs.StringSliceVar(&opts.Langs, "lang", nil, "search only the code blocks of this fence language (repeatable; implies --in code)")
+	fs.BoolVarP(&ignoreCase, "ignore-case", "i", false, "match case-insensitively")
+	fs.BoolVarP(&fixed, "fixed-strings", "F", false, "match the pattern as plain text")
+	fs.BoolVar(&words, "word-regexp", false, "match only whole words")
+	fs.IntVar(&context, "context", 0, "lines of context around each match, as -B and -A together")
+	fs.IntVarP(&opts.Before, "before", "B", 0, "lines of context before each match")
+	fs.IntVarP(&opts.After, "after", "A", 0, "lines of context after each match")
+	fs.BoolVarP(&files, "files-with-matches", "l", false, "print only the paths of the entries that match")
+	fs.BoolVarP(&count, "count", "c", false, "print the number of matching lines of each entry")
+	return cmd
+}
+
+// grepEntries returns the entries named by refs, or every entry.
+func (a *app) grepEntries(refs []string) ([]*entry.Entry, error) {
+	if len(refs) == 0 {
+		return a.tree.Entries()
+	}
+	var out []*entry.Entry
+	for _, ref := range refs {
+		e, err := a.resolve(ref)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, e)
+	}
+	return out, nil
+}
+
+// printGrep prints lines as grep does, separating the groups of context
+// with "--" and passing the matches through mark.
+func printGrep(w io.Writer, lines []grep.Line, context bool, mark func(string) string) {
+	for i, l := range lines {
+		if context && i > 0 && (l.Path != lines[i-1].Path || l.Line != lines[i-1].Line+1) {
+			fmt.Fprintln(w, "--")
+		}
+		if !l.Match {
+			fmt.Fprintf(w, "%s-%d-%s\n", l.Path, l.Line, l.Text)
+			continue
+		}
+		var b strings.Builder
+		at := 0
+		for _, r := range l.Ranges {
+			if r[0] == r[1] {
+				continue
+			}
+			b.WriteString(l.Text[at:r[0]])
+			b.WriteString(mark(l.Text[r[0]:r[1]]))
+			at = r[1]
+		}
+		b.WriteString(l.Text[at:])
+		fmt.Fprintf(w, "%s:%d:%s\n", l.Path, l.Line, b.String())
+	}
+}
@@ -0,0 +1,67 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestGrep(t *testing.T) {
+	root := newTree(t, map[string]string{
+		"go/slices.md":  "---\ntitle: Copy slices\ntags: [go]\n---\n\nUse copy to copy.\n\n```go\nn := copy(dst, src)\n```\n",
+		"git/rebase.md": "---\ntitle: Rebase\ndraft: true\n---\n\nCopy the branch first.\n\n```sh\ngit branch copy\n```\n",
+	})
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"copy"}, "git/rebase.md:9:git branch copy\ngo/slices.md:6:Use copy to copy.\ngo/slices.md:9:n := copy(dst, src)\n"},
+		{[]string{"-i", "^copy", "--in", "prose"}, "git/rebase.md:6:Copy the branch first.\n"},
+		{[]string{"copy", "--lang", "go"}, "go/slices.md:9:n := copy(dst, src)\n"},
+		{[]string{"-F", "copy(", "--context", "1", "go/slices.md"}, "go/slices.md-8-```go\ngo/slices.md:9:n := copy(dst, src)\ngo/slices.md-10-```\n"},
+		{[]string{"--word-regexp", "-i", "cop", "-A", "1"}, ""},
+		{[]string{"title", "-B", "1", "-A", "0"}, "git/rebase.md-1----\ngit/rebase.md:2:title: Rebase\n--\ngo/slices.md-1----\ngo/slices.md:2:title: Copy slices\n"},
+		{[]string{"-i", "copy", "-c"}, "git/rebase.md:2\ngo/slices.md:3\n"},
+		{[]string{"-i", "copy", "-l", "--in", "code"}, "git/rebase.md\ngo/slices.md\n"},
+	}
+	for _, tt := range tests {
+		out, err := run(t, root, append([]string{"grep"}, tt.args...)...)
+		if tt.want == "" {
+			var exit *exitError
+			if !errors.As(err, &exit) || exit.code != 1 || out != "" {
+				t.Errorf("grep %q = %q, %v, want status 1", tt.args, out, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("grep %q: %v", tt.args, err)
+			continue
+		}
+		if out != tt.want {
+			t.Errorf("grep %q =\n%s\nwant\n%s", tt.args, out, tt.want)
+		}
+	}
+
+	out := mustRun(t, root, "grep", "copy\\(", "--json")
+	var doc struct {
+		Kind string
+		Data []struct {
+			Path, Text, Lang string
+			Line             int
+			Match            bool
+			Ranges           [][]int
+		}
+	}
+	if err := json.Unmarshal([]byte(out), &doc); err != nil || doc.Kind != "grep" || len(doc.Data) != 1 {
+		t.Fatalf("grep --json: %v\n%s", err, out)
+	}
+	if l := doc.Data[0]; l.Path != "go/slices.md" || l.Line != 9 || l.Lang != "go" || !l.Match || len(l.Ranges) != 1 || l.Ranges[0][0] != 5 {
+		t.Errorf("grep --json = %+v", l)
+	}
+
+	for _, args := range [][]string{{"copy", "--in", "front"}, {"("}, {"copy", "go/nowhere.md"}} {
+		if _, err := run(t, root, append([]string{"grep"}, args...)...); err == nil {
+			t.Errorf("grep %q succeeded", args)
+		}
+	}
+}
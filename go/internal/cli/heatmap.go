@@ -0,0 +1,60 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/canhta/til/go/internal/fsutil"
+	"github.com/canhta/til/go/internal/heatmap"
+)
+
+func newHeatmapCmd(a *app) *cobra.Command {
+	var (
+		year     int
+		svg      string
+		gitDates bool
+	)
+	cmd := &cobra.Command{
+		Use:   "heatmap",
+		Short: "Show a calendar heatmap of entry creation dates",
+		Long: `Heatmap draws a GitHub-style calendar of the past year, or of --year, with
+each day shaded by the number of entries created on it. With --svg it writes
+the calendar as an SVG image instead; til build embeds the same image on the
+site's index page.`,
+		Example: `  til heatmap
+  til heatmap --year 2024 --svg heatmap.svg`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			entries, err := a.datedEntries(cmd.Context(), gitDates)
+			if err != nil {
+				return err
+			}
+			var m *heatmap.Map
+			if year != 0 {
+				m = heatmap.Year(entries, year)
+			} else {
+				m = heatmap.LastYear(entries, time.Now())
+			}
+			switch svg {
+			case "":
+				out := cmd.OutOrStdout()
+				return m.WriteTerminal(out, isTerminal(out) && os.Getenv("NO_COLOR") == "")
+			case "-":
+				_, err := cmd.OutOrStdout().Write(m.SVG())
+				return err
+			}
+			if err := fsutil.WriteFile(svg, m.SVG(), 0o644); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.ErrOrStderr(), "wrote %s\n", svg)
+			return nil
+		},
+	}
+	cmd.Flags().IntVar(&year, "year", 0, "show this calendar year instead of the last 52 weeks")
+	cmd.Flags().StringVar(&svg, "svg", "", `write an SVG image to this file ("-" for stdout)`)
+	cmd.Flags().BoolVar(&gitDates, "git-dates", false, "date entries by their first and last commit")
+	return cmd
+}
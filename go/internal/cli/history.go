@@ -0,0 +1,154 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/canhta/til/go/internal/git"
+	"github.com/canhta/til/go/internal/wdiff"
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+// DiffContext is the number of unchanged lines til diff shows around
+// changes by default.
+const DiffContext = 3
+
+func newHistoryCmd(a *app) *cobra.Command {
+	var limit int
+	cmd := &cobra.Command{
+		Use:   "history <entry>",
+		Short: "List the commits that changed an entry",
+		Long: `History lists the commits that changed an entry, newest first, with their
+dates, authors and messages. Commits from before the entry was renamed are
+included, marked with the path it had then. Pass a commit to til diff to
+see what changed since.`,
+		Example: `  til history go/slices
+  til history go/slices -n 5`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			e, err := a.resolve(args[0])
+			if err != nil {
+				return err
+			}
+			repo, file, err := a.entryRepo(cmd.Context(), e)
+			if err != nil {
+				return err
+			}
+			revs, err := repo.Log(cmd.Context(), file)
+			if err != nil {
+				return err
+			}
+			if limit > 0 && len(revs) > limit {
+				revs = revs[:limit]
+			}
+			return a.output(cmd, revs, func(w io.Writer) error {
+				if len(revs) == 0 {
+					fmt.Fprintf(w, "%s has not been committed.\n", e.Path)
+					return nil
+				}
+				current, _ := filepath.Rel(repo.Root, file)
+				tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+				fmt.Fprintln(tw, "DATE\tCOMMIT\tAUTHOR\tMESSAGE")
+				for _, r := range revs {
+					msg := r.Subject
+					if r.Path != filepath.ToSlash(current) {
+						msg += fmt.Sprintf(" (as %s)", r.Path)
+					}
+					fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", r.Date.Format(entry.DateLayout), r.Short(), r.Author, msg)
+				}
+				return tw.Flush()
+			})
+		},
+	}
+	withJSON(cmd, "history")
+	cmd.Flags().IntVarP(&limit, "limit", "n", 0, "show at most this many commits")
+	return cmd
+}
+
+func newDiffCmd(a *app) *cobra.Command {
+	var (
+		context int
+		all     bool
+	)
+	cmd := &cobra.Command{
+		Use:   "diff <entry> <rev>",
+		Short: "Show the words of an entry changed since a commit",
+		Long: `Diff compares an entry as of a commit with its file now, word by word:
+removed words are shown [-like this-] and added ones {+like this+}, or in
+red and green on a terminal. Only the changed lines are shown, with a few
+around them for context. The commit is anything git understands, such as a
+hash from til history, HEAD~2 or a tag; the entry is followed across
+renames.`,
+		Example: `  til diff go/slices HEAD
+  til diff go/slices 3f2a9c1 --all`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			e, err := a.resolve(args[0])
+			if err != nil {
+				return err
+			}
+			repo, file, err := a.entryRepo(ctx, e)
+			if err != nil {
+				return err
+			}
+			revs, err := repo.Log(ctx, file)
+			if err != nil {
+				return err
+			}
+			rev, ok, err := repo.At(ctx, revs, args[1])
+			if err != nil {
+				return err
+			}
+			if !ok {
+				return fmt.Errorf("%s did not exist as of %s", e.Path, args[1])
+			}
+			old, err := repo.Show(ctx, rev.Hash, rev.Path)
+			if err != nil {
+				return err
+			}
+			cur, err := a.tree.Read(e.Path)
+			if err != nil {
+				return err
+			}
+			out := cmd.OutOrStdout()
+			chunks := wdiff.Diff(string(old), string(cur))
+			if !wdiff.Changed(chunks) {
+				fmt.Fprintf(out, "%s has not changed since %s.\n", e.Path, rev.Short())
+				return nil
+			}
+			fmt.Fprintf(out, "%s since %s %s (%s)\n", e.Path, rev.Short(), rev.Subject, rev.Date.Format(entry.DateLayout))
+			if all {
+				context = -1
+			}
+			return wdiff.Write(out, chunks, context, isTerminal(out) && os.Getenv("NO_COLOR") == "")
+		},
+	}
+	cmd.Flags().IntVarP(&context, "context", "U", DiffContext, "unchanged lines shown around each change")
+	cmd.Flags().BoolVar(&all, "all", false, "show the whole entry")
+	return cmd
+}
+
+// entryRepo returns the repository holding e and the absolute path of its
+// file.
+func (a *app) entryRepo(ctx context.Context, e *entry.Entry) (*git.Repo, string, error) {
+	repo, err := git.Open(ctx, a.tree.Root)
+	if errors.Is(err, git.ErrNotRepo) {
+		return nil, "", fmt.Errorf("%s is not in a git repository", a.tree.Root)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	file, err := filepath.EvalSymlinks(a.tree.Abs(e.Path))
+	if err != nil {
+		return nil, "", err
+	}
+	return repo, file, nil
+}
@@ -0,0 +1,58 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHistoryAndDiff(t *testing.T) {
+	root := newTree(t, map[string]string{"go/slice.md": "# Slices\n\nA slice may copy the array.\n\nThe end.\n"})
+	initGit(t, root)
+	first := strings.TrimSpace(runGit(t, root, "rev-parse", "--short", "HEAD"))
+	runGit(t, root, "mv", "go/slice.md", "go/slices.md")
+	runGit(t, root, "commit", "--quiet", "-m", "Rename slices")
+	writeFile(t, root, "go/slices.md", "# Slices\n\nA slice always shares the array.\n\nThe end.\n")
+	runGit(t, root, "commit", "--quiet", "-am", "Explain slices")
+
+	out := mustRun(t, root, "history", "go/slices")
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 4 || !strings.HasPrefix(lines[0], "DATE") ||
+		!strings.HasSuffix(lines[1], "Ann     Explain slices") ||
+		!strings.HasSuffix(lines[3], "init (as go/slice.md)") || !strings.Contains(lines[3], first) {
+		t.Errorf("history =\n%s", out)
+	}
+	if out := mustRun(t, root, "history", "go/slices", "-n", "1"); strings.Count(out, "\n") != 2 {
+		t.Errorf("history -n 1 =\n%s", out)
+	}
+
+	writeFile(t, root, "go/slices.md", "# Slices\n\nA slice always shares the array.\n\nThe very end.\n")
+	out = mustRun(t, root, "diff", "go/slices", first, "-U", "0")
+	if !strings.HasPrefix(out, "go/slices.md since "+first+" init (") ||
+		!strings.Contains(out, "@@ line 3 @@\nA slice [-may-]{+always+} [-copy-]{+shares+} the array.\n@@ line 5 @@\nThe{+ very+} end.\n") {
+		t.Errorf("diff =\n%s", out)
+	}
+	if out := mustRun(t, root, "diff", "go/slices", "HEAD", "--all"); !strings.Contains(out, "# Slices\n\nA slice always shares the array.\n\nThe {+very +}end.\n") {
+		t.Errorf("diff --all =\n%s", out)
+	}
+	writeFile(t, root, "go/slices.md", "# Slices\n\nA slice always shares the array.\n\nThe end.\n")
+	if out := mustRun(t, root, "diff", "go/slices", "HEAD"); !strings.Contains(out, "has not changed since") {
+		t.Errorf("diff of an unchanged entry =\n%s", out)
+	}
+
+	writeFile(t, root, "go/new.md", "# New\n")
+	if out := mustRun(t, root, "history", "go/new"); out != "go/new.md has not been committed.\n" {
+		t.Errorf("history of a new entry = %q", out)
+	}
+	for _, args := range [][]string{{"diff", "go/new", "HEAD"}, {"diff", "go/slices", "nope"}} {
+		if _, err := run(t, root, args...); err == nil {
+			t.Errorf("%s succeeded", strings.Join(args, " "))
+		}
+	}
+}
+
+func TestHistoryNoRepo(t *testing.T) {
+	root := newTree(t, map[string]string{"go/slices.md": "# Slices\n"})
+	if _, err := run(t, root, "history", "go/slices"); err == nil || !strings.Contains(err.Error(), "is not in a git repository") {
+		t.Errorf("history outside a repo = %v", err)
+	}
+}
@@ -0,0 +1,69 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestHooks(t *testing.T) {
+	root := newTree(t, map[string]string{
+		"go/slices.md": "---\ntitle: Slices\ndate: 2024-03-01\n---\n\nAppend.\n",
+		"go/maps.md":   "---\ntitle: Maps\ndate: 2024-03-02\ndraft: true\n---\n\nRandom.\n",
+	})
+	bin := t.TempDir()
+	log := filepath.Join(bin, "log")
+	// Each hook logs its event and last argument, pre-save printing nothing
+	// to keep the content.
+	hook := filepath.Join(bin, "hook")
+	if err := os.WriteFile(hook, []byte("#!/bin/sh\ncat > /dev/null\necho \"$1 ${2:-}\" >> "+log+"\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	editor := filepath.Join(bin, "editor")
+	if err := os.WriteFile(editor, []byte("#!/bin/sh\necho 'Edited.' >> \"$1\"\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	writeConfig(t, "editor = "+quote(editor)+"\n[site]\nbase_url = \"https://til.example.com\"\n[hooks]\n"+
+		"pre_save = [["+quote(hook)+", \"pre-save\"]]\n"+
+		"post_save = [["+quote(hook)+", \"post-save\"]]\n"+
+		"pre_build = [["+quote(hook)+", \"pre-build\"]]\n"+
+		"post_publish = [["+quote(hook)+", \"post-publish\"]]\n")
+	readLog := func() string {
+		t.Helper()
+		data, _ := os.ReadFile(log)
+		os.Remove(log)
+		return string(data)
+	}
+
+	mustRun(t, root, "new", "go", "Generics", "--no-edit")
+	if got := readLog(); got != "pre-save go/generics.md\npost-save go/generics.md\n" {
+		t.Errorf("hooks of til new ran as\n%s", got)
+	}
+	mustRun(t, root, "edit", "go/slices.md")
+	if got := readLog(); got != "pre-save go/slices.md\npost-save go/slices.md\n" {
+		t.Errorf("hooks of til edit ran as\n%s", got)
+	}
+	if got := readFile(t, root, "go/slices.md"); !strings.HasSuffix(got, "Append.\nEdited.\n") {
+		t.Errorf("edited entry =\n%s", got)
+	}
+	mustRun(t, root, "build")
+	if got := readLog(); got != "pre-build \n" {
+		t.Errorf("hooks of til build ran as\n%s", got)
+	}
+	mustRun(t, root, "publish", "go/maps.md")
+	if got := readLog(); !strings.HasSuffix(got, "post-publish go/maps.md\n") || !strings.HasPrefix(got, "pre-save go/maps.md\n") {
+		t.Errorf("hooks of til publish ran as\n%s", got)
+	}
+
+	writeConfig(t, "[hooks]\npre_save = [[\"false\"]]\npre_build = [[\"false\"]]\n")
+	if _, err := run(t, root, "new", "go", "Errors", "--no-edit"); err == nil || !strings.Contains(err.Error(), "pre-save hook false") {
+		t.Errorf("new with a failing pre-save hook = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "go", "errors.md")); err == nil {
+		t.Error("entry written despite the failing pre-save hook")
+	}
+	if _, err := run(t, root, "build"); err == nil || !strings.Contains(err.Error(), "pre-build hook false") {
+		t.Errorf("build with a failing pre-build hook = %v", err)
+	}
+}
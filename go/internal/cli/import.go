@@ -0,0 +1,417 @@
+package cli
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/spf13/cobra"
+
+	"github.com/canhta/til/go/internal/capture"
+	"github.com/canhta/til/go/internal/importer"
+	"github.com/canhta/til/go/internal/notes"
+)
+
+func newImportCmd(a *app) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "import",
+		Short: "Import notes from other applications",
+		Long: `Import converts notes from other applications into entries. Each entry
+records the note it came from as imported in its frontmatter, so running
+an import again only adds the notes that are new.`,
+	}
+	cmd.AddCommand(
+		newImportObsidianCmd(a),
+		newImportNotionCmd(a),
+		newImportBookmarksCmd(a),
+		newImportReadLaterCmd(a, "pocket", importer.Pocket),
+		newImportReadLaterCmd(a, "instapaper", importer.Instapaper),
+	)
+	return cmd
+}
+
+func newImportObsidianCmd(a *app) *cobra.Command {
+	var (
+		mapFile  string
+		category string
+		dryRun   bool
+	)
+	cmd := &cobra.Command{
+		Use:   "obsidian <vault>",
+		Short: "Import the notes of an Obsidian vault",
+		Long: `Import the notes of an Obsidian vault. Notes in the vault's top folder go in
+the category given by --category, inbox by default, and notes in folders
+in the category named after the top folder, with deeper folders as tags.
+A --map file assigns categories to folders instead:
+
+  "Programming/Go" = "go"
+  Journal = "daily"
+
+Frontmatter is carried over, with tags, aliases and dates, and inline
+#tags are added to the tags. Wiki links to other notes of the vault point
+to their entries, embedded notes become include directives, and
+attachments are copied into the assets of the entries using them.
+Callouts are mapped to the five callout types entries support and
+%%comments%% are removed. Canvases and Excalidraw drawings are skipped.`,
+		Example: `  til import obsidian ~/Notes --dry-run
+  til import obsidian ~/Notes --map folders.toml`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts := importer.ObsidianOptions{Category: category}
+			if mapFile != "" {
+				if _, err := toml.DecodeFile(mapFile, &opts.Map); err != nil {
+					return fmt.Errorf("%s: %w", mapFile, err)
+				}
+			}
+			for _, c := range append([]string{category}, mapValues(opts.Map)...) {
+				if c != "" && (notes.Skip(c) || strings.ContainsAny(c, `/\`)) {
+					return fmt.Errorf("invalid category %q", c)
+				}
+			}
+			if info, err := os.Stat(args[0]); err != nil {
+				return err
+			} else if !info.IsDir() {
+				return fmt.Errorf("%s is not a directory", args[0])
+			}
+			ns, skipped, err := importer.Obsidian(os.DirFS(args[0]), opts)
+			if err != nil {
+				return err
+			}
+			return a.importNotes(cmd, ns, skipped, dryRun, nil)
+		},
+	}
+	withJSON(cmd, "import")
+	cmd.Flags().StringVar(&mapFile, "map", "", "TOML `file` mapping vault folders to categories")
+	cmd.Flags().StringVarP(&category, "category", "c", importer.DefaultCategory, "category of the notes in the vault's top folder")
+	cmd.Flags().BoolVarP(&dryRun, "dry-run", "n", false, "list the entries that would be created without writing them")
+	a.commitFlag(cmd)
+	return cmd
+}
+
+func newImportNotionCmd(a *app) *cobra.Command {
+	var (
+		category string
+		dryRun   bool
+	)
+	cmd := &cobra.Command{
+		Use:   "notion <export>",
+		Short: "Import the pages of a Notion export",
+		Long: `Import the pages of a Notion workspace export, in Markdown & CSV or HTML,
+given as the downloaded zip file or the folder it was extracted to.
+Exports split into several archives are read whole.
+
+The IDs Notion appends to file names are removed. Subpages of a top-level
+page go in the category named after it, with the pages between as tags;
+top-level pages without subpages go in the category given by --category,
+inbox by default. The rows of a database become entries tagged with its
+name, their properties carried over as frontmatter: tags, created and
+edited dates, a URL as source, and the others as they are. Links between
+pages point to their entries and attachments are copied into the assets
+of the entries using them. Pages that only link to their subpages are
+skipped.`,
+		Example: `  til import notion ~/Downloads/Export-3f2a.zip --dry-run`,
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if category != "" && (notes.Skip(category) || strings.ContainsAny(category, `/\`)) {
+				return fmt.Errorf("invalid category %q", category)
+			}
+			info, err := os.Stat(args[0])
+			if err != nil {
+				return err
+			}
+			fsys := os.DirFS(args[0])
+			if !info.IsDir() {
+				z, err := zip.OpenReader(args[0])
+				if err != nil {
+					return fmt.Errorf("%s: %w", args[0], err)
+				}
+				defer z.Close()
+				fsys = z
+			}
+			ns, skipped, err := importer.Notion(fsys, importer.NotionOptions{Category: category})
+			if err != nil {
+				return err
+			}
+			return a.importNotes(cmd, ns, skipped, dryRun, nil)
+		},
+	}
+	withJSON(cmd, "import")
+	cmd.Flags().StringVarP(&category, "category", "c", importer.DefaultCategory, "category of top-level pages without subpages")
+	cmd.Flags().BoolVarP(&dryRun, "dry-run", "n", false, "list the entries that would be created without writing them")
+	a.commitFlag(cmd)
+	return cmd
+}
+
+func newImportBookmarksCmd(a *app) *cobra.Command {
+	var (
+		category string
+		fetch    bool
+		timeout  time.Duration
+		dryRun   bool
+	)
+	cmd := &cobra.Command{
+		Use:   "bookmarks <file.html>",
+		Short: "Import browser bookmarks as a reading list",
+		Long: `Import the bookmarks a browser exports as an HTML file, in the Netscape
+bookmark format, as stub entries tagged reading-list with the bookmarked
+URL as source. A bookmark's folder is its category, and the folders over
+it are tags; bookmarks outside folders go in the category given by
+--category, inbox by default. Bookmarks whose URL is the source of an
+entry already are skipped, so a newer export can be imported again.
+
+With --fetch, each new bookmark's page is fetched as til capture does,
+adding an excerpt of its text, its author and its publication date.`,
+		Example: `  til import bookmarks bookmarks.html --dry-run
+  til import bookmarks bookmarks.html --fetch -c reading`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if category != "" && (notes.Skip(category) || strings.ContainsAny(category, `/\`)) {
+				return fmt.Errorf("invalid category %q", category)
+			}
+			f, err := os.Open(args[0])
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			ns, skipped, err := importer.Bookmarks(f, importer.BookmarksOptions{Category: category})
+			if err != nil {
+				return fmt.Errorf("%s: %w", args[0], err)
+			}
+			var prepare func([]*importer.Note)
+			if fetch && !dryRun {
+				prepare = func(ns []*importer.Note) { a.fetchPages(cmd, ns, timeout) }
+			}
+			return a.importNotes(cmd, ns, skipped, dryRun, prepare)
+		},
+	}
+	withJSON(cmd, "import")
+	cmd.Flags().StringVarP(&category, "category", "c", importer.DefaultCategory, "category of bookmarks outside folders")
+	cmd.Flags().BoolVar(&fetch, "fetch", false, "fetch each new bookmark's page for an excerpt")
+	cmd.Flags().DurationVar(&timeout, "timeout", 15*time.Second, "give up fetching a page after this long")
+	cmd.Flags().BoolVarP(&dryRun, "dry-run", "n", false, "list the entries that would be created without writing them")
+	a.commitFlag(cmd)
+	return cmd
+}
+
+// readLaterDocs holds the help of the read-later importers by command.
+var readLaterDocs = map[string]struct{ use, short, long, example string }{
+	"pocket": {
+		use:   "pocket <export>",
+		short: "Import the articles saved in Pocket",
+		long: `Import the articles saved in Pocket, from the HTML file of older exports, a
+CSV file of newer ones or the zip file holding them. Each article becomes a
+stub entry tagged reading-list, with its URL as source, dated when it was
+saved and with its Pocket tags; those in the archive are marked read.`,
+		example: `  til import pocket ~/Downloads/pocket.zip -c reading`,
+	},
+	"instapaper": {
+		use:   "instapaper <export.csv>",
+		short: "Import the articles saved in Instapaper",
+		long: `Import the articles saved in Instapaper, from its CSV export. Each article
+becomes a stub entry tagged reading-list, with its URL as source, dated
+when it was saved, with its tags and any text selected in it quoted.
+Articles in folders of your own go in the category named after the
+folder; archived ones are marked read and starred ones tagged starred.`,
+		example: `  til import instapaper instapaper-export.csv --dry-run`,
+	},
+}
+
+// newImportReadLaterCmd returns the command importing the export of a
+// read-later service with convert.
+func newImportReadLaterCmd(a *app, name string, convert func(io.Reader, importer.ReadLaterOptions) ([]*importer.Note, []importer.Skipped, error)) *cobra.Command {
+	var (
+		category string
+		fetch    bool
+		timeout  time.Duration
+		dryRun   bool
+	)
+	doc := readLaterDocs[name]
+	cmd := &cobra.Command{
+		Use:   doc.use,
+		Short: doc.short,
+		Long: doc.long + `
+
+Articles whose URL is the source of an entry already are skipped, so a
+newer export can be imported again. With --fetch, each new article's page
+is fetched as til capture does, adding an excerpt of its text, its author
+and its publication date.`,
+		Example: doc.example,
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if category != "" && (notes.Skip(category) || strings.ContainsAny(category, `/\`)) {
+				return fmt.Errorf("invalid category %q", category)
+			}
+			opts := importer.ReadLaterOptions{Category: category}
+			var ns []*importer.Note
+			var skipped []importer.Skipped
+			add := func(r io.Reader, file string) error {
+				n, s, err := convert(r, opts)
+				if err != nil {
+					return fmt.Errorf("%s: %w", file, err)
+				}
+				ns, skipped = append(ns, n...), append(skipped, s...)
+				return nil
+			}
+			if strings.EqualFold(filepath.Ext(args[0]), ".zip") {
+				z, err := zip.OpenReader(args[0])
+				if err != nil {
+					return fmt.Errorf("%s: %w", args[0], err)
+				}
+				defer z.Close()
+				for _, f := range z.File {
+					if !strings.EqualFold(path.Ext(f.Name), ".csv") && !strings.EqualFold(path.Ext(f.Name), ".html") {
+						continue
+					}
+					r, err := f.Open()
+					if err != nil {
+						return err
+					}
+					err = add(r, f.Name)
+					r.Close()
+					if err != nil {
+						return err
+					}
+				}
+			} else {
+				f, err := os.Open(args[0])
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+				if err := add(f, args[0]); err != nil {
+					return err
+				}
+			}
+			var prepare func([]*importer.Note)
+			if fetch && !dryRun {
+				prepare = func(ns []*importer.Note) { a.fetchPages(cmd, ns, timeout) }
+			}
+			return a.importNotes(cmd, ns, skipped, dryRun, prepare)
+		},
+	}
+	withJSON(cmd, "import")
+	cmd.Flags().StringVarP(&category, "category", "c", importer.DefaultCategory, "category to save the articles in")
+	cmd.Flags().BoolVar(&fetch, "fetch", false, "fetch each new article's page for an excerpt")
+	cmd.Flags().DurationVar(&timeout, "timeout", 15*time.Second, "give up fetching a page after this long")
+	cmd.Flags().BoolVarP(&dryRun, "dry-run", "n", false, "list the entries that would be created without writing them")
+	a.commitFlag(cmd)
+	return cmd
+}
+
+// fetchJobs is how many pages fetchPages fetches at once.
+const fetchJobs = 8
+
+// fetchPages adds an excerpt of the page a note is about to its body, with
+// its author and publication date. Pages that cannot be fetched are
+// reported and the notes left as they are.
+func (a *app) fetchPages(cmd *cobra.Command, ns []*importer.Note, timeout time.Duration) {
+	var (
+		mu  sync.Mutex
+		wg  sync.WaitGroup
+		sem = make(chan struct{}, fetchJobs)
+	)
+	for _, n := range ns {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			page, err := capture.Fetch(cmd.Context(), n.URL, capture.FetchOptions{Timeout: timeout, MaxBytes: maxPage})
+			if err != nil {
+				mu.Lock()
+				fmt.Fprintf(cmd.ErrOrStderr(), "warning: %v\n", err)
+				mu.Unlock()
+				return
+			}
+			n.Body = quoteExcerpt(page.Excerpt) + n.Body
+			if page.Author != "" {
+				n.Fields = append(n.Fields, importer.MetaField{Key: "author", Value: page.Author})
+			}
+			if d := page.Published; !d.IsZero() {
+				n.Fields = append(n.Fields, importer.MetaField{Key: "published", Value: time.Date(d.Year(), d.Month(), d.Day(), 0, 0, 0, 0, time.UTC)})
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// importNotes writes the converted notes ns to the tree, commits them, and
+// reports what was created, imported before and skipped. If prepare is not
+// nil, it is given the notes not imported before to complete first.
+func (a *app) importNotes(cmd *cobra.Command, ns []*importer.Note, skipped []importer.Skipped, dryRun bool, prepare func([]*importer.Note)) error {
+	if err := importer.Assign(a.tree, ns); err != nil {
+		return err
+	}
+	if prepare != nil {
+		prepare(slices.DeleteFunc(slices.Clone(ns), func(n *importer.Note) bool { return n.Path == "" }))
+	}
+	res := importer.Result{Created: []string{}, Existing: []string{}, Skipped: skipped, Assets: []string{}}
+	if res.Skipped == nil {
+		res.Skipped = []importer.Skipped{}
+	}
+	if err := importer.Write(a.tree, ns, &res, dryRun); err != nil {
+		return err
+	}
+	if !dryRun && len(res.Created) > 0 {
+		if err := a.commitEntry(cmd, res.Created[0], "import", slices.Concat(res.Created[1:], res.Assets)...); err != nil {
+			return err
+		}
+	}
+	return a.output(cmd, res, func(w io.Writer) error {
+		for _, p := range res.Created {
+			fmt.Fprintln(w, p)
+		}
+		for _, s := range res.Skipped {
+			fmt.Fprintf(cmd.ErrOrStderr(), "skipped %s: %s\n", s.Source, s.Reason)
+		}
+		verb, n := "created", "entries"
+		if dryRun {
+			verb = "would create"
+		}
+		if len(res.Created) == 1 {
+			n = "entry"
+		}
+		fmt.Fprintf(cmd.ErrOrStderr(), "%s %d %s, %d already imported, %d skipped\n",
+			verb, len(res.Created), n, len(res.Existing), len(res.Skipped))
+		return nil
+	})
+}
+
+// importNote writes the entry of the single note n and commits it with
+// verb, returning its path, or "" if n was imported before.
+func (a *app) importNote(cmd *cobra.Command, n *importer.Note, verb string, dryRun bool) (string, error) {
+	ns := []*importer.Note{n}
+	if err := importer.Assign(a.tree, ns); err != nil {
+		return "", err
+	}
+	var res importer.Result
+	if err := importer.Write(a.tree, ns, &res, dryRun); err != nil {
+		return "", err
+	}
+	if n.Path == "" {
+		return "", nil
+	}
+	if !dryRun {
+		if err := a.commitEntry(cmd, n.Path, verb, res.Assets...); err != nil {
+			return "", err
+		}
+	}
+	return n.Path, nil
+}
+
+// mapValues returns the values of m.
+func mapValues(m map[string]string) []string {
+	out := make([]string, 0, len(m))
+	for _, v := range m {
+		out = append(out, v)
+	}
+	return out
+}
@@ -0,0 +1,163 @@
+package cli
+
+import (
+	"archive/zip"
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeDir writes files below a new temporary directory and returns it.
+func writeDir(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for p, data := range files {
+		abs := filepath.Join(dir, filepath.FromSlash(p))
+		if err := os.MkdirAll(filepath.Dir(abs), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(abs, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestImportObsidian(t *testing.T) {
+	vault := writeDir(t, map[string]string{
+		"Programming/Go/Slices.md": "# Slices\n\nSee [[Rebase]].\n",
+		"Git/Rebase.md":            "Rebase.\n",
+		"Inbox.md":                 "Loose.\n",
+		"Board.canvas":             "{}",
+	})
+	mapFile := filepath.Join(t.TempDir(), "map.toml")
+	if err := os.WriteFile(mapFile, []byte("\"Programming/Go\" = \"go\"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	root := newTree(t, nil)
+
+	want := "git/rebase.md\nscratch/inbox.md\ngo/slices.md\nskipped Board.canvas: canvas\nwould create 3 entries, 0 already imported, 1 skipped\n"
+	args := []string{"import", "obsidian", vault, "--map", mapFile, "--category", "scratch"}
+	if out := mustRun(t, root, append(args, "-n")...); out != want {
+		t.Errorf("import obsidian -n =\n%s\nwant\n%s", out, want)
+	}
+	if _, err := os.Stat(filepath.Join(root, "go", "slices.md")); err == nil {
+		t.Error("dry run wrote an entry")
+	}
+	out := mustRun(t, root, args...)
+	if !strings.Contains(out, "go/slices.md\n") || !strings.Contains(out, "scratch/inbox.md\n") || !strings.Contains(out, "created 3 entries, 0 already imported, 1 skipped\n") {
+		t.Errorf("import obsidian =\n%s", out)
+	}
+	if got := readFile(t, root, "go/slices.md"); !strings.Contains(got, "See [[git/rebase]].\n") {
+		t.Errorf("go/slices.md =\n%s", got)
+	}
+	if out := mustRun(t, root, args...); !strings.Contains(out, "created 0 entries, 3 already imported") {
+		t.Errorf("second import =\n%s", out)
+	}
+
+	for _, bad := range [][]string{{"--category", "_drafts"}, {"--category", "a/b"}} {
+		if _, err := run(t, root, append([]string{"import", "obsidian", vault}, bad...)...); err == nil || !strings.Contains(err.Error(), "invalid category") {
+			t.Errorf("import obsidian %s = %v", strings.Join(bad, " "), err)
+		}
+	}
+	if _, err := run(t, root, "import", "obsidian", mapFile); err == nil || !strings.Contains(err.Error(), "is not a directory") {
+		t.Errorf("import obsidian of a file = %v", err)
+	}
+}
+
+func TestImportNotion(t *testing.T) {
+	id := "22222222222222222222222222222222"
+	var buf bytes.Buffer
+	z := zip.NewWriter(&buf)
+	f, _ := z.Create("Slices " + id + ".md")
+	f.Write([]byte("# Slices\n\nSlices share arrays.\n"))
+	z.Close()
+	export := filepath.Join(t.TempDir(), "Export.zip")
+	if err := os.WriteFile(export, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	root := newTree(t, nil)
+
+	if out := mustRun(t, root, "import", "notion", export, "-c", "go"); out != "go/slices.md\ncreated 1 entry, 0 already imported, 0 skipped\n" {
+		t.Errorf("import notion =\n%s", out)
+	}
+	if got := readFile(t, root, "go/slices.md"); !strings.Contains(got, "imported: notion:"+id+"\n") || !strings.HasSuffix(got, "\n# Slices\n\nSlices share arrays.\n") {
+		t.Errorf("go/slices.md =\n%s", got)
+	}
+	if out := mustRun(t, root, "import", "notion", export); out != "created 0 entries, 1 already imported, 0 skipped\n" {
+		t.Errorf("second import =\n%s", out)
+	}
+	notZip := filepath.Join(t.TempDir(), "export.zip")
+	if err := os.WriteFile(notZip, []byte("not a zip"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := run(t, root, "import", "notion", notZip); err == nil || !strings.HasPrefix(err.Error(), notZip+": ") {
+		t.Error("import notion of a file that is no zip succeeded")
+	}
+}
+
+func TestImportBookmarks(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/gone" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><head><title>Slices</title><meta name="author" content="Rob"></head><body><p>Slices share their backing arrays.</p></body></html>`))
+	}))
+	defer srv.Close()
+	file := filepath.Join(t.TempDir(), "bookmarks.html")
+	html := "<DL><p>\n<DT><H3>Go</H3>\n<DL><p>\n<DT><A HREF=\"" + srv.URL + "/slices\">Slices</A>\n<DT><A HREF=\"" + srv.URL + "/gone\">Gone</A>\n</DL><p>\n<DT><A HREF=\"ftp://example.com\">FTP</A>\n</DL>\n"
+	if err := os.WriteFile(file, []byte(html), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	root := newTree(t, nil)
+
+	out := mustRun(t, root, "import", "bookmarks", file, "--fetch")
+	if !strings.Contains(out, "go/slices.md\ngo/gone.md\n") || !strings.Contains(out, "warning: ") || !strings.Contains(out, "skipped ftp://example.com: not a web page\ncreated 2 entries, 0 already imported, 1 skipped\n") {
+		t.Errorf("import bookmarks --fetch =\n%s", out)
+	}
+	got := readFile(t, root, "go/slices.md")
+	for _, want := range []string{"tags: [reading-list]\n", "source: " + srv.URL + "/slices\n", "author: Rob\n", "Slices share their backing arrays.", "Source: <" + srv.URL + "/slices>\n"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("go/slices.md lacks %q:\n%s", want, got)
+		}
+	}
+	if out := mustRun(t, root, "import", "bookmarks", file); !strings.Contains(out, "created 0 entries, 2 already imported, 1 skipped\n") {
+		t.Errorf("second import =\n%s", out)
+	}
+}
+
+func TestImportPocket(t *testing.T) {
+	var buf bytes.Buffer
+	z := zip.NewWriter(&buf)
+	for name, data := range map[string]string{
+		"part_000000.csv": "title,url,time_added,tags,status\nSlices,https://go.dev/blog/slices,1709251200,go,unread\n",
+		"part_000001.csv": "title,url,time_added,tags,status\nEssay,https://example.com/essay,1709251200,,archive\n",
+		"README.txt":      "ignored",
+	} {
+		f, _ := z.Create(name)
+		f.Write([]byte(data))
+	}
+	z.Close()
+	export := filepath.Join(t.TempDir(), "pocket.zip")
+	if err := os.WriteFile(export, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	root := newTree(t, nil)
+
+	out := mustRun(t, root, "import", "pocket", export, "-c", "reading")
+	if !strings.Contains(out, "reading/slices.md\n") || !strings.Contains(out, "reading/essay.md\n") || !strings.Contains(out, "created 2 entries") {
+		t.Errorf("import pocket =\n%s", out)
+	}
+	if got := readFile(t, root, "reading/essay.md"); !strings.Contains(got, "source: https://example.com/essay\nread: true\n") {
+		t.Errorf("reading/essay.md =\n%s", got)
+	}
+	if out := mustRun(t, root, "import", "instapaper", export); !strings.Contains(out, "created 0 entries, 2 already imported") {
+		t.Errorf("import instapaper of the same URLs =\n%s", out)
+	}
+}
@@ -0,0 +1,156 @@
+package cli
+
+import (
+	"bytes"
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/canhta/til/go/internal/config"
+	"github.com/canhta/til/go/internal/fsutil"
+	"github.com/canhta/til/go/internal/mirror"
+	"github.com/canhta/til/go/internal/readme"
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+func newIndexCmd(a *app) *cobra.Command {
+	var (
+		opts  readme.Options
+		check bool
+		init  bool
+	)
+	cmd := &cobra.Command{
+		Use:   "index",
+		Short: "Regenerate the entry index in README.md",
+		Long: `Index regenerates the table of contents in README.md from the entries'
+frontmatter, leaving out drafts and private entries. Only the section between the
+` + readme.StartMarker + ` and ` + readme.EndMarker + ` markers is rewritten.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			file := filepath.Join(a.tree.Root, "README.md")
+			old, err := os.ReadFile(file)
+			if err != nil {
+				return err
+			}
+			entries, err := a.tree.Entries()
+			if err != nil {
+				return err
+			}
+			index := readme.Generate(entry.Public(entries), opts)
+			updated, err := readme.Splice(old, index)
+			if errors.Is(err, readme.ErrNoMarkers) && init {
+				updated = append(append(bytes.TrimRight(old, "\n"), "\n\n"...), index...)
+			} else if err != nil {
+				return fmt.Errorf("%w; add them where the index belongs or pass --init to append one", err)
+			}
+			if bytes.Equal(old, updated) {
+				return nil
+			}
+			if check {
+				return &exitError{code: 1, err: errors.New("README.md index is out of date; run til index")}
+			}
+			return fsutil.WriteFile(file, updated, 0o644)
+		},
+	}
+	cmd.AddCommand(newIndexPushCmd(a), newIndexRebuildCmd(a), newIndexDoctorCmd(a))
+	cmd.Flags().IntVar(&opts.Newest, "newest", 5, "number of newest entries to list")
+	cmd.Flags().BoolVar(&check, "check", false, "exit non-zero instead of writing when the index is stale")
+	cmd.Flags().BoolVar(&init, "init", false, "append an index when README.md has no markers")
+	return cmd
+}
+
+func newIndexPushCmd(a *app) *cobra.Command {
+	var (
+		target string
+		opts   mirror.Options
+	)
+	cmd := &cobra.Command{
+		Use:   "push",
+		Short: "Mirror the entries into Meilisearch or Elasticsearch",
+		Long: `Push mirrors the public entries into an index of a Meilisearch or
+Elasticsearch server, one document per entry with its path, title,
+category, tags, author, language, dates, site URL when [site] base_url is
+absolute, and markdown body. The index is created if needed.
+
+Pushes are incremental: only the entries changed since the last push to the
+index are sent, and the documents of entries removed, made private or
+drafts are deleted. --full sends every entry again, as after the index was
+emptied.
+
+The server is configured in [index.meilisearch] or [index.elasticsearch],
+with url, index and key_env, defaulting to localhost, the index til and
+the API key in $MEILISEARCH_API_KEY or $ELASTICSEARCH_API_KEY.`,
+		Example: `  til index push --target meilisearch
+  til index push --target elasticsearch --full`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			t, err := a.mirrorTarget(target)
+			if err != nil {
+				return err
+			}
+			all, err := a.tree.Entries()
+			if err != nil {
+				return err
+			}
+			entries := entry.Public(all)
+			url := a.entryURLs(entries)
+			docs := make([]mirror.Document, len(entries))
+			for i, e := range entries {
+				var u string
+				if url != nil {
+					u = url(e.Path)
+				}
+				docs[i] = mirror.NewDocument(e, u)
+			}
+			st, err := mirror.Push(cmd.Context(), a.tree, t, docs, opts)
+			if err != nil {
+				return err
+			}
+			return a.output(cmd, st, func(w io.Writer) error {
+				verb := "pushed"
+				if opts.DryRun {
+					verb = "would push"
+				}
+				fmt.Fprintf(w, "%s %s: %d updated, %d deleted, %d unchanged\n", verb, target, st.Upserted, st.Deleted, st.Unchanged)
+				return nil
+			})
+		},
+	}
+	withJSON(cmd, "push")
+	cmd.Flags().StringVar(&target, "target", "", "search engine: meilisearch or elasticsearch")
+	cmd.Flags().BoolVar(&opts.Full, "full", false, "send every entry, not only those changed since the last push")
+	cmd.Flags().BoolVarP(&opts.DryRun, "dry-run", "n", false, "count the changes without sending them")
+	_ = cmd.MarkFlagRequired("target")
+	return cmd
+}
+
+// mirrorTarget returns the index of the named search engine, as
+// configured.
+func (a *app) mirrorTarget(name string) (mirror.Target, error) {
+	var cfg config.SearchEngine
+	var url, env string
+	switch name {
+	case "meilisearch":
+		cfg, url, env = a.cfg.Index.Meilisearch, "http://localhost:7700", "MEILISEARCH_API_KEY"
+	case "elasticsearch":
+		cfg, url, env = a.cfg.Index.Elasticsearch, "http://localhost:9200", "ELASTICSEARCH_API_KEY"
+	default:
+		return nil, fmt.Errorf("unknown target %q: want meilisearch or elasticsearch", name)
+	}
+	if cfg.URL != "" {
+		url = cfg.URL
+	}
+	if cfg.KeyEnv != "" {
+		env = cfg.KeyEnv
+	}
+	index := cmp.Or(cfg.Index, mirror.DefaultIndex)
+	if name == "meilisearch" {
+		return mirror.Meilisearch{URL: url, Index: index, Key: os.Getenv(env)}, nil
+	}
+	return mirror.Elasticsearch{URL: url, Index: index, Key: os.Getenv(env)}, nil
+}
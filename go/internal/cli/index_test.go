@@ -0,0 +1,143 @@
+package cli
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIndex(t *testing.T) {
+	root := newTree(t, map[string]string{
+		"README.md":     "# Notes\n\nHand-written.\n\n<!-- til:index:start -->\nstale\n<!-- til:index:end -->\n\n## License\n",
+		"go/slices.md":  "---\ntitle: Slices\ndate: 2024-03-01\n---\n",
+		"go/wip.md":     "---\ntitle: Unfinished\ndraft: true\n---\n",
+		"git/rebase.md": "---\ntitle: Rebase\ndate: 2024-04-01\n---\n",
+	})
+	if _, err := run(t, root, "index", "--check"); err == nil || !strings.Contains(err.Error(), "out of date") {
+		t.Errorf("index --check of a stale index = %v", err)
+	}
+	mustRun(t, root, "index")
+	got := readFile(t, root, "README.md")
+	for _, want := range []string{
+		"# Notes\n\nHand-written.\n\n<!-- til:index:start -->\n\n_2 TILs across 2 categories._\n",
+		"- 2024-04-01 [Rebase](git/rebase.md) · git\n",
+		"<!-- til:index:end -->\n\n## License\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("README.md =\n%s\nwant %q", got, want)
+		}
+	}
+	if strings.Contains(got, "stale") || strings.Contains(got, "Unfinished") {
+		t.Errorf("README.md kept the old index or listed a draft:\n%s", got)
+	}
+	mustRun(t, root, "index", "--check")
+
+	writeFile(t, root, "README.md", "# Notes\n")
+	if _, err := run(t, root, "index"); err == nil || !strings.Contains(err.Error(), "--init") {
+		t.Errorf("index without markers = %v", err)
+	}
+	mustRun(t, root, "index", "--init")
+	if got := readFile(t, root, "README.md"); !strings.HasPrefix(got, "# Notes\n\n<!-- til:index:start -->\n") {
+		t.Errorf("README.md after --init =\n%s", got)
+	}
+}
+
+func TestIndexPush(t *testing.T) {
+	var upserts, deletes int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer k1" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/indexes/notes/documents":
+			upserts++
+		case "/indexes/notes/documents/delete-batch":
+			deletes++
+		case "/tasks/1":
+			io.WriteString(w, `{"status": "succeeded"}`)
+			return
+		}
+		io.WriteString(w, `{"taskUid": 1}`)
+	}))
+	defer srv.Close()
+	root := newTree(t, map[string]string{
+		"go/slices.md": "---\ntitle: Slices\n---\n\nSlices.\n",
+		"go/draft.md":  "---\ntitle: Draft\ndraft: true\n---\n",
+	})
+	writeConfig(t, "[index.meilisearch]\nurl = \""+srv.URL+"\"\nindex = \"notes\"\nkey_env = \"TIL_TEST_KEY\"\n")
+	t.Setenv("TIL_TEST_KEY", "k1")
+
+	if out := mustRun(t, root, "index", "push", "--target", "meilisearch"); out != "pushed meilisearch: 1 updated, 0 deleted, 0 unchanged\n" {
+		t.Errorf("index push = %q", out)
+	}
+	writeFile(t, root, "go/maps.md", "# Maps\n")
+	if out := mustRun(t, root, "index", "push", "--target", "meilisearch", "-n"); out != "would push meilisearch: 1 updated, 0 deleted, 1 unchanged\n" {
+		t.Errorf("index push -n = %q", out)
+	}
+	if err := os.Remove(filepath.Join(root, "go", "slices.md")); err != nil {
+		t.Fatal(err)
+	}
+	if out := mustRun(t, root, "index", "push", "--target", "meilisearch", "--json"); !strings.Contains(out, `"upserted": 1`) || !strings.Contains(out, `"deleted": 1`) {
+		t.Errorf("index push --json =\n%s", out)
+	}
+	if upserts != 2 || deletes != 1 {
+		t.Errorf("sent %d upserts and %d deletes", upserts, deletes)
+	}
+
+	t.Setenv("TIL_TEST_KEY", "wrong")
+	if _, err := run(t, root, "index", "push", "--target", "meilisearch", "--full"); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("index push with a wrong key = %v", err)
+	}
+	if _, err := run(t, root, "index", "push", "--target", "solr"); err == nil || err.Error() != `unknown target "solr": want meilisearch or elasticsearch` {
+		t.Errorf("index push --target solr = %v", err)
+	}
+}
+
+func TestIndexRebuildAndDoctor(t *testing.T) {
+	root := newTree(t, map[string]string{
+		"go/slices.md":  "# Slices\n\nOld.\n",
+		"git/rebase.md": "# Rebase\n",
+	})
+	hourAgo := time.Now().Add(-time.Hour)
+	age := func(p string) {
+		t.Helper()
+		if err := os.Chtimes(filepath.Join(root, p), hourAgo, hourAgo); err != nil {
+			t.Fatal(err)
+		}
+	}
+	age("go/slices.md")
+	age("git/rebase.md")
+	if out := mustRun(t, root, "index", "rebuild"); !strings.Contains(out, "cached 2 entries in ") {
+		t.Errorf("index rebuild = %q", out)
+	}
+	out := mustRun(t, root, "index", "doctor")
+	if !strings.Contains(out, "2 entries cached\n") || !strings.Contains(out, ", 0 parsed anew") {
+		t.Errorf("index doctor =\n%s", out)
+	}
+
+	writeFile(t, root, "go/maps.md", "# Maps\n")
+	out = mustRun(t, root, "index", "doctor", "--json")
+	if !strings.Contains(out, `"kind": "cache"`) || !strings.Contains(out, `"go/maps.md"`) {
+		t.Errorf("index doctor --json =\n%s", out)
+	}
+
+	// A file changed keeping its size and modification time is served out
+	// of date, which doctor finds and rebuild repairs.
+	writeFile(t, root, "go/slices.md", "# Slices\n\nNew.\n")
+	age("go/slices.md")
+	out, err := run(t, root, "index", "doctor")
+	if err == nil || err.Error() != "the entry cache is corrupt; run til index rebuild" || !strings.Contains(out, "corrupt: go/slices.md\n") {
+		t.Errorf("index doctor of a corrupt cache = %q, %v", out, err)
+	}
+	mustRun(t, root, "index", "rebuild")
+	if out := mustRun(t, root, "show", "slices"); !strings.Contains(out, "New.") {
+		t.Errorf("show after rebuild =\n%s", out)
+	}
+	mustRun(t, root, "index", "doctor")
+}
@@ -0,0 +1,46 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLang(t *testing.T) {
+	root := newTree(t, map[string]string{
+		"go/slices.md": "---\ntitle: Slices\ndate: 2024-05-01\n---\n\nSlices share arrays.\n",
+		"go/maps.md":   "---\ntitle: Maps\ndate: 2024-04-01\nlang: en-GB\n---\n\nMaps.\n",
+	})
+	mustRun(t, root, "new", "go", "Lát cắt", "--no-edit", "--lang", "vi", "--translation-of", "go/slices.md")
+	got := readFile(t, root, "go/lat_cat.md")
+	if !strings.Contains(got, "\nlang: vi\n") || !strings.Contains(got, "\ntranslation_of: slices\n") {
+		t.Errorf("translation =\n%s", got)
+	}
+	if _, err := run(t, root, "new", "go", "Nowhere", "--no-edit", "--translation-of", "go/nowhere.md"); err == nil || !strings.HasPrefix(err.Error(), "--translation-of: ") {
+		t.Errorf("new --translation-of a missing entry = %v", err)
+	}
+
+	if out := mustRun(t, root, "list", "--lang", "vi"); !strings.Contains(out, "go/lat_cat.md") || strings.Contains(out, "go/slices.md") {
+		t.Errorf("list --lang vi =\n%s", out)
+	}
+	if out := mustRun(t, root, "list", "--lang", "en"); !strings.Contains(out, "go/slices.md") || !strings.Contains(out, "go/maps.md") || strings.Contains(out, "lat_cat") {
+		t.Errorf("list --lang en =\n%s", out)
+	}
+	if out := mustRun(t, root, "search", "lang:en-gb"); !strings.Contains(out, "go/maps.md") || strings.Contains(out, "go/slices.md") {
+		t.Errorf("search lang:en-gb =\n%s", out)
+	}
+
+	// Entries naming no language are in the configured one.
+	writeConfig(t, "lang = \"vi\"\n")
+	if out := mustRun(t, root, "list", "--lang", "vi"); !strings.Contains(out, "go/slices.md") || strings.Contains(out, "go/maps.md") {
+		t.Errorf("list --lang vi with lang = vi =\n%s", out)
+	}
+
+	writeFile(t, root, "go/lost.md", "---\ntitle: Lost\ntranslation_of: nowhere\n---\n\nLost.\n")
+	out := mustRun(t, root, "build")
+	if !strings.Contains(out, `go/lost.md: translation_of "nowhere": names no entry`) {
+		t.Errorf("build =\n%s", out)
+	}
+	if page := readFile(t, root, "public/go/slices/index.html"); !strings.Contains(page, `<html lang="vi">`) || !strings.Contains(page, `<link rel="alternate" hreflang="vi" href="/go/lat-cat/">`) {
+		t.Errorf("public/go/slices/index.html =\n%s", page)
+	}
+}
@@ -0,0 +1,176 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+
+	"github.com/spf13/cobra"
+
+	"github.com/canhta/til/go/internal/lint"
+	"github.com/canhta/til/go/internal/schema"
+	"github.com/canhta/til/go/internal/wayback"
+)
+
+func newLintCmd(a *app) *cobra.Command {
+	var (
+		fix      bool
+		external bool
+		rules    []string
+		list     bool
+		archive  string
+	)
+	cmd := &cobra.Command{
+		Use:   "lint [entry...]",
+		Short: "Check entries for broken links, missing frontmatter and more",
+		Long: `Lint checks all entries, or the given ones, with every rule: broken
+internal links, missing required frontmatter fields ([lint] required,
+default title and date, and [schema] required), duplicate titles and slugs,
+untagged entries, code block lines longer than [lint] max_code_line
+(default 100), and frontmatter breaking the [schema] of the config file:
+categories and tags it does not allow, and values of the wrong type or
+outside those declared. Problems are printed as path:line: message (rule),
+and lint exits non-zero when any remain.
+
+--external also requests every http(s) link and reports those that fail,
+at most one request per host each [lint] url_interval (default 1s), with
+results cached in .til/urls.json for [lint] url_ttl (default 24h).
+
+--archive, which implies --external, looks up the links answering 404 or
+410 on the Wayback Machine, taking the snapshot closest to the entry's date
+or requesting a fresh one when there is none, and rewrites them to point
+to it. --archive=append keeps the dead link and follows it with one to the
+snapshot instead. Links followed by such a snapshot link, and links to
+snapshots, are not checked.
+
+--fix resolves the problems that can be resolved safely: markdown links to
+an entry that moved, and missing titles, categories and slugs, which are
+written as the entry already derives them from its file.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			out := cmd.OutOrStdout()
+			if list {
+				for _, r := range lint.Rules() {
+					fmt.Fprintf(out, "%-18s %s\n", r.Name(), r.Doc())
+				}
+				return nil
+			}
+			selected, err := lint.Select(rules, a.cfg.Lint.Disable)
+			if err != nil {
+				return err
+			}
+			mode := wayback.Mode(archive)
+			if mode != "" && mode != wayback.Replace && mode != wayback.Append {
+				return fmt.Errorf("unknown --archive mode %q (want %s or %s)", archive, wayback.Replace, wayback.Append)
+			}
+			if (external || mode != "") && !slices.Contains(rules, "dead-url") {
+				urls, _ := lint.Select([]string{"dead-url"}, nil)
+				selected = append(selected, urls...)
+			}
+			all, err := a.tree.Entries()
+			if err != nil {
+				return err
+			}
+			entries, err := a.entriesOrAll(args)
+			if err != nil {
+				return err
+			}
+			opts, err := a.lintOptions()
+			if err != nil {
+				return err
+			}
+			opts.Archive = mode
+			c := lint.NewContext(a.tree, all, entries, opts)
+			issues, err := lint.Run(cmd.Context(), c, selected)
+			if err != nil {
+				return err
+			}
+			var fixed []lint.Issue
+			if fix || mode != "" {
+				// --archive alone fixes only the links it archives.
+				var fixing, remaining []lint.Issue
+				for _, is := range issues {
+					if is.Fixable() && (fix || is.Rule == "dead-url") {
+						fixing = append(fixing, is)
+					} else {
+						remaining = append(remaining, is)
+					}
+				}
+				if fixed, err = lint.Fix(a.tree, fixing); err != nil {
+					return err
+				}
+				issues = remaining
+			}
+			report := struct {
+				Issues []lint.Issue `json:"issues"`
+				Fixed  []lint.Issue `json:"fixed"`
+			}{issues, fixed}
+			if report.Issues == nil {
+				report.Issues = []lint.Issue{}
+			}
+			if report.Fixed == nil {
+				report.Fixed = []lint.Issue{}
+			}
+			err = a.output(cmd, report, func(w io.Writer) error {
+				for _, is := range fixed {
+					fmt.Fprintf(w, "fixed %s\n", is)
+				}
+				for _, is := range issues {
+					fmt.Fprintln(w, is)
+				}
+				if n := fixable(issues); n > 0 && !fix {
+					fmt.Fprintf(w, "%d of %d problems can be fixed with --fix\n", n, len(issues))
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			if len(issues) > 0 {
+				return &exitError{code: 1, err: errors.New(plural(len(issues), "problem"))}
+			}
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&fix, "fix", false, "fix the problems that can be fixed safely")
+	cmd.Flags().BoolVar(&external, "external", false, "also check external links (needs the network)")
+	cmd.Flags().StringVar(&archive, "archive", "", "link dead pages to Wayback Machine snapshots: replace or append (implies --external)")
+	cmd.Flags().Lookup("archive").NoOptDefVal = string(wayback.Replace)
+	cmd.Flags().StringSliceVarP(&rules, "rule", "r", nil, "run only the named rules (repeatable)")
+	cmd.Flags().BoolVar(&list, "list-rules", false, "list the rules and exit")
+	return withJSON(cmd, "lint")
+}
+
+// lintOptions returns the options of the builtin rules from the config
+// file, with the fields [schema] requires added to those [lint] does.
+func (a *app) lintOptions() (lint.Options, error) {
+	if err := schema.Valid(a.cfg.Schema); err != nil {
+		return lint.Options{}, err
+	}
+	required := a.cfg.Lint.Required
+	if len(required) == 0 {
+		required = lint.DefaultRequired
+	}
+	for _, key := range a.cfg.Schema.Required {
+		if !slices.Contains(required, key) {
+			required = append(slices.Clip(required), key)
+		}
+	}
+	return lint.Options{
+		Required:    required,
+		Schema:      a.cfg.Schema,
+		MaxCodeLine: a.cfg.Lint.MaxCodeLine,
+		URLTTL:      a.cfg.Lint.URLTTL.Duration,
+		URLInterval: a.cfg.Lint.URLInterval.Duration,
+	}, nil
+}
+
+func fixable(issues []lint.Issue) int {
+	n := 0
+	for _, is := range issues {
+		if is.Fixable() {
+			n++
+		}
+	}
+	return n
+}
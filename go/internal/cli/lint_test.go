@@ -0,0 +1,60 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestLint(t *testing.T) {
+	root := newTree(t, map[string]string{
+		"go/slices.md":  "---\ndate: 2024-01-01\ntags: [go]\n---\n# Slices\n\nSee [rebase](rebase.md).\n",
+		"git/rebase.md": "---\ntitle: Rebase\ndate: 2024-01-01\n---\n",
+	})
+	want := "git/rebase.md:1: no tags (untagged)\n" +
+		"go/slices.md:1: missing title (\"Slices\" from the file) (required-field)\n" +
+		"go/slices.md:7: link to missing rebase.md, moved to git/rebase.md (broken-link)\n" +
+		"2 of 3 problems can be fixed with --fix\n"
+	out, err := run(t, root, "lint")
+	var exit *exitError
+	if !errors.As(err, &exit) || exit.code != 1 || err.Error() != "3 problems" {
+		t.Errorf("lint = %v", err)
+	}
+	if out != want {
+		t.Errorf("lint =\n%s\nwant\n%s", out, want)
+	}
+
+	if out := mustRun(t, root, "lint", "--rule", "untagged", "go/slices.md"); out != "" {
+		t.Errorf("lint --rule untagged go/slices.md =\n%s", out)
+	}
+	writeConfig(t, "[lint]\ndisable = [\"untagged\"]\n")
+	out, _ = run(t, root, "lint", "--fix", "--json")
+	var doc struct {
+		Data struct {
+			Issues, Fixed []struct {
+				Path, Rule string
+				Fixable    bool
+			}
+		}
+	}
+	if err := json.Unmarshal([]byte(out), &doc); err != nil {
+		t.Fatalf("lint --fix --json: %v\n%s", err, out)
+	}
+	if len(doc.Data.Fixed) != 2 || len(doc.Data.Issues) != 0 {
+		t.Errorf("lint --fix --json = %+v", doc.Data)
+	}
+	if got := readFile(t, root, "go/slices.md"); got != "---\ndate: 2024-01-01\ntags: [go]\ntitle: Slices\n---\n# Slices\n\nSee [rebase](../git/rebase.md).\n" {
+		t.Errorf("go/slices.md after lint --fix:\n%s", got)
+	}
+	if out := mustRun(t, root, "lint"); out != "" {
+		t.Errorf("lint after --fix =\n%s", out)
+	}
+
+	if out := mustRun(t, root, "lint", "--list-rules"); !strings.Contains(out, "dead-url") || !strings.Contains(out, "broken-link") {
+		t.Errorf("lint --list-rules =\n%s", out)
+	}
+	if _, err := run(t, root, "lint", "--rule", "nope"); err == nil || !strings.Contains(err.Error(), `unknown lint rule "nope"`) {
+		t.Errorf("lint --rule nope = %v", err)
+	}
+}
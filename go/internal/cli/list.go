@@ -0,0 +1,222 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/canhta/til/go/internal/gitdates"
+	"github.com/canhta/til/go/internal/query"
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+// listFilter holds the filter flags of til list.
+type listFilter struct {
+	tags         []string
+	allTags      bool
+	categories   []string
+	title        string
+	authors      []string
+	langs        []string
+	since, until string
+	updatedSince string
+	or           bool
+}
+
+func (f *listFilter) register(cmd *cobra.Command) {
+	fs := cmd.Flags()
+	fs.StringSliceVarP(&f.tags, "tag", "t", nil, "match entries with this tag (repeatable; any of them unless --all-tags)")
+	fs.BoolVar(&f.allTags, "all-tags", false, "require every --tag instead of any")
+	fs.StringSliceVarP(&f.categories, "category", "c", nil, "match entries in this category (repeatable)")
+	fs.StringVar(&f.title, "title", "", "match entries whose title contains this text")
+	fs.StringSliceVarP(&f.authors, "author", "a", nil, "match entries by this author (repeatable)")
+	fs.StringSliceVar(&f.langs, "lang", nil, "match entries written in this language, such as vi or en-US (repeatable)")
+	fs.StringVar(&f.since, "since", "", "created on or after this date (YYYY-MM-DD, YYYY-MM, 7d, 2w, 3m, 1y)")
+	fs.StringVar(&f.until, "until", "", "created on or before this date")
+	fs.StringVar(&f.updatedSince, "updated-since", "", "updated on or after this date")
+	fs.BoolVar(&f.or, "or", false, "match entries satisfying any filter instead of all")
+}
+
+// expr builds the query for the flags. Values of one flag are OR'ed;
+// different flags are AND'ed unless --or is given. Entries naming no
+// language are in lang.
+func (f *listFilter) expr(now time.Time, lang string) (query.Expr, error) {
+	var groups []query.Expr
+	if len(f.tags) > 0 {
+		var xs []query.Expr
+		for _, t := range f.tags {
+			xs = append(xs, query.Tag(t))
+		}
+		if f.allTags {
+			groups = append(groups, query.And(xs))
+		} else {
+			groups = append(groups, query.Or(xs))
+		}
+	}
+	if len(f.categories) > 0 {
+		var xs []query.Expr
+		for _, c := range f.categories {
+			xs = append(xs, query.Category(c))
+		}
+		groups = append(groups, query.Or(xs))
+	}
+	if f.title != "" {
+		groups = append(groups, query.Title(f.title))
+	}
+	if len(f.authors) > 0 {
+		var xs []query.Expr
+		for _, a := range f.authors {
+			xs = append(xs, query.Author(a))
+		}
+		groups = append(groups, query.Or(xs))
+	}
+	if len(f.langs) > 0 {
+		var xs []query.Expr
+		for _, l := range f.langs {
+			xs = append(xs, query.Lang{Tag: l, Default: lang})
+		}
+		groups = append(groups, query.Or(xs))
+	}
+	if f.since != "" || f.until != "" {
+		r := query.DateRange{Field: query.Created}
+		if f.since != "" {
+			from, err := query.ParseDate(f.since, now)
+			if err != nil {
+				return nil, fmt.Errorf("--since: %w", err)
+			}
+			r.From = from
+		}
+		if f.until != "" {
+			_, to, err := query.ParseSpan(f.until, now)
+			if err != nil {
+				return nil, fmt.Errorf("--until: %w", err)
+			}
+			r.To = to
+		}
+		groups = append(groups, r)
+	}
+	if f.updatedSince != "" {
+		from, err := query.ParseDate(f.updatedSince, now)
+		if err != nil {
+			return nil, fmt.Errorf("--updated-since: %w", err)
+		}
+		groups = append(groups, query.DateRange{Field: query.Updated, From: from})
+	}
+	switch {
+	case len(groups) == 0:
+		return query.All{}, nil
+	case len(groups) == 1:
+		return groups[0], nil
+	case f.or:
+		return query.Or(groups), nil
+	}
+	return query.And(groups), nil
+}
+
+func newListCmd(a *app) *cobra.Command {
+	var (
+		filter   listFilter
+		sortKey  string
+		reverse  bool
+		limit    int
+		gitDates bool
+		archived bool
+	)
+	cmd := &cobra.Command{
+		Use:   "list [query]",
+		Short: "List entries matching metadata filters",
+		Long: `List prints the entries matching the filter flags and, if given, a query
+in the language of til search, such as @name for a configured collection.
+Archived entries are left out unless --archived is given.`,
+		Example: `  til list --tag go --since 2024-01-01 --category databases --sort created
+  til list --tag go --tag rust --since 7d --json
+  til list --author "Jane Doe"
+  til list --lang vi
+  til list @go-perf`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			now := time.Now()
+			x, err := filter.expr(now, a.lang())
+			if err != nil {
+				return err
+			}
+			if len(args) > 0 {
+				q, err := a.parseQuery(strings.Join(args, " "), now)
+				if err != nil {
+					return err
+				}
+				x = query.And{q, x}
+			}
+			if !archived {
+				x = query.And{x, query.Not{X: query.Archived{}}}
+			}
+			entries, err := a.datedEntries(cmd.Context(), gitDates)
+			if err != nil {
+				return err
+			}
+			entries = query.Filter(entries, x)
+			if err := query.Sort(entries, sortKey, reverse); err != nil {
+				return err
+			}
+			if limit > 0 && len(entries) > limit {
+				entries = entries[:limit]
+			}
+			return a.outputEntries(cmd, entries)
+		},
+	}
+	withJSON(cmd, "entries")
+	filter.register(cmd)
+	cmd.Flags().StringVarP(&sortKey, "sort", "s", "created", "sort by "+strings.Join(query.SortKeys, ", "))
+	cmd.Flags().BoolVarP(&reverse, "reverse", "r", false, "reverse the sort order")
+	cmd.Flags().IntVarP(&limit, "limit", "n", 0, "show at most this many entries")
+	cmd.Flags().BoolVar(&gitDates, "git-dates", false, "date entries by their first and last commit")
+	cmd.Flags().BoolVar(&archived, "archived", false, "include archived entries")
+	return cmd
+}
+
+// datedEntries loads the entries of the tree, taking their dates from git
+// history when gitDates is set or configured.
+func (a *app) datedEntries(ctx context.Context, gitDates bool) ([]*entry.Entry, error) {
+	entries, err := a.tree.Entries()
+	if err != nil {
+		return nil, err
+	}
+	if gitDates || a.cfg.Git.Dates {
+		if err := gitdates.Apply(ctx, a.tree, entries); err != nil {
+			return nil, err
+		}
+	}
+	return entries, nil
+}
+
+// outputEntries prints entries as a table, or with --json as summaries.
+func (a *app) outputEntries(cmd *cobra.Command, entries []*entry.Entry) error {
+	out := make([]entry.Summary, len(entries))
+	for i, e := range entries {
+		out[i] = entry.Summarize(e)
+	}
+	return a.output(cmd, out, func(w io.Writer) error { return writeEntriesTable(w, entries) })
+}
+
+func writeEntriesTable(w io.Writer, entries []*entry.Entry) error {
+	// Buffered, as the tab writer writes each line on its own.
+	bw := bufio.NewWriter(w)
+	tw := tabwriter.NewWriter(bw, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "CREATED\tCATEGORY\tTITLE\tTAGS\tPATH")
+	for _, e := range entries {
+		created := "-"
+		if d := e.Created(); !d.IsZero() {
+			created = d.Format(entry.DateLayout)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", created, e.Meta.Category, e.Meta.Title, strings.Join(e.Meta.Tags, ","), e.Path)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,75 @@
+package cli
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+func TestList(t *testing.T) {
+	root := newTree(t, map[string]string{
+		"go/slices.md":     "---\ntitle: Slices\ndate: 2024-03-01\ntags: [go, slices]\n---\n",
+		"go/maps.md":       "---\ntitle: Maps\ndate: 2024-05-01\ntags: [go]\n---\n",
+		"databases/idx.md": "---\ntitle: Partial indexes\ndate: 2023-12-01\ntags: [postgres]\n---\n",
+		"rust/own.md":      "---\ntitle: Ownership\ntags: [rust]\narchived: true\n---\n",
+	})
+	want := "CREATED     CATEGORY  TITLE   TAGS       PATH\n" +
+		"2024-05-01  go        Maps    go         go/maps.md\n" +
+		"2024-03-01  go        Slices  go,slices  go/slices.md\n"
+	if out := mustRun(t, root, "list", "--tag", "go", "--since", "2024-01-01"); out != want {
+		t.Errorf("list --tag go --since 2024-01-01 =\n%s\nwant\n%s", out, want)
+	}
+	tests := []struct {
+		args []string
+		want []string
+	}{
+		{nil, []string{"go/maps.md", "go/slices.md", "databases/idx.md"}},
+		{[]string{"--sort", "title"}, []string{"go/maps.md", "databases/idx.md", "go/slices.md"}},
+		{[]string{"--sort", "created", "--reverse"}, []string{"databases/idx.md", "go/slices.md", "go/maps.md"}},
+		{[]string{"--category", "databases", "--tag", "slices"}, nil},
+		{[]string{"--category", "databases", "--tag", "slices", "--or"}, []string{"go/slices.md", "databases/idx.md"}},
+		{[]string{"--tag", "go", "--tag", "slices", "--all-tags"}, []string{"go/slices.md"}},
+		{[]string{"--until", "2024-03"}, []string{"go/slices.md", "databases/idx.md"}},
+		{[]string{"--since", "2024-04", "--category", "go,databases"}, []string{"go/maps.md"}},
+		{[]string{"--limit", "1"}, []string{"go/maps.md"}},
+		{[]string{"--archived", "--tag", "rust"}, []string{"rust/own.md"}},
+		{[]string{"--", "tag:go", "-slices"}, []string{"go/maps.md"}},
+	}
+	for _, tt := range tests {
+		out := mustRun(t, root, append([]string{"list", "--json"}, tt.args...)...)
+		var got struct {
+			Kind string
+			Data []entry.Summary
+		}
+		if err := json.Unmarshal([]byte(out), &got); err != nil || got.Kind != "entries" {
+			t.Fatalf("list --json %s: %v\n%s", strings.Join(tt.args, " "), err, out)
+		}
+		var paths []string
+		for _, s := range got.Data {
+			paths = append(paths, s.Path)
+		}
+		if !slices.Equal(paths, tt.want) {
+			t.Errorf("list %s = %q, want %q", strings.Join(tt.args, " "), paths, tt.want)
+		}
+	}
+	for _, args := range [][]string{{"--since", "last week"}, {"--sort", "size"}} {
+		if _, err := run(t, root, append([]string{"list"}, args...)...); err == nil {
+			t.Errorf("list %s succeeded", strings.Join(args, " "))
+		}
+	}
+}
+
+func TestListSinceRelative(t *testing.T) {
+	recent := time.Now().AddDate(0, 0, -3).Format(entry.DateLayout)
+	root := newTree(t, map[string]string{
+		"go/new.md": "---\ntitle: New\ndate: " + recent + "\n---\n",
+		"go/old.md": "---\ntitle: Old\ndate: 2020-01-01\n---\n",
+	})
+	if out := mustRun(t, root, "list", "--since", "7d"); !strings.Contains(out, "go/new.md") || strings.Contains(out, "go/old.md") {
+		t.Errorf("list --since 7d =\n%s", out)
+	}
+}
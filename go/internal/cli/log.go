@@ -0,0 +1,81 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/canhta/til/go/internal/journal"
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+func newLogCmd(a *app) *cobra.Command {
+	var day string
+	cmd := &cobra.Command{
+		Use:   "log [message]",
+		Short: "Append a timestamped bullet to today's journal",
+		Long: `Log appends the message as a bullet, stamped with the time, to the journal
+file of the day, ` + journal.Dir + `/<yyyy-mm-dd>.md, creating it if needed. Journal
+files are only ever appended to, so they merge without conflicts; each
+bullet is found by til search on its own and counted by til stats.
+
+Without a message, log lists the bullets of the day.`,
+		Example: `  til log "go test -count=1 skips the test cache"
+  til log
+  til log --day 2026-10-01`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			now := time.Now()
+			if len(args) == 0 {
+				t := now
+				if day != "" {
+					var err error
+					if t, err = time.ParseInLocation(entry.DateLayout, day, time.Local); err != nil {
+						return fmt.Errorf("--day %q: want a date like 2026-10-01", day)
+					}
+				}
+				return a.listJournal(cmd, t)
+			}
+			if day != "" {
+				return errors.New("--day only lists a day; bullets are logged to today's file")
+			}
+			changed, item, err := journal.Append(a.tree, now, strings.Join(args, " "))
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "%s:%d\n", item.Path, item.Line)
+			return a.commitEntry(cmd, changed[0], "log", changed[1:]...)
+		},
+	}
+	a.commitFlag(cmd)
+	withJSON(cmd, "journal")
+	cmd.Flags().StringVar(&day, "day", "", "list the bullets of this day instead of today")
+	return cmd
+}
+
+// listJournal prints the bullets of the journal file of the day of t.
+func (a *app) listJournal(cmd *cobra.Command, t time.Time) error {
+	var items []journal.Item
+	e, err := a.tree.Load(journal.File(t))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if e != nil {
+		items = journal.Items(e)
+	}
+	return a.output(cmd, items, func(w io.Writer) error {
+		if len(items) == 0 {
+			fmt.Fprintf(w, "Nothing logged on %s.\n", t.Format(entry.DateLayout))
+			return nil
+		}
+		for _, it := range items {
+			text := strings.ReplaceAll(it.Text, "\n", "\n      ")
+			fmt.Fprintf(w, "%s  %s\n", it.Time.Format(journal.TimeLayout), text)
+		}
+		return nil
+	})
+}
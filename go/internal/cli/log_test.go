@@ -0,0 +1,49 @@
+package cli
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/canhta/til/go/internal/journal"
+)
+
+func TestLog(t *testing.T) {
+	root := newTree(t, map[string]string{"go/slices.md": "# Slices\n"})
+	today := journal.File(time.Now())
+
+	if out := mustRun(t, root, "log"); out != "Nothing logged on "+time.Now().Format("2006-01-02")+".\n" {
+		t.Errorf("log of an empty day = %q", out)
+	}
+	if out := mustRun(t, root, "log", "--", "go", "test", "-count=1", "skips", "the", "cache"); out != today+":6\n" {
+		t.Errorf("log = %q, want %s:6", out, today)
+	}
+	if out := mustRun(t, root, "log", "bisect finds the bad commit"); out != today+":7\n" {
+		t.Errorf("second log = %q", out)
+	}
+	if got := readFile(t, root, "journal/.gitattributes"); !strings.Contains(got, "*.md merge=union") {
+		t.Errorf(".gitattributes = %q", got)
+	}
+	out := mustRun(t, root, "log")
+	if !regexp.MustCompile(`^\d\d:\d\d  go test -count=1 skips the cache\n\d\d:\d\d  bisect finds the bad commit\n$`).MatchString(out) {
+		t.Errorf("log listing =\n%s", out)
+	}
+
+	if out := mustRun(t, root, "search", "bisect"); !strings.HasPrefix(out, today+":7  ") {
+		t.Errorf("search =\n%s", out)
+	}
+	if out := mustRun(t, root, "stats"); !regexp.MustCompile(`Journal +2 items over 1 day\n`).MatchString(out) {
+		t.Errorf("stats =\n%s", out)
+	}
+
+	writeFile(t, root, "journal/2026-10-01.md", "- 08:00 early\n  and long\n")
+	if out := mustRun(t, root, "log", "--day", "2026-10-01"); out != "08:00  early\n      and long\n" {
+		t.Errorf("log --day = %q", out)
+	}
+	for _, args := range [][]string{{"log", "--day", "yesterday"}, {"log", "--day", "2026-10-01", "msg"}, {"log", " "}} {
+		if _, err := run(t, root, args...); err == nil {
+			t.Errorf("%q succeeded", args)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+
+	"github.com/spf13/cobra"
+
+	"github.com/canhta/til/go/internal/logging"
+)
+
+// logFlags are the global flags for the log of the builds, syncs and code
+// runs a command does.
+type logFlags struct {
+	verbose bool
+	trace   bool
+	format  string
+}
+
+func (f *logFlags) register(cmd *cobra.Command) {
+	cmd.PersistentFlags().BoolVar(&f.verbose, "verbose", false, "log what builds, syncs and code runs do to stderr")
+	cmd.PersistentFlags().BoolVar(&f.trace, "trace", false, "log more than --verbose, with the time each phase took, and sum the phases up at the end")
+	cmd.PersistentFlags().StringVar(&f.format, "log-format", "text", "format of the --verbose and --trace log: text or json")
+	_ = cmd.RegisterFlagCompletionFunc("log-format", cobra.FixedCompletions([]string{"text", "json"}, cobra.ShellCompDirectiveNoFileComp))
+}
+
+// startLogging puts the logger the log flags ask for in the context of cmd,
+// and with --trace a recorder of the spans started under a span for the
+// whole command.
+func (a *app) startLogging(cmd *cobra.Command) error {
+	f := &a.log
+	if f.format != "text" && f.format != "json" {
+		return fmt.Errorf("--log-format %q: want text or json", f.format)
+	}
+	if !f.verbose && !f.trace {
+		return nil
+	}
+	opts := logging.Options{Level: slog.LevelDebug, JSON: f.format == "json"}
+	if f.trace {
+		opts.Level = logging.LevelTrace
+	}
+	ctx := logging.With(cmd.Context(), logging.New(cmd.ErrOrStderr(), opts))
+	if f.trace {
+		a.spans = logging.NewRecorder()
+		ctx, a.endSpan = logging.Start(a.spans.With(ctx), cmd.CommandPath())
+	}
+	cmd.SetContext(ctx)
+	return nil
+}
+
+// writeTrace ends the span of the command and writes the summary of the
+// spans recorded with --trace to w, if the command got as far as starting
+// them.
+func (a *app) writeTrace(w io.Writer) {
+	if a.spans == nil {
+		return
+	}
+	a.endSpan()
+	fmt.Fprintln(w, "trace:")
+	_ = a.spans.Write(w)
+}
@@ -0,0 +1,50 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestVerbose(t *testing.T) {
+	root := newTree(t, map[string]string{"go/slices.md": "---\ntitle: Slices\n---\n\nSlices.\n"})
+	out := mustRun(t, root, "build", "--verbose")
+	if !strings.Contains(out, "level=DEBUG msg=\"loaded entries\" entries=1") || strings.Contains(out, "level=TRACE") {
+		t.Errorf("build --verbose =\n%s", out)
+	}
+	if out := mustRun(t, root, "build"); strings.Contains(out, "level=") {
+		t.Errorf("build logged without --verbose:\n%s", out)
+	}
+	out = mustRun(t, root, "build", "--verbose", "--log-format", "json")
+	line, _, _ := strings.Cut(out, "\n")
+	var rec map[string]any
+	if err := json.Unmarshal([]byte(line), &rec); err != nil || rec["level"] != "DEBUG" {
+		t.Errorf("build --log-format json =\n%s", out)
+	}
+	if _, err := run(t, root, "list", "--log-format", "xml"); err == nil || err.Error() != `--log-format "xml": want text or json` {
+		t.Errorf("--log-format xml = %v", err)
+	}
+}
+
+func TestTrace(t *testing.T) {
+	root := newTree(t, map[string]string{"go/slices.md": "---\ntitle: Slices\n---\n\nSlices.\n"})
+	// As Main does, bar the exit code.
+	a := &app{}
+	cmd := newRootCmd(a)
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(&out)
+	cmd.SetArgs([]string{"-C", root, "build", "--trace"})
+	if err := cmd.ExecuteContext(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	a.writeTrace(&out)
+	got := out.String()
+	for _, want := range []string{"level=TRACE msg=\"til build/load\" took=", "\ntrace:\ntil build ", "\n  load ", "\n  render "} {
+		if !strings.Contains(got, want) {
+			t.Errorf("build --trace lacks %q:\n%s", want, got)
+		}
+	}
+}
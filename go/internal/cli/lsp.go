@@ -0,0 +1,53 @@
+package cli
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/canhta/til/go/internal/lint"
+	"github.com/canhta/til/go/internal/lsp"
+)
+
+func newLSPCmd(a *app) *cobra.Command {
+	var stdio bool
+	cmd := &cobra.Command{
+		Use:   "lsp",
+		Short: "Serve the notes to editors as a language server",
+		Long: `Lsp runs a language server for the entries of the notes tree, speaking the
+Language Server Protocol over stdin and stdout, so that editors with an LSP
+client get help editing entries without a plugin of their own:
+
+  - completion of the tags of the tree in the tags of the frontmatter, and
+    of entries after [[;
+  - going to the entry a [[wikilink]] or markdown link points to;
+  - a preview of that entry, its title, tags and first lines, on hover;
+  - the problems til lint finds, as diagnostics, updated as you type.
+
+The rules are those til lint runs by default, less [lint] disable; links
+are checked against the entries on disk, reloaded whenever an entry is
+saved, and the entries open as they are in the editor.
+
+In Neovim:
+
+  vim.lsp.start({ name = "til", cmd = { "til", "lsp" }, root_dir = vim.fs.root(0, { ".til", ".git" }) })
+
+In VS Code, any generic LSP client extension can run til lsp for markdown
+files.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rules, err := lint.Select(nil, a.cfg.Lint.Disable)
+			if err != nil {
+				return err
+			}
+			opts, err := a.lintOptions()
+			if err != nil {
+				return err
+			}
+			s := lsp.New(a.tree, lsp.Options{Lint: opts, Rules: rules})
+			return s.Serve(cmd.Context(), cmd.InOrStdin(), cmd.OutOrStdout())
+		},
+	}
+	// Clients commonly pass --stdio, the only transport there is.
+	cmd.Flags().BoolVar(&stdio, "stdio", true, "talk over stdin and stdout")
+	_ = cmd.Flags().MarkHidden("stdio")
+	return cmd
+}
@@ -0,0 +1,37 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestLSP(t *testing.T) {
+	root := newTree(t, map[string]string{"go/slices.md": "---\ntitle: Slices\n---\n\nSee [[nowhere]].\n"})
+	var in strings.Builder
+	for _, m := range []string{
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`,
+		`{"jsonrpc":"2.0","method":"textDocument/didOpen","params":{"textDocument":{"uri":"file://` + root + `/go/slices.md","version":1,"text":"---\ntitle: Slices\n---\n\nSee [[nowhere]].\n"}}}`,
+		`{"jsonrpc":"2.0","id":2,"method":"shutdown"}`,
+		`{"jsonrpc":"2.0","method":"exit"}`,
+	} {
+		fmt.Fprintf(&in, "Content-Length: %d\r\n\r\n%s", len(m), m)
+	}
+	cmd := newRootCmd(&app{})
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(&out)
+	cmd.SetIn(strings.NewReader(in.String()))
+	cmd.SetArgs([]string{"-C", root, "lsp", "--stdio"})
+	if err := cmd.ExecuteContext(context.Background()); err != nil {
+		t.Fatalf("til lsp: %v\n%s", err, out.String())
+	}
+	got := out.String()
+	for _, want := range []string{`"serverInfo":{"name":"til"}`, `"code":"broken-link"`, `"message":"[[nowhere]] matches no entry"`, `{"jsonrpc":"2.0","id":2,"result":null}`} {
+		if !strings.Contains(got, want) {
+			t.Errorf("til lsp lacks %s:\n%s", want, got)
+		}
+	}
+}
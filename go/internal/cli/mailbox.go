@@ -0,0 +1,163 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/canhta/til/go/internal/importer"
+	"github.com/canhta/til/go/internal/mailbox"
+	"github.com/canhta/til/go/internal/notes"
+)
+
+func newMailboxCmd(a *app) *cobra.Command {
+	var (
+		once     bool
+		interval time.Duration
+		category string
+		dryRun   bool
+	)
+	cmd := &cobra.Command{
+		Use:   "mailbox",
+		Short: "Capture entries mailed to an IMAP account",
+		Long: `Mailbox polls the folder of the IMAP account in the [mailbox] config
+section and turns each unread message into an entry: its subject is the
+title, its text the body and its attachments the entry's assets, with
+inline images kept in place. Mail sent to a plus address is tagged with
+what follows the plus, so mail to til+go@example.com is tagged go and mail
+to til+go+tips@example.com go and tips. Processed mail is moved to the
+[mailbox] archive folder, or marked read if none is set.
+
+  [mailbox]
+  host = "imap.example.com"
+  username = "til@example.com"
+  password_env = "TIL_MAIL_PASSWORD"
+  archive = "Archive"
+  address = "til@example.com"
+  allow_from = ["me@example.com"]
+
+Anyone who knows the address can add entries, so set allow_from to the
+addresses you write from. Mail from other senders is left unprocessed but
+marked read. Mailbox keeps polling, every [mailbox] interval or 5m by
+default, until interrupted; --once polls a single time, as from cron.`,
+		Example: `  til mailbox --once
+  TIL_MAIL_PASSWORD=... til mailbox --interval 1m`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg := a.cfg.Mailbox
+			if cfg.Host == "" {
+				return errors.New("set host in [mailbox]")
+			}
+			s := mailbox.Server{Host: cfg.Host, Port: cfg.Port, Security: cfg.Security, Username: cfg.Username}
+			if cfg.PasswordEnv != "" {
+				s.Password = os.Getenv(cfg.PasswordEnv)
+				if s.Password == "" {
+					return fmt.Errorf("$%s is not set", cfg.PasswordEnv)
+				}
+			}
+			if !cmd.Flags().Changed("category") && cfg.Category != "" {
+				category = cfg.Category
+			}
+			if notes.Skip(category) || strings.ContainsAny(category, `/\`) {
+				return fmt.Errorf("invalid category %q", category)
+			}
+			if !cmd.Flags().Changed("interval") && cfg.Interval.Duration > 0 {
+				interval = cfg.Interval.Duration
+			}
+			if interval <= 0 {
+				return fmt.Errorf("invalid interval %s", interval)
+			}
+			opts := mailbox.Options{Category: category, Address: cfg.Address, AllowFrom: cfg.AllowFrom}
+			ctx := cmd.Context()
+			for {
+				err := a.pollMailbox(cmd, s, opts, dryRun)
+				if once || dryRun {
+					return err
+				}
+				if ctx.Err() != nil {
+					return nil
+				}
+				if err != nil {
+					fmt.Fprintf(cmd.ErrOrStderr(), "warning: %v\n", err)
+				}
+				select {
+				case <-ctx.Done():
+					return nil
+				case <-time.After(interval):
+				}
+			}
+		},
+	}
+	cmd.Flags().BoolVar(&once, "once", false, "poll once and exit")
+	cmd.Flags().DurationVar(&interval, "interval", 5*time.Minute, "time between polls (default from [mailbox] interval)")
+	cmd.Flags().StringVarP(&category, "category", "c", importer.DefaultCategory, "category of the entries (default from [mailbox] category)")
+	cmd.Flags().BoolVarP(&dryRun, "dry-run", "n", false, "poll once and list the entries that would be created, leaving the mail as it is")
+	a.commitFlag(cmd)
+	return cmd
+}
+
+// pollMailbox turns the unread mail in the [mailbox] folder into entries
+// and archives it.
+func (a *app) pollMailbox(cmd *cobra.Command, s mailbox.Server, opts mailbox.Options, dryRun bool) error {
+	cfg := a.cfg.Mailbox
+	c, err := mailbox.Dial(cmd.Context(), s)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	folder := cfg.Folder
+	if folder == "" {
+		folder = "INBOX"
+	}
+	if err := c.Select(folder); err != nil {
+		return err
+	}
+	uids, err := c.Unseen()
+	if err != nil {
+		return err
+	}
+	for _, uid := range uids {
+		if cmd.Context().Err() != nil {
+			return nil
+		}
+		raw, err := c.Fetch(uid)
+		if err != nil {
+			return err
+		}
+		n, err := mailbox.Convert(raw, opts)
+		if err != nil {
+			// Mail that cannot be captured is left where it is, read, so
+			// it is not tried again.
+			fmt.Fprintf(cmd.ErrOrStderr(), "warning: message %d: %v\n", uid, err)
+			if !dryRun {
+				if err := c.MarkSeen(uid); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		rel, err := a.importNote(cmd, n, "mail", dryRun)
+		if err != nil {
+			return err
+		}
+		if rel != "" {
+			fmt.Fprintln(cmd.OutOrStdout(), rel)
+		}
+		if dryRun {
+			continue
+		}
+		if err := c.MarkSeen(uid); err != nil {
+			return err
+		}
+		if cfg.Archive != "" {
+			if err := c.Move(uid, cfg.Archive); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// imapServer serves messages by UID over a minimal IMAP with the MOVE
+// extension, recording the commands that change the folder.
+func imapServer(t *testing.T, messages []string) (port int, changes func() []string) {
+	t.Helper()
+	var (
+		mu  sync.Mutex
+		log []string
+	)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				fmt.Fprint(conn, "* OK ready\r\n")
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					tag, command, _ := strings.Cut(strings.TrimRight(line, "\r\n"), " ")
+					f := strings.Fields(command)
+					switch {
+					case f[0] == "CAPABILITY":
+						fmt.Fprint(conn, "* CAPABILITY IMAP4rev1 MOVE\r\n")
+					case command == "UID SEARCH UNSEEN":
+						var uids []string
+						for i := range messages {
+							uids = append(uids, strconv.Itoa(i+1))
+						}
+						fmt.Fprintf(conn, "* SEARCH %s\r\n", strings.Join(uids, " "))
+					case f[0] == "UID" && f[1] == "FETCH":
+						i, _ := strconv.Atoi(f[2])
+						m := strings.ReplaceAll(messages[i-1], "\n", "\r\n")
+						fmt.Fprintf(conn, "* 1 FETCH (UID %d BODY[] {%d}\r\n%s)\r\n", i, len(m), m)
+					case f[0] == "UID":
+						mu.Lock()
+						log = append(log, command)
+						mu.Unlock()
+					}
+					fmt.Fprintf(conn, "%s OK done\r\n", tag)
+				}
+			}()
+		}
+	}()
+	return ln.Addr().(*net.TCPAddr).Port, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), log...)
+	}
+}
+
+func TestMailbox(t *testing.T) {
+	port, changes := imapServer(t, []string{
+		"Message-ID: <1@x>\nFrom: me@example.com\nTo: til+go@example.com\nSubject: Slices\n\nSlices share arrays.\n",
+		"Message-ID: <2@x>\nFrom: spam@example.org\nSubject: Buy\n\nNow.\n",
+	})
+	root := newTree(t, nil)
+
+	if _, err := run(t, root, "mailbox", "--once"); err == nil || err.Error() != "set host in [mailbox]" {
+		t.Errorf("mailbox without a host = %v", err)
+	}
+	writeConfig(t, fmt.Sprintf("[mailbox]\nhost = \"127.0.0.1\"\nport = %d\nsecurity = \"none\"\nusername = \"til\"\npassword_env = \"TIL_TEST_MAIL\"\narchive = \"Archive\"\nallow_from = [\"me@example.com\"]\n", port))
+	if _, err := run(t, root, "mailbox", "--once"); err == nil || err.Error() != "$TIL_TEST_MAIL is not set" {
+		t.Errorf("mailbox without a password = %v", err)
+	}
+	t.Setenv("TIL_TEST_MAIL", "secret")
+
+	want := "inbox/slices.md\nwarning: message 2: sender not allowed: spam@example.org\n"
+	if out := mustRun(t, root, "mailbox", "-n"); out != want || len(changes()) != 0 {
+		t.Errorf("mailbox -n =\n%s\nchanged %q", out, changes())
+	}
+	if out := mustRun(t, root, "mailbox", "--once", "-c", "go"); out != "go/slices.md\nwarning: message 2: sender not allowed: spam@example.org\n" {
+		t.Errorf("mailbox --once =\n%s", out)
+	}
+	if got := readFile(t, root, "go/slices.md"); !strings.Contains(got, "tags: [go]\n") || !strings.Contains(got, "imported: mail:1@x\n") || !strings.HasSuffix(got, "\nSlices share arrays.\n") {
+		t.Errorf("go/slices.md =\n%s", got)
+	}
+	wantChanges := `UID STORE 1 +FLAGS.SILENT (\Seen),UID MOVE 1 "Archive",UID STORE 2 +FLAGS.SILENT (\Seen)`
+	if got := strings.Join(changes(), ","); got != wantChanges {
+		t.Errorf("changes = %s, want %s", got, wantChanges)
+	}
+
+	// The server still offers the message, which is not imported twice.
+	if out := mustRun(t, root, "mailbox", "--once"); out != "warning: message 2: sender not allowed: spam@example.org\n" {
+		t.Errorf("second mailbox --once =\n%s", out)
+	}
+	if _, err := run(t, root, "mailbox", "--once", "--interval", "0s"); err == nil {
+		t.Error("mailbox with a zero interval succeeded")
+	}
+}
@@ -0,0 +1,212 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/canhta/til/go/internal/move"
+	"github.com/canhta/til/go/internal/notes"
+	"github.com/canhta/til/go/internal/tags"
+	"github.com/canhta/til/go/internal/verify"
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+func newMetaCmd(a *app) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "meta",
+		Short: "Edit the frontmatter of many entries at once",
+	}
+	cmd.AddCommand(newMetaSetCmd(a))
+	return cmd
+}
+
+// metaEdit is the change til meta set makes to each entry.
+type metaEdit struct {
+	tag, untag []string
+	fields     []string
+	unset      []string
+	category   string
+}
+
+func newMetaSetCmd(a *app) *cobra.Command {
+	var (
+		ed     metaEdit
+		filter string
+		all    bool
+		dryRun bool
+	)
+	cmd := &cobra.Command{
+		Use:   "set [entry...]",
+		Short: "Add and remove tags, set fields and move entries to a category",
+		Long: `Set edits the frontmatter of the given entries, of those matching the
+--filter query, as til search takes it, or with --all of every entry: it
+adds and removes tags, sets and unsets fields, and moves entries, with
+their assets, to another category, where their site URLs redirect from the
+old ones. The links to the entries changed are rewritten as til mv does.
+
+Only the lines of the fields changed are rewritten. Every file is written
+in one transaction: when one cannot be, the others are restored and
+nothing changes. --dry-run shows the changes to each frontmatter as a diff
+instead.`,
+		Example: `  til meta set --tag go --filter 'category:golang'
+  til meta set --category go --filter 'category:golang' -n
+  til meta set --field difficulty=easy --untag wip go/slices go/maps`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(ed.tag)+len(ed.untag)+len(ed.fields)+len(ed.unset) == 0 && ed.category == "" {
+				return errors.New("nothing to change; pass --tag, --untag, --field, --unset or --category")
+			}
+			if len(args) == 0 && filter == "" && !all {
+				return errors.New("name the entries, or select them with --filter or --all")
+			}
+			if ed.category != "" && (notes.Skip(ed.category) || strings.ContainsAny(ed.category, `/\`)) {
+				return fmt.Errorf("invalid category %q", ed.category)
+			}
+			allow, err := tags.LoadAllowlist(a.tree)
+			if err != nil {
+				return err
+			}
+			if u := allow.Unknown(ed.tag); len(u) > 0 {
+				return fmt.Errorf("tags not in the allowlist: %s", strings.Join(u, ", "))
+			}
+			entries, err := a.entriesOrAll(args)
+			if err != nil {
+				return err
+			}
+			if filter != "" {
+				x, err := a.parseQuery(filter, time.Now())
+				if err != nil {
+					return err
+				}
+				entries = slices.DeleteFunc(entries, func(e *entry.Entry) bool { return !x.Match(e) })
+			}
+			return a.setMeta(cmd.OutOrStdout(), entries, ed, dryRun)
+		},
+	}
+	fs := cmd.Flags()
+	fs.StringArrayVarP(&ed.tag, "tag", "t", nil, "add a tag (repeatable)")
+	fs.StringArrayVar(&ed.untag, "untag", nil, "remove a tag (repeatable)")
+	fs.StringArrayVar(&ed.fields, "field", nil, "set a field, as key=value with the value in YAML (repeatable)")
+	fs.StringArrayVar(&ed.unset, "unset", nil, "remove a field (repeatable)")
+	fs.StringVar(&ed.category, "category", "", "move the entries to this category")
+	fs.StringVarP(&filter, "filter", "f", "", "edit the entries matching this query")
+	fs.BoolVar(&all, "all", false, "edit every entry")
+	fs.BoolVarP(&dryRun, "dry-run", "n", false, "show the changes as a diff without writing them")
+	return cmd
+}
+
+// setMeta applies ed to entries as one transaction, or with dryRun prints
+// the changes it would make.
+func (a *app) setMeta(out io.Writer, entries []*entry.Entry, ed metaEdit, dryRun bool) error {
+	type value struct {
+		key string
+		v   any
+	}
+	var fields []value
+	for _, f := range ed.fields {
+		key, v, ok := strings.Cut(f, "=")
+		if !ok || key == "" {
+			return fmt.Errorf("--field %s: want key=value", f)
+		}
+		fields = append(fields, value{key, fieldValue(v)})
+	}
+	edits := map[string][]byte{}
+	moves := map[string]string{}
+	for _, e := range entries {
+		data, err := a.tree.Read(e.Path)
+		if err != nil {
+			return err
+		}
+		dest := e.Path
+		if ed.category != "" && ed.category != e.Meta.Category {
+			_, rest, _ := strings.Cut(e.Path, "/")
+			dest = path.Join(ed.category, rest)
+		}
+		next, err := entry.Rewrite(data, func(f *entry.Front) error {
+			if len(ed.tag)+len(ed.untag) > 0 {
+				var old []string
+				if _, err := f.Get("tags", &old); err != nil {
+					return err
+				}
+				t := slices.DeleteFunc(slices.Clone(old), func(t string) bool { return slices.Contains(ed.untag, t) })
+				for _, add := range ed.tag {
+					if !slices.Contains(t, add) {
+						t = append(t, add)
+					}
+				}
+				if !slices.Equal(t, old) {
+					if err := f.Set("tags", t); err != nil {
+						return err
+					}
+				}
+			}
+			for _, v := range fields {
+				if err := f.Set(v.key, v.v); err != nil {
+					return err
+				}
+			}
+			for _, key := range ed.unset {
+				if _, err := f.Delete(key); err != nil {
+					return err
+				}
+			}
+			if dest != e.Path && f.Has("category") {
+				return f.Set("category", ed.category)
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("%s: %w", e.Path, err)
+		}
+		// Entries the edit leaves as they were are not rewritten, and those
+		// only moved are not given an empty frontmatter block.
+		if string(frontOf(data)) == string(frontOf(next)) {
+			if dest == e.Path {
+				continue
+			}
+			next = data
+		}
+		if dryRun {
+			printMetaDiff(out, e.Path, dest, data, next)
+		}
+		edits[e.Path] = next
+		if dest != e.Path {
+			moves[e.Path] = dest
+		}
+	}
+	// Moves, and edits to slugs and titles, change what links resolve to.
+	plan, err := move.Entries(a.tree, moves, edits)
+	if err != nil {
+		return err
+	}
+	if dryRun {
+		return a.printMove(out, plan, edits)
+	}
+	if err := a.applyMove(out, plan); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "%d entries updated\n", len(edits))
+	return nil
+}
+
+func frontOf(data []byte) []byte {
+	front, _, _ := entry.SplitFrontmatter(data)
+	return front
+}
+
+// printMetaDiff prints the lines of the frontmatter changed from data to
+// next, under the paths of the entry before and after.
+func printMetaDiff(w io.Writer, from, to string, data, next []byte) {
+	fmt.Fprintf(w, "--- %s\n+++ %s\n", from, to)
+	for _, l := range strings.SplitAfter(verify.Diff(string(frontOf(data)), string(frontOf(next))), "\n") {
+		if strings.HasPrefix(l, "-") || strings.HasPrefix(l, "+") {
+			fmt.Fprint(w, l)
+		}
+	}
+}
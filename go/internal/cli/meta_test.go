@@ -0,0 +1,115 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMetaSet(t *testing.T) {
+	root := newTree(t, map[string]string{
+		"go/slices.md":  "---\ntitle: Slices\ntags: [go, wip] # keep\nlegacy: 1\n---\n\nSee [maps](maps.md).\n",
+		"go/maps.md":    "---\ntitle: Maps\ntags:\n  - go\n---\n\nMaps.\n",
+		"git/rebase.md": "---\ntitle: Rebase\ntags: [git]\n---\n\nRebase.\n",
+	})
+	out := mustRun(t, root, "meta", "set", "--tag", "go", "--tag", "perf", "--untag", "wip", "--field", "difficulty=easy", "--field", "minutes=5", "--unset", "legacy", "go/slices.md", "go/maps.md")
+	if !strings.HasSuffix(out, "2 entries updated\n") {
+		t.Errorf("meta set =\n%s", out)
+	}
+	if got, want := readFile(t, root, "go/slices.md"), "---\ntitle: Slices\ntags: [go, perf] # keep\ndifficulty: easy\nminutes: 5\n---\n\nSee [maps](maps.md).\n"; got != want {
+		t.Errorf("go/slices.md =\n%s\nwant\n%s", got, want)
+	}
+	if got, want := readFile(t, root, "go/maps.md"), "---\ntitle: Maps\ntags:\n  - go\n  - perf\ndifficulty: easy\nminutes: 5\n---\n\nMaps.\n"; got != want {
+		t.Errorf("go/maps.md =\n%s\nwant\n%s", got, want)
+	}
+	// Entries the edit leaves as they were are not counted.
+	if out := mustRun(t, root, "meta", "set", "--tag", "perf", "--filter", "tag:go"); out != "0 entries updated\n" {
+		t.Errorf("meta set again =\n%s", out)
+	}
+
+	out = mustRun(t, root, "meta", "set", "-n", "--untag", "perf", "--all")
+	want := "--- go/maps.md\n+++ go/maps.md\n-  - perf\n--- go/slices.md\n+++ go/slices.md\n-tags: [go, perf] # keep\n+tags: [go] # keep\n"
+	if out != want {
+		t.Errorf("meta set -n =\n%s\nwant\n%s", out, want)
+	}
+	if got := readFile(t, root, "go/maps.md"); !strings.Contains(got, "perf") {
+		t.Error("meta set -n wrote go/maps.md")
+	}
+
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"go/slices.md"}, "nothing to change"},
+		{[]string{"--tag", "x"}, "name the entries"},
+		{[]string{"--category", "a/b", "--all"}, `invalid category "a/b"`},
+		{[]string{"--category", ".til", "--all"}, `invalid category ".til"`},
+		{[]string{"--field", "=x", "--all"}, "--field =x: want key=value"},
+		{[]string{"--tag", "x", "go/nowhere.md"}, "nowhere"},
+		{[]string{"--tag", "x", "--filter", "created:>bad"}, "bad"},
+	}
+	for _, tt := range tests {
+		if _, err := run(t, root, append([]string{"meta", "set"}, tt.args...)...); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("meta set %q = %v, want %s", tt.args, err, tt.want)
+		}
+	}
+	writeFile(t, root, ".til/tags.txt", "go\ngit\n")
+	if _, err := run(t, root, "meta", "set", "--tag", "rust", "--all"); err == nil || err.Error() != "tags not in the allowlist: rust" {
+		t.Errorf("meta set with a tag not allowed = %v", err)
+	}
+}
+
+func TestMetaSetCategory(t *testing.T) {
+	root := newTree(t, map[string]string{
+		"golang/slices.md":           "---\ntitle: Slices\ncategory: golang\n---\n\n![diagram](assets/slices/d.svg) and [maps](maps.md).\n",
+		"golang/assets/slices/d.svg": "<svg/>",
+		"golang/maps.md":             "---\ntitle: Maps\n---\n\nSee [slices](slices.md).\n",
+		"git/rebase.md":              "---\ntitle: Rebase\n---\n\nLike [slices](../golang/slices.md) and [[maps]].\n",
+	})
+	mustRun(t, root, "build")
+	out := mustRun(t, root, "meta", "set", "--category", "go", "--filter", "category:golang")
+	for _, want := range []string{"moved golang/maps.md to go/maps.md\n", "moved golang/slices.md to go/slices.md\n", "rewrote the links of git/rebase.md\n", "2 entries updated\n"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("meta set --category lacks %s:\n%s", want, out)
+		}
+	}
+	if got, want := readFile(t, root, "go/slices.md"), "---\ntitle: Slices\ncategory: go\n---\n\n![diagram](assets/slices/d.svg) and [maps](maps.md).\n"; got != want {
+		t.Errorf("go/slices.md =\n%s\nwant\n%s", got, want)
+	}
+	if got, want := readFile(t, root, "git/rebase.md"), "---\ntitle: Rebase\n---\n\nLike [slices](../go/slices.md) and [[maps]].\n"; got != want {
+		t.Errorf("git/rebase.md =\n%s\nwant\n%s", got, want)
+	}
+	if readFile(t, root, "go/assets/slices/d.svg") != "<svg/>" {
+		t.Error("assets not moved")
+	}
+	for _, p := range []string{"golang/slices.md", "golang/maps.md", "golang/assets/slices/d.svg"} {
+		if _, err := os.Stat(filepath.Join(root, p)); err == nil {
+			t.Errorf("%s left behind", p)
+		}
+	}
+	// Entries without frontmatter move as they are.
+	writeFile(t, root, "golang/chans.md", "# Channels\n")
+	mustRun(t, root, "meta", "set", "--category", "go", "golang/chans.md")
+	if got := readFile(t, root, "go/chans.md"); got != "# Channels\n" {
+		t.Errorf("go/chans.md =\n%s", got)
+	}
+
+	// The site redirects from the URLs the entries were published at.
+	mustRun(t, root, "build")
+	if got := readFile(t, root, "public/golang/slices/index.html"); !strings.Contains(got, `url=/go/slices/"`) {
+		t.Errorf("public/golang/slices/index.html =\n%s", got)
+	}
+
+	// A move onto an existing entry changes nothing.
+	writeFile(t, root, "git/maps.md", "---\ntitle: Git maps\n---\n")
+	if _, err := run(t, root, "meta", "set", "--category", "git", "--tag", "x", "go/slices.md", "go/maps.md"); err == nil {
+		t.Error("meta set moving onto an existing entry succeeded")
+	}
+	if got := readFile(t, root, "go/slices.md"); strings.Contains(got, "tags") || strings.Contains(got, "category: git") {
+		t.Errorf("go/slices.md changed by a failed meta set:\n%s", got)
+	}
+	if _, err := os.Stat(filepath.Join(root, "git/slices.md")); err == nil {
+		t.Error("git/slices.md written by a failed meta set")
+	}
+}
@@ -0,0 +1,124 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"github.com/canhta/til/go/internal/config"
+	"github.com/canhta/til/go/internal/schema"
+)
+
+func newMigrateCmd(a *app) *cobra.Command {
+	var (
+		renames, deletes, defaults []string
+		dryRun                     bool
+	)
+	cmd := &cobra.Command{
+		Use:   "migrate [entry...]",
+		Short: "Rewrite the frontmatter of entries as the schema changed",
+		Long: `Migrate applies the [[schema.migrations]] of the config file, in order, to
+every entry or the given ones: renaming a field, replacing some of its
+values, deleting it, or setting a default on the entries without it.
+
+  [[schema.migrations]]
+  field = "level"
+  rename = "difficulty"
+
+  [[schema.migrations]]
+  field = "difficulty"
+  values = { beginner = "easy", advanced = "hard" }
+
+Migrations change only the lines of the fields they touch and do nothing
+to entries already migrated, so they can stay in the config file and be
+run again. --rename, --delete and --default give migrations on the command
+line instead, applied in that order. The files are rewritten together, or none is when an entry
+cannot be migrated.`,
+		Example: `  til migrate -n
+  til migrate --rename level=difficulty
+  til migrate --default difficulty=easy go/channels`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ms := a.cfg.Schema.Migrations
+			if len(renames)+len(deletes)+len(defaults) > 0 {
+				ms = nil
+				for _, r := range renames {
+					from, to, ok := strings.Cut(r, "=")
+					if !ok {
+						return fmt.Errorf("--rename %s: want old=new", r)
+					}
+					ms = append(ms, config.Migration{Field: from, Rename: to})
+				}
+				for _, d := range deletes {
+					ms = append(ms, config.Migration{Field: d, Delete: true})
+				}
+				for _, d := range defaults {
+					key, value, ok := strings.Cut(d, "=")
+					if !ok {
+						return fmt.Errorf("--default %s: want field=value", d)
+					}
+					ms = append(ms, config.Migration{Field: key, Default: fieldValue(value)})
+				}
+			}
+			if len(ms) == 0 {
+				return fmt.Errorf("no migrations; add [[schema.migrations]] to the config file or pass --rename, --delete or --default")
+			}
+			if err := schema.Valid(config.Schema{Migrations: ms}); err != nil {
+				return err
+			}
+			entries, err := a.entriesOrAll(args)
+			if err != nil {
+				return err
+			}
+			files := map[string][]byte{}
+			out := cmd.OutOrStdout()
+			for _, e := range entries {
+				data, err := a.tree.Read(e.Path)
+				if err != nil {
+					return err
+				}
+				next, done, err := schema.Migrate(data, ms)
+				if err != nil {
+					return fmt.Errorf("%s: %w", e.Path, err)
+				}
+				if len(done) == 0 {
+					continue
+				}
+				files[e.Path] = next
+				fmt.Fprintf(out, "%s: %s\n", e.Path, strings.Join(done, ", "))
+			}
+			if dryRun {
+				return nil
+			}
+			if err := a.tree.WriteAll(files); err != nil {
+				return err
+			}
+			fmt.Fprintf(out, "%d entries migrated\n", len(files))
+			return nil
+		},
+	}
+	cmd.Flags().StringArrayVar(&renames, "rename", nil, "rename a field, as old=new (repeatable)")
+	cmd.Flags().StringArrayVar(&deletes, "delete", nil, "delete a field (repeatable)")
+	cmd.Flags().StringArrayVar(&defaults, "default", nil, "set a field on the entries without it, as field=value (repeatable)")
+	cmd.Flags().BoolVarP(&dryRun, "dry-run", "n", false, "show the changes without writing them")
+	return cmd
+}
+
+// fieldValue parses a frontmatter value given on the command line as YAML,
+// so that true, 3 and [a, b] are a boolean, a number and a list; anything
+// else, or nothing, is the string itself.
+func fieldValue(s string) any {
+	var v any
+	if err := yaml.Unmarshal([]byte(s), &v); err != nil || v == nil {
+		return s
+	}
+	if l, ok := v.([]any); ok {
+		items := make([]string, len(l))
+		for i, it := range l {
+			items[i] = fmt.Sprint(it)
+		}
+		return items
+	}
+	return v
+}
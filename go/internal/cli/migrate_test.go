@@ -0,0 +1,103 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+)
+
+const schemaConfig = `[schema]
+required = ["difficulty"]
+categories = ["go", "git"]
+
+[schema.fields.difficulty]
+values = ["easy", "hard"]
+
+[[schema.migrations]]
+field = "level"
+rename = "difficulty"
+
+[[schema.migrations]]
+field = "difficulty"
+values = { beginner = "easy" }
+`
+
+func TestSchemaNewAndLint(t *testing.T) {
+	root := newTree(t, map[string]string{
+		"go/slices.md": "---\ntitle: Slices\ndate: 2024-01-01\ntags: [go]\ndifficulty: medium\n---\n",
+	})
+	writeConfig(t, schemaConfig)
+
+	if _, err := run(t, root, "new", "rust", "Ownership", "--no-edit"); err == nil || !strings.Contains(err.Error(), `the entry would break the schema: category "rust" is not one of go, git`) {
+		t.Errorf("new in a category not in the schema = %v", err)
+	}
+	out := mustRun(t, root, "new", "go", "Maps", "--no-edit")
+	if !strings.Contains(out, "warning: go/maps.md: missing difficulty") {
+		t.Errorf("new =\n%s", out)
+	}
+
+	out, err := run(t, root, "lint", "--rule", "schema", "--rule", "required-field")
+	if err == nil {
+		t.Error("lint succeeded")
+	}
+	for _, want := range []string{"go/maps.md:1: missing difficulty (required-field)", `go/slices.md:5: difficulty "medium" is not one of easy, hard (schema)`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("lint lacks %s:\n%s", want, out)
+		}
+	}
+
+	writeConfig(t, "[schema.fields.difficulty]\ntype = \"enum\"\n")
+	if _, err := run(t, root, "lint"); err == nil || !strings.Contains(err.Error(), `unknown type "enum"`) {
+		t.Errorf("lint with a bad schema = %v", err)
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	root := newTree(t, map[string]string{
+		"go/slices.md":  "---\ntitle: Slices\nlevel: beginner\n---\n",
+		"go/maps.md":    "---\ntitle: Maps\ndifficulty: hard\n---\n",
+		"git/rebase.md": "---\ntitle: Rebase\nlevel: hard\ndifficulty: easy\n---\n",
+	})
+	if _, err := run(t, root, "migrate"); err == nil || !strings.HasPrefix(err.Error(), "no migrations") {
+		t.Errorf("migrate without migrations = %v", err)
+	}
+	writeConfig(t, schemaConfig)
+
+	// One entry cannot be migrated, so none is.
+	if _, err := run(t, root, "migrate"); err == nil || err.Error() != "git/rebase.md: cannot rename level: difficulty is already set" {
+		t.Errorf("migrate = %v", err)
+	}
+	if got := readFile(t, root, "go/slices.md"); got != "---\ntitle: Slices\nlevel: beginner\n---\n" {
+		t.Errorf("go/slices.md migrated despite the failure:\n%s", got)
+	}
+
+	writeFile(t, root, "git/rebase.md", "---\ntitle: Rebase\n---\n")
+	if out := mustRun(t, root, "migrate", "-n"); out != "go/slices.md: renamed level to difficulty, changed difficulty from beginner to easy\n" {
+		t.Errorf("migrate -n =\n%s", out)
+	}
+	if got := readFile(t, root, "go/slices.md"); !strings.Contains(got, "level: beginner") {
+		t.Errorf("migrate -n wrote go/slices.md:\n%s", got)
+	}
+	if out := mustRun(t, root, "migrate"); !strings.HasSuffix(out, "1 entries migrated\n") {
+		t.Errorf("migrate =\n%s", out)
+	}
+	if got := readFile(t, root, "go/slices.md"); got != "---\ntitle: Slices\ndifficulty: easy\n---\n" {
+		t.Errorf("go/slices.md =\n%s", got)
+	}
+	if out := mustRun(t, root, "migrate"); out != "0 entries migrated\n" {
+		t.Errorf("migrate again =\n%s", out)
+	}
+
+	// Flags replace the migrations of the config file.
+	out := mustRun(t, root, "migrate", "--rename", "difficulty=level", "--default", "level=[a, 3]", "--default", "reviewed=true", "git/rebase.md")
+	if out != "git/rebase.md: set level to [a 3], set reviewed to true\n1 entries migrated\n" {
+		t.Errorf("migrate with flags =\n%s", out)
+	}
+	if got := readFile(t, root, "git/rebase.md"); got != "---\ntitle: Rebase\nlevel: [a, \"3\"]\nreviewed: true\n---\n" {
+		t.Errorf("git/rebase.md =\n%s", got)
+	}
+	for _, args := range [][]string{{"--rename", "level"}, {"--default", "level"}} {
+		if _, err := run(t, root, append([]string{"migrate"}, args...)...); err == nil {
+			t.Errorf("migrate %q succeeded", args)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/x/ansi"
+	"github.com/spf13/cobra"
+
+	"github.com/canhta/til/go/internal/fsutil"
+	"github.com/canhta/til/go/internal/notes"
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+// motdFile is the file in the state directory holding the tip of the day.
+const motdFile = "motd.json"
+
+// motdWords is how much of an entry without code its tip shows.
+const motdWords = 40
+
+func newMotdCmd(a *app) *cobra.Command {
+	var (
+		again bool
+		lines int
+	)
+	cmd := &cobra.Command{
+		Use:   "motd",
+		Short: "Print a short tip from a random entry, for shell startup",
+		Long: `Motd prints a tip from an entry picked at random: its title and path, and
+its first code block, cut to --lines lines, or the start of its prose when
+it has no code. Drafts, private and archived entries are left out.
+
+The tip is picked once a day and kept in ` + notes.StateDir + `/` + motdFile + `, so that
+printing it again, as every new shell does, reads that file and not the
+entries; --new picks another for the rest of the day. Outside a notes tree,
+or in one without entries, motd prints nothing, so that it can go in a
+shell's startup file as is:
+
+  # ~/.zshrc or ~/.bashrc
+  til motd`,
+		Example: `  til motd
+  til motd --new --lines 4`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if lines < 1 {
+				return errors.New("--lines must be at least 1")
+			}
+			if !a.inNotes() {
+				return nil
+			}
+			day := time.Now().Format(time.DateOnly)
+			t, err := a.loadMotd(day)
+			if err != nil || again {
+				if t, err = a.pickMotd(day); err != nil || t == nil {
+					return err
+				}
+			}
+			return a.output(cmd, t, func(w io.Writer) error {
+				return t.write(w, lines, min(termWidth(w), 80), isTerminal(w) && os.Getenv("NO_COLOR") == "")
+			})
+		},
+	}
+	withJSON(cmd, "motd")
+	cmd.Flags().BoolVar(&again, "new", false, "pick another entry for today")
+	cmd.Flags().IntVarP(&lines, "lines", "n", 8, "show at most this many lines of code")
+	return cmd
+}
+
+// motd is the tip of a day.
+type motd struct {
+	Day   string `json:"day"`
+	Path  string `json:"path"`
+	Title string `json:"title"`
+	// Lang and Code are the entry's first code block; Text is the start
+	// of its prose when it has none.
+	Lang string `json:"lang,omitempty"`
+	Code string `json:"code,omitempty"`
+	Text string `json:"text,omitempty"`
+}
+
+// inNotes reports whether the tree is a notes tree rather than a directory
+// FindRoot settled on for want of one, which motd leaves alone rather
+// than walk it on every new shell.
+func (a *app) inNotes() bool {
+	for _, marker := range []string{notes.StateDir, ".git"} {
+		if fi, err := os.Stat(filepath.Join(a.tree.Root, marker)); err == nil && fi.IsDir() {
+			return true
+		}
+	}
+	return false
+}
+
+// loadMotd returns the tip saved for day, failing when there is none or
+// its entry is gone.
+func (a *app) loadMotd(day string) (*motd, error) {
+	data, err := os.ReadFile(a.tree.StatePath(motdFile))
+	if err != nil {
+		return nil, err
+	}
+	var t motd
+	if err := json.Unmarshal(data, &t); err != nil {
+		return nil, err
+	}
+	if t.Day != day {
+		return nil, errors.New("tip of another day")
+	}
+	if ok, err := a.tree.Exists(t.Path); err != nil || !ok {
+		return nil, fmt.Errorf("%s is gone", t.Path)
+	}
+	return &t, nil
+}
+
+// pickMotd picks the tip of day and saves it, returning nil when there is
+// no entry to pick.
+func (a *app) pickMotd(day string) (*motd, error) {
+	entries, err := a.tree.Entries()
+	if err != nil {
+		return nil, err
+	}
+	entries = slices.DeleteFunc(entries, func(e *entry.Entry) bool {
+		return e.Meta.Draft || e.Meta.Private || e.Meta.Archived
+	})
+	if len(entries) == 0 {
+		return nil, nil
+	}
+	e := entries[rand.IntN(len(entries))]
+	t := &motd{Day: day, Path: e.Path, Title: e.Meta.Title}
+	blocks := entry.CodeBlocks(e.Body)
+	if i := slices.IndexFunc(blocks, func(b entry.CodeBlock) bool { return strings.TrimSpace(b.Code) != "" }); i >= 0 {
+		t.Lang, t.Code = blocks[i].Lang, blocks[i].Code
+	} else {
+		t.Text = entry.Excerpt(e.Body, motdWords)
+	}
+	data, err := json.Marshal(t)
+	if err != nil {
+		return nil, err
+	}
+	// Failing to save only costs the next shell the picking.
+	_ = fsutil.WriteFile(a.tree.StatePath(motdFile), data, 0o644)
+	return t, nil
+}
+
+// write prints the tip, its code cut to lines lines and its prose wrapped
+// at width, in bold and dim when color is set.
+func (t *motd) write(w io.Writer, lines, width int, color bool) error {
+	bold, dim, reset := "", "", ""
+	if color {
+		bold, dim, reset = "\x1b[1m", "\x1b[2m", "\x1b[0m"
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%sTIL%s %s  %s%s%s\n", dim, reset, bold+t.Title+reset, dim, t.Path, reset)
+	if t.Code != "" {
+		code := strings.Split(strings.TrimRight(t.Code, "\n"), "\n")
+		more := len(code) > lines
+		if more {
+			code = code[:lines]
+		}
+		for _, l := range code {
+			b.WriteString("  " + strings.ReplaceAll(l, "\t", "    ") + "\n")
+		}
+		if more {
+			fmt.Fprintf(&b, "  %s…%s\n", dim, reset)
+		}
+	} else if t.Text != "" {
+		for _, l := range strings.Split(ansi.Wordwrap(t.Text, width-2, ""), "\n") {
+			b.WriteString("  " + l + "\n")
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,97 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMotd(t *testing.T) {
+	root := newTree(t, map[string]string{
+		"go/slices.md": "---\ntitle: Slices\n---\n\n# Slices\n\n```go\na := []int{1, 2, 3}\nb := a[:2]\nb = append(b, 4)\nfmt.Println(a)\n```\n",
+		"go/wip.md":    "---\ntitle: Unfinished\ndraft: true\n---\n\n```go\nwip\n```\n",
+		"go/secret.md": "---\ntitle: Secret\nprivate: true\n---\n\nShh.\n",
+		"go/old.md":    "---\ntitle: Old\narchived: true\n---\n\nOld.\n",
+	})
+	want := "TIL Slices  go/slices.md\n  a := []int{1, 2, 3}\n  b := a[:2]\n  b = append(b, 4)\n  fmt.Println(a)\n"
+	if out := mustRun(t, root, "motd"); out != want {
+		t.Errorf("motd =\n%s\nwant\n%s", out, want)
+	}
+	if out := mustRun(t, root, "motd", "-n", "2"); out != "TIL Slices  go/slices.md\n  a := []int{1, 2, 3}\n  b := a[:2]\n  …\n" {
+		t.Errorf("motd -n 2 =\n%s", out)
+	}
+	if out := mustRun(t, root, "motd", "--json"); !strings.Contains(out, `"kind": "motd"`) || !strings.Contains(out, `"lang": "go"`) {
+		t.Errorf("motd --json =\n%s", out)
+	}
+	if _, err := run(t, root, "motd", "--lines", "0"); err == nil || err.Error() != "--lines must be at least 1" {
+		t.Errorf("motd --lines 0 = %v", err)
+	}
+}
+
+// TestMotdCached checks that the tip of the day is read back from the state
+// directory, and picked anew with --new, on another day or once its entry
+// is gone.
+func TestMotdCached(t *testing.T) {
+	root := newTree(t, map[string]string{
+		"go/maps.md":    "---\ntitle: Maps\n---\n\nMaps are references to a hash table, so that passing one to a function lets it change the map of the caller.\n",
+		"git/rebase.md": "---\ntitle: Rebase\n---\n\nRebase onto main.\n",
+	})
+	today := time.Now().Format(time.DateOnly)
+	saved := func(day, p string) {
+		t.Helper()
+		writeFile(t, root, ".til/motd.json", `{"day":"`+day+`","path":"`+p+`","title":"Saved","text":"From the cache."}`)
+	}
+
+	saved(today, "git/rebase.md")
+	if out := mustRun(t, root, "motd"); out != "TIL Saved  git/rebase.md\n  From the cache.\n" {
+		t.Errorf("motd with a tip saved =\n%s", out)
+	}
+	for _, tt := range []struct {
+		name string
+		args []string
+		day  string
+		path string
+	}{
+		{"--new", []string{"--new"}, today, "git/rebase.md"},
+		{"another day", nil, "2000-01-01", "git/rebase.md"},
+		{"entry gone", nil, today, "git/gone.md"},
+	} {
+		saved(tt.day, tt.path)
+		out := mustRun(t, root, append([]string{"motd"}, tt.args...)...)
+		if strings.Contains(out, "Saved") || !strings.HasPrefix(out, "TIL Maps  go/maps.md\n") && out != "TIL Rebase  git/rebase.md\n  Rebase onto main.\n" {
+			t.Errorf("motd, %s =\n%s", tt.name, out)
+		}
+		if got := readFile(t, root, ".til/motd.json"); !strings.Contains(got, `"day":"`+today+`"`) || strings.Contains(got, "Saved") {
+			t.Errorf("motd, %s, saved %s", tt.name, got)
+		}
+	}
+
+	// Prose is wrapped and indented.
+	writeFile(t, root, ".til/motd.json", `{"day":"`+today+`","path":"go/maps.md","title":"Maps","text":"`+strings.Repeat("word ", 20)+`end"}`)
+	out := mustRun(t, root, "motd")
+	for _, l := range strings.Split(strings.TrimSuffix(out, "\n"), "\n")[1:] {
+		if !strings.HasPrefix(l, "  word") && l != "  end" || len(l) > 80 {
+			t.Errorf("motd prose line %q", l)
+		}
+	}
+}
+
+func TestMotdOutsideNotes(t *testing.T) {
+	root := newTree(t, map[string]string{"go/slices.md": "# Slices\n"})
+	if err := os.Remove(filepath.Join(root, ".til")); err != nil {
+		t.Fatal(err)
+	}
+	if out := mustRun(t, root, "motd"); out != "" {
+		t.Errorf("motd outside a notes tree = %q", out)
+	}
+	if _, err := os.Stat(filepath.Join(root, ".til")); err == nil {
+		t.Error("motd outside a notes tree saved a tip")
+	}
+
+	empty := newTree(t, nil)
+	if out := mustRun(t, empty, "motd"); out != "" {
+		t.Errorf("motd without entries = %q", out)
+	}
+}
@@ -0,0 +1,217 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/canhta/til/go/internal/move"
+	"github.com/canhta/til/go/internal/notes"
+	"github.com/canhta/til/go/internal/site"
+	"github.com/canhta/til/go/internal/verify"
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+func newMvCmd(a *app) *cobra.Command {
+	var dryRun bool
+	cmd := &cobra.Command{
+		Use:   "mv <entry>... [destination]",
+		Short: "Move entries to another category or file, rewriting the links to them",
+		Long: `Mv moves entries, with their assets, into the category directory given
+as the destination, such as databases/, or renames a single entry to the
+file given, such as databases/slices-and-arrays.md. Given one entry alone,
+it moves the entry to the category its category frontmatter names, once
+that was changed by hand. A category field the entries set is updated to
+the category they move to.
+
+The links of every entry to the moved entries and their assets, and those
+of the moved entries themselves, are rewritten to keep pointing where they
+did, and the site redirects from the URLs of the moved entries to their new
+ones. When a [[link]] names several entries, one of them moved, mv cannot
+tell which is meant: it reports those links and moves nothing. --dry-run
+shows the changes instead.`,
+		Example: `  til mv go/slices.md databases/
+  til mv go/slices databases/slices-and-arrays.md
+  til mv go/slices -n`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			refs, dest := args, ""
+			if len(args) > 1 {
+				refs, dest = args[:len(args)-1], args[len(args)-1]
+			}
+			if dest != "" && strings.HasSuffix(dest, ".md") && len(refs) > 1 {
+				return fmt.Errorf("cannot rename %d entries to %s; give a category directory", len(refs), dest)
+			}
+			moves := map[string]string{}
+			edits := map[string][]byte{}
+			var order []string
+			for _, ref := range refs {
+				e, err := a.resolve(ref)
+				if err != nil {
+					return err
+				}
+				data, err := a.tree.Read(e.Path)
+				if err != nil {
+					return err
+				}
+				to, err := mvDest(e, data, dest)
+				if err != nil {
+					return err
+				}
+				if to == e.Path {
+					return fmt.Errorf("%s is at %s already", ref, to)
+				}
+				cat := entry.CategoryOf(to)
+				if notes.Skip(cat) || !strings.Contains(to, "/") {
+					return fmt.Errorf("cannot move %s to %s, which is not in a category", e.Path, to)
+				}
+				if _, ok := moves[e.Path]; !ok {
+					order = append(order, e.Path)
+				}
+				moves[e.Path] = to
+				// Entries take the category of their directory unless they
+				// name one.
+				if cat == entry.CategoryOf(e.Path) {
+					continue
+				}
+				named := false
+				next, err := entry.Rewrite(data, func(f *entry.Front) error {
+					if named = f.Has("category"); !named {
+						return nil
+					}
+					return f.Set("category", cat)
+				})
+				if err != nil {
+					return fmt.Errorf("%s: %w", e.Path, err)
+				}
+				// Rewrite gives a file without frontmatter a block of its
+				// own, even an empty one.
+				if named && string(next) != string(data) {
+					edits[e.Path] = next
+				}
+			}
+			plan, err := move.Entries(a.tree, moves, edits)
+			if err != nil {
+				return err
+			}
+			out := cmd.OutOrStdout()
+			if dryRun {
+				for _, from := range order {
+					if next, ok := edits[from]; ok {
+						data, err := a.tree.Read(from)
+						if err != nil {
+							return err
+						}
+						printMetaDiff(out, from, moves[from], data, next)
+					}
+				}
+				return a.printMove(out, plan, edits)
+			}
+			if err := a.applyMove(out, plan); err != nil {
+				return err
+			}
+			also := slices.DeleteFunc(append(slices.Sorted(maps.Keys(plan.Files)), plan.Remove...), func(p string) bool { return p == order[0] })
+			return a.commitEntry(cmd, order[0], "move", also...)
+		},
+	}
+	cmd.Flags().BoolVarP(&dryRun, "dry-run", "n", false, "show the changes without making them")
+	a.commitFlag(cmd)
+	return cmd
+}
+
+// mvDest returns where til mv moves the entry e, of file content data, to:
+// into the directory dest, to the file dest names, or with no dest to the
+// category its frontmatter names.
+func mvDest(e *entry.Entry, data []byte, dest string) (string, error) {
+	name := path.Base(e.Path)
+	switch {
+	case dest == "":
+		var cat string
+		f, err := entry.ParseFront(frontOf(data))
+		if err == nil {
+			_, err = f.Get("category", &cat)
+		}
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", e.Path, err)
+		}
+		if cat == "" {
+			return "", fmt.Errorf("%s names no category; give a destination", e.Path)
+		}
+		_, rest, _ := strings.Cut(e.Path, "/")
+		return path.Join(cat, rest), nil
+	case strings.HasSuffix(dest, ".md") && !strings.HasSuffix(dest, "/"):
+		return cleanDest(dest)
+	}
+	return cleanDest(path.Join(dest, name))
+}
+
+// cleanDest returns the slash-separated path p given on the command line,
+// relative to the notes root, or an error if it leaves the root.
+func cleanDest(p string) (string, error) {
+	c := path.Clean(strings.ReplaceAll(p, `\`, "/"))
+	if path.IsAbs(c) || c == ".." || strings.HasPrefix(c, "../") {
+		return "", fmt.Errorf("%s is outside the notes root", p)
+	}
+	return c, nil
+}
+
+// printMove prints the files plan moves and the links it rewrites, as a
+// diff of the lines changed. edits are the contents the plan started from,
+// by old path, whose other changes are printed already.
+func (a *app) printMove(w io.Writer, plan *move.Plan, edits map[string][]byte) error {
+	for _, from := range slices.Sorted(maps.Keys(plan.Moves)) {
+		if _, ok := edits[from]; !ok {
+			fmt.Fprintf(w, "rename %s => %s\n", from, plan.Moves[from])
+		}
+	}
+	for _, from := range slices.Sorted(maps.Keys(plan.Assets)) {
+		fmt.Fprintf(w, "rename %s => %s\n", from, plan.Assets[from])
+	}
+	old := map[string]string{}
+	for from, to := range plan.Moves {
+		old[to] = from
+	}
+	for _, to := range plan.Relinked {
+		from, ok := old[to]
+		if !ok {
+			from = to
+		}
+		data, ok := edits[from]
+		if !ok {
+			var err error
+			if data, err = a.tree.Read(from); err != nil {
+				return err
+			}
+		}
+		fmt.Fprintf(w, "--- %s\n+++ %s\n", from, to)
+		for _, l := range strings.SplitAfter(verify.Diff(string(data), string(plan.Files[to])), "\n") {
+			if strings.HasPrefix(l, "-") || strings.HasPrefix(l, "+") {
+				fmt.Fprint(w, l)
+			}
+		}
+	}
+	return nil
+}
+
+// applyMove carries out plan as one transaction, and has the site redirect
+// from the URLs of the moved entries.
+func (a *app) applyMove(w io.Writer, plan *move.Plan) error {
+	if err := a.tree.Replace(plan.Files, plan.Remove); err != nil {
+		return err
+	}
+	if err := site.MovePermalinks(a.tree, a.cfg.Site.Permalink, plan.Moved, plan.Moves); err != nil {
+		return err
+	}
+	for _, from := range slices.Sorted(maps.Keys(plan.Moves)) {
+		fmt.Fprintf(w, "moved %s to %s\n", from, plan.Moves[from])
+	}
+	for _, p := range plan.Relinked {
+		fmt.Fprintf(w, "rewrote the links of %s\n", p)
+	}
+	return nil
+}
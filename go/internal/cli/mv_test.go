@@ -0,0 +1,112 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMv(t *testing.T) {
+	root := newTree(t, map[string]string{
+		"go/slices.md":           "---\ntitle: Slices\n---\n\nSee [maps](maps.md) and ![d](assets/slices/d.svg).\n",
+		"go/assets/slices/d.svg": "<svg/>",
+		"go/maps.md":             "---\ntitle: Maps\n---\n\nSee [slices](slices.md) and [[go/slices]].\n",
+		"db/sql.md":              "# SQL\n",
+	})
+	mustRun(t, root, "build")
+
+	out := mustRun(t, root, "mv", "-n", "slices", "db/")
+	for _, want := range []string{
+		"rename go/slices.md => db/slices.md\n",
+		"rename go/assets/slices/d.svg => db/assets/slices/d.svg\n",
+		"--- go/maps.md\n+++ go/maps.md\n-See [slices](slices.md) and [[go/slices]].\n+See [slices](../db/slices.md) and [[db/slices]].\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("mv -n lacks %q:\n%s", want, out)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(root, "db/slices.md")); err == nil {
+		t.Error("mv -n moved the entry")
+	}
+
+	out = mustRun(t, root, "mv", "slices", "db/")
+	if want := "moved go/slices.md to db/slices.md\nrewrote the links of go/maps.md\nrewrote the links of db/slices.md\n"; out != want {
+		t.Errorf("mv =\n%s\nwant\n%s", out, want)
+	}
+	if got := readFile(t, root, "db/slices.md"); got != "---\ntitle: Slices\n---\n\nSee [maps](../go/maps.md) and ![d](assets/slices/d.svg).\n" {
+		t.Errorf("db/slices.md =\n%s", got)
+	}
+	if readFile(t, root, "db/assets/slices/d.svg") != "<svg/>" {
+		t.Error("assets not moved")
+	}
+	for _, p := range []string{"go/slices.md", "go/assets/slices"} {
+		if _, err := os.Stat(filepath.Join(root, p)); err == nil {
+			t.Errorf("%s left behind", p)
+		}
+	}
+	mustRun(t, root, "build")
+	if got := readFile(t, root, "public/go/slices/index.html"); !strings.Contains(got, `url=/db/slices/"`) {
+		t.Errorf("public/go/slices/index.html =\n%s", got)
+	}
+
+	// Renaming to a file.
+	if out := mustRun(t, root, "mv", "db/slices", "db/arrays.md"); !strings.HasPrefix(out, "moved db/slices.md to db/arrays.md\n") {
+		t.Errorf("mv to a file = %q", out)
+	}
+	if got := readFile(t, root, "go/maps.md"); !strings.Contains(got, "[slices](../db/arrays.md) and [[db/arrays]]") {
+		t.Errorf("go/maps.md =\n%s", got)
+	}
+
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"db/arrays", "db/"}, "db/arrays is at db/arrays.md already"},
+		{[]string{"db/arrays", "sql", "git/x.md"}, "cannot rename 2 entries to git/x.md; give a category directory"},
+		{[]string{"db/arrays", "arrays.md"}, "cannot move db/arrays.md to arrays.md, which is not in a category"},
+		{[]string{"db/arrays", "../x/"}, "../x/arrays.md is outside the notes root"},
+		{[]string{"db/arrays", "go/maps.md"}, "db/arrays.md: cannot move to go/maps.md, which exists"},
+		{[]string{"db/arrays"}, "db/arrays.md names no category; give a destination"},
+	}
+	for _, tt := range tests {
+		if _, err := run(t, root, append([]string{"mv"}, tt.args...)...); err == nil || err.Error() != tt.want {
+			t.Errorf("mv %q = %v, want %s", tt.args, err, tt.want)
+		}
+	}
+}
+
+// TestMvCategory checks mv of an entry whose category frontmatter was
+// changed by hand, and of entries naming a category.
+func TestMvCategory(t *testing.T) {
+	root := newTree(t, map[string]string{
+		"go/slices.md": "---\ntitle: Slices\ncategory: db\n---\n",
+		"go/maps.md":   "---\ntitle: Maps\ncategory: go\n---\n",
+		"go/chans.md":  "# Channels\n",
+	})
+	if out := mustRun(t, root, "mv", "go/slices"); out != "moved go/slices.md to db/slices.md\n" {
+		t.Errorf("mv without a destination = %q", out)
+	}
+	mustRun(t, root, "mv", "go/maps", "go/chans", "rust/")
+	if got := readFile(t, root, "rust/maps.md"); got != "---\ntitle: Maps\ncategory: rust\n---\n" {
+		t.Errorf("rust/maps.md =\n%s", got)
+	}
+	if got := readFile(t, root, "rust/chans.md"); got != "# Channels\n" {
+		t.Errorf("rust/chans.md =\n%s", got)
+	}
+}
+
+func TestMvAmbiguous(t *testing.T) {
+	root := newTree(t, map[string]string{
+		"go/slices.md":   "# Slices\n",
+		"rust/slices.md": "# Slices\n",
+		"git/rebase.md":  "# Rebase\n\nSee [[slices]].\n",
+	})
+	_, err := run(t, root, "mv", "go/slices", "db/")
+	if err == nil || !strings.Contains(err.Error(), "git/rebase.md:3: [[slices]] names several entries, go/slices.md among them") {
+		t.Errorf("mv = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "go/slices.md")); err != nil {
+		t.Error("go/slices.md moved despite an ambiguous link")
+	}
+}
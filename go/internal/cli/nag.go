@@ -0,0 +1,93 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/canhta/til/go/internal/goals"
+)
+
+func newNagCmd(a *app) *cobra.Command {
+	var (
+		notifyHooks bool
+		quiet       bool
+		gitDates    bool
+	)
+	cmd := &cobra.Command{
+		Use:   "nag",
+		Short: "Exit non-zero when behind on writing goals",
+		Long: `Nag checks the [[goals]] of the config and exits with status 1 when any is
+behind its pace: the target prorated over the days of the period so far, so
+a goal of 3 entries a week expects one by Wednesday and three by Sunday.
+
+  [[goals]]
+  entries = 3
+  per = "week"   # day, week (from Monday), month or year
+  tag = "go"     # optional; category also narrows what counts
+
+With --notify the goals behind are also posted to the [notify] webhooks.
+It is meant to be run from cron, which mails what it prints.`,
+		Example: `  til nag
+  0 20 * * * cd ~/til && til nag --quiet --notify`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(a.cfg.Goals) == 0 {
+				return errors.New("no goals set; add [[goals]] to the config")
+			}
+			entries, err := a.datedEntries(cmd.Context(), gitDates)
+			if err != nil {
+				return err
+			}
+			ps, err := goals.Check(a.cfg.Goals, entries, time.Now())
+			if err != nil {
+				return err
+			}
+			behind := goals.Behind(ps)
+			err = a.output(cmd, ps, func(w io.Writer) error {
+				for _, p := range ps {
+					if quiet && !p.Behind {
+						continue
+					}
+					fmt.Fprintln(w, p)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			if len(behind) == 0 {
+				return nil
+			}
+			if notifyHooks {
+				hooks, err := a.webhooks()
+				if err != nil {
+					return err
+				}
+				if len(hooks) == 0 {
+					return errors.New("--notify: no webhooks in [notify]")
+				}
+				lines := make([]string, len(behind))
+				for i, p := range behind {
+					lines[i] = p.String()
+				}
+				msg := "Behind on writing goals:\n" + strings.Join(lines, "\n")
+				for _, h := range hooks {
+					if err := h.PostText(cmd.Context(), msg); err != nil {
+						fmt.Fprintf(cmd.ErrOrStderr(), "notify %s: %v\n", h.Kind, err)
+					}
+				}
+			}
+			return &exitError{code: 1, err: fmt.Errorf("%s behind", plural(len(behind), "goal"))}
+		},
+	}
+	withJSON(cmd, "goals")
+	cmd.Flags().BoolVar(&notifyHooks, "notify", false, "post the goals behind to the [notify] webhooks")
+	cmd.Flags().BoolVarP(&quiet, "quiet", "q", false, "print only the goals behind")
+	cmd.Flags().BoolVar(&gitDates, "git-dates", false, "date entries by their first and last commit")
+	return cmd
+}
@@ -0,0 +1,65 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+func TestNag(t *testing.T) {
+	var posted string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct{ Text string }
+		json.NewDecoder(r.Body).Decode(&body)
+		posted = body.Text
+	}))
+	defer srv.Close()
+	today := time.Now().Format(entry.DateLayout)
+	root := newTree(t, map[string]string{
+		"go/slices.md": "---\ntitle: Slices\ndate: " + today + "\n---\n",
+	})
+	if _, err := run(t, root, "nag"); err == nil || !strings.Contains(err.Error(), "no goals set") {
+		t.Errorf("nag without goals = %v", err)
+	}
+
+	writeConfig(t, "[[goals]]\nentries = 1\nper = \"day\"\n\n[[goals]]\nentries = 1\nper = \"day\"\ncategory = \"git\"\n")
+	out, err := run(t, root, "nag")
+	var exit *exitError
+	if !errors.As(err, &exit) || exit.code != 1 || err.Error() != "1 goal behind" {
+		t.Errorf("nag = %v", err)
+	}
+	want := "1 entry per day: 1 of 1 since " + today + ", done\n" +
+		"1 entry per day in git: 0 of 1 since " + today + ", behind (1 expected by now)\n"
+	if out != want {
+		t.Errorf("nag =\n%s\nwant\n%s", out, want)
+	}
+	if out, _ := run(t, root, "nag", "--quiet"); out != "1 entry per day in git: 0 of 1 since "+today+", behind (1 expected by now)\n" {
+		t.Errorf("nag --quiet =\n%s", out)
+	}
+	if out := mustRun(t, root, "stats"); !strings.Contains(out, "\nGOALS\n") || !strings.Contains(out, "1 entry per day in git") {
+		t.Errorf("stats =\n%s", out)
+	}
+
+	if _, err := run(t, root, "nag", "--notify"); err == nil || !strings.Contains(err.Error(), "no webhooks in [notify]") {
+		t.Errorf("nag --notify without webhooks = %v", err)
+	}
+	writeConfig(t, "[[goals]]\nentries = 1\nper = \"day\"\ncategory = \"git\"\n\n[notify]\nattempts = 1\n\n[[notify.webhooks]]\nurl = \""+srv.URL+"\"\n")
+	if _, err := run(t, root, "nag", "--notify"); err == nil {
+		t.Error("nag --notify succeeded")
+	}
+	if posted != "Behind on writing goals:\n1 entry per day in git: 0 of 1 since "+today+", behind (1 expected by now)" {
+		t.Errorf("posted %q", posted)
+	}
+
+	writeFile(t, root, "git/rebase.md", "---\ntitle: Rebase\ndate: "+today+"\n---\n")
+	posted = ""
+	if out := mustRun(t, root, "nag", "--notify"); !strings.HasSuffix(out, ", done\n") || posted != "" {
+		t.Errorf("nag with the goals met =\n%s\nposted %q", out, posted)
+	}
+}
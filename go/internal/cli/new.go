@@ -0,0 +1,306 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+
+	"github.com/canhta/til/go/internal/git"
+	"github.com/canhta/til/go/internal/notes"
+	"github.com/canhta/til/go/internal/schema"
+	"github.com/canhta/til/go/internal/tags"
+	"github.com/canhta/til/go/internal/tmpl"
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+func newNewCmd(a *app) *cobra.Command {
+	var (
+		tagList []string
+		slug    string
+		noEdit  bool
+		draft   bool
+		private bool
+		lang    string
+		of      string
+		tv      templateVars
+	)
+	cmd := &cobra.Command{
+		Use:   "new <category> <title>",
+		Short: "Scaffold a new entry and open it in $EDITOR",
+		Long: `New renders the template of the category into a new entry and opens it
+in the editor. An entry that would break the [schema] of the config file,
+by its category or tags, is not created; the fields the schema requires
+that are still unset once the editor closes are reported.
+
+Templates are the files of .til/templates: <category>.md, else default.md,
+or <name>.md with --template name. Besides {{.Title}}, {{.Date}},
+{{.Category}}, {{.Slug}}, {{.Tags}} and {{.Author}}, a template can use
+variables of its own, such as {{.Topic}}: their values are given with
+--var, or asked for when the terminal is interactive.`,
+		Example: `  til new go "Slices share arrays"
+  til new ops "Disk full on db-1" --template postmortem --var Service=db`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := tv.prepare(cmd); err != nil {
+				return err
+			}
+			rel, content, err := a.scaffold(args[0], args[1], slug, tagList, &tv)
+			if err != nil {
+				return err
+			}
+			if of != "" {
+				orig, err := a.resolve(of)
+				if err != nil {
+					return fmt.Errorf("--translation-of: %w", err)
+				}
+				of = orig.Meta.Slug
+			}
+			for _, f := range []struct{ key, value string }{{"lang", lang}, {"translation_of", of}} {
+				if f.value == "" {
+					continue
+				}
+				if content, err = entry.Rewrite(content, func(fm *entry.Front) error { return fm.Set(f.key, f.value) }); err != nil {
+					return err
+				}
+			}
+			for _, f := range []struct {
+				key string
+				on  bool
+			}{{"draft", draft}, {"private", private}} {
+				if !f.on {
+					continue
+				}
+				if content, err = entry.Rewrite(content, func(fm *entry.Front) error { return fm.Set(f.key, true) }); err != nil {
+					return err
+				}
+			}
+			if private {
+				if rel, err = a.createPrivate(rel, content); err != nil {
+					return err
+				}
+				fmt.Fprintln(cmd.OutOrStdout(), rel)
+				if !noEdit {
+					if err := a.editPrivate(cmd, rel); err != nil {
+						return err
+					}
+				}
+				return a.commitEntry(cmd, rel, "add")
+			}
+			if err := a.tree.Create(rel, content); err != nil {
+				return err
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), rel)
+			if !noEdit {
+				if err := a.openEditor(rel); err != nil {
+					return err
+				}
+			}
+			a.warnSchema(cmd, rel)
+			return a.commitEntry(cmd, rel, "add")
+		},
+	}
+	cmd.Flags().StringSliceVarP(&tagList, "tag", "t", nil, "tag the entry (repeatable)")
+	cmd.Flags().StringVar(&slug, "slug", "", "override the slug derived from the title")
+	cmd.Flags().BoolVar(&noEdit, "no-edit", false, "do not open the editor")
+	cmd.Flags().BoolVar(&draft, "draft", false, "mark the entry as a draft; see til publish")
+	cmd.Flags().BoolVar(&private, "private", false, "encrypt the entry at rest; see til edit")
+	cmd.Flags().StringVar(&lang, "lang", "", "language the entry is written in, such as vi (default from lang in the config file, else en)")
+	cmd.Flags().StringVar(&of, "translation-of", "", "mark the entry as a translation of this one")
+	cmd.Flags().StringVar(&tv.name, "template", "", "render this template of .til/templates instead of the category's")
+	cmd.Flags().StringArrayVar(&tv.vars, "var", nil, "give a template variable its value, as name=value (repeatable)")
+	_ = cmd.RegisterFlagCompletionFunc("template", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		names, err := tmpl.Names(a.tree.StatePath(tmpl.Dir))
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveError
+		}
+		return names, cobra.ShellCompDirectiveNoFileComp
+	})
+	a.commitFlag(cmd)
+	return cmd
+}
+
+// templateVars chooses the template of a new entry and the values of its
+// variables.
+type templateVars struct {
+	// name is the template, or empty for the category's.
+	name string
+	// vars are the values given, as name=value.
+	vars []string
+	// ask, when set, asks for the value of a variable not given one.
+	ask func(name string) (string, error)
+}
+
+// prepare sets tv to ask for variables on the terminal of cmd, if it has
+// one.
+func (tv *templateVars) prepare(cmd *cobra.Command) error {
+	for _, v := range tv.vars {
+		if name, _, ok := strings.Cut(v, "="); !ok || name == "" {
+			return fmt.Errorf("--var %s: want name=value", v)
+		}
+	}
+	f, ok := cmd.InOrStdin().(*os.File)
+	if !ok || !term.IsTerminal(int(f.Fd())) {
+		return nil
+	}
+	in := bufio.NewReader(f)
+	w := cmd.ErrOrStderr()
+	tv.ask = func(name string) (string, error) {
+		fmt.Fprintf(w, "%s: ", tmpl.Label(name))
+		line, err := in.ReadString('\n')
+		if err != nil && line == "" {
+			fmt.Fprintln(w)
+			return "", err
+		}
+		return strings.TrimSpace(line), nil
+	}
+	return nil
+}
+
+// values returns the values of the variables of t, asking for those not
+// given when it can.
+func (tv *templateVars) values(t *tmpl.Template) (map[string]string, error) {
+	names := t.Vars()
+	out := map[string]string{}
+	for _, v := range tv.vars {
+		name, value, _ := strings.Cut(v, "=")
+		i := slices.IndexFunc(names, func(n string) bool { return strings.EqualFold(n, name) })
+		if i < 0 {
+			if len(names) == 0 {
+				return nil, fmt.Errorf("--var %s: template %s has no variables", name, t.Source)
+			}
+			return nil, fmt.Errorf("--var %s: template %s has no such variable; it has %s", name, t.Source, strings.Join(names, ", "))
+		}
+		out[names[i]] = value
+	}
+	var missing []string
+	for _, name := range names {
+		if _, ok := out[name]; ok {
+			continue
+		}
+		if tv.ask == nil {
+			missing = append(missing, name)
+			continue
+		}
+		v, err := tv.ask(name)
+		if err != nil {
+			return nil, err
+		}
+		out[name] = v
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("template %s needs --var for %s", t.Source, strings.Join(missing, ", "))
+	}
+	return out, nil
+}
+
+// scaffold checks the category, slug and tags of a new entry and renders
+// its template, returning the entry's path and initial content. An empty
+// slug is derived from the title. tv, when not nil, chooses the template
+// and its variables; otherwise they are left empty.
+func (a *app) scaffold(category, title, slug string, tagList []string, tv *templateVars) (string, []byte, error) {
+	if category == "" || notes.Skip(category) || strings.ContainsAny(category, `/\`) {
+		return "", nil, fmt.Errorf("invalid category %q", category)
+	}
+	if slug == "" {
+		slug = entry.Slugify(title)
+	}
+	if slug == "" {
+		return "", nil, fmt.Errorf("cannot derive a slug from %q; pass --slug", title)
+	}
+	if !entry.ValidSlug(slug) {
+		return "", nil, fmt.Errorf("invalid slug %q", slug)
+	}
+	var all []string
+	for _, t := range append(slices.Clone(a.cfg.Tags), tagList...) {
+		if !slices.Contains(all, t) {
+			all = append(all, t)
+		}
+	}
+	tagList = all
+	allow, err := tags.LoadAllowlist(a.tree)
+	if err != nil {
+		return "", nil, err
+	}
+	if u := allow.Unknown(tagList); len(u) > 0 {
+		return "", nil, fmt.Errorf("tags not in the allowlist: %s", strings.Join(u, ", "))
+	}
+	dir := a.tree.StatePath(tmpl.Dir)
+	var t *tmpl.Template
+	if tv != nil && tv.name != "" {
+		t, err = tmpl.Named(dir, tv.name)
+	} else {
+		t, err = tmpl.Lookup(dir, category)
+	}
+	if err != nil {
+		return "", nil, err
+	}
+	var vars map[string]string
+	if tv != nil {
+		if vars, err = tv.values(t); err != nil {
+			return "", nil, err
+		}
+	}
+	author := git.UserName(context.Background(), a.tree.Root)
+	content, err := t.Render(tmpl.Data{
+		Title:    title,
+		Date:     time.Now().Format(entry.DateLayout),
+		Category: category,
+		Slug:     slug,
+		Tags:     tagList,
+		Author:   author,
+		Vars:     vars,
+	})
+	if err != nil {
+		return "", nil, fmt.Errorf("template %s: %w", t.Source, err)
+	}
+	rel := path.Join(category, entry.FileName(slug))
+	// The tree's own templates may leave the author out.
+	if _, _, ok := entry.SplitFrontmatter(content); ok && author != "" {
+		if e, err := entry.Parse(rel, content); err == nil && e.Meta.Author == "" {
+			if content, err = entry.Rewrite(content, func(fm *entry.Front) error { return fm.Set("author", author) }); err != nil {
+				return "", nil, err
+			}
+		}
+	}
+	// Fields left unset are for the editor to fill in, and reported after.
+	var broken []string
+	for _, p := range a.schemaProblems(rel, content) {
+		if !p.Unset {
+			broken = append(broken, p.Message)
+		}
+	}
+	if len(broken) > 0 {
+		return "", nil, fmt.Errorf("the entry would break the schema: %s", strings.Join(broken, "; "))
+	}
+	return rel, content, nil
+}
+
+// schemaProblems returns the problems of the entry file content under
+// [schema]; a file that does not parse has none, lint reporting it.
+func (a *app) schemaProblems(rel string, content []byte) []schema.Problem {
+	e, err := entry.Parse(rel, content)
+	if err != nil {
+		return nil
+	}
+	return schema.Check(a.cfg.Schema, e)
+}
+
+// warnSchema reports the problems of the entry at rel under [schema] to
+// stderr.
+func (a *app) warnSchema(cmd *cobra.Command, rel string) {
+	content, err := a.tree.Read(rel)
+	if err != nil {
+		return
+	}
+	for _, p := range a.schemaProblems(rel, content) {
+		fmt.Fprintf(cmd.ErrOrStderr(), "warning: %s: %s\n", rel, p.Message)
+	}
+}
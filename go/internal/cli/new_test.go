@@ -0,0 +1,96 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+func TestNewSlug(t *testing.T) {
+	root := newTree(t, nil)
+	if out := mustRun(t, root, "new", "go", "Slices share arrays", "--no-edit"); out != "go/slices_share_arrays.md\n" {
+		t.Errorf("new = %q", out)
+	}
+	if out := mustRun(t, root, "new", "go", "Other", "--slug", "my-slug", "--no-edit"); out != "go/my_slug.md\n" {
+		t.Errorf("new --slug = %q", out)
+	}
+	for _, slug := range []string{"../../escaped", "../.til/templates/go", `..\x`, ".hidden", "a/b"} {
+		if _, err := run(t, root, "new", "go", "x", "--slug", slug, "--no-edit"); err == nil || !strings.Contains(err.Error(), "invalid slug") {
+			t.Errorf("new --slug %s: %v", slug, err)
+		}
+	}
+	for _, p := range []string{filepath.Join("..", "escaped.md"), filepath.Join(".til", "templates", "go.md"), filepath.Join("go", ".hidden.md")} {
+		if _, err := os.Stat(filepath.Join(root, p)); err == nil {
+			t.Errorf("%s written", p)
+		}
+	}
+	for _, category := range []string{"..", ".til", "_drafts", "a/b", ""} {
+		if _, err := run(t, root, "new", category, "x", "--no-edit"); err == nil {
+			t.Errorf("new in category %q succeeded", category)
+		}
+	}
+}
+
+func TestNew(t *testing.T) {
+	root := newTree(t, map[string]string{
+		".til/templates/git.md": "---\ntitle: {{ yaml .Title }}\ntags: [{{ join .Tags \", \" }}]\n---\n\nGit: {{ .Title }}\n",
+	})
+	mustRun(t, root, "new", "go", "Slices share arrays", "--tag", "go", "--tag", "slices", "--no-edit")
+	data := readFile(t, root, "go/slices_share_arrays.md")
+	e, err := entry.Parse("go/slices_share_arrays.md", []byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Meta.Title != "Slices share arrays" || e.Meta.Slug != "slices-share-arrays" || e.Meta.Category != "go" ||
+		!slices.Equal(e.Meta.Tags, []string{"go", "slices"}) || e.Meta.Date.Format(entry.DateLayout) != time.Now().Format(entry.DateLayout) {
+		t.Errorf("new entry %+v:\n%s", e.Meta, data)
+	}
+	if _, err := run(t, root, "new", "go", "Slices share arrays", "--no-edit"); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("new over an entry: %v", err)
+	}
+
+	mustRun(t, root, "new", "git", "Rebase onto", "-t", "git", "--no-edit")
+	if got, want := readFile(t, root, "git/rebase_onto.md"), "---\ntitle: Rebase onto\ntags: [git]\n---\n\nGit: Rebase onto\n"; got != want {
+		t.Errorf("git/rebase_onto.md = %q, want %q", got, want)
+	}
+}
+
+func TestNewTemplateVars(t *testing.T) {
+	root := newTree(t, nil)
+	writeFile(t, root, ".til/templates/postmortem.md", "---\ntitle: {{.Title}}\nservice: {{.Service | yaml}}\n---\n\n# {{.Title}}\n\nImpact: {{.Impact}}\n")
+	writeFile(t, root, ".til/templates/ops.md", "---\ntitle: {{.Title}}\n---\n")
+
+	mustRun(t, root, "new", "ops", "Disk full", "--no-edit", "--template", "postmortem", "--var", "service=db: primary", "--var", "Impact=none")
+	if got, want := readFile(t, root, "ops/disk_full.md"), "---\ntitle: Disk full\nservice: 'db: primary'\n---\n\n# Disk full\n\nImpact: none\n"; got != want {
+		t.Errorf("ops/disk_full.md =\n%s\nwant\n%s", got, want)
+	}
+	// Without --template the category's is used.
+	mustRun(t, root, "new", "ops", "Restart", "--no-edit")
+	if got := readFile(t, root, "ops/restart.md"); got != "---\ntitle: Restart\n---\n" {
+		t.Errorf("ops/restart.md =\n%s", got)
+	}
+
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"--template", "postmortem", "--var", "Service=db"}, "needs --var for Impact"},
+		{[]string{"--template", "postmortem", "--var", "Service=db", "--var", "Impact=x", "--var", "Owner=me"}, "--var Owner: template " + filepath.Join(root, ".til", "templates", "postmortem.md") + " has no such variable; it has Service, Impact"},
+		{[]string{"--var", "Owner=me"}, "has no variables"},
+		{[]string{"--var", "Owner"}, "--var Owner: want name=value"},
+		{[]string{"--template", "incident"}, `no template "incident"`},
+	}
+	for _, tt := range tests {
+		if _, err := run(t, root, append([]string{"new", "ops", "Another", "--no-edit"}, tt.args...)...); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("new %q = %v, want %s", tt.args, err, tt.want)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(root, "ops", "another.md")); err == nil {
+		t.Error("ops/another.md written by a failed new")
+	}
+}
@@ -0,0 +1,126 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/canhta/til/go/internal/notify"
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+func newNotifyCmd(a *app) *cobra.Command {
+	var dryRun bool
+	cmd := &cobra.Command{
+		Use:   "notify <entry>",
+		Short: "Announce an entry on the configured webhooks",
+		Long: `Notify posts a message announcing an entry, with its title, tags, excerpt
+and site URL, to each webhook of the [notify] config section. til publish
+does so too unless [notify] manual is set.
+
+  [[notify.webhooks]]
+  url_env = "TIL_SLACK_WEBHOOK"
+  template = "{{link .URL .Title}}: {{.Excerpt}}"
+
+A webhook's kind, slack, discord or json, is taken from its host unless
+set. Templates are Go text/templates given the entry's Title, Path,
+Category, Tags, Date, Excerpt and URL, with link, quote and join functions.
+Failed posts are retried with exponential backoff.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			hooks, err := a.webhooks()
+			if err != nil {
+				return err
+			}
+			if len(hooks) == 0 {
+				return errors.New("no webhooks configured; add [[notify.webhooks]] to the config file")
+			}
+			e, err := a.resolve(args[0])
+			if err != nil {
+				return err
+			}
+			if dryRun {
+				d, err := a.notifyData(e)
+				if err != nil {
+					return err
+				}
+				for i, h := range hooks {
+					msg, err := h.Message(d)
+					if err != nil {
+						return err
+					}
+					fmt.Fprintf(cmd.OutOrStdout(), "webhook %d (%s):\n%s\n\n", i+1, h.Kind, msg)
+				}
+				return nil
+			}
+			return a.announce(cmd.Context(), cmd.OutOrStdout(), cmd.ErrOrStderr(), e, hooks)
+		},
+	}
+	cmd.Flags().BoolVarP(&dryRun, "dry-run", "n", false, "print the messages instead of posting them")
+	return cmd
+}
+
+// webhooks returns the configured webhooks.
+func (a *app) webhooks() ([]*notify.Webhook, error) {
+	cfg := a.cfg.Notify
+	var out []*notify.Webhook
+	for i, c := range cfg.Webhooks {
+		u := c.URL
+		if c.URLEnv != "" {
+			if u = os.Getenv(c.URLEnv); u == "" {
+				return nil, fmt.Errorf("webhook %d: $%s is not set", i+1, c.URLEnv)
+			}
+		}
+		h, err := notify.New(u, c.Kind, c.Template)
+		if err != nil {
+			return nil, err
+		}
+		if cfg.Attempts > 0 {
+			h.Attempts = cfg.Attempts
+		}
+		if cfg.Backoff.Duration > 0 {
+			h.Backoff = cfg.Backoff.Duration
+		}
+		out = append(out, h)
+	}
+	return out, nil
+}
+
+// notifyData returns the template data of e, linking its page when the
+// site's address is configured.
+func (a *app) notifyData(e *entry.Entry) (notify.Data, error) {
+	entries, err := a.tree.Entries()
+	if err != nil {
+		return notify.Data{}, err
+	}
+	var url string
+	if urls := a.entryURLs(entries); urls != nil {
+		url = urls(e.Path)
+	}
+	return notify.DataFor(e, url), nil
+}
+
+// announce posts e to hooks, reporting each failure to stderr.
+func (a *app) announce(ctx context.Context, stdout, stderr io.Writer, e *entry.Entry, hooks []*notify.Webhook) error {
+	d, err := a.notifyData(e)
+	if err != nil {
+		return err
+	}
+	failed := 0
+	for _, h := range hooks {
+		if err := h.Post(ctx, d); err != nil {
+			fmt.Fprintf(stderr, "notify %s: %v\n", h.Kind, err)
+			failed++
+			continue
+		}
+		fmt.Fprintf(stdout, "announced %s on %s\n", e.Path, h.Kind)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%s failed", plural(failed, "webhook"))
+	}
+	return nil
+}
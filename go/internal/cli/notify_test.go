@@ -0,0 +1,60 @@
+package cli
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestNotify(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		posts []string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct{ Text string }
+		json.NewDecoder(r.Body).Decode(&body)
+		mu.Lock()
+		posts = append(posts, body.Text)
+		mu.Unlock()
+	}))
+	defer srv.Close()
+	root := newTree(t, map[string]string{
+		"go/slices.md": "---\ntitle: Slices\ntags: [go]\n---\n\nSlices share arrays.\n",
+		"go/maps.md":   "---\ntitle: Maps\ndraft: true\n---\n\nMaps are unordered.\n",
+	})
+
+	if _, err := run(t, root, "notify", "slices"); err == nil || !strings.Contains(err.Error(), "no webhooks configured") {
+		t.Errorf("notify without webhooks = %v", err)
+	}
+
+	t.Setenv("TIL_TEST_HOOK", srv.URL)
+	writeConfig(t, "[site]\nbase_url = \"https://til.example/\"\n\n[notify]\nattempts = 1\n\n[[notify.webhooks]]\nurl_env = \"TIL_TEST_HOOK\"\ntemplate = \"{{link .URL .Title}}: {{.Excerpt}}\"\n")
+	want := "webhook 1 (json):\nSlices https://til.example/go/slices/: Slices share arrays.\n\n"
+	if out := mustRun(t, root, "notify", "slices", "--dry-run"); out != want || len(posts) != 0 {
+		t.Errorf("notify --dry-run =\n%s\nposted %q", out, posts)
+	}
+	if out := mustRun(t, root, "notify", "slices"); out != "announced go/slices.md on json\n" {
+		t.Errorf("notify =\n%s", out)
+	}
+	if out := mustRun(t, root, "publish", "maps"); !strings.Contains(out, "announced go/maps.md on json\n") {
+		t.Errorf("publish =\n%s", out)
+	}
+	if len(posts) != 2 || posts[0] != "Slices https://til.example/go/slices/: Slices share arrays." || !strings.HasPrefix(posts[1], "Maps https://til.example/go/maps/") {
+		t.Errorf("posted %q", posts)
+	}
+
+	srv.Close()
+	out, err := run(t, root, "notify", "slices")
+	if err == nil || err.Error() != "1 webhook failed" || !strings.Contains(out, "notify json: ") {
+		t.Errorf("notify to a closed server = %v\n%s", err, out)
+	}
+
+	t.Setenv("TIL_TEST_HOOK", "")
+	if _, err := run(t, root, "notify", "slices"); err == nil || err.Error() != "webhook 1: $TIL_TEST_HOOK is not set" {
+		t.Errorf("notify without the URL = %v", err)
+	}
+}
@@ -0,0 +1,102 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/canhta/til/go/internal/site"
+)
+
+func newOpenCmd(a *app) *cobra.Command {
+	var (
+		first   bool
+		out     string
+		printed bool
+	)
+	cmd := &cobra.Command{
+		Use:   "open [entry]",
+		Short: "Open an entry's page in the browser",
+		Long: `Open opens the site page of an entry in the browser: on the published site
+when [site] base_url is an absolute URL, otherwise the page til build wrote
+into ./public. The entry is found as til edit finds it, exactly by path, ID,
+file stem or slug, or else in a fuzzy finder over titles and paths.
+
+The browser is $BROWSER if set, else the system's default.`,
+		Example: `  til open slice
+  til open --first --print go/slices`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var ref string
+			if len(args) == 1 {
+				ref = args[0]
+			}
+			e, err := a.pickEntry(ref, first)
+			if err != nil {
+				return err
+			}
+			entries, err := a.tree.Entries()
+			if err != nil {
+				return err
+			}
+			var u string
+			if urls := a.entryURLs(entries); urls != nil {
+				u = urls(e.Path)
+			} else {
+				if !filepath.IsAbs(out) {
+					out = filepath.Join(a.tree.Root, out)
+				}
+				pg := site.New(entries, site.Options{BaseURL: "/", Permalink: a.cfg.Site.Permalink}).Page(e.Path)
+				if pg == nil {
+					return fmt.Errorf("%s has no page on the site", e.Path)
+				}
+				file := filepath.Join(out, filepath.FromSlash(strings.TrimPrefix(pg.URL, "/")), "index.html")
+				if _, err := os.Stat(file); errors.Is(err, fs.ErrNotExist) {
+					return fmt.Errorf("%s is not built; run til build, or set [site] base_url to open the published site", e.Path)
+				}
+				u = (&url.URL{Scheme: "file", Path: filepath.ToSlash(file)}).String()
+			}
+			if u == "" {
+				return fmt.Errorf("%s has no page on the site", e.Path)
+			}
+			if printed {
+				_, err := fmt.Fprintln(cmd.OutOrStdout(), u)
+				return err
+			}
+			return openBrowser(u)
+		},
+	}
+	pickFlag(cmd, &first)
+	cmd.Flags().StringVarP(&out, "out", "o", "public", "directory the site was built into, relative to the notes root")
+	cmd.Flags().BoolVar(&printed, "print", false, "print the URL instead of opening it")
+	return cmd
+}
+
+// openBrowser opens u in $BROWSER or the system's default browser.
+func openBrowser(u string) error {
+	var argv []string
+	switch b := strings.Fields(os.Getenv("BROWSER")); {
+	case len(b) > 0:
+		argv = b
+	case runtime.GOOS == "darwin":
+		argv = []string{"open"}
+	case runtime.GOOS == "windows":
+		argv = []string{"rundll32", "url.dll,FileProtocolHandler"}
+	default:
+		argv = []string{"xdg-open"}
+	}
+	c := exec.Command(argv[0], append(argv[1:], u)...)
+	c.Stdout, c.Stderr = os.Stdout, os.Stderr
+	if err := c.Run(); err != nil {
+		return fmt.Errorf("opening %s: %w", u, err)
+	}
+	return nil
+}
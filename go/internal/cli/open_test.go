@@ -0,0 +1,83 @@
+package cli
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/canhta/til/go/internal/notes"
+)
+
+var openFiles = map[string]string{
+	"go/slices.md":  "---\ntitle: Slices share arrays\ndate: 2024-03-01\n---\n\nAppend.\n",
+	"go/maps.md":    "---\ntitle: Map order\ndate: 2024-05-01\n---\n\nRandom.\n",
+	"git/rebase.md": "---\ntitle: Rebase onto\ndate: 2024-04-01\n---\n\nOnto.\n",
+}
+
+func TestOpen(t *testing.T) {
+	root := newTree(t, openFiles)
+	if _, err := run(t, root, "open", "--print", "go/slices"); err == nil || !strings.Contains(err.Error(), "go/slices.md is not built; run til build") {
+		t.Errorf("open before a build = %v", err)
+	}
+	mustRun(t, root, "build")
+	want := "file://" + filepath.ToSlash(filepath.Join(root, "public", "go", "slices", "index.html")) + "\n"
+	if _, err := os.Stat(filepath.Join(root, "public", "go", "slices", "index.html")); err != nil {
+		t.Fatalf("page not built where expected: %v", err)
+	}
+	if out := mustRun(t, root, "open", "--print", "go/slices"); out != want {
+		t.Errorf("open --print go/slices = %q, want %q", out, want)
+	}
+
+	writeConfig(t, "[site]\nbase_url = \"https://til.example.com\"\n")
+	if out := mustRun(t, root, "open", "--print", "rebase"); out != "https://til.example.com/git/rebase/\n" {
+		t.Errorf("open --print rebase = %q", out)
+	}
+
+	browser := filepath.Join(t.TempDir(), "browser")
+	if err := os.WriteFile(browser, []byte("#!/bin/sh\necho \"$@\" > \"$0.out\"\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("BROWSER", browser)
+	mustRun(t, root, "open", "map order")
+	if got, _ := os.ReadFile(browser + ".out"); string(got) != "https://til.example.com/go/maps/\n" {
+		t.Errorf("browser opened %q", got)
+	}
+}
+
+func TestPickEntry(t *testing.T) {
+	root := newTree(t, openFiles)
+	writeConfig(t, "[site]\nbase_url = \"https://til.example.com\"\n")
+	tests := []struct {
+		args []string
+		want string
+		err  string
+	}{
+		// Exactly by path, then fuzzily by the one matching title.
+		{[]string{"go/maps.md"}, "go/maps/", ""},
+		{[]string{"shrarr"}, "go/slices/", ""},
+		{[]string{"go"}, "", `"go" matches 2 entries: go/maps.md, go/slices.md; pass --first for the best match`},
+		{[]string{"--first", "o"}, "go/maps/", ""},
+		{nil, "", "no entry given and no terminal to pick one on"},
+		{[]string{"--first"}, "go/maps/", ""},
+		{[]string{"zzz"}, "", "not found"},
+	}
+	for _, tt := range tests {
+		out, err := run(t, root, append([]string{"open", "--print"}, tt.args...)...)
+		name := "open " + strings.Join(tt.args, " ")
+		switch {
+		case tt.err != "":
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%s = %v, want error %q", name, err, tt.err)
+			}
+		case err != nil:
+			t.Errorf("%s: %v", name, err)
+		case out != "https://til.example.com/"+tt.want+"\n":
+			t.Errorf("%s = %q, want %s", name, out, tt.want)
+		}
+	}
+	if _, err := run(t, root, "open", "zzz"); !errors.Is(err, notes.ErrNotFound) {
+		t.Errorf("open zzz = %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,66 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+
+	"github.com/spf13/cobra"
+)
+
+// jsonSchema is the version of the documents printed with --json. It is
+// raised only when a field is removed or changes meaning; new fields may
+// appear at any time.
+const jsonSchema = 1
+
+// document wraps the output of every command run with --json, so scripts
+// can check what they are reading:
+//
+//	{"schema": 1, "kind": "entries", "data": [...]}
+type document struct {
+	Schema int    `json:"schema"`
+	Kind   string `json:"kind"`
+	Data   any    `json:"data"`
+}
+
+// jsonAnnotation marks commands that accept the global --json flag.
+const jsonAnnotation = "til.json"
+
+// withJSON marks cmd as printing documents of the given kind with --json.
+func withJSON(cmd *cobra.Command, kind string) *cobra.Command {
+	if cmd.Annotations == nil {
+		cmd.Annotations = map[string]string{}
+	}
+	cmd.Annotations[jsonAnnotation] = kind
+	return cmd
+}
+
+// checkJSON rejects --json on commands that do not support it.
+func (a *app) checkJSON(cmd *cobra.Command) error {
+	if a.json && cmd.Annotations[jsonAnnotation] == "" {
+		return fmt.Errorf("%s does not support --json", cmd.CommandPath())
+	}
+	return nil
+}
+
+// output prints data as a --json document when the flag is set and with
+// text otherwise.
+func (a *app) output(cmd *cobra.Command, data any, text func(io.Writer) error) error {
+	out := cmd.OutOrStdout()
+	if !a.json {
+		return text(out)
+	}
+	return writeJSON(out, cmd.Annotations[jsonAnnotation], data)
+}
+
+// writeJSON prints data as a document of the given kind. Nil slices are
+// printed as empty arrays.
+func writeJSON(w io.Writer, kind string, data any) error {
+	if v := reflect.ValueOf(data); v.Kind() == reflect.Slice && v.IsNil() {
+		data = reflect.MakeSlice(v.Type(), 0, 0).Interface()
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(document{Schema: jsonSchema, Kind: kind, Data: data})
+}
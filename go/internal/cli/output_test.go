@@ -0,0 +1,56 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestWriteJSON(t *testing.T) {
+	var buf bytes.Buffer
+	var none []string
+	if err := writeJSON(&buf, "tags", none); err != nil {
+		t.Fatal(err)
+	}
+	if want := "{\n  \"schema\": 1,\n  \"kind\": \"tags\",\n  \"data\": []\n}\n"; buf.String() != want {
+		t.Errorf("writeJSON(nil) = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestJSONDocuments(t *testing.T) {
+	root := newTree(t, map[string]string{
+		"go/slices.md": "---\ntitle: Slices\ndate: 2024-03-01\ntags: [go, slices]\n---\n\nSlices share arrays.\n",
+		"go/maps.md":   "---\ntitle: Maps\ndate: 2024-05-01\ntags: [go]\n---\n\nMaps and slices.\n",
+	})
+	tests := []struct {
+		args []string
+		kind string
+	}{
+		{[]string{"list"}, "entries"},
+		{[]string{"search", "slices"}, "search"},
+		{[]string{"search", "nothing-matches-this"}, "search"},
+		{[]string{"stats"}, "stats"},
+		{[]string{"tags", "list"}, "tags"},
+		{[]string{"related", "go/slices.md"}, "related"},
+	}
+	for _, tt := range tests {
+		out := mustRun(t, root, append(tt.args, "--json")...)
+		var doc struct {
+			Schema int
+			Kind   string
+			Data   json.RawMessage
+		}
+		if err := json.Unmarshal([]byte(out), &doc); err != nil {
+			t.Fatalf("%s --json: %v\n%s", strings.Join(tt.args, " "), err, out)
+		}
+		if doc.Schema != jsonSchema || doc.Kind != tt.kind || len(doc.Data) == 0 || string(doc.Data) == "null" {
+			t.Errorf("%s --json = %s", strings.Join(tt.args, " "), out)
+		}
+	}
+
+	// Commands that print nothing to read reject the flag.
+	if out, err := run(t, root, "new", "go", "Channels", "--json"); err == nil || !strings.Contains(err.Error(), "does not support --json") {
+		t.Errorf("new --json = %v\n%s", err, out)
+	}
+}
@@ -0,0 +1,85 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+
+	"github.com/canhta/til/go/internal/fuzzy"
+	"github.com/canhta/til/go/internal/notes"
+	"github.com/canhta/til/go/internal/tui"
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+// maxListed caps the matches named when a fuzzy query is ambiguous.
+const maxListed = 5
+
+// pickEntry resolves ref as Resolve does, by path, ID, file stem or slug,
+// and failing that fuzzily on titles and paths, newest first. A single
+// match is taken, as is the best one with first; otherwise they are offered
+// in a fuzzy finder on the terminal. An empty ref matches every entry.
+func (a *app) pickEntry(ref string, first bool) (*entry.Entry, error) {
+	var resolveErr error
+	if ref != "" {
+		e, err := a.resolve(ref)
+		if err == nil {
+			return e, nil
+		}
+		resolveErr = err
+	}
+	entries, err := a.tree.Entries()
+	if err != nil {
+		return nil, err
+	}
+	entries = slices.Clone(entries)
+	slices.SortStableFunc(entries, func(x, y *entry.Entry) int { return y.Created().Compare(x.Created()) })
+	items := make([]tui.Item, len(entries))
+	labels := make([]string, len(entries))
+	for i, e := range entries {
+		items[i] = tui.Item{Label: e.Meta.Title, Detail: e.Path}
+		labels[i] = items[i].Label + " " + items[i].Detail
+	}
+	matches := fuzzy.Find(ref, labels)
+	switch {
+	case len(matches) == 0 && resolveErr != nil:
+		return nil, resolveErr
+	case len(matches) == 0:
+		return nil, fmt.Errorf("%q: %w", ref, notes.ErrNotFound)
+	case first || len(matches) == 1:
+		return entries[matches[0].Index], nil
+	}
+	if !term.IsTerminal(int(os.Stderr.Fd())) {
+		if ref == "" {
+			return nil, errors.New("no entry given and no terminal to pick one on")
+		}
+		var paths []string
+		for _, m := range matches[:min(len(matches), maxListed)] {
+			paths = append(paths, entries[m.Index].Path)
+		}
+		if len(matches) > maxListed {
+			paths = append(paths, "…")
+		}
+		return nil, fmt.Errorf("%q matches %d entries: %s; pass --first for the best match",
+			ref, len(matches), strings.Join(paths, ", "))
+	}
+	i, err := tui.Pick(items, ref)
+	if errors.Is(err, tui.ErrCanceled) {
+		// As fzf does when interrupted.
+		return nil, &exitError{code: 130, err: err}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return entries[i], nil
+}
+
+// pickFlag registers --first on a command taking an entry pickEntry
+// resolves.
+func pickFlag(cmd *cobra.Command, first *bool) {
+	cmd.Flags().BoolVar(first, "first", false, "take the best fuzzy match instead of asking")
+}
@@ -0,0 +1,102 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+
+	"github.com/spf13/cobra"
+
+	"github.com/canhta/til/go/internal/private"
+)
+
+// editPrivate opens the encrypted entry at rel in the editor through a
+// decrypted temporary copy, then encrypts the result back. If the private
+// flag was removed, the entry is stored decrypted instead.
+func (a *app) editPrivate(cmd *cobra.Command, rel string) error {
+	keys, err := private.Load(a.cfg.Private)
+	if err != nil {
+		return err
+	}
+	data, err := a.tree.Read(rel)
+	if err != nil {
+		return err
+	}
+	plain, err := keys.Decrypt(data)
+	if err != nil {
+		return fmt.Errorf("%s: %w", rel, err)
+	}
+	edited, err := editTemp(path.Base(private.PlainPath(rel)), plain)
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(edited, plain) {
+		return nil
+	}
+	if !private.IsPrivate(edited) {
+		plainRel := private.PlainPath(rel)
+		if err := a.tree.Create(plainRel, edited); err != nil {
+			return err
+		}
+		if err := a.tree.Remove(rel); err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.ErrOrStderr(), "%s is no longer private; stored it decrypted as %s\n", rel, plainRel)
+		return a.commitEntry(cmd, plainRel, "update", rel)
+	}
+	sealed, err := keys.Encrypt(edited)
+	if err != nil {
+		return err
+	}
+	if err := a.tree.Write(rel, sealed); err != nil {
+		return err
+	}
+	return a.commitEntry(cmd, rel, "update")
+}
+
+// createPrivate encrypts content and stores it as a new encrypted entry in
+// place of the markdown file rel, returning the path of the encrypted file.
+func (a *app) createPrivate(rel string, content []byte) (string, error) {
+	keys, err := private.Load(a.cfg.Private)
+	if err != nil {
+		return "", err
+	}
+	sealed, err := keys.Encrypt(content)
+	if err != nil {
+		return "", err
+	}
+	encRel := private.EncryptedPath(rel)
+	if ok, err := a.tree.Exists(rel); err != nil || ok {
+		if err == nil {
+			err = fmt.Errorf("%s already exists", rel)
+		}
+		return "", err
+	}
+	return encRel, a.tree.Create(encRel, sealed)
+}
+
+// seal encrypts the markdown entry at rel, marked private, and removes the
+// plain file. It returns the path of the encrypted file.
+func (a *app) seal(cmd *cobra.Command, rel string) (string, error) {
+	plain, err := a.tree.Read(rel)
+	if err != nil {
+		return "", err
+	}
+	keys, err := private.Load(a.cfg.Private)
+	if err != nil {
+		return "", err
+	}
+	sealed, err := keys.Encrypt(plain)
+	if err != nil {
+		return "", err
+	}
+	encRel := private.EncryptedPath(rel)
+	if err := a.tree.Create(encRel, sealed); err != nil {
+		return "", err
+	}
+	if err := a.tree.Remove(rel); err != nil {
+		return "", err
+	}
+	fmt.Fprintf(cmd.ErrOrStderr(), "encrypted %s as %s\n", rel, encRel)
+	return encRel, nil
+}
@@ -0,0 +1,87 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+
+	"github.com/canhta/til/go/internal/config"
+	"github.com/canhta/til/go/internal/private"
+)
+
+// setEditor makes script, run by sh with the file as $1, the editor.
+func setEditor(t *testing.T, script string) {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), "editor.sh")
+	if err := os.WriteFile(file, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("EDITOR", "sh "+file)
+}
+
+func TestPrivate(t *testing.T) {
+	root := newTree(t, map[string]string{"go/open.md": "---\ntitle: Open\n---\n\nPublic.\n"})
+	id, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := filepath.Join(t.TempDir(), "key.txt")
+	if err := os.WriteFile(key, []byte(id.String()+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	writeConfig(t, "[private]\nidentity = \""+key+"\"\n")
+	keys, err := private.Load(config.Private{Identity: key})
+	if err != nil {
+		t.Fatal(err)
+	}
+	decrypt := func(rel string) string {
+		t.Helper()
+		plain, err := keys.Decrypt([]byte(readFile(t, root, rel)))
+		if err != nil {
+			t.Fatalf("%s: %v", rel, err)
+		}
+		return string(plain)
+	}
+
+	if out := mustRun(t, root, "new", "go", "Secret", "--private", "--no-edit"); out != "go/secret.md.age\n" {
+		t.Errorf("new --private = %q", out)
+	}
+	if got := decrypt("go/secret.md.age"); !strings.Contains(got, "title: Secret") || !strings.Contains(got, "private: true") {
+		t.Errorf("go/secret.md.age holds:\n%s", got)
+	}
+	if out := mustRun(t, root, "list"); strings.Contains(out, "secret") {
+		t.Errorf("list shows the private entry:\n%s", out)
+	}
+
+	setEditor(t, "echo 'Hush.' >> \"$1\"\n")
+	mustRun(t, root, "edit", "secret")
+	if got := decrypt("go/secret.md.age"); !strings.HasSuffix(got, "Hush.\n") {
+		t.Errorf("edited go/secret.md.age holds:\n%s", got)
+	}
+	if _, err := os.Stat(filepath.Join(root, "go", "secret.md")); err == nil {
+		t.Error("editing left a decrypted go/secret.md")
+	}
+
+	// Marking an entry private encrypts it.
+	setEditor(t, "sed -i 's/^title: Open$/title: Open\\nprivate: true/' \"$1\"\n")
+	mustRun(t, root, "edit", "open")
+	if got := readFile(t, root, "go/open.md"); !strings.HasPrefix(got, "<") {
+		t.Errorf("go/open.md is still stored:\n%s", got)
+	}
+	if got := decrypt("go/open.md.age"); !strings.Contains(got, "Public.") {
+		t.Errorf("go/open.md.age holds:\n%s", got)
+	}
+
+	// Removing the flag stores it decrypted again.
+	setEditor(t, "sed -i '/^private: true$/d' \"$1\"\n")
+	mustRun(t, root, "edit", "go/open.md.age")
+	if got := readFile(t, root, "go/open.md"); got != "---\ntitle: Open\n---\n\nPublic.\n" {
+		t.Errorf("go/open.md =\n%s", got)
+	}
+	if _, err := os.Stat(filepath.Join(root, "go", "open.md.age")); err == nil {
+		t.Error("go/open.md.age was kept")
+	}
+}
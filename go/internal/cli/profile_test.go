@@ -0,0 +1,90 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// runHere runs til with args and no -C, finding the notes root as the
+// user's shell would.
+func runHere(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+	cmd := newRootCmd(&app{})
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(&out)
+	cmd.SetIn(strings.NewReader(""))
+	cmd.SetArgs(args)
+	err := cmd.ExecuteContext(context.Background())
+	return out.String(), err
+}
+
+func TestProfiles(t *testing.T) {
+	home := newTree(t, map[string]string{"go/slices.md": "# Slices\n"})
+	work := newTree(t, map[string]string{"ops/disk.md": "# Disk full\n"})
+	// Out of any notes root, and with $TIL_DIR unset rather than empty,
+	// which would override the dir setting.
+	t.Chdir(t.TempDir())
+	os.Unsetenv("TIL_DIR")
+	editor := filepath.Join(t.TempDir(), "editor")
+	if err := os.WriteFile(editor, []byte("#!/bin/sh\necho \"$@\" >> \"$0.log\"\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	writeConfig(t, "dir = "+quote(home)+"\n"+
+		"editor = "+quote(editor+" --wait")+"\n"+
+		"tags = [\"til\"]\n"+
+		"[profiles.work]\n"+
+		"dir = "+quote(work)+"\n"+
+		"tags = [\"work\"]\n")
+
+	for _, tt := range []struct {
+		args []string
+		env  string
+		want string
+	}{
+		{[]string{"list"}, "", "go/slices.md"},
+		{[]string{"--profile", "work", "list"}, "", "ops/disk.md"},
+		{[]string{"list"}, "work", "ops/disk.md"},
+	} {
+		t.Setenv("TIL_PROFILE", tt.env)
+		out, err := runHere(t, tt.args...)
+		if err != nil || !strings.Contains(out, tt.want) || strings.Count(out, ".md") != 1 {
+			t.Errorf("til %s with TIL_PROFILE=%q = %v\n%s", strings.Join(tt.args, " "), tt.env, err, out)
+		}
+	}
+	t.Setenv("TIL_PROFILE", "")
+
+	// -C and $TIL_DIR win over the dir of the profile.
+	t.Setenv("TIL_DIR", home)
+	if out, err := runHere(t, "--profile", "work", "list"); err != nil || !strings.Contains(out, "go/slices.md") {
+		t.Errorf("list with $TIL_DIR = %v\n%s", err, out)
+	}
+	os.Unsetenv("TIL_DIR")
+
+	out, err := runHere(t, "--profile", "work", "new", "ops", "Inode exhaustion", "--tag", "disk")
+	if err != nil {
+		t.Fatalf("new: %v\n%s", err, out)
+	}
+	if got := readFile(t, work, "ops/inode_exhaustion.md"); !strings.Contains(got, "tags: [work, disk]") {
+		t.Errorf("new entry =\n%s\nwant the profile's tags", got)
+	}
+	if log, _ := os.ReadFile(editor + ".log"); string(log) != "--wait "+filepath.Join(work, "ops", "inode_exhaustion.md")+"\n" {
+		t.Errorf("editor ran with %q", log)
+	}
+
+	if _, err := runHere(t, "--profile", "home", "list"); err == nil || !strings.Contains(err.Error(), `no profile "home"`) {
+		t.Errorf("list with an unknown profile = %v", err)
+	}
+	t.Setenv("TIL_TAGS", "x")
+	if out, _ := runHere(t, "new", "go", "Maps", "--no-edit", "--tag", "x"); !strings.Contains(readFile(t, home, "go/maps.md"), "tags: [x]") {
+		t.Errorf("new with $TIL_TAGS =\n%s\n%s", out, readFile(t, home, "go/maps.md"))
+	}
+}
+
+func quote(s string) string {
+	return `"` + strings.ReplaceAll(s, `\`, `\\`) + `"`
+}
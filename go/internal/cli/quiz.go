@@ -0,0 +1,137 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/canhta/til/go/internal/query"
+	"github.com/canhta/til/go/internal/quiz"
+	"github.com/canhta/til/go/internal/review"
+)
+
+func newQuizCmd(a *app) *cobra.Command {
+	var (
+		filter   listFilter
+		limit    int
+		seed     uint64
+		noRecord bool
+	)
+	cmd := &cobra.Command{
+		Use:   "quiz",
+		Short: "Quiz yourself on the questions in entries",
+		Long: `Quiz asks the questions found in the matching entries, in random order.
+An entry asks a question with a **Q:** line answered by the **A:** line
+after it, or with a heading ending in a question mark answered by its
+section:
+
+  **Q:** What does append return?
+  **A:** The updated slice.
+
+After each answer, grade how well you knew it as in til review. Each
+entry's grades, averaged, are recorded like a review of the entry, so
+spaced repetition brings back entries you struggled with sooner; pass
+--no-record to practise without scheduling.`,
+		Example: `  til quiz --tag go
+  til quiz --category go --limit 10`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			now := time.Now()
+			x, err := filter.expr(now, a.lang())
+			if err != nil {
+				return err
+			}
+			entries, err := a.tree.Entries()
+			if err != nil {
+				return err
+			}
+			var qs []quiz.Question
+			for _, e := range query.Filter(entries, x) {
+				qs = append(qs, quiz.Extract(e)...)
+			}
+			out := cmd.OutOrStdout()
+			if len(qs) == 0 {
+				fmt.Fprintln(out, "No questions found; see til quiz --help for how entries ask them.")
+				return nil
+			}
+			if seed == 0 {
+				seed = rand.Uint64()
+			}
+			qs = quiz.Shuffle(qs, seed)
+			if limit > 0 && len(qs) > limit {
+				qs = qs[:limit]
+			}
+
+			in := bufio.NewReader(cmd.InOrStdin())
+			grades := map[string][]review.Grade{}
+			var order []string
+			asked, recalled := 0, 0
+		session:
+			for i, q := range qs {
+				fmt.Fprintf(out, "\n[%d/%d] %s  (%s)\n", i+1, len(qs), q.Question, q.Entry.Path)
+				answer, err := prompt(out, in, "Enter to show the answer, s to skip, q to quit: ")
+				switch {
+				case err != nil || answer == "q":
+					break session
+				case answer == "s":
+					continue
+				}
+				fmt.Fprintf(out, "\n%s\n\n", q.Answer)
+				g, err := promptGrade(out, in)
+				if err != nil {
+					break session
+				}
+				if g == 0 {
+					continue
+				}
+				asked++
+				if g >= review.Hard {
+					recalled++
+				}
+				if !slices.Contains(order, q.Entry.Path) {
+					order = append(order, q.Entry.Path)
+				}
+				grades[q.Entry.Path] = append(grades[q.Entry.Path], g)
+			}
+			if asked == 0 {
+				return nil
+			}
+			fmt.Fprintf(out, "\nScore: %d of %d recalled (%.0f%%).\n", recalled, asked, 100*float64(recalled)/float64(asked))
+			if noRecord {
+				return nil
+			}
+			st, err := review.Open(a.tree)
+			if err != nil {
+				return err
+			}
+			defer st.Close()
+			for _, p := range order {
+				next, err := st.Record(cmd.Context(), p, meanGrade(grades[p]), time.Now())
+				if err != nil {
+					return err
+				}
+				fmt.Fprintf(out, "%s: next review %s\n", p, formatDue(next.Due))
+			}
+			return nil
+		},
+	}
+	filter.register(cmd)
+	cmd.Flags().IntVarP(&limit, "limit", "n", 0, "ask at most this many questions")
+	cmd.Flags().Uint64Var(&seed, "seed", 0, "shuffle with this seed, for a repeatable order")
+	cmd.Flags().BoolVar(&noRecord, "no-record", false, "do not record the grades for spaced repetition")
+	return cmd
+}
+
+// meanGrade returns the average of gs, rounded to the nearest grade.
+func meanGrade(gs []review.Grade) review.Grade {
+	sum := 0
+	for _, g := range gs {
+		sum += int(g)
+	}
+	return review.Grade(math.Round(float64(sum) / float64(len(gs))))
+}
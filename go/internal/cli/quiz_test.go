@@ -0,0 +1,67 @@
+package cli
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/canhta/til/go/internal/notes"
+	"github.com/canhta/til/go/internal/review"
+)
+
+func TestQuiz(t *testing.T) {
+	root := newTree(t, map[string]string{
+		"go/slices.md":  "---\ntitle: Slices\ntags: [go]\n---\n\n**Q:** What does append return?\n**A:** The updated slice.\n\n## Why copy?\n\nTo stop sharing.\n",
+		"go/maps.md":    "---\ntitle: Maps\ntags: [go]\n---\n\n**Q:** Is map order stable?\n**A:** No.\n",
+		"git/rebase.md": "---\ntitle: Rebase\ntags: [git]\n---\n\n**Q:** What does rebase rewrite?\n**A:** Commits.\n",
+	})
+	cards := func() map[string]review.Card {
+		t.Helper()
+		st, err := review.Open(notes.Open(root))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer st.Close()
+		cards, err := st.Cards(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cards
+	}
+
+	out, err := runStdin(t, root, "\n1\n\n2\n\n4\n", "quiz", "--tag", "go", "--seed", "1", "--no-record")
+	if err != nil {
+		t.Fatalf("quiz --no-record: %v\n%s", err, out)
+	}
+	if strings.Contains(out, "rebase") || !strings.Contains(out, "[1/3] ") || !strings.Contains(out, "[3/3] ") {
+		t.Errorf("quiz --tag go =\n%s", out)
+	}
+	if !strings.Contains(out, "Score: 2 of 3 recalled (67%).") {
+		t.Errorf("quiz --tag go =\n%s", out)
+	}
+	if c := cards(); len(c) != 0 {
+		t.Errorf("quiz --no-record recorded %v", c)
+	}
+
+	// The same seed asks in the same order.
+	again, _ := runStdin(t, root, "q\n", "quiz", "--tag", "go", "--seed", "1")
+	if first, _, _ := strings.Cut(out, "Enter"); !strings.HasPrefix(again, first) {
+		t.Errorf("quiz --seed 1 asked\n%s\nthen\n%s", out, again)
+	}
+
+	out, err = runStdin(t, root, "\n3\n", "quiz", "--category", "git")
+	if err != nil {
+		t.Fatalf("quiz --category git: %v\n%s", err, out)
+	}
+	if !strings.Contains(out, "What does rebase rewrite?  (git/rebase.md)") || !strings.Contains(out, "\nCommits.\n") ||
+		!strings.Contains(out, "Score: 1 of 1 recalled (100%).") || !strings.Contains(out, "git/rebase.md: next review ") {
+		t.Errorf("quiz --category git =\n%s", out)
+	}
+	if c := cards(); len(c) != 1 || c["git/rebase.md"].IsNew() {
+		t.Errorf("cards after quiz = %v", c)
+	}
+
+	if out := mustRun(t, root, "quiz", "--tag", "rust"); !strings.HasPrefix(out, "No questions found") {
+		t.Errorf("quiz --tag rust = %q", out)
+	}
+}
@@ -0,0 +1,84 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/canhta/til/go/internal/query"
+	"github.com/canhta/til/go/pkg/entry"
+	"github.com/canhta/til/go/pkg/render"
+)
+
+func newRandomCmd(a *app) *cobra.Command {
+	var (
+		filter   listFilter
+		pathOnly bool
+		edit     bool
+	)
+	cmd := &cobra.Command{
+		Use:   "random [query]",
+		Short: "Show a random entry",
+		Long: `Random picks an entry at random, among those matching the filter flags
+and query if given, and prints it.`,
+		Example: `  til random
+  til random --tag go
+  til edit "$(til random --path)"`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			now := time.Now()
+			x, err := filter.expr(now, a.lang())
+			if err != nil {
+				return err
+			}
+			if len(args) > 0 {
+				q, err := a.parseQuery(strings.Join(args, " "), now)
+				if err != nil {
+					return err
+				}
+				x = query.And{q, x}
+			}
+			entries, err := a.tree.Entries()
+			if err != nil {
+				return err
+			}
+			entries = query.Filter(entries, x)
+			if len(entries) == 0 {
+				return errors.New("no entries match")
+			}
+			e := entries[rand.IntN(len(entries))]
+			if edit {
+				return a.openEditor(e.Path)
+			}
+			return a.output(cmd, []entry.Summary{entry.Summarize(e)}, func(w io.Writer) error {
+				if pathOnly {
+					_, err := fmt.Fprintln(w, e.Path)
+					return err
+				}
+				return writeEntry(w, e)
+			})
+		},
+	}
+	withJSON(cmd, "entries")
+	filter.register(cmd)
+	cmd.Flags().BoolVar(&pathOnly, "path", false, "print only the entry's path")
+	cmd.Flags().BoolVarP(&edit, "edit", "e", false, "open the entry in $EDITOR instead of printing it")
+	return cmd
+}
+
+// writeEntry prints e's title, metadata and body.
+func writeEntry(w io.Writer, e *entry.Entry) error {
+	meta := e.Path
+	if d := e.Created(); !d.IsZero() {
+		meta += "  " + d.Format(entry.DateLayout)
+	}
+	if len(e.Meta.Tags) > 0 {
+		meta += "  #" + strings.Join(e.Meta.Tags, " #")
+	}
+	_, err := fmt.Fprintf(w, "%s\n%s\n\n%s\n", e.Meta.Title, meta, strings.TrimSpace(string(render.StripTitle(e.Body))))
+	return err
+}
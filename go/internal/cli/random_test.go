@@ -0,0 +1,44 @@
+package cli
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+func TestRandom(t *testing.T) {
+	root := newTree(t, map[string]string{
+		"go/slices.md":  "---\ntitle: Slices\ndate: 2024-03-01\ntags: [go]\n---\n# Slices\n\nSlices share arrays.\n",
+		"go/maps.md":    "---\ntitle: Maps\ndate: 2024-05-01\ntags: [go]\n---\n\nMaps are unordered.\n",
+		"git/rebase.md": "---\ntitle: Rebase\ntags: [git]\n---\n\nRebase onto.\n",
+	})
+	seen := map[string]bool{}
+	for range 40 {
+		seen[strings.TrimSpace(mustRun(t, root, "random", "--tag", "go", "--path"))] = true
+	}
+	if len(seen) != 2 || !seen["go/slices.md"] || !seen["go/maps.md"] {
+		t.Errorf("random --tag go picked %v", seen)
+	}
+
+	if out := mustRun(t, root, "random", "--", "category:git"); out != "Rebase\ngit/rebase.md  #git\n\nRebase onto.\n" {
+		t.Errorf("random category:git =\n%s", out)
+	}
+	if out := mustRun(t, root, "random", "slices"); out != "Slices\ngo/slices.md  2024-03-01  #go\n\nSlices share arrays.\n" {
+		t.Errorf("random slices =\n%s", out)
+	}
+	var doc struct {
+		Kind string
+		Data []entry.Summary
+	}
+	if err := json.Unmarshal([]byte(mustRun(t, root, "random", "--category", "git", "--json")), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.Kind != "entries" || len(doc.Data) != 1 || doc.Data[0].Path != "git/rebase.md" {
+		t.Errorf("random --json = %+v", doc)
+	}
+	if _, err := run(t, root, "random", "--tag", "rust"); err == nil || err.Error() != "no entries match" {
+		t.Errorf("random --tag rust = %v", err)
+	}
+}
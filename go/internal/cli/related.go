@@ -0,0 +1,53 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/canhta/til/go/internal/related"
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+func newRelatedCmd(a *app) *cobra.Command {
+	var limit int
+	cmd := &cobra.Command{
+		Use:   "related <entry>",
+		Short: "List the entries most similar to an entry",
+		Long: `Related ranks other entries by how similar they are to the given one,
+combining the TF-IDF similarity of their text with the overlap of their tags.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			target, err := a.resolve(args[0])
+			if err != nil {
+				return err
+			}
+			entries, err := a.tree.Entries()
+			if err != nil {
+				return err
+			}
+			m, err := related.Compute(cmd.Context(), a.tree, entries)
+			if err != nil {
+				return err
+			}
+			matches := m.Related(target.Path, limit)
+			return a.output(cmd, matches, func(w io.Writer) error {
+				byPath := map[string]*entry.Entry{}
+				for _, e := range entries {
+					byPath[e.Path] = e
+				}
+				tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+				fmt.Fprintln(tw, "SCORE\tTITLE\tPATH")
+				for _, r := range matches {
+					fmt.Fprintf(tw, "%.2f\t%s\t%s\n", r.Score, byPath[r.Path].Meta.Title, r.Path)
+				}
+				return tw.Flush()
+			})
+		},
+	}
+	withJSON(cmd, "related")
+	cmd.Flags().IntVarP(&limit, "limit", "n", 5, fmt.Sprintf("show at most this many entries (up to %d)", related.Keep))
+	return cmd
+}
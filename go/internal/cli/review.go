@@ -0,0 +1,175 @@
+package cli
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/canhta/til/go/internal/query"
+	"github.com/canhta/til/go/internal/review"
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+func newReviewCmd(a *app) *cobra.Command {
+	var (
+		filter   listFilter
+		newLimit int
+		list     bool
+	)
+	cmd := &cobra.Command{
+		Use:   "review",
+		Short: "Review due entries with spaced repetition",
+		Long: `Review shows the entries due for review one at a time. After reading an
+entry, grade how well you remembered it: again, hard, good or easy. Entries
+you recall well come back at growing intervals (SM-2); ones you forgot come
+back tomorrow. Scheduling state is kept in .til/review.db.`,
+		Example: `  til review
+  til review --tag go --new 10
+  til review --list`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			now := time.Now()
+			x, err := filter.expr(now, a.lang())
+			if err != nil {
+				return err
+			}
+			entries, err := a.tree.Entries()
+			if err != nil {
+				return err
+			}
+			entries = query.Filter(entries, x)
+			byPath := map[string]*entry.Entry{}
+			paths := make([]string, len(entries))
+			for i, e := range entries {
+				byPath[e.Path] = e
+				paths[i] = e.Path
+			}
+
+			st, err := review.Open(a.tree)
+			if err != nil {
+				return err
+			}
+			defer st.Close()
+			ctx := cmd.Context()
+			q, err := st.Queue(ctx, paths, now, newLimit)
+			if err != nil {
+				return err
+			}
+			cards := append(q.Due, q.New...)
+			out := cmd.OutOrStdout()
+			if list {
+				return writeQueue(out, cards, byPath)
+			}
+			if len(cards) == 0 {
+				fmt.Fprintln(out, "Nothing to review.")
+				return nil
+			}
+
+			in := bufio.NewReader(cmd.InOrStdin())
+			done := 0
+		session:
+			for i, c := range cards {
+				e := byPath[c.Path]
+				state := "due"
+				if c.IsNew() {
+					state = "new"
+				}
+				fmt.Fprintf(out, "\n[%d/%d] %s  (%s, %s)\n", i+1, len(cards), e.Meta.Title, e.Path, state)
+				answer, err := prompt(out, in, "Enter to show, s to skip, q to quit: ")
+				switch {
+				case err != nil || answer == "q":
+					break session
+				case answer == "s":
+					continue
+				}
+				fmt.Fprintf(out, "\n%s\n\n", strings.TrimSpace(string(e.Body)))
+				g, err := promptGrade(out, in)
+				if err != nil {
+					break session
+				}
+				if g == 0 {
+					continue
+				}
+				next, err := st.Record(ctx, c.Path, g, time.Now())
+				if err != nil {
+					return err
+				}
+				done++
+				fmt.Fprintf(out, "%s: next review %s\n", g, formatDue(next.Due))
+			}
+			fmt.Fprintf(out, "\nReviewed %d of %d.\n", done, len(cards))
+			return nil
+		},
+	}
+	filter.register(cmd)
+	cmd.Flags().IntVar(&newLimit, "new", 5, "introduce at most this many never-reviewed entries (-1 for all)")
+	cmd.Flags().BoolVar(&list, "list", false, "list the review queue instead of reviewing")
+	return cmd
+}
+
+// errQuit ends an interactive session early.
+var errQuit = errors.New("quit")
+
+// prompt prints msg and reads one line of input, lowercased and trimmed.
+func prompt(w io.Writer, r *bufio.Reader, msg string) (string, error) {
+	fmt.Fprint(w, msg)
+	line, err := r.ReadString('\n')
+	if err != nil && line == "" {
+		fmt.Fprintln(w)
+		return "", err
+	}
+	return strings.ToLower(strings.TrimSpace(line)), nil
+}
+
+// promptGrade asks for a grade until it gets one. A zero grade means the
+// entry was skipped; errQuit means the user quit.
+func promptGrade(w io.Writer, r *bufio.Reader) (review.Grade, error) {
+	for {
+		answer, err := prompt(w, r, "Grade: 1 again, 2 hard, 3 good, 4 easy (s skip, q quit): ")
+		if err != nil {
+			return 0, err
+		}
+		switch answer {
+		case "q":
+			return 0, errQuit
+		case "s":
+			return 0, nil
+		}
+		g, err := review.ParseGrade(answer)
+		if err == nil {
+			return g, nil
+		}
+		fmt.Fprintln(w, err)
+	}
+}
+
+// formatDue describes a due date relative to today.
+func formatDue(t time.Time) string {
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	days := int(t.Sub(today).Hours() / 24)
+	if days <= 1 {
+		return "tomorrow"
+	}
+	return fmt.Sprintf("in %d days (%s)", days, t.Format(entry.DateLayout))
+}
+
+func writeQueue(w io.Writer, cards []review.Card, byPath map[string]*entry.Entry) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "DUE\tINTERVAL\tTITLE\tPATH")
+	for _, c := range cards {
+		due, interval := "new", "-"
+		if !c.IsNew() {
+			due = c.Due.Format(entry.DateLayout)
+			interval = fmt.Sprintf("%dd", c.Interval)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", due, interval, byPath[c.Path].Meta.Title, c.Path)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,221 @@
+// Package cli implements the til command line.
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/canhta/til/go/internal/config"
+	"github.com/canhta/til/go/internal/editor"
+	"github.com/canhta/til/go/internal/hooks"
+	"github.com/canhta/til/go/internal/logging"
+	"github.com/canhta/til/go/internal/notes"
+	"github.com/canhta/til/go/internal/store"
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+// app holds state shared by all commands.
+type app struct {
+	dir     string
+	profile string
+	// workspace is set by --workspace, or to the workspace chosen with
+	// til workspace use when the tree is one.
+	workspace string
+	tree      *notes.Tree
+	cfg       *config.Config
+	// hooks runs the hooks of the config file; nil when none are.
+	hooks *hooks.Runner
+	// noCommit is set by --no-commit on commands that commit entries.
+	noCommit bool
+	// json is set by --json, for commands marked withJSON.
+	json bool
+	// log holds the log flags. With --trace, spans records the spans of
+	// the command and endSpan ends the one of the command itself.
+	log     logFlags
+	spans   *logging.Recorder
+	endSpan func()
+}
+
+// Main runs the command line and returns the process exit code.
+func Main(ctx context.Context, args []string) int {
+	a := &app{}
+	cmd := newRootCmd(a)
+	cmd.SetArgs(args)
+	err := cmd.ExecuteContext(ctx)
+	a.writeTrace(os.Stderr)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "til:", err)
+		return exitCode(err)
+	}
+	return 0
+}
+
+// exitError carries a specific exit code out of a command.
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string { return e.err.Error() }
+func (e *exitError) Unwrap() error { return e.err }
+
+func exitCode(err error) int {
+	if e, ok := err.(*exitError); ok {
+		return e.code
+	}
+	return 1
+}
+
+func newRootCmd(a *app) *cobra.Command {
+	root := &cobra.Command{
+		Use:           "til",
+		Short:         "Manage a Today I Learned notes repository",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			// Completion parses the flags of the command line being
+			// completed only after this, and sets up in complete.
+			if n := cmd.Name(); n == cobra.ShellCompRequestCmd || n == cobra.ShellCompNoDescRequestCmd {
+				return nil
+			}
+			if err := a.checkJSON(cmd); err != nil {
+				return err
+			}
+			if err := a.startLogging(cmd); err != nil {
+				return err
+			}
+			return a.init()
+		},
+	}
+	root.PersistentFlags().StringVarP(&a.dir, "dir", "C", os.Getenv("TIL_DIR"), "notes root (default: the workspace in use, else dir of the config file, else the nearest directory with .til or .git)")
+	root.PersistentFlags().StringVar(&a.profile, "profile", os.Getenv("TIL_PROFILE"), "configuration profile to use, from [profiles.<name>] of the config file")
+	_ = root.RegisterFlagCompletionFunc("profile", completeProfiles)
+	root.PersistentFlags().StringVarP(&a.workspace, "workspace", "w", os.Getenv("TIL_WORKSPACE"), "workspace to use, from [workspaces] of the config file")
+	_ = root.RegisterFlagCompletionFunc("workspace", a.completeWorkspaces)
+	root.PersistentFlags().BoolVar(&a.json, "json", false, "print machine-readable JSON, on commands that support it")
+	a.log.register(root)
+
+	root.AddCommand(
+		newNewCmd(a),
+		newEditCmd(a),
+		newSearchCmd(a),
+		newBuildCmd(a),
+		newServeCmd(a),
+		newRunCmd(a),
+		newVerifyCmd(a),
+		newIndexCmd(a),
+		newTagsCmd(a),
+		newListCmd(a),
+		newReviewCmd(a),
+		newExportCmd(a),
+		newBrowseCmd(a),
+		newStatsCmd(a),
+		newHeatmapCmd(a),
+		newGraphCmd(a),
+		newRelatedCmd(a),
+		newCaptureCmd(a),
+		newDraftsCmd(a),
+		newPublishCmd(a),
+		newAttachCmd(a),
+		newAPICmd(a),
+		newLintCmd(a),
+		newTOCCmd(a),
+		newRandomCmd(a),
+		newTodayCmd(a), newDigestCmd(a), newNotifyCmd(a), newCrosspostCmd(a), newGistCmd(a),
+		newImportCmd(a),
+		newMailboxCmd(a),
+		newBotCmd(a),
+		newOpenCmd(a),
+		newWorkspaceCmd(a),
+		newWatchCmd(a),
+		newBenchCmd(a),
+		newShareCmd(a),
+		newWalkCmd(a),
+		newScaffoldCmd(a),
+		newQuizCmd(a), newNagCmd(a), newDedupeCmd(a), newHistoryCmd(a), newDiffCmd(a), newLogCmd(a), newSyncCmd(a), newWebmentionCmd(a), newGrepCmd(a), newMigrateCmd(a), newMetaCmd(a),
+		newArchiveCmd(a), newRmCmd(a), newRestoreCmd(a), newMvCmd(a), newChangelogCmd(a), newShowCmd(a), newSnippetCmd(a), newLSPCmd(a), newMotdCmd(a),
+	)
+	a.registerCompletions(root)
+	return root
+}
+
+func (a *app) init() error {
+	cfg, err := config.Load(a.profile)
+	if err != nil {
+		return err
+	}
+	a.cfg = cfg
+	editor.Configured = strings.Fields(cfg.Editor)
+	dir := a.dir
+	if dir == "" && a.workspace == "" {
+		if a.workspace, err = currentWorkspace(); err != nil {
+			return err
+		}
+		if _, ok := cfg.Workspaces[a.workspace]; a.workspace != "" && !ok {
+			return fmt.Errorf("workspace %q in use is not in the config file; run til workspace use --clear", a.workspace)
+		}
+	}
+	if dir == "" && a.workspace != "" {
+		if dir, err = a.workspaceDir(a.workspace); err != nil {
+			return err
+		}
+	}
+	if dir == "" && cfg.Dir != "" {
+		if dir, err = expandHome(cfg.Dir); err != nil {
+			return err
+		}
+	}
+	if dir == "" {
+		wd, err := os.Getwd()
+		if err != nil {
+			return err
+		}
+		if dir, err = notes.FindRoot(wd); err != nil {
+			return err
+		}
+	}
+	a.tree = notes.Open(dir)
+	if err := store.Check(cfg.Store.Backend); err != nil {
+		return err
+	}
+	r, err := hooks.New(dir, cfg.Hooks, os.Stderr)
+	if err != nil {
+		return err
+	}
+	if !r.Empty() {
+		a.hooks = r
+		a.tree.Store = hooks.Wrap(a.tree.Store, r)
+	}
+	return nil
+}
+
+// resolve finds the entry referred to by ref on the command line: a file
+// path, relative to the working directory or absolute, or anything
+// notes.Tree.Resolve takes.
+func (a *app) resolve(ref string) (*entry.Entry, error) {
+	if fi, err := os.Stat(ref); err == nil && !fi.IsDir() {
+		rel, err := a.tree.Rel(ref)
+		if err != nil {
+			return nil, err
+		}
+		return a.tree.Load(rel)
+	}
+	return a.tree.Resolve(ref)
+}
+
+// expandHome replaces a leading ~ of p with the home directory.
+func expandHome(p string) (string, error) {
+	if p != "~" && !strings.HasPrefix(p, "~/") {
+		return p, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, p[1:]), nil
+}
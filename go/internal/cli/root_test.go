@@ -0,0 +1,109 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newTree writes files to a new notes root and returns it. The config and
+// state of the user are hidden from the commands run in it.
+func newTree(t *testing.T, files map[string]string) string {
+	t.Helper()
+	home := t.TempDir()
+	for _, env := range []string{"HOME", "XDG_CONFIG_HOME", "XDG_STATE_HOME"} {
+		t.Setenv(env, home)
+	}
+	for _, env := range []string{"TIL_CONFIG", "TIL_DIR", "TIL_PROFILE", "TIL_WORKSPACE", "EDITOR", "VISUAL"} {
+		t.Setenv(env, "")
+	}
+	root := t.TempDir()
+	if err := os.Mkdir(filepath.Join(root, ".til"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for p, data := range files {
+		writeFile(t, root, p, data)
+	}
+	return root
+}
+
+// writeConfig writes the config file of the commands run after newTree.
+func writeConfig(t *testing.T, data string) {
+	t.Helper()
+	writeFile(t, os.Getenv("XDG_CONFIG_HOME"), "til/config.toml", data)
+}
+
+func writeFile(t *testing.T, root, p, data string) {
+	t.Helper()
+	file := filepath.Join(root, filepath.FromSlash(p))
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(file, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readFile(t *testing.T, root, p string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(root, filepath.FromSlash(p)))
+	if err != nil {
+		return "<" + err.Error() + ">"
+	}
+	return string(data)
+}
+
+// run runs til with args in the notes root dir and returns its output.
+func run(t *testing.T, dir string, args ...string) (string, error) {
+	t.Helper()
+	a := &app{}
+	cmd := newRootCmd(a)
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(&out)
+	cmd.SetIn(strings.NewReader(""))
+	cmd.SetArgs(append([]string{"-C", dir}, args...))
+	err := cmd.ExecuteContext(context.Background())
+	return out.String(), err
+}
+
+// mustRun is run failing the test on an error.
+func mustRun(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	out, err := run(t, dir, args...)
+	if err != nil {
+		t.Fatalf("til %s: %v\n%s", strings.Join(args, " "), err, out)
+	}
+	return out
+}
+
+func TestResolve(t *testing.T) {
+	root := newTree(t, map[string]string{
+		"go/basic_syntax.md": "---\ntitle: Basic syntax\nslug: syntax\n---\n\n## Variables\n",
+		"git/syntax_of.md":   "# Syntax of git\n",
+	})
+	t.Chdir(filepath.Join(root, "go"))
+	for _, ref := range []string{
+		"go/basic_syntax.md",
+		"go/basic_syntax",
+		"basic_syntax",
+		"syntax",
+		"basic_syntax.md",
+		filepath.Join(root, "go", "basic_syntax.md"),
+	} {
+		out, err := run(t, root, "toc", ref)
+		if err != nil {
+			t.Errorf("toc %s: %v", ref, err)
+		} else if !strings.Contains(out, "Variables  #variables") {
+			t.Errorf("toc %s = %q", ref, out)
+		}
+	}
+	for _, ref := range []string{"nowhere", "basic"} {
+		if _, err := run(t, root, "toc", ref); err == nil {
+			t.Errorf("toc %s succeeded", ref)
+		}
+	}
+}
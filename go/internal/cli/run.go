@@ -0,0 +1,163 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/canhta/til/go/internal/runner"
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+func newRunCmd(a *app) *cobra.Command {
+	var (
+		block   int
+		lang    string
+		timeout time.Duration
+		sandbox bool
+		update  bool
+	)
+	cmd := &cobra.Command{
+		Use:   "run <entry>",
+		Short: "Run the code blocks of an entry",
+		Long: `Run extracts the fenced code blocks of an entry whose language has a
+runner (go, python, node, rust and sh by default; more can be configured in
+the [runners] section of the config file), runs each one on its own and
+prints its output. Blocks tagged {norun} are skipped, as are the {bench}
+blocks til bench runs. The exit status is non-zero when any block fails to
+build or run.
+
+An entry whose frontmatter names a companion program, as til scaffold
+creates, runs it after its blocks; source: is relative to the entry's
+directory, and an ` + "```output {source}" + ` block records its output.
+
+An ` + "```output" + ` block right after a code block records what it prints, as
+checked by til verify. --update rewrites the output blocks of the blocks
+that ran successfully with their output; to start recording a block's
+output, add an empty output block after it.
+
+Go blocks can use modules beyond the standard library: comments heading
+the block, such as // require github.com/google/uuid v1.6.0, or a
+` + "```go.mod" + ` block right before it pin their versions, and go mod tidy
+resolves the rest through the module cache shared with your own builds.
+
+--sandbox, [sandbox] enabled in the config file or sandbox: in the entry's
+frontmatter runs each block confined to its throwaway directory, without
+the network and with the memory and time limits configured, using
+bubblewrap, unshare, sandbox-exec or a container as [sandbox] backend
+chooses. The frontmatter can set limits of its own:
+
+  sandbox:
+    timeout: 2m
+    memory: 512M
+    network: true`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			e, err := a.resolve(args[0])
+			if err != nil {
+				return err
+			}
+			reg, err := a.registry(timeout, sandbox)
+			if err != nil {
+				return err
+			}
+			out := cmd.OutOrStdout()
+			ran, failed := 0, 0
+			outputs := map[int]string{}
+			snippets, err := a.tree.Snippets(e)
+			if err != nil {
+				return err
+			}
+			for _, s := range snippets {
+				b := s.Code
+				if !reg.Runnable(b) || (block > 0 && b.Index+1 != block) || (lang != "" && b.Lang != lang) {
+					continue
+				}
+				ran++
+				res := reg.Run(cmd.Context(), e, s)
+				printResult(out, e, res)
+				if res.Failed() {
+					failed++
+				} else if update && s.Output != nil && s.Output.Code != string(res.Output) {
+					outputs[b.Index] = string(res.Output)
+				}
+			}
+			if ran == 0 {
+				return fmt.Errorf("%s: no runnable code blocks", e.Path)
+			}
+			if len(outputs) > 0 {
+				if err := a.updateOutputs(cmd, e, outputs); err != nil {
+					return err
+				}
+			}
+			if failed > 0 {
+				return &exitError{code: 1, err: fmt.Errorf("%d of %d blocks failed", failed, ran)}
+			}
+			return nil
+		},
+	}
+	cmd.Flags().IntVarP(&block, "block", "b", 0, "run only the Nth code block (1-based, counting all fences)")
+	cmd.Flags().StringVarP(&lang, "lang", "l", "", "run only blocks of this fence language")
+	cmd.Flags().DurationVar(&timeout, "timeout", 0, "limit for each build and run step (default from config, else 30s)")
+	sandboxFlag(cmd, &sandbox)
+	cmd.Flags().BoolVarP(&update, "update", "u", false, "rewrite the output blocks with what the blocks printed")
+	a.commitFlag(cmd)
+	return cmd
+}
+
+// updateOutputs rewrites the output blocks of e with outputs, keyed by the
+// index of the code block each follows, and commits the entry.
+func (a *app) updateOutputs(cmd *cobra.Command, e *entry.Entry, outputs map[int]string) error {
+	data, err := a.tree.Read(e.Path)
+	if err != nil {
+		return err
+	}
+	_, body, _ := entry.SplitFrontmatter(data)
+	data = append(data[:len(data)-len(body):len(data)-len(body)], entry.SetOutputs(body, outputs)...)
+	if err := a.tree.Write(e.Path, data); err != nil {
+		return err
+	}
+	fmt.Fprintf(cmd.ErrOrStderr(), "updated %s in %s\n", plural(len(outputs), "output block"), e.Path)
+	return a.commitEntry(cmd, e.Path, "update output of")
+}
+
+// sandboxFlag registers --sandbox on a command running code blocks.
+func sandboxFlag(cmd *cobra.Command, sandbox *bool) {
+	cmd.Flags().BoolVar(sandbox, "sandbox", false, "run every block in the sandbox, as [sandbox] enabled does")
+}
+
+// noSandboxFlag registers --no-sandbox on commands verifying code blocks,
+// which run every block in the sandbox unless it is given.
+func noSandboxFlag(cmd *cobra.Command, noSandbox *bool) {
+	cmd.Flags().BoolVar(noSandbox, "no-sandbox", false, "run blocks unconfined, as til run does, but for those of entries asking for the sandbox")
+}
+
+// registry returns the runners of the config file, with the timeout
+// overriding theirs when positive, and its sandbox, enabled for all blocks
+// if sandbox is set.
+func (a *app) registry(timeout time.Duration, sandbox bool) (*runner.Registry, error) {
+	reg := runner.NewRegistry(a.cfg.Runners, timeout)
+	sb, err := runner.NewSandbox(a.cfg.Sandbox)
+	if err != nil {
+		return nil, err
+	}
+	sb.Enabled = sb.Enabled || sandbox
+	reg.Sandbox = sb
+	return reg, nil
+}
+
+func printResult(w io.Writer, e *entry.Entry, res *runner.Result) {
+	status := "ok"
+	if res.Failed() {
+		status = fmt.Sprintf("FAIL (%s: %v)", res.Phase, res.Err)
+	}
+	if res.Block.File != "" {
+		fmt.Fprintf(w, "── %s source [%s] %s %s\n", res.Block.File, res.Block.Lang, status, res.Duration.Round(time.Millisecond))
+	} else {
+		fmt.Fprintf(w, "── %s:%d block %d [%s] %s %s\n",
+			e.Path, e.FileLine(res.Block.Line), res.Block.Index+1, res.Block.Lang, status, res.Duration.Round(time.Millisecond))
+	}
+	w.Write(res.Output)
+}
@@ -0,0 +1,66 @@
+package cli
+
+import (
+	"errors"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds Go programs")
+	}
+	root := newTree(t, map[string]string{
+		"go/hello.md":  "# Hello\n\n```go\nfmt.Println(\"hello\")\n```\n\nNot run:\n\n```go {norun}\nthis does not compile\n```\n\n```go\nfmt.Println(\"again\")\n```\n",
+		"go/broken.md": "# Broken\n\n```go\nfmt.Println(\"ok\")\n```\n\n```go\nvar x int = \"s\"\n```\n",
+		"go/prose.md":  "# Prose\n\n```text\nnothing to run\n```\n",
+	})
+	out := mustRun(t, root, "run", "hello")
+	if !strings.Contains(out, "── go/hello.md:3 block 1 [go] ok ") || !strings.Contains(out, "\nhello\n") ||
+		!strings.Contains(out, "── go/hello.md:13 block 3 [go] ok ") || !strings.Contains(out, "\nagain\n") {
+		t.Errorf("run hello:\n%s", out)
+	}
+	if strings.Contains(out, "block 2") {
+		t.Errorf("run hello ran the {norun} block:\n%s", out)
+	}
+	if out := mustRun(t, root, "run", "hello", "--block", "3"); strings.Contains(out, "hello\n") || !strings.Contains(out, "again\n") {
+		t.Errorf("run hello --block 3:\n%s", out)
+	}
+
+	out, err := run(t, root, "run", "broken")
+	var exit *exitError
+	if !errors.As(err, &exit) || exit.code != 1 || !strings.Contains(err.Error(), "1 of 2 blocks failed") {
+		t.Errorf("run broken = %v", err)
+	}
+	if !strings.Contains(out, "block 1 [go] ok") || !strings.Contains(out, "block 2 [go] FAIL (build: ") {
+		t.Errorf("run broken:\n%s", out)
+	}
+
+	if _, err := run(t, root, "run", "prose"); err == nil || !strings.Contains(err.Error(), "no runnable code blocks") {
+		t.Errorf("run prose = %v", err)
+	}
+}
+
+func TestRunUpdate(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not installed")
+	}
+	root := newTree(t, map[string]string{
+		"sh/echo.md": "---\ntitle: Echo\n---\n\n```sh\necho one\necho two\n```\n\n```output\none\n```\n\n```sh\necho unrecorded\n```\n\n```sh\nexit 1\n```\n\n```output\nkept\n```\n",
+	})
+	out, err := run(t, root, "verify", "--no-sandbox")
+	if err == nil || !strings.Contains(out, "--- expected\n+++ actual\n one\n+two\n") {
+		t.Errorf("verify = %v\n%s", err, out)
+	}
+	if _, err := run(t, root, "run", "echo", "--update"); err == nil {
+		t.Error("run with a failing block succeeded")
+	}
+	want := "---\ntitle: Echo\n---\n\n```sh\necho one\necho two\n```\n\n```output\none\ntwo\n```\n\n```sh\necho unrecorded\n```\n\n```sh\nexit 1\n```\n\n```output\nkept\n```\n"
+	if got := readFile(t, root, "sh/echo.md"); got != want {
+		t.Errorf("after run --update:\n%s\nwant only the output of the block that ran updated:\n%s", got, want)
+	}
+	if out, err := run(t, root, "run", "echo", "--update", "--block", "1"); err != nil || !strings.Contains(out, "one\ntwo\n") || strings.Contains(out, "updated") {
+		t.Errorf("run --update of a current block = %v\n%s", err, out)
+	}
+}
@@ -0,0 +1,111 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+// skeletons are the companion programs til scaffold creates, by language:
+// the file name and its content, a format taking the entry's title, its
+// path and the program's directory name.
+var skeletons = map[string]struct{ file, src string }{
+	"go": {"main.go", `// Command %[3]s is the runnable example of %[2]s.
+package main
+
+import "fmt"
+
+func main() {
+	fmt.Println(%[1]q)
+}
+`},
+	"python": {"main.py", `# The runnable example of %[2]s.
+
+print(%[1]q)
+`},
+	"node": {"main.js", `// The runnable example of %[2]s.
+
+console.log(%[1]q);
+`},
+	"rust": {"main.rs", `// The runnable example of %[2]s.
+
+fn main() {
+    println!(%[1]q);
+}
+`},
+	"sh": {"main.sh", `# The runnable example of %[2]s.
+
+echo %[1]q
+`},
+}
+
+func newScaffoldCmd(a *app) *cobra.Command {
+	var (
+		tagList []string
+		slug    string
+		noEdit  bool
+	)
+	langs := make([]string, 0, len(skeletons))
+	for l := range skeletons {
+		langs = append(langs, l)
+	}
+	slices.Sort(langs)
+	cmd := &cobra.Command{
+		Use:   "scaffold <lang> <topic>",
+		Short: "Create an entry with a runnable companion program",
+		Long: `Scaffold creates an entry on topic in the category named after the language,
+as til new does, and a companion program next to it in a directory named
+after the entry's slug: go/<slug>/main.go for go. The entry's source:
+frontmatter names the program, so til run and til verify run it along with
+the entry's code blocks, and an ` + "```output {source}" + ` block records what
+it prints.
+
+Languages: ` + strings.Join(langs, ", ") + `.`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			lang, topic := args[0], args[1]
+			sk, ok := skeletons[lang]
+			if !ok {
+				return fmt.Errorf("no skeleton for %q (want one of %s)", lang, strings.Join(langs, ", "))
+			}
+			rel, content, err := a.scaffold(lang, topic, slug, tagList, nil)
+			if err != nil {
+				return err
+			}
+			stem := strings.TrimSuffix(path.Base(rel), path.Ext(rel))
+			source := path.Join(stem, sk.file)
+			content, err = entry.Rewrite(content, func(fm *entry.Front) error { return fm.Set("source", source) })
+			if err != nil {
+				return err
+			}
+			content = fmt.Appendf(bytes.TrimRight(content, "\n"), "\n\nThe program is `%s`; til run runs it.\n\n```output {%s}\n%s\n```\n",
+				source, entry.SourceAttr, topic)
+			src := path.Join(path.Dir(rel), source)
+			if err := a.tree.Create(src, fmt.Appendf(nil, sk.src, topic, rel, path.Base(stem))); err != nil {
+				return err
+			}
+			if err := a.tree.Create(rel, content); err != nil {
+				return err
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), rel)
+			fmt.Fprintln(cmd.OutOrStdout(), src)
+			if !noEdit {
+				if err := a.openEditor(rel); err != nil {
+					return err
+				}
+			}
+			return a.commitEntry(cmd, rel, "add", src)
+		},
+	}
+	cmd.Flags().StringSliceVarP(&tagList, "tag", "t", nil, "tag the entry (repeatable)")
+	cmd.Flags().StringVar(&slug, "slug", "", "override the slug derived from the topic")
+	cmd.Flags().BoolVar(&noEdit, "no-edit", false, "do not open the editor")
+	a.commitFlag(cmd)
+	return cmd
+}
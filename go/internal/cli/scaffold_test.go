@@ -0,0 +1,51 @@
+package cli
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestScaffold(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not installed")
+	}
+	root := newTree(t, nil)
+	if out := mustRun(t, root, "scaffold", "sh", "Trap signals", "--no-edit", "--tag", "signals"); out != "sh/trap_signals.md\nsh/trap_signals/main.sh\n" {
+		t.Errorf("scaffold = %q", out)
+	}
+	md := readFile(t, root, "sh/trap_signals.md")
+	if !strings.Contains(md, "\nsource: trap_signals/main.sh\n") || !strings.HasSuffix(md, "```output {source}\nTrap signals\n```\n") {
+		t.Errorf("entry =\n%s", md)
+	}
+	if src := readFile(t, root, "sh/trap_signals/main.sh"); !strings.Contains(src, "echo \"Trap signals\"\n") {
+		t.Errorf("program =\n%s", src)
+	}
+
+	if out := mustRun(t, root, "run", "trap_signals"); !strings.HasPrefix(out, "── sh/trap_signals/main.sh source [sh] ok ") || !strings.HasSuffix(out, "\nTrap signals\n") {
+		t.Errorf("run =\n%s", out)
+	}
+	mustRun(t, root, "verify", "--no-sandbox")
+
+	writeFile(t, root, "sh/trap_signals/main.sh", "echo changed\n")
+	out, err := run(t, root, "verify", "--no-sandbox")
+	if err == nil || !strings.Contains(out, "sh/trap_signals/main.sh (source of sh/trap_signals.md, sh)") {
+		t.Errorf("verify after changing the program = %v\n%s", err, out)
+	}
+	mustRun(t, root, "run", "trap_signals", "--update")
+	if md := readFile(t, root, "sh/trap_signals.md"); !strings.HasSuffix(md, "```output {source}\nchanged\n```\n") {
+		t.Errorf("entry after run --update =\n%s", md)
+	}
+
+	if _, err := run(t, root, "scaffold", "sh", "Trap signals", "--no-edit"); err == nil {
+		t.Error("scaffold over an existing entry succeeded")
+	}
+	if _, err := run(t, root, "scaffold", "cobol", "Hello", "--no-edit"); err == nil || !strings.Contains(err.Error(), `no skeleton for "cobol"`) {
+		t.Errorf("scaffold cobol = %v", err)
+	}
+
+	writeFile(t, root, "sh/lost.md", "---\ntitle: Lost\nsource: lost/main.sh\n---\n")
+	if out, err := run(t, root, "verify", "--no-sandbox", "lost"); err == nil || !strings.Contains(out, "sh/lost.md: source: ") {
+		t.Errorf("verify with a missing program = %v\n%s", err, out)
+	}
+}
@@ -0,0 +1,178 @@
+package cli
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/canhta/til/go/internal/notes"
+	"github.com/canhta/til/go/internal/search"
+	"github.com/canhta/til/go/internal/semantic"
+)
+
+func newSearchCmd(a *app) *cobra.Command {
+	var (
+		opts          search.Options
+		byMeaning     bool
+		allWorkspaces bool
+	)
+	cmd := &cobra.Command{
+		Use:   "search <query>",
+		Short: "Full-text search over titles, bodies and tags",
+		Long: `Search finds entries containing the words of the query.
+
+Queries can combine words and "quoted phrases" with field filters and
+boolean operators:
+
+  til search 'tag:go AND created:>2024-06 AND "append"'
+  til search 'title:slices OR (category:git -rebase)'
+
+Filters are tag:, category:, title:, author:, lang:, created: and updated:.
+Dates take the forms of til list --since, compared with >, >=, <, <= or
+given as a from..to range. Operators are AND, OR and NOT (or a leading -);
+terms side by side are AND'ed. @name stands for a collection from the
+config file. Archived entries are left out unless --archived is given.
+
+With --semantic it instead ranks entries by how close their meaning is to
+the query, using the embeddings endpoint configured under [embeddings] in
+the config file. When no model is configured or the endpoint cannot be
+reached, it falls back to full-text search.`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			query := strings.Join(args, " ")
+			out := cmd.OutOrStdout()
+			if !a.json && isTerminal(out) {
+				opts.MarkStart, opts.MarkEnd = "\x1b[1;33m", "\x1b[0m"
+			} else if !a.json {
+				opts.MarkStart, opts.MarkEnd = "**", "**"
+			}
+			run := func(tree *notes.Tree) ([]search.Result, error) {
+				if byMeaning {
+					results, err := a.semanticSearch(cmd.Context(), tree, query, opts)
+					if !errors.Is(err, semantic.ErrUnavailable) {
+						return results, err
+					}
+					fmt.Fprintf(cmd.ErrOrStderr(), "til: %v; falling back to full-text search\n", err)
+					byMeaning = false
+				}
+				return a.textSearch(cmd.Context(), tree, query, opts)
+			}
+			if allWorkspaces {
+				results, err := a.searchWorkspaces(cmd, run, opts.Limit)
+				if err != nil {
+					return err
+				}
+				return a.output(cmd, results, func(w io.Writer) error {
+					for _, r := range results {
+						fmt.Fprintf(w, "%s: %s  %s\n    %s\n", r.Workspace, resultPath(r.Result), r.Title, r.Snippet)
+					}
+					return nil
+				})
+			}
+			results, err := run(a.tree)
+			if err != nil {
+				return err
+			}
+			return a.output(cmd, results, func(w io.Writer) error {
+				for _, r := range results {
+					fmt.Fprintf(w, "%s  %s\n    %s\n", resultPath(r), r.Title, r.Snippet)
+				}
+				return nil
+			})
+		},
+	}
+	withJSON(cmd, "search")
+	cmd.Flags().IntVarP(&opts.Limit, "limit", "n", 20, "maximum number of results")
+	cmd.Flags().BoolVar(&opts.Raw, "raw", false, "pass the query to FTS5 unchanged (supports AND, OR, NEAR, column:term)")
+	cmd.Flags().BoolVar(&byMeaning, "semantic", false, "rank entries by meaning using the configured embedding model")
+	cmd.Flags().BoolVar(&opts.Archived, "archived", false, "include archived entries")
+	cmd.Flags().BoolVar(&allWorkspaces, "all-workspaces", false, "search every workspace of the config file, labeling results with theirs")
+	return cmd
+}
+
+// resultPath returns the path of r, with the line of a journal bullet.
+func resultPath(r search.Result) string {
+	if r.Line > 0 {
+		return fmt.Sprintf("%s:%d", r.Path, r.Line)
+	}
+	return r.Path
+}
+
+// workspaceResult is a search hit labeled with the workspace it is in.
+type workspaceResult struct {
+	Workspace string `json:"workspace"`
+	search.Result
+}
+
+// searchWorkspaces runs a search in every workspace and merges the hits,
+// best first, up to limit. Workspaces that cannot be searched are warned
+// about and skipped.
+func (a *app) searchWorkspaces(cmd *cobra.Command, run func(*notes.Tree) ([]search.Result, error), limit int) ([]workspaceResult, error) {
+	if len(a.cfg.Workspaces) == 0 {
+		return nil, errors.New("no workspaces; add them to [workspaces] in the config file")
+	}
+	var out []workspaceResult
+	for _, name := range slices.Sorted(maps.Keys(a.cfg.Workspaces)) {
+		dir, err := a.workspaceDir(name)
+		if err == nil {
+			_, err = os.Stat(dir)
+		}
+		var results []search.Result
+		if err == nil {
+			results, err = run(notes.Open(dir))
+		}
+		if err != nil {
+			fmt.Fprintf(cmd.ErrOrStderr(), "warning: workspace %s: %v\n", name, err)
+			continue
+		}
+		for _, r := range results {
+			out = append(out, workspaceResult{Workspace: name, Result: r})
+		}
+	}
+	slices.SortStableFunc(out, func(x, y workspaceResult) int { return cmp.Compare(y.Score, x.Score) })
+	if limit > 0 && len(out) > limit {
+		out = out[:limit]
+	}
+	return out, nil
+}
+
+func (a *app) textSearch(ctx context.Context, tree *notes.Tree, q string, opts search.Options) ([]search.Result, error) {
+	ix, err := search.Open(tree)
+	if err != nil {
+		return nil, err
+	}
+	defer ix.Close()
+	if _, err := ix.Sync(ctx); err != nil {
+		return nil, err
+	}
+	if opts.Raw {
+		return ix.Search(ctx, q, opts)
+	}
+	x, err := a.parseQuery(q, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	return ix.Query(ctx, x, opts)
+}
+
+func (a *app) semanticSearch(ctx context.Context, tree *notes.Tree, query string, opts search.Options) ([]search.Result, error) {
+	model, err := semantic.NewEmbedder(a.cfg.Embeddings)
+	if err != nil {
+		return nil, err
+	}
+	ix, err := semantic.Open(tree, model)
+	if err != nil {
+		return nil, err
+	}
+	defer ix.Close()
+	return ix.Search(ctx, query, opts)
+}
@@ -0,0 +1,28 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSearch(t *testing.T) {
+	root := newTree(t, map[string]string{
+		"go/slices.md":  "---\ntitle: Slices share arrays\ntags: [go]\n---\nAppending may reallocate the backing array.\n",
+		"git/rebase.md": "---\ntitle: Rebase onto\ntags: [git]\n---\nMove commits to another branch.\n",
+	})
+	want := "go/slices.md  Slices share arrays\n    Appending may reallocate the **backing** array.\n"
+	if out := mustRun(t, root, "search", "backing"); out != want {
+		t.Errorf("search =\n%s\nwant\n%s", out, want)
+	}
+	if _, err := os.Stat(filepath.Join(root, ".til", "index.db")); err != nil {
+		t.Errorf("index not kept: %v", err)
+	}
+	writeFile(t, root, "git/bisect.md", "# Bisect\n\nFind the commit that broke the backing store.\n")
+	if out := mustRun(t, root, "search", "backing", "store"); out != "git/bisect.md  Bisect\n    # Bisect Find the commit that broke the **backing** **store**.\n" {
+		t.Errorf("search after adding an entry =\n%s", out)
+	}
+	if out := mustRun(t, root, "search", "nothing"); out != "" {
+		t.Errorf("search without hits = %q", out)
+	}
+}
@@ -0,0 +1,34 @@
+package cli
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/canhta/til/go/internal/serve"
+	"github.com/canhta/til/go/internal/site"
+)
+
+func newServeCmd(a *app) *cobra.Command {
+	var (
+		opts site.Options
+		addr string
+	)
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Serve the site locally, rebuilding and reloading on change",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := a.siteOptions(&opts); err != nil {
+				return err
+			}
+			b, err := site.NewBuilder(a.tree, opts)
+			if err != nil {
+				return err
+			}
+			s := &serve.Server{Addr: addr, Tree: a.tree, Builder: b, Out: opts.Out}
+			return s.Run(cmd.Context())
+		},
+	}
+	siteFlags(cmd.Flags(), &opts)
+	cmd.Flags().StringVar(&addr, "addr", "localhost:4000", "address to listen on")
+	return cmd
+}
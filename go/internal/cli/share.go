@@ -0,0 +1,105 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+
+	"github.com/canhta/til/go/internal/bench"
+	"github.com/canhta/til/go/internal/playground"
+	"github.com/canhta/til/go/internal/runner"
+	"github.com/canhta/til/go/pkg/entry"
+	"github.com/canhta/til/go/pkg/render"
+)
+
+func newShareCmd(a *app) *cobra.Command {
+	var block int
+	cmd := &cobra.Command{
+		Use:   "share <entry>",
+		Short: "Share an entry's Go code blocks on the Go Playground",
+		Long: `Share uploads the Go code blocks of an entry to the Go Playground, completed
+into programs as til run completes them, and records each snippet's ID in
+the block's play attribute:
+
+  ` + "```go {play=\"abc123\"}" + `
+
+Rendered pages follow such a block with a link running it on the
+Playground. Blocks tagged {norun} or {bench} are skipped unless picked with
+--block. Snippet IDs follow the code, so sharing an unchanged block again
+keeps its ID.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			e, err := a.resolve(args[0])
+			if err != nil {
+				return err
+			}
+			if e.Meta.Private {
+				return fmt.Errorf("%s is private", e.Path)
+			}
+			var blocks []entry.CodeBlock
+			for _, b := range entry.CodeBlocks(e.Body) {
+				if b.Lang != "go" || (block > 0 && b.Index+1 != block) || (block == 0 && (b.Has("norun") || b.Has(bench.Attr))) {
+					continue
+				}
+				blocks = append(blocks, b)
+			}
+			if len(blocks) == 0 {
+				return fmt.Errorf("%s: no Go code blocks to share", e.Path)
+			}
+
+			c := playground.Client{API: a.cfg.Playground.API}
+			changed := map[int]string{}
+			var res []shareResult
+			for _, b := range blocks {
+				src, err := runner.GoProgram(b.Code)
+				if err != nil {
+					return fmt.Errorf("%s:%d: %w", e.Path, e.FileLine(b.Line), err)
+				}
+				id, err := c.Share(cmd.Context(), src)
+				if err != nil {
+					return err
+				}
+				if b.Attrs[playground.Attr] != id {
+					changed[b.Index] = id
+				}
+				res = append(res, shareResult{Block: b.Index + 1, ID: id, URL: render.PlayURL(id)})
+			}
+			if len(changed) > 0 {
+				data, err := a.tree.Read(e.Path)
+				if err != nil {
+					return err
+				}
+				_, body, _ := entry.SplitFrontmatter(data)
+				n := len(data) - len(body)
+				for i, id := range changed {
+					body = entry.SetAttr(body, i, playground.Attr, id)
+				}
+				data = append(data[:n:n], body...)
+				if err := a.tree.Write(e.Path, data); err != nil {
+					return err
+				}
+				if err := a.commitEntry(cmd, e.Path, "share"); err != nil {
+					return err
+				}
+			}
+			return a.output(cmd, res, func(w io.Writer) error {
+				for _, r := range res {
+					fmt.Fprintf(w, "block %d: %s\n", r.Block, r.URL)
+				}
+				return nil
+			})
+		},
+	}
+	withJSON(cmd, "share")
+	cmd.Flags().IntVarP(&block, "block", "b", 0, "share only the Nth code block (1-based, counting all fences)")
+	a.commitFlag(cmd)
+	return cmd
+}
+
+// shareResult is a code block shared by til share.
+type shareResult struct {
+	Block int    `json:"block"`
+	ID    string `json:"id"`
+	URL   string `json:"url"`
+}
@@ -0,0 +1,80 @@
+package cli
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakePlayground fakes the Go Playground, deriving IDs from the content as it
+// does, and records the programs shared.
+func fakePlayground(t *testing.T) (*[]string, string) {
+	t.Helper()
+	var (
+		mu     sync.Mutex
+		shared []string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		shared = append(shared, string(body))
+		mu.Unlock()
+		sum := sha256.Sum256(body)
+		io.WriteString(w, hex.EncodeToString(sum[:])[:10])
+	}))
+	t.Cleanup(srv.Close)
+	return &shared, srv.URL
+}
+
+func TestShare(t *testing.T) {
+	shared, api := fakePlayground(t)
+	root := newTree(t, map[string]string{
+		"go/hello.md":  "# Hello\n\n```go\nfmt.Println(\"hello\")\n```\n\n```go {norun}\nnot shared\n```\n\n```go {label=\"again\"}\nfmt.Println(\"again\")\n```\n",
+		"go/secret.md": "---\ntitle: Secret\nprivate: true\n---\n\n```go\nfmt.Println()\n```\n",
+		"go/prose.md":  "# Prose\n\n```sh\necho hi\n```\n",
+	})
+	writeConfig(t, "[playground]\napi = "+quote(api)+"\n")
+	out := mustRun(t, root, "share", "hello")
+	if len(*shared) != 2 || !strings.HasPrefix((*shared)[0], "package main\n") || !strings.Contains((*shared)[0], "fmt.Println(\"hello\")") {
+		t.Fatalf("shared %q", *shared)
+	}
+	got := readFile(t, root, "go/hello.md")
+	var ids []string
+	for _, l := range strings.Split(got, "\n") {
+		if _, id, ok := strings.Cut(l, `play="`); ok {
+			ids = append(ids, strings.TrimSuffix(strings.TrimSuffix(id, `"}`), `"`))
+		}
+	}
+	if len(ids) != 2 || !strings.Contains(got, "```go {play=\""+ids[0]+"\"}\n") || !strings.Contains(got, "```go {label=\"again\" play=\""+ids[1]+"\"}\n") || !strings.Contains(got, "```go {norun}\n") {
+		t.Fatalf("after share:\n%s", got)
+	}
+	if want := "block 1: https://go.dev/play/p/" + ids[0] + "\nblock 3: https://go.dev/play/p/" + ids[1] + "\n"; out != want {
+		t.Errorf("share = %q, want %q", out, want)
+	}
+
+	// Unchanged blocks keep their IDs; --block picks even a {norun} one.
+	mustRun(t, root, "share", "hello")
+	if again := readFile(t, root, "go/hello.md"); again != got {
+		t.Errorf("sharing again changed the entry:\n%s", again)
+	}
+	if _, err := run(t, root, "share", "hello", "--block", "2"); err == nil || !strings.Contains(err.Error(), "go/hello.md:") {
+		t.Errorf("share --block 2 of a block that does not compile into a program = %v", err)
+	}
+
+	mustRun(t, root, "build")
+	if page := readFile(t, root, "public/go/hello/index.html"); !strings.Contains(page, `<a href="https://go.dev/play/p/`+ids[0]+`">Run in the Go Playground</a>`) {
+		t.Errorf("built page:\n%s", page)
+	}
+
+	if _, err := run(t, root, "share", "secret"); err == nil || err.Error() != "go/secret.md is private" {
+		t.Errorf("share secret = %v", err)
+	}
+	if _, err := run(t, root, "share", "prose"); err == nil || err.Error() != "go/prose.md: no Go code blocks to share" {
+		t.Errorf("share prose = %v", err)
+	}
+}
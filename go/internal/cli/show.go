@@ -0,0 +1,102 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+
+	"github.com/canhta/til/go/internal/links"
+	"github.com/canhta/til/go/internal/termrender"
+	"github.com/canhta/til/go/pkg/render"
+)
+
+func newShowCmd(a *app) *cobra.Command {
+	var (
+		first bool
+		opts  termrender.Options
+		paged bool
+	)
+	cmd := &cobra.Command{
+		Use:   "show <entry>",
+		Short: "Render an entry in the terminal",
+		Long: `Show prints an entry rendered for the terminal: headings and emphasis
+styled, paragraphs wrapped to the terminal's width, code blocks highlighted
+and numbered, tables aligned, and links to other entries followed by the
+path of the entry they point to. The entry is found as til edit finds it,
+exactly by path, ID, file stem or slug, or else in a fuzzy finder over
+titles and paths.
+
+Colors are used when printing to a terminal. With --pager the output goes
+through $PAGER, or less -R, when it is one.`,
+		Example: `  til show go/slices
+  til show --pager slice
+  til show --width 60 go/slices | lpr`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var ref string
+			if len(args) == 1 {
+				ref = args[0]
+			}
+			if err := render.CheckStyle(opts.Style); err != nil {
+				return err
+			}
+			e, err := a.pickEntry(ref, first)
+			if err != nil {
+				return err
+			}
+			entries, err := a.tree.Entries()
+			if err != nil {
+				return err
+			}
+			opts.Index = links.NewIndex(entries)
+			w := cmd.OutOrStdout()
+			opts.Color = isTerminal(w)
+			if opts.Width == 0 {
+				opts.Width = termWidth(w)
+			}
+			out := termrender.Render(e, opts)
+			if paged && opts.Color {
+				return page(w, out)
+			}
+			_, err = io.WriteString(w, out)
+			return err
+		},
+	}
+	pickFlag(cmd, &first)
+	cmd.Flags().BoolVarP(&paged, "pager", "p", false, "page the output through $PAGER when printing to a terminal")
+	cmd.Flags().IntVar(&opts.Width, "width", 0, "wrap at this many columns (default the terminal's width, at most 100)")
+	cmd.Flags().StringVar(&opts.Style, "style", "monokai", "chroma style for code blocks")
+	return cmd
+}
+
+// termWidth returns the width to wrap output to w at: the terminal's, up
+// to 100 columns to keep lines readable, or termrender.DefaultWidth when w
+// is not a terminal.
+func termWidth(w io.Writer) int {
+	if f, ok := w.(*os.File); ok {
+		if width, _, err := term.GetSize(int(f.Fd())); err == nil && width > 0 {
+			return min(width-1, 100)
+		}
+	}
+	return termrender.DefaultWidth
+}
+
+// page shows out in $PAGER, or less -R, writing to w.
+func page(w io.Writer, out string) error {
+	argv := strings.Fields(os.Getenv("PAGER"))
+	if len(argv) == 0 {
+		argv = []string{"less", "-R"}
+	}
+	c := exec.Command(argv[0], argv[1:]...)
+	c.Stdin = strings.NewReader(out)
+	c.Stdout, c.Stderr = w, os.Stderr
+	if err := c.Run(); err != nil {
+		return fmt.Errorf("pager %s: %w", argv[0], err)
+	}
+	return nil
+}
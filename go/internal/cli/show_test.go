@@ -0,0 +1,29 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestShow(t *testing.T) {
+	root := newTree(t, map[string]string{
+		"go/slices.md": "---\ntitle: Slices\ntags: [go]\n---\n\n# Slices\n\nSee [[maps]].\n\n```go\nx := 1\n```\n",
+		"go/maps.md":   "# Maps\n\nMaps are hash tables of keys to values, resized as they grow.\n",
+	})
+	// Output to a file is plain.
+	want := "Slices\ngo/slices.md  #go\n\nSee Maps → go/maps.md.\n\n── [1] go\nx := 1\n──\n"
+	if out := mustRun(t, root, "show", "go/slices", "--pager"); out != want {
+		t.Errorf("show =\n%q\nwant\n%q", out, want)
+	}
+	// A fuzzy match of a single entry.
+	out := mustRun(t, root, "show", "mps", "--width", "20")
+	if want := "Maps\ngo/maps.md\n\nMaps are hash tables\nof keys to values,\nresized as they\ngrow.\n"; out != want {
+		t.Errorf("show --width 20 =\n%q\nwant\n%q", out, want)
+	}
+	if _, err := run(t, root, "show", "go/slices", "--style", "nosuchstyle"); err == nil || !strings.Contains(err.Error(), "nosuchstyle") {
+		t.Errorf("show --style nosuchstyle = %v", err)
+	}
+	if _, err := run(t, root, "show", "zzzz"); err == nil {
+		t.Error("show of no entry succeeded")
+	}
+}
@@ -0,0 +1,102 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/canhta/til/go/internal/clipboard"
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+func newSnippetCmd(a *app) *cobra.Command {
+	var (
+		first   bool
+		nth     int
+		lang    string
+		copied  bool
+		printed bool
+	)
+	cmd := &cobra.Command{
+		Use:   "snippet <entry>",
+		Short: "Print or copy a code block of an entry",
+		Long: `Snippet prints the code of the Nth code block of an entry, the first unless
+--nth says otherwise, counting all fences as til show numbers them. With
+--lang, only blocks of that fence language are counted.
+
+With --copy the code goes to the system clipboard instead: through pbcopy,
+wl-copy, xclip, xsel or clip.exe, else the terminal's OSC 52 escape. When
+copying fails, --print prints the code instead of failing. The entry is found
+as til edit finds it.`,
+		Example: `  til snippet go/slices --copy
+  til snippet slices --nth 2 --copy --print
+  til snippet --lang sh go/modules | sh`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if nth < 1 {
+				return fmt.Errorf("--nth must be at least 1")
+			}
+			var ref string
+			if len(args) == 1 {
+				ref = args[0]
+			}
+			e, err := a.pickEntry(ref, first)
+			if err != nil {
+				return err
+			}
+			var blocks []entry.CodeBlock
+			for _, b := range entry.CodeBlocks(e.Body) {
+				if lang == "" || strings.EqualFold(b.Lang, lang) {
+					blocks = append(blocks, b)
+				}
+			}
+			kind := "code block"
+			if lang != "" {
+				kind = lang + " " + kind
+			}
+			switch {
+			case len(blocks) == 0:
+				return fmt.Errorf("%s has no %ss", e.Path, kind)
+			case nth > len(blocks):
+				return fmt.Errorf("%s has only %s", e.Path, plural(len(blocks), kind))
+			}
+			b := blocks[nth-1]
+			res := snippetResult{Path: e.Path, Block: b.Index + 1, Lang: b.Lang, Line: e.FileLine(b.Line), Code: b.Code}
+			if copied && !a.json {
+				err := clipboard.Copy(b.Code)
+				if err == nil {
+					fmt.Fprintf(cmd.ErrOrStderr(), "copied block %d of %s (%s)\n", res.Block, e.Path, plural(strings.Count(b.Code, "\n"), "line"))
+					return nil
+				}
+				if !printed {
+					return fmt.Errorf("copying: %w (use --print to print instead)", err)
+				}
+				fmt.Fprintf(cmd.ErrOrStderr(), "warning: copying: %v; printing instead\n", err)
+			}
+			return a.output(cmd, res, func(w io.Writer) error {
+				_, err := io.WriteString(w, b.Code)
+				return err
+			})
+		},
+	}
+	withJSON(cmd, "snippet")
+	pickFlag(cmd, &first)
+	cmd.Flags().IntVar(&nth, "nth", 1, "take the Nth code block (1-based)")
+	cmd.Flags().StringVarP(&lang, "lang", "l", "", "count only blocks of this fence language")
+	cmd.Flags().BoolVar(&copied, "copy", false, "copy the code to the system clipboard")
+	cmd.Flags().BoolVar(&printed, "print", false, "with --copy, print the code when copying fails")
+	return cmd
+}
+
+// snippetResult is the code block til snippet took.
+type snippetResult struct {
+	Path string `json:"path"`
+	// Block is the 1-based position of the block among all fences.
+	Block int    `json:"block"`
+	Lang  string `json:"lang"`
+	// Line is the line of the opening fence in the file.
+	Line int    `json:"line"`
+	Code string `json:"code"`
+}
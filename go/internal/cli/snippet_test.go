@@ -0,0 +1,95 @@
+package cli
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeClipboard puts a wl-copy running script first on PATH.
+func fakeClipboard(t *testing.T, script string) {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "wl-copy"), []byte("#!/bin/sh\n"+script+"\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Setenv("WAYLAND_DISPLAY", "wayland-0")
+}
+
+func TestSnippet(t *testing.T) {
+	root := newTree(t, map[string]string{
+		"go/slices.md": "---\ntitle: Slices\n---\n\n```go\nx := 1\n```\n\n```sh\ngo test ./...\ngo vet ./...\n```\n\n```go\ny := 2\n```\n",
+		"go/maps.md":   "# Maps\n",
+	})
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"go/slices"}, "x := 1\n"},
+		{[]string{"go/slices", "--nth", "3"}, "y := 2\n"},
+		{[]string{"go/slices", "--lang", "GO", "--nth", "2"}, "y := 2\n"},
+		{[]string{"go/slices", "-l", "sh"}, "go test ./...\ngo vet ./...\n"},
+	}
+	for _, tt := range tests {
+		if out := mustRun(t, root, append([]string{"snippet"}, tt.args...)...); out != tt.want {
+			t.Errorf("snippet %q = %q, want %q", tt.args, out, tt.want)
+		}
+	}
+	var doc struct {
+		Kind string
+		Data struct {
+			Path       string
+			Block      int
+			Lang, Code string
+			Line       int
+		}
+	}
+	if err := json.Unmarshal([]byte(mustRun(t, root, "snippet", "go/slices", "-l", "sh", "--json")), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if res := doc.Data; doc.Kind != "snippet" || res.Path != "go/slices.md" || res.Block != 2 || res.Lang != "sh" || res.Line != 9 || res.Code != "go test ./...\ngo vet ./...\n" {
+		t.Errorf("snippet --json = %+v", doc)
+	}
+
+	errs := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"go/slices", "--nth", "0"}, "--nth must be at least 1"},
+		{[]string{"go/slices", "--nth", "4"}, "go/slices.md has only 3 code blocks"},
+		{[]string{"go/slices", "-l", "sh", "--nth", "2"}, "go/slices.md has only 1 sh code block"},
+		{[]string{"go/slices", "-l", "rust"}, "go/slices.md has no rust code blocks"},
+		{[]string{"go/maps"}, "go/maps.md has no code blocks"},
+	}
+	for _, tt := range errs {
+		if _, err := run(t, root, append([]string{"snippet"}, tt.args...)...); err == nil || err.Error() != tt.want {
+			t.Errorf("snippet %q = %v, want %s", tt.args, err, tt.want)
+		}
+	}
+}
+
+func TestSnippetCopy(t *testing.T) {
+	root := newTree(t, map[string]string{
+		"go/slices.md": "# Slices\n\n```sh\ngo test ./...\ngo vet ./...\n```\n",
+	})
+	clip := filepath.Join(t.TempDir(), "clip")
+	fakeClipboard(t, "cat > "+clip)
+	if out := mustRun(t, root, "snippet", "go/slices", "--copy"); out != "copied block 1 of go/slices.md (2 lines)\n" {
+		t.Errorf("snippet --copy = %q", out)
+	}
+	if data, _ := os.ReadFile(clip); string(data) != "go test ./...\ngo vet ./...\n" {
+		t.Errorf("clipboard = %q", data)
+	}
+
+	fakeClipboard(t, "echo no display >&2; exit 1")
+	if _, err := run(t, root, "snippet", "go/slices", "--copy"); err == nil || !strings.HasSuffix(err.Error(), "(use --print to print instead)") {
+		t.Errorf("snippet --copy failing = %v", err)
+	}
+	out := mustRun(t, root, "snippet", "go/slices", "--copy", "--print")
+	if !strings.Contains(out, "warning: copying: wl-copy: exit status 1: no display; printing instead\n") || !strings.HasSuffix(out, "go test ./...\ngo vet ./...\n") {
+		t.Errorf("snippet --copy --print =\n%s", out)
+	}
+}
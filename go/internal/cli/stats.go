@@ -0,0 +1,141 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/canhta/til/go/internal/goals"
+	"github.com/canhta/til/go/internal/query"
+	"github.com/canhta/til/go/internal/stats"
+)
+
+func newStatsCmd(a *app) *cobra.Command {
+	var (
+		filter   listFilter
+		gitDates bool
+		top      int
+	)
+	cmd := &cobra.Command{
+		Use:   "stats",
+		Short: "Show statistics: entries per month, category, tag and author, and streaks",
+		Example: `  til stats
+  til stats --since 1y --json`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			now := time.Now()
+			x, err := filter.expr(now, a.lang())
+			if err != nil {
+				return err
+			}
+			entries, err := a.datedEntries(cmd.Context(), gitDates)
+			if err != nil {
+				return err
+			}
+			s := stats.Compute(query.Filter(entries, x), now)
+			if s.Goals, err = goals.Check(a.cfg.Goals, entries, now); err != nil {
+				return err
+			}
+			return a.output(cmd, s, func(w io.Writer) error { return writeStats(w, s, top) })
+		},
+	}
+	withJSON(cmd, "stats")
+	filter.register(cmd)
+	cmd.Flags().BoolVar(&gitDates, "git-dates", false, "date entries by their first and last commit")
+	cmd.Flags().IntVar(&top, "top", 10, "show at most this many tags (0 for all)")
+	return cmd
+}
+
+func writeStats(w io.Writer, s *stats.Stats, top int) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "Entries\t%d\n", s.Entries)
+	if s.Undated > 0 {
+		fmt.Fprintf(tw, "Undated\t%d\n", s.Undated)
+	}
+	fmt.Fprintf(tw, "Words\t%d (%d per entry)\n", s.Words, s.AverageWords)
+	fmt.Fprintf(tw, "Code\t%s\n", plural(s.CodeLines, "line"))
+	fmt.Fprintf(tw, "Reading time\t%s\n", readingTime(s.ReadingMinutes))
+	fmt.Fprintf(tw, "Current streak\t%s\n", plural(s.CurrentStreak, "day"))
+	longest := plural(s.LongestStreak, "day")
+	if s.LongestStreakEnd != "" {
+		longest += " (ended " + s.LongestStreakEnd + ")"
+	}
+	fmt.Fprintf(tw, "Longest streak\t%s\n", longest)
+	if j := s.Journal; j != nil {
+		fmt.Fprintf(tw, "Journal\t%s over %s\n", plural(j.Items, "item"), plural(j.Days, "day"))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	section := func(title string, rows [][2]string) {
+		if len(rows) == 0 {
+			return
+		}
+		fmt.Fprintf(tw, "\n%s\n", strings.ToUpper(title))
+		width := 0
+		for _, r := range rows {
+			width = max(width, len(r[1]))
+		}
+		for _, r := range rows {
+			fmt.Fprintf(tw, "%s\t%*s\n", r[0], width, r[1])
+		}
+	}
+	if len(s.Goals) > 0 {
+		fmt.Fprintf(tw, "\nGOALS\n")
+		for _, p := range s.Goals {
+			fmt.Fprintf(tw, "%s\t%s\n", p.Goal, p.Status())
+		}
+	}
+	var rows [][2]string
+	for _, c := range s.PerMonth {
+		rows = append(rows, [2]string{c.Key, fmt.Sprint(c.Count)})
+	}
+	section("Per month", rows)
+	rows = nil
+	if s.Journal != nil {
+		for _, c := range s.Journal.PerMonth {
+			rows = append(rows, [2]string{c.Key, fmt.Sprint(c.Count)})
+		}
+		section("Journal items per month", rows)
+		rows = nil
+	}
+	for _, c := range s.PerCategory {
+		rows = append(rows, [2]string{c.Key, fmt.Sprint(c.Count)})
+	}
+	section("Per category", rows)
+	rows = nil
+	for _, c := range s.PerAuthor {
+		rows = append(rows, [2]string{c.Key, fmt.Sprint(c.Count)})
+	}
+	section("Per author", rows)
+	rows = nil
+	for i, c := range s.PerTag {
+		if top > 0 && i == top {
+			rows = append(rows, [2]string{fmt.Sprintf("(%d more)", len(s.PerTag)-top), ""})
+			break
+		}
+		rows = append(rows, [2]string{c.Tag, fmt.Sprint(c.Count)})
+	}
+	section("Per tag", rows)
+	return tw.Flush()
+}
+
+// readingTime formats a reading time in minutes as hours and minutes.
+func readingTime(minutes int) string {
+	if minutes < 60 {
+		return fmt.Sprintf("%d min", minutes)
+	}
+	return fmt.Sprintf("%dh %02dmin", minutes/60, minutes%60)
+}
+
+func plural(n int, unit string) string {
+	if n == 1 {
+		return "1 " + unit
+	}
+	return fmt.Sprintf("%d %ss", n, unit)
+}
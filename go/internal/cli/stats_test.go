@@ -0,0 +1,43 @@
+package cli
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestStats(t *testing.T) {
+	root := newTree(t, map[string]string{
+		"go/a.md":  "---\ndate: 2024-05-30\ntags: [go]\n---\none two three four\n",
+		"go/b.md":  "---\ndate: 2024-05-31\ntags: [go, slices]\n---\none two\n",
+		"git/c.md": "---\ndate: 2024-04-01\n---\nwords\n",
+	})
+	out := mustRun(t, root, "stats")
+	for _, want := range []string{
+		"Entries         3\nWords           7 (2 per entry)\n",
+		"Longest streak  2 days (ended 2024-05-31)\n",
+		"PER MONTH\n2024-04  1\n2024-05  2\n",
+		"PER TAG\ngo      2\nslices  1\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("stats lacks %q:\n%s", want, out)
+		}
+	}
+	var doc struct {
+		Kind string
+		Data struct {
+			Entries       int `json:"entries"`
+			LongestStreak int `json:"longest_streak"`
+			PerMonth      []struct {
+				Key   string
+				Count int
+			} `json:"per_month"`
+		}
+	}
+	if err := json.Unmarshal([]byte(mustRun(t, root, "stats", "--json")), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.Kind != "stats" || doc.Data.Entries != 3 || doc.Data.LongestStreak != 2 || len(doc.Data.PerMonth) != 2 {
+		t.Errorf("stats --json = %+v", doc)
+	}
+}
@@ -0,0 +1,344 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/canhta/til/go/internal/fsutil"
+	"github.com/canhta/til/go/internal/git"
+	"github.com/canhta/til/go/internal/gitsync"
+	"github.com/canhta/til/go/internal/logging"
+	"github.com/canhta/til/go/internal/remote"
+)
+
+func newSyncCmd(a *app) *cobra.Command {
+	var (
+		prefer, remoteURL string
+		dryRun            bool
+	)
+	cmd := &cobra.Command{
+		Use:   "sync",
+		Short: "Commit, pull and push the notes in one go, resolving entry conflicts",
+		Long: `Sync commits the changed entries and assets of the notes tree, rebases them
+onto the remote branch, and pushes the result.
+
+When an entry was changed differently on both sides, the conflict is
+resolved instead of left between conflict markers: in the frontmatter the
+version updated last wins, where both sides added list items all of them
+are kept, and anything else is shown for you to keep the local side, the
+remote one or both (or as --prefer says). A conflict in any other file
+stops the sync and leaves the branch as it was.
+
+Where git is not an option, --remote, or [sync] remote, syncs with a copy
+of the tree in an S3-compatible bucket (s3://bucket/prefix) or a WebDAV
+directory (webdav://host/path) instead. A file changed on one side is
+copied to the other, one changed on both sides is taken from the side
+that changed it last, and deletions are kept as tombstones so that they
+reach every machine. --dry-run shows the uploads, downloads and deletions
+without making them. S3 credentials are read from $AWS_ACCESS_KEY_ID and
+$AWS_SECRET_ACCESS_KEY, and the WebDAV password from $TIL_WEBDAV_PASSWORD,
+or the variables [sync] names.`,
+		Example: `  til sync
+  til sync --prefer local
+  til sync --remote s3://bucket/til --dry-run
+  til sync --remote webdav://me@dav.example.com/til`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if remoteURL == "" {
+				remoteURL = a.cfg.Sync.Remote
+			}
+			if remoteURL != "" {
+				return a.syncRemote(cmd, remoteURL, dryRun)
+			}
+			if dryRun {
+				return errors.New("--dry-run needs --remote")
+			}
+			var choose gitsync.Chooser
+			switch prefer {
+			case "":
+				in := bufio.NewReader(cmd.InOrStdin())
+				choose = func(c gitsync.Conflict) (gitsync.Choice, error) {
+					return promptConflict(cmd.OutOrStdout(), in, c)
+				}
+			case "local", "remote":
+				choice := gitsync.KeepLocal
+				if prefer == "remote" {
+					choice = gitsync.KeepRemote
+				}
+				choose = func(gitsync.Conflict) (gitsync.Choice, error) { return choice, nil }
+			default:
+				return fmt.Errorf("--prefer %q: want local or remote", prefer)
+			}
+			return a.sync(cmd, choose)
+		},
+	}
+	cmd.Flags().StringVar(&prefer, "prefer", "", "resolve conflicts the rules leave open with this side, local or remote, instead of asking")
+	cmd.Flags().StringVar(&remoteURL, "remote", "", "sync with this S3 or WebDAV URL instead of git")
+	cmd.Flags().BoolVarP(&dryRun, "dry-run", "n", false, "with --remote, show what would be synced and change nothing")
+	return cmd
+}
+
+func (a *app) sync(cmd *cobra.Command, choose gitsync.Chooser) error {
+	ctx := cmd.Context()
+	out := cmd.OutOrStdout()
+	repo, err := git.Open(ctx, a.tree.Root)
+	if errors.Is(err, git.ErrNotRepo) {
+		return fmt.Errorf("%s is not in a git repository", a.tree.Root)
+	}
+	if err != nil {
+		return err
+	}
+	if busy, err := repo.Busy(ctx); err != nil {
+		return err
+	} else if busy != "" {
+		return fmt.Errorf("cannot sync: %s", busy)
+	}
+	upstream, err := repo.Upstream(ctx)
+	if err != nil {
+		return err
+	}
+	sctx, end := logging.Start(ctx, "commit")
+	n, err := a.commitLocal(sctx, repo)
+	end()
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		fmt.Fprintf(out, "committed %s\n", plural(n, "changed file"))
+	}
+	sctx, end = logging.Start(ctx, "fetch", "upstream", upstream)
+	err = repo.Fetch(sctx)
+	end()
+	if err != nil {
+		return err
+	}
+	behind, err := repo.Count(ctx, "HEAD..@{upstream}")
+	if err != nil {
+		return err
+	}
+	logging.From(ctx).Debug("fetched", "upstream", upstream, "behind", behind)
+	if behind > 0 {
+		sctx, end = logging.Start(ctx, "rebase")
+		err := a.rebase(sctx, out, repo, choose)
+		end()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "pulled %s from %s\n", plural(behind, "commit"), upstream)
+	}
+	ahead, err := repo.Count(ctx, "@{upstream}..HEAD")
+	if err != nil {
+		return err
+	}
+	if ahead > 0 {
+		sctx, end = logging.Start(ctx, "push", "upstream", upstream)
+		err := repo.Push(sctx)
+		end()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "pushed %s to %s\n", plural(ahead, "commit"), upstream)
+	}
+	if n == 0 && behind == 0 && ahead == 0 {
+		fmt.Fprintf(out, "up to date with %s\n", upstream)
+	}
+	return nil
+}
+
+// syncRemote syncs the tree with the S3 or WebDAV remote at rawURL.
+func (a *app) syncRemote(cmd *cobra.Command, rawURL string, dryRun bool) error {
+	ctx := cmd.Context()
+	out := cmd.OutOrStdout()
+	cfg := a.cfg.Sync
+	env := func(name, def string) string {
+		if name == "" {
+			name = def
+		}
+		return os.Getenv(name)
+	}
+	r, err := remote.Open(rawURL, remote.Options{
+		Endpoint:     cfg.Endpoint,
+		Region:       cfg.Region,
+		AccessKey:    env(cfg.AccessKeyEnv, "AWS_ACCESS_KEY_ID"),
+		SecretKey:    env(cfg.SecretKeyEnv, "AWS_SECRET_ACCESS_KEY"),
+		SessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+		Password:     env(cfg.PasswordEnv, "TIL_WEBDAV_PASSWORD"),
+	})
+	if err != nil {
+		return err
+	}
+	shown := remote.Redact(rawURL)
+	sctx, end := logging.Start(ctx, "prepare", "remote", shown)
+	plan, err := remote.Prepare(sctx, a.tree, r, rawURL)
+	end()
+	if err != nil {
+		return err
+	}
+	logging.From(ctx).Debug("planned sync", "remote", shown, "steps", len(plan.Steps))
+	if len(plan.Steps) == 0 && dryRun {
+		fmt.Fprintf(out, "up to date with %s\n", shown)
+		return nil
+	}
+	for _, s := range plan.Steps {
+		fmt.Fprintf(out, "%-13s  %s\n", s.Action, s.Path)
+	}
+	if dryRun {
+		fmt.Fprintf(out, "dry run: nothing synced with %s\n", shown)
+		return nil
+	}
+	sctx, end = logging.Start(ctx, "apply", "remote", shown)
+	err = plan.Apply(sctx, a.tree, r)
+	end()
+	if err != nil {
+		return err
+	}
+	if len(plan.Steps) == 0 {
+		fmt.Fprintf(out, "up to date with %s\n", shown)
+	} else {
+		fmt.Fprintf(out, "synced %s with %s\n", plural(len(plan.Steps), "file"), shown)
+	}
+	return nil
+}
+
+// commitLocal commits the changes to tracked files of the tree and its
+// untracked entries and assets, leaving out other untracked files such as
+// the state directory and built sites. It returns the number of files
+// committed.
+func (a *app) commitLocal(ctx context.Context, repo *git.Repo) (int, error) {
+	root, err := filepath.Abs(a.tree.Root)
+	if err == nil {
+		root, err = filepath.EvalSymlinks(root)
+	}
+	if err != nil {
+		return 0, err
+	}
+	changes, err := repo.Status(ctx, root)
+	if err != nil || len(changes) == 0 {
+		return 0, err
+	}
+	infos, err := a.tree.Files()
+	if err != nil {
+		return 0, err
+	}
+	inTree := map[string]bool{}
+	for _, f := range infos {
+		inTree[f.Path] = true
+	}
+	var files []string
+	for _, c := range changes {
+		file := filepath.Join(repo.Root, filepath.FromSlash(c.Path))
+		rel, err := filepath.Rel(root, file)
+		if err != nil {
+			return 0, err
+		}
+		if !c.Untracked || inTree[filepath.ToSlash(rel)] {
+			files = append(files, file)
+		}
+	}
+	if len(files) == 0 {
+		return 0, nil
+	}
+	return len(files), repo.CommitFiles(ctx, "til: sync", files...)
+}
+
+// rebase rebases onto the upstream branch, resolving the conflicts of
+// entries as it stops on them. The rebase is aborted when it cannot go on.
+func (a *app) rebase(ctx context.Context, out io.Writer, repo *git.Repo, choose gitsync.Chooser) error {
+	err := repo.Rebase(ctx)
+	for err != nil {
+		conflicts, cerr := repo.Conflicts(ctx)
+		if cerr == nil && len(conflicts) == 0 {
+			cerr = err
+		}
+		if cerr == nil {
+			cerr = resolveConflicts(ctx, out, repo, conflicts, choose)
+		}
+		if cerr != nil {
+			if rebasing, _ := repo.Rebasing(ctx); rebasing {
+				if err := repo.Abort(ctx); err != nil {
+					return fmt.Errorf("%w; aborting the rebase failed too: %v", cerr, err)
+				}
+				return fmt.Errorf("sync stopped, nothing was pulled or pushed: %w", cerr)
+			}
+			return cerr
+		}
+		err = repo.Continue(ctx)
+	}
+	return nil
+}
+
+// resolveConflicts merges the conflicting versions of entries and stages
+// the results.
+func resolveConflicts(ctx context.Context, out io.Writer, repo *git.Repo, paths []string, choose gitsync.Chooser) error {
+	for _, p := range paths {
+		if !strings.HasSuffix(p, ".md") {
+			return fmt.Errorf("%s conflicts and is not an entry; sync with git to resolve it", p)
+		}
+		// Rebasing replays the local commits onto the remote ones.
+		local, lok, err := repo.Stage(ctx, git.Theirs, p)
+		if err != nil {
+			return err
+		}
+		remote, rok, err := repo.Stage(ctx, git.Ours, p)
+		if err != nil {
+			return err
+		}
+		if !lok || !rok {
+			return fmt.Errorf("%s was changed on one side and deleted on the other; sync with git to resolve it", p)
+		}
+		base, _, err := repo.Stage(ctx, git.Base, p)
+		if err != nil {
+			return err
+		}
+		merged, err := gitsync.Merge(ctx, repo, p, local, base, remote, choose)
+		if err != nil {
+			return err
+		}
+		if err := fsutil.WriteFile(filepath.Join(repo.Root, filepath.FromSlash(p)), merged, 0o644); err != nil {
+			return err
+		}
+		if err := repo.Add(ctx, p); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "resolved %s\n", p)
+	}
+	return nil
+}
+
+// promptConflict shows both sides of c and asks which to keep.
+func promptConflict(w io.Writer, r *bufio.Reader, c gitsync.Conflict) (gitsync.Choice, error) {
+	fmt.Fprintf(w, "\nConflict in %s\n", c.Path)
+	for _, side := range []struct {
+		name  string
+		lines []string
+	}{{"local", c.Local}, {"remote", c.Remote}} {
+		fmt.Fprintf(w, "--- %s\n", side.name)
+		for _, l := range side.lines {
+			fmt.Fprintf(w, "  %s\n", strings.TrimRight(l, "\r\n"))
+		}
+	}
+	for {
+		answer, err := prompt(w, r, "Keep l local, r remote, b both (q to stop): ")
+		if err != nil {
+			return 0, errors.New("no answer; pass --prefer to sync unattended")
+		}
+		switch answer {
+		case "l":
+			return gitsync.KeepLocal, nil
+		case "r":
+			return gitsync.KeepRemote, nil
+		case "b":
+			return gitsync.KeepBoth, nil
+		case "q":
+			return 0, errQuit
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package cli
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeS3 serves objects from memory to any signed request.
+func fakeS3(t *testing.T) *httptest.Server {
+	t.Helper()
+	var mu sync.Mutex
+	objects := map[string][]byte{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if !strings.Contains(r.Header.Get("Authorization"), "Credential=key/") {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			data, ok := objects[r.URL.Path]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Write(data)
+		case http.MethodPut:
+			objects[r.URL.Path], _ = io.ReadAll(r.Body)
+		case http.MethodDelete:
+			delete(objects, r.URL.Path)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestSyncRemote(t *testing.T) {
+	laptop := newTree(t, map[string]string{"go/slices.md": "# Slices\n"})
+	srv := fakeS3(t)
+	writeConfig(t, "[sync]\nremote = \"s3://notes/til\"\nendpoint = \""+srv.URL+"\"\naccess_key_env = \"TEST_KEY\"\n")
+	t.Setenv("TEST_KEY", "key")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	desktop := t.TempDir()
+
+	if out := mustRun(t, laptop, "sync", "--dry-run"); out != "upload         go/slices.md\ndry run: nothing synced with s3://notes/til\n" {
+		t.Errorf("sync --dry-run = %q", out)
+	}
+	if out := mustRun(t, laptop, "sync"); out != "upload         go/slices.md\nsynced 1 file with s3://notes/til\n" {
+		t.Errorf("sync = %q", out)
+	}
+	if out := mustRun(t, desktop, "sync"); !strings.HasPrefix(out, "download       go/slices.md\n") {
+		t.Errorf("sync on another machine = %q", out)
+	}
+	if got := readFile(t, desktop, "go/slices.md"); got != "# Slices\n" {
+		t.Errorf("downloaded go/slices.md = %q", got)
+	}
+
+	if err := os.Remove(filepath.Join(desktop, "go", "slices.md")); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, desktop, "go/maps.md", "# Maps\n")
+	mustRun(t, desktop, "sync")
+	if out := mustRun(t, laptop, "sync"); out != "download       go/maps.md\ndelete local   go/slices.md\nsynced 2 files with s3://notes/til\n" {
+		t.Errorf("sync after changes elsewhere = %q", out)
+	}
+	if _, err := os.Stat(filepath.Join(laptop, "go", "slices.md")); !os.IsNotExist(err) {
+		t.Error("the deletion did not reach the laptop")
+	}
+	if out := mustRun(t, laptop, "sync"); out != "up to date with s3://notes/til\n" {
+		t.Errorf("sync when up to date = %q", out)
+	}
+
+	t.Setenv("TEST_KEY", "")
+	if _, err := run(t, laptop, "sync"); err == nil || !strings.Contains(err.Error(), "no S3 credentials") {
+		t.Errorf("sync without credentials = %v", err)
+	}
+}
@@ -0,0 +1,149 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/canhta/til/go/internal/tags"
+)
+
+func newTagsCmd(a *app) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "tags",
+		Short: "List, rename, merge and validate tags",
+	}
+	cmd.AddCommand(
+		newTagsListCmd(a),
+		newTagsRenameCmd(a),
+		newTagsMergeCmd(a),
+		newTagsCheckCmd(a),
+	)
+	return cmd
+}
+
+func newTagsListCmd(a *app) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List tags with the number of entries using each",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			entries, err := a.tree.Entries()
+			if err != nil {
+				return err
+			}
+			counts := tags.Counts(entries)
+			return a.output(cmd, counts, func(w io.Writer) error {
+				for _, c := range counts {
+					fmt.Fprintf(w, "%5d  %s\n", c.Count, c.Tag)
+				}
+				return nil
+			})
+		},
+	}
+	return withJSON(cmd, "tags")
+}
+
+func newTagsRenameCmd(a *app) *cobra.Command {
+	var dryRun bool
+	cmd := &cobra.Command{
+		Use:   "rename <old> <new>",
+		Short: "Rename a tag across all entries",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			from, to := args[0], args[1]
+			entries, err := a.tree.Entries()
+			if err != nil {
+				return err
+			}
+			for _, c := range tags.Counts(entries) {
+				if c.Tag == to {
+					return fmt.Errorf("tag %q is already used by %d entries; use til tags merge %s %s", to, c.Count, from, to)
+				}
+			}
+			return a.replaceTags(cmd, []string{from}, to, dryRun)
+		},
+	}
+	cmd.Flags().BoolVarP(&dryRun, "dry-run", "n", false, "show the changes without writing them")
+	return cmd
+}
+
+func newTagsMergeCmd(a *app) *cobra.Command {
+	var dryRun bool
+	cmd := &cobra.Command{
+		Use:   "merge <tag>... <into>",
+		Short: "Merge one or more tags into another",
+		Args:  cobra.MinimumNArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return a.replaceTags(cmd, args[:len(args)-1], args[len(args)-1], dryRun)
+		},
+	}
+	cmd.Flags().BoolVarP(&dryRun, "dry-run", "n", false, "show the changes without writing them")
+	return cmd
+}
+
+func (a *app) replaceTags(cmd *cobra.Command, from []string, to string, dryRun bool) error {
+	allow, err := tags.LoadAllowlist(a.tree)
+	if err != nil {
+		return err
+	}
+	if u := allow.Unknown([]string{to}); len(u) > 0 {
+		return fmt.Errorf("tag %q is not in the allowlist %s", to, a.tree.StatePath(tags.AllowlistFile))
+	}
+	entries, err := a.tree.Entries()
+	if err != nil {
+		return err
+	}
+	replace := func(t []string) []string { return tags.Replace(t, from, to) }
+	out := cmd.OutOrStdout()
+	if dryRun {
+		for _, e := range entries {
+			if next := replace(e.Meta.Tags); !slices.Equal(next, e.Meta.Tags) {
+				fmt.Fprintf(out, "%s: [%s] -> [%s]\n", e.Path, strings.Join(e.Meta.Tags, ", "), strings.Join(next, ", "))
+			}
+		}
+		return nil
+	}
+	changes, err := tags.Rewrite(a.tree, entries, replace)
+	if err != nil {
+		return err
+	}
+	for _, c := range changes {
+		fmt.Fprintf(out, "%s: [%s] -> [%s]\n", c.Path, strings.Join(c.Old, ", "), strings.Join(c.New, ", "))
+	}
+	fmt.Fprintf(out, "%d entries updated\n", len(changes))
+	return nil
+}
+
+func newTagsCheckCmd(a *app) *cobra.Command {
+	return &cobra.Command{
+		Use:   "check",
+		Short: "Report tags missing from the allowlist (.til/tags.txt)",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			allow, err := tags.LoadAllowlist(a.tree)
+			if err != nil {
+				return err
+			}
+			if allow == nil {
+				return fmt.Errorf("no allowlist at %s", a.tree.StatePath(tags.AllowlistFile))
+			}
+			entries, err := a.tree.Entries()
+			if err != nil {
+				return err
+			}
+			vs := allow.Check(entries)
+			for _, v := range vs {
+				fmt.Fprintf(cmd.OutOrStdout(), "%s: unknown tags %s\n", v.Path, strings.Join(v.Tags, ", "))
+			}
+			if len(vs) > 0 {
+				return &exitError{code: 1, err: errors.New("entries use tags outside the allowlist")}
+			}
+			return nil
+		},
+	}
+}
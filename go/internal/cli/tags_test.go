@@ -0,0 +1,44 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTags(t *testing.T) {
+	root := newTree(t, map[string]string{
+		"go/a.md": "---\ntitle: A\ntags: [golang, web]\n---\n",
+		"go/b.md": "---\ntitle: B\ntags: [go]\n---\n",
+		"js/c.md": "---\ntitle: C\ntags: [js, web, ts]\n---\n",
+	})
+	if out := mustRun(t, root, "tags", "list"); out != "    2  web\n    1  go\n    1  golang\n    1  js\n    1  ts\n" {
+		t.Errorf("tags list =\n%s", out)
+	}
+	if _, err := run(t, root, "tags", "rename", "golang", "go"); err == nil || !strings.Contains(err.Error(), "til tags merge golang go") {
+		t.Errorf("tags rename onto a used tag = %v", err)
+	}
+	if out := mustRun(t, root, "tags", "merge", "golang", "go", "-n"); out != "go/a.md: [golang, web] -> [go, web]\n" {
+		t.Errorf("tags merge -n = %q", out)
+	}
+	if got := readFile(t, root, "go/a.md"); !strings.Contains(got, "tags: [golang, web]") {
+		t.Errorf("tags merge -n wrote go/a.md:\n%s", got)
+	}
+	if out := mustRun(t, root, "tags", "merge", "js", "ts", "javascript"); out != "js/c.md: [js, web, ts] -> [javascript, web]\n1 entries updated\n" {
+		t.Errorf("tags merge = %q", out)
+	}
+	if out := mustRun(t, root, "tags", "rename", "web", "www"); !strings.HasSuffix(out, "2 entries updated\n") {
+		t.Errorf("tags rename = %q", out)
+	}
+	if got := readFile(t, root, "js/c.md"); got != "---\ntitle: C\ntags: [javascript, www]\n---\n" {
+		t.Errorf("js/c.md = %q", got)
+	}
+
+	writeFile(t, root, ".til/tags.txt", "go\nwww\n")
+	out, err := run(t, root, "tags", "check")
+	if err == nil || !strings.Contains(out, "go/a.md: unknown tags golang\n") || !strings.Contains(out, "js/c.md: unknown tags javascript\n") {
+		t.Errorf("tags check = %v\n%s", err, out)
+	}
+	if _, err := run(t, root, "tags", "rename", "golang", "golang2"); err == nil || !strings.Contains(err.Error(), "not in the allowlist") {
+		t.Errorf("tags rename to an unknown tag = %v", err)
+	}
+}
@@ -0,0 +1,54 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/canhta/til/go/pkg/render"
+)
+
+func newTOCCmd(a *app) *cobra.Command {
+	var markdown bool
+	cmd := &cobra.Command{
+		Use:   "toc <entry>",
+		Short: "Print the table of contents of an entry",
+		Long: `Toc lists the headings of an entry below its title, indented by level,
+with the anchors they get on the site. --markdown prints them as a list of
+links to paste into the entry.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			e, err := a.resolve(args[0])
+			if err != nil {
+				return err
+			}
+			// Anchors are counted as on the site, where the title heading is
+			// not part of the content.
+			body := render.StripTitle(e.Body)
+			skipped := bytes.Count(e.Body[:len(e.Body)-len(body)], []byte("\n"))
+			headings := render.Headings(body)
+			top := 6
+			for i := range headings {
+				headings[i].Line = e.FileLine(headings[i].Line + skipped)
+				top = min(top, headings[i].Level)
+			}
+			return a.output(cmd, headings, func(w io.Writer) error {
+				for _, h := range headings {
+					indent := strings.Repeat("  ", h.Level-top)
+					if markdown {
+						fmt.Fprintf(w, "%s- [%s](#%s)\n", indent, h.Text, h.ID)
+					} else {
+						fmt.Fprintf(w, "%s%s  #%s\n", indent, h.Text, h.ID)
+					}
+				}
+				return nil
+			})
+		},
+	}
+	withJSON(cmd, "toc")
+	cmd.Flags().BoolVar(&markdown, "markdown", false, "print a markdown list of links")
+	return cmd
+}
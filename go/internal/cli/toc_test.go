@@ -0,0 +1,36 @@
+package cli
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestTOC(t *testing.T) {
+	root := newTree(t, map[string]string{
+		"go/slices.md": "---\ntitle: Slices\n---\n# Slices\n\n## Setup\n\n### Make\n\n```\n## not a heading\n```\n\n## Setup\n\n## Pitfalls & gotchas\n",
+	})
+	want := "Setup  #setup\n  Make  #make\nSetup  #setup-1\nPitfalls & gotchas  #pitfalls-gotchas\n"
+	if out := mustRun(t, root, "toc", "slices"); out != want {
+		t.Errorf("toc slices =\n%s\nwant\n%s", out, want)
+	}
+	want = "- [Setup](#setup)\n  - [Make](#make)\n- [Setup](#setup-1)\n- [Pitfalls & gotchas](#pitfalls-gotchas)\n"
+	if out := mustRun(t, root, "toc", "go/slices.md", "--markdown"); out != want {
+		t.Errorf("toc --markdown =\n%s\nwant\n%s", out, want)
+	}
+
+	var doc struct {
+		Kind string
+		Data []struct {
+			Level int
+			Text  string
+			ID    string
+			Line  int
+		}
+	}
+	if err := json.Unmarshal([]byte(mustRun(t, root, "toc", "slices", "--json")), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.Kind != "toc" || len(doc.Data) != 4 || doc.Data[0].Line != 6 || doc.Data[1].Line != 8 || doc.Data[3].Line != 16 {
+		t.Errorf("toc --json = %+v, want file lines", doc)
+	}
+}
@@ -0,0 +1,70 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/canhta/til/go/internal/query"
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+func newTodayCmd(a *app) *cobra.Command {
+	var (
+		day      string
+		gitDates bool
+	)
+	cmd := &cobra.Command{
+		Use:   "today",
+		Short: "List entries written on this day in earlier years",
+		Long: `Today lists the entries created on today's calendar day in previous
+years, oldest first. --date picks another day.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			now := time.Now()
+			if day != "" {
+				d, err := time.ParseInLocation(entry.DateLayout, day, now.Location())
+				if err != nil {
+					return fmt.Errorf("--date: want YYYY-MM-DD: %w", err)
+				}
+				now = d
+			}
+			entries, err := a.datedEntries(cmd.Context(), gitDates)
+			if err != nil {
+				return err
+			}
+			entries = query.Filter(entries, query.OnThisDay{Day: now})
+			if err := query.Sort(entries, "created", true); err != nil {
+				return err
+			}
+			out := make([]entry.Summary, len(entries))
+			for i, e := range entries {
+				out[i] = entry.Summarize(e)
+			}
+			return a.output(cmd, out, func(w io.Writer) error {
+				if len(entries) == 0 {
+					fmt.Fprintf(w, "Nothing written on %s in earlier years.\n", now.Format("January 2"))
+					return nil
+				}
+				return writeOnThisDay(w, entries, now)
+			})
+		},
+	}
+	withJSON(cmd, "entries")
+	cmd.Flags().StringVar(&day, "date", "", "list the entries of this day's date instead (YYYY-MM-DD)")
+	cmd.Flags().BoolVar(&gitDates, "git-dates", false, "date entries by their first and last commit")
+	return cmd
+}
+
+func writeOnThisDay(w io.Writer, entries []*entry.Entry, now time.Time) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "AGO\tCREATED\tTITLE\tPATH")
+	for _, e := range entries {
+		years := now.Year() - e.Created().Year()
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", plural(years, "year"), e.Created().Format(entry.DateLayout), e.Meta.Title, e.Path)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,43 @@
+package cli
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+func TestToday(t *testing.T) {
+	ago := func(years int) string { return time.Now().AddDate(-years, 0, 0).Format(entry.DateLayout) }
+	root := newTree(t, map[string]string{
+		"go/old.md":    "---\ntitle: Old\ndate: " + ago(3) + "\n---\n",
+		"go/recent.md": "---\ntitle: Recent\ndate: " + ago(1) + "\n---\n",
+		"go/now.md":    "---\ntitle: Now\ndate: " + ago(0) + "\n---\n",
+		"go/other.md":  "---\ntitle: Other\ndate: 2020-01-01\n---\n",
+		"go/leap.md":   "---\ntitle: Leap\ndate: 2020-02-29\n---\n",
+	})
+	var doc struct {
+		Data []entry.Summary
+	}
+	if err := json.Unmarshal([]byte(mustRun(t, root, "today", "--json")), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Data) != 2 || doc.Data[0].Path != "go/old.md" || doc.Data[1].Path != "go/recent.md" {
+		t.Errorf("today --json = %+v, want the earlier years' entries oldest first", doc.Data)
+	}
+
+	want := "AGO      CREATED     TITLE  PATH\n4 years  2020-01-01  Other  go/other.md\n"
+	if out := mustRun(t, root, "today", "--date", "2024-01-01"); out != want {
+		t.Errorf("today --date 2024-01-01 =\n%s\nwant\n%s", out, want)
+	}
+	if out := mustRun(t, root, "today", "--date", "2023-02-28"); out != "AGO      CREATED     TITLE  PATH\n3 years  2020-02-29  Leap   go/leap.md\n" {
+		t.Errorf("today --date 2023-02-28 =\n%s", out)
+	}
+	if out := mustRun(t, root, "today", "--date", "2019-01-01"); out != "Nothing written on January 1 in earlier years.\n" {
+		t.Errorf("today --date 2019-01-01 =\n%s", out)
+	}
+	if _, err := run(t, root, "today", "--date", "yesterday"); err == nil {
+		t.Error("today --date yesterday succeeded")
+	}
+}
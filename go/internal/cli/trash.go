@@ -0,0 +1,152 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/canhta/til/go/internal/assets"
+	"github.com/canhta/til/go/internal/trash"
+)
+
+func newRmCmd(a *app) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "rm <entry>...",
+		Short: "Move entries and their assets to the trash",
+		Long: `Rm moves entries, with their assets, out of the tree and into .trash at
+the notes root, from where til restore brings them back. Removed entries
+are kept for [trash] retention in the config file, 30 days by default,
+and purged after by til rm and til restore.`,
+		Example: `  til rm go/old-gopath
+  til restore go/old-gopath`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			files, err := a.tree.Files()
+			if err != nil {
+				return err
+			}
+			var entries, paths []string
+			for _, ref := range args {
+				e, err := a.resolve(ref)
+				if err != nil {
+					return err
+				}
+				if slices.Contains(entries, e.Path) {
+					continue
+				}
+				entries = append(entries, e.Path)
+				paths = append(paths, e.Path)
+				dir := assets.DirFor(e.Path) + "/"
+				for _, f := range files {
+					if strings.HasPrefix(f.Path, dir) {
+						paths = append(paths, f.Path)
+					}
+				}
+			}
+			now := time.Now()
+			if err := trash.Put(a.tree, paths, now); err != nil {
+				return err
+			}
+			for _, p := range entries {
+				fmt.Fprintf(cmd.OutOrStdout(), "removed %s\n", p)
+			}
+			a.purgeTrash(cmd, now)
+			return a.commitEntry(cmd, paths[0], "remove", paths[1:]...)
+		},
+	}
+	a.commitFlag(cmd)
+	return cmd
+}
+
+func newRestoreCmd(a *app) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "restore [entry...]",
+		Short: "Bring entries back from the trash",
+		Long: `Restore puts entries removed with til rm back where they were, with their
+assets. An entry removed more than once comes back as it last was. Without
+arguments, restore lists the entries in the trash, most recently removed
+first, with the day each is purged on.`,
+		Example: `  til restore
+  til restore go/old-gopath`,
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if a.tree == nil {
+				if err := a.init(); err != nil {
+					return nil, cobra.ShellCompDirectiveError
+				}
+			}
+			items, err := trash.List(a.tree)
+			if err != nil {
+				return nil, cobra.ShellCompDirectiveError
+			}
+			var out []string
+			for _, it := range items {
+				out = append(out, strings.TrimSuffix(it.Path, ".md")+"\t"+it.Title)
+			}
+			return out, cobra.ShellCompDirectiveNoFileComp
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			now := time.Now()
+			a.purgeTrash(cmd, now)
+			items, err := trash.List(a.tree)
+			if err != nil {
+				return err
+			}
+			if len(args) == 0 {
+				return a.output(cmd, items, func(w io.Writer) error {
+					if len(items) == 0 {
+						fmt.Fprintln(w, "the trash is empty")
+						return nil
+					}
+					tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+					fmt.Fprintln(tw, "PATH\tTITLE\tREMOVED\tPURGED")
+					for _, it := range items {
+						fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", it.Path, it.Title,
+							it.Removed.Local().Format("2006-01-02 15:04"),
+							it.Removed.Add(a.trashRetention()).Local().Format("2006-01-02"))
+					}
+					return tw.Flush()
+				})
+			}
+			var restored []string
+			for _, ref := range args {
+				it, ok := trash.Find(items, ref)
+				if !ok {
+					return fmt.Errorf("%s is not in the trash", ref)
+				}
+				if err := trash.Restore(a.tree, it); err != nil {
+					return err
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "restored %s\n", it.Path)
+				restored = append(restored, it.Files...)
+			}
+			return a.commitEntry(cmd, restored[0], "restore", restored[1:]...)
+		},
+	}
+	withJSON(cmd, "trash")
+	a.commitFlag(cmd)
+	return cmd
+}
+
+// trashRetention returns how long removed entries are kept.
+func (a *app) trashRetention() time.Duration {
+	if d := a.cfg.Trash.Retention.Duration; d > 0 {
+		return d
+	}
+	return trash.Retention
+}
+
+// purgeTrash deletes the removals older than the retention period,
+// warning of a failure rather than failing.
+func (a *app) purgeTrash(cmd *cobra.Command, now time.Time) {
+	n, err := trash.Purge(a.tree, a.trashRetention(), now)
+	if err != nil {
+		fmt.Fprintf(cmd.ErrOrStderr(), "warning: purging the trash: %v\n", err)
+	} else if n > 0 {
+		fmt.Fprintf(cmd.ErrOrStderr(), "purged %d entries from the trash\n", n)
+	}
+}
@@ -0,0 +1,125 @@
+package cli
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRmAndRestore(t *testing.T) {
+	root := newTree(t, map[string]string{
+		"go/slices.md":           "---\ntitle: Slices\n---\n\n![d](assets/slices/d.svg)\n",
+		"go/assets/slices/d.svg": "<svg/>",
+		"go/maps.md":             "---\ntitle: Maps\n---\n",
+		"git/rebase.md":          "---\ntitle: Rebase\n---\n",
+	})
+	if out := mustRun(t, root, "restore"); out != "the trash is empty\n" {
+		t.Errorf("restore of an empty trash = %q", out)
+	}
+	if out := mustRun(t, root, "rm", "go/slices", "slices", "go/maps.md"); out != "removed go/slices.md\nremoved go/maps.md\n" {
+		t.Errorf("rm = %q", out)
+	}
+	mustRun(t, root, "rm", "git/rebase.md")
+	for _, p := range []string{"go/slices.md", "go/assets/slices/d.svg", "go/maps.md"} {
+		if _, err := os.Stat(filepath.Join(root, p)); err == nil {
+			t.Errorf("%s still in the tree", p)
+		}
+	}
+	if out := mustRun(t, root, "list"); strings.Contains(out, "slices") {
+		t.Errorf("list shows a removed entry:\n%s", out)
+	}
+
+	out := mustRun(t, root, "restore")
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 4 || !strings.HasPrefix(lines[0], "PATH") || !strings.HasPrefix(lines[1], "git/rebase.md  Rebase") {
+		t.Errorf("restore =\n%s", out)
+	}
+	var doc struct {
+		Kind string
+		Data []struct {
+			Path  string
+			Files []string
+		}
+	}
+	if err := json.Unmarshal([]byte(mustRun(t, root, "restore", "--json")), &doc); err != nil || doc.Kind != "trash" || len(doc.Data) != 3 {
+		t.Fatalf("restore --json = %+v, %v", doc, err)
+	}
+	if d := doc.Data[2]; d.Path != "go/slices.md" || len(d.Files) != 2 {
+		t.Errorf("trash item = %+v", d)
+	}
+
+	if out := mustRun(t, root, "restore", "slices"); out != "restored go/slices.md\n" {
+		t.Errorf("restore slices = %q", out)
+	}
+	if readFile(t, root, "go/assets/slices/d.svg") != "<svg/>" {
+		t.Error("assets not restored")
+	}
+	if _, err := run(t, root, "restore", "slices"); err == nil || err.Error() != "slices is not in the trash" {
+		t.Errorf("restore slices again = %v", err)
+	}
+	writeFile(t, root, "go/maps.md", "---\ntitle: New maps\n---\n")
+	if _, err := run(t, root, "restore", "go/maps"); err == nil || !strings.Contains(err.Error(), "go/maps.md exists") {
+		t.Errorf("restore over an entry = %v", err)
+	}
+
+	// Removals past the retention are purged.
+	writeConfig(t, "[trash]\nretention = \"1ns\"\n")
+	out = mustRun(t, root, "restore")
+	if !strings.Contains(out, "purged 2 entries from the trash\n") || !strings.Contains(out, "the trash is empty\n") {
+		t.Errorf("restore after the retention =\n%s", out)
+	}
+}
+
+func TestRmCommits(t *testing.T) {
+	root := newTree(t, map[string]string{
+		"go/slices.md":           "---\ntitle: Slices\n---\n",
+		"go/assets/slices/d.svg": "<svg/>",
+	})
+	initGit(t, root)
+	writeConfig(t, "[git]\ncommit = true\n")
+	mustRun(t, root, "rm", "go/slices")
+	if got := runGit(t, root, "log", "-1", "--format=%s", "--name-status"); !strings.Contains(got, "til: remove go/slices.md") || !strings.Contains(got, "D\tgo/assets/slices/d.svg") || !strings.Contains(got, "D\tgo/slices.md") {
+		t.Errorf("commit of rm =\n%s", got)
+	}
+	// The trash ignores itself.
+	if got := runGit(t, root, "status", "--porcelain"); strings.Contains(got, "go/") || strings.Contains(got, ".trash") {
+		t.Errorf("git status after rm =\n%s", got)
+	}
+	mustRun(t, root, "restore", "go/slices")
+	if got := runGit(t, root, "log", "-1", "--format=%s", "--name-status"); !strings.Contains(got, "A\tgo/slices.md") {
+		t.Errorf("commit of restore =\n%s", got)
+	}
+}
+
+func TestArchive(t *testing.T) {
+	root := newTree(t, map[string]string{
+		"go/slices.md": "---\ntitle: Slices\ntags: [go]\n---\n",
+		"go/maps.md":   "---\ntitle: Maps\ntags: [go]\n---\n",
+	})
+	if out := mustRun(t, root, "archive", "go/slices", "slices"); out != "archived go/slices.md\n" {
+		t.Errorf("archive = %q", out)
+	}
+	if got := readFile(t, root, "go/slices.md"); got != "---\ntitle: Slices\ntags: [go]\narchived: true\n---\n" {
+		t.Errorf("go/slices.md =\n%s", got)
+	}
+	if out := mustRun(t, root, "archive", "go/slices"); out != "go/slices.md is already archived\n" {
+		t.Errorf("archive again = %q", out)
+	}
+	if out := mustRun(t, root, "list"); strings.Contains(out, "slices") || !strings.Contains(out, "maps") {
+		t.Errorf("list =\n%s", out)
+	}
+	if out := mustRun(t, root, "list", "--archived"); !strings.Contains(out, "slices") {
+		t.Errorf("list --archived =\n%s", out)
+	}
+	if out := mustRun(t, root, "search", "slices"); strings.Contains(out, "go/slices.md") {
+		t.Errorf("search =\n%s", out)
+	}
+	if out := mustRun(t, root, "archive", "--undo", "go/slices", "go/maps"); out != "go/maps.md is already unarchived\nunarchived go/slices.md\n" {
+		t.Errorf("archive --undo = %q", out)
+	}
+	if got := readFile(t, root, "go/slices.md"); got != "---\ntitle: Slices\ntags: [go]\n---\n" {
+		t.Errorf("go/slices.md =\n%s", got)
+	}
+}
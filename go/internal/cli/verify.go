@@ -0,0 +1,113 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/canhta/til/go/internal/verify"
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+func newVerifyCmd(a *app) *cobra.Command {
+	var (
+		jobs      int
+		timeout   time.Duration
+		quiet     bool
+		noSandbox bool
+	)
+	cmd := &cobra.Command{
+		Use:   "verify [entry...]",
+		Short: "Run every code block and check it against its recorded output",
+		Long: `Verify runs every code block that has a runner, across all entries or the
+given ones, and compares its output with an immediately following ` + "```output" + `
+block when one is present, ignoring trailing whitespace. It prints a diff
+for each block whose output differs and a summary, and exits non-zero when
+any block fails to build, fails to run or produces different output.
+
+Unlike til run, verify runs every block in the sandbox of [sandbox] in the
+config file, with its backend and limits, whether or not it is enabled:
+the blocks of the whole tree are run, unread. --no-sandbox runs them
+unconfined, as til run does.
+
+til run --update records the output of the blocks again.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			entries, err := a.entriesOrAll(args)
+			if err != nil {
+				return err
+			}
+			reg, err := a.registry(timeout, !noSandbox)
+			if err != nil {
+				return err
+			}
+			out := cmd.OutOrStdout()
+			start := time.Now()
+			rep := verify.Run(cmd.Context(), entries, verify.Options{
+				Registry: reg,
+				Tree:     a.tree,
+				Jobs:     jobs,
+				Progress: func(c *verify.Case) {
+					if !quiet {
+						fmt.Fprintf(out, "%-8s %s\n", strings.ToUpper(string(c.Status)), caseLocation(c))
+					}
+				},
+			})
+			printReport(out, rep, time.Since(start))
+			if !rep.OK() {
+				return &exitError{code: 1, err: fmt.Errorf("%d of %d blocks failed verification", len(rep.Cases)-rep.Count(verify.Pass), len(rep.Cases))}
+			}
+			return nil
+		},
+	}
+	cmd.Flags().IntVarP(&jobs, "jobs", "j", 0, "blocks to run concurrently (default: number of CPUs)")
+	cmd.Flags().DurationVar(&timeout, "timeout", 0, "limit for each build and run step (default from config, else 30s)")
+	cmd.Flags().BoolVarP(&quiet, "quiet", "q", false, "print only the summary")
+	noSandboxFlag(cmd, &noSandbox)
+	return cmd
+}
+
+// entriesOrAll resolves refs, or loads every entry when refs is empty.
+func (a *app) entriesOrAll(refs []string) ([]*entry.Entry, error) {
+	if len(refs) == 0 {
+		return a.tree.Entries()
+	}
+	entries := make([]*entry.Entry, 0, len(refs))
+	for _, ref := range refs {
+		e, err := a.resolve(ref)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+func caseLocation(c *verify.Case) string {
+	b := c.Snippet.Code
+	if b.File != "" {
+		return fmt.Sprintf("%s (source of %s, %s)", b.File, c.Entry.Path, b.Lang)
+	}
+	return fmt.Sprintf("%s:%d (block %d, %s)", c.Entry.Path, c.Entry.FileLine(b.Line), b.Index+1, b.Lang)
+}
+
+func printReport(w io.Writer, rep *verify.Report, elapsed time.Duration) {
+	for _, c := range rep.Cases {
+		if c.Status == verify.Pass {
+			continue
+		}
+		fmt.Fprintf(w, "\n--- %s %s\n", strings.ToUpper(string(c.Status)), caseLocation(c))
+		switch c.Status {
+		case verify.Fail:
+			fmt.Fprintf(w, "%s: %v\n", c.Result.Phase, c.Result.Err)
+			w.Write(c.Result.Output)
+		case verify.Mismatch:
+			fmt.Fprintf(w, "--- expected\n+++ actual\n%s", verify.Diff(c.Expected, c.Actual))
+		}
+	}
+	fmt.Fprintf(w, "\n%d entries, %d blocks: %d passed, %d failed, %d mismatched, %d skipped (%s)\n",
+		rep.Entries, len(rep.Cases), rep.Count(verify.Pass), rep.Count(verify.Fail), rep.Count(verify.Mismatch),
+		rep.Skipped, elapsed.Round(time.Millisecond))
+}
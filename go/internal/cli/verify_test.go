@@ -0,0 +1,57 @@
+package cli
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestVerifySandboxesByDefault(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not installed")
+	}
+	root := newTree(t, map[string]string{
+		"sh/echo.md": "# Echo\n\n```sh\necho hi\n```\n\n```output\nhi\n```\n",
+	})
+	// The none backend cannot take the network away, so confined blocks
+	// fail to run.
+	writeConfig(t, "[sandbox]\nbackend = \"none\"\n")
+	out, err := run(t, root, "verify")
+	if err == nil || !strings.Contains(out, "no backend to take the network away") {
+		t.Errorf("verify = %v\n%s", err, out)
+	}
+	if out := mustRun(t, root, "verify", "--no-sandbox"); !strings.Contains(out, "1 passed") {
+		t.Errorf("verify --no-sandbox:\n%s", out)
+	}
+	if out := mustRun(t, root, "run", "echo"); !strings.Contains(out, "hi") {
+		t.Errorf("run:\n%s", out)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not installed")
+	}
+	root := newTree(t, map[string]string{
+		"sh/ok.md":  "# OK\n\n```sh\necho hi\n```\n\n```output\nhi\n```\n",
+		"sh/bad.md": "# Bad\n\n```sh\necho two\n```\n\n```output\none\n```\n",
+	})
+	out, err := run(t, root, "verify", "--no-sandbox", "-q")
+	if err == nil || err.Error() != "1 of 2 blocks failed verification" {
+		t.Errorf("verify = %v", err)
+	}
+	for _, want := range []string{
+		"\n--- MISMATCH sh/bad.md:3 (block 1, sh)\n--- expected\n+++ actual\n-one\n+two\n",
+		"\n2 entries, 2 blocks: 1 passed, 0 failed, 1 mismatched, 0 skipped (",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("verify:\n%s\nwant %q", out, want)
+		}
+	}
+	if strings.Contains(out, "PASS") {
+		t.Errorf("verify -q printed progress:\n%s", out)
+	}
+	if out := mustRun(t, root, "verify", "--no-sandbox", "ok"); !strings.Contains(out, "PASS     sh/ok.md:3 (block 1, sh)\n") {
+		t.Errorf("verify ok:\n%s", out)
+	}
+}
@@ -0,0 +1,100 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+
+	"github.com/canhta/til/go/internal/walk"
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+func newWalkCmd(a *app) *cobra.Command {
+	var (
+		block   int
+		noPause bool
+		timeout time.Duration
+		sandbox bool
+	)
+	cmd := &cobra.Command{
+		Use:   "walk <entry>",
+		Short: "Step through a code block of an entry",
+		Long: `Walk tours a code block step by step. Lines holding only a // --- comment
+(# --- in Python and shell) split the block into steps; each step runs
+with the code of the steps before it, and walk shows the step's code and
+what it printed beyond what they did, pausing for Enter in between. q
+stops the walk.
+
+The block is the first runnable one with step markers, or the one --block
+picks. Walk does not pause when stdin is not a terminal, or with
+--no-pause.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			e, err := a.resolve(args[0])
+			if err != nil {
+				return err
+			}
+			reg, err := a.registry(timeout, sandbox)
+			if err != nil {
+				return err
+			}
+			var runnable []entry.Snippet
+			for _, s := range entry.Snippets(e.Body) {
+				if reg.Runnable(s.Code) && (block == 0 || s.Code.Index+1 == block) {
+					runnable = append(runnable, s)
+				}
+			}
+			if len(runnable) == 0 {
+				return fmt.Errorf("%s: no runnable code blocks", e.Path)
+			}
+			s := runnable[0]
+			if i := slices.IndexFunc(runnable, func(s entry.Snippet) bool {
+				return slices.ContainsFunc(strings.Split(s.Code.Code, "\n"), walk.IsMarker)
+			}); i >= 0 {
+				s = runnable[i]
+			}
+
+			out := cmd.OutOrStdout()
+			pause := !noPause && term.IsTerminal(int(os.Stdin.Fd()))
+			in := bufio.NewReader(cmd.InOrStdin())
+			steps := walk.Steps(s.Code.Code)
+			prev := ""
+			for i, st := range steps {
+				line := e.FileLine(s.Code.Line) + st.Line
+				fmt.Fprintf(out, "── step %d of %d, %s:%d\n", i+1, len(steps), e.Path, line)
+				for _, l := range strings.Split(strings.TrimRight(st.Code, "\n"), "\n") {
+					fmt.Fprintf(out, "  %s\n", l)
+				}
+				b := s.Code
+				b.Code = st.Program
+				res := reg.Run(cmd.Context(), e, entry.Snippet{Code: b, Module: s.Module})
+				output := string(res.Output)
+				fmt.Fprintln(out, "▶")
+				out.Write([]byte(walk.Since(prev, output)))
+				if res.Failed() {
+					return fmt.Errorf("step %d: %s: %w", i+1, res.Phase, res.Err)
+				}
+				prev = output
+				if pause && i < len(steps)-1 {
+					fmt.Fprint(cmd.ErrOrStderr(), "[Enter] next step, [q] quit ")
+					answer, err := in.ReadString('\n')
+					if err != nil || strings.EqualFold(strings.TrimSpace(answer), "q") {
+						return nil
+					}
+				}
+			}
+			return nil
+		},
+	}
+	cmd.Flags().IntVarP(&block, "block", "b", 0, "walk the Nth code block (1-based, counting all fences)")
+	cmd.Flags().BoolVar(&noPause, "no-pause", false, "run every step without waiting for Enter")
+	cmd.Flags().DurationVar(&timeout, "timeout", 0, "limit for each build and run step (default from config, else 30s)")
+	sandboxFlag(cmd, &sandbox)
+	return cmd
+}
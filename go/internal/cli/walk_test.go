@@ -0,0 +1,34 @@
+package cli
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestWalk(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not installed")
+	}
+	root := newTree(t, map[string]string{
+		"sh/tour.md": "---\ntitle: Tour\n---\n\n```sh\necho plain\n```\n\n```sh\nx=1\necho \"one $x\"\n# ---\nx=2\necho \"two $x\"\n# ---\nexit 3\n```\n",
+	})
+	out, err := run(t, root, "walk", "tour", "--no-pause")
+	if err == nil || !strings.Contains(err.Error(), "step 3: run: ") {
+		t.Errorf("walk = %v", err)
+	}
+	want := "── step 1 of 3, sh/tour.md:10\n  x=1\n  echo \"one $x\"\n▶\none 1\n" +
+		"── step 2 of 3, sh/tour.md:13\n  x=2\n  echo \"two $x\"\n▶\ntwo 2\n" +
+		"── step 3 of 3, sh/tour.md:16\n  exit 3\n▶\n"
+	if !strings.HasPrefix(out, want) {
+		t.Errorf("walk =\n%s\nwant\n%s", out, want)
+	}
+
+	out = mustRun(t, root, "walk", "tour", "--block", "1")
+	if want := "── step 1 of 1, sh/tour.md:6\n  echo plain\n▶\nplain\n"; out != want {
+		t.Errorf("walk --block 1 =\n%s\nwant\n%s", out, want)
+	}
+	if _, err := run(t, root, "walk", "tour", "--block", "4"); err == nil || !strings.Contains(err.Error(), "no runnable code blocks") {
+		t.Errorf("walk --block 4 = %v", err)
+	}
+}
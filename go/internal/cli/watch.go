@@ -0,0 +1,290 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/canhta/til/go/internal/lint"
+	"github.com/canhta/til/go/internal/runner"
+	"github.com/canhta/til/go/internal/search"
+	"github.com/canhta/til/go/internal/site"
+	"github.com/canhta/til/go/internal/store"
+	"github.com/canhta/til/go/internal/verify"
+	"github.com/canhta/til/go/pkg/entry"
+	"github.com/canhta/til/go/pkg/hook"
+)
+
+// watchDebounce coalesces the bursts of file events editors make on save
+// into one round of work.
+const watchDebounce = 200 * time.Millisecond
+
+func newWatchCmd(a *app) *cobra.Command {
+	var (
+		opts      site.Options
+		noBuild   bool
+		noVerify  bool
+		noLint    bool
+		timeout   time.Duration
+		noSandbox bool
+	)
+	cmd := &cobra.Command{
+		Use:   "watch",
+		Short: "Re-index, rebuild and check entries as they change",
+		Long: `Watch observes the notes tree until interrupted. Whenever entries change it
+brings the search index up to date, rebuilds the site as til build does,
+re-rendering only what changed, and lints the changed entries and verifies
+their code blocks as til lint and til verify do, in the sandbox unless
+--no-sandbox is given. It prints one line for each step and the problems
+found, so it can run in a terminal beside the editor.`,
+		Example: `  til watch
+  til watch --no-build --no-verify`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			w := &watcher{
+				a:      a,
+				log:    log.New(cmd.OutOrStdout(), "", log.Ltime),
+				verify: !noVerify,
+				lint:   !noLint,
+			}
+			if !noBuild {
+				if err := a.siteOptions(&opts); err != nil {
+					return err
+				}
+				b, err := site.NewBuilder(a.tree, opts)
+				if err != nil {
+					return err
+				}
+				w.builder, w.out = b, opts.Out
+			}
+			if !noVerify {
+				var err error
+				if w.registry, err = a.registry(timeout, !noSandbox); err != nil {
+					return err
+				}
+			}
+			watchCtx, stop := context.WithCancel(ctx)
+			defer stop()
+			events, err := a.tree.Store.Watch(watchCtx)
+			if err != nil {
+				return err
+			}
+			w.index(ctx)
+			w.build(ctx)
+			w.log.Printf("watching %s", a.tree.Root)
+			w.loop(ctx, events)
+			return nil
+		},
+	}
+	siteFlags(cmd.Flags(), &opts)
+	cmd.Flags().BoolVar(&noBuild, "no-build", false, "do not rebuild the site")
+	cmd.Flags().BoolVar(&noVerify, "no-verify", false, "do not verify the code blocks of changed entries")
+	cmd.Flags().BoolVar(&noLint, "no-lint", false, "do not lint changed entries")
+	cmd.Flags().DurationVar(&timeout, "timeout", 0, "limit for each build and run step of verification (default from config, else 30s)")
+	noSandboxFlag(cmd, &noSandbox)
+	return cmd
+}
+
+// watcher runs the work of til watch after each change.
+type watcher struct {
+	a   *app
+	log *log.Logger
+	// builder rebuilds the site into out; nil with --no-build.
+	builder  *site.Builder
+	out      string
+	registry *runner.Registry
+	verify   bool
+	lint     bool
+}
+
+func (w *watcher) loop(ctx context.Context, events <-chan store.Event) {
+	var (
+		timer   *time.Timer
+		pending <-chan time.Time
+		touched = map[string]bool{}
+	)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ev, ok := <-events:
+			if !ok {
+				return
+			}
+			if w.ignored(ev.Path) {
+				continue
+			}
+			touched[ev.Path] = !ev.Removed
+			if timer == nil {
+				timer = time.NewTimer(watchDebounce)
+			} else {
+				timer.Reset(watchDebounce)
+			}
+			pending = timer.C
+		case <-pending:
+			pending = nil
+			w.changed(ctx, touched)
+			clear(touched)
+		}
+	}
+}
+
+// ignored reports whether the file at the slash-separated path p lies in
+// the build output.
+func (w *watcher) ignored(p string) bool {
+	if w.builder == nil {
+		return false
+	}
+	rel, err := w.a.tree.Rel(w.out)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, "../") {
+		return false
+	}
+	return p == rel || strings.HasPrefix(p, rel+"/")
+}
+
+// changed does the work for the files touched, mapped to whether they
+// still exist.
+func (w *watcher) changed(ctx context.Context, touched map[string]bool) {
+	var paths []string
+	removed := 0
+	for p, exists := range touched {
+		if !strings.HasSuffix(p, ".md") {
+			continue
+		}
+		if exists {
+			paths = append(paths, p)
+		} else {
+			removed++
+		}
+	}
+	slices.Sort(paths)
+	var what []string
+	if len(paths) > 0 {
+		what = append(what, "changed "+strings.Join(paths, ", "))
+	}
+	if removed > 0 {
+		what = append(what, fmt.Sprintf("removed %d", removed))
+	}
+	if len(what) > 0 {
+		w.log.Print(strings.Join(what, "; "))
+	}
+	w.index(ctx)
+	w.build(ctx)
+	var entries []*entry.Entry
+	for _, p := range paths {
+		e, err := w.a.tree.Load(p)
+		if err != nil {
+			w.log.Printf("error: %v", err)
+			continue
+		}
+		entries = append(entries, e)
+	}
+	if len(entries) == 0 {
+		return
+	}
+	w.check(ctx, entries)
+}
+
+// index brings the search index up to date.
+func (w *watcher) index(ctx context.Context) {
+	ix, err := search.Open(w.a.tree)
+	if err != nil {
+		w.log.Printf("index failed: %v", err)
+		return
+	}
+	defer ix.Close()
+	st, err := ix.Sync(ctx)
+	if err != nil {
+		w.log.Printf("index failed: %v", err)
+		return
+	}
+	if n := st.Added + st.Updated + st.Removed; n > 0 {
+		w.log.Printf("indexed: %d added, %d updated, %d removed", st.Added, st.Updated, st.Removed)
+	}
+}
+
+// build rebuilds the site, unless --no-build.
+func (w *watcher) build(ctx context.Context) {
+	if w.builder == nil {
+		return
+	}
+	if err := w.a.hooks.Run(ctx, &hook.Payload{Event: hook.PreBuild, Out: w.out}); err != nil {
+		w.log.Printf("build failed: %v", err)
+		return
+	}
+	start := time.Now()
+	s, err := w.builder.Build(ctx)
+	if err != nil {
+		w.log.Printf("build failed: %v", err)
+		return
+	}
+	w.log.Printf("built %d of %d entries in %s", len(s.Rendered), len(s.Pages), time.Since(start).Round(time.Millisecond))
+	for _, err := range s.IncludeErrors {
+		w.log.Printf("warning: %v", err)
+	}
+	for _, err := range s.TranslationErrors {
+		w.log.Printf("warning: %v", err)
+	}
+	for _, err := range s.DiagramErrors {
+		w.log.Printf("warning: %v; left for the browser to draw", err)
+	}
+	for _, err := range s.ImageErrors {
+		w.log.Printf("warning: %v; published as SVG", err)
+	}
+}
+
+// check lints entries and verifies their code blocks.
+func (w *watcher) check(ctx context.Context, entries []*entry.Entry) {
+	if w.lint {
+		w.lintEntries(ctx, entries)
+	}
+	if !w.verify {
+		return
+	}
+	rep := verify.Run(ctx, entries, verify.Options{Registry: w.registry, Tree: w.a.tree})
+	if len(rep.Cases) == 0 {
+		return
+	}
+	for _, c := range rep.Cases {
+		switch c.Status {
+		case verify.Fail:
+			w.log.Printf("FAIL %s: %s: %v", caseLocation(c), c.Result.Phase, c.Result.Err)
+		case verify.Mismatch:
+			w.log.Printf("MISMATCH %s", caseLocation(c))
+		}
+	}
+	w.log.Printf("verified %s: %d passed", plural(len(rep.Cases), "block"), rep.Count(verify.Pass))
+}
+
+func (w *watcher) lintEntries(ctx context.Context, entries []*entry.Entry) {
+	rules, err := lint.Select(nil, w.a.cfg.Lint.Disable)
+	if err != nil {
+		w.log.Printf("lint failed: %v", err)
+		return
+	}
+	all, err := w.a.tree.Entries()
+	if err != nil {
+		w.log.Printf("lint failed: %v", err)
+		return
+	}
+	opts, err := w.a.lintOptions()
+	if err != nil {
+		w.log.Printf("lint failed: %v", err)
+		return
+	}
+	c := lint.NewContext(w.a.tree, all, entries, opts)
+	issues, err := lint.Run(ctx, c, rules)
+	if err != nil {
+		w.log.Printf("lint failed: %v", err)
+		return
+	}
+	for _, is := range issues {
+		w.log.Print(is)
+	}
+}
@@ -0,0 +1,103 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// syncBuffer is a bytes.Buffer safe to write while the test reads it.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestWatch(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not installed")
+	}
+	root := newTree(t, map[string]string{
+		"sh/ok.md": "---\ntitle: OK\n---\n\n```sh\necho hi\n```\n\n```output\nhi\n```\n",
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var out syncBuffer
+	cmd := newRootCmd(&app{})
+	cmd.SetOut(&out)
+	cmd.SetErr(&out)
+	cmd.SetArgs([]string{"-C", root, "watch", "--no-sandbox"})
+	done := make(chan error, 1)
+	go func() { done <- cmd.ExecuteContext(ctx) }()
+	// wait returns the output once it has every line containing one of
+	// want, and what followed since the last wait.
+	seen := 0
+	wait := func(want ...string) string {
+		t.Helper()
+		deadline := time.Now().Add(10 * time.Second)
+		for {
+			s := out.String()
+			got := s[seen:]
+			if !slices.ContainsFunc(want, func(w string) bool { return !strings.Contains(got, w) }) {
+				seen = len(s)
+				return got
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("waiting for %q; got\n%s", want, got)
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+	}
+
+	got := wait("watching " + root)
+	if !strings.Contains(got, "indexed: 1 added, 0 updated, 0 removed") || !strings.Contains(got, "built 1 of 1 entries") {
+		t.Errorf("startup:\n%s", got)
+	}
+
+	writeFile(t, root, "sh/bad.md", "# Bad\n\n```sh\necho two\n```\n\n```output\none\n```\n")
+	got = wait("changed sh/bad.md", "verified 1 block: 0 passed")
+	for _, want := range []string{"indexed: 1 added", "built 1 of 2 entries", "MISMATCH sh/bad.md"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("after adding sh/bad.md, no %q in\n%s", want, got)
+		}
+	}
+	if ok := readFile(t, root, "public/sh/bad/index.html"); !strings.Contains(ok, "Bad") {
+		t.Errorf("sh/bad not built:\n%s", ok)
+	}
+
+	if err := os.Remove(filepath.Join(root, "sh", "bad.md")); err != nil {
+		t.Fatal(err)
+	}
+	got = wait("removed 1", "built 0 of 1 entries")
+	if strings.Contains(got, "verified") || strings.Contains(got, "changed public/") {
+		t.Errorf("after removing sh/bad.md:\n%s", got)
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("watch = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("watch did not stop when canceled")
+	}
+}
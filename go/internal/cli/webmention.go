@@ -0,0 +1,189 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/canhta/til/go/internal/webmention"
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+func newWebmentionCmd(a *app) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "webmention",
+		Short: "Send and fetch webmentions",
+		Long: `Webmention tells the pages entries link to that they are linked, and
+fetches the webmentions other sites sent about entries, which til build
+lists under each entry.
+
+  [site.webmention]
+  endpoint = "https://webmention.io/example.com/webmention"
+  send = true   # send on til publish
+  fetch = true  # fetch on every til build
+
+Every page advertises the endpoint, where other sites send their mentions.
+Received mentions are fetched from a webmention.io-style jf2 API, by
+default webmention.io's with the token in $WEBMENTION_IO_TOKEN. Both need
+an absolute [site] base_url.`,
+	}
+	cmd.AddCommand(
+		newWebmentionSendCmd(a),
+		newWebmentionFetchCmd(a),
+	)
+	return cmd
+}
+
+func newWebmentionSendCmd(a *app) *cobra.Command {
+	var all, dryRun bool
+	cmd := &cobra.Command{
+		Use:   "send [entry...]",
+		Short: "Send webmentions for the external links of entries",
+		Long: `Send sends a webmention to each web page the entries link to that accepts
+them, from the entry's page on the site; without arguments, for every
+published entry. Pages are mentioned once: only links added since the last
+send are, unless --all is given, and pages an entry no longer links to are
+mentioned again so they learn of it. What was sent is recorded in the
+state directory.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			entries, err := a.tree.Entries()
+			if err != nil {
+				return err
+			}
+			urls := a.entryURLs(entries)
+			if urls == nil {
+				return errors.New("webmentions need the site's address; set an absolute [site] base_url")
+			}
+			if len(args) > 0 {
+				entries = entries[:0:0]
+				for _, ref := range args {
+					e, err := a.resolve(ref)
+					if err != nil {
+						return err
+					}
+					entries = append(entries, e)
+				}
+			}
+			return a.sendWebmentions(cmd.Context(), cmd.OutOrStdout(), cmd.ErrOrStderr(), entries, urls, all, dryRun)
+		},
+	}
+	cmd.Flags().BoolVar(&all, "all", false, "mention every linked page again, not only those linked since the last send")
+	cmd.Flags().BoolVarP(&dryRun, "dry-run", "n", false, "print the mentions instead of sending them")
+	return cmd
+}
+
+// sendWebmentions sends the pending webmentions of the published ones of
+// entries, reporting each failure to stderr.
+func (a *app) sendWebmentions(ctx context.Context, stdout, stderr io.Writer, entries []*entry.Entry, urls func(string) string, all, dryRun bool) error {
+	sent, err := webmention.LoadSent(a.tree)
+	if err != nil {
+		return err
+	}
+	var origin string
+	if u, err := url.Parse(a.cfg.Site.BaseURL); err == nil {
+		origin = u.Scheme + "://" + u.Host
+	}
+	var c webmention.Client
+	failed, n := 0, 0
+	for _, e := range entries {
+		if e.Meta.Draft || e.Meta.Private || e.Meta.Archived {
+			continue
+		}
+		source := urls(e.Path)
+		if source == "" {
+			continue
+		}
+		targets := webmention.Targets(e, origin)
+		for _, t := range sent.Pending(source, targets, all) {
+			linked := slices.Contains(targets, t)
+			if dryRun {
+				fmt.Fprintf(stdout, "%s → %s\n", source, t)
+				continue
+			}
+			err := c.Send(ctx, source, t)
+			switch {
+			case errors.Is(err, webmention.ErrNoEndpoint):
+				// Recorded all the same, so the page is not asked again.
+			case err != nil:
+				fmt.Fprintf(stderr, "webmention %s: %v\n", t, err)
+				failed++
+				continue
+			case linked:
+				fmt.Fprintf(stdout, "mentioned %s from %s\n", t, e.Path)
+				n++
+			default:
+				fmt.Fprintf(stdout, "told %s that %s no longer links to it\n", t, e.Path)
+				n++
+			}
+			sent.Record(source, t, linked, time.Now())
+		}
+	}
+	if dryRun {
+		return nil
+	}
+	if err := sent.Save(a.tree); err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("%s failed", plural(failed, "webmention"))
+	}
+	if n == 0 {
+		fmt.Fprintln(stdout, "no pages to mention")
+	}
+	return nil
+}
+
+func newWebmentionFetchCmd(a *app) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "fetch",
+		Short: "Fetch the webmentions the site received",
+		Long: `Fetch lists the webmentions the site received from the [site.webmention]
+api and keeps them in the state directory, where til build finds them.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ms, err := a.fetchWebmentions(cmd.Context())
+			if err != nil {
+				return err
+			}
+			return a.output(cmd, ms, func(w io.Writer) error {
+				fmt.Fprintf(w, "fetched %s\n", plural(len(ms), "webmention"))
+				return nil
+			})
+		},
+	}
+	return withJSON(cmd, "webmentions")
+}
+
+// fetchWebmentions fetches the webmentions of the site and caches them.
+func (a *app) fetchWebmentions(ctx context.Context) ([]webmention.Received, error) {
+	cfg := a.cfg.Site.Webmention
+	u, err := url.Parse(a.cfg.Site.BaseURL)
+	if err != nil || u.Host == "" {
+		return nil, errors.New("webmentions need the site's address; set an absolute [site] base_url")
+	}
+	api := cfg.API
+	if api == "" {
+		api = webmention.DefaultAPI
+	}
+	env := cfg.TokenEnv
+	if env == "" {
+		env = "WEBMENTION_IO_TOKEN"
+	}
+	token := os.Getenv(env)
+	if token == "" && api == webmention.DefaultAPI {
+		return nil, fmt.Errorf("$%s is not set; it holds the webmention.io API token", env)
+	}
+	var c webmention.Client
+	ms, err := c.Fetch(ctx, api, u.Hostname(), token)
+	if err != nil {
+		return nil, err
+	}
+	return ms, webmention.SaveReceived(a.tree, ms)
+}
@@ -0,0 +1,116 @@
+package cli
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestWebmentionSend(t *testing.T) {
+	var mu sync.Mutex
+	var mentions []string
+	pages := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/wm":
+			r.ParseForm()
+			mu.Lock()
+			mentions = append(mentions, r.PostForm.Get("source")+" "+strings.TrimPrefix(r.PostForm.Get("target"), "http://"+r.Host))
+			mu.Unlock()
+			w.WriteHeader(http.StatusAccepted)
+		case "/plain":
+			w.Write([]byte("no endpoint here"))
+		case "/broken":
+			http.NotFound(w, r)
+		default:
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<link rel="webmention" href="/wm">`))
+		}
+	}))
+	defer pages.Close()
+	root := newTree(t, map[string]string{
+		"go/slices.md": "---\ntitle: Slices\n---\n\nSee " + pages.URL + "/a and " + pages.URL + "/plain, and [maps](https://til.example/go/maps/).\n",
+		"go/maps.md":   "---\ntitle: Maps\n---\n\nSee " + pages.URL + "/broken.\n",
+		"go/draft.md":  "---\ntitle: Draft\ndraft: true\n---\n\nSee " + pages.URL + "/d.\n",
+	})
+
+	if _, err := run(t, root, "webmention", "send"); err == nil || !strings.Contains(err.Error(), "base_url") {
+		t.Errorf("send without a base URL = %v", err)
+	}
+	writeConfig(t, "[site]\nbase_url = \"https://til.example/\"\n")
+
+	out := mustRun(t, root, "webmention", "send", "--dry-run", "go/slices.md")
+	if want := "https://til.example/go/slices/ → " + pages.URL + "/a\nhttps://til.example/go/slices/ → " + pages.URL + "/plain\n"; out != want {
+		t.Errorf("send --dry-run =\n%s\nwant\n%s", out, want)
+	}
+	if len(mentions) != 0 {
+		t.Fatalf("dry run sent %q", mentions)
+	}
+
+	out, err := run(t, root, "webmention", "send")
+	if err == nil || err.Error() != "1 webmention failed" {
+		t.Errorf("send = %v, want the broken page to fail", err)
+	}
+	for _, want := range []string{"webmention " + pages.URL + "/broken: 404 Not Found\n", "mentioned " + pages.URL + "/a from go/slices.md\n"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("send =\n%s\nwant %s", out, want)
+		}
+	}
+	if want := "https://til.example/go/slices/ /a"; len(mentions) != 1 || mentions[0] != want {
+		t.Errorf("mentions sent = %q, want %q", mentions, want)
+	}
+
+	// Pages mentioned, or without an endpoint, are not asked again.
+	writeFile(t, root, "go/maps.md", "---\ntitle: Maps\n---\n\nNo links.\n")
+	if out := mustRun(t, root, "webmention", "send"); out != "no pages to mention\n" {
+		t.Errorf("second send =\n%s", out)
+	}
+	// A page no longer linked is told so.
+	writeFile(t, root, "go/slices.md", "---\ntitle: Slices\n---\n\nSee "+pages.URL+"/plain.\n")
+	if out := mustRun(t, root, "webmention", "send"); out != "told "+pages.URL+"/a that go/slices.md no longer links to it\n" {
+		t.Errorf("send after unlinking =\n%s", out)
+	}
+	if len(mentions) != 2 || mentions[1] != mentions[0] {
+		t.Errorf("mentions sent = %q", mentions)
+	}
+}
+
+func TestWebmentionFetch(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("domain") != "til.example" || r.URL.Query().Get("token") != "tok" {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		w.Write([]byte(`{"children":[{"wm-id":7,"wm-property":"in-reply-to","url":"https://bob.example/r","wm-target":"https://til.example/go/slices/","published":"2024-07-02T00:00:00Z","author":{"name":"Bob"},"content":{"text":"Nice find."}}]}`))
+	}))
+	defer api.Close()
+	root := newTree(t, map[string]string{
+		"go/slices.md": "---\ntitle: Slices\n---\n\nSlices.\n",
+	})
+	writeConfig(t, "[site]\nbase_url = \"https://til.example/\"\n\n[site.webmention]\nendpoint = \"https://webmention.io/til.example/webmention\"\napi = \""+api.URL+"\"\ntoken_env = \"TIL_TEST_WM_TOKEN\"\n")
+
+	if _, err := run(t, root, "webmention", "fetch"); err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("fetch without the token = %v", err)
+	}
+	t.Setenv("TIL_TEST_WM_TOKEN", "tok")
+	if out := mustRun(t, root, "webmention", "fetch"); out != "fetched 1 webmention\n" {
+		t.Errorf("fetch = %q", out)
+	}
+
+	// The build lists the mentions fetched, without fetching again.
+	api.Close()
+	mustRun(t, root, "build")
+	page := readFile(t, root, "public/go/slices/index.html")
+	for _, want := range []string{`<link rel="webmention" href="https://webmention.io/til.example/webmention">`, `<a href="https://bob.example/r">Bob</a>`, "<p>Nice find.</p>"} {
+		if !strings.Contains(page, want) {
+			t.Errorf("entry page lacks %s:\n%s", want, page)
+		}
+	}
+	if _, err := run(t, root, "build", "--webmentions"); err != nil {
+		t.Fatalf("build --webmentions with the API down: %v", err)
+	}
+	if page := readFile(t, root, "public/go/slices/index.html"); !strings.Contains(page, "<p>Nice find.</p>") {
+		t.Error("build --webmentions with the API down dropped the mentions fetched before")
+	}
+}
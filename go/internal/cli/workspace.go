@@ -0,0 +1,182 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/canhta/til/go/internal/config"
+)
+
+// workspaceFile holds the name of the workspace chosen with til workspace
+// use, in the user's state directory.
+const workspaceFile = "workspace"
+
+func newWorkspaceCmd(a *app) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "workspace",
+		Short: "List and switch between the notes roots of the config file",
+		Long: `A workspace is a notes root named in the [workspaces] table of the config
+file:
+
+  [workspaces]
+  personal = "~/til"
+  work = "~/work/til"
+
+Commands run in the workspace given with -w, else in the one chosen with til
+workspace use, else in the dir of the config file or the notes root of the
+working directory. --dir overrides them all.`,
+		// Workspaces need no notes tree, so a chosen workspace since
+		// removed from the config file can still be switched from.
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if err := a.checkJSON(cmd); err != nil {
+				return err
+			}
+			if err := a.startLogging(cmd); err != nil {
+				return err
+			}
+			cfg, err := config.Load(a.profile)
+			a.cfg = cfg
+			return err
+		},
+	}
+	cmd.AddCommand(
+		newWorkspaceListCmd(a),
+		newWorkspaceUseCmd(a),
+	)
+	return cmd
+}
+
+// workspaceInfo is a workspace as printed by til workspace list.
+type workspaceInfo struct {
+	Name    string `json:"name"`
+	Dir     string `json:"dir"`
+	Current bool   `json:"current"`
+}
+
+func newWorkspaceListCmd(a *app) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the workspaces, marking the one in use",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			current := a.workspace
+			if current == "" {
+				var err error
+				if current, err = currentWorkspace(); err != nil {
+					return err
+				}
+			}
+			var list []workspaceInfo
+			for _, name := range slices.Sorted(maps.Keys(a.cfg.Workspaces)) {
+				list = append(list, workspaceInfo{Name: name, Dir: a.cfg.Workspaces[name], Current: name == current})
+			}
+			return a.output(cmd, list, func(w io.Writer) error {
+				if len(list) == 0 {
+					_, err := fmt.Fprintln(w, "no workspaces; add them to [workspaces] in the config file")
+					return err
+				}
+				tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+				for _, ws := range list {
+					mark := " "
+					if ws.Current {
+						mark = "*"
+					}
+					fmt.Fprintf(tw, "%s %s\t%s\n", mark, ws.Name, ws.Dir)
+				}
+				return tw.Flush()
+			})
+		},
+	}
+	return withJSON(cmd, "workspaces")
+}
+
+func newWorkspaceUseCmd(a *app) *cobra.Command {
+	var unset bool
+	cmd := &cobra.Command{
+		Use:   "use <workspace>",
+		Short: "Run later commands in a workspace",
+		Example: `  til workspace use work
+  til workspace use --clear`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if unset {
+				return cobra.NoArgs(cmd, args)
+			}
+			return cobra.ExactArgs(1)(cmd, args)
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) > 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return a.completeWorkspaces(cmd, args, toComplete)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			file, err := workspacePath()
+			if err != nil {
+				return err
+			}
+			if unset {
+				if err := os.Remove(file); err != nil && !errors.Is(err, fs.ErrNotExist) {
+					return err
+				}
+				_, err := fmt.Fprintln(cmd.OutOrStdout(), "No workspace in use")
+				return err
+			}
+			name := args[0]
+			dir, err := a.workspaceDir(name)
+			if err != nil {
+				return err
+			}
+			if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+				return err
+			}
+			if err := os.WriteFile(file, []byte(name+"\n"), 0o644); err != nil {
+				return err
+			}
+			_, err = fmt.Fprintf(cmd.OutOrStdout(), "Using workspace %s (%s)\n", name, dir)
+			return err
+		},
+	}
+	cmd.Flags().BoolVar(&unset, "clear", false, "stop using a workspace, going back to the config file's dir or the working directory")
+	return cmd
+}
+
+// workspaceDir returns the notes root of the named workspace.
+func (a *app) workspaceDir(name string) (string, error) {
+	dir, ok := a.cfg.Workspaces[name]
+	if !ok {
+		return "", fmt.Errorf("no workspace %q in the config file", name)
+	}
+	return expandHome(dir)
+}
+
+func workspacePath() (string, error) {
+	dir, err := config.StateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, workspaceFile), nil
+}
+
+// currentWorkspace returns the workspace chosen with til workspace use, if
+// any.
+func currentWorkspace() (string, error) {
+	file, err := workspacePath()
+	if err != nil {
+		return "", err
+	}
+	b, err := os.ReadFile(file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", nil
+	}
+	return strings.TrimSpace(string(b)), err
+}
@@ -0,0 +1,101 @@
+package cli
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestWorkspace(t *testing.T) {
+	personal := newTree(t, map[string]string{"go/slices.md": "# Slices share arrays\n\nAppend to a slice.\n"})
+	work := newTree(t, map[string]string{
+		"ops/disk.md":  "# Disk full\n\nA slice of the disk.\n",
+		"ops/inode.md": "# Inodes\n\nRun out of inodes.\n",
+	})
+	t.Chdir(t.TempDir())
+	os.Unsetenv("TIL_DIR")
+	writeConfig(t, "dir = "+quote(personal)+"\n[workspaces]\npersonal = "+quote(personal)+"\nwork = "+quote(work)+"\ngone = \"/nonexistent/til\"\n")
+
+	list := func(args ...string) string {
+		t.Helper()
+		out, err := runHere(t, append(args, "list")...)
+		if err != nil {
+			t.Fatalf("til %s list: %v\n%s", strings.Join(args, " "), err, out)
+		}
+		return out
+	}
+	if out := list(); !strings.Contains(out, "go/slices.md") {
+		t.Errorf("list with no workspace =\n%s", out)
+	}
+	if out := list("-w", "work"); !strings.Contains(out, "ops/disk.md") {
+		t.Errorf("list -w work =\n%s", out)
+	}
+	if _, err := runHere(t, "-w", "team", "list"); err == nil || !strings.Contains(err.Error(), `no workspace "team"`) {
+		t.Errorf("list -w team = %v", err)
+	}
+
+	if out, err := runHere(t, "workspace", "use", "work"); err != nil || out != "Using workspace work ("+work+")\n" {
+		t.Errorf("workspace use work = %q, %v", out, err)
+	}
+	if out := list(); !strings.Contains(out, "ops/disk.md") {
+		t.Errorf("list in workspace work =\n%s", out)
+	}
+	if out := list("-w", "personal"); !strings.Contains(out, "go/slices.md") {
+		t.Errorf("-w does not override the workspace in use:\n%s", out)
+	}
+	if out := list("-C", personal); !strings.Contains(out, "go/slices.md") {
+		t.Errorf("-C does not override the workspace in use:\n%s", out)
+	}
+	out, _ := runHere(t, "workspace", "list")
+	if want := "  gone      /nonexistent/til\n  personal  " + personal + "\n* work      " + work + "\n"; out != want {
+		t.Errorf("workspace list =\n%s\nwant\n%s", out, want)
+	}
+	out, _ = runHere(t, "workspace", "list", "--json", "-w", "personal")
+	var got struct{ Data []workspaceInfo }
+	if err := json.Unmarshal([]byte(out), &got); err != nil || len(got.Data) != 3 || !got.Data[1].Current {
+		t.Errorf("workspace list --json -w personal = %v\n%s", err, out)
+	}
+
+	// A workspace in use that is no longer configured is reported, and can
+	// be switched from.
+	runHere(t, "workspace", "use", "gone")
+	writeConfig(t, "[workspaces]\nwork = "+quote(work)+"\n")
+	if _, err := runHere(t, "list"); err == nil || !strings.Contains(err.Error(), `workspace "gone" in use is not in the config file`) {
+		t.Errorf("list in a removed workspace = %v", err)
+	}
+	if out, err := runHere(t, "workspace", "use", "--clear"); err != nil || out != "No workspace in use\n" {
+		t.Errorf("workspace use --clear = %q, %v", out, err)
+	}
+	if _, err := runHere(t, "workspace", "use", "gone"); err == nil {
+		t.Error("workspace use of an unknown workspace succeeded")
+	}
+}
+
+func TestSearchAllWorkspaces(t *testing.T) {
+	personal := newTree(t, map[string]string{"go/slices.md": "# Slices share arrays\n\nAppend to a slice.\n"})
+	work := newTree(t, map[string]string{"ops/disk.md": "# Disk full\n\nA slice of the disk.\n", "ops/inode.md": "# Inodes\n\nRun out of inodes.\n"})
+	if _, err := run(t, personal, "search", "--all-workspaces", "slice"); err == nil || !strings.Contains(err.Error(), "no workspaces") {
+		t.Errorf("search --all-workspaces with none = %v", err)
+	}
+	writeConfig(t, "[workspaces]\npersonal = "+quote(personal)+"\nwork = "+quote(work)+"\ngone = \"/nonexistent/til\"\n")
+
+	out := mustRun(t, personal, "search", "--all-workspaces", "--json", "slice")
+	var got struct{ Data []workspaceResult }
+	if err := json.Unmarshal([]byte(out[strings.Index(out, "{"):]), &got); err != nil {
+		t.Fatalf("%v\n%s", err, out)
+	}
+	labeled := map[string]string{}
+	for _, r := range got.Data {
+		labeled[r.Path] = r.Workspace
+	}
+	if len(got.Data) != 2 || labeled["go/slices.md"] != "personal" || labeled["ops/disk.md"] != "work" {
+		t.Errorf("search --all-workspaces = %+v", got.Data)
+	}
+	if !strings.HasPrefix(out, "warning: workspace gone: ") {
+		t.Errorf("missing workspace not warned about:\n%s", out)
+	}
+	if out := mustRun(t, personal, "search", "--all-workspaces", "-n", "1", "slice"); strings.Count(out, ".md") != 1 {
+		t.Errorf("search --all-workspaces -n 1 =\n%s", out)
+	}
+}
@@ -0,0 +1,99 @@
+// Package clipboard copies text to and reads text from the system
+// clipboard.
+package clipboard
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// tools are clipboard commands tried in order; the text is piped to stdin.
+var tools = [][]string{
+	{"pbcopy"},
+	{"wl-copy"},
+	{"xclip", "-selection", "clipboard"},
+	{"xsel", "--clipboard", "--input"},
+	{"clip.exe"},
+}
+
+// Copy puts text on the clipboard using the first available clipboard
+// command. Without one, for instance over SSH, it falls back to the OSC 52
+// terminal escape sequence, which most modern terminals honour.
+func Copy(text string) error {
+	for _, argv := range tools {
+		if !usable(argv[0]) {
+			continue
+		}
+		cmd := exec.Command(argv[0], argv[1:]...)
+		cmd.Stdin = strings.NewReader(text)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("%s: %v: %s", argv[0], err, strings.TrimSpace(string(out)))
+		}
+		return nil
+	}
+	return osc52(text)
+}
+
+// pasteTools read the clipboard to stdout, in the order of tools.
+var pasteTools = [][]string{
+	{"pbpaste"},
+	{"wl-paste", "--no-newline"},
+	{"xclip", "-selection", "clipboard", "-out"},
+	{"xsel", "--clipboard", "--output"},
+	{"powershell.exe", "-NoProfile", "-Command", "Get-Clipboard"},
+}
+
+// Paste returns the text on the clipboard, using the first available
+// clipboard command.
+func Paste() (string, error) {
+	for _, argv := range pasteTools {
+		if !usable(argv[0]) {
+			continue
+		}
+		var stderr strings.Builder
+		cmd := exec.Command(argv[0], argv[1:]...)
+		cmd.Stderr = &stderr
+		out, err := cmd.Output()
+		if err != nil {
+			return "", fmt.Errorf("%s: %v: %s", argv[0], err, strings.TrimSpace(stderr.String()))
+		}
+		if argv[0] == "powershell.exe" {
+			out = []byte(strings.ReplaceAll(string(out), "\r\n", "\n"))
+		}
+		return string(out), nil
+	}
+	return "", fmt.Errorf("no clipboard command found (install wl-clipboard, xclip or xsel)")
+}
+
+// usable reports whether the clipboard command name can run here.
+func usable(name string) bool {
+	switch {
+	case strings.HasPrefix(name, "pb") && runtime.GOOS != "darwin":
+		return false
+	case strings.HasPrefix(name, "wl-") && os.Getenv("WAYLAND_DISPLAY") == "":
+		return false
+	case strings.HasPrefix(name, "x") && os.Getenv("DISPLAY") == "":
+		return false
+	}
+	_, err := exec.LookPath(name)
+	return err == nil
+}
+
+func osc52(text string) error {
+	tty, err := os.OpenFile("/dev/tty", os.O_WRONLY, 0)
+	if err != nil {
+		return fmt.Errorf("no clipboard command found and no terminal to copy through")
+	}
+	defer tty.Close()
+	seq := "\x1b]52;c;" + base64.StdEncoding.EncodeToString([]byte(text)) + "\a"
+	if os.Getenv("TMUX") != "" {
+		// tmux passes the sequence through only when wrapped.
+		seq = "\x1bPtmux;\x1b" + seq + "\x1b\\"
+	}
+	_, err = tty.WriteString(seq)
+	return err
+}
@@ -0,0 +1,56 @@
+package clipboard
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// fakeWayland puts wl-copy and wl-paste scripts keeping the clipboard in a
+// file first on PATH, and returns the file.
+func fakeWayland(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	clip := filepath.Join(dir, "clip")
+	for name, script := range map[string]string{
+		"wl-copy":  "#!/bin/sh\ncat > " + clip + "\n",
+		"wl-paste": "#!/bin/sh\n[ \"$1\" = --no-newline ] || exit 2\ncat " + clip + "\n",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(script), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Setenv("WAYLAND_DISPLAY", "wayland-0")
+	t.Setenv("DISPLAY", "")
+	return clip
+}
+
+func TestCopyPaste(t *testing.T) {
+	clip := fakeWayland(t)
+	if err := Copy("git rebase --onto\n"); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(clip); string(data) != "git rebase --onto\n" {
+		t.Errorf("clipboard = %q", data)
+	}
+	got, err := Paste()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "git rebase --onto\n" {
+		t.Errorf("Paste = %q", got)
+	}
+}
+
+func TestPasteFails(t *testing.T) {
+	fakeWayland(t)
+	// The clipboard file does not exist yet.
+	if _, err := Paste(); err == nil {
+		t.Error("Paste succeeded on a failing wl-paste")
+	}
+	t.Setenv("WAYLAND_DISPLAY", "")
+	if _, err := Paste(); err == nil {
+		t.Error("Paste succeeded without a clipboard command")
+	}
+}
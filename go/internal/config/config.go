@@ -0,0 +1,658 @@
+// Package config loads the til configuration file.
+//
+// The file lives at $TIL_CONFIG if set, else at
+// $XDG_CONFIG_HOME/til/config.toml, falling back to
+// ~/.config/til/config.toml. A missing file yields the zero Config.
+//
+// A profile is a [profiles.<name>] table of the file holding any of its
+// settings, which override those outside when the profile is loaded; tables
+// such as runners merge by key. Environment variables override both: each
+// setting other than tables and arrays of tables can be set as TIL_ and its
+// dotted key upper-cased with underscores, such as TIL_SITE_BASE_URL, with
+// arrays comma-separated.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// Config is the parsed configuration file.
+type Config struct {
+	// Dir is the notes root til uses unless --dir or $TIL_DIR names one,
+	// instead of finding it from the working directory. A leading ~ is the
+	// home directory.
+	Dir string `toml:"dir"`
+	// Editor is the editor command line, used before $VISUAL and $EDITOR.
+	Editor string `toml:"editor"`
+	// Tags are added to every entry created with til new and til capture.
+	Tags []string `toml:"tags"`
+	// Lang is the language entries are written in unless their lang
+	// frontmatter names another, as a BCP 47 tag. Defaults to en.
+	Lang string `toml:"lang"`
+	// Workspaces are notes roots keyed by name, chosen with --workspace or
+	// til workspace use. A leading ~ is the home directory.
+	Workspaces map[string]string `toml:"workspaces"`
+	// Runners configures snippet runners keyed by fence language.
+	Runners map[string]Runner `toml:"runners"`
+	Sandbox Sandbox           `toml:"sandbox"`
+	Git     Git               `toml:"git"`
+	// Embeddings configures the model used by til search --semantic.
+	Embeddings Embeddings `toml:"embeddings"`
+	// Collections are saved searches keyed by name, referred to as @name
+	// in queries.
+	Collections map[string]string `toml:"collections"`
+	Private     Private           `toml:"private"`
+	Store       Store             `toml:"store"`
+	Sync        Sync              `toml:"sync"`
+	API         API               `toml:"api"`
+	Site        Site              `toml:"site"`
+	Lint        Lint              `toml:"lint"`
+	Schema      Schema            `toml:"schema"`
+	Trash       Trash             `toml:"trash"`
+	Digest      Digest            `toml:"digest"`
+	Notify      Notify            `toml:"notify"`
+	Crosspost   Crosspost         `toml:"crosspost"`
+	Index       Index             `toml:"index"`
+	Gist        Gist              `toml:"gist"`
+	Playground  Playground        `toml:"playground"`
+	Mailbox     Mailbox           `toml:"mailbox"`
+	Bot         Bot               `toml:"bot"`
+	Hooks       Hooks             `toml:"hooks"`
+	// Goals are the writing targets til stats, til digest and til nag
+	// report progress on.
+	Goals []Goal `toml:"goals"`
+}
+
+// Goal is a target number of entries per period, as
+//
+//	[[goals]]
+//	entries = 3
+//	per = "week"
+//	tag = "go"
+type Goal struct {
+	Entries int `toml:"entries"`
+	// Per is the period: "day", "week", starting on Monday, "month" or
+	// "year". Defaults to week.
+	Per string `toml:"per"`
+	// Tag and Category, when set, count only the entries with that tag or
+	// in that category.
+	Tag      string `toml:"tag"`
+	Category string `toml:"category"`
+}
+
+// Hooks configures the commands and plugins run on lifecycle events, as
+// package hook describes. Each event lists command lines run in order.
+type Hooks struct {
+	PreSave     [][]string `toml:"pre_save"`
+	PostSave    [][]string `toml:"post_save"`
+	PreBuild    [][]string `toml:"pre_build"`
+	PostPublish [][]string `toml:"post_publish"`
+	// Plugins are Go plugin files, loaded in order and run before the
+	// commands. A leading ~ is the home directory.
+	Plugins []string `toml:"plugins"`
+	// Timeout bounds each command. Defaults to 1m.
+	Timeout Duration `toml:"timeout"`
+}
+
+// Bot configures the chat bots of til bot.
+type Bot struct {
+	Telegram Telegram `toml:"telegram"`
+}
+
+// Telegram configures til bot telegram.
+type Telegram struct {
+	// TokenEnv names the environment variable holding the bot token.
+	// Defaults to TELEGRAM_BOT_TOKEN.
+	TokenEnv string `toml:"token_env"`
+	// AllowUsers lists the IDs of the users whose messages are saved.
+	AllowUsers []int64 `toml:"allow_users"`
+	// Category holds the entries. Defaults to inbox.
+	Category string `toml:"category"`
+	// API is the endpoint of a local Bot API server.
+	API string `toml:"api"`
+}
+
+// Mailbox configures the IMAP account til mailbox reads.
+type Mailbox struct {
+	Host string `toml:"host"`
+	// Port defaults to 993, or 143 unless Security is "tls".
+	Port int `toml:"port"`
+	// Security is "tls", "starttls" or "none", for mail bridges on the
+	// same machine. Defaults to tls.
+	Security string `toml:"security"`
+	Username string `toml:"username"`
+	// PasswordEnv names the environment variable holding the password.
+	PasswordEnv string `toml:"password_env"`
+	// Folder is read for new mail. Defaults to INBOX.
+	Folder string `toml:"folder"`
+	// Archive is the folder processed mail is moved to. Empty leaves it
+	// in Folder, marked read.
+	Archive string `toml:"archive"`
+	// Address is the address mail is sent to; tags are only taken from
+	// plus addresses of it.
+	Address string `toml:"address"`
+	// AllowFrom lists the senders accepted, as addresses or domains such
+	// as "@example.com". Empty accepts every sender.
+	AllowFrom []string `toml:"allow_from"`
+	// Category holds the entries. Defaults to inbox.
+	Category string `toml:"category"`
+	// Interval is the time between polls. Defaults to 5m.
+	Interval Duration `toml:"interval"`
+}
+
+// Gist configures til gist.
+type Gist struct {
+	// TokenEnv names the environment variable holding a GitHub token with
+	// the gist scope. Defaults to GITHUB_TOKEN.
+	TokenEnv string `toml:"token_env"`
+	// Public creates public gists instead of secret ones.
+	Public bool `toml:"public"`
+	// API is the REST endpoint of a GitHub Enterprise server.
+	API string `toml:"api"`
+}
+
+// Playground configures til share.
+type Playground struct {
+	// API is the endpoint snippets are shared through. Defaults to
+	// https://play.golang.org.
+	API string `toml:"api"`
+}
+
+// Crosspost configures the platforms of til crosspost.
+type Crosspost struct {
+	DevTo    DevTo    `toml:"devto"`
+	Hashnode Hashnode `toml:"hashnode"`
+}
+
+// DevTo configures posting to dev.to.
+type DevTo struct {
+	// KeyEnv names the environment variable holding the API key. Defaults
+	// to DEVTO_API_KEY.
+	KeyEnv string `toml:"key_env"`
+	// API is the API endpoint of another Forem instance.
+	API string `toml:"api"`
+}
+
+// Hashnode configures posting to a Hashnode publication.
+type Hashnode struct {
+	// TokenEnv names the environment variable holding the personal access
+	// token. Defaults to HASHNODE_TOKEN.
+	TokenEnv string `toml:"token_env"`
+	// Publication is the ID of the publication posted to.
+	Publication string `toml:"publication"`
+	// API overrides the GraphQL endpoint.
+	API string `toml:"api"`
+}
+
+// Index configures the search engines til index push mirrors entries to.
+type Index struct {
+	Meilisearch SearchEngine `toml:"meilisearch"`
+	// Elasticsearch also serves OpenSearch.
+	Elasticsearch SearchEngine `toml:"elasticsearch"`
+}
+
+// SearchEngine configures the index of a search engine.
+type SearchEngine struct {
+	// URL is the server's. Defaults to the engine's port on localhost.
+	URL string `toml:"url"`
+	// Index names the index. Defaults to til.
+	Index string `toml:"index"`
+	// KeyEnv names the environment variable holding the API key, which
+	// may be unset for a server without authentication. Defaults to
+	// MEILISEARCH_API_KEY or ELASTICSEARCH_API_KEY.
+	KeyEnv string `toml:"key_env"`
+}
+
+// Notify configures the webhooks entries are announced on by til publish
+// and til notify.
+type Notify struct {
+	Webhooks []Webhook `toml:"webhooks"`
+	// Manual announces entries only with til notify, not when published.
+	Manual bool `toml:"manual"`
+	// Attempts is how many times a post is tried. Defaults to 4.
+	Attempts int `toml:"attempts"`
+	// Backoff is the wait after a first failed post, doubled after each
+	// further one. Defaults to 1s.
+	Backoff Duration `toml:"backoff"`
+}
+
+// Webhook is a Slack, Discord or generic JSON webhook.
+type Webhook struct {
+	URL string `toml:"url"`
+	// URLEnv names the environment variable holding the URL instead, as
+	// webhook URLs are secrets.
+	URLEnv string `toml:"url_env"`
+	// Kind is "slack", "discord" or "json". Defaults to the kind of the
+	// URL's host, else json.
+	Kind string `toml:"kind"`
+	// Template is a Go text/template of the message, given the entry's
+	// Title, Path, Category, Tags, Date, Excerpt and URL.
+	Template string `toml:"template"`
+}
+
+// Digest configures til digest.
+type Digest struct {
+	// Channels lists where digests go: "stdout", "email" and "webhook".
+	// Defaults to stdout.
+	Channels []string `toml:"channels"`
+	// SkipEmpty sends nothing when nothing is due or resurfaced.
+	SkipEmpty bool `toml:"skip_empty"`
+	// Limit caps the entries listed per section. Defaults to 10.
+	Limit int `toml:"limit"`
+	// Webhook is the URL digests are posted to as JSON.
+	Webhook string      `toml:"webhook"`
+	Email   DigestEmail `toml:"email"`
+}
+
+// DigestEmail configures the SMTP server digests are mailed through.
+type DigestEmail struct {
+	Host string `toml:"host"`
+	// Port defaults to 587; 465 means TLS from the start.
+	Port     int    `toml:"port"`
+	Username string `toml:"username"`
+	// PasswordEnv names the environment variable holding the password.
+	PasswordEnv string   `toml:"password_env"`
+	From        string   `toml:"from"`
+	To          []string `toml:"to"`
+}
+
+// Lint configures til lint.
+type Lint struct {
+	// Required lists the frontmatter fields every entry must set. Defaults
+	// to title and date.
+	Required []string `toml:"required"`
+	// MaxCodeLine is the longest code block line allowed. Defaults to 100.
+	MaxCodeLine int `toml:"max_code_line"`
+	// Disable lists rules not to run unless asked for by name.
+	Disable []string `toml:"disable"`
+	// URLInterval is the least time between two requests to one host when
+	// checking external links. Defaults to 1s.
+	URLInterval Duration `toml:"url_interval"`
+	// URLTTL is how long a checked link is trusted. Defaults to 24h.
+	URLTTL Duration `toml:"url_ttl"`
+}
+
+// Trash configures where til rm keeps removed entries.
+type Trash struct {
+	// Retention is how long removed entries are kept before they are
+	// purged. Defaults to 720h, 30 days.
+	Retention Duration `toml:"retention"`
+}
+
+// Schema describes the frontmatter of entries, which til lint and til new
+// check, and the migrations til migrate applies when it changes:
+//
+//	[schema]
+//	required = ["title", "date", "tags"]
+//	categories = ["go", "git", "inbox"]
+//
+//	[schema.fields.difficulty]
+//	type = "string"
+//	values = ["easy", "hard"]
+//
+//	[[schema.migrations]]
+//	field = "level"
+//	rename = "difficulty"
+type Schema struct {
+	// Required lists the fields every entry must set to a value that is
+	// not empty, along with those of [lint] required.
+	Required []string `toml:"required"`
+	// Categories and Tags, when set, are the only ones entries may use.
+	Categories []string `toml:"categories"`
+	Tags       []string `toml:"tags"`
+	// Fields declares fields by name; til's own need no declaring.
+	Fields map[string]Field `toml:"fields"`
+	// Closed rejects the fields neither declared nor til's own.
+	Closed bool `toml:"closed"`
+	// Migrations are applied in order by til migrate.
+	Migrations []Migration `toml:"migrations"`
+}
+
+// Field is the declaration of a frontmatter field.
+type Field struct {
+	// Type is "string", "int", "number", "bool", "date" or "list" of
+	// strings. Empty allows any value.
+	Type string `toml:"type"`
+	// Values, when set, are the only values the field, or each item of a
+	// list, may take.
+	Values []string `toml:"values"`
+}
+
+// Migration changes one frontmatter field of every entry setting it, or
+// with Default of every entry not setting it. Each is a no-op on entries
+// already migrated.
+type Migration struct {
+	Field string `toml:"field"`
+	// Rename is the field's new name.
+	Rename string `toml:"rename"`
+	// Values replaces values of the field, or of its list items, by key.
+	Values map[string]string `toml:"values"`
+	// Delete removes the field.
+	Delete bool `toml:"delete"`
+	// Default is set as the field's value on entries without it.
+	Default any `toml:"default"`
+}
+
+// Site configures the generated site.
+type Site struct {
+	// Theme is a builtin theme name or a directory, relative to the notes
+	// root, holding a theme. Defaults to "default".
+	Theme string `toml:"theme"`
+	// BaseURL is the URL the site is published at, the default of
+	// --base-url. Digests and announcements link to entries when it is
+	// absolute, and the site has feeds and a sitemap.
+	BaseURL string `toml:"base_url"`
+	// Robots is the content of the site's robots.txt, by default allowing
+	// every crawler everything. A Sitemap line is added unless it has one.
+	Robots string `toml:"robots"`
+	// Permalink is the pattern of entry URLs, such as "/:year/:month/:slug/".
+	// Defaults to "/:category/:slug/".
+	Permalink string `toml:"permalink"`
+	// Highlight names the chroma style of code blocks, overriding the
+	// theme's.
+	Highlight string `toml:"highlight"`
+	// LineNumbers numbers the lines of every code block.
+	LineNumbers bool `toml:"line_numbers"`
+	// PerPage is the number of entries on each page of category and
+	// month listings. Defaults to 20.
+	PerPage int `toml:"per_page"`
+	// History publishes a page listing the commits of each entry, linked
+	// from the entry's page.
+	History bool `toml:"history"`
+	// Changelog publishes a page summarizing the entries added and updated
+	// in each of the last months.
+	Changelog bool `toml:"changelog"`
+	// Mermaid is the command rendering mermaid diagrams to SVG, with
+	// "{file}", "{out}" and "{id}" expanded as package diagram describes.
+	// Defaults to mermaid-cli's mmdc when it is installed.
+	Mermaid []string `toml:"mermaid"`
+	// Math typesets TeX written between $ and $$ in entries.
+	Math bool `toml:"math"`
+	// KaTeX is the command pre-rendering math to HTML, reading TeX on stdin;
+	// "{display}" expands to --display-mode for display math. Defaults to
+	// KaTeX's katex CLI when it is installed, else math is typeset in the
+	// browser.
+	KaTeX []string `toml:"katex"`
+	// OGImage is the command converting entries' preview cards from SVG to
+	// PNG, with "{file}" and "{out}" expanded to the SVG and PNG files.
+	// Defaults to librsvg's rsvg-convert when it is installed, else cards
+	// are published as SVG.
+	OGImage []string `toml:"og_image"`
+	// Webmention configures sending and receiving webmentions.
+	Webmention Webmention `toml:"webmention"`
+}
+
+// Webmention configures the webmentions of the site, as
+//
+//	[site.webmention]
+//	endpoint = "https://webmention.io/example.com/webmention"
+//	send = true
+//	fetch = true
+type Webmention struct {
+	// Endpoint is where other sites send webmentions of entries,
+	// advertised by every page.
+	Endpoint string `toml:"endpoint"`
+	// Send sends webmentions to the pages an entry links to when it is
+	// published with til publish.
+	Send bool `toml:"send"`
+	// Fetch fetches the webmentions received on every til build, as the
+	// --webmentions flag does.
+	Fetch bool `toml:"fetch"`
+	// API is the webmention.io-style jf2 API listing the webmentions
+	// received. Defaults to webmention.io's.
+	API string `toml:"api"`
+	// TokenEnv names the environment variable holding the API token.
+	// Defaults to WEBMENTION_IO_TOKEN.
+	TokenEnv string `toml:"token_env"`
+}
+
+// API configures the server of til api.
+type API struct {
+	// TokenEnv names the environment variable holding the bearer token
+	// clients must send. It is required to serve beyond localhost.
+	TokenEnv string `toml:"token_env"`
+	// Origins are the browser origins allowed to call the API, or "*".
+	Origins []string `toml:"origins"`
+	// Web configures the web app served by til api --web.
+	Web Web `toml:"web"`
+}
+
+// Web configures the web app, as
+//
+//	[api.web.users]
+//	ana = "$2a$10$..." # til api --hash-password
+//
+//	[api.web.github]
+//	client_id = "Iv1.0123456789abcdef"
+//	allow = ["ana", "ben"]
+type Web struct {
+	// Users maps user names to the bcrypt hashes of their passwords.
+	Users map[string]string `toml:"users"`
+	// SessionKeyEnv names the environment variable holding the key session
+	// cookies are signed with, so that sign-ins outlast restarts.
+	SessionKeyEnv string    `toml:"session_key_env"`
+	GitHub        WebGitHub `toml:"github"`
+}
+
+// WebGitHub configures signing in to the web app with a GitHub OAuth app.
+type WebGitHub struct {
+	ClientID string `toml:"client_id"`
+	// ClientSecretEnv names the environment variable holding the client
+	// secret. Defaults to GITHUB_CLIENT_SECRET.
+	ClientSecretEnv string `toml:"client_secret_env"`
+	// Allow lists the GitHub logins allowed to sign in.
+	Allow []string `toml:"allow"`
+	// URL is the address of a GitHub Enterprise server.
+	URL string `toml:"url"`
+}
+
+// Store configures where entry files are kept.
+type Store struct {
+	// Backend names the storage backend; "fs", the default, keeps entries
+	// in the notes directory.
+	Backend string `toml:"backend"`
+}
+
+// Sync configures til sync with a remote other than git.
+type Sync struct {
+	// Remote is synced with by til sync when --remote is not given: an
+	// s3://bucket/prefix URL, or the https:// URL of a WebDAV directory.
+	Remote string `toml:"remote"`
+	// Endpoint is the URL of an S3-compatible service. Defaults to AWS in
+	// Region.
+	Endpoint string `toml:"endpoint"`
+	// Region is the S3 region. Defaults to $AWS_REGION, else us-east-1.
+	Region string `toml:"region"`
+	// AccessKeyEnv and SecretKeyEnv name the environment variables holding
+	// the S3 credentials. Default to AWS_ACCESS_KEY_ID and
+	// AWS_SECRET_ACCESS_KEY.
+	AccessKeyEnv string `toml:"access_key_env"`
+	SecretKeyEnv string `toml:"secret_key_env"`
+	// PasswordEnv names the environment variable holding the WebDAV
+	// password of the user in the URL. Defaults to TIL_WEBDAV_PASSWORD.
+	PasswordEnv string `toml:"password_env"`
+}
+
+// Private configures the encryption of private entries.
+type Private struct {
+	// Identity is an age identity file or SSH private key. Without it,
+	// ~/.ssh/id_ed25519 and then ~/.ssh/id_rsa are used.
+	Identity string `toml:"identity"`
+	// Recipients are further age or SSH public keys entries are encrypted
+	// to, such as those of other machines.
+	Recipients []string `toml:"recipients"`
+}
+
+// Git configures version control of the notes tree.
+type Git struct {
+	// Commit commits entries created or edited with til new and til edit.
+	Commit bool `toml:"commit"`
+	// Push pushes after each such commit.
+	Push bool `toml:"push"`
+	// Dates takes entry dates from commit history instead of frontmatter,
+	// as the --git-dates flag does.
+	Dates bool `toml:"dates"`
+}
+
+// Embeddings configures an OpenAI-compatible embeddings endpoint, such as
+// Ollama's http://localhost:11434/v1/embeddings.
+type Embeddings struct {
+	URL   string `toml:"url"`
+	Model string `toml:"model"`
+	// KeyEnv names the environment variable holding the API key, if the
+	// endpoint needs one.
+	KeyEnv string `toml:"key_env"`
+}
+
+// Runner configures how code blocks of one language are executed.
+//
+// Command and Build are argument vectors in which "{file}" expands to the
+// snippet's source file and "{dir}" to its working directory.
+type Runner struct {
+	// Aliases are additional fence languages handled by this runner.
+	Aliases []string `toml:"aliases"`
+	// File is the name the snippet is written to, e.g. "main.py".
+	File string `toml:"file"`
+	// Files are extra files written to the working directory before
+	// building, keyed by name.
+	Files map[string]string `toml:"files"`
+	// Setup runs once in the working directory before Build.
+	Setup []string `toml:"setup"`
+	// Build optionally compiles the snippet before Command runs.
+	Build   []string `toml:"build"`
+	Command []string `toml:"command"`
+	Timeout Duration `toml:"timeout"`
+}
+
+// Sandbox confines the code blocks that til run and til verify execute;
+// see package runner. Entries can ask for it and tighten its limits in
+// their frontmatter.
+type Sandbox struct {
+	// Enabled runs every code block in the sandbox. til verify and til
+	// watch confine every block whether or not it is set.
+	Enabled bool `toml:"enabled"`
+	// Backend confines the run: "bwrap", "unshare", "sandbox-exec",
+	// "docker", "podman" or "none", which only applies the limits.
+	// Defaults to the first of bwrap, unshare and sandbox-exec installed.
+	Backend string `toml:"backend"`
+	// Image is the container image of the docker and podman backends.
+	Image string `toml:"image"`
+	// Memory caps the memory of each run, as in "256M".
+	Memory string `toml:"memory"`
+	// Network lets runs reach the network.
+	Network bool `toml:"network"`
+	// Timeout bounds each run, instead of the runner's timeout.
+	Timeout Duration `toml:"timeout"`
+}
+
+// Duration is a time.Duration written as a string such as "10s".
+type Duration struct {
+	time.Duration
+}
+
+// UnmarshalText parses a duration string.
+func (d *Duration) UnmarshalText(b []byte) error {
+	v, err := time.ParseDuration(string(b))
+	if err != nil {
+		return err
+	}
+	d.Duration = v
+	return nil
+}
+
+// MarshalText formats the duration.
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(d.Duration.String()), nil
+}
+
+// Path returns the location of the configuration file.
+func Path() (string, error) {
+	if file := os.Getenv("TIL_CONFIG"); file != "" {
+		return file, nil
+	}
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "til", "config.toml"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".config", "til", "config.toml"), nil
+}
+
+// StateDir returns the directory of the state til keeps outside notes
+// trees: $XDG_STATE_HOME/til, falling back to ~/.local/state/til.
+func StateDir() (string, error) {
+	if dir := os.Getenv("XDG_STATE_HOME"); dir != "" {
+		return filepath.Join(dir, "til"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".local", "state", "til"), nil
+}
+
+// Load reads the configuration file with the named profile applied, none
+// if empty, and then the environment.
+func Load(profile string) (*Config, error) {
+	file, err := Path()
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := LoadFile(file, profile)
+	if err != nil {
+		return nil, err
+	}
+	if err := cfg.applyEnv(os.LookupEnv); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// document is the configuration file: the settings and the profiles
+// overriding them.
+type document struct {
+	Config
+	Profiles map[string]toml.Primitive `toml:"profiles"`
+}
+
+// LoadFile reads the configuration from file with the named profile
+// applied, none if empty. A missing file is not an error, unless a profile
+// is asked for.
+func LoadFile(file, profile string) (*Config, error) {
+	var doc document
+	md, err := toml.DecodeFile(file, &doc)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("config %s: %w", file, err)
+	}
+	if profile == "" {
+		return &doc.Config, nil
+	}
+	p, ok := doc.Profiles[profile]
+	if !ok {
+		return nil, fmt.Errorf("config %s: no profile %q", file, profile)
+	}
+	if err := md.PrimitiveDecode(p, &doc.Config); err != nil {
+		return nil, fmt.Errorf("config %s: profile %s: %w", file, profile, err)
+	}
+	return &doc.Config, nil
+}
+
+// Profiles returns the names of the profiles in file, sorted.
+func Profiles(file string) ([]string, error) {
+	var doc document
+	if _, err := toml.DecodeFile(file, &doc); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("config %s: %w", file, err)
+	}
+	return slices.Sorted(maps.Keys(doc.Profiles)), nil
+}
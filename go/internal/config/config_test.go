@@ -0,0 +1,144 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+const file = `
+dir = "~/notes"
+tags = ["til"]
+
+[runners.python]
+file = "main.py"
+run = ["python3", "main.py"]
+
+[runners.go]
+file = "main.go"
+
+[site]
+theme = "default"
+base_url = "https://til.example.com"
+
+[profiles.work]
+dir = "~/work/notes"
+tags = ["work", "til"]
+
+[profiles.work.runners.python]
+file = "app.py"
+
+[profiles.work.site]
+theme = "work"
+
+[profiles.empty]
+`
+
+func writeFile(t *testing.T, data string) string {
+	t.Helper()
+	p := filepath.Join(t.TempDir(), "config.toml")
+	if err := os.WriteFile(p, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestLoadFile(t *testing.T) {
+	p := writeFile(t, file)
+	cfg, err := LoadFile(p, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Dir != "~/notes" || !slices.Equal(cfg.Tags, []string{"til"}) || cfg.Site.Theme != "default" || cfg.Runners["python"].File != "main.py" {
+		t.Errorf("LoadFile = %+v", cfg)
+	}
+
+	cfg, err = LoadFile(p, "work")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Dir != "~/work/notes" || !slices.Equal(cfg.Tags, []string{"work", "til"}) {
+		t.Errorf("work: dir %q, tags %q", cfg.Dir, cfg.Tags)
+	}
+	if cfg.Site.Theme != "work" || cfg.Site.BaseURL != "https://til.example.com" {
+		t.Errorf("work: site %+v, want the theme overridden only", cfg.Site)
+	}
+	if py := cfg.Runners["python"]; py.File != "app.py" || cfg.Runners["go"].File != "main.go" {
+		t.Errorf("work: runners %+v, want merged by key", cfg.Runners)
+	}
+
+	if cfg, err := LoadFile(p, "empty"); err != nil || cfg.Dir != "~/notes" {
+		t.Errorf("LoadFile(empty) = %+v, %v", cfg, err)
+	}
+	if _, err := LoadFile(p, "home"); err == nil || !strings.Contains(err.Error(), `no profile "home"`) {
+		t.Errorf("LoadFile(home) = %v", err)
+	}
+	if names, err := Profiles(p); err != nil || !slices.Equal(names, []string{"empty", "work"}) {
+		t.Errorf("Profiles = %q, %v", names, err)
+	}
+}
+
+func TestLoadFileMissing(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "config.toml")
+	if cfg, err := LoadFile(p, ""); err != nil || cfg.Dir != "" {
+		t.Errorf("LoadFile(missing) = %+v, %v", cfg, err)
+	}
+	if _, err := LoadFile(p, "work"); err == nil {
+		t.Error("LoadFile(missing, work) succeeded")
+	}
+	if names, err := Profiles(p); err != nil || names != nil {
+		t.Errorf("Profiles(missing) = %q, %v", names, err)
+	}
+	if _, err := LoadFile(writeFile(t, "dir = ["), ""); err == nil {
+		t.Error("LoadFile(bad toml) succeeded")
+	}
+}
+
+func TestApplyEnv(t *testing.T) {
+	env := map[string]string{
+		"TIL_DIR":             "/srv/notes",
+		"TIL_TAGS":            "a, b",
+		"TIL_SITE_BASE_URL":   "https://example.org",
+		"TIL_TRASH_RETENTION": "48h",
+		"TIL_RUNNERS":         "ignored",
+	}
+	cfg, _ := LoadFile(writeFile(t, file), "")
+	if err := cfg.applyEnv(func(k string) (string, bool) { v, ok := env[k]; return v, ok }); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Dir != "/srv/notes" || !slices.Equal(cfg.Tags, []string{"a", "b"}) || cfg.Site.BaseURL != "https://example.org" {
+		t.Errorf("applyEnv: dir %q, tags %q, base_url %q", cfg.Dir, cfg.Tags, cfg.Site.BaseURL)
+	}
+	if cfg.Trash.Retention.Duration != 48*time.Hour {
+		t.Errorf("applyEnv: retention %v", cfg.Trash.Retention)
+	}
+	if cfg.Site.Theme != "default" || len(cfg.Runners) != 2 {
+		t.Errorf("applyEnv changed settings not in the environment: %+v", cfg)
+	}
+
+	// An empty list empties the setting.
+	env = map[string]string{"TIL_TAGS": ""}
+	if err := cfg.applyEnv(func(k string) (string, bool) { v, ok := env[k]; return v, ok }); err != nil || len(cfg.Tags) != 0 {
+		t.Errorf("applyEnv(TIL_TAGS=) = %q, %v", cfg.Tags, err)
+	}
+
+	env = map[string]string{"TIL_TRASH_RETENTION": "a month"}
+	if err := cfg.applyEnv(func(k string) (string, bool) { v, ok := env[k]; return v, ok }); err == nil || !strings.Contains(err.Error(), "$TIL_TRASH_RETENTION") {
+		t.Errorf("applyEnv(bad duration) = %v", err)
+	}
+}
+
+func TestPath(t *testing.T) {
+	t.Setenv("TIL_CONFIG", "")
+	t.Setenv("XDG_CONFIG_HOME", "/xdg")
+	if p, _ := Path(); p != filepath.Join("/xdg", "til", "config.toml") {
+		t.Errorf("Path = %q", p)
+	}
+	t.Setenv("TIL_CONFIG", "/etc/til.toml")
+	if p, _ := Path(); p != "/etc/til.toml" {
+		t.Errorf("Path with $TIL_CONFIG = %q", p)
+	}
+}
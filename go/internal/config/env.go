@@ -0,0 +1,96 @@
+package config
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// envPrefix starts the names of the environment variables overriding
+// settings.
+const envPrefix = "TIL_"
+
+// applyEnv overrides the settings of c with the environment variables
+// lookup finds for them.
+func (c *Config) applyEnv(lookup func(string) (string, bool)) error {
+	return applyEnv(reflect.ValueOf(c).Elem(), "", lookup)
+}
+
+func applyEnv(v reflect.Value, key string, lookup func(string) (string, bool)) error {
+	t := v.Type()
+	for i := range t.NumField() {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("toml"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		k := name
+		if key != "" {
+			k = key + "." + name
+		}
+		fv := v.Field(i)
+		if _, ok := fv.Addr().Interface().(encoding.TextUnmarshaler); !ok && fv.Kind() == reflect.Struct {
+			if err := applyEnv(fv, k, lookup); err != nil {
+				return err
+			}
+			continue
+		}
+		env := envName(k)
+		s, ok := lookup(env)
+		if !ok {
+			continue
+		}
+		if err := setEnv(fv, s); err != nil {
+			return fmt.Errorf("$%s: %w", env, err)
+		}
+	}
+	return nil
+}
+
+// envName returns the environment variable overriding the setting of the
+// dotted key.
+func envName(key string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+// setEnv sets v to s, parsed as the kind of v. Slices are comma-separated;
+// an empty s empties them. Maps and slices of structs are left alone.
+func setEnv(v reflect.Value, s string) error {
+	if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return u.UnmarshalText([]byte(s))
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Slice:
+		if k := v.Type().Elem().Kind(); k == reflect.Struct || k == reflect.Slice || k == reflect.Map {
+			return nil
+		}
+		var parts []string
+		if s != "" {
+			parts = strings.Split(s, ",")
+		}
+		sl := reflect.MakeSlice(v.Type(), len(parts), len(parts))
+		for i, p := range parts {
+			if err := setEnv(sl.Index(i), strings.TrimSpace(p)); err != nil {
+				return err
+			}
+		}
+		v.Set(sl)
+	}
+	return nil
+}
@@ -0,0 +1,184 @@
+// Package crosspost publishes entries as articles on blogging platforms,
+// dev.to and Hashnode, linking back to the site as the canonical copy.
+//
+// The article ID a platform assigns is stored in the entry's frontmatter,
+// so posting the entry again updates the article:
+//
+//	crosspost:
+//	  devto:
+//	    id: "1712345"
+//	    url: https://dev.to/me/slices-3kd9
+package crosspost
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/canhta/til/go/internal/include"
+	"github.com/canhta/til/go/internal/links"
+	"github.com/canhta/til/go/internal/site"
+	"github.com/canhta/til/go/pkg/entry"
+	"github.com/canhta/til/go/pkg/render"
+)
+
+// Field is the frontmatter field remote articles are recorded in.
+const Field = "crosspost"
+
+// ErrNotFound is returned when updating an article the platform no longer
+// has.
+var ErrNotFound = errors.New("article not found")
+
+// Article is an entry as posted to a platform.
+type Article struct {
+	Title string `json:"title"`
+	// Markdown is the body without the title heading, with includes
+	// expanded and links to entries and assets made absolute.
+	Markdown    string   `json:"markdown"`
+	Tags        []string `json:"tags"`
+	Description string   `json:"description"`
+	// Canonical is the entry's page on the site.
+	Canonical string `json:"canonical_url"`
+	Published bool   `json:"published"`
+}
+
+// Remote is an article on a platform.
+type Remote struct {
+	ID  string `yaml:"id" json:"id"`
+	URL string `yaml:"url" json:"url"`
+}
+
+// Target is a platform articles are posted to.
+type Target interface {
+	Name() string
+	// Post creates the article, or updates prev when it is not nil.
+	Post(ctx context.Context, a Article, prev *Remote) (Remote, error)
+}
+
+// Prepare returns the article for e, whose links are resolved against
+// ix and s, and the references left unresolved. s must have an origin.
+func Prepare(e *entry.Entry, s *site.Site, ix *links.Index) (Article, []string) {
+	var unresolved []string
+	x := include.Expand(e, ix)
+	for _, err := range x.Errors {
+		unresolved = append(unresolved, err.Error())
+	}
+	body, dangling := s.AbsLinks(e.Path, render.StripTitle(x.Body))
+	unresolved = append(unresolved, dangling...)
+	a := Article{
+		Title:       e.Meta.Title,
+		Markdown:    strings.TrimSpace(string(body)) + "\n",
+		Tags:        e.Meta.Tags,
+		Description: plain(entry.Excerpt(e.Body, 30)),
+		Published:   !e.Meta.Draft,
+	}
+	if p := s.Page(e.Path); p != nil {
+		a.Canonical = s.AbsURL(p.URL)
+	}
+	if a.Tags == nil {
+		a.Tags = []string{}
+	}
+	return a, unresolved
+}
+
+// Remotes returns the articles recorded in e's frontmatter, keyed by
+// target name.
+func Remotes(e *entry.Entry) (map[string]Remote, error) {
+	out := map[string]Remote{}
+	if e.Front == nil {
+		return out, nil
+	}
+	f, err := entry.ParseFront(e.Front)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := f.Get(Field, &out); err != nil {
+		return nil, fmt.Errorf("%s: %w", Field, err)
+	}
+	return out, nil
+}
+
+// Record returns the entry file data with r recorded as the article on
+// target.
+func Record(data []byte, target string, r Remote) ([]byte, error) {
+	return entry.Rewrite(data, func(f *entry.Front) error {
+		remotes := map[string]Remote{}
+		if _, err := f.Get(Field, &remotes); err != nil {
+			return err
+		}
+		remotes[target] = r
+		return f.Set(Field, remotes)
+	})
+}
+
+var (
+	tagRE = regexp.MustCompile(`[^a-z0-9]+`)
+	// Markdown links and images, and wiki links with optional labels.
+	mdLinkRE   = regexp.MustCompile(`!?\[([^\[\]]*)\]\([^)]*\)`)
+	wikiLinkRE = regexp.MustCompile(`\[\[([^\[\]|]+)(?:\|([^\[\]]+))?\]\]`)
+)
+
+// plain reduces the links of a markdown excerpt to their text.
+func plain(s string) string {
+	s = wikiLinkRE.ReplaceAllStringFunc(s, func(m string) string {
+		sub := wikiLinkRE.FindStringSubmatch(m)
+		if sub[2] != "" {
+			return sub[2]
+		}
+		return sub[1]
+	})
+	s = mdLinkRE.ReplaceAllString(s, "$1")
+	return strings.Join(strings.Fields(strings.NewReplacer("`", "", "**", "", "__", "").Replace(s)), " ")
+}
+
+// tagSlug reduces a tag to the lowercase letters and digits platforms
+// accept.
+func tagSlug(t string) string {
+	return tagRE.ReplaceAllString(strings.ToLower(t), "")
+}
+
+// do sends a JSON request and decodes a 2xx JSON response into out.
+func do(ctx context.Context, client *http.Client, method, url string, header http.Header, in, out any) error {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header = header.Clone()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrNotFound
+	}
+	if resp.StatusCode/100 != 2 {
+		var e struct {
+			Error string `json:"error"`
+		}
+		if json.Unmarshal(data, &e) == nil && e.Error != "" {
+			return fmt.Errorf("%s: %s", resp.Status, e.Error)
+		}
+		return errors.New(resp.Status)
+	}
+	return json.Unmarshal(data, out)
+}
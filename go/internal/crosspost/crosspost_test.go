@@ -0,0 +1,89 @@
+package crosspost
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/canhta/til/go/internal/links"
+	"github.com/canhta/til/go/internal/site"
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+func parse(t *testing.T, p, data string) *entry.Entry {
+	t.Helper()
+	e, err := entry.Parse(p, []byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+func TestPrepare(t *testing.T) {
+	sl := parse(t, "go/slices.md", "---\ntitle: Slices\ntags: [go, Data Structures]\ndraft: true\n---\n# Slices\n\nSee [maps](maps.md), [[git/rebase|rebasing]] and [[nowhere]].\n\n{{include \"go/maps#order\"}}\n")
+	maps := parse(t, "go/maps.md", "---\ntitle: Maps\n---\n\n## Order\n\nRandom **order**.\n")
+	rebase := parse(t, "git/rebase.md", "---\ntitle: Rebase\n---\n")
+	entries := []*entry.Entry{sl, maps, rebase}
+	s := site.New(entries, site.Options{BaseURL: "https://til.example/notes/"})
+
+	a, unresolved := Prepare(sl, s, links.NewIndex(entries))
+	want := Article{
+		Title:       "Slices",
+		Markdown:    "See [maps](https://til.example/notes/go/maps/), [rebasing](https://til.example/notes/git/rebase/) and [[nowhere]].\n\n\nRandom **order**.\n",
+		Tags:        []string{"go", "Data Structures"},
+		Description: "See maps, rebasing and nowhere. {{include \"go/maps#order\"}}",
+		Canonical:   "https://til.example/notes/go/slices/",
+		Published:   false,
+	}
+	if a.Title != want.Title || a.Markdown != want.Markdown || !slices.Equal(a.Tags, want.Tags) || a.Description != want.Description || a.Canonical != want.Canonical || a.Published {
+		t.Errorf("Prepare =\n%+v\nwant\n%+v", a, want)
+	}
+	if len(unresolved) != 1 {
+		t.Errorf("unresolved = %q, want [[nowhere]]", unresolved)
+	}
+	if a, _ := Prepare(rebase, s, links.NewIndex(entries)); a.Tags == nil || !a.Published {
+		t.Errorf("Prepare(rebase) = %+v", a)
+	}
+}
+
+func TestRecord(t *testing.T) {
+	data := []byte("---\ntitle: Slices\n---\n\nBody.\n")
+	data, err := Record(data, "devto", Remote{ID: "17", URL: "https://dev.to/me/slices"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, err = Record(data, "hashnode", Remote{ID: "abc", URL: "https://me.hashnode.dev/slices"}); err != nil {
+		t.Fatal(err)
+	}
+	if data, err = Record(data, "devto", Remote{ID: "18", URL: "https://dev.to/me/slices-2"}); err != nil {
+		t.Fatal(err)
+	}
+	remotes, err := Remotes(parse(t, "go/slices.md", string(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(remotes) != 2 || remotes["devto"].ID != "18" || remotes["hashnode"].URL != "https://me.hashnode.dev/slices" {
+		t.Errorf("Remotes = %v\n%s", remotes, data)
+	}
+	if got, _ := Remotes(parse(t, "go/x.md", "# X\n")); len(got) != 0 {
+		t.Errorf("Remotes without frontmatter = %v", got)
+	}
+	if _, err := Remotes(parse(t, "go/x.md", "---\ncrosspost: nope\n---\n")); err == nil {
+		t.Error("Remotes accepted a scalar crosspost field")
+	}
+}
+
+func TestPlain(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"See [maps](maps.md) and ![img](a.png).", "See maps and img."},
+		{"[[go/maps]] and [[git/rebase|rebasing]]", "go/maps and rebasing"},
+		{"Use `--onto` **now**\n  please", "Use --onto now please"},
+	}
+	for _, tt := range tests {
+		if got := plain(tt.in); got != tt.want {
+			t.Errorf("plain(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+	if got := tagSlug("Data Structures!"); got != "datastructures" {
+		t.Errorf("tagSlug = %q", got)
+	}
+}
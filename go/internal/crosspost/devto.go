@@ -0,0 +1,61 @@
+package crosspost
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+)
+
+// DevToAPI is the dev.to (Forem) API endpoint.
+const DevToAPI = "https://dev.to/api"
+
+// DevTo posts to dev.to, or another Forem instance, with an API key.
+type DevTo struct {
+	Key string
+	// API defaults to DevToAPI.
+	API    string
+	Client *http.Client
+}
+
+// Name implements Target.
+func (DevTo) Name() string { return "devto" }
+
+// devtoTags is the most tags dev.to accepts on an article.
+const devtoTags = 4
+
+// Post implements Target. Articles not published are saved as drafts.
+func (d DevTo) Post(ctx context.Context, a Article, prev *Remote) (Remote, error) {
+	var tags []string
+	for _, t := range a.Tags {
+		if s := tagSlug(t); s != "" && len(tags) < devtoTags {
+			tags = append(tags, s)
+		}
+	}
+	article := map[string]any{
+		"title":         a.Title,
+		"body_markdown": a.Markdown,
+		"published":     a.Published,
+		"tags":          tags,
+		"description":   a.Description,
+	}
+	if a.Canonical != "" {
+		article["canonical_url"] = a.Canonical
+	}
+	api := d.API
+	if api == "" {
+		api = DevToAPI
+	}
+	method, url := http.MethodPost, api+"/articles"
+	if prev != nil {
+		method, url = http.MethodPut, url+"/"+prev.ID
+	}
+	var resp struct {
+		ID  int64  `json:"id"`
+		URL string `json:"url"`
+	}
+	header := http.Header{"Api-Key": {d.Key}}
+	if err := do(ctx, d.Client, method, url, header, map[string]any{"article": article}, &resp); err != nil {
+		return Remote{}, err
+	}
+	return Remote{ID: strconv.FormatInt(resp.ID, 10), URL: resp.URL}, nil
+}
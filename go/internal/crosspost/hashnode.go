@@ -0,0 +1,100 @@
+package crosspost
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// HashnodeAPI is Hashnode's GraphQL endpoint.
+const HashnodeAPI = "https://gql.hashnode.com"
+
+// Hashnode posts to a Hashnode publication with a personal access token.
+type Hashnode struct {
+	Token string
+	// Publication is the ID of the publication posted to.
+	Publication string
+	// API defaults to HashnodeAPI.
+	API    string
+	Client *http.Client
+}
+
+// Name implements Target.
+func (Hashnode) Name() string { return "hashnode" }
+
+const (
+	hashnodePublish = `mutation Publish($input: PublishPostInput!) {
+  publishPost(input: $input) { post { id url } }
+}`
+	hashnodeUpdate = `mutation Update($input: UpdatePostInput!) {
+  updatePost(input: $input) { post { id url } }
+}`
+)
+
+// Post implements Target. Hashnode has no drafts through this API, so
+// articles not published are refused.
+func (h Hashnode) Post(ctx context.Context, a Article, prev *Remote) (Remote, error) {
+	if !a.Published {
+		return Remote{}, errors.New("drafts cannot be posted to Hashnode")
+	}
+	if h.Publication == "" {
+		return Remote{}, errors.New("no publication ID")
+	}
+	tags := []map[string]string{}
+	for _, t := range a.Tags {
+		if s := tagSlug(t); s != "" {
+			tags = append(tags, map[string]string{"slug": s, "name": t})
+		}
+	}
+	input := map[string]any{
+		"title":           a.Title,
+		"contentMarkdown": a.Markdown,
+		"tags":            tags,
+		"subtitle":        a.Description,
+	}
+	if a.Canonical != "" {
+		input["originalArticleURL"] = a.Canonical
+	}
+	query, op := hashnodePublish, "publishPost"
+	if prev != nil {
+		query, op = hashnodeUpdate, "updatePost"
+		input["id"] = prev.ID
+	} else {
+		input["publicationId"] = h.Publication
+	}
+	var resp struct {
+		Data map[string]struct {
+			Post Remote `json:"post"`
+		} `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	api := h.API
+	if api == "" {
+		api = HashnodeAPI
+	}
+	header := http.Header{"Authorization": {h.Token}}
+	body := map[string]any{"query": query, "variables": map[string]any{"input": input}}
+	if err := do(ctx, h.Client, http.MethodPost, api, header, body, &resp); err != nil {
+		return Remote{}, err
+	}
+	if len(resp.Errors) > 0 {
+		var msgs []string
+		for _, e := range resp.Errors {
+			msgs = append(msgs, e.Message)
+		}
+		msg := strings.Join(msgs, "; ")
+		if prev != nil && strings.Contains(strings.ToLower(msg), "not found") {
+			return Remote{}, fmt.Errorf("%w: %s", ErrNotFound, msg)
+		}
+		return Remote{}, errors.New(msg)
+	}
+	r := resp.Data[op].Post
+	if r.ID == "" {
+		return Remote{}, errors.New("no post in response")
+	}
+	return r, nil
+}
@@ -0,0 +1,176 @@
+package crosspost
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+var article = Article{
+	Title:       "Slices",
+	Markdown:    "Body.\n",
+	Tags:        []string{"go", "Data Structures", "c++", "a", "b"},
+	Description: "Body.",
+	Canonical:   "https://til.example/go/slices/",
+	Published:   true,
+}
+
+func TestDevTo(t *testing.T) {
+	var method, path, key string
+	var got struct {
+		Article map[string]any `json:"article"`
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method, path, key = r.Method, r.URL.Path, r.Header.Get("Api-Key")
+		got.Article = nil
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Error(err)
+		}
+		w.Write([]byte(`{"id":17,"url":"https://dev.to/me/slices"}`))
+	}))
+	defer srv.Close()
+	d := DevTo{Key: "k", API: srv.URL}
+	ctx := context.Background()
+
+	r, err := d.Post(ctx, article, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r != (Remote{ID: "17", URL: "https://dev.to/me/slices"}) {
+		t.Errorf("Post = %+v", r)
+	}
+	if method != http.MethodPost || path != "/articles" || key != "k" {
+		t.Errorf("request = %s %s, key %q", method, path, key)
+	}
+	var tags []string
+	for _, s := range got.Article["tags"].([]any) {
+		tags = append(tags, s.(string))
+	}
+	if want := []string{"go", "datastructures", "c", "a"}; !slices.Equal(tags, want) {
+		t.Errorf("tags = %q, want %q", tags, want)
+	}
+	if got.Article["canonical_url"] != article.Canonical || got.Article["published"] != true || got.Article["body_markdown"] != "Body.\n" {
+		t.Errorf("article = %v", got.Article)
+	}
+
+	if _, err := d.Post(ctx, Article{Title: "Draft"}, &r); err != nil {
+		t.Fatal(err)
+	}
+	if method != http.MethodPut || path != "/articles/17" {
+		t.Errorf("update = %s %s", method, path)
+	}
+	if _, ok := got.Article["canonical_url"]; ok || got.Article["published"] != false {
+		t.Errorf("draft article = %v", got.Article)
+	}
+}
+
+func TestDevToErrors(t *testing.T) {
+	tests := []struct {
+		status int
+		body   string
+		want   string
+	}{
+		{http.StatusNotFound, `{"error":"not found"}`, ErrNotFound.Error()},
+		{http.StatusUnprocessableEntity, `{"error":"Title can't be blank"}`, "422 Unprocessable Entity: Title can't be blank"},
+		{http.StatusUnauthorized, `nope`, "401 Unauthorized"},
+	}
+	for _, tt := range tests {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(tt.status)
+			w.Write([]byte(tt.body))
+		}))
+		_, err := DevTo{API: srv.URL}.Post(context.Background(), article, &Remote{ID: "1"})
+		srv.Close()
+		if err == nil || err.Error() != tt.want {
+			t.Errorf("Post with %d = %v, want %q", tt.status, err, tt.want)
+		}
+	}
+}
+
+// hashnode serves the GraphQL API, answering with the response for the
+// request's operation.
+func hashnode(t *testing.T, responses map[string]string) (*httptest.Server, *map[string]any) {
+	t.Helper()
+	var input map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "tok" {
+			t.Errorf("Authorization = %q", r.Header.Get("Authorization"))
+		}
+		var req struct {
+			Query     string
+			Variables struct{ Input map[string]any }
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Error(err)
+		}
+		input = req.Variables.Input
+		for op, resp := range responses {
+			if strings.Contains(req.Query, op+"(") {
+				w.Write([]byte(resp))
+				return
+			}
+		}
+		t.Errorf("unexpected query %s", req.Query)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &input
+}
+
+func TestHashnode(t *testing.T) {
+	srv, input := hashnode(t, map[string]string{
+		"publishPost": `{"data":{"publishPost":{"post":{"id":"p1","url":"https://me.hashnode.dev/slices"}}}}`,
+		"updatePost":  `{"data":{"updatePost":{"post":{"id":"p1","url":"https://me.hashnode.dev/slices-1"}}}}`,
+	})
+	h := Hashnode{Token: "tok", Publication: "pub", API: srv.URL}
+	ctx := context.Background()
+
+	r, err := h.Post(ctx, article, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r != (Remote{ID: "p1", URL: "https://me.hashnode.dev/slices"}) {
+		t.Errorf("Post = %+v", r)
+	}
+	in := *input
+	if in["publicationId"] != "pub" || in["title"] != "Slices" || in["contentMarkdown"] != "Body.\n" || in["originalArticleURL"] != article.Canonical || in["subtitle"] != "Body." {
+		t.Errorf("publish input = %v", in)
+	}
+	if tags := in["tags"].([]any); len(tags) != 5 || tags[1].(map[string]any)["slug"] != "datastructures" || tags[1].(map[string]any)["name"] != "Data Structures" {
+		t.Errorf("tags = %v", tags)
+	}
+
+	if r, err = h.Post(ctx, article, &r); err != nil || r.URL != "https://me.hashnode.dev/slices-1" {
+		t.Fatalf("update = %+v, %v", r, err)
+	}
+	if in := *input; in["id"] != "p1" || in["publicationId"] != nil {
+		t.Errorf("update input = %v", in)
+	}
+}
+
+func TestHashnodeErrors(t *testing.T) {
+	srv, _ := hashnode(t, map[string]string{
+		"publishPost": `{"data":{"publishPost":{"post":{"id":""}}}}`,
+		"updatePost":  `{"errors":[{"message":"Post not found"},{"message":"try again"}]}`,
+	})
+	h := Hashnode{Token: "tok", Publication: "pub", API: srv.URL}
+	ctx := context.Background()
+
+	if _, err := h.Post(ctx, Article{Title: "Draft"}, nil); err == nil || !strings.Contains(err.Error(), "drafts cannot be posted") {
+		t.Errorf("Post(draft) = %v", err)
+	}
+	if _, err := (Hashnode{API: srv.URL}).Post(ctx, article, nil); err == nil || !strings.Contains(err.Error(), "no publication ID") {
+		t.Errorf("Post without a publication = %v", err)
+	}
+	if _, err := h.Post(ctx, article, nil); err == nil || err.Error() != "no post in response" {
+		t.Errorf("Post with no post = %v", err)
+	}
+	_, err := h.Post(ctx, article, &Remote{ID: "p1"})
+	if !errors.Is(err, ErrNotFound) || !strings.Contains(err.Error(), "Post not found; try again") {
+		t.Errorf("update of a deleted post = %v", err)
+	}
+}
@@ -0,0 +1,197 @@
+// Package dedupe finds entries with the same or nearly the same content.
+//
+// Each body is cut into shingles, the runs of Shingle consecutive words,
+// and pairs are scored by the Jaccard similarity of their shingle sets: the
+// share of shingles they have in common. Comparing every pair does not scale
+// with the tree, so only pairs whose MinHash signatures agree on a whole
+// band are compared (locality-sensitive hashing). Pairs scoring 0.5 or more
+// are all but certain to be found; those much below it may be missed.
+package dedupe
+
+import (
+	"hash/fnv"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+// Shingle is the number of words in each shingle.
+const Shingle = 3
+
+// DefaultThreshold is the lowest score reported by default.
+const DefaultThreshold = 0.7
+
+// bands × rows is the length of a MinHash signature.
+const (
+	bands = 32
+	rows  = 4
+)
+
+// Pair is two entries found alike, A before B by path.
+type Pair struct {
+	A string `json:"a"`
+	B string `json:"b"`
+	// Score is the Jaccard similarity of their shingles, from 0 to 1.
+	Score float64 `json:"score"`
+	// Identical is set when the bodies have the same words in the same
+	// order, whatever the case, punctuation and spacing.
+	Identical bool `json:"identical"`
+}
+
+type doc struct {
+	e        *entry.Entry
+	words    string
+	shingles map[uint64]bool
+	sig      [bands * rows]uint64
+}
+
+// Find returns the pairs of entries scoring at least threshold, most alike
+// first. Entries without words are ignored.
+func Find(entries []*entry.Entry, threshold float64) []Pair {
+	var docs []*doc
+	for _, e := range entries {
+		if d := newDoc(e); d != nil {
+			docs = append(docs, d)
+		}
+	}
+	sort.Slice(docs, func(i, j int) bool { return docs[i].e.Path < docs[j].e.Path })
+
+	candidates := map[[2]int]bool{}
+	for b := range bands {
+		buckets := map[uint64][]int{}
+		for i, d := range docs {
+			k := uint64(b)
+			for _, v := range d.sig[b*rows : (b+1)*rows] {
+				k = mix(k ^ v)
+			}
+			for _, j := range buckets[k] {
+				candidates[[2]int{j, i}] = true
+			}
+			buckets[k] = append(buckets[k], i)
+		}
+	}
+
+	var pairs []Pair
+	for c := range candidates {
+		a, b := docs[c[0]], docs[c[1]]
+		p := Pair{A: a.e.Path, B: b.e.Path, Identical: a.words == b.words}
+		if p.Identical {
+			p.Score = 1
+		} else {
+			p.Score = jaccard(a.shingles, b.shingles)
+		}
+		if p.Score >= threshold {
+			pairs = append(pairs, p)
+		}
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i].Score != pairs[j].Score {
+			return pairs[i].Score > pairs[j].Score
+		}
+		if pairs[i].A != pairs[j].A {
+			return pairs[i].A < pairs[j].A
+		}
+		return pairs[i].B < pairs[j].B
+	})
+	return pairs
+}
+
+func newDoc(e *entry.Entry) *doc {
+	words := strings.FieldsFunc(strings.ToLower(string(e.Body)), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(words) == 0 {
+		return nil
+	}
+	d := &doc{e: e, words: strings.Join(words, " "), shingles: map[uint64]bool{}}
+	n := max(len(words)-Shingle+1, 1)
+	for i := range n {
+		h := fnv.New64a()
+		h.Write([]byte(strings.Join(words[i:min(i+Shingle, len(words))], " ")))
+		d.shingles[h.Sum64()] = true
+	}
+	for i := range d.sig {
+		d.sig[i] = ^uint64(0)
+	}
+	for s := range d.shingles {
+		for i := range d.sig {
+			if v := mix(s ^ seeds[i]); v < d.sig[i] {
+				d.sig[i] = v
+			}
+		}
+	}
+	return d
+}
+
+func jaccard(a, b map[uint64]bool) float64 {
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+	common := 0
+	for s := range a {
+		if b[s] {
+			common++
+		}
+	}
+	return float64(common) / float64(len(a)+len(b)-common)
+}
+
+// seeds pick the hash function of each signature value.
+var seeds = func() (s [bands * rows]uint64) {
+	x := uint64(0x9e3779b97f4a7c15)
+	for i := range s {
+		x += 0x9e3779b97f4a7c15
+		s[i] = mix(x)
+	}
+	return s
+}()
+
+// mix is the finalizer of splitmix64.
+func mix(x uint64) uint64 {
+	x = (x ^ x>>30) * 0xbf58476d1ce4e5b9
+	x = (x ^ x>>27) * 0x94d049bb133111eb
+	return x ^ x>>31
+}
+
+// Richer returns a and b ordered by the richness of their bodies: more
+// words, then more lines of code, then more bytes. a comes first on a tie.
+func Richer(a, b *entry.Entry) (keep, drop *entry.Entry) {
+	ka := []int{a.Counts.Words, a.Counts.CodeLines, len(a.Body)}
+	kb := []int{b.Counts.Words, b.Counts.CodeLines, len(b.Body)}
+	if slices.Compare(kb, ka) > 0 {
+		return b, a
+	}
+	return a, b
+}
+
+// Merge returns the file data of keep with drop merged into it: it gets the
+// tags of both, its own first, and the earlier of their creation dates.
+// Its body is kept as is.
+func Merge(data []byte, keep, drop *entry.Entry) ([]byte, error) {
+	tags := slices.Clone(keep.Meta.Tags)
+	for _, t := range drop.Meta.Tags {
+		if !slices.Contains(tags, t) {
+			tags = append(tags, t)
+		}
+	}
+	setTags := !slices.Equal(tags, keep.Meta.Tags)
+	d := drop.Meta.Date
+	setDate := !d.IsZero() && (keep.Meta.Date.IsZero() || d.Before(keep.Meta.Date))
+	if !setTags && !setDate {
+		return data, nil
+	}
+	return entry.Rewrite(data, func(f *entry.Front) error {
+		if setTags {
+			if err := f.Set("tags", tags); err != nil {
+				return err
+			}
+		}
+		if setDate {
+			return f.Set("date", d)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,93 @@
+package dedupe
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+func parse(t *testing.T, p, data string) *entry.Entry {
+	t.Helper()
+	e, err := entry.Parse(p, []byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+const text = "Appending to a slice may reuse the backing array of another slice cut from the same array, " +
+	"so writes through one show through the other until append runs out of capacity and reallocates. " +
+	"Copy the slice first when both must change independently, or use a full slice expression to cap it."
+
+func TestFind(t *testing.T) {
+	entries := []*entry.Entry{
+		parse(t, "go/slices.md", "# Slices\n\n"+text+"\n"),
+		parse(t, "go/shared.md", "# Shared arrays\n\n"+strings.ToUpper(strings.ReplaceAll(text, ",", " ,;"))+"\n"),
+		parse(t, "go/near.md", "# Near\n\n"+strings.Replace(text, "reallocates", "grows", 1)+"\n"),
+		parse(t, "go/maps.md", "# Maps\n\nIterating over a map visits its keys in an order that changes from run to run.\n"),
+		parse(t, "go/empty.md", "---\ntitle: Empty\n---\n"),
+		parse(t, "go/empty2.md", "---\ntitle: Empty too\n---\n\n```\n```\n"),
+	}
+	pairs := Find(entries, 0.5)
+	if len(pairs) != 3 {
+		t.Fatalf("Find = %+v, want the three pairs of slice entries", pairs)
+	}
+	// The headings count as words, so the bodies differ by a few.
+	for i, p := range pairs {
+		if p.A >= p.B || p.A == "go/maps.md" || p.B == "go/maps.md" || p.Identical || p.Score < 0.8 || p.Score >= 1 {
+			t.Errorf("pair = %+v", p)
+		}
+		if i > 0 && p.Score > pairs[i-1].Score {
+			t.Errorf("pairs not most alike first: %+v", pairs)
+		}
+	}
+	if got := Find(entries, 0.99); len(got) != 0 {
+		t.Errorf("Find(0.99) = %+v", got)
+	}
+
+	same := []*entry.Entry{
+		parse(t, "a.md", "Hello, World!\n"),
+		parse(t, "b.md", "hello   world\n"),
+	}
+	if got := Find(same, 1); len(got) != 1 || !got[0].Identical || got[0].Score != 1 || got[0].A != "a.md" {
+		t.Errorf("Find(identical) = %+v", got)
+	}
+}
+
+func TestRicher(t *testing.T) {
+	short := parse(t, "a.md", "one two\n")
+	long := parse(t, "b.md", "one two three\n")
+	code := parse(t, "c.md", "one two\n\n```go\nx := 1\n```\n")
+	for _, tt := range []struct{ a, b, keep *entry.Entry }{
+		{short, long, long},
+		{long, short, long},
+		{short, code, code},
+		{short, parse(t, "d.md", "one two\n"), short},
+	} {
+		if keep, _ := Richer(tt.a, tt.b); keep != tt.keep {
+			t.Errorf("Richer(%s, %s) keeps %s", tt.a.Path, tt.b.Path, keep.Path)
+		}
+	}
+}
+
+func TestMerge(t *testing.T) {
+	data := "---\ntitle: Slices\ndate: 2024-06-01\ntags: [go, slices]\n---\n\nBody.\n"
+	keep := parse(t, "go/slices.md", data)
+	tests := []struct {
+		drop, want string
+	}{
+		{"---\ntitle: Old\ndate: 2023-01-02\ntags: [arrays, go]\n---\n", "---\ntitle: Slices\ndate: 2023-01-02\ntags: [go, slices, arrays]\n---\n\nBody.\n"},
+		{"---\ntitle: Later\ndate: 2025-01-01\ntags: [slices]\n---\n", data},
+		{"# Undated\n", data},
+	}
+	for _, tt := range tests {
+		got, err := Merge([]byte(data), keep, parse(t, "go/dup.md", tt.drop))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != tt.want {
+			t.Errorf("Merge(%q) =\n%s\nwant\n%s", tt.drop, got, tt.want)
+		}
+	}
+}
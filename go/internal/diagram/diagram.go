@@ -0,0 +1,120 @@
+// Package diagram renders diagram sources, such as mermaid fences, to SVG
+// with an external command, caching the results.
+package diagram
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/canhta/til/go/internal/fsutil"
+	"github.com/canhta/til/go/internal/notes"
+)
+
+// Dir is the cache directory inside the notes state directory.
+const Dir = "diagrams"
+
+// Timeout bounds each run of the command.
+const Timeout = time.Minute
+
+// ErrUnavailable is returned when no command is configured or found.
+var ErrUnavailable = errors.New("no diagram renderer available")
+
+// DefaultMermaid is the mermaid-cli invocation used when mmdc is on the
+// PATH and no command is configured. In arguments, "{file}" expands to the
+// source file, "{out}" to the SVG file to write and "{id}" to an id for the
+// SVG element, unique to the source so several diagrams can share a page.
+var DefaultMermaid = []string{"mmdc", "--quiet", "--input", "{file}", "--output", "{out}", "--svgId", "{id}"}
+
+// Renderer renders mermaid diagrams.
+type Renderer struct {
+	command []string
+	cache   string
+}
+
+// New returns a Renderer for tree running command, or DefaultMermaid when
+// command is empty and mmdc is installed. Its Render method returns
+// ErrUnavailable when there is no command to run.
+func New(tree *notes.Tree, command []string) *Renderer {
+	if len(command) == 0 {
+		if _, err := exec.LookPath(DefaultMermaid[0]); err == nil {
+			command = DefaultMermaid
+		}
+	}
+	return &Renderer{command: command, cache: tree.StatePath(Dir)}
+}
+
+// Command returns the command line r runs, nil when there is none.
+func (r *Renderer) Command() []string {
+	return r.command
+}
+
+// Render returns the SVG for src, from the cache when the same source was
+// rendered before.
+func (r *Renderer) Render(ctx context.Context, src []byte) ([]byte, error) {
+	if len(r.command) == 0 {
+		return nil, ErrUnavailable
+	}
+	sum := sha256.Sum256(append([]byte(strings.Join(r.command, "\x00")+"\x00"), src...))
+	key := hex.EncodeToString(sum[:])
+	cached := filepath.Join(r.cache, key+".svg")
+	if svg, err := os.ReadFile(cached); err == nil {
+		return svg, nil
+	}
+	svg, err := r.run(ctx, src, "diagram-"+key[:12])
+	if err != nil {
+		return nil, err
+	}
+	if err := fsutil.WriteFile(cached, svg, 0o644); err != nil {
+		return nil, err
+	}
+	return svg, nil
+}
+
+func (r *Renderer) run(ctx context.Context, src []byte, id string) ([]byte, error) {
+	dir, err := os.MkdirTemp("", "til-diagram-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	file, out := filepath.Join(dir, "diagram.mmd"), filepath.Join(dir, "diagram.svg")
+	if err := os.WriteFile(file, src, 0o644); err != nil {
+		return nil, err
+	}
+	rep := strings.NewReplacer("{file}", file, "{out}", out, "{id}", id)
+	args := make([]string, len(r.command))
+	for i, a := range r.command {
+		args[i] = rep.Replace(a)
+	}
+	ctx, cancel := context.WithTimeout(ctx, Timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stderr, &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%s: %w: %s", args[0], err, msg)
+		}
+		return nil, fmt.Errorf("%s: %w", args[0], err)
+	}
+	svg, err := os.ReadFile(out)
+	if err != nil {
+		return nil, fmt.Errorf("%s wrote no SVG: %w", args[0], err)
+	}
+	// Inlined SVG takes no XML declaration.
+	if bytes.HasPrefix(svg, []byte("<?xml")) {
+		if i := bytes.Index(svg, []byte("?>")); i >= 0 {
+			svg = svg[i+2:]
+		}
+	}
+	return bytes.TrimSpace(svg), nil
+}
@@ -0,0 +1,108 @@
+package diagram
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/canhta/til/go/internal/notes"
+)
+
+// fakeMermaid writes a script standing in for mmdc: it logs each run to
+// runs, fails on sources containing "bad" and writes no SVG for "none".
+func fakeMermaid(t *testing.T) (command []string, runs string) {
+	t.Helper()
+	dir := t.TempDir()
+	runs = filepath.Join(dir, "runs")
+	script := filepath.Join(dir, "mmdc.sh")
+	body := `echo run >> "` + runs + `"
+case "$(cat "$1")" in
+*bad*) echo "Parse error on line 1" >&2; exit 1 ;;
+*none*) exit 0 ;;
+esac
+printf '<?xml version="1.0"?>\n<svg id="%s">%s</svg>\n' "$3" "$(cat "$1")" > "$2"
+`
+	if err := os.WriteFile(script, []byte(body), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return []string{"sh", script, "{file}", "{out}", "{id}"}, runs
+}
+
+func countRuns(t *testing.T, runs string) int {
+	t.Helper()
+	data, err := os.ReadFile(runs)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Count(string(data), "run\n")
+}
+
+func TestRender(t *testing.T) {
+	command, runs := fakeMermaid(t)
+	tree := notes.Open(t.TempDir())
+	r := New(tree, command)
+	ctx := context.Background()
+
+	svg, err := r.Render(ctx, []byte("graph TD; A-->B"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(svg)
+	if !strings.HasPrefix(got, `<svg id="diagram-`) || !strings.HasSuffix(got, ">graph TD; A-->B</svg>") {
+		t.Errorf("Render = %q, want the SVG without its XML declaration", got)
+	}
+	again, err := r.Render(ctx, []byte("graph TD; A-->B"))
+	if err != nil || string(again) != got {
+		t.Errorf("second Render = %q, %v", again, err)
+	}
+	if n := countRuns(t, runs); n != 1 {
+		t.Errorf("command ran %d times, want 1 with the cache", n)
+	}
+	other, _ := r.Render(ctx, []byte("graph LR; C-->D"))
+	if string(other[:22]) == got[:22] {
+		t.Errorf("diagrams share the id of %q", other)
+	}
+	if n := countRuns(t, runs); n != 2 {
+		t.Errorf("command ran %d times, want 2", n)
+	}
+
+	// A new command does not reuse the other's cache.
+	if _, err := New(tree, append(command, "--theme")).Render(ctx, []byte("graph TD; A-->B")); err != nil {
+		t.Fatal(err)
+	}
+	if n := countRuns(t, runs); n != 3 {
+		t.Errorf("command ran %d times, want 3", n)
+	}
+}
+
+func TestRenderErrors(t *testing.T) {
+	command, _ := fakeMermaid(t)
+	r := New(notes.Open(t.TempDir()), command)
+	ctx := context.Background()
+	if _, err := r.Render(ctx, []byte("bad")); err == nil || !strings.Contains(err.Error(), "Parse error on line 1") {
+		t.Errorf("Render(bad) = %v, want the command's stderr", err)
+	}
+	if _, err := r.Render(ctx, []byte("none")); err == nil || !strings.Contains(err.Error(), "wrote no SVG") {
+		t.Errorf("Render(none) = %v", err)
+	}
+	if entries, _ := os.ReadDir(r.cache); len(entries) != 0 {
+		t.Errorf("failures were cached: %v", entries)
+	}
+}
+
+func TestUnavailable(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+	r := New(notes.Open(t.TempDir()), nil)
+	if r.Command() != nil {
+		t.Errorf("Command() = %q without mmdc", r.Command())
+	}
+	if _, err := r.Render(context.Background(), []byte("graph TD")); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("Render = %v, want ErrUnavailable", err)
+	}
+}
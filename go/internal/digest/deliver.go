@@ -0,0 +1,138 @@
+package digest
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Email is an SMTP server and the addresses digests are sent between.
+type Email struct {
+	Host string
+	// Port defaults to 587. On port 465 the connection is TLS from the
+	// start; on others STARTTLS is used when the server offers it.
+	Port     int
+	Username string
+	Password string
+	From     string
+	To       []string
+}
+
+// Send mails d as plain text.
+func (m Email) Send(ctx context.Context, d *Digest) error {
+	if m.Host == "" || m.From == "" || len(m.To) == 0 {
+		return errors.New("email: host, from and to must be set")
+	}
+	port := m.Port
+	if port == 0 {
+		port = 587
+	}
+	addr := net.JoinHostPort(m.Host, strconv.Itoa(port))
+	var auth smtp.Auth
+	if m.Username != "" {
+		auth = smtp.PlainAuth("", m.Username, m.Password, m.Host)
+	}
+	msg := m.message(d, time.Now())
+	if port != 465 {
+		return smtp.SendMail(addr, auth, m.From, m.To, msg)
+	}
+	dialer := &tls.Dialer{Config: &tls.Config{ServerName: m.Host}}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	c, err := smtp.NewClient(conn, m.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+	if auth != nil {
+		if err := c.Auth(auth); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(m.From); err != nil {
+		return err
+	}
+	for _, to := range m.To {
+		if err := c.Rcpt(to); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// message formats d as an RFC 5322 message.
+func (m Email) message(d *Digest, now time.Time) []byte {
+	var b bytes.Buffer
+	header := func(k, v string) { fmt.Fprintf(&b, "%s: %s\r\n", k, v) }
+	header("From", m.From)
+	header("To", strings.Join(m.To, ", "))
+	header("Subject", mime.QEncoding.Encode("utf-8", d.Subject()))
+	header("Date", now.Format(time.RFC1123Z))
+	header("MIME-Version", "1.0")
+	header("Content-Type", `text/plain; charset="utf-8"`)
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(d.Text(), "\n", "\r\n"))
+	return b.Bytes()
+}
+
+// Webhook posts digests as JSON to URL: {"text": ..., "digest": {...}},
+// where text is the plain-text digest, which chat services such as Slack
+// show as the message.
+type Webhook struct {
+	URL    string
+	Client *http.Client
+}
+
+// Send posts d, failing unless the endpoint answers 2xx.
+func (h Webhook) Send(ctx context.Context, d *Digest) error {
+	body, err := json.Marshal(map[string]any{"text": d.Text(), "digest": d})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	client := h.Client
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		if msg = bytes.TrimSpace(msg); len(msg) > 0 {
+			return fmt.Errorf("webhook: %s: %s", resp.Status, msg)
+		}
+		return fmt.Errorf("webhook: %s", resp.Status)
+	}
+	return nil
+}
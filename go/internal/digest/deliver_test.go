@@ -0,0 +1,146 @@
+package digest
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+var sample = &Digest{Date: "2024-06-15", DueCount: 1, Due: []Item{{Path: "go/slices.md", Title: "Slices"}}, OnThisDay: []Item{}, Streak: 3, Entries: 4}
+
+func TestWebhook(t *testing.T) {
+	var got struct {
+		Text   string
+		Digest Digest
+	}
+	var ctype string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctype = r.Header.Get("Content-Type")
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Error(err)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+	if err := (Webhook{URL: srv.URL}).Send(context.Background(), sample); err != nil {
+		t.Fatal(err)
+	}
+	if ctype != "application/json" || got.Text != sample.Text() || got.Digest.Streak != 3 || got.Digest.Due[0].Path != "go/slices.md" {
+		t.Errorf("posted %s %+v", ctype, got)
+	}
+
+	fail := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "invalid_token", http.StatusForbidden)
+	}))
+	defer fail.Close()
+	if err := (Webhook{URL: fail.URL}).Send(context.Background(), sample); err == nil || err.Error() != "webhook: 403 Forbidden: invalid_token" {
+		t.Errorf("Send to a failing endpoint = %v", err)
+	}
+}
+
+func TestEmailMessage(t *testing.T) {
+	m := Email{From: "til@example.com", To: []string{"a@example.com", "b@example.com"}}
+	d := *sample
+	d.Due = []Item{{Path: "go/café.md", Title: "Café"}}
+	msg := string(m.message(&d, time.Date(2024, time.June, 15, 7, 0, 0, 0, time.UTC)))
+	head, body, ok := strings.Cut(msg, "\r\n\r\n")
+	if !ok {
+		t.Fatalf("message without a body: %q", msg)
+	}
+	want := "From: til@example.com\r\nTo: a@example.com, b@example.com\r\n" +
+		"Subject: TIL digest 2024-06-15: 1 due for review, 3-day streak\r\n" +
+		"Date: Sat, 15 Jun 2024 07:00:00 +0000\r\nMIME-Version: 1.0\r\nContent-Type: text/plain; charset=\"utf-8\""
+	if head != want {
+		t.Errorf("headers =\n%s\nwant\n%s", head, want)
+	}
+	if body != strings.ReplaceAll(d.Text(), "\n", "\r\n") || !strings.Contains(body, "  - Café (go/café.md)\r\n") {
+		t.Errorf("body = %q", body)
+	}
+}
+
+// smtpServer accepts one SMTP session on a local port and returns the
+// port and a channel receiving the envelope and message.
+func smtpServer(t *testing.T) (int, <-chan []string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	got := make(chan []string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		reply := func(s string) { conn.Write([]byte(s + "\r\n")) }
+		var session []string
+		reply("220 test ESMTP")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			line = strings.TrimRight(line, "\r\n")
+			switch cmd := strings.ToUpper(strings.SplitN(line, " ", 2)[0]); cmd {
+			case "EHLO", "HELO":
+				reply("250 test")
+			case "MAIL", "RCPT":
+				session = append(session, line)
+				reply("250 ok")
+			case "DATA":
+				reply("354 go ahead")
+				var msg strings.Builder
+				for {
+					l, err := r.ReadString('\n')
+					if err != nil || l == ".\r\n" {
+						break
+					}
+					msg.WriteString(l)
+				}
+				session = append(session, msg.String())
+				reply("250 queued")
+			case "QUIT":
+				reply("221 bye")
+				got <- session
+				return
+			default:
+				reply("502 unknown " + cmd)
+			}
+		}
+	}()
+	return ln.Addr().(*net.TCPAddr).Port, got
+}
+
+func TestEmailSend(t *testing.T) {
+	port, got := smtpServer(t)
+	m := Email{Host: "127.0.0.1", Port: port, From: "til@example.com", To: []string{"me@example.com"}}
+	if err := m.Send(context.Background(), sample); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case session := <-got:
+		if len(session) != 3 || session[0] != "MAIL FROM:<til@example.com>" || !strings.HasPrefix(session[1], "RCPT TO:<me@example.com>") {
+			t.Errorf("session = %q", session)
+		}
+		if !strings.Contains(session[2], "Subject: "+sample.Subject()+"\r\n") || !strings.Contains(session[2], "  - Slices (go/slices.md)\r\n") {
+			t.Errorf("message =\n%s", session[2])
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no mail received")
+	}
+
+	for _, m := range []Email{{From: "a@b", To: []string{"c@d"}}, {Host: "h", To: []string{"c@d"}}, {Host: "h", From: "a@b"}} {
+		if err := m.Send(context.Background(), sample); err == nil || !strings.Contains(err.Error(), "must be set") {
+			t.Errorf("Send(%+v) = %v", m, err)
+		}
+	}
+}
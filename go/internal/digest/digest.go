@@ -0,0 +1,190 @@
+// Package digest composes a daily summary of a notes tree, the entries due
+// for review, those written on this day in earlier years, the writing
+// streak and progress on goals, and delivers it by email or webhook.
+package digest
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/canhta/til/go/internal/config"
+	"github.com/canhta/til/go/internal/goals"
+	"github.com/canhta/til/go/internal/notes"
+	"github.com/canhta/til/go/internal/query"
+	"github.com/canhta/til/go/internal/review"
+	"github.com/canhta/til/go/internal/stats"
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+// DefaultLimit is the number of entries listed per section by default.
+const DefaultLimit = 10
+
+// Item is an entry listed in a digest.
+type Item struct {
+	Path  string `json:"path"`
+	Title string `json:"title"`
+	// URL is the entry's page on the site, when its address is known.
+	URL     string `json:"url,omitempty"`
+	Created string `json:"created,omitempty"`
+}
+
+// Digest is the summary of one day.
+type Digest struct {
+	Date string `json:"date"`
+	// DueCount is the number of entries due for review, of which Due lists
+	// the most overdue.
+	DueCount  int    `json:"due_count"`
+	Due       []Item `json:"due"`
+	OnThisDay []Item `json:"on_this_day"`
+	// Streak is the number of consecutive days, ending today or yesterday,
+	// with an entry written.
+	Streak  int `json:"streak"`
+	Entries int `json:"entries"`
+	// Goals is the progress on the writing goals.
+	Goals []goals.Progress `json:"goals,omitempty"`
+}
+
+// Options configures Compose.
+type Options struct {
+	Now time.Time
+	// URL returns the site URL of the entry at path, or "".
+	URL func(path string) string
+	// Limit caps the entries listed per section. Defaults to DefaultLimit.
+	Limit int
+	// Goals are the writing goals to report progress on.
+	Goals []config.Goal
+}
+
+// Compose summarises entries of tree as of opts.Now. Entries never
+// reviewed are not counted as due.
+func Compose(ctx context.Context, tree *notes.Tree, entries []*entry.Entry, opts Options) (*Digest, error) {
+	if opts.Now.IsZero() {
+		opts.Now = time.Now()
+	}
+	if opts.Limit <= 0 {
+		opts.Limit = DefaultLimit
+	}
+	byPath := map[string]*entry.Entry{}
+	paths := make([]string, len(entries))
+	for i, e := range entries {
+		byPath[e.Path] = e
+		paths[i] = e.Path
+	}
+	item := func(e *entry.Entry) Item {
+		it := Item{Path: e.Path, Title: e.Meta.Title}
+		if opts.URL != nil {
+			it.URL = opts.URL(e.Path)
+		}
+		if d := e.Created(); !d.IsZero() {
+			it.Created = d.Format(entry.DateLayout)
+		}
+		return it
+	}
+
+	d := &Digest{Date: opts.Now.Format(entry.DateLayout), Due: []Item{}, OnThisDay: []Item{}, Entries: len(entries)}
+	st, err := review.Open(tree)
+	if err != nil {
+		return nil, err
+	}
+	defer st.Close()
+	q, err := st.Queue(ctx, paths, opts.Now, 0)
+	if err != nil {
+		return nil, err
+	}
+	d.DueCount = len(q.Due)
+	for _, c := range q.Due[:min(len(q.Due), opts.Limit)] {
+		d.Due = append(d.Due, item(byPath[c.Path]))
+	}
+
+	old := query.Filter(entries, query.OnThisDay{Day: opts.Now})
+	if err := query.Sort(old, "created", true); err != nil {
+		return nil, err
+	}
+	for _, e := range old[:min(len(old), opts.Limit)] {
+		d.OnThisDay = append(d.OnThisDay, item(e))
+	}
+	d.Streak = stats.Compute(entries, opts.Now).CurrentStreak
+	if d.Goals, err = goals.Check(opts.Goals, entries, opts.Now); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// Empty reports whether the digest has nothing to review or resurface.
+func (d *Digest) Empty() bool {
+	return d.DueCount == 0 && len(d.OnThisDay) == 0
+}
+
+// Subject is a one-line summary, as for an email subject.
+func (d *Digest) Subject() string {
+	var parts []string
+	if d.DueCount > 0 {
+		parts = append(parts, fmt.Sprintf("%d due for review", d.DueCount))
+	}
+	if n := len(d.OnThisDay); n > 0 {
+		parts = append(parts, fmt.Sprintf("%d from this day", n))
+	}
+	if d.Streak > 1 {
+		parts = append(parts, fmt.Sprintf("%d-day streak", d.Streak))
+	}
+	if n := len(goals.Behind(d.Goals)); n == 1 {
+		parts = append(parts, "1 goal behind")
+	} else if n > 1 {
+		parts = append(parts, fmt.Sprintf("%d goals behind", n))
+	}
+	if len(parts) == 0 {
+		parts = append(parts, "nothing due")
+	}
+	return "TIL digest " + d.Date + ": " + strings.Join(parts, ", ")
+}
+
+// Text renders the digest as plain text.
+func (d *Digest) Text() string {
+	var b strings.Builder
+	b.WriteString(d.Subject() + "\n")
+	section := func(title string, items []Item, more int) {
+		if len(items) == 0 {
+			return
+		}
+		b.WriteString("\n" + title + "\n")
+		for _, it := range items {
+			line := "  - " + it.Title
+			if it.URL != "" {
+				line += " <" + it.URL + ">"
+			} else {
+				line += " (" + it.Path + ")"
+			}
+			b.WriteString(line + "\n")
+		}
+		if more > 0 {
+			fmt.Fprintf(&b, "  and %d more; run til review\n", more)
+		}
+	}
+	section("Due for review:", d.Due, d.DueCount-len(d.Due))
+	if len(d.OnThisDay) > 0 {
+		var items []Item
+		year := d.Date[:4]
+		for _, it := range d.OnThisDay {
+			it.Title += " [" + it.Created[:4] + "]"
+			items = append(items, it)
+		}
+		section("On this day, before "+year+":", items, 0)
+	}
+	switch {
+	case d.Streak == 1:
+		b.WriteString("\nWriting streak: 1 day.\n")
+	case d.Streak > 1:
+		fmt.Fprintf(&b, "\nWriting streak: %d days.\n", d.Streak)
+	default:
+		b.WriteString("\nNo writing streak; write something today.\n")
+	}
+	if len(d.Goals) > 0 {
+		b.WriteString("\nGoals:\n")
+		for _, p := range d.Goals {
+			b.WriteString("  - " + p.String() + "\n")
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,121 @@
+package digest
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/canhta/til/go/internal/config"
+	"github.com/canhta/til/go/internal/notes"
+	"github.com/canhta/til/go/internal/review"
+)
+
+var now = time.Date(2024, time.June, 15, 12, 0, 0, 0, time.Local)
+
+// compose composes the digest of a tree with the given files, in which
+// reviewed entries were last reviewed on the days given.
+func compose(t *testing.T, files map[string]string, reviewed map[string]time.Time, opts Options) *Digest {
+	t.Helper()
+	tree := notes.Open(t.TempDir())
+	for p, data := range files {
+		if err := tree.Write(p, []byte(data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ctx := context.Background()
+	st, err := review.Open(tree)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for p, at := range reviewed {
+		if _, err := st.Record(ctx, p, review.Good, at); err != nil {
+			t.Fatal(err)
+		}
+	}
+	st.Close()
+	entries, err := tree.Entries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts.Now = now
+	d, err := Compose(ctx, tree, entries, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+var digestFiles = map[string]string{
+	"go/slices.md":  "---\ntitle: Slices\ndate: 2024-06-14\n---\n",
+	"go/maps.md":    "---\ntitle: Maps\ndate: 2024-06-15\n---\n",
+	"git/rebase.md": "---\ntitle: Rebase\ndate: 2022-06-15\n---\n",
+	"git/bisect.md": "---\ntitle: Bisect\ndate: 2021-06-15\n---\n",
+	"git/stash.md":  "---\ntitle: Stash\ndate: 2021-03-01\n---\n",
+}
+
+func paths(items []Item) []string {
+	var out []string
+	for _, it := range items {
+		out = append(out, it.Path)
+	}
+	return out
+}
+
+func TestCompose(t *testing.T) {
+	reviewed := map[string]time.Time{
+		"go/slices.md":  now.AddDate(0, 0, -30),
+		"git/stash.md":  now.AddDate(0, 0, -20),
+		"go/maps.md":    now,
+		"git/rebase.md": now.Add(-time.Hour),
+	}
+	d := compose(t, digestFiles, reviewed, Options{
+		URL:   func(p string) string { return "https://til.example/" + strings.TrimSuffix(p, ".md") + "/" },
+		Goals: []config.Goal{{Entries: 3, Per: "week"}},
+	})
+	if d.Date != "2024-06-15" || d.Entries != 5 || d.Streak != 2 || d.DueCount != 2 {
+		t.Errorf("digest = %+v", d)
+	}
+	if !slices.Equal(paths(d.Due), []string{"go/slices.md", "git/stash.md"}) {
+		t.Errorf("Due = %q, want the most overdue first", paths(d.Due))
+	}
+	if !slices.Equal(paths(d.OnThisDay), []string{"git/bisect.md", "git/rebase.md"}) {
+		t.Errorf("OnThisDay = %q, want oldest first", paths(d.OnThisDay))
+	}
+	want := "TIL digest 2024-06-15: 2 due for review, 2 from this day, 2-day streak\n" +
+		"\nDue for review:\n" +
+		"  - Slices <https://til.example/go/slices/>\n" +
+		"  - Stash <https://til.example/git/stash/>\n" +
+		"\nOn this day, before 2024:\n" +
+		"  - Bisect [2021] <https://til.example/git/bisect/>\n" +
+		"  - Rebase [2022] <https://til.example/git/rebase/>\n" +
+		"\nWriting streak: 2 days.\n" +
+		"\nGoals:\n" +
+		"  - " + d.Goals[0].String() + "\n"
+	if got := d.Text(); got != want {
+		t.Errorf("Text =\n%s\nwant\n%s", got, want)
+	}
+	if d.Empty() {
+		t.Error("Empty() = true")
+	}
+
+	d = compose(t, digestFiles, reviewed, Options{Limit: 1})
+	if len(d.Due) != 1 || len(d.OnThisDay) != 1 || d.DueCount != 2 {
+		t.Errorf("digest with Limit 1 = %+v", d)
+	}
+	if got := d.Text(); !slices.Contains(strings.Split(got, "\n"), "  - Slices (go/slices.md)") || !slices.Contains(strings.Split(got, "\n"), "  and 1 more; run til review") {
+		t.Errorf("Text with Limit 1 =\n%s", got)
+	}
+}
+
+func TestComposeEmpty(t *testing.T) {
+	d := compose(t, map[string]string{"go/old.md": "---\ntitle: Old\ndate: 2020-01-01\n---\n"}, nil, Options{})
+	if !d.Empty() || d.Due == nil || d.OnThisDay == nil {
+		t.Errorf("digest = %+v, want empty with non-nil lists", d)
+	}
+	want := "TIL digest 2024-06-15: nothing due\n\nNo writing streak; write something today.\n"
+	if got := d.Text(); got != want {
+		t.Errorf("Text = %q, want %q", got, want)
+	}
+}
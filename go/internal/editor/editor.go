@@ -0,0 +1,37 @@
+// Package editor opens files in the user's editor.
+package editor
+
+import (
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+)
+
+// Configured is the editor command line of the configuration file, used
+// before $VISUAL and $EDITOR when set.
+var Configured []string
+
+// Command returns the configured editor command line: Configured, then
+// $VISUAL, then $EDITOR, then vi.
+func Command() []string {
+	if len(Configured) > 0 {
+		return slices.Clip(Configured)
+	}
+	for _, env := range []string{"VISUAL", "EDITOR"} {
+		if v := strings.Fields(os.Getenv(env)); len(v) > 0 {
+			return v
+		}
+	}
+	return []string{"vi"}
+}
+
+// Open opens file in the editor and waits for it to exit.
+func Open(file string) error {
+	argv := append(Command(), file)
+	cmd := exec.Command(argv[0], argv[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
@@ -0,0 +1,79 @@
+// Package export converts entries into formats consumed by other tools.
+package export
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/canhta/til/go/pkg/entry"
+	"github.com/canhta/til/go/pkg/render"
+)
+
+// AnkiOptions configures an Anki export.
+type AnkiOptions struct {
+	// Deck names the deck of each card. "{category}" is replaced by the
+	// entry's category.
+	Deck string
+	// Style is the chroma style for code blocks.
+	Style string
+}
+
+// DefaultDeck files cards into one subdeck per category.
+const DefaultDeck = "TIL::{category}"
+
+// Anki writes entries as an Anki text import file: one Basic note per entry
+// with the title on the front and the rendered body on the back. Each note
+// is keyed by the entry ID, so importing again updates existing notes
+// instead of duplicating them.
+func Anki(w io.Writer, entries []*entry.Entry, opts AnkiOptions) error {
+	if opts.Deck == "" {
+		opts.Deck = DefaultDeck
+	}
+	if opts.Style == "" {
+		opts.Style = "github"
+	}
+	r := render.New(render.Options{Highlight: opts.Style})
+	var head bytes.Buffer
+	for _, h := range []string{
+		"separator:tab",
+		"html:true",
+		"notetype:Basic",
+		"columns:GUID\tFront\tBack\tDeck\tTags",
+		"guid column:1",
+		"deck column:4",
+		"tags column:5",
+	} {
+		fmt.Fprintf(&head, "#%s\n", h)
+	}
+	if _, err := w.Write(head.Bytes()); err != nil {
+		return err
+	}
+	cw := csv.NewWriter(w)
+	cw.Comma = '\t'
+	for _, e := range entries {
+		back, err := r.RenderFrom(render.StripTitle(e.Body), e.Path)
+		if err != nil {
+			return fmt.Errorf("%s: %w", e.Path, err)
+		}
+		deck := strings.ReplaceAll(opts.Deck, "{category}", e.Meta.Category)
+		tags := make([]string, len(e.Meta.Tags))
+		for i, t := range e.Meta.Tags {
+			// Anki separates tags with spaces.
+			tags[i] = strings.ReplaceAll(t, " ", "_")
+		}
+		if err := cw.Write([]string{
+			"til:" + e.ID(),
+			e.Meta.Title,
+			strings.TrimSpace(string(back)),
+			deck,
+			strings.Join(tags, " "),
+		}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
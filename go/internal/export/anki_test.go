@@ -0,0 +1,66 @@
+package export
+
+import (
+	"bytes"
+	"encoding/csv"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+func parse(t *testing.T, p, data string) *entry.Entry {
+	t.Helper()
+	e, err := entry.Parse(p, []byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+func TestAnki(t *testing.T) {
+	entries := []*entry.Entry{
+		parse(t, "go/slices.md", "---\ntitle: Slices share arrays\ntags: [go, data structures]\n---\n\n# Slices share arrays\n\nAppend may \"reallocate\".\n\n```go\ns = append(s, 1)\n```\n"),
+		parse(t, "git/rebase.md", "# Rebase onto\n\nUse `--onto`.\n"),
+	}
+	var buf bytes.Buffer
+	if err := Anki(&buf, entries, AnkiOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	head, body, _ := strings.Cut(buf.String(), "#tags column:5\n")
+	if want := "#separator:tab\n#html:true\n#notetype:Basic\n#columns:GUID\tFront\tBack\tDeck\tTags\n#guid column:1\n#deck column:4\n"; head != want {
+		t.Errorf("header = %q, want %q", head, want)
+	}
+	r := csv.NewReader(strings.NewReader(body))
+	r.Comma = '\t'
+	rows, err := r.ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("%d notes, want 2", len(rows))
+	}
+	note := rows[0]
+	if want := []string{"til:go/slices", "Slices share arrays", note[2], "TIL::go", "go data_structures"}; !slices.Equal(note, want) {
+		t.Errorf("note = %q, want %q", note, want)
+	}
+	back := note[2]
+	if strings.Contains(back, "<h1") || !strings.Contains(back, "<p>Append may &quot;reallocate&quot;.</p>") {
+		t.Errorf("back = %s, want the body without its title", back)
+	}
+	if !strings.Contains(back, "<pre") || !strings.Contains(back, "style=") {
+		t.Errorf("back = %s, want code highlighted inline", back)
+	}
+	if rows[1][0] != "til:git/rebase" || rows[1][3] != "TIL::git" || rows[1][4] != "" {
+		t.Errorf("note = %q", rows[1])
+	}
+
+	buf.Reset()
+	if err := Anki(&buf, entries[1:], AnkiOptions{Deck: "Notes::{category}::cards"}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "\tNotes::git::cards\t") {
+		t.Errorf("deck not named from the option:\n%s", buf.String())
+	}
+}
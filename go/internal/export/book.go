@@ -0,0 +1,307 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"embed"
+	"encoding/base64"
+	"fmt"
+	"html/template"
+	"io"
+	"mime"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/canhta/til/go/internal/assets"
+	"github.com/canhta/til/go/internal/include"
+	"github.com/canhta/til/go/internal/links"
+	"github.com/canhta/til/go/internal/notes"
+	"github.com/canhta/til/go/pkg/entry"
+	"github.com/canhta/til/go/pkg/render"
+)
+
+//go:embed book
+var bookFiles embed.FS
+
+// BookFormat is the file format of a book export.
+type BookFormat string
+
+const (
+	EPUB BookFormat = "epub"
+	// HTML is a single self-contained page laid out for printing, to PDF
+	// for instance.
+	HTML BookFormat = "html"
+)
+
+// BookFormats lists the supported formats.
+var BookFormats = []BookFormat{EPUB, HTML}
+
+// BookOptions configures a book export.
+type BookOptions struct {
+	Format BookFormat
+	Title  string
+	Author string
+	// Style is the chroma style for code blocks.
+	Style string
+	// Now dates the book.
+	Now time.Time
+}
+
+type book struct {
+	ID, Title, Author string
+	Modified          string
+	Now, First, Last  time.Time
+	Count             int
+	Chapters          []*chapter
+	Images            []*image
+	CSS               template.CSS
+}
+
+type chapter struct {
+	ID, Name, File, Href string
+	Order                int
+	Sections             []*section
+}
+
+type section struct {
+	ID, Title, Href string
+	Order           int
+	Date            time.Time
+	Tags            []string
+	Content         template.HTML
+}
+
+type image struct {
+	ID, File, Type string
+	Data           []byte
+}
+
+// Book writes entries of tree as a book with a cover page, a table of
+// contents and one chapter per category, entries oldest first. Code is
+// highlighted with inline styles and images attached to entries are
+// embedded; links between entries point within the book.
+func Book(w io.Writer, tree *notes.Tree, entries []*entry.Entry, opts BookOptions) error {
+	if opts.Style == "" {
+		opts.Style = "github"
+	}
+	if opts.Now.IsZero() {
+		opts.Now = time.Now()
+	}
+	epub := opts.Format == EPUB
+	if !epub && opts.Format != HTML {
+		return fmt.Errorf("unknown book format %q", opts.Format)
+	}
+	css, err := bookFiles.ReadFile("book/style.css")
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256([]byte(opts.Title + opts.Now.Format(time.RFC3339)))
+	b := &book{
+		ID:       fmt.Sprintf("urn:uuid:%x-%x-%x-%x-%x", sum[0:4], sum[4:6], sum[6:8], sum[8:10], sum[10:16]),
+		Title:    opts.Title,
+		Author:   opts.Author,
+		Modified: opts.Now.UTC().Format("2006-01-02T15:04:05Z"),
+		Now:      opts.Now,
+		Count:    len(entries),
+		CSS:      template.CSS(css),
+	}
+
+	sorted := append([]*entry.Entry(nil), entries...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, c := sorted[i], sorted[j]
+		if a.Meta.Category != c.Meta.Category {
+			return a.Meta.Category < c.Meta.Category
+		}
+		return a.Meta.Date.Before(c.Meta.Date)
+	})
+	href := map[string]string{}
+	sections := map[string]*section{}
+	byCat := map[string]*chapter{}
+	order := 1
+	for _, e := range sorted {
+		c := byCat[e.Meta.Category]
+		if c == nil {
+			id := "c-" + entry.Slugify(e.Meta.Category)
+			c = &chapter{ID: id, Name: e.Meta.Category, File: id + ".xhtml", Href: "#" + id, Order: order}
+			if epub {
+				c.Href = c.File
+			}
+			order++
+			byCat[e.Meta.Category] = c
+			b.Chapters = append(b.Chapters, c)
+		}
+		s := &section{ID: "e-" + entry.Slugify(strings.TrimSuffix(e.Path, ".md")), Title: e.Meta.Title, Order: order, Date: e.Meta.Date, Tags: e.Meta.Tags}
+		order++
+		s.Href = "#" + s.ID
+		if epub {
+			s.Href = c.File + s.Href
+		}
+		href[e.Path] = s.Href
+		sections[e.Path] = s
+		c.Sections = append(c.Sections, s)
+		if d := e.Meta.Date; !d.IsZero() {
+			if b.First.IsZero() || d.Before(b.First) {
+				b.First = d
+			}
+			if d.After(b.Last) {
+				b.Last = d
+			}
+		}
+	}
+
+	all, err := assets.List(tree)
+	if err != nil {
+		return err
+	}
+	isAsset := map[string]bool{}
+	for _, p := range all {
+		isAsset[p] = assets.IsImage(p)
+	}
+	images := map[string]string{}
+	embedImage := func(p string) (string, error) {
+		if u, ok := images[p]; ok {
+			return u, nil
+		}
+		data, err := tree.Read(p)
+		if err != nil {
+			return "", err
+		}
+		typ := mime.TypeByExtension(strings.ToLower(path.Ext(p)))
+		u := "data:" + typ + ";base64," + base64.StdEncoding.EncodeToString(data)
+		if epub {
+			img := &image{ID: fmt.Sprintf("img-%d", len(b.Images)+1), Type: typ, Data: data}
+			img.File = "images/" + img.ID + path.Ext(p)
+			b.Images = append(b.Images, img)
+			u = img.File
+		}
+		images[p] = u
+		return u, nil
+	}
+	var linkErr error
+	ix := links.NewIndex(entries)
+	r := render.New(render.Options{
+		Highlight: opts.Style,
+		XHTML:     epub,
+		ResolveWiki: func(from, target string) (string, string, bool) {
+			e, res := ix.Resolve(from, links.Link{Target: target, Wiki: true})
+			if res != links.Resolved {
+				return "", "", false
+			}
+			return href[e.Path], e.Meta.Title, true
+		},
+		ResolveLink: func(from, dest string) (string, bool) {
+			u, err := url.Parse(dest)
+			if err != nil || u.Scheme != "" || u.Host != "" || u.Path == "" {
+				return "", false
+			}
+			target := path.Clean(path.Join(path.Dir(from), u.Path))
+			// Heading anchors are unique only within an entry, so fragments are
+			// dropped.
+			if h, ok := href[target]; ok {
+				return h, true
+			}
+			if !isAsset[target] {
+				return "", false
+			}
+			img, err := embedImage(target)
+			if err != nil {
+				linkErr = err
+				return "", false
+			}
+			return img, true
+		},
+	})
+	for _, e := range sorted {
+		x := include.Expand(e, ix)
+		if len(x.Errors) > 0 {
+			return x.Errors[0]
+		}
+		html, err := r.RenderFrom(render.StripTitle(x.Body), e.Path)
+		if err == nil {
+			err = linkErr
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", e.Path, err)
+		}
+		sections[e.Path].Content = template.HTML(html)
+	}
+
+	t, err := template.ParseFS(bookFiles, "book/*.tmpl", "book/*.opf", "book/*.ncx", "book/*.html")
+	if err != nil {
+		return err
+	}
+	data := struct{ Book *book }{b}
+	if !epub {
+		return t.ExecuteTemplate(w, "book.html", data)
+	}
+	return writeEPUB(w, t, b, data)
+}
+
+type zipFile struct {
+	name string
+	data []byte
+}
+
+// xmlDecl starts every XML file of an EPUB.
+const xmlDecl = `<?xml version="1.0" encoding="UTF-8"?>` + "\n"
+
+func writeEPUB(w io.Writer, t *template.Template, b *book, data any) error {
+	z := zip.NewWriter(w)
+	// The mimetype file must come first, uncompressed.
+	f, err := z.CreateHeader(&zip.FileHeader{Name: "mimetype", Method: zip.Store, Modified: b.Now})
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(f, "application/epub+zip"); err != nil {
+		return err
+	}
+	container, err := bookFiles.ReadFile("book/container.xml")
+	if err != nil {
+		return err
+	}
+	files := []zipFile{
+		{"META-INF/container.xml", container},
+		{"OEBPS/style.css", []byte(b.CSS)},
+	}
+	page := func(name, tmpl string, data any) error {
+		var buf bytes.Buffer
+		buf.WriteString(xmlDecl)
+		if err := t.ExecuteTemplate(&buf, tmpl, data); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		files = append(files, zipFile{"OEBPS/" + name, buf.Bytes()})
+		return nil
+	}
+	for _, p := range []string{"content.opf", "toc.ncx"} {
+		if err := page(p, p, b); err != nil {
+			return err
+		}
+	}
+	for _, p := range []string{"cover.xhtml", "nav.xhtml"} {
+		if err := page(p, p, data); err != nil {
+			return err
+		}
+	}
+	for _, c := range b.Chapters {
+		if err := page(c.File, "chapter.xhtml", c); err != nil {
+			return err
+		}
+	}
+	for _, img := range b.Images {
+		files = append(files, zipFile{"OEBPS/" + img.File, img.Data})
+	}
+	for _, f := range files {
+		fw, err := z.CreateHeader(&zip.FileHeader{Name: f.name, Method: zip.Deflate, Modified: b.Now})
+		if err != nil {
+			return err
+		}
+		if _, err := fw.Write(f.data); err != nil {
+			return err
+		}
+	}
+	return z.Close()
+}
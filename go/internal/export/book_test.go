@@ -0,0 +1,134 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/canhta/til/go/internal/notes"
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+func bookTree(t *testing.T) (*notes.Tree, []*entry.Entry) {
+	t.Helper()
+	tree := notes.Open(t.TempDir())
+	for p, data := range map[string]string{
+		"go/slices.md":                "---\ntitle: Slices & arrays\ndate: 2024-06-01\ntags: [go]\n---\n\n![header](assets/slices/header.png)\n\nSee [[git/rebase]] and [maps](maps.md#order).\n\n```go\nx := 1\n```\n",
+		"go/maps.md":                  "---\ntitle: Maps\ndate: 2024-05-01\n---\n\nUnordered.\n",
+		"git/rebase.md":               "---\ntitle: Rebase onto\ndate: 2023-01-10\n---\n\nUse `--onto`.\n",
+		"go/assets/slices/header.png": "\x89PNG",
+	} {
+		if err := tree.Write(p, []byte(data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	entries, err := tree.Entries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tree, entries
+}
+
+var bookNow = time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC)
+
+func TestBookEPUB(t *testing.T) {
+	tree, entries := bookTree(t)
+	var buf bytes.Buffer
+	opts := BookOptions{Format: EPUB, Title: "My TIL", Author: "Ann", Now: bookNow}
+	if err := Book(&buf, tree, entries, opts); err != nil {
+		t.Fatal(err)
+	}
+	z, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	files := map[string]string{}
+	for _, f := range z.File {
+		names = append(names, f.Name)
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+		files[f.Name] = string(data)
+	}
+	want := []string{
+		"mimetype", "META-INF/container.xml", "OEBPS/style.css", "OEBPS/content.opf", "OEBPS/toc.ncx",
+		"OEBPS/cover.xhtml", "OEBPS/nav.xhtml", "OEBPS/c-git.xhtml", "OEBPS/c-go.xhtml", "OEBPS/images/img-1.png",
+	}
+	if !slices.Equal(names, want) {
+		t.Fatalf("files = %q, want %q", names, want)
+	}
+	if z.File[0].Method != zip.Store || files["mimetype"] != "application/epub+zip" {
+		t.Error("mimetype is not first and stored")
+	}
+	for name, data := range files {
+		if !strings.HasSuffix(name, ".xhtml") && !strings.HasSuffix(name, ".opf") && !strings.HasSuffix(name, ".ncx") {
+			continue
+		}
+		d := xml.NewDecoder(strings.NewReader(data))
+		d.Strict = true
+		d.Entity = xml.HTMLEntity
+		for {
+			_, err := d.Token()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				t.Errorf("%s is not well-formed: %v\n%s", name, err, data)
+				break
+			}
+		}
+	}
+	opf := files["OEBPS/content.opf"]
+	for _, want := range []string{"<dc:title>My TIL</dc:title>", "<dc:creator>Ann</dc:creator>", "2024-07-01T12:00:00Z", `href="images/img-1.png" media-type="image/png"`} {
+		if !strings.Contains(opf, want) {
+			t.Errorf("content.opf lacks %s:\n%s", want, opf)
+		}
+	}
+	goChapter := files["OEBPS/c-go.xhtml"]
+	if i, j := strings.Index(goChapter, "Maps"), strings.Index(goChapter, "Slices &amp; arrays"); i < 0 || j < i {
+		t.Errorf("c-go.xhtml is not oldest first:\n%s", goChapter)
+	}
+	for _, want := range []string{`href="c-git.xhtml#e-git-rebase"`, `href="c-go.xhtml#e-go-maps"`, `src="images/img-1.png"`} {
+		if !strings.Contains(goChapter, want) {
+			t.Errorf("c-go.xhtml lacks %s:\n%s", want, goChapter)
+		}
+	}
+
+	var again bytes.Buffer
+	if err := Book(&again, tree, entries, opts); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), again.Bytes()) {
+		t.Error("the same book came out different")
+	}
+}
+
+func TestBookHTML(t *testing.T) {
+	tree, entries := bookTree(t)
+	var buf bytes.Buffer
+	if err := Book(&buf, tree, entries, BookOptions{Format: HTML, Title: "My TIL", Now: bookNow}); err != nil {
+		t.Fatal(err)
+	}
+	page := buf.String()
+	for _, want := range []string{"<title>My TIL</title>", `id="e-go-slices"`, `href="#e-git-rebase"`, `src="data:image/png;base64,iVBORw=="`} {
+		if !strings.Contains(page, want) {
+			t.Errorf("the book lacks %s:\n%s", want, page)
+		}
+	}
+	if i, j := strings.Index(page, `id="c-git"`), strings.Index(page, `id="c-go"`); i < 0 || j < i {
+		t.Error("chapters are not sorted by category")
+	}
+	if err := Book(io.Discard, tree, entries, BookOptions{Format: "pdf"}); err == nil {
+		t.Error("Book accepted the pdf format")
+	}
+}
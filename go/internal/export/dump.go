@@ -0,0 +1,151 @@
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/canhta/til/go/pkg/entry"
+	"github.com/canhta/til/go/pkg/render"
+)
+
+// DumpSchema is the version of the schema of JSON and CSV dumps. It is
+// raised only when a field or column is removed or changes meaning; new
+// ones may appear at any time, CSV columns after the existing ones.
+const DumpSchema = 1
+
+// Record is an entry in a dump: its summary, as til list --json prints
+// it, with the state, the frontmatter as written, including fields til does
+// not know, the markdown body, and on request the rendered body.
+type Record struct {
+	entry.Summary
+	Draft    bool `json:"draft"`
+	Archived bool `json:"archived"`
+	// Frontmatter is the frontmatter decoded from YAML; empty when the
+	// entry has none.
+	Frontmatter map[string]any `json:"frontmatter"`
+	// Body is the markdown following the frontmatter.
+	Body string `json:"body"`
+	// HTML is the body rendered to HTML, title heading stripped, with
+	// DumpOptions.HTML.
+	HTML string `json:"html,omitempty"`
+}
+
+// DumpOptions configures a dump.
+type DumpOptions struct {
+	// HTML renders the body of each entry too.
+	HTML bool
+	// Style is the chroma style of rendered code blocks.
+	Style string
+}
+
+// Dump is the JSON document of a dump:
+//
+//	{"schema": 1, "entries": [...]}
+type Dump struct {
+	Schema  int      `json:"schema"`
+	Entries []Record `json:"entries"`
+}
+
+// Records returns the dump records of entries.
+func Records(entries []*entry.Entry, opts DumpOptions) ([]Record, error) {
+	if opts.Style == "" {
+		opts.Style = "github"
+	}
+	var r *render.Renderer
+	if opts.HTML {
+		r = render.New(render.Options{Highlight: opts.Style})
+	}
+	out := make([]Record, len(entries))
+	for i, e := range entries {
+		rec := Record{
+			Summary:     entry.Summarize(e),
+			Draft:       e.Meta.Draft,
+			Archived:    e.Meta.Archived,
+			Frontmatter: map[string]any{},
+			Body:        string(e.Body),
+		}
+		var front yaml.Node
+		if err := yaml.Unmarshal(e.Front, &front); err != nil {
+			return nil, fmt.Errorf("%s: frontmatter: %w", e.Path, err)
+		}
+		if len(front.Content) > 0 {
+			untime(&front)
+			if err := front.Decode(&rec.Frontmatter); err != nil {
+				return nil, fmt.Errorf("%s: frontmatter: %w", e.Path, err)
+			}
+		}
+		if r != nil {
+			html, err := r.RenderFrom(render.StripTitle(e.Body), e.Path)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", e.Path, err)
+			}
+			rec.HTML = string(html)
+		}
+		out[i] = rec
+	}
+	return out, nil
+}
+
+// untime retags the timestamps under n as strings, so that dates are
+// dumped as written rather than as times in UTC.
+func untime(n *yaml.Node) {
+	if n.Kind == yaml.ScalarNode && n.ShortTag() == "!!timestamp" {
+		n.Tag = "!!str"
+	}
+	for _, c := range n.Content {
+		untime(c)
+	}
+}
+
+// JSON writes entries as a Dump document.
+func JSON(w io.Writer, entries []*entry.Entry, opts DumpOptions) error {
+	recs, err := Records(entries, opts)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(Dump{Schema: DumpSchema, Entries: recs})
+}
+
+// CSVColumns are the columns of a CSV dump, in order, named as the JSON
+// fields of Record. Tags are separated by semicolons, the frontmatter is
+// a JSON object, and html is left empty unless DumpOptions.HTML is set.
+var CSVColumns = []string{
+	"path", "title", "category", "slug", "tags", "author", "lang", "translation_of",
+	"created", "updated", "words", "code_lines", "reading_minutes",
+	"draft", "archived", "frontmatter", "body", "html",
+}
+
+// CSV writes entries as CSV with a header row of CSVColumns.
+func CSV(w io.Writer, entries []*entry.Entry, opts DumpOptions) error {
+	recs, err := Records(entries, opts)
+	if err != nil {
+		return err
+	}
+	cw := csv.NewWriter(w)
+	if err := cw.Write(CSVColumns); err != nil {
+		return err
+	}
+	for _, r := range recs {
+		front, err := json.Marshal(r.Frontmatter)
+		if err != nil {
+			return fmt.Errorf("%s: frontmatter: %w", r.Path, err)
+		}
+		if err := cw.Write([]string{
+			r.Path, r.Title, r.Category, r.Slug, strings.Join(r.Tags, ";"), r.Author, r.Lang, r.TranslationOf,
+			r.Created, r.Updated, strconv.Itoa(r.Words), strconv.Itoa(r.CodeLines), strconv.Itoa(r.ReadingMinutes),
+			strconv.FormatBool(r.Draft), strconv.FormatBool(r.Archived), string(front), r.Body, r.HTML,
+		}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,103 @@
+package export
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+func dumpEntries(t *testing.T) []*entry.Entry {
+	t.Helper()
+	return []*entry.Entry{
+		parse(t, "go/slices.md", "---\ntitle: Slices\ndate: 2024-06-01\ntags: [go, memory]\ndifficulty: easy\nsandbox: {image: golang}\ndraft: true\n---\n\n# Slices\n\nSlices share arrays.\n"),
+		parse(t, "git/rebase.md", "# Rebase\n\nRebase `onto`.\n"),
+	}
+}
+
+func TestJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := JSON(&buf, dumpEntries(t), DumpOptions{HTML: true}); err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		Schema  int
+		Entries []map[string]any
+	}
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.Schema != DumpSchema || len(doc.Entries) != 2 {
+		t.Fatalf("dump = %s", buf.Bytes())
+	}
+	slices := doc.Entries[0]
+	for key, want := range map[string]any{
+		"path":     "go/slices.md",
+		"title":    "Slices",
+		"category": "go",
+		"created":  "2024-06-01",
+		"draft":    true,
+		"archived": false,
+		"body":     "\n# Slices\n\nSlices share arrays.\n",
+		"html":     "<p>Slices share arrays.</p>\n",
+	} {
+		if slices[key] != want {
+			t.Errorf("%s = %#v, want %#v", key, slices[key], want)
+		}
+	}
+	front, _ := json.Marshal(slices["frontmatter"])
+	if want := `{"date":"2024-06-01","difficulty":"easy","draft":true,"sandbox":{"image":"golang"},"tags":["go","memory"],"title":"Slices"}`; string(front) != want {
+		t.Errorf("frontmatter = %s, want %s", front, want)
+	}
+	if front, _ := json.Marshal(doc.Entries[1]["frontmatter"]); string(front) != "{}" {
+		t.Errorf("frontmatter of an entry without any = %s", front)
+	}
+
+	buf.Reset()
+	if err := JSON(&buf, dumpEntries(t), DumpOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), `"html"`) {
+		t.Errorf("dump without HTML has html:\n%s", buf.String())
+	}
+}
+
+func TestCSV(t *testing.T) {
+	var buf bytes.Buffer
+	if err := CSV(&buf, dumpEntries(t), DumpOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 3 || !slices.Equal(rows[0], CSVColumns) {
+		t.Fatalf("rows = %q", rows)
+	}
+	row := map[string]string{}
+	for i, col := range CSVColumns {
+		row[col] = rows[1][i]
+	}
+	for col, want := range map[string]string{
+		"path":        "go/slices.md",
+		"tags":        "go;memory",
+		"created":     "2024-06-01",
+		"words":       "4",
+		"draft":       "true",
+		"archived":    "false",
+		"frontmatter": `{"date":"2024-06-01","difficulty":"easy","draft":true,"sandbox":{"image":"golang"},"tags":["go","memory"],"title":"Slices"}`,
+		"body":        "\n# Slices\n\nSlices share arrays.\n",
+		"html":        "",
+	} {
+		if row[col] != want {
+			t.Errorf("%s = %q, want %q", col, row[col], want)
+		}
+	}
+	if rows[2][0] != "git/rebase.md" || rows[2][1] != "Rebase" {
+		t.Errorf("row of git/rebase.md = %q", rows[2])
+	}
+}
@@ -0,0 +1,234 @@
+package export
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/canhta/til/go/internal/assets"
+	"github.com/canhta/til/go/internal/links"
+	"github.com/canhta/til/go/internal/notes"
+	"github.com/canhta/til/go/pkg/entry"
+	"github.com/canhta/til/go/pkg/render"
+)
+
+// Generator is a static site generator entries can be exported to.
+type Generator string
+
+const (
+	Hugo   Generator = "hugo"
+	Jekyll Generator = "jekyll"
+)
+
+// DefaultPrefix is the URL path Jekyll exports serve entries under.
+const DefaultPrefix = "til"
+
+// SiteOptions configures an export for a static site generator.
+type SiteOptions struct {
+	Generator Generator
+	// Prefix is the URL path below which Jekyll serves the entries. Hugo
+	// derives it from where the output directory sits in content/.
+	Prefix string
+}
+
+// File is an exported file at a slash-separated path below the output
+// directory.
+type File struct {
+	Path string
+	Data []byte
+}
+
+// ownFields are the frontmatter fields mapped to the generator's own;
+// other fields are copied as they are.
+var ownFields = map[string]bool{
+	"title": true, "date": true, "updated": true, "category": true,
+	"slug": true, "tags": true, "draft": true, "private": true,
+}
+
+// Site converts entries of tree, with their assets, into the content files
+// of a Hugo or Jekyll site. Each entry becomes a page at <category>/<slug>/:
+// a page bundle for Hugo, a post with that permalink below Prefix for
+// Jekyll, which either way keeps the entry's assets next to the page. Drafts
+// stay drafts. Links to other exported entries and to assets become
+// relative URLs; wiki links to entries not exported become plain text.
+func Site(tree *notes.Tree, entries []*entry.Entry, opts SiteOptions) ([]File, error) {
+	if opts.Prefix == "" {
+		opts.Prefix = DefaultPrefix
+	}
+	opts.Prefix = strings.Trim(opts.Prefix, "/")
+	byPath := make(map[string]*entry.Entry, len(entries))
+	for _, e := range entries {
+		byPath[e.Path] = e
+	}
+	all, err := assets.List(tree)
+	if err != nil {
+		return nil, err
+	}
+	// owner maps asset paths of exported entries to their place below the
+	// entry's page.
+	owner := map[string]string{}
+	var files []File
+	for _, p := range all {
+		cat, rest, _ := strings.Cut(p, "/")
+		stem, sub, _ := strings.Cut(strings.TrimPrefix(rest, assets.Dir+"/"), "/")
+		e := byPath[cat+"/"+stem+".md"]
+		if e == nil {
+			continue
+		}
+		owner[p] = pageDir(e) + sub
+		data, err := tree.Read(p)
+		if err != nil {
+			return nil, err
+		}
+		out := owner[p]
+		if opts.Generator == Jekyll {
+			out = opts.Prefix + "/" + out
+		}
+		files = append(files, File{Path: out, Data: data})
+	}
+
+	ix := links.NewIndex(entries)
+	for _, e := range entries {
+		from := pageDir(e)
+		body := links.Rewrite(render.StripTitle(e.Body), func(l links.Link) (string, bool) {
+			if l.Wiki {
+				to, res := ix.Resolve(e.Path, l)
+				label := l.Label
+				if label == "" {
+					label = l.Target
+					if res == links.Resolved {
+						label = to.Meta.Title
+					}
+				}
+				if res != links.Resolved {
+					return label, true
+				}
+				return "[" + label + "](" + relURL(from, pageDir(to)) + ")", true
+			}
+			target := path.Clean(path.Join(path.Dir(e.Path), l.Target))
+			var url string
+			if to := byPath[target]; to != nil {
+				url = relURL(from, pageDir(to))
+			} else if a, ok := owner[target]; ok {
+				url = relURL(from, a)
+			} else {
+				return "", false
+			}
+			if l.Fragment != "" {
+				url += "#" + l.Fragment
+			}
+			return "[" + l.Label + "](" + url + ")", true
+		})
+		f, err := convert(e, body, opts)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", e.Path, err)
+		}
+		files = append(files, f)
+	}
+	return files, nil
+}
+
+// pageDir is the slash-terminated directory of the page an entry becomes.
+func pageDir(e *entry.Entry) string {
+	return e.Meta.Category + "/" + e.Meta.Slug + "/"
+}
+
+// relURL returns the URL of to relative to the page at the directory from,
+// both slash-separated paths from the same root.
+func relURL(from, to string) string {
+	f := strings.Split(strings.TrimSuffix(from, "/"), "/")
+	t := strings.Split(to, "/")
+	i := 0
+	for i < len(f) && i < len(t)-1 && f[i] == t[i] {
+		i++
+	}
+	u := strings.Repeat("../", len(f)-i) + strings.Join(t[i:], "/")
+	if u == "" {
+		return "./"
+	}
+	return u
+}
+
+// convert writes the page file for e with the given body.
+func convert(e *entry.Entry, body []byte, opts SiteOptions) (File, error) {
+	m := e.Meta
+	front := &yaml.Node{Kind: yaml.MappingNode}
+	set := func(key string, v any) error {
+		var n yaml.Node
+		if t, ok := v.(time.Time); ok {
+			n = yaml.Node{Kind: yaml.ScalarNode, Tag: "!!timestamp", Value: t.Format(entry.DateLayout)}
+		} else if err := n.Encode(v); err != nil {
+			return err
+		}
+		front.Content = append(front.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, &n)
+		return nil
+	}
+	date := m.Date
+	if date.IsZero() {
+		date = e.ModTime
+	}
+	var file string
+	var fields []any
+	switch opts.Generator {
+	case Hugo:
+		file = pageDir(e) + "index.md"
+		fields = []any{"title", m.Title, "date", date}
+		if !m.Updated.IsZero() {
+			fields = append(fields, "lastmod", m.Updated)
+		}
+		if m.Draft {
+			fields = append(fields, "draft", true)
+		}
+	case Jekyll:
+		file = "_posts/" + date.Format(entry.DateLayout) + "-" + m.Category + "-" + m.Slug + ".md"
+		if m.Draft {
+			file = "_drafts/" + m.Category + "-" + m.Slug + ".md"
+		}
+		fields = []any{"layout", "post", "title", m.Title, "date", date}
+		if !m.Updated.IsZero() {
+			fields = append(fields, "last_modified_at", m.Updated)
+		}
+		// Code in entries is not Liquid.
+		fields = append(fields, "permalink", "/"+opts.Prefix+"/"+pageDir(e), "render_with_liquid", false)
+	default:
+		return File{}, fmt.Errorf("unknown generator %q", opts.Generator)
+	}
+	fields = append(fields, "categories", []string{m.Category})
+	if len(m.Tags) > 0 {
+		fields = append(fields, "tags", m.Tags)
+	}
+	for i := 0; i < len(fields); i += 2 {
+		if err := set(fields[i].(string), fields[i+1]); err != nil {
+			return File{}, err
+		}
+	}
+	var orig yaml.Node
+	if err := yaml.Unmarshal(e.Front, &orig); err != nil {
+		return File{}, err
+	}
+	if len(orig.Content) == 1 && orig.Content[0].Kind == yaml.MappingNode {
+		kv := orig.Content[0].Content
+		for i := 0; i+1 < len(kv); i += 2 {
+			if !ownFields[kv[i].Value] {
+				front.Content = append(front.Content, kv[i], kv[i+1])
+			}
+		}
+	}
+	var buf bytes.Buffer
+	buf.WriteString("---\n")
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(front); err != nil {
+		return File{}, err
+	}
+	if err := enc.Close(); err != nil {
+		return File{}, err
+	}
+	buf.WriteString("---\n\n")
+	buf.Write(bytes.TrimLeft(body, "\n"))
+	return File{Path: file, Data: buf.Bytes()}, nil
+}
@@ -0,0 +1,98 @@
+package export
+
+import (
+	"testing"
+
+	"github.com/canhta/til/go/internal/notes"
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+func siteTree(t *testing.T) (*notes.Tree, []*entry.Entry) {
+	t.Helper()
+	tree := notes.Open(t.TempDir())
+	for p, data := range map[string]string{
+		"go/slices.md":                "---\ntitle: Slices share arrays\ndate: 2024-06-01\nupdated: 2024-06-03\ntags: [go]\nsource: https://go.dev\n---\n\n# Slices share arrays\n\n![header](assets/slices/header.png) See [maps](maps.md#order), [[git/rebase]], [[nowhere]] and [[rebase|this]].\n",
+		"go/maps.md":                  "---\ntitle: Maps\ndate: 2024-05-01\ndraft: true\n---\n\nUnordered.\n",
+		"git/rebase.md":               "---\ntitle: Rebase onto\ndate: 2023-01-10\n---\n\nUse `--onto`.\n",
+		"go/assets/slices/header.png": "png",
+		"go/assets/other/x.png":       "orphan",
+	} {
+		if err := tree.Write(p, []byte(data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	entries, err := tree.Entries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tree, entries
+}
+
+const slicesBody = "![header](header.png) See [maps](../maps/#order), [Rebase onto](../../git/rebase/), nowhere and [this](../../git/rebase/).\n"
+
+func TestSite(t *testing.T) {
+	tree, entries := siteTree(t)
+	tests := []struct {
+		gen  Generator
+		want map[string]string
+	}{
+		{Hugo, map[string]string{
+			"go/slices/header.png": "png",
+			"git/rebase/index.md":  "---\ntitle: Rebase onto\ndate: 2023-01-10\ncategories:\n  - git\n---\n\nUse `--onto`.\n",
+			"go/maps/index.md":     "---\ntitle: Maps\ndate: 2024-05-01\ndraft: true\ncategories:\n  - go\n---\n\nUnordered.\n",
+			"go/slices/index.md": "---\ntitle: Slices share arrays\ndate: 2024-06-01\nlastmod: 2024-06-03\ncategories:\n  - go\ntags:\n  - go\nsource: https://go.dev\n---\n\n" +
+				slicesBody,
+		}},
+		{Jekyll, map[string]string{
+			"til/go/slices/header.png":        "png",
+			"_posts/2023-01-10-git-rebase.md": "---\nlayout: post\ntitle: Rebase onto\ndate: 2023-01-10\npermalink: /til/git/rebase/\nrender_with_liquid: false\ncategories:\n  - git\n---\n\nUse `--onto`.\n",
+			"_drafts/go-maps.md":              "---\nlayout: post\ntitle: Maps\ndate: 2024-05-01\npermalink: /til/go/maps/\nrender_with_liquid: false\ncategories:\n  - go\n---\n\nUnordered.\n",
+			"_posts/2024-06-01-go-slices.md": "---\nlayout: post\ntitle: Slices share arrays\ndate: 2024-06-01\nlast_modified_at: 2024-06-03\npermalink: /til/go/slices/\nrender_with_liquid: false\ncategories:\n  - go\ntags:\n  - go\nsource: https://go.dev\n---\n\n" +
+				slicesBody,
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.gen), func(t *testing.T) {
+			files, err := Site(tree, entries, SiteOptions{Generator: tt.gen})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(files) != len(tt.want) {
+				t.Errorf("exported %d files, want %d", len(files), len(tt.want))
+			}
+			for _, f := range files {
+				want, ok := tt.want[f.Path]
+				if !ok {
+					t.Errorf("unexpected file %s", f.Path)
+				} else if string(f.Data) != want {
+					t.Errorf("%s =\n%s\nwant\n%s", f.Path, f.Data, want)
+				}
+			}
+		})
+	}
+
+	files, err := Site(tree, entries, SiteOptions{Generator: Jekyll, Prefix: "/notes/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if files[0].Path != "notes/go/slices/header.png" {
+		t.Errorf("asset exported to %s with prefix notes", files[0].Path)
+	}
+	if _, err := Site(tree, entries, SiteOptions{Generator: "gatsby"}); err == nil {
+		t.Error("Site accepted an unknown generator")
+	}
+}
+
+func TestRelURL(t *testing.T) {
+	tests := []struct{ from, to, want string }{
+		{"go/slices/", "go/maps/", "../maps/"},
+		{"go/slices/", "git/rebase/", "../../git/rebase/"},
+		{"go/slices/", "go/slices/a.png", "a.png"},
+		{"go/slices/", "go/slices/", "./"},
+	}
+	for _, tt := range tests {
+		if got := relURL(tt.from, tt.to); got != tt.want {
+			t.Errorf("relURL(%s, %s) = %s, want %s", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
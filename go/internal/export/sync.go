@@ -0,0 +1,101 @@
+package export
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/canhta/til/go/internal/fsutil"
+)
+
+// Manifest is the file, inside the output directory, recording what an
+// export wrote there.
+const Manifest = ".til-export.json"
+
+type manifest struct {
+	// Files maps each exported path to the SHA-256 of its content.
+	Files map[string]string `json:"files"`
+}
+
+// SyncStats reports what a Sync changed.
+type SyncStats struct {
+	Written, Unchanged int
+	// Removed lists files of a previous export that were not exported
+	// again.
+	Removed []string
+}
+
+// Sync writes files below dir, leaving files whose content did not change
+// since the previous sync untouched and removing those no longer exported.
+// Files in dir that til did not write are never replaced or removed, so
+// dir can be part of a larger site.
+func Sync(dir string, files []File) (SyncStats, error) {
+	var st SyncStats
+	old := manifest{Files: map[string]string{}}
+	if data, err := os.ReadFile(filepath.Join(dir, Manifest)); err == nil {
+		if err := json.Unmarshal(data, &old); err != nil {
+			return st, fmt.Errorf("%s: %w", Manifest, err)
+		}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return st, err
+	}
+
+	next := manifest{Files: make(map[string]string, len(files))}
+	var pending []File
+	for _, f := range files {
+		sum := sha256.Sum256(f.Data)
+		h := hex.EncodeToString(sum[:])
+		if _, dup := next.Files[f.Path]; dup {
+			return st, fmt.Errorf("two entries export to %s", f.Path)
+		}
+		next.Files[f.Path] = h
+		file := filepath.Join(dir, filepath.FromSlash(f.Path))
+		cur, err := os.ReadFile(file)
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			pending = append(pending, f)
+		case err != nil:
+			return st, err
+		case bytes.Equal(cur, f.Data):
+			st.Unchanged++
+		case old.Files[f.Path] == "":
+			return st, fmt.Errorf("%s exists and was not written by til export", file)
+		default:
+			pending = append(pending, f)
+		}
+	}
+	for _, f := range pending {
+		if err := fsutil.WriteFile(filepath.Join(dir, filepath.FromSlash(f.Path)), f.Data, 0o644); err != nil {
+			return st, err
+		}
+		st.Written++
+	}
+	for p := range old.Files {
+		if _, ok := next.Files[p]; ok {
+			continue
+		}
+		file := filepath.Join(dir, filepath.FromSlash(p))
+		if err := os.Remove(file); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return st, err
+		}
+		for d := filepath.Dir(file); d != filepath.Clean(dir); d = filepath.Dir(d) {
+			if os.Remove(d) != nil {
+				break // not empty
+			}
+		}
+		st.Removed = append(st.Removed, p)
+	}
+	sort.Strings(st.Removed)
+	data, err := json.MarshalIndent(next, "", "  ")
+	if err != nil {
+		return st, err
+	}
+	return st, fsutil.WriteFile(filepath.Join(dir, Manifest), append(data, '\n'), 0o644)
+}
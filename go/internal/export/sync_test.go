@@ -0,0 +1,64 @@
+package export
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestSync(t *testing.T) {
+	dir := t.TempDir()
+	read := func(p string) string {
+		data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(p)))
+		if err != nil {
+			return "<missing>"
+		}
+		return string(data)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "about.md"), []byte("mine"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	st, err := Sync(dir, []File{{"go/slices/index.md", []byte("slices")}, {"go/maps/index.md", []byte("maps")}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Written != 2 || st.Unchanged != 0 || len(st.Removed) != 0 {
+		t.Errorf("first Sync = %+v", st)
+	}
+	slicesFile := filepath.Join(dir, "go", "slices", "index.md")
+	before, _ := os.Stat(slicesFile)
+
+	st, err = Sync(dir, []File{{"go/slices/index.md", []byte("slices")}, {"git/rebase/index.md", []byte("rebase")}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Written != 1 || st.Unchanged != 1 || !slices.Equal(st.Removed, []string{"go/maps/index.md"}) {
+		t.Errorf("second Sync = %+v", st)
+	}
+	if after, _ := os.Stat(slicesFile); !after.ModTime().Equal(before.ModTime()) {
+		t.Error("an unchanged file was written again")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "go", "maps")); !os.IsNotExist(err) {
+		t.Errorf("the emptied go/maps directory was kept: %v", err)
+	}
+	if read("git/rebase/index.md") != "rebase" || read("about.md") != "mine" {
+		t.Errorf("git/rebase/index.md = %q, about.md = %q", read("git/rebase/index.md"), read("about.md"))
+	}
+	if m := read(Manifest); !strings.Contains(m, `"git/rebase/index.md"`) || strings.Contains(m, "maps") {
+		t.Errorf("%s =\n%s", Manifest, m)
+	}
+
+	// Files til did not write are left alone.
+	if _, err := Sync(dir, []File{{"about.md", []byte("exported")}}); err == nil || !strings.Contains(err.Error(), "was not written by til export") {
+		t.Errorf("Sync over a foreign file = %v", err)
+	}
+	if read("about.md") != "mine" {
+		t.Errorf("about.md = %q", read("about.md"))
+	}
+	if _, err := Sync(dir, []File{{"a.md", nil}, {"a.md", nil}}); err == nil {
+		t.Error("Sync accepted two files at one path")
+	}
+}
@@ -0,0 +1,102 @@
+// Package fsutil provides file helpers shared across til's packages.
+package fsutil
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// WriteFile replaces file with data through a temporary file in the same
+// directory, so readers never observe a partial write. An existing file
+// keeps its permissions; a new one gets perm.
+func WriteFile(file string, data []byte, perm os.FileMode) error {
+	if fi, err := os.Stat(file); err == nil {
+		perm = fi.Mode().Perm()
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(file), "."+filepath.Base(file)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), perm); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), file)
+}
+
+// WriteFiles replaces several files as one unit. Every file is first
+// staged next to its target; only when all are staged are they renamed
+// into place. If a rename fails, files already replaced are restored from
+// their previous contents.
+func WriteFiles(files map[string][]byte) error {
+	type staged struct {
+		file, tmp string
+		old       []byte
+		existed   bool
+	}
+	var all []*staged
+	cleanup := func() {
+		for _, s := range all {
+			os.Remove(s.tmp)
+		}
+	}
+	for file, data := range files {
+		s := &staged{file: file}
+		perm := os.FileMode(0o644)
+		if fi, err := os.Stat(file); err == nil {
+			perm = fi.Mode().Perm()
+			if s.old, err = os.ReadFile(file); err != nil {
+				cleanup()
+				return err
+			}
+			s.existed = true
+		}
+		if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+			cleanup()
+			return err
+		}
+		tmp, err := os.CreateTemp(filepath.Dir(file), "."+filepath.Base(file)+".tmp-*")
+		if err != nil {
+			cleanup()
+			return err
+		}
+		s.tmp = tmp.Name()
+		all = append(all, s)
+		_, err = tmp.Write(data)
+		if cerr := tmp.Close(); err == nil {
+			err = cerr
+		}
+		if err == nil {
+			err = os.Chmod(s.tmp, perm)
+		}
+		if err != nil {
+			cleanup()
+			return err
+		}
+	}
+	for i, s := range all {
+		if err := os.Rename(s.tmp, s.file); err != nil {
+			for _, done := range all[:i] {
+				if done.existed {
+					WriteFile(done.file, done.old, 0o644)
+				} else {
+					os.Remove(done.file)
+				}
+			}
+			cleanup()
+			return fmt.Errorf("replace %s: %w (earlier files restored)", s.file, err)
+		}
+	}
+	return nil
+}
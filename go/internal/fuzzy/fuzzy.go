@@ -0,0 +1,151 @@
+// Package fuzzy ranks strings against a pattern the way fzf does: each
+// word of the pattern must appear in a string with its characters in
+// order, not necessarily adjacent, and matches starting words or running
+// together rank first.
+package fuzzy
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Scores of a match.
+const (
+	scoreMatch       = 16
+	bonusBoundary    = 8
+	bonusConsecutive = 6
+	bonusFirst       = 4
+	penaltyGapStart  = 3
+	penaltyGap       = 1
+)
+
+// Match is a string matching a pattern.
+type Match struct {
+	// Index is the string's position in the list searched.
+	Index int
+	Score int
+	// Positions are the indexes of the matched runes, ascending.
+	Positions []int
+}
+
+// Find returns the strings of items matching pattern, best first; ties go
+// to shorter strings, then to those earlier in items. An empty pattern
+// matches every string, in order. A word of the pattern with upper case
+// letters matches case-sensitively.
+func Find(pattern string, items []string) []Match {
+	words := strings.Fields(pattern)
+	var out []Match
+	for i, item := range items {
+		m := Match{Index: i}
+		ok := true
+		for _, w := range words {
+			score, pos, found := match([]rune(w), []rune(item), !hasUpper(w))
+			if !found {
+				ok = false
+				break
+			}
+			m.Score += score
+			m.Positions = append(m.Positions, pos...)
+		}
+		if !ok {
+			continue
+		}
+		slices.Sort(m.Positions)
+		m.Positions = slices.Compact(m.Positions)
+		out = append(out, m)
+	}
+	if len(words) == 0 {
+		return out
+	}
+	slices.SortStableFunc(out, func(a, b Match) int {
+		if a.Score != b.Score {
+			return b.Score - a.Score
+		}
+		return utf8.RuneCountInString(items[a.Index]) - utf8.RuneCountInString(items[b.Index])
+	})
+	return out
+}
+
+// match finds pattern in text: the first occurrence of the pattern's runes
+// in order, narrowed from its end back to the shortest such run, and
+// scores it.
+func match(pattern, text []rune, fold bool) (int, []int, bool) {
+	if len(pattern) == 0 {
+		return 0, nil, true
+	}
+	eq := func(a, b rune) bool {
+		if fold {
+			return unicode.ToLower(a) == unicode.ToLower(b)
+		}
+		return a == b
+	}
+	// Scan forward for the end of the first occurrence.
+	pi, end := 0, -1
+	for ti, r := range text {
+		if eq(r, pattern[pi]) {
+			pi++
+			if pi == len(pattern) {
+				end = ti
+				break
+			}
+		}
+	}
+	if end < 0 {
+		return 0, nil, false
+	}
+	// Scan back from it for the latest start.
+	pos := make([]int, len(pattern))
+	pi = len(pattern) - 1
+	for ti := end; ti >= 0; ti-- {
+		if eq(text[ti], pattern[pi]) {
+			pos[pi] = ti
+			if pi--; pi < 0 {
+				break
+			}
+		}
+	}
+	score := 0
+	for i, p := range pos {
+		score += scoreMatch
+		b := boundary(text, p)
+		if i == 0 {
+			b += bonusFirst
+			if p == 0 {
+				b += bonusBoundary
+			}
+		}
+		if i > 0 {
+			if gap := p - pos[i-1] - 1; gap == 0 {
+				b = max(b, bonusConsecutive)
+			} else {
+				score -= penaltyGapStart + (gap-1)*penaltyGap
+			}
+		}
+		score += b
+	}
+	return score, pos, true
+}
+
+// boundary returns the bonus of a match at text[i]: at the start of a
+// word, path element or camelCase hump.
+func boundary(text []rune, i int) int {
+	if i == 0 {
+		return bonusBoundary
+	}
+	prev, r := text[i-1], text[i]
+	switch {
+	case prev == '/' || prev == ' ' || prev == '_' || prev == '-' || prev == '.':
+		return bonusBoundary
+	case unicode.IsLower(prev) && unicode.IsUpper(r):
+		return bonusBoundary - 1
+	case !unicode.IsLetter(prev) && !unicode.IsDigit(prev) && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+		return bonusBoundary - 2
+	}
+	return 0
+}
+
+func hasUpper(s string) bool {
+	return strings.IndexFunc(s, unicode.IsUpper) >= 0
+}
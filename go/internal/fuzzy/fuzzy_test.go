@@ -0,0 +1,75 @@
+package fuzzy
+
+import (
+	"slices"
+	"testing"
+)
+
+func indexes(ms []Match) []int {
+	var out []int
+	for _, m := range ms {
+		out = append(out, m.Index)
+	}
+	return out
+}
+
+func TestFind(t *testing.T) {
+	items := []string{
+		"Slices share arrays go/slices.md",
+		"Maps go/maps.md",
+		"Rebase onto git/rebase.md",
+		"Partial indexes databases/idx.md",
+		"Go modules go/mod.md",
+	}
+	tests := []struct {
+		pattern string
+		want    []int
+	}{
+		{"", []int{0, 1, 2, 3, 4}},
+		{"maps", []int{1}},
+		{"go", []int{4, 1, 0}},
+		{"sl arr", []int{0}},
+		{"rbs", []int{2, 3}},
+		{"zzz", nil},
+		{"Go", []int{4}},
+		{"MAPS", nil},
+		{"idx db", []int{3}},
+	}
+	for _, tt := range tests {
+		if got := indexes(Find(tt.pattern, items)); !slices.Equal(got, tt.want) {
+			t.Errorf("Find(%q) = %v, want %v", tt.pattern, got, tt.want)
+		}
+	}
+}
+
+func TestFindRanking(t *testing.T) {
+	// Matches at the start beat those at a word start, which beat those
+	// inside words; runs beat gaps.
+	items := []string{"xmapsx", "a_maps", "maps", "xmxaxpxs"}
+	if got := indexes(Find("maps", items)); !slices.Equal(got, []int{2, 1, 0, 3}) {
+		t.Errorf("Find(maps) = %v, want [2 1 0 3]", got)
+	}
+	// Ties go to the shorter string, then the earlier one.
+	if got := indexes(Find("ab", []string{"ab long", "ab", "ab long"})); !slices.Equal(got, []int{1, 0, 2}) {
+		t.Errorf("Find(ab) = %v, want [1 0 2]", got)
+	}
+	if got := indexes(Find("pm", []string{"pmap", "partialMap"})); got[0] != 0 {
+		t.Errorf("Find(pm) = %v", got)
+	}
+}
+
+func TestPositions(t *testing.T) {
+	m := Find("sa ar", []string{"Slices share arrays"})
+	if len(m) != 1 {
+		t.Fatal("no match")
+	}
+	// "sa" narrows to the shortest run ending at the first full match,
+	// "share".
+	if want := []int{7, 9, 10}; !slices.Equal(m[0].Positions, want) {
+		t.Errorf("Positions = %v, want %v", m[0].Positions, want)
+	}
+	m = Find("écl", []string{"Éclair"})
+	if len(m) != 1 || !slices.Equal(m[0].Positions, []int{0, 1, 2}) {
+		t.Errorf("Find(écl) = %+v, want rune positions", m)
+	}
+}
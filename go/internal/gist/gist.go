@@ -0,0 +1,178 @@
+// Package gist shares single entries as GitHub Gists: the entry's markdown
+// with each code block alongside as a file of its own.
+package gist
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+// API is the GitHub REST API endpoint.
+const API = "https://api.github.com"
+
+// Field is the frontmatter field the gist ID is recorded in.
+const Field = "gist"
+
+// ErrNotFound is returned when updating a gist GitHub no longer has, or
+// cannot be seen with the token.
+var ErrNotFound = errors.New("gist not found")
+
+// exts maps fence languages to file extensions. Other languages get
+// ".txt".
+var exts = map[string]string{
+	"go": ".go", "python": ".py", "py": ".py", "javascript": ".js", "js": ".js",
+	"typescript": ".ts", "ts": ".ts", "rust": ".rs", "rs": ".rs", "sh": ".sh",
+	"bash": ".sh", "shell": ".sh", "zsh": ".zsh", "c": ".c", "cpp": ".cpp",
+	"c++": ".cpp", "java": ".java", "kotlin": ".kt", "ruby": ".rb", "rb": ".rb",
+	"php": ".php", "swift": ".swift", "sql": ".sql", "html": ".html", "css": ".css",
+	"json": ".json", "yaml": ".yaml", "yml": ".yaml", "toml": ".toml", "xml": ".xml",
+	"lua": ".lua", "haskell": ".hs", "elixir": ".ex", "dockerfile": ".dockerfile",
+	"makefile": ".mk", "diff": ".diff", "mermaid": ".mmd", "tex": ".tex", "latex": ".tex",
+}
+
+// File is a file of a gist.
+type File struct {
+	Name    string
+	Content string
+}
+
+// Files returns the files of a gist sharing e: markdown as <slug>.md,
+// followed by each code block as <slug>-<n> with the extension of its
+// language, or the name given by the block's file attribute.
+func Files(e *entry.Entry, markdown []byte) []File {
+	slug := e.Meta.Slug
+	files := []File{{Name: slug + ".md", Content: string(markdown)}}
+	used := map[string]bool{files[0].Name: true}
+	for i, s := range entry.Snippets(markdown) {
+		b := s.Code
+		if strings.TrimSpace(b.Code) == "" {
+			continue
+		}
+		name := path.Base(b.Attrs["file"])
+		if name == "." || name == "/" {
+			ext, ok := exts[strings.ToLower(b.Lang)]
+			if !ok {
+				ext = ".txt"
+			}
+			name = slug + "-" + strconv.Itoa(i+1) + ext
+		}
+		for n := 2; used[name]; n++ {
+			ext := path.Ext(name)
+			name = strings.TrimSuffix(name, ext) + "-" + strconv.Itoa(n) + ext
+		}
+		used[name] = true
+		files = append(files, File{Name: name, Content: b.Code})
+	}
+	return files
+}
+
+// Gist is a gist as GitHub returns it.
+type Gist struct {
+	ID  string `json:"id"`
+	URL string `json:"html_url"`
+	// Files names the gist's files.
+	Files map[string]json.RawMessage `json:"files"`
+}
+
+// Client calls the gists API with a token allowed to write gists.
+type Client struct {
+	Token string
+	// API defaults to API.
+	API    string
+	Client *http.Client
+}
+
+// Create creates a gist, secret unless public.
+func (c Client) Create(ctx context.Context, description string, public bool, files []File) (*Gist, error) {
+	body := map[string]any{"description": description, "public": public, "files": contents(files, nil)}
+	return c.do(ctx, http.MethodPost, "/gists", body)
+}
+
+// Update replaces the files of the gist id with files, deleting those no
+// longer among them.
+func (c Client) Update(ctx context.Context, id, description string, files []File) (*Gist, error) {
+	old, err := c.do(ctx, http.MethodGet, "/gists/"+id, nil)
+	if err != nil {
+		return nil, err
+	}
+	body := map[string]any{"description": description, "files": contents(files, old.Files)}
+	return c.do(ctx, http.MethodPatch, "/gists/"+id, body)
+}
+
+// contents is the files field of a request: files by name, with the names
+// in old not among them set to null, which deletes them.
+func contents(files []File, old map[string]json.RawMessage) map[string]any {
+	out := map[string]any{}
+	for name := range old {
+		out[name] = nil
+	}
+	for _, f := range files {
+		out[f.Name] = map[string]string{"content": f.Content}
+	}
+	return out
+}
+
+func (c Client) do(ctx context.Context, method, p string, in any) (*Gist, error) {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(data)
+	}
+	api := c.API
+	if api == "" {
+		api = API
+	}
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(api, "/")+p, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	req.Header.Set("Authorization", "Bearer "+c.Token)
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	client := c.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 10<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+	if resp.StatusCode/100 != 2 {
+		var e struct {
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(data, &e) == nil && e.Message != "" {
+			return nil, fmt.Errorf("github: %s: %s", resp.Status, e.Message)
+		}
+		return nil, fmt.Errorf("github: %s", resp.Status)
+	}
+	var g Gist
+	if err := json.Unmarshal(data, &g); err != nil {
+		return nil, fmt.Errorf("github: %w", err)
+	}
+	return &g, nil
+}
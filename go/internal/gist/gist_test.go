@@ -0,0 +1,126 @@
+package gist
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+func TestFiles(t *testing.T) {
+	body := "# Slices\n\n```go\nfmt.Println(1)\n```\n\n```output\n1\n```\n\n```sh {file=run.sh}\ngo run .\n```\n\n```\n\n```\n\n```brainfuck\n+.\n```\n\n```bash {file=dir/run.sh}\necho\n```\n\n```text {file=slices-1.go}\nclash\n```\n"
+	e, err := entry.Parse("go/slices.md", []byte(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := Files(e, []byte(body))
+	var names []string
+	for _, f := range files {
+		names = append(names, f.Name)
+	}
+	want := []string{"slices.md", "slices-1.go", "run.sh", "slices-4.txt", "run-2.sh", "slices-1-2.go"}
+	if !slices.Equal(names, want) {
+		t.Errorf("Files = %q, want %q", names, want)
+	}
+	if files[0].Content != body || files[1].Content != "fmt.Println(1)\n" || files[2].Content != "go run .\n" {
+		t.Errorf("Files = %q", files)
+	}
+}
+
+// server is a fake gists API keeping a single gist's files.
+func server(t *testing.T) (*httptest.Server, *[]string, map[string]string) {
+	t.Helper()
+	var requests []string
+	stored := map[string]string{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		if r.Header.Get("Authorization") != "Bearer tok" || r.Header.Get("X-GitHub-Api-Version") == "" {
+			t.Errorf("headers = %v", r.Header)
+		}
+		if r.URL.Path == "/gists/gone" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Method != http.MethodGet {
+			var req struct {
+				Public *bool
+				Files  map[string]*struct{ Content string }
+			}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				t.Error(err)
+			}
+			if r.Method == http.MethodPost && (req.Public == nil || *req.Public) {
+				t.Errorf("public = %v, want false", req.Public)
+			}
+			for name, f := range req.Files {
+				if f == nil {
+					delete(stored, name)
+					continue
+				}
+				stored[name] = f.Content
+			}
+		}
+		files := map[string]any{}
+		for name := range stored {
+			files[name] = map[string]string{"filename": name}
+		}
+		json.NewEncoder(w).Encode(map[string]any{"id": "g1", "html_url": "https://gist.github.com/g1", "files": files})
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &requests, stored
+}
+
+func TestClient(t *testing.T) {
+	srv, requests, stored := server(t)
+	c := Client{Token: "tok", API: srv.URL + "/"}
+	ctx := context.Background()
+
+	g, err := c.Create(ctx, "Slices", false, []File{{"slices.md", "# Slices\n"}, {"slices-1.go", "package main\n"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.ID != "g1" || g.URL != "https://gist.github.com/g1" || len(g.Files) != 2 {
+		t.Errorf("Create = %+v", g)
+	}
+	if _, err := c.Update(ctx, "g1", "Slices", []File{{"slices.md", "# Slices, again\n"}}); err != nil {
+		t.Fatal(err)
+	}
+	if len(stored) != 1 || stored["slices.md"] != "# Slices, again\n" {
+		t.Errorf("files after update = %v, want the code block deleted", stored)
+	}
+	if want := []string{"POST /gists", "GET /gists/g1", "PATCH /gists/g1"}; !slices.Equal(*requests, want) {
+		t.Errorf("requests = %q, want %q", *requests, want)
+	}
+	if _, err := c.Update(ctx, "gone", "Slices", nil); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Update(gone) = %v, want ErrNotFound", err)
+	}
+}
+
+func TestClientErrors(t *testing.T) {
+	tests := []struct {
+		status int
+		body   string
+		want   string
+	}{
+		{http.StatusUnauthorized, `{"message":"Bad credentials"}`, "github: 401 Unauthorized: Bad credentials"},
+		{http.StatusBadGateway, ``, "github: 502 Bad Gateway"},
+		{http.StatusOK, `not json`, "github: invalid character"},
+	}
+	for _, tt := range tests {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(tt.status)
+			w.Write([]byte(tt.body))
+		}))
+		_, err := Client{API: srv.URL}.Create(context.Background(), "", true, nil)
+		srv.Close()
+		if err == nil || !strings.HasPrefix(err.Error(), tt.want) {
+			t.Errorf("Create with %d %q = %v, want %q", tt.status, tt.body, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,375 @@
+// Package git commits entry changes to the repository holding the notes
+// tree and reads their history. It drives the git command so the user's
+// hooks, signing and credentials apply.
+package git
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/canhta/til/go/internal/logging"
+)
+
+// ErrNotRepo is returned when the directory is not inside a work tree.
+var ErrNotRepo = errors.New("not a git repository")
+
+// Repo is a git work tree.
+type Repo struct {
+	// Root is the top-level directory of the work tree.
+	Root string
+}
+
+// Open returns the repository containing dir.
+func Open(ctx context.Context, dir string) (*Repo, error) {
+	out, err := run(ctx, dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, ErrNotRepo
+	}
+	return &Repo{Root: strings.TrimSpace(out)}, nil
+}
+
+// UserName returns the user.name git config has for dir, or "" when it has
+// none or git is missing.
+func UserName(ctx context.Context, dir string) string {
+	out, err := run(ctx, dir, "config", "user.name")
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(out)
+}
+
+// Busy reports why the repository cannot take a commit right now, such as
+// an unfinished merge or rebase, or "" when it can.
+func (r *Repo) Busy(ctx context.Context) (string, error) {
+	out, err := run(ctx, r.Root, "rev-parse", "--git-dir")
+	if err != nil {
+		return "", err
+	}
+	dir := strings.TrimSpace(out)
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(r.Root, dir)
+	}
+	for name, state := range map[string]string{
+		"MERGE_HEAD":       "a merge is in progress",
+		"CHERRY_PICK_HEAD": "a cherry-pick is in progress",
+		"REVERT_HEAD":      "a revert is in progress",
+		"rebase-merge":     "a rebase is in progress",
+		"rebase-apply":     "a rebase is in progress",
+	} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			return state, nil
+		}
+	}
+	return "", nil
+}
+
+// Changed reports whether any of files differs from HEAD or is untracked.
+func (r *Repo) Changed(ctx context.Context, files ...string) (bool, error) {
+	out, err := run(ctx, r.Root, append([]string{"status", "--porcelain", "--"}, files...)...)
+	if err != nil {
+		return false, err
+	}
+	return strings.TrimSpace(out) != "", nil
+}
+
+// CommitFiles commits the current contents of files alone, leaving any
+// other staged or unstaged changes in the work tree as they are.
+func (r *Repo) CommitFiles(ctx context.Context, msg string, files ...string) error {
+	if _, err := run(ctx, r.Root, append([]string{"add", "--"}, files...)...); err != nil {
+		return err
+	}
+	_, err := run(ctx, r.Root, append([]string{"commit", "--quiet", "-m", msg, "--only", "--"}, files...)...)
+	return err
+}
+
+// Push pushes the current branch to its upstream.
+func (r *Repo) Push(ctx context.Context) error {
+	_, err := run(ctx, r.Root, "push", "--quiet")
+	return err
+}
+
+// Revision is a commit changing a file.
+type Revision struct {
+	Hash    string    `json:"hash"`
+	Author  string    `json:"author"`
+	Date    time.Time `json:"date"`
+	Subject string    `json:"subject"`
+	// Path is the file's path at the commit, relative to Root, which
+	// differs from its current one when it was renamed since.
+	Path string `json:"path"`
+}
+
+// Short returns the abbreviated hash of r.
+func (r Revision) Short() string {
+	return r.Hash[:min(len(r.Hash), 7)]
+}
+
+// Log returns the commits changing file, newest first, following it
+// across renames.
+func (r *Repo) Log(ctx context.Context, file string) ([]Revision, error) {
+	out, err := run(ctx, r.Root, "log", "--follow", "--name-only", "--format=%x00%H%x1f%an%x1f%aI%x1f%s", "--", file)
+	if err != nil {
+		return nil, err
+	}
+	var revs []Revision
+	for _, rec := range strings.Split(out, "\x00")[1:] {
+		head, names, _ := strings.Cut(rec, "\n")
+		f := strings.SplitN(head, "\x1f", 4)
+		if len(f) < 4 {
+			continue
+		}
+		date, err := time.Parse(time.RFC3339, f[2])
+		if err != nil {
+			return nil, fmt.Errorf("git log: %w", err)
+		}
+		rev := Revision{Hash: f[0], Author: f[1], Date: date, Subject: f[3]}
+		rev.Path, _, _ = strings.Cut(strings.TrimSpace(names), "\n")
+		revs = append(revs, rev)
+	}
+	return revs, nil
+}
+
+// Resolve returns the commit hash rev names.
+func (r *Repo) Resolve(ctx context.Context, rev string) (string, error) {
+	out, err := run(ctx, r.Root, "rev-parse", "--verify", "--quiet", "--end-of-options", rev+"^{commit}")
+	if err != nil {
+		return "", fmt.Errorf("unknown revision %q", rev)
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// IsAncestor reports whether commit a is b or one of its ancestors.
+func (r *Repo) IsAncestor(ctx context.Context, a, b string) bool {
+	_, err := run(ctx, r.Root, "merge-base", "--is-ancestor", a, b)
+	return err == nil
+}
+
+// At returns the revision of revs, as returned by Log, current as of
+// commit rev: the newest that is rev or one of its ancestors. It reports
+// false when the file did not exist yet at rev.
+func (r *Repo) At(ctx context.Context, revs []Revision, rev string) (Revision, bool, error) {
+	hash, err := r.Resolve(ctx, rev)
+	if err != nil {
+		return Revision{}, false, err
+	}
+	for _, c := range revs {
+		if c.Hash == hash || r.IsAncestor(ctx, c.Hash, hash) {
+			return c, true, nil
+		}
+	}
+	return Revision{}, false, nil
+}
+
+// Show returns the contents of the file at path, relative to Root, as of
+// commit rev.
+func (r *Repo) Show(ctx context.Context, rev, path string) ([]byte, error) {
+	out, err := run(ctx, r.Root, "show", rev+":"+path)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(out), nil
+}
+
+// Change is a file differing from HEAD.
+type Change struct {
+	// Path is relative to Root.
+	Path      string
+	Untracked bool
+}
+
+// Status returns the changed and untracked files below dir, untracked
+// directories listed file by file.
+func (r *Repo) Status(ctx context.Context, dir string) ([]Change, error) {
+	out, err := run(ctx, r.Root, "status", "--porcelain=v1", "-z", "--untracked-files=all", "--", dir)
+	if err != nil {
+		return nil, err
+	}
+	var changes []Change
+	fields := strings.Split(out, "\x00")
+	for i := 0; i < len(fields); i++ {
+		f := fields[i]
+		if len(f) < 4 {
+			continue
+		}
+		changes = append(changes, Change{Path: f[3:], Untracked: f[:2] == "??"})
+		if f[0] == 'R' || f[0] == 'C' {
+			// The original path of a rename follows.
+			i++
+		}
+	}
+	return changes, nil
+}
+
+// ErrNoUpstream is returned by Pull when the current branch tracks no
+// remote branch.
+var ErrNoUpstream = errors.New("the current branch has no upstream; set one with git push -u")
+
+// Upstream returns the remote branch the current branch tracks.
+func (r *Repo) Upstream(ctx context.Context) (string, error) {
+	out, err := run(ctx, r.Root, "rev-parse", "--abbrev-ref", "--symbolic-full-name", "@{upstream}")
+	if err != nil {
+		return "", ErrNoUpstream
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// Count returns the number of commits in the range revs, as in git
+// rev-list a..b.
+func (r *Repo) Count(ctx context.Context, revs string) (int, error) {
+	out, err := run(ctx, r.Root, "rev-list", "--count", revs)
+	if err != nil {
+		return 0, err
+	}
+	var n int
+	_, err = fmt.Sscan(out, &n)
+	return n, err
+}
+
+// Fetch fetches from the remote of the current branch.
+func (r *Repo) Fetch(ctx context.Context) error {
+	_, err := run(ctx, r.Root, "fetch", "--quiet")
+	return err
+}
+
+// Rebase rebases the current branch onto its upstream, stashing other
+// changes meanwhile. It fails when a commit conflicts, leaving the rebase
+// stopped for the conflicts to be resolved.
+func (r *Repo) Rebase(ctx context.Context) error {
+	_, err := run(ctx, r.Root, "rebase", "--autostash", "@{upstream}")
+	return err
+}
+
+// Continue resumes a rebase stopped by conflicts once they are resolved
+// and added, skipping the commit when the resolution left it empty.
+func (r *Repo) Continue(ctx context.Context) error {
+	_, err := run(ctx, r.Root, "rebase", "--continue")
+	if err == nil {
+		return nil
+	}
+	if c, cerr := r.Conflicts(ctx); cerr != nil || len(c) > 0 {
+		return err
+	}
+	if changed, cerr := r.staged(ctx); cerr != nil || changed {
+		return err
+	}
+	_, err = run(ctx, r.Root, "rebase", "--skip")
+	return err
+}
+
+// Abort abandons a rebase, restoring the branch as it was.
+func (r *Repo) Abort(ctx context.Context) error {
+	_, err := run(ctx, r.Root, "rebase", "--abort")
+	return err
+}
+
+// Rebasing reports whether a rebase is stopped in the work tree.
+func (r *Repo) Rebasing(ctx context.Context) (bool, error) {
+	busy, err := r.Busy(ctx)
+	return busy == "a rebase is in progress", err
+}
+
+func (r *Repo) staged(ctx context.Context) (bool, error) {
+	out, err := run(ctx, r.Root, "diff", "--cached", "--name-only")
+	return strings.TrimSpace(out) != "", err
+}
+
+// Conflicts returns the paths, relative to Root, of the files with
+// unresolved conflicts.
+func (r *Repo) Conflicts(ctx context.Context) ([]string, error) {
+	out, err := run(ctx, r.Root, "diff", "--name-only", "-z", "--diff-filter=U")
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for _, p := range strings.Split(out, "\x00") {
+		if p != "" {
+			paths = append(paths, p)
+		}
+	}
+	return paths, nil
+}
+
+// Stages of a conflicted file in the index. During a rebase, Ours is the
+// upstream side and Theirs the commit being replayed.
+const (
+	Base   = 1
+	Ours   = 2
+	Theirs = 3
+)
+
+// Stage returns the version of the conflicted file at path in stage n,
+// reporting false when that side has none, as when it deleted the file.
+func (r *Repo) Stage(ctx context.Context, n int, path string) ([]byte, bool, error) {
+	if _, err := run(ctx, r.Root, "rev-parse", "--verify", "--quiet", fmt.Sprintf(":%d:%s", n, path)); err != nil {
+		return nil, false, nil
+	}
+	out, err := run(ctx, r.Root, "show", fmt.Sprintf(":%d:%s", n, path))
+	if err != nil {
+		return nil, false, err
+	}
+	return []byte(out), true, nil
+}
+
+// Add stages files, given relative to Root.
+func (r *Repo) Add(ctx context.Context, files ...string) error {
+	_, err := run(ctx, r.Root, append([]string{"add", "--"}, files...)...)
+	return err
+}
+
+// MergeFile merges the changes from base to ours and from base to theirs,
+// line by line. Conflicting changes are left between diff3-style markers:
+// "<<<<<<< ours", "||||||| base", "=======" and ">>>>>>> theirs".
+func (r *Repo) MergeFile(ctx context.Context, ours, base, theirs []byte) ([]byte, error) {
+	dir, err := os.MkdirTemp("", "til-merge-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	var files []string
+	for i, data := range [][]byte{ours, base, theirs} {
+		f := filepath.Join(dir, fmt.Sprint(i))
+		if err := os.WriteFile(f, data, 0o644); err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+	}
+	cmd := exec.CommandContext(ctx, "git", append([]string{"merge-file", "-p", "--diff3", "-L", "ours", "-L", "base", "-L", "theirs"}, files...)...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	err = cmd.Run()
+	// A positive exit status is the number of conflicts.
+	var exit *exec.ExitError
+	if err != nil && !(errors.As(err, &exit) && exit.ExitCode() > 0 && exit.ExitCode() < 128) {
+		return nil, fmt.Errorf("git merge-file: %s", strings.TrimSpace(stderr.String()+" "+err.Error()))
+	}
+	return stdout.Bytes(), nil
+}
+
+func run(ctx context.Context, dir string, args ...string) (string, error) {
+	ctx, end := logging.Start(ctx, "git "+args[0])
+	defer end()
+	start := time.Now()
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	// git is never left waiting on an editor, as rebase --continue would.
+	cmd.Env = append(os.Environ(), "GIT_EDITOR=true")
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		logging.From(ctx).Debug("git failed", "args", args, "err", msg, "took", time.Since(start))
+		return "", fmt.Errorf("git %s: %s", args[0], msg)
+	}
+	logging.From(ctx).Debug("git", "args", args, "took", time.Since(start))
+	return stdout.String(), nil
+}
@@ -0,0 +1,220 @@
+package git
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// newRepo returns a new repository with a first commit, isolated from the
+// user's git config.
+func newRepo(t *testing.T) *Repo {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	for _, v := range []string{"GIT_AUTHOR", "GIT_COMMITTER"} {
+		t.Setenv(v+"_NAME", "Ann")
+		t.Setenv(v+"_EMAIL", "ann@example.com")
+	}
+	dir := t.TempDir()
+	gitRun(t, dir, "init", "--quiet", "--initial-branch=main")
+	write(t, dir, "README.md", "# Notes\n")
+	gitRun(t, dir, "add", ".")
+	gitRun(t, dir, "commit", "--quiet", "-m", "init")
+	r, err := Open(context.Background(), dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func gitRun(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	out, err := run(context.Background(), dir, args...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func write(t *testing.T, dir, p, data string) {
+	t.Helper()
+	file := filepath.Join(dir, filepath.FromSlash(p))
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(file, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOpen(t *testing.T) {
+	r := newRepo(t)
+	sub := filepath.Join(r.Root, "go")
+	if err := os.Mkdir(sub, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	got, err := Open(context.Background(), sub)
+	if err != nil || got.Root != r.Root {
+		t.Errorf("Open(%s) = %v, %v; want root %s", sub, got, err, r.Root)
+	}
+	if _, err := Open(context.Background(), t.TempDir()); err != ErrNotRepo {
+		t.Errorf("Open outside a repository = %v, want ErrNotRepo", err)
+	}
+	if name := UserName(context.Background(), r.Root); name != "" {
+		t.Errorf("UserName = %q without user.name", name)
+	}
+	gitRun(t, r.Root, "config", "user.name", "Ann Smith")
+	if name := UserName(context.Background(), r.Root); name != "Ann Smith" {
+		t.Errorf("UserName = %q", name)
+	}
+}
+
+func TestCommitFiles(t *testing.T) {
+	ctx := context.Background()
+	r := newRepo(t)
+	write(t, r.Root, "go/a.md", "# A\n")
+	write(t, r.Root, "go/b.md", "# B\n")
+	write(t, r.Root, "README.md", "# Notes, edited\n")
+	gitRun(t, r.Root, "add", "go/b.md")
+	a := filepath.Join(r.Root, "go", "a.md")
+	if changed, err := r.Changed(ctx, a); err != nil || !changed {
+		t.Fatalf("Changed(untracked) = %v, %v", changed, err)
+	}
+	if err := r.CommitFiles(ctx, "til: add go/a.md", a); err != nil {
+		t.Fatal(err)
+	}
+	if changed, err := r.Changed(ctx, a); err != nil || changed {
+		t.Errorf("Changed after the commit = %v, %v", changed, err)
+	}
+	if got := gitRun(t, r.Root, "show", "--name-only", "--format=%s", "HEAD"); got != "til: add go/a.md\n\ngo/a.md\n" {
+		t.Errorf("HEAD = %q", got)
+	}
+	// The other changes are left as they were, staged or not.
+	if got := gitRun(t, r.Root, "status", "--porcelain"); got != " M README.md\nA  go/b.md\n" {
+		t.Errorf("status = %q", got)
+	}
+	revs, err := r.Log(ctx, a)
+	if err != nil || len(revs) != 1 || revs[0].Subject != "til: add go/a.md" || revs[0].Author != "Ann" || revs[0].Path != "go/a.md" {
+		t.Errorf("Log = %+v, %v", revs, err)
+	}
+}
+
+func TestBusy(t *testing.T) {
+	ctx := context.Background()
+	r := newRepo(t)
+	if busy, err := r.Busy(ctx); busy != "" || err != nil {
+		t.Errorf("Busy = %q, %v", busy, err)
+	}
+	gitRun(t, r.Root, "checkout", "--quiet", "-b", "other")
+	write(t, r.Root, "README.md", "other\n")
+	gitRun(t, r.Root, "commit", "--quiet", "-am", "other")
+	gitRun(t, r.Root, "checkout", "--quiet", "main")
+	write(t, r.Root, "README.md", "main\n")
+	gitRun(t, r.Root, "commit", "--quiet", "-am", "main")
+	if _, err := run(ctx, r.Root, "merge", "other"); err == nil {
+		t.Fatal("merge did not conflict")
+	}
+	if busy, err := r.Busy(ctx); busy != "a merge is in progress" || err != nil {
+		t.Errorf("Busy = %q, %v", busy, err)
+	}
+}
+
+func TestPush(t *testing.T) {
+	ctx := context.Background()
+	r := newRepo(t)
+	remote := t.TempDir()
+	gitRun(t, remote, "init", "--quiet", "--bare")
+	if err := r.Push(ctx); err == nil {
+		t.Error("Push without an upstream succeeded")
+	}
+	gitRun(t, r.Root, "remote", "add", "origin", remote)
+	gitRun(t, r.Root, "push", "--quiet", "-u", "origin", "main")
+	write(t, r.Root, "go/a.md", "# A\n")
+	if err := r.CommitFiles(ctx, "til: add go/a.md", filepath.Join(r.Root, "go", "a.md")); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Push(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := gitRun(t, remote, "log", "-1", "--format=%s", "main"); strings.TrimSpace(got) != "til: add go/a.md" {
+		t.Errorf("remote main = %q", got)
+	}
+}
+
+func TestLog(t *testing.T) {
+	r := newRepo(t)
+	ctx := context.Background()
+	commit := func(msg string) string {
+		t.Helper()
+		gitRun(t, r.Root, "add", "-A")
+		gitRun(t, r.Root, "commit", "--quiet", "-m", msg)
+		return strings.TrimSpace(gitRun(t, r.Root, "rev-parse", "HEAD"))
+	}
+	write(t, r.Root, "go/slice.md", "# Slices\n\nSlices share arrays.\n")
+	first := commit("add slices")
+	write(t, r.Root, "go/maps.md", "# Maps\n")
+	other := commit("add maps")
+	gitRun(t, r.Root, "mv", "go/slice.md", "go/slices.md")
+	renamed := commit("rename slices")
+	write(t, r.Root, "go/slices.md", "# Slices\n\nSlices share backing arrays.\n")
+	last := commit("edit slices")
+
+	revs, err := r.Log(ctx, filepath.Join(r.Root, "go", "slices.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, rev := range revs {
+		got = append(got, rev.Subject+" "+rev.Path)
+	}
+	if want := []string{"edit slices go/slices.md", "rename slices go/slices.md", "add slices go/slice.md"}; !slices.Equal(got, want) {
+		t.Fatalf("Log = %q, want %q", got, want)
+	}
+	if revs[0].Hash != last || revs[0].Author != "Ann" || revs[0].Date.IsZero() || revs[0].Short() != last[:7] {
+		t.Errorf("Log[0] = %+v", revs[0])
+	}
+
+	tests := []struct {
+		rev  string
+		want string
+	}{
+		{"HEAD", last},
+		{"HEAD~1", renamed},
+		{other, first},
+		{first[:10], first},
+	}
+	for _, tt := range tests {
+		got, ok, err := r.At(ctx, revs, tt.rev)
+		if err != nil || !ok || got.Hash != tt.want {
+			t.Errorf("At(%s) = %s, %v, %v, want %s", tt.rev, got.Hash, ok, err, tt.want)
+		}
+	}
+	if _, ok, err := r.At(ctx, revs, "HEAD~4"); ok || err != nil {
+		t.Errorf("At(before the entry) = %v, %v, want false", ok, err)
+	}
+	if _, _, err := r.At(ctx, revs, "nope"); err == nil {
+		t.Error("At(nope) succeeded")
+	}
+
+	data, err := r.Show(ctx, first, "go/slice.md")
+	if err != nil || string(data) != "# Slices\n\nSlices share arrays.\n" {
+		t.Errorf("Show = %q, %v", data, err)
+	}
+	if _, err := r.Show(ctx, first, "go/slices.md"); err == nil {
+		t.Error("Show of a path missing at the commit succeeded")
+	}
+	if h, err := r.Resolve(ctx, "main"); err != nil || h != last {
+		t.Errorf("Resolve(main) = %s, %v, want %s", h, err, last)
+	}
+	if revs, err := r.Log(ctx, filepath.Join(r.Root, "go", "new.md")); err != nil || len(revs) != 0 {
+		t.Errorf("Log of an uncommitted file = %v, %v", revs, err)
+	}
+}
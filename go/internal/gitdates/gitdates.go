@@ -0,0 +1,309 @@
+// Package gitdates derives entry dates from git history: an entry was
+// created when its file was first committed and updated when it was last
+// committed.
+//
+// History is read with go-git along the first-parent chain of HEAD, so
+// changes merged from other branches date from their merge. Rename
+// detection carries an entry's creation date across moves. The dates are
+// cached in the notes state directory together with the commit they were
+// computed at; later runs only walk the commits added since.
+package gitdates
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	_ "modernc.org/sqlite" // database/sql driver
+
+	"github.com/canhta/til/go/internal/notes"
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+// File is the cache file name inside the notes state directory.
+const File = "gitdates.db"
+
+const schema = `
+CREATE TABLE IF NOT EXISTS meta (
+	key   TEXT PRIMARY KEY,
+	value TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS dates (
+	path    TEXT PRIMARY KEY,
+	created INTEGER NOT NULL,
+	updated INTEGER NOT NULL
+);
+`
+
+// Dates are the commit times of one file.
+type Dates struct {
+	Created, Updated time.Time
+}
+
+// Apply replaces the created and updated dates of entries with those from
+// git history. Entries never committed keep their frontmatter dates. It is
+// a no-op when the tree is not inside a git repository.
+func Apply(ctx context.Context, tree *notes.Tree, entries []*entry.Entry) error {
+	dates, err := Load(ctx, tree)
+	if err != nil || dates == nil {
+		return err
+	}
+	for _, e := range entries {
+		if d, ok := dates[e.Path]; ok {
+			e.Meta.Date = d.Created
+			e.Meta.Updated = d.Updated
+		}
+	}
+	return nil
+}
+
+// Load returns the commit dates of the files in tree keyed by their
+// slash-separated path relative to the tree root, bringing the cache up to
+// date with HEAD first. It returns nil when the tree is not in a git
+// repository or the repository has no commits.
+func Load(ctx context.Context, tree *notes.Tree) (map[string]Dates, error) {
+	repo, err := git.PlainOpenWithOptions(tree.Root, &git.PlainOpenOptions{DetectDotGit: true})
+	if errors.Is(err, git.ErrRepositoryNotExists) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	head, err := repo.Head()
+	if errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	prefix, err := treePrefix(repo, tree.Root)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := openCache(tree)
+	if err != nil {
+		return nil, err
+	}
+	defer c.db.Close()
+	if err := c.sync(ctx, repo, head.Hash()); err != nil {
+		return nil, err
+	}
+	all, err := c.dates(ctx)
+	if err != nil {
+		return nil, err
+	}
+	dates := map[string]Dates{}
+	for p, d := range all {
+		if rel, ok := strings.CutPrefix(p, prefix); ok {
+			dates[rel] = d
+		}
+	}
+	return dates, nil
+}
+
+// treePrefix returns the path of root inside the work tree of repo,
+// slash-separated with a trailing slash, or "" at the top level.
+func treePrefix(repo *git.Repository, root string) (string, error) {
+	wt, err := repo.Worktree()
+	if err != nil {
+		return "", err
+	}
+	top, err := filepath.EvalSymlinks(wt.Filesystem.Root())
+	if err != nil {
+		return "", err
+	}
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return "", err
+	}
+	if abs, err = filepath.EvalSymlinks(abs); err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(top, abs)
+	if err != nil {
+		return "", err
+	}
+	if rel == "." {
+		return "", nil
+	}
+	return filepath.ToSlash(rel) + "/", nil
+}
+
+type cache struct {
+	db *sql.DB
+}
+
+func openCache(tree *notes.Tree) (*cache, error) {
+	file := tree.StatePath(File)
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return nil, err
+	}
+	db, err := sql.Open("sqlite", "file:"+file+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("git dates cache: %w", err)
+	}
+	return &cache{db: db}, nil
+}
+
+func (c *cache) dates(ctx context.Context) (map[string]Dates, error) {
+	rows, err := c.db.QueryContext(ctx, `SELECT path, created, updated FROM dates`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := map[string]Dates{}
+	for rows.Next() {
+		var p string
+		var created, updated int64
+		if err := rows.Scan(&p, &created, &updated); err != nil {
+			return nil, err
+		}
+		out[p] = Dates{Created: time.Unix(created, 0), Updated: time.Unix(updated, 0)}
+	}
+	return out, rows.Err()
+}
+
+// sync walks the commits between the cached head and head and folds their
+// changes into the cache. If the cached head is no longer on the
+// first-parent chain, as after a rebase, the cache is rebuilt.
+func (c *cache) sync(ctx context.Context, repo *git.Repository, head plumbing.Hash) error {
+	var cached string
+	err := c.db.QueryRowContext(ctx, `SELECT value FROM meta WHERE key = 'head'`).Scan(&cached)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	if cached == head.String() {
+		return nil
+	}
+	w, err := walk(ctx, repo, head, plumbing.NewHash(cached))
+	if err != nil {
+		return err
+	}
+
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if !w.reachedStop {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM dates`); err != nil {
+			return err
+		}
+	}
+	// Files renamed in the new commits take over the dates recorded under
+	// their old name.
+	for old, cur := range w.renamed {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO dates (path, created, updated) SELECT ?, created, updated FROM dates WHERE path = ?
+			ON CONFLICT (path) DO UPDATE SET created = min(created, excluded.created)`, cur, old); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM dates WHERE path = ?`, old); err != nil {
+			return err
+		}
+	}
+	for p, d := range w.dates {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO dates (path, created, updated) VALUES (?, ?, ?)
+			ON CONFLICT (path) DO UPDATE SET
+				created = min(created, excluded.created),
+				updated = max(updated, excluded.updated)`,
+			p, d.Created.Unix(), d.Updated.Unix()); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.ExecContext(ctx, `INSERT OR REPLACE INTO meta (key, value) VALUES ('head', ?)`, head.String()); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+type walkResult struct {
+	dates map[string]Dates
+	// renamed maps names a file had before the walked commits to the name
+	// it has at head.
+	renamed map[string]string
+	// reachedStop reports whether the walk ended at the stop commit rather
+	// than the root of history.
+	reachedStop bool
+}
+
+// walk collects the dates of markdown files changed by the first-parent
+// commits from head back to, but excluding, stop.
+func walk(ctx context.Context, repo *git.Repository, head, stop plumbing.Hash) (*walkResult, error) {
+	res := &walkResult{dates: map[string]Dates{}, renamed: map[string]string{}}
+	// alias maps an older name of a file to its name at head.
+	alias := map[string]string{}
+	current := func(p string) string {
+		if cur, ok := alias[p]; ok {
+			return cur
+		}
+		return p
+	}
+	commit, err := repo.CommitObject(head)
+	if err != nil {
+		return nil, err
+	}
+	for commit != nil {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if commit.Hash == stop {
+			res.reachedStop = true
+			break
+		}
+		tree, err := commit.Tree()
+		if err != nil {
+			return nil, err
+		}
+		var parent *object.Commit
+		var parentTree *object.Tree
+		if commit.NumParents() > 0 {
+			if parent, err = commit.Parent(0); err != nil {
+				return nil, err
+			}
+			if parentTree, err = parent.Tree(); err != nil {
+				return nil, err
+			}
+		}
+		changes, err := object.DiffTreeWithOptions(ctx, parentTree, tree, object.DefaultDiffTreeOptions)
+		if err != nil {
+			return nil, err
+		}
+		when := commit.Author.When
+		for _, ch := range changes {
+			from, to := ch.From.Name, ch.To.Name
+			if to == "" || !strings.HasSuffix(to, ".md") {
+				continue
+			}
+			p := current(to)
+			d, ok := res.dates[p]
+			if !ok || when.After(d.Updated) {
+				d.Updated = when
+			}
+			if !ok || when.Before(d.Created) {
+				d.Created = when
+			}
+			res.dates[p] = d
+			if from != "" && from != to {
+				alias[from] = p
+				res.renamed[from] = p
+			}
+		}
+		commit = parent
+	}
+	return res, nil
+}
@@ -0,0 +1,161 @@
+package gitdates
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/canhta/til/go/internal/notes"
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+// repo is a git work tree whose commits are dated by the test.
+type repo struct {
+	t   *testing.T
+	dir string
+}
+
+func newRepo(t *testing.T) *repo {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	r := &repo{t: t, dir: t.TempDir()}
+	r.git("", "init", "--quiet")
+	return r
+}
+
+func (r *repo) git(date string, args ...string) {
+	r.t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = r.dir
+	cmd.Env = append(os.Environ(), "GIT_AUTHOR_NAME=Ann", "GIT_AUTHOR_EMAIL=ann@example.com",
+		"GIT_COMMITTER_NAME=Ann", "GIT_COMMITTER_EMAIL=ann@example.com")
+	if date != "" {
+		cmd.Env = append(cmd.Env, "GIT_AUTHOR_DATE="+date+"T12:00:00Z", "GIT_COMMITTER_DATE="+date+"T12:00:00Z")
+	}
+	if out, err := cmd.CombinedOutput(); err != nil {
+		r.t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+	}
+}
+
+func (r *repo) write(p, data string) {
+	r.t.Helper()
+	file := filepath.Join(r.dir, filepath.FromSlash(p))
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		r.t.Fatal(err)
+	}
+	if err := os.WriteFile(file, []byte(data), 0o644); err != nil {
+		r.t.Fatal(err)
+	}
+}
+
+// commit commits every change in the work tree at date.
+func (r *repo) commit(date, msg string) {
+	r.t.Helper()
+	r.git("", "add", "-A")
+	r.git(date, "commit", "--quiet", "-m", msg)
+}
+
+func day(s string) time.Time {
+	t, err := time.Parse(time.DateOnly, s)
+	if err != nil {
+		panic(err)
+	}
+	return t.Add(12 * time.Hour)
+}
+
+func TestLoad(t *testing.T) {
+	ctx := context.Background()
+	r := newRepo(t)
+	tree := notes.Open(filepath.Join(r.dir, "notes"))
+	if dates, err := Load(ctx, tree); dates != nil || err != nil {
+		t.Fatalf("Load without commits = %v, %v", dates, err)
+	}
+	r.write("notes/go/a.md", "# A\n\nFirst version of a longer entry about slices.\n")
+	r.write("notes/go/b.md", "# B\n")
+	r.write("README.md", "# Outside the tree\n")
+	r.commit("2024-01-01", "add")
+	r.write("notes/go/b.md", "# B\n\nEdited.\n")
+	r.commit("2024-02-01", "edit b")
+	r.git("", "mv", "notes/go/a.md", "notes/go/slices.md")
+	r.commit("2024-03-01", "rename a")
+
+	want := map[string]Dates{
+		"go/slices.md": {day("2024-01-01"), day("2024-03-01")},
+		"go/b.md":      {day("2024-01-01"), day("2024-02-01")},
+	}
+	check := func(when string) {
+		t.Helper()
+		dates, err := Load(ctx, tree)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(dates) != len(want) {
+			t.Errorf("%s: Load = %v, want %v", when, dates, want)
+		}
+		for p, w := range want {
+			if d := dates[p]; !d.Created.Equal(w.Created) || !d.Updated.Equal(w.Updated) {
+				t.Errorf("%s: dates of %s = %v, want %v", when, p, d, w)
+			}
+		}
+	}
+	check("first load")
+	if _, err := os.Stat(tree.StatePath(File)); err != nil {
+		t.Errorf("no cache: %v", err)
+	}
+	check("cached")
+
+	// Later commits are folded into the cache.
+	r.write("notes/go/b.md", "# B\n\nEdited again.\n")
+	r.write("notes/go/c.md", "# C\n")
+	r.commit("2024-04-01", "edit b, add c")
+	want["go/b.md"] = Dates{day("2024-01-01"), day("2024-04-01")}
+	want["go/c.md"] = Dates{day("2024-04-01"), day("2024-04-01")}
+	check("after a commit")
+
+	// A rewritten history rebuilds it.
+	r.git("", "reset", "--quiet", "--hard", "HEAD~1")
+	r.write("notes/go/d.md", "# D\n")
+	r.commit("2024-05-01", "add d")
+	delete(want, "go/c.md")
+	want["go/b.md"] = Dates{day("2024-01-01"), day("2024-02-01")}
+	want["go/d.md"] = Dates{day("2024-05-01"), day("2024-05-01")}
+	check("after a reset")
+}
+
+func TestApply(t *testing.T) {
+	r := newRepo(t)
+	r.write("go/a.md", "---\ndate: 2020-01-01\n---\n# A\n")
+	r.commit("2024-01-01", "add a")
+	r.write("go/new.md", "---\ndate: 2020-06-01\n---\n# New\n")
+	tree := notes.Open(r.dir)
+	entries, err := tree.Entries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Apply(context.Background(), tree, entries); err != nil {
+		t.Fatal(err)
+	}
+	byPath := map[string]*entry.Entry{}
+	for _, e := range entries {
+		byPath[e.Path] = e
+	}
+	if e := byPath["go/a.md"]; !e.Meta.Date.Equal(day("2024-01-01")) || !e.Meta.Updated.Equal(day("2024-01-01")) {
+		t.Errorf("go/a.md dated %s, updated %s", e.Meta.Date, e.Meta.Updated)
+	}
+	if e := byPath["go/new.md"]; e.Meta.Date.Format(time.DateOnly) != "2020-06-01" {
+		t.Errorf("uncommitted go/new.md dated %s, want its frontmatter date", e.Meta.Date)
+	}
+
+	outside := notes.Open(t.TempDir())
+	if err := Apply(context.Background(), outside, entries); err != nil {
+		t.Errorf("Apply outside a repository = %v", err)
+	}
+}
@@ -0,0 +1,189 @@
+// Package gitsync resolves the conflicts syncing a notes tree with its
+// remote leaves in entries, so that they need not be fixed by hand between
+// raw conflict markers.
+//
+// The two versions of an entry are merged line by line from the version
+// they share. Changes that still conflict are resolved by where they are:
+//
+//   - in the frontmatter, the version updated last wins, by its updated
+//     date, else its creation date, else the local one;
+//   - where both sides only have list items, both sets of items are kept,
+//     the local ones first, dropping duplicates;
+//   - anywhere else, a Chooser picks the local side, the remote one or
+//     both.
+package gitsync
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/canhta/til/go/internal/git"
+	"github.com/canhta/til/go/internal/logging"
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+// Choice is how a Chooser resolves a Conflict.
+type Choice int
+
+const (
+	KeepLocal Choice = iota
+	KeepRemote
+	KeepBoth
+)
+
+// Conflict is a change made differently on both sides, as lines with
+// their line endings.
+type Conflict struct {
+	Path                string
+	Local, Base, Remote []string
+}
+
+// A Chooser resolves a conflict the rules leave open. An error aborts the
+// merge.
+type Chooser func(Conflict) (Choice, error)
+
+var listItemRE = regexp.MustCompile(`^\s*([-*+]|\d+[.)])\s`)
+
+// Merge merges the local and remote versions of the entry file at path,
+// both changed from base, which is nil when both sides added the file.
+func Merge(ctx context.Context, repo *git.Repo, path string, local, base, remote []byte, choose Chooser) ([]byte, error) {
+	merged, err := repo.MergeFile(ctx, local, base, remote)
+	if err != nil {
+		return nil, err
+	}
+	remoteNewer := newer(path, remote, local)
+	log := logging.From(ctx).With("path", path)
+
+	var out bytes.Buffer
+	// front tracks whether the lines written so far open a frontmatter
+	// block without closing it.
+	front, lines := false, 0
+	emit := func(ls []string) {
+		for _, l := range ls {
+			t := strings.TrimRight(l, "\r\n")
+			switch {
+			case lines == 0 && t == "---":
+				front = true
+			case front && (t == "---" || t == "..."):
+				front = false
+			}
+			out.WriteString(l)
+			lines++
+		}
+	}
+	var c *Conflict
+	var side *[]string
+	for _, l := range strings.SplitAfter(string(merged), "\n") {
+		marker := strings.TrimRight(l, "\r\n")
+		switch {
+		case c == nil && marker == "<<<<<<< ours":
+			c = &Conflict{Path: path}
+			side = &c.Local
+		case c != nil && marker == "||||||| base":
+			side = &c.Base
+		case c != nil && marker == "=======":
+			side = &c.Remote
+		case c != nil && marker == ">>>>>>> theirs":
+			var res []string
+			switch {
+			case front:
+				res = c.Local
+				if remoteNewer {
+					res = c.Remote
+				}
+				log.Debug("resolved frontmatter conflict", "remote_newer", remoteNewer)
+			case listOnly(c.Local) && listOnly(c.Remote):
+				res = union(c.Local, c.Remote)
+				log.Debug("resolved list conflict", "items", len(res))
+			default:
+				choice, err := choose(*c)
+				if err != nil {
+					return nil, err
+				}
+				keep := "both"
+				switch choice {
+				case KeepLocal:
+					res, keep = c.Local, "local"
+				case KeepRemote:
+					res, keep = c.Remote, "remote"
+				default:
+					res = append(append([]string{}, c.Local...), c.Remote...)
+				}
+				log.Debug("resolved conflict by choice", "keep", keep)
+			}
+			emit(res)
+			c = nil
+		case c != nil:
+			*side = append(*side, l)
+		default:
+			emit([]string{l})
+		}
+	}
+	if c != nil {
+		return nil, errors.New(path + ": unterminated conflict")
+	}
+	return out.Bytes(), nil
+}
+
+// newer reports whether the entry file a was updated after b.
+func newer(path string, a, b []byte) bool {
+	when := func(data []byte) (updated, created int64) {
+		e, err := entry.Parse(path, data)
+		if err != nil {
+			return 0, 0
+		}
+		if !e.Meta.Updated.IsZero() {
+			updated = e.Meta.Updated.Unix()
+		}
+		if !e.Meta.Date.IsZero() {
+			created = e.Meta.Date.Unix()
+		}
+		return updated, created
+	}
+	au, ac := when(a)
+	bu, bc := when(b)
+	if au != bu {
+		return au > bu
+	}
+	return ac > bc
+}
+
+// listOnly reports whether lines are list items, their indented
+// continuations and blank lines, with at least one item.
+func listOnly(lines []string) bool {
+	items := 0
+	for _, l := range lines {
+		switch {
+		case listItemRE.MatchString(l):
+			items++
+		case strings.TrimSpace(l) == "":
+		case items > 0 && (strings.HasPrefix(l, " ") || strings.HasPrefix(l, "\t")):
+		default:
+			return false
+		}
+	}
+	return items > 0
+}
+
+// union returns the lines of a followed by those of b not in a.
+func union(a, b []string) []string {
+	seen := map[string]bool{}
+	out := append([]string{}, a...)
+	for _, l := range a {
+		seen[strings.TrimSpace(l)] = true
+	}
+	for _, l := range b {
+		if t := strings.TrimSpace(l); t != "" && !seen[t] {
+			seen[t] = true
+			out = append(out, l)
+		}
+	}
+	// The last line of a may lack the line break the items after it need.
+	if n := len(a); n > 0 && len(out) > n && !strings.HasSuffix(out[n-1], "\n") {
+		out[n-1] += "\n"
+	}
+	return out
+}
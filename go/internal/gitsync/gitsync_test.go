@@ -0,0 +1,128 @@
+package gitsync
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/canhta/til/go/internal/git"
+)
+
+func merge(t *testing.T, local, base, remote string, choose Chooser) (string, error) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	if choose == nil {
+		choose = func(c Conflict) (Choice, error) {
+			t.Errorf("unexpected conflict: %+v", c)
+			return KeepLocal, nil
+		}
+	}
+	var b []byte
+	if base != "" {
+		b = []byte(base)
+	}
+	out, err := Merge(context.Background(), &git.Repo{Root: t.TempDir()}, "go/x.md", []byte(local), b, []byte(remote), choose)
+	return string(out), err
+}
+
+func lines(ls ...string) string {
+	return strings.Join(ls, "\n") + "\n"
+}
+
+func TestMergeClean(t *testing.T) {
+	base := lines("---", "title: A", "---", "", "one", "", "two", "", "three")
+	local := lines("---", "title: A", "---", "", "one, local", "", "two", "", "three")
+	remote := lines("---", "title: A", "---", "", "one", "", "two", "", "three, remote")
+	got, err := merge(t, local, base, remote, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := lines("---", "title: A", "---", "", "one, local", "", "two", "", "three, remote"); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestMergeFrontmatter(t *testing.T) {
+	base := lines("---", "title: A", "updated: 2024-01-01", "---", "", "body")
+	newer := lines("---", "title: Remote", "updated: 2024-03-01", "---", "", "body")
+	older := lines("---", "title: Local", "updated: 2024-02-01", "---", "", "body")
+	got, err := merge(t, older, base, newer, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != newer {
+		t.Errorf("remote updated last: got\n%s\nwant\n%s", got, newer)
+	}
+	got, err = merge(t, newer, base, older, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != newer {
+		t.Errorf("local updated last: got\n%s\nwant\n%s", got, newer)
+	}
+}
+
+func TestMergeLists(t *testing.T) {
+	base := lines("# Links", "", "- a", "", "end")
+	local := lines("# Links", "", "- a", "- b", "- c", "", "end")
+	remote := lines("# Links", "", "- a", "- c", "- d", "", "end")
+	got, err := merge(t, local, base, remote, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := lines("# Links", "", "- a", "- b", "- c", "- d", "", "end"); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestMergeAddedOnBothSides(t *testing.T) {
+	got, err := merge(t, lines("- local"), "", lines("- remote"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := lines("- local", "- remote"); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestMergeChooser(t *testing.T) {
+	base := lines("intro", "", "text", "", "outro")
+	local := lines("intro", "", "local text", "", "outro")
+	remote := lines("intro", "", "remote text", "", "outro")
+	tests := []struct {
+		choice Choice
+		want   string
+	}{
+		{KeepLocal, local},
+		{KeepRemote, remote},
+		{KeepBoth, lines("intro", "", "local text", "remote text", "", "outro")},
+	}
+	for _, tt := range tests {
+		var seen Conflict
+		got, err := merge(t, local, base, remote, func(c Conflict) (Choice, error) {
+			seen = c
+			return tt.choice, nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("choice %d: got\n%s\nwant\n%s", tt.choice, got, tt.want)
+		}
+		if seen.Path != "go/x.md" || strings.Join(seen.Local, "") != "local text\n" || strings.Join(seen.Base, "") != "text\n" || strings.Join(seen.Remote, "") != "remote text\n" {
+			t.Errorf("conflict = %+v", seen)
+		}
+	}
+}
+
+func TestMergeChooserError(t *testing.T) {
+	abort := errors.New("abort")
+	_, err := merge(t, lines("a local"), lines("a"), lines("a remote"), func(Conflict) (Choice, error) { return KeepLocal, abort })
+	if !errors.Is(err, abort) {
+		t.Errorf("err = %v, want %v", err, abort)
+	}
+}
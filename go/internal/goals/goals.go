@@ -0,0 +1,159 @@
+// Package goals measures progress on writing goals, targets of a number of
+// entries per day, week, month or year.
+//
+// A goal is behind when the entries of the current period fall short of
+// its pace: the target prorated over the days of the period so far, today
+// included, so a goal of 3 a week expects one entry by Wednesday and all
+// three by Sunday.
+package goals
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/canhta/til/go/internal/config"
+	"github.com/canhta/til/go/internal/query"
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+// Progress is how far a goal is in its current period.
+type Progress struct {
+	// Goal describes the goal, as "3 entries per week tagged go".
+	Goal     string `json:"goal"`
+	Per      string `json:"per"`
+	Tag      string `json:"tag,omitempty"`
+	Category string `json:"category,omitempty"`
+	Target   int    `json:"target"`
+	// Start and End are the first and last days of the period.
+	Start string `json:"start"`
+	End   string `json:"end"`
+	// Count is the number of matching entries created in the period so
+	// far, and Pace the number expected by now.
+	Count  int  `json:"count"`
+	Pace   int  `json:"pace"`
+	Behind bool `json:"behind"`
+}
+
+// Remaining is the number of entries still to write in the period.
+func (p Progress) Remaining() int { return max(p.Target-p.Count, 0) }
+
+// Status reports the count against the target, as "1 of 3 since
+// 2026-10-12, behind (2 expected by now)".
+func (p Progress) Status() string {
+	s := fmt.Sprintf("%d of %d since %s", p.Count, p.Target, p.Start)
+	switch {
+	case p.Count >= p.Target:
+		s += ", done"
+	case p.Behind:
+		s += fmt.Sprintf(", behind (%d expected by now)", p.Pace)
+	default:
+		s += ", on track"
+	}
+	return s
+}
+
+// String is the goal and its status.
+func (p Progress) String() string { return p.Goal + ": " + p.Status() }
+
+// Check measures each goal against entries as of now.
+func Check(goals []config.Goal, entries []*entry.Entry, now time.Time) ([]Progress, error) {
+	out := make([]Progress, 0, len(goals))
+	for i, g := range goals {
+		if g.Entries <= 0 {
+			return nil, fmt.Errorf("goal %d: entries must be positive", i+1)
+		}
+		per := g.Per
+		if per == "" {
+			per = "week"
+		}
+		start, days, err := period(per, now)
+		if err != nil {
+			return nil, fmt.Errorf("goal %d: %w", i+1, err)
+		}
+		end := start.AddDate(0, 0, days)
+		var x query.And
+		if g.Tag != "" {
+			x = append(x, query.Tag(g.Tag))
+		}
+		if g.Category != "" {
+			x = append(x, query.Category(g.Category))
+		}
+		p := Progress{
+			Goal:     describe(g.Entries, per, g.Tag, g.Category),
+			Per:      per,
+			Tag:      g.Tag,
+			Category: g.Category,
+			Target:   g.Entries,
+			Start:    start.Format(entry.DateLayout),
+			End:      end.AddDate(0, 0, -1).Format(entry.DateLayout),
+		}
+		for _, e := range query.Filter(entries, x) {
+			if d := day(e.Created()); !e.Created().IsZero() && !d.Before(start) && d.Before(end) {
+				p.Count++
+			}
+		}
+		elapsed := daysBetween(start, day(now)) + 1
+		p.Pace = g.Entries * elapsed / days
+		p.Behind = p.Count < p.Pace
+		out = append(out, p)
+	}
+	return out, nil
+}
+
+// Behind returns the goals of ps that are behind.
+func Behind(ps []Progress) []Progress {
+	var out []Progress
+	for _, p := range ps {
+		if p.Behind {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// period returns the first day of the period per holding now and its
+// length in days.
+func period(per string, now time.Time) (time.Time, int, error) {
+	d := day(now)
+	switch per {
+	case "day":
+		return d, 1, nil
+	case "week":
+		// Weeks start on Monday.
+		start := d.AddDate(0, 0, -((int(d.Weekday()) + 6) % 7))
+		return start, 7, nil
+	case "month":
+		start := time.Date(d.Year(), d.Month(), 1, 0, 0, 0, 0, time.UTC)
+		return start, daysBetween(start, start.AddDate(0, 1, 0)), nil
+	case "year":
+		start := time.Date(d.Year(), 1, 1, 0, 0, 0, 0, time.UTC)
+		return start, daysBetween(start, start.AddDate(1, 0, 0)), nil
+	}
+	return time.Time{}, 0, fmt.Errorf("unknown period %q: want day, week, month or year", per)
+}
+
+// day returns t's calendar day, as a UTC midnight so that days compare
+// equal whatever the zone of the time they came from.
+func day(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func daysBetween(a, b time.Time) int {
+	return int(b.Sub(a).Hours() / 24)
+}
+
+func describe(n int, per, tag, category string) string {
+	unit := "entries"
+	if n == 1 {
+		unit = "entry"
+	}
+	parts := []string{fmt.Sprintf("%d %s per %s", n, unit, per)}
+	if category != "" {
+		parts = append(parts, "in "+category)
+	}
+	if tag != "" {
+		parts = append(parts, "tagged "+tag)
+	}
+	return strings.Join(parts, " ")
+}
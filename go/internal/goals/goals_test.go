@@ -0,0 +1,89 @@
+package goals
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/canhta/til/go/internal/config"
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+// now is a Wednesday.
+var now = time.Date(2026, 10, 14, 21, 0, 0, 0, time.UTC)
+
+func entries(t *testing.T) []*entry.Entry {
+	t.Helper()
+	files := []struct{ path, data string }{
+		{"go/slices.md", "---\ntitle: Slices\ndate: 2026-10-12\ntags: [go]\n---\n"},
+		{"go/maps.md", "---\ntitle: Maps\ndate: 2026-10-14\ntags: [go]\n---\n"},
+		{"git/rebase.md", "---\ntitle: Rebase\ndate: 2026-10-02\ntags: [git]\n---\n"},
+		{"git/old.md", "---\ntitle: Old\ndate: 2026-10-11\n---\n"},
+		{"git/undated.md", "# Undated\n"},
+	}
+	var out []*entry.Entry
+	for _, f := range files {
+		e, err := entry.Parse(f.path, []byte(f.data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		out = append(out, e)
+	}
+	return out
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		goal   config.Goal
+		want   string
+		behind bool
+	}{
+		{config.Goal{Entries: 3}, "3 entries per week: 2 of 3 since 2026-10-12, on track", false},
+		{config.Goal{Entries: 7, Per: "week"}, "7 entries per week: 2 of 7 since 2026-10-12, behind (3 expected by now)", true},
+		{config.Goal{Entries: 1, Per: "day"}, "1 entry per day: 1 of 1 since 2026-10-14, done", false},
+		{config.Goal{Entries: 10, Per: "month"}, "10 entries per month: 4 of 10 since 2026-10-01, on track", false},
+		{config.Goal{Entries: 2, Per: "week", Category: "git"}, "2 entries per week in git: 0 of 2 since 2026-10-12, on track", false},
+		{config.Goal{Entries: 1, Per: "month", Tag: "git", Category: "git"}, "1 entry per month in git tagged git: 1 of 1 since 2026-10-01, done", false},
+		{config.Goal{Entries: 365, Per: "year", Tag: "go"}, "365 entries per year tagged go: 2 of 365 since 2026-01-01, behind (287 expected by now)", true},
+	}
+	for _, tt := range tests {
+		ps, err := Check([]config.Goal{tt.goal}, entries(t), now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := ps[0].String(); got != tt.want {
+			t.Errorf("Check(%+v) = %q, want %q", tt.goal, got, tt.want)
+		}
+		if ps[0].Behind != tt.behind {
+			t.Errorf("Check(%+v).Behind = %v", tt.goal, ps[0].Behind)
+		}
+	}
+}
+
+func TestCheckMany(t *testing.T) {
+	ps, err := Check([]config.Goal{{Entries: 7}, {Entries: 1, Per: "day"}, {Entries: 30, Per: "month"}}, entries(t), now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	behind := Behind(ps)
+	if len(behind) != 2 || behind[0].Target != 7 || behind[1].Target != 30 {
+		t.Errorf("Behind = %v", behind)
+	}
+	if p := ps[0]; p.Start != "2026-10-12" || p.End != "2026-10-18" || p.Remaining() != 5 {
+		t.Errorf("week goal = %+v, remaining %d", p, p.Remaining())
+	}
+	if p := ps[1]; p.Remaining() != 0 || p.End != p.Start {
+		t.Errorf("day goal = %+v, remaining %d", p, p.Remaining())
+	}
+	if p := ps[2]; p.End != "2026-10-31" {
+		t.Errorf("month goal = %+v", p)
+	}
+}
+
+func TestCheckErrors(t *testing.T) {
+	for _, g := range []config.Goal{{}, {Entries: -1}, {Entries: 1, Per: "fortnight"}} {
+		if _, err := Check([]config.Goal{{Entries: 1}, g}, nil, now); err == nil || !strings.HasPrefix(err.Error(), "goal 2: ") {
+			t.Errorf("Check(%+v) = %v, want an error about goal 2", g, err)
+		}
+	}
+}
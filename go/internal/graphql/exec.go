@@ -0,0 +1,515 @@
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+)
+
+// MaxDepth is how deeply selections may nest, so that a query cannot
+// follow links between entries without end.
+const MaxDepth = 12
+
+// Request is a GraphQL request as clients post it.
+type Request struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName,omitempty"`
+	Variables     map[string]any `json:"variables,omitempty"`
+}
+
+// Response is the answer to a request.
+type Response struct {
+	// Data is nil when the request failed before it was executed, and
+	// also when a non-null root field failed, which Executed tells apart.
+	Data     *Map
+	Errors   []Error
+	Executed bool
+}
+
+// MarshalJSON encodes r with its data when it was executed, null or not,
+// and without when the request itself was in error, as the spec says.
+func (r *Response) MarshalJSON() ([]byte, error) {
+	type response struct {
+		Data   *Map    `json:"data"`
+		Errors []Error `json:"errors,omitempty"`
+	}
+	if !r.Executed {
+		return json.Marshal(struct {
+			Errors []Error `json:"errors,omitempty"`
+		}{r.Errors})
+	}
+	return json.Marshal(response{r.Data, r.Errors})
+}
+
+// Error is an error raised by a request or one of its fields.
+type Error struct {
+	Message string `json:"message"`
+	// Path is the response keys and list indexes of the field that failed.
+	Path []any `json:"path,omitempty"`
+}
+
+// Map is a response object, whose keys keep the order of the query.
+type Map struct {
+	keys []string
+	vals map[string]any
+}
+
+func (m *Map) set(k string, v any) {
+	if _, ok := m.vals[k]; !ok {
+		m.keys = append(m.keys, k)
+	}
+	m.vals[k] = v
+}
+
+// Get returns the value at k.
+func (m *Map) Get(k string) any { return m.vals[k] }
+
+func (m *Map) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, k := range m.keys {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		key, _ := json.Marshal(k)
+		b.Write(key)
+		b.WriteByte(':')
+		v, err := json.Marshal(m.vals[k])
+		if err != nil {
+			return nil, err
+		}
+		b.Write(v)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+// errNull is returned for a non-null field that resolved to null, which
+// then nulls its parent.
+var errNull = errors.New("null")
+
+type executor struct {
+	schema *Schema
+	doc    *document
+	vars   map[string]any
+	errs   []Error
+}
+
+// Execute runs the request against s. Errors in the request itself leave
+// it unexecuted; errors in fields null them and are listed alongside the
+// rest of the data.
+func (s *Schema) Execute(ctx context.Context, req Request) *Response {
+	doc, err := parse(req.Query)
+	if err != nil {
+		return fail(err)
+	}
+	op, err := doc.operation(req.OperationName)
+	if err != nil {
+		return fail(err)
+	}
+	if op.kind != "query" {
+		return fail(fmt.Errorf("%s operations are not supported", op.kind))
+	}
+	ex := &executor{schema: s, doc: doc, vars: map[string]any{}}
+	for _, v := range op.vars {
+		raw, ok := req.Variables[v.name]
+		if !ok && v.def != nil {
+			ex.vars[v.name], err = ex.coerceLiteral(v.def, parseType(v.typ))
+		} else if ok || parseType(v.typ).nonNull {
+			ex.vars[v.name], err = coerce(raw, parseType(v.typ))
+		}
+		if err != nil {
+			return fail(fmt.Errorf("variable $%s: %v", v.name, err))
+		}
+	}
+	if err := ex.check(s.object(s.Query), op.sel, 1, map[string]bool{}); err != nil {
+		return fail(err)
+	}
+	data, err := ex.object(ctx, s.object(s.Query), nil, op.sel, nil)
+	if err != nil && !errors.Is(err, errNull) {
+		return fail(err)
+	}
+	return &Response{Data: data, Errors: ex.errs, Executed: true}
+}
+
+func fail(err error) *Response {
+	return &Response{Errors: []Error{{Message: err.Error()}}}
+}
+
+func (doc *document) operation(name string) (*operation, error) {
+	if name == "" {
+		if len(doc.operations) > 1 {
+			return nil, errors.New("operationName is required with several operations")
+		}
+		return doc.operations[0], nil
+	}
+	for _, op := range doc.operations {
+		if op.name == name {
+			return op, nil
+		}
+	}
+	return nil, fmt.Errorf("no operation named %s", name)
+}
+
+// check validates sel against o before anything is resolved: fields must
+// exist and selections must match their types.
+func (ex *executor) check(o *Object, sel []selection, depth int, active map[string]bool) error {
+	if depth > MaxDepth {
+		return fmt.Errorf("the query nests deeper than %d levels", MaxDepth)
+	}
+	for _, s := range sel {
+		switch s := s.(type) {
+		case *field:
+			if s.name == "__typename" {
+				continue
+			}
+			f := o.field(s.name)
+			if f == nil {
+				return fmt.Errorf("line %d: %s has no field %s", s.line, o.Name, s.name)
+			}
+			for _, a := range s.args {
+				if !hasArg(f, a.name) {
+					return fmt.Errorf("line %d: %s.%s has no argument %s", s.line, o.Name, s.name, a.name)
+				}
+			}
+			n := parseType(f.Type).named()
+			switch obj := ex.schema.object(n); {
+			case obj == nil && s.sel != nil:
+				return fmt.Errorf("line %d: %s is a %s and has no fields to select", s.line, s.key(), n)
+			case obj != nil && s.sel == nil:
+				return fmt.Errorf("line %d: select the fields of %s wanted from %s", s.line, n, s.key())
+			case obj != nil:
+				if err := ex.check(obj, s.sel, depth+1, active); err != nil {
+					return err
+				}
+			}
+		case *spread:
+			fr := ex.doc.fragments[s.name]
+			if fr == nil {
+				return fmt.Errorf("no fragment named %s", s.name)
+			}
+			if active[s.name] {
+				return fmt.Errorf("fragment %s spreads itself", s.name)
+			}
+			active[s.name] = true
+			if fr.on == o.Name {
+				if err := ex.check(o, fr.sel, depth, active); err != nil {
+					return err
+				}
+			}
+			delete(active, s.name)
+		case *inline:
+			if s.on == "" || s.on == o.Name {
+				if err := ex.check(o, s.sel, depth, active); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func hasArg(f *Field, name string) bool {
+	for _, a := range f.Args {
+		if a.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// collect returns the fields of sel to resolve on o, merging those with
+// the same response key and expanding fragments.
+func (ex *executor) collect(o *Object, sel []selection, keys *[]string, fields map[string][]*field) error {
+	for _, s := range sel {
+		switch s := s.(type) {
+		case *field:
+			ok, err := ex.included(s.directives)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				continue
+			}
+			k := s.key()
+			if _, ok := fields[k]; !ok {
+				*keys = append(*keys, k)
+			}
+			fields[k] = append(fields[k], s)
+		case *spread:
+			fr := ex.doc.fragments[s.name]
+			ok, err := ex.included(s.directives)
+			if err != nil {
+				return err
+			}
+			if !ok || fr.on != o.Name {
+				continue
+			}
+			if err := ex.collect(o, fr.sel, keys, fields); err != nil {
+				return err
+			}
+		case *inline:
+			ok, err := ex.included(s.directives)
+			if err != nil {
+				return err
+			}
+			if !ok || s.on != "" && s.on != o.Name {
+				continue
+			}
+			if err := ex.collect(o, s.sel, keys, fields); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// included applies @include and @skip.
+func (ex *executor) included(ds []directive) (bool, error) {
+	for _, d := range ds {
+		if d.name != "include" && d.name != "skip" {
+			return false, fmt.Errorf("unknown directive @%s", d.name)
+		}
+		if len(d.args) != 1 || d.args[0].name != "if" {
+			return false, fmt.Errorf("@%s takes one argument, if", d.name)
+		}
+		v, err := ex.coerceLiteral(d.args[0].val, parseType("Boolean!"))
+		b, ok := v.(bool)
+		if err == nil && !ok {
+			err = errors.New("if is null")
+		}
+		if err != nil {
+			return false, fmt.Errorf("@%s: %v", d.name, err)
+		}
+		if b == (d.name == "skip") {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// object resolves the fields of sel on parent, which is of type o.
+func (ex *executor) object(ctx context.Context, o *Object, parent any, sel []selection, path []any) (*Map, error) {
+	var keys []string
+	fields := map[string][]*field{}
+	if err := ex.collect(o, sel, &keys, fields); err != nil {
+		return nil, err
+	}
+	m := &Map{vals: map[string]any{}}
+	for _, k := range keys {
+		fs := fields[k]
+		if fs[0].name == "__typename" {
+			m.set(k, o.Name)
+			continue
+		}
+		var merged []selection
+		for _, f := range fs {
+			merged = append(merged, f.sel...)
+		}
+		v, err := ex.field(ctx, o.field(fs[0].name), fs[0], parent, merged, append(path[:len(path):len(path)], k))
+		if err != nil {
+			return nil, err
+		}
+		m.set(k, v)
+	}
+	return m, nil
+}
+
+// field resolves f on parent and completes its value.
+func (ex *executor) field(ctx context.Context, def *Field, f *field, parent any, sel []selection, path []any) (any, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	args, err := ex.args(def, f.args)
+	var v any
+	switch {
+	case err != nil:
+	case def.Resolve != nil:
+		v, err = def.Resolve(ctx, parent, args)
+	default:
+		m, _ := parent.(map[string]any)
+		v = m[def.Name]
+	}
+	typ := parseType(def.Type)
+	if err != nil {
+		ex.errs = append(ex.errs, Error{Message: err.Error(), Path: path})
+		if typ.nonNull {
+			return nil, errNull
+		}
+		return nil, nil
+	}
+	return ex.complete(ctx, typ, v, sel, path)
+}
+
+// complete turns v, of type typ, into response data.
+func (ex *executor) complete(ctx context.Context, typ typeRef, v any, sel []selection, path []any) (any, error) {
+	if isNil(v) {
+		if typ.nonNull {
+			ex.errs = append(ex.errs, Error{Message: "null value for a non-null field", Path: path})
+			return nil, errNull
+		}
+		return nil, nil
+	}
+	var (
+		out any
+		err error
+	)
+	switch {
+	case typ.list != nil:
+		rv := reflect.ValueOf(v)
+		if rv.Kind() != reflect.Slice {
+			return nil, fmt.Errorf("%v: a list resolved to %T", path, v)
+		}
+		list := make([]any, rv.Len())
+		for i := range list {
+			if list[i], err = ex.complete(ctx, *typ.list, rv.Index(i).Interface(), sel, append(path[:len(path):len(path)], i)); err != nil {
+				break
+			}
+		}
+		out = list
+	case ex.schema.object(typ.name) != nil:
+		out, err = ex.object(ctx, ex.schema.object(typ.name), v, sel, path)
+	default:
+		out = v
+	}
+	if errors.Is(err, errNull) {
+		if typ.nonNull {
+			return nil, errNull
+		}
+		return nil, nil
+	}
+	return out, err
+}
+
+func isNil(v any) bool {
+	if v == nil {
+		return true
+	}
+	switch rv := reflect.ValueOf(v); rv.Kind() {
+	case reflect.Pointer, reflect.Map, reflect.Interface:
+		return rv.IsNil()
+	}
+	return false
+}
+
+// args coerces the arguments given to a field, applying defaults.
+func (ex *executor) args(def *Field, given []argument) (map[string]any, error) {
+	out := map[string]any{}
+	for _, a := range def.Args {
+		typ := parseType(a.Type)
+		var lit value
+		found := false
+		for _, g := range given {
+			if g.name == a.Name {
+				lit, found = g.val, true
+			}
+		}
+		if name, ok := lit.(variable); ok {
+			if v, ok := ex.vars[string(name)]; ok {
+				if v == nil && typ.nonNull {
+					return nil, fmt.Errorf("%s: $%s is null", a.Name, name)
+				}
+				out[a.Name] = v
+				continue
+			}
+			found = false
+		}
+		switch {
+		case found:
+			v, err := ex.coerceLiteral(lit, typ)
+			if err != nil {
+				return nil, fmt.Errorf("%s(%s): %v", def.Name, a.Name, err)
+			}
+			out[a.Name] = v
+		case a.Default != nil:
+			out[a.Name] = a.Default
+		case typ.nonNull:
+			return nil, fmt.Errorf("%s: argument %s is required", def.Name, a.Name)
+		default:
+			out[a.Name] = nil
+		}
+	}
+	return out, nil
+}
+
+// coerceLiteral coerces a value written in the query to typ.
+func (ex *executor) coerceLiteral(v value, typ typeRef) (any, error) {
+	switch v := v.(type) {
+	case variable:
+		x, ok := ex.vars[string(v)]
+		if !ok && typ.nonNull {
+			return nil, fmt.Errorf("$%s is not given", v)
+		}
+		return x, nil
+	case []value:
+		if typ.list == nil {
+			return nil, fmt.Errorf("want %s, got a list", typ)
+		}
+		list := make([]any, len(v))
+		for i, x := range v {
+			var err error
+			if list[i], err = ex.coerceLiteral(x, *typ.list); err != nil {
+				return nil, err
+			}
+		}
+		return list, nil
+	case objectValue:
+		return nil, fmt.Errorf("want %s, got an object", typ)
+	case enum:
+		return nil, fmt.Errorf("want %s, got %s", typ, string(v))
+	case int64:
+		return coerce(float64(v), typ)
+	}
+	return coerce(v, typ)
+}
+
+// coerce coerces a JSON value, or a scalar literal, to typ.
+func coerce(v any, typ typeRef) (any, error) {
+	if v == nil {
+		if typ.nonNull {
+			return nil, fmt.Errorf("want %s, got null", typ)
+		}
+		return nil, nil
+	}
+	if typ.list != nil {
+		items, ok := v.([]any)
+		if !ok {
+			// A single value stands for a list of one.
+			items = []any{v}
+		}
+		list := make([]any, len(items))
+		for i, x := range items {
+			var err error
+			if list[i], err = coerce(x, *typ.list); err != nil {
+				return nil, err
+			}
+		}
+		return list, nil
+	}
+	switch typ.name {
+	case "String", "ID":
+		if s, ok := v.(string); ok {
+			return s, nil
+		}
+		if n, ok := v.(float64); ok && typ.name == "ID" && n == math.Trunc(n) {
+			return fmt.Sprint(int64(n)), nil
+		}
+	case "Int":
+		if n, ok := v.(float64); ok && n == math.Trunc(n) && math.Abs(n) <= math.MaxInt32 {
+			return int(n), nil
+		}
+	case "Float":
+		if n, ok := v.(float64); ok {
+			return n, nil
+		}
+	case "Boolean":
+		if b, ok := v.(bool); ok {
+			return b, nil
+		}
+	}
+	return nil, fmt.Errorf("want %s, got %v", typ, v)
+}
@@ -0,0 +1,227 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+type book struct {
+	id, title, author string
+	tags              []string
+}
+
+var books = []*book{
+	{"1", "Dune", "Frank Herbert", []string{"sf"}},
+	{"2", "Emma", "Jane Austen", nil},
+	{"3", "Persuasion", "Jane Austen", []string{"romance", "classic"}},
+}
+
+// testSchema is a schema of books and their authors.
+func testSchema() *Schema {
+	byID := func(id string) *book {
+		for _, b := range books {
+			if b.id == id {
+				return b
+			}
+		}
+		return nil
+	}
+	return &Schema{
+		Query: "Query",
+		Types: []*Object{
+			{Name: "Query", Doc: "The root.", Fields: []*Field{
+				{Name: "hello", Type: "String!", Args: []Arg{{Name: "name", Type: "String", Default: "world"}},
+					Resolve: func(_ context.Context, _ any, args map[string]any) (any, error) {
+						return "hello " + args["name"].(string), nil
+					}},
+				{Name: "books", Type: "[Book!]!", Args: []Arg{
+					{Name: "first", Type: "Int", Doc: "At most this many."},
+					{Name: "ids", Type: "[ID!]"},
+				}, Resolve: func(_ context.Context, _ any, args map[string]any) (any, error) {
+					out := books
+					if ids, ok := args["ids"].([]any); ok {
+						out = nil
+						for _, id := range ids {
+							out = append(out, byID(id.(string)))
+						}
+					}
+					if n, ok := args["first"].(int); ok && n < len(out) {
+						out = out[:n]
+					}
+					return out, nil
+				}},
+				{Name: "book", Type: "Book", Args: []Arg{{Name: "id", Type: "ID!"}},
+					Resolve: func(_ context.Context, _ any, args map[string]any) (any, error) {
+						if b := byID(args["id"].(string)); b != nil {
+							return b, nil
+						}
+						return nil, errors.New("no such book")
+					}},
+				{Name: "stats", Type: "Stats", Resolve: func(context.Context, any, map[string]any) (any, error) {
+					return map[string]any{"count": len(books), "ratio": 0.5}, nil
+				}},
+			}},
+			{Name: "Book", Fields: []*Field{
+				{Name: "id", Type: "ID!", Resolve: func(_ context.Context, p any, _ map[string]any) (any, error) { return p.(*book).id, nil }},
+				{Name: "title", Type: "String!", Resolve: func(_ context.Context, p any, _ map[string]any) (any, error) { return p.(*book).title, nil }},
+				{Name: "tags", Type: "[String!]!", Resolve: func(_ context.Context, p any, _ map[string]any) (any, error) { return p.(*book).tags, nil }},
+				{Name: "author", Type: "Author!", Resolve: func(_ context.Context, p any, _ map[string]any) (any, error) {
+					return map[string]any{"name": p.(*book).author}, nil
+				}},
+				{Name: "sequel", Type: "Book!", Doc: "Fails for every book.", Resolve: func(context.Context, any, map[string]any) (any, error) {
+					return nil, errors.New("no sequel")
+				}},
+				{Name: "similar", Type: "[Book!]!", Resolve: func(context.Context, any, map[string]any) (any, error) { return books, nil }},
+			}},
+			{Name: "Author", Fields: []*Field{{Name: "name", Type: "String!"}}},
+			{Name: "Stats", Fields: []*Field{{Name: "count", Type: "Int!"}, {Name: "ratio", Type: "Float"}}},
+		},
+	}
+}
+
+// execute runs query and returns the response as JSON.
+func execute(t *testing.T, query string, vars map[string]any, op string) string {
+	t.Helper()
+	resp := testSchema().Execute(context.Background(), Request{Query: query, Variables: vars, OperationName: op})
+	data, err := json.Marshal(resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestExecute(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		vars  map[string]any
+		op    string
+		want  string
+	}{
+		{"default argument", `{ hello }`, nil, "", `{"data":{"hello":"hello world"}}`},
+		{"aliases keep the order of the query", `{ b: hello(name: "Bob") a: hello }`, nil, "",
+			`{"data":{"b":"hello Bob","a":"hello world"}}`},
+		{"nested objects", `{ books(first: 2) { title author { name } tags } }`, nil, "",
+			`{"data":{"books":[{"title":"Dune","author":{"name":"Frank Herbert"},"tags":["sf"]},{"title":"Emma","author":{"name":"Jane Austen"},"tags":[]}]}}`},
+		{"variables", `query Q($id: ID!, $n: Int = 1) { book(id: $id) { title } books(first: $n) { id } }`, map[string]any{"id": "3"}, "",
+			`{"data":{"book":{"title":"Persuasion"},"books":[{"id":"1"}]}}`},
+		{"ID from a number and a list from one value", `query($ids: [ID!]) { books(ids: $ids) { title } }`, map[string]any{"ids": 2.0}, "",
+			`{"data":{"books":[{"title":"Emma"}]}}`},
+		{"list literal", `{ books(ids: ["3", "1"]) { id } }`, nil, "", `{"data":{"books":[{"id":"3"},{"id":"1"}]}}`},
+		{"fragments and typename", `{ book(id: "1") { ...Parts ... on Book { id } __typename } } fragment Parts on Book { title author { ... { name } } }`, nil, "",
+			`{"data":{"book":{"title":"Dune","author":{"name":"Frank Herbert"},"id":"1","__typename":"Book"}}}`},
+		{"merged fields", `{ book(id: "2") { author { name } author { n: name } } }`, nil, "",
+			`{"data":{"book":{"author":{"name":"Jane Austen","n":"Jane Austen"}}}}`},
+		{"include and skip", `query($yes: Boolean!) { a: hello @include(if: $yes) b: hello @skip(if: $yes) c: hello @include(if: false) }`, map[string]any{"yes": true}, "",
+			`{"data":{"a":"hello world"}}`},
+		{"named operation", `query A { a: hello } query B { b: hello }`, nil, "B", `{"data":{"b":"hello world"}}`},
+		{"map fields", `{ stats { count ratio } }`, nil, "", `{"data":{"stats":{"count":3,"ratio":0.5}}}`},
+		{"block string", "{ hello(name: \"\"\"\n    Bob\n    \"\"\") }", nil, "", `{"data":{"hello":"hello Bob"}}`},
+		{"comments", "# hi\n{ hello # there\n}", nil, "", `{"data":{"hello":"hello world"}}`},
+
+		// A failing nullable field is null; a non-null one nulls the
+		// nearest nullable field above it.
+		{"nullable field error", `{ book(id: "9") { title } hello }`, nil, "",
+			`{"data":{"book":null,"hello":"hello world"},"errors":[{"message":"no such book","path":["book"]}]}`},
+		{"non-null field error", `{ book(id: "1") { title sequel { title } } }`, nil, "",
+			`{"data":{"book":null},"errors":[{"message":"no sequel","path":["book","sequel"]}]}`},
+		{"non-null in a list", `{ book(id: "1") { similar { sequel { id } } } }`, nil, "",
+			`{"data":{"book":null},"errors":[{"message":"no sequel","path":["book","similar",0,"sequel"]}]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := execute(t, tt.query, tt.vars, tt.op); got != tt.want {
+				t.Errorf("got  %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExecuteErrors(t *testing.T) {
+	deep := "{ books { " + strings.Repeat("similar { ", MaxDepth) + "id" + strings.Repeat(" }", MaxDepth) + " } }"
+	tests := []struct {
+		query string
+		vars  map[string]any
+		op    string
+		want  string
+	}{
+		{`{ nope }`, nil, "", "line 1: Query has no field nope"},
+		{"{\n  books { id pages }\n}", nil, "", "line 2: Book has no field pages"},
+		{`{ hello(title: "x") }`, nil, "", "line 1: Query.hello has no argument title"},
+		{`{ hello { x } }`, nil, "", "line 1: hello is a String and has no fields to select"},
+		{`{ b: books }`, nil, "", "line 1: select the fields of Book wanted from b"},
+		{`{ book(id: "1") { ...A } } fragment A on Book { ...A }`, nil, "", "fragment A spreads itself"},
+		{`{ book(id: "1") { ...Missing } }`, nil, "", "no fragment named Missing"},
+		{`fragment A on Book { id } fragment A on Book { id } { hello }`, nil, "", "fragment A is defined twice"},
+		{deep, nil, "", "the query nests deeper than 12 levels"},
+		{`mutation { hello }`, nil, "", "mutation operations are not supported"},
+		{`query A { hello } query B { hello }`, nil, "", "operationName is required with several operations"},
+		{`query A { hello }`, nil, "C", "no operation named C"},
+		{`fragment A on Book { id }`, nil, "", "no operation in the request"},
+		{`{ hello`, nil, "", "line 1: unexpected end of request"},
+		{`{ }`, nil, "", "line 1: empty selection"},
+		{`{ hello(name: "x) }`, nil, "", "line 1: unterminated string"},
+		{`query($id: ID!) { book(id: $id) { id } }`, nil, "", "variable $id: want ID!, got null"},
+		{`query($n: Int) { books(first: $n) { id } }`, map[string]any{"n": 1.5}, "", "variable $n: want Int, got 1.5"},
+		{`{ hello @deprecated }`, nil, "", "unknown directive @deprecated"},
+	}
+	for _, tt := range tests {
+		resp := testSchema().Execute(context.Background(), Request{Query: tt.query, Variables: tt.vars, OperationName: tt.op})
+		if resp.Executed || len(resp.Errors) != 1 || !strings.Contains(resp.Errors[0].Message, tt.want) {
+			got, _ := json.Marshal(resp)
+			t.Errorf("Execute(%q) = %s, want the error %q", tt.query, got, tt.want)
+		}
+	}
+
+	// Errors in arguments are errors of the field alone.
+	if got := execute(t, `{ books(first: "two") { id } hello }`, nil, ""); got != `{"data":null,"errors":[{"message":"books(first): want Int, got two","path":["books"]}]}` {
+		t.Errorf("bad argument = %s", got)
+	}
+	if got := execute(t, `{ book { id } }`, nil, ""); got != `{"data":{"book":null},"errors":[{"message":"book: argument id is required","path":["book"]}]}` {
+		t.Errorf("missing argument = %s", got)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if resp := testSchema().Execute(ctx, Request{Query: `{ hello }`}); resp.Executed || len(resp.Errors) != 1 {
+		t.Errorf("Execute with a cancelled context = %+v", resp)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	if err := testSchema().Check(); err != nil {
+		t.Fatal(err)
+	}
+	s := testSchema()
+	s.Query = "Root"
+	if err := s.Check(); err == nil {
+		t.Error("Check accepted a missing root type")
+	}
+	s = testSchema()
+	s.Types[2].Fields = append(s.Types[2].Fields, &Field{Name: "born", Type: "Date"})
+	if err := s.Check(); err == nil || err.Error() != "graphql: Author.born: unknown type Date" {
+		t.Errorf("Check = %v", err)
+	}
+	s = testSchema()
+	s.Types[0].Fields[0].Args = append(s.Types[0].Fields[0].Args, Arg{Name: "who", Type: "Author"})
+	if err := s.Check(); err == nil {
+		t.Error("Check accepted an object argument")
+	}
+}
+
+func TestSDL(t *testing.T) {
+	sdl := testSchema().SDL()
+	for _, want := range []string{
+		"schema {\n  query: Query\n}\n",
+		"\"The root.\"\ntype Query {\n  hello(name: String = \"world\"): String!\n  books(\n    \"At most this many.\"\n    first: Int\n    ids: [ID!]\n  ): [Book!]!\n",
+		"  \"Fails for every book.\"\n  sequel: Book!\n",
+		"type Author {\n  name: String!\n}\n",
+	} {
+		if !strings.Contains(sdl, want) {
+			t.Errorf("SDL lacks\n%s\nin\n%s", want, sdl)
+		}
+	}
+}
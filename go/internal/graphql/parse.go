@@ -0,0 +1,555 @@
+package graphql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// document is a parsed request: its operations and fragments.
+type document struct {
+	operations []*operation
+	fragments  map[string]*fragment
+}
+
+type operation struct {
+	kind string // "query", "mutation" or "subscription"
+	name string
+	vars []varDef
+	sel  []selection
+}
+
+type varDef struct {
+	name string
+	typ  string
+	def  value
+}
+
+type fragment struct {
+	name, on string
+	sel      []selection
+}
+
+// selection is a *field, *spread or *inline.
+type selection interface{}
+
+type field struct {
+	alias, name string
+	args        []argument
+	directives  []directive
+	sel         []selection
+	line        int
+}
+
+func (f *field) key() string {
+	if f.alias != "" {
+		return f.alias
+	}
+	return f.name
+}
+
+type spread struct {
+	name       string
+	directives []directive
+}
+
+type inline struct {
+	on         string
+	directives []directive
+	sel        []selection
+}
+
+type argument struct {
+	name string
+	val  value
+}
+
+type directive struct {
+	name string
+	args []argument
+}
+
+// value is a literal or variable in a request: nil, bool, int64, float64,
+// string, enum, variable, []value or objectValue.
+type value any
+
+type enum string
+
+type variable string
+
+type objectValue []argument
+
+type tokKind int
+
+const (
+	tEOF tokKind = iota
+	tPunct
+	tName
+	tInt
+	tFloat
+	tString
+)
+
+type tok struct {
+	kind tokKind
+	text string
+	line int
+}
+
+// lex splits a request into tokens, dropping whitespace, commas and
+// comments.
+func lex(src string) ([]tok, error) {
+	var toks []tok
+	line := 1
+	src = strings.TrimPrefix(src, "\ufeff")
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == '\n':
+			line++
+			i++
+		case c == ' ' || c == '\t' || c == '\r' || c == ',':
+			i++
+		case c == '#':
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case strings.HasPrefix(src[i:], "..."):
+			toks = append(toks, tok{tPunct, "...", line})
+			i += 3
+		case strings.IndexByte("!$&()[]{}:=@|", c) >= 0:
+			toks = append(toks, tok{tPunct, string(c), line})
+			i++
+		case c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z':
+			j := i + 1
+			for j < len(src) && (src[j] == '_' || 'a' <= src[j] && src[j] <= 'z' || 'A' <= src[j] && src[j] <= 'Z' || '0' <= src[j] && src[j] <= '9') {
+				j++
+			}
+			toks = append(toks, tok{tName, src[i:j], line})
+			i = j
+		case c == '-' || '0' <= c && c <= '9':
+			j, kind := i+1, tInt
+			for j < len(src) && ('0' <= src[j] && src[j] <= '9' || strings.IndexByte(".eE+-", src[j]) >= 0) {
+				if strings.IndexByte(".eE", src[j]) >= 0 {
+					kind = tFloat
+				}
+				j++
+			}
+			toks = append(toks, tok{kind, src[i:j], line})
+			i = j
+		case strings.HasPrefix(src[i:], `"""`):
+			end := strings.Index(src[i+3:], `"""`)
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: unterminated block string", line)
+			}
+			text := src[i+3 : i+3+end]
+			toks = append(toks, tok{tString, blockString(text), line})
+			line += strings.Count(text, "\n")
+			i += 6 + end
+		case c == '"':
+			s, n, err := quoted(src[i:])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", line, err)
+			}
+			toks = append(toks, tok{tString, s, line})
+			i += n
+		default:
+			r, _ := utf8.DecodeRuneInString(src[i:])
+			return nil, fmt.Errorf("line %d: unexpected character %q", line, r)
+		}
+	}
+	return append(toks, tok{tEOF, "", line}), nil
+}
+
+// quoted reads the string at the start of s, returning its value and
+// length.
+func quoted(s string) (string, int, error) {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '\n':
+			return "", 0, fmt.Errorf("unterminated string")
+		case '"':
+			v, err := strconv.Unquote(s[:i+1])
+			if err != nil {
+				return "", 0, fmt.Errorf("invalid string %s", s[:i+1])
+			}
+			return v, i + 1, nil
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated string")
+}
+
+// blockString strips the common indentation and blank first and last
+// lines of a block string.
+func blockString(s string) string {
+	lines := strings.Split(strings.ReplaceAll(s, `\"""`, `"""`), "\n")
+	indent := -1
+	for _, l := range lines[1:] {
+		if t := strings.TrimLeft(l, " \t"); t != "" {
+			if n := len(l) - len(t); indent < 0 || n < indent {
+				indent = n
+			}
+		}
+	}
+	for i := 1; i < len(lines) && indent > 0; i++ {
+		if len(lines[i]) >= indent {
+			lines[i] = lines[i][indent:]
+		}
+	}
+	for len(lines) > 0 && strings.TrimSpace(lines[0]) == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return strings.Join(lines, "\n")
+}
+
+type parser struct {
+	toks []tok
+	pos  int
+}
+
+// parse parses a request document.
+func parse(src string) (*document, error) {
+	toks, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{toks: toks}
+	doc := &document{fragments: map[string]*fragment{}}
+	for p.peek().kind != tEOF {
+		t := p.peek()
+		switch {
+		case t.kind == tPunct && t.text == "{":
+			sel, err := p.selectionSet()
+			if err != nil {
+				return nil, err
+			}
+			doc.operations = append(doc.operations, &operation{kind: "query", sel: sel})
+		case t.kind == tName && (t.text == "query" || t.text == "mutation" || t.text == "subscription"):
+			op, err := p.operation()
+			if err != nil {
+				return nil, err
+			}
+			doc.operations = append(doc.operations, op)
+		case t.kind == tName && t.text == "fragment":
+			f, err := p.fragment()
+			if err != nil {
+				return nil, err
+			}
+			if doc.fragments[f.name] != nil {
+				return nil, fmt.Errorf("fragment %s is defined twice", f.name)
+			}
+			doc.fragments[f.name] = f
+		default:
+			return nil, p.unexpected()
+		}
+	}
+	if len(doc.operations) == 0 {
+		return nil, fmt.Errorf("no operation in the request")
+	}
+	return doc, nil
+}
+
+func (p *parser) peek() tok { return p.toks[p.pos] }
+
+func (p *parser) next() tok {
+	t := p.toks[p.pos]
+	if t.kind != tEOF {
+		p.pos++
+	}
+	return t
+}
+
+// punct consumes the punctuator s if it comes next.
+func (p *parser) punct(s string) bool {
+	if t := p.peek(); t.kind == tPunct && t.text == s {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(s string) error {
+	if !p.punct(s) {
+		return p.unexpected()
+	}
+	return nil
+}
+
+func (p *parser) name() (string, error) {
+	if t := p.peek(); t.kind == tName {
+		p.pos++
+		return t.text, nil
+	}
+	return "", p.unexpected()
+}
+
+func (p *parser) unexpected() error {
+	t := p.peek()
+	if t.kind == tEOF {
+		return fmt.Errorf("line %d: unexpected end of request", t.line)
+	}
+	return fmt.Errorf("line %d: unexpected %q", t.line, t.text)
+}
+
+func (p *parser) operation() (*operation, error) {
+	op := &operation{kind: p.next().text}
+	if p.peek().kind == tName {
+		op.name = p.next().text
+	}
+	if p.punct("(") {
+		for !p.punct(")") {
+			if err := p.expect("$"); err != nil {
+				return nil, err
+			}
+			name, err := p.name()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(":"); err != nil {
+				return nil, err
+			}
+			typ, err := p.typeRef()
+			if err != nil {
+				return nil, err
+			}
+			v := varDef{name: name, typ: typ}
+			if p.punct("=") {
+				if v.def, err = p.value(true); err != nil {
+					return nil, err
+				}
+			}
+			op.vars = append(op.vars, v)
+		}
+	}
+	if _, err := p.directives(); err != nil {
+		return nil, err
+	}
+	var err error
+	op.sel, err = p.selectionSet()
+	return op, err
+}
+
+// typeRef parses a type such as [String!]!, returning it as written.
+func (p *parser) typeRef() (string, error) {
+	var typ string
+	if p.punct("[") {
+		inner, err := p.typeRef()
+		if err != nil {
+			return "", err
+		}
+		if err := p.expect("]"); err != nil {
+			return "", err
+		}
+		typ = "[" + inner + "]"
+	} else {
+		name, err := p.name()
+		if err != nil {
+			return "", err
+		}
+		typ = name
+	}
+	if p.punct("!") {
+		typ += "!"
+	}
+	return typ, nil
+}
+
+func (p *parser) fragment() (*fragment, error) {
+	p.next()
+	name, err := p.name()
+	if err != nil {
+		return nil, err
+	}
+	if on, _ := p.name(); on != "on" {
+		return nil, fmt.Errorf("fragment %s: want \"on\" and a type", name)
+	}
+	f := &fragment{name: name}
+	if f.on, err = p.name(); err != nil {
+		return nil, err
+	}
+	if _, err := p.directives(); err != nil {
+		return nil, err
+	}
+	f.sel, err = p.selectionSet()
+	return f, err
+}
+
+func (p *parser) selectionSet() ([]selection, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	var sel []selection
+	for !p.punct("}") {
+		s, err := p.selection()
+		if err != nil {
+			return nil, err
+		}
+		sel = append(sel, s)
+	}
+	if len(sel) == 0 {
+		return nil, fmt.Errorf("line %d: empty selection", p.peek().line)
+	}
+	return sel, nil
+}
+
+func (p *parser) selection() (selection, error) {
+	if p.punct("...") {
+		if t := p.peek(); t.kind == tName && t.text != "on" {
+			p.next()
+			d, err := p.directives()
+			return &spread{name: t.text, directives: d}, err
+		}
+		in := &inline{}
+		if t := p.peek(); t.kind == tName && t.text == "on" {
+			p.next()
+			var err error
+			if in.on, err = p.name(); err != nil {
+				return nil, err
+			}
+		}
+		var err error
+		if in.directives, err = p.directives(); err != nil {
+			return nil, err
+		}
+		in.sel, err = p.selectionSet()
+		return in, err
+	}
+	f := &field{line: p.peek().line}
+	name, err := p.name()
+	if err != nil {
+		return nil, err
+	}
+	if p.punct(":") {
+		f.alias = name
+		if name, err = p.name(); err != nil {
+			return nil, err
+		}
+	}
+	f.name = name
+	if f.args, err = p.arguments(false); err != nil {
+		return nil, err
+	}
+	if f.directives, err = p.directives(); err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind == tPunct && t.text == "{" {
+		if f.sel, err = p.selectionSet(); err != nil {
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+func (p *parser) arguments(constant bool) ([]argument, error) {
+	if !p.punct("(") {
+		return nil, nil
+	}
+	var args []argument
+	for !p.punct(")") {
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+		v, err := p.value(constant)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, argument{name, v})
+	}
+	return args, nil
+}
+
+func (p *parser) directives() ([]directive, error) {
+	var ds []directive
+	for p.punct("@") {
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		args, err := p.arguments(false)
+		if err != nil {
+			return nil, err
+		}
+		ds = append(ds, directive{name, args})
+	}
+	return ds, nil
+}
+
+// value parses a value; constant ones may not hold variables.
+func (p *parser) value(constant bool) (value, error) {
+	start := p.pos
+	t := p.next()
+	switch t.kind {
+	case tInt:
+		n, err := strconv.ParseInt(t.text, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid number %s", t.line, t.text)
+		}
+		return n, nil
+	case tFloat:
+		f, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid number %s", t.line, t.text)
+		}
+		return f, nil
+	case tString:
+		return t.text, nil
+	case tName:
+		switch t.text {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		case "null":
+			return nil, nil
+		}
+		return enum(t.text), nil
+	case tPunct:
+		switch t.text {
+		case "$":
+			if constant {
+				break
+			}
+			name, err := p.name()
+			return variable(name), err
+		case "[":
+			list := []value{}
+			for !p.punct("]") {
+				v, err := p.value(constant)
+				if err != nil {
+					return nil, err
+				}
+				list = append(list, v)
+			}
+			return list, nil
+		case "{":
+			obj := objectValue{}
+			for !p.punct("}") {
+				name, err := p.name()
+				if err != nil {
+					return nil, err
+				}
+				if err := p.expect(":"); err != nil {
+					return nil, err
+				}
+				v, err := p.value(constant)
+				if err != nil {
+					return nil, err
+				}
+				obj = append(obj, argument{name, v})
+			}
+			return obj, nil
+		}
+	}
+	p.pos = start
+	return nil, p.unexpected()
+}
@@ -0,0 +1,199 @@
+// Package graphql executes GraphQL queries against a schema of object
+// types whose fields are resolved by Go functions.
+//
+// It covers the query language as clients use it to read data: operations
+// with variables, aliases, arguments, named and inline fragments, @include
+// and @skip, and __typename. Mutations, subscriptions, interfaces, unions
+// and introspection are not supported; Schema.SDL describes the schema to
+// clients instead.
+package graphql
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Schema is a set of object types, one of which is the root of queries.
+type Schema struct {
+	// Query names the root type.
+	Query string
+	// Types are the object types, in the order SDL lists them.
+	Types []*Object
+}
+
+// Object is an object type.
+type Object struct {
+	Name   string
+	Doc    string
+	Fields []*Field
+}
+
+// Field is a field of an object type.
+type Field struct {
+	Name string
+	Doc  string
+	// Type is written as in SDL, such as "[Entry!]!", and may name the
+	// scalars String, Int, Float, Boolean and ID, or an object type.
+	Type string
+	Args []Arg
+	// Resolve returns the value of the field of parent, which is the value
+	// the parent field resolved to, or nil for the root. Objects are
+	// passed on to their own fields; lists are slices; scalars are Go
+	// values that encode to the matching JSON. Resolve may be nil for
+	// scalar fields of map[string]any parents, which are looked up by name.
+	Resolve func(ctx context.Context, parent any, args map[string]any) (any, error)
+}
+
+// Arg is an argument of a field. Arguments are passed to resolvers as
+// string, int, float64, bool or []any values, or nil when they are
+// nullable and missing without a default.
+type Arg struct {
+	Name    string
+	Type    string
+	Default any
+	Doc     string
+}
+
+func (s *Schema) object(name string) *Object {
+	for _, o := range s.Types {
+		if o.Name == name {
+			return o
+		}
+	}
+	return nil
+}
+
+func (o *Object) field(name string) *Field {
+	for _, f := range o.Fields {
+		if f.Name == name {
+			return f
+		}
+	}
+	return nil
+}
+
+// typeRef is a parsed SDL type.
+type typeRef struct {
+	name    string
+	list    *typeRef
+	nonNull bool
+}
+
+func parseType(s string) typeRef {
+	var t typeRef
+	if strings.HasSuffix(s, "!") {
+		t.nonNull, s = true, s[:len(s)-1]
+	}
+	if strings.HasPrefix(s, "[") && strings.HasSuffix(s, "]") {
+		inner := parseType(s[1 : len(s)-1])
+		t.list = &inner
+		return t
+	}
+	t.name = s
+	return t
+}
+
+func (t typeRef) String() string {
+	s := t.name
+	if t.list != nil {
+		s = "[" + t.list.String() + "]"
+	}
+	if t.nonNull {
+		s += "!"
+	}
+	return s
+}
+
+// named returns the type t is, or is a list of.
+func (t typeRef) named() string {
+	for t.list != nil {
+		t = *t.list
+	}
+	return t.name
+}
+
+var scalars = map[string]bool{"String": true, "Int": true, "Float": true, "Boolean": true, "ID": true}
+
+// Check reports types referred to but not defined, so that a mistake in a
+// schema shows when it is built rather than when a query reaches it.
+func (s *Schema) Check() error {
+	if s.object(s.Query) == nil {
+		return fmt.Errorf("graphql: no root type %s", s.Query)
+	}
+	for _, o := range s.Types {
+		for _, f := range o.Fields {
+			if n := parseType(f.Type).named(); !scalars[n] && s.object(n) == nil {
+				return fmt.Errorf("graphql: %s.%s: unknown type %s", o.Name, f.Name, n)
+			}
+			for _, a := range f.Args {
+				if n := parseType(a.Type).named(); !scalars[n] {
+					return fmt.Errorf("graphql: %s.%s(%s): arguments must be scalars or lists of them", o.Name, f.Name, a.Name)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// SDL returns the schema in the GraphQL schema definition language.
+func (s *Schema) SDL() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "schema {\n  query: %s\n}\n", s.Query)
+	for _, o := range s.Types {
+		b.WriteString("\n")
+		doc(&b, "", o.Doc)
+		fmt.Fprintf(&b, "type %s {\n", o.Name)
+		for _, f := range o.Fields {
+			doc(&b, "  ", f.Doc)
+			fmt.Fprintf(&b, "  %s", f.Name)
+			if len(f.Args) > 0 {
+				// Arguments go on lines of their own when any is documented.
+				long := slices.ContainsFunc(f.Args, func(a Arg) bool { return a.Doc != "" })
+				b.WriteString("(")
+				for i, a := range f.Args {
+					switch {
+					case long:
+						b.WriteString("\n")
+						doc(&b, "    ", a.Doc)
+						b.WriteString("    ")
+					case i > 0:
+						b.WriteString(", ")
+					}
+					fmt.Fprintf(&b, "%s: %s", a.Name, a.Type)
+					if a.Default != nil {
+						fmt.Fprintf(&b, " = %s", literal(a.Default))
+					}
+				}
+				if long {
+					b.WriteString("\n  ")
+				}
+				b.WriteString(")")
+			}
+			fmt.Fprintf(&b, ": %s\n", f.Type)
+		}
+		b.WriteString("}\n")
+	}
+	return b.String()
+}
+
+func doc(b *strings.Builder, indent, s string) {
+	if s != "" {
+		fmt.Fprintf(b, "%s%q\n", indent, s)
+	}
+}
+
+func literal(v any) string {
+	switch v := v.(type) {
+	case string:
+		return fmt.Sprintf("%q", v)
+	case []any:
+		parts := make([]string, len(v))
+		for i, x := range v {
+			parts[i] = literal(x)
+		}
+		return "[" + strings.Join(parts, ", ") + "]"
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,151 @@
+// Package grep finds the lines of entries matching a regular expression,
+// optionally only inside or outside their fenced code blocks.
+package grep
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+// Regions of an entry a search may be restricted to.
+const (
+	All   = "all"
+	Code  = "code"
+	Prose = "prose"
+)
+
+// Options configures a search.
+type Options struct {
+	// In is the region searched: All, the default, for the whole file
+	// including frontmatter, Code for the lines inside fenced code blocks,
+	// or Prose for the other lines of the body.
+	In string
+	// Langs restricts code to the blocks of these fence languages.
+	Langs []string
+	// Before and After are the lines of context kept around each match.
+	Before, After int
+}
+
+// Check reports an unknown region.
+func (o Options) Check() error {
+	switch o.In {
+	case "", All, Code, Prose:
+		return nil
+	}
+	return fmt.Errorf("unknown region %q; want %s, %s or %s", o.In, All, Code, Prose)
+}
+
+// Line is a line of an entry file: a match, or context around matches.
+type Line struct {
+	Path string `json:"path"`
+	// Line is the 1-based line within the file.
+	Line int    `json:"line"`
+	Text string `json:"text"`
+	// Match is false for context lines.
+	Match bool `json:"match"`
+	// Ranges are the byte offsets [start, end) of the matches in Text.
+	Ranges [][]int `json:"ranges,omitempty"`
+	// Lang is the language of the code block the line is in, if any.
+	Lang string `json:"lang,omitempty"`
+}
+
+// region is the part of the file a line belongs to.
+type region struct {
+	front, code bool
+	lang        string
+	// fence marks the opening and closing lines of code blocks.
+	fence bool
+}
+
+// Entry returns the lines of e matching re in the region opts asks for,
+// with their context, in file order. Context lines are taken from the
+// whole file, whatever the region.
+func Entry(e *entry.Entry, re *regexp.Regexp, opts Options) []Line {
+	lines, regions := fileLines(e)
+	var want []bool
+	for i, text := range lines {
+		if opts.searches(regions[i]) && re.MatchString(text) {
+			if want == nil {
+				want = make([]bool, len(lines))
+			}
+			want[i] = true
+		}
+	}
+	if want == nil {
+		return nil
+	}
+	var out []Line
+	last := -1
+	for i := range lines {
+		if !want[i] {
+			continue
+		}
+		for j := max(i-opts.Before, last+1); j < i; j++ {
+			out = append(out, line(e, lines, regions, j, nil))
+		}
+		if i > last {
+			out = append(out, line(e, lines, regions, i, re))
+			last = i
+		}
+		for j := i + 1; j <= i+opts.After && j < len(lines) && !want[j]; j++ {
+			out = append(out, line(e, lines, regions, j, nil))
+			last = j
+		}
+	}
+	return out
+}
+
+func line(e *entry.Entry, lines []string, regions []region, i int, re *regexp.Regexp) Line {
+	l := Line{Path: e.Path, Line: i + 1, Text: lines[i], Lang: regions[i].lang}
+	if re != nil {
+		l.Match = true
+		l.Ranges = re.FindAllStringIndex(lines[i], -1)
+	}
+	return l
+}
+
+func (o Options) searches(r region) bool {
+	switch o.In {
+	case Code:
+		return r.code && !r.fence && (len(o.Langs) == 0 || slices.ContainsFunc(o.Langs, func(l string) bool { return strings.EqualFold(l, r.lang) }))
+	case Prose:
+		return !r.code && !r.front
+	}
+	return true
+}
+
+// fileLines returns the lines of e's file, frontmatter included, and the
+// region of each.
+func fileLines(e *entry.Entry) ([]string, []region) {
+	var lines []string
+	if e.BodyLine > 1 {
+		fm := strings.Split(strings.TrimSuffix(string(e.Front), "\n"), "\n")
+		// The frontmatter fences enclose it, and anything else before the
+		// body is blank.
+		lines = append(lines, "---")
+		lines = append(lines, fm...)
+		lines = append(lines, "---")
+		for len(lines) < e.BodyLine-1 {
+			lines = append(lines, "")
+		}
+		lines = lines[:e.BodyLine-1]
+	}
+	front := len(lines)
+	lines = append(lines, strings.Split(strings.TrimSuffix(string(e.Body), "\n"), "\n")...)
+	regions := make([]region, len(lines))
+	for i := range front {
+		regions[i].front = true
+	}
+	for _, b := range entry.CodeBlocks(e.Body) {
+		for n := b.Line; n <= b.EndLine; n++ {
+			if i := front + n - 1; i < len(regions) {
+				regions[i] = region{code: true, lang: b.Lang, fence: n == b.Line || n == b.EndLine}
+			}
+		}
+	}
+	return lines, regions
+}
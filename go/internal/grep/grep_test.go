@@ -0,0 +1,94 @@
+package grep
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"testing"
+
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+const copyEntry = "---\ntitle: Copy slices\ntags: [go]\n---\n\n# Copy slices\n\nUse copy to copy a slice.\n\n```go\nn := copy(dst, src)\n```\n\n```sh\ncopy a b\n```\n\nThe end.\n"
+
+func parse(t *testing.T, p, data string) *entry.Entry {
+	t.Helper()
+	e, err := entry.Parse(p, []byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+// short formats lines as grep prints them, with the language of code.
+func short(ls []Line) []string {
+	var out []string
+	for _, l := range ls {
+		sep := "-"
+		if l.Match {
+			sep = ":"
+		}
+		s := fmt.Sprintf("%d%s%s", l.Line, sep, l.Text)
+		if l.Lang != "" {
+			s += " [" + l.Lang + "]"
+		}
+		out = append(out, s)
+	}
+	return out
+}
+
+func TestEntry(t *testing.T) {
+	e := parse(t, "go/slices.md", copyEntry)
+	re := regexp.MustCompile(`(?i)copy`)
+	tests := []struct {
+		name string
+		opts Options
+		want []string
+	}{
+		{"all", Options{}, []string{"2:title: Copy slices", "6:# Copy slices", "8:Use copy to copy a slice.", "11:n := copy(dst, src) [go]", "15:copy a b [sh]"}},
+		{"code", Options{In: Code}, []string{"11:n := copy(dst, src) [go]", "15:copy a b [sh]"}},
+		{"code in go", Options{In: Code, Langs: []string{"GO"}}, []string{"11:n := copy(dst, src) [go]"}},
+		{"prose", Options{In: Prose}, []string{"6:# Copy slices", "8:Use copy to copy a slice."}},
+		{"context", Options{In: Code, Langs: []string{"sh"}, Before: 1, After: 2}, []string{"14-```sh [sh]", "15:copy a b [sh]", "16-``` [sh]", "17-"}},
+		// Context is cut short by the next match and never repeated.
+		{"overlapping context", Options{In: Prose, Before: 2, After: 3}, []string{"4----", "5-", "6:# Copy slices", "7-", "8:Use copy to copy a slice.", "9-", "10-```go [go]", "11-n := copy(dst, src) [go]"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := short(Entry(e, re, tt.opts)); !slices.Equal(got, tt.want) {
+				t.Errorf("Entry =\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+
+	ls := Entry(e, re, Options{In: Prose})
+	if want := [][]int{{4, 8}, {12, 16}}; ls[1].Path != "go/slices.md" || !slices.EqualFunc(ls[1].Ranges, want, slices.Equal) {
+		t.Errorf("line = %+v, want ranges %v", ls[1], want)
+	}
+	if got := Entry(e, regexp.MustCompile(`^tags:`), Options{In: Prose}); got != nil {
+		t.Errorf("frontmatter searched as prose: %v", got)
+	}
+	// Fence lines are not code.
+	if got := Entry(e, regexp.MustCompile("```"), Options{In: Code}); got != nil {
+		t.Errorf("fences searched as code: %v", got)
+	}
+}
+
+func TestEntryWithoutFrontmatter(t *testing.T) {
+	e := parse(t, "git/rebase.md", "# Rebase\n\n    git rebase --onto main\n\nRebase onto main.\n")
+	got := short(Entry(e, regexp.MustCompile(`onto`), Options{}))
+	if want := []string{"3:    git rebase --onto main", "5:Rebase onto main."}; !slices.Equal(got, want) {
+		t.Errorf("Entry = %q, want %q", got, want)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	for _, in := range []string{"", All, Code, Prose} {
+		if err := (Options{In: in}).Check(); err != nil {
+			t.Errorf("Check(%q) = %v", in, err)
+		}
+	}
+	if err := (Options{In: "front"}).Check(); err == nil {
+		t.Error("Check(front) succeeded")
+	}
+}
@@ -0,0 +1,197 @@
+// Package heatmap lays out entry creation dates as a GitHub-style
+// contribution calendar: one column per week, one row per weekday, each
+// day shaded by how many entries were created on it.
+package heatmap
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+// Levels is the number of shades, including the one for empty days.
+const Levels = 5
+
+// Day is one cell of the calendar.
+type Day struct {
+	Date  time.Time
+	Count int
+	// Level is the shade, from 0 (no entries) to Levels-1.
+	Level int
+	// Outside marks the padding days before From and after To that
+	// complete the first and last weeks.
+	Outside bool
+}
+
+// Map is a calendar of daily entry counts.
+type Map struct {
+	From, To time.Time
+	// Weeks are columns of seven days starting on Sunday.
+	Weeks [][7]Day
+	Total int
+	Max   int
+}
+
+// New builds the calendar for the days from..to inclusive.
+func New(entries []*entry.Entry, from, to time.Time) *Map {
+	from, to = dayOf(from), dayOf(to)
+	counts := map[time.Time]int{}
+	m := &Map{From: from, To: to}
+	for _, e := range entries {
+		d := e.Created()
+		if d.IsZero() {
+			continue
+		}
+		d = dayOf(d)
+		if d.Before(from) || d.After(to) {
+			continue
+		}
+		counts[d]++
+		m.Total++
+		m.Max = max(m.Max, counts[d])
+	}
+	start := from.AddDate(0, 0, -int(from.Weekday()))
+	for d := start; !d.After(to); {
+		var week [7]Day
+		for i := range week {
+			n := counts[d]
+			week[i] = Day{Date: d, Count: n, Level: level(n, m.Max), Outside: d.Before(from) || d.After(to)}
+			d = d.AddDate(0, 0, 1)
+		}
+		m.Weeks = append(m.Weeks, week)
+	}
+	return m
+}
+
+// LastYear builds the calendar for the 52 weeks ending at now.
+func LastYear(entries []*entry.Entry, now time.Time) *Map {
+	return New(entries, now.AddDate(0, 0, -364), now)
+}
+
+// Year builds the calendar for one calendar year.
+func Year(entries []*entry.Entry, year int) *Map {
+	return New(entries, time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(year, 12, 31, 0, 0, 0, 0, time.UTC))
+}
+
+func dayOf(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// level spreads counts over the shades relative to the busiest day.
+func level(n, peak int) int {
+	if n == 0 || peak == 0 {
+		return 0
+	}
+	return (n*(Levels-1) + peak - 1) / peak
+}
+
+var weekdays = [7]string{"", "Mon", "", "Wed", "", "Fri", ""}
+
+// termColors are 256-colour palette indexes for the shades.
+var termColors = [Levels]int{236, 22, 28, 34, 40}
+
+// WriteTerminal draws the calendar with coloured cells, or with shade
+// characters when color is false.
+func (m *Map) WriteTerminal(w io.Writer, color bool) error {
+	var b strings.Builder
+	b.WriteString("    ")
+	b.WriteString(m.monthRow(2))
+	b.WriteByte('\n')
+	for row := 0; row < 7; row++ {
+		fmt.Fprintf(&b, "%-4s", weekdays[row])
+		for _, week := range m.Weeks {
+			d := week[row]
+			switch {
+			case d.Outside:
+				b.WriteString("  ")
+			case color:
+				fmt.Fprintf(&b, "\x1b[38;5;%dm■\x1b[0m ", termColors[d.Level])
+			default:
+				b.WriteString(shades[d.Level] + " ")
+			}
+		}
+		b.WriteByte('\n')
+	}
+	fmt.Fprintf(&b, "%d entries from %s to %s\n", m.Total, m.From.Format(entry.DateLayout), m.To.Format(entry.DateLayout))
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+var shades = [Levels]string{"·", "░", "▒", "▓", "█"}
+
+// monthRow labels the weeks in which a month starts, with cells of the
+// given width.
+func (m *Map) monthRow(cell int) string {
+	row := []byte(strings.Repeat(" ", len(m.Weeks)*cell+3))
+	for i, week := range m.Weeks {
+		if mo, ok := monthStart(week); ok {
+			copy(row[i*cell:], mo.String()[:3])
+		}
+	}
+	return strings.TrimRight(string(row), " ")
+}
+
+// monthStart reports the month whose first day falls in week.
+func monthStart(week [7]Day) (time.Month, bool) {
+	for _, d := range week {
+		if d.Date.Day() == 1 && !d.Outside {
+			return d.Date.Month(), true
+		}
+	}
+	return 0, false
+}
+
+// svgColors are the fill colours of the shades.
+var svgColors = [Levels]string{"#ebedf0", "#9be9a8", "#40c463", "#30a14e", "#216e39"}
+
+const (
+	cellSize = 11
+	cellGap  = 3
+	leftPad  = 28
+	topPad   = 16
+)
+
+// SVG renders the calendar as a standalone SVG image. Each cell carries a
+// title with its date and count, shown as a tooltip by browsers.
+func (m *Map) SVG() []byte {
+	step := cellSize + cellGap
+	width := leftPad + len(m.Weeks)*step
+	height := topPad + 7*step
+	var b bytes.Buffer
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="system-ui, sans-serif" font-size="9" fill="#666">`+"\n", width, height, width, height)
+	fmt.Fprintf(&b, "<title>%d entries from %s to %s</title>\n", m.Total, m.From.Format(entry.DateLayout), m.To.Format(entry.DateLayout))
+	for i, week := range m.Weeks {
+		if mo, ok := monthStart(week); ok {
+			fmt.Fprintf(&b, `<text x="%d" y="%d">%s</text>`+"\n", leftPad+i*step, topPad-5, mo.String()[:3])
+		}
+	}
+	for row, name := range weekdays {
+		if name != "" {
+			fmt.Fprintf(&b, `<text x="0" y="%d">%s</text>`+"\n", topPad+row*step+cellSize-2, name)
+		}
+	}
+	for i, week := range m.Weeks {
+		for row, d := range week {
+			if d.Outside {
+				continue
+			}
+			label := fmt.Sprintf("%s: %d %s", d.Date.Format(entry.DateLayout), d.Count, plural(d.Count))
+			fmt.Fprintf(&b, `<rect x="%d" y="%d" width="%d" height="%d" rx="2" fill="%s"><title>%s</title></rect>`+"\n",
+				leftPad+i*step, topPad+row*step, cellSize, cellSize, svgColors[d.Level], html.EscapeString(label))
+		}
+	}
+	b.WriteString("</svg>\n")
+	return b.Bytes()
+}
+
+func plural(n int) string {
+	if n == 1 {
+		return "entry"
+	}
+	return "entries"
+}
@@ -0,0 +1,120 @@
+package heatmap
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+func dated(t *testing.T, dates ...string) []*entry.Entry {
+	t.Helper()
+	var out []*entry.Entry
+	for _, d := range dates {
+		e, err := entry.Parse("go/"+d+".md", []byte("---\ndate: "+d+"\n---\n"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		out = append(out, e)
+	}
+	return out
+}
+
+func date(s string) time.Time {
+	t, err := time.Parse(time.DateOnly, s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestNew(t *testing.T) {
+	entries := dated(t, "2024-06-05", "2024-06-05", "2024-06-10", "2024-06-01", "2024-06-20")
+	entries = append(entries, &entry.Entry{Path: "go/undated.md"})
+	// Wednesday to Tuesday: the grid runs from Sunday to Saturday.
+	m := New(entries, date("2024-06-05"), date("2024-06-18").Add(20*time.Hour))
+	if m.Total != 3 || m.Max != 2 || len(m.Weeks) != 3 {
+		t.Fatalf("total %d, max %d, %d weeks", m.Total, m.Max, len(m.Weeks))
+	}
+	if first := m.Weeks[0][0]; !first.Date.Equal(date("2024-06-02")) || !first.Outside {
+		t.Errorf("first cell = %+v", first)
+	}
+	if last := m.Weeks[2][6]; !last.Date.Equal(date("2024-06-22")) || !last.Outside {
+		t.Errorf("last cell = %+v", last)
+	}
+	for _, tt := range []struct {
+		week, day    int
+		count, level int
+	}{
+		{0, 3, 2, 4},
+		{1, 1, 1, 2},
+		{1, 2, 0, 0},
+	} {
+		d := m.Weeks[tt.week][tt.day]
+		if d.Count != tt.count || d.Level != tt.level || d.Outside {
+			t.Errorf("%s = %+v, want count %d, level %d", d.Date.Format(time.DateOnly), d, tt.count, tt.level)
+		}
+	}
+}
+
+func TestLevel(t *testing.T) {
+	for _, tt := range []struct{ n, peak, want int }{
+		{0, 0, 0}, {0, 5, 0}, {1, 1, 4}, {1, 8, 1}, {2, 8, 1}, {3, 8, 2}, {5, 8, 3}, {7, 8, 4}, {8, 8, 4},
+	} {
+		if got := level(tt.n, tt.peak); got != tt.want {
+			t.Errorf("level(%d, %d) = %d, want %d", tt.n, tt.peak, got, tt.want)
+		}
+	}
+}
+
+func TestWriteTerminal(t *testing.T) {
+	m := New(dated(t, "2024-06-05", "2024-06-05", "2024-06-10"), date("2024-05-29"), date("2024-06-11"))
+	var b bytes.Buffer
+	if err := m.WriteTerminal(&b, false); err != nil {
+		t.Fatal(err)
+	}
+	// June starts on the Saturday of the first week.
+	want := "    Jun\n" +
+		"      · · \n" +
+		"Mon   · ▒ \n" +
+		"      · · \n" +
+		"Wed · █   \n" +
+		"    · ·   \n" +
+		"Fri · ·   \n" +
+		"    · ·   \n" +
+		"3 entries from 2024-05-29 to 2024-06-11\n"
+	if got := b.String(); got != want {
+		t.Errorf("WriteTerminal =\n%s\nwant\n%s", got, want)
+	}
+	b.Reset()
+	if err := m.WriteTerminal(&b, true); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(b.String(), "\x1b[38;5;40m■\x1b[0m") {
+		t.Errorf("no colour for the busiest day:\n%q", b.String())
+	}
+}
+
+func TestSVG(t *testing.T) {
+	svg := string(Year(dated(t, "2024-06-05", "2024-06-05", "2024-06-10"), 2024).SVG())
+	if n := strings.Count(svg, "<rect "); n != 366 {
+		t.Errorf("%d cells, want one per day of 2024", n)
+	}
+	for _, want := range []string{
+		`<svg xmlns="http://www.w3.org/2000/svg" width="770" height="114"`,
+		"<title>3 entries from 2024-01-01 to 2024-12-31</title>",
+		`fill="#216e39"><title>2024-06-05: 2 entries</title>`,
+		`fill="#40c463"><title>2024-06-10: 1 entry</title>`,
+		`fill="#ebedf0"><title>2024-06-11: 0 entries</title>`,
+		">Jan</text>", ">Dec</text>", ">Wed</text>",
+	} {
+		if !strings.Contains(svg, want) {
+			t.Errorf("SVG lacks %s", want)
+		}
+	}
+	if !strings.HasSuffix(svg, "</svg>\n") {
+		t.Error("SVG is not closed")
+	}
+}
@@ -0,0 +1,131 @@
+// Package hooks runs the hooks of the config file on lifecycle events:
+// commands, given the event's payload as JSON on stdin, and Go plugins.
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/canhta/til/go/internal/config"
+	"github.com/canhta/til/go/pkg/hook"
+)
+
+// DefaultTimeout bounds a hook command unless configured otherwise.
+const DefaultTimeout = time.Minute
+
+// Runner runs the hooks of a notes tree.
+type Runner struct {
+	// Root is the notes root, the working directory of commands.
+	Root     string
+	Commands map[hook.Event][][]string
+	Plugins  []hook.Plugin
+	Timeout  time.Duration
+	// Stderr receives what commands print, the content printed by
+	// pre-save commands excepted.
+	Stderr io.Writer
+}
+
+// New returns the runner of the hooks configured in cfg, loading its
+// plugins.
+func New(root string, cfg config.Hooks, stderr io.Writer) (*Runner, error) {
+	r := &Runner{
+		Root: root,
+		Commands: map[hook.Event][][]string{
+			hook.PreSave:     cfg.PreSave,
+			hook.PostSave:    cfg.PostSave,
+			hook.PreBuild:    cfg.PreBuild,
+			hook.PostPublish: cfg.PostPublish,
+		},
+		Timeout: cfg.Timeout.Duration,
+		Stderr:  stderr,
+	}
+	if r.Timeout <= 0 {
+		r.Timeout = DefaultTimeout
+	}
+	for _, file := range cfg.Plugins {
+		p, err := Load(file)
+		if err != nil {
+			return nil, err
+		}
+		r.Plugins = append(r.Plugins, p)
+	}
+	return r, nil
+}
+
+// Empty reports whether r runs nothing.
+func (r *Runner) Empty() bool {
+	if r == nil {
+		return true
+	}
+	for _, cmds := range r.Commands {
+		if len(cmds) > 0 {
+			return false
+		}
+	}
+	return len(r.Plugins) == 0
+}
+
+// Run runs the plugins and then the commands of p.Event, stopping at the
+// first failure. Pre-save hooks may change p.Content. A nil runner runs
+// nothing.
+func (r *Runner) Run(ctx context.Context, p *hook.Payload) error {
+	if r == nil {
+		return nil
+	}
+	p.Root = r.Root
+	for _, pl := range r.Plugins {
+		if err := pl.Handle(ctx, p); err != nil {
+			return fmt.Errorf("%s plugin %s: %w", p.Event, pl.Name(), err)
+		}
+	}
+	for _, argv := range r.Commands[p.Event] {
+		if len(argv) == 0 {
+			continue
+		}
+		if err := r.command(ctx, argv, p); err != nil {
+			return fmt.Errorf("%s hook %s: %w", p.Event, filepath.Base(argv[0]), err)
+		}
+	}
+	return nil
+}
+
+// command runs one hook command.
+func (r *Runner) command(ctx context.Context, argv []string, p *hook.Payload) error {
+	payload, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, r.Timeout)
+	defer cancel()
+	args := argv[1:]
+	if p.Path != "" {
+		args = append(args[:len(args):len(args)], p.Path)
+	}
+	c := exec.CommandContext(ctx, argv[0], args...)
+	c.Dir = r.Root
+	c.Stdin = bytes.NewReader(payload)
+	c.Stderr = r.Stderr
+	var out bytes.Buffer
+	if p.Event == hook.PreSave {
+		c.Stdout = &out
+	} else {
+		c.Stdout = r.Stderr
+	}
+	if err := c.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return fmt.Errorf("timed out after %s", r.Timeout)
+		}
+		return err
+	}
+	if strings.TrimSpace(out.String()) != "" {
+		p.Content = out.String()
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/canhta/til/go/internal/config"
+	"github.com/canhta/til/go/pkg/hook"
+)
+
+// script writes an executable shell script to dir and returns its path.
+func script(t *testing.T, dir, name, body string) string {
+	t.Helper()
+	file := filepath.Join(dir, name)
+	if err := os.WriteFile(file, []byte("#!/bin/sh\n"+body), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+// recorder is a plugin recording the events it handles.
+type recorder struct {
+	events []hook.Event
+	err    error
+}
+
+func (r *recorder) Name() string { return "recorder" }
+
+func (r *recorder) Handle(ctx context.Context, p *hook.Payload) error {
+	r.events = append(r.events, p.Event)
+	if p.Event == hook.PreSave {
+		p.Content = strings.ToUpper(p.Content)
+	}
+	return r.err
+}
+
+func TestRun(t *testing.T) {
+	root := t.TempDir()
+	bin := t.TempDir()
+	// The payload and arguments are saved in the notes root, the working
+	// directory; stdout is the new content.
+	format := script(t, bin, "format", "cat > payload.json\necho \"$@\" > args\necho formatted\n")
+	logged := script(t, bin, "log", "echo post-save $1\necho to stderr >&2\n")
+	var stderr bytes.Buffer
+	r, err := New(root, config.Hooks{
+		PreSave:  [][]string{{format, "--in-place"}},
+		PostSave: [][]string{{logged}},
+	}, &stderr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pl := &recorder{}
+	r.Plugins = append(r.Plugins, pl)
+	if r.Empty() || r.Timeout != DefaultTimeout {
+		t.Errorf("runner = %+v", r)
+	}
+
+	p := &hook.Payload{Event: hook.PreSave, Path: "go/slices.md", Content: "# slices\n"}
+	if err := r.Run(context.Background(), p); err != nil {
+		t.Fatal(err)
+	}
+	if p.Content != "formatted\n" {
+		t.Errorf("content = %q, want the command's output", p.Content)
+	}
+	var got hook.Payload
+	data, _ := os.ReadFile(filepath.Join(root, "payload.json"))
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	want := hook.Payload{Event: hook.PreSave, Root: root, Path: "go/slices.md", Content: "# SLICES\n"}
+	if got != want {
+		t.Errorf("payload = %+v, want %+v, changed by the plugin first", got, want)
+	}
+	if args, _ := os.ReadFile(filepath.Join(root, "args")); string(args) != "--in-place go/slices.md\n" {
+		t.Errorf("args = %q", args)
+	}
+
+	if err := r.Run(context.Background(), &hook.Payload{Event: hook.PostSave, Path: "go/slices.md"}); err != nil {
+		t.Fatal(err)
+	}
+	if stderr.String() != "post-save go/slices.md\nto stderr\n" {
+		t.Errorf("stderr = %q", stderr.String())
+	}
+	if err := r.Run(context.Background(), &hook.Payload{Event: hook.PreBuild}); err != nil {
+		t.Fatal(err)
+	}
+	if want := []hook.Event{hook.PreSave, hook.PostSave, hook.PreBuild}; !slices.Equal(pl.events, want) {
+		t.Errorf("plugin handled %q, want %q", pl.events, want)
+	}
+
+	// A pre-save command printing nothing keeps the content.
+	r.Commands[hook.PreSave] = [][]string{{"true"}}
+	p = &hook.Payload{Event: hook.PreSave, Path: "go/maps.md", Content: "# maps\n"}
+	if err := r.Run(context.Background(), p); err != nil || p.Content != "# MAPS\n" {
+		t.Errorf("Run(silent pre-save) = %q, %v", p.Content, err)
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	bin := t.TempDir()
+	r, _ := New(t.TempDir(), config.Hooks{
+		PreSave:  [][]string{{script(t, bin, "fail", "exit 3\n")}, {script(t, bin, "never", "touch never\n")}},
+		PreBuild: [][]string{{script(t, bin, "slow", "exec sleep 5\n")}},
+	}, &bytes.Buffer{})
+	err := r.Run(context.Background(), &hook.Payload{Event: hook.PreSave, Path: "go/slices.md"})
+	if err == nil || err.Error() != "pre-save hook fail: exit status 3" {
+		t.Errorf("Run(failing) = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(r.Root, "never")); err == nil {
+		t.Error("commands ran after a failure")
+	}
+
+	r.Timeout = 50 * time.Millisecond
+	if err := r.Run(context.Background(), &hook.Payload{Event: hook.PreBuild}); err == nil || !strings.Contains(err.Error(), "pre-build hook slow: timed out after 50ms") {
+		t.Errorf("Run(slow) = %v", err)
+	}
+
+	failing := errors.New("no screenshot")
+	r.Plugins = []hook.Plugin{&recorder{err: failing}}
+	if err := r.Run(context.Background(), &hook.Payload{Event: hook.PostPublish}); !errors.Is(err, failing) || !strings.HasPrefix(err.Error(), "post-publish plugin recorder: ") {
+		t.Errorf("Run(failing plugin) = %v", err)
+	}
+
+	var nilRunner *Runner
+	if !nilRunner.Empty() || nilRunner.Run(context.Background(), &hook.Payload{}) != nil {
+		t.Error("a nil runner ran something")
+	}
+	if r, _ := New("", config.Hooks{}, nil); !r.Empty() {
+		t.Error("no hooks configured and not Empty")
+	}
+	if _, err := New("", config.Hooks{Plugins: []string{filepath.Join(bin, "missing.so")}}, nil); err == nil || !strings.Contains(err.Error(), "missing.so") {
+		t.Errorf("New with a missing plugin = %v", err)
+	}
+}
@@ -0,0 +1,36 @@
+package hooks
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"plugin"
+	"strings"
+
+	"github.com/canhta/til/go/pkg/hook"
+)
+
+// Load opens the Go plugin in file and returns the hook.Plugin its New
+// function makes. A leading ~ in file is the home directory.
+func Load(file string) (hook.Plugin, error) {
+	if rest, ok := strings.CutPrefix(file, "~/"); ok {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, err
+		}
+		file = filepath.Join(home, rest)
+	}
+	so, err := plugin.Open(file)
+	if err != nil {
+		return nil, fmt.Errorf("plugin %s: %w", file, err)
+	}
+	sym, err := so.Lookup("New")
+	if err != nil {
+		return nil, fmt.Errorf("plugin %s: %w", file, err)
+	}
+	newPlugin, ok := sym.(func() hook.Plugin)
+	if !ok {
+		return nil, fmt.Errorf("plugin %s: New is a %T, not a func() hook.Plugin", file, sym)
+	}
+	return newPlugin(), nil
+}
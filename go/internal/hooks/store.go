@@ -0,0 +1,97 @@
+package hooks
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/canhta/til/go/internal/store"
+	"github.com/canhta/til/go/pkg/hook"
+)
+
+// Wrap returns s with the save hooks of r run around each write of an
+// entry. Post-save failures are warned about on r.Stderr, as the entry is
+// saved by then.
+func Wrap(s store.Store, r *Runner) store.Store {
+	h := &hooked{Store: s, r: r}
+	if l, ok := s.(store.Local); ok {
+		return &hookedLocal{hooked: h, Local: l}
+	}
+	return h
+}
+
+type hooked struct {
+	store.Store
+	r *Runner
+}
+
+// hookedLocal keeps a local store local.
+type hookedLocal struct {
+	*hooked
+	store.Local
+}
+
+var (
+	_ store.Batcher = (*hooked)(nil)
+	_ store.Local   = (*hookedLocal)(nil)
+)
+
+// isEntry reports whether p is an entry file, as opposed to assets and
+// encrypted entries.
+func isEntry(p string) bool {
+	return strings.HasSuffix(p, ".md")
+}
+
+// Put implements store.Store.
+func (h *hooked) Put(ctx context.Context, p string, data []byte) error {
+	if !isEntry(p) {
+		return h.Store.Put(ctx, p, data)
+	}
+	data, err := h.preSave(ctx, p, data)
+	if err != nil {
+		return err
+	}
+	if err := h.Store.Put(ctx, p, data); err != nil {
+		return err
+	}
+	h.postSave(ctx, p, data)
+	return nil
+}
+
+// PutAll implements store.Batcher.
+func (h *hooked) PutAll(ctx context.Context, files map[string][]byte) error {
+	saved := make(map[string][]byte, len(files))
+	for p, data := range files {
+		if isEntry(p) {
+			var err error
+			if data, err = h.preSave(ctx, p, data); err != nil {
+				return err
+			}
+		}
+		saved[p] = data
+	}
+	if err := store.PutAll(ctx, h.Store, saved); err != nil {
+		return err
+	}
+	for p, data := range saved {
+		if isEntry(p) {
+			h.postSave(ctx, p, data)
+		}
+	}
+	return nil
+}
+
+func (h *hooked) preSave(ctx context.Context, p string, data []byte) ([]byte, error) {
+	pl := &hook.Payload{Event: hook.PreSave, Path: p, Content: string(data)}
+	if err := h.r.Run(ctx, pl); err != nil {
+		return nil, fmt.Errorf("%s: %w", p, err)
+	}
+	return []byte(pl.Content), nil
+}
+
+func (h *hooked) postSave(ctx context.Context, p string, data []byte) {
+	pl := &hook.Payload{Event: hook.PostSave, Path: p, Content: string(data)}
+	if err := h.r.Run(ctx, pl); err != nil {
+		fmt.Fprintf(h.r.Stderr, "warning: %s: %v\n", p, err)
+	}
+}
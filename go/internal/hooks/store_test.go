@@ -0,0 +1,63 @@
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/canhta/til/go/internal/config"
+	"github.com/canhta/til/go/internal/store"
+)
+
+func TestWrap(t *testing.T) {
+	root := t.TempDir()
+	bin := t.TempDir()
+	var stderr bytes.Buffer
+	r, err := New(root, config.Hooks{
+		PreSave:  [][]string{{script(t, bin, "upper", "cat >/dev/null\necho \"# formatted $1\"\n")}},
+		PostSave: [][]string{{script(t, bin, "saved", "echo $1 >> saved.log\nexit 1\n")}},
+	}, &stderr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := Wrap(&store.FS{Root: root}, r)
+	if _, ok := s.(store.Local); !ok {
+		t.Error("a wrapped local store is not Local")
+	}
+	ctx := context.Background()
+	if err := s.Put(ctx, "go/slices.md", []byte("# slices\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.PutAll(ctx, s, map[string][]byte{"go/maps.md": []byte("# maps\n"), "go/img.png": []byte("png")}); err != nil {
+		t.Fatal(err)
+	}
+	for p, want := range map[string]string{"go/slices.md": "# formatted go/slices.md\n", "go/maps.md": "# formatted go/maps.md\n", "go/img.png": "png"} {
+		if got, _, _ := s.Get(ctx, p); string(got) != want {
+			t.Errorf("%s = %q, want %q", p, got, want)
+		}
+	}
+	log, _ := os.ReadFile(filepath.Join(root, "saved.log"))
+	if string(log) != "go/slices.md\ngo/maps.md\n" {
+		t.Errorf("post-save ran for %q, want the entries only", log)
+	}
+	// Post-save failures are warnings, as the entry is saved by then.
+	if got := stderr.String(); strings.Count(got, "warning: ") != 2 || !strings.Contains(got, "warning: go/maps.md: post-save hook saved: exit status 1") {
+		t.Errorf("stderr = %q", got)
+	}
+
+	r.Commands["pre-save"] = [][]string{{"false"}}
+	if err := s.Put(ctx, "go/new.md", []byte("# new\n")); err == nil || !strings.HasPrefix(err.Error(), "go/new.md: pre-save hook false") {
+		t.Errorf("Put with a failing pre-save = %v", err)
+	}
+	if err := store.PutAll(ctx, s, map[string][]byte{"go/a.md": nil, "go/b.png": nil}); err == nil {
+		t.Error("PutAll with a failing pre-save succeeded")
+	}
+	for _, p := range []string{"go/new.md", "go/a.md", "go/b.png"} {
+		if ok, _ := store.Exists(ctx, s, p); ok {
+			t.Errorf("%s written despite the failing pre-save", p)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package importer
+
+import (
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// ReadingList is the tag of imported bookmarks and saved articles.
+const ReadingList = "reading-list"
+
+// BookmarksOptions configures Bookmarks.
+type BookmarksOptions struct {
+	// Category holds the bookmarks outside folders. Defaults to
+	// DefaultCategory.
+	Category string
+}
+
+// browserRoots are the top folders browsers export, which are not folders
+// the user made.
+var browserRoots = map[string]bool{
+	"bookmarks":         true,
+	"bookmarks bar":     true,
+	"bookmarks toolbar": true,
+	"bookmarks menu":    true,
+	"favorites bar":     true,
+	"other bookmarks":   true,
+	"mobile bookmarks":  true,
+	"other favorites":   true,
+}
+
+// Bookmarks converts a bookmarks file in the Netscape format browsers
+// export into stub entries tagged ReadingList, with the URL as source. A
+// bookmark's folder is its category and the folders over it are tags.
+// Bookmarks of pages other than web pages, and repeated ones, are skipped.
+func Bookmarks(r io.Reader, opts BookmarksOptions) ([]*Note, []Skipped, error) {
+	var (
+		out     []*Note
+		skipped []Skipped
+		folders []string
+		folder  string
+		cur     *Note
+		seen    = map[string]bool{}
+	)
+	// text collects the text of the element being read: a folder name, a
+	// bookmark title or its description.
+	var text *strings.Builder
+	var into *string
+	done := func() {
+		if text != nil {
+			*into = strings.Join(strings.Fields(text.String()), " ")
+			text, into = nil, nil
+		}
+	}
+	z := html.NewTokenizer(r)
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			if z.Err() == io.EOF {
+				done()
+				for _, n := range out {
+					finishBookmark(n)
+				}
+				return out, skipped, nil
+			}
+			return nil, nil, z.Err()
+		case html.TextToken:
+			if text != nil {
+				text.Write(z.Text())
+			}
+		case html.StartTagToken, html.EndTagToken:
+			name, hasAttr := z.TagName()
+			a := atom.Lookup(name)
+			if tt == html.EndTagToken {
+				switch a {
+				case atom.H3, atom.A:
+					done()
+				case atom.Dl:
+					done()
+					if len(folders) > 0 {
+						folders = folders[:len(folders)-1]
+					}
+				}
+				continue
+			}
+			done()
+			attrs := map[string]string{}
+			for more := hasAttr; more; {
+				var k, v []byte
+				k, v, more = z.TagAttr()
+				attrs[string(k)] = string(v)
+			}
+			switch a {
+			case atom.H3:
+				text, into = &strings.Builder{}, &folder
+			case atom.Dl:
+				folders = append(folders, folder)
+				folder = ""
+			case atom.A:
+				cur = nil
+				href := strings.TrimSpace(attrs["href"])
+				u, err := url.Parse(href)
+				switch {
+				case err != nil || u.Scheme != "http" && u.Scheme != "https" || u.Host == "":
+					skipped = append(skipped, Skipped{href, "not a web page"})
+					continue
+				case seen[href]:
+					skipped = append(skipped, Skipped{href, "repeated"})
+					continue
+				}
+				seen[href] = true
+				cur = &Note{URL: href, Tags: []string{ReadingList}}
+				cur.Category, cur.Tags = bookmarkCategory(folders, opts, cur.Tags)
+				cur.Tags = append(cur.Tags, strings.Split(attrs["tags"], ",")...)
+				cur.Date = unixDate(attrs["add_date"])
+				if m := unixDate(attrs["last_modified"]); m.After(cur.Date) {
+					cur.Updated = m
+				}
+				out = append(out, cur)
+				text, into = &strings.Builder{}, &cur.Title
+			case atom.Dd:
+				if cur != nil {
+					text, into = &strings.Builder{}, &cur.Body
+				}
+			case atom.Dt:
+				cur = nil
+			}
+		}
+	}
+}
+
+// finishBookmark completes a bookmark read with its description as body.
+func finishBookmark(n *Note) {
+	if n.Title == "" {
+		n.Title = n.URL
+	}
+	n.Tags = cleanTags(n.Tags)
+	n.Body = strings.TrimSpace(n.Body + "\n\nSource: <" + n.URL + ">")
+}
+
+// bookmarkCategory returns the category and tags of a bookmark in
+// folders, the innermost last.
+func bookmarkCategory(folders []string, opts BookmarksOptions, tags []string) (string, []string) {
+	var names []string
+	for i, f := range folders {
+		if f == "" || i <= 1 && browserRoots[strings.ToLower(f)] {
+			continue
+		}
+		names = append(names, f)
+	}
+	if len(names) == 0 {
+		if opts.Category != "" {
+			return opts.Category, tags
+		}
+		return DefaultCategory, tags
+	}
+	return Category(names[len(names)-1]), append(tags, names[:len(names)-1]...)
+}
+
+// unixDate reads a bookmark timestamp, in seconds since the epoch or, as
+// some browsers write them, milliseconds or microseconds.
+func unixDate(s string) time.Time {
+	n, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+	if err != nil || n <= 0 {
+		return time.Time{}
+	}
+	for n > 1e11 {
+		n /= 1000
+	}
+	return time.Unix(n, 0)
+}
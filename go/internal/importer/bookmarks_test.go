@@ -0,0 +1,76 @@
+package importer
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+const bookmarksHTML = `<!DOCTYPE NETSCAPE-Bookmark-file-1>
+<META HTTP-EQUIV="Content-Type" CONTENT="text/html; charset=UTF-8">
+<TITLE>Bookmarks</TITLE>
+<H1>Bookmarks</H1>
+<DL><p>
+    <DT><H3 PERSONAL_TOOLBAR_FOLDER="true">Bookmarks bar</H3>
+    <DL><p>
+        <DT><H3>Programming</H3>
+        <DL><p>
+            <DT><H3>Go Lang</H3>
+            <DL><p>
+                <DT><A HREF="https://go.dev/blog/slices" ADD_DATE="1709251200" LAST_MODIFIED="1709510400000" TAGS="Slices,go">Go   Slices</A>
+                <DD>How slices
+                work.
+                <DT><A HREF="https://go.dev/blog/slices">Again</A>
+            </DL><p>
+        </DL><p>
+        <DT><A HREF="javascript:alert(1)">Bookmarklet</A>
+        <DT><A HREF="https://example.com/" ADD_DATE="1709251200000000"></A>
+    </DL><p>
+    <DT><H3>Reading</H3>
+    <DL><p>
+        <DT><A HREF="https://example.com/essay">Essay</A>
+    </DL><p>
+</DL><p>
+`
+
+func TestBookmarks(t *testing.T) {
+	ns, skipped, err := Bookmarks(strings.NewReader(bookmarksHTML), BookmarksOptions{Category: "links"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, n := range ns {
+		got = append(got, n.Category+" "+n.Title+" "+n.URL+" "+strings.Join(n.Tags, ","))
+	}
+	want := []string{
+		"go-lang Go Slices https://go.dev/blog/slices reading-list,programming,slices,go",
+		"links https://example.com/ https://example.com/ reading-list",
+		"reading Essay https://example.com/essay reading-list",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("notes =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if want := []Skipped{{"https://go.dev/blog/slices", "repeated"}, {"javascript:alert(1)", "not a web page"}}; !slices.Equal(skipped, want) {
+		t.Errorf("skipped = %v, want %v", skipped, want)
+	}
+	n := ns[0]
+	if n.Body != "How slices work.\n\nSource: <https://go.dev/blog/slices>" {
+		t.Errorf("body = %q", n.Body)
+	}
+	if !n.Date.Equal(time.Unix(1709251200, 0)) || !n.Updated.Equal(time.Unix(1709510400, 0)) {
+		t.Errorf("dates = %v, %v", n.Date, n.Updated)
+	}
+	if !ns[1].Date.Equal(time.Unix(1709251200, 0)) || !ns[1].Updated.IsZero() {
+		t.Errorf("microsecond date = %v", ns[1].Date)
+	}
+
+	// Bookmarks already imported are found by their URL.
+	tree := newTree(t, map[string]string{"reading/essay.md": "---\ntitle: Essay\nsource: https://example.com/essay\n---\n"})
+	if err := Assign(tree, ns); err != nil {
+		t.Fatal(err)
+	}
+	if ns[2].Existing != "reading/essay.md" || ns[0].Path != "go-lang/go_slices.md" {
+		t.Errorf("Assign = %+v, %+v", ns[0], ns[2])
+	}
+}
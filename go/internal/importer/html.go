@@ -0,0 +1,398 @@
+package importer
+
+import (
+	"io"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+
+	"github.com/canhta/til/go/internal/capture"
+)
+
+// Markdown converts the HTML document read from r to markdown, as notes
+// exported as HTML are.
+func Markdown(r io.Reader) (string, error) {
+	doc, err := html.Parse(r)
+	if err != nil {
+		return "", err
+	}
+	return markdownOf(doc), nil
+}
+
+// markdownOf converts the HTML inside n to markdown. It covers what
+// note-taking applications export: headings, paragraphs, emphasis, links,
+// images, lists and to-do lists, quotes, code, tables and callouts.
+func markdownOf(n *html.Node) string {
+	return strings.Join(blocks(n), "\n\n")
+}
+
+// blocks converts the children of n into markdown blocks, gathering
+// inline content between them into paragraphs.
+func blocks(n *html.Node) []string {
+	var out []string
+	var para strings.Builder
+	flush := func() {
+		if p := strings.TrimSpace(para.String()); p != "" {
+			out = append(out, p)
+		}
+		para.Reset()
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.TextNode || c.Type == html.ElementNode && !isBlock(c) {
+			para.WriteString(inline(c))
+			continue
+		}
+		if c.Type != html.ElementNode {
+			continue
+		}
+		flush()
+		if b := block(c); strings.TrimSpace(b) != "" {
+			out = append(out, b)
+		}
+	}
+	flush()
+	return out
+}
+
+// blockElems are the elements converted as blocks.
+var blockElems = map[atom.Atom]bool{
+	atom.P: true, atom.Div: true, atom.H1: true, atom.H2: true, atom.H3: true, atom.H4: true,
+	atom.H5: true, atom.H6: true, atom.Ul: true, atom.Ol: true, atom.Pre: true, atom.Blockquote: true,
+	atom.Hr: true, atom.Table: true, atom.Figure: true, atom.Details: true, atom.Article: true,
+	atom.Section: true, atom.Header: true, atom.Main: true, atom.Body: true, atom.Html: true,
+	atom.Aside: true, atom.Head: true, atom.Script: true, atom.Style: true, atom.Nav: true,
+}
+
+func isBlock(n *html.Node) bool {
+	return blockElems[n.DataAtom]
+}
+
+// block converts the block element n.
+func block(n *html.Node) string {
+	switch n.DataAtom {
+	case atom.Head, atom.Script, atom.Style, atom.Nav:
+		return ""
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		level := int(n.Data[1] - '0')
+		return strings.Repeat("#", level) + " " + strings.TrimSpace(inline(n))
+	case atom.P:
+		return strings.TrimSpace(inlineChildren(n))
+	case atom.Hr:
+		return "---"
+	case atom.Pre:
+		lang := ""
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.DataAtom == atom.Code {
+				for _, cl := range strings.Fields(attr(c, "class")) {
+					if l, ok := strings.CutPrefix(cl, "language-"); ok {
+						lang = strings.ToLower(strings.ReplaceAll(l, " ", ""))
+					}
+				}
+			}
+		}
+		return capture.Fence(strings.TrimRight(rawText(n), "\n"), lang)
+	case atom.Ul, atom.Ol:
+		return list(n)
+	case atom.Blockquote:
+		return quote(markdownOf(n))
+	case atom.Table:
+		return table(n)
+	case atom.Details:
+		var out []string
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.DataAtom == atom.Summary {
+				if s := strings.TrimSpace(inlineChildren(c)); s != "" {
+					out = append(out, "**"+s+"**")
+				}
+				c.Parent.RemoveChild(c)
+				break
+			}
+		}
+		return strings.Join(append(out, blocks(n)...), "\n\n")
+	case atom.Figure, atom.Aside:
+		if hasClass(n, "callout") || n.DataAtom == atom.Aside {
+			return callout(n)
+		}
+		if hasClass(n, "equation") {
+			if tex := annotation(n); tex != "" {
+				return "$$\n" + tex + "\n$$"
+			}
+		}
+		if hasClass(n, "image") {
+			var img string
+			walkElems(n, func(c *html.Node) bool {
+				if c.DataAtom == atom.Img && img == "" {
+					img = inline(c)
+				}
+				return img == ""
+			})
+			return img
+		}
+	}
+	return markdownOf(n)
+}
+
+// callout converts a callout, typed by its icon.
+func callout(n *html.Node) string {
+	icon := ""
+	walkElems(n, func(c *html.Node) bool {
+		if hasClass(c, "icon") {
+			icon = strings.TrimSpace(rawText(c))
+			c.Parent.RemoveChild(c)
+			return false
+		}
+		return true
+	})
+	body := strings.TrimSpace(markdownOf(n))
+	if icon == "" {
+		icon, body = splitIcon(body)
+	}
+	return quote("[!" + iconCallout(icon) + "]\n" + body)
+}
+
+// iconCallouts maps callout icons to callout types; others are notes.
+var iconCallouts = map[string]string{
+	"💡": "TIP", "✅": "TIP", "👍": "TIP", "⚠️": "WARNING", "⚠": "WARNING", "❗": "IMPORTANT",
+	"‼️": "IMPORTANT", "📌": "IMPORTANT", "🚨": "CAUTION", "⛔": "CAUTION", "❌": "CAUTION",
+	"🛑": "CAUTION", "🔥": "CAUTION",
+}
+
+func iconCallout(icon string) string {
+	if t, ok := iconCallouts[icon]; ok {
+		return t
+	}
+	return "NOTE"
+}
+
+// splitIcon separates a leading emoji from text.
+func splitIcon(text string) (icon, rest string) {
+	first, after, _ := strings.Cut(text, " ")
+	for _, r := range first {
+		if r < 0x2000 {
+			return "", text
+		}
+	}
+	return first, strings.TrimSpace(after)
+}
+
+// annotation returns the TeX source a rendered equation keeps.
+func annotation(n *html.Node) string {
+	tex := ""
+	walkElems(n, func(c *html.Node) bool {
+		if c.DataAtom == atom.Annotation && tex == "" {
+			tex = strings.TrimSpace(rawText(c))
+		}
+		return tex == ""
+	})
+	return tex
+}
+
+// list converts a list, with to-do items as task list items.
+func list(n *html.Node) string {
+	var items []string
+	i := 1
+	if s, err := strconv.Atoi(attr(n, "start")); err == nil {
+		i = s
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.DataAtom != atom.Li {
+			continue
+		}
+		marker := "- "
+		if n.DataAtom == atom.Ol {
+			marker = strconv.Itoa(i) + ". "
+			i++
+		}
+		walkElems(c, func(d *html.Node) bool {
+			if hasClass(d, "checkbox") {
+				if hasClass(d, "checkbox-on") {
+					marker += "[x] "
+				} else {
+					marker += "[ ] "
+				}
+				d.Parent.RemoveChild(d)
+				return false
+			}
+			return d == c || !isBlock(d) || d.DataAtom == atom.Div
+		})
+		content := strings.Join(blocks(c), "\n")
+		pad := strings.Repeat(" ", len(marker))
+		if strings.HasSuffix(marker, "] ") {
+			pad = "  "
+		}
+		lines := strings.Split(strings.TrimSpace(content), "\n")
+		for j := 1; j < len(lines); j++ {
+			if lines[j] != "" {
+				lines[j] = pad + lines[j]
+			}
+		}
+		items = append(items, marker+strings.Join(lines, "\n"))
+	}
+	return strings.Join(items, "\n")
+}
+
+// table converts a table, its first row as the header.
+func table(n *html.Node) string {
+	var rows [][]string
+	walkElems(n, func(c *html.Node) bool {
+		if c.DataAtom != atom.Tr {
+			return true
+		}
+		var row []string
+		for d := c.FirstChild; d != nil; d = d.NextSibling {
+			if d.DataAtom == atom.Td || d.DataAtom == atom.Th {
+				cell := strings.Join(strings.Fields(inlineChildren(d)), " ")
+				row = append(row, strings.ReplaceAll(cell, "|", `\|`))
+			}
+		}
+		rows = append(rows, row)
+		return false
+	})
+	if len(rows) == 0 {
+		return ""
+	}
+	width := 0
+	for _, r := range rows {
+		width = max(width, len(r))
+	}
+	var b strings.Builder
+	for i, r := range rows {
+		for len(r) < width {
+			r = append(r, "")
+		}
+		b.WriteString("| " + strings.Join(r, " | ") + " |\n")
+		if i == 0 {
+			b.WriteString(strings.Repeat("| --- ", width) + "|\n")
+		}
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// quote prefixes the lines of text with "> ".
+func quote(text string) string {
+	lines := strings.Split(strings.TrimSpace(text), "\n")
+	for i, l := range lines {
+		lines[i] = strings.TrimRight("> "+l, " ")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// inline converts n as inline content.
+func inline(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return collapse(n.Data)
+	}
+	if n.Type != html.ElementNode {
+		return ""
+	}
+	switch n.DataAtom {
+	case atom.Br:
+		return "\\\n"
+	case atom.Img:
+		return "![" + attr(n, "alt") + "](" + attr(n, "src") + ")"
+	case atom.Code:
+		return "`" + rawText(n) + "`"
+	case atom.Script, atom.Style:
+		return ""
+	}
+	in := inlineChildren(n)
+	if strings.TrimSpace(in) == "" {
+		return in
+	}
+	wrap := func(mark string) string {
+		lead, trail := in[:len(in)-len(strings.TrimLeft(in, " "))], in[len(strings.TrimRight(in, " ")):]
+		return lead + mark + strings.TrimSpace(in) + mark + trail
+	}
+	switch n.DataAtom {
+	case atom.Strong, atom.B:
+		return wrap("**")
+	case atom.Em, atom.I:
+		return wrap("*")
+	case atom.Del, atom.S:
+		return wrap("~~")
+	case atom.A:
+		if href := attr(n, "href"); href != "" {
+			return "[" + strings.TrimSpace(in) + "](" + href + ")"
+		}
+	}
+	return in
+}
+
+func inlineChildren(n *html.Node) string {
+	var b strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		b.WriteString(inline(c))
+	}
+	return b.String()
+}
+
+// collapse collapses runs of whitespace in text to single spaces.
+func collapse(text string) string {
+	f := strings.Fields(text)
+	if len(f) == 0 {
+		if text != "" {
+			return " "
+		}
+		return ""
+	}
+	s := strings.Join(f, " ")
+	if strings.TrimLeft(text, " \t\r\n") != text {
+		s = " " + s
+	}
+	if strings.TrimRight(text, " \t\r\n") != text {
+		s += " "
+	}
+	return s
+}
+
+// walkElems calls visit for n and the elements inside it, descending into
+// those for which it returns true.
+func walkElems(n *html.Node, visit func(*html.Node) bool) {
+	if n.Type == html.ElementNode && !visit(n) {
+		return
+	}
+	for c := n.FirstChild; c != nil; {
+		next := c.NextSibling
+		walkElems(c, visit)
+		c = next
+	}
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func hasClass(n *html.Node, class string) bool {
+	for _, c := range strings.Fields(attr(n, "class")) {
+		if c == class {
+			return true
+		}
+	}
+	return false
+}
+
+// rawText returns the text inside n as it is.
+func rawText(n *html.Node) string {
+	var b strings.Builder
+	var rec func(*html.Node)
+	rec = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			b.WriteString(n.Data)
+		}
+		if n.DataAtom == atom.Br {
+			b.WriteByte('\n')
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			rec(c)
+		}
+	}
+	rec(n)
+	return b.String()
+}
@@ -0,0 +1,272 @@
+// Package importer converts notes from other applications into entries.
+//
+// A converter reads its source into Notes, which Assign places in the
+// tree and Write saves with their attachments. Every entry records the
+// note it came from in frontmatter, as imported, and bookmarks and saved
+// articles their URL as source, so running an import again skips what is
+// already there.
+package importer
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/canhta/til/go/internal/assets"
+	"github.com/canhta/til/go/internal/notes"
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+// Field is the frontmatter field recording the note an entry was imported
+// from; SourceField the one recording the URL a bookmark points to.
+const (
+	Field       = "imported"
+	SourceField = "source"
+)
+
+// DefaultCategory is the category of notes their source puts in none.
+const DefaultCategory = "inbox"
+
+// Note is a note converted from another application.
+type Note struct {
+	// Key identifies the note in its source across imports, such as
+	// "obsidian:Go/Slices.md".
+	Key      string
+	Title    string
+	Category string
+	Tags     []string
+	Date     time.Time
+	Updated  time.Time
+	// URL is the page a bookmark or saved article is about.
+	URL string
+	// Fields are further frontmatter fields, written in order.
+	Fields []MetaField
+	// Body is the markdown without a title heading.
+	Body   string
+	Assets []Asset
+
+	// Path is where the entry is written, set by Assign.
+	Path string
+	// Existing is the entry imported from the same note or URL before,
+	// set by Assign instead of Path.
+	Existing string
+
+	// relink, if set, returns the body with links to other notes of the
+	// source resolved, given the paths of their entries by Key.
+	relink func(pathOf func(key string) (string, bool)) string
+}
+
+// MetaField is a frontmatter field.
+type MetaField struct {
+	Key   string
+	Value any
+}
+
+// Asset is a file a note links to.
+type Asset struct {
+	// Ref is the link destination the body uses for the file.
+	Ref  string
+	Name string
+	Data []byte
+}
+
+// Skipped is a note of the source that was not converted.
+type Skipped struct {
+	Source string `json:"source"`
+	Reason string `json:"reason"`
+}
+
+// Result is the outcome of an import.
+type Result struct {
+	Created []string `json:"created"`
+	// Existing lists the entries previously imported from notes of the
+	// source.
+	Existing []string  `json:"existing"`
+	Skipped  []Skipped `json:"skipped"`
+	// Assets lists the attachments stored.
+	Assets []string `json:"assets"`
+}
+
+// Assign sets the Path of each note not imported before, deriving the
+// file name from the title and adding a numeric suffix to names taken in
+// the tree or by another note, or else its Existing entry.
+func Assign(tree *notes.Tree, ns []*Note) error {
+	entries, err := tree.Entries()
+	if err != nil {
+		return err
+	}
+	imported := map[string]string{}
+	taken := map[string]bool{}
+	for _, e := range entries {
+		taken[e.Path] = true
+		if e.Front == nil {
+			continue
+		}
+		f, err := entry.ParseFront(e.Front)
+		if err != nil {
+			continue
+		}
+		for _, k := range []string{Field, SourceField} {
+			var v string
+			if ok, err := f.Get(k, &v); ok && err == nil && v != "" {
+				imported[k+"\x00"+v] = e.Path
+			}
+		}
+	}
+	for _, n := range ns {
+		if p, ok := imported[Field+"\x00"+n.Key]; ok && n.Key != "" {
+			n.Existing = p
+			continue
+		}
+		if p, ok := imported[SourceField+"\x00"+n.URL]; ok && n.URL != "" {
+			n.Existing = p
+			continue
+		}
+		if n.Category == "" {
+			n.Category = DefaultCategory
+		}
+		base := entry.Slugify(n.Title)
+		if base == "" {
+			base = "note"
+		}
+		slug := base
+		for i := 2; taken[path.Join(n.Category, entry.FileName(slug))]; i++ {
+			slug = fmt.Sprintf("%s-%d", base, i)
+		}
+		n.Path = path.Join(n.Category, entry.FileName(slug))
+		taken[n.Path] = true
+		if n.Key != "" {
+			imported[Field+"\x00"+n.Key] = n.Path
+		}
+		if n.URL != "" {
+			imported[SourceField+"\x00"+n.URL] = n.Path
+		}
+	}
+	return nil
+}
+
+// Write saves the notes Assign gave a path, storing their assets next to
+// them, and records them in res. Nothing is written when dryRun is set.
+func Write(tree *notes.Tree, ns []*Note, res *Result, dryRun bool) error {
+	paths := map[string]string{}
+	for _, n := range ns {
+		if n.Key != "" {
+			paths[n.Key] = n.Path + n.Existing
+		}
+	}
+	pathOf := func(key string) (string, bool) {
+		p, ok := paths[key]
+		return p, ok && p != ""
+	}
+	for _, n := range ns {
+		if n.Existing != "" {
+			res.Existing = append(res.Existing, n.Existing)
+			continue
+		}
+		if n.Path == "" {
+			continue
+		}
+		body := n.Body
+		if n.relink != nil {
+			body = n.relink(pathOf)
+		}
+		for _, a := range n.Assets {
+			rel := path.Join(assets.DirFor(n.Path), a.Name)
+			if !dryRun {
+				var err error
+				if rel, err = assets.Store(tree, n.Path, a.Name, a.Data); err != nil {
+					return err
+				}
+			}
+			res.Assets = append(res.Assets, rel)
+			b, _ := assets.Link([]byte(body), a.Ref, relTo(n.Path, rel), a.Name)
+			body = string(b)
+		}
+		data, err := format(n, body)
+		if err != nil {
+			return fmt.Errorf("%s: %w", n.Key, err)
+		}
+		if !dryRun {
+			if err := tree.Create(n.Path, data); err != nil {
+				return err
+			}
+		}
+		res.Created = append(res.Created, n.Path)
+	}
+	return nil
+}
+
+// relTo returns the link from the entry at from to the file at to.
+func relTo(from, to string) string {
+	return strings.TrimPrefix(to, path.Dir(from)+"/")
+}
+
+// format formats n as an entry file with body.
+func format(n *Note, body string) ([]byte, error) {
+	f, err := entry.ParseFront(nil)
+	if err != nil {
+		return nil, err
+	}
+	date := n.Date
+	if date.IsZero() {
+		date = time.Now()
+	}
+	tags := slices.Compact(slices.Sorted(slices.Values(n.Tags)))
+	if tags == nil {
+		tags = []string{}
+	}
+	fields := []MetaField{
+		{"title", n.Title},
+		{"date", day(date)},
+		{"category", n.Category},
+		{"slug", strings.ReplaceAll(strings.TrimSuffix(path.Base(n.Path), ".md"), "_", "-")},
+		{"tags", tags},
+	}
+	if !n.Updated.IsZero() && day(n.Updated).After(day(date)) {
+		fields = append(fields, MetaField{"updated", day(n.Updated)})
+	}
+	if n.URL != "" {
+		fields = append(fields, MetaField{SourceField, n.URL})
+	}
+	fields = append(fields, n.Fields...)
+	if n.Key != "" {
+		fields = append(fields, MetaField{Field, n.Key})
+	}
+	for _, fl := range fields {
+		if err := f.Set(fl.Key, fl.Value); err != nil {
+			return nil, err
+		}
+	}
+	text := "\n# " + n.Title + "\n"
+	if b := strings.TrimSpace(body); b != "" {
+		text += "\n" + b + "\n"
+	}
+	return entry.JoinFrontmatter(f.Bytes(), []byte(text)), nil
+}
+
+// day truncates t to its calendar day, as dates are written.
+func day(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+var tagCleanRE = regexp.MustCompile(`[^a-z0-9/_-]+`)
+
+// Tag normalizes a tag from another application: lowercased, without a
+// leading "#", spaces turned into dashes.
+func Tag(t string) string {
+	t = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(t), "#")))
+	t = tagCleanRE.ReplaceAllString(strings.ReplaceAll(t, " ", "-"), "")
+	return strings.Trim(t, "-/")
+}
+
+// Category turns a folder name into a category name, DefaultCategory if
+// it has none or one the tree ignores.
+func Category(folder string) string {
+	if c := entry.Slugify(folder); c != "" && !notes.Skip(c) {
+		return c
+	}
+	return DefaultCategory
+}
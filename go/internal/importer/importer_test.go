@@ -0,0 +1,118 @@
+package importer
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/canhta/til/go/internal/notes"
+)
+
+func newTree(t *testing.T, files map[string]string) *notes.Tree {
+	t.Helper()
+	tree := notes.Open(t.TempDir())
+	for p, data := range files {
+		if err := tree.Write(p, []byte(data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return tree
+}
+
+func readFile(t *testing.T, tree *notes.Tree, p string) string {
+	t.Helper()
+	data, err := tree.Read(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestAssignAndWrite(t *testing.T) {
+	tree := newTree(t, map[string]string{
+		"go/slices.md": "---\ntitle: Slices\n---\n",
+		"go/old.md":    "---\ntitle: Old\nimported: app:old\n---\n",
+		"web/page.md":  "---\ntitle: Page\nsource: https://example.com/page\n---\n",
+	})
+	day := time.Date(2024, 3, 1, 15, 0, 0, 0, time.UTC)
+	ns := []*Note{
+		{Key: "app:slices", Title: "Slices", Category: "go", Tags: []string{"b", "a", "b"}, Date: day, Updated: day.AddDate(0, 0, 2),
+			Fields: []MetaField{{"aliases", []string{"arrays"}}}, Body: "See ![](img.png).\n",
+			Assets: []Asset{{Ref: "img.png", Name: "img.png", Data: []byte("png")}}},
+		{Key: "app:slices-too", Title: "Slices", Category: "go", Date: day,
+			relink: func(pathOf func(string) (string, bool)) string {
+				p, _ := pathOf("app:slices")
+				q, ok := pathOf("app:missing")
+				return p + " " + q + " " + map[bool]string{true: "found", false: "missing"}[ok]
+			}},
+		{Key: "app:old", Title: "Renamed"},
+		{Title: "Page again", URL: "https://example.com/page"},
+		{Title: "!!!", Date: day},
+	}
+	if err := Assign(tree, ns); err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, n := range ns {
+		got = append(got, n.Path+"|"+n.Existing)
+	}
+	want := []string{"go/slices_2.md|", "go/slices_3.md|", "|go/old.md", "|web/page.md", "inbox/note.md|"}
+	if !slices.Equal(got, want) {
+		t.Errorf("Assign = %q, want %q", got, want)
+	}
+
+	var dry Result
+	if err := Write(tree, ns, &dry, true); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tree.Read("go/slices_2.md"); err == nil {
+		t.Error("dry run wrote an entry")
+	}
+	var res Result
+	if err := Write(tree, ns, &res, false); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(res.Created, dry.Created) || !slices.Equal(res.Assets, dry.Assets) {
+		t.Errorf("dry run = %+v, want %+v", dry, res)
+	}
+	if want := []string{"go/slices_2.md", "go/slices_3.md", "inbox/note.md"}; !slices.Equal(res.Created, want) {
+		t.Errorf("Created = %q, want %q", res.Created, want)
+	}
+	if want := []string{"go/old.md", "web/page.md"}; !slices.Equal(res.Existing, want) {
+		t.Errorf("Existing = %q, want %q", res.Existing, want)
+	}
+	if len(res.Assets) != 1 {
+		t.Fatalf("Assets = %q", res.Assets)
+	}
+	if data, err := tree.Read(res.Assets[0]); err != nil || string(data) != "png" {
+		t.Errorf("asset %s = %q, %v", res.Assets[0], data, err)
+	}
+
+	wantSlices := "---\ntitle: Slices\ndate: 2024-03-01\ncategory: go\nslug: slices-2\ntags: [a, b]\nupdated: 2024-03-03\naliases: [arrays]\nimported: app:slices\n---\n\n# Slices\n\nSee ![](assets/slices_2/img.png).\n"
+	if got := readFile(t, tree, "go/slices_2.md"); got != wantSlices {
+		t.Errorf("go/slices_2.md =\n%s\nwant\n%s", got, wantSlices)
+	}
+	if got := readFile(t, tree, "go/slices_3.md"); !strings.Contains(got, "\ngo/slices_2.md  missing\n") {
+		t.Errorf("go/slices_3.md not relinked:\n%s", got)
+	}
+}
+
+func TestTag(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"#Go", "go"},
+		{" Data Structures ", "data-structures"},
+		{"lang/Go!", "lang/go"},
+		{"#-/", ""},
+	}
+	for _, tt := range tests {
+		if got := Tag(tt.in); got != tt.want {
+			t.Errorf("Tag(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+	for in, want := range map[string]string{"Go Notes": "go-notes", "": DefaultCategory, "!!": DefaultCategory, "Public": DefaultCategory} {
+		if got := Category(in); got != want {
+			t.Errorf("Category(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
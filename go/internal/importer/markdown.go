@@ -0,0 +1,157 @@
+package importer
+
+import (
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/canhta/til/go/internal/links"
+	"github.com/canhta/til/go/pkg/entry"
+	"github.com/canhta/til/go/pkg/render"
+)
+
+var (
+	mdLinkRE   = regexp.MustCompile(`(!?)\[([^\[\]]*)\]\(<?([^)<>]+?)>?\)`)
+	codeSpanRE = regexp.MustCompile("`+[^`]*`+")
+	// placeholderRE matches the stand-ins for links to other notes.
+	placeholderRE = regexp.MustCompile("\x00(\\d+)\x00")
+)
+
+// link is a reference to another note of the source, written once the
+// path of its entry is known.
+type link struct {
+	// key is the Key of the note linked to.
+	key string
+	// name is the note's name in the source, for links to notes not
+	// imported.
+	name    string
+	heading string
+	label   string
+	include bool
+}
+
+// write renders l as a link in the entry imported from the note self.
+// Links to notes not imported are left as wiki links to their name.
+func (l link) write(self string, pathOf func(string) (string, bool)) string {
+	to, ok := pathOf(l.key)
+	from, _ := pathOf(self)
+	if !ok {
+		if l.label != "" && l.label != l.name {
+			return "[[" + l.name + "|" + l.label + "]]"
+		}
+		return "[[" + l.name + "]]"
+	}
+	ref := strings.TrimSuffix(to, ".md")
+	switch {
+	case l.include:
+		if l.heading != "" {
+			ref += "#" + l.heading
+		}
+		return `{{include "` + ref + `"}}`
+	case l.heading != "":
+		label := l.label
+		if label == "" {
+			label = l.heading
+		}
+		return "[" + label + "](" + links.Relative(from, to) + "#" + render.Anchor(l.heading) + ")"
+	case l.label != "":
+		return "[[" + ref + "|" + l.label + "]]"
+	}
+	return "[[" + ref + "]]"
+}
+
+// refs collects what the body of a converted note refers to: attachments,
+// stored with the note, and other notes, linked through placeholders until
+// their entries have paths.
+type refs struct {
+	n     *Note
+	read  func(name string) ([]byte, error)
+	files map[string]string
+	links []link
+}
+
+func newRefs(n *Note, read func(string) ([]byte, error)) *refs {
+	return &refs{n: n, read: read, files: map[string]string{}}
+}
+
+// attach adds the file at p of the source to the note's assets and returns
+// the link destination standing for it, or "" if it cannot be read.
+func (r *refs) attach(p string) string {
+	if ref, ok := r.files[p]; ok {
+		return ref
+	}
+	data, err := r.read(p)
+	if err != nil {
+		return ""
+	}
+	ref := "attachment-" + strconv.Itoa(len(r.files)) + "/" + path.Base(p)
+	r.files[p] = ref
+	r.n.Assets = append(r.n.Assets, Asset{Ref: ref, Name: path.Base(p), Data: data})
+	return ref
+}
+
+// link returns the placeholder for l.
+func (r *refs) link(l link) string {
+	r.links = append(r.links, l)
+	return "\x00" + strconv.Itoa(len(r.links)-1) + "\x00"
+}
+
+// finish sets the note's body to text, with its placeholders resolved when
+// the note is written.
+func (r *refs) finish(text string) {
+	r.n.Body = text
+	if len(r.links) == 0 {
+		return
+	}
+	ls, self := r.links, r.n.Key
+	r.n.relink = func(pathOf func(string) (string, bool)) string {
+		return placeholderRE.ReplaceAllStringFunc(text, func(m string) string {
+			i, _ := strconv.Atoi(strings.Trim(m, "\x00"))
+			return ls[i].write(self, pathOf)
+		})
+	}
+}
+
+// codeLines returns the zero-based indexes of the lines of src inside
+// fenced code blocks, fences included.
+func codeLines(src string) map[int]bool {
+	code := map[int]bool{}
+	for _, b := range entry.CodeBlocks([]byte(src)) {
+		for i := b.Line - 1; i < b.EndLine; i++ {
+			code[i] = true
+		}
+	}
+	return code
+}
+
+// outsideCode applies fn to the parts of line outside code spans.
+func outsideCode(line string, fn func(string) string) string {
+	var b strings.Builder
+	at := 0
+	for _, s := range codeSpanRE.FindAllStringIndex(line, -1) {
+		b.WriteString(fn(line[at:s[0]]))
+		b.WriteString(line[s[0]:s[1]])
+		at = s[1]
+	}
+	b.WriteString(fn(line[at:]))
+	return b.String()
+}
+
+// replaceMatches replaces the matches of re in s with the result of fn
+// for their submatches.
+func replaceMatches(re *regexp.Regexp, s string, fn func(m []string) string) string {
+	return re.ReplaceAllStringFunc(s, func(m string) string {
+		return fn(re.FindStringSubmatch(m))
+	})
+}
+
+// stripHeading removes a leading level-one heading reading title.
+func stripHeading(body []byte, title string) []byte {
+	rest := strings.TrimLeft(string(body), " \t\r\n")
+	first, after, _ := strings.Cut(rest, "\n")
+	if strings.HasPrefix(first, "# ") && strings.TrimSpace(first[2:]) == title {
+		return []byte(after)
+	}
+	return body
+}
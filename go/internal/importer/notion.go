@@ -0,0 +1,590 @@
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"io/fs"
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+// NotionOptions configures Notion.
+type NotionOptions struct {
+	// Category holds the top-level pages without subpages. Defaults to
+	// DefaultCategory.
+	Category string
+}
+
+var (
+	// notionIDRE matches the ID Notion appends to the names of exported
+	// pages and folders, as in "Slices 1f2e3d4c5b6a79880123456789abcdef".
+	notionIDRE = regexp.MustCompile(`^(.*?)\s+([0-9a-f]{32})$`)
+	// notionURLRE matches links to pages on notion.so.
+	notionURLRE = regexp.MustCompile(`^https://(?:www\.)?notion\.so/.*?([0-9a-f]{32})(?:[?#].*)?$`)
+	propRE      = regexp.MustCompile(`^([^:\s][^:]{0,60}):\s+(.*)$`)
+)
+
+// export indexes the files of a Notion export.
+type export struct {
+	files map[string]exportFile
+	// pages maps page IDs to the paths of their files.
+	pages map[string]string
+	// databases maps the IDs of databases to their titles, and tables to
+	// the CSV files listing their rows.
+	databases map[string]string
+	tables    map[string]string
+	// parents holds the IDs of pages with subpages.
+	parents map[string]bool
+}
+
+type exportFile struct {
+	fsys fs.FS
+	name string
+}
+
+func (x *export) read(p string) ([]byte, error) {
+	f, ok := x.files[p]
+	if !ok {
+		return nil, fs.ErrNotExist
+	}
+	return fs.ReadFile(f.fsys, f.name)
+}
+
+// Notion converts the pages of a Notion export, in markdown or HTML, in
+// fsys, reading the archives of exports split in several parts.
+//
+// Page titles are taken from Notion's file names, without the IDs it
+// appends. Subpages of a top-level page go in the category named after it,
+// with the pages between as tags. The rows of a database become entries
+// tagged with its name, their properties carried over as frontmatter.
+func Notion(fsys fs.FS, opts NotionOptions) ([]*Note, []Skipped, error) {
+	x := &export{
+		files: map[string]exportFile{}, pages: map[string]string{}, databases: map[string]string{},
+		tables: map[string]string{}, parents: map[string]bool{},
+	}
+	if err := x.add(fsys); err != nil {
+		return nil, nil, err
+	}
+	for p := range x.files {
+		for _, dir := range dirs(p) {
+			if _, id := pageName(dir); id != "" {
+				x.parents[id] = true
+			}
+		}
+	}
+	paths := make([]string, 0, len(x.pages))
+	for _, p := range x.pages {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	var out []*Note
+	var skipped []Skipped
+	rows := map[string]bool{}
+	for _, p := range paths {
+		n, reason, err := x.convert(p, opts, rows)
+		if err != nil {
+			return nil, nil, err
+		}
+		if n == nil {
+			if reason != "" {
+				skipped = append(skipped, Skipped{p, reason})
+			}
+			continue
+		}
+		out = append(out, n)
+	}
+	// Rows without a page of their own are converted from the table.
+	ids := make([]string, 0, len(x.tables))
+	for id := range x.tables {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		keys, table, err := x.table(id)
+		if err != nil {
+			skipped = append(skipped, Skipped{x.tables[id], err.Error()})
+			continue
+		}
+		for _, row := range table {
+			title := row[keys[0]]
+			if rows[id+"\x00"+title] || title == "" {
+				continue
+			}
+			n := &Note{Key: "notion:" + id + "/" + title, Title: title}
+			n.Category, n.Tags = x.category(x.tables[id], id, opts)
+			x.properties(n, keys[1:], row, id)
+			n.Tags = cleanTags(n.Tags)
+			out = append(out, n)
+		}
+	}
+	return out, skipped, nil
+}
+
+// add indexes the files of fsys, and those of the archives in it.
+func (x *export) add(fsys fs.FS) error {
+	return fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		if strings.HasPrefix(d.Name(), ".") || strings.HasPrefix(p, "__MACOSX/") {
+			return nil
+		}
+		if strings.EqualFold(path.Ext(p), ".zip") {
+			data, err := fs.ReadFile(fsys, p)
+			if err != nil {
+				return err
+			}
+			z, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+			if err != nil {
+				return err
+			}
+			return x.add(z)
+		}
+		x.files[p] = exportFile{fsys, p}
+		stem, ext := strings.TrimSuffix(path.Base(p), path.Ext(p)), strings.ToLower(path.Ext(p))
+		title, id := pageName(strings.TrimSuffix(stem, "_all"))
+		switch {
+		case id == "":
+		case ext == ".csv":
+			// The _all table lists the rows the view exported filters out.
+			if old, ok := x.tables[id]; !ok || !strings.HasSuffix(strings.TrimSuffix(old, ".csv"), "_all") {
+				x.tables[id] = p
+			}
+			x.databases[id] = title
+		case ext == ".md":
+			x.pages[id] = p
+		case ext == ".html":
+			x.pages[id] = p
+			// HTML exports have a page for each database, listing its rows.
+			data, err := fs.ReadFile(fsys, p)
+			if err != nil {
+				return err
+			}
+			if bytes.Contains(data, []byte(`class="collection-content"`)) {
+				x.databases[id] = title
+			}
+		}
+		return nil
+	})
+}
+
+// pageName splits a file or folder name into the title and the ID Notion
+// appended to it.
+func pageName(name string) (title, id string) {
+	if m := notionIDRE.FindStringSubmatch(name); m != nil {
+		return m[1], m[2]
+	}
+	return name, ""
+}
+
+// dirs returns the folders leading to the slash-separated path p.
+func dirs(p string) []string {
+	d := path.Dir(p)
+	if d == "." {
+		return nil
+	}
+	return strings.Split(d, "/")
+}
+
+// category returns the category of the page at p and the tags the pages
+// over it give it; id is the page's own ID or, for a row, its database's.
+func (x *export) category(p, id string, opts NotionOptions) (string, []string) {
+	var over []string
+	for _, d := range dirs(p) {
+		if title, id := pageName(d); id != "" {
+			over = append(over, title)
+		}
+	}
+	if len(over) == 0 {
+		if _, ok := x.databases[id]; ok || x.parents[id] {
+			return Category(x.title(id)), nil
+		}
+		if opts.Category != "" {
+			return opts.Category, nil
+		}
+		return DefaultCategory, nil
+	}
+	return Category(over[0]), over[1:]
+}
+
+// title returns the title of the page or database id.
+func (x *export) title(id string) string {
+	if t, ok := x.databases[id]; ok {
+		return t
+	}
+	t, _ := pageName(strings.TrimSuffix(path.Base(x.pages[id]), path.Ext(x.pages[id])))
+	return t
+}
+
+// database returns the ID of the database the page at p is a row of.
+func (x *export) database(p string) (string, bool) {
+	ds := dirs(p)
+	if len(ds) == 0 {
+		return "", false
+	}
+	_, id := pageName(ds[len(ds)-1])
+	_, ok := x.databases[id]
+	return id, ok && id != ""
+}
+
+// table reads the CSV listing the rows of the database id, returning its
+// columns, the first naming the rows, and rows by column.
+func (x *export) table(id string) ([]string, []map[string]string, error) {
+	data, err := x.read(x.tables[id])
+	if err != nil {
+		return nil, nil, err
+	}
+	r := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\ufeff"))))
+	r.FieldsPerRecord, r.LazyQuotes = -1, true
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(records) == 0 {
+		return []string{""}, nil, nil
+	}
+	keys := records[0]
+	var rows []map[string]string
+	for _, rec := range records[1:] {
+		row := map[string]string{}
+		for i, v := range rec {
+			if i < len(keys) {
+				row[keys[i]] = strings.TrimSpace(v)
+			}
+		}
+		rows = append(rows, row)
+	}
+	return keys, rows, nil
+}
+
+// convert converts the page at p, or returns why it cannot; a page
+// standing for a database is dropped without a reason, as its rows are
+// imported instead. Rows found in a database's table are recorded in rows.
+func (x *export) convert(p string, opts NotionOptions, rows map[string]bool) (*Note, string, error) {
+	data, err := x.read(p)
+	if err != nil {
+		return nil, "", err
+	}
+	stem := strings.TrimSuffix(path.Base(p), path.Ext(p))
+	title, id := pageName(stem)
+	n := &Note{Key: "notion:" + id, Title: title}
+	if info, err := fs.Stat(x.files[p].fsys, x.files[p].name); err == nil {
+		n.Date = info.ModTime()
+	}
+	var body string
+	var keys []string
+	props := map[string]string{}
+	if strings.EqualFold(path.Ext(p), ".html") {
+		doc, err := html.Parse(bytes.NewReader(data))
+		if err != nil {
+			return nil, err.Error(), nil
+		}
+		if _, ok := x.databases[id]; ok {
+			return nil, "", nil
+		}
+		var t string
+		t, keys, props, body = htmlPage(doc)
+		if t != "" {
+			n.Title = t
+		}
+	} else {
+		body = string(data)
+		if h := entry.Heading(data); h != "" && len(stripHeading(data, h)) < len(data) {
+			n.Title, body = h, string(stripHeading(data, h))
+		}
+	}
+	db, isRow := x.database(p)
+	if isRow {
+		if _, ok := x.tables[db]; ok {
+			tkeys, table, err := x.table(db)
+			if err == nil {
+				for _, row := range table {
+					if row[tkeys[0]] == n.Title && !rows[db+"\x00"+n.Title] {
+						rows[db+"\x00"+n.Title] = true
+						keys, props = tkeys[1:], row
+						break
+					}
+				}
+			}
+		}
+		var inline []string
+		var inlineProps map[string]string
+		inline, inlineProps, body = splitProperties(body, keys)
+		if len(keys) == 0 {
+			keys, props = inline, inlineProps
+		}
+	}
+	n.Category, n.Tags = x.category(p, id, opts)
+	if !isRow {
+		db = ""
+	}
+	x.properties(n, keys, props, db)
+	if !isRow && len(n.Fields) == 0 && n.URL == "" && x.onlyLinks(p, body) {
+		if strings.TrimSpace(body) == "" && !x.parents[id] {
+			return nil, "empty", nil
+		}
+		return nil, "only links to other pages", nil
+	}
+	r := newRefs(n, x.read)
+	r.finish(x.body(p, body, r))
+	n.Tags = cleanTags(n.Tags)
+	return n, "", nil
+}
+
+// properties applies the properties of a page, named in order by keys,
+// to n: tags, dates and a source URL to their fields, the others as
+// frontmatter. A row also gets the tag of its database db.
+func (x *export) properties(n *Note, keys []string, props map[string]string, db string) {
+	if db != "" {
+		n.Tags = append(n.Tags, x.title(db))
+	}
+	for _, k := range keys {
+		v := strings.TrimSpace(props[k])
+		if v == "" {
+			continue
+		}
+		switch strings.ToLower(strings.TrimSpace(k)) {
+		case "name", "title":
+			continue
+		case "tags", "tag", "labels", "topics", "keywords", "categories", "category":
+			n.Tags = append(n.Tags, strings.Split(v, ",")...)
+			continue
+		case "created", "created time", "created at", "date", "date created":
+			if t, ok := notionDate(v); ok {
+				n.Date = t
+				continue
+			}
+		case "last edited time", "last edited", "updated", "modified", "last modified":
+			if t, ok := notionDate(v); ok {
+				n.Updated = t
+				continue
+			}
+		case "url", "link", "source":
+			if strings.HasPrefix(v, "http://") || strings.HasPrefix(v, "https://") {
+				n.URL = v
+				continue
+			}
+		}
+		key := fieldKey(k)
+		switch key {
+		case "", "title", "date", "category", "slug", "tags", "updated", SourceField, Field:
+			continue
+		}
+		n.Fields = append(n.Fields, MetaField{key, v})
+	}
+}
+
+var fieldKeyRE = regexp.MustCompile(`[^a-z0-9]+`)
+
+// fieldKey turns a property name into a frontmatter field name.
+func fieldKey(name string) string {
+	return strings.Trim(fieldKeyRE.ReplaceAllString(strings.ToLower(name), "_"), "_")
+}
+
+// notionDate reads a date property, the start of a range.
+func notionDate(v string) (time.Time, bool) {
+	v, _, _ = strings.Cut(strings.TrimPrefix(v, "@"), "→")
+	return parseDate(strings.TrimSpace(v))
+}
+
+// splitProperties removes the "Name: value" lines following the title of
+// a row's markdown page. With keys, only lines naming a column are taken.
+func splitProperties(body string, keys []string) ([]string, map[string]string, string) {
+	known := map[string]bool{}
+	for _, k := range keys {
+		known[k] = true
+	}
+	lines := strings.Split(strings.TrimLeft(body, "\r\n"), "\n")
+	var names []string
+	props := map[string]string{}
+	i := 0
+	for ; i < len(lines); i++ {
+		m := propRE.FindStringSubmatch(strings.TrimRight(lines[i], "\r"))
+		if m == nil || len(keys) > 0 && !known[m[1]] {
+			break
+		}
+		names = append(names, m[1])
+		props[m[1]] = m[2]
+	}
+	return names, props, strings.Join(lines[i:], "\n")
+}
+
+// htmlPage reads an exported HTML page: its title, properties and body as
+// markdown.
+func htmlPage(doc *html.Node) (title string, keys []string, props map[string]string, body string) {
+	props = map[string]string{}
+	var content *html.Node
+	walkElems(doc, func(n *html.Node) bool {
+		switch {
+		case n.DataAtom == atom.Title && title == "":
+			title = strings.TrimSpace(rawText(n))
+		case n.DataAtom == atom.H1 && hasClass(n, "page-title"):
+			title = strings.Join(strings.Fields(rawText(n)), " ")
+			return false
+		case n.DataAtom == atom.Table && hasClass(n, "properties"):
+			walkElems(n, func(tr *html.Node) bool {
+				if tr.DataAtom != atom.Tr {
+					return true
+				}
+				var k string
+				var vals []string
+				for c := tr.FirstChild; c != nil; c = c.NextSibling {
+					switch c.DataAtom {
+					case atom.Th:
+						k = strings.TrimSpace(rawText(c))
+					case atom.Td:
+						walkElems(c, func(s *html.Node) bool {
+							if hasClass(s, "selected-value") {
+								vals = append(vals, strings.TrimSpace(rawText(s)))
+								return false
+							}
+							return true
+						})
+						if len(vals) == 0 {
+							vals = []string{strings.Join(strings.Fields(rawText(c)), " ")}
+						}
+					}
+				}
+				if k != "" {
+					keys = append(keys, k)
+					props[k] = strings.Join(vals, ", ")
+				}
+				return false
+			})
+			return false
+		case hasClass(n, "page-body"):
+			content = n
+			return false
+		}
+		return true
+	})
+	if content != nil {
+		body = markdownOf(content)
+	}
+	return title, keys, props, body
+}
+
+// body converts the markdown of the page at from: links to other pages
+// and attachments, and callouts outside code.
+func (x *export) body(from, src string, r *refs) string {
+	lines := strings.Split(src, "\n")
+	code := codeLines(src)
+	var out []string
+	aside := false
+	for i, line := range lines {
+		if code[i] {
+			out = append(out, line)
+			continue
+		}
+		// Markdown exports keep callouts as <aside> elements.
+		switch t := strings.TrimSpace(line); {
+		case t == "<aside>":
+			aside = true
+			out = append(out, "> [!NOTE]")
+			continue
+		case t == "</aside>" && aside:
+			aside = false
+			continue
+		}
+		line = outsideCode(line, func(text string) string {
+			return replaceMatches(mdLinkRE, text, func(m []string) string {
+				return x.link(from, m, r)
+			})
+		})
+		if aside {
+			if out[len(out)-1] == "> [!NOTE]" {
+				icon, rest := splitIcon(strings.TrimSpace(line))
+				if icon != "" {
+					out[len(out)-1] = "> [!" + iconCallout(icon) + "]"
+					line = rest
+				}
+			}
+			line = strings.TrimRight("> "+line, " ")
+		}
+		out = append(out, line)
+	}
+	return strings.Join(out, "\n")
+}
+
+// resolve returns the path in the export of the file the link destination
+// dest in the page at from names, and the ID of the page or database if it
+// is one.
+func (x *export) resolve(from, dest string) (target, frag, id string, ok bool) {
+	if u := notionURLRE.FindStringSubmatch(dest); u != nil {
+		if x.pages[u[1]] == "" && x.databases[u[1]] == "" {
+			return "", "", "", false
+		}
+		return x.pages[u[1]], "", u[1], true
+	}
+	if strings.Contains(dest, ":") || strings.HasPrefix(dest, "#") {
+		return "", "", "", false
+	}
+	target, frag, _ = strings.Cut(dest, "#")
+	if u, err := url.PathUnescape(target); err == nil {
+		target = u
+	}
+	target = path.Join(path.Dir(from), target)
+	_, id = pageName(strings.TrimSuffix(strings.TrimSuffix(path.Base(target), path.Ext(target)), "_all"))
+	if x.pages[id] == "" && x.databases[id] == "" {
+		id = ""
+	}
+	return target, frag, id, true
+}
+
+// link converts the markdown link m in the page at from. Links to
+// databases are reduced to their text, as their rows are entries of their
+// own.
+func (x *export) link(from string, m []string, r *refs) string {
+	target, frag, id, ok := x.resolve(from, m[3])
+	switch {
+	case !ok:
+		return m[0]
+	case id != "" && x.databases[id] != "":
+		return m[2]
+	case id != "":
+		title := x.title(id)
+		label := m[2]
+		if label == title {
+			label = ""
+		}
+		return r.link(link{key: "notion:" + id, name: title, heading: frag, label: label})
+	}
+	if _, ok := x.files[target]; ok {
+		if ref := r.attach(target); ref != "" {
+			return m[1] + "[" + m[2] + "](" + ref + ")"
+		}
+	}
+	return m[0]
+}
+
+// onlyLinks reports whether the markdown of the page at from has nothing
+// but links to other pages and databases.
+func (x *export) onlyLinks(from, src string) bool {
+	lines := strings.Split(src, "\n")
+	code := codeLines(src)
+	for i, line := range lines {
+		if code[i] {
+			return false
+		}
+		rest := replaceMatches(mdLinkRE, line, func(m []string) string {
+			if _, _, id, ok := x.resolve(from, m[3]); ok && id != "" {
+				return ""
+			}
+			return m[0]
+		})
+		if strings.Trim(rest, " \t\r-*+") != "" {
+			return false
+		}
+	}
+	return true
+}
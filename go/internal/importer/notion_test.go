@@ -0,0 +1,166 @@
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+const (
+	idProgramming = "11111111111111111111111111111111"
+	idSlices      = "22222222222222222222222222222222"
+	idDeep        = "33333333333333333333333333333333"
+	idReading     = "44444444444444444444444444444444"
+	idBook        = "55555555555555555555555555555555"
+	idLoose       = "66666666666666666666666666666666"
+)
+
+// zipOf returns a zip archive of files.
+func zipOf(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for name, data := range files {
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Write([]byte(data))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestNotion(t *testing.T) {
+	mod := time.Date(2024, 1, 2, 12, 0, 0, 0, time.UTC)
+	export := fstest.MapFS{
+		"Programming " + idProgramming + ".md": {Data: []byte("# Programming\n\n[Slices](Programming%20" + idProgramming + "/Slices%20" + idSlices + ".md)\n"), ModTime: mod},
+		"Programming " + idProgramming + "/Slices " + idSlices + ".md": {Data: []byte("# Slices\n\n" +
+			"See [Loose](../Loose%20" + idLoose + ".md), [the deep one](https://www.notion.so/Deep-" + idDeep + "), [Reading](../Reading%20" + idReading + ".csv) and [elsewhere](https://example.com).\n\n" +
+			"![diagram](Slices%20" + idSlices + "/diagram.png)\n\n<aside>\n💡 Copy first.\n\n</aside>\n\n```go\n[x](Loose%20" + idLoose + ".md)\n```\n"), ModTime: mod},
+		"Programming " + idProgramming + "/Slices " + idSlices + "/diagram.png":            {Data: []byte("png")},
+		"Programming " + idProgramming + "/Slices " + idSlices + "/Deep " + idDeep + ".md": {Data: []byte("# Deep\n\nDeep.\n"), ModTime: mod},
+		"Export-Part-2.zip": {Data: zipOf(t, map[string]string{
+			"Reading " + idReading + ".csv":                    "\ufeffName,Tags,Created,URL,Rating\nBook,\"Go, Books\",\"March 1, 2024\",https://example.com/book,5\nPaper,,2024/02/01,,3\n",
+			"Reading " + idReading + "/Book " + idBook + ".md": "# Book\n\nTags: Go, Books\nCreated: March 1, 2024\nURL: https://example.com/book\nRating: 5\n\nNotes on the book.\n",
+			"Loose " + idLoose + ".md":                         "# Loose\n\nLoose note.\n",
+			"Index 77777777777777777777777777777777.md":        "# Index\n\n- [Loose](Loose%20" + idLoose + ".md)\n",
+			"Empty 88888888888888888888888888888888.md":        "# Empty\n",
+		})},
+		"__MACOSX/._Loose.md": {Data: []byte("junk")},
+	}
+	ns, skipped, err := Notion(export, NotionOptions{Category: "scratch"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, n := range ns {
+		got = append(got, n.Key+" "+n.Category+" "+n.Title+" "+strings.Join(n.Tags, ","))
+	}
+	want := []string{
+		"notion:" + idLoose + " scratch Loose ",
+		"notion:" + idSlices + " programming Slices ",
+		"notion:" + idDeep + " programming Deep slices",
+		"notion:" + idBook + " reading Book reading,go,books",
+		"notion:" + idReading + "/Paper reading Paper reading",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("notes =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	var reasons []string
+	for _, s := range skipped {
+		reasons = append(reasons, s.Source+": "+s.Reason)
+	}
+	if want := []string{
+		"Empty 88888888888888888888888888888888.md: empty",
+		"Index 77777777777777777777777777777777.md: only links to other pages",
+		"Programming " + idProgramming + ".md: only links to other pages",
+	}; !slices.Equal(reasons, want) {
+		t.Errorf("skipped = %q, want %q", reasons, want)
+	}
+
+	tree := newTree(t, nil)
+	if err := Assign(tree, ns); err != nil {
+		t.Fatal(err)
+	}
+	var res Result
+	if err := Write(tree, ns, &res, false); err != nil {
+		t.Fatal(err)
+	}
+	wantFiles := map[string]string{
+		"programming/slices.md": "---\ntitle: Slices\ndate: 2024-01-02\ncategory: programming\nslug: slices\ntags: []\nimported: notion:" + idSlices + "\n---\n\n# Slices\n\n" +
+			"See [[scratch/loose]], [[programming/deep|the deep one]], Reading and [elsewhere](https://example.com).\n\n" +
+			"![diagram](assets/slices/diagram.png)\n\n> [!TIP]\n> Copy first.\n>\n\n```go\n[x](Loose%20" + idLoose + ".md)\n```\n",
+		"reading/book.md":  "---\ntitle: Book\ndate: 2024-03-01\ncategory: reading\nslug: book\ntags: [books, go, reading]\nsource: https://example.com/book\nrating: \"5\"\nimported: notion:" + idBook + "\n---\n\n# Book\n\nNotes on the book.\n",
+		"reading/paper.md": "---\ntitle: Paper\ndate: 2024-02-01\ncategory: reading\nslug: paper\ntags: [reading]\nrating: \"3\"\nimported: notion:" + idReading + "/Paper\n---\n\n# Paper\n",
+	}
+	for p, want := range wantFiles {
+		if got := readFile(t, tree, p); got != want {
+			t.Errorf("%s =\n%s\nwant\n%s", p, got, want)
+		}
+	}
+	if want := []string{"programming/assets/slices/diagram.png"}; !slices.Equal(res.Assets, want) {
+		t.Errorf("Assets = %q, want %q", res.Assets, want)
+	}
+
+	// A second import finds every page already there.
+	again, _, err := Notion(export, NotionOptions{Category: "scratch"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Assign(tree, again); err != nil {
+		t.Fatal(err)
+	}
+	for _, n := range again {
+		if n.Path != "" || n.Existing == "" {
+			t.Errorf("second import placed %s at %q", n.Key, n.Path)
+		}
+	}
+}
+
+func TestNotionHTML(t *testing.T) {
+	page := `<html><head><title>Slices</title></head><body><article>
+<header><h1 class="page-title">Slices  share arrays</h1>
+<table class="properties"><tbody>
+<tr><th>Tags</th><td><span class="selected-value">go</span><span class="selected-value">Data</span></td></tr>
+<tr><th>Created</th><td>March 1, 2024 3:04 PM</td></tr>
+<tr><th>Status</th><td>Done</td></tr>
+</tbody></table></header>
+<div class="page-body"><p>Use <strong>copy</strong> and <a href="Loose%20` + idLoose + `.html">Loose</a>.</p>
+<ul><li>one</li><li>two</li></ul>
+<figure class="callout"><div class="icon">⚠️</div><div>Mind the <code>cap</code>.</div></figure>
+<pre><code class="language-go">s = append(s, 1)</code></pre></div>
+</article></body></html>`
+	db := `<html><body><h1 class="page-title">Reading</h1><table class="collection-content"></table></body></html>`
+	export := fstest.MapFS{
+		"Slices " + idSlices + ".html":   {Data: []byte(page)},
+		"Loose " + idLoose + ".html":     {Data: []byte(`<html><body><h1 class="page-title">Loose</h1><div class="page-body"><p>Loose note.</p></div></body></html>`)},
+		"Reading " + idReading + ".html": {Data: []byte(db)},
+	}
+	ns, skipped, err := Notion(export, NotionOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ns) != 2 || len(skipped) != 0 {
+		t.Fatalf("notes %d, skipped %v, want 2 and none", len(ns), skipped)
+	}
+	tree := newTree(t, nil)
+	if err := Assign(tree, ns); err != nil {
+		t.Fatal(err)
+	}
+	var res Result
+	if err := Write(tree, ns, &res, false); err != nil {
+		t.Fatal(err)
+	}
+	want := "---\ntitle: Slices share arrays\ndate: 2024-03-01\ncategory: inbox\nslug: slices-share-arrays\ntags: [data, go]\nstatus: Done\nimported: notion:" + idSlices + "\n---\n\n" +
+		"# Slices share arrays\n\nUse **copy** and [[inbox/loose]].\n\n- one\n- two\n\n> [!WARNING]\n> Mind the `cap`.\n\n```go\ns = append(s, 1)\n```\n"
+	if got := readFile(t, tree, "inbox/slices_share_arrays.md"); got != want {
+		t.Errorf("inbox/slices_share_arrays.md =\n%s\nwant\n%s", got, want)
+	}
+}
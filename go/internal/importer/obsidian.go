@@ -0,0 +1,420 @@
+package importer
+
+import (
+	"fmt"
+	"io/fs"
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+// ObsidianOptions configures Obsidian.
+type ObsidianOptions struct {
+	// Map maps vault folders to categories; the longest folder containing
+	// a note wins. Other notes go in the category named after their top
+	// folder, with deeper folders as tags.
+	Map map[string]string
+	// Category holds the notes at the top of the vault. Defaults to
+	// DefaultCategory.
+	Category string
+}
+
+// calloutTypes maps Obsidian callout types to the five callouts entries
+// support.
+var calloutTypes = map[string]string{
+	"note": "NOTE", "info": "NOTE", "todo": "NOTE", "abstract": "NOTE", "summary": "NOTE",
+	"tldr": "NOTE", "quote": "NOTE", "cite": "NOTE", "example": "NOTE",
+	"tip": "TIP", "hint": "TIP", "success": "TIP", "check": "TIP", "done": "TIP",
+	"important": "IMPORTANT", "question": "IMPORTANT", "help": "IMPORTANT", "faq": "IMPORTANT",
+	"warning": "WARNING", "attention": "WARNING",
+	"caution": "CAUTION", "danger": "CAUTION", "error": "CAUTION", "failure": "CAUTION",
+	"fail": "CAUTION", "missing": "CAUTION", "bug": "CAUTION",
+}
+
+var (
+	obsidianCalloutRE = regexp.MustCompile(`^(\s*>\s*)\[!([A-Za-z-]+)\][+-]?(.*)$`)
+	// embedRE and wikiRE match ![[target]] and [[target]], each optionally
+	// with #heading, ^block and |alias parts.
+	embedRE   = regexp.MustCompile(`!\[\[([^\[\]|#^]*)(#[^\[\]|^]*)?(\^[^\[\]|]*)?(?:\|([^\[\]]*))?\]\]`)
+	wikiRE    = regexp.MustCompile(`\[\[([^\[\]|#^]*)(#[^\[\]|^]*)?(\^[^\[\]|]*)?(?:\|([^\[\]]*))?\]\]`)
+	inlineTag = regexp.MustCompile(`(?:^|\s)#([A-Za-z][\w/-]*)`)
+	commentRE = regexp.MustCompile(`%%.*?%%`)
+)
+
+// vault indexes the files of an Obsidian vault.
+type vault struct {
+	fsys  fs.FS
+	notes map[string]bool
+	files map[string]bool
+	// byName maps lower-cased file names, and note names without .md, to
+	// the paths having them.
+	byName map[string][]string
+}
+
+// Obsidian converts the notes of the vault in fsys. Attachments they embed
+// or link to are copied; canvases and drawings are reported as skipped.
+func Obsidian(fsys fs.FS, opts ObsidianOptions) ([]*Note, []Skipped, error) {
+	v := &vault{fsys: fsys, notes: map[string]bool{}, files: map[string]bool{}, byName: map[string][]string{}}
+	var skipped []Skipped
+	err := fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p != "." && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+		name := strings.ToLower(d.Name())
+		switch {
+		case strings.HasSuffix(name, ".excalidraw.md"):
+			skipped = append(skipped, Skipped{p, "Excalidraw drawing"})
+		case strings.HasSuffix(name, ".canvas"):
+			skipped = append(skipped, Skipped{p, "canvas"})
+		case strings.HasSuffix(name, ".md"):
+			v.notes[p] = true
+			v.byName[strings.TrimSuffix(name, ".md")] = append(v.byName[strings.TrimSuffix(name, ".md")], p)
+		default:
+			v.files[p] = true
+			v.byName[name] = append(v.byName[name], p)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, ps := range v.byName {
+		// Obsidian prefers the match nearest the vault's top.
+		sort.Slice(ps, func(i, j int) bool {
+			if a, b := strings.Count(ps[i], "/"), strings.Count(ps[j], "/"); a != b {
+				return a < b
+			}
+			return ps[i] < ps[j]
+		})
+	}
+	paths := make([]string, 0, len(v.notes))
+	for p := range v.notes {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	var out []*Note
+	for _, p := range paths {
+		n, reason, err := v.convert(p, opts)
+		if err != nil {
+			return nil, nil, err
+		}
+		if n == nil {
+			skipped = append(skipped, Skipped{p, reason})
+			continue
+		}
+		out = append(out, n)
+	}
+	return out, skipped, nil
+}
+
+// resolve finds the note or file a link target in the note at from names:
+// a path from the vault's top or relative to from, else a name anywhere.
+func (v *vault) resolve(from, target string) (string, bool) {
+	target = strings.TrimSpace(target)
+	if target == "" {
+		return from, true
+	}
+	for _, p := range []string{target, path.Join(path.Dir(from), target)} {
+		p = path.Clean(p)
+		for _, q := range []string{p, p + ".md"} {
+			if v.notes[q] || v.files[q] {
+				return q, true
+			}
+		}
+	}
+	name := strings.ToLower(path.Base(target))
+	if ps := v.byName[name]; len(ps) > 0 {
+		return ps[0], true
+	}
+	if ps := v.byName[strings.TrimSuffix(name, ".md")]; len(ps) > 0 && v.notes[ps[0]] {
+		return ps[0], true
+	}
+	return "", false
+}
+
+// convert converts the note at p, or returns why it cannot.
+func (v *vault) convert(p string, opts ObsidianOptions) (*Note, string, error) {
+	data, err := fs.ReadFile(v.fsys, p)
+	if err != nil {
+		return nil, "", err
+	}
+	info, err := fs.Stat(v.fsys, p)
+	if err != nil {
+		return nil, "", err
+	}
+	front, body, _ := entry.SplitFrontmatter(data)
+	meta := map[string]any{}
+	if err := yaml.Unmarshal(front, &meta); err != nil {
+		return nil, "frontmatter: " + err.Error(), nil
+	}
+	stem := strings.TrimSuffix(path.Base(p), path.Ext(p))
+	n := &Note{Key: "obsidian:" + p, Title: stem, Date: info.ModTime()}
+	if h := entry.Heading(body); h != "" && len(stripHeading(body, h)) < len(body) {
+		n.Title, body = h, stripHeading(body, h)
+	}
+	if t, ok := meta["title"].(string); ok && t != "" {
+		n.Title = t
+	}
+	if strings.TrimSpace(string(body)) == "" && len(meta) == 0 {
+		return nil, "empty", nil
+	}
+	n.Category, n.Tags = v.category(p, opts)
+	for _, k := range []string{"tags", "tag"} {
+		for _, t := range stringList(meta[k]) {
+			n.Tags = append(n.Tags, strings.Split(t, ",")...)
+		}
+	}
+	for _, k := range []string{"created", "date", "created_at"} {
+		if t, ok := parseDate(meta[k]); ok {
+			n.Date = t
+			break
+		}
+	}
+	for _, k := range []string{"updated", "modified", "updated_at", "last_modified"} {
+		if t, ok := parseDate(meta[k]); ok {
+			n.Updated = t
+			break
+		}
+	}
+	if aliases := stringList(meta["aliases"]); len(aliases) > 0 {
+		n.Fields = append(n.Fields, MetaField{"aliases", aliases})
+	} else if aliases := stringList(meta["alias"]); len(aliases) > 0 {
+		n.Fields = append(n.Fields, MetaField{"aliases", aliases})
+	}
+	for _, k := range sortedKeys(meta) {
+		switch k {
+		case "title", "tags", "tag", "aliases", "alias", "created", "date", "created_at", "updated",
+			"modified", "updated_at", "last_modified", "category", "slug", "cssclass", "cssclasses",
+			"publish", "permalink", Field, SourceField:
+			continue
+		}
+		n.Fields = append(n.Fields, MetaField{k, meta[k]})
+	}
+	if u, ok := meta[SourceField].(string); ok {
+		n.URL = u
+	}
+
+	r := newRefs(n, func(p string) ([]byte, error) { return fs.ReadFile(v.fsys, p) })
+	text, tags := v.body(p, string(body), r)
+	n.Tags = cleanTags(append(n.Tags, tags...))
+	r.finish(text)
+	return n, "", nil
+}
+
+// category returns the category of the note at p and the tags its
+// deeper folders give it.
+func (v *vault) category(p string, opts ObsidianOptions) (string, []string) {
+	dir := path.Dir(p)
+	best, category := "", ""
+	for folder, c := range opts.Map {
+		f := strings.Trim(folder, "/")
+		if (dir == f || strings.HasPrefix(dir, f+"/")) && len(f) > len(best) {
+			best, category = f, c
+		}
+	}
+	if best != "" {
+		return category, nil
+	}
+	if dir == "." {
+		if opts.Category != "" {
+			return opts.Category, nil
+		}
+		return DefaultCategory, nil
+	}
+	parts := strings.Split(dir, "/")
+	return Category(parts[0]), parts[1:]
+}
+
+// body converts the markdown of the note at from: callouts, embeds,
+// wiki links, links to other notes and attachments, and comments outside
+// code. It returns the inline #tags found.
+func (v *vault) body(from, src string, r *refs) (string, []string) {
+	lines := strings.Split(src, "\n")
+	code := codeLines(src)
+	var tags []string
+	inComment := false
+	var out []string
+	for i, line := range lines {
+		if code[i] {
+			out = append(out, line)
+			continue
+		}
+		// Comments, %% like this %%, are private to Obsidian. Lines left
+		// empty by removing them are dropped.
+		had := strings.TrimSpace(line) != ""
+		if inComment {
+			end := strings.Index(line, "%%")
+			if end < 0 {
+				continue
+			}
+			line, inComment = line[end+2:], false
+		}
+		line = commentRE.ReplaceAllString(line, "")
+		if j := strings.Index(line, "%%"); j >= 0 {
+			line, inComment = line[:j], true
+		}
+		if had && strings.TrimSpace(line) == "" {
+			continue
+		}
+		if m := obsidianCalloutRE.FindStringSubmatch(line); m != nil {
+			typ, ok := calloutTypes[strings.ToLower(m[2])]
+			if !ok {
+				typ = "NOTE"
+			}
+			title := strings.TrimSpace(m[3])
+			if title == "" && !ok {
+				title = m[2]
+			}
+			line = strings.TrimRight(m[1]+"[!"+typ+"] "+title, " ")
+		}
+		standalone := embedRE.MatchString(line) && strings.TrimSpace(embedRE.ReplaceAllString(line, "")) == ""
+		line = outsideCode(line, func(text string) string {
+			text = replaceMatches(embedRE, text, func(m []string) string {
+				p, ok := v.resolve(from, m[1])
+				switch {
+				case !ok:
+					return m[0]
+				case v.files[p]:
+					if ref := r.attach(p); ref != "" {
+						alt := strings.TrimSuffix(path.Base(p), path.Ext(p))
+						return "![" + alt + "](" + ref + ")"
+					}
+					return m[0]
+				}
+				return r.link(link{key: "obsidian:" + p, name: m[1], heading: strings.TrimPrefix(m[2], "#"), label: m[4], include: standalone})
+			})
+			text = replaceMatches(wikiRE, text, func(m []string) string {
+				p, ok := v.resolve(from, m[1])
+				switch {
+				case !ok:
+					return m[0]
+				case v.files[p]:
+					if ref := r.attach(p); ref != "" {
+						label := m[4]
+						if label == "" {
+							label = path.Base(p)
+						}
+						return "[" + label + "](" + ref + ")"
+					}
+					return m[0]
+				}
+				return r.link(link{key: "obsidian:" + p, name: m[1], heading: strings.TrimPrefix(m[2], "#"), label: m[4]})
+			})
+			text = replaceMatches(mdLinkRE, text, func(m []string) string {
+				dest := m[3]
+				if strings.Contains(dest, ":") || strings.HasPrefix(dest, "#") {
+					return m[0]
+				}
+				target, frag, _ := strings.Cut(dest, "#")
+				if u, err := url.PathUnescape(target); err == nil {
+					target = u
+				}
+				p, ok := v.resolve(from, target)
+				switch {
+				case !ok:
+					return m[0]
+				case v.files[p]:
+					if ref := r.attach(p); ref != "" {
+						return m[1] + "[" + m[2] + "](" + ref + ")"
+					}
+					return m[0]
+				}
+				name := strings.TrimSuffix(path.Base(p), ".md")
+				label := m[2]
+				if label == "" {
+					label = name
+				}
+				return r.link(link{key: "obsidian:" + p, name: name, heading: frag, label: label})
+			})
+			for _, m := range inlineTag.FindAllStringSubmatch(text, -1) {
+				tags = append(tags, m[1])
+			}
+			return text
+		})
+		out = append(out, line)
+	}
+	return strings.Join(out, "\n"), tags
+}
+
+// stringList reads a frontmatter value written as a list or as a
+// comma-separated string.
+func stringList(v any) []string {
+	switch v := v.(type) {
+	case string:
+		var out []string
+		for _, s := range strings.Split(v, ",") {
+			for _, f := range strings.Fields(s) {
+				out = append(out, f)
+			}
+		}
+		return out
+	case []any:
+		var out []string
+		for _, x := range v {
+			if s := strings.TrimSpace(fmt.Sprint(x)); s != "" && x != nil {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+// dateLayouts are the date formats found in notes' frontmatter.
+var dateLayouts = []string{
+	entry.DateLayout, "2006-01-02 15:04", "2006-01-02 15:04:05", "2006-01-02T15:04", time.RFC3339,
+	"2006-01-02T15:04:05", "2006/01/02", "January 2, 2006", "January 2, 2006 3:04 PM", "Jan 2, 2006",
+}
+
+// parseDate reads a frontmatter date.
+func parseDate(v any) (time.Time, bool) {
+	switch v := v.(type) {
+	case time.Time:
+		return v, true
+	case string:
+		for _, l := range dateLayouts {
+			if t, err := time.ParseInLocation(l, strings.TrimSpace(v), time.Local); err == nil {
+				return t, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// cleanTags normalizes tags, dropping empty and repeated ones.
+func cleanTags(tags []string) []string {
+	seen := map[string]bool{}
+	var out []string
+	for _, t := range tags {
+		if t = Tag(t); t != "" && !seen[t] {
+			seen[t] = true
+			out = append(out, t)
+		}
+	}
+	return out
+}
@@ -0,0 +1,93 @@
+package importer
+
+import (
+	"slices"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestObsidian(t *testing.T) {
+	vault := fstest.MapFS{
+		"Go/Slices.md":            {Data: []byte("---\ntags: [go, Data Structures]\ncreated: 2024-03-01\naliases: arrays\nrating: 5\ncssclass: wide\n---\n# Slices share arrays\n\n> [!tip]- Remember\n> Copy first.\n\n> [!custom]\n> Odd.\n\nSee [[Maps#Iteration order|order]], [[Maps]], [[Nowhere]] and [rebase](../Git/Rebase.md).\n\n![[Maps#Iteration order]]\n\n![[diagram.png]] and [[notes.pdf|the PDF]] #pitfall %%secret%%\n\n%%\nhidden\n%%\n\n```go\n// [[not a link]] #notatag %%kept%%\n```\n\n`[[code]]`\n")},
+		"Go/Maps.md":              {Data: []byte("# Maps\n\n## Iteration order\n\nRandom.\n")},
+		"Go/deep/Chan.md":         {Data: []byte("Channels.\n")},
+		"Git/Rebase.md":           {Data: []byte("---\ntitle: Rebase onto\nupdated: 2024-05-01\n---\nRebase.\n"), ModTime: time.Date(2024, 1, 2, 12, 0, 0, 0, time.UTC)},
+		"Top.md":                  {Data: []byte("Top note.\n")},
+		"Empty.md":                {Data: []byte("\n")},
+		"Bad.md":                  {Data: []byte("---\n: [\n---\nBody.\n")},
+		"Board.canvas":            {Data: []byte("{}")},
+		"Sketch.excalidraw.md":    {Data: []byte("drawing")},
+		"attachments/diagram.png": {Data: []byte("png")},
+		"Go/notes.pdf":            {Data: []byte("pdf")},
+		".obsidian/app.json":      {Data: []byte("{}")},
+	}
+	ns, skipped, err := Obsidian(vault, ObsidianOptions{Map: map[string]string{"Git": "vcs"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	for _, n := range ns {
+		keys = append(keys, n.Key+" "+n.Category+" "+n.Title)
+	}
+	want := []string{
+		"obsidian:Git/Rebase.md vcs Rebase onto",
+		"obsidian:Go/Maps.md go Maps",
+		"obsidian:Go/Slices.md go Slices share arrays",
+		"obsidian:Go/deep/Chan.md go Chan",
+		"obsidian:Top.md inbox Top",
+	}
+	if !slices.Equal(keys, want) {
+		t.Errorf("notes = %q, want %q", keys, want)
+	}
+	var reasons []string
+	for _, s := range skipped {
+		reasons = append(reasons, s.Source+": "+s.Reason)
+	}
+	if len(reasons) != 4 || reasons[0] != "Board.canvas: canvas" || reasons[1] != "Sketch.excalidraw.md: Excalidraw drawing" || reasons[3] != "Empty.md: empty" {
+		t.Errorf("skipped = %q", reasons)
+	}
+	if tags := ns[3].Tags; !slices.Equal(tags, []string{"deep"}) {
+		t.Errorf("tags of Go/deep/Chan.md = %q, want the folder below the category", tags)
+	}
+
+	tree := newTree(t, nil)
+	if err := Assign(tree, ns); err != nil {
+		t.Fatal(err)
+	}
+	var res Result
+	if err := Write(tree, ns, &res, false); err != nil {
+		t.Fatal(err)
+	}
+	wantSlices := "---\ntitle: Slices share arrays\ndate: 2024-03-01\ncategory: go\nslug: slices-share-arrays\n" +
+		"tags: [data-structures, go, pitfall]\naliases: [arrays]\nrating: 5\nimported: obsidian:Go/Slices.md\n---\n\n" +
+		"# Slices share arrays\n\n" +
+		"> [!TIP] Remember\n> Copy first.\n\n" +
+		"> [!NOTE] custom\n> Odd.\n\n" +
+		"See [order](maps.md#iteration-order), [[go/maps]], [[Nowhere]] and [[vcs/rebase_onto|rebase]].\n\n" +
+		"{{include \"go/maps#Iteration order\"}}\n\n" +
+		"![diagram](assets/slices_share_arrays/diagram.png) and [the PDF](assets/slices_share_arrays/notes.pdf) #pitfall \n\n\n" +
+		"```go\n// [[not a link]] #notatag %%kept%%\n```\n\n`[[code]]`\n"
+	if got := readFile(t, tree, "go/slices_share_arrays.md"); got != wantSlices {
+		t.Errorf("go/slices_share_arrays.md =\n%s\nwant\n%s", got, wantSlices)
+	}
+	if got := readFile(t, tree, "vcs/rebase_onto.md"); got != "---\ntitle: Rebase onto\ndate: 2024-01-02\ncategory: vcs\nslug: rebase-onto\ntags: []\nupdated: 2024-05-01\nimported: obsidian:Git/Rebase.md\n---\n\n# Rebase onto\n\nRebase.\n" {
+		t.Errorf("vcs/rebase_onto.md =\n%s", got)
+	}
+
+	// A second import finds every note already there.
+	again, _, err := Obsidian(vault, ObsidianOptions{Map: map[string]string{"Git": "vcs"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Assign(tree, again); err != nil {
+		t.Fatal(err)
+	}
+	res = Result{}
+	if err := Write(tree, again, &res, false); err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Created) != 0 || len(res.Existing) != 5 {
+		t.Errorf("second import = %+v", res)
+	}
+}
@@ -0,0 +1,184 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// ReadLaterOptions configures Pocket and Instapaper.
+type ReadLaterOptions struct {
+	// Category holds the saved articles, except those in Instapaper
+	// folders. Defaults to DefaultCategory.
+	Category string
+}
+
+// ReadField is the frontmatter field marking an article read.
+const ReadField = "read"
+
+// Pocket converts Pocket's export, the HTML file of older exports or a CSV
+// file of newer ones, into stub entries tagged ReadingList with the saved
+// URL as source, dated when they were saved. Articles in the archive are
+// marked read.
+func Pocket(r io.Reader, opts ReadLaterOptions) ([]*Note, []Skipped, error) {
+	br := bufio.NewReader(r)
+	head, _ := br.Peek(512)
+	if bytes.HasPrefix(bytes.TrimSpace(bytes.TrimPrefix(head, []byte("\ufeff"))), []byte("<")) {
+		return pocketHTML(br, opts)
+	}
+	rows, err := readCSV(br)
+	if err != nil {
+		return nil, nil, err
+	}
+	s := newSaved(opts)
+	for _, row := range rows {
+		s.add(row["url"], row["title"], unixDate(row["time_added"]), strings.Split(row["tags"], "|"), "",
+			row["status"] == "archive")
+	}
+	return s.out, s.skipped, nil
+}
+
+// pocketHTML reads an HTML export: a list of links under "Unread" and
+// another under "Read Archive".
+func pocketHTML(r io.Reader, opts ReadLaterOptions) ([]*Note, []Skipped, error) {
+	doc, err := html.Parse(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	s := newSaved(opts)
+	archived := false
+	walkElems(doc, func(n *html.Node) bool {
+		switch n.DataAtom {
+		case atom.H1:
+			archived = strings.Contains(strings.ToLower(rawText(n)), "archive")
+			return false
+		case atom.A:
+			s.add(attr(n, "href"), strings.Join(strings.Fields(rawText(n)), " "), unixDate(attr(n, "time_added")),
+				strings.Split(attr(n, "tags"), ","), "", archived)
+			return false
+		}
+		return true
+	})
+	return s.out, s.skipped, nil
+}
+
+// Instapaper converts Instapaper's CSV export into stub entries tagged
+// ReadingList with the saved URL as source and the selected text, if any,
+// quoted. Articles in folders of the user's go in the category named after
+// the folder; archived ones are marked read and starred ones tagged
+// starred.
+func Instapaper(r io.Reader, opts ReadLaterOptions) ([]*Note, []Skipped, error) {
+	rows, err := readCSV(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	s := newSaved(opts)
+	for _, row := range rows {
+		var tags []string
+		if t := row["tags"]; strings.HasPrefix(t, "[") {
+			_ = json.Unmarshal([]byte(t), &tags)
+		} else if t != "" {
+			tags = strings.Split(t, ",")
+		}
+		folder := row["folder"]
+		switch strings.ToLower(folder) {
+		case "unread", "":
+			folder = ""
+		case "archive":
+			folder = ""
+			row["status"] = "archive"
+		case "starred":
+			folder = ""
+			tags = append(tags, "starred")
+		}
+		n := s.add(row["url"], row["title"], unixDate(row["timestamp"]), tags, row["selection"], row["status"] == "archive")
+		if n != nil && folder != "" {
+			n.Category = Category(folder)
+		}
+	}
+	return s.out, s.skipped, nil
+}
+
+// saved collects the articles of a read-later export.
+type saved struct {
+	opts    ReadLaterOptions
+	out     []*Note
+	skipped []Skipped
+	seen    map[string]bool
+}
+
+func newSaved(opts ReadLaterOptions) *saved {
+	if opts.Category == "" {
+		opts.Category = DefaultCategory
+	}
+	return &saved{opts: opts, seen: map[string]bool{}}
+}
+
+// add converts a saved article and returns it, or nil if it is skipped.
+func (s *saved) add(rawURL, title string, date time.Time, tags []string, selection string, read bool) *Note {
+	rawURL = strings.TrimSpace(rawURL)
+	u, err := url.Parse(rawURL)
+	switch {
+	case err != nil || u.Scheme != "http" && u.Scheme != "https" || u.Host == "":
+		s.skipped = append(s.skipped, Skipped{rawURL, "not a web page"})
+		return nil
+	case s.seen[rawURL]:
+		s.skipped = append(s.skipped, Skipped{rawURL, "repeated"})
+		return nil
+	}
+	s.seen[rawURL] = true
+	title = strings.TrimSpace(title)
+	if title == "" || title == rawURL {
+		title = u.Host + strings.TrimSuffix(u.Path, "/")
+	}
+	n := &Note{
+		Title:    title,
+		Category: s.opts.Category,
+		Tags:     cleanTags(append([]string{ReadingList}, tags...)),
+		Date:     date,
+		URL:      rawURL,
+	}
+	if read {
+		n.Fields = append(n.Fields, MetaField{ReadField, true})
+	}
+	if sel := strings.TrimSpace(selection); sel != "" {
+		n.Body = quote(sel) + "\n\n"
+	}
+	n.Body += "Source: <" + rawURL + ">"
+	s.out = append(s.out, n)
+	return n
+}
+
+// readCSV reads a CSV file with a header into rows keyed by the lowercased
+// column names.
+func readCSV(r io.Reader) ([]map[string]string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	cr := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\ufeff"))))
+	cr.FieldsPerRecord, cr.LazyQuotes = -1, true
+	records, err := cr.ReadAll()
+	if err != nil || len(records) == 0 {
+		return nil, err
+	}
+	var rows []map[string]string
+	for _, rec := range records[1:] {
+		row := map[string]string{}
+		for i, v := range rec {
+			if i < len(records[0]) {
+				row[strings.ToLower(strings.TrimSpace(records[0][i]))] = strings.TrimSpace(v)
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
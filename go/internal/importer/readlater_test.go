@@ -0,0 +1,88 @@
+package importer
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// summary describes notes as category, title, URL, tags and further
+// fields.
+func summary(ns []*Note) []string {
+	var out []string
+	for _, n := range ns {
+		s := n.Category + " " + n.Title + " " + n.URL + " " + strings.Join(n.Tags, ",")
+		for _, f := range n.Fields {
+			s += " " + f.Key
+		}
+		out = append(out, s)
+	}
+	return out
+}
+
+func TestPocket(t *testing.T) {
+	csv := "\ufefftitle,url,time_added,tags,status\n" +
+		"Slices,https://go.dev/blog/slices,1709251200,go|Data Structures,unread\n" +
+		",https://example.com/essay/,1709251200,,archive\n" +
+		"Again,https://go.dev/blog/slices,1709251200,,unread\n" +
+		"Mail,mailto:me@example.com,,,unread\n"
+	ns, skipped, err := Pocket(strings.NewReader(csv), ReadLaterOptions{Category: "reading"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"reading Slices https://go.dev/blog/slices reading-list,go,data-structures",
+		"reading example.com/essay https://example.com/essay/ reading-list read",
+	}
+	if got := summary(ns); !slices.Equal(got, want) {
+		t.Errorf("Pocket(csv) = %q, want %q", got, want)
+	}
+	if want := []Skipped{{"https://go.dev/blog/slices", "repeated"}, {"mailto:me@example.com", "not a web page"}}; !slices.Equal(skipped, want) {
+		t.Errorf("skipped = %v, want %v", skipped, want)
+	}
+	if !ns[0].Date.Equal(time.Unix(1709251200, 0)) || ns[0].Body != "Source: <https://go.dev/blog/slices>" {
+		t.Errorf("note = %+v", ns[0])
+	}
+
+	page := `<!DOCTYPE html><html><body><h1>Unread</h1><ul>
+<li><a href="https://go.dev/blog/slices" time_added="1709251200" tags="go,slices">Slices</a></li>
+</ul><h1>Read Archive</h1><ul>
+<li><a href="https://example.com/essay" time_added="1709251200" tags="">Essay</a></li>
+</ul></body></html>`
+	ns, _, err = Pocket(strings.NewReader(page), ReadLaterOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []string{
+		"inbox Slices https://go.dev/blog/slices reading-list,go,slices",
+		"inbox Essay https://example.com/essay reading-list read",
+	}
+	if got := summary(ns); !slices.Equal(got, want) {
+		t.Errorf("Pocket(html) = %q, want %q", got, want)
+	}
+}
+
+func TestInstapaper(t *testing.T) {
+	csv := "URL,Title,Selection,Folder,Timestamp,Tags\n" +
+		"https://go.dev/blog/slices,Slices,\"Slices share\narrays.\",Unread,1709251200,\"[\"\"go\"\"]\"\n" +
+		"https://example.com/essay,Essay,,Archive,1709251200,\n" +
+		"https://example.com/star,Star,,Starred,1709251200,\"a,b\"\n" +
+		"https://example.com/db,Indexes,,Databases,1709251200,\n"
+	ns, skipped, err := Instapaper(strings.NewReader(csv), ReadLaterOptions{Category: "reading"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"reading Slices https://go.dev/blog/slices reading-list,go",
+		"reading Essay https://example.com/essay reading-list read",
+		"reading Star https://example.com/star reading-list,a,b,starred",
+		"databases Indexes https://example.com/db reading-list",
+	}
+	if got := summary(ns); !slices.Equal(got, want) || len(skipped) != 0 {
+		t.Errorf("Instapaper = %q, skipped %v\nwant %q", got, skipped, want)
+	}
+	if want := "> Slices share\n> arrays.\n\nSource: <https://go.dev/blog/slices>"; ns[0].Body != want {
+		t.Errorf("body = %q, want %q", ns[0].Body, want)
+	}
+}
@@ -0,0 +1,234 @@
+// Package include expands transclusion directives, which embed another
+// entry, or one section of it, in an entry's body:
+//
+//	{{include "go/slices#pitfalls"}}
+//
+// A directive stands on a line of its own outside code. Its reference is
+// resolved like the target of a [[wiki link]]; the part after "#" names a
+// heading by its text or slug, and the section runs from below that heading
+// to the next heading of the same or a higher level. A whole entry is
+// included without its title heading. Included entries may include others,
+// but not, directly or not, themselves.
+package include
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/canhta/til/go/internal/links"
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+var (
+	directiveRE = regexp.MustCompile(`^ {0,3}\{\{\s*include\s+"([^"]+)"\s*\}\}\s*$`)
+	headingRE   = regexp.MustCompile(`^ {0,3}(#{1,6})[ \t]+(.*?)[ \t#]*$`)
+)
+
+var (
+	// ErrCycle is reported for an entry that includes itself.
+	ErrCycle = errors.New("include cycle")
+	// ErrNotFound is reported for references naming no entry or section.
+	ErrNotFound = errors.New("not found")
+)
+
+// Error is a directive that could not be expanded and was left as written.
+type Error struct {
+	// Path and Line locate the directive in its entry file.
+	Path string
+	Line int
+	Ref  string
+	Err  error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s:%d: include %q: %v", e.Path, e.Line, e.Ref, e.Err)
+}
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// Result is an expanded body.
+type Result struct {
+	Body []byte
+	// Uses lists the paths of the entries included, directly or through
+	// another included entry, in order of first use.
+	Uses []string
+	// Errors lists the directives of the entry that could not be
+	// expanded. Those of included entries are reported when expanding
+	// them, except for cycles, which fail the directive leading into them.
+	Errors []*Error
+}
+
+// Directive is an include directive found in a body.
+type Directive struct {
+	Ref string
+	// Line is the 1-based line within the body.
+	Line int
+}
+
+// Parse returns the include directives in body, ignoring code blocks.
+func Parse(body []byte) []Directive {
+	lines := strings.Split(string(body), "\n")
+	code := codeLines(body)
+	var out []Directive
+	for i, line := range lines {
+		if m := directiveRE.FindStringSubmatch(strings.TrimSuffix(line, "\r")); m != nil && !code[i] {
+			out = append(out, Directive{Ref: m[1], Line: i + 1})
+		}
+	}
+	return out
+}
+
+// Expand returns the body of e with its directives replaced by the content
+// they name, resolved against ix. Relative markdown links in included
+// content are rewritten to work from e.
+func Expand(e *entry.Entry, ix *links.Index) *Result {
+	x := &expander{ix: ix, seen: map[string]bool{}}
+	r := &Result{}
+	r.Body = x.expand(e, e.Body, []string{e.Path})
+	r.Uses = x.uses
+	for _, err := range x.errs {
+		if err.Path == e.Path {
+			r.Errors = append(r.Errors, err)
+		}
+	}
+	return r
+}
+
+type expander struct {
+	ix   *links.Index
+	uses []string
+	seen map[string]bool
+	errs []*Error
+}
+
+// expand expands the directives of body, which comes from e. stack holds
+// the entries being expanded.
+func (x *expander) expand(e *entry.Entry, body []byte, stack []string) []byte {
+	directives := Parse(body)
+	if len(directives) == 0 {
+		return body
+	}
+	lines := strings.SplitAfter(string(body), "\n")
+	for _, d := range directives {
+		content, err := x.include(e, d.Ref, stack)
+		if err != nil {
+			x.errs = append(x.errs, &Error{Path: e.Path, Line: e.FileLine(d.Line), Ref: d.Ref, Err: err})
+			continue
+		}
+		if !strings.HasSuffix(content, "\n") {
+			content += "\n"
+		}
+		lines[d.Line-1] = content
+	}
+	return []byte(strings.Join(lines, ""))
+}
+
+// include returns the content ref names for the entry from, with its links
+// rebased to work there.
+func (x *expander) include(from *entry.Entry, ref string, stack []string) (string, error) {
+	target, name, _ := strings.Cut(ref, "#")
+	e, res := x.ix.Resolve(from.Path, links.Link{Target: target, Wiki: true})
+	switch res {
+	case links.Missing:
+		return "", ErrNotFound
+	case links.Ambiguous:
+		return "", fmt.Errorf("%q names several entries", target)
+	}
+	for i, p := range stack {
+		if p == e.Path {
+			return "", fmt.Errorf("%w: %s", ErrCycle, strings.Join(append(stack[i:], e.Path), " -> "))
+		}
+	}
+	body := stripTitle(e.Body)
+	if name != "" {
+		var ok bool
+		if body, ok = Section(e.Body, name); !ok {
+			return "", fmt.Errorf("section %q: %w", name, ErrNotFound)
+		}
+	}
+	if !x.seen[e.Path] {
+		x.seen[e.Path] = true
+		x.uses = append(x.uses, e.Path)
+	}
+	n := len(x.errs)
+	body = x.expand(e, body, append(stack, e.Path))
+	// A cycle through e fails the directive including it.
+	for _, err := range x.errs[n:] {
+		if errors.Is(err, ErrCycle) {
+			x.errs = x.errs[:n]
+			return "", err.Err
+		}
+	}
+	return string(rebase(body, e.Path, from.Path)), nil
+}
+
+// Section returns the content under the heading of body whose text or
+// slug is name, up to the next heading of the same or a higher level.
+func Section(body []byte, name string) ([]byte, bool) {
+	lines := strings.SplitAfter(string(body), "\n")
+	code := codeLines(body)
+	want := entry.Slugify(name)
+	start, level := -1, 0
+	for i, line := range lines {
+		if code[i] {
+			continue
+		}
+		m := headingRE.FindStringSubmatch(strings.TrimRight(line, "\r\n"))
+		if m == nil {
+			continue
+		}
+		if start >= 0 && len(m[1]) <= level {
+			return []byte(strings.Join(lines[start:i], "")), true
+		}
+		if start < 0 && (m[2] == name || entry.Slugify(m[2]) == want) {
+			start, level = i+1, len(m[1])
+		}
+	}
+	if start < 0 {
+		return nil, false
+	}
+	return []byte(strings.Join(lines[start:], "")), true
+}
+
+// codeLines reports which 0-based lines of body belong to fenced code.
+func codeLines(body []byte) map[int]bool {
+	code := map[int]bool{}
+	for _, b := range entry.CodeBlocks(body) {
+		for i := b.Line - 1; i < b.EndLine; i++ {
+			code[i] = true
+		}
+	}
+	return code
+}
+
+// stripTitle removes the level-one heading naming an entry from the top of
+// its body.
+func stripTitle(body []byte) []byte {
+	rest := strings.TrimLeft(string(body), " \t\r\n")
+	if !strings.HasPrefix(rest, "# ") {
+		return body
+	}
+	_, after, _ := strings.Cut(rest, "\n")
+	return []byte(after)
+}
+
+// rebase rewrites the relative markdown links of body, written in the
+// entry at from, to work from the entry at to.
+func rebase(body []byte, from, to string) []byte {
+	if path.Dir(from) == path.Dir(to) {
+		return body
+	}
+	return links.Rewrite(body, func(l links.Link) (string, bool) {
+		if l.Wiki {
+			return "", false
+		}
+		dest := links.Relative(to, path.Clean(path.Join(path.Dir(from), l.Target)))
+		if l.Fragment != "" {
+			dest += "#" + l.Fragment
+		}
+		return "[" + l.Label + "](" + dest + ")", true
+	})
+}
@@ -0,0 +1,94 @@
+package include
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/canhta/til/go/internal/links"
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+func TestParse(t *testing.T) {
+	body := "{{include \"a\"}}\n   {{ include  \"b#sec\" }}\n    {{include \"indented code\"}}\ntext {{include \"c\"}}\n```\n{{include \"fenced\"}}\n```\n{{include \"d\"}}\r\n"
+	want := []Directive{{"a", 1}, {"b#sec", 2}, {"d", 8}}
+	if got := Parse([]byte(body)); !slices.Equal(got, want) {
+		t.Errorf("Parse = %v, want %v", got, want)
+	}
+}
+
+func TestSection(t *testing.T) {
+	body := "# Title\n\nintro\n\n## Pitfalls\n\nAliasing.\n\n### Deeper\n\nStill in.\n\n```sh\n## not a heading\n```\n\n## Next\n\nOut.\n"
+	tests := []struct {
+		name, want string
+		ok         bool
+	}{
+		{"Pitfalls", "\nAliasing.\n\n### Deeper\n\nStill in.\n\n```sh\n## not a heading\n```\n\n", true},
+		{"pitfalls", "\nAliasing.\n\n### Deeper\n\nStill in.\n\n```sh\n## not a heading\n```\n\n", true},
+		{"deeper", "\nStill in.\n\n```sh\n## not a heading\n```\n\n", true},
+		{"Next", "\nOut.\n", true},
+		{"not a heading", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := Section([]byte(body), tt.name)
+		if string(got) != tt.want || ok != tt.ok {
+			t.Errorf("Section(%q) = %q, %v, want %q, %v", tt.name, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func index(t *testing.T, files map[string]string) (*links.Index, map[string]*entry.Entry) {
+	t.Helper()
+	byPath := map[string]*entry.Entry{}
+	var all []*entry.Entry
+	for p, data := range files {
+		e, err := entry.Parse(p, []byte(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		byPath[p] = e
+		all = append(all, e)
+	}
+	return links.NewIndex(all), byPath
+}
+
+func TestExpand(t *testing.T) {
+	ix, entries := index(t, map[string]string{
+		"go/slices.md":  "---\ntitle: Slices\n---\n# Slices\n\nWhole.\n\n## Pitfalls\n\nSee [maps](maps.md#order).\n{{include \"go/tail\"}}\n\n## Other\n",
+		"go/tail.md":    "# Tail\n\nThe tail.\n",
+		"git/notes.md":  "---\ntitle: Notes\n---\n\nStart.\n{{include \"go/slices#pitfalls\"}}\nMiddle.\n{{include \"go/tail\"}}\n{{include \"nowhere\"}}\n{{include \"go/tail#none\"}}\n",
+		"git/loop.md":   "{{include \"git/back\"}}\n",
+		"git/back.md":   "{{include \"git/loop\"}}\n",
+		"git/direct.md": "{{include \"git/direct\"}}\n",
+	})
+
+	r := Expand(entries["git/notes.md"], ix)
+	want := "\nStart.\n\nSee [maps](../go/maps.md#order).\n\nThe tail.\n\nMiddle.\n\nThe tail.\n{{include \"nowhere\"}}\n{{include \"go/tail#none\"}}\n"
+	if string(r.Body) != want {
+		t.Errorf("Expand(git/notes) =\n%q\nwant\n%q", r.Body, want)
+	}
+	if !slices.Equal(r.Uses, []string{"go/slices.md", "go/tail.md"}) {
+		t.Errorf("Uses = %q", r.Uses)
+	}
+	if len(r.Errors) != 2 || r.Errors[0].Line != 9 || !errors.Is(r.Errors[0], ErrNotFound) || !errors.Is(r.Errors[1], ErrNotFound) {
+		t.Fatalf("Errors = %v", r.Errors)
+	}
+	if got := r.Errors[1].Error(); got != `git/notes.md:10: include "go/tail#none": section "none": not found` {
+		t.Errorf("Errors[1] = %s", got)
+	}
+
+	// Within one category links are left as written.
+	if r := Expand(entries["go/slices.md"], ix); string(r.Body) != "# Slices\n\nWhole.\n\n## Pitfalls\n\nSee [maps](maps.md#order).\n\nThe tail.\n\n## Other\n" {
+		t.Errorf("Expand(go/slices) = %q", r.Body)
+	}
+
+	for _, p := range []string{"git/loop.md", "git/direct.md"} {
+		r := Expand(entries[p], ix)
+		if len(r.Errors) != 1 || !errors.Is(r.Errors[0], ErrCycle) || string(r.Body) != string(entries[p].Body) {
+			t.Errorf("Expand(%s) = %q, %v", p, r.Body, r.Errors)
+		}
+	}
+	if got := Expand(entries["git/loop.md"], ix).Errors[0].Error(); got != `git/loop.md:1: include "git/back": include cycle: git/loop.md -> git/back.md -> git/loop.md` {
+		t.Errorf("cycle error = %s", got)
+	}
+}
@@ -0,0 +1,136 @@
+// Package journal keeps a daily log alongside the topic entries: one file
+// per day in the journal directory, to which til log appends timestamped
+// bullets.
+//
+//	---
+//	title: Wednesday, October 14, 2026
+//	date: 2026-10-14
+//	---
+//
+//	- 09:30 found why the build was slow
+//	- 14:05 go test -count=1 skips the cache
+//	  and -run narrows it down
+//
+// Each bullet is an Item of its own to search and stats. Files are only
+// ever appended to, and the directory's .gitattributes merges them with
+// git's union driver, so logging on two machines cannot conflict.
+package journal
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/canhta/til/go/internal/notes"
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+// Dir is the directory of the journal inside the notes root, and so the
+// category of its files.
+const Dir = "journal"
+
+// TimeLayout formats the time starting each bullet.
+const TimeLayout = "15:04"
+
+// attributes is the .gitattributes written to Dir.
+const attributes = "# Journal files are append-only; keep both sides of a merge.\n*.md merge=union\n"
+
+var itemRE = regexp.MustCompile(`^- (\d\d:\d\d) (.*)$`)
+
+// Item is a bullet of a journal file.
+type Item struct {
+	Path string `json:"path"`
+	// Line is the 1-based line of the file the bullet starts on.
+	Line int       `json:"line"`
+	Time time.Time `json:"time"`
+	// Text is the bullet without its time, with continuation lines joined
+	// by newlines.
+	Text string `json:"text"`
+}
+
+// Is reports whether e is a journal file.
+func Is(e *entry.Entry) bool {
+	return e.Meta.Category == Dir
+}
+
+// File returns the path of the journal file of the day of t.
+func File(t time.Time) string {
+	return path.Join(Dir, t.Format(entry.DateLayout)+".md")
+}
+
+// Items returns the bullets of the journal file e, in order. Their day is
+// that of e's date, else of its file name; lines other than bullets and
+// their indented continuations are ignored.
+func Items(e *entry.Entry) []Item {
+	day := e.Meta.Date
+	if day.IsZero() {
+		day, _ = time.Parse(entry.DateLayout, e.Stem())
+	}
+	var items []Item
+	var cur *Item
+	for i, line := range strings.Split(string(e.Body), "\n") {
+		line = strings.TrimRight(line, " \t\r")
+		if m := itemRE.FindStringSubmatch(line); m != nil {
+			t, err := time.Parse(TimeLayout, m[1])
+			if err == nil {
+				items = append(items, Item{
+					Path: e.Path,
+					Line: e.FileLine(i + 1),
+					Time: time.Date(day.Year(), day.Month(), day.Day(), t.Hour(), t.Minute(), 0, 0, time.Local),
+					Text: m[2],
+				})
+				cur = &items[len(items)-1]
+				continue
+			}
+		}
+		if cont, ok := strings.CutPrefix(line, "  "); ok && cur != nil && strings.TrimSpace(cont) != "" {
+			cur.Text += "\n" + strings.TrimSpace(cont)
+			continue
+		}
+		cur = nil
+	}
+	return items
+}
+
+// Append adds msg as a bullet at time t to the journal file of its day,
+// creating the file, and the journal's .gitattributes, when missing. It
+// returns the paths of the files it created or changed, the journal file
+// first, and the item added.
+func Append(tree *notes.Tree, t time.Time, msg string) ([]string, Item, error) {
+	msg = strings.TrimSpace(msg)
+	if msg == "" {
+		return nil, Item{}, errors.New("empty message")
+	}
+	rel := File(t)
+	bullet := "- " + t.Format(TimeLayout) + " " + strings.ReplaceAll(msg, "\n", "\n  ") + "\n"
+	data, err := tree.Read(rel)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		data = []byte("---\ntitle: " + t.Format("Monday, January 2, 2006") + "\ndate: " + t.Format(entry.DateLayout) + "\n---\n\n")
+	case err != nil:
+		return nil, Item{}, err
+	case len(data) > 0 && !bytes.HasSuffix(data, []byte("\n")):
+		data = append(data, '\n')
+	}
+	line := bytes.Count(data, []byte("\n")) + 1
+	data = append(data, bullet...)
+	if err := tree.Write(rel, data); err != nil {
+		return nil, Item{}, err
+	}
+	changed := []string{rel}
+	attrs := path.Join(Dir, ".gitattributes")
+	if ok, err := tree.Exists(attrs); err != nil {
+		return nil, Item{}, err
+	} else if !ok {
+		if err := tree.Create(attrs, []byte(attributes)); err != nil {
+			return nil, Item{}, err
+		}
+		changed = append(changed, attrs)
+	}
+	item := Item{Path: rel, Line: line, Time: t.Truncate(time.Minute), Text: msg}
+	return changed, item, nil
+}
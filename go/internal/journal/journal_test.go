@@ -0,0 +1,96 @@
+package journal
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/canhta/til/go/internal/notes"
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+func TestItems(t *testing.T) {
+	e, err := entry.Parse("journal/2026-10-14.md", []byte("---\ntitle: Wednesday\ndate: 2026-10-13\n---\n\n"+
+		"- 09:30 found why the build was slow\n"+
+		"- 14:05 go test -count=1 skips the cache\n"+
+		"  and -run narrows it down\n"+
+		"\n"+
+		"  not a continuation after a blank line\n"+
+		"- 25:00 not a time\n"+
+		"- 16:00   spaced out  \n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := func(h, m int) time.Time { return time.Date(2026, 10, 13, h, m, 0, 0, time.Local) }
+	want := []Item{
+		{Path: e.Path, Line: 6, Time: at(9, 30), Text: "found why the build was slow"},
+		{Path: e.Path, Line: 7, Time: at(14, 5), Text: "go test -count=1 skips the cache\nand -run narrows it down"},
+		{Path: e.Path, Line: 12, Time: at(16, 0), Text: "  spaced out"},
+	}
+	if got := Items(e); !reflect.DeepEqual(got, want) {
+		t.Errorf("Items =\n%+v\nwant\n%+v", got, want)
+	}
+
+	// Without a date, the day is that of the file name.
+	e, err = entry.Parse("journal/2026-10-01.md", []byte("- 08:00 early\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := Items(e); len(got) != 1 || !got[0].Time.Equal(time.Date(2026, 10, 1, 8, 0, 0, 0, time.Local)) || got[0].Line != 1 {
+		t.Errorf("Items of an undated file = %+v", got)
+	}
+	if !Is(e) {
+		t.Error("Is(journal file) = false")
+	}
+	if e, _ := entry.Parse("go/slices.md", []byte("- 08:00 early\n")); Is(e) {
+		t.Error("Is(go/slices.md) = true")
+	}
+}
+
+func TestAppend(t *testing.T) {
+	tree := notes.Open(t.TempDir())
+	now := time.Date(2026, 10, 14, 9, 30, 45, 0, time.Local)
+	changed, item, err := Append(tree, now, "  found why\nthe build was slow ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"journal/2026-10-14.md", "journal/.gitattributes"}; !slices.Equal(changed, want) {
+		t.Errorf("changed = %q, want %q", changed, want)
+	}
+	if want := (Item{Path: "journal/2026-10-14.md", Line: 6, Time: now.Truncate(time.Minute), Text: "found why\nthe build was slow"}); item != want {
+		t.Errorf("item = %+v, want %+v", item, want)
+	}
+
+	// A file edited by hand without a final newline gets one first.
+	data, _ := tree.Read(File(now))
+	if err := tree.Write(File(now), data[:len(data)-1]); err != nil {
+		t.Fatal(err)
+	}
+	changed, item, err = Append(tree, now.Add(time.Hour), "later")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(changed, []string{"journal/2026-10-14.md"}) || item.Line != 8 {
+		t.Errorf("second Append = %q, %+v", changed, item)
+	}
+	data, _ = tree.Read(File(now))
+	want := "---\ntitle: Wednesday, October 14, 2026\ndate: 2026-10-14\n---\n\n- 09:30 found why\n  the build was slow\n- 10:30 later\n"
+	if string(data) != want {
+		t.Errorf("file =\n%s\nwant\n%s", data, want)
+	}
+	e, err := tree.Load(File(now))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if items := Items(e); len(items) != 2 || items[0].Text != "found why\nthe build was slow" || items[1].Line != 8 {
+		t.Errorf("Items after Append = %+v", items)
+	}
+	if attrs, _ := tree.Read("journal/.gitattributes"); string(attrs) != attributes {
+		t.Errorf(".gitattributes = %q", attrs)
+	}
+
+	if _, _, err := Append(tree, now, " \n"); err == nil {
+		t.Error("Append of an empty message succeeded")
+	}
+}
@@ -0,0 +1,98 @@
+// Package katex pre-renders TeX math to HTML with an external command,
+// KaTeX's CLI by default, caching the results.
+package katex
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/canhta/til/go/internal/fsutil"
+	"github.com/canhta/til/go/internal/notes"
+)
+
+// Dir is the cache directory inside the notes state directory.
+const Dir = "math"
+
+// Timeout bounds each run of the command.
+const Timeout = 30 * time.Second
+
+// ErrUnavailable is returned when no command is configured or found.
+var ErrUnavailable = errors.New("no math renderer available")
+
+// DefaultCommand is the KaTeX CLI invocation used when katex is on the PATH
+// and no command is configured. The command reads TeX on stdin and writes
+// HTML to stdout; an argument "{display}" expands to "--display-mode" for
+// display math and is dropped for inline math.
+var DefaultCommand = []string{"katex", "{display}"}
+
+// Renderer renders TeX to HTML.
+type Renderer struct {
+	command []string
+	cache   string
+}
+
+// New returns a Renderer for tree running command, or DefaultCommand when
+// command is empty and katex is installed. Its Render method returns
+// ErrUnavailable when there is no command to run.
+func New(tree *notes.Tree, command []string) *Renderer {
+	if len(command) == 0 {
+		if _, err := exec.LookPath(DefaultCommand[0]); err == nil {
+			command = DefaultCommand
+		}
+	}
+	return &Renderer{command: command, cache: tree.StatePath(Dir)}
+}
+
+// Command returns the command line r runs, nil when there is none.
+func (r *Renderer) Command() []string {
+	return r.command
+}
+
+// Render returns the HTML for tex, from the cache when the same math was
+// rendered before.
+func (r *Renderer) Render(ctx context.Context, tex []byte, display bool) ([]byte, error) {
+	if len(r.command) == 0 {
+		return nil, ErrUnavailable
+	}
+	var args []string
+	for _, a := range r.command {
+		if a == "{display}" {
+			if display {
+				args = append(args, "--display-mode")
+			}
+			continue
+		}
+		args = append(args, a)
+	}
+	sum := sha256.Sum256(append([]byte(strings.Join(args, "\x00")+"\x00"), tex...))
+	cached := filepath.Join(r.cache, hex.EncodeToString(sum[:])+".html")
+	if html, err := os.ReadFile(cached); err == nil {
+		return html, nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, Timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Stdin = bytes.NewReader(tex)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%s: %w: %s", args[0], err, msg)
+		}
+		return nil, fmt.Errorf("%s: %w", args[0], err)
+	}
+	html := bytes.TrimSpace(stdout.Bytes())
+	if err := fsutil.WriteFile(cached, html, 0o644); err != nil {
+		return nil, err
+	}
+	return html, nil
+}
@@ -0,0 +1,75 @@
+package katex
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/canhta/til/go/internal/notes"
+)
+
+// fakeKaTeX writes a script standing in for the katex CLI: it logs each run
+// to runs, echoes its arguments and input and fails on "bad".
+func fakeKaTeX(t *testing.T) (command []string, runs string) {
+	t.Helper()
+	dir := t.TempDir()
+	runs = filepath.Join(dir, "runs")
+	script := filepath.Join(dir, "katex.sh")
+	body := `echo run >> "` + runs + `"
+tex=$(cat)
+if [ "$tex" = bad ]; then echo "KaTeX parse error: Undefined control sequence" >&2; exit 1; fi
+echo "  <k args=\"$*\">$tex</k>"
+`
+	if err := os.WriteFile(script, []byte(body), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return []string{"sh", script, "{display}"}, runs
+}
+
+func TestRender(t *testing.T) {
+	command, runs := fakeKaTeX(t)
+	r := New(notes.Open(t.TempDir()), command)
+	ctx := context.Background()
+
+	tests := []struct {
+		tex     string
+		display bool
+		want    string
+	}{
+		{`\mu`, false, `<k args="">\mu</k>`},
+		{`\mu`, true, `<k args="--display-mode">\mu</k>`},
+		{`\mu`, false, `<k args="">\mu</k>`},
+	}
+	for _, tt := range tests {
+		got, err := r.Render(ctx, []byte(tt.tex), tt.display)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != tt.want {
+			t.Errorf("Render(%q, %v) = %q, want %q", tt.tex, tt.display, got, tt.want)
+		}
+	}
+	data, _ := os.ReadFile(runs)
+	if n := strings.Count(string(data), "run\n"); n != 2 {
+		t.Errorf("command ran %d times, want 2 with the cache", n)
+	}
+
+	_, err := r.Render(ctx, []byte("bad"), false)
+	if err == nil || !strings.Contains(err.Error(), "Undefined control sequence") {
+		t.Errorf("Render(bad) = %v, want the command's stderr", err)
+	}
+}
+
+func TestUnavailable(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+	r := New(notes.Open(t.TempDir()), nil)
+	if r.Command() != nil {
+		t.Errorf("Command() = %q without katex", r.Command())
+	}
+	if _, err := r.Render(context.Background(), []byte("x"), false); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("Render = %v, want ErrUnavailable", err)
+	}
+}
@@ -0,0 +1,285 @@
+// Package links finds the links between entries and builds the link graph.
+//
+// Entries link to each other with wiki-style [[target]] or [[target|label]]
+// references, where target is an entry ID, file stem, slug or title, and with
+// ordinary markdown links to the relative path of another entry file.
+package links
+
+import (
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+// Link is a reference from one entry to another.
+type Link struct {
+	// Target is the reference as written: the text inside [[...]] before
+	// any "|", or the destination of a markdown link.
+	Target string
+	// Label is the text after "|", or "".
+	Label string
+	// Line is the 1-based line within the body, and Start and End the byte
+	// offsets within it of the whole [[...]] reference or markdown link.
+	Line       int
+	Start, End int
+	// Wiki reports whether the link uses [[...]] syntax.
+	Wiki bool
+	// Fragment is the part of a markdown link after "#", if any.
+	Fragment string
+}
+
+var (
+	wikiRE = regexp.MustCompile(`\[\[([^\[\]\n|]+)(?:\|([^\[\]\n]+))?\]\]`)
+	mdRE   = regexp.MustCompile(`\]\(<?([^)\s>]+\.md)(?:#([^)\s>]*))?>?(?:\s+"[^"]*")?\)`)
+	codeRE = regexp.MustCompile("`+[^`]*`+")
+	// anyRE matches markdown links and images to any destination.
+	anyRE = regexp.MustCompile(`\[([^\[\]\n]*)\]\(<?([^)\s>#]*)(#[^)\s>]*)?>?(?:\s+"[^"]*")?\)`)
+)
+
+// Parse returns the links in body, ignoring code blocks and code spans.
+func Parse(body []byte) []Link {
+	lines := strings.Split(string(body), "\n")
+	for _, b := range entry.CodeBlocks(body) {
+		for i := b.Line - 1; i < b.EndLine && i < len(lines); i++ {
+			lines[i] = ""
+		}
+	}
+	var out []Link
+	for i, line := range lines {
+		line = codeRE.ReplaceAllStringFunc(line, func(s string) string { return strings.Repeat(" ", len(s)) })
+		for _, m := range wikiRE.FindAllStringSubmatchIndex(line, -1) {
+			l := Link{Target: strings.TrimSpace(line[m[2]:m[3]]), Line: i + 1, Start: m[0], End: m[1], Wiki: true}
+			if m[4] >= 0 {
+				l.Label = strings.TrimSpace(line[m[4]:m[5]])
+			}
+			out = append(out, l)
+		}
+		for _, m := range mdRE.FindAllStringSubmatchIndex(line, -1) {
+			target := line[m[2]:m[3]]
+			if strings.Contains(target, "://") {
+				continue
+			}
+			l := Link{Target: target, Line: i + 1, Start: linkStart(line, m[0]), End: m[1]}
+			if m[4] >= 0 {
+				l.Fragment = line[m[4]:m[5]]
+			}
+			out = append(out, l)
+		}
+	}
+	return out
+}
+
+// linkStart returns the offset of the "[" opening the text of the markdown
+// link whose "](" is at end in line, or end when there is none.
+func linkStart(line string, end int) int {
+	depth := 0
+	for i := end - 1; i >= 0; i-- {
+		switch line[i] {
+		case ']':
+			depth++
+		case '[':
+			if depth == 0 {
+				return i
+			}
+			depth--
+		}
+	}
+	return end
+}
+
+// Rewrite returns body with links outside code replaced by the text fn
+// returns for them; links for which it returns false are kept. For a wiki
+// link the Link passed has Wiki set. For a markdown link or image, Target
+// is its destination without any #fragment, Label its text and Fragment
+// the fragment; the replacement is for the bracketed text and destination,
+// so an image's leading "!" is kept. Absolute URLs are never passed to fn.
+func Rewrite(body []byte, fn func(Link) (string, bool)) []byte {
+	lines := strings.SplitAfter(string(body), "\n")
+	code := map[int]bool{}
+	for _, b := range entry.CodeBlocks(body) {
+		for i := b.Line - 1; i < b.EndLine && i < len(lines); i++ {
+			code[i] = true
+		}
+	}
+	var out strings.Builder
+	for i, line := range lines {
+		if code[i] {
+			out.WriteString(line)
+			continue
+		}
+		masked := codeRE.ReplaceAllStringFunc(line, func(s string) string { return strings.Repeat(" ", len(s)) })
+		type edit struct {
+			start, end int
+			text       string
+		}
+		var edits []edit
+		for _, m := range wikiRE.FindAllStringSubmatchIndex(masked, -1) {
+			l := Link{Target: strings.TrimSpace(line[m[2]:m[3]]), Line: i + 1, Wiki: true}
+			if m[4] >= 0 {
+				l.Label = strings.TrimSpace(line[m[4]:m[5]])
+			}
+			if text, ok := fn(l); ok {
+				edits = append(edits, edit{m[0], m[1], text})
+			}
+		}
+		for _, m := range anyRE.FindAllStringSubmatchIndex(masked, -1) {
+			dest := line[m[4]:m[5]]
+			if dest == "" || strings.Contains(dest, ":") || strings.HasPrefix(dest, "/") {
+				continue
+			}
+			l := Link{Target: dest, Label: line[m[2]:m[3]], Line: i + 1}
+			if m[6] >= 0 {
+				l.Fragment = line[m[6]+1 : m[7]]
+			}
+			if text, ok := fn(l); ok {
+				edits = append(edits, edit{m[0], m[1], text})
+			}
+		}
+		sort.Slice(edits, func(a, b int) bool { return edits[a].start < edits[b].start })
+		last := 0
+		for _, e := range edits {
+			if e.start < last {
+				continue
+			}
+			out.WriteString(line[last:e.start])
+			out.WriteString(e.text)
+			last = e.end
+		}
+		out.WriteString(line[last:])
+	}
+	return []byte(out.String())
+}
+
+// Relative returns the relative path linking the file at the
+// slash-separated path from to the file at to, both relative to the root.
+func Relative(from, to string) string {
+	up := ""
+	for d := path.Dir(from); d != "." && d != "/"; d = path.Dir(d) {
+		if rest, ok := strings.CutPrefix(to, d+"/"); ok {
+			return up + rest
+		}
+		up += "../"
+	}
+	return up + to
+}
+
+// Index resolves link targets to entries.
+type Index struct {
+	byPath map[string]*entry.Entry
+	// byKey maps each lookup key to the entries it names; more than one
+	// entry makes the key ambiguous.
+	byKey map[string][]*entry.Entry
+}
+
+// NewIndex indexes entries by ID, file stem, slug and slugified title.
+func NewIndex(entries []*entry.Entry) *Index {
+	ix := &Index{byPath: map[string]*entry.Entry{}, byKey: map[string][]*entry.Entry{}}
+	for _, e := range entries {
+		ix.byPath[e.Path] = e
+		seen := map[string]bool{}
+		for _, k := range []string{e.ID(), e.Stem(), e.Meta.Slug, entry.Slugify(e.Stem()), entry.Slugify(e.Meta.Title)} {
+			if k == "" || seen[k] {
+				continue
+			}
+			seen[k] = true
+			ix.byKey[k] = append(ix.byKey[k], e)
+		}
+	}
+	return ix
+}
+
+// Resolution is the outcome of resolving a link.
+type Resolution int
+
+const (
+	Resolved Resolution = iota
+	Missing
+	Ambiguous
+)
+
+// Resolve finds the entry a link from the entry at the slash-separated path
+// from points to. Markdown links are resolved relative to from; wiki
+// targets match an entry ID exactly first, then a stem, slug or title.
+// Among several matches, an entry in from's category wins.
+func (ix *Index) Resolve(from string, l Link) (*entry.Entry, Resolution) {
+	if !l.Wiki {
+		p := path.Clean(path.Join(path.Dir(from), l.Target))
+		if e, ok := ix.byPath[p]; ok {
+			return e, Resolved
+		}
+		return nil, Missing
+	}
+	t := strings.TrimSuffix(strings.Trim(l.Target, "/"), ".md")
+	if e, ok := ix.byPath[t+".md"]; ok {
+		return e, Resolved
+	}
+	matches := ix.byKey[t]
+	if len(matches) == 0 {
+		matches = ix.byKey[entry.Slugify(t)]
+	}
+	switch len(matches) {
+	case 0:
+		return nil, Missing
+	case 1:
+		return matches[0], Resolved
+	}
+	var local []*entry.Entry
+	for _, e := range matches {
+		if e.Meta.Category == entry.CategoryOf(from) {
+			local = append(local, e)
+		}
+	}
+	if len(local) == 1 {
+		return local[0], Resolved
+	}
+	return nil, Ambiguous
+}
+
+// Dangling is a link whose target does not resolve to a single entry.
+type Dangling struct {
+	From string
+	Link Link
+	// Ambiguous is set when the target names several entries.
+	Ambiguous bool
+}
+
+// Graph is the directed link graph between entries, keyed by entry path.
+type Graph struct {
+	// Out lists the entries each entry links to, in order of first link.
+	Out map[string][]string
+	// In lists the entries linking to each entry, sorted by path.
+	In       map[string][]string
+	Dangling []Dangling
+}
+
+// NewGraph builds the link graph of entries.
+func NewGraph(entries []*entry.Entry) *Graph {
+	ix := NewIndex(entries)
+	g := &Graph{Out: map[string][]string{}, In: map[string][]string{}}
+	for _, e := range entries {
+		seen := map[string]bool{}
+		for _, l := range Parse(e.Body) {
+			to, res := ix.Resolve(e.Path, l)
+			if res != Resolved {
+				if l.Wiki {
+					g.Dangling = append(g.Dangling, Dangling{From: e.Path, Link: l, Ambiguous: res == Ambiguous})
+				}
+				continue
+			}
+			if to.Path == e.Path || seen[to.Path] {
+				continue
+			}
+			seen[to.Path] = true
+			g.Out[e.Path] = append(g.Out[e.Path], to.Path)
+			g.In[to.Path] = append(g.In[to.Path], e.Path)
+		}
+	}
+	for _, in := range g.In {
+		sort.Strings(in)
+	}
+	sort.SliceStable(g.Dangling, func(i, j int) bool { return g.Dangling[i].From < g.Dangling[j].From })
+	return g
+}
@@ -0,0 +1,163 @@
+package links
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+func parse(t *testing.T, p, data string) *entry.Entry {
+	t.Helper()
+	e, err := entry.Parse(p, []byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+func TestParse(t *testing.T) {
+	body := "See [[git/rebase]] and [[ slices | how slices work ]].\n" +
+		"Also [maps](maps.md#iteration \"Maps\") and [web](https://example.com/x.md).\n" +
+		"Not `[[in code]]`, nor:\n\n```\n[[in a block]]\n```\n\n[nested [brackets]](../git/bisect.md)\n"
+	want := []Link{
+		{Target: "git/rebase", Line: 1, Start: 4, End: 18, Wiki: true},
+		{Target: "slices", Label: "how slices work", Line: 1, Start: 23, End: 53, Wiki: true},
+		{Target: "maps.md", Line: 2, Start: 5, End: 37, Fragment: "iteration"},
+		{Target: "../git/bisect.md", Line: 9, Start: 0, End: 37},
+	}
+	if got := Parse([]byte(body)); !reflect.DeepEqual(got, want) {
+		t.Errorf("Parse =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestRelative(t *testing.T) {
+	for _, tt := range []struct{ from, to, want string }{
+		{"go/a.md", "go/b.md", "b.md"},
+		{"go/a.md", "git/b.md", "../git/b.md"},
+		{"go/deep/a.md", "go/b.md", "../b.md"},
+		{"go/a.md", "go/deep/b.md", "deep/b.md"},
+		{"README.md", "go/b.md", "go/b.md"},
+	} {
+		if got := Relative(tt.from, tt.to); got != tt.want {
+			t.Errorf("Relative(%s, %s) = %s, want %s", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+func TestResolve(t *testing.T) {
+	ix := NewIndex([]*entry.Entry{
+		parse(t, "go/slices.md", "---\ntitle: Slices share arrays\nslug: share\n---\n"),
+		parse(t, "go/maps.md", "# Maps\n"),
+		parse(t, "rust/maps.md", "# Maps in Rust\n"),
+		parse(t, "git/rebase.md", "# Rebase onto\n"),
+	})
+	tests := []struct {
+		from string
+		l    Link
+		want string
+		res  Resolution
+	}{
+		{"git/x.md", Link{Target: "go/slices", Wiki: true}, "go/slices.md", Resolved},
+		{"git/x.md", Link{Target: "go/slices.md", Wiki: true}, "go/slices.md", Resolved},
+		{"git/x.md", Link{Target: "slices", Wiki: true}, "go/slices.md", Resolved},
+		{"git/x.md", Link{Target: "share", Wiki: true}, "go/slices.md", Resolved},
+		{"git/x.md", Link{Target: "Slices share arrays", Wiki: true}, "go/slices.md", Resolved},
+		{"git/x.md", Link{Target: "maps", Wiki: true}, "", Ambiguous},
+		{"rust/x.md", Link{Target: "maps", Wiki: true}, "rust/maps.md", Resolved},
+		{"git/x.md", Link{Target: "nowhere", Wiki: true}, "", Missing},
+		{"go/x.md", Link{Target: "../git/rebase.md"}, "git/rebase.md", Resolved},
+		{"go/x.md", Link{Target: "maps.md"}, "go/maps.md", Resolved},
+		{"go/x.md", Link{Target: "rebase.md"}, "", Missing},
+	}
+	for _, tt := range tests {
+		e, res := ix.Resolve(tt.from, tt.l)
+		got := ""
+		if e != nil {
+			got = e.Path
+		}
+		if got != tt.want || res != tt.res {
+			t.Errorf("Resolve(%s, %q) = %q, %v; want %q, %v", tt.from, tt.l.Target, got, res, tt.want, tt.res)
+		}
+	}
+}
+
+func TestNewGraph(t *testing.T) {
+	g := NewGraph([]*entry.Entry{
+		parse(t, "go/slices.md", "# Slices\n\nSee [[maps]], [maps](maps.md) again, [[slices]] itself and [[nowhere]].\n"),
+		parse(t, "go/maps.md", "# Maps\n\nBack to [[slices]].\n"),
+		parse(t, "git/rebase.md", "# Rebase\n\nSee [[go/maps]] and [[go/slices|slices]], but not [gone](gone.md).\n"),
+	})
+	wantOut := map[string][]string{
+		"go/slices.md":  {"go/maps.md"},
+		"go/maps.md":    {"go/slices.md"},
+		"git/rebase.md": {"go/maps.md", "go/slices.md"},
+	}
+	wantIn := map[string][]string{
+		"go/maps.md":   {"git/rebase.md", "go/slices.md"},
+		"go/slices.md": {"git/rebase.md", "go/maps.md"},
+	}
+	if !reflect.DeepEqual(g.Out, wantOut) || !reflect.DeepEqual(g.In, wantIn) {
+		t.Errorf("Out = %v\nIn = %v", g.Out, g.In)
+	}
+	if len(g.Dangling) != 1 || g.Dangling[0].From != "go/slices.md" || g.Dangling[0].Link.Target != "nowhere" || g.Dangling[0].Ambiguous {
+		t.Errorf("Dangling = %+v", g.Dangling)
+	}
+}
+
+func TestRewrite(t *testing.T) {
+	body := "See [[go/maps|maps]], [x](../git/rebase.md#onto \"t\") and ![img](assets/a.png).\n" +
+		"Keep [web](https://go.dev), [root](/about) and `[[code]]`.\n" +
+		"```\n[[fenced]] [f](f.md)\n```\n"
+	var seen []string
+	got := Rewrite([]byte(body), func(l Link) (string, bool) {
+		seen = append(seen, fmt.Sprintf("%s|%s|%s|%v|%d", l.Target, l.Label, l.Fragment, l.Wiki, l.Line))
+		switch {
+		case l.Wiki:
+			return "WIKI(" + l.Target + ")", true
+		case l.Fragment != "":
+			return "[" + l.Label + "](moved#" + l.Fragment + ")", true
+		}
+		return "", false
+	})
+	want := "See WIKI(go/maps), [x](moved#onto) and ![img](assets/a.png).\n" +
+		"Keep [web](https://go.dev), [root](/about) and `[[code]]`.\n" +
+		"```\n[[fenced]] [f](f.md)\n```\n"
+	if string(got) != want {
+		t.Errorf("Rewrite =\n%s\nwant\n%s", got, want)
+	}
+	if w := []string{"go/maps|maps||true|1", "../git/rebase.md|x|onto|false|1", "assets/a.png|img||false|1"}; !slices.Equal(seen, w) {
+		t.Errorf("links passed = %q, want %q", seen, w)
+	}
+}
+
+func TestRewriteURL(t *testing.T) {
+	const u = "https://go.dev/x"
+	body := "![shot](" + u + " \"Shot\"), [a](<" + u + ">), " + u + "/y, " + u + "?q=1 and (" + u + ").\n" +
+		"`" + u + "`\n" +
+		"```\n" + u + "\n```\n" +
+		"Last " + u + "\n"
+	var seen []string
+	got := RewriteURL([]byte(body), u, func(written, rest string) (string, bool) {
+		seen = append(seen, written+"|"+rest)
+		return "U", true
+	})
+	want := "U, U, " + u + "/y, " + u + "?q=1 and (U).\n" +
+		"`" + u + "`\n" +
+		"```\n" + u + "\n```\n" +
+		"Last U\n"
+	if string(got) != want {
+		t.Errorf("RewriteURL =\n%s\nwant\n%s", got, want)
+	}
+	w := []string{
+		"![shot](" + u + " \"Shot\")|, [a](<" + u + ">), " + u + "/y, " + u + "?q=1 and (" + u + ").",
+		"[a](<" + u + ">)|, " + u + "/y, " + u + "?q=1 and (" + u + ").",
+		u + "|).",
+		u + "|",
+	}
+	if !slices.Equal(seen, w) {
+		t.Errorf("places passed =\n%q\nwant\n%q", seen, w)
+	}
+}
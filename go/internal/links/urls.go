@@ -0,0 +1,114 @@
+package links
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+var urlRE = regexp.MustCompile("https?://[^\\s<>\"'`\\]]+")
+
+// URL is a web address written in an entry.
+type URL struct {
+	URL string
+	// Line is the 1-based line within the body.
+	Line int
+}
+
+// URLs returns the http(s) URLs in body outside code, with their body
+// lines, in order.
+func URLs(body []byte) []URL {
+	lines := strings.Split(string(body), "\n")
+	for _, b := range entry.CodeBlocks(body) {
+		for i := b.Line - 1; i < b.EndLine && i < len(lines); i++ {
+			lines[i] = ""
+		}
+	}
+	var out []URL
+	for i, line := range lines {
+		line = codeRE.ReplaceAllString(line, "")
+		for _, u := range urlRE.FindAllString(line, -1) {
+			out = append(out, URL{trimURL(u), i + 1})
+		}
+	}
+	return out
+}
+
+// trimURL drops the punctuation prose puts after a URL, and a closing
+// parenthesis not opened within it, as around a markdown link destination.
+func trimURL(u string) string {
+	for {
+		t := strings.TrimRight(u, ".,;:!?*_")
+		if strings.HasSuffix(t, ")") && strings.Count(t, ")") > strings.Count(t, "(") {
+			t = t[:len(t)-1]
+		}
+		if t == u {
+			return u
+		}
+		u = t
+	}
+}
+
+// RewriteURL returns body with each place outside code the URL u is
+// written replaced by the text fn returns for it, or kept when fn returns
+// false. written is the markdown link or image to u, as "[label](u)", the
+// autolink "<u>" or the bare URL, and rest the text following it on its
+// line.
+func RewriteURL(body []byte, u string, fn func(written, rest string) (string, bool)) []byte {
+	q := regexp.QuoteMeta(u)
+	mdLink := regexp.MustCompile(`!?\[[^\[\]\n]*\]\(<?` + q + `>?(?:\s+"[^"]*")?\)`)
+	auto := regexp.MustCompile(`<` + q + `>`)
+	lines := strings.SplitAfter(string(body), "\n")
+	code := map[int]bool{}
+	for _, b := range entry.CodeBlocks(body) {
+		for i := b.Line - 1; i < b.EndLine && i < len(lines); i++ {
+			code[i] = true
+		}
+	}
+	var out strings.Builder
+	for i, line := range lines {
+		if code[i] || !strings.Contains(line, u) {
+			out.WriteString(line)
+			continue
+		}
+		masked := codeRE.ReplaceAllStringFunc(line, func(s string) string { return strings.Repeat(" ", len(s)) })
+		var spans [][]int
+		taken := func(start, end int) bool {
+			for _, s := range spans {
+				if start < s[1] && s[0] < end {
+					return true
+				}
+			}
+			return false
+		}
+		for _, re := range []*regexp.Regexp{mdLink, auto} {
+			for _, m := range re.FindAllStringIndex(masked, -1) {
+				if !taken(m[0], m[1]) {
+					spans = append(spans, m)
+				}
+			}
+		}
+		// Bare URLs are those the URL pattern finds whole, not inside
+		// another URL.
+		for _, m := range urlRE.FindAllStringIndex(masked, -1) {
+			if end := m[0] + len(u); trimURL(masked[m[0]:m[1]]) == u && !taken(m[0], end) {
+				spans = append(spans, []int{m[0], end})
+			}
+		}
+		sort.Slice(spans, func(a, b int) bool { return spans[a][0] < spans[b][0] })
+		last := 0
+		for _, s := range spans {
+			text, ok := fn(line[s[0]:s[1]], strings.TrimSuffix(line[s[1]:], "\n"))
+			if !ok {
+				continue
+			}
+			out.WriteString(line[last:s[0]])
+			out.WriteString(text)
+			last = s[1]
+		}
+		out.WriteString(line[last:])
+	}
+	return []byte(out.String())
+}
@@ -0,0 +1,37 @@
+package lint
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+func init() { Register(codeLines{}) }
+
+type codeLines struct{}
+
+func (codeLines) Name() string { return "code-line-length" }
+func (codeLines) Doc() string  { return "code block lines longer than the limit" }
+
+func (codeLines) Check(_ context.Context, c *Context) ([]Issue, error) {
+	max := c.Options.MaxCodeLine
+	var issues []Issue
+	for _, e := range c.Entries {
+		for _, b := range entry.CodeBlocks(e.Body) {
+			for i, line := range strings.Split(strings.TrimSuffix(b.Code, "\n"), "\n") {
+				n := utf8.RuneCountInString(line) + 3*strings.Count(line, "\t")
+				if n > max {
+					issues = append(issues, Issue{
+						Path:    e.Path,
+						Line:    e.FileLine(b.Line + 1 + i),
+						Message: fmt.Sprintf("code line is %d characters, over %d", n, max),
+					})
+				}
+			}
+		}
+	}
+	return issues, nil
+}
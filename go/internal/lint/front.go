@@ -0,0 +1,134 @@
+package lint
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+func init() {
+	Register(requiredFields{})
+	Register(untagged{})
+	Register(duplicates{
+		field: "title",
+		value: func(e *entry.Entry) string { return e.Meta.Title },
+		key:   func(e *entry.Entry) string { return strings.ToLower(e.Meta.Title) },
+	})
+	Register(duplicates{
+		field: "slug",
+		value: func(e *entry.Entry) string { return e.Meta.Slug },
+		key:   func(e *entry.Entry) string { return e.Meta.Category + "/" + e.Meta.Slug },
+	})
+}
+
+type requiredFields struct{}
+
+func (requiredFields) Name() string { return "required-field" }
+func (requiredFields) Doc() string  { return "frontmatter fields that must be set" }
+
+// Check reports required fields missing from the frontmatter. Fields the
+// entry derives from its file, the title, category and slug, are fixed by
+// writing the derived value.
+func (requiredFields) Check(_ context.Context, c *Context) ([]Issue, error) {
+	var issues []Issue
+	for _, e := range c.Entries {
+		f, err := entry.ParseFront(e.Front)
+		if err != nil {
+			issues = append(issues, Issue{Path: e.Path, Line: 1, Message: fmt.Sprintf("frontmatter: %v", err)})
+			continue
+		}
+		for _, key := range c.Options.Required {
+			if f.Has(key) {
+				continue
+			}
+			is := Issue{Path: e.Path, Line: 1, Message: fmt.Sprintf("missing %s", key)}
+			if v := derived(e, key); v != "" {
+				is.Message += fmt.Sprintf(" (%q from the file)", v)
+				is.Fix = setField(key, v)
+			}
+			issues = append(issues, is)
+		}
+	}
+	return issues, nil
+}
+
+func derived(e *entry.Entry, key string) string {
+	switch key {
+	case "title":
+		return e.Meta.Title
+	case "category":
+		return e.Meta.Category
+	case "slug":
+		return e.Meta.Slug
+	}
+	return ""
+}
+
+// setField returns a fix setting key to value unless the entry has it.
+func setField(key, value string) func([]byte) ([]byte, error) {
+	return func(data []byte) ([]byte, error) {
+		return entry.Rewrite(data, func(f *entry.Front) error {
+			if f.Has(key) {
+				return nil
+			}
+			return f.Set(key, value)
+		})
+	}
+}
+
+type untagged struct{}
+
+func (untagged) Name() string { return "untagged" }
+func (untagged) Doc() string  { return "entries without tags" }
+
+func (untagged) Check(_ context.Context, c *Context) ([]Issue, error) {
+	var issues []Issue
+	for _, e := range c.Entries {
+		if len(e.Meta.Tags) == 0 {
+			issues = append(issues, Issue{Path: e.Path, Line: fieldLine(e, "tags"), Message: "no tags"})
+		}
+	}
+	return issues, nil
+}
+
+// duplicates reports entries sharing a key with another entry of the tree:
+// titles, compared without case, or slugs within a category, which would
+// collide on the site.
+type duplicates struct {
+	field string
+	value func(*entry.Entry) string
+	key   func(*entry.Entry) string
+}
+
+func (d duplicates) Name() string { return "duplicate-" + d.field }
+func (d duplicates) Doc() string  { return "entries sharing a " + d.field + " with another entry" }
+
+func (d duplicates) Check(_ context.Context, c *Context) ([]Issue, error) {
+	byKey := map[string][]string{}
+	for _, e := range c.All {
+		k := d.key(e)
+		byKey[k] = append(byKey[k], e.Path)
+	}
+	var issues []Issue
+	for _, e := range c.Entries {
+		var others []string
+		for _, p := range byKey[d.key(e)] {
+			if p != e.Path {
+				others = append(others, p)
+			}
+		}
+		if len(others) == 0 {
+			continue
+		}
+		sort.Strings(others)
+		issues = append(issues, Issue{
+			Path:    e.Path,
+			Line:    fieldLine(e, d.field),
+			Message: fmt.Sprintf("%s %q also used by %s", d.field, d.value(e), strings.Join(others, ", ")),
+		})
+	}
+	return issues, nil
+}
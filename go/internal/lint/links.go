@@ -0,0 +1,88 @@
+package lint
+
+import (
+	"context"
+	"fmt"
+	"path"
+
+	"github.com/canhta/til/go/internal/include"
+	"github.com/canhta/til/go/internal/links"
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+func init() {
+	Register(brokenLinks{})
+	Register(brokenIncludes{})
+}
+
+type brokenLinks struct{}
+
+func (brokenLinks) Name() string { return "broken-link" }
+func (brokenLinks) Doc() string  { return "links to entries that do not exist or are ambiguous" }
+
+// Check reports links that do not resolve to a single entry. A markdown
+// link whose file name matches exactly one entry, as when the entry was
+// moved to another category, is fixed by pointing it there.
+func (brokenLinks) Check(_ context.Context, c *Context) ([]Issue, error) {
+	byName := map[string][]*entry.Entry{}
+	for _, e := range c.All {
+		byName[path.Base(e.Path)] = append(byName[path.Base(e.Path)], e)
+	}
+	var issues []Issue
+	for _, e := range c.Entries {
+		for _, l := range links.Parse(e.Body) {
+			_, res := c.Links.Resolve(e.Path, l)
+			if res == links.Resolved {
+				continue
+			}
+			is := Issue{Path: e.Path, Line: e.FileLine(l.Line)}
+			switch {
+			case res == links.Ambiguous:
+				is.Message = fmt.Sprintf("[[%s]] names several entries", l.Target)
+			case l.Wiki:
+				is.Message = fmt.Sprintf("[[%s]] matches no entry", l.Target)
+			default:
+				is.Message = fmt.Sprintf("link to missing %s", l.Target)
+				if m := byName[path.Base(l.Target)]; len(m) == 1 {
+					is.Message += ", moved to " + m[0].Path
+					is.Fix = relink(e.Path, l.Target, m[0].Path)
+				}
+			}
+			issues = append(issues, is)
+		}
+	}
+	return issues, nil
+}
+
+// relink returns a fix pointing the markdown links to target in the entry
+// at from to the entry at to.
+func relink(from, target, to string) func([]byte) ([]byte, error) {
+	rel := links.Relative(from, to)
+	return func(data []byte) ([]byte, error) {
+		return links.Rewrite(data, func(l links.Link) (string, bool) {
+			if l.Wiki || l.Target != target {
+				return "", false
+			}
+			dest := rel
+			if l.Fragment != "" {
+				dest += "#" + l.Fragment
+			}
+			return "[" + l.Label + "](" + dest + ")", true
+		}), nil
+	}
+}
+
+type brokenIncludes struct{}
+
+func (brokenIncludes) Name() string { return "broken-include" }
+func (brokenIncludes) Doc() string  { return "include directives naming no entry or section, or cycles" }
+
+func (brokenIncludes) Check(_ context.Context, c *Context) ([]Issue, error) {
+	var issues []Issue
+	for _, e := range c.Entries {
+		for _, err := range include.Expand(e, c.Links).Errors {
+			issues = append(issues, Issue{Path: err.Path, Line: err.Line, Message: fmt.Sprintf("include %q: %v", err.Ref, err.Err)})
+		}
+	}
+	return issues, nil
+}
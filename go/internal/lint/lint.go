@@ -0,0 +1,243 @@
+// Package lint checks entries for problems such as broken links, missing
+// frontmatter and duplicate titles.
+//
+// Checks are Rules registered by name; the builtin ones are registered by
+// this package and others can be added with Register. Issues a rule knows
+// how to resolve safely carry a fix, which Fix applies.
+package lint
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/canhta/til/go/internal/config"
+	"github.com/canhta/til/go/internal/links"
+	"github.com/canhta/til/go/internal/notes"
+	"github.com/canhta/til/go/internal/wayback"
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+// Issue is a problem found in an entry file.
+type Issue struct {
+	Path string `json:"path"`
+	// Line is the 1-based line in the file.
+	Line    int    `json:"line"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+	// Fix, when set, returns the file content with the issue resolved.
+	Fix func(data []byte) ([]byte, error) `json:"-"`
+}
+
+// Fixable reports whether the issue can be fixed automatically.
+func (i Issue) Fixable() bool { return i.Fix != nil }
+
+// MarshalJSON adds "fixable" to the issue's fields.
+func (i Issue) MarshalJSON() ([]byte, error) {
+	type plain Issue
+	return json.Marshal(struct {
+		plain
+		Fixable bool `json:"fixable"`
+	}{plain(i), i.Fixable()})
+}
+
+func (i Issue) String() string {
+	return fmt.Sprintf("%s:%d: %s (%s)", i.Path, i.Line, i.Message, i.Rule)
+}
+
+// Options configures the builtin rules.
+type Options struct {
+	// Required lists the frontmatter fields every entry must set. Defaults
+	// to DefaultRequired.
+	Required []string
+	// Schema is checked by the schema rule. Its required fields are
+	// reported by required-field, which Required should list.
+	Schema config.Schema
+	// MaxCodeLine is the longest code line allowed, in characters with
+	// tabs counted as four. Defaults to DefaultMaxCodeLine.
+	MaxCodeLine int
+	// URLCache is the file caching the results of external URL checks.
+	URLCache string
+	// URLTTL is how long a checked URL is trusted. Defaults to a day.
+	URLTTL time.Duration
+	// URLInterval is the least time between two requests to one host.
+	// Defaults to a second.
+	URLInterval time.Duration
+	// Client makes the URL requests. Defaults to one with a 15s timeout.
+	Client *http.Client
+	// Archive looks up Wayback Machine snapshots of the pages dead-url
+	// finds gone, and fixes their links as the mode says; see package
+	// wayback.
+	Archive wayback.Mode
+	// Wayback defaults to a client of the Internet Archive's APIs.
+	Wayback *wayback.Client
+}
+
+const (
+	DefaultMaxCodeLine = 100
+	// URLCacheFile is the default URL cache inside the notes state
+	// directory.
+	URLCacheFile = "urls.json"
+)
+
+// DefaultRequired are the frontmatter fields required by default.
+var DefaultRequired = []string{"title", "date"}
+
+// Context is what rules check: a set of entries within a tree.
+type Context struct {
+	Tree *notes.Tree
+	// All is every entry of the tree, against which links and duplicates
+	// are checked.
+	All []*entry.Entry
+	// Entries are the entries to check.
+	Entries []*entry.Entry
+	Links   *links.Index
+	Options Options
+}
+
+// NewContext prepares to check entries of tree, which has the entries all.
+func NewContext(tree *notes.Tree, all, entries []*entry.Entry, opts Options) *Context {
+	if len(opts.Required) == 0 {
+		opts.Required = DefaultRequired
+	}
+	if opts.MaxCodeLine <= 0 {
+		opts.MaxCodeLine = DefaultMaxCodeLine
+	}
+	if opts.URLCache == "" {
+		opts.URLCache = tree.StatePath(URLCacheFile)
+	}
+	if opts.URLTTL <= 0 {
+		opts.URLTTL = 24 * time.Hour
+	}
+	if opts.URLInterval <= 0 {
+		opts.URLInterval = time.Second
+	}
+	if opts.Client == nil {
+		opts.Client = &http.Client{Timeout: 15 * time.Second}
+	}
+	return &Context{Tree: tree, All: all, Entries: entries, Links: links.NewIndex(all), Options: opts}
+}
+
+// Rule is a check run over entries.
+type Rule interface {
+	Name() string
+	// Doc describes the rule in one line.
+	Doc() string
+	Check(ctx context.Context, c *Context) ([]Issue, error)
+}
+
+// Optional is implemented by rules that only run when asked for by name,
+// such as those needing the network.
+type Optional interface {
+	Optional() bool
+}
+
+var registry = map[string]Rule{}
+
+// Register adds a rule, replacing any registered under the same name.
+func Register(r Rule) { registry[r.Name()] = r }
+
+// Rules returns the registered rules sorted by name.
+func Rules() []Rule {
+	out := make([]Rule, 0, len(registry))
+	for _, r := range registry {
+		out = append(out, r)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name() < out[j].Name() })
+	return out
+}
+
+// Select returns the rules named, or when names is empty every rule that
+// is not Optional, leaving out those in disabled.
+func Select(names, disabled []string) ([]Rule, error) {
+	skip := map[string]bool{}
+	for _, n := range append(append([]string(nil), disabled...), names...) {
+		if registry[n] == nil {
+			return nil, fmt.Errorf("unknown lint rule %q", n)
+		}
+	}
+	for _, n := range disabled {
+		skip[n] = true
+	}
+	var out []Rule
+	if len(names) > 0 {
+		for _, n := range names {
+			out = append(out, registry[n])
+		}
+		return out, nil
+	}
+	for _, r := range Rules() {
+		if o, ok := r.(Optional); ok && o.Optional() || skip[r.Name()] {
+			continue
+		}
+		out = append(out, r)
+	}
+	return out, nil
+}
+
+// Run checks c with rules, returning the issues sorted by location.
+func Run(ctx context.Context, c *Context, rules []Rule) ([]Issue, error) {
+	var issues []Issue
+	for _, r := range rules {
+		found, err := r.Check(ctx, c)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", r.Name(), err)
+		}
+		for i := range found {
+			found[i].Rule = r.Name()
+		}
+		issues = append(issues, found...)
+	}
+	sort.SliceStable(issues, func(i, j int) bool {
+		a, b := issues[i], issues[j]
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		return a.Line < b.Line
+	})
+	return issues, nil
+}
+
+// Fix applies the fixes of issues, rewriting the files involved as one
+// unit, and returns the issues fixed.
+func Fix(tree *notes.Tree, issues []Issue) ([]Issue, error) {
+	files := map[string][]byte{}
+	var fixed []Issue
+	for _, is := range issues {
+		if is.Fix == nil {
+			continue
+		}
+		data, ok := files[is.Path]
+		if !ok {
+			var err error
+			if data, err = tree.Read(is.Path); err != nil {
+				return nil, err
+			}
+		}
+		next, err := is.Fix(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", is, err)
+		}
+		files[is.Path] = next
+		fixed = append(fixed, is)
+	}
+	if err := tree.WriteAll(files); err != nil {
+		return nil, err
+	}
+	return fixed, nil
+}
+
+// fieldLine returns the file line of the frontmatter field key in e, or 1
+// when it is not set.
+func fieldLine(e *entry.Entry, key string) int {
+	for i, l := range strings.Split(string(e.Front), "\n") {
+		if strings.HasPrefix(l, key+":") {
+			return i + 2
+		}
+	}
+	return 1
+}
@@ -0,0 +1,150 @@
+package lint
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/canhta/til/go/internal/config"
+	"github.com/canhta/til/go/internal/notes"
+)
+
+func newTree(t *testing.T, files map[string]string) *notes.Tree {
+	t.Helper()
+	tree := notes.Open(t.TempDir())
+	for p, data := range files {
+		if err := tree.Write(p, []byte(data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return tree
+}
+
+// check runs the rules named over every entry of tree.
+func check(t *testing.T, tree *notes.Tree, opts Options, names ...string) []Issue {
+	t.Helper()
+	all, err := tree.Entries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rules, err := Select(names, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issues, err := Run(context.Background(), NewContext(tree, all, all, opts), rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return issues
+}
+
+func strs(issues []Issue) []string {
+	var out []string
+	for _, is := range issues {
+		out = append(out, is.String())
+	}
+	return out
+}
+
+func TestSelect(t *testing.T) {
+	rules, err := Select(nil, []string{"untagged"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, r := range rules {
+		names = append(names, r.Name())
+	}
+	if slices.Contains(names, "untagged") || slices.Contains(names, "dead-url") || !slices.Contains(names, "broken-link") {
+		t.Errorf("Select(nil, untagged) = %q, want the default rules less untagged", names)
+	}
+	if rules, _ := Select([]string{"dead-url"}, nil); len(rules) != 1 || rules[0].Name() != "dead-url" {
+		t.Errorf("Select(dead-url) = %v", rules)
+	}
+	for _, names := range [][]string{{"nope"}, nil} {
+		if _, err := Select(names, []string{"nope"}); err == nil {
+			t.Errorf("Select(%q, nope) accepted an unknown rule", names)
+		}
+	}
+}
+
+func TestRules(t *testing.T) {
+	tree := newTree(t, map[string]string{
+		"go/slices.md":  "---\ntitle: Slices\ndate: 2024-01-01\ntags: [go]\n---\n\nSee [[nowhere]] and [rebase](../rebase.md#onto).\n\n```go\n" + strings.Repeat("x", 30) + "\n\t" + strings.Repeat("y", 20) + "\n```\n",
+		"go/maps.md":    "---\ntitle: maps\ndate: 2024-01-02\n---\n\nMaps.\n",
+		"git/maps.md":   "---\ntitle: Maps\ndate: 2024-01-03\ntags: [git]\n---\n",
+		"git/rebase.md": "---\ntags: [git]\n---\n# Rebase\n",
+		"rust/own.md":   "---\ntitle: Ownership\ndate: 2024-01-05\ntags: [rust]\n---\n\nUnlike [[maps]].\n",
+		"git/other.md":  "---\ntitle: Other\ndate: 2024-01-04\nslug: rebase\ntags: [git]\n---\n",
+	})
+	got := strs(check(t, tree, Options{MaxCodeLine: 20}, "broken-link", "required-field", "untagged", "duplicate-title", "duplicate-slug", "code-line-length"))
+	want := []string{
+		"git/maps.md:2: title \"Maps\" also used by go/maps.md (duplicate-title)",
+		"git/other.md:4: slug \"rebase\" also used by git/rebase.md (duplicate-slug)",
+		"git/rebase.md:1: missing title (\"Rebase\" from the file) (required-field)",
+		"git/rebase.md:1: missing date (required-field)",
+		"git/rebase.md:1: slug \"rebase\" also used by git/other.md (duplicate-slug)",
+		"go/maps.md:1: no tags (untagged)",
+		"go/maps.md:2: title \"maps\" also used by git/maps.md (duplicate-title)",
+		"go/slices.md:7: [[nowhere]] matches no entry (broken-link)",
+		"go/slices.md:7: link to missing ../rebase.md, moved to git/rebase.md (broken-link)",
+		"go/slices.md:10: code line is 30 characters, over 20 (code-line-length)",
+		"go/slices.md:11: code line is 24 characters, over 20 (code-line-length)",
+		"rust/own.md:7: [[maps]] names several entries (broken-link)",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("issues =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestSchemaRule(t *testing.T) {
+	tree := newTree(t, map[string]string{
+		"go/slices.md":  "---\ntitle: Slices\ntags: [go, perf]\ndifficulty: medium\n---\n",
+		"git/rebase.md": "---\ntitle: Rebase\ndifficulty: \"\"\n---\n",
+		"rust/own.md":   "---\ntitle: Own\ndifficulty: easy\n---\n",
+	})
+	s := config.Schema{
+		Required:   []string{"difficulty"},
+		Categories: []string{"go", "git"},
+		Tags:       []string{"go"},
+		Fields:     map[string]config.Field{"difficulty": {Values: []string{"easy", "hard"}}},
+	}
+	got := strs(check(t, tree, Options{Schema: s}, "schema"))
+	// Missing fields are left to required-field; empty ones are not.
+	want := []string{
+		"git/rebase.md:3: difficulty is empty (schema)",
+		"go/slices.md:3: tags not in the schema: perf (schema)",
+		`go/slices.md:4: difficulty "medium" is not one of easy, hard (schema)`,
+		`rust/own.md:1: category "rust" is not one of go, git (schema)`,
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("issues =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if got := check(t, tree, Options{}, "schema"); len(got) != 0 {
+		t.Errorf("issues without a schema = %v", got)
+	}
+}
+
+func TestFix(t *testing.T) {
+	tree := newTree(t, map[string]string{
+		"go/slices.md":  "---\ndate: 2024-01-01\ntags: [go]\n---\n# Slices\n\nSee [rebase](rebase.md#onto) and [again](rebase.md).\n",
+		"git/rebase.md": "---\ntitle: Rebase\ndate: 2024-01-01\ntags: [git]\n---\n",
+	})
+	issues := check(t, tree, Options{}, "broken-link", "required-field")
+	fixed, err := Fix(tree, issues)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fixed) != len(issues) || len(fixed) != 3 {
+		t.Errorf("fixed %q out of %q", strs(fixed), strs(issues))
+	}
+	data, _ := tree.Read("go/slices.md")
+	want := "---\ndate: 2024-01-01\ntags: [go]\ntitle: Slices\n---\n# Slices\n\nSee [rebase](../git/rebase.md#onto) and [again](../git/rebase.md).\n"
+	if string(data) != want {
+		t.Errorf("go/slices.md after Fix =\n%s\nwant\n%s", data, want)
+	}
+	if issues := check(t, tree, Options{}, "broken-link", "required-field"); len(issues) != 0 {
+		t.Errorf("issues after Fix = %q", strs(issues))
+	}
+}
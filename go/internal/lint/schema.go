@@ -0,0 +1,38 @@
+package lint
+
+import (
+	"context"
+
+	"github.com/canhta/til/go/internal/schema"
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+func init() { Register(schemaRule{}) }
+
+type schemaRule struct{}
+
+func (schemaRule) Name() string { return "schema" }
+func (schemaRule) Doc() string  { return "frontmatter breaking the [schema] of the config file" }
+
+// Check reports the problems of each entry under the schema, but for
+// missing required fields, which are left to required-field and its fixes.
+func (schemaRule) Check(_ context.Context, c *Context) ([]Issue, error) {
+	if schema.Empty(c.Options.Schema) {
+		return nil, nil
+	}
+	var issues []Issue
+	for _, e := range c.Entries {
+		f, err := entry.ParseFront(e.Front)
+		if err != nil {
+			// Reported by required-field.
+			continue
+		}
+		for _, p := range schema.Check(c.Options.Schema, e) {
+			if p.Unset && !f.Has(p.Field) {
+				continue
+			}
+			issues = append(issues, Issue{Path: e.Path, Line: fieldLine(e, p.Field), Message: p.Message})
+		}
+	}
+	return issues, nil
+}
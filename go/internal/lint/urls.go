@@ -0,0 +1,272 @@
+package lint
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/canhta/til/go/internal/fsutil"
+	"github.com/canhta/til/go/internal/links"
+	"github.com/canhta/til/go/internal/wayback"
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+func init() { Register(deadURLs{}) }
+
+// HostJobs is how many hosts are checked at once.
+const HostJobs = 4
+
+type deadURLs struct{}
+
+func (deadURLs) Name() string   { return "dead-url" }
+func (deadURLs) Doc() string    { return "external links that fail to load (needs the network)" }
+func (deadURLs) Optional() bool { return true }
+
+// urlResult is a cached check of one URL.
+type urlResult struct {
+	Status  int       `json:"status,omitempty"`
+	Error   string    `json:"error,omitempty"`
+	Checked time.Time `json:"checked"`
+	// Snapshot is the Wayback Machine snapshot found for a page gone.
+	Snapshot string `json:"snapshot,omitempty"`
+}
+
+func (r urlResult) dead() bool { return r.Error != "" || r.Status >= 400 }
+
+// gone reports whether the server says the page is no more, rather than
+// failing to serve it for now.
+func (r urlResult) gone() bool {
+	return r.Status == http.StatusNotFound || r.Status == http.StatusGone
+}
+
+func (r urlResult) String() string {
+	if r.Error != "" {
+		return r.Error
+	}
+	return fmt.Sprintf("%d %s", r.Status, http.StatusText(r.Status))
+}
+
+type urlUse struct {
+	e    *entry.Entry
+	line int
+}
+
+// Check requests every http(s) URL outside code, one host request at a
+// time per Options.URLInterval, and reports those that fail or answer with
+// an error status. Results are cached for Options.URLTTL. Links already
+// followed by a link to a snapshot, as wayback.Append leaves them, are
+// not requested, nor are snapshots themselves.
+//
+// With Options.Archive, the pages gone are looked up on the Wayback
+// Machine, for the snapshot closest to the entry's date or else a fresh
+// one, and the issues carry the fix linking to it.
+func (deadURLs) Check(ctx context.Context, c *Context) ([]Issue, error) {
+	uses := map[string][]urlUse{}
+	for _, e := range c.Entries {
+		for _, u := range links.URLs(e.Body) {
+			if wayback.Archived(e.Body, u.URL) || wayback.IsSnapshot(u.URL) {
+				continue
+			}
+			uses[u.URL] = append(uses[u.URL], urlUse{e, e.FileLine(u.Line)})
+		}
+	}
+	cache := map[string]urlResult{}
+	if data, err := os.ReadFile(c.Options.URLCache); err == nil {
+		if err := json.Unmarshal(data, &cache); err != nil {
+			return nil, fmt.Errorf("%s: %w", c.Options.URLCache, err)
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	now := time.Now()
+	byHost := map[string][]string{}
+	for u := range uses {
+		if r, ok := cache[u]; ok && now.Sub(r.Checked) < c.Options.URLTTL {
+			continue
+		}
+		if p, err := url.Parse(u); err == nil {
+			byHost[p.Host] = append(byHost[p.Host], u)
+		}
+	}
+	var (
+		mu  sync.Mutex
+		wg  sync.WaitGroup
+		sem = make(chan struct{}, HostJobs)
+	)
+	for _, urls := range byHost {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			for i, u := range urls {
+				if i > 0 {
+					select {
+					case <-ctx.Done():
+						return
+					case <-time.After(c.Options.URLInterval):
+					}
+				}
+				r, ok := checkURL(ctx, c.Options.Client, u)
+				if !ok {
+					continue
+				}
+				mu.Lock()
+				r.Snapshot = cache[u].Snapshot
+				cache[u] = r
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	var unarchived map[string]error
+	if c.Options.Archive != "" {
+		unarchived = archive(ctx, c, uses, cache)
+	}
+	for u, r := range cache {
+		if _, used := uses[u]; !used && now.Sub(r.Checked) >= c.Options.URLTTL {
+			delete(cache, u)
+		}
+	}
+	data, err := json.MarshalIndent(cache, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := fsutil.WriteFile(c.Options.URLCache, append(data, '\n'), 0o644); err != nil {
+		return nil, err
+	}
+	var issues []Issue
+	for u, us := range uses {
+		r, ok := cache[u]
+		if !ok || !r.dead() {
+			continue
+		}
+		msg := fmt.Sprintf("dead link %s: %s", u, r)
+		var fix func([]byte) ([]byte, error)
+		switch {
+		case r.Snapshot != "" && c.Options.Archive != "":
+			msg += ", archived at " + r.Snapshot
+			fix = func(data []byte) ([]byte, error) {
+				return wayback.Rewrite(data, u, r.Snapshot, c.Options.Archive), nil
+			}
+		case unarchived[u] != nil:
+			msg += fmt.Sprintf(", not archived: %v", unarchived[u])
+		}
+		for _, use := range us {
+			issues = append(issues, Issue{Path: use.e.Path, Line: use.line, Message: msg, Fix: fix})
+		}
+	}
+	return issues, nil
+}
+
+// archive finds the snapshots of the pages gone among uses without one in
+// cache, requesting a snapshot of those the archive has none of, one
+// request each Options.URLInterval. It returns why pages were left without.
+func archive(ctx context.Context, c *Context, uses map[string][]urlUse, cache map[string]urlResult) map[string]error {
+	client := c.Options.Wayback
+	if client == nil {
+		client = &wayback.Client{}
+	}
+	failed := map[string]error{}
+	first := true
+	for _, u := range slices.Sorted(maps.Keys(uses)) {
+		r, ok := cache[u]
+		if !ok || !r.gone() || r.Snapshot != "" {
+			continue
+		}
+		// The page as it was when the oldest entry linked to it.
+		var near time.Time
+		for _, use := range uses[u] {
+			if d := use.e.Created(); !d.IsZero() && (near.IsZero() || d.Before(near)) {
+				near = d
+			}
+		}
+		wait := func() bool {
+			if !first {
+				select {
+				case <-ctx.Done():
+					return false
+				case <-time.After(c.Options.URLInterval):
+				}
+			}
+			first = false
+			return true
+		}
+		if !wait() {
+			break
+		}
+		s, err := client.Snapshot(ctx, u, near)
+		if err == nil && s == "" && wait() {
+			s, err = client.Request(ctx, u)
+		}
+		if err != nil {
+			failed[u] = err
+			continue
+		}
+		r.Snapshot = s
+		cache[u] = r
+	}
+	return failed
+}
+
+// checkURL requests u, with HEAD and then GET for servers refusing HEAD.
+// It reports false when the answer says nothing of the URL, as when the
+// server is rate limiting.
+func checkURL(ctx context.Context, client *http.Client, u string) (urlResult, bool) {
+	r := urlResult{Checked: time.Now()}
+	var status int
+	var err error
+	for _, method := range []string{http.MethodHead, http.MethodGet} {
+		if status, err = request(ctx, client, method, u); err != nil || !refusesHead(status) {
+			break
+		}
+	}
+	switch {
+	case ctx.Err() != nil, status == http.StatusTooManyRequests:
+		return r, false
+	case err != nil:
+		var ue *url.Error
+		if errors.As(err, &ue) {
+			err = ue.Err
+		}
+		r.Error = err.Error()
+	default:
+		r.Status = status
+	}
+	return r, true
+}
+
+func refusesHead(status int) bool {
+	switch status {
+	case http.StatusMethodNotAllowed, http.StatusForbidden, http.StatusNotImplemented, http.StatusBadRequest, http.StatusNotFound:
+		return true
+	}
+	return false
+}
+
+func request(ctx context.Context, client *http.Client, method, u string) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, method, u, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("User-Agent", "til-lint (link checker)")
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	resp.Body.Close()
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,178 @@
+package lint
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/canhta/til/go/internal/wayback"
+)
+
+func TestDeadURLs(t *testing.T) {
+	var (
+		mu   sync.Mutex
+		hits []string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		hits = append(hits, r.Method+" "+r.URL.Path)
+		mu.Unlock()
+		switch r.URL.Path {
+		case "/gone":
+			w.WriteHeader(http.StatusNotFound)
+		case "/nohead":
+			if r.Method == http.MethodHead {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+			}
+		case "/busy":
+			w.WriteHeader(http.StatusTooManyRequests)
+		case "/down":
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+	tree := newTree(t, map[string]string{
+		"go/a.md": "---\ntitle: A\n---\n\n" +
+			"[ok](" + srv.URL + "/ok) [gone](" + srv.URL + "/gone)\n" +
+			srv.URL + "/nohead and " + srv.URL + "/busy\n\n" +
+			"```\n" + srv.URL + "/code\n```\n",
+		"go/b.md": "---\ntitle: B\n---\n\nAlso [down](" + srv.URL + "/down) and [gone](" + srv.URL + "/gone).\n",
+	})
+	opts := Options{URLInterval: time.Millisecond, Client: srv.Client()}
+
+	got := strs(check(t, tree, opts, "dead-url"))
+	want := []string{
+		"go/a.md:5: dead link " + srv.URL + "/gone: 404 Not Found (dead-url)",
+		"go/b.md:5: dead link " + srv.URL + "/down: 500 Internal Server Error (dead-url)",
+		"go/b.md:5: dead link " + srv.URL + "/gone: 404 Not Found (dead-url)",
+	}
+	slices.Sort(got)
+	if !slices.Equal(got, want) {
+		t.Errorf("issues =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	slices.Sort(hits)
+	if want := []string{"GET /gone", "GET /nohead", "HEAD /busy", "HEAD /down", "HEAD /gone", "HEAD /nohead", "HEAD /ok"}; !slices.Equal(hits, want) {
+		t.Errorf("requests = %q, want %q", hits, want)
+	}
+
+	var cache map[string]urlResult
+	data, err := os.ReadFile(tree.StatePath(URLCacheFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, &cache); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := cache[srv.URL+"/busy"]; ok || cache[srv.URL+"/nohead"].Status != http.StatusOK || len(cache) != 4 {
+		t.Errorf("cache = %+v, want all but the rate limited URL", cache)
+	}
+
+	// Within the TTL, only what went unanswered is requested again.
+	hits = nil
+	if got := check(t, tree, opts, "dead-url"); len(got) != 3 {
+		t.Errorf("cached issues = %q", strs(got))
+	}
+	if !slices.Equal(hits, []string{"HEAD /busy"}) {
+		t.Errorf("requests with the cache = %q", hits)
+	}
+
+	hits = nil
+	opts.URLTTL = time.Nanosecond
+	check(t, tree, opts, "dead-url")
+	if len(hits) != 7 {
+		t.Errorf("requests after the TTL = %q", hits)
+	}
+}
+
+func TestDeadURLUnreachable(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	srv.Close()
+	tree := newTree(t, map[string]string{"go/a.md": "---\ntitle: A\n---\n\n<" + srv.URL + "/x>\n"})
+	got := check(t, tree, Options{Client: srv.Client()}, "dead-url")
+	if len(got) != 1 || !strings.Contains(got[0].Message, "connection refused") || got[0].Fixable() {
+		t.Errorf("issues = %q", strs(got))
+	}
+}
+
+func TestDeadURLArchive(t *testing.T) {
+	var (
+		mu   sync.Mutex
+		hits []string
+	)
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		hits = append(hits, r.Method+" "+r.URL.Path)
+		mu.Unlock()
+		switch r.URL.Path {
+		case "/gone", "/old", "/broken":
+			w.WriteHeader(http.StatusNotFound)
+		case "/down":
+			w.WriteHeader(http.StatusInternalServerError)
+		case "/available":
+			if r.URL.Query().Get("url") == srv.URL+"/gone" && r.URL.Query().Get("timestamp") == "20240601" {
+				io.WriteString(w, `{"archived_snapshots": {"closest": {"available": true, "status": "200", "url": "http://web.archive.org/web/20240601000000/`+srv.URL+`/gone"}}}`)
+				return
+			}
+			io.WriteString(w, `{"archived_snapshots": {}}`)
+		case "/save/" + srv.URL + "/old":
+			w.Header().Set("Content-Location", "/web/20241001000000/"+srv.URL+"/old")
+		default:
+			if strings.HasPrefix(r.URL.Path, "/save/") {
+				w.WriteHeader(http.StatusBadGateway)
+			}
+		}
+	}))
+	defer srv.Close()
+	tree := newTree(t, map[string]string{
+		"go/a.md": "---\ntitle: A\ndate: 2024-06-01\n---\n\n[docs](" + srv.URL + "/gone), [old](" + srv.URL + "/old)\n",
+		"go/b.md": "---\ntitle: B\ndate: 2025-01-01\n---\n\n[down](" + srv.URL + "/down), [broken](" + srv.URL + "/broken) and [docs](" + srv.URL + "/gone)\n",
+	})
+	opts := Options{
+		URLInterval: time.Millisecond,
+		Client:      srv.Client(),
+		Archive:     wayback.Append,
+		Wayback:     &wayback.Client{HTTP: srv.Client(), Available: srv.URL + "/available", Save: srv.URL + "/save/"},
+	}
+	issues := check(t, tree, opts, "dead-url")
+	got := strs(issues)
+	slices.Sort(got)
+	gone := "https://web.archive.org/web/20240601000000/" + srv.URL + "/gone"
+	old := "https://web.archive.org/web/20241001000000/" + srv.URL + "/old"
+	want := []string{
+		"go/a.md:6: dead link " + srv.URL + "/gone: 404 Not Found, archived at " + gone + " (dead-url)",
+		"go/a.md:6: dead link " + srv.URL + "/old: 404 Not Found, archived at " + old + " (dead-url)",
+		"go/b.md:6: dead link " + srv.URL + "/broken: 404 Not Found, not archived: wayback save: 502 Bad Gateway (dead-url)",
+		"go/b.md:6: dead link " + srv.URL + "/down: 500 Internal Server Error (dead-url)",
+		"go/b.md:6: dead link " + srv.URL + "/gone: 404 Not Found, archived at " + gone + " (dead-url)",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("issues =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if _, err := Fix(tree, issues); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := tree.Read("go/a.md")
+	if want := "[docs](" + srv.URL + "/gone) ([archived](" + gone + ")), [old](" + srv.URL + "/old) ([archived](" + old + "))\n"; !strings.HasSuffix(string(data), want) {
+		t.Errorf("go/a.md =\n%s", data)
+	}
+
+	// Archived links and snapshots are not checked, and the snapshots
+	// found are cached.
+	hits = nil
+	got = strs(check(t, tree, opts, "dead-url"))
+	slices.Sort(got)
+	if len(got) != 2 || !strings.Contains(got[0], "/broken") || !strings.Contains(got[1], "/down") {
+		t.Errorf("issues after the fix =\n%s", strings.Join(got, "\n"))
+	}
+	if !slices.Equal(hits, []string{"GET /available", "GET /save/" + srv.URL + "/broken"}) {
+		t.Errorf("requests after the fix = %q", hits)
+	}
+}
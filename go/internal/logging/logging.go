@@ -0,0 +1,238 @@
+// Package logging carries a structured logger and timing spans through the
+// context of a command, for the subsystems worth watching when something is
+// slow or flaky: the site build, sync and the code runners.
+//
+// Code logs to the logger of its context, which discards everything unless
+// the command line set one up:
+//
+//	logging.From(ctx).Debug("fetched", "remote", url)
+//
+// and times its phases with spans, which are logged at LevelTrace when they
+// end and summed up by a Recorder when one is in the context:
+//
+//	ctx, end := logging.Start(ctx, "render")
+//	defer end()
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LevelTrace is below slog.LevelDebug, for the end of every span.
+const LevelTrace = slog.LevelDebug - 4
+
+// Options configures a logger made by New.
+type Options struct {
+	// Level is the lowest level logged.
+	Level slog.Level
+	// JSON logs one JSON object per line instead of key=value text.
+	JSON bool
+}
+
+// New returns a logger writing to w as opts says.
+func New(w io.Writer, opts Options) *slog.Logger {
+	ho := &slog.HandlerOptions{
+		Level: opts.Level,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.LevelKey && len(groups) == 0 && a.Value.Any() == LevelTrace {
+				a.Value = slog.StringValue("TRACE")
+			}
+			return a
+		},
+	}
+	if opts.JSON {
+		return slog.New(slog.NewJSONHandler(w, ho))
+	}
+	return slog.New(slog.NewTextHandler(w, ho))
+}
+
+type loggerKey struct{}
+
+var discard = slog.New(slog.DiscardHandler)
+
+// With returns a copy of ctx carrying l.
+func With(ctx context.Context, l *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, l)
+}
+
+// From returns the logger of ctx, or one discarding everything.
+func From(ctx context.Context) *slog.Logger {
+	if l, ok := ctx.Value(loggerKey{}).(*slog.Logger); ok {
+		return l
+	}
+	return discard
+}
+
+// span is one timed phase, nested in the span of the context it started in.
+type span struct {
+	name     string
+	start    time.Time
+	took     time.Duration
+	ended    bool
+	children []*span
+}
+
+type spanKey struct{}
+
+// current is the span of a context and the recorder it belongs to.
+type current struct {
+	rec  *Recorder
+	span *span
+	// path names the span and its parents, as logged.
+	path string
+}
+
+// Start starts a span named name, ending when the returned function is
+// called, and returns a context for the spans nested in it. The end is
+// logged at LevelTrace with args and the time taken.
+func Start(ctx context.Context, name string, args ...any) (context.Context, func()) {
+	l := From(ctx)
+	parent, _ := ctx.Value(spanKey{}).(*current)
+	cur := &current{path: name}
+	if parent != nil {
+		cur.rec = parent.rec
+		if parent.path != "" {
+			cur.path = parent.path + "/" + name
+		}
+	}
+	if cur.rec == nil && !l.Enabled(ctx, LevelTrace) {
+		return ctx, func() {}
+	}
+	s := &span{name: name, start: time.Now()}
+	cur.span = s
+	if cur.rec != nil {
+		cur.rec.add(parent, s)
+	}
+	var once sync.Once
+	return context.WithValue(ctx, spanKey{}, cur), func() {
+		once.Do(func() {
+			took := time.Since(s.start)
+			if cur.rec != nil {
+				cur.rec.end(s, took)
+			}
+			l.Log(ctx, LevelTrace, cur.path, append(args, "took", took)...)
+		})
+	}
+}
+
+// Recorder keeps the spans started in its context, to sum them up.
+type Recorder struct {
+	mu    sync.Mutex
+	spans []*span
+}
+
+// NewRecorder returns an empty Recorder.
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+// With returns a copy of ctx whose spans, and the spans nested in them, r
+// records.
+func (r *Recorder) With(ctx context.Context) context.Context {
+	return context.WithValue(ctx, spanKey{}, &current{rec: r})
+}
+
+func (r *Recorder) add(parent *current, s *span) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if parent != nil && parent.span != nil {
+		parent.span.children = append(parent.span.children, s)
+	} else {
+		r.spans = append(r.spans, s)
+	}
+}
+
+func (r *Recorder) end(s *span, took time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s.took, s.ended = took, true
+}
+
+// phase is the spans of one name under one parent, summed up.
+type phase struct {
+	name     string
+	n        int
+	took     time.Duration
+	children []*phase
+}
+
+// sum merges the spans of each name, in the order they first started, with
+// the spans nested in them. Spans not yet ended count the time so far.
+func sum(spans []*span, now time.Time) []*phase {
+	var phases []*phase
+	byName := map[string]*phase{}
+	nested := map[*phase][]*span{}
+	for _, s := range spans {
+		p := byName[s.name]
+		if p == nil {
+			p = &phase{name: s.name}
+			byName[s.name] = p
+			phases = append(phases, p)
+		}
+		p.n++
+		if s.ended {
+			p.took += s.took
+		} else {
+			p.took += now.Sub(s.start)
+		}
+		nested[p] = append(nested[p], s.children...)
+	}
+	for _, p := range phases {
+		p.children = sum(nested[p], now)
+	}
+	return phases
+}
+
+// Write writes the spans recorded as an indented tree, a line for each
+// phase with the time its spans took together and how many there were when
+// more than one. Spans run concurrently may add up to more than the time
+// their parent took.
+func (r *Recorder) Write(w io.Writer) error {
+	r.mu.Lock()
+	phases := sum(r.spans, time.Now())
+	r.mu.Unlock()
+	type line struct{ label, took string }
+	var lines []line
+	var walk func(ps []*phase, depth int)
+	walk = func(ps []*phase, depth int) {
+		for _, p := range ps {
+			label := strings.Repeat("  ", depth) + p.name
+			if p.n > 1 {
+				label += fmt.Sprintf(" ×%d", p.n)
+			}
+			lines = append(lines, line{label, round(p.took).String()})
+			walk(p.children, depth+1)
+		}
+	}
+	walk(phases, 0)
+	width := 0
+	for _, l := range lines {
+		width = max(width, len([]rune(l.label)))
+	}
+	for _, l := range lines {
+		pad := width - len([]rune(l.label))
+		if _, err := fmt.Fprintf(w, "%s%s  %9s\n", l.label, strings.Repeat(" ", pad), l.took); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// round rounds d to three significant digits or so, enough to compare
+// phases by.
+func round(d time.Duration) time.Duration {
+	switch {
+	case d >= time.Second:
+		return d.Round(10 * time.Millisecond)
+	case d >= time.Millisecond:
+		return d.Round(10 * time.Microsecond)
+	default:
+		return d.Round(time.Microsecond)
+	}
+}
@@ -0,0 +1,131 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNew(t *testing.T) {
+	var buf bytes.Buffer
+	l := New(&buf, Options{Level: slog.LevelDebug})
+	l.Debug("fetched", "remote", "origin")
+	l.Log(context.Background(), LevelTrace, "not logged")
+	if got := buf.String(); !strings.Contains(got, "level=DEBUG msg=fetched remote=origin\n") || strings.Contains(got, "not logged") {
+		t.Errorf("text log =\n%s", got)
+	}
+
+	buf.Reset()
+	l = New(&buf, Options{Level: LevelTrace, JSON: true})
+	l.Log(context.Background(), LevelTrace, "render", "pages", 3)
+	var rec map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &rec); err != nil {
+		t.Fatalf("JSON log %q: %v", buf.String(), err)
+	}
+	if rec["level"] != "TRACE" || rec["msg"] != "render" || rec["pages"] != 3.0 {
+		t.Errorf("JSON log = %v", rec)
+	}
+}
+
+func TestFrom(t *testing.T) {
+	ctx := context.Background()
+	if From(ctx).Enabled(ctx, slog.LevelError) {
+		t.Error("the logger of a bare context logs")
+	}
+	var buf bytes.Buffer
+	l := New(&buf, Options{})
+	if From(With(ctx, l)) != l {
+		t.Error("From does not return the logger of With")
+	}
+}
+
+func TestStart(t *testing.T) {
+	// Without a logger at LevelTrace nor a recorder, spans do nothing.
+	ctx := context.Background()
+	if sctx, end := Start(ctx, "render"); sctx != ctx {
+		t.Error("Start made a span nobody sees")
+	} else {
+		end()
+	}
+
+	var buf bytes.Buffer
+	ctx = With(ctx, New(&buf, Options{Level: LevelTrace}))
+	ctx, end := Start(ctx, "build")
+	_, endRender := Start(ctx, "render", "pages", 2)
+	endRender()
+	endRender()
+	end()
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[0], "level=TRACE msg=build/render pages=2 took=") || !strings.Contains(lines[1], "msg=build took=") {
+		t.Errorf("spans logged =\n%s", buf.String())
+	}
+}
+
+func TestRecorder(t *testing.T) {
+	r := NewRecorder()
+	ctx, end := Start(r.With(context.Background()), "til build")
+	for range 2 {
+		pctx, endPage := Start(ctx, "page")
+		_, endRun := Start(pctx, "run go")
+		endRun()
+		endPage()
+	}
+	_, endLoad := Start(ctx, "load")
+	endLoad()
+	_, endOpen := Start(ctx, "open")
+	end()
+	_ = endOpen
+
+	var buf bytes.Buffer
+	if err := r.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var labels []string
+	for _, l := range strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n") {
+		// Each line is the label, padded, two spaces and the time in nine.
+		labels = append(labels, strings.TrimRight(l[:len(l)-11], " "))
+	}
+	want := []string{"til build", "  page ×2", "    run go ×2", "  load", "  open"}
+	if strings.Join(labels, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Write =\n%s\nwant labels %q", buf.String(), want)
+	}
+}
+
+// TestRecorderWrite checks the sums and the layout of spans whose times are
+// known.
+func TestRecorderWrite(t *testing.T) {
+	r := &Recorder{spans: []*span{
+		{name: "til build", ended: true, took: 2 * time.Second, children: []*span{
+			{name: "load", ended: true, took: 1234567 * time.Nanosecond},
+			{name: "render", ended: true, took: 1500 * time.Microsecond},
+			{name: "render", ended: true, took: 500 * time.Microsecond},
+		}},
+	}}
+	var buf bytes.Buffer
+	if err := r.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	want := "til build           2s\n" +
+		"  load          1.23ms\n" +
+		"  render ×2        2ms\n"
+	if buf.String() != want {
+		t.Errorf("Write =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestRound(t *testing.T) {
+	tests := []struct{ in, want time.Duration }{
+		{1234567890 * time.Nanosecond, 1230 * time.Millisecond},
+		{1234567 * time.Nanosecond, 1230 * time.Microsecond},
+		{1234 * time.Nanosecond, time.Microsecond},
+	}
+	for _, tt := range tests {
+		if got := round(tt.in); got != tt.want {
+			t.Errorf("round(%v) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package lsp
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/canhta/til/go/internal/tags"
+)
+
+// complete returns the completions at pos in d: the entries to link to
+// after an unclosed [[, and the tags of the tree in the tags of the
+// frontmatter.
+func (s *Server) complete(d *document, pos position) []completionItem {
+	line := lineAt(d.text, pos.Line)
+	off := offset(line, pos.Character)
+	before := line[:off]
+	if i := strings.LastIndex(before, "[["); i >= 0 && !strings.ContainsAny(before[i+2:], "[]|") {
+		return s.completeLinks(d, pos, line, i+2, off)
+	}
+	if inTags(d.text, pos.Line, before) {
+		start := strings.LastIndexAny(before, " \t,[\"'") + 1
+		return s.completeTags(d, pos, line, start)
+	}
+	return []completionItem{}
+}
+
+// completeLinks completes the target of a wikilink written from start to
+// off in line. The target of a link already closed is replaced to its "|"
+// or "]]"; one not closed yet is closed.
+func (s *Server) completeLinks(d *document, pos position, line string, start, off int) []completionItem {
+	closing := "]]"
+	end := pos
+	if i := strings.IndexAny(line[off:], "[]|"); i >= 0 && line[off+i] != '[' && (line[off+i] == '|' || strings.HasPrefix(line[off+i:], "]]")) {
+		closing = ""
+		end.Character = column(line, off+i)
+	}
+	rng := span{position{pos.Line, column(line, start)}, end}
+	items := []completionItem{}
+	for _, e := range s.entries() {
+		if e.Path == d.path {
+			continue
+		}
+		id := e.ID()
+		items = append(items, completionItem{
+			Label:      id,
+			Kind:       completionReference,
+			Detail:     e.Meta.Title,
+			FilterText: id + " " + e.Meta.Title,
+			TextEdit:   &textEdit{Range: rng, NewText: id + closing},
+		})
+	}
+	return items
+}
+
+// completeTags completes the tag written from start to the position in
+// line with the tags of the tree, most used first, leaving out those d
+// has already.
+func (s *Server) completeTags(d *document, pos position, line string, start int) []completionItem {
+	var have []string
+	if d.entry != nil {
+		have = d.entry.Meta.Tags
+	}
+	rng := span{position{pos.Line, column(line, start)}, pos}
+	items := []completionItem{}
+	for i, c := range tags.Counts(s.entries()) {
+		if slices.Contains(have, c.Tag) {
+			continue
+		}
+		items = append(items, completionItem{
+			Label:    c.Tag,
+			Kind:     completionKeyword,
+			Detail:   entryCount(c.Count),
+			SortText: fmt.Sprintf("%06d", i),
+			TextEdit: &textEdit{Range: rng, NewText: c.Tag},
+		})
+	}
+	return items
+}
+
+var (
+	tagsKeyRE  = regexp.MustCompile(`^tags:`)
+	listItemRE = regexp.MustCompile(`^\s*-(\s|$)`)
+)
+
+// inTags reports whether the nth line of text, of which before is the
+// part before the cursor, is within the tags of the frontmatter: after
+// "tags:" on its line, in a flow list or a plain value, or in an item of
+// the block list following it.
+func inTags(text string, n int, before string) bool {
+	if lineAt(text, 0) != "---" || n == 0 {
+		return false
+	}
+	for i := 1; i < n; i++ {
+		if l := lineAt(text, i); l == "---" || l == "..." {
+			return false
+		}
+	}
+	if tagsKeyRE.MatchString(before) {
+		return true
+	}
+	if !listItemRE.MatchString(before) {
+		return false
+	}
+	for i := n - 1; i > 0; i-- {
+		l := lineAt(text, i)
+		switch {
+		case listItemRE.MatchString(l):
+			continue
+		case tagsKeyRE.MatchString(l):
+			return strings.TrimSpace(strings.TrimPrefix(l, "tags:")) == ""
+		}
+		return false
+	}
+	return false
+}
+
+func entryCount(n int) string {
+	if n == 1 {
+		return "1 entry"
+	}
+	return fmt.Sprintf("%d entries", n)
+}
@@ -0,0 +1,28 @@
+package lsp
+
+import "testing"
+
+func TestInTags(t *testing.T) {
+	const text = "---\ntitle: Slices\ntags: [go, sl\ntopics:\n  - go\ntags2:\n---\n\ntags: not frontmatter\n"
+	block := "---\ntags:\n  - go\n  - sl\n---\n"
+	tests := []struct {
+		text   string
+		n      int
+		before string
+		want   bool
+	}{
+		{text, 2, "tags: [go, sl", true},
+		{text, 1, "title: Sl", false},
+		{text, 4, "  - go", false},
+		{text, 8, "tags: not", false},
+		{block, 3, "  - sl", true},
+		{block, 2, "  - ", true},
+		{"---\ntags: [go]\n  - go\n---\n", 2, "  - go", false},
+		{"# Slices\ntags: x\n", 1, "tags: x", false},
+	}
+	for _, tt := range tests {
+		if got := inTags(tt.text, tt.n, tt.before); got != tt.want {
+			t.Errorf("inTags(%q, %d, %q) = %v, want %v", tt.text, tt.n, tt.before, got, tt.want)
+		}
+	}
+}
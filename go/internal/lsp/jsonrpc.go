@@ -0,0 +1,114 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"strings"
+)
+
+// JSON-RPC error codes.
+const (
+	codeInvalidRequest = -32600
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+	codeInternalError  = -32603
+)
+
+// message is a JSON-RPC request or notification, which has no ID, from
+// the client. Responses to requests of the server's, which it sends none
+// of, are messages without a method.
+type message struct {
+	ID     json.RawMessage `json:"id,omitempty"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string { return e.Message }
+
+// conn reads and writes JSON-RPC messages framed by a Content-Length
+// header, as the protocol sends them over stdin and stdout.
+type conn struct {
+	r *textproto.Reader
+	w io.Writer
+}
+
+func newConn(r io.Reader, w io.Writer) *conn {
+	return &conn{r: textproto.NewReader(bufio.NewReader(r)), w: w}
+}
+
+// read reads the next message, returning io.EOF when the input ends
+// between two.
+func (c *conn) read() (*message, error) {
+	h, err := c.r.ReadMIMEHeader()
+	if err != nil {
+		if errors.Is(err, io.EOF) && len(h) == 0 {
+			return nil, io.EOF
+		}
+		return nil, fmt.Errorf("reading header: %w", err)
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(h.Get("Content-Length")))
+	if err != nil || n < 0 {
+		return nil, fmt.Errorf("bad Content-Length %q", h.Get("Content-Length"))
+	}
+	data := make([]byte, n)
+	if _, err := io.ReadFull(c.r.R, data); err != nil {
+		return nil, fmt.Errorf("reading message: %w", err)
+	}
+	var m message
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("decoding message: %w", err)
+	}
+	return &m, nil
+}
+
+// reply answers the request with the given ID with result, or err when it
+// is not nil.
+func (c *conn) reply(id json.RawMessage, result any, err error) error {
+	if err == nil {
+		return c.write(struct {
+			JSONRPC string          `json:"jsonrpc"`
+			ID      json.RawMessage `json:"id"`
+			Result  any             `json:"result"`
+		}{"2.0", id, result})
+	}
+	var re *rpcError
+	if !errors.As(err, &re) {
+		re = &rpcError{Code: codeInternalError, Message: err.Error()}
+	}
+	return c.write(struct {
+		JSONRPC string          `json:"jsonrpc"`
+		ID      json.RawMessage `json:"id"`
+		Error   *rpcError       `json:"error"`
+	}{"2.0", id, re})
+}
+
+// notify sends the notification method with params.
+func (c *conn) notify(method string, params any) error {
+	return c.write(struct {
+		JSONRPC string `json:"jsonrpc"`
+		Method  string `json:"method"`
+		Params  any    `json:"params"`
+	}{"2.0", method, params})
+}
+
+func (c *conn) write(v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(c.w, "Content-Length: %d\r\n\r\n", len(data)); err != nil {
+		return err
+	}
+	_, err = c.w.Write(data)
+	return err
+}
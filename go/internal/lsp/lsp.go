@@ -0,0 +1,471 @@
+// Package lsp serves a notes tree to editors over the Language Server
+// Protocol, so that any editor with a client edits entries with the help of
+// the tree: completion of tags in the frontmatter and of entries in
+// [[wikilinks]], going to the entry a link points to, a preview of it on
+// hover, and the problems til lint finds as diagnostics.
+//
+// Documents are synced in full on every change. The entries on disk are
+// loaded when the server starts and again whenever a document is saved,
+// with the documents open taking the place of their files.
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/canhta/til/go/internal/links"
+	"github.com/canhta/til/go/internal/lint"
+	"github.com/canhta/til/go/internal/logging"
+	"github.com/canhta/til/go/internal/notes"
+	"github.com/canhta/til/go/pkg/entry"
+	"github.com/canhta/til/go/pkg/render"
+)
+
+// Options configures a Server.
+type Options struct {
+	// Lint and Rules are what diagnostics are checked with.
+	Lint  lint.Options
+	Rules []lint.Rule
+}
+
+// Server is a language server for the entries of a tree.
+type Server struct {
+	tree *notes.Tree
+	opts Options
+	// root is the absolute root of the tree, with symlinks resolved.
+	root string
+	out  *conn
+	// all are the entries on disk as of the last load, and docs the
+	// documents open, by path.
+	all  []*entry.Entry
+	docs map[string]*document
+	// index resolves links among all and the documents open; nil when
+	// either changed since it was made.
+	index    *links.Index
+	loaded   bool
+	shutdown bool
+}
+
+// document is an entry open in the editor.
+type document struct {
+	uri     string
+	path    string
+	version int
+	text    string
+	// entry is the text parsed, or nil when it does not parse, as err
+	// says.
+	entry *entry.Entry
+	err   error
+}
+
+// New returns a Server for tree.
+func New(tree *notes.Tree, opts Options) *Server {
+	return &Server{tree: tree, opts: opts, docs: map[string]*document{}}
+}
+
+// errExit is returned by handlers to stop serving.
+var errExit = errors.New("exit")
+
+// Serve reads requests from r and writes responses to w until the client
+// sends exit or r ends. It returns an error when the client exits without
+// shutting the server down first, as the protocol has the exit status say.
+func (s *Server) Serve(ctx context.Context, r io.Reader, w io.Writer) error {
+	root, err := filepath.Abs(s.tree.Root)
+	if err == nil {
+		root, err = filepath.EvalSymlinks(root)
+	}
+	if err != nil {
+		return err
+	}
+	s.root = root
+	s.out = newConn(r, w)
+	log := logging.From(ctx)
+	for {
+		m, err := s.out.read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if m.Method == "" {
+			continue
+		}
+		start := time.Now()
+		result, err := s.handle(ctx, m)
+		if errors.Is(err, errExit) {
+			if !s.shutdown {
+				return errors.New("exit before shutdown")
+			}
+			return nil
+		}
+		if err != nil {
+			log.Debug("lsp "+m.Method+" failed", "err", err, "took", time.Since(start))
+		} else {
+			log.Debug("lsp "+m.Method, "took", time.Since(start))
+		}
+		if m.ID == nil {
+			continue
+		}
+		if err := s.out.reply(m.ID, result, err); err != nil {
+			return err
+		}
+	}
+}
+
+// handle handles the request or notification m, returning the result of
+// a request.
+func (s *Server) handle(ctx context.Context, m *message) (any, error) {
+	if s.shutdown && m.Method != "exit" {
+		return nil, &rpcError{Code: codeInvalidRequest, Message: "server is shut down"}
+	}
+	decode := func(v any) error {
+		if err := json.Unmarshal(m.Params, v); err != nil {
+			return &rpcError{Code: codeInvalidParams, Message: err.Error()}
+		}
+		return nil
+	}
+	switch m.Method {
+	case "initialize":
+		return map[string]any{
+			"capabilities": map[string]any{
+				"textDocumentSync": map[string]any{
+					"openClose": true,
+					"change":    1, // full
+					"save":      true,
+				},
+				"completionProvider": map[string]any{"triggerCharacters": []string{"[", " ", ","}},
+				"definitionProvider": true,
+				"hoverProvider":      true,
+			},
+			"serverInfo": map[string]any{"name": "til"},
+		}, nil
+	case "initialized":
+		s.load()
+		return nil, nil
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+	case "exit":
+		return nil, errExit
+	case "textDocument/didOpen":
+		var p didOpenParams
+		if err := decode(&p); err != nil {
+			return nil, err
+		}
+		if !s.loaded {
+			s.load()
+		}
+		if d := s.open(p.TextDocument.URI); d != nil {
+			s.update(d, p.TextDocument.Version, p.TextDocument.Text)
+			return nil, s.diagnose(ctx, d)
+		}
+		return nil, nil
+	case "textDocument/didChange":
+		var p didChangeParams
+		if err := decode(&p); err != nil {
+			return nil, err
+		}
+		d := s.doc(p.TextDocument.URI)
+		if d == nil || len(p.ContentChanges) == 0 {
+			return nil, nil
+		}
+		s.update(d, p.TextDocument.Version, p.ContentChanges[len(p.ContentChanges)-1].Text)
+		return nil, s.diagnose(ctx, d)
+	case "textDocument/didSave", "workspace/didChangeWatchedFiles":
+		s.load()
+		for _, d := range s.docs {
+			if err := s.diagnose(ctx, d); err != nil {
+				return nil, err
+			}
+		}
+		return nil, nil
+	case "textDocument/didClose":
+		var p documentParams
+		if err := decode(&p); err != nil {
+			return nil, err
+		}
+		if d := s.doc(p.TextDocument.URI); d != nil {
+			delete(s.docs, d.path)
+			s.index = nil
+			return nil, s.out.notify("textDocument/publishDiagnostics", publishDiagnosticsParams{URI: d.uri, Diagnostics: []diagnostic{}})
+		}
+		return nil, nil
+	case "textDocument/completion":
+		var p positionParams
+		if err := decode(&p); err != nil {
+			return nil, err
+		}
+		items := []completionItem{}
+		if d := s.doc(p.TextDocument.URI); d != nil {
+			items = s.complete(d, p.Position)
+		}
+		return completionList{Items: items}, nil
+	case "textDocument/definition":
+		var p positionParams
+		if err := decode(&p); err != nil {
+			return nil, err
+		}
+		if d := s.doc(p.TextDocument.URI); d != nil {
+			if loc := s.definition(d, p.Position); loc != nil {
+				return loc, nil
+			}
+		}
+		return nil, nil
+	case "textDocument/hover":
+		var p positionParams
+		if err := decode(&p); err != nil {
+			return nil, err
+		}
+		if d := s.doc(p.TextDocument.URI); d != nil {
+			if h := s.hover(d, p.Position); h != nil {
+				return h, nil
+			}
+		}
+		return nil, nil
+	}
+	if m.ID == nil || strings.HasPrefix(m.Method, "$/") {
+		return nil, nil
+	}
+	return nil, &rpcError{Code: codeMethodNotFound, Message: "method not supported: " + m.Method}
+}
+
+// load reloads the entries on disk, keeping those loaded before when they
+// fail to, as while an entry being edited is broken.
+func (s *Server) load() {
+	s.loaded = true
+	all, err := s.tree.Entries()
+	if err != nil {
+		_ = s.out.notify("window/showMessage", showMessageParams{Type: messageWarning, Message: "til: " + err.Error()})
+		return
+	}
+	s.all, s.index = all, nil
+}
+
+// path returns the path in the tree of the entry file uri names, or "" when
+// it names none.
+func (s *Server) path(uri string) string {
+	file := uriFile(uri)
+	if file == "" || !strings.HasSuffix(file, ".md") {
+		return ""
+	}
+	if dir, err := filepath.EvalSymlinks(filepath.Dir(file)); err == nil {
+		file = filepath.Join(dir, filepath.Base(file))
+	}
+	rel, err := filepath.Rel(s.root, file)
+	if err != nil {
+		return ""
+	}
+	rel = filepath.ToSlash(rel)
+	if strings.HasPrefix(rel, "../") || !strings.Contains(rel, "/") {
+		return ""
+	}
+	for _, dir := range strings.Split(rel, "/")[:strings.Count(rel, "/")] {
+		if notes.Skip(dir) {
+			return ""
+		}
+	}
+	return rel
+}
+
+// open returns the document of uri, opening it, or nil when uri names no
+// entry file.
+func (s *Server) open(uri string) *document {
+	p := s.path(uri)
+	if p == "" {
+		return nil
+	}
+	d := &document{uri: uri, path: p}
+	s.docs[p] = d
+	return d
+}
+
+// doc returns the open document of uri, or nil.
+func (s *Server) doc(uri string) *document {
+	return s.docs[s.path(uri)]
+}
+
+// update sets the text of d and parses it.
+func (s *Server) update(d *document, version int, text string) {
+	d.version, d.text = version, text
+	d.entry, d.err = entry.Parse(d.path, []byte(text))
+	s.index = nil
+}
+
+// entries returns the entries of the tree, with those of the documents
+// open as they are in the editor.
+func (s *Server) entries() []*entry.Entry {
+	out := make([]*entry.Entry, 0, len(s.all)+len(s.docs))
+	seen := map[string]bool{}
+	for _, e := range s.all {
+		if d := s.docs[e.Path]; d != nil {
+			seen[e.Path] = true
+			if d.entry != nil {
+				e = d.entry
+			}
+		}
+		out = append(out, e)
+	}
+	for p, d := range s.docs {
+		if !seen[p] && d.entry != nil {
+			out = append(out, d.entry)
+		}
+	}
+	return out
+}
+
+func (s *Server) links() *links.Index {
+	if s.index == nil {
+		s.index = links.NewIndex(s.entries())
+	}
+	return s.index
+}
+
+// uri returns the URI of the entry file at the path p.
+func (s *Server) uri(p string) string {
+	if d := s.docs[p]; d != nil {
+		return d.uri
+	}
+	return fileURI(filepath.Join(s.root, filepath.FromSlash(p)))
+}
+
+var yamlLineRE = regexp.MustCompile(`yaml: line (\d+):`)
+
+// diagnose publishes the problems of d: its frontmatter failing to parse,
+// or else the issues the lint rules find.
+func (s *Server) diagnose(ctx context.Context, d *document) error {
+	diags := []diagnostic{}
+	if d.err != nil {
+		msg := strings.TrimPrefix(d.err.Error(), d.path+": ")
+		// YAML counts the lines of the frontmatter, which starts on the
+		// second line of the file.
+		line := 0
+		if m := yamlLineRE.FindStringSubmatch(msg); m != nil {
+			line, _ = strconv.Atoi(m[1])
+		}
+		diags = append(diags, diagnostic{Range: s.lineSpan(d, line), Severity: severityError, Source: "til", Message: msg})
+	} else {
+		c := lint.NewContext(s.tree, s.entries(), []*entry.Entry{d.entry}, s.opts.Lint)
+		issues, err := lint.Run(ctx, c, s.opts.Rules)
+		if err != nil {
+			return err
+		}
+		for _, is := range issues {
+			if is.Path != d.path {
+				continue
+			}
+			diags = append(diags, diagnostic{
+				Range:    s.lineSpan(d, max(is.Line-1, 0)),
+				Severity: severityWarning,
+				Code:     is.Rule,
+				Source:   "til",
+				Message:  is.Message,
+			})
+		}
+	}
+	return s.out.notify("textDocument/publishDiagnostics", publishDiagnosticsParams{URI: d.uri, Version: d.version, Diagnostics: diags})
+}
+
+// lineSpan returns the span of the nth line of d, 0-based.
+func (s *Server) lineSpan(d *document, n int) span {
+	return span{position{n, 0}, position{n, column(lineAt(d.text, n), len(lineAt(d.text, n)))}}
+}
+
+// linkAt returns the link of d at pos, if there is one.
+func (s *Server) linkAt(d *document, pos position) (links.Link, bool) {
+	if d.entry == nil {
+		return links.Link{}, false
+	}
+	line := lineAt(d.text, pos.Line)
+	off := offset(line, pos.Character)
+	bodyLine := pos.Line + 2 - d.entry.BodyLine
+	for _, l := range links.Parse(d.entry.Body) {
+		if l.Line == bodyLine && l.Start <= off && off <= l.End {
+			return l, true
+		}
+	}
+	return links.Link{}, false
+}
+
+// definition returns the location of the entry the link at pos points to.
+func (s *Server) definition(d *document, pos position) *location {
+	l, ok := s.linkAt(d, pos)
+	if !ok {
+		return nil
+	}
+	to, res := s.links().Resolve(d.path, l)
+	if res != links.Resolved {
+		return nil
+	}
+	return &location{URI: s.uri(to.Path)}
+}
+
+// previewLines and previewBytes bound the part of an entry's body a hover
+// shows.
+const (
+	previewLines = 12
+	previewBytes = 1200
+)
+
+// hover describes the entry the link at pos points to, with its title,
+// path and tags and the start of its body.
+func (s *Server) hover(d *document, pos position) *hover {
+	l, ok := s.linkAt(d, pos)
+	if !ok {
+		return nil
+	}
+	line := lineAt(d.text, pos.Line)
+	rng := &span{position{pos.Line, column(line, l.Start)}, position{pos.Line, column(line, l.End)}}
+	to, res := s.links().Resolve(d.path, l)
+	var b strings.Builder
+	switch res {
+	case links.Missing:
+		fmt.Fprintf(&b, "No entry matches `%s`.", l.Target)
+	case links.Ambiguous:
+		fmt.Fprintf(&b, "`%s` names several entries.", l.Target)
+	default:
+		fmt.Fprintf(&b, "**%s**  \n`%s`", to.Meta.Title, to.Path)
+		if len(to.Meta.Tags) > 0 {
+			fmt.Fprintf(&b, " · %s", strings.Join(to.Meta.Tags, ", "))
+		}
+		if !to.Meta.Date.IsZero() {
+			fmt.Fprintf(&b, " · %s", to.Meta.Date.Format(time.DateOnly))
+		}
+		if body := preview(to.Body); body != "" {
+			b.WriteString("\n\n---\n\n" + body)
+		}
+	}
+	return &hover{Contents: markupContent{Kind: "markdown", Value: b.String()}, Range: rng}
+}
+
+// preview returns the start of body, without its title, cut at a line
+// within previewLines and previewBytes. A code block cut short is closed.
+func preview(body []byte) string {
+	text := strings.TrimSpace(string(render.StripTitle(body)))
+	lines := strings.Split(text, "\n")
+	var out []string
+	n, fence := 0, false
+	for i, l := range lines {
+		if i == previewLines || n+len(l) > previewBytes && i > 0 {
+			if fence {
+				out = append(out, "```")
+			}
+			out = append(out, "", "…")
+			break
+		}
+		if t := strings.TrimSpace(l); strings.HasPrefix(t, "```") || strings.HasPrefix(t, "~~~") {
+			fence = !fence
+		}
+		out = append(out, l)
+		n += len(l) + 1
+	}
+	return strings.Join(out, "\n")
+}
@@ -0,0 +1,276 @@
+package lsp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/canhta/til/go/internal/lint"
+	"github.com/canhta/til/go/internal/notes"
+)
+
+// response is a message from the server: a response to a request, or a
+// notification.
+type response struct {
+	ID     json.RawMessage `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+	Result json.RawMessage `json:"result"`
+	Error  *rpcError       `json:"error"`
+}
+
+// session sends the client messages msgs, numbering the requests from 1,
+// and returns the messages the server sent and the error Serve returned.
+func session(t *testing.T, s *Server, msgs ...map[string]any) ([]response, error) {
+	t.Helper()
+	var in bytes.Buffer
+	c := newConn(nil, &in)
+	id := 0
+	for _, m := range msgs {
+		m["jsonrpc"] = "2.0"
+		if _, ok := m["notify"]; ok {
+			delete(m, "notify")
+		} else {
+			id++
+			m["id"] = id
+		}
+		if err := c.write(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var out bytes.Buffer
+	err := s.Serve(context.Background(), &in, &out)
+	var got []response
+	r := newConn(&out, nil)
+	for {
+		h, herr := r.r.ReadMIMEHeader()
+		if herr != nil {
+			break
+		}
+		n, err := strconv.Atoi(h.Get("Content-Length"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		data := make([]byte, n)
+		if _, err := io.ReadFull(r.r.R, data); err != nil {
+			t.Fatal(err)
+		}
+		var m response
+		if err := json.Unmarshal(data, &m); err != nil {
+			t.Fatalf("decoding %s: %v", data, err)
+		}
+		got = append(got, m)
+	}
+	return got, err
+}
+
+func request(method string, params any) map[string]any {
+	return map[string]any{"method": method, "params": params}
+}
+
+func notification(method string, params any) map[string]any {
+	return map[string]any{"method": method, "params": params, "notify": true}
+}
+
+// result returns the result of the response to request id, decoded into
+// v, failing when it is an error.
+func result(t *testing.T, rs []response, id int, v any) {
+	t.Helper()
+	for _, r := range rs {
+		if string(r.ID) != strconv.Itoa(id) {
+			continue
+		}
+		if r.Error != nil {
+			t.Fatalf("request %d failed: %v", id, r.Error)
+		}
+		if err := json.Unmarshal(r.Result, v); err != nil {
+			t.Fatalf("decoding result %s: %v", r.Result, err)
+		}
+		return
+	}
+	t.Fatalf("no response to request %d", id)
+}
+
+func newServer(t *testing.T, files map[string]string) (*Server, string) {
+	t.Helper()
+	tree := notes.Open(t.TempDir())
+	for p, data := range files {
+		if err := tree.Write(p, []byte(data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	rules, err := lint.Select([]string{"broken-link"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return New(tree, Options{Rules: rules}), tree.Root
+}
+
+const mapsText = "---\ntitle: Maps\ntags: [go]\n---\n\nSee [[slices]] and [[nowhere]].\n"
+
+func TestServe(t *testing.T) {
+	s, root := newServer(t, map[string]string{
+		"go/slices.md":  "---\ntitle: Slices\ntags: [go, memory]\ndate: 2024-06-01\n---\n\n# Slices\n\nSlices share arrays.\n",
+		"git/rebase.md": "---\ntitle: Rebase\ntags: [git, memory]\n---\n\nRebase onto main.\n",
+		"go/maps.md":    "---\ntitle: Maps\n---\n",
+	})
+	uri := fileURI(filepath.Join(root, "go", "maps.md"))
+	doc := map[string]any{"uri": uri}
+	at := func(line, char int) map[string]any {
+		return map[string]any{"textDocument": doc, "position": map[string]any{"line": line, "character": char}}
+	}
+	rs, err := session(t, s,
+		request("initialize", map[string]any{}),
+		notification("initialized", map[string]any{}),
+		notification("textDocument/didOpen", map[string]any{"textDocument": map[string]any{"uri": uri, "version": 1, "text": mapsText}}),
+		request("textDocument/definition", at(5, 7)),
+		request("textDocument/hover", at(5, 7)),
+		request("textDocument/hover", at(5, 22)),
+		request("textDocument/definition", at(5, 1)),
+		notification("textDocument/didChange", map[string]any{
+			"textDocument":   map[string]any{"uri": uri, "version": 2},
+			"contentChanges": []map[string]any{{"text": "---\ntitle: Maps\ntags: [go, me]\n---\n\nSee [[sl\n"}},
+		}),
+		request("textDocument/completion", at(5, 8)),
+		request("textDocument/completion", at(2, 13)),
+		request("textDocument/completion", at(0, 0)),
+		request("workspace/symbol", map[string]any{}),
+		request("shutdown", nil),
+		notification("exit", nil),
+	)
+	if err != nil {
+		t.Fatalf("Serve = %v", err)
+	}
+
+	var init struct {
+		Capabilities struct {
+			HoverProvider bool `json:"hoverProvider"`
+		} `json:"capabilities"`
+	}
+	result(t, rs, 1, &init)
+	if !init.Capabilities.HoverProvider {
+		t.Errorf("initialize = %+v", init)
+	}
+
+	var diags []publishDiagnosticsParams
+	for _, r := range rs {
+		if r.Method == "textDocument/publishDiagnostics" {
+			var p publishDiagnosticsParams
+			if err := json.Unmarshal(r.Params, &p); err != nil {
+				t.Fatal(err)
+			}
+			diags = append(diags, p)
+		}
+	}
+	if len(diags) != 2 || diags[0].URI != uri || diags[0].Version != 1 || len(diags[0].Diagnostics) != 1 {
+		t.Fatalf("diagnostics = %+v", diags)
+	}
+	if d := diags[0].Diagnostics[0]; d.Code != "broken-link" || d.Message != "[[nowhere]] matches no entry" || d.Range != (span{position{5, 0}, position{5, 31}}) {
+		t.Errorf("diagnostic = %+v", d)
+	}
+	// [[sl is no link yet.
+	if d := diags[1]; d.Version != 2 || len(d.Diagnostics) != 0 {
+		t.Errorf("diagnostics after the change = %+v", d)
+	}
+
+	var loc location
+	result(t, rs, 2, &loc)
+	if want := fileURI(filepath.Join(root, "go", "slices.md")); loc.URI != want {
+		t.Errorf("definition = %s, want %s", loc.URI, want)
+	}
+	var h hover
+	result(t, rs, 3, &h)
+	if want := "**Slices**  \n`go/slices.md` · go, memory · 2024-06-01\n\n---\n\nSlices share arrays."; h.Contents.Value != want || *h.Range != (span{position{5, 4}, position{5, 14}}) {
+		t.Errorf("hover = %q %+v, want %q", h.Contents.Value, h.Range, want)
+	}
+	result(t, rs, 4, &h)
+	if h.Contents.Value != "No entry matches `nowhere`." {
+		t.Errorf("hover of a broken link = %q", h.Contents.Value)
+	}
+	var none *location
+	result(t, rs, 5, &none)
+	if none != nil {
+		t.Errorf("definition off a link = %+v", none)
+	}
+
+	var links, tags, other completionList
+	result(t, rs, 6, &links)
+	var got []string
+	for _, it := range links.Items {
+		got = append(got, it.Label+" "+it.TextEdit.NewText)
+		if it.TextEdit.Range != (span{position{5, 6}, position{5, 8}}) {
+			t.Errorf("completion %s replaces %+v", it.Label, it.TextEdit.Range)
+		}
+	}
+	if strings.Join(got, ", ") != "git/rebase git/rebase]], go/slices go/slices]]" {
+		t.Errorf("link completions = %q", got)
+	}
+	result(t, rs, 7, &tags)
+	got = nil
+	for _, it := range tags.Items {
+		got = append(got, it.Label+" "+it.Detail)
+	}
+	if strings.Join(got, ", ") != "memory 2 entries, git 1 entry" {
+		t.Errorf("tag completions = %q", got)
+	}
+	result(t, rs, 8, &other)
+	if len(other.Items) != 0 {
+		t.Errorf("completions off links and tags = %+v", other.Items)
+	}
+	for _, r := range rs {
+		if string(r.ID) == "9" && (r.Error == nil || r.Error.Code != codeMethodNotFound) {
+			t.Errorf("workspace/symbol = %+v", r.Error)
+		}
+	}
+}
+
+func TestServeExit(t *testing.T) {
+	s, _ := newServer(t, nil)
+	if _, err := session(t, s, notification("exit", nil)); err == nil || err.Error() != "exit before shutdown" {
+		t.Errorf("exit before shutdown = %v", err)
+	}
+	s, _ = newServer(t, nil)
+	rs, err := session(t, s, request("shutdown", nil), request("initialize", map[string]any{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rs) != 2 || rs[1].Error == nil || rs[1].Error.Code != codeInvalidRequest {
+		t.Errorf("request after shutdown = %+v", rs)
+	}
+}
+
+func TestPath(t *testing.T) {
+	s, root := newServer(t, nil)
+	s.root = root
+	tests := []struct{ file, want string }{
+		{filepath.Join(root, "go", "slices.md"), "go/slices.md"},
+		{filepath.Join(root, "README.md"), ""},
+		{filepath.Join(root, "go", "slices.txt"), ""},
+		{filepath.Join(root, ".til", "x", "a.md"), ""},
+		{filepath.Join(filepath.Dir(root), "go", "a.md"), ""},
+	}
+	for _, tt := range tests {
+		if got := s.path(fileURI(tt.file)); got != tt.want {
+			t.Errorf("path(%s) = %q, want %q", tt.file, got, tt.want)
+		}
+	}
+	if got := s.path("untitled:Untitled-1"); got != "" {
+		t.Errorf("path of an untitled document = %q", got)
+	}
+}
+
+func TestPreview(t *testing.T) {
+	if got := preview([]byte("# Title\n\nFirst.\n")); got != "First." {
+		t.Errorf("preview = %q", got)
+	}
+	long := "Intro.\n\n```go\n" + strings.Repeat("x := 1\n", 20) + "```\n"
+	got := preview([]byte(long))
+	if lines := strings.Split(got, "\n"); len(lines) != previewLines+3 || !strings.HasSuffix(got, "x := 1\n```\n\n…") {
+		t.Errorf("preview of a long entry =\n%s", got)
+	}
+}
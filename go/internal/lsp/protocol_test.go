@@ -0,0 +1,48 @@
+package lsp
+
+import (
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestColumn(t *testing.T) {
+	// é is two bytes and one UTF-16 unit, 🙂 four bytes and two units.
+	line := "é🙂x"
+	for _, tt := range []struct{ off, col int }{{0, 0}, {2, 1}, {6, 3}, {7, 4}, {99, 4}} {
+		if got := column(line, tt.off); got != tt.col {
+			t.Errorf("column(%d) = %d, want %d", tt.off, got, tt.col)
+		}
+		if tt.off <= len(line) {
+			if got := offset(line, tt.col); got != tt.off {
+				t.Errorf("offset(%d) = %d, want %d", tt.col, got, tt.off)
+			}
+		}
+	}
+	if got := offset(line, 99); got != len(line) {
+		t.Errorf("offset past the end = %d", got)
+	}
+}
+
+func TestLineAt(t *testing.T) {
+	text := "one\r\ntwo\nthree"
+	for n, want := range []string{"one", "two", "three", ""} {
+		if got := lineAt(text, n); got != want {
+			t.Errorf("lineAt(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
+
+func TestURI(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "go", "a b.md")
+	uri := fileURI(file)
+	if runtime.GOOS != "windows" && uri != "file://"+filepath.ToSlash(filepath.Dir(file))+"/a%20b.md" {
+		t.Errorf("fileURI = %s", uri)
+	}
+	if got := uriFile(uri); got != file {
+		t.Errorf("uriFile(%s) = %s, want %s", uri, got, file)
+	}
+	if got := uriFile("untitled:Untitled-1"); got != "" {
+		t.Errorf("uriFile of an untitled document = %q", got)
+	}
+}
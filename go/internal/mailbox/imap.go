@@ -0,0 +1,312 @@
+package mailbox
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Security is how the connection to a server is protected.
+const (
+	// TLS is TLS from the start, as on port 993.
+	TLS = "tls"
+	// StartTLS upgrades a plain connection, as on port 143.
+	StartTLS = "starttls"
+	// None sends everything, password included, in the clear. It is only
+	// meant for servers on the same machine, such as mail bridges.
+	None = "none"
+)
+
+// Server is an IMAP server and the account read on it.
+type Server struct {
+	Host string
+	// Port defaults to 993, or 143 unless Security is TLS.
+	Port int
+	// Security defaults to TLS.
+	Security string
+	Username string
+	Password string
+}
+
+// maxLiteral bounds the size of a message read from the server.
+const maxLiteral = 64 << 20
+
+// Client is a connection to an IMAP server speaking enough of IMAP4rev1
+// (RFC 3501) to read a folder and move what was read out of it.
+type Client struct {
+	conn net.Conn
+	r    *bufio.Reader
+	tag  int
+	caps map[string]bool
+}
+
+// response is an untagged response: its text, with the literals sent in it
+// in order and replaced by their {size}.
+type response struct {
+	text     string
+	literals [][]byte
+}
+
+// Dial connects to s and logs in.
+func Dial(ctx context.Context, s Server) (*Client, error) {
+	if s.Host == "" {
+		return nil, errors.New("imap: host must be set")
+	}
+	security := s.Security
+	if security == "" {
+		security = TLS
+	}
+	port := s.Port
+	if port == 0 {
+		port = 143
+		if security == TLS {
+			port = 993
+		}
+	}
+	addr := net.JoinHostPort(s.Host, strconv.Itoa(port))
+	var (
+		conn net.Conn
+		err  error
+	)
+	switch security {
+	case TLS:
+		d := &tls.Dialer{NetDialer: &net.Dialer{Timeout: 30 * time.Second}, Config: &tls.Config{ServerName: s.Host}}
+		conn, err = d.DialContext(ctx, "tcp", addr)
+	case StartTLS, None:
+		d := &net.Dialer{Timeout: 30 * time.Second}
+		conn, err = d.DialContext(ctx, "tcp", addr)
+	default:
+		return nil, fmt.Errorf("imap: unknown security %q: want tls, starttls or none", security)
+	}
+	if err != nil {
+		return nil, err
+	}
+	c := &Client{conn: conn, r: bufio.NewReader(conn)}
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+	if err := c.login(s, security); err != nil {
+		conn.Close()
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *Client) login(s Server, security string) error {
+	greeting, err := c.readLine()
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(greeting.text, "* OK") && !strings.HasPrefix(greeting.text, "* PREAUTH") {
+		return fmt.Errorf("imap: %s", greeting.text)
+	}
+	if err := c.capabilities(); err != nil {
+		return err
+	}
+	if security == StartTLS {
+		if !c.caps["STARTTLS"] {
+			return errors.New("imap: server does not offer STARTTLS")
+		}
+		if _, err := c.cmd("STARTTLS"); err != nil {
+			return err
+		}
+		tc := tls.Client(c.conn, &tls.Config{ServerName: s.Host})
+		if err := tc.Handshake(); err != nil {
+			return err
+		}
+		c.conn, c.r = tc, bufio.NewReader(tc)
+		if err := c.capabilities(); err != nil {
+			return err
+		}
+	}
+	if strings.HasPrefix(greeting.text, "* PREAUTH") {
+		return nil
+	}
+	if _, err := c.cmd("LOGIN " + quote(s.Username) + " " + quote(s.Password)); err != nil {
+		return err
+	}
+	// Servers may offer more once logged in.
+	return c.capabilities()
+}
+
+func (c *Client) capabilities() error {
+	res, err := c.cmd("CAPABILITY")
+	if err != nil {
+		return err
+	}
+	c.caps = map[string]bool{}
+	for _, r := range res {
+		if rest, ok := strings.CutPrefix(r.text, "CAPABILITY "); ok {
+			for _, f := range strings.Fields(rest) {
+				c.caps[strings.ToUpper(f)] = true
+			}
+		}
+	}
+	return nil
+}
+
+// Close logs out and closes the connection.
+func (c *Client) Close() error {
+	c.cmd("LOGOUT")
+	return c.conn.Close()
+}
+
+// Select opens folder for reading and writing.
+func (c *Client) Select(folder string) error {
+	_, err := c.cmd("SELECT " + quote(folder))
+	return err
+}
+
+// Unseen returns the UIDs of the messages in the selected folder not read
+// yet, oldest first.
+func (c *Client) Unseen() ([]uint32, error) {
+	res, err := c.cmd("UID SEARCH UNSEEN")
+	if err != nil {
+		return nil, err
+	}
+	var uids []uint32
+	for _, r := range res {
+		rest, ok := strings.CutPrefix(r.text, "SEARCH")
+		if !ok {
+			continue
+		}
+		for _, f := range strings.Fields(rest) {
+			if n, err := strconv.ParseUint(f, 10, 32); err == nil {
+				uids = append(uids, uint32(n))
+			}
+		}
+	}
+	return uids, nil
+}
+
+// Fetch returns the message uid as it was sent, without marking it read.
+func (c *Client) Fetch(uid uint32) ([]byte, error) {
+	res, err := c.cmd(fmt.Sprintf("UID FETCH %d BODY.PEEK[]", uid))
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range res {
+		if strings.Contains(r.text, "FETCH") && len(r.literals) > 0 {
+			return r.literals[0], nil
+		}
+	}
+	return nil, fmt.Errorf("imap: message %d not found", uid)
+}
+
+// MarkSeen marks the message uid read.
+func (c *Client) MarkSeen(uid uint32) error {
+	_, err := c.cmd(fmt.Sprintf(`UID STORE %d +FLAGS.SILENT (\Seen)`, uid))
+	return err
+}
+
+// Move moves the message uid to folder, copying and deleting it where the
+// server lacks the MOVE extension (RFC 6851).
+func (c *Client) Move(uid uint32, folder string) error {
+	if c.caps["MOVE"] {
+		_, err := c.cmd(fmt.Sprintf("UID MOVE %d %s", uid, quote(folder)))
+		return err
+	}
+	if _, err := c.cmd(fmt.Sprintf("UID COPY %d %s", uid, quote(folder))); err != nil {
+		return err
+	}
+	if _, err := c.cmd(fmt.Sprintf(`UID STORE %d +FLAGS.SILENT (\Deleted)`, uid)); err != nil {
+		return err
+	}
+	if c.caps["UIDPLUS"] {
+		_, err := c.cmd(fmt.Sprintf("UID EXPUNGE %d", uid))
+		return err
+	}
+	_, err := c.cmd("EXPUNGE")
+	return err
+}
+
+// cmd sends a command and returns the untagged responses up to its
+// completion, failing unless it completes with OK.
+func (c *Client) cmd(command string) ([]response, error) {
+	c.tag++
+	tag := "t" + strconv.Itoa(c.tag)
+	c.conn.SetDeadline(time.Now().Add(5 * time.Minute))
+	if _, err := io.WriteString(c.conn, tag+" "+command+"\r\n"); err != nil {
+		return nil, err
+	}
+	var res []response
+	for {
+		r, err := c.readLine()
+		if err != nil {
+			return nil, err
+		}
+		if rest, ok := strings.CutPrefix(r.text, "* "); ok {
+			r.text = rest
+			res = append(res, r)
+			continue
+		}
+		rest, ok := strings.CutPrefix(r.text, tag+" ")
+		if !ok {
+			// A continuation request or a stray line.
+			continue
+		}
+		status, text, _ := strings.Cut(rest, " ")
+		if !strings.EqualFold(status, "OK") {
+			verb, _, _ := strings.Cut(command, " ")
+			if verb == "UID" {
+				verb = strings.Fields(command)[1]
+			}
+			return res, fmt.Errorf("imap: %s: %s", verb, text)
+		}
+		return res, nil
+	}
+}
+
+// readLine reads a response line, with the literals it holds.
+func (c *Client) readLine() (response, error) {
+	var r response
+	var b strings.Builder
+	for {
+		line, err := c.r.ReadString('\n')
+		if err != nil {
+			return r, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		b.WriteString(line)
+		n, ok := literalSize(line)
+		if !ok {
+			r.text = b.String()
+			return r, nil
+		}
+		if n > maxLiteral {
+			return r, fmt.Errorf("imap: message of %d bytes is too large", n)
+		}
+		data := make([]byte, n)
+		if _, err := io.ReadFull(c.r, data); err != nil {
+			return r, err
+		}
+		r.literals = append(r.literals, data)
+	}
+}
+
+// literalSize returns the size of the literal line announces at its end.
+func literalSize(line string) (int, bool) {
+	if !strings.HasSuffix(line, "}") {
+		return 0, false
+	}
+	i := strings.LastIndexByte(line, '{')
+	if i < 0 {
+		return 0, false
+	}
+	n, err := strconv.Atoi(line[i+1 : len(line)-1])
+	return n, err == nil && n >= 0
+}
+
+// quote returns s as an IMAP quoted string.
+func quote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
@@ -0,0 +1,241 @@
+package mailbox
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeIMAP is an IMAP server holding one folder, INBOX.
+type fakeIMAP struct {
+	caps     string
+	password string
+
+	mu       sync.Mutex
+	messages map[uint32]string
+	seen     map[uint32]bool
+	deleted  map[uint32]bool
+	moved    map[uint32]string
+	log      []string
+}
+
+// serve starts f on a local port and returns the server to dial.
+func (f *fakeIMAP) serve(t *testing.T) Server {
+	t.Helper()
+	f.seen, f.deleted, f.moved = map[uint32]bool{}, map[uint32]bool{}, map[uint32]string{}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go f.handle(conn)
+		}
+	}()
+	addr := ln.Addr().(*net.TCPAddr)
+	return Server{Host: "127.0.0.1", Port: addr.Port, Security: None, Username: "til", Password: f.password}
+}
+
+func (f *fakeIMAP) handle(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	fmt.Fprint(conn, "* OK fake ready\r\n")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		tag, command, _ := strings.Cut(strings.TrimRight(line, "\r\n"), " ")
+		f.mu.Lock()
+		f.log = append(f.log, command)
+		untagged, status := f.reply(command)
+		f.mu.Unlock()
+		fmt.Fprint(conn, untagged+tag+" "+status+"\r\n")
+		if command == "LOGOUT" {
+			return
+		}
+	}
+}
+
+// reply returns the untagged responses to command and its status.
+func (f *fakeIMAP) reply(command string) (string, string) {
+	fields := strings.Fields(command)
+	uid := func(i int) uint32 {
+		n, _ := strconv.ParseUint(fields[i], 10, 32)
+		return uint32(n)
+	}
+	switch {
+	case fields[0] == "CAPABILITY":
+		return "* CAPABILITY IMAP4rev1 " + f.caps + "\r\n", "OK done"
+	case fields[0] == "LOGIN":
+		if fields[2] != strconv.Quote(f.password) {
+			return "", "NO invalid credentials"
+		}
+	case fields[0] == "SELECT":
+		if fields[1] != `"INBOX"` {
+			return "", "NO no such folder"
+		}
+		return fmt.Sprintf("* %d EXISTS\r\n", len(f.messages)), "OK done"
+	case fields[0] == "LOGOUT":
+		return "* BYE\r\n", "OK done"
+	case fields[0] == "EXPUNGE" || command == "UID EXPUNGE "+fields[len(fields)-1]:
+		for u := range f.deleted {
+			delete(f.messages, u)
+		}
+	case command == "UID SEARCH UNSEEN":
+		var uids []string
+		for _, u := range f.sorted() {
+			if !f.seen[u] {
+				uids = append(uids, strconv.Itoa(int(u)))
+			}
+		}
+		return "* SEARCH " + strings.Join(uids, " ") + "\r\n", "OK done"
+	case fields[1] == "FETCH":
+		m, ok := f.messages[uid(2)]
+		if !ok {
+			return "", "OK done"
+		}
+		return fmt.Sprintf("* 1 FETCH (UID %d BODY[] {%d}\r\n%s)\r\n", uid(2), len(m), m), "OK done"
+	case fields[1] == "STORE":
+		if fields[4] == `(\Deleted)` {
+			f.deleted[uid(2)] = true
+		} else {
+			f.seen[uid(2)] = true
+		}
+	case fields[1] == "MOVE" || fields[1] == "COPY":
+		f.moved[uid(2)] = fields[3]
+		if fields[1] == "MOVE" {
+			delete(f.messages, uid(2))
+		}
+	}
+	return "", "OK done"
+}
+
+func (f *fakeIMAP) sorted() []uint32 {
+	var uids []uint32
+	for u := range f.messages {
+		uids = append(uids, u)
+	}
+	slices.Sort(uids)
+	return uids
+}
+
+func TestClient(t *testing.T) {
+	for _, caps := range []string{"MOVE", "UIDPLUS", ""} {
+		t.Run("caps "+caps, func(t *testing.T) {
+			f := &fakeIMAP{caps: caps, password: `pa"ss`, messages: map[uint32]string{
+				3: "Subject: Three\r\n\r\nthree\r\n",
+				7: "Subject: Seven\r\n\r\nseven\r\n",
+			}}
+			s := f.serve(t)
+			c, err := Dial(context.Background(), s)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer c.Close()
+			if err := c.Select("INBOX"); err != nil {
+				t.Fatal(err)
+			}
+			uids, err := c.Unseen()
+			if err != nil || !slices.Equal(uids, []uint32{3, 7}) {
+				t.Fatalf("Unseen = %v, %v", uids, err)
+			}
+			raw, err := c.Fetch(7)
+			if err != nil || string(raw) != "Subject: Seven\r\n\r\nseven\r\n" {
+				t.Errorf("Fetch = %q, %v", raw, err)
+			}
+			if _, err := c.Fetch(9); err == nil || err.Error() != "imap: message 9 not found" {
+				t.Errorf("Fetch(9) = %v", err)
+			}
+			if err := c.MarkSeen(3); err != nil {
+				t.Fatal(err)
+			}
+			if err := c.Move(7, "Archive"); err != nil {
+				t.Fatal(err)
+			}
+
+			f.mu.Lock()
+			defer f.mu.Unlock()
+			if !f.seen[3] || f.moved[7] != `"Archive"` || f.messages[7] != "" {
+				t.Errorf("seen %v, moved %v, left %v", f.seen, f.moved, f.sorted())
+			}
+			var moves []string
+			for _, l := range f.log {
+				if strings.Contains(l, " 7 ") || strings.HasSuffix(l, "EXPUNGE") || strings.HasPrefix(l, "UID EXPUNGE") {
+					moves = append(moves, l)
+				}
+			}
+			want := map[string][]string{
+				"MOVE":    {"UID FETCH 7 BODY.PEEK[]", `UID MOVE 7 "Archive"`},
+				"UIDPLUS": {"UID FETCH 7 BODY.PEEK[]", `UID COPY 7 "Archive"`, `UID STORE 7 +FLAGS.SILENT (\Deleted)`, "UID EXPUNGE 7"},
+				"":        {"UID FETCH 7 BODY.PEEK[]", `UID COPY 7 "Archive"`, `UID STORE 7 +FLAGS.SILENT (\Deleted)`, "EXPUNGE"},
+			}[caps]
+			if !slices.Equal(moves, want) {
+				t.Errorf("commands = %q, want %q", moves, want)
+			}
+			if f.log[1] != `LOGIN "til" "pa\"ss"` {
+				t.Errorf("login = %q", f.log[1])
+			}
+		})
+	}
+}
+
+func TestClientErrors(t *testing.T) {
+	f := &fakeIMAP{password: "secret", messages: map[uint32]string{}}
+	s := f.serve(t)
+	ctx := context.Background()
+
+	bad := s
+	bad.Password = "wrong"
+	if _, err := Dial(ctx, bad); err == nil || err.Error() != "imap: LOGIN: invalid credentials" {
+		t.Errorf("Dial with a wrong password = %v", err)
+	}
+	c, err := Dial(ctx, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if err := c.Select("Nowhere"); err == nil || err.Error() != "imap: SELECT: no such folder" {
+		t.Errorf("Select(Nowhere) = %v", err)
+	}
+
+	starttls := s
+	starttls.Security = StartTLS
+	if _, err := Dial(ctx, starttls); err == nil || err.Error() != "imap: server does not offer STARTTLS" {
+		t.Errorf("Dial with starttls = %v", err)
+	}
+	for _, s := range []Server{{}, {Host: "localhost", Security: "ssl"}} {
+		if _, err := Dial(ctx, s); err == nil {
+			t.Errorf("Dial(%+v) succeeded", s)
+		}
+	}
+}
+
+func TestLiteralSize(t *testing.T) {
+	tests := []struct {
+		line string
+		n    int
+		ok   bool
+	}{
+		{"* 1 FETCH (BODY[] {42}", 42, true},
+		{"* 1 FETCH (BODY[] {}", 0, false},
+		{"* OK done}", 0, false},
+		{"* SEARCH 1 2", 0, false},
+	}
+	for _, tt := range tests {
+		if n, ok := literalSize(tt.line); n != tt.n || ok != tt.ok {
+			t.Errorf("literalSize(%q) = %d, %v, want %d, %v", tt.line, n, ok, tt.n, tt.ok)
+		}
+	}
+}
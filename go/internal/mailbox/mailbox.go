@@ -0,0 +1,162 @@
+// Package mailbox turns mail sent to an IMAP account into entries.
+//
+// A message's subject is the entry's title, its text the body and its
+// attachments the entry's assets. Mail sent to a plus address is tagged
+// with what follows the plus: til+go@example.com tags entries go, and
+// til+go+tips@example.com go and tips.
+package mailbox
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mime"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/canhta/til/go/internal/capture"
+	"github.com/canhta/til/go/internal/importer"
+)
+
+// Options configures Convert.
+type Options struct {
+	// Category holds the entries. Defaults to importer.DefaultCategory.
+	Category string
+	// Address is the address mail is sent to. If set, tags are only taken
+	// from plus addresses of it; otherwise from any plus address the
+	// message was delivered to.
+	Address string
+	// AllowFrom lists the senders accepted, as addresses or as domains
+	// such as "@example.com". Empty accepts every sender.
+	AllowFrom []string
+}
+
+// ErrSender is returned by Convert for mail from senders not allowed.
+var ErrSender = errors.New("sender not allowed")
+
+// Convert converts the message raw into a note keyed by its Message-ID, so
+// a message is only imported once.
+func Convert(raw []byte, opts Options) (*importer.Note, error) {
+	m, err := Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+	if !allowed(m.From, opts.AllowFrom) {
+		return nil, fmt.Errorf("%w: %s", ErrSender, m.From)
+	}
+	id := m.ID
+	if id == "" {
+		sum := sha256.Sum256(raw)
+		id = hex.EncodeToString(sum[:8])
+	}
+	n := &importer.Note{
+		Key:      "mail:" + id,
+		Category: opts.Category,
+		Tags:     plusTags(m.To, opts.Address),
+		Date:     m.Date,
+	}
+	if n.Category == "" {
+		n.Category = importer.DefaultCategory
+	}
+	switch {
+	case strings.TrimSpace(m.Text) != "":
+		n.Body = capture.Body(stripSignature(m.Text), "")
+	case m.HTML != "":
+		body, err := importer.Markdown(strings.NewReader(m.HTML))
+		if err != nil {
+			return nil, err
+		}
+		n.Body = stripSignature(body)
+	}
+	n.Title = stripReply(m.Subject)
+	if n.Title == "" {
+		n.Title = capture.Title(n.Body)
+	}
+	if n.Title == "" {
+		n.Title = "Mail from " + m.From
+	}
+	for i, a := range m.Attachments {
+		name := a.Name
+		if name == "" {
+			name = fmt.Sprintf("attachment-%d", i+1)
+			if exts, _ := mime.ExtensionsByType(a.Type); len(exts) > 0 {
+				name += exts[0]
+			}
+		}
+		// Inline images are referred to by Content-ID; other attachments
+		// by nothing in the body, so links to them are appended.
+		ref := "attachment:" + name
+		if a.ContentID != "" {
+			ref = "cid:" + a.ContentID
+		}
+		n.Assets = append(n.Assets, importer.Asset{Ref: ref, Name: name, Data: a.Data})
+	}
+	return n, nil
+}
+
+// allowed reports whether mail from the address from is accepted.
+func allowed(from string, allow []string) bool {
+	if len(allow) == 0 {
+		return true
+	}
+	_, domain, _ := strings.Cut(from, "@")
+	for _, a := range allow {
+		a = strings.ToLower(strings.TrimSpace(a))
+		if a == from || strings.HasPrefix(a, "@") && a[1:] == domain {
+			return true
+		}
+	}
+	return false
+}
+
+// plusTags returns the tags of the plus addresses in to: those of address,
+// or any if address is empty.
+func plusTags(to []string, address string) []string {
+	wantLocal, wantDomain, _ := strings.Cut(strings.ToLower(address), "@")
+	var tags []string
+	for _, addr := range to {
+		local, domain, _ := strings.Cut(addr, "@")
+		base, plus, ok := strings.Cut(local, "+")
+		if !ok || address != "" && (base != wantLocal || domain != wantDomain) {
+			continue
+		}
+		for _, t := range strings.Split(plus, "+") {
+			if t = importer.Tag(t); t != "" {
+				tags = append(tags, t)
+			}
+		}
+	}
+	return slices.Compact(slices.Sorted(slices.Values(tags)))
+}
+
+var replyRE = regexp.MustCompile(`(?i)^\s*((re|fwd?|aw|wg|tr)\s*:\s*)+`)
+
+// stripReply removes reply and forward prefixes from a subject.
+func stripReply(subject string) string {
+	return strings.TrimSpace(replyRE.ReplaceAllString(subject, ""))
+}
+
+// sentFromRE matches the signatures mail applications add on phones.
+var sentFromRE = regexp.MustCompile(`(?i)^(sent|get outlook) (from|for) (my )?\S`)
+
+// stripSignature removes the signature from the end of a message's text:
+// what follows a "-- " line, or a closing "Sent from my phone" line.
+func stripSignature(text string) string {
+	lines := strings.Split(strings.TrimRight(text, "\n"), "\n")
+	for i, l := range lines {
+		// Converted HTML ends the delimiter with a hard line break.
+		if strings.TrimRight(l, ` \`) == "--" {
+			lines = lines[:i]
+			break
+		}
+	}
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) > 0 && sentFromRE.MatchString(strings.TrimSpace(lines[len(lines)-1])) {
+		lines = lines[:len(lines)-1]
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
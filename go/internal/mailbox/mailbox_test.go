@@ -0,0 +1,153 @@
+package mailbox
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// crlf turns the line breaks of a message written in Go source into the
+// ones mail uses.
+func crlf(s string) []byte { return []byte(strings.ReplaceAll(s, "\n", "\r\n")) }
+
+var multipartMail = crlf(`Message-ID: <abc@mail.example.com>
+From: "Me" <Me@Example.com>
+To: til+go+Tips@example.com, other+x@elsewhere.org
+Delivered-To: til+slices@example.com
+Date: Fri, 01 Mar 2024 15:04:05 +0100
+Subject: Fwd: Re: =?UTF-8?Q?Slices_=E2=9C=93?=
+Content-Type: multipart/mixed; boundary="outer"
+
+--outer
+Content-Type: multipart/related; boundary="inner"
+
+--inner
+Content-Type: multipart/alternative; boundary="alt"
+
+--alt
+Content-Type: text/plain; charset=iso-8859-1
+Content-Transfer-Encoding: quoted-printable
+
+Caf=E9 slices share arrays.
+
+--=20
+Me
+--alt
+Content-Type: text/html; charset=utf-8
+
+<p>Café slices share arrays: <img src="cid:img1@x"></p>
+--alt--
+--inner
+Content-Type: image/png
+Content-ID: <img1@x>
+Content-Transfer-Encoding: base64
+
+cG5n
+--inner--
+--outer
+Content-Type: application/pdf; name="=?UTF-8?Q?r=C3=A9sum=C3=A9.pdf?="
+Content-Disposition: attachment; filename="..\dir\notes.pdf"
+
+pdf
+--outer
+Content-Type: application/pgp-signature
+
+sig
+--outer--
+`)
+
+func TestParse(t *testing.T) {
+	m, err := Parse(multipartMail)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.ID != "abc@mail.example.com" || m.From != "me@example.com" || m.Subject != "Fwd: Re: Slices ✓" {
+		t.Errorf("headers = %q, %q, %q", m.ID, m.From, m.Subject)
+	}
+	if want := []string{"til+slices@example.com", "til+go+tips@example.com", "other+x@elsewhere.org"}; !slices.Equal(m.To, want) {
+		t.Errorf("To = %q, want %q", m.To, want)
+	}
+	if !m.Date.Equal(time.Date(2024, 3, 1, 14, 4, 5, 0, time.UTC)) {
+		t.Errorf("Date = %v", m.Date)
+	}
+	if m.Text != "Café slices share arrays.\n\n-- \nMe" || !strings.Contains(m.HTML, "Café slices") {
+		t.Errorf("Text = %q, HTML = %q", m.Text, m.HTML)
+	}
+	if len(m.Attachments) != 2 {
+		t.Fatalf("Attachments = %+v", m.Attachments)
+	}
+	if a := m.Attachments[0]; a.Name != "" || a.Type != "image/png" || a.ContentID != "img1@x" || string(a.Data) != "png" {
+		t.Errorf("inline image = %+v", a)
+	}
+	if a := m.Attachments[1]; a.Name != "notes.pdf" || string(a.Data) != "pdf" {
+		t.Errorf("attachment = %+v", a)
+	}
+}
+
+func TestConvert(t *testing.T) {
+	n, err := Convert(multipartMail, Options{Category: "go", Address: "til@example.com", AllowFrom: []string{"@example.com"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n.Key != "mail:abc@mail.example.com" || n.Title != "Slices ✓" || n.Category != "go" {
+		t.Errorf("note = %+v", n)
+	}
+	if !slices.Equal(n.Tags, []string{"go", "slices", "tips"}) {
+		t.Errorf("tags = %q, want those of plus addresses of til@example.com", n.Tags)
+	}
+	if n.Body != "Café slices share arrays." {
+		t.Errorf("body = %q, want the text without the signature", n.Body)
+	}
+	var assets []string
+	for _, a := range n.Assets {
+		assets = append(assets, a.Ref+" "+a.Name)
+	}
+	if want := []string{"cid:img1@x attachment-1.png", "attachment:notes.pdf notes.pdf"}; !slices.Equal(assets, want) {
+		t.Errorf("assets = %q, want %q", assets, want)
+	}
+
+	if n, _ := Convert(multipartMail, Options{}); !slices.Equal(n.Tags, []string{"go", "slices", "tips", "x"}) || n.Category != "inbox" {
+		t.Errorf("without an address: tags %q, category %q", n.Tags, n.Category)
+	}
+	for _, allow := range [][]string{{"you@example.com"}, {"@example.org"}} {
+		if _, err := Convert(multipartMail, Options{AllowFrom: allow}); !errors.Is(err, ErrSender) {
+			t.Errorf("Convert allowing %q = %v, want ErrSender", allow, err)
+		}
+	}
+
+	html := crlf("From: me@example.com\nContent-Type: text/html\n\n<h2>Maps</h2><p>Random <b>order</b>.</p><p>--<br>Me</p>\n")
+	n, err = Convert(html, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n.Title != "Maps" || !strings.Contains(n.Body, "Random **order**.") || strings.Contains(n.Body, "Me") {
+		t.Errorf("HTML mail = %q: %q", n.Title, n.Body)
+	}
+	if !strings.HasPrefix(n.Key, "mail:") || len(n.Key) != len("mail:")+16 {
+		t.Errorf("key without a Message-ID = %q", n.Key)
+	}
+	if n, _ := Convert(crlf("From: me@example.com\n\n"), Options{}); n.Title != "Mail from me@example.com" {
+		t.Errorf("title of an empty mail = %q", n.Title)
+	}
+}
+
+func TestStripSignature(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"Body.\n\n-- \nMe\n", "Body."},
+		{"Body.\n\nSent from my iPhone\n", "Body."},
+		{"Body.\n\nGet Outlook for iOS", "Body."},
+		{"Sent from my desk, this is the note.\nMore.", "Sent from my desk, this is the note.\nMore."},
+	}
+	for _, tt := range tests {
+		if got := stripSignature(tt.in); got != tt.want {
+			t.Errorf("stripSignature(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+	for in, want := range map[string]string{"Re: RE: Fwd:FW: Slices": "Slices", "Aw: Slices": "Slices", "Regarding slices": "Regarding slices"} {
+		if got := stripReply(in); got != want {
+			t.Errorf("stripReply(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package mailbox
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"path"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html/charset"
+)
+
+// Message is a parsed mail message.
+type Message struct {
+	// ID is the Message-ID, without angle brackets.
+	ID      string
+	Subject string
+	// From is the sender's address.
+	From string
+	// To lists the addresses the message was delivered to.
+	To   []string
+	Date time.Time
+	// Text and HTML are the plain and HTML versions of the body, decoded
+	// to UTF-8.
+	Text        string
+	HTML        string
+	Attachments []Attachment
+}
+
+// Attachment is a file sent with a message.
+type Attachment struct {
+	Name string
+	Type string
+	// ContentID is the ID the HTML body refers to an inline image by.
+	ContentID string
+	Data      []byte
+}
+
+var wordDecoder = &mime.WordDecoder{CharsetReader: charset.NewReaderLabel}
+
+// Parse parses the message raw as it was sent.
+func Parse(raw []byte) (*Message, error) {
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return nil, err
+	}
+	h := msg.Header
+	m := &Message{ID: strings.Trim(strings.TrimSpace(h.Get("Message-Id")), "<>")}
+	if s, err := wordDecoder.DecodeHeader(h.Get("Subject")); err == nil {
+		m.Subject = strings.Join(strings.Fields(s), " ")
+	} else {
+		m.Subject = strings.Join(strings.Fields(h.Get("Subject")), " ")
+	}
+	parser := mail.AddressParser{WordDecoder: wordDecoder}
+	if from, err := parser.Parse(h.Get("From")); err == nil {
+		m.From = strings.ToLower(from.Address)
+	}
+	for _, k := range []string{"Delivered-To", "X-Original-To", "To", "Cc"} {
+		for _, v := range h[k] {
+			list, err := parser.ParseList(v)
+			if err != nil {
+				continue
+			}
+			for _, a := range list {
+				m.To = append(m.To, strings.ToLower(a.Address))
+			}
+		}
+	}
+	if d, err := h.Date(); err == nil {
+		m.Date = d
+	}
+	if err := m.part(h, msg.Body); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// header is the header of a message or of a part of it.
+type header interface {
+	Get(key string) string
+}
+
+// part reads a body part with header h into m, descending into multiparts.
+func (m *Message) part(h header, body io.Reader) error {
+	mediaType, params, err := mime.ParseMediaType(h.Get("Content-Type"))
+	if err != nil {
+		mediaType, params = "text/plain", map[string]string{}
+	}
+	if strings.HasPrefix(mediaType, "multipart/") {
+		mr := multipart.NewReader(body, params["boundary"])
+		for {
+			p, err := mr.NextRawPart()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if err := m.part(p.Header, p); err != nil {
+				return err
+			}
+		}
+	}
+	data, err := io.ReadAll(decode(body, h.Get("Content-Transfer-Encoding")))
+	if err != nil {
+		return fmt.Errorf("%s part: %w", mediaType, err)
+	}
+	disposition, dparams, _ := mime.ParseMediaType(h.Get("Content-Disposition"))
+	name := dparams["filename"]
+	if name == "" {
+		name = params["name"]
+	}
+	if n, err := wordDecoder.DecodeHeader(name); err == nil {
+		name = n
+	}
+	name = path.Base(strings.ReplaceAll(name, `\`, "/"))
+	if name == "." || name == "/" {
+		name = ""
+	}
+	if disposition != "attachment" && name == "" {
+		switch {
+		case mediaType == "text/plain" && m.Text == "":
+			m.Text = decodeText(data, params["charset"])
+			return nil
+		case mediaType == "text/html" && m.HTML == "":
+			m.HTML = decodeText(data, params["charset"])
+			return nil
+		case mediaType == "message/delivery-status", strings.HasPrefix(mediaType, "text/"):
+			return nil
+		}
+	}
+	switch mediaType {
+	case "application/pgp-signature", "application/pkcs7-signature", "application/x-pkcs7-signature":
+		return nil
+	}
+	m.Attachments = append(m.Attachments, Attachment{
+		Name:      name,
+		Type:      mediaType,
+		ContentID: strings.Trim(strings.TrimSpace(h.Get("Content-Id")), "<>"),
+		Data:      data,
+	})
+	return nil
+}
+
+// decode undoes the transfer encoding of a part.
+func decode(r io.Reader, encoding string) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, r)
+	case "quoted-printable":
+		return quotedprintable.NewReader(r)
+	}
+	return r
+}
+
+// decodeText converts text in the named charset to UTF-8, with line
+// breaks as \n.
+func decodeText(data []byte, label string) string {
+	if label != "" && !strings.EqualFold(label, "utf-8") && !strings.EqualFold(label, "us-ascii") {
+		if r, err := charset.NewReaderLabel(label, bytes.NewReader(data)); err == nil {
+			if d, err := io.ReadAll(r); err == nil {
+				data = d
+			}
+		}
+	}
+	return strings.ReplaceAll(string(data), "\r\n", "\n")
+}
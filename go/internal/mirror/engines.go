@@ -0,0 +1,220 @@
+package mirror
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DefaultIndex is the index documents are pushed to unless configured.
+const DefaultIndex = "til"
+
+// Meilisearch is an index of a Meilisearch server.
+type Meilisearch struct {
+	// URL is the server's, such as http://localhost:7700.
+	URL   string
+	Index string
+	// Key is an API key allowed to add and delete documents; empty for a
+	// server without a master key.
+	Key    string
+	Client *http.Client
+}
+
+// Name implements Target.
+func (m Meilisearch) Name() string { return "meilisearch " + m.URL + " " + m.Index }
+
+// Upsert implements Target, creating the index if needed.
+func (m Meilisearch) Upsert(ctx context.Context, docs []Document) error {
+	return m.task(ctx, "/documents?primaryKey=id", docs)
+}
+
+// Delete implements Target.
+func (m Meilisearch) Delete(ctx context.Context, ids []string) error {
+	return m.task(ctx, "/documents/delete-batch", ids)
+}
+
+// task posts in to the endpoint of the index and waits for the task the
+// server queues to finish, as it only then reports a failure.
+func (m Meilisearch) task(ctx context.Context, endpoint string, in any) error {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	var resp struct {
+		TaskUID int64 `json:"taskUid"`
+	}
+	u := strings.TrimSuffix(m.URL, "/") + "/indexes/" + url.PathEscape(m.Index) + endpoint
+	if err := send(ctx, m.Client, http.MethodPost, u, m.header(), "application/json", body, &resp); err != nil {
+		return err
+	}
+	for wait := 50 * time.Millisecond; ; wait = min(2*wait, 2*time.Second) {
+		var t struct {
+			Status string `json:"status"`
+			Error  *struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		u := fmt.Sprintf("%s/tasks/%d", strings.TrimSuffix(m.URL, "/"), resp.TaskUID)
+		if err := send(ctx, m.Client, http.MethodGet, u, m.header(), "", nil, &t); err != nil {
+			return err
+		}
+		switch t.Status {
+		case "succeeded":
+			return nil
+		case "failed", "canceled":
+			if t.Error != nil {
+				return fmt.Errorf("meilisearch task %d: %s", resp.TaskUID, t.Error.Message)
+			}
+			return fmt.Errorf("meilisearch task %d %s", resp.TaskUID, t.Status)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+func (m Meilisearch) header() http.Header {
+	h := http.Header{}
+	if m.Key != "" {
+		h.Set("Authorization", "Bearer "+m.Key)
+	}
+	return h
+}
+
+// Elasticsearch is an index of an Elasticsearch or OpenSearch cluster.
+type Elasticsearch struct {
+	// URL is the cluster's, such as http://localhost:9200.
+	URL   string
+	Index string
+	// Key is an API key, sent as "ApiKey <key>"; empty for a cluster
+	// without security.
+	Key    string
+	Client *http.Client
+}
+
+// Name implements Target.
+func (e Elasticsearch) Name() string { return "elasticsearch " + e.URL + " " + e.Index }
+
+// Upsert implements Target, creating the index if needed.
+func (e Elasticsearch) Upsert(ctx context.Context, docs []Document) error {
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	for _, d := range docs {
+		if err := enc.Encode(map[string]any{"index": map[string]string{"_id": d.ID}}); err != nil {
+			return err
+		}
+		if err := enc.Encode(d); err != nil {
+			return err
+		}
+	}
+	return e.bulk(ctx, b.Bytes())
+}
+
+// Delete implements Target. Documents the index no longer has are taken
+// as deleted.
+func (e Elasticsearch) Delete(ctx context.Context, ids []string) error {
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	for _, id := range ids {
+		if err := enc.Encode(map[string]any{"delete": map[string]string{"_id": id}}); err != nil {
+			return err
+		}
+	}
+	return e.bulk(ctx, b.Bytes())
+}
+
+// bulk sends the bulk request body, failing on the first failed action.
+func (e Elasticsearch) bulk(ctx context.Context, body []byte) error {
+	h := http.Header{}
+	if e.Key != "" {
+		h.Set("Authorization", "ApiKey "+e.Key)
+	}
+	var resp struct {
+		Errors bool                  `json:"errors"`
+		Items  []map[string]bulkItem `json:"items"`
+	}
+	u := strings.TrimSuffix(e.URL, "/") + "/" + url.PathEscape(e.Index) + "/_bulk"
+	if err := send(ctx, e.Client, http.MethodPost, u, h, "application/x-ndjson", body, &resp); err != nil {
+		return err
+	}
+	if !resp.Errors {
+		return nil
+	}
+	for _, item := range resp.Items {
+		for action, it := range item {
+			if it.Error == nil || action == "delete" && it.Status == http.StatusNotFound {
+				continue
+			}
+			return fmt.Errorf("elasticsearch: %s %s: %s: %s", action, it.ID, it.Error.Type, it.Error.Reason)
+		}
+	}
+	return nil
+}
+
+// bulkItem is the result of one action of a bulk request.
+type bulkItem struct {
+	ID     string `json:"_id"`
+	Status int    `json:"status"`
+	Error  *struct {
+		Type   string `json:"type"`
+		Reason string `json:"reason"`
+	} `json:"error"`
+}
+
+// send makes a request of body, of the given content type, and decodes the
+// JSON response into out.
+func send(ctx context.Context, client *http.Client, method, u string, header http.Header, contentType string, body []byte, out any) error {
+	var r io.Reader
+	if body != nil {
+		r = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, r)
+	if err != nil {
+		return err
+	}
+	req.Header = header.Clone()
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	req.Header.Set("Accept", "application/json")
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 16<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode/100 != 2 {
+		var e struct {
+			// Meilisearch reports a message, Elasticsearch an error object.
+			Message string `json:"message"`
+			Error   struct {
+				Reason string `json:"reason"`
+			} `json:"error"`
+		}
+		if json.Unmarshal(data, &e) == nil {
+			if e.Message != "" {
+				return fmt.Errorf("%s: %s", resp.Status, e.Message)
+			}
+			if e.Error.Reason != "" {
+				return fmt.Errorf("%s: %s", resp.Status, e.Error.Reason)
+			}
+		}
+		return errors.New(resp.Status)
+	}
+	return json.Unmarshal(data, out)
+}
@@ -0,0 +1,124 @@
+package mirror
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestMeilisearch(t *testing.T) {
+	var mu sync.Mutex
+	var got []string
+	polls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			io.WriteString(w, `{"message": "The provided API key is invalid."}`)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		switch {
+		case r.Method == http.MethodPost:
+			got = append(got, r.URL.RequestURI()+" "+string(body))
+			io.WriteString(w, `{"taskUid": 7}`)
+		case r.URL.Path == "/tasks/7":
+			// The task is enqueued at first.
+			if polls++; polls < 2 {
+				io.WriteString(w, `{"status": "enqueued"}`)
+				return
+			}
+			io.WriteString(w, `{"status": "succeeded"}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	m := Meilisearch{URL: srv.URL + "/", Index: "my til", Key: "secret"}
+	if m.Name() != "meilisearch "+srv.URL+"/ my til" {
+		t.Errorf("Name = %q", m.Name())
+	}
+	if err := m.Upsert(ctx, []Document{{ID: "a1", Path: "go/slices.md", Tags: []string{}}}); err != nil {
+		t.Fatal(err)
+	}
+	polls = 0
+	if err := m.Delete(ctx, []string{"a1", "b2"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || !strings.HasPrefix(got[0], `/indexes/my%20til/documents?primaryKey=id [{"id":"a1","path":"go/slices.md"`) || got[1] != `/indexes/my%20til/documents/delete-batch ["a1","b2"]` {
+		t.Errorf("requests =\n%s", strings.Join(got, "\n"))
+	}
+
+	m.Key = "wrong"
+	if err := m.Delete(ctx, []string{"a1"}); err == nil || err.Error() != "401 Unauthorized: The provided API key is invalid." {
+		t.Errorf("Delete with a wrong key = %v", err)
+	}
+}
+
+func TestMeilisearchTaskFails(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			io.WriteString(w, `{"taskUid": 8}`)
+			return
+		}
+		io.WriteString(w, `{"status": "failed", "error": {"message": "invalid document id"}}`)
+	}))
+	defer srv.Close()
+	m := Meilisearch{URL: srv.URL, Index: "til"}
+	if err := m.Upsert(context.Background(), []Document{{ID: "a1"}}); err == nil || err.Error() != "meilisearch task 8: invalid document id" {
+		t.Errorf("Upsert = %v", err)
+	}
+}
+
+func TestElasticsearch(t *testing.T) {
+	var bodies []string
+	respond := `{"errors": false, "items": []}`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/til/_bulk" || r.Header.Get("Content-Type") != "application/x-ndjson" || r.Header.Get("Authorization") != "ApiKey k" {
+			w.WriteHeader(http.StatusBadRequest)
+			io.WriteString(w, `{"error": {"reason": "bad request"}}`)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		io.WriteString(w, respond)
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	e := Elasticsearch{URL: srv.URL, Index: "til", Key: "k"}
+	if err := e.Upsert(ctx, []Document{{ID: "a1", Path: "go/slices.md"}}); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(bodies[0]), "\n")
+	var doc Document
+	if len(lines) != 2 || lines[0] != `{"index":{"_id":"a1"}}` || json.Unmarshal([]byte(lines[1]), &doc) != nil || doc.Path != "go/slices.md" {
+		t.Errorf("bulk upsert =\n%s", bodies[0])
+	}
+
+	// Deleting a document already gone is no error.
+	respond = `{"errors": true, "items": [{"delete": {"_id": "a1", "status": 404, "error": {"type": "not_found", "reason": "gone"}}}]}`
+	if err := e.Delete(ctx, []string{"a1"}); err != nil {
+		t.Errorf("Delete of a missing document = %v", err)
+	}
+	if bodies[1] != `{"delete":{"_id":"a1"}}`+"\n" {
+		t.Errorf("bulk delete = %q", bodies[1])
+	}
+	respond = `{"errors": true, "items": [{"index": {"_id": "a1", "status": 400, "error": {"type": "mapper_parsing_exception", "reason": "failed to parse"}}}]}`
+	if err := e.Upsert(ctx, []Document{{ID: "a1"}}); err == nil || err.Error() != "elasticsearch: index a1: mapper_parsing_exception: failed to parse" {
+		t.Errorf("Upsert of a bad document = %v", err)
+	}
+
+	e.Key = ""
+	if err := e.Delete(ctx, []string{"a1"}); err == nil || err.Error() != "400 Bad Request: bad request" {
+		t.Errorf("Delete without a key = %v", err)
+	}
+}
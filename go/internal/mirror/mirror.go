@@ -0,0 +1,202 @@
+// Package mirror keeps the entries of a notes tree in an external search
+// engine, Meilisearch or Elasticsearch, for search UIs hosted apart from
+// til.
+//
+// Each public entry is one document. Pushes are incremental: the state
+// directory records a hash of every document as last pushed to each index,
+// so a push sends only the documents that changed and deletes those of
+// entries gone since.
+package mirror
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+
+	"github.com/canhta/til/go/internal/fsutil"
+	"github.com/canhta/til/go/internal/notes"
+	"github.com/canhta/til/go/pkg/entry"
+	"github.com/canhta/til/go/pkg/render"
+)
+
+// StateFile is the file in the state directory holding the hash of every
+// document pushed, by index then document ID.
+const StateFile = "mirror.json"
+
+// Batch is the most documents sent in one request.
+const Batch = 500
+
+// Document is an entry as pushed to a search engine.
+type Document struct {
+	// ID is derived from the entry's path, in the characters every engine
+	// accepts.
+	ID       string   `json:"id"`
+	Path     string   `json:"path"`
+	Title    string   `json:"title"`
+	Category string   `json:"category"`
+	Tags     []string `json:"tags"`
+	Author   string   `json:"author,omitempty"`
+	Lang     string   `json:"lang,omitempty"`
+	// URL is the entry's page on the site, when the site has an absolute
+	// base URL.
+	URL string `json:"url,omitempty"`
+	// Created and Updated are the date and updated frontmatter, with
+	// CreatedAt and UpdatedAt the same as Unix times for sorting, zero when
+	// unset. File times are left out, so that checking out a tree anew
+	// changes no document.
+	Created   string `json:"created,omitempty"`
+	Updated   string `json:"updated,omitempty"`
+	CreatedAt int64  `json:"created_at"`
+	UpdatedAt int64  `json:"updated_at"`
+	// Body is the markdown body without the title heading.
+	Body string `json:"body"`
+}
+
+// ID returns the document ID of the entry at path p.
+func ID(p string) string {
+	sum := sha256.Sum256([]byte(p))
+	return hex.EncodeToString(sum[:12])
+}
+
+// NewDocument returns the document of e, whose page is at url, if any.
+func NewDocument(e *entry.Entry, url string) Document {
+	d := Document{
+		ID:       ID(e.Path),
+		Path:     e.Path,
+		Title:    e.Meta.Title,
+		Category: e.Meta.Category,
+		Tags:     e.Meta.Tags,
+		Author:   e.Meta.Author,
+		Lang:     e.Meta.Lang,
+		URL:      url,
+		Body:     string(render.StripTitle(e.Body)),
+	}
+	if d.Tags == nil {
+		d.Tags = []string{}
+	}
+	if t := e.Created(); !t.IsZero() {
+		d.Created, d.CreatedAt = t.Format(entry.DateLayout), t.Unix()
+	}
+	if t := e.Meta.Updated; !t.IsZero() {
+		d.Updated, d.UpdatedAt = t.Format(entry.DateLayout), t.Unix()
+	}
+	return d
+}
+
+// Target is an index of a search engine.
+type Target interface {
+	// Name identifies the index in the state file, by engine, URL and
+	// index name.
+	Name() string
+	Upsert(ctx context.Context, docs []Document) error
+	Delete(ctx context.Context, ids []string) error
+}
+
+// Stats reports what a Push sent.
+type Stats struct {
+	Upserted  int `json:"upserted"`
+	Deleted   int `json:"deleted"`
+	Unchanged int `json:"unchanged"`
+}
+
+// Options controls a Push.
+type Options struct {
+	// Full sends every document, as when the index was emptied since the
+	// last push.
+	Full bool
+	// DryRun works out the changes without sending them.
+	DryRun bool
+}
+
+// Push brings the index of t up to date with docs. What it sent is
+// recorded batch by batch, so a push failing part way resumes where it
+// stopped.
+func Push(ctx context.Context, tree *notes.Tree, t Target, docs []Document, opts Options) (Stats, error) {
+	state, err := loadState(tree)
+	if err != nil {
+		return Stats{}, err
+	}
+	last := state[t.Name()]
+	if last == nil {
+		last = map[string]string{}
+	}
+	var st Stats
+	var changed []Document
+	hashes := map[string]string{}
+	for _, d := range docs {
+		data, err := json.Marshal(d)
+		if err != nil {
+			return st, err
+		}
+		sum := sha256.Sum256(data)
+		h := hex.EncodeToString(sum[:])
+		hashes[d.ID] = h
+		if last[d.ID] == h && !opts.Full {
+			st.Unchanged++
+			continue
+		}
+		changed = append(changed, d)
+	}
+	var gone []string
+	for _, id := range slices.Sorted(maps.Keys(last)) {
+		if _, ok := hashes[id]; !ok {
+			gone = append(gone, id)
+		}
+	}
+	if opts.DryRun {
+		st.Upserted, st.Deleted = len(changed), len(gone)
+		return st, nil
+	}
+	save := func() error {
+		state[t.Name()] = last
+		return saveState(tree, state)
+	}
+	for batch := range slices.Chunk(changed, Batch) {
+		if err := t.Upsert(ctx, batch); err != nil {
+			return st, errors.Join(err, save())
+		}
+		for _, d := range batch {
+			last[d.ID] = hashes[d.ID]
+		}
+		st.Upserted += len(batch)
+	}
+	for batch := range slices.Chunk(gone, Batch) {
+		if err := t.Delete(ctx, batch); err != nil {
+			return st, errors.Join(err, save())
+		}
+		for _, id := range batch {
+			delete(last, id)
+		}
+		st.Deleted += len(batch)
+	}
+	return st, save()
+}
+
+func loadState(tree *notes.Tree) (map[string]map[string]string, error) {
+	state := map[string]map[string]string{}
+	data, err := os.ReadFile(tree.StatePath(StateFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("%s: %w", StateFile, err)
+	}
+	return state, nil
+}
+
+func saveState(tree *notes.Tree, state map[string]map[string]string) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return fsutil.WriteFile(tree.StatePath(StateFile), append(data, '\n'), 0o644)
+}
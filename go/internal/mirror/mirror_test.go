@@ -0,0 +1,140 @@
+package mirror
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/canhta/til/go/internal/notes"
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+// fake is a Target recording what it is sent, failing the upsert after
+// failAfter batches when it is set.
+type fake struct {
+	name      string
+	upserts   [][]string
+	deletes   [][]string
+	failAfter int
+}
+
+func (f *fake) Name() string { return f.name }
+
+func (f *fake) Upsert(ctx context.Context, docs []Document) error {
+	if f.failAfter > 0 && len(f.upserts) == f.failAfter {
+		return errors.New("server down")
+	}
+	var paths []string
+	for _, d := range docs {
+		paths = append(paths, d.Path)
+	}
+	f.upserts = append(f.upserts, paths)
+	return nil
+}
+
+func (f *fake) Delete(ctx context.Context, ids []string) error {
+	f.deletes = append(f.deletes, ids)
+	return nil
+}
+
+func parse(t *testing.T, p, data string) *entry.Entry {
+	t.Helper()
+	e, err := entry.Parse(p, []byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+func TestNewDocument(t *testing.T) {
+	e := parse(t, "go/slices.md", "---\ntitle: Slices\ndate: 2024-06-01\nupdated: 2024-06-03\nauthor: Ann\n---\n\n# Slices\n\nSlices share arrays.\n")
+	d := NewDocument(e, "https://til.example/go/slices/")
+	want := Document{
+		ID: ID("go/slices.md"), Path: "go/slices.md", Title: "Slices", Category: "go", Tags: []string{}, Author: "Ann",
+		URL: "https://til.example/go/slices/", Created: "2024-06-01", Updated: "2024-06-03",
+		CreatedAt: 1717200000, UpdatedAt: 1717372800, Body: "\nSlices share arrays.\n",
+	}
+	if d.ID != want.ID || d.Title != want.Title || d.Created != want.Created || d.Updated != want.Updated ||
+		d.CreatedAt != want.CreatedAt || d.UpdatedAt != want.UpdatedAt || d.URL != want.URL || d.Author != want.Author ||
+		d.Tags == nil || d.Body != want.Body {
+		t.Errorf("NewDocument =\n%+v\nwant\n%+v", d, want)
+	}
+	if id := ID("go/slices.md"); len(id) != 24 || id == ID("go/maps.md") {
+		t.Errorf("ID = %q", id)
+	}
+	if d := NewDocument(parse(t, "git/rebase.md", "# Rebase\n"), ""); d.Created != "" || d.CreatedAt != 0 || d.URL != "" {
+		t.Errorf("NewDocument of an undated entry = %+v", d)
+	}
+}
+
+func TestPush(t *testing.T) {
+	ctx := context.Background()
+	tree := notes.Open(t.TempDir())
+	docs := func(bodies map[string]string) []Document {
+		var out []Document
+		for _, p := range []string{"git/rebase.md", "go/maps.md", "go/slices.md"} {
+			if b, ok := bodies[p]; ok {
+				out = append(out, NewDocument(parse(t, p, b), ""))
+			}
+		}
+		return out
+	}
+	v1 := map[string]string{"git/rebase.md": "# Rebase\n", "go/maps.md": "# Maps\n", "go/slices.md": "# Slices\n"}
+	f := &fake{name: "fake one"}
+	if st, err := Push(ctx, tree, f, docs(v1), Options{}); err != nil || st != (Stats{Upserted: 3}) {
+		t.Fatalf("first Push = %+v, %v", st, err)
+	}
+	if !slices.Equal(f.upserts[0], []string{"git/rebase.md", "go/maps.md", "go/slices.md"}) {
+		t.Errorf("upserts = %q", f.upserts)
+	}
+
+	// Only changes are sent, and entries gone are deleted.
+	v2 := map[string]string{"go/maps.md": "# Maps\n\nMore.\n", "go/slices.md": "# Slices\n"}
+	if st, err := Push(ctx, tree, f, docs(v2), Options{DryRun: true}); err != nil || st != (Stats{Upserted: 1, Deleted: 1, Unchanged: 1}) {
+		t.Errorf("dry run = %+v, %v", st, err)
+	}
+	if len(f.upserts) != 1 || len(f.deletes) != 0 {
+		t.Errorf("dry run sent %q, deleted %q", f.upserts, f.deletes)
+	}
+	if st, err := Push(ctx, tree, f, docs(v2), Options{}); err != nil || st != (Stats{Upserted: 1, Deleted: 1, Unchanged: 1}) {
+		t.Errorf("second Push = %+v, %v", st, err)
+	}
+	if !slices.Equal(f.upserts[1], []string{"go/maps.md"}) || len(f.deletes) != 1 || !slices.Equal(f.deletes[0], []string{ID("git/rebase.md")}) {
+		t.Errorf("upserts = %q, deletes = %q", f.upserts, f.deletes)
+	}
+	if st, err := Push(ctx, tree, f, docs(v2), Options{}); err != nil || st != (Stats{Unchanged: 2}) {
+		t.Errorf("Push of no changes = %+v, %v", st, err)
+	}
+	if st, err := Push(ctx, tree, f, docs(v2), Options{Full: true}); err != nil || st != (Stats{Upserted: 2}) {
+		t.Errorf("full Push = %+v, %v", st, err)
+	}
+
+	// Each index has a state of its own.
+	other := &fake{name: "fake two"}
+	if st, err := Push(ctx, tree, other, docs(v2), Options{}); err != nil || st != (Stats{Upserted: 2}) {
+		t.Errorf("Push to another index = %+v, %v", st, err)
+	}
+}
+
+// TestPushResumes checks that the batches sent before a failure are not
+// sent again.
+func TestPushResumes(t *testing.T) {
+	ctx := context.Background()
+	tree := notes.Open(t.TempDir())
+	var docs []Document
+	for i := range Batch + 10 {
+		p := fmt.Sprintf("go/e%d.md", i)
+		docs = append(docs, Document{ID: ID(p), Path: p})
+	}
+	f := &fake{name: "fake", failAfter: 1}
+	st, err := Push(ctx, tree, f, docs, Options{})
+	if err == nil || st.Upserted != Batch {
+		t.Fatalf("failing Push = %+v, %v", st, err)
+	}
+	f.failAfter = 0
+	if st, err := Push(ctx, tree, f, docs, Options{}); err != nil || st != (Stats{Upserted: 10, Unchanged: Batch}) {
+		t.Errorf("resumed Push = %+v, %v", st, err)
+	}
+}
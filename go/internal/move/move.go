@@ -0,0 +1,243 @@
+// Package move plans moving entries to other files of a notes tree: their
+// assets move along, and the links of every entry, the moved ones
+// included, are rewritten to keep pointing where they did.
+//
+// Markdown links, to entries and to any other file, are made relative to
+// the new places of both ends. A wiki link resolving differently after the
+// move, since it named the entry by its old path or another entry in a
+// category now takes precedence, is made to name its target by path, and
+// so is the translation_of field. A wiki link naming several entries, one
+// of them moved, cannot be told apart and stops the move.
+package move
+
+import (
+	"fmt"
+	"maps"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/canhta/til/go/internal/assets"
+	"github.com/canhta/til/go/internal/links"
+	"github.com/canhta/til/go/internal/notes"
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+// Plan is the files a move writes and removes.
+type Plan struct {
+	// Files are the contents to write, keyed by path: the moved entries
+	// and assets at their new paths, and the entries whose links change.
+	Files map[string][]byte
+	// Remove are the old paths of the moved files.
+	Remove []string
+	// Moves maps the old path of each moved entry to its new one.
+	Moves map[string]string
+	// Assets maps the old path of each moved asset to its new one.
+	Assets map[string]string
+	// Moved are the moved entries as they were.
+	Moved []*entry.Entry
+	// Relinked are the entries, by their paths after the move, whose links
+	// are rewritten.
+	Relinked []string
+}
+
+// Ambiguity is a link that cannot be rewritten, as it names several
+// entries.
+type Ambiguity struct {
+	Path string
+	Line int
+	// Link is the link as written.
+	Link string
+	// Moved is the moved entry among those it names.
+	Moved string
+}
+
+func (a Ambiguity) String() string {
+	return fmt.Sprintf("%s:%d: %s names several entries, %s among them", a.Path, a.Line, a.Link, a.Moved)
+}
+
+// AmbiguousError is returned for a move some links of which cannot be
+// rewritten.
+type AmbiguousError struct {
+	Links []Ambiguity
+}
+
+func (e *AmbiguousError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "cannot rewrite %d links unambiguously; name their targets by path, such as [[go/slices]], and move again:", len(e.Links))
+	for _, a := range e.Links {
+		b.WriteString("\n  " + a.String())
+	}
+	return b.String()
+}
+
+// Entries plans moving the entries at the old paths of moves to the new
+// ones. edits, keyed by old path, are contents to start from instead of
+// the files in the tree, such as entries whose frontmatter is being
+// changed too; they are written back whether or not their links change.
+func Entries(tree *notes.Tree, moves map[string]string, edits map[string][]byte) (*Plan, error) {
+	entries, err := tree.Entries()
+	if err != nil {
+		return nil, err
+	}
+	byPath := map[string]*entry.Entry{}
+	for _, e := range entries {
+		byPath[e.Path] = e
+	}
+	p := &Plan{Files: map[string][]byte{}, Moves: map[string]string{}, Assets: map[string]string{}}
+	// moved maps every moved file, entry or asset, to its new path.
+	moved := map[string]string{}
+	taken := map[string]string{}
+	for _, from := range slices.Sorted(maps.Keys(moves)) {
+		to := moves[from]
+		e, ok := byPath[from]
+		if !ok {
+			return nil, fmt.Errorf("%s: %w", from, notes.ErrNotFound)
+		}
+		if from == to {
+			continue
+		}
+		if other, ok := taken[to]; ok {
+			return nil, fmt.Errorf("%s: cannot move to %s, as %s moves there", from, to, other)
+		}
+		if ok, err := tree.Exists(to); err != nil {
+			return nil, err
+		} else if ok {
+			return nil, fmt.Errorf("%s: cannot move to %s, which exists", from, to)
+		}
+		taken[to] = from
+		moved[from] = to
+		p.Moves[from] = to
+		p.Moved = append(p.Moved, e)
+	}
+	if len(p.Moves) > 0 {
+		files, err := tree.Files()
+		if err != nil {
+			return nil, err
+		}
+		for from, to := range p.Moves {
+			dir, dest := assets.DirFor(from)+"/", assets.DirFor(to)+"/"
+			for _, f := range files {
+				if rest, ok := strings.CutPrefix(f.Path, dir); ok {
+					moved[f.Path] = dest + rest
+					p.Assets[f.Path] = dest + rest
+				}
+			}
+		}
+	}
+	after := func(q string) string {
+		if to, ok := moved[q]; ok {
+			return to
+		}
+		return q
+	}
+
+	// The entries as they are after the move, for resolving wiki links.
+	next := make([]*entry.Entry, len(entries))
+	for i, e := range entries {
+		next[i] = e
+		data, edited := edits[e.Path]
+		if _, ok := p.Moves[e.Path]; ok || edited {
+			if !edited {
+				if data, err = tree.Read(e.Path); err != nil {
+					return nil, err
+				}
+			}
+			if next[i], err = entry.Parse(after(e.Path), data); err != nil {
+				return nil, err
+			}
+		}
+	}
+	oldIx, newIx := links.NewIndex(entries), links.NewIndex(next)
+	movedIx := links.NewIndex(p.Moved)
+	newOf := map[string]*entry.Entry{}
+	for i, e := range entries {
+		newOf[e.Path] = next[i]
+	}
+
+	var ambiguous []Ambiguity
+	for _, e := range entries {
+		from, to := e.Path, after(e.Path)
+		// relink returns the wiki link target naming, from to, the entry
+		// target names from from, if it must change.
+		relink := func(target string) (string, bool) {
+			l := links.Link{Target: target, Wiki: true}
+			t, res := oldIx.Resolve(from, l)
+			if res != links.Resolved {
+				return "", false
+			}
+			u, res := newIx.Resolve(to, l)
+			want := newOf[t.Path]
+			if res == links.Resolved && u.Path == want.Path {
+				return "", false
+			}
+			return want.ID(), true
+		}
+		data, ok := edits[from]
+		if !ok {
+			if data, err = tree.Read(from); err != nil {
+				return nil, err
+			}
+		}
+		body := links.Rewrite(data, func(l links.Link) (string, bool) {
+			if l.Wiki {
+				if _, res := oldIx.Resolve(from, l); res == links.Ambiguous {
+					if m, r := movedIx.Resolve(from, l); r == links.Resolved {
+						ambiguous = append(ambiguous, Ambiguity{Path: from, Line: l.Line, Link: "[[" + l.Target + "]]", Moved: m.Path})
+					}
+					return "", false
+				}
+				id, ok := relink(l.Target)
+				if !ok {
+					return "", false
+				}
+				if l.Label != "" {
+					id += "|" + l.Label
+				}
+				return "[[" + id + "]]", true
+			}
+			target := path.Clean(path.Join(path.Dir(from), l.Target))
+			if to == from && after(target) == target {
+				return "", false
+			}
+			dest := links.Relative(to, after(target))
+			if dest == l.Target {
+				return "", false
+			}
+			if l.Fragment != "" {
+				dest += "#" + l.Fragment
+			}
+			return "[" + l.Label + "](" + dest + ")", true
+		})
+		changed := string(body) != string(data)
+		if of := e.Meta.TranslationOf; of != "" {
+			if id, ok := relink(of); ok {
+				if body, err = entry.Rewrite(body, func(f *entry.Front) error { return f.Set("translation_of", id) }); err != nil {
+					return nil, fmt.Errorf("%s: %w", from, err)
+				}
+				changed = true
+			}
+		}
+		if changed {
+			p.Relinked = append(p.Relinked, to)
+		}
+		if changed || ok || to != from {
+			p.Files[to] = body
+		}
+		if to != from {
+			p.Remove = append(p.Remove, from)
+		}
+	}
+	if len(ambiguous) > 0 {
+		return nil, &AmbiguousError{Links: ambiguous}
+	}
+	for _, from := range slices.Sorted(maps.Keys(p.Assets)) {
+		data, err := tree.Read(from)
+		if err != nil {
+			return nil, err
+		}
+		p.Files[p.Assets[from]] = data
+		p.Remove = append(p.Remove, from)
+	}
+	return p, nil
+}
@@ -0,0 +1,153 @@
+package move
+
+import (
+	"errors"
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/canhta/til/go/internal/notes"
+)
+
+func newTree(t *testing.T, files map[string]string) *notes.Tree {
+	t.Helper()
+	tree := notes.Open(t.TempDir())
+	for p, data := range files {
+		if err := tree.Write(p, []byte(data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return tree
+}
+
+func TestEntries(t *testing.T) {
+	tree := newTree(t, map[string]string{
+		"go/slices.md":           "---\ntitle: Slices\n---\n\nSee [maps](maps.md), [[maps]] and ![d](assets/slices/d.svg).\n",
+		"go/assets/slices/d.svg": "<svg/>",
+		"go/maps.md":             "---\ntitle: Maps\n---\n\nSee [slices](slices.md#copy), [[slices|the slices]] and [[go/slices]].\n",
+		"go/slices-vi.md":        "---\ntitle: Lát cắt\nlang: vi\ntranslation_of: go/slices\n---\n\nLát cắt.\n",
+		"git/rebase.md":          "# Rebase\n\n[[slices]] and [s](../go/slices.md).\n",
+		"db/sql.md":              "# SQL\n",
+	})
+	plan, err := Entries(tree, map[string]string{"go/slices.md": "db/slices.md"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"db/slices.md":           "---\ntitle: Slices\n---\n\nSee [maps](../go/maps.md), [[maps]] and ![d](assets/slices/d.svg).\n",
+		"db/assets/slices/d.svg": "<svg/>",
+		"go/maps.md":             "---\ntitle: Maps\n---\n\nSee [slices](../db/slices.md#copy), [[slices|the slices]] and [[db/slices]].\n",
+		"go/slices-vi.md":        "---\ntitle: Lát cắt\nlang: vi\ntranslation_of: db/slices\n---\n\nLát cắt.\n",
+		"git/rebase.md":          "# Rebase\n\n[[slices]] and [s](../db/slices.md).\n",
+	}
+	if got := slices.Sorted(maps.Keys(plan.Files)); !slices.Equal(got, slices.Sorted(maps.Keys(want))) {
+		t.Errorf("files = %q", got)
+	}
+	for p, data := range want {
+		if got := string(plan.Files[p]); got != data {
+			t.Errorf("%s =\n%s\nwant\n%s", p, got, data)
+		}
+	}
+	if want := []string{"go/slices.md", "go/assets/slices/d.svg"}; !slices.Equal(plan.Remove, want) {
+		t.Errorf("remove = %q, want %q", plan.Remove, want)
+	}
+	if want := []string{"git/rebase.md", "go/maps.md", "go/slices-vi.md", "db/slices.md"}; !slices.Equal(plan.Relinked, want) {
+		t.Errorf("relinked = %q, want %q", plan.Relinked, want)
+	}
+	if len(plan.Moved) != 1 || plan.Moved[0].Path != "go/slices.md" || plan.Assets["go/assets/slices/d.svg"] != "db/assets/slices/d.svg" {
+		t.Errorf("moved = %v, assets = %v", plan.Moved, plan.Assets)
+	}
+	// Planning changes nothing.
+	if ok, _ := tree.Exists("db/slices.md"); ok {
+		t.Error("db/slices.md written by Entries")
+	}
+}
+
+// TestEntriesPrecedence checks wiki links that resolve to another entry
+// once the entry linking moves into the category of that entry.
+func TestEntriesPrecedence(t *testing.T) {
+	tree := newTree(t, map[string]string{
+		"go/slices.md":   "# Slices\n",
+		"rust/slices.md": "# Slices\n",
+		"go/maps.md":     "# Maps\n\n[[slices]] and [[slices|these]].\n",
+	})
+	plan, err := Entries(tree, map[string]string{"go/maps.md": "rust/maps.md"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(plan.Files["rust/maps.md"]), "# Maps\n\n[[go/slices]] and [[go/slices|these]].\n"; got != want {
+		t.Errorf("rust/maps.md =\n%s\nwant\n%s", got, want)
+	}
+	if !slices.Equal(plan.Relinked, []string{"rust/maps.md"}) {
+		t.Errorf("relinked = %q", plan.Relinked)
+	}
+}
+
+func TestEntriesEdits(t *testing.T) {
+	tree := newTree(t, map[string]string{
+		"go/slices.md": "---\ntitle: Slices\ncategory: go\n---\n",
+		"go/maps.md":   "# Maps\n",
+	})
+	edited := "---\ntitle: Slices\ncategory: db\n---\n"
+	plan, err := Entries(tree, map[string]string{"go/slices.md": "db/slices.md"}, map[string][]byte{"go/slices.md": []byte(edited)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(plan.Files["db/slices.md"]); got != edited {
+		t.Errorf("db/slices.md = %q, want the edit", got)
+	}
+	if len(plan.Relinked) != 0 {
+		t.Errorf("relinked = %q", plan.Relinked)
+	}
+	if _, ok := plan.Files["go/maps.md"]; ok {
+		t.Error("go/maps.md written though its links do not change")
+	}
+}
+
+func TestEntriesAmbiguous(t *testing.T) {
+	tree := newTree(t, map[string]string{
+		"go/slices.md":   "# Slices\n",
+		"rust/slices.md": "# Slices\n",
+		"git/rebase.md":  "# Rebase\n\nSee [[slices]].\n",
+	})
+	_, err := Entries(tree, map[string]string{"go/slices.md": "db/slices.md"}, nil)
+	var amb *AmbiguousError
+	if !errors.As(err, &amb) {
+		t.Fatalf("Entries = %v, want an AmbiguousError", err)
+	}
+	if len(amb.Links) != 1 || amb.Links[0].String() != "git/rebase.md:3: [[slices]] names several entries, go/slices.md among them" {
+		t.Errorf("ambiguous links = %v", amb.Links)
+	}
+	if !strings.HasPrefix(err.Error(), "cannot rewrite 1 links unambiguously") {
+		t.Errorf("error = %v", err)
+	}
+	// The link names no moved entry, so moving another is fine.
+	if _, err := Entries(tree, map[string]string{"git/rebase.md": "db/rebase.md"}, nil); err != nil {
+		t.Errorf("move of git/rebase.md = %v", err)
+	}
+}
+
+func TestEntriesErrors(t *testing.T) {
+	tree := newTree(t, map[string]string{
+		"go/slices.md": "# Slices\n",
+		"go/maps.md":   "# Maps\n",
+	})
+	tests := []struct {
+		moves map[string]string
+		want  string
+	}{
+		{map[string]string{"go/nowhere.md": "db/nowhere.md"}, "go/nowhere.md: " + notes.ErrNotFound.Error()},
+		{map[string]string{"go/slices.md": "go/maps.md"}, "go/slices.md: cannot move to go/maps.md, which exists"},
+		{map[string]string{"go/maps.md": "db/x.md", "go/slices.md": "db/x.md"}, "go/slices.md: cannot move to db/x.md, as go/maps.md moves there"},
+	}
+	for _, tt := range tests {
+		if _, err := Entries(tree, tt.moves, nil); err == nil || err.Error() != tt.want {
+			t.Errorf("Entries(%v) = %v, want %s", tt.moves, err, tt.want)
+		}
+	}
+	plan, err := Entries(tree, map[string]string{"go/slices.md": "go/slices.md"}, nil)
+	if err != nil || len(plan.Files) != 0 || len(plan.Remove) != 0 {
+		t.Errorf("move in place = %+v, %v", plan, err)
+	}
+}
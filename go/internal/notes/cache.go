@@ -0,0 +1,318 @@
+package notes
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/canhta/til/go/internal/fsutil"
+	"github.com/canhta/til/go/internal/pool"
+	"github.com/canhta/til/go/internal/store"
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+// CacheFile is the file in the state directory holding the parsed entries,
+// so that listing the tree parses only the files changed since.
+const CacheFile = "entries.cache"
+
+// CacheVersion is the version of the cache format. It is raised whenever
+// entry.Parse derives something new, so that caches written before are
+// rebuilt rather than missing it.
+const CacheVersion = 1
+
+// cache is the content of CacheFile, gob-encoded but for its text, which
+// follows as is: the entries parsed as of Written, each with the size,
+// modification time and hash of its file.
+type cache struct {
+	Version int
+	// Written is when the entries were read. A file modified at or after it
+	// may have changed again within its timestamp's granularity, so its
+	// hash is checked even when its size and time match.
+	Written int64
+	Entries []cached
+	// text holds the frontmatter and body of every entry, one after the
+	// other, which the entries slice. It is read with the file rather than
+	// decoded, which is what makes loading the cache fast.
+	text []byte
+}
+
+// cached is an entry in the cache.
+type cached struct {
+	Path     string
+	ModTime  int64
+	Size     int64
+	Hash     [sha256.Size]byte
+	Meta     entry.Meta
+	BodyLine int
+	Counts   entry.Counts
+	// Front and Body are the ends of the frontmatter and the body in the
+	// text,
+	// the frontmatter starting at the end of the previous entry's body.
+	// NoFront is set when the entry has no frontmatter, as opposed to an
+	// empty one.
+	Front, Body int
+	NoFront     bool
+
+	front, body []byte
+}
+
+func (c *cached) entry(modTime time.Time) *entry.Entry {
+	return &entry.Entry{
+		Path:     c.Path,
+		Meta:     c.Meta,
+		Front:    c.front,
+		Body:     c.body,
+		BodyLine: c.BodyLine,
+		Counts:   c.Counts,
+		ModTime:  modTime,
+	}
+}
+
+// fresh reports whether the cached entry e is that of the file f, as far
+// as telling without reading the file goes.
+func (c *cache) fresh(e *cached, f store.Info) bool {
+	return e.ModTime == f.ModTime.UnixNano() && e.Size == f.Size && e.ModTime < c.Written
+}
+
+// loadCache reads the cache, keyed by path. A cache missing, unreadable or
+// of another version is empty.
+func (t *Tree) loadCache() (*cache, map[string]*cached, error) {
+	data, err := os.ReadFile(t.StatePath(CacheFile))
+	if err != nil {
+		return &cache{}, nil, err
+	}
+	var c cache
+	r := bytes.NewReader(data)
+	if err := gob.NewDecoder(r).Decode(&c); err != nil {
+		return &cache{}, nil, fmt.Errorf("%s: %w", CacheFile, err)
+	}
+	c.text = data[len(data)-r.Len():]
+	if c.Version != CacheVersion {
+		return &cache{}, nil, fmt.Errorf("%s: version %d, want %d", CacheFile, c.Version, CacheVersion)
+	}
+	byPath := make(map[string]*cached, len(c.Entries))
+	start := 0
+	for i := range c.Entries {
+		e := &c.Entries[i]
+		if start > e.Front || e.Front > e.Body || e.Body > len(c.text) {
+			return &cache{}, nil, fmt.Errorf("%s: %s: bad offsets", CacheFile, e.Path)
+		}
+		// Capped, so that appending to one entry's text copies it rather
+		// than overwriting the next.
+		if !e.NoFront {
+			e.front = c.text[start:e.Front:e.Front]
+		}
+		e.body = c.text[e.Front:e.Body:e.Body]
+		start = e.Body
+		byPath[e.Path] = e
+	}
+	return &c, byPath, nil
+}
+
+func (t *Tree) saveCache(c *cache) error {
+	var n int
+	for i := range c.Entries {
+		n += len(c.Entries[i].front) + len(c.Entries[i].body)
+	}
+	c.text = make([]byte, 0, n)
+	for i := range c.Entries {
+		e := &c.Entries[i]
+		c.text = append(c.text, e.front...)
+		e.Front = len(c.text)
+		c.text = append(c.text, e.body...)
+		e.Body = len(c.text)
+		e.NoFront = e.front == nil
+	}
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(c); err != nil {
+		return err
+	}
+	buf.Write(c.text)
+	return fsutil.WriteFile(t.StatePath(CacheFile), buf.Bytes(), 0o644)
+}
+
+// Entries loads every entry in the tree, parsing concurrently those whose
+// file changed since the cache was written and taking the rest from it.
+// The errors of all entries failing to load are returned together. The
+// cache is brought up to date if it can be; a failure to write it only
+// costs the next call the parsing.
+func (t *Tree) Entries() ([]*entry.Entry, error) {
+	entries, _, err := t.entries(false)
+	return entries, err
+}
+
+// entries loads every entry, ignoring the cache when rebuild is set, and
+// reports how many it parsed.
+func (t *Tree) entries(rebuild bool) ([]*entry.Entry, int, error) {
+	now := time.Now().UnixNano()
+	files, err := t.EntryFiles()
+	if err != nil {
+		return nil, 0, err
+	}
+	old, byPath, _ := t.loadCache()
+	if rebuild {
+		byPath = nil
+	}
+	next := &cache{Version: CacheVersion, Written: now, Entries: make([]cached, len(files))}
+	entries := make([]*entry.Entry, len(files))
+	parsed := make([]bool, len(files))
+	err = pool.Run(context.Background(), 0, len(files), func(_, i int) error {
+		f := files[i]
+		c := byPath[f.Path]
+		if c != nil && old.fresh(c, f) {
+			next.Entries[i] = *c
+			entries[i] = c.entry(f.ModTime)
+			return nil
+		}
+		data, fi, err := t.Store.Get(context.Background(), f.Path)
+		if err != nil {
+			return err
+		}
+		sum := sha256.Sum256(data)
+		if c != nil && c.Hash == sum {
+			// Touched but unchanged, as by a checkout.
+			n := *c
+			n.ModTime, n.Size = f.ModTime.UnixNano(), f.Size
+			next.Entries[i] = n
+			entries[i] = n.entry(f.ModTime)
+			return nil
+		}
+		e, err := entry.Parse(f.Path, data)
+		if err != nil {
+			return err
+		}
+		e.ModTime = fi.ModTime
+		entries[i], parsed[i] = e, true
+		next.Entries[i] = cached{
+			Path: f.Path, ModTime: f.ModTime.UnixNano(), Size: f.Size, Hash: sum,
+			Meta: e.Meta, BodyLine: e.BodyLine, Counts: e.Counts, front: e.Front, body: e.Body,
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	n := 0
+	for _, p := range parsed {
+		if p {
+			n++
+		}
+	}
+	if changed(old, next) {
+		_ = t.saveCache(next)
+	}
+	return entries, n, nil
+}
+
+// changed reports whether the cache next differs from old in anything but
+// the time it was written.
+func changed(old, next *cache) bool {
+	if old.Version != next.Version || len(old.Entries) != len(next.Entries) {
+		return true
+	}
+	for i := range old.Entries {
+		o, n := &old.Entries[i], &next.Entries[i]
+		if o.Path != n.Path || o.ModTime != n.ModTime || o.Size != n.Size || o.Hash != n.Hash {
+			return true
+		}
+		// A file modified close to the last write is checked until a write
+		// after it vouches for its time.
+		if o.ModTime >= old.Written {
+			return true
+		}
+	}
+	return false
+}
+
+// RebuildCache parses every entry anew and rewrites the cache, returning
+// the number of entries.
+func (t *Tree) RebuildCache() (int, error) {
+	if err := os.Remove(t.StatePath(CacheFile)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return 0, err
+	}
+	entries, _, err := t.entries(true)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := os.Stat(t.StatePath(CacheFile)); err != nil && len(entries) > 0 {
+		return 0, fmt.Errorf("writing %s: %w", CacheFile, err)
+	}
+	return len(entries), nil
+}
+
+// CacheReport is what CheckCache found.
+type CacheReport struct {
+	// Unreadable is why the cache could not be read, if it could not.
+	Unreadable string `json:"unreadable,omitempty"`
+	// Cached counts the entries in the cache.
+	Cached int `json:"cached"`
+	// Stale lists the entries whose file changed since the cache was
+	// written, re-parsed when next loaded; Missing those not in it, and
+	// Gone those cached without a file.
+	Stale   []string `json:"stale,omitempty"`
+	Missing []string `json:"missing,omitempty"`
+	Gone    []string `json:"gone,omitempty"`
+	// Corrupt lists the entries whose file matches the cache by size and
+	// time but not by content, which the cache would serve out of date.
+	Corrupt []string `json:"corrupt,omitempty"`
+	// Parsed is how many entries bringing the cache up to date parsed, and
+	// Load how long loading every entry took after.
+	Load   time.Duration `json:"load_ns"`
+	Parsed int           `json:"parsed"`
+}
+
+// CheckCache compares the cache with every entry file, reading each, and
+// then times loading the entries through it.
+func (t *Tree) CheckCache() (*CacheReport, error) {
+	r := &CacheReport{}
+	files, err := t.EntryFiles()
+	if err != nil {
+		return nil, err
+	}
+	c, byPath, err := t.loadCache()
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		r.Unreadable = err.Error()
+	}
+	r.Cached = len(c.Entries)
+	for _, f := range files {
+		e := byPath[f.Path]
+		if e == nil {
+			r.Missing = append(r.Missing, f.Path)
+			continue
+		}
+		delete(byPath, f.Path)
+		if !c.fresh(e, f) {
+			r.Stale = append(r.Stale, f.Path)
+			continue
+		}
+		data, err := t.Read(f.Path)
+		if err != nil {
+			return nil, err
+		}
+		if sha256.Sum256(data) != e.Hash {
+			r.Corrupt = append(r.Corrupt, f.Path)
+		}
+	}
+	for _, e := range c.Entries {
+		if byPath[e.Path] != nil {
+			r.Gone = append(r.Gone, e.Path)
+		}
+	}
+	if len(r.Corrupt) > 0 {
+		return r, nil
+	}
+	if _, r.Parsed, err = t.entries(false); err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	if _, _, err := t.entries(false); err != nil {
+		return nil, err
+	}
+	r.Load = time.Since(start)
+	return r, nil
+}
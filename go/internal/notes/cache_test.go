@@ -0,0 +1,138 @@
+package notes
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+// loadAll loads the entries of tree, returning how many were parsed.
+func loadAll(t *testing.T, tree *Tree) int {
+	t.Helper()
+	_, n, err := tree.entries(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+// age sets the modification time of the file p in tree to ago before now,
+// as of a file written well before the cache.
+func age(t *testing.T, tree *Tree, p string, ago time.Duration) {
+	t.Helper()
+	mt := time.Now().Add(-ago).Truncate(time.Second)
+	if err := os.Chtimes(filepath.Join(tree.Root, filepath.FromSlash(p)), mt, mt); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestEntriesCache(t *testing.T) {
+	files := map[string]string{
+		"go/slices.md":  "---\ntitle: Slices\ntags: [go]\n---\n\nSlices share arrays.\n\n```go\nx := 1\n```\n",
+		"go/empty.md":   "---\n---\n\nNo fields.\n",
+		"git/rebase.md": "# Rebase\n\nNo frontmatter.\n",
+	}
+	tree := newTree(t, files)
+	for p := range files {
+		age(t, tree, p, time.Hour)
+	}
+	parsed, err := tree.Entries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(tree.StatePath(CacheFile)); err != nil {
+		t.Fatalf("cache not written: %v", err)
+	}
+	if n := loadAll(t, tree); n != 0 {
+		t.Errorf("parsed %d entries with the cache fresh", n)
+	}
+	cached, err := tree.Entries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(cached, parsed) {
+		for i := range cached {
+			t.Errorf("cached %+v\nparsed %+v", cached[i], parsed[i])
+		}
+	}
+
+	// A file changed is parsed again, one only touched is not.
+	if err := tree.Write("go/slices.md", []byte(files["go/slices.md"]+"\nMore.\n")); err != nil {
+		t.Fatal(err)
+	}
+	age(t, tree, "go/slices.md", time.Minute)
+	age(t, tree, "git/rebase.md", 2*time.Minute)
+	if n := loadAll(t, tree); n != 1 {
+		t.Errorf("parsed %d entries, want the one changed", n)
+	}
+	es, _ := tree.Entries()
+	if i := slices.IndexFunc(es, func(e *entry.Entry) bool { return e.Path == "go/slices.md" }); string(es[i].Body) != "\nSlices share arrays.\n\n```go\nx := 1\n```\n\nMore.\n" {
+		t.Errorf("body of go/slices.md = %q", es[i].Body)
+	}
+
+	// Files gone and added.
+	if err := tree.Remove("go/empty.md"); err != nil {
+		t.Fatal(err)
+	}
+	if err := tree.Write("go/maps.md", []byte("# Maps\n")); err != nil {
+		t.Fatal(err)
+	}
+	r, err := tree.CheckCache()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Cached != 3 || !slices.Equal(r.Missing, []string{"go/maps.md"}) || !slices.Equal(r.Gone, []string{"go/empty.md"}) || r.Parsed != 1 {
+		t.Errorf("CheckCache = %+v", r)
+	}
+}
+
+// TestCacheCorrupt checks a file changed keeping its size and time, which
+// the cache serves out of date until it is rebuilt.
+func TestCacheCorrupt(t *testing.T) {
+	tree := newTree(t, map[string]string{"go/slices.md": "# Slices\n\nOld.\n"})
+	age(t, tree, "go/slices.md", time.Hour)
+	loadAll(t, tree)
+	if err := tree.Write("go/slices.md", []byte("# Slices\n\nNew.\n")); err != nil {
+		t.Fatal(err)
+	}
+	age(t, tree, "go/slices.md", time.Hour)
+	if es, _ := tree.Entries(); string(es[0].Body) != "# Slices\n\nOld.\n" {
+		t.Errorf("body = %q, want the cached one", es[0].Body)
+	}
+	r, err := tree.CheckCache()
+	if err != nil || !slices.Equal(r.Corrupt, []string{"go/slices.md"}) {
+		t.Errorf("CheckCache = %+v, %v", r, err)
+	}
+	if n, err := tree.RebuildCache(); n != 1 || err != nil {
+		t.Errorf("RebuildCache = %d, %v", n, err)
+	}
+	if es, _ := tree.Entries(); string(es[0].Body) != "# Slices\n\nNew.\n" {
+		t.Errorf("body after RebuildCache = %q", es[0].Body)
+	}
+	if r, err := tree.CheckCache(); err != nil || len(r.Corrupt)+len(r.Stale)+len(r.Missing) != 0 || r.Parsed != 0 {
+		t.Errorf("CheckCache after RebuildCache = %+v, %v", r, err)
+	}
+}
+
+func TestCacheUnreadable(t *testing.T) {
+	tree := newTree(t, map[string]string{"go/slices.md": "# Slices\n"})
+	if err := os.MkdirAll(filepath.Dir(tree.StatePath(CacheFile)), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(tree.StatePath(CacheFile), []byte("not a cache"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	r, err := tree.CheckCache()
+	if err != nil || r.Unreadable == "" || !slices.Equal(r.Missing, []string{"go/slices.md"}) || r.Parsed != 1 {
+		t.Errorf("CheckCache = %+v, %v", r, err)
+	}
+	// Loading replaced the cache.
+	if r, _ := tree.CheckCache(); r.Unreadable != "" || r.Cached != 1 {
+		t.Errorf("CheckCache after loading = %+v", r)
+	}
+}
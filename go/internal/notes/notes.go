@@ -0,0 +1,363 @@
+// Package notes locates the notes root and enumerates the entries in it.
+//
+// A notes tree is a directory whose top-level subdirectories are categories
+// ("go", "git", ...) holding markdown entries. Hidden directories and the
+// tool's own state directory are ignored.
+//
+// Entry files are read and written through a store.Store, the notes
+// directory itself by default.
+package notes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/canhta/til/go/internal/store"
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+// StateDir is the directory, relative to the root, holding templates and
+// local state such as indexes.
+const StateDir = ".til"
+
+// skipDirs are directory names never treated as categories or walked.
+var skipDirs = map[string]bool{
+	"node_modules": true,
+	"public":       true,
+	"testdata":     true,
+	"vendor":       true,
+}
+
+// ErrNotFound is returned when a reference does not match any entry.
+var ErrNotFound = errors.New("entry not found")
+
+// Tree is a notes tree rooted at Root.
+type Tree struct {
+	// Root is the local notes directory, which holds the state directory
+	// whatever the Store.
+	Root string
+	// Store holds the entry files.
+	Store store.Store
+}
+
+// FindRoot walks up from dir to the nearest directory containing a StateDir
+// or a .git directory. It returns dir itself when neither is found.
+func FindRoot(dir string) (string, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	for d := abs; ; {
+		for _, marker := range []string{StateDir, ".git"} {
+			if fi, err := os.Stat(filepath.Join(d, marker)); err == nil && fi.IsDir() {
+				return d, nil
+			}
+		}
+		parent := filepath.Dir(d)
+		if parent == d {
+			return abs, nil
+		}
+		d = parent
+	}
+}
+
+// Open returns the tree rooted at root, keeping its files in the directory.
+func Open(root string) *Tree {
+	return &Tree{Root: root, Store: &store.FS{Root: root, Skip: Skip}}
+}
+
+// Abs returns the absolute file path for the slash-separated relative path p.
+// The file exists only for local stores; see File.
+func (t *Tree) Abs(p string) string {
+	return filepath.Join(t.Root, filepath.FromSlash(p))
+}
+
+// Rel returns the slash-separated path of file relative to the root.
+func (t *Tree) Rel(file string) (string, error) {
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(t.Root, abs)
+	if err != nil {
+		return "", err
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// StatePath returns the path of name inside the state directory.
+func (t *Tree) StatePath(name ...string) string {
+	return filepath.Join(append([]string{t.Root, StateDir}, name...)...)
+}
+
+// Skip reports whether a directory with the given name is ignored.
+func Skip(name string) bool {
+	return strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") || skipDirs[name]
+}
+
+// InTree reports whether the slash-separated relative path p names a file
+// the tree lists: one below a category, in no skipped directory, and not a
+// dot file.
+func InTree(p string) bool {
+	if !filepath.IsLocal(p) || path.Clean(p) != p {
+		return false
+	}
+	parts := strings.Split(p, "/")
+	if len(parts) < 2 || strings.HasPrefix(parts[len(parts)-1], ".") {
+		return false
+	}
+	return !slices.ContainsFunc(parts[:len(parts)-1], Skip)
+}
+
+// Paths returns the relative paths of all entry files, sorted.
+func (t *Tree) Paths() ([]string, error) {
+	return t.walk(".md")
+}
+
+// EncryptedPaths returns the relative paths of all encrypted entry files,
+// those ending in ".md.age", sorted.
+func (t *Tree) EncryptedPaths() ([]string, error) {
+	return t.walk(".md.age")
+}
+
+// EntryFiles describes all entry files, sorted by path.
+func (t *Tree) EntryFiles() ([]store.Info, error) {
+	return t.filter(".md")
+}
+
+func (t *Tree) walk(suffix string) ([]string, error) {
+	files, err := t.filter(suffix)
+	paths := make([]string, len(files))
+	for i, f := range files {
+		paths[i] = f.Path
+	}
+	return paths, err
+}
+
+// filter returns the files below categories whose names end in suffix.
+func (t *Tree) filter(suffix string) ([]store.Info, error) {
+	files, err := t.Files()
+	if err != nil {
+		return nil, err
+	}
+	out := files[:0]
+	for _, f := range files {
+		if strings.HasSuffix(f.Path, suffix) {
+			out = append(out, f)
+		}
+	}
+	return out, nil
+}
+
+// Files lists every file in the categories of the tree, sorted by path.
+func (t *Tree) Files() ([]store.Info, error) {
+	return t.Store.List(context.Background())
+}
+
+// Read returns the content of the file at the relative path p.
+func (t *Tree) Read(p string) ([]byte, error) {
+	data, _, err := t.Store.Get(context.Background(), p)
+	return data, err
+}
+
+// Write creates or replaces the file at the relative path p.
+func (t *Tree) Write(p string, data []byte) error {
+	return t.Store.Put(context.Background(), p, data)
+}
+
+// WriteAll replaces several files, keyed by relative path, as one unit
+// where the store allows it.
+func (t *Tree) WriteAll(files map[string][]byte) error {
+	return store.PutAll(context.Background(), t.Store, files)
+}
+
+// Replace writes files, keyed by relative path, and then removes the files
+// at the paths in remove, as one unit: when a removal fails, the files
+// written and removed so far are restored before the error is returned.
+func (t *Tree) Replace(files map[string][]byte, remove []string) error {
+	ctx := context.Background()
+	old := map[string][]byte{}
+	var created []string
+	for p := range files {
+		data, _, err := t.Store.Get(ctx, p)
+		switch {
+		case err == nil:
+			old[p] = data
+		case errors.Is(err, store.ErrNotExist):
+			created = append(created, p)
+		default:
+			return err
+		}
+	}
+	removed := map[string][]byte{}
+	for _, p := range remove {
+		if _, ok := removed[p]; ok {
+			continue
+		}
+		data, _, err := t.Store.Get(ctx, p)
+		if err != nil {
+			return err
+		}
+		removed[p] = data
+	}
+	if err := t.WriteAll(files); err != nil {
+		return err
+	}
+	for i, p := range remove {
+		if err := t.Store.Delete(ctx, p); err != nil {
+			for _, q := range created {
+				t.Store.Delete(ctx, q)
+			}
+			for _, q := range remove[:i] {
+				old[q] = removed[q]
+			}
+			t.WriteAll(old)
+			return err
+		}
+	}
+	return nil
+}
+
+// Create writes a new file at the relative path p, failing if one exists.
+func (t *Tree) Create(p string, data []byte) error {
+	ok, err := store.Exists(context.Background(), t.Store, p)
+	if err != nil {
+		return err
+	}
+	if ok {
+		return fmt.Errorf("%s already exists", p)
+	}
+	return t.Write(p, data)
+}
+
+// Remove deletes the file at the relative path p.
+func (t *Tree) Remove(p string) error {
+	return t.Store.Delete(context.Background(), p)
+}
+
+// Exists reports whether there is a file at the relative path p.
+func (t *Tree) Exists(p string) (bool, error) {
+	return store.Exists(context.Background(), t.Store, p)
+}
+
+// File returns the local file holding the relative path p, if the store
+// keeps its files locally.
+func (t *Tree) File(p string) (string, bool) {
+	if l, ok := t.Store.(store.Local); ok {
+		return l.File(p), true
+	}
+	return "", false
+}
+
+// Load reads and parses the entry at the relative path p.
+func (t *Tree) Load(p string) (*entry.Entry, error) {
+	data, fi, err := t.Store.Get(context.Background(), p)
+	if err != nil {
+		return nil, err
+	}
+	e, err := entry.Parse(p, data)
+	if err != nil {
+		return nil, err
+	}
+	e.ModTime = fi.ModTime
+	return e, nil
+}
+
+// Snippets returns the snippets of e: its code blocks and then, if it names
+// one, its companion program.
+func (t *Tree) Snippets(e *entry.Entry) ([]entry.Snippet, error) {
+	snippets := entry.Snippets(e.Body)
+	if p := e.SourcePath(); p != "" {
+		code, err := t.Read(p)
+		if err != nil {
+			return snippets, fmt.Errorf("%s: source: %w", e.Path, err)
+		}
+		snippets = append(snippets, entry.SourceSnippet(e, code))
+	}
+	return snippets, nil
+}
+
+// Categories returns the names of the categories holding files, sorted.
+func (t *Tree) Categories() ([]string, error) {
+	files, err := t.Files()
+	if err != nil {
+		return nil, err
+	}
+	var cats []string
+	for _, f := range files {
+		cat, _, _ := strings.Cut(f.Path, "/")
+		cats = append(cats, cat)
+	}
+	sort.Strings(cats)
+	return slices.Compact(cats), nil
+}
+
+// Resolve finds the entry referred to by ref, which may be its path, a
+// "category/stem" ID, a bare stem or a slug. Only the entries of the tree
+// match: a file path naming anything else does not.
+func (t *Tree) Resolve(ref string) (*entry.Entry, error) {
+	ref = strings.TrimSuffix(filepath.ToSlash(ref), ".md")
+	entries, err := t.Entries()
+	if err != nil {
+		return nil, err
+	}
+	var matches []*entry.Entry
+	for _, e := range entries {
+		if e.ID() == ref {
+			return e, nil
+		}
+		if e.Stem() == ref || e.Meta.Slug == ref {
+			matches = append(matches, e)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("%q: %w", ref, ErrNotFound)
+	case 1:
+		return matches[0], nil
+	}
+	ids := make([]string, len(matches))
+	for i, e := range matches {
+		ids[i] = e.ID()
+	}
+	return nil, fmt.Errorf("%q is ambiguous: %s", ref, strings.Join(ids, ", "))
+}
+
+// ResolveEncrypted finds the encrypted entry file named by ref: a file path,
+// or a path or file stem with or without extension; a stem also matches in
+// its slugified form. Encrypted entries cannot be matched by frontmatter
+// slug or title without decrypting them.
+func (t *Tree) ResolveEncrypted(ref string) (string, error) {
+	if fi, err := os.Stat(ref); err == nil && !fi.IsDir() && strings.HasSuffix(ref, ".md.age") {
+		return t.Rel(ref)
+	}
+	ref = strings.TrimSuffix(strings.TrimSuffix(filepath.ToSlash(ref), ".age"), ".md")
+	paths, err := t.EncryptedPaths()
+	if err != nil {
+		return "", err
+	}
+	var matches []string
+	for _, p := range paths {
+		id := strings.TrimSuffix(p, ".md.age")
+		if id == ref {
+			return p, nil
+		}
+		if stem := path.Base(id); stem == ref || entry.Slugify(stem) == ref {
+			matches = append(matches, p)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("%q: %w", ref, ErrNotFound)
+	case 1:
+		return matches[0], nil
+	}
+	return "", fmt.Errorf("%q is ambiguous: %s", ref, strings.Join(matches, ", "))
+}
@@ -0,0 +1,291 @@
+package notes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/canhta/til/go/internal/store"
+)
+
+func TestInTree(t *testing.T) {
+	tests := []struct {
+		p    string
+		want bool
+	}{
+		{"go/slices.md", true},
+		{"go/deep/er/x.md", true},
+		{"go/assets/a.png", true},
+		{"README.md", false},
+		{"", false},
+		{"/etc/passwd", false},
+		{"../x/y.md", false},
+		{"go/../../x.md", false},
+		{"go/./x.md", false},
+		{"go//x.md", false},
+		{".git/config", false},
+		{".til/remotes.json", false},
+		{"go/.git/hooks/pre-commit", false},
+		{"_drafts/x.md", false},
+		{"public/index.html", false},
+		{"go/node_modules/x.md", false},
+		{"go/.x.md.swp", false},
+	}
+	for _, tt := range tests {
+		if got := InTree(tt.p); got != tt.want {
+			t.Errorf("InTree(%q) = %v, want %v", tt.p, got, tt.want)
+		}
+	}
+}
+
+// newTree writes files to a new tree.
+func newTree(t *testing.T, files map[string]string) *Tree {
+	t.Helper()
+	tree := Open(t.TempDir())
+	for p, data := range files {
+		file := tree.Abs(p)
+		if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return tree
+}
+
+func TestPaths(t *testing.T) {
+	tree := newTree(t, map[string]string{
+		"go/slices.md":         "# Slices\n",
+		"go/deep/nested.md":    "# Nested\n",
+		"git/rebase.md":        "# Rebase\n",
+		"git/notes.txt":        "not an entry\n",
+		"README.md":            "# Notes\n",
+		".til/templates/go.md": "---\n---\n",
+		"_drafts/x.md":         "# Draft\n",
+		"node_modules/x/y.md":  "# Dep\n",
+		"go/.hidden.md":        "# Hidden\n",
+	})
+	got, err := tree.Paths()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"git/rebase.md", "go/deep/nested.md", "go/slices.md"}; !slices.Equal(got, want) {
+		t.Errorf("Paths() = %q, want %q", got, want)
+	}
+	cats, err := tree.Categories()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"git", "go"}; !slices.Equal(cats, want) {
+		t.Errorf("Categories() = %q, want %q", cats, want)
+	}
+}
+
+func TestEntries(t *testing.T) {
+	files := map[string]string{}
+	var want []string
+	for i := range 40 {
+		p := fmt.Sprintf("go/e%02d.md", i)
+		files[p] = fmt.Sprintf("# Entry %d\n", i)
+		want = append(want, p)
+	}
+	tree := newTree(t, files)
+	entries, err := tree.Entries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, e := range entries {
+		got = append(got, e.Path)
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("Entries() = %q, want them in path order", got)
+	}
+
+	// The errors of every entry failing to load are returned together.
+	for _, p := range []string{"go/e03.md", "go/e31.md"} {
+		if err := tree.Write(p, []byte("---\ntags: [unclosed\n---\n")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, err = tree.Entries()
+	if err == nil || !strings.Contains(err.Error(), "go/e03.md") || !strings.Contains(err.Error(), "go/e31.md") {
+		t.Errorf("Entries() = %v, want both failures", err)
+	}
+}
+
+func TestResolve(t *testing.T) {
+	tree := newTree(t, map[string]string{
+		"go/slices.md":  "---\ntitle: Slices\nslug: copy-slices\n---\n",
+		"go/maps.md":    "# Maps\n",
+		"rust/maps.md":  "# Maps in Rust\n",
+		"git/rebase.md": "# Rebase\n",
+	})
+	for ref, want := range map[string]string{
+		"go/slices.md": "go/slices.md",
+		"go/slices":    "go/slices.md",
+		"slices":       "go/slices.md",
+		"copy-slices":  "go/slices.md",
+		"go/maps":      "go/maps.md",
+		"rust/maps.md": "rust/maps.md",
+		"rebase.md":    "git/rebase.md",
+	} {
+		e, err := tree.Resolve(ref)
+		if err != nil {
+			t.Errorf("Resolve(%s): %v", ref, err)
+		} else if e.Path != want {
+			t.Errorf("Resolve(%s) = %s, want %s", ref, e.Path, want)
+		}
+	}
+	if _, err := tree.Resolve("nowhere"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Resolve(nowhere) = %v, want ErrNotFound", err)
+	}
+	if _, err := tree.Resolve("maps"); err == nil || !strings.Contains(err.Error(), "ambiguous: go/maps, rust/maps") {
+		t.Errorf("Resolve(maps) = %v, want ambiguous", err)
+	}
+}
+
+func TestCreate(t *testing.T) {
+	tree := newTree(t, map[string]string{"go/slices.md": "# Slices\n"})
+	if err := tree.Create("go/slices.md", []byte("# Other\n")); err == nil {
+		t.Error("Create overwrote go/slices.md")
+	}
+	if data, _ := tree.Read("go/slices.md"); string(data) != "# Slices\n" {
+		t.Errorf("go/slices.md = %q", data)
+	}
+	if err := tree.Create("rust/new/x.md", []byte("# X\n")); err != nil {
+		t.Fatal(err)
+	}
+	e, err := tree.Load("rust/new/x.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Meta.Title != "X" || e.Path != "rust/new/x.md" {
+		t.Errorf("Load = %q at %s", e.Meta.Title, e.Path)
+	}
+}
+
+func TestFindRoot(t *testing.T) {
+	tree := newTree(t, map[string]string{".til/x": "", "go/deep/x.md": ""})
+	got, err := FindRoot(tree.Abs("go/deep"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, _ := filepath.EvalSymlinks(tree.Root); got != tree.Root && got != want {
+		t.Errorf("FindRoot = %s, want %s", got, tree.Root)
+	}
+}
+
+// memStore is a Store keeping files in a map. Delete fails for paths in
+// failDelete.
+type memStore struct {
+	files      map[string][]byte
+	failDelete map[string]bool
+}
+
+func (m *memStore) List(ctx context.Context) ([]store.Info, error) {
+	var out []store.Info
+	for _, p := range slices.Sorted(maps.Keys(m.files)) {
+		out = append(out, store.Info{Path: p, Size: int64(len(m.files[p]))})
+	}
+	return out, nil
+}
+
+func (m *memStore) Get(ctx context.Context, p string) ([]byte, store.Info, error) {
+	data, ok := m.files[p]
+	if !ok {
+		return nil, store.Info{}, store.ErrNotExist
+	}
+	return data, store.Info{Path: p, Size: int64(len(data))}, nil
+}
+
+func (m *memStore) Put(ctx context.Context, p string, data []byte) error {
+	m.files[p] = data
+	return nil
+}
+
+func (m *memStore) Delete(ctx context.Context, p string) error {
+	if m.failDelete[p] {
+		return errors.New("delete failed")
+	}
+	delete(m.files, p)
+	return nil
+}
+
+func (m *memStore) Watch(ctx context.Context) (<-chan store.Event, error) {
+	return nil, errors.ErrUnsupported
+}
+
+func TestTreeOverStore(t *testing.T) {
+	m := &memStore{files: map[string][]byte{
+		"go/slices.md":      []byte("# Slices\n"),
+		"go/secret.md.age":  []byte("sealed"),
+		"go/assets/s/a.png": []byte("png"),
+	}}
+	tree := &Tree{Root: t.TempDir(), Store: m}
+	paths, err := tree.Paths()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(paths, []string{"go/slices.md"}) {
+		t.Errorf("Paths = %q", paths)
+	}
+	if enc, _ := tree.EncryptedPaths(); !slices.Equal(enc, []string{"go/secret.md.age"}) {
+		t.Errorf("EncryptedPaths = %q", enc)
+	}
+	if _, ok := tree.File("go/slices.md"); ok {
+		t.Error("File reports a local file for a remote store")
+	}
+	if err := tree.Create("go/slices.md", nil); err == nil {
+		t.Error("Create overwrote go/slices.md")
+	}
+	if err := tree.Create("git/rebase.md", []byte("# Rebase\n")); err != nil {
+		t.Fatal(err)
+	}
+	e, err := tree.Resolve("rebase")
+	if err != nil || e.Meta.Title != "Rebase" {
+		t.Errorf("Resolve(rebase) = %v, %v", e, err)
+	}
+	if _, err := os.Stat(tree.Abs("git/rebase.md")); err == nil {
+		t.Error("the entry was written to the notes directory")
+	}
+}
+
+func TestReplaceRollsBack(t *testing.T) {
+	m := &memStore{
+		files: map[string][]byte{
+			"go/a.md": []byte("a"),
+			"go/b.md": []byte("b"),
+			"go/c.md": []byte("c"),
+		},
+		failDelete: map[string]bool{"go/c.md": true},
+	}
+	tree := &Tree{Root: t.TempDir(), Store: m}
+	err := tree.Replace(map[string][]byte{"go/a.md": []byte("a2"), "go/new.md": []byte("new")}, []string{"go/b.md", "go/c.md"})
+	if err == nil {
+		t.Fatal("Replace succeeded")
+	}
+	want := map[string]string{"go/a.md": "a", "go/b.md": "b", "go/c.md": "c"}
+	got := map[string]string{}
+	for p, data := range m.files {
+		got[p] = string(data)
+	}
+	if !maps.Equal(got, want) {
+		t.Errorf("after a failed Replace files = %v, want %v", got, want)
+	}
+
+	m.failDelete = nil
+	if err := tree.Replace(map[string][]byte{"go/a.md": []byte("a2")}, []string{"go/b.md"}); err != nil {
+		t.Fatal(err)
+	}
+	if string(m.files["go/a.md"]) != "a2" || m.files["go/b.md"] != nil {
+		t.Errorf("after Replace files = %q", slices.Sorted(maps.Keys(m.files)))
+	}
+}
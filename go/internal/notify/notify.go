@@ -0,0 +1,266 @@
+// Package notify announces entries on chat webhooks, formatting a message
+// from a template and posting it as Slack, Discord or plain JSON expects.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+// Webhook kinds, which decide the payload and how links are written.
+const (
+	// Slack posts {"text": message} with mrkdwn links.
+	Slack = "slack"
+	// Discord posts {"content": message} with markdown links.
+	Discord = "discord"
+	// JSON posts {"text": message, "entry": data}.
+	JSON = "json"
+)
+
+// DefaultTemplate is the message template used when none is configured.
+const DefaultTemplate = `New TIL: {{link .URL .Title}}{{range .Tags}} #{{.}}{{end}}
+{{quote .Excerpt}}`
+
+// Defaults for retrying failed posts.
+const (
+	DefaultAttempts = 4
+	DefaultBackoff  = time.Second
+	// maxWait caps the wait between attempts, whatever Retry-After says.
+	maxWait = time.Minute
+	// discordLimit is the longest message Discord accepts.
+	discordLimit = 2000
+)
+
+// ExcerptWords is the length of the excerpt given to templates.
+const ExcerptWords = 40
+
+// Data is the data available to message templates.
+type Data struct {
+	Title    string   `json:"title"`
+	Path     string   `json:"path"`
+	Category string   `json:"category"`
+	Tags     []string `json:"tags"`
+	Date     string   `json:"date,omitempty"`
+	Excerpt  string   `json:"excerpt"`
+	// URL is the entry's page on the site, or "" when the site's address
+	// is not known.
+	URL string `json:"url,omitempty"`
+}
+
+// DataFor returns the template data of e, whose page is at url.
+func DataFor(e *entry.Entry, url string) Data {
+	d := Data{
+		Title:    e.Meta.Title,
+		Path:     e.Path,
+		Category: e.Meta.Category,
+		Tags:     e.Meta.Tags,
+		Excerpt:  entry.Excerpt(e.Body, ExcerptWords),
+		URL:      url,
+	}
+	if d.Tags == nil {
+		d.Tags = []string{}
+	}
+	if t := e.Created(); !t.IsZero() {
+		d.Date = t.Format(entry.DateLayout)
+	}
+	return d
+}
+
+// Webhook is an endpoint entries are announced on.
+type Webhook struct {
+	URL  string
+	Kind string
+	// Attempts is how many times a post is tried, Backoff the wait after
+	// the first failure, doubled after each further one. A response's
+	// Retry-After overrides the wait.
+	Attempts int
+	Backoff  time.Duration
+	Client   *http.Client
+
+	tmpl *template.Template
+}
+
+// New returns the webhook at rawURL. An empty kind is inferred from the
+// host, defaulting to JSON, and an empty text to DefaultTemplate.
+func New(rawURL, kind, text string) (*Webhook, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, fmt.Errorf("webhook %q: not an http(s) URL", rawURL)
+	}
+	if kind == "" {
+		kind = kindOf(u.Hostname())
+	}
+	if kind != Slack && kind != Discord && kind != JSON {
+		return nil, fmt.Errorf("webhook %q: unknown kind %q: want slack, discord or json", rawURL, kind)
+	}
+	if text == "" {
+		text = DefaultTemplate
+	}
+	t, err := template.New(kind).Funcs(funcs(kind)).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("webhook %q: %w", rawURL, err)
+	}
+	return &Webhook{URL: rawURL, Kind: kind, Attempts: DefaultAttempts, Backoff: DefaultBackoff, tmpl: t}, nil
+}
+
+func kindOf(host string) string {
+	switch {
+	case host == "hooks.slack.com":
+		return Slack
+	case host == "discord.com" || host == "discordapp.com" || strings.HasSuffix(host, ".discord.com"):
+		return Discord
+	}
+	return JSON
+}
+
+// funcs are the template functions, which write markup as kind expects.
+func funcs(kind string) template.FuncMap {
+	return template.FuncMap{
+		// link links label to url, or is label alone without a url.
+		"link": func(url, label string) string {
+			switch {
+			case url == "":
+				return label
+			case kind == Slack:
+				label = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", "|", "¦").Replace(label)
+				return "<" + url + "|" + label + ">"
+			case kind == Discord:
+				return "[" + strings.NewReplacer("[", `\[`, "]", `\]`).Replace(label) + "](" + url + ")"
+			}
+			return label + " " + url
+		},
+		// quote marks s as a quotation.
+		"quote": func(s string) string {
+			if s == "" || kind == JSON {
+				return s
+			}
+			return "> " + strings.ReplaceAll(s, "\n", "\n> ")
+		},
+		"join": strings.Join,
+	}
+}
+
+// Message renders the message announcing d.
+func (w *Webhook) Message(d Data) (string, error) {
+	var b strings.Builder
+	if err := w.tmpl.Execute(&b, d); err != nil {
+		return "", err
+	}
+	msg := strings.TrimSpace(b.String())
+	if w.Kind == Discord {
+		if r := []rune(msg); len(r) > discordLimit {
+			msg = string(r[:discordLimit-1]) + "…"
+		}
+	}
+	return msg, nil
+}
+
+// Post announces d, retrying on network errors, 429 and 5xx responses.
+func (w *Webhook) Post(ctx context.Context, d Data) error {
+	msg, err := w.Message(d)
+	if err != nil {
+		return err
+	}
+	return w.send(ctx, msg, map[string]any{"text": msg, "entry": d})
+}
+
+// PostText posts msg as is, not through the template, as for reminders
+// about no particular entry.
+func (w *Webhook) PostText(ctx context.Context, msg string) error {
+	if w.Kind == Discord {
+		if r := []rune(msg); len(r) > discordLimit {
+			msg = string(r[:discordLimit-1]) + "…"
+		}
+	}
+	return w.send(ctx, msg, map[string]any{"text": msg})
+}
+
+// send posts msg, as the JSON payload given for generic webhooks.
+func (w *Webhook) send(ctx context.Context, msg string, payload any) error {
+	switch w.Kind {
+	case Slack:
+		payload = map[string]string{"text": msg}
+	case Discord:
+		payload = map[string]string{"content": msg}
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	client := w.Client
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	attempts := max(w.Attempts, 1)
+	wait := w.Backoff
+	for i := 1; ; i++ {
+		retryAfter, err := w.post(ctx, client, body)
+		var perm *permanentError
+		if err == nil || errors.As(err, &perm) || i == attempts {
+			return err
+		}
+		// Jitter spreads out retries from several clients.
+		delay := wait + rand.N(wait/4+1)
+		if retryAfter > 0 {
+			delay = retryAfter
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(min(delay, maxWait)):
+		}
+		wait *= 2
+	}
+}
+
+// permanentError is a failure not worth retrying.
+type permanentError struct{ err error }
+
+func (e *permanentError) Error() string { return e.err.Error() }
+
+// post makes one attempt, returning the wait a 429 or 503 response asks
+// for.
+func (w *Webhook) post(ctx context.Context, client *http.Client, body []byte) (time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, &permanentError{err}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return 0, &permanentError{err}
+		}
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 == 2 {
+		return 0, nil
+	}
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	err = errors.New(resp.Status)
+	if msg = bytes.TrimSpace(msg); len(msg) > 0 {
+		err = fmt.Errorf("%s: %s", resp.Status, msg)
+	}
+	if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode < 500 {
+		return 0, &permanentError{err}
+	}
+	var wait time.Duration
+	if s, perr := strconv.Atoi(resp.Header.Get("Retry-After")); perr == nil && s > 0 {
+		wait = time.Duration(s) * time.Second
+	}
+	return wait, err
+}
@@ -0,0 +1,145 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+var data = Data{
+	Title:   "Slices <alias> | [arrays]",
+	Path:    "go/slices.md",
+	Tags:    []string{"go", "slices"},
+	Excerpt: "Append may write\ninto another slice.",
+	URL:     "https://til.example/go/slices/",
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct{ url, kind, want string }{
+		{"https://hooks.slack.com/services/x", "", Slack},
+		{"https://discord.com/api/webhooks/1/x", "", Discord},
+		{"https://ptb.discord.com/api/webhooks/1/x", "", Discord},
+		{"https://example.com/hook", "", JSON},
+		{"https://example.com/hook", Slack, Slack},
+	}
+	for _, tt := range tests {
+		w, err := New(tt.url, tt.kind, "")
+		if err != nil || w.Kind != tt.want {
+			t.Errorf("New(%q, %q) = %v, %v, want kind %s", tt.url, tt.kind, w, err, tt.want)
+		}
+	}
+	for _, args := range [][3]string{{"ftp://example.com", "", ""}, {"https://example.com", "irc", ""}, {"https://example.com", "", "{{.Nope"}} {
+		if _, err := New(args[0], args[1], args[2]); err == nil {
+			t.Errorf("New(%q) succeeded", args)
+		}
+	}
+}
+
+func TestMessage(t *testing.T) {
+	tests := []struct{ kind, want string }{
+		{Slack, "New TIL: <https://til.example/go/slices/|Slices &lt;alias&gt; ¦ [arrays]> #go #slices\n> Append may write\n> into another slice."},
+		{Discord, "New TIL: [Slices <alias> | \\[arrays\\]](https://til.example/go/slices/) #go #slices\n> Append may write\n> into another slice."},
+		{JSON, "New TIL: Slices <alias> | [arrays] https://til.example/go/slices/ #go #slices\nAppend may write\ninto another slice."},
+	}
+	for _, tt := range tests {
+		w, _ := New("https://example.com", tt.kind, "")
+		if got, err := w.Message(data); err != nil || got != tt.want {
+			t.Errorf("%s Message = %q, %v, want %q", tt.kind, got, err, tt.want)
+		}
+	}
+
+	w, _ := New("https://example.com", Slack, `{{link .URL .Title}} in {{.Category}}: {{join .Tags ", "}}`)
+	d := data
+	d.URL, d.Category = "", "go"
+	if got, _ := w.Message(d); got != "Slices <alias> | [arrays] in go: go, slices" {
+		t.Errorf("custom template = %q", got)
+	}
+
+	w, _ = New("https://discord.com/api/webhooks/1/x", "", "{{.Excerpt}}")
+	d.Excerpt = strings.Repeat("é", 3000)
+	if got, _ := w.Message(d); len([]rune(got)) != discordLimit || !strings.HasSuffix(got, "…") {
+		t.Errorf("long Discord message has %d runes", len([]rune(got)))
+	}
+}
+
+func TestDataFor(t *testing.T) {
+	e, err := entry.Parse("go/slices.md", []byte("---\ntitle: Slices\ndate: 2024-06-01\n---\n# Slices\n\nAppend may reallocate.\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := DataFor(e, "https://til.example/go/slices/")
+	if d.Title != "Slices" || d.Category != "go" || d.Date != "2024-06-01" || d.Tags == nil || d.Excerpt != "Append may reallocate." {
+		t.Errorf("DataFor = %+v", d)
+	}
+}
+
+func TestPost(t *testing.T) {
+	var (
+		calls atomic.Int32
+		body  map[string]any
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch calls.Add(1) {
+		case 1:
+			w.WriteHeader(http.StatusBadGateway)
+		case 2:
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+		default:
+			json.NewDecoder(r.Body).Decode(&body)
+		}
+	}))
+	defer srv.Close()
+
+	w, _ := New(srv.URL, "", "{{.Title}}")
+	w.Backoff = time.Millisecond
+	start := time.Now()
+	if err := w.Post(context.Background(), data); err != nil {
+		t.Fatal(err)
+	}
+	if calls.Load() != 3 || time.Since(start) < time.Second {
+		t.Errorf("%d calls in %v, want 3 waiting out Retry-After", calls.Load(), time.Since(start))
+	}
+	if body["text"] != data.Title || body["entry"].(map[string]any)["path"] != "go/slices.md" {
+		t.Errorf("posted %v", body)
+	}
+
+	w.Kind, body = Discord, nil
+	calls.Store(2)
+	if err := w.PostText(context.Background(), "reminder"); err != nil || body["content"] != "reminder" || body["text"] != nil {
+		t.Errorf("PostText = %v, posted %v", err, body)
+	}
+}
+
+func TestPostGivesUp(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if r.URL.Path == "/bad" {
+			http.Error(w, "invalid_payload", http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	w, _ := New(srv.URL+"/bad", "", "")
+	w.Backoff = time.Millisecond
+	if err := w.Post(context.Background(), data); err == nil || err.Error() != "400 Bad Request: invalid_payload" || calls.Load() != 1 {
+		t.Errorf("Post = %v after %d calls, want no retries of a 400", err, calls.Load())
+	}
+
+	calls.Store(0)
+	w, _ = New(srv.URL+"/down", "", "")
+	w.Backoff, w.Attempts = time.Millisecond, 3
+	if err := w.Post(context.Background(), data); err == nil || err.Error() != "503 Service Unavailable" || calls.Load() != 3 {
+		t.Errorf("Post = %v after %d calls, want 3 attempts", err, calls.Load())
+	}
+}
@@ -0,0 +1,210 @@
+// Package ogimage draws social preview images, the cards link previews
+// show for an entry, from an SVG template.
+//
+// Cards are SVG. Most sites showing previews want a raster image, so when
+// a converter command is configured or found, cards are converted to PNG
+// with it and the results cached in the notes state directory.
+package ogimage
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	_ "embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/canhta/til/go/internal/fsutil"
+	"github.com/canhta/til/go/internal/notes"
+)
+
+// Dir is the cache directory inside the notes state directory.
+const Dir = "og-images"
+
+// Width and Height are the size of a card, that of Open Graph's large
+// images.
+const (
+	Width  = 1200
+	Height = 630
+)
+
+// Timeout bounds each run of the converter.
+const Timeout = 30 * time.Second
+
+// TitleLine and TitleLines bound the title as drawn by the default
+// template: at most TitleLines lines of about TitleLine characters.
+const (
+	TitleLine  = 28
+	TitleLines = 3
+)
+
+// ErrUnavailable is returned by Convert when no converter is configured or
+// found.
+var ErrUnavailable = errors.New("no SVG to PNG converter available")
+
+// DefaultConverter is the librsvg invocation used when rsvg-convert is on
+// the PATH and no command is configured. In arguments, "{file}" expands to
+// the SVG file and "{out}" to the PNG file to write.
+var DefaultConverter = []string{"rsvg-convert", "--width", "1200", "--height", "630", "--output", "{out}", "{file}"}
+
+//go:embed card.svg
+var defaultTemplate string
+
+// Default is the card template used when the theme has none.
+var Default = template.Must(Parse(defaultTemplate))
+
+// Card is the data a card template is executed with, with Width and
+// Height.
+type Card struct {
+	Title    string
+	Category string
+	Site     string
+	// Date is the entry's creation date, or "".
+	Date string
+	// Lines is Title wrapped to fit the card.
+	Lines         []string
+	Width, Height int
+}
+
+// NewCard returns the card of an entry.
+func NewCard(title, category, site, date string) Card {
+	return Card{Title: title, Category: category, Site: site, Date: date, Lines: Wrap(title, TitleLine, TitleLines), Width: Width, Height: Height}
+}
+
+// Parse parses a card template, a text/template of an SVG document. The
+// template's html function escapes text for XML.
+func Parse(text string) (*template.Template, error) {
+	return template.New("card").Parse(text)
+}
+
+// SVG draws c with tmpl.
+func SVG(tmpl *template.Template, c Card) ([]byte, error) {
+	var b bytes.Buffer
+	if err := tmpl.Execute(&b, c); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// Wrap breaks s into at most n lines of about width characters at
+// spaces, ending the last with an ellipsis when s does not fit.
+func Wrap(s string, width, n int) []string {
+	var lines []string
+	var cur string
+	words := strings.Fields(s)
+	for i, w := range words {
+		if cur != "" && len([]rune(cur))+1+len([]rune(w)) > width {
+			lines = append(lines, cur)
+			cur = ""
+			if len(lines) == n {
+				// Words are dropped to make room for the ellipsis.
+				last := lines[n-1]
+				for len([]rune(last)) >= width {
+					j := strings.LastIndexByte(last, ' ')
+					if j < 0 {
+						last = string([]rune(last)[:width-1])
+						break
+					}
+					last = last[:j]
+				}
+				lines[n-1] = last + "…"
+				return lines
+			}
+		}
+		if cur != "" {
+			cur += " "
+		}
+		cur += w
+		if i == len(words)-1 {
+			lines = append(lines, cur)
+		}
+	}
+	return lines
+}
+
+// Converter turns SVG cards into PNG.
+type Converter struct {
+	command []string
+	cache   string
+}
+
+// NewConverter returns a Converter for tree running command, or
+// DefaultConverter when command is empty and rsvg-convert is installed.
+func NewConverter(tree *notes.Tree, command []string) *Converter {
+	if len(command) == 0 {
+		if _, err := exec.LookPath(DefaultConverter[0]); err == nil {
+			command = DefaultConverter
+		}
+	}
+	return &Converter{command: command, cache: tree.StatePath(Dir)}
+}
+
+// Command returns the command line c runs, nil when there is none.
+func (c *Converter) Command() []string {
+	return c.command
+}
+
+// Convert returns the PNG of svg, from the cache when the same card was
+// converted before.
+func (c *Converter) Convert(ctx context.Context, svg []byte) ([]byte, error) {
+	if len(c.command) == 0 {
+		return nil, ErrUnavailable
+	}
+	sum := sha256.Sum256(append([]byte(strings.Join(c.command, "\x00")+"\x00"), svg...))
+	cached := filepath.Join(c.cache, hex.EncodeToString(sum[:])+".png")
+	if png, err := os.ReadFile(cached); err == nil {
+		return png, nil
+	}
+	png, err := c.run(ctx, svg)
+	if err != nil {
+		return nil, err
+	}
+	if err := fsutil.WriteFile(cached, png, 0o644); err != nil {
+		return nil, err
+	}
+	return png, nil
+}
+
+func (c *Converter) run(ctx context.Context, svg []byte) ([]byte, error) {
+	dir, err := os.MkdirTemp("", "til-og-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	file, out := filepath.Join(dir, "card.svg"), filepath.Join(dir, "card.png")
+	if err := os.WriteFile(file, svg, 0o644); err != nil {
+		return nil, err
+	}
+	rep := strings.NewReplacer("{file}", file, "{out}", out)
+	args := make([]string, len(c.command))
+	for i, a := range c.command {
+		args[i] = rep.Replace(a)
+	}
+	ctx, cancel := context.WithTimeout(ctx, Timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stderr, &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%s: %w: %s", args[0], err, msg)
+		}
+		return nil, fmt.Errorf("%s: %w", args[0], err)
+	}
+	png, err := os.ReadFile(out)
+	if err != nil {
+		return nil, fmt.Errorf("%s wrote no PNG: %w", args[0], err)
+	}
+	if !bytes.HasPrefix(png, []byte("\x89PNG")) {
+		return nil, fmt.Errorf("%s did not write a PNG", args[0])
+	}
+	return png, nil
+}
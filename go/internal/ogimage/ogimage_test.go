@@ -0,0 +1,138 @@
+package ogimage
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/canhta/til/go/internal/notes"
+)
+
+func TestWrap(t *testing.T) {
+	tests := []struct {
+		s     string
+		width int
+		n     int
+		want  []string
+	}{
+		{"", 10, 2, nil},
+		{"Slices", 10, 2, []string{"Slices"}},
+		{"Slices share their arrays", 12, 3, []string{"Slices share", "their arrays"}},
+		{"one two three four five six", 9, 2, []string{"one two", "three…"}},
+		{"Supercalifragilistic words", 8, 1, []string{"Superca…"}},
+	}
+	for _, tt := range tests {
+		if got := Wrap(tt.s, tt.width, tt.n); !slices.Equal(got, tt.want) {
+			t.Errorf("Wrap(%q, %d, %d) = %q, want %q", tt.s, tt.width, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestSVG(t *testing.T) {
+	c := NewCard("Maps & <sets> in Go, and why iteration order is random", "go", "My TIL", "2024-06-01")
+	if c.Width != Width || c.Height != Height || len(c.Lines) != 2 {
+		t.Errorf("NewCard = %+v", c)
+	}
+	svg, err := SVG(Default, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := string(svg)
+	if !strings.HasPrefix(s, "<svg ") || !strings.Contains(s, ">Maps &amp; &lt;sets&gt; in Go, and why</tspan>") || !strings.Contains(s, "My TIL · 2024-06-01") {
+		t.Errorf("SVG =\n%s", s)
+	}
+	if strings.Contains(s, "<sets>") {
+		t.Errorf("SVG does not escape the title:\n%s", s)
+	}
+
+	tmpl, err := Parse(`<svg>{{.Title}} {{.Nope}}</svg>`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := SVG(tmpl, c); err == nil {
+		t.Error("SVG with a bad field succeeded")
+	}
+}
+
+// converter writes a script standing in for rsvg-convert, which copies a
+// PNG signature to its output and counts its runs in dir/runs.
+func converter(t *testing.T, body string) ([]string, string) {
+	t.Helper()
+	dir := t.TempDir()
+	script := filepath.Join(dir, "convert")
+	data := "#!/bin/sh\necho run >> " + filepath.Join(dir, "runs") + "\n" + body + "\n"
+	if err := os.WriteFile(script, []byte(data), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return []string{script, "{file}", "{out}"}, filepath.Join(dir, "runs")
+}
+
+func runs(t *testing.T, file string) int {
+	t.Helper()
+	data, err := os.ReadFile(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0
+	} else if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Count(string(data), "run\n")
+}
+
+func TestConvert(t *testing.T) {
+	tree := notes.Open(t.TempDir())
+	ctx := context.Background()
+	cmd, counter := converter(t, `printf '\211PNG %s' "$(cat "$1")" > "$2"`)
+	c := NewConverter(tree, cmd)
+	if !slices.Equal(c.Command(), cmd) {
+		t.Errorf("Command() = %q", c.Command())
+	}
+	for range 2 {
+		png, err := c.Convert(ctx, []byte("<svg/>"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(png) != "\x89PNG <svg/>" {
+			t.Errorf("Convert = %q", png)
+		}
+	}
+	if n := runs(t, counter); n != 1 {
+		t.Errorf("converter ran %d times, want the second card from the cache", n)
+	}
+	if _, err := c.Convert(ctx, []byte("<svg>other</svg>")); err != nil || runs(t, counter) != 2 {
+		t.Errorf("Convert(other) = %v after %d runs", err, runs(t, counter))
+	}
+	if entries, _ := os.ReadDir(tree.StatePath(Dir)); len(entries) != 2 {
+		t.Errorf("cache holds %d files, want 2", len(entries))
+	}
+}
+
+func TestConvertErrors(t *testing.T) {
+	tree := notes.Open(t.TempDir())
+	ctx := context.Background()
+	tests := []struct {
+		name, body, want string
+	}{
+		{"failing", "echo cannot parse >&2; exit 1", "exit status 1: cannot parse"},
+		{"no output", "true", "wrote no PNG"},
+		{"not a PNG", `echo nope > "$2"`, "did not write a PNG"},
+	}
+	for _, tt := range tests {
+		cmd, _ := converter(t, tt.body)
+		if _, err := NewConverter(tree, cmd).Convert(ctx, []byte("<svg/>")); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: Convert = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+
+	t.Setenv("PATH", t.TempDir())
+	c := NewConverter(tree, nil)
+	if c.Command() != nil {
+		t.Errorf("Command() without rsvg-convert = %q", c.Command())
+	}
+	if _, err := c.Convert(ctx, []byte("<svg/>")); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("Convert without a converter = %v, want ErrUnavailable", err)
+	}
+}
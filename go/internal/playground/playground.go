@@ -0,0 +1,67 @@
+// Package playground shares Go code blocks on the Go Playground.
+//
+// A shared block records the snippet ID in its play attribute,
+// ```go {play=abc123}, which rendered pages turn into a link running the
+// snippet on the Playground.
+package playground
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// API is the Playground endpoint snippets are shared through.
+const API = "https://play.golang.org"
+
+// Attr is the code block attribute the snippet ID is recorded in.
+const Attr = "play"
+
+// Client shares snippets on the Playground.
+type Client struct {
+	// API defaults to API.
+	API    string
+	Client *http.Client
+}
+
+// Share uploads src, a complete Go program, and returns its snippet ID.
+// The Playground derives IDs from the content, so sharing the same source
+// again returns the same ID.
+func (c Client) Share(ctx context.Context, src []byte) (string, error) {
+	api := c.API
+	if api == "" {
+		api = API
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(api, "/")+"/share", bytes.NewReader(src))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	client := c.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<10))
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode/100 != 2 {
+		if msg := strings.TrimSpace(string(data)); msg != "" && !bytes.ContainsAny(data, "<>") {
+			return "", fmt.Errorf("playground: %s: %s", resp.Status, msg)
+		}
+		return "", fmt.Errorf("playground: %s", resp.Status)
+	}
+	id := strings.TrimSpace(string(data))
+	if id == "" || strings.ContainsAny(id, "/ \t\n<>") {
+		return "", fmt.Errorf("playground: unexpected response %q", id)
+	}
+	return id, nil
+}
@@ -0,0 +1,59 @@
+package playground
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestShare(t *testing.T) {
+	var got, contentType string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/share" {
+			http.NotFound(w, r)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		got, contentType = string(body), r.Header.Get("Content-Type")
+		io.WriteString(w, "abc123\n")
+	}))
+	defer srv.Close()
+
+	id, err := Client{API: srv.URL + "/"}.Share(context.Background(), []byte("package main\n"))
+	if err != nil || id != "abc123" {
+		t.Fatalf("Share = %q, %v", id, err)
+	}
+	if got != "package main\n" || contentType != "text/plain; charset=utf-8" {
+		t.Errorf("request = %q, Content-Type %q", got, contentType)
+	}
+}
+
+func TestShareErrors(t *testing.T) {
+	tests := []struct {
+		status int
+		body   string
+		want   string
+	}{
+		{http.StatusRequestEntityTooLarge, "Snippet is too large\n", "playground: 413 Request Entity Too Large: Snippet is too large"},
+		{http.StatusBadGateway, "<html>bad gateway</html>", "playground: 502 Bad Gateway"},
+		{http.StatusOK, "", `playground: unexpected response ""`},
+		{http.StatusOK, "<html>login</html>", `playground: unexpected response "<html>login</html>"`},
+	}
+	for _, tt := range tests {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(tt.status)
+			io.WriteString(w, tt.body)
+		}))
+		_, err := Client{API: srv.URL}.Share(context.Background(), nil)
+		srv.Close()
+		if err == nil || err.Error() != tt.want {
+			t.Errorf("Share with %d %q = %v, want %s", tt.status, tt.body, err, tt.want)
+		}
+	}
+	if _, err := (Client{API: "http://127.0.0.1:1"}).Share(context.Background(), nil); err == nil || !strings.Contains(err.Error(), "connect") {
+		t.Errorf("Share with no server = %v", err)
+	}
+}
@@ -0,0 +1,45 @@
+// Package pool runs work over a bounded number of goroutines.
+package pool
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// Size returns the number of goroutines Run uses for n given: n itself, or
+// the number of CPUs usable when n is less than one.
+func Size(n int) int {
+	if n < 1 {
+		return runtime.GOMAXPROCS(0)
+	}
+	return n
+}
+
+// Run calls fn for each i in [0, n) on at most Size(workers) goroutines at
+// once, passing the index of the goroutine, and waits for them. Every call
+// is made even when some fail; their errors are joined in index order.
+// Once ctx is done no more calls start and its error is returned.
+func Run(ctx context.Context, workers, n int, fn func(worker, i int) error) error {
+	errs := make([]error, n)
+	var next atomic.Int64
+	var wg sync.WaitGroup
+	for w := range min(Size(workers), n) {
+		wg.Go(func() {
+			for ctx.Err() == nil {
+				i := int(next.Add(1)) - 1
+				if i >= n {
+					return
+				}
+				errs[i] = fn(w, i)
+			}
+		})
+	}
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return errors.Join(errs...)
+}
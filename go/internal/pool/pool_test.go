@@ -0,0 +1,100 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSize(t *testing.T) {
+	if got := Size(3); got != 3 {
+		t.Errorf("Size(3) = %d", got)
+	}
+	for _, n := range []int{0, -1} {
+		if got := Size(n); got != runtime.GOMAXPROCS(0) {
+			t.Errorf("Size(%d) = %d, want GOMAXPROCS", n, got)
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	const n = 50
+	var (
+		mu      sync.Mutex
+		done    = make([]int, n)
+		workers = map[int]bool{}
+		running atomic.Int32
+		peak    atomic.Int32
+	)
+	err := Run(context.Background(), 4, n, func(w, i int) error {
+		if r := running.Add(1); r > peak.Load() {
+			peak.Store(r)
+		}
+		defer running.Add(-1)
+		time.Sleep(time.Millisecond)
+		mu.Lock()
+		done[i]++
+		workers[w] = true
+		mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, c := range done {
+		if c != 1 {
+			t.Errorf("fn(%d) called %d times", i, c)
+		}
+	}
+	if peak.Load() > 4 {
+		t.Errorf("%d calls at once, want at most 4", peak.Load())
+	}
+	for w := range workers {
+		if w < 0 || w >= 4 {
+			t.Errorf("worker index %d", w)
+		}
+	}
+
+	if err := Run(context.Background(), 8, 0, func(w, i int) error { panic("called") }); err != nil {
+		t.Errorf("Run of nothing = %v", err)
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	var calls atomic.Int32
+	err := Run(context.Background(), 3, 10, func(w, i int) error {
+		calls.Add(1)
+		if i%4 == 1 {
+			return fmt.Errorf("entry %d", i)
+		}
+		return nil
+	})
+	if calls.Load() != 10 {
+		t.Errorf("%d calls, want every one despite the failures", calls.Load())
+	}
+	if err == nil || err.Error() != "entry 1\nentry 5\nentry 9" {
+		t.Errorf("Run = %q, want the errors in index order", err)
+	}
+}
+
+func TestRunCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var calls atomic.Int32
+	err := Run(ctx, 2, 1000, func(w, i int) error {
+		if calls.Add(1) == 5 {
+			cancel()
+		}
+		return errors.New("ignored")
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Run = %v, want the context's error", err)
+	}
+	if c := calls.Load(); c < 5 || c > 6 {
+		t.Errorf("%d calls, want no more started after the cancel", c)
+	}
+}
@@ -0,0 +1,215 @@
+// Package private encrypts private entries at rest with age.
+//
+// A private entry is stored as <name>.md.age, an ASCII-armored age file
+// holding the whole markdown file, frontmatter included. Such files are not
+// entries as far as the rest of til is concerned, so they never reach the
+// site, its feeds, the README index or the search index.
+//
+// Entries are encrypted to the public key of the configured identity, an
+// age identity file or an SSH key, and to any extra recipients, so the same
+// notes can be read from several machines.
+package private
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"filippo.io/age"
+	"filippo.io/age/agessh"
+	"filippo.io/age/armor"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/term"
+
+	"github.com/canhta/til/go/internal/config"
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+// Ext is the extension of encrypted entry files.
+const Ext = ".md.age"
+
+// defaultKeys are the identities tried when none is configured.
+var defaultKeys = []string{"~/.ssh/id_ed25519", "~/.ssh/id_rsa"}
+
+// Keys decrypts and encrypts private entries.
+type Keys struct {
+	// Source is the identity file the keys were loaded from.
+	Source     string
+	identities []age.Identity
+	recipients []age.Recipient
+}
+
+// Load reads the identity configured in cfg, or the default SSH key.
+func Load(cfg config.Private) (*Keys, error) {
+	files := defaultKeys
+	if cfg.Identity != "" {
+		files = []string{cfg.Identity}
+	}
+	var k *Keys
+	for _, f := range files {
+		file, err := expand(f)
+		if err != nil {
+			return nil, err
+		}
+		data, err := os.ReadFile(file)
+		if errors.Is(err, os.ErrNotExist) && cfg.Identity == "" {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("private: %w", err)
+		}
+		if k, err = parseIdentity(file, data); err != nil {
+			return nil, fmt.Errorf("private: %s: %w", file, err)
+		}
+		break
+	}
+	if k == nil {
+		return nil, fmt.Errorf("private: no key found; set [private] identity in the config file to an age identity or SSH private key")
+	}
+	for _, r := range cfg.Recipients {
+		rs, err := parseRecipient(r)
+		if err != nil {
+			return nil, fmt.Errorf("private: recipient %q: %w", r, err)
+		}
+		k.recipients = append(k.recipients, rs)
+	}
+	return k, nil
+}
+
+func parseIdentity(file string, data []byte) (*Keys, error) {
+	k := &Keys{Source: file}
+	if bytes.Contains(data, []byte("AGE-SECRET-KEY-")) {
+		ids, err := age.ParseIdentities(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		k.identities = ids
+		for _, id := range ids {
+			if x, ok := id.(*age.X25519Identity); ok {
+				k.recipients = append(k.recipients, x.Recipient())
+			}
+		}
+		return k, nil
+	}
+	// An SSH key: the public half, needed to encrypt, is read from the
+	// .pub file beside it.
+	pubData, err := os.ReadFile(file + ".pub")
+	if err != nil {
+		return nil, fmt.Errorf("reading the public key: %w", err)
+	}
+	pub, _, _, _, err := ssh.ParseAuthorizedKey(pubData)
+	if err != nil {
+		return nil, err
+	}
+	r, err := agessh.ParseRecipient(strings.TrimSpace(string(pubData)))
+	if err != nil {
+		return nil, err
+	}
+	k.recipients = append(k.recipients, r)
+	id, err := agessh.ParseIdentity(data)
+	var missing *ssh.PassphraseMissingError
+	if errors.As(err, &missing) {
+		id, err = agessh.NewEncryptedSSHIdentity(pub, data, func() ([]byte, error) { return passphrase(file) })
+	}
+	if err != nil {
+		return nil, err
+	}
+	k.identities = append(k.identities, id)
+	return k, nil
+}
+
+func parseRecipient(s string) (age.Recipient, error) {
+	if strings.HasPrefix(s, "age1") {
+		return age.ParseX25519Recipient(s)
+	}
+	return agessh.ParseRecipient(s)
+}
+
+// passphrase asks for the passphrase of an SSH key on the terminal.
+func passphrase(file string) ([]byte, error) {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return nil, fmt.Errorf("%s is passphrase-protected and there is no terminal to ask on", file)
+	}
+	defer tty.Close()
+	fmt.Fprintf(tty, "Passphrase for %s: ", file)
+	p, err := term.ReadPassword(int(tty.Fd()))
+	fmt.Fprintln(tty)
+	return p, err
+}
+
+func expand(p string) (string, error) {
+	if rest, ok := strings.CutPrefix(p, "~/"); ok {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(home, rest), nil
+	}
+	return p, nil
+}
+
+// Encrypt returns plain encrypted to every recipient, armored.
+func (k *Keys) Encrypt(plain []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	aw := armor.NewWriter(&buf)
+	w, err := age.Encrypt(aw, k.recipients...)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(plain); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	if err := aw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Decrypt decrypts an encrypted entry file, armored or not.
+func (k *Keys) Decrypt(data []byte) ([]byte, error) {
+	var src io.Reader = bytes.NewReader(data)
+	br := bufio.NewReader(src)
+	if start, _ := br.Peek(len(armor.Header)); string(start) == armor.Header {
+		src = armor.NewReader(br)
+	} else {
+		src = br
+	}
+	r, err := age.Decrypt(src, k.identities...)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
+
+// IsPrivate reports whether the frontmatter of the markdown file data marks
+// it private.
+func IsPrivate(data []byte) bool {
+	front, _, ok := entry.SplitFrontmatter(data)
+	if !ok {
+		return false
+	}
+	f, err := entry.ParseFront(front)
+	if err != nil {
+		return false
+	}
+	var v bool
+	found, err := f.Get("private", &v)
+	return found && err == nil && v
+}
+
+// PlainPath returns the path of the markdown file for the encrypted entry
+// at p.
+func PlainPath(p string) string { return strings.TrimSuffix(p, Ext) + ".md" }
+
+// EncryptedPath returns the path of the encrypted file for the markdown
+// entry at p.
+func EncryptedPath(p string) string { return strings.TrimSuffix(p, ".md") + Ext }
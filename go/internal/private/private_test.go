@@ -0,0 +1,153 @@
+package private
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/canhta/til/go/internal/config"
+)
+
+// ageIdentity writes a new age identity file and returns its path and
+// public key.
+func ageIdentity(t *testing.T) (string, string) {
+	t.Helper()
+	id, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(t.TempDir(), "key.txt")
+	if err := os.WriteFile(file, []byte("# created by test\n"+id.String()+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return file, id.Recipient().String()
+}
+
+// sshKey writes a new unencrypted ed25519 key pair and returns the path of
+// the private key.
+func sshKey(t *testing.T, dir string) string {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, err := ssh.MarshalPrivateKey(priv, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	spub, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(dir, "id_ed25519")
+	if err := os.WriteFile(file, pem.EncodeToMemory(block), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(file+".pub", ssh.MarshalAuthorizedKey(spub), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+const plain = "---\ntitle: Secret\nprivate: true\n---\n\nHush.\n"
+
+func TestEncryptDecrypt(t *testing.T) {
+	file, _ := ageIdentity(t)
+	otherFile, other := ageIdentity(t)
+	keys, err := Load(config.Private{Identity: file, Recipients: []string{other}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keys.Source != file {
+		t.Errorf("Source = %s", keys.Source)
+	}
+	sealed, err := keys.Encrypt([]byte(plain))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(sealed), "-----BEGIN AGE ENCRYPTED FILE-----") || strings.Contains(string(sealed), "Hush") {
+		t.Fatalf("Encrypt =\n%s", sealed)
+	}
+	for _, f := range []string{file, otherFile} {
+		k, err := Load(config.Private{Identity: f})
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := k.Decrypt(sealed)
+		if err != nil {
+			t.Fatalf("Decrypt with %s: %v", f, err)
+		}
+		if string(got) != plain {
+			t.Errorf("Decrypt = %q", got)
+		}
+	}
+
+	stranger, _ := ageIdentity(t)
+	k, _ := Load(config.Private{Identity: stranger})
+	if _, err := k.Decrypt(sealed); err == nil {
+		t.Error("a key that is not a recipient decrypted the entry")
+	}
+}
+
+func TestLoadSSHKey(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	if _, err := Load(config.Private{}); err == nil || !strings.Contains(err.Error(), "no key found") {
+		t.Errorf("Load without keys = %v", err)
+	}
+	if err := os.Mkdir(filepath.Join(home, ".ssh"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	file := sshKey(t, filepath.Join(home, ".ssh"))
+	keys, err := Load(config.Private{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keys.Source != file {
+		t.Errorf("Source = %s, want the default key %s", keys.Source, file)
+	}
+	sealed, err := keys.Encrypt([]byte(plain))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := keys.Decrypt(sealed); err != nil || string(got) != plain {
+		t.Errorf("Decrypt = %q, %v", got, err)
+	}
+
+	if _, err := Load(config.Private{Identity: filepath.Join(home, "missing")}); err == nil {
+		t.Error("Load of a missing configured identity succeeded")
+	}
+	if _, err := Load(config.Private{Identity: file, Recipients: []string{"nonsense"}}); err == nil {
+		t.Error("Load accepted a bad recipient")
+	}
+}
+
+func TestIsPrivate(t *testing.T) {
+	for data, want := range map[string]bool{
+		plain:                                 true,
+		"---\ntitle: Open\n---\n":             false,
+		"---\nprivate: false\n---\n":          false,
+		"---\nprivate: maybe\n---\n":          false,
+		"# No frontmatter\n\nprivate: true\n": false,
+	} {
+		if got := IsPrivate([]byte(data)); got != want {
+			t.Errorf("IsPrivate(%q) = %v, want %v", data, got, want)
+		}
+	}
+}
+
+func TestPaths(t *testing.T) {
+	if got := EncryptedPath("go/secret.md"); got != "go/secret.md.age" {
+		t.Errorf("EncryptedPath = %s", got)
+	}
+	if got := PlainPath("go/secret.md.age"); got != "go/secret.md" {
+		t.Errorf("PlainPath = %s", got)
+	}
+}
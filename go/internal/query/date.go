@@ -0,0 +1,62 @@
+package query
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseDate parses an absolute or relative date relative to now:
+//
+//	2024-06-01     a day
+//	2024-06        the first day of a month
+//	2024           the first day of a year
+//	today, yesterday
+//	7d, 2w, 3m, 1y that many days, weeks, months or years ago
+//
+// The result is midnight in now's location.
+func ParseDate(s string, now time.Time) (time.Time, error) {
+	from, _, err := ParseSpan(s, now)
+	return from, err
+}
+
+// ParseSpan parses s like ParseDate and also returns the end of the period
+// it names: the next day, month or year for absolute dates, the next day
+// for relative ones.
+func ParseSpan(s string, now time.Time) (from, to time.Time, err error) {
+	s = strings.TrimSpace(strings.ToLower(s))
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	nextDay := func(t time.Time) (time.Time, time.Time, error) { return t, t.AddDate(0, 0, 1), nil }
+	switch s {
+	case "today":
+		return nextDay(day)
+	case "yesterday":
+		return nextDay(day.AddDate(0, 0, -1))
+	}
+	if t, err := time.ParseInLocation("2006-01-02", s, now.Location()); err == nil {
+		return nextDay(t)
+	}
+	if t, err := time.ParseInLocation("2006-01", s, now.Location()); err == nil {
+		return t, t.AddDate(0, 1, 0), nil
+	}
+	if t, err := time.ParseInLocation("2006", s, now.Location()); err == nil {
+		return t, t.AddDate(1, 0, 0), nil
+	}
+	if len(s) >= 2 {
+		n, err := strconv.Atoi(s[:len(s)-1])
+		if err == nil && n >= 0 {
+			switch s[len(s)-1] {
+			case 'd':
+				return nextDay(day.AddDate(0, 0, -n))
+			case 'w':
+				return nextDay(day.AddDate(0, 0, -7*n))
+			case 'm':
+				return nextDay(day.AddDate(0, -n, 0))
+			case 'y':
+				return nextDay(day.AddDate(-n, 0, 0))
+			}
+		}
+	}
+	return time.Time{}, time.Time{}, fmt.Errorf("invalid date %q (want YYYY-MM-DD, YYYY-MM, YYYY, today, yesterday or a relative form like 7d, 2w, 3m, 1y)", s)
+}
@@ -0,0 +1,345 @@
+package query
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Parse parses the query language used by til search:
+//
+//	go slices              entries containing both words
+//	"copy a slice"         a phrase
+//	tag:go category:git    metadata filters; title:"..." takes a phrase
+//	author:"Jane Doe"      entries by an author
+//	lang:vi                entries written in Vietnamese; see Parser
+//	created:2024-06        created in June 2024
+//	created:>2024-06       after June 2024; also >=, < and <=
+//	updated:2024-01..2024-06
+//	a AND b, a OR b, NOT a, -a, (a OR b) c
+//	@go-perf               a collection; see Parser
+//
+// Terms side by side are AND'ed; AND binds tighter than OR. Operators are
+// recognized in upper case only. Dates take any form ParseSpan accepts and
+// are interpreted relative to now. An empty query matches every entry.
+func Parse(s string, now time.Time) (Expr, error) {
+	return Parser{Now: now}.Parse(s)
+}
+
+// Parser parses queries that may refer to collections, named queries
+// written as @name.
+type Parser struct {
+	Now         time.Time
+	Collections map[string]string
+	// Lang is the language of entries that name none, matched by lang:.
+	Lang string
+}
+
+// Parse parses s as Parse does, expanding references to collections.
+func (ps Parser) Parse(s string) (Expr, error) {
+	return ps.parse(s, nil)
+}
+
+// parse parses s; stack holds the collections being expanded, to catch
+// cycles.
+func (ps Parser) parse(s string, stack []string) (Expr, error) {
+	toks, err := lex(s)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{toks: toks, ps: ps, stack: stack}
+	if len(toks) == 0 {
+		return All{}, nil
+	}
+	x, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t != nil {
+		return nil, fmt.Errorf("query: unexpected %q at offset %d", t.text, t.pos)
+	}
+	return x, nil
+}
+
+type tokenKind int
+
+const (
+	tWord tokenKind = iota
+	tPhrase
+	tField
+	tLParen
+	tRParen
+	// tMinus is a "-" directly before a term.
+	tMinus
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	// field and value are set for tField tokens.
+	field, value string
+	pos          int
+}
+
+func lex(s string) ([]token, error) {
+	var toks []token
+	rs := []rune(s)
+	for i := 0; i < len(rs); {
+		r := rs[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(' || r == ')':
+			kind := tLParen
+			if r == ')' {
+				kind = tRParen
+			}
+			toks = append(toks, token{kind: kind, text: string(r), pos: i})
+			i++
+		case r == '-' && i+1 < len(rs) && !unicode.IsSpace(rs[i+1]) && rs[i+1] != ')':
+			toks = append(toks, token{kind: tMinus, text: "-", pos: i})
+			i++
+		case r == '"':
+			text, n, err := quoted(rs, i)
+			if err != nil {
+				return nil, err
+			}
+			toks = append(toks, token{kind: tPhrase, text: text, pos: i})
+			i = n
+		default:
+			start := i
+			for i < len(rs) && !unicode.IsSpace(rs[i]) && !strings.ContainsRune(`()"`, rs[i]) {
+				i++
+			}
+			word := string(rs[start:i])
+			field, value, isField := strings.Cut(word, ":")
+			if !isField || field == "" {
+				toks = append(toks, token{kind: tWord, text: word, pos: start})
+				continue
+			}
+			if value == "" && i < len(rs) && rs[i] == '"' {
+				text, n, err := quoted(rs, i)
+				if err != nil {
+					return nil, err
+				}
+				value, i = text, n
+			}
+			toks = append(toks, token{kind: tField, text: string(rs[start:i]), field: field, value: value, pos: start})
+		}
+	}
+	return toks, nil
+}
+
+// quoted reads the double-quoted string starting at rs[i] and returns its
+// contents and the index after the closing quote. A doubled quote stands
+// for one quote character.
+func quoted(rs []rune, i int) (string, int, error) {
+	var b strings.Builder
+	for j := i + 1; j < len(rs); j++ {
+		if rs[j] == '"' {
+			if j+1 < len(rs) && rs[j+1] == '"' {
+				b.WriteRune('"')
+				j++
+				continue
+			}
+			return b.String(), j + 1, nil
+		}
+		b.WriteRune(rs[j])
+	}
+	return "", 0, fmt.Errorf("query: unterminated quote at offset %d", i)
+}
+
+type parser struct {
+	toks  []token
+	i     int
+	ps    Parser
+	stack []string
+}
+
+func (p *parser) peek() *token {
+	if p.i < len(p.toks) {
+		return &p.toks[p.i]
+	}
+	return nil
+}
+
+func (p *parser) keyword(kw string) bool {
+	t := p.peek()
+	if t != nil && t.kind == tWord && t.text == kw {
+		p.i++
+		return true
+	}
+	return false
+}
+
+func (p *parser) or() (Expr, error) {
+	var xs Or
+	for {
+		x, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		xs = append(xs, x)
+		if !p.keyword("OR") {
+			break
+		}
+	}
+	if len(xs) == 1 {
+		return xs[0], nil
+	}
+	return xs, nil
+}
+
+func (p *parser) and() (Expr, error) {
+	var xs And
+	for {
+		x, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		xs = append(xs, x)
+		if p.keyword("AND") {
+			continue
+		}
+		t := p.peek()
+		if t == nil || t.kind == tRParen || t.kind == tWord && t.text == "OR" {
+			break
+		}
+	}
+	if len(xs) == 1 {
+		return xs[0], nil
+	}
+	return xs, nil
+}
+
+func (p *parser) unary() (Expr, error) {
+	if p.keyword("NOT") {
+		x, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return Not{x}, nil
+	}
+	t := p.peek()
+	if t == nil {
+		return nil, fmt.Errorf("query: unexpected end of query")
+	}
+	p.i++
+	switch t.kind {
+	case tMinus:
+		x, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return Not{x}, nil
+	case tLParen:
+		x, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if c := p.peek(); c == nil || c.kind != tRParen {
+			return nil, fmt.Errorf("query: missing ) for ( at offset %d", t.pos)
+		}
+		p.i++
+		return x, nil
+	case tRParen:
+		return nil, fmt.Errorf("query: unexpected ) at offset %d", t.pos)
+	case tPhrase:
+		return Text(t.text), nil
+	case tField:
+		return p.field(t)
+	}
+	switch t.text {
+	case "AND", "OR":
+		return nil, fmt.Errorf("query: %s at offset %d needs a term on both sides", t.text, t.pos)
+	}
+	if name, ok := strings.CutPrefix(t.text, "@"); ok && name != "" {
+		return p.collection(name)
+	}
+	return Text(t.text), nil
+}
+
+func (p *parser) collection(name string) (Expr, error) {
+	q, ok := p.ps.Collections[name]
+	if !ok {
+		return nil, fmt.Errorf("query: no collection @%s", name)
+	}
+	for _, n := range p.stack {
+		if n == name {
+			return nil, fmt.Errorf("query: collection @%s refers to itself", name)
+		}
+	}
+	x, err := p.ps.parse(q, append(p.stack, name))
+	if err != nil {
+		return nil, fmt.Errorf("@%s: %w", name, err)
+	}
+	return Collection{Name: name, X: x}, nil
+}
+
+// Fields are the field names accepted before a colon.
+var Fields = []string{"tag", "category", "title", "author", "lang", "created", "updated"}
+
+func (p *parser) field(t *token) (Expr, error) {
+	if t.value == "" {
+		return nil, fmt.Errorf("query: %s: needs a value", t.field)
+	}
+	switch strings.ToLower(t.field) {
+	case "tag":
+		return Tag(t.value), nil
+	case "category", "cat":
+		return Category(t.value), nil
+	case "title":
+		return Title(t.value), nil
+	case "author":
+		return Author(t.value), nil
+	case "lang":
+		return Lang{Tag: t.value, Default: p.ps.Lang}, nil
+	case "created":
+		return p.dateRange(Created, t.value)
+	case "updated":
+		return p.dateRange(Updated, t.value)
+	}
+	return nil, fmt.Errorf("query: unknown field %q (want one of %s)", t.field, strings.Join(Fields, ", "))
+}
+
+// dateRange parses the value of a date field: a date, a comparison with
+// one, or a from..to range. Comparisons are with the whole period a date
+// names, so >2024-06 means from July 2024 on.
+func (p *parser) dateRange(f DateField, v string) (Expr, error) {
+	r := DateRange{Field: f}
+	span := func(s string) (time.Time, time.Time, error) {
+		from, to, err := ParseSpan(s, p.ps.Now)
+		if err != nil {
+			return from, to, fmt.Errorf("query: %s: %w", f, err)
+		}
+		return from, to, nil
+	}
+	var err error
+	switch {
+	case strings.HasPrefix(v, ">="):
+		r.From, _, err = span(v[2:])
+	case strings.HasPrefix(v, "<="):
+		_, r.To, err = span(v[2:])
+	case strings.HasPrefix(v, ">"):
+		_, r.From, err = span(v[1:])
+	case strings.HasPrefix(v, "<"):
+		r.To, _, err = span(v[1:])
+	case strings.Contains(v, ".."):
+		a, b, _ := strings.Cut(v, "..")
+		if a != "" {
+			if r.From, _, err = span(a); err != nil {
+				return nil, err
+			}
+		}
+		if b != "" {
+			_, r.To, err = span(b)
+		}
+	default:
+		r.From, r.To, err = span(v)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
@@ -0,0 +1,280 @@
+// Package query filters and sorts entries.
+//
+// A query is a tree of Expr values. Leaf expressions test one piece of
+// entry metadata; And, Or and Not combine them.
+package query
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+// Expr is a predicate over entries.
+type Expr interface {
+	Match(e *entry.Entry) bool
+	String() string
+}
+
+// All matches every entry.
+type All struct{}
+
+func (All) Match(*entry.Entry) bool { return true }
+func (All) String() string          { return "*" }
+
+// And matches entries matched by every operand.
+type And []Expr
+
+func (a And) Match(e *entry.Entry) bool {
+	for _, x := range a {
+		if !x.Match(e) {
+			return false
+		}
+	}
+	return true
+}
+
+func (a And) String() string { return join(a, " AND ") }
+
+// Or matches entries matched by any operand.
+type Or []Expr
+
+func (o Or) Match(e *entry.Entry) bool {
+	for _, x := range o {
+		if x.Match(e) {
+			return true
+		}
+	}
+	return false
+}
+
+func (o Or) String() string { return join(o, " OR ") }
+
+func join(xs []Expr, sep string) string {
+	parts := make([]string, len(xs))
+	for i, x := range xs {
+		parts[i] = x.String()
+		if _, ok := x.(And); ok {
+			parts[i] = "(" + parts[i] + ")"
+		} else if _, ok := x.(Or); ok {
+			parts[i] = "(" + parts[i] + ")"
+		}
+	}
+	return strings.Join(parts, sep)
+}
+
+// Not matches entries not matched by X.
+type Not struct{ X Expr }
+
+func (n Not) Match(e *entry.Entry) bool { return !n.X.Match(e) }
+func (n Not) String() string            { return "NOT " + n.X.String() }
+
+// Collection is a reference to a named query, matching what the query
+// matches.
+type Collection struct {
+	Name string
+	X    Expr
+}
+
+func (c Collection) Match(e *entry.Entry) bool { return c.X.Match(e) }
+func (c Collection) String() string            { return "@" + c.Name }
+
+// Tag matches entries carrying the tag, case-insensitively.
+type Tag string
+
+func (t Tag) Match(e *entry.Entry) bool {
+	for _, x := range e.Meta.Tags {
+		if strings.EqualFold(x, string(t)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (t Tag) String() string { return "tag:" + string(t) }
+
+// Category matches entries in the category, case-insensitively.
+type Category string
+
+func (c Category) Match(e *entry.Entry) bool { return strings.EqualFold(e.Meta.Category, string(c)) }
+func (c Category) String() string            { return "category:" + string(c) }
+
+// Author matches entries by the author, case-insensitively.
+type Author string
+
+func (a Author) Match(e *entry.Entry) bool { return strings.EqualFold(e.Meta.Author, string(a)) }
+func (a Author) String() string            { return "author:" + string(a) }
+
+// Lang matches entries written in the language Tag. A tag without a
+// region, such as "vi", matches its regional variants too, such as
+// "vi-VN". Entries naming no language are in Default.
+type Lang struct {
+	Tag, Default string
+}
+
+func (l Lang) Match(e *entry.Entry) bool {
+	lang := e.Meta.Lang
+	if lang == "" {
+		lang = l.Default
+	}
+	return strings.EqualFold(lang, l.Tag) || len(lang) > len(l.Tag) && lang[len(l.Tag)] == '-' && strings.EqualFold(lang[:len(l.Tag)], l.Tag)
+}
+
+func (l Lang) String() string { return "lang:" + l.Tag }
+
+// Title matches entries whose title contains the substring,
+// case-insensitively.
+type Title string
+
+func (t Title) Match(e *entry.Entry) bool {
+	return strings.Contains(strings.ToLower(e.Meta.Title), strings.ToLower(string(t)))
+}
+
+func (t Title) String() string { return "title:" + string(t) }
+
+// Text matches entries whose title, body or tags contain the text,
+// case-insensitively.
+type Text string
+
+func (t Text) Match(e *entry.Entry) bool {
+	needle := strings.ToLower(string(t))
+	if strings.Contains(strings.ToLower(e.Meta.Title), needle) || strings.Contains(strings.ToLower(string(e.Body)), needle) {
+		return true
+	}
+	for _, x := range e.Meta.Tags {
+		if strings.Contains(strings.ToLower(x), needle) {
+			return true
+		}
+	}
+	return false
+}
+
+func (t Text) String() string {
+	s := string(t)
+	if strings.ContainsAny(s, " \t\"():") || s == "AND" || s == "OR" || s == "NOT" || strings.HasPrefix(s, "-") {
+		return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+	}
+	return s
+}
+
+// DateField selects which date a DateRange tests.
+type DateField string
+
+const (
+	Created DateField = "created"
+	Updated DateField = "updated"
+)
+
+// Date returns the date of e selected by f.
+func (f DateField) Date(e *entry.Entry) time.Time {
+	if f == Updated {
+		return e.LastUpdated()
+	}
+	return e.Created()
+}
+
+// DateRange matches entries whose date falls in [From, To). A zero bound
+// is open. Entries without a date never match.
+type DateRange struct {
+	Field    DateField
+	From, To time.Time
+}
+
+func (r DateRange) Match(e *entry.Entry) bool {
+	d := r.Field.Date(e)
+	if d.IsZero() {
+		return false
+	}
+	if !r.From.IsZero() && d.Before(r.From) {
+		return false
+	}
+	if !r.To.IsZero() && !d.Before(r.To) {
+		return false
+	}
+	return true
+}
+
+func (r DateRange) String() string {
+	switch {
+	case r.To.IsZero():
+		return fmt.Sprintf("%s:>=%s", r.Field, r.From.Format(entry.DateLayout))
+	case r.From.IsZero():
+		return fmt.Sprintf("%s:<%s", r.Field, r.To.Format(entry.DateLayout))
+	}
+	return fmt.Sprintf("%s:%s..%s", r.Field, r.From.Format(entry.DateLayout), r.To.Format(entry.DateLayout))
+}
+
+// OnThisDay matches entries created on the calendar day of Day in an
+// earlier year. In years without February 29, entries from that day match
+// on the 28th.
+type OnThisDay struct{ Day time.Time }
+
+func (o OnThisDay) Match(e *entry.Entry) bool {
+	d := e.Created()
+	if d.IsZero() || d.Year() >= o.Day.Year() || d.Month() != o.Day.Month() {
+		return false
+	}
+	if d.Day() == o.Day.Day() {
+		return true
+	}
+	leap := time.Date(o.Day.Year(), time.February, 29, 0, 0, 0, 0, time.UTC).Day() == 29
+	return d.Month() == time.February && d.Day() == 29 && o.Day.Day() == 28 && !leap
+}
+
+func (o OnThisDay) String() string { return "on-this-day:" + o.Day.Format("01-02") }
+
+// Archived matches archived entries.
+type Archived struct{}
+
+func (Archived) Match(e *entry.Entry) bool { return e.Meta.Archived }
+func (Archived) String() string            { return "archived" }
+
+// Filter returns the entries matched by x, in their original order.
+func Filter(entries []*entry.Entry, x Expr) []*entry.Entry {
+	var out []*entry.Entry
+	for _, e := range entries {
+		if x.Match(e) {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// SortKeys are the accepted sort keys.
+var SortKeys = []string{"created", "updated", "title", "category", "path", "words", "code", "reading"}
+
+// Sort orders entries by key. Dates sort newest first, and word counts,
+// code line counts and reading times largest first; the rest sort
+// ascending. reverse flips the order.
+func Sort(entries []*entry.Entry, key string, reverse bool) error {
+	var less func(a, b *entry.Entry) bool
+	switch key {
+	case "created", "updated":
+		f := DateField(key)
+		less = func(a, b *entry.Entry) bool { return f.Date(a).After(f.Date(b)) }
+	case "title":
+		less = func(a, b *entry.Entry) bool { return strings.ToLower(a.Meta.Title) < strings.ToLower(b.Meta.Title) }
+	case "category":
+		less = func(a, b *entry.Entry) bool { return a.Meta.Category < b.Meta.Category }
+	case "path":
+		less = func(a, b *entry.Entry) bool { return a.Path < b.Path }
+	case "words":
+		less = func(a, b *entry.Entry) bool { return a.Counts.Words > b.Counts.Words }
+	case "code":
+		less = func(a, b *entry.Entry) bool { return a.Counts.CodeLines > b.Counts.CodeLines }
+	case "reading":
+		less = func(a, b *entry.Entry) bool { return a.Counts.ReadingMinutes() > b.Counts.ReadingMinutes() }
+	default:
+		return fmt.Errorf("unknown sort key %q (want one of %s)", key, strings.Join(SortKeys, ", "))
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		if reverse {
+			return less(entries[j], entries[i])
+		}
+		return less(entries[i], entries[j])
+	})
+	return nil
+}
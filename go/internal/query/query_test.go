@@ -0,0 +1,226 @@
+package query
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+var now = time.Date(2024, 6, 15, 12, 0, 0, 0, time.UTC)
+
+func entries(t *testing.T) []*entry.Entry {
+	t.Helper()
+	files := []struct{ path, data string }{
+		{"go/slices.md", "---\ntitle: Copy a slice\ndate: 2024-06-01\ntags: [go, slices]\nauthor: Jane Doe\n---\nUse copy to copy a slice.\n"},
+		{"go/maps.md", "---\ntitle: Maps\ndate: 2024-01-10\nupdated: 2024-06-10\ntags: [go]\n---\nMaps are not ordered.\n"},
+		{"git/rebase.md", "---\ntitle: Rebase onto\ndate: 2023-06-15\ntags: [git]\nlang: vi-VN\n---\nDùng git rebase --onto.\n"},
+		{"git/undated.md", "# Undated\n\nNo date here, and a longer body of words.\n"},
+	}
+	var out []*entry.Entry
+	for _, f := range files {
+		e, err := entry.Parse(f.path, []byte(f.data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		out = append(out, e)
+	}
+	return out
+}
+
+func paths(es []*entry.Entry) []string {
+	out := []string{}
+	for _, e := range es {
+		out = append(out, e.Path)
+	}
+	return out
+}
+
+func TestFilter(t *testing.T) {
+	tests := []struct {
+		q    string
+		want []string
+	}{
+		{"", []string{"go/slices.md", "go/maps.md", "git/rebase.md", "git/undated.md"}},
+		{"tag:go", []string{"go/slices.md", "go/maps.md"}},
+		{"tag:GO -slices", []string{"go/maps.md"}},
+		{"category:git", []string{"git/rebase.md", "git/undated.md"}},
+		{`"copy a slice"`, []string{"go/slices.md"}},
+		{"copy slice", []string{"go/slices.md"}},
+		{"maps OR rebase", []string{"go/maps.md", "git/rebase.md"}},
+		{"tag:go AND NOT maps OR tag:git", []string{"go/slices.md", "git/rebase.md"}},
+		{"(maps OR rebase) tag:go", []string{"go/maps.md"}},
+		{`author:"jane doe"`, []string{"go/slices.md"}},
+		{"title:rebase", []string{"git/rebase.md"}},
+		{"lang:vi", []string{"git/rebase.md"}},
+		{"lang:en", []string{"go/slices.md", "go/maps.md", "git/undated.md"}},
+		{"created:2024-06", []string{"go/slices.md"}},
+		{"created:>=2024", []string{"go/slices.md", "go/maps.md"}},
+		{"created:<2024", []string{"git/rebase.md"}},
+		{"created:>2024-01", []string{"go/slices.md"}},
+		{"created:2023-01..2023-12", []string{"git/rebase.md"}},
+		{"created:2023-01..2024-01", []string{"go/maps.md", "git/rebase.md"}},
+		{"updated:>=7d", []string{"go/maps.md"}},
+		{"updated:>=30d", []string{"go/slices.md", "go/maps.md"}},
+		{"updated:2024-06-10", []string{"go/maps.md"}},
+		{"@gonotes", []string{"go/slices.md", "go/maps.md"}},
+		{"@gonotes -@copy", []string{"go/maps.md"}},
+	}
+	ps := Parser{Now: now, Lang: "en", Collections: map[string]string{"gonotes": "tag:go", "copy": `"copy a"`}}
+	for _, tt := range tests {
+		x, err := ps.Parse(tt.q)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.q, err)
+			continue
+		}
+		if got := paths(Filter(entries(t), x)); !slices.Equal(got, tt.want) {
+			t.Errorf("Filter(%q) [%s] = %q, want %q", tt.q, x, got, tt.want)
+		}
+	}
+}
+
+func TestParseString(t *testing.T) {
+	tests := []struct{ q, want string }{
+		{"", "*"},
+		{"a b", "a AND b"},
+		{"a OR b c", "a OR (b AND c)"},
+		{"-tag:go", "NOT tag:go"},
+		{`"two words" and`, `"two words" AND and`},
+		{"created:2024-06", "created:2024-06-01..2024-07-01"},
+		{"created:>=2024", "created:>=2024-01-01"},
+		{"updated:<2024-02-03", "updated:<2024-02-03"},
+		{"cat:go", "category:go"},
+	}
+	for _, tt := range tests {
+		x, err := Parse(tt.q, now)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.q, err)
+			continue
+		}
+		if got := x.String(); got != tt.want {
+			t.Errorf("Parse(%q) = %s, want %s", tt.q, got, tt.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	ps := Parser{Now: now, Collections: map[string]string{"loop": "@again", "again": "a @loop"}}
+	tests := []struct{ q, want string }{
+		{"(a OR b", "missing )"},
+		{"a)", `unexpected ")"`},
+		{"a AND", "unexpected end"},
+		{"OR a", "needs a term on both sides"},
+		{"tag:", "needs a value"},
+		{"colour:red", "unknown field"},
+		{"created:someday", "invalid date"},
+		{`"open`, ""},
+		{"@nope", "no collection @nope"},
+		{"@loop", "refers to itself"},
+	}
+	for _, tt := range tests {
+		_, err := ps.Parse(tt.q)
+		if err == nil {
+			t.Errorf("Parse(%q) succeeded", tt.q)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Parse(%q) = %v, want an error with %q", tt.q, err, tt.want)
+		}
+	}
+}
+
+func TestParseSpan(t *testing.T) {
+	day := func(y int, m time.Month, d int) time.Time { return time.Date(y, m, d, 0, 0, 0, 0, time.UTC) }
+	tests := []struct {
+		s        string
+		from, to time.Time
+	}{
+		{"2024-02-29", day(2024, 2, 29), day(2024, 3, 1)},
+		{"2024-12", day(2024, 12, 1), day(2025, 1, 1)},
+		{"2023", day(2023, 1, 1), day(2024, 1, 1)},
+		{"today", day(2024, 6, 15), day(2024, 6, 16)},
+		{"Yesterday", day(2024, 6, 14), day(2024, 6, 15)},
+		{"2w", day(2024, 6, 1), day(2024, 6, 2)},
+		{"1m", day(2024, 5, 15), day(2024, 5, 16)},
+	}
+	for _, tt := range tests {
+		from, to, err := ParseSpan(tt.s, now)
+		if err != nil || !from.Equal(tt.from) || !to.Equal(tt.to) {
+			t.Errorf("ParseSpan(%q) = %v, %v, %v; want %v, %v", tt.s, from, to, err, tt.from, tt.to)
+		}
+	}
+}
+
+func TestSort(t *testing.T) {
+	tests := []struct {
+		key     string
+		reverse bool
+		want    []string
+	}{
+		{"created", false, []string{"go/slices.md", "go/maps.md", "git/rebase.md", "git/undated.md"}},
+		{"updated", false, []string{"go/maps.md", "go/slices.md", "git/rebase.md", "git/undated.md"}},
+		{"title", false, []string{"go/slices.md", "go/maps.md", "git/rebase.md", "git/undated.md"}},
+		{"path", true, []string{"go/slices.md", "go/maps.md", "git/undated.md", "git/rebase.md"}},
+		{"words", false, []string{"git/undated.md", "go/slices.md", "go/maps.md", "git/rebase.md"}},
+	}
+	for _, tt := range tests {
+		es := entries(t)
+		if err := Sort(es, tt.key, tt.reverse); err != nil {
+			t.Fatal(err)
+		}
+		if got := paths(es); !slices.Equal(got, tt.want) {
+			t.Errorf("Sort(%s, %v) = %q, want %q", tt.key, tt.reverse, got, tt.want)
+		}
+	}
+	if err := Sort(entries(t), "size", false); err == nil {
+		t.Error("Sort accepted an unknown key")
+	}
+}
+
+func TestOnThisDay(t *testing.T) {
+	tests := []struct {
+		created, day string
+		want         bool
+	}{
+		{"2023-06-15", "2024-06-15", true},
+		{"2024-06-15", "2024-06-15", false},
+		{"2023-06-14", "2024-06-15", false},
+		{"2020-02-29", "2023-02-28", true},
+		{"2020-02-29", "2024-02-28", false},
+	}
+	for _, tt := range tests {
+		e, err := entry.Parse("go/x.md", []byte("---\ndate: "+tt.created+"\n---\n"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		day, _ := time.Parse(entry.DateLayout, tt.day)
+		if got := (OnThisDay{day}).Match(e); got != tt.want {
+			t.Errorf("entry of %s on %s: %v, want %v", tt.created, tt.day, got, tt.want)
+		}
+	}
+}
+
+func TestLang(t *testing.T) {
+	tests := []struct {
+		tag, lang string
+		want      bool
+	}{
+		{"vi", "vi", true},
+		{"vi", "VI-vn", true},
+		{"vi-VN", "vi-vn", true},
+		{"vi-VN", "vi", false},
+		{"v", "vi", false},
+		{"en", "", true},
+		{"vi", "", false},
+		{"zh", "zh-Hant-TW", true},
+		{"zh-Hant", "zh-Hant-TW", true},
+	}
+	for _, tt := range tests {
+		e := &entry.Entry{Meta: entry.Meta{Lang: tt.lang}}
+		if got := (Lang{Tag: tt.tag, Default: "en"}).Match(e); got != tt.want {
+			t.Errorf("lang:%s matches %q = %v, want %v", tt.tag, tt.lang, got, tt.want)
+		}
+	}
+}
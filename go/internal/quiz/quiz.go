@@ -0,0 +1,122 @@
+// Package quiz extracts question and answer pairs from entries.
+//
+// Two conventions are recognized, outside code blocks:
+//
+//	**Q:** What does append return?
+//	**A:** The updated slice, which may share the old backing array.
+//
+// where the answer runs to the next blank line, question or heading; and
+// a heading ending in a question mark, answered by the section below it up
+// to the next heading of the same or a higher level.
+package quiz
+
+import (
+	"math/rand/v2"
+	"regexp"
+	"strings"
+
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+var (
+	questionRE = regexp.MustCompile(`^\s*\*\*Q:?\*\*:?\s*(.*)$`)
+	answerRE   = regexp.MustCompile(`^\s*\*\*A:?\*\*:?\s*(.*)$`)
+	headingRE  = regexp.MustCompile(`^ {0,3}(#{1,6})[ \t]+(.*?)[ \t#]*$`)
+)
+
+// Question is a question and its answer, from an entry.
+type Question struct {
+	Entry    *entry.Entry
+	Question string
+	Answer   string
+	// Line is the 1-based line of the question within the entry's body.
+	Line int
+}
+
+// Extract returns the questions of e in order.
+func Extract(e *entry.Entry) []Question {
+	lines := strings.Split(string(e.Body), "\n")
+	code := codeLines(e.Body, len(lines))
+	var (
+		qs []Question
+		// cur is the **Q:** question being read, with its answer once
+		// **A:** is seen; open is the question heading being answered.
+		cur     *Question
+		inA     bool
+		open    *Question
+		openLvl int
+	)
+	endQA := func() {
+		if cur != nil && inA && strings.TrimSpace(cur.Answer) != "" {
+			cur.Answer = strings.TrimSpace(cur.Answer)
+			qs = append(qs, *cur)
+		}
+		cur, inA = nil, false
+	}
+	endHeading := func() {
+		if open != nil && strings.TrimSpace(open.Answer) != "" {
+			open.Answer = strings.TrimSpace(open.Answer)
+			qs = append(qs, *open)
+		}
+		open = nil
+	}
+	for i, line := range lines {
+		if !code[i] {
+			if m := headingRE.FindStringSubmatch(line); m != nil {
+				endQA()
+				if open != nil && len(m[1]) <= openLvl {
+					endHeading()
+				}
+				if open == nil && strings.HasSuffix(m[2], "?") {
+					open, openLvl = &Question{Entry: e, Question: m[2], Line: i + 1}, len(m[1])
+					continue
+				}
+			} else if m := questionRE.FindStringSubmatch(line); m != nil {
+				endQA()
+				cur = &Question{Entry: e, Question: strings.TrimSpace(m[1]), Line: i + 1}
+				continue
+			} else if m := answerRE.FindStringSubmatch(line); m != nil && cur != nil && !inA {
+				inA = true
+				cur.Answer = m[1] + "\n"
+				continue
+			} else if cur != nil && strings.TrimSpace(line) == "" {
+				if inA {
+					endQA()
+				}
+			} else if cur != nil {
+				if inA {
+					cur.Answer += line + "\n"
+				} else {
+					cur.Question += " " + strings.TrimSpace(line)
+				}
+				continue
+			}
+		}
+		if open != nil {
+			open.Answer += line + "\n"
+		}
+	}
+	endQA()
+	endHeading()
+	return qs
+}
+
+// codeLines marks the 0-based lines of body inside fenced code blocks,
+// fences included.
+func codeLines(body []byte, n int) []bool {
+	code := make([]bool, n)
+	for _, b := range entry.CodeBlocks(body) {
+		for l := b.Line - 1; l < b.EndLine && l < n; l++ {
+			code[l] = true
+		}
+	}
+	return code
+}
+
+// Shuffle returns the questions in a random order drawn from seed.
+func Shuffle(qs []Question, seed uint64) []Question {
+	out := append([]Question(nil), qs...)
+	r := rand.New(rand.NewPCG(seed, seed))
+	r.Shuffle(len(out), func(i, j int) { out[i], out[j] = out[j], out[i] })
+	return out
+}
@@ -0,0 +1,49 @@
+package quiz
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+func TestExtract(t *testing.T) {
+	body := "# Slices\n\n" +
+		"**Q:** What does append\nreturn?\n**A:** The updated slice,\nwhich may share the array.\n\n" +
+		"**Q**: Unanswered?\n\n" +
+		"```md\n**Q:** In code?\n**A:** Ignored.\n```\n\n" +
+		"## Why copy?\n\nTo stop sharing.\n\n### Example\n\n```go\n// ## Not a heading?\ncopy(dst, src)\n```\n\n" +
+		"## Empty?\n\n## Notes\n\n**Q:** Last\n**A:** At the end"
+	e, err := entry.Parse("go/slices.md", []byte(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := Extract(e)
+	want := []Question{
+		{Entry: e, Question: "What does append return?", Answer: "The updated slice,\nwhich may share the array.", Line: 3},
+		{Entry: e, Question: "Why copy?", Answer: "To stop sharing.\n\n### Example\n\n```go\n// ## Not a heading?\ncopy(dst, src)\n```", Line: 15},
+		{Entry: e, Question: "Last", Answer: "At the end", Line: 30},
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("Extract =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestShuffle(t *testing.T) {
+	var qs []Question
+	for _, q := range []string{"a", "b", "c", "d", "e", "f"} {
+		qs = append(qs, Question{Question: q})
+	}
+	a, b := Shuffle(qs, 7), Shuffle(qs, 7)
+	if !slices.Equal(a, b) {
+		t.Errorf("Shuffle with one seed = %v and %v", a, b)
+	}
+	if qs[0].Question != "a" {
+		t.Error("Shuffle reordered its argument")
+	}
+	sorted := slices.SortedFunc(slices.Values(a), func(x, y Question) int { return strings.Compare(x.Question, y.Question) })
+	if !slices.Equal(sorted, qs) {
+		t.Errorf("Shuffle = %v, want a permutation of %v", a, qs)
+	}
+}
@@ -0,0 +1,135 @@
+// Package readme generates the entry index embedded in the repository
+// README.
+//
+// The generated section sits between StartMarker and EndMarker; everything
+// outside the markers is left untouched so hand-written sections survive.
+package readme
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+const (
+	StartMarker = "<!-- til:index:start -->"
+	EndMarker   = "<!-- til:index:end -->"
+)
+
+// ErrNoMarkers is returned by Splice when the README lacks the markers.
+var ErrNoMarkers = errors.New("README has no " + StartMarker + " / " + EndMarker + " markers")
+
+// Options configures the generated index.
+type Options struct {
+	// Newest is the number of recent entries listed. Zero omits the list.
+	Newest int
+}
+
+// Generate renders the index for entries, including the markers.
+func Generate(entries []*entry.Entry, opts Options) []byte {
+	byCat := map[string][]*entry.Entry{}
+	var cats []string
+	for _, e := range entries {
+		c := e.Meta.Category
+		if _, ok := byCat[c]; !ok {
+			cats = append(cats, c)
+		}
+		byCat[c] = append(byCat[c], e)
+	}
+	sort.Strings(cats)
+
+	var b bytes.Buffer
+	b.WriteString(StartMarker + "\n\n")
+	fmt.Fprintf(&b, "_%d TILs across %d categories._\n\n", len(entries), len(cats))
+
+	b.WriteString("### Categories\n\n")
+	for _, c := range cats {
+		fmt.Fprintf(&b, "- [%s](#%s) (%d)\n", displayName(c), anchor(c), len(byCat[c]))
+	}
+
+	if newest := newestEntries(entries, opts.Newest); len(newest) > 0 {
+		b.WriteString("\n### Newest\n\n")
+		for _, e := range newest {
+			fmt.Fprintf(&b, "- %s %s · %s\n", e.Meta.Date.Format(entry.DateLayout), link(e), e.Meta.Category)
+		}
+	}
+
+	b.WriteString("\n---\n")
+	for _, c := range cats {
+		list := byCat[c]
+		sort.SliceStable(list, func(i, j int) bool {
+			return strings.ToLower(list[i].Meta.Title) < strings.ToLower(list[j].Meta.Title)
+		})
+		fmt.Fprintf(&b, "\n### %s\n\n", c)
+		for _, e := range list {
+			fmt.Fprintf(&b, "- %s\n", link(e))
+		}
+	}
+	b.WriteString("\n" + EndMarker + "\n")
+	return b.Bytes()
+}
+
+// Splice replaces the marked section of readme with index.
+func Splice(readme, index []byte) ([]byte, error) {
+	start := bytes.Index(readme, []byte(StartMarker))
+	end := bytes.Index(readme, []byte(EndMarker))
+	if start < 0 || end < start {
+		return nil, ErrNoMarkers
+	}
+	end += len(EndMarker)
+	if end < len(readme) && readme[end] == '\n' {
+		end++
+	}
+	var b bytes.Buffer
+	b.Write(readme[:start])
+	b.Write(index)
+	b.Write(readme[end:])
+	return b.Bytes(), nil
+}
+
+func newestEntries(entries []*entry.Entry, n int) []*entry.Entry {
+	if n <= 0 {
+		return nil
+	}
+	var dated []*entry.Entry
+	for _, e := range entries {
+		if !e.Meta.Date.IsZero() {
+			dated = append(dated, e)
+		}
+	}
+	sort.SliceStable(dated, func(i, j int) bool { return dated[i].Meta.Date.After(dated[j].Meta.Date) })
+	if len(dated) > n {
+		dated = dated[:n]
+	}
+	return dated
+}
+
+func link(e *entry.Entry) string {
+	// Spaces would end the link destination.
+	return fmt.Sprintf("[%s](%s)", e.Meta.Title, strings.ReplaceAll(e.Path, " ", "%20"))
+}
+
+// displayName capitalizes a category directory name for the category list.
+func displayName(c string) string {
+	return entry.Humanize(c)
+}
+
+// anchor returns the anchor GitHub assigns to the category heading:
+// lowercased, punctuation other than "-" and "_" dropped, spaces as "-".
+func anchor(c string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(c) {
+		switch {
+		case r == ' ':
+			b.WriteByte('-')
+		case r == '-' || r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r):
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,84 @@
+package readme
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+func parse(t *testing.T, p, data string) *entry.Entry {
+	t.Helper()
+	e, err := entry.Parse(p, []byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+func TestGenerate(t *testing.T) {
+	entries := []*entry.Entry{
+		parse(t, "go/slices.md", "---\ntitle: Slices share arrays\ndate: 2024-03-01\n---\n"),
+		parse(t, "go/maps.md", "---\ntitle: maps are references\ndate: 2024-05-01\n---\n"),
+		parse(t, "dev ops/disk full.md", "---\ntitle: Disk full\ndate: 2024-04-01\n---\n"),
+		parse(t, "git/rebase.md", "# Rebase onto\n"),
+	}
+	want := StartMarker + `
+
+_4 TILs across 3 categories._
+
+### Categories
+
+- [Dev ops](#dev-ops) (1)
+- [Git](#git) (1)
+- [Go](#go) (2)
+
+### Newest
+
+- 2024-05-01 [maps are references](go/maps.md) · go
+- 2024-04-01 [Disk full](dev%20ops/disk%20full.md) · dev ops
+
+---
+
+### dev ops
+
+- [Disk full](dev%20ops/disk%20full.md)
+
+### git
+
+- [Rebase onto](git/rebase.md)
+
+### go
+
+- [maps are references](go/maps.md)
+- [Slices share arrays](go/slices.md)
+
+` + EndMarker + "\n"
+	if got := string(Generate(entries, Options{Newest: 2})); got != want {
+		t.Errorf("Generate =\n%s\nwant\n%s", got, want)
+	}
+	got := string(Generate(entries, Options{}))
+	if want := "\n### Newest\n"; strings.Contains(got, want) {
+		t.Errorf("Generate without Newest lists the newest:\n%s", got)
+	}
+}
+
+func TestSplice(t *testing.T) {
+	index := []byte(StartMarker + "\nnew\n" + EndMarker + "\n")
+	tests := []struct{ readme, want string }{
+		{"# Notes\n\n" + StartMarker + "\nold\n" + EndMarker + "\n\n## About\n", "# Notes\n\n" + StartMarker + "\nnew\n" + EndMarker + "\n\n## About\n"},
+		{StartMarker + EndMarker, StartMarker + "\nnew\n" + EndMarker + "\n"},
+	}
+	for _, tt := range tests {
+		got, err := Splice([]byte(tt.readme), index)
+		if err != nil || string(got) != tt.want {
+			t.Errorf("Splice(%q) = %q, %v; want %q", tt.readme, got, err, tt.want)
+		}
+	}
+	for _, readme := range []string{"# Notes\n", StartMarker + "\n", EndMarker + "\n" + StartMarker + "\n"} {
+		if _, err := Splice([]byte(readme), index); !errors.Is(err, ErrNoMarkers) {
+			t.Errorf("Splice(%q) = %v, want ErrNoMarkers", readme, err)
+		}
+	}
+}
@@ -0,0 +1,372 @@
+// Package related recommends entries similar to a given one, scoring pairs
+// by the TF-IDF cosine similarity of their text and the overlap of their
+// tags.
+//
+// Term vectors and each entry's list of nearest neighbours are cached in
+// the notes state directory. An update re-tokenizes only the entries whose
+// files changed and recomputes only the neighbour lists those changes can
+// affect; lists of unchanged entries are patched with scores against the
+// changed ones.
+package related
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+
+	_ "modernc.org/sqlite" // database/sql driver
+
+	"github.com/canhta/til/go/internal/notes"
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+// File is the cache file name inside the notes state directory.
+const File = "related.db"
+
+// Keep is the number of neighbours cached per entry, the most that can be
+// asked for.
+const Keep = 10
+
+const (
+	textWeight = 0.7
+	tagWeight  = 0.3
+)
+
+const schema = `
+CREATE TABLE IF NOT EXISTS terms (
+	path  TEXT PRIMARY KEY,
+	stamp TEXT NOT NULL,
+	terms TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS neighbours (
+	path       TEXT PRIMARY KEY,
+	neighbours TEXT NOT NULL
+);
+`
+
+// Match is an entry similar to another, with a score from 0 to 1.
+type Match struct {
+	Path  string  `json:"path"`
+	Score float64 `json:"score"`
+}
+
+// Matrix holds the nearest neighbours of every entry.
+type Matrix struct {
+	rows map[string][]Match
+}
+
+// Related returns up to n entries most similar to the entry at p, best
+// first. Entries sharing nothing with p are left out.
+func (m *Matrix) Related(p string, n int) []Match {
+	row := m.rows[p]
+	if n > 0 && len(row) > n {
+		row = row[:n]
+	}
+	return row
+}
+
+// Compute brings the cache of tree up to date with entries and returns the
+// resulting matrix.
+func Compute(ctx context.Context, tree *notes.Tree, entries []*entry.Entry) (*Matrix, error) {
+	file := tree.StatePath(File)
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return nil, err
+	}
+	db, err := sql.Open("sqlite", "file:"+file+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	if _, err := db.Exec(schema); err != nil {
+		return nil, fmt.Errorf("related cache: %w", err)
+	}
+	c := &cache{db: db}
+	if err := c.load(ctx); err != nil {
+		return nil, err
+	}
+	return c.update(ctx, entries)
+}
+
+type cache struct {
+	db     *sql.DB
+	stamps map[string]string
+	terms  map[string]map[string]float64
+	rows   map[string][]Match
+}
+
+func (c *cache) load(ctx context.Context) error {
+	c.stamps, c.terms, c.rows = map[string]string{}, map[string]map[string]float64{}, map[string][]Match{}
+	rows, err := c.db.QueryContext(ctx, `SELECT path, stamp, terms FROM terms`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var p, stamp, terms string
+		if err := rows.Scan(&p, &stamp, &terms); err != nil {
+			return err
+		}
+		tf := map[string]float64{}
+		if err := json.Unmarshal([]byte(terms), &tf); err != nil {
+			continue
+		}
+		c.stamps[p], c.terms[p] = stamp, tf
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	nrows, err := c.db.QueryContext(ctx, `SELECT path, neighbours FROM neighbours`)
+	if err != nil {
+		return err
+	}
+	defer nrows.Close()
+	for nrows.Next() {
+		var p, data string
+		if err := nrows.Scan(&p, &data); err != nil {
+			return err
+		}
+		var row []Match
+		if json.Unmarshal([]byte(data), &row) == nil {
+			c.rows[p] = row
+		}
+	}
+	return nrows.Err()
+}
+
+// stamp identifies the version of an entry's metadata and text.
+func stamp(e *entry.Entry) string {
+	return fmt.Sprintf("%d %d %s", e.ModTime.UnixNano(), len(e.Body), strings.Join(e.Meta.Tags, ","))
+}
+
+func (c *cache) update(ctx context.Context, entries []*entry.Entry) (*Matrix, error) {
+	byPath := map[string]*entry.Entry{}
+	// dirty holds entries that are new, changed or removed.
+	dirty := map[string]bool{}
+	for _, e := range entries {
+		byPath[e.Path] = e
+		if s := stamp(e); c.stamps[e.Path] != s {
+			c.terms[e.Path] = termFreqs(e)
+			c.stamps[e.Path] = s
+			dirty[e.Path] = true
+		}
+	}
+	for p := range c.stamps {
+		if byPath[p] == nil {
+			dirty[p] = true
+			delete(c.stamps, p)
+			delete(c.terms, p)
+			delete(c.rows, p)
+		}
+	}
+	if len(dirty) == 0 && len(c.rows) == len(entries) {
+		return &Matrix{rows: c.rows}, nil
+	}
+
+	vecs := weigh(c.terms)
+	score := func(a, b string) float64 {
+		return textWeight*dot(vecs[a], vecs[b]) + tagWeight*jaccard(byPath[a].Meta.Tags, byPath[b].Meta.Tags)
+	}
+	changed := map[string]bool{}
+	for _, e := range entries {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		p := e.Path
+		row, ok := c.rows[p]
+		stale := dirty[p] || !ok
+		for _, m := range row {
+			stale = stale || dirty[m.Path]
+		}
+		if stale {
+			// Score against every other entry.
+			row = row[:0:0]
+			for _, o := range entries {
+				if o.Path != p {
+					row = insert(row, Match{Path: o.Path, Score: score(p, o.Path)})
+				}
+			}
+		} else {
+			// Only the changed entries can have entered the list.
+			for q := range dirty {
+				if byPath[q] != nil && q != p {
+					row = insert(row, Match{Path: q, Score: score(p, q)})
+				}
+			}
+			if !rowHas(row, dirty) {
+				continue
+			}
+		}
+		c.rows[p] = row
+		changed[p] = true
+	}
+	if err := c.save(ctx, dirty, changed); err != nil {
+		return nil, err
+	}
+	return &Matrix{rows: c.rows}, nil
+}
+
+func rowHas(row []Match, set map[string]bool) bool {
+	for _, m := range row {
+		if set[m.Path] {
+			return true
+		}
+	}
+	return false
+}
+
+// insert adds m to the best-first row, keeping at most Keep matches with a
+// positive score.
+func insert(row []Match, m Match) []Match {
+	if m.Score <= 0 {
+		return row
+	}
+	i := sort.Search(len(row), func(i int) bool {
+		if row[i].Score != m.Score {
+			return row[i].Score < m.Score
+		}
+		return row[i].Path > m.Path
+	})
+	if i >= Keep {
+		return row
+	}
+	row = append(row, Match{})
+	copy(row[i+1:], row[i:])
+	row[i] = m
+	if len(row) > Keep {
+		row = row[:Keep]
+	}
+	return row
+}
+
+func (c *cache) save(ctx context.Context, dirty, changed map[string]bool) error {
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for p := range dirty {
+		tf, ok := c.terms[p]
+		if !ok {
+			if _, err := tx.ExecContext(ctx, `DELETE FROM terms WHERE path = ?`, p); err != nil {
+				return err
+			}
+			if _, err := tx.ExecContext(ctx, `DELETE FROM neighbours WHERE path = ?`, p); err != nil {
+				return err
+			}
+			continue
+		}
+		data, _ := json.Marshal(tf)
+		if _, err := tx.ExecContext(ctx, `INSERT OR REPLACE INTO terms (path, stamp, terms) VALUES (?, ?, ?)`, p, c.stamps[p], string(data)); err != nil {
+			return err
+		}
+	}
+	for p := range changed {
+		data, _ := json.Marshal(c.rows[p])
+		if _, err := tx.ExecContext(ctx, `INSERT OR REPLACE INTO neighbours (path, neighbours) VALUES (?, ?)`, p, string(data)); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// termFreqs counts the terms of an entry, its title counting double.
+func termFreqs(e *entry.Entry) map[string]float64 {
+	tf := map[string]float64{}
+	for _, t := range Tokens(e.Meta.Title) {
+		tf[t] += 2
+	}
+	for _, t := range Tokens(string(e.Body)) {
+		tf[t]++
+	}
+	return tf
+}
+
+// Tokens splits s into the terms entries are compared on: lower-cased runs
+// of letters and digits, at least three long, stopwords dropped.
+func Tokens(s string) []string {
+	words := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	out := words[:0]
+	for _, w := range words {
+		if len(w) >= 3 && !stopwords[w] {
+			out = append(out, w)
+		}
+	}
+	return out
+}
+
+var stopwords = map[string]bool{}
+
+func init() {
+	for _, w := range strings.Fields(`the and for are but not you all any can had her was one our out
+		has him his how its may new now old see two way who did get let put say she too use with this that
+		from they will what when your have more than then them been were which their there would about into
+		also some just like only over such very each other these those here where after before while because`) {
+		stopwords[w] = true
+	}
+}
+
+// weigh turns term frequencies into unit-length TF-IDF vectors.
+func weigh(terms map[string]map[string]float64) map[string]map[string]float64 {
+	df := map[string]int{}
+	for _, tf := range terms {
+		for t := range tf {
+			df[t]++
+		}
+	}
+	n := float64(len(terms))
+	vecs := make(map[string]map[string]float64, len(terms))
+	for p, tf := range terms {
+		v := make(map[string]float64, len(tf))
+		norm := 0.0
+		for t, f := range tf {
+			w := (1 + math.Log(f)) * math.Log(1+n/float64(df[t]))
+			v[t] = w
+			norm += w * w
+		}
+		if norm > 0 {
+			norm = math.Sqrt(norm)
+			for t := range v {
+				v[t] /= norm
+			}
+		}
+		vecs[p] = v
+	}
+	return vecs
+}
+
+func dot(a, b map[string]float64) float64 {
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+	s := 0.0
+	for t, w := range a {
+		s += w * b[t]
+	}
+	return s
+}
+
+func jaccard(a, b []string) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	set := map[string]bool{}
+	for _, t := range a {
+		set[t] = true
+	}
+	both := 0
+	for _, t := range b {
+		if set[t] {
+			both++
+		}
+	}
+	return float64(both) / float64(len(set)+len(b)-both)
+}
@@ -0,0 +1,166 @@
+package related
+
+import (
+	"context"
+	"math"
+	"os"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/canhta/til/go/internal/notes"
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+func TestTokens(t *testing.T) {
+	got := Tokens("The Go maps, and 2 slices: append() copies the backing-array in 1.21!")
+	want := []string{"maps", "slices", "append", "copies", "backing", "array"}
+	if !slices.Equal(got, want) {
+		t.Errorf("Tokens = %q, want %q", got, want)
+	}
+}
+
+func TestJaccard(t *testing.T) {
+	tests := []struct {
+		a, b []string
+		want float64
+	}{
+		{nil, []string{"go"}, 0},
+		{[]string{"go"}, []string{"go"}, 1},
+		{[]string{"go", "slices"}, []string{"go", "maps"}, 1.0 / 3},
+		{[]string{"go"}, []string{"rust"}, 0},
+	}
+	for _, tt := range tests {
+		if got := jaccard(tt.a, tt.b); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("jaccard(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestInsertKeepsBest(t *testing.T) {
+	var row []Match
+	for i := range Keep + 5 {
+		row = insert(row, Match{Path: string(rune('a' + i)), Score: float64(i % 7)})
+	}
+	row = insert(row, Match{Path: "zero", Score: 0})
+	if len(row) != Keep {
+		t.Fatalf("len(row) = %d, want %d", len(row), Keep)
+	}
+	for i := 1; i < len(row); i++ {
+		a, b := row[i-1], row[i]
+		if a.Score < b.Score || a.Score == b.Score && a.Path > b.Path {
+			t.Errorf("row not best first at %d: %v", i, row)
+		}
+		if b.Path == "zero" {
+			t.Error("row kept a zero score")
+		}
+	}
+}
+
+var files = map[string]string{
+	"go/slices.md":  "---\ntitle: Slices share arrays\ntags: [go, slices]\n---\n\nAppending to a slice may reuse the backing array of another slice.\n",
+	"go/append.md":  "---\ntitle: Append grows slices\ntags: [go, slices]\n---\n\nAppend reallocates the backing array when a slice runs out of capacity.\n",
+	"go/maps.md":    "---\ntitle: Map iteration order\ntags: [go, maps]\n---\n\nIterating over a map visits keys in random order.\n",
+	"git/rebase.md": "---\ntitle: Interactive rebase\ntags: [git]\n---\n\nRebase rewrites commits onto a new base branch.\n",
+}
+
+func newTree(t *testing.T) *notes.Tree {
+	t.Helper()
+	tree := notes.Open(t.TempDir())
+	for p, data := range files {
+		if err := tree.Write(p, []byte(data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return tree
+}
+
+func compute(t *testing.T, tree *notes.Tree) (*Matrix, []*entry.Entry) {
+	t.Helper()
+	entries, err := tree.Entries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := Compute(context.Background(), tree, entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m, entries
+}
+
+func paths(row []Match) []string {
+	var out []string
+	for _, m := range row {
+		out = append(out, m.Path)
+	}
+	return out
+}
+
+func TestCompute(t *testing.T) {
+	tree := newTree(t)
+	m, _ := compute(t, tree)
+	got := m.Related("go/slices.md", 0)
+	if want := []string{"go/append.md", "go/maps.md"}; !slices.Equal(paths(got), want) {
+		t.Errorf("Related(go/slices.md) = %v, want %q", got, want)
+	}
+	for _, r := range got {
+		if r.Score <= 0 || r.Score > 1 {
+			t.Errorf("score of %s = %v, want in (0, 1]", r.Path, r.Score)
+		}
+	}
+	if got := m.Related("go/slices.md", 1); !slices.Equal(paths(got), []string{"go/append.md"}) {
+		t.Errorf("Related(go/slices.md, 1) = %v", got)
+	}
+	if got := m.Related("git/rebase.md", 0); len(got) != 0 {
+		t.Errorf("Related(git/rebase.md) = %v, want none", got)
+	}
+	if _, err := os.Stat(tree.StatePath(File)); err != nil {
+		t.Errorf("cache not written: %v", err)
+	}
+}
+
+// TestComputeIncremental checks that updating the cache after edits gives
+// the same neighbours as computing it afresh.
+func TestComputeIncremental(t *testing.T) {
+	tree := newTree(t)
+	compute(t, tree)
+
+	later := time.Now().Add(time.Hour)
+	if err := tree.Write("git/rebase.md", []byte("---\ntitle: Rebase slices of history\ntags: [git, slices]\n---\n\nRebase a slice of commits onto another branch.\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(tree.Abs("git/rebase.md"), later, later); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(tree.Abs("go/maps.md")); err != nil {
+		t.Fatal(err)
+	}
+	if err := tree.Write("go/copy.md", []byte("---\ntitle: Copy slices\ntags: [go, slices]\n---\n\nCopy moves elements between slices sharing no backing array.\n")); err != nil {
+		t.Fatal(err)
+	}
+	m, entries := compute(t, tree)
+
+	if err := os.Remove(tree.StatePath(File)); err != nil {
+		t.Fatal(err)
+	}
+	fresh, _ := compute(t, tree)
+	for _, e := range entries {
+		got, want := m.Related(e.Path, 0), fresh.Related(e.Path, 0)
+		if !slices.Equal(paths(got), paths(want)) {
+			t.Errorf("Related(%s) = %v, want %v", e.Path, got, want)
+			continue
+		}
+		for i := range got {
+			if math.Abs(got[i].Score-want[i].Score) > 1e-9 {
+				t.Errorf("Related(%s) = %v, want %v", e.Path, got, want)
+				break
+			}
+		}
+	}
+	if got := m.Related("go/maps.md", 0); len(got) != 0 {
+		t.Errorf("removed entry still has neighbours %v", got)
+	}
+	if !slices.Contains(paths(m.Related("go/slices.md", 0)), "git/rebase.md") {
+		t.Errorf("Related(go/slices.md) = %v, want the edited git/rebase.md", m.Related("go/slices.md", 0))
+	}
+}
@@ -0,0 +1,106 @@
+// Package remote syncs a notes tree with a copy kept in S3-compatible
+// storage or in a WebDAV directory, for machines where git is not an
+// option.
+//
+// The remote holds each file of the tree under its path, next to a
+// manifest recording the hash and modification time of every file, and a
+// tombstone for every file deleted. Each machine keeps the manifest as of
+// its last sync, so that a sync can tell which side changed a file: a file
+// changed on one side only goes to the other, and a file changed on both
+// goes the way of the one changed last. Deletions travel the same way as
+// changes, so a deleted file is not brought back by a machine that still
+// has it.
+package remote
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ErrNotExist is returned, possibly wrapped, for paths the remote holds no
+// file at.
+var ErrNotExist = fs.ErrNotExist
+
+// Remote holds files under slash-separated paths.
+type Remote interface {
+	Get(ctx context.Context, p string) ([]byte, error)
+	Put(ctx context.Context, p string, data []byte) error
+	// Delete removes the file at p, if there is one.
+	Delete(ctx context.Context, p string) error
+}
+
+// Options are the settings and credentials of the remotes Open returns.
+type Options struct {
+	// Endpoint is the URL of an S3-compatible service; s3:// URLs default
+	// to AWS in Region.
+	Endpoint  string
+	Region    string
+	AccessKey string
+	SecretKey string
+	// SessionToken goes with temporary S3 credentials.
+	SessionToken string
+	// Password is the WebDAV password of the user of the URL, unless the
+	// URL has one.
+	Password string
+	Client   *http.Client
+}
+
+// Open returns the remote at rawURL: s3://bucket/prefix for a bucket, or
+// the http:// or https:// URL of a WebDAV directory, which webdav:// is
+// short for.
+func Open(rawURL string, opts Options) (Remote, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	client := opts.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	switch u.Scheme {
+	case "s3":
+		if u.Host == "" {
+			return nil, fmt.Errorf("%s: no bucket", rawURL)
+		}
+		if opts.AccessKey == "" || opts.SecretKey == "" {
+			return nil, fmt.Errorf("%s: no S3 credentials", rawURL)
+		}
+		return newS3(u.Host, strings.Trim(u.Path, "/"), opts, client)
+	case "webdav":
+		u.Scheme = "https"
+		fallthrough
+	case "http", "https":
+		return newWebDAV(u, opts.Password, client), nil
+	}
+	return nil, fmt.Errorf("%s: want an s3://, webdav:// or https:// URL", rawURL)
+}
+
+// Redact returns rawURL without the password it may hold, for messages.
+func Redact(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	return u.Redacted()
+}
+
+// escape escapes each segment of the slash-separated path p for a URL,
+// leaving only the characters SigV4 leaves unescaped.
+func escape(p string) string {
+	var b strings.Builder
+	for i := 0; i < len(p); i++ {
+		c := p[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~', c == '/':
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,195 @@
+package remote
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/canhta/til/go/internal/logging"
+)
+
+// DefaultRegion is the S3 region used when neither the options nor the
+// environment name one.
+const DefaultRegion = "us-east-1"
+
+// s3 keeps files as the objects of a bucket below a prefix, addressed by
+// path so that any S3-compatible service will do.
+type s3 struct {
+	endpoint *url.URL
+	bucket   string
+	prefix   string
+	region   string
+	key      string
+	secret   string
+	token    string
+	client   *http.Client
+}
+
+func newS3(bucket, prefix string, opts Options, client *http.Client) (*s3, error) {
+	region := opts.Region
+	for _, env := range []string{"AWS_REGION", "AWS_DEFAULT_REGION"} {
+		if region == "" {
+			region = os.Getenv(env)
+		}
+	}
+	if region == "" {
+		region = DefaultRegion
+	}
+	endpoint := opts.Endpoint
+	if endpoint == "" {
+		endpoint = "https://s3." + region + ".amazonaws.com"
+	}
+	u, err := url.Parse(strings.TrimSuffix(endpoint, "/"))
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("S3 endpoint %s: want an http:// or https:// URL", endpoint)
+	}
+	return &s3{
+		endpoint: u, bucket: bucket, prefix: prefix, region: region,
+		key: opts.AccessKey, secret: opts.SecretKey, token: opts.SessionToken, client: client,
+	}, nil
+}
+
+func (s *s3) Get(ctx context.Context, p string) ([]byte, error) {
+	return s.do(ctx, http.MethodGet, p, nil)
+}
+
+func (s *s3) Put(ctx context.Context, p string, data []byte) error {
+	_, err := s.do(ctx, http.MethodPut, p, data)
+	return err
+}
+
+func (s *s3) Delete(ctx context.Context, p string) error {
+	_, err := s.do(ctx, http.MethodDelete, p, nil)
+	return err
+}
+
+// do sends a signed request for the object at p and returns the body of
+// the response.
+func (s *s3) do(ctx context.Context, method, p string, body []byte) ([]byte, error) {
+	u := *s.endpoint
+	key := path.Join(s.bucket, s.prefix, p)
+	u.Path += "/" + key
+	u.RawPath = s.endpoint.EscapedPath() + "/" + escape(key)
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if body == nil {
+		req.Body, req.GetBody, req.ContentLength = http.NoBody, nil, 0
+	}
+	if s.token != "" {
+		req.Header.Set("X-Amz-Security-Token", s.token)
+	}
+	sign(req, body, s.key, s.secret, s.region, time.Now())
+	log := logging.From(ctx).With("method", method, "key", key)
+	start := time.Now()
+	resp, err := s.client.Do(req)
+	if err != nil {
+		log.Debug("s3 request failed", "err", err, "took", time.Since(start))
+		return nil, err
+	}
+	defer resp.Body.Close()
+	log.Debug("s3 request", "status", resp.StatusCode, "took", time.Since(start))
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<30))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotFound && method == http.MethodGet {
+		return nil, fmt.Errorf("s3://%s: %w", key, ErrNotExist)
+	}
+	if resp.StatusCode/100 != 2 {
+		var e struct {
+			Code    string
+			Message string
+		}
+		if xml.Unmarshal(data, &e) == nil && e.Code != "" {
+			return nil, fmt.Errorf("s3: %s %s: %s: %s", method, key, e.Code, e.Message)
+		}
+		return nil, fmt.Errorf("s3: %s %s: %s", method, key, resp.Status)
+	}
+	return data, nil
+}
+
+// sign signs req with AWS Signature Version 4 for S3, covering its host,
+// its headers and body.
+func sign(req *http.Request, body []byte, key, secret, region string, now time.Time) {
+	now = now.UTC()
+	stamp := now.Format("20060102T150405Z")
+	day := stamp[:8]
+	sum := sha256.Sum256(body)
+	payload := hex.EncodeToString(sum[:])
+	req.Header.Set("X-Amz-Date", stamp)
+	req.Header.Set("X-Amz-Content-Sha256", payload)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonical strings.Builder
+	for _, name := range names {
+		canonical.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signed := strings.Join(names, ";")
+
+	request := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		canonicalQuery(req.URL.Query()),
+		canonical.String(),
+		signed,
+		payload,
+	}, "\n")
+	scope := day + "/" + region + "/s3/aws4_request"
+	hash := sha256.Sum256([]byte(request))
+	toSign := "AWS4-HMAC-SHA256\n" + stamp + "\n" + scope + "\n" + hex.EncodeToString(hash[:])
+
+	k := []byte("AWS4" + secret)
+	for _, part := range []string{day, region, "s3", "aws4_request"} {
+		k = hmacSHA256(k, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(k, toSign))
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s", key, scope, signed, signature))
+}
+
+func canonicalQuery(q url.Values) string {
+	keys := make([]string, 0, len(q))
+	for k := range q {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var parts []string
+	for _, k := range keys {
+		values := append([]string{}, q[k]...)
+		sort.Strings(values)
+		for _, v := range values {
+			parts = append(parts, strings.ReplaceAll(escape(k), "/", "%2F")+"="+strings.ReplaceAll(escape(v), "/", "%2F"))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
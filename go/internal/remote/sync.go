@@ -0,0 +1,258 @@
+package remote
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/canhta/til/go/internal/fsutil"
+	"github.com/canhta/til/go/internal/notes"
+)
+
+// ManifestFile is the path of the manifest on the remote.
+const ManifestFile = ".til-sync.json"
+
+// StateFile is the file in the state directory holding the manifest of
+// every remote as of the last sync with it, by URL.
+const StateFile = "remotes.json"
+
+// Record is what a manifest knows of a file.
+type Record struct {
+	// Hash is the hex SHA-256 of the content; empty for tombstones.
+	Hash string `json:"hash,omitempty"`
+	// Time is when the file was last modified, or deleted.
+	Time    time.Time `json:"time"`
+	Deleted bool      `json:"deleted,omitempty"`
+}
+
+// sameContent reports whether a and b leave the same file, or none.
+func (a Record) sameContent(b Record) bool {
+	return a.Deleted == b.Deleted && (a.Deleted || a.Hash == b.Hash)
+}
+
+func (a Record) same(b Record) bool {
+	return a.sameContent(b) && a.Time.Equal(b.Time)
+}
+
+// Manifest records the files of a side by path.
+type Manifest map[string]Record
+
+// Action is what a Step does.
+type Action string
+
+const (
+	Upload       Action = "upload"
+	Download     Action = "download"
+	DeleteRemote Action = "delete remote"
+	DeleteLocal  Action = "delete local"
+)
+
+// Step is an action taken on a file to sync it.
+type Step struct {
+	Path   string `json:"path"`
+	Action Action `json:"action"`
+}
+
+// Plan is a sync with a remote worked out, to be applied or shown.
+type Plan struct {
+	Steps []Step
+	url   string
+	// merged is the manifest of both sides once synced, remote the one the
+	// remote has now.
+	merged, remote Manifest
+}
+
+// Prepare works out how to sync tree with the remote r at url.
+func Prepare(ctx context.Context, tree *notes.Tree, r Remote, url string) (*Plan, error) {
+	state, err := loadState(tree)
+	if err != nil {
+		return nil, err
+	}
+	last := state[Redact(url)]
+	local, err := scan(tree, last, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	remote := Manifest{}
+	data, err := r.Get(ctx, ManifestFile)
+	switch {
+	case errors.Is(err, ErrNotExist):
+	case err != nil:
+		return nil, err
+	default:
+		if err := json.Unmarshal(data, &remote); err != nil {
+			return nil, fmt.Errorf("%s on %s: %w", ManifestFile, Redact(url), err)
+		}
+	}
+	// Whoever can write to the remote writes its manifest: a path outside
+	// the files of the tree would have Apply write or delete anything.
+	for p := range remote {
+		if !notes.InTree(p) {
+			return nil, fmt.Errorf("%s on %s: %q is not a file of the tree", ManifestFile, Redact(url), p)
+		}
+	}
+
+	paths := map[string]bool{}
+	for p := range local {
+		paths[p] = true
+	}
+	for p := range remote {
+		paths[p] = true
+	}
+	plan := &Plan{url: url, merged: Manifest{}, remote: remote}
+	for p := range paths {
+		l, hasL := local[p]
+		r, hasR := remote[p]
+		b, hasB := last[p]
+		var localWins bool
+		switch {
+		case !hasR:
+			localWins = true
+		case !hasL:
+		case l.sameContent(r):
+			plan.merged[p] = r
+			if l.Time.After(r.Time) {
+				plan.merged[p] = l
+			}
+			continue
+		case hasB && l.same(b):
+		case hasB && r.same(b):
+			localWins = true
+		default:
+			// Changed on both sides: the last change wins.
+			localWins = l.Time.After(r.Time)
+		}
+		if localWins {
+			plan.merged[p] = l
+			switch {
+			case !l.Deleted:
+				plan.Steps = append(plan.Steps, Step{p, Upload})
+			case hasR && !r.Deleted:
+				plan.Steps = append(plan.Steps, Step{p, DeleteRemote})
+			}
+			continue
+		}
+		plan.merged[p] = r
+		switch {
+		case !r.Deleted:
+			plan.Steps = append(plan.Steps, Step{p, Download})
+		case hasL && !l.Deleted:
+			plan.Steps = append(plan.Steps, Step{p, DeleteLocal})
+		}
+	}
+	sort.Slice(plan.Steps, func(i, j int) bool { return plan.Steps[i].Path < plan.Steps[j].Path })
+	return plan, nil
+}
+
+// scan returns the manifest of the files of tree now, given the manifest
+// as of the last sync: files unchanged since keep their record, and files
+// gone since get a tombstone.
+func scan(tree *notes.Tree, last Manifest, now time.Time) (Manifest, error) {
+	files, err := tree.Files()
+	if err != nil {
+		return nil, err
+	}
+	m := Manifest{}
+	for _, f := range files {
+		data, err := tree.Read(f.Path)
+		if err != nil {
+			return nil, err
+		}
+		rec := Record{Hash: hash(data), Time: f.ModTime.UTC()}
+		if old, ok := last[f.Path]; ok && old.sameContent(rec) {
+			rec = old
+		}
+		m[f.Path] = rec
+	}
+	for p, old := range last {
+		if _, ok := m[p]; ok {
+			continue
+		}
+		if !old.Deleted {
+			old = Record{Time: now.UTC(), Deleted: true}
+		}
+		m[p] = old
+	}
+	return m, nil
+}
+
+// Apply carries out the plan, then records the synced manifest on the
+// remote and in the state of tree.
+func (p *Plan) Apply(ctx context.Context, tree *notes.Tree, r Remote) error {
+	for _, s := range p.Steps {
+		var err error
+		switch s.Action {
+		case Upload:
+			var data []byte
+			if data, err = tree.Read(s.Path); err == nil {
+				err = r.Put(ctx, s.Path, data)
+			}
+		case Download:
+			var data []byte
+			if data, err = r.Get(ctx, s.Path); err == nil {
+				if hash(data) != p.merged[s.Path].Hash {
+					err = errors.New("the remote file does not match its manifest; is another machine syncing?")
+				} else {
+					err = tree.Write(s.Path, data)
+				}
+			}
+		case DeleteRemote:
+			err = r.Delete(ctx, s.Path)
+		case DeleteLocal:
+			err = tree.Remove(s.Path)
+		}
+		if err != nil {
+			return fmt.Errorf("%s %s: %w", s.Action, s.Path, err)
+		}
+	}
+	data, err := json.MarshalIndent(p.merged, "", "  ")
+	if err != nil {
+		return err
+	}
+	if old, _ := json.MarshalIndent(p.remote, "", "  "); !bytes.Equal(old, data) {
+		if err := r.Put(ctx, ManifestFile, append(data, '\n')); err != nil {
+			return err
+		}
+	}
+	state, err := loadState(tree)
+	if err != nil {
+		return err
+	}
+	state[Redact(p.url)] = p.merged
+	return saveState(tree, state)
+}
+
+func hash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func loadState(tree *notes.Tree) (map[string]Manifest, error) {
+	state := map[string]Manifest{}
+	data, err := os.ReadFile(tree.StatePath(StateFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("%s: %w", StateFile, err)
+	}
+	return state, nil
+}
+
+func saveState(tree *notes.Tree, state map[string]Manifest) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return fsutil.WriteFile(tree.StatePath(StateFile), append(data, '\n'), 0o644)
+}
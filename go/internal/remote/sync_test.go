@@ -0,0 +1,185 @@
+package remote
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/canhta/til/go/internal/notes"
+)
+
+// memRemote is a Remote keeping its files in memory.
+type memRemote map[string][]byte
+
+func (m memRemote) Get(_ context.Context, p string) ([]byte, error) {
+	data, ok := m[p]
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", p, ErrNotExist)
+	}
+	return data, nil
+}
+
+func (m memRemote) Put(_ context.Context, p string, data []byte) error {
+	m[p] = data
+	return nil
+}
+
+func (m memRemote) Delete(_ context.Context, p string) error {
+	delete(m, p)
+	return nil
+}
+
+const testURL = "s3://bucket/notes"
+
+func newTree(t *testing.T, files map[string]string) *notes.Tree {
+	t.Helper()
+	tree := notes.Open(t.TempDir())
+	for p, data := range files {
+		write(t, tree, p, data, time.Time{})
+	}
+	return tree
+}
+
+// write writes a file of tree, modified at mod unless it is zero.
+func write(t *testing.T, tree *notes.Tree, p, data string, mod time.Time) {
+	t.Helper()
+	if err := tree.Write(p, []byte(data)); err != nil {
+		t.Fatal(err)
+	}
+	if !mod.IsZero() {
+		if err := os.Chtimes(tree.Abs(p), mod, mod); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func read(t *testing.T, tree *notes.Tree, p string) string {
+	t.Helper()
+	data, err := tree.Read(p)
+	if err != nil {
+		return "<" + err.Error() + ">"
+	}
+	return string(data)
+}
+
+// sync syncs tree with r and returns the steps taken.
+func sync(t *testing.T, tree *notes.Tree, r Remote) []Step {
+	t.Helper()
+	ctx := context.Background()
+	plan, err := Prepare(ctx, tree, r, testURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := plan.Apply(ctx, tree, r); err != nil {
+		t.Fatal(err)
+	}
+	return plan.Steps
+}
+
+func checkSteps(t *testing.T, got []Step, want ...Step) {
+	t.Helper()
+	if !slices.Equal(got, want) {
+		t.Errorf("steps = %v, want %v", got, want)
+	}
+}
+
+func TestSync(t *testing.T) {
+	r := memRemote{}
+	a := newTree(t, map[string]string{"go/a.md": "a\n", "go/b.md": "b\n"})
+	checkSteps(t, sync(t, a, r), Step{"go/a.md", Upload}, Step{"go/b.md", Upload})
+	checkSteps(t, sync(t, a, r))
+
+	b := newTree(t, map[string]string{"git/c.md": "c\n"})
+	checkSteps(t, sync(t, b, r), Step{"git/c.md", Upload}, Step{"go/a.md", Download}, Step{"go/b.md", Download})
+	if got := read(t, b, "go/a.md"); got != "a\n" {
+		t.Errorf("go/a.md = %q", got)
+	}
+
+	// A change and a deletion on b travel to a.
+	write(t, b, "go/a.md", "a, changed\n", time.Now().Add(time.Minute))
+	if err := b.Remove("go/b.md"); err != nil {
+		t.Fatal(err)
+	}
+	checkSteps(t, sync(t, b, r), Step{"go/a.md", Upload}, Step{"go/b.md", DeleteRemote})
+	checkSteps(t, sync(t, a, r), Step{"git/c.md", Download}, Step{"go/a.md", Download}, Step{"go/b.md", DeleteLocal})
+	if got := read(t, a, "go/a.md"); got != "a, changed\n" {
+		t.Errorf("go/a.md = %q", got)
+	}
+	if ok, _ := a.Exists("go/b.md"); ok {
+		t.Error("go/b.md deleted on b is still on a")
+	}
+
+	// The deleted file is not brought back by a sync of a tree still
+	// holding it as of before.
+	checkSteps(t, sync(t, a, r))
+	checkSteps(t, sync(t, b, r))
+}
+
+func TestSyncConflict(t *testing.T) {
+	r := memRemote{}
+	a := newTree(t, map[string]string{"go/a.md": "a\n"})
+	sync(t, a, r)
+	b := newTree(t, nil)
+	sync(t, b, r)
+
+	now := time.Now()
+	write(t, a, "go/a.md", "from a\n", now.Add(time.Minute))
+	write(t, b, "go/a.md", "from b\n", now.Add(2*time.Minute))
+	checkSteps(t, sync(t, a, r), Step{"go/a.md", Upload})
+	// Changed on both sides, b's change last.
+	checkSteps(t, sync(t, b, r), Step{"go/a.md", Upload})
+	checkSteps(t, sync(t, a, r), Step{"go/a.md", Download})
+	if got := read(t, a, "go/a.md"); got != "from b\n" {
+		t.Errorf("go/a.md = %q, want b's", got)
+	}
+}
+
+func TestApplyChecksHash(t *testing.T) {
+	r := memRemote{}
+	sync(t, newTree(t, map[string]string{"go/a.md": "a\n"}), r)
+	r["go/a.md"] = []byte("tampered\n")
+	b := newTree(t, nil)
+	plan, err := Prepare(context.Background(), b, r, testURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := plan.Apply(context.Background(), b, r); err == nil || !strings.Contains(err.Error(), "does not match its manifest") {
+		t.Errorf("Apply = %v, want a hash mismatch", err)
+	}
+	if ok, _ := b.Exists("go/a.md"); ok {
+		t.Error("mismatched file written")
+	}
+}
+
+func TestPrepareRejectsPaths(t *testing.T) {
+	for _, p := range []string{
+		"../../.ssh/authorized_keys",
+		"go/../../x.md",
+		"/etc/passwd",
+		".git/hooks/post-checkout",
+		"go/.git/config",
+		".til/remotes.json",
+		"node_modules/x/y.md",
+		"README.md",
+		"go/.hidden.md",
+		"go//a.md",
+	} {
+		t.Run(p, func(t *testing.T) {
+			manifest, _ := json.Marshal(Manifest{p: {Hash: hash([]byte("x")), Time: time.Now()}})
+			r := memRemote{ManifestFile: manifest, p: []byte("x")}
+			tree := newTree(t, nil)
+			if _, err := Prepare(context.Background(), tree, r, testURL); err == nil || !strings.Contains(err.Error(), "is not a file of the tree") {
+				t.Errorf("Prepare = %v, want the path rejected", err)
+			}
+			if _, err := os.Stat(filepath.Join(tree.Root, filepath.FromSlash(p))); err == nil {
+				t.Error("file written")
+			}
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package remote
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/canhta/til/go/internal/logging"
+)
+
+// webdav keeps files below a directory of a WebDAV server, creating the
+// directories they need.
+type webdav struct {
+	base     *url.URL
+	user     string
+	password string
+	client   *http.Client
+}
+
+func newWebDAV(u *url.URL, password string, client *http.Client) *webdav {
+	d := &webdav{client: client}
+	if u.User != nil {
+		d.user = u.User.Username()
+		if p, ok := u.User.Password(); ok {
+			password = p
+		}
+	}
+	d.password = password
+	base := *u
+	base.User = nil
+	base.Path = strings.TrimSuffix(base.Path, "/")
+	base.RawPath = ""
+	d.base = &base
+	return d
+}
+
+func (d *webdav) Get(ctx context.Context, p string) ([]byte, error) {
+	resp, data, err := d.do(ctx, http.MethodGet, p, nil)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, fmt.Errorf("%s: %w", d.url(p), ErrNotExist)
+	case resp.StatusCode/100 != 2:
+		return nil, fmt.Errorf("webdav: GET %s: %s", d.url(p), resp.Status)
+	}
+	return data, nil
+}
+
+func (d *webdav) Put(ctx context.Context, p string, data []byte) error {
+	resp, _, err := d.do(ctx, http.MethodPut, p, data)
+	if err != nil {
+		return err
+	}
+	// Servers answer 409 Conflict, or some 404, when the directory of a
+	// file does not exist.
+	if resp.StatusCode == http.StatusConflict || resp.StatusCode == http.StatusNotFound {
+		if err := d.mkdirs(ctx, path.Dir(p)); err != nil {
+			return err
+		}
+		if resp, _, err = d.do(ctx, http.MethodPut, p, data); err != nil {
+			return err
+		}
+	}
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("webdav: PUT %s: %s", d.url(p), resp.Status)
+	}
+	return nil
+}
+
+func (d *webdav) Delete(ctx context.Context, p string) error {
+	resp, _, err := d.do(ctx, http.MethodDelete, p, nil)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode/100 != 2 && resp.StatusCode != http.StatusNotFound {
+		return fmt.Errorf("webdav: DELETE %s: %s", d.url(p), resp.Status)
+	}
+	return nil
+}
+
+// mkdirs creates the directory dir, relative to the base, and those above
+// it that are missing, up to the base itself.
+func (d *webdav) mkdirs(ctx context.Context, dir string) error {
+	top := dir == "."
+	if top {
+		dir = ""
+	}
+	resp, _, err := d.do(ctx, "MKCOL", strings.TrimPrefix(dir+"/", "/"), nil)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode == http.StatusConflict && !top {
+		if err := d.mkdirs(ctx, path.Dir(dir)); err != nil {
+			return err
+		}
+		if resp, _, err = d.do(ctx, "MKCOL", dir+"/", nil); err != nil {
+			return err
+		}
+	}
+	// 405 Method Not Allowed is the answer for a directory that exists.
+	if resp.StatusCode/100 != 2 && resp.StatusCode != http.StatusMethodNotAllowed {
+		return fmt.Errorf("webdav: MKCOL %s: %s", d.url(dir), resp.Status)
+	}
+	return nil
+}
+
+func (d *webdav) url(p string) string {
+	u := *d.base
+	u.Path += "/" + p
+	u.RawPath = d.base.EscapedPath() + "/" + escape(p)
+	return u.String()
+}
+
+func (d *webdav) do(ctx context.Context, method, p string, body []byte) (*http.Response, []byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, d.url(p), bytes.NewReader(body))
+	if err != nil {
+		return nil, nil, err
+	}
+	if body == nil {
+		req.Body, req.GetBody, req.ContentLength = http.NoBody, nil, 0
+	}
+	if d.user != "" {
+		req.SetBasicAuth(d.user, d.password)
+	}
+	log := logging.From(ctx).With("method", method, "path", p)
+	start := time.Now()
+	resp, err := d.client.Do(req)
+	if err != nil {
+		log.Debug("webdav request failed", "err", err, "took", time.Since(start))
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	log.Debug("webdav request", "status", resp.StatusCode, "took", time.Since(start))
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<30))
+	if err != nil {
+		return nil, nil, err
+	}
+	return resp, data, nil
+}
@@ -0,0 +1,118 @@
+package remote
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// davServer is a WebDAV server holding files and directories in memory,
+// answering as servers do for missing and existing directories.
+type davServer struct {
+	files map[string]string
+	dirs  map[string]bool
+	log   []string
+}
+
+func (s *davServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if user, pass, ok := r.BasicAuth(); !ok || user != "ann" || pass != "secret" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	p := strings.TrimPrefix(r.URL.Path, "/dav")
+	s.log = append(s.log, r.Method+" "+r.URL.EscapedPath())
+	switch r.Method {
+	case http.MethodGet:
+		data, ok := s.files[p]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		io.WriteString(w, data)
+	case http.MethodPut:
+		if !s.dirs[path.Dir(p)] {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		data, _ := io.ReadAll(r.Body)
+		s.files[p] = string(data)
+		w.WriteHeader(http.StatusCreated)
+	case http.MethodDelete:
+		if _, ok := s.files[p]; !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		delete(s.files, p)
+		w.WriteHeader(http.StatusNoContent)
+	case "MKCOL":
+		dir := strings.TrimSuffix(p, "/")
+		switch {
+		case s.dirs[dir]:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		case !s.dirs[path.Dir(dir)]:
+			w.WriteHeader(http.StatusConflict)
+		default:
+			s.dirs[dir] = true
+			w.WriteHeader(http.StatusCreated)
+		}
+	}
+}
+
+func TestWebDAV(t *testing.T) {
+	dav := &davServer{files: map[string]string{}, dirs: map[string]bool{"/": true}}
+	srv := httptest.NewServer(dav)
+	defer srv.Close()
+	ctx := context.Background()
+
+	r, err := Open(strings.Replace(srv.URL, "http://", "http://ann@", 1)+"/dav/my notes/", Options{Password: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The directories are made as needed, the base included.
+	if err := r.Put(ctx, "go/deep/a b.md", []byte("# A\n")); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"PUT /dav/my%20notes/go/deep/a%20b.md",
+		"MKCOL /dav/my%20notes/go/deep/",
+		"MKCOL /dav/my%20notes/go/",
+		"MKCOL /dav/my%20notes/",
+		"MKCOL /dav/my%20notes/go/",
+		"MKCOL /dav/my%20notes/go/deep/",
+		"PUT /dav/my%20notes/go/deep/a%20b.md",
+	}
+	if !slices.Equal(dav.log, want) {
+		t.Errorf("requests =\n%s\nwant\n%s", strings.Join(dav.log, "\n"), strings.Join(want, "\n"))
+	}
+	if got := dav.files["/my notes/go/deep/a b.md"]; got != "# A\n" {
+		t.Errorf("files = %q", dav.files)
+	}
+
+	dav.log = nil
+	if err := r.Put(ctx, "go/b.md", []byte("# B\n")); err != nil || len(dav.log) != 1 {
+		t.Errorf("Put into an existing directory = %v, requests %q", err, dav.log)
+	}
+	if data, err := r.Get(ctx, "go/b.md"); err != nil || string(data) != "# B\n" {
+		t.Errorf("Get = %q, %v", data, err)
+	}
+	if err := r.Delete(ctx, "go/b.md"); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Delete(ctx, "go/b.md"); err != nil {
+		t.Errorf("Delete of a missing file = %v", err)
+	}
+	if _, err := r.Get(ctx, "go/b.md"); !errors.Is(err, ErrNotExist) {
+		t.Errorf("Get of a deleted file = %v, want ErrNotExist", err)
+	}
+
+	r, _ = Open(srv.URL+"/dav", Options{})
+	if _, err := r.Get(ctx, "go/a.md"); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("Get without credentials = %v", err)
+	}
+}
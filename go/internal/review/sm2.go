@@ -0,0 +1,110 @@
+// Package review schedules entries for spaced-repetition review using a
+// variant of the SM-2 algorithm. Scheduling state is kept in a SQLite
+// database in the notes state directory, apart from the markdown files.
+package review
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// Grade is how well an entry was recalled.
+type Grade int
+
+const (
+	Again Grade = iota + 1
+	Hard
+	Good
+	Easy
+)
+
+var gradeNames = map[Grade]string{Again: "again", Hard: "hard", Good: "good", Easy: "easy"}
+
+func (g Grade) String() string { return gradeNames[g] }
+
+// ParseGrade accepts a grade name, its first letter, or its number 1-4.
+func ParseGrade(s string) (Grade, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	for g, name := range gradeNames {
+		if s == name || s == name[:1] || s == fmt.Sprint(int(g)) {
+			return g, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid grade %q (want again, hard, good or easy)", s)
+}
+
+// quality maps a grade onto SM-2's 0-5 response quality.
+func (g Grade) quality() float64 {
+	switch g {
+	case Again:
+		return 1
+	case Hard:
+		return 3
+	case Good:
+		return 4
+	}
+	return 5
+}
+
+const (
+	initialEase = 2.5
+	minEase     = 1.3
+	// easyBonus stretches the interval of entries graded easy.
+	easyBonus = 1.3
+)
+
+// Card is the scheduling state of one entry.
+type Card struct {
+	Path string
+	// Ease is SM-2's easiness factor.
+	Ease float64
+	// Interval is the current spacing in days.
+	Interval int
+	// Reps counts consecutive successful reviews.
+	Reps     int
+	Due      time.Time
+	Reviewed time.Time
+}
+
+// NewCard returns the state of an entry that was never reviewed. It is due
+// immediately.
+func NewCard(path string) Card {
+	return Card{Path: path, Ease: initialEase}
+}
+
+// IsNew reports whether the card was never reviewed.
+func (c Card) IsNew() bool {
+	return c.Reviewed.IsZero()
+}
+
+// Schedule returns the card after a review graded g at now.
+func (c Card) Schedule(g Grade, now time.Time) Card {
+	q := g.quality()
+	if q < 3 {
+		c.Reps = 0
+		c.Interval = 1
+	} else {
+		switch c.Reps {
+		case 0:
+			c.Interval = 1
+		case 1:
+			c.Interval = 6
+		default:
+			c.Interval = int(math.Round(float64(c.Interval) * c.Ease))
+		}
+		if g == Hard && c.Reps > 0 {
+			c.Interval = max(1, int(math.Round(float64(c.Interval)*0.8)))
+		}
+		if g == Easy {
+			c.Interval = int(math.Round(float64(c.Interval) * easyBonus))
+		}
+		c.Reps++
+	}
+	c.Ease = math.Max(minEase, c.Ease+0.1-(5-q)*(0.08+(5-q)*0.02))
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	c.Due = day.AddDate(0, 0, c.Interval)
+	c.Reviewed = now
+	return c
+}
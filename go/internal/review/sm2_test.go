@@ -0,0 +1,58 @@
+package review
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestParseGrade(t *testing.T) {
+	for s, want := range map[string]Grade{"again": Again, "H": Hard, " good ": Good, "4": Easy, "e": Easy, "1": Again} {
+		if g, err := ParseGrade(s); err != nil || g != want {
+			t.Errorf("ParseGrade(%q) = %v, %v; want %v", s, g, err, want)
+		}
+	}
+	for _, s := range []string{"", "5", "great"} {
+		if _, err := ParseGrade(s); err == nil {
+			t.Errorf("ParseGrade(%q) succeeded", s)
+		}
+	}
+}
+
+func TestSchedule(t *testing.T) {
+	now := time.Date(2024, 6, 1, 15, 30, 0, 0, time.UTC)
+	day := func(n int) time.Time { return time.Date(2024, 6, 1+n, 0, 0, 0, 0, time.UTC) }
+	tests := []struct {
+		name     string
+		grades   []Grade
+		interval int
+		reps     int
+		ease     float64
+	}{
+		{"good once", []Grade{Good}, 1, 1, 2.5},
+		{"good twice", []Grade{Good, Good}, 6, 2, 2.5},
+		{"good thrice", []Grade{Good, Good, Good}, 15, 3, 2.5},
+		{"easy", []Grade{Easy}, 1, 1, 2.6},
+		{"easy after good", []Grade{Good, Easy}, 8, 2, 2.6},
+		{"hard", []Grade{Good, Good, Hard}, 12, 3, 2.36},
+		{"again", []Grade{Good, Good, Again}, 1, 0, 1.96},
+		{"ease floor", []Grade{Again, Again, Again, Again}, 1, 0, 1.3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewCard("go/x.md")
+			if !c.IsNew() {
+				t.Fatal("new card is not new")
+			}
+			for _, g := range tt.grades {
+				c = c.Schedule(g, now)
+			}
+			if c.Interval != tt.interval || c.Reps != tt.reps || math.Abs(c.Ease-tt.ease) > 1e-9 {
+				t.Errorf("interval %d, reps %d, ease %v; want %d, %d, %v", c.Interval, c.Reps, c.Ease, tt.interval, tt.reps, tt.ease)
+			}
+			if !c.Due.Equal(day(tt.interval)) || !c.Reviewed.Equal(now) || c.IsNew() {
+				t.Errorf("due %s, reviewed %s", c.Due, c.Reviewed)
+			}
+		})
+	}
+}
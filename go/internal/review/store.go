@@ -0,0 +1,155 @@
+package review
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	_ "modernc.org/sqlite" // database/sql driver
+
+	"github.com/canhta/til/go/internal/notes"
+)
+
+// File is the review database name inside the notes state directory.
+const File = "review.db"
+
+const schema = `
+CREATE TABLE IF NOT EXISTS cards (
+	path     TEXT PRIMARY KEY,
+	ease     REAL NOT NULL,
+	interval INTEGER NOT NULL,
+	reps     INTEGER NOT NULL,
+	due      INTEGER NOT NULL,
+	reviewed INTEGER NOT NULL
+);
+CREATE TABLE IF NOT EXISTS log (
+	path  TEXT NOT NULL,
+	at    INTEGER NOT NULL,
+	grade INTEGER NOT NULL
+);
+`
+
+// Store persists cards and the review log.
+type Store struct {
+	db *sql.DB
+}
+
+// Open opens (creating if needed) the review database of tree.
+func Open(tree *notes.Tree) (*Store, error) {
+	file := tree.StatePath(File)
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return nil, err
+	}
+	db, err := sql.Open("sqlite", "file:"+file+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("review database: %w", err)
+	}
+	return &Store{db: db}, nil
+}
+
+// Close closes the database.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Cards returns the stored cards keyed by path.
+func (s *Store) Cards(ctx context.Context) (map[string]Card, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT path, ease, interval, reps, due, reviewed FROM cards`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	cards := map[string]Card{}
+	for rows.Next() {
+		var c Card
+		var due, reviewed int64
+		if err := rows.Scan(&c.Path, &c.Ease, &c.Interval, &c.Reps, &due, &reviewed); err != nil {
+			return nil, err
+		}
+		c.Due, c.Reviewed = time.Unix(due, 0), time.Unix(reviewed, 0)
+		cards[c.Path] = c
+	}
+	return cards, rows.Err()
+}
+
+// Card returns the card for path, or a new card if it was never reviewed.
+func (s *Store) Card(ctx context.Context, path string) (Card, error) {
+	c := NewCard(path)
+	var due, reviewed int64
+	err := s.db.QueryRowContext(ctx, `SELECT ease, interval, reps, due, reviewed FROM cards WHERE path = ?`, path).
+		Scan(&c.Ease, &c.Interval, &c.Reps, &due, &reviewed)
+	if err == sql.ErrNoRows {
+		return c, nil
+	}
+	if err != nil {
+		return c, err
+	}
+	c.Due, c.Reviewed = time.Unix(due, 0), time.Unix(reviewed, 0)
+	return c, nil
+}
+
+// Record schedules path after a review graded g at now and stores the
+// result. Other practice modes, such as quizzes, feed grades in here too.
+func (s *Store) Record(ctx context.Context, path string, g Grade, now time.Time) (Card, error) {
+	c, err := s.Card(ctx, path)
+	if err != nil {
+		return c, err
+	}
+	c = c.Schedule(g, now)
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return c, err
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO cards (path, ease, interval, reps, due, reviewed) VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (path) DO UPDATE SET ease = excluded.ease, interval = excluded.interval,
+			reps = excluded.reps, due = excluded.due, reviewed = excluded.reviewed`,
+		c.Path, c.Ease, c.Interval, c.Reps, c.Due.Unix(), c.Reviewed.Unix()); err != nil {
+		return c, err
+	}
+	if _, err := tx.ExecContext(ctx, `INSERT INTO log (path, at, grade) VALUES (?, ?, ?)`, path, now.Unix(), int(g)); err != nil {
+		return c, err
+	}
+	return c, tx.Commit()
+}
+
+// Queue is the set of cards to review in a session.
+type Queue struct {
+	// Due are previously reviewed cards whose due date has passed, most
+	// overdue first.
+	Due []Card
+	// New are cards never reviewed, in path order.
+	New []Card
+}
+
+// Queue builds the review queue for the entries at paths as of now. At
+// most newLimit new cards are included; a negative limit means no limit.
+func (s *Store) Queue(ctx context.Context, paths []string, now time.Time, newLimit int) (*Queue, error) {
+	cards, err := s.Cards(ctx)
+	if err != nil {
+		return nil, err
+	}
+	q := &Queue{}
+	for _, p := range paths {
+		c, ok := cards[p]
+		switch {
+		case !ok:
+			if newLimit < 0 || len(q.New) < newLimit {
+				q.New = append(q.New, NewCard(p))
+			}
+		case !c.Due.After(now):
+			q.Due = append(q.Due, c)
+		}
+	}
+	sort.SliceStable(q.Due, func(i, j int) bool { return q.Due[i].Due.Before(q.Due[j].Due) })
+	return q, nil
+}
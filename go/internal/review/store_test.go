@@ -0,0 +1,66 @@
+package review
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/canhta/til/go/internal/notes"
+)
+
+func TestStore(t *testing.T) {
+	ctx := context.Background()
+	tree := notes.Open(t.TempDir())
+	s, err := Open(tree)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.Local)
+	if c, err := s.Card(ctx, "go/a.md"); err != nil || !c.IsNew() || c.Ease != initialEase {
+		t.Errorf("Card of an unreviewed entry = %+v, %v", c, err)
+	}
+	if _, err := s.Record(ctx, "go/a.md", Good, now); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Record(ctx, "go/a.md", Good, now.AddDate(0, 0, 1)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Record(ctx, "go/b.md", Again, now); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The schedule outlives the store.
+	if s, err = Open(tree); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	c, err := s.Card(ctx, "go/a.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Reps != 2 || c.Interval != 6 || !c.Due.Equal(time.Date(2024, 6, 8, 0, 0, 0, 0, time.Local)) {
+		t.Errorf("Card(go/a.md) = %+v", c)
+	}
+	var n int
+	if err := s.db.QueryRow(`SELECT count(*) FROM log`).Scan(&n); err != nil || n != 3 {
+		t.Errorf("%d reviews logged, %v", n, err)
+	}
+
+	paths := []string{"go/a.md", "go/b.md", "go/c.md", "go/d.md", "go/e.md"}
+	q, err := s.Queue(ctx, paths, now.AddDate(0, 0, 3), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(q.Due) != 1 || q.Due[0].Path != "go/b.md" || len(q.New) != 2 || q.New[0].Path != "go/c.md" || q.New[1].Path != "go/d.md" {
+		t.Errorf("Queue on day 3 = %+v", q)
+	}
+	if q, err = s.Queue(ctx, paths, now.AddDate(0, 0, 7), -1); err != nil {
+		t.Fatal(err)
+	}
+	if len(q.Due) != 2 || q.Due[0].Path != "go/b.md" || q.Due[1].Path != "go/a.md" || len(q.New) != 3 {
+		t.Errorf("Queue on day 7 = %+v", q)
+	}
+}
@@ -0,0 +1,76 @@
+package runner
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/canhta/til/go/internal/config"
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+// Command runs code blocks through external commands, as configured by a
+// config.Runner.
+type Command struct {
+	Spec config.Runner
+}
+
+// Run writes block to the spec's source file and runs the setup, build and
+// run commands in turn.
+func (c *Command) Run(ctx context.Context, block entry.CodeBlock, sb *Sandbox) *Result {
+	start := time.Now()
+	res := &Result{Block: block, Phase: PhaseSetup}
+	defer func() { res.Duration = time.Since(start) }()
+
+	w, err := newWorkdir(c.Spec.Timeout.Duration)
+	if err != nil {
+		res.Err = err
+		return res
+	}
+	defer w.Close()
+	file := c.Spec.File
+	if file == "" {
+		file = "snippet"
+	}
+	files := map[string][]byte{file: []byte(block.Code)}
+	for name, data := range c.Spec.Files {
+		files[name] = []byte(data)
+	}
+	if res.Err = w.write(files); res.Err != nil {
+		return res
+	}
+
+	steps := []struct {
+		phase Phase
+		argv  []string
+	}{
+		{PhaseSetup, c.Spec.Setup},
+		{PhaseBuild, c.Spec.Build},
+		{PhaseRun, c.Spec.Command},
+	}
+	for _, step := range steps {
+		if len(step.argv) == 0 && step.phase != PhaseRun {
+			continue
+		}
+		res.Phase = step.phase
+		argv := expand(step.argv, w.path(file), w.dir)
+		if step.phase == PhaseRun {
+			res.Output, res.Err = w.sandboxed(ctx, sb, nil, argv)
+		} else {
+			res.Output, res.Err = w.exec(ctx, nil, argv)
+		}
+		if res.Err != nil {
+			return res
+		}
+	}
+	return res
+}
+
+func expand(argv []string, file, dir string) []string {
+	r := strings.NewReplacer("{file}", file, "{dir}", dir)
+	out := make([]string, len(argv))
+	for i, a := range argv {
+		out[i] = r.Replace(a)
+	}
+	return out
+}
@@ -0,0 +1,148 @@
+package runner
+
+import (
+	"context"
+	"go/parser"
+	"go/token"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+// Go builds and runs Go code blocks in a throwaway module.
+type Go struct {
+	// Timeout bounds each of the build and run steps.
+	Timeout time.Duration
+}
+
+// Run builds and executes block.
+func (g *Go) Run(ctx context.Context, block entry.CodeBlock, sb *Sandbox) *Result {
+	return g.RunModule(ctx, block, "", sb)
+}
+
+// RunModule builds and executes block in a module declaring the
+// requirements of gomod, the content of a go.mod block, and of the
+// "// require path version" comments heading the block. gomod is used as
+// the go.mod file if it has a module directive; otherwise its directives
+// are added to the throwaway module's. When the program imports packages
+// outside the standard library, go mod tidy resolves them first, through
+// the user's module cache.
+func (g *Go) RunModule(ctx context.Context, block entry.CodeBlock, gomod string, sb *Sandbox) *Result {
+	start := time.Now()
+	res := &Result{Block: block, Phase: PhaseBuild}
+	defer func() { res.Duration = time.Since(start) }()
+
+	src, err := GoProgram(block.Code)
+	if err != nil {
+		res.Err = err
+		res.Output = []byte(err.Error() + "\n")
+		return res
+	}
+	w, err := newWorkdir(g.Timeout)
+	if err != nil {
+		res.Err = err
+		return res
+	}
+	defer w.Close()
+	mod, deps := goMod(block.Code, gomod)
+	if res.Err = w.write(map[string][]byte{
+		"go.mod":  mod,
+		"main.go": src,
+	}); res.Err != nil {
+		return res
+	}
+
+	env := []string{"GOWORK=off", "GOFLAGS=-mod=mod"}
+	if deps || importsModules(src) {
+		res.Phase = PhaseSetup
+		if res.Output, res.Err = w.exec(ctx, env, []string{"go", "mod", "tidy"}); res.Err != nil {
+			return res
+		}
+		res.Phase = PhaseBuild
+	}
+	bin := w.path("snippet")
+	if res.Output, res.Err = w.exec(ctx, env, []string{"go", "build", "-o", bin, "."}); res.Err != nil {
+		return res
+	}
+	res.Phase = PhaseRun
+	res.Output, res.Err = w.sandboxed(ctx, sb, env, []string{bin})
+	return res
+}
+
+// goMod returns the go.mod file of a snippet with the given code and go.mod
+// block, and whether it declares requirements.
+func goMod(code, gomod string) ([]byte, bool) {
+	mod := "module snippet\n\ngo 1.21\n"
+	for _, line := range strings.Split(gomod, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "module ") {
+			return []byte(gomod), true
+		}
+	}
+	deps := strings.TrimSpace(gomod) != ""
+	if deps {
+		mod += "\n" + strings.TrimSpace(gomod) + "\n"
+	}
+	for _, line := range strings.Split(code, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		req, ok := strings.CutPrefix(line, "// require ")
+		if !ok {
+			break
+		}
+		mod += "require " + strings.TrimSpace(req) + "\n"
+		deps = true
+	}
+	return []byte(mod), deps
+}
+
+// importsModules reports whether the Go source src imports a package
+// outside the standard library, whose path starts with a domain name.
+func importsModules(src []byte) bool {
+	f, err := parser.ParseFile(token.NewFileSet(), "main.go", src, parser.ImportsOnly)
+	if err != nil {
+		return false
+	}
+	for _, imp := range f.Imports {
+		p, _ := strconv.Unquote(imp.Path.Value)
+		if first, _, _ := strings.Cut(p, "/"); strings.Contains(first, ".") {
+			return true
+		}
+	}
+	return false
+}
+
+// Bench builds src, the source of a test file, in a throwaway module and
+// runs its benchmarks with memory statistics, passing args on to the test
+// binary. Only running the binary is confined to sb.
+func (g *Go) Bench(ctx context.Context, src []byte, args []string, sb *Sandbox) *Result {
+	start := time.Now()
+	res := &Result{Phase: PhaseBuild}
+	defer func() { res.Duration = time.Since(start) }()
+
+	w, err := newWorkdir(g.Timeout)
+	if err != nil {
+		res.Err = err
+		return res
+	}
+	defer w.Close()
+	if res.Err = w.write(map[string][]byte{
+		"go.mod":        []byte("module bench\n\ngo 1.24\n"),
+		"bench_test.go": src,
+	}); res.Err != nil {
+		return res
+	}
+
+	env := []string{"GOWORK=off", "GOFLAGS=-mod=mod"}
+	bin := w.path("bench.test")
+	if res.Output, res.Err = w.exec(ctx, env, []string{"go", "test", "-c", "-o", bin, "."}); res.Err != nil {
+		return res
+	}
+	res.Phase = PhaseRun
+	argv := append([]string{bin, "-test.run", "^$", "-test.bench", ".", "-test.benchmem"}, args...)
+	res.Output, res.Err = w.sandboxed(ctx, sb, env, argv)
+	return res
+}
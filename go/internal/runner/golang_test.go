@@ -0,0 +1,147 @@
+package runner
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+func TestGoProgram(t *testing.T) {
+	tests := []struct {
+		name, code string
+		want       []string
+	}{
+		{"statements", `fmt.Println("hi")`,
+			[]string{"package main\n", `import "fmt"`, "func main() {\n\tfmt.Println(\"hi\")\n}"}},
+		{"declarations", "func main() {\n\tfmt.Println(strings.ToUpper(\"hi\"))\n}",
+			[]string{"package main\n", `"fmt"`, `"strings"`}},
+		{"no main", "type T struct{}\n\nfunc (T) String() string { return \"t\" }",
+			[]string{"package main\n", "type T struct{}", "func main()"}},
+		{"package", "// Package x.\npackage x\n\nfunc F() {}",
+			[]string{"// Package x.\npackage x\n", "func F() {}"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GoProgram(tt.code)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, w := range tt.want {
+				if !strings.Contains(string(got), w) {
+					t.Errorf("GoProgram(%q) = %q, want it to contain %q", tt.code, got, w)
+				}
+			}
+		})
+	}
+	if got, err := GoProgram("type T struct{}\n\nfunc main() {}"); err != nil || strings.Count(string(got), "func main") != 1 {
+		t.Errorf("GoProgram of a program with main = %q, %v", got, err)
+	}
+	if _, err := GoProgram("func main() {"); err == nil {
+		t.Error("GoProgram accepted code that does not parse")
+	}
+}
+
+func TestGoMod(t *testing.T) {
+	tests := []struct {
+		name, code, gomod string
+		want              string
+		deps              bool
+	}{
+		{"stdlib", "fmt.Println()", "", "module snippet\n\ngo 1.21\n", false},
+		{"require comments", "// require github.com/google/uuid v1.6.0\n\n// require golang.org/x/text v0.14.0\nfmt.Println()\n// require not/this v1\n", "",
+			"module snippet\n\ngo 1.21\nrequire github.com/google/uuid v1.6.0\nrequire golang.org/x/text v0.14.0\n", true},
+		{"directives", "fmt.Println()", "require github.com/google/uuid v1.6.0\n",
+			"module snippet\n\ngo 1.21\n\nrequire github.com/google/uuid v1.6.0\n", true},
+		{"whole file", "fmt.Println()", "module example.com/m\n\ngo 1.22\n", "module example.com/m\n\ngo 1.22\n", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, deps := goMod(tt.code, tt.gomod)
+			if string(got) != tt.want || deps != tt.deps {
+				t.Errorf("goMod = %q, %v; want %q, %v", got, deps, tt.want, tt.deps)
+			}
+		})
+	}
+}
+
+func TestImportsModules(t *testing.T) {
+	for src, want := range map[string]bool{
+		"package main\n\nimport \"fmt\"\n":                                    false,
+		"package main\n\nimport (\n\t\"net/http\"\n\t\"os\"\n)\n":             false,
+		"package main\n\nimport \"github.com/google/uuid\"\n":                 true,
+		"package main\n\nimport (\n\t\"fmt\"\n\tx \"golang.org/x/text\"\n)\n": true,
+	} {
+		if got := importsModules([]byte(src)); got != want {
+			t.Errorf("importsModules(%q) = %v, want %v", src, got, want)
+		}
+	}
+}
+
+func TestGoRun(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds Go programs")
+	}
+	g := &Go{}
+	tests := []struct {
+		name, code string
+		phase      Phase
+		fails      bool
+		output     string
+	}{
+		{"runs", `fmt.Println("hello")`, PhaseRun, false, "hello\n"},
+		{"exit status", "fmt.Println(\"bye\")\nos.Exit(3)", PhaseRun, true, "bye\n"},
+		{"does not compile", "var x int = \"s\"\n_ = x", PhaseBuild, true, "cannot use"},
+		{"does not parse", "func main() {", PhaseBuild, true, "expected"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := g.Run(context.Background(), entry.CodeBlock{Lang: "go", Code: tt.code}, nil)
+			if res.Failed() != tt.fails || res.Phase != tt.phase {
+				t.Fatalf("Run: phase %s, err %v; want phase %s, failed %v\n%s", res.Phase, res.Err, tt.phase, tt.fails, res.Output)
+			}
+			if !strings.Contains(string(res.Output), tt.output) {
+				t.Errorf("output = %q, want %q", res.Output, tt.output)
+			}
+		})
+	}
+}
+
+func TestGoRunModule(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds Go programs")
+	}
+	// A module replaced by a local directory resolves without the network.
+	t.Setenv("GOPROXY", "off")
+	dir := t.TempDir()
+	for name, data := range map[string]string{
+		"go.mod":   "module example.com/greet\n\ngo 1.21\n",
+		"greet.go": "package greet\n\nfunc Hello() string { return \"hello from a module\" }\n",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	block := entry.CodeBlock{Lang: "go", Code: "package main\n\nimport (\n\t\"fmt\"\n\n\t\"example.com/greet\"\n)\n\nfunc main() { fmt.Println(greet.Hello()) }\n"}
+	res := (&Go{}).RunModule(context.Background(), block, "require example.com/greet v0.0.0\nreplace example.com/greet => "+dir+"\n", nil)
+	if res.Failed() || string(res.Output) != "hello from a module\n" {
+		t.Errorf("RunModule: phase %s, err %v\n%s", res.Phase, res.Err, res.Output)
+	}
+
+	// The requirement may also head the block, with the replace in go.mod.
+	headed := block
+	headed.Code = "// require example.com/greet v0.0.0\n" + block.Code
+	res = (&Go{}).RunModule(context.Background(), headed, "replace example.com/greet => "+dir+"\n", nil)
+	if res.Failed() || string(res.Output) != "hello from a module\n" {
+		t.Errorf("RunModule with a require comment: phase %s, err %v\n%s", res.Phase, res.Err, res.Output)
+	}
+
+	// Without the requirement, tidy fails to find the module.
+	res = (&Go{}).RunModule(context.Background(), block, "", nil)
+	if !res.Failed() || res.Phase != PhaseSetup {
+		t.Errorf("RunModule without the requirement: phase %s, err %v\n%s", res.Phase, res.Err, res.Output)
+	}
+}
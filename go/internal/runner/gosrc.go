@@ -0,0 +1,50 @@
+package runner
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strings"
+
+	"golang.org/x/tools/imports"
+)
+
+// GoProgram turns a Go snippet into a complete main package. Snippets that
+// already declare a package are used as is; snippets made of top-level
+// declarations get a package clause (and an empty main if they lack one);
+// anything else is treated as the body of main. Missing imports are added.
+func GoProgram(code string) ([]byte, error) {
+	src := code
+	if !hasPackageClause(code) {
+		file := "package main\n\n" + code
+		if f, err := parser.ParseFile(token.NewFileSet(), "main.go", file, 0); err == nil {
+			if !hasMain(f) {
+				file += "\nfunc main() {}\n"
+			}
+			src = file
+		} else {
+			src = "package main\n\nfunc main() {\n" + code + "\n}\n"
+		}
+	}
+	return imports.Process("main.go", []byte(src), &imports.Options{Comments: true, TabIndent: true, TabWidth: 8})
+}
+
+func hasMain(f *ast.File) bool {
+	for _, d := range f.Decls {
+		if fn, ok := d.(*ast.FuncDecl); ok && fn.Recv == nil && fn.Name.Name == "main" {
+			return true
+		}
+	}
+	return false
+}
+
+func hasPackageClause(code string) bool {
+	for _, line := range strings.Split(code, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "//") {
+			continue
+		}
+		return strings.HasPrefix(line, "package ")
+	}
+	return false
+}
@@ -0,0 +1,167 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/canhta/til/go/internal/config"
+	"github.com/canhta/til/go/internal/logging"
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+// builtin are the runners available without configuration. The Go runner
+// is not listed: it is always registered unless the config overrides "go"
+// with a command.
+var builtin = map[string]config.Runner{
+	"python": {
+		Aliases: []string{"py", "python3"},
+		File:    "main.py",
+		Command: []string{"python3", "{file}"},
+	},
+	"node": {
+		Aliases: []string{"js", "javascript"},
+		File:    "main.js",
+		Command: []string{"node", "{file}"},
+	},
+	"rust": {
+		Aliases: []string{"rs"},
+		File:    "main.rs",
+		Build:   []string{"rustc", "--edition", "2021", "-o", "{dir}/snippet", "{file}"},
+		Command: []string{"{dir}/snippet"},
+	},
+	"sh": {
+		Aliases: []string{"bash", "shell"},
+		File:    "main.sh",
+		Command: []string{"bash", "{file}"},
+	},
+}
+
+// Registry maps fence languages to runners.
+type Registry struct {
+	// Sandbox confines the code blocks run with Run; nil runs them all
+	// unconfined.
+	Sandbox *Sandbox
+	runners map[string]Runner
+	names   []string
+}
+
+// NewRegistry returns the builtin runners overlaid with the runners from
+// the config file. A configured runner replaces a builtin of the same name
+// field by field: unset fields keep their builtin values. A positive
+// timeout overrides every configured timeout.
+func NewRegistry(cfg map[string]config.Runner, timeout time.Duration) *Registry {
+	r := &Registry{runners: map[string]Runner{}}
+	goRunner := &Go{Timeout: timeout}
+	if c, ok := cfg["go"]; ok && timeout <= 0 {
+		goRunner.Timeout = c.Timeout.Duration
+	}
+	r.register("go", nil, goRunner)
+
+	specs := map[string]config.Runner{}
+	for name, spec := range builtin {
+		specs[name] = spec
+	}
+	for name, c := range cfg {
+		specs[name] = merge(specs[name], c)
+	}
+	for name, spec := range specs {
+		if len(spec.Command) == 0 {
+			// Nothing to run, e.g. a "go" entry that only sets a timeout.
+			continue
+		}
+		if timeout > 0 {
+			spec.Timeout.Duration = timeout
+		}
+		r.register(name, spec.Aliases, &Command{Spec: spec})
+	}
+	sort.Strings(r.names)
+	return r
+}
+
+func merge(base, over config.Runner) config.Runner {
+	if over.Aliases != nil {
+		base.Aliases = over.Aliases
+	}
+	if over.File != "" {
+		base.File = over.File
+	}
+	if over.Files != nil {
+		base.Files = over.Files
+	}
+	if over.Setup != nil {
+		base.Setup = over.Setup
+	}
+	if over.Build != nil {
+		base.Build = over.Build
+	}
+	if over.Command != nil {
+		base.Command = over.Command
+	}
+	if over.Timeout.Duration != 0 {
+		base.Timeout = over.Timeout
+	}
+	return base
+}
+
+func (r *Registry) register(name string, aliases []string, run Runner) {
+	if _, ok := r.runners[name]; !ok {
+		r.names = append(r.names, name)
+	}
+	r.runners[name] = run
+	for _, a := range aliases {
+		r.runners[a] = run
+	}
+}
+
+// Lookup returns the runner for the fence language lang.
+func (r *Registry) Lookup(lang string) (Runner, bool) {
+	run, ok := r.runners[lang]
+	return run, ok
+}
+
+// Run runs the code block of s, one of the snippets of e, with the runner
+// for its language, in the sandbox when it is enabled or e asks for it. A
+// Go block runs in a module with the requirements of s's go.mod block.
+func (r *Registry) Run(ctx context.Context, e *entry.Entry, s entry.Snippet) *Result {
+	block := s.Code
+	ctx, end := logging.Start(ctx, "run "+block.Lang, "path", e.Path, "line", e.FileLine(block.Line))
+	defer end()
+	res := r.run(ctx, e, s)
+	log := logging.From(ctx).With("path", e.Path, "line", e.FileLine(block.Line), "lang", block.Lang)
+	if res.Failed() {
+		log.Debug("block failed", "phase", res.Phase, "err", res.Err, "took", res.Duration)
+	} else {
+		log.Debug("block ran", "took", res.Duration)
+	}
+	return res
+}
+
+func (r *Registry) run(ctx context.Context, e *entry.Entry, s entry.Snippet) *Result {
+	block := s.Code
+	run, ok := r.runners[block.Lang]
+	if !ok {
+		return &Result{Block: block, Phase: PhaseSetup, Err: fmt.Errorf("no runner for %q", block.Lang)}
+	}
+	sb, err := r.Sandbox.For(e.Meta.Sandbox)
+	if err != nil {
+		return &Result{Block: block, Phase: PhaseSetup, Err: err}
+	}
+	if g, ok := run.(*Go); ok && s.Module != nil {
+		return g.RunModule(ctx, block, s.Module.Code, sb)
+	}
+	return run.Run(ctx, block, sb)
+}
+
+// Names returns the primary names of the registered runners.
+func (r *Registry) Names() []string {
+	return r.names
+}
+
+// Runnable reports whether block has a runner and is not marked {norun}
+// or, being a benchmark fragment for til bench, {bench}.
+func (r *Registry) Runnable(b entry.CodeBlock) bool {
+	_, ok := r.runners[b.Lang]
+	return ok && !b.Has("norun") && !b.Has("bench")
+}
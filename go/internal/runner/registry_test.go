@@ -0,0 +1,137 @@
+package runner
+
+import (
+	"context"
+	"os/exec"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/canhta/til/go/internal/config"
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+func TestNewRegistry(t *testing.T) {
+	r := NewRegistry(map[string]config.Runner{
+		"python": {Command: []string{"pypy3", "{file}"}},
+		"ruby":   {Aliases: []string{"rb"}, File: "main.rb", Command: []string{"ruby", "{file}"}, Timeout: config.Duration{Duration: time.Minute}},
+		"go":     {Timeout: config.Duration{Duration: 2 * time.Minute}},
+		"empty":  {File: "x"},
+	}, 0)
+	if want := []string{"go", "node", "python", "ruby", "rust", "sh"}; !slices.Equal(r.Names(), want) {
+		t.Errorf("Names() = %q, want %q", r.Names(), want)
+	}
+	py, ok := r.Lookup("py")
+	if !ok {
+		t.Fatal("no runner for py")
+	}
+	if got := py.(*Command).Spec; got.File != "main.py" || !slices.Equal(got.Command, []string{"pypy3", "{file}"}) || !slices.Contains(got.Aliases, "python3") {
+		t.Errorf("python = %+v, want the builtin with the configured command", got)
+	}
+	if rb, ok := r.Lookup("rb"); !ok || rb.(*Command).Spec.Timeout.Duration != time.Minute {
+		t.Errorf("rb = %v, %v", rb, ok)
+	}
+	if g, ok := r.Lookup("go"); !ok || g.(*Go).Timeout != 2*time.Minute {
+		t.Errorf("go = %+v, want the Go runner with the configured timeout", g)
+	}
+	if _, ok := r.Lookup("empty"); ok {
+		t.Error("registered a runner without a command")
+	}
+
+	r = NewRegistry(map[string]config.Runner{
+		"go":   {Timeout: config.Duration{Duration: time.Hour}},
+		"ruby": {Command: []string{"ruby"}, Timeout: config.Duration{Duration: time.Hour}},
+	}, time.Second)
+	if g, _ := r.Lookup("go"); g.(*Go).Timeout != time.Second {
+		t.Errorf("go timeout = %s, want the override", g.(*Go).Timeout)
+	}
+	if rb, _ := r.Lookup("ruby"); rb.(*Command).Spec.Timeout.Duration != time.Second {
+		t.Errorf("ruby timeout = %s, want the override", rb.(*Command).Spec.Timeout.Duration)
+	}
+}
+
+func TestNewRegistryReplacesGo(t *testing.T) {
+	r := NewRegistry(map[string]config.Runner{"go": {File: "main.go", Command: []string{"yaegi", "{file}"}}}, 0)
+	if g, _ := r.Lookup("go"); g == nil {
+		t.Fatal("no runner for go")
+	} else if _, ok := g.(*Command); !ok {
+		t.Errorf("go = %T, want the configured command", g)
+	}
+	if want := []string{"go", "node", "python", "rust", "sh"}; !slices.Equal(r.Names(), want) {
+		t.Errorf("Names() = %q, want %q", r.Names(), want)
+	}
+}
+
+func TestRunnable(t *testing.T) {
+	r := NewRegistry(nil, 0)
+	for _, tt := range []struct {
+		b    entry.CodeBlock
+		want bool
+	}{
+		{entry.CodeBlock{Lang: "go"}, true},
+		{entry.CodeBlock{Lang: "bash"}, true},
+		{entry.CodeBlock{Lang: "text"}, false},
+		{entry.CodeBlock{Lang: "go", Attrs: map[string]string{"norun": ""}}, false},
+		{entry.CodeBlock{Lang: "go", Attrs: map[string]string{"bench": ""}}, false},
+	} {
+		if got := r.Runnable(tt.b); got != tt.want {
+			t.Errorf("Runnable(%+v) = %v, want %v", tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestCommandRun(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not installed")
+	}
+	tests := []struct {
+		name  string
+		spec  config.Runner
+		code  string
+		phase Phase
+		fails bool
+		out   string
+	}{
+		{"run", config.Runner{File: "main.sh", Command: []string{"sh", "{file}"}}, "echo hi", PhaseRun, false, "hi\n"},
+		{"files and dir", config.Runner{
+			Files:   map[string]string{"lib/data.txt": "data\n"},
+			Setup:   []string{"sh", "-c", "cat lib/data.txt > setup.txt"},
+			Build:   []string{"cp", "{file}", "{dir}/built.sh"},
+			Command: []string{"sh", "{dir}/built.sh"},
+		}, "cat setup.txt", PhaseRun, false, "data\n"},
+		{"run fails", config.Runner{Command: []string{"sh", "{file}"}}, "echo out; exit 2", PhaseRun, true, "out\n"},
+		{"setup fails", config.Runner{Setup: []string{"false"}, Command: []string{"sh", "{file}"}}, "echo hi", PhaseSetup, true, ""},
+		{"build fails", config.Runner{Build: []string{"sh", "-c", "echo nope; exit 1"}, Command: []string{"sh", "{file}"}}, "echo hi", PhaseBuild, true, "nope\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := (&Command{Spec: tt.spec}).Run(context.Background(), entry.CodeBlock{Lang: "sh", Code: tt.code}, nil)
+			if res.Failed() != tt.fails || res.Phase != tt.phase || string(res.Output) != tt.out {
+				t.Errorf("Run = phase %s, err %v, output %q; want phase %s, failed %v, output %q",
+					res.Phase, res.Err, res.Output, tt.phase, tt.fails, tt.out)
+			}
+		})
+	}
+	res := (&Command{Spec: config.Runner{Command: []string{"sh", "{file}"}, Timeout: config.Duration{Duration: 100 * time.Millisecond}}}).
+		Run(context.Background(), entry.CodeBlock{Code: "sleep 5"}, nil)
+	if res.Err == nil || !strings.Contains(res.Err.Error(), "timed out after 100ms") || res.Duration > 3*time.Second {
+		t.Errorf("Run of a block outliving its timeout = %v after %s", res.Err, res.Duration)
+	}
+}
+
+func TestRegistryRun(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not installed")
+	}
+	r := NewRegistry(nil, 0)
+	e := &entry.Entry{Path: "sh/x.md"}
+	res := r.Run(context.Background(), e, entry.Snippet{Code: entry.CodeBlock{Lang: "shell", Code: "echo $((1+2))"}})
+	if res.Failed() || string(res.Output) != "3\n" {
+		t.Errorf("Run = %v, %q", res.Err, res.Output)
+	}
+	res = r.Run(context.Background(), e, entry.Snippet{Code: entry.CodeBlock{Lang: "cobol"}})
+	if !res.Failed() || res.Phase != PhaseSetup || !strings.Contains(res.Err.Error(), `no runner for "cobol"`) {
+		t.Errorf("Run of cobol = %s, %v", res.Phase, res.Err)
+	}
+}
@@ -0,0 +1,152 @@
+// Package runner executes the code blocks found in entries.
+//
+// Runners are looked up by fence language in a Registry. Go blocks are
+// handled by a dedicated runner that completes partial snippets into
+// programs; every other language runs through a configurable Command.
+package runner
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/canhta/til/go/internal/logging"
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+// DefaultTimeout bounds each build and run step when none is configured.
+const DefaultTimeout = 30 * time.Second
+
+// Phase identifies the step at which a snippet failed.
+type Phase string
+
+const (
+	PhaseSetup Phase = "setup"
+	PhaseBuild Phase = "build"
+	PhaseRun   Phase = "run"
+)
+
+// Result is the outcome of running one code block.
+type Result struct {
+	Block entry.CodeBlock
+	// Output holds the combined stdout and stderr of the failing or final
+	// step.
+	Output   []byte
+	Phase    Phase
+	Err      error
+	Duration time.Duration
+}
+
+// Failed reports whether the block did not build or exited unsuccessfully.
+func (r *Result) Failed() bool { return r.Err != nil }
+
+// Runner executes code blocks of one language. The run step is confined to
+// sb unless it is nil.
+type Runner interface {
+	Run(ctx context.Context, block entry.CodeBlock, sb *Sandbox) *Result
+}
+
+// workdir is a throwaway directory a snippet is built and run in.
+type workdir struct {
+	dir     string
+	timeout time.Duration
+}
+
+func newWorkdir(timeout time.Duration) (*workdir, error) {
+	dir, err := os.MkdirTemp("", "til-run-")
+	if err != nil {
+		return nil, err
+	}
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	return &workdir{dir: dir, timeout: timeout}, nil
+}
+
+func (w *workdir) Close() error {
+	return os.RemoveAll(w.dir)
+}
+
+func (w *workdir) path(name string) string {
+	return filepath.Join(w.dir, name)
+}
+
+func (w *workdir) write(files map[string][]byte) error {
+	for name, data := range files {
+		file := w.path(name)
+		if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(file, data, 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// exec runs argv in the working directory, returning its combined output.
+func (w *workdir) exec(ctx context.Context, env []string, argv []string) ([]byte, error) {
+	return w.run(ctx, env, argv, w.timeout)
+}
+
+// sandboxed runs argv as exec does, confined to sb unless it is nil.
+func (w *workdir) sandboxed(ctx context.Context, sb *Sandbox, env []string, argv []string) ([]byte, error) {
+	if sb == nil {
+		return w.exec(ctx, env, argv)
+	}
+	argv, err := sb.wrap(w.dir, argv)
+	if err != nil {
+		return nil, err
+	}
+	timeout := w.timeout
+	if sb.Timeout > 0 {
+		timeout = sb.Timeout
+	}
+	return w.run(ctx, env, argv, timeout)
+}
+
+func (w *workdir) run(ctx context.Context, env []string, argv []string, timeout time.Duration) ([]byte, error) {
+	if len(argv) == 0 {
+		return nil, errors.New("empty command")
+	}
+	ctx, end := logging.Start(ctx, stepName(argv))
+	defer end()
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	var out bytes.Buffer
+	cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
+	cmd.Dir = w.dir
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	// Children left holding the output open do not delay a timeout.
+	cmd.WaitDelay = time.Second
+	start := time.Now()
+	err := cmd.Run()
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		err = fmt.Errorf("timed out after %s", timeout)
+	}
+	log := logging.From(ctx)
+	if err != nil {
+		log.Debug("exec failed", "argv", argv, "err", err, "took", time.Since(start))
+	} else {
+		log.Debug("exec", "argv", argv, "took", time.Since(start))
+	}
+	return out.Bytes(), err
+}
+
+// stepName names the span of running argv: its program, followed by a
+// subcommand as in go build.
+func stepName(argv []string) string {
+	name := filepath.Base(argv[0])
+	if len(argv) > 1 && argv[1] != "" && !strings.HasPrefix(argv[1], "-") && !strings.ContainsAny(argv[1], "./") {
+		name += " " + argv[1]
+	}
+	return name
+}
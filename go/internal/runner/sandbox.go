@@ -0,0 +1,203 @@
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/canhta/til/go/internal/config"
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+// Sandbox confines the run step of code blocks: the program built from the
+// snippet, or the interpreter running it. Setup and build steps run the
+// configured toolchain and are left alone.
+//
+// Each backend runs the snippet in its throwaway working directory:
+//
+//   - bwrap (bubblewrap) mounts the file system read-only but for the
+//     working directory and a fresh /tmp, in new namespaces;
+//   - unshare runs it in new user and process namespaces and, unless the
+//     network is allowed, a network namespace;
+//   - sandbox-exec, on macOS, denies writes outside the working directory
+//     and, unless allowed, the network;
+//   - docker and podman run it in a container of the configured image;
+//   - none applies the limits only, and cannot take the network away.
+//
+// Memory is capped with a container's memory limit or, on the other
+// backends, the data size limit of ulimit -d.
+type Sandbox struct {
+	// Enabled confines every code block; otherwise only those of entries
+	// asking for it are.
+	Enabled bool
+	Backend string
+	Image   string
+	// Memory is in bytes; zero leaves memory unlimited.
+	Memory  int64
+	Network bool
+	// Timeout, if positive, bounds the run step instead of the runner's
+	// timeout.
+	Timeout time.Duration
+}
+
+// Backends are the names of the sandbox backends.
+var Backends = []string{"bwrap", "unshare", "sandbox-exec", "docker", "podman", "none"}
+
+// NewSandbox returns the sandbox the config describes.
+func NewSandbox(cfg config.Sandbox) (*Sandbox, error) {
+	s := &Sandbox{
+		Enabled: cfg.Enabled,
+		Backend: cfg.Backend,
+		Image:   cfg.Image,
+		Network: cfg.Network,
+		Timeout: cfg.Timeout.Duration,
+	}
+	if s.Backend != "" && s.Backend != "auto" && !slices.Contains(Backends, s.Backend) {
+		return nil, fmt.Errorf("sandbox: unknown backend %q (want one of %s)", s.Backend, strings.Join(Backends, ", "))
+	}
+	if cfg.Memory != "" {
+		var err error
+		if s.Memory, err = ParseSize(cfg.Memory); err != nil {
+			return nil, fmt.Errorf("sandbox: memory: %w", err)
+		}
+	}
+	return s, nil
+}
+
+// For returns the sandbox the code blocks of an entry with frontmatter m
+// run in, nil when they run unconfined. An entry's limits only tighten the
+// configured ones, the snippets it runs being the ones not trusted: its
+// timeout and memory cap apply when lower or none is configured, and it
+// can take the network away but not grant it. Nor can it turn an enabled
+// sandbox off.
+func (s *Sandbox) For(m entry.Sandbox) (*Sandbox, error) {
+	if s == nil || !s.Enabled && !m.On {
+		return nil, nil
+	}
+	sb := *s
+	if m.Timeout > 0 && (sb.Timeout <= 0 || m.Timeout < sb.Timeout) {
+		sb.Timeout = m.Timeout
+	}
+	if m.Memory != "" {
+		mem, err := ParseSize(m.Memory)
+		if err != nil {
+			return nil, fmt.Errorf("sandbox: memory: %w", err)
+		}
+		if mem > 0 && (sb.Memory <= 0 || mem < sb.Memory) {
+			sb.Memory = mem
+		}
+	}
+	if m.Network != nil {
+		sb.Network = sb.Network && *m.Network
+	}
+	return &sb, nil
+}
+
+// wrap returns the command line running argv in the sandbox, in dir.
+func (s *Sandbox) wrap(dir string, argv []string) ([]string, error) {
+	backend, err := s.backend()
+	if err != nil {
+		return nil, err
+	}
+	if s.Memory > 0 && backend != "docker" && backend != "podman" {
+		argv = append([]string{"sh", "-c", fmt.Sprintf(`ulimit -d %d && exec "$@"`, s.Memory/1024), "sh"}, argv...)
+	}
+	switch backend {
+	case "bwrap":
+		args := []string{"bwrap", "--ro-bind", "/", "/", "--dev", "/dev", "--proc", "/proc", "--tmpfs", "/tmp",
+			"--bind", dir, dir, "--chdir", dir, "--setenv", "HOME", dir, "--setenv", "TMPDIR", "/tmp",
+			"--unshare-all", "--die-with-parent", "--new-session"}
+		if s.Network {
+			args = append(args, "--share-net")
+		}
+		return append(append(args, "--"), argv...), nil
+	case "unshare":
+		args := []string{"unshare", "--user", "--map-root-user", "--pid", "--fork", "--kill-child"}
+		if !s.Network {
+			args = append(args, "--net")
+		}
+		return append(append(args, "--"), argv...), nil
+	case "sandbox-exec":
+		real, err := filepath.EvalSymlinks(dir)
+		if err != nil {
+			return nil, err
+		}
+		profile := fmt.Sprintf(`(version 1)(allow default)(deny file-write*)`+
+			`(allow file-write* (subpath %q) (literal "/dev/null") (literal "/dev/stdout") (literal "/dev/stderr") (literal "/dev/tty"))`, real)
+		if !s.Network {
+			profile += "(deny network*)"
+		}
+		return append([]string{"sandbox-exec", "-p", profile}, argv...), nil
+	case "docker", "podman":
+		if s.Image == "" {
+			return nil, fmt.Errorf("sandbox: the %s backend needs [sandbox] image", backend)
+		}
+		args := []string{backend, "run", "--rm", "-i", "-v", dir + ":" + dir, "-w", dir, "-e", "HOME=" + dir}
+		if !s.Network {
+			args = append(args, "--network", "none")
+		}
+		if s.Memory > 0 {
+			args = append(args, "--memory", strconv.FormatInt(s.Memory, 10))
+		}
+		if runtime.GOOS != "windows" {
+			args = append(args, "--user", fmt.Sprintf("%d:%d", os.Getuid(), os.Getgid()))
+		}
+		return append(append(args, s.Image), argv...), nil
+	}
+	if !s.Network {
+		return nil, errors.New("sandbox: no backend to take the network away; install bubblewrap, set [sandbox] backend, or allow the network")
+	}
+	return argv, nil
+}
+
+// backend returns the backend to use: the configured one if installed, or
+// the first installed of those suitable for the system.
+func (s *Sandbox) backend() (string, error) {
+	if s.Backend != "" && s.Backend != "auto" {
+		if s.Backend != "none" {
+			if _, err := exec.LookPath(s.Backend); err != nil {
+				return "", fmt.Errorf("sandbox: %w", err)
+			}
+		}
+		return s.Backend, nil
+	}
+	candidates := []string{"bwrap", "unshare"}
+	if runtime.GOOS == "darwin" {
+		candidates = []string{"sandbox-exec"}
+	}
+	for _, c := range candidates {
+		if _, err := exec.LookPath(c); err == nil {
+			return c, nil
+		}
+	}
+	return "none", nil
+}
+
+// ParseSize parses a size in bytes with an optional K, M or G suffix, in
+// powers of 1024, such as "512M".
+func ParseSize(s string) (int64, error) {
+	t := strings.TrimSuffix(strings.ToUpper(strings.TrimSpace(s)), "B")
+	mul := int64(1)
+	if i := len(t) - 1; i >= 0 {
+		switch t[i] {
+		case 'K':
+			mul, t = 1<<10, t[:i]
+		case 'M':
+			mul, t = 1<<20, t[:i]
+		case 'G':
+			mul, t = 1<<30, t[:i]
+		}
+	}
+	n, err := strconv.ParseInt(strings.TrimSpace(t), 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return n * mul, nil
+}
@@ -0,0 +1,73 @@
+package runner
+
+import (
+	"os/exec"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+func TestSandboxForOnlyTightens(t *testing.T) {
+	yes, no := true, false
+	cfg := &Sandbox{Enabled: true, Backend: "none", Memory: 256 << 20, Timeout: 10 * time.Second}
+	tests := []struct {
+		name string
+		cfg  *Sandbox
+		m    entry.Sandbox
+		want Sandbox
+	}{
+		{"config", cfg, entry.Sandbox{}, *cfg},
+		{"looser", cfg, entry.Sandbox{Timeout: 100 * time.Hour, Memory: "100G", Network: &yes}, *cfg},
+		{"tighter", cfg, entry.Sandbox{Timeout: time.Second, Memory: "64M"},
+			Sandbox{Enabled: true, Backend: "none", Memory: 64 << 20, Timeout: time.Second}},
+		{"unlimited", cfg, entry.Sandbox{Memory: "0"}, *cfg},
+		{"network off", &Sandbox{Enabled: true, Network: true}, entry.Sandbox{Network: &no}, Sandbox{Enabled: true}},
+		{"network kept", &Sandbox{Enabled: true, Network: true}, entry.Sandbox{Network: &yes}, Sandbox{Enabled: true, Network: true}},
+		{"asked for", &Sandbox{}, entry.Sandbox{On: true, Timeout: time.Minute, Memory: "1G", Network: &yes},
+			Sandbox{Memory: 1 << 30, Timeout: time.Minute}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.cfg.For(tt.m)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got == nil || *got != tt.want {
+				t.Errorf("For(%+v) = %+v, want %+v", tt.m, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSandboxForOff(t *testing.T) {
+	for _, s := range []*Sandbox{nil, {}} {
+		if got, err := s.For(entry.Sandbox{}); got != nil || err != nil {
+			t.Errorf("For on %+v = %+v, %v; want nil", s, got, err)
+		}
+	}
+	if _, err := (&Sandbox{Enabled: true}).For(entry.Sandbox{Memory: "lots"}); err == nil {
+		t.Error("For accepted a bad memory size")
+	}
+}
+
+func TestSandboxWrapUnshare(t *testing.T) {
+	if _, err := exec.LookPath("unshare"); err != nil {
+		t.Skip("unshare not installed")
+	}
+	for _, network := range []bool{false, true} {
+		s := &Sandbox{Enabled: true, Backend: "unshare", Network: network}
+		got, err := s.wrap(t.TempDir(), []string{"./prog"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []string{"unshare", "--user", "--map-root-user", "--pid", "--fork", "--kill-child", "--net", "--", "./prog"}
+		if network {
+			want = slices.Delete(want, 6, 7)
+		}
+		if !slices.Equal(got, want) {
+			t.Errorf("network %v: wrap = %q, want %q", network, got, want)
+		}
+	}
+}
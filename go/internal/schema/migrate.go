@@ -0,0 +1,133 @@
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/canhta/til/go/internal/config"
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+func validMigration(m config.Migration) error {
+	if m.Field == "" {
+		return errors.New("no field")
+	}
+	n := 0
+	for _, set := range []bool{m.Rename != "", m.Values != nil, m.Delete, m.Default != nil} {
+		if set {
+			n++
+		}
+	}
+	if n != 1 {
+		return fmt.Errorf("field %s: set one of rename, values, delete and default", m.Field)
+	}
+	return nil
+}
+
+// Migrate applies ms in order to the frontmatter of the entry file data,
+// returning the new content and a description of each change made. data
+// is returned as is when no migration changed it.
+func Migrate(data []byte, ms []config.Migration) ([]byte, []string, error) {
+	var done []string
+	out, err := entry.Rewrite(data, func(f *entry.Front) error {
+		for _, m := range ms {
+			msg, err := apply(f, m)
+			if err != nil {
+				return err
+			}
+			if msg != "" {
+				done = append(done, msg)
+			}
+		}
+		return nil
+	})
+	if err != nil || len(done) == 0 {
+		return data, nil, err
+	}
+	return out, done, nil
+}
+
+// apply applies m to f, describing the change, or returning "" when f
+// needs none.
+func apply(f *entry.Front, m config.Migration) (string, error) {
+	if err := validMigration(m); err != nil {
+		return "", err
+	}
+	key := m.Field
+	if m.Default != nil {
+		if f.Has(key) {
+			return "", nil
+		}
+		v := m.Default
+		// TOML arrays decode as []any.
+		if l, ok := v.([]any); ok {
+			s := make([]string, len(l))
+			for i, it := range l {
+				s[i] = fmt.Sprint(it)
+			}
+			v = s
+		}
+		if err := f.Set(key, v); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("set %s to %v", key, m.Default), nil
+	}
+	if !f.Has(key) {
+		return "", nil
+	}
+	switch {
+	case m.Rename != "":
+		if f.Has(m.Rename) {
+			return "", fmt.Errorf("cannot rename %s: %s is already set", key, m.Rename)
+		}
+		if _, err := f.Rename(key, m.Rename); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("renamed %s to %s", key, m.Rename), nil
+	case m.Delete:
+		if _, err := f.Delete(key); err != nil {
+			return "", err
+		}
+		return "deleted " + key, nil
+	}
+	var n yaml.Node
+	if _, err := f.Get(key, &n); err != nil {
+		return "", err
+	}
+	switch n.Kind {
+	case yaml.ScalarNode:
+		to, ok := m.Values[n.Value]
+		if !ok || to == n.Value {
+			return "", nil
+		}
+		if err := f.Set(key, to); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("changed %s from %s to %s", key, n.Value, to), nil
+	case yaml.SequenceNode:
+		var old, next []string
+		changed := false
+		for _, it := range n.Content {
+			if it.Kind != yaml.ScalarNode {
+				return "", nil
+			}
+			v := it.Value
+			old = append(old, v)
+			if to, ok := m.Values[v]; ok && to != v {
+				v, changed = to, true
+			}
+			next = append(next, v)
+		}
+		if !changed {
+			return "", nil
+		}
+		if err := f.Set(key, next); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("changed %s from [%s] to [%s]", key, strings.Join(old, ", "), strings.Join(next, ", ")), nil
+	}
+	return "", nil
+}
@@ -0,0 +1,79 @@
+package schema
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/canhta/til/go/internal/config"
+)
+
+func TestMigrate(t *testing.T) {
+	ms := []config.Migration{
+		{Field: "level", Rename: "difficulty"},
+		{Field: "difficulty", Values: map[string]string{"beginner": "easy", "advanced": "hard"}},
+		{Field: "tags", Values: map[string]string{"golang": "go"}},
+		{Field: "legacy", Delete: true},
+		{Field: "topics", Default: []any{"memory", 2}},
+		{Field: "reviewed", Default: false},
+	}
+	tests := []struct {
+		name, in, want string
+		done           []string
+	}{
+		{
+			"all",
+			"---\ntitle: Slices\nlevel: beginner # was skill\ntags: [golang, slices]\nlegacy: 1\n---\n\nBody.\n",
+			"---\ntitle: Slices\ndifficulty: easy # was skill\ntags: [go, slices]\ntopics: [memory, \"2\"]\nreviewed: false\n---\n\nBody.\n",
+			[]string{"renamed level to difficulty", "changed difficulty from beginner to easy", "changed tags from [golang, slices] to [go, slices]", "deleted legacy", "set topics to [memory 2]", "set reviewed to false"},
+		},
+		{
+			"migrated already",
+			"---\ntitle: Slices\ndifficulty: easy\ntags: [go]\ntopics: []\nreviewed: true\n---\n\nBody.\n",
+			"",
+			nil,
+		},
+		{
+			"unknown values kept",
+			"---\ntitle: Slices\ndifficulty: medium\ntags:\n  - golang\n  - rust\ntopics: [perf]\nreviewed: true\n---\n",
+			"---\ntitle: Slices\ndifficulty: medium\ntags:\n  - go\n  - rust\ntopics: [perf]\nreviewed: true\n---\n",
+			[]string{"changed tags from [golang, rust] to [go, rust]"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, done, err := Migrate([]byte(tt.in), ms)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := tt.want
+			if want == "" {
+				want = tt.in
+			}
+			if string(got) != want {
+				t.Errorf("Migrate =\n%s\nwant\n%s", got, want)
+			}
+			if !slices.Equal(done, tt.done) {
+				t.Errorf("changes = %q, want %q", done, tt.done)
+			}
+		})
+	}
+}
+
+func TestMigrateErrors(t *testing.T) {
+	in := "---\ntitle: Slices\nlevel: easy\ndifficulty: hard\n---\n"
+	if _, _, err := Migrate([]byte(in), []config.Migration{{Field: "level", Rename: "difficulty"}}); err == nil || err.Error() != "cannot rename level: difficulty is already set" {
+		t.Errorf("rename onto a set field = %v", err)
+	}
+	if _, _, err := Migrate([]byte(in), []config.Migration{{Field: "level"}}); err == nil {
+		t.Error("migration doing nothing accepted")
+	}
+}
+
+// TestMigrateNoFrontmatter checks that entries without frontmatter get it
+// from a default.
+func TestMigrateNoFrontmatter(t *testing.T) {
+	got, done, err := Migrate([]byte("# Slices\n"), []config.Migration{{Field: "difficulty", Default: "easy"}})
+	if err != nil || string(got) != "---\ndifficulty: easy\n---\n\n# Slices\n" || len(done) != 1 {
+		t.Errorf("Migrate without frontmatter = %q, %q, %v", got, done, err)
+	}
+}
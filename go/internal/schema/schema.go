@@ -0,0 +1,229 @@
+// Package schema checks the frontmatter of entries against the schema of
+// the config file, and migrates entries when the schema changes.
+//
+// A schema lists the fields entries must set, the categories and tags they
+// may use, and the type and allowed values of the fields it declares. til's
+// own fields, such as title and tags, have their types without being
+// declared; a closed schema rejects every other field.
+package schema
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/canhta/til/go/internal/config"
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+// Field types.
+const (
+	String = "string"
+	Int    = "int"
+	Number = "number"
+	Bool   = "bool"
+	Date   = "date"
+	List   = "list"
+)
+
+// Builtin are the types of the fields til reads, by name. Those with the
+// empty type take values of several shapes.
+var Builtin = map[string]string{
+	"title":          String,
+	"date":           Date,
+	"updated":        Date,
+	"published":      Date,
+	"category":       String,
+	"slug":           String,
+	"tags":           List,
+	"author":         String,
+	"lang":           String,
+	"translation_of": String,
+	"draft":          Bool,
+	"private":        Bool,
+	"archived":       Bool,
+	"read":           Bool,
+	"source":         String,
+	"imported":       String,
+	"sandbox":        "",
+	"crosspost":      "",
+	"gist":           "",
+}
+
+// Problem is a way an entry breaks the schema.
+type Problem struct {
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+	// Unset is true for a required field that is missing or empty.
+	Unset bool `json:"unset,omitempty"`
+}
+
+func (p Problem) String() string { return p.Message }
+
+// Empty reports whether s checks nothing.
+func Empty(s config.Schema) bool {
+	return len(s.Required) == 0 && len(s.Categories) == 0 && len(s.Tags) == 0 && len(s.Fields) == 0 && !s.Closed
+}
+
+// Check returns the problems of e under s.
+func Check(s config.Schema, e *entry.Entry) []Problem {
+	f, err := entry.ParseFront(e.Front)
+	if err != nil {
+		return []Problem{{Message: err.Error()}}
+	}
+	var out []Problem
+	for _, key := range s.Required {
+		var n yaml.Node
+		ok, err := f.Get(key, &n)
+		switch {
+		case err != nil:
+			out = append(out, Problem{Field: key, Message: fmt.Sprintf("%s: %v", key, err)})
+		case !ok:
+			out = append(out, Problem{Field: key, Message: "missing " + key, Unset: true})
+		case empty(&n):
+			out = append(out, Problem{Field: key, Message: key + " is empty", Unset: true})
+		}
+	}
+	if len(s.Categories) > 0 && !slices.Contains(s.Categories, e.Meta.Category) {
+		out = append(out, Problem{Field: "category", Message: fmt.Sprintf("category %q is not one of %s", e.Meta.Category, strings.Join(s.Categories, ", "))})
+	}
+	if len(s.Tags) > 0 {
+		var unknown []string
+		for _, t := range e.Meta.Tags {
+			if !slices.Contains(s.Tags, t) {
+				unknown = append(unknown, t)
+			}
+		}
+		if len(unknown) > 0 {
+			out = append(out, Problem{Field: "tags", Message: "tags not in the schema: " + strings.Join(unknown, ", ")})
+		}
+	}
+	for _, key := range f.Keys() {
+		decl, declared := s.Fields[key]
+		if !declared {
+			typ, ok := Builtin[key]
+			if !ok {
+				if s.Closed {
+					out = append(out, Problem{Field: key, Message: "unknown field " + key})
+				}
+				continue
+			}
+			decl.Type = typ
+		}
+		var n yaml.Node
+		if _, err := f.Get(key, &n); err != nil {
+			out = append(out, Problem{Field: key, Message: fmt.Sprintf("%s: %v", key, err)})
+			continue
+		}
+		if empty(&n) {
+			continue
+		}
+		if msg := checkValue(decl, &n); msg != "" {
+			out = append(out, Problem{Field: key, Message: key + " " + msg})
+		}
+	}
+	return out
+}
+
+// checkValue returns how n fails the declaration, or "".
+func checkValue(decl config.Field, n *yaml.Node) string {
+	var items []*yaml.Node
+	switch decl.Type {
+	case "":
+		if n.Kind == yaml.ScalarNode {
+			items = []*yaml.Node{n}
+		}
+	case List:
+		if n.Kind != yaml.SequenceNode {
+			return "is not a list"
+		}
+		for _, it := range n.Content {
+			if it.Kind != yaml.ScalarNode {
+				return "has an item that is not a single value"
+			}
+		}
+		items = n.Content
+	default:
+		if n.Kind != yaml.ScalarNode {
+			return "is not " + article(decl.Type)
+		}
+		if !scalarIs(decl.Type, n) {
+			return fmt.Sprintf("is not %s: %s", article(decl.Type), n.Value)
+		}
+		items = []*yaml.Node{n}
+	}
+	if len(decl.Values) == 0 {
+		return ""
+	}
+	for _, it := range items {
+		if !slices.Contains(decl.Values, it.Value) {
+			return fmt.Sprintf("%q is not one of %s", it.Value, strings.Join(decl.Values, ", "))
+		}
+	}
+	return ""
+}
+
+func scalarIs(typ string, n *yaml.Node) bool {
+	switch tag := n.ShortTag(); typ {
+	case Int:
+		return tag == "!!int"
+	case Number:
+		return tag == "!!int" || tag == "!!float"
+	case Bool:
+		return tag == "!!bool"
+	case Date:
+		if tag == "!!timestamp" {
+			return true
+		}
+		for _, layout := range []string{entry.DateLayout, time.RFC3339} {
+			if _, err := time.Parse(layout, n.Value); err == nil {
+				return true
+			}
+		}
+		return false
+	}
+	// Any single value reads as a string.
+	return true
+}
+
+func article(typ string) string {
+	switch typ {
+	case Int:
+		return "an integer"
+	case List:
+		return "a list"
+	}
+	return "a " + typ
+}
+
+// empty reports whether n is null, an empty string or an empty list or
+// mapping.
+func empty(n *yaml.Node) bool {
+	switch n.Kind {
+	case yaml.ScalarNode:
+		return n.ShortTag() == "!!null" || n.Value == ""
+	case yaml.SequenceNode, yaml.MappingNode:
+		return len(n.Content) == 0
+	}
+	return false
+}
+
+// Valid reports fields of unknown types and malformed migrations.
+func Valid(s config.Schema) error {
+	for name, f := range s.Fields {
+		switch f.Type {
+		case "", String, Int, Number, Bool, Date, List:
+		default:
+			return fmt.Errorf("schema: field %s: unknown type %q", name, f.Type)
+		}
+	}
+	for i, m := range s.Migrations {
+		if err := validMigration(m); err != nil {
+			return fmt.Errorf("schema: migration %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package schema
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/canhta/til/go/internal/config"
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+var testSchema = config.Schema{
+	Required:   []string{"title", "tags", "difficulty"},
+	Categories: []string{"go", "git"},
+	Tags:       []string{"go", "git", "slices"},
+	Fields: map[string]config.Field{
+		"difficulty": {Type: String, Values: []string{"easy", "hard"}},
+		"minutes":    {Type: Int},
+		"score":      {Type: Number},
+		"reviewed":   {Type: Date},
+		"topics":     {Type: List, Values: []string{"memory", "perf"}},
+		"any":        {},
+	},
+}
+
+func parse(t *testing.T, p, data string) *entry.Entry {
+	t.Helper()
+	e, err := entry.Parse(p, []byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+func messages(ps []Problem) []string {
+	var out []string
+	for _, p := range ps {
+		s := p.Field + ": " + p.Message
+		if p.Unset {
+			s += " (unset)"
+		}
+		out = append(out, s)
+	}
+	return out
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name, path, front string
+		schema            config.Schema
+		want              []string
+	}{
+		{"valid", "go/slices.md", "title: Slices\ntags: [go, slices]\ndifficulty: easy\nminutes: 5\nscore: 4.5\nreviewed: 2024-06-01\ntopics: [memory]\nany: [1, {a: b}]\nextra: yes", testSchema, nil},
+		{"unset", "go/slices.md", "title: \"\"\ntags: []\ndate: 2024-06-01", testSchema, []string{
+			"title: title is empty (unset)", "tags: tags is empty (unset)", "difficulty: missing difficulty (unset)",
+		}},
+		{"not allowed", "rust/own.md", "title: Own\ntags: [rust, go, borrow]\ndifficulty: medium\ntopics: [memory, style]", testSchema, []string{
+			`category: category "rust" is not one of go, git`,
+			"tags: tags not in the schema: rust, borrow",
+			`difficulty: difficulty "medium" is not one of easy, hard`,
+			`topics: topics "style" is not one of memory, perf`,
+		}},
+		// Fields are checked whatever else they are; an empty list is a
+		// list with no values to check.
+		{"empty values", "go/slices.md", "topics: []\nminutes: ~", config.Schema{Fields: testSchema.Fields}, nil},
+		{"closed", "go/slices.md", "title: Slices\nsandbox: {image: golang}\nnotes: x\nany: 1", config.Schema{Closed: true, Fields: map[string]config.Field{"any": {}}}, []string{
+			"notes: unknown field notes",
+		}},
+		{"open", "go/slices.md", "notes: x\nminutes: five", config.Schema{}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := parse(t, tt.path, "---\n"+tt.front+"\n---\n\nBody.\n")
+			if got := messages(Check(tt.schema, e)); !slices.Equal(got, tt.want) {
+				t.Errorf("Check =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
+
+// TestCheckTypes checks frontmatter of the wrong types, which for til's own
+// fields does not parse into an entry but is checked all the same.
+func TestCheckTypes(t *testing.T) {
+	front := "title: [a, b]\ntags: go\ndifficulty: easy\nminutes: 2.5\nscore: many\nreviewed: June\ntopics: [[memory]]\ndraft: maybe\ndate: 2024-06-01T10:00:00Z\n"
+	e := &entry.Entry{Path: "go/slices.md", Front: []byte(front), Meta: entry.Meta{Category: "go"}}
+	want := []string{
+		"title: title is not a string",
+		"tags: tags is not a list",
+		"minutes: minutes is not an integer: 2.5",
+		"score: score is not a number: many",
+		"reviewed: reviewed is not a date: June",
+		"topics: topics has an item that is not a single value",
+		"draft: draft is not a bool: maybe",
+	}
+	if got := messages(Check(testSchema, e)); !slices.Equal(got, want) {
+		t.Errorf("Check =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestEmpty(t *testing.T) {
+	if !Empty(config.Schema{Migrations: []config.Migration{{Field: "a", Delete: true}}}) {
+		t.Error("schema with only migrations is not empty")
+	}
+	for _, s := range []config.Schema{{Closed: true}, {Tags: []string{"go"}}, {Required: []string{"date"}}, {Fields: testSchema.Fields}} {
+		if Empty(s) {
+			t.Errorf("Empty(%+v) = true", s)
+		}
+	}
+}
+
+func TestValid(t *testing.T) {
+	if err := Valid(testSchema); err != nil {
+		t.Errorf("Valid = %v", err)
+	}
+	tests := []struct {
+		schema config.Schema
+		want   string
+	}{
+		{config.Schema{Fields: map[string]config.Field{"level": {Type: "enum"}}}, `schema: field level: unknown type "enum"`},
+		{config.Schema{Migrations: []config.Migration{{Field: "a", Delete: true}, {Rename: "b"}}}, "schema: migration 2: no field"},
+		{config.Schema{Migrations: []config.Migration{{Field: "a"}}}, "schema: migration 1: field a: set one of rename, values, delete and default"},
+		{config.Schema{Migrations: []config.Migration{{Field: "a", Rename: "b", Delete: true}}}, "schema: migration 1: field a: set one of rename, values, delete and default"},
+	}
+	for _, tt := range tests {
+		if err := Valid(tt.schema); err == nil || err.Error() != tt.want {
+			t.Errorf("Valid(%+v) = %v, want %s", tt.schema, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,444 @@
+// Package search maintains a SQLite FTS5 index over the entries of a notes
+// tree.
+//
+// The index lives in the tree's state directory and is refreshed
+// incrementally: only files whose size or modification time changed since
+// the last sync are re-parsed. Each bullet of a journal file is indexed as
+// a document of its own, found by its words, while the file itself is only
+// found by its title and tags.
+package search
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	_ "modernc.org/sqlite" // database/sql driver
+
+	"github.com/canhta/til/go/internal/journal"
+	"github.com/canhta/til/go/internal/notes"
+	"github.com/canhta/til/go/internal/query"
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+// File is the index file name inside the notes state directory.
+const File = "index.db"
+
+const schema = `
+CREATE TABLE IF NOT EXISTS docs (
+	path  TEXT PRIMARY KEY,
+	mtime INTEGER NOT NULL,
+	size  INTEGER NOT NULL
+);
+CREATE VIRTUAL TABLE IF NOT EXISTS fts USING fts5(
+	path UNINDEXED,
+	title,
+	body,
+	tags,
+	tokenize = 'porter unicode61'
+);
+CREATE TABLE IF NOT EXISTS items (
+	id    INTEGER PRIMARY KEY,
+	line  INTEGER NOT NULL,
+	title TEXT NOT NULL
+);
+`
+
+// Index is an open search index.
+type Index struct {
+	db   *sql.DB
+	tree *notes.Tree
+}
+
+// Open opens (creating if needed) the search index of tree.
+func Open(tree *notes.Tree) (*Index, error) {
+	file := tree.StatePath(File)
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return nil, err
+	}
+	db, err := sql.Open("sqlite", "file:"+file+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("search index: %w", err)
+	}
+	return &Index{db: db, tree: tree}, nil
+}
+
+// Close closes the index.
+func (ix *Index) Close() error {
+	return ix.db.Close()
+}
+
+// SyncStats reports what a Sync changed.
+type SyncStats struct {
+	Added, Updated, Removed int
+}
+
+// Sync brings the index up to date with the files in the tree.
+func (ix *Index) Sync(ctx context.Context) (SyncStats, error) {
+	var st SyncStats
+	known := map[string][2]int64{}
+	rows, err := ix.db.QueryContext(ctx, `SELECT path, mtime, size FROM docs`)
+	if err != nil {
+		return st, err
+	}
+	for rows.Next() {
+		var p string
+		var mtime, size int64
+		if err := rows.Scan(&p, &mtime, &size); err != nil {
+			rows.Close()
+			return st, err
+		}
+		known[p] = [2]int64{mtime, size}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return st, err
+	}
+
+	files, err := ix.tree.EntryFiles()
+	if err != nil {
+		return st, err
+	}
+	tx, err := ix.db.BeginTx(ctx, nil)
+	if err != nil {
+		return st, err
+	}
+	defer tx.Rollback()
+
+	for _, f := range files {
+		p := f.Path
+		stamp := [2]int64{f.ModTime.UnixNano(), f.Size}
+		prev, ok := known[p]
+		delete(known, p)
+		if ok && prev == stamp {
+			continue
+		}
+		e, err := ix.tree.Load(p)
+		if err != nil {
+			return st, err
+		}
+		if ok {
+			st.Updated++
+			if err := remove(ctx, tx, p); err != nil {
+				return st, err
+			}
+		} else {
+			st.Added++
+		}
+		if err := insert(ctx, tx, e); err != nil {
+			return st, err
+		}
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO docs (path, mtime, size) VALUES (?, ?, ?)
+			 ON CONFLICT (path) DO UPDATE SET mtime = excluded.mtime, size = excluded.size`,
+			p, stamp[0], stamp[1]); err != nil {
+			return st, err
+		}
+	}
+	for p := range known {
+		st.Removed++
+		if err := remove(ctx, tx, p); err != nil {
+			return st, err
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM docs WHERE path = ?`, p); err != nil {
+			return st, err
+		}
+	}
+	return st, tx.Commit()
+}
+
+// insert indexes e, and each of its bullets when it is a journal file.
+func insert(ctx context.Context, tx *sql.Tx, e *entry.Entry) error {
+	tags := strings.Join(e.Meta.Tags, " ")
+	body := string(e.Body)
+	if journal.Is(e) {
+		body = ""
+	}
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO fts (path, title, body, tags) VALUES (?, ?, ?, ?)`,
+		e.Path, e.Meta.Title, body, tags); err != nil {
+		return err
+	}
+	if !journal.Is(e) {
+		return nil
+	}
+	for _, it := range journal.Items(e) {
+		res, err := tx.ExecContext(ctx,
+			`INSERT INTO fts (path, title, body, tags) VALUES (?, '', ?, ?)`,
+			e.Path, it.Text, tags)
+		if err != nil {
+			return err
+		}
+		id, err := res.LastInsertId()
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO items (id, line, title) VALUES (?, ?, ?)`,
+			id, it.Line, it.Time.Format(entry.DateLayout+" "+journal.TimeLayout)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// remove drops the documents of the file at p.
+func remove(ctx context.Context, tx *sql.Tx, p string) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM items WHERE id IN (SELECT rowid FROM fts WHERE path = ?)`, p); err != nil {
+		return err
+	}
+	_, err := tx.ExecContext(ctx, `DELETE FROM fts WHERE path = ?`, p)
+	return err
+}
+
+// Summary is what the index holds of an entry besides its body.
+type Summary struct {
+	Path  string
+	Title string
+	Tags  []string
+}
+
+// Summaries returns the path, title and tags of every indexed entry,
+// sorted by path, without reading the entries themselves. Call Sync first
+// for them to be current.
+func (ix *Index) Summaries(ctx context.Context) ([]Summary, error) {
+	rows, err := ix.db.QueryContext(ctx, `SELECT path, title, tags FROM fts WHERE rowid NOT IN (SELECT id FROM items) ORDER BY path`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []Summary
+	for rows.Next() {
+		var s Summary
+		var tags string
+		if err := rows.Scan(&s.Path, &s.Title, &tags); err != nil {
+			return nil, err
+		}
+		s.Tags = strings.Fields(tags)
+		out = append(out, s)
+	}
+	return out, rows.Err()
+}
+
+// Result is a single search hit.
+type Result struct {
+	Path  string `json:"path"`
+	Title string `json:"title"`
+	// Line is the line a journal bullet starts on, with Title its date and
+	// time; it is 0 for whole files.
+	Line    int     `json:"line,omitempty"`
+	Snippet string  `json:"snippet"`
+	Score   float64 `json:"score"`
+}
+
+// Options controls a search.
+type Options struct {
+	Limit int
+	// Raw passes the query to FTS5 unchanged instead of treating it as
+	// plain words.
+	Raw bool
+	// Mark is placed around matched terms in titles and snippets.
+	MarkStart, MarkEnd string
+	// Archived includes archived entries, which are otherwise left out.
+	Archived bool
+}
+
+// Search returns the entries matching query, best match first.
+func (ix *Index) Search(ctx context.Context, query string, opts Options) ([]Result, error) {
+	if !opts.Raw {
+		query = Quote(query)
+	}
+	if query == "" {
+		return nil, nil
+	}
+	if opts.Limit <= 0 {
+		opts.Limit = 20
+	}
+	if opts.Archived {
+		return ix.match(ctx, query, opts, opts.Limit)
+	}
+	hits, err := ix.match(ctx, query, opts, -1)
+	if err != nil {
+		return nil, err
+	}
+	return ix.filter(hits, nil, opts)
+}
+
+// match runs an FTS5 query, returning at most limit results, or all of
+// them when limit is negative.
+func (ix *Index) match(ctx context.Context, query string, opts Options, limit int) ([]Result, error) {
+	// Column weights: title matches count most, then tags, then body.
+	rows, err := ix.db.QueryContext(ctx, `
+		SELECT fts.path,
+		       COALESCE(items.title, highlight(fts, 1, ?, ?)),
+		       COALESCE(items.line, 0),
+		       snippet(fts, 2, ?, ?, '…', 12),
+		       bm25(fts, 0, 10.0, 1.0, 5.0) AS score
+		FROM fts LEFT JOIN items ON items.id = fts.rowid
+		WHERE fts MATCH ?
+		ORDER BY score LIMIT ?`,
+		opts.MarkStart, opts.MarkEnd, opts.MarkStart, opts.MarkEnd, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("search %q: %w", query, err)
+	}
+	defer rows.Close()
+	var results []Result
+	for rows.Next() {
+		var r Result
+		if err := rows.Scan(&r.Path, &r.Title, &r.Line, &r.Snippet, &r.Score); err != nil {
+			return nil, err
+		}
+		r.Snippet = strings.Join(strings.Fields(r.Snippet), " ")
+		// bm25 scores are negative; flip them so larger is better.
+		r.Score = -r.Score
+		results = append(results, r)
+	}
+	return results, rows.Err()
+}
+
+// Quote turns free text into an FTS5 query matching all of its words. The
+// last word is matched as a prefix so partially typed queries still hit.
+func Quote(q string) string {
+	words := strings.Fields(q)
+	for i, w := range words {
+		words[i] = `"` + strings.ReplaceAll(w, `"`, `""`) + `"`
+		if i == len(words)-1 {
+			words[i] += "*"
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// Query returns the entries matched by x, best match first. The words and
+// phrases of x are looked up in the index and ranked; the remaining
+// filters are applied to the hits. A query without words returns the
+// matching entries newest first.
+func (ix *Index) Query(ctx context.Context, x query.Expr, opts Options) ([]Result, error) {
+	if opts.Limit <= 0 {
+		opts.Limit = 20
+	}
+	if words, ok := plainWords(x); ok {
+		// Plain words keep the prefix match on the last one.
+		return ix.Search(ctx, strings.Join(words, " "), opts)
+	}
+	fts, rest := split(x)
+	if fts == "" {
+		entries, err := ix.tree.Entries()
+		if err != nil {
+			return nil, err
+		}
+		entries = query.Filter(entries, rest)
+		if !opts.Archived {
+			entries = query.Filter(entries, query.Not{X: query.Archived{}})
+		}
+		if err := query.Sort(entries, "created", false); err != nil {
+			return nil, err
+		}
+		var results []Result
+		for _, e := range entries[:min(opts.Limit, len(entries))] {
+			results = append(results, Result{Path: e.Path, Title: e.Meta.Title, Snippet: entry.Excerpt(e.Body, 12)})
+		}
+		return results, nil
+	}
+	hits, err := ix.match(ctx, fts, opts, -1)
+	if err != nil {
+		return nil, err
+	}
+	if _, all := rest.(query.All); all && opts.Archived {
+		return hits[:min(opts.Limit, len(hits))], nil
+	}
+	return ix.filter(hits, rest, opts)
+}
+
+// filter returns the first opts.Limit hits whose entries x, if not nil,
+// matches, leaving out archived entries unless opts include them.
+func (ix *Index) filter(hits []Result, x query.Expr, opts Options) ([]Result, error) {
+	var results []Result
+	for _, r := range hits {
+		e, err := ix.tree.Load(r.Path)
+		if err != nil {
+			return nil, err
+		}
+		if e.Meta.Archived && !opts.Archived || x != nil && !x.Match(e) {
+			continue
+		}
+		results = append(results, r)
+		if len(results) == opts.Limit {
+			break
+		}
+	}
+	return results, nil
+}
+
+// split divides x into an FTS5 query and the part of x the index cannot
+// answer. Words and phrases combined with AND and OR translate directly,
+// and so does an AND mixing them with filters, whose filters are left in
+// rest; anything else is left whole in rest. An empty fts means the index
+// is not consulted.
+func split(x query.Expr) (fts string, rest query.Expr) {
+	switch x := x.(type) {
+	case query.Collection:
+		return split(x.X)
+	case query.Text:
+		return `"` + strings.ReplaceAll(string(x), `"`, `""`) + `"`, query.All{}
+	case query.And:
+		var parts []string
+		var left query.And
+		for _, y := range x {
+			f, r := split(y)
+			if f != "" {
+				parts = append(parts, f)
+			}
+			if _, all := r.(query.All); !all {
+				left = append(left, r)
+			}
+		}
+		fts = strings.Join(parts, " AND ")
+		if len(parts) > 1 {
+			fts = "(" + fts + ")"
+		}
+		switch len(left) {
+		case 0:
+			return fts, query.All{}
+		case 1:
+			return fts, left[0]
+		}
+		return fts, left
+	case query.Or:
+		parts := make([]string, len(x))
+		for i, y := range x {
+			f, r := split(y)
+			if _, all := r.(query.All); f == "" || !all {
+				return "", x
+			}
+			parts[i] = f
+		}
+		return "(" + strings.Join(parts, " OR ") + ")", query.All{}
+	}
+	return "", x
+}
+
+// plainWords returns the words of a query made only of unquoted words.
+func plainWords(x query.Expr) ([]string, bool) {
+	xs, ok := x.(query.And)
+	if !ok {
+		xs = query.And{x}
+	}
+	var words []string
+	for _, y := range xs {
+		t, ok := y.(query.Text)
+		if !ok || strings.ContainsFunc(string(t), unicode.IsSpace) {
+			return nil, false
+		}
+		words = append(words, string(t))
+	}
+	return words, true
+}
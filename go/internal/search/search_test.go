@@ -0,0 +1,224 @@
+package search
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/canhta/til/go/internal/notes"
+)
+
+func newTree(t *testing.T, files map[string]string) *notes.Tree {
+	t.Helper()
+	tree := notes.Open(t.TempDir())
+	for p, data := range files {
+		write(t, tree, p, data)
+	}
+	return tree
+}
+
+// write writes a file of tree, modified a second after it was last.
+func write(t *testing.T, tree *notes.Tree, p, data string) {
+	t.Helper()
+	mod := time.Now()
+	if fi, err := os.Stat(tree.Abs(p)); err == nil {
+		mod = fi.ModTime().Add(time.Second)
+	}
+	if err := os.MkdirAll(filepath.Dir(tree.Abs(p)), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(tree.Abs(p), []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(tree.Abs(p), mod, mod); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func open(t *testing.T, tree *notes.Tree) *Index {
+	t.Helper()
+	ix, err := Open(tree)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ix.Close() })
+	return ix
+}
+
+func sync(t *testing.T, ix *Index) SyncStats {
+	t.Helper()
+	st, err := ix.Sync(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return st
+}
+
+func search(t *testing.T, ix *Index, q string, opts Options) []string {
+	t.Helper()
+	res, err := ix.Search(context.Background(), q, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	paths := []string{}
+	for _, r := range res {
+		paths = append(paths, r.Path)
+	}
+	return paths
+}
+
+var files = map[string]string{
+	"go/slices.md":    "---\ntitle: Slices share arrays\ntags: [go]\n---\nAppending may reallocate the backing array.\n",
+	"go/maps.md":      "---\ntitle: Maps\ntags: [go, slices]\n---\nIteration order is random.\n",
+	"git/rebase.md":   "---\ntitle: Rebase onto\ntags: [git]\n---\nMove commits from one branch to another; slices of history.\n",
+	"git/bisect.md":   "---\ntitle: Bisect\ntags: [git]\n---\nFind the commit that introduced a bug.\n",
+	"rust/running.md": "---\ntitle: Running tests\ntags: [rust]\n---\ncargo test runs them.\n",
+}
+
+func TestSync(t *testing.T) {
+	tree := newTree(t, files)
+	ix := open(t, tree)
+	if st := sync(t, ix); st != (SyncStats{Added: 5}) {
+		t.Errorf("first sync = %+v", st)
+	}
+	if st := sync(t, ix); st != (SyncStats{}) {
+		t.Errorf("sync of an unchanged tree = %+v", st)
+	}
+	write(t, tree, "go/maps.md", "---\ntitle: Maps\n---\nUse a sorted slice of keys for a stable walk.\n")
+	write(t, tree, "go/new.md", "# New\n")
+	if err := os.Remove(tree.Abs("git/bisect.md")); err != nil {
+		t.Fatal(err)
+	}
+	if st := sync(t, ix); st != (SyncStats{Added: 1, Updated: 1, Removed: 1}) {
+		t.Errorf("sync after changes = %+v", st)
+	}
+	if got := search(t, ix, "sorted", Options{}); !slices.Equal(got, []string{"go/maps.md"}) {
+		t.Errorf("search for the new words of go/maps.md = %q", got)
+	}
+	if got := search(t, ix, "random", Options{}); len(got) != 0 {
+		t.Errorf("search for its old words = %q", got)
+	}
+	if got := search(t, ix, "bisect", Options{}); len(got) != 0 {
+		t.Errorf("search for a removed entry = %q", got)
+	}
+
+	// The index survives being reopened.
+	ix.Close()
+	if st := sync(t, open(t, tree)); st != (SyncStats{}) {
+		t.Errorf("sync after reopening = %+v", st)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	ix := open(t, newTree(t, files))
+	sync(t, ix)
+	tests := []struct {
+		q    string
+		want []string
+	}{
+		// Titles count most, then tags, then bodies.
+		{"slices", []string{"go/slices.md", "go/maps.md", "git/rebase.md"}},
+		{"commit", []string{"git/bisect.md", "git/rebase.md"}},
+		{"commits branch", []string{"git/rebase.md"}},
+		// The last word matches as a prefix, and words are stemmed.
+		{"reba", []string{"git/rebase.md"}},
+		{"run", []string{"rust/running.md"}},
+		{"slices nowhere", []string{}},
+		{`"share" OR`, []string{}},
+		{"", []string{}},
+	}
+	for _, tt := range tests {
+		if got := search(t, ix, tt.q, Options{}); !slices.Equal(got, tt.want) {
+			t.Errorf("Search(%q) = %q, want %q", tt.q, got, tt.want)
+		}
+	}
+	if got := search(t, ix, "slices", Options{Limit: 1}); !slices.Equal(got, []string{"go/slices.md"}) {
+		t.Errorf("Search with a limit = %q", got)
+	}
+	if got := search(t, ix, "rebase OR bisect", Options{Raw: true}); !slices.Equal(got, []string{"git/bisect.md", "git/rebase.md"}) && !slices.Equal(got, []string{"git/rebase.md", "git/bisect.md"}) {
+		t.Errorf("raw Search = %q", got)
+	}
+	if _, err := ix.Search(context.Background(), `"unbalanced`, Options{Raw: true}); err == nil {
+		t.Error("raw Search accepted a bad query")
+	}
+}
+
+func TestSearchMarks(t *testing.T) {
+	ix := open(t, newTree(t, files))
+	sync(t, ix)
+	res, err := ix.Search(context.Background(), "backing", Options{MarkStart: "[", MarkEnd: "]"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res) != 1 || res[0].Title != "Slices share arrays" || res[0].Snippet != "Appending may reallocate the [backing] array." || res[0].Score <= 0 {
+		t.Errorf("Search = %+v", res)
+	}
+}
+
+func TestQuote(t *testing.T) {
+	for q, want := range map[string]string{
+		"":               "",
+		"slice":          `"slice"*`,
+		"copy a slice":   `"copy" "a" "slice"*`,
+		`say "hi" AND x`: `"say" """hi""" "AND" "x"*`,
+	} {
+		if got := Quote(q); got != want {
+			t.Errorf("Quote(%q) = %s, want %s", q, got, want)
+		}
+	}
+}
+
+func TestSummaries(t *testing.T) {
+	ix := open(t, newTree(t, files))
+	sync(t, ix)
+	got, err := ix.Summaries(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 5 || got[0].Path != "git/bisect.md" || got[2].Title != "Maps" || !slices.Equal(got[2].Tags, []string{"go", "slices"}) {
+		t.Errorf("Summaries = %+v", got)
+	}
+}
+
+func TestSearchJournal(t *testing.T) {
+	tree := newTree(t, map[string]string{
+		"go/slices.md":          files["go/slices.md"],
+		"journal/2026-10-14.md": "---\ntitle: Wednesday, October 14, 2026\ndate: 2026-10-14\ntags: [work]\n---\n\n- 09:30 found why the build was slow\n- 14:05 the cache\n  makes builds fast\n",
+	})
+	ix := open(t, tree)
+	sync(t, ix)
+	res, err := ix.Search(context.Background(), "builds", Options{MarkStart: "[", MarkEnd: "]"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res) != 2 {
+		t.Fatalf("Search(builds) = %+v, want both bullets", res)
+	}
+	slices.SortFunc(res, func(a, b Result) int { return a.Line - b.Line })
+	if r := res[1]; r.Path != "journal/2026-10-14.md" || r.Line != 8 || r.Title != "2026-10-14 14:05" || r.Snippet != "the cache makes [builds] fast" {
+		t.Errorf("bullet = %+v", r)
+	}
+	// The file itself is found by its title and tags, not its bullets.
+	if got := search(t, ix, "wednesday", Options{}); !slices.Equal(got, []string{"journal/2026-10-14.md"}) {
+		t.Errorf("Search(wednesday) = %q", got)
+	}
+	if res, _ := ix.Search(context.Background(), "work", Options{}); len(res) != 3 {
+		t.Errorf("Search(work) = %+v, want the file and both bullets by their tag", res)
+	}
+
+	write(t, tree, "journal/2026-10-14.md", "---\ntitle: Wednesday, October 14, 2026\n---\n\n- 09:30 nothing\n")
+	sync(t, ix)
+	if got := search(t, ix, "builds", Options{}); len(got) != 0 {
+		t.Errorf("Search for removed bullets = %q", got)
+	}
+	sums, err := ix.Summaries(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sums) != 2 {
+		t.Errorf("Summaries = %+v, want one per file", sums)
+	}
+}
@@ -0,0 +1,102 @@
+package semantic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/canhta/til/go/internal/config"
+)
+
+// ErrUnavailable is returned when no embedding model is configured or the
+// configured endpoint cannot be reached.
+var ErrUnavailable = errors.New("embedding model unavailable")
+
+// Embedder turns texts into vectors.
+type Embedder interface {
+	// Model names the model; vectors from different models are never
+	// compared.
+	Model() string
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// NewEmbedder returns a client for the endpoint in cfg, or ErrUnavailable
+// when none is configured.
+func NewEmbedder(cfg config.Embeddings) (Embedder, error) {
+	if cfg.URL == "" || cfg.Model == "" {
+		return nil, fmt.Errorf("%w: set embeddings.url and embeddings.model in the config file", ErrUnavailable)
+	}
+	c := &client{url: cfg.URL, model: cfg.Model, http: &http.Client{Timeout: 2 * time.Minute}}
+	if cfg.KeyEnv != "" {
+		c.key = os.Getenv(cfg.KeyEnv)
+	}
+	return c, nil
+}
+
+// client speaks the OpenAI embeddings API, which Ollama, llama.cpp's server
+// and most hosted providers implement.
+type client struct {
+	url, model, key string
+	http            *http.Client
+}
+
+func (c *client) Model() string { return c.model }
+
+func (c *client) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	body, err := json.Marshal(map[string]any{"model": c.model, "input": texts})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.key != "" {
+		req.Header.Set("Authorization", "Bearer "+c.key)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		err := fmt.Errorf("embeddings %s: %s: %s", c.url, resp.Status, bytes.TrimSpace(msg))
+		if resp.StatusCode == http.StatusNotFound || resp.StatusCode >= 500 {
+			err = fmt.Errorf("%w: %v", ErrUnavailable, err)
+		}
+		return nil, err
+	}
+	var out struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, fmt.Errorf("embeddings %s: %w", c.url, err)
+	}
+	vecs := make([][]float32, len(texts))
+	for _, d := range out.Data {
+		if d.Index < 0 || d.Index >= len(vecs) {
+			return nil, fmt.Errorf("embeddings %s: response index %d out of range", c.url, d.Index)
+		}
+		vecs[d.Index] = d.Embedding
+	}
+	for i, v := range vecs {
+		if len(v) == 0 {
+			return nil, fmt.Errorf("embeddings %s: no vector for input %d", c.url, i)
+		}
+	}
+	return vecs, nil
+}
@@ -0,0 +1,99 @@
+package semantic
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/canhta/til/go/internal/config"
+)
+
+func TestNewEmbedderUnconfigured(t *testing.T) {
+	for _, cfg := range []config.Embeddings{{}, {URL: "http://localhost"}, {Model: "m"}} {
+		if _, err := NewEmbedder(cfg); !errors.Is(err, ErrUnavailable) {
+			t.Errorf("NewEmbedder(%+v) = %v, want ErrUnavailable", cfg, err)
+		}
+	}
+}
+
+func TestClientEmbed(t *testing.T) {
+	t.Setenv("TIL_TEST_KEY", "secret")
+	var got struct {
+		Model string   `json:"model"`
+		Input []string `json:"input"`
+	}
+	var auth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Error(err)
+		}
+		// Out of order, as the API allows.
+		w.Write([]byte(`{"data":[{"index":1,"embedding":[0,1]},{"index":0,"embedding":[1,0]}]}`))
+	}))
+	defer srv.Close()
+
+	c, err := NewEmbedder(config.Embeddings{URL: srv.URL, Model: "nomic", KeyEnv: "TIL_TEST_KEY"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Model() != "nomic" {
+		t.Errorf("Model() = %q", c.Model())
+	}
+	vecs, err := c.Embed(context.Background(), []string{"a", "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vecs) != 2 || !slices.Equal(vecs[0], []float32{1, 0}) || !slices.Equal(vecs[1], []float32{0, 1}) {
+		t.Errorf("Embed = %v", vecs)
+	}
+	if got.Model != "nomic" || !slices.Equal(got.Input, []string{"a", "b"}) {
+		t.Errorf("request = %+v", got)
+	}
+	if auth != "Bearer secret" {
+		t.Errorf("Authorization = %q", auth)
+	}
+}
+
+func TestClientEmbedErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      int
+		body        string
+		unavailable bool
+	}{
+		{"not found", http.StatusNotFound, `no such model`, true},
+		{"server error", http.StatusBadGateway, ``, true},
+		{"bad request", http.StatusBadRequest, `input too long`, false},
+		{"missing vector", http.StatusOK, `{"data":[{"index":0,"embedding":[1]}]}`, false},
+		{"bad index", http.StatusOK, `{"data":[{"index":5,"embedding":[1]}]}`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer srv.Close()
+			c, _ := NewEmbedder(config.Embeddings{URL: srv.URL, Model: "m"})
+			_, err := c.Embed(context.Background(), []string{"a", "b"})
+			if err == nil {
+				t.Fatal("Embed succeeded")
+			}
+			if errors.Is(err, ErrUnavailable) != tt.unavailable {
+				t.Errorf("Embed = %v, want unavailable %v", err, tt.unavailable)
+			}
+		})
+	}
+
+	srv := httptest.NewServer(http.NotFoundHandler())
+	srv.Close()
+	c, _ := NewEmbedder(config.Embeddings{URL: srv.URL, Model: "m"})
+	if _, err := c.Embed(context.Background(), []string{"a"}); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("Embed on a closed server = %v, want ErrUnavailable", err)
+	}
+}
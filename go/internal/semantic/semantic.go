@@ -0,0 +1,238 @@
+// Package semantic ranks entries by the meaning of a query rather than its
+// words, comparing embedding vectors from a configured model.
+//
+// Entry vectors are cached in the notes state directory, keyed by model, and
+// only entries whose files changed are re-embedded on each sync.
+package semantic
+
+import (
+	"context"
+	"database/sql"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	_ "modernc.org/sqlite" // database/sql driver
+
+	"github.com/canhta/til/go/internal/notes"
+	"github.com/canhta/til/go/internal/search"
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+// File is the vector cache file name inside the notes state directory.
+const File = "embeddings.db"
+
+const schema = `
+CREATE TABLE IF NOT EXISTS vectors (
+	path   TEXT NOT NULL,
+	model  TEXT NOT NULL,
+	stamp  TEXT NOT NULL,
+	vector BLOB NOT NULL,
+	PRIMARY KEY (path, model)
+);
+`
+
+// batch is the number of entries embedded per request.
+const batch = 16
+
+// maxInput caps the text sent for one entry, in bytes.
+const maxInput = 8000
+
+// Index is an open vector cache.
+type Index struct {
+	db    *sql.DB
+	tree  *notes.Tree
+	model Embedder
+}
+
+// Open opens (creating if needed) the vector cache of tree for model.
+func Open(tree *notes.Tree, model Embedder) (*Index, error) {
+	file := tree.StatePath(File)
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return nil, err
+	}
+	db, err := sql.Open("sqlite", "file:"+file+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("embeddings cache: %w", err)
+	}
+	return &Index{db: db, tree: tree, model: model}, nil
+}
+
+// Close closes the cache.
+func (ix *Index) Close() error {
+	return ix.db.Close()
+}
+
+// Search embeds query and returns up to opts.Limit entries, most similar
+// first, leaving out archived entries unless opts include them. Entries
+// changed since the last search are embedded first.
+func (ix *Index) Search(ctx context.Context, query string, opts search.Options) ([]search.Result, error) {
+	if strings.TrimSpace(query) == "" {
+		return nil, nil
+	}
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = 20
+	}
+	entries, err := ix.tree.Entries()
+	if err != nil {
+		return nil, err
+	}
+	vecs, err := ix.sync(ctx, entries)
+	if err != nil {
+		return nil, err
+	}
+	qv, err := ix.model.Embed(ctx, []string{query})
+	if err != nil {
+		return nil, err
+	}
+	q := normalize(qv[0])
+	results := make([]search.Result, 0, len(entries))
+	for _, e := range entries {
+		v := vecs[e.Path]
+		if len(v) != len(q) || e.Meta.Archived && !opts.Archived {
+			continue
+		}
+		results = append(results, search.Result{Path: e.Path, Title: e.Meta.Title, Snippet: entry.Excerpt(e.Body, 24), Score: dot(q, v)})
+	}
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Score > results[j].Score })
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+// stamp identifies the version of an entry's file.
+func stamp(e *entry.Entry) string {
+	return fmt.Sprintf("%d %d", e.ModTime.UnixNano(), len(e.Body))
+}
+
+// sync embeds the entries that are new or changed, drops vectors of removed
+// entries and returns the vector of every entry keyed by path.
+func (ix *Index) sync(ctx context.Context, entries []*entry.Entry) (map[string][]float32, error) {
+	model := ix.model.Model()
+	stamps := map[string]string{}
+	vecs := map[string][]float32{}
+	rows, err := ix.db.QueryContext(ctx, `SELECT path, stamp, vector FROM vectors WHERE model = ?`, model)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var p, s string
+		var blob []byte
+		if err := rows.Scan(&p, &s, &blob); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		stamps[p], vecs[p] = s, decode(blob)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var todo []*entry.Entry
+	live := map[string]bool{}
+	for _, e := range entries {
+		live[e.Path] = true
+		if stamps[e.Path] != stamp(e) {
+			todo = append(todo, e)
+		}
+	}
+	tx, err := ix.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	for p := range stamps {
+		if !live[p] {
+			delete(vecs, p)
+			if _, err := tx.ExecContext(ctx, `DELETE FROM vectors WHERE path = ?`, p); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for len(todo) > 0 {
+		n := min(batch, len(todo))
+		texts := make([]string, n)
+		for i, e := range todo[:n] {
+			texts[i] = text(e)
+		}
+		out, err := ix.model.Embed(ctx, texts)
+		if err != nil {
+			return nil, err
+		}
+		for i, e := range todo[:n] {
+			v := normalize(out[i])
+			vecs[e.Path] = v
+			if _, err := tx.ExecContext(ctx,
+				`INSERT OR REPLACE INTO vectors (path, model, stamp, vector) VALUES (?, ?, ?, ?)`,
+				e.Path, model, stamp(e), encode(v)); err != nil {
+				return nil, err
+			}
+		}
+		todo = todo[n:]
+	}
+	return vecs, tx.Commit()
+}
+
+// text is what gets embedded for an entry.
+func text(e *entry.Entry) string {
+	s := e.Meta.Title + "\n"
+	if len(e.Meta.Tags) > 0 {
+		s += "Tags: " + strings.Join(e.Meta.Tags, ", ") + "\n"
+	}
+	s += "\n" + string(e.Body)
+	if len(s) > maxInput {
+		s = strings.ToValidUTF8(s[:maxInput], "")
+	}
+	return s
+}
+
+func normalize(v []float32) []float32 {
+	var sum float64
+	for _, x := range v {
+		sum += float64(x) * float64(x)
+	}
+	if sum == 0 {
+		return v
+	}
+	norm := float32(math.Sqrt(sum))
+	out := make([]float32, len(v))
+	for i, x := range v {
+		out[i] = x / norm
+	}
+	return out
+}
+
+func dot(a, b []float32) float64 {
+	var s float64
+	for i := range a {
+		s += float64(a[i]) * float64(b[i])
+	}
+	return s
+}
+
+func encode(v []float32) []byte {
+	b := make([]byte, 4*len(v))
+	for i, x := range v {
+		binary.LittleEndian.PutUint32(b[4*i:], math.Float32bits(x))
+	}
+	return b
+}
+
+func decode(b []byte) []float32 {
+	v := make([]float32, len(b)/4)
+	for i := range v {
+		v[i] = math.Float32frombits(binary.LittleEndian.Uint32(b[4*i:]))
+	}
+	return v
+}
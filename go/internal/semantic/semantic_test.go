@@ -0,0 +1,149 @@
+package semantic
+
+import (
+	"context"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/canhta/til/go/internal/notes"
+	"github.com/canhta/til/go/internal/search"
+)
+
+// fake embeds a text as the counts of a few words in it.
+type fake struct {
+	model    string
+	embedded []string
+}
+
+var words = []string{"slice", "map", "rebase", "commit"}
+
+func (f *fake) Model() string { return f.model }
+
+func (f *fake) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	out := make([][]float32, len(texts))
+	for i, s := range texts {
+		f.embedded = append(f.embedded, strings.SplitN(s, "\n", 2)[0])
+		v := make([]float32, len(words))
+		for j, w := range words {
+			v[j] = float32(strings.Count(strings.ToLower(s), w))
+		}
+		out[i] = v
+	}
+	return out, nil
+}
+
+func newTree(t *testing.T, files map[string]string) *notes.Tree {
+	t.Helper()
+	tree := notes.Open(t.TempDir())
+	for p, data := range files {
+		if err := tree.Write(p, []byte(data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return tree
+}
+
+func paths(results []search.Result) []string {
+	var out []string
+	for _, r := range results {
+		out = append(out, r.Path)
+	}
+	return out
+}
+
+func TestSearch(t *testing.T) {
+	tree := newTree(t, map[string]string{
+		"go/slices.md":  "# Slices\n\nA slice shares its array with every other slice cut from it.\n",
+		"go/maps.md":    "# Maps\n\nA map is iterated in random order.\n",
+		"git/rebase.md": "# Rebase\n\nRebase replays each commit onto a new base.\n",
+		"git/old.md":    "---\ntitle: Old rebase\narchived: true\n---\n\nRebase, rebase, rebase.\n",
+	})
+	f := &fake{model: "m1"}
+	ix, err := Open(tree, f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ix.Close()
+	ctx := context.Background()
+
+	got, err := ix.Search(ctx, "rebase my commit", search.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"git/rebase.md", "go/maps.md", "go/slices.md"}; !slices.Equal(paths(got), want) {
+		t.Errorf("Search = %q, want %q", paths(got), want)
+	}
+	if got[0].Title != "Rebase" || got[0].Score <= 0.5 || got[1].Score != 0 {
+		t.Errorf("Search = %+v", got)
+	}
+	if len(f.embedded) != 5 {
+		t.Errorf("embedded %q, want 4 entries and the query", f.embedded)
+	}
+
+	got, err = ix.Search(ctx, "slice", search.Options{Limit: 1, Archived: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(paths(got), []string{"go/slices.md"}) {
+		t.Errorf("Search(slice, limit 1) = %q", paths(got))
+	}
+	if got, _ := ix.Search(ctx, "rebase", search.Options{Archived: true}); len(got) != 4 || got[0].Path != "git/old.md" {
+		t.Errorf("Search(rebase, archived) = %q", paths(got))
+	}
+	if got, err := ix.Search(ctx, "  ", search.Options{}); got != nil || err != nil {
+		t.Errorf("Search(blank) = %v, %v", got, err)
+	}
+}
+
+func TestSearchEmbedsOnlyChanges(t *testing.T) {
+	tree := newTree(t, map[string]string{
+		"go/slices.md":  "# Slices\n\nslice\n",
+		"go/maps.md":    "# Maps\n\nmap\n",
+		"git/rebase.md": "# Rebase\n\nrebase\n",
+	})
+	ctx := context.Background()
+	search1 := func(f *fake) []string {
+		t.Helper()
+		ix, err := Open(tree, f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer ix.Close()
+		if _, err := ix.Search(ctx, "commit", search.Options{}); err != nil {
+			t.Fatal(err)
+		}
+		got := f.embedded[:len(f.embedded)-1] // less the query
+		slices.Sort(got)
+		return got
+	}
+
+	if got := search1(&fake{model: "m1"}); len(got) != 3 {
+		t.Errorf("first search embedded %q", got)
+	}
+	if got := search1(&fake{model: "m1"}); len(got) != 0 {
+		t.Errorf("unchanged search embedded %q", got)
+	}
+
+	later := time.Now().Add(time.Hour)
+	if err := tree.Write("go/maps.md", []byte("# Maps\n\nmap and commit\n")); err != nil {
+		t.Fatal(err)
+	}
+	os.Chtimes(tree.Abs("go/maps.md"), later, later)
+	if err := tree.Write("go/new.md", []byte("# New\n\ncommit\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(tree.Abs("git/rebase.md")); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := search1(&fake{model: "m1"}), []string{"Maps", "New"}; !slices.Equal(got, want) {
+		t.Errorf("after edits embedded %q, want %q", got, want)
+	}
+
+	// Vectors of another model are never reused.
+	if got := search1(&fake{model: "m2"}); len(got) != 3 {
+		t.Errorf("new model embedded %q", got)
+	}
+}
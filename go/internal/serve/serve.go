@@ -0,0 +1,210 @@
+// Package serve runs a development server for the generated site. It
+// rebuilds the site when the notes tree changes and tells connected
+// browsers to reload through server-sent events.
+package serve
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/canhta/til/go/internal/notes"
+	"github.com/canhta/til/go/internal/site"
+	"github.com/canhta/til/go/internal/store"
+)
+
+// eventsPath is the SSE endpoint browsers subscribe to.
+const eventsPath = "/_til/events"
+
+// reloadScript is injected into every HTML page served.
+const reloadScript = `<script>new EventSource("` + eventsPath + `").addEventListener("reload", () => location.reload());</script>`
+
+// debounce coalesces bursts of file events (editors often write several
+// times per save) into one rebuild.
+const debounce = 100 * time.Millisecond
+
+// Server serves a site and keeps it up to date.
+type Server struct {
+	Addr    string
+	Tree    *notes.Tree
+	Builder *site.Builder
+	// Out is the build output directory, whose changes are ignored.
+	Out string
+	Log *log.Logger
+
+	mu      sync.Mutex
+	clients map[chan struct{}]bool
+}
+
+// Run builds the site, then serves it until ctx is cancelled.
+func (s *Server) Run(ctx context.Context) error {
+	if s.Log == nil {
+		s.Log = log.New(os.Stderr, "", log.LstdFlags)
+	}
+	s.clients = map[chan struct{}]bool{}
+	if _, err := s.Builder.Build(ctx); err != nil {
+		return err
+	}
+
+	watchCtx, stop := context.WithCancel(ctx)
+	defer stop()
+	events, err := s.Tree.Store.Watch(watchCtx)
+	if err != nil {
+		return err
+	}
+
+	ln, err := net.Listen("tcp", s.Addr)
+	if err != nil {
+		return err
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc(eventsPath, s.handleEvents)
+	mux.Handle("/", injectReload(http.FileServer(http.Dir(s.Out))))
+	srv := &http.Server{Handler: mux}
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		srv.Shutdown(shutdown)
+	}()
+	go s.loop(ctx, events)
+
+	s.Log.Printf("serving %s on http://%s/", s.Out, ln.Addr())
+	if err := srv.Serve(ln); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// ignored reports whether the file at the slash-separated path p lies in
+// the build output.
+func (s *Server) ignored(p string) bool {
+	rel, err := s.Tree.Rel(s.Out)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, "../") {
+		return false
+	}
+	return p == rel || strings.HasPrefix(p, rel+"/")
+}
+
+func (s *Server) loop(ctx context.Context, events <-chan store.Event) {
+	var (
+		timer   *time.Timer
+		pending <-chan time.Time
+	)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ev, ok := <-events:
+			if !ok {
+				return
+			}
+			if s.ignored(ev.Path) {
+				continue
+			}
+			if timer == nil {
+				timer = time.NewTimer(debounce)
+			} else {
+				timer.Reset(debounce)
+			}
+			pending = timer.C
+		case <-pending:
+			pending = nil
+			s.rebuild(ctx)
+		}
+	}
+}
+
+func (s *Server) rebuild(ctx context.Context) {
+	start := time.Now()
+	st, err := s.Builder.Build(ctx)
+	if err != nil {
+		s.Log.Printf("build failed: %v", err)
+		return
+	}
+	s.Log.Printf("rebuilt %d of %d entries in %s", len(st.Rendered), len(st.Pages), time.Since(start).Round(time.Millisecond))
+	s.broadcast()
+}
+
+func (s *Server) broadcast() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for c := range s.clients {
+		select {
+		case c <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	c := make(chan struct{}, 1)
+	s.mu.Lock()
+	s.clients[c] = true
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.clients, c)
+		s.mu.Unlock()
+	}()
+	fmt.Fprint(w, ": connected\n\n")
+	flusher.Flush()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-c:
+			fmt.Fprint(w, "event: reload\ndata: {}\n\n")
+			flusher.Flush()
+		}
+	}
+}
+
+// injectReload adds the reload script to HTML responses from next.
+func injectReload(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := &recorder{header: http.Header{}, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+		body := rec.body.Bytes()
+		if strings.HasPrefix(rec.header.Get("Content-Type"), "text/html") {
+			if i := bytes.LastIndex(body, []byte("</body>")); i >= 0 {
+				body = append(body[:i:i], append([]byte(reloadScript), body[i:]...)...)
+			} else {
+				body = append(body, reloadScript...)
+			}
+			rec.header.Del("Content-Length")
+		}
+		for k, v := range rec.header {
+			w.Header()[k] = v
+		}
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(rec.status)
+		w.Write(body)
+	})
+}
+
+// recorder buffers a response so it can be rewritten.
+type recorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (r *recorder) Header() http.Header         { return r.header }
+func (r *recorder) WriteHeader(status int)      { r.status = status }
+func (r *recorder) Write(b []byte) (int, error) { return r.body.Write(b) }
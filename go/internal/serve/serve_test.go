@@ -0,0 +1,148 @@
+package serve
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/canhta/til/go/internal/notes"
+	"github.com/canhta/til/go/internal/site"
+	"github.com/canhta/til/go/internal/store"
+)
+
+func TestInjectReload(t *testing.T) {
+	tests := []struct {
+		name, ctype, body, want string
+	}{
+		{"before body end", "text/html; charset=utf-8", "<html><body><p>hi</p></body></html>", "<html><body><p>hi</p>" + reloadScript + "</body></html>"},
+		{"appended", "text/html", "<p>hi</p>", "<p>hi</p>" + reloadScript},
+		{"last body end", "text/html", "<pre></body></pre></body>", "<pre></body></pre>" + reloadScript + "</body>"},
+		{"not html", "text/css", "body{}", "body{}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := injectReload(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", tt.ctype)
+				w.Header().Set("Content-Length", "1")
+				w.WriteHeader(http.StatusTeapot)
+				io.WriteString(w, tt.body)
+			}))
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+			if got := w.Body.String(); got != tt.want {
+				t.Errorf("body = %q, want %q", got, tt.want)
+			}
+			if w.Code != http.StatusTeapot || w.Header().Get("Cache-Control") != "no-store" {
+				t.Errorf("status %d, headers %v", w.Code, w.Header())
+			}
+			if tt.ctype != "text/css" && w.Header().Get("Content-Length") != "" {
+				t.Error("stale Content-Length kept")
+			}
+		})
+	}
+}
+
+func TestIgnored(t *testing.T) {
+	root := t.TempDir()
+	s := &Server{Tree: notes.Open(root), Out: filepath.Join(root, "public")}
+	for p, want := range map[string]bool{
+		"public":            true,
+		"public/index.html": true,
+		"publicity/x.md":    false,
+		"go/public.md":      false,
+	} {
+		if got := s.ignored(p); got != want {
+			t.Errorf("ignored(%s) = %v, want %v", p, got, want)
+		}
+	}
+	// Output outside the tree ignores nothing of it.
+	s.Out = filepath.Join(t.TempDir(), "public")
+	if s.ignored("public/index.html") {
+		t.Error("ignored a file of the tree for output elsewhere")
+	}
+}
+
+func TestRebuildAndReload(t *testing.T) {
+	root := t.TempDir()
+	tree := notes.Open(root)
+	if err := tree.Write("go/a.md", []byte("# A\n")); err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(root, "public")
+	b, err := site.NewBuilder(tree, site.Options{Out: out})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.Build(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{Tree: tree, Builder: b, Out: out, Log: log.New(io.Discard, "", 0), clients: map[chan struct{}]bool{}}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := make(chan store.Event)
+	go s.loop(ctx, events)
+
+	ts := httptest.NewServer(http.HandlerFunc(s.handleEvents))
+	defer ts.Close()
+	req, _ := http.NewRequestWithContext(ctx, "GET", ts.URL, nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("Content-Type = %s", ct)
+	}
+	lines := make(chan string)
+	go func() {
+		sc := bufio.NewScanner(resp.Body)
+		for sc.Scan() {
+			lines <- sc.Text()
+		}
+		close(lines)
+	}()
+	next := func() string {
+		select {
+		case l := <-lines:
+			return l
+		case <-time.After(5 * time.Second):
+			t.Fatal("no event")
+			return ""
+		}
+	}
+	if l := next(); l != ": connected" {
+		t.Fatalf("first line = %q", l)
+	}
+	next()
+
+	if err := tree.Write("go/b.md", []byte("# B\n")); err != nil {
+		t.Fatal(err)
+	}
+	// The writes of a build to its output do not trigger another.
+	events <- store.Event{Path: "public/index.html"}
+	events <- store.Event{Path: "go/b.md"}
+	if l := next(); l != "event: reload" {
+		t.Fatalf("event = %q", l)
+	}
+	if _, err := os.Stat(filepath.Join(out, "go", "b", "index.html")); err != nil {
+		t.Errorf("not rebuilt: %v", err)
+	}
+	next()
+	next()
+	select {
+	case l := <-lines:
+		t.Errorf("unexpected %q", l)
+	case <-time.After(3 * debounce):
+	}
+	if !strings.Contains(reloadScript, eventsPath) {
+		t.Error("the reload script does not listen to the events")
+	}
+}
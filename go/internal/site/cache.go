@@ -0,0 +1,118 @@
+package site
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"fmt"
+	"html/template"
+	"os"
+	"runtime/debug"
+	"strings"
+
+	"github.com/canhta/til/go/internal/fsutil"
+	"github.com/canhta/til/go/pkg/render"
+)
+
+// CacheFile is the file inside the notes state directory holding the
+// rendered entries of the last build.
+const CacheFile = "render-cache.gob"
+
+// cacheVersion is raised when rendering changes in ways the options do not
+// capture, invalidating every cached entry.
+const cacheVersion = 1
+
+// rendered is an entry's markdown rendered to HTML, with what the page
+// template needs to know about it.
+type rendered struct {
+	HTML    template.HTML
+	Mermaid bool
+	// Math and MathScript are Page.Math and Page.MathScript.
+	Math, MathScript bool
+	TOC              []render.Heading
+	// Links records how the links of the entry resolved when it was
+	// rendered. The HTML is current while they resolve the same.
+	Links []lookup
+}
+
+// lookup is the resolution of one link during rendering.
+type lookup struct {
+	Wiki   bool
+	Target string
+	URL    string
+	Title  string
+	OK     bool
+}
+
+// current reports whether the links r recorded for the entry at from still
+// resolve as they did.
+func (s *Site) current(from string, r rendered) bool {
+	for _, l := range r.Links {
+		var now lookup
+		if l.Wiki {
+			now = lookup{Wiki: true, Target: l.Target}
+			now.URL, now.Title, now.OK = s.ResolveWiki(from, l.Target)
+		} else {
+			now = lookup{Target: l.Target}
+			now.URL, now.OK = s.ResolveLink(from, l.Target)
+		}
+		if now != l {
+			return false
+		}
+	}
+	return true
+}
+
+// fingerprint identifies what rendering depends on besides each entry's
+// source: til itself and the renderer options.
+func (b *Builder) fingerprint(mermaid, katex []string) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "v%d\n", cacheVersion)
+	if info, ok := debug.ReadBuildInfo(); ok {
+		sb.WriteString(info.Main.Version + "\n")
+		for _, s := range info.Settings {
+			if s.Key == "vcs.revision" || s.Key == "vcs.modified" {
+				sb.WriteString(s.Key + "=" + s.Value + "\n")
+			}
+		}
+	}
+	fmt.Fprintf(&sb, "highlight=%s\nline-numbers=%t\nmath=%t\nmermaid=%q\nkatex=%q\n",
+		b.opts.Highlight, b.opts.LineNumbers, b.opts.Math, mermaid, katex)
+	return sb.String()
+}
+
+// renderKey addresses the rendering of src, the markdown of the entry at
+// path with its includes expanded.
+func (b *Builder) renderKey(path string, src []byte) string {
+	h := sha256.New()
+	h.Write([]byte(b.version))
+	h.Write([]byte{0})
+	h.Write([]byte(path))
+	h.Write([]byte{0})
+	h.Write(src)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// loadCache reads the entries rendered by the last build, if any. A cache
+// that cannot be read is started afresh.
+func (b *Builder) loadCache() map[string]rendered {
+	cache := map[string]rendered{}
+	data, err := os.ReadFile(b.tree.StatePath(CacheFile))
+	if err != nil {
+		return cache
+	}
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&cache); err != nil {
+		return map[string]rendered{}
+	}
+	return cache
+}
+
+// saveCache writes the rendered entries for the next build.
+func (b *Builder) saveCache() error {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(b.cache); err != nil {
+		return err
+	}
+	return fsutil.WriteFile(b.tree.StatePath(CacheFile), buf.Bytes(), 0o644)
+}
@@ -0,0 +1,81 @@
+package site
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestBuildCache(t *testing.T) {
+	tree := newTree(t, map[string]string{
+		"go/slices.md":  "---\ntitle: Slices\n---\n\nSee [[go/maps]] and [[go/generics]].\n",
+		"go/maps.md":    "---\ntitle: Maps\n---\n\nMaps are *unordered*.\n",
+		"git/rebase.md": "---\ntitle: Rebase\n---\n\nUse `--onto`.\n",
+	})
+	out := filepath.Join(t.TempDir(), "public")
+	// Each build has a builder of its own, as each til build does.
+	rendered := func(opts Options) []string {
+		t.Helper()
+		opts.Out = out
+		s, _ := build(t, tree, opts)
+		return slices.Sorted(slices.Values(s.Rendered))
+	}
+	write := func(p, data string) {
+		t.Helper()
+		if err := tree.Write(p, []byte(data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	all := []string{"git/rebase.md", "go/maps.md", "go/slices.md"}
+	if got := rendered(Options{}); !slices.Equal(got, all) {
+		t.Errorf("first build rendered %q", got)
+	}
+	if got := rendered(Options{}); len(got) != 0 {
+		t.Errorf("unchanged build rendered %q", got)
+	}
+	if _, err := os.Stat(tree.StatePath(CacheFile)); err != nil {
+		t.Errorf("no cache: %v", err)
+	}
+
+	write("git/rebase.md", "---\ntitle: Rebase\n---\n\nUse `--onto` twice.\n")
+	if got := rendered(Options{}); !slices.Equal(got, []string{"git/rebase.md"}) {
+		t.Errorf("after editing git/rebase rendered %q", got)
+	}
+	if page := readOut(t, out, "git/rebase/index.html"); !strings.Contains(page, "twice") {
+		t.Errorf("git/rebase not updated:\n%s", page)
+	}
+
+	// A page is rendered again when a link of it resolves differently: to
+	// a retitled entry, or to one that did not exist. Its own title is not
+	// part of the body rendered.
+	write("go/maps.md", "---\ntitle: Hash maps\n---\n\nMaps are *unordered*.\n")
+	if got := rendered(Options{}); !slices.Equal(got, []string{"go/slices.md"}) {
+		t.Errorf("after retitling go/maps rendered %q", got)
+	}
+	if page := readOut(t, out, "go/maps/index.html"); !strings.Contains(page, "<title>Hash maps") {
+		t.Errorf("go/maps not retitled:\n%s", page)
+	}
+	if page := readOut(t, out, "go/slices/index.html"); !strings.Contains(page, "Hash maps") {
+		t.Errorf("go/slices does not link the new title:\n%s", page)
+	}
+	write("go/generics.md", "---\ntitle: Generics\n---\n\nType parameters.\n")
+	if got := rendered(Options{}); !slices.Equal(got, []string{"go/generics.md", "go/slices.md"}) {
+		t.Errorf("after adding go/generics rendered %q", got)
+	}
+
+	if got := rendered(Options{Force: true}); len(got) != 4 {
+		t.Errorf("--force rendered %q", got)
+	}
+	if got := rendered(Options{LineNumbers: true}); len(got) != 4 {
+		t.Errorf("new renderer options rendered %q", got)
+	}
+	if err := os.WriteFile(tree.StatePath(CacheFile), []byte("garbage"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if got := rendered(Options{LineNumbers: true}); len(got) != 4 {
+		t.Errorf("with a corrupt cache rendered %q", got)
+	}
+}
@@ -0,0 +1,64 @@
+package site
+
+import (
+	"context"
+	"time"
+
+	"github.com/canhta/til/go/internal/changelog"
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+// ChangelogMonths is the number of months the changelog page covers.
+const ChangelogMonths = 12
+
+// Changes are the entries added and updated in one month of the changelog.
+type Changes struct {
+	// Month is the first day of the month.
+	Month          time.Time
+	Added, Updated []Change
+}
+
+// Change is an entry of the changelog.
+type Change struct {
+	Page *Page
+	Date time.Time
+	// Words is the number of words an update added and removed, zero when
+	// only its updated frontmatter tells of it.
+	Words int
+}
+
+// loadChangelog sets the Changelog of the site from the entries added and
+// updated over the last ChangelogMonths months, as package changelog
+// tells, when the theme can show them.
+func (b *Builder) loadChangelog(ctx context.Context) error {
+	if _, ok := b.opts.Templates.pages["changelog.html"]; !ok {
+		return nil
+	}
+	entries := make([]*entry.Entry, len(b.site.Pages))
+	for i, p := range b.site.Pages {
+		entries[i] = p.Entry
+	}
+	now := time.Now()
+	periods := changelog.Months(now, ChangelogMonths)
+	if err := changelog.Compose(ctx, b.tree, entries, periods, changelog.Options{Now: now}); err != nil {
+		return err
+	}
+	b.site.Changelog = nil
+	for _, p := range periods {
+		if len(p.Changes) == 0 {
+			continue
+		}
+		cs := &Changes{Month: p.From}
+		for _, c := range p.Changes {
+			pc := Change{Page: b.site.byPath[c.Path], Date: c.Date, Words: c.Words}
+			if c.Kind == changelog.Added {
+				cs.Added = append(cs.Added, pc)
+			} else {
+				cs.Updated = append(cs.Updated, pc)
+			}
+		}
+		b.site.Changelog = append(b.site.Changelog, cs)
+	}
+	b.site.ChangelogURL = b.site.Base + "changes/"
+	return nil
+}
@@ -0,0 +1,44 @@
+package site
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuildChangelog(t *testing.T) {
+	today := time.Now().Format(time.DateOnly)
+	tree := newTree(t, map[string]string{
+		"go/slices.md": "---\ntitle: Slices\ndate: " + today + "\n---\n\nSlices.\n",
+		"go/maps.md":   "---\ntitle: Maps\ndate: 2020-01-01\nupdated: " + today + "\n---\n\nMaps.\n",
+		"go/chans.md":  "---\ntitle: Channels\ndate: 2020-01-01\n---\n\nChannels.\n",
+		"go/draft.md":  "---\ntitle: Draft\ndate: " + today + "\ndraft: true\n---\n\nDraft.\n",
+	})
+	s, out := build(t, tree, Options{Changelog: true})
+	if len(s.Changelog) != 1 || s.ChangelogURL != "/changes/" {
+		t.Fatalf("changelog = %v at %q", s.Changelog, s.ChangelogURL)
+	}
+	cs := s.Changelog[0]
+	if len(cs.Added) != 1 || cs.Added[0].Page.Entry.Path != "go/slices.md" || len(cs.Updated) != 1 || cs.Updated[0].Page.Entry.Path != "go/maps.md" {
+		t.Errorf("changes = %+v", cs)
+	}
+	page := readOut(t, out, "changes/index.html")
+	for _, want := range []string{"<h2>" + time.Now().Format("January 2006") + "</h2>", `<h3>Added</h3>`, `<a href="/go/slices/">Slices</a>`, `<h3>Updated</h3>`, `<a href="/go/maps/">Maps</a>`} {
+		if !strings.Contains(page, want) {
+			t.Errorf("changes/index.html lacks %s:\n%s", want, page)
+		}
+	}
+	for _, absent := range []string{"Channels", "Draft"} {
+		if strings.Contains(page, absent) {
+			t.Errorf("changes/index.html lists %s:\n%s", absent, page)
+		}
+	}
+	if !strings.Contains(readOut(t, out, "index.html"), `href="/changes/"`) {
+		t.Error("index.html does not link to the changelog")
+	}
+
+	s, out = build(t, tree, Options{})
+	if s.Changelog != nil || exists(out, "changes/index.html") {
+		t.Error("changelog published without Options.Changelog")
+	}
+}
@@ -0,0 +1,254 @@
+package site
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+// feed is a set of pages published as RSS and Atom.
+type feed struct {
+	title string
+	// dir is the URL path of the directory holding feed.xml and atom.xml.
+	dir   string
+	pages []*Page
+}
+
+// TagDir returns the URL path of the directory for tag's pages and feeds.
+func (s *Site) TagDir(tag string) string {
+	slug := entry.Slugify(tag)
+	if slug == "" {
+		slug = url.PathEscape(strings.ToLower(tag))
+	}
+	return s.Base + "tags/" + slug + "/"
+}
+
+// feeds returns the site-wide feed followed by one feed per tag.
+func (s *Site) feeds(limit int) []feed {
+	if limit <= 0 {
+		limit = 20
+	}
+	clip := func(pages []*Page) []*Page {
+		if len(pages) > limit {
+			return pages[:limit]
+		}
+		return pages
+	}
+	feeds := []feed{{title: s.Title, dir: s.Base, pages: clip(s.Pages)}}
+	byTag := map[string][]*Page{}
+	for _, p := range s.Pages {
+		for _, t := range p.Tags {
+			byTag[t] = append(byTag[t], p)
+		}
+	}
+	tags := make([]string, 0, len(byTag))
+	for t := range byTag {
+		tags = append(tags, t)
+	}
+	sort.Strings(tags)
+	for _, t := range tags {
+		feeds = append(feeds, feed{title: s.Title + " · #" + t, dir: s.TagDir(t), pages: clip(byTag[t])})
+	}
+	return feeds
+}
+
+// writeFeeds writes feed.xml (RSS 2.0) and atom.xml for every feed.
+func (s *Site) writeFeeds(w *Writer, limit int) error {
+	for _, f := range s.feeds(limit) {
+		rel := strings.TrimPrefix(f.dir, s.Base)
+		rss, err := s.rss(f)
+		if err != nil {
+			return err
+		}
+		if err := w.Write(rel+"feed.xml", rss); err != nil {
+			return err
+		}
+		atom, err := s.atom(f)
+		if err != nil {
+			return err
+		}
+		if err := w.Write(rel+"atom.xml", atom); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GUID returns the stable identifier of a page in feeds: a tag URI
+// (RFC 4151) built from the site host, the creation date and the entry's
+// category and slug, so it survives URL layout changes.
+func (s *Site) GUID(p *Page) string {
+	host := strings.TrimPrefix(strings.TrimPrefix(s.Origin, "https://"), "http://")
+	date := "2000-01-01"
+	if !p.Date.IsZero() {
+		date = p.Date.Format(entry.DateLayout)
+	}
+	return fmt.Sprintf("tag:%s,%s:%s/%s", host, date, p.Entry.Meta.Category, p.Entry.Meta.Slug)
+}
+
+// absContent rewrites root-relative links in rendered HTML to absolute
+// URLs, since feed readers resolve links against the feed URL, if at all.
+func (s *Site) absContent(html string) string {
+	return strings.NewReplacer(`href="/`, `href="`+s.Origin+`/`, `src="/`, `src="`+s.Origin+`/`).Replace(html)
+}
+
+func (s *Site) updated(pages []*Page) time.Time {
+	var t time.Time
+	for _, p := range pages {
+		if u := p.Entry.LastUpdated(); u.After(t) {
+			t = u
+		}
+	}
+	if t.IsZero() {
+		t = time.Now()
+	}
+	return t.UTC()
+}
+
+type rssDoc struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	AtomNS  string     `xml:"xmlns:atom,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate"`
+	Self          atomLink  `xml:"atom:link"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string   `xml:"title"`
+	Link        string   `xml:"link"`
+	GUID        rssGUID  `xml:"guid"`
+	PubDate     string   `xml:"pubDate,omitempty"`
+	Categories  []string `xml:"category"`
+	Description string   `xml:"description"`
+}
+
+type rssGUID struct {
+	IsPermaLink string `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+func (s *Site) rss(f feed) ([]byte, error) {
+	doc := rssDoc{
+		Version: "2.0",
+		AtomNS:  "http://www.w3.org/2005/Atom",
+		Channel: rssChannel{
+			Title:         f.title,
+			Link:          s.AbsURL(f.dir),
+			Description:   f.title,
+			LastBuildDate: s.updated(f.pages).Format(time.RFC1123Z),
+			Self:          atomLink{Href: s.AbsURL(f.dir + "feed.xml"), Rel: "self", Type: "application/rss+xml"},
+		},
+	}
+	for _, p := range f.pages {
+		item := rssItem{
+			Title:       p.Title,
+			Link:        s.AbsURL(p.URL),
+			GUID:        rssGUID{IsPermaLink: "false", Value: s.GUID(p)},
+			Categories:  p.Tags,
+			Description: s.absContent(string(p.Content)),
+		}
+		if !p.Date.IsZero() {
+			item.PubDate = p.Date.Format(time.RFC1123Z)
+		}
+		doc.Channel.Items = append(doc.Channel.Items, item)
+	}
+	return marshalXML(doc)
+}
+
+type atomDoc struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomEntry struct {
+	Title      string         `xml:"title"`
+	ID         string         `xml:"id"`
+	Link       atomLink       `xml:"link"`
+	Published  string         `xml:"published,omitempty"`
+	Updated    string         `xml:"updated"`
+	Author     *atomAuthor    `xml:"author"`
+	Categories []atomCategory `xml:"category"`
+	Content    atomContent    `xml:"content"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+	URI  string `xml:"uri"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomContent struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+func (s *Site) atom(f feed) ([]byte, error) {
+	doc := atomDoc{
+		Title:   f.title,
+		ID:      s.AbsURL(f.dir),
+		Updated: s.updated(f.pages).Format(time.RFC3339),
+		Links: []atomLink{
+			{Href: s.AbsURL(f.dir + "atom.xml"), Rel: "self", Type: "application/atom+xml"},
+			{Href: s.AbsURL(f.dir), Rel: "alternate", Type: "text/html"},
+		},
+	}
+	for _, p := range f.pages {
+		e := atomEntry{
+			Title:   p.Title,
+			ID:      s.GUID(p),
+			Link:    atomLink{Href: s.AbsURL(p.URL), Rel: "alternate"},
+			Updated: p.Entry.LastUpdated().UTC().Format(time.RFC3339),
+			Content: atomContent{Type: "html", Value: s.absContent(string(p.Content))},
+		}
+		if !p.Date.IsZero() {
+			e.Published = p.Date.UTC().Format(time.RFC3339)
+		}
+		if p.Author != nil {
+			e.Author = &atomAuthor{Name: p.Author.Name, URI: s.AbsURL(p.Author.URL)}
+		}
+		for _, t := range p.Tags {
+			e.Categories = append(e.Categories, atomCategory{Term: t})
+		}
+		doc.Entries = append(doc.Entries, e)
+	}
+	return marshalXML(doc)
+}
+
+func marshalXML(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	enc := xml.NewEncoder(&buf)
+	enc.Indent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,81 @@
+package site
+
+import (
+	"encoding/xml"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestFeeds(t *testing.T) {
+	tree := newTree(t, map[string]string{
+		"go/slices.md":  "---\ntitle: Slices share arrays\ndate: 2024-06-01\ntags: [go, slices]\n---\n\nSee [maps](maps.md).\n",
+		"go/maps.md":    "---\ntitle: Maps\ndate: 2024-05-01\ntags: [go]\nslug: go-maps\n---\n\nMaps are *unordered*.\n",
+		"git/rebase.md": "---\ntitle: Rebase onto\ndate: 2023-01-10\n---\n\nUse `--onto`.\n",
+	})
+	_, out := build(t, tree, Options{Title: "My TIL", BaseURL: "https://example.com/til/", FeedLimit: 2})
+
+	feed := readOut(t, out, "feed.xml")
+	for _, want := range []string{
+		"<link>https://example.com/til/</link>",
+		`<atom:link href="https://example.com/til/feed.xml" rel="self" type="application/rss+xml"></atom:link>`,
+	} {
+		if !strings.Contains(feed, want) {
+			t.Errorf("feed.xml lacks %s:\n%s", want, feed)
+		}
+	}
+	var rss rssDoc
+	if err := xml.Unmarshal([]byte(feed), &rss); err != nil {
+		t.Fatal(err)
+	}
+	ch := rss.Channel
+	if ch.Title != "My TIL" {
+		t.Errorf("channel = %+v", ch)
+	}
+	if len(ch.Items) != 2 {
+		t.Fatalf("%d items, want the limit of 2", len(ch.Items))
+	}
+	item := ch.Items[0]
+	if item.Title != "Slices share arrays" || item.Link != "https://example.com/til/go/slices/" ||
+		item.GUID.Value != "tag:example.com,2024-06-01:go/slices" || item.GUID.IsPermaLink != "false" ||
+		item.PubDate != "Sat, 01 Jun 2024 00:00:00 +0000" || !slices.Equal(item.Categories, []string{"go", "slices"}) {
+		t.Errorf("item = %+v", item)
+	}
+	if !strings.Contains(item.Description, `href="https://example.com/til/go/go-maps/"`) {
+		t.Errorf("content links are not absolute: %s", item.Description)
+	}
+	if ch.Items[1].GUID.Value != "tag:example.com,2024-05-01:go/go-maps" {
+		t.Errorf("GUID of go/maps.md = %s, want it from the slug", ch.Items[1].GUID.Value)
+	}
+
+	var atom atomDoc
+	if err := xml.Unmarshal([]byte(readOut(t, out, "atom.xml")), &atom); err != nil {
+		t.Fatal(err)
+	}
+	if atom.Title != "My TIL" || atom.ID != "https://example.com/til/" || len(atom.Entries) != 2 {
+		t.Fatalf("atom = %+v", atom)
+	}
+	e := atom.Entries[0]
+	if e.ID != item.GUID.Value || e.Published != "2024-06-01T00:00:00Z" || e.Content.Type != "html" ||
+		!strings.Contains(e.Content.Value, "<p>See ") || len(e.Categories) != 2 {
+		t.Errorf("atom entry = %+v", e)
+	}
+
+	var tag rssDoc
+	if err := xml.Unmarshal([]byte(readOut(t, out, "tags/slices/feed.xml")), &tag); err != nil {
+		t.Fatal(err)
+	}
+	if ch := tag.Channel; ch.Title != "My TIL · #slices" || len(ch.Items) != 1 || ch.Items[0].Title != "Slices share arrays" {
+		t.Errorf("tags/slices/feed.xml = %+v", ch)
+	}
+	if !exists(out, "tags/go/atom.xml") || !exists(out, "tags/go/feed.xml") {
+		t.Error("no feeds for tag go")
+	}
+}
+
+func TestFeedsNeedAbsoluteBase(t *testing.T) {
+	_, out := build(t, newTree(t, siteFiles), Options{})
+	if exists(out, "feed.xml") || exists(out, "atom.xml") {
+		t.Error("feeds written without an absolute base URL")
+	}
+}
@@ -0,0 +1,72 @@
+package site
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/canhta/til/go/internal/notes"
+)
+
+// commitAll commits every file of tree with message, making tree a git
+// repository first if need be, isolated from the user's git config.
+func commitAll(t *testing.T, tree *notes.Tree, message string) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	for _, v := range []string{"GIT_AUTHOR", "GIT_COMMITTER"} {
+		t.Setenv(v+"_NAME", "Ann")
+		t.Setenv(v+"_EMAIL", "ann@example.com")
+	}
+	for _, args := range [][]string{{"init", "--quiet"}, {"add", "-A"}, {"commit", "--quiet", "-m", message}} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = tree.Root
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+		}
+	}
+}
+
+func TestBuildHistory(t *testing.T) {
+	tree := newTree(t, map[string]string{"go/slices.md": "# Slices\n"})
+	commitAll(t, tree, "Add slices")
+	if err := tree.Write("go/slices.md", []byte("# Slices\n\nThey share arrays.\n")); err != nil {
+		t.Fatal(err)
+	}
+	commitAll(t, tree, "Explain slices")
+	if err := tree.Write("go/maps.md", []byte("# Maps\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	s, out := build(t, tree, Options{History: true})
+	page := s.Page("go/slices.md")
+	if page == nil || len(page.History) != 2 || page.HistoryURL != "/go/slices/history/" {
+		t.Fatalf("page = %+v", page)
+	}
+	if !strings.Contains(readOut(t, out, "go/slices/index.html"), `href="/go/slices/history/"`) {
+		t.Error("entry page does not link its history")
+	}
+	hist := readOut(t, out, "go/slices/history/index.html")
+	if i, j := strings.Index(hist, "Explain slices"), strings.Index(hist, "Add slices"); i < 0 || j < i || !strings.Contains(hist, "Ann") {
+		t.Errorf("history page =\n%s", hist)
+	}
+	if exists(out, "go/maps/history/index.html") || s.Page("go/maps.md").HistoryURL != "" {
+		t.Error("uncommitted entry has a history page")
+	}
+
+	_, out = build(t, tree, Options{})
+	if exists(out, "go/slices/history/index.html") || strings.Contains(readOut(t, out, "go/slices/index.html"), "history-link") {
+		t.Error("history built without Options.History")
+	}
+}
+
+func TestBuildHistoryNoRepo(t *testing.T) {
+	tree := newTree(t, map[string]string{"go/slices.md": "# Slices\n"})
+	s, out := build(t, tree, Options{History: true})
+	if s.Page("go/slices.md").HistoryURL != "" || exists(out, "go/slices/history/index.html") {
+		t.Error("history built outside a git repository")
+	}
+}
@@ -0,0 +1,140 @@
+package site
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/language/display"
+
+	"github.com/canhta/til/go/internal/links"
+)
+
+// Alternate is a version of a page in another language.
+type Alternate struct {
+	// Lang is a language tag, or "x-default" for the page to show readers
+	// of the other languages.
+	Lang string
+	URL  string
+}
+
+// LangURL returns the URL path of the listing of the entries in the
+// language tag.
+func (s *Site) LangURL(tag string) string {
+	return s.Base + "lang/" + url.PathEscape(strings.ToLower(tag)) + "/"
+}
+
+// LangName returns the name of the language tag in that language, such as
+// "Tiếng Việt" for vi, or the tag itself when it is unknown.
+func (s *Site) LangName(tag string) string {
+	t, err := language.Parse(tag)
+	if err != nil {
+		return tag
+	}
+	if name := display.Self.Name(t); name != "" {
+		return name
+	}
+	return tag
+}
+
+// languages lists the entries of each language, when there are several,
+// and links the translations of each entry to one another.
+func (s *Site) languages() {
+	byLang := map[string]*Category{}
+	for _, p := range s.Pages {
+		key := strings.ToLower(p.Lang)
+		c := byLang[key]
+		if c == nil {
+			c = &Category{Name: s.LangName(p.Lang), Lang: p.Lang, URL: s.LangURL(p.Lang)}
+			byLang[key] = c
+		}
+		c.Pages = append(c.Pages, p)
+	}
+	if len(byLang) > 1 {
+		for _, c := range byLang {
+			s.Langs = append(s.Langs, c)
+		}
+		sort.Slice(s.Langs, func(i, j int) bool { return s.Langs[i].Lang < s.Langs[j].Lang })
+	}
+	groups := map[*Page][]*Page{}
+	for _, p := range s.Pages {
+		o := s.original(p)
+		groups[o] = append(groups[o], p)
+	}
+	for _, g := range groups {
+		if len(g) < 2 {
+			continue
+		}
+		for _, p := range g {
+			for _, t := range g {
+				if t != p {
+					p.Translations = append(p.Translations, t)
+				}
+			}
+			sort.Slice(p.Translations, func(i, j int) bool { return p.Translations[i].Lang < p.Translations[j].Lang })
+		}
+	}
+}
+
+// original follows the translation_of references from p to the entry
+// first written, which a cycle of references makes the one of them with
+// the least path.
+func (s *Site) original(p *Page) *Page {
+	chain := []*Page{p}
+	for {
+		of := p.Entry.Meta.TranslationOf
+		if of == "" {
+			return p
+		}
+		e, res := s.links.Resolve(p.Entry.Path, links.Link{Target: of, Wiki: true})
+		if res != links.Resolved {
+			if len(chain) == 1 {
+				s.TranslationErrors = append(s.TranslationErrors, fmt.Errorf("%s: translation_of %q: %s", p.Entry.Path, of, describe(res)))
+			}
+			return p
+		}
+		q := s.byPath[e.Path]
+		if i := slices.Index(chain, q); i >= 0 {
+			cycle := chain[i:]
+			return slices.MinFunc(cycle, func(a, b *Page) int { return strings.Compare(a.Entry.Path, b.Entry.Path) })
+		}
+		chain = append(chain, q)
+		p = q
+	}
+}
+
+func describe(res links.Resolution) string {
+	if res == links.Ambiguous {
+		return "names several entries"
+	}
+	return "names no entry"
+}
+
+// alternates are the hreflang links of p's page, to itself and its
+// translations.
+func (p *Page) alternates() []Alternate {
+	if len(p.Translations) == 0 {
+		return nil
+	}
+	out := []Alternate{{Lang: p.Lang, URL: p.URL}}
+	for _, t := range p.Translations {
+		out = append(out, Alternate{Lang: t.Lang, URL: t.URL})
+	}
+	return out
+}
+
+// langAlternates are the hreflang links of the index and the language
+// listings, to one another.
+func (s *Site) langAlternates() []Alternate {
+	if len(s.Langs) == 0 {
+		return nil
+	}
+	out := []Alternate{{Lang: "x-default", URL: s.Base}}
+	for _, c := range s.Langs {
+		out = append(out, Alternate{Lang: c.Lang, URL: c.URL})
+	}
+	return out
+}
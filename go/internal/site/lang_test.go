@@ -0,0 +1,105 @@
+package site
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func langs(ps []*Page) []string {
+	var out []string
+	for _, p := range ps {
+		out = append(out, p.Lang+":"+p.Entry.Path)
+	}
+	return out
+}
+
+func TestLanguages(t *testing.T) {
+	tree := newTree(t, map[string]string{
+		"go/slices.md":    "---\ntitle: Slices\ndate: 2024-06-01\n---\n\nSlices share arrays.\n",
+		"go/slices-vi.md": "---\ntitle: Lát cắt\ndate: 2024-06-02\nlang: vi\ntranslation_of: slices\n---\n\nLát cắt dùng chung mảng.\n",
+		"go/slices-fr.md": "---\ntitle: Tranches\ndate: 2024-06-03\nlang: fr\ntranslation_of: slices-vi\n---\n\nLes tranches.\n",
+		"go/maps.md":      "---\ntitle: Maps\ndate: 2024-05-01\n---\n\nMaps.\n",
+		// A cycle of references, and one to no entry.
+		"git/a.md":    "---\ntitle: A\nlang: de\ntranslation_of: b\n---\n\nA.\n",
+		"git/b.md":    "---\ntitle: B\ntranslation_of: a\n---\n\nB.\n",
+		"git/lost.md": "---\ntitle: Lost\nlang: vi\ntranslation_of: nowhere\n---\n\nLost.\n",
+	})
+	s, out := build(t, tree, Options{BaseURL: "https://til.example/"})
+
+	var listings []string
+	for _, c := range s.Langs {
+		listings = append(listings, c.Lang+" "+c.Name+" "+c.URL)
+	}
+	if want := []string{"de Deutsch /lang/de/", "en English /lang/en/", "fr français /lang/fr/", "vi Tiếng Việt /lang/vi/"}; !slices.Equal(listings, want) {
+		t.Errorf("languages = %q, want %q", listings, want)
+	}
+	if got := langs(s.Langs[3].Pages); !slices.Equal(got, []string{"vi:go/slices-vi.md", "vi:git/lost.md"}) {
+		t.Errorf("vi pages = %q", got)
+	}
+
+	tests := []struct {
+		path string
+		want []string
+	}{
+		// Translations of translations are of the original still.
+		{"go/slices.md", []string{"fr:go/slices-fr.md", "vi:go/slices-vi.md"}},
+		{"go/slices-vi.md", []string{"en:go/slices.md", "fr:go/slices-fr.md"}},
+		{"go/slices-fr.md", []string{"en:go/slices.md", "vi:go/slices-vi.md"}},
+		{"go/maps.md", nil},
+		{"git/a.md", []string{"en:git/b.md"}},
+		{"git/b.md", []string{"de:git/a.md"}},
+		{"git/lost.md", nil},
+	}
+	for _, tt := range tests {
+		if got := langs(s.byPath[tt.path].Translations); !slices.Equal(got, tt.want) {
+			t.Errorf("translations of %s = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+	if len(s.TranslationErrors) != 1 || !strings.Contains(s.TranslationErrors[0].Error(), `git/lost.md: translation_of "nowhere": names no entry`) {
+		t.Errorf("translation errors = %v", s.TranslationErrors)
+	}
+
+	page := readOut(t, out, "go/slices-vi/index.html")
+	for _, want := range []string{
+		`<html lang="vi">`,
+		`<link rel="alternate" hreflang="vi" href="https://til.example/go/slices-vi/">`,
+		`<link rel="alternate" hreflang="en" href="https://til.example/go/slices/">`,
+		`<link rel="alternate" hreflang="fr" href="https://til.example/go/slices-fr/">`,
+		`<a href="/go/slices/" hreflang="en" lang="en">English</a>, <a href="/go/slices-fr/" hreflang="fr" lang="fr">français</a>`,
+	} {
+		if !strings.Contains(page, want) {
+			t.Errorf("go/slices-vi/index.html lacks %s:\n%s", want, page)
+		}
+	}
+	if page := readOut(t, out, "go/maps/index.html"); !strings.Contains(page, `<html lang="en">`) || strings.Contains(page, "hreflang") {
+		t.Errorf("go/maps/index.html =\n%s", page)
+	}
+	listing := readOut(t, out, "lang/vi/index.html")
+	if !strings.Contains(listing, `<html lang="vi">`) || !strings.Contains(listing, `href="/go/slices-vi/"`) || strings.Contains(listing, `href="/go/maps/"`) {
+		t.Errorf("lang/vi/index.html =\n%s", listing)
+	}
+	index := readOut(t, out, "index.html")
+	for _, want := range []string{`<link rel="alternate" hreflang="x-default" href="https://til.example/">`, `<a href="/lang/vi/" hreflang="vi" lang="vi">Tiếng Việt (2)</a>`} {
+		if !strings.Contains(index, want) {
+			t.Errorf("index.html lacks %s:\n%s", want, index)
+		}
+	}
+}
+
+func TestLanguagesOne(t *testing.T) {
+	tree := newTree(t, map[string]string{
+		"go/slices.md": "---\ntitle: Lát cắt\n---\n\nLát cắt.\n",
+		"go/maps.md":   "---\ntitle: Bản đồ\nlang: VI\n---\n\nBản đồ.\n",
+	})
+	s, out := build(t, tree, Options{Lang: "vi"})
+	if len(s.Langs) != 0 {
+		t.Errorf("languages = %v, want none for a site in one language", s.Langs)
+	}
+	if exists(out, "lang/vi/index.html") {
+		t.Error("lang/vi/index.html written for a site in one language")
+	}
+	if index := readOut(t, out, "index.html"); !strings.Contains(index, `<html lang="vi">`) || strings.Contains(index, "hreflang") {
+		t.Errorf("index.html =\n%s", index)
+	}
+}
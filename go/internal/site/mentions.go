@@ -0,0 +1,48 @@
+package site
+
+import "github.com/canhta/til/go/internal/webmention"
+
+// matchMentions lists each of ms under the page it mentions, at its URL or
+// one the page moved from.
+func (s *Site) matchMentions(ms []webmention.Received) {
+	if len(ms) == 0 || s.Origin == "" {
+		return
+	}
+	byURL := map[string]*Page{}
+	for _, p := range s.Pages {
+		byURL[webmention.Key(s.AbsURL(p.URL))] = p
+	}
+	for _, r := range s.Redirects {
+		if p := byURL[webmention.Key(s.AbsURL(r.To))]; p != nil {
+			byURL[webmention.Key(s.AbsURL(r.From))] = p
+		}
+	}
+	for _, m := range ms {
+		if p := byURL[webmention.Key(m.Target)]; p != nil {
+			p.Mentions = append(p.Mentions, m)
+		}
+	}
+}
+
+// Reactions are the likes, reposts and bookmarks among the page's
+// mentions.
+func (p *Page) Reactions() []webmention.Received {
+	var out []webmention.Received
+	for _, m := range p.Mentions {
+		if m.Reaction() {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+// Replies are the replies and other mentions of the page, which have text.
+func (p *Page) Replies() []webmention.Received {
+	var out []webmention.Received
+	for _, m := range p.Mentions {
+		if !m.Reaction() {
+			out = append(out, m)
+		}
+	}
+	return out
+}
@@ -0,0 +1,58 @@
+package site
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/canhta/til/go/internal/webmention"
+)
+
+func TestBuildMentions(t *testing.T) {
+	tree := newTree(t, map[string]string{
+		"go/slices.md": "---\ntitle: Slices\ndate: 2024-06-01\n---\n\nSlices.\n",
+		"go/maps.md":   "---\ntitle: Maps\ndate: 2024-05-01\n---\n\nMaps.\n",
+	})
+	opts := Options{BaseURL: "https://til.example/", Webmention: "https://webmention.io/til.example/webmention"}
+	build(t, tree, opts)
+	// The slug changes, so mentions of the old URL are of the new one.
+	if err := tree.Write("go/slices.md", []byte("---\ntitle: Slices\ndate: 2024-06-01\nslug: shared\n---\n\nSlices.\n")); err != nil {
+		t.Fatal(err)
+	}
+	opts.Mentions = []webmention.Received{
+		{Kind: webmention.Reply, Target: "https://til.example/go/shared/", Source: "https://bob.example/r", Author: webmention.Author{Name: "Bob"}, Published: time.Date(2024, 7, 2, 0, 0, 0, 0, time.UTC), Text: "Nice <b>find</b>."},
+		{Kind: webmention.Like, Target: "https://www.til.example/go/slices#top", Source: "https://ann.example/l", Author: webmention.Author{Name: "Ann", Photo: "https://ann.example/me.jpg"}},
+		{Kind: webmention.Mention, Target: "https://til.example/go/gone/", Source: "https://x.example/"},
+	}
+	s, out := build(t, tree, opts)
+
+	p := s.byPath["go/slices.md"]
+	if len(p.Mentions) != 2 || len(p.Reactions()) != 1 || len(p.Replies()) != 1 || p.Replies()[0].Author.Name != "Bob" {
+		t.Errorf("mentions = %+v", p.Mentions)
+	}
+	if got := s.byPath["go/maps.md"].Mentions; len(got) != 0 {
+		t.Errorf("go/maps.md mentions = %+v", got)
+	}
+	page := readOut(t, out, "go/shared/index.html")
+	for _, want := range []string{
+		`<link rel="webmention" href="https://webmention.io/til.example/webmention">`,
+		`<aside class="mentions" id="mentions">`,
+		`<a class="reaction like" href="https://ann.example/l" title="Ann: like"><img src="https://ann.example/me.jpg" alt="Ann"`,
+		`<li class="reply"><p class="meta"><a href="https://bob.example/r">Bob</a>`,
+		`<time datetime="2024-07-02">Jul 2, 2024</time>`,
+		`<p>Nice &lt;b&gt;find&lt;/b&gt;.</p>`,
+	} {
+		if !strings.Contains(page, want) {
+			t.Errorf("go/shared/index.html lacks %s:\n%s", want, page)
+		}
+	}
+	if page := readOut(t, out, "go/maps/index.html"); strings.Contains(page, `class="mentions"`) {
+		t.Errorf("go/maps/index.html lists mentions:\n%s", page)
+	}
+
+	// Without an absolute base URL mentions match no page.
+	s, _ = build(t, tree, Options{Mentions: opts.Mentions})
+	if got := s.byPath["go/slices.md"].Mentions; len(got) != 0 {
+		t.Errorf("mentions without a base URL = %+v", got)
+	}
+}
@@ -0,0 +1,201 @@
+package site
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/canhta/til/go/internal/fsutil"
+	"github.com/canhta/til/go/internal/notes"
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+// DefaultPermalink is the pattern of entry URLs unless configured.
+const DefaultPermalink = "/:category/:slug/"
+
+// PermalinkFile records, in the notes state directory, the URLs each entry
+// was published at, so that the site redirects from those it moved from.
+const PermalinkFile = "permalinks.json"
+
+// RedirectsFile lists the site's redirects, one "from to 301" line each,
+// in the format of Netlify and Cloudflare Pages. Pages at the old URLs
+// redirect browsers too, for hosts that do not read it.
+const RedirectsFile = "_redirects"
+
+var permalinkTokenRE = regexp.MustCompile(`:[a-z]+`)
+
+// permalinkTokens are the tokens of a permalink pattern. Date tokens are
+// those of the entry's creation date.
+var permalinkTokens = []string{":category", ":slug", ":year", ":month", ":day"}
+
+// CheckPermalink reports whether pattern is a valid permalink pattern: a
+// path made of permalinkTokens and literal text, with :slug in it.
+func CheckPermalink(pattern string) error {
+	for _, t := range permalinkTokenRE.FindAllString(pattern, -1) {
+		if !slices.Contains(permalinkTokens, t) {
+			return fmt.Errorf("permalink %q: unknown token %s: want %s", pattern, t, strings.Join(permalinkTokens, ", "))
+		}
+	}
+	if !strings.Contains(pattern, ":slug") {
+		return fmt.Errorf("permalink %q: has no :slug", pattern)
+	}
+	return nil
+}
+
+// permalink returns the URL path of e below base, following pattern.
+// Undated entries follow DefaultPermalink when pattern has date tokens.
+func permalink(base, pattern string, e *entry.Entry) string {
+	if pattern == "" {
+		pattern = DefaultPermalink
+	}
+	d := e.Meta.Date
+	if d.IsZero() && (strings.Contains(pattern, ":year") || strings.Contains(pattern, ":month") || strings.Contains(pattern, ":day")) {
+		pattern = DefaultPermalink
+	}
+	u := permalinkTokenRE.ReplaceAllStringFunc(pattern, func(t string) string {
+		switch t {
+		case ":category":
+			return e.Meta.Category
+		case ":slug":
+			return e.Meta.Slug
+		case ":year":
+			return d.Format("2006")
+		case ":month":
+			return d.Format("01")
+		case ":day":
+			return d.Format("02")
+		}
+		return t
+	})
+	u = strings.Trim(u, "/")
+	if u == "" {
+		return base
+	}
+	return base + u + "/"
+}
+
+// Redirect is a URL path the site redirects from, to another.
+type Redirect struct {
+	From, To string
+}
+
+// history maps entry paths to the URLs, relative to the site's base, the
+// entries were published at, latest last.
+type history map[string][]string
+
+func loadHistory(tree *notes.Tree) (history, error) {
+	h := history{}
+	data, err := os.ReadFile(tree.StatePath(PermalinkFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return h, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &h); err != nil {
+		return nil, fmt.Errorf("%s: %w", PermalinkFile, err)
+	}
+	return h, nil
+}
+
+func (h history) save(tree *notes.Tree) error {
+	data, err := json.MarshalIndent(h, "", "  ")
+	if err != nil {
+		return err
+	}
+	return fsutil.WriteFile(tree.StatePath(PermalinkFile), append(data, '\n'), 0o644)
+}
+
+// MovePermalinks carries the URLs entries moved to other files were
+// published at, keyed by their old paths, over to their new ones, so that
+// the site redirects from them. Of the moved entries, as they were, those
+// public but never built into the site are taken to be published at the
+// URL pattern gives them.
+func MovePermalinks(tree *notes.Tree, pattern string, moved []*entry.Entry, moves map[string]string) error {
+	h, err := loadHistory(tree)
+	if err != nil {
+		return err
+	}
+	for _, e := range entry.Public(moved) {
+		if _, ok := h[e.Path]; !ok {
+			h[e.Path] = []string{strings.TrimPrefix(permalink("/", pattern, e), "/")}
+		}
+	}
+	changed := false
+	for from, to := range moves {
+		urls, ok := h[from]
+		if !ok {
+			continue
+		}
+		h[to] = append(urls, h[to]...)
+		delete(h, from)
+		changed = true
+	}
+	if !changed {
+		return nil
+	}
+	return h.save(tree)
+}
+
+// trackPermalinks records the URL of every page in the history kept in the
+// state directory and sets s.Redirects from the URLs entries were published
+// at before. An old URL now taken by another page is not redirected, and
+// one several entries moved from redirects to the first.
+func (s *Site) trackPermalinks(tree *notes.Tree) error {
+	h, err := loadHistory(tree)
+	if err != nil {
+		return err
+	}
+	taken := map[string]bool{}
+	for _, p := range s.Pages {
+		taken[p.URL] = true
+	}
+	for _, cs := range [][]*Category{s.Categories, s.Collections, s.Months, s.Authors, s.Langs} {
+		for _, c := range cs {
+			taken[c.URL] = true
+		}
+	}
+	changed := false
+	for _, p := range s.Pages {
+		rel := strings.TrimPrefix(p.URL, s.Base)
+		urls := h[p.Entry.Path]
+		if n := len(urls); n == 0 || urls[n-1] != rel {
+			urls = append(slices.DeleteFunc(urls, func(u string) bool { return u == rel }), rel)
+			h[p.Entry.Path] = urls
+			changed = true
+		}
+		for _, old := range urls[:len(urls)-1] {
+			if from := s.Base + old; !taken[from] {
+				taken[from] = true
+				s.Redirects = append(s.Redirects, Redirect{From: from, To: p.URL})
+			}
+		}
+	}
+	for _, c := range s.Categories {
+		// Category pages used to be at the category's directory.
+		if from := s.Base + c.Name + "/"; !taken[from] {
+			taken[from] = true
+			s.Redirects = append(s.Redirects, Redirect{From: from, To: c.URL})
+		}
+	}
+	sort.Slice(s.Redirects, func(i, j int) bool { return s.Redirects[i].From < s.Redirects[j].From })
+	if !changed {
+		return nil
+	}
+	return h.save(tree)
+}
+
+// redirectsFile returns the content of RedirectsFile.
+func (s *Site) redirectsFile() []byte {
+	var b strings.Builder
+	for _, r := range s.Redirects {
+		fmt.Fprintf(&b, "%s %s 301\n", r.From, r.To)
+	}
+	return []byte(b.String())
+}
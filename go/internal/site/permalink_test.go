@@ -0,0 +1,120 @@
+package site
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+func TestCheckPermalink(t *testing.T) {
+	for pattern, ok := range map[string]bool{
+		DefaultPermalink:             true,
+		"/:year/:month/:day/:slug/":  true,
+		"/notes/:slug":               true,
+		"/:category/":                false,
+		"/:category/:title/":         false,
+		"/:year/:slug-:hour/":        false,
+		"/:category/:slug/:category": true,
+	} {
+		if err := CheckPermalink(pattern); (err == nil) != ok {
+			t.Errorf("CheckPermalink(%q) = %v, want ok %v", pattern, err, ok)
+		}
+	}
+}
+
+func TestPermalink(t *testing.T) {
+	dated, _ := entry.Parse("go/slices.md", []byte("---\ntitle: Slices\ndate: 2024-06-01\n---\n"))
+	undated, _ := entry.Parse("go/maps.md", []byte("# Maps\n"))
+	tests := []struct {
+		base, pattern string
+		e             *entry.Entry
+		want          string
+	}{
+		{"/", "", dated, "/go/slices/"},
+		{"/til/", "/:year/:month/:day/:slug/", dated, "/til/2024/06/01/slices/"},
+		{"/", "/:year/:slug", undated, "/go/maps/"},
+		{"/", "/notes/:slug.html", dated, "/notes/slices.html/"},
+		{"/", ":slug", dated, "/slices/"},
+	}
+	for _, tt := range tests {
+		if got := permalink(tt.base, tt.pattern, tt.e); got != tt.want {
+			t.Errorf("permalink(%q, %q, %s) = %q, want %q", tt.base, tt.pattern, tt.e.Path, got, tt.want)
+		}
+	}
+}
+
+func TestBuildRedirects(t *testing.T) {
+	tree := newTree(t, map[string]string{
+		"go/slices.md": "---\ntitle: Slices\ndate: 2024-06-01\n---\n\nSlices.\n",
+		"go/maps.md":   "---\ntitle: Maps\ndate: 2024-05-01\n---\n\nMaps.\n",
+	})
+	s, _ := build(t, tree, Options{})
+	if want := []Redirect{{"/go/", "/categories/go/"}}; !slices.Equal(s.Redirects, want) {
+		t.Errorf("redirects = %v, want %v", s.Redirects, want)
+	}
+
+	// A new slug redirects from the old URL.
+	if err := tree.Write("go/slices.md", []byte("---\ntitle: Slices\ndate: 2024-06-01\nslug: shared\n---\n\nSlices.\n")); err != nil {
+		t.Fatal(err)
+	}
+	s, out := build(t, tree, Options{})
+	if want := []Redirect{{"/go/", "/categories/go/"}, {"/go/slices/", "/go/shared/"}}; !slices.Equal(s.Redirects, want) {
+		t.Errorf("redirects = %v, want %v", s.Redirects, want)
+	}
+	if got := readOut(t, out, RedirectsFile); got != "/go/ /categories/go/ 301\n/go/slices/ /go/shared/ 301\n" {
+		t.Errorf("%s =\n%s", RedirectsFile, got)
+	}
+	if got := readOut(t, out, "go/slices/index.html"); !strings.Contains(got, `url=/go/shared/"`) {
+		t.Errorf("go/slices/index.html:\n%s", got)
+	}
+
+	// So does a new pattern, from every URL the entry had.
+	s, out = build(t, tree, Options{Permalink: "/:year/:month/:slug/"})
+	want := []Redirect{{"/go/", "/categories/go/"}, {"/go/maps/", "/2024/05/maps/"}, {"/go/shared/", "/2024/06/shared/"}, {"/go/slices/", "/2024/06/shared/"}}
+	if !slices.Equal(s.Redirects, want) {
+		t.Errorf("redirects = %v, want %v", s.Redirects, want)
+	}
+	if !exists(out, "2024/06/shared/index.html") {
+		t.Error("2024/06/shared/index.html not written")
+	}
+
+	// An old URL another page took is not redirected.
+	if err := tree.Write("go/maps.md", []byte("---\ntitle: Maps\ndate: 2024-05-01\nslug: slices\n---\n\nMaps.\n")); err != nil {
+		t.Fatal(err)
+	}
+	s, _ = build(t, tree, Options{})
+	for _, r := range s.Redirects {
+		if r.From == "/go/slices/" {
+			t.Errorf("redirects from the URL of go/maps.md: %v", s.Redirects)
+		}
+	}
+
+	if _, err := NewBuilder(tree, Options{Permalink: "/:category/"}); err == nil {
+		t.Error("NewBuilder accepted a permalink without :slug")
+	}
+}
+
+func TestMovePermalinks(t *testing.T) {
+	tree := newTree(t, map[string]string{
+		"go/new.md": "---\ntitle: Moved\ndate: 2024-06-01\n---\n",
+	})
+	old, _ := entry.Parse("go/old.md", []byte("---\ntitle: Moved\ndate: 2024-06-01\n---\n"))
+	draft, _ := entry.Parse("go/draft.md", []byte("---\ntitle: Draft\ndraft: true\n---\n"))
+	moves := map[string]string{"go/old.md": "go/new.md", "go/draft.md": "go/drafted.md"}
+	if err := MovePermalinks(tree, "", []*entry.Entry{old, draft}, moves); err != nil {
+		t.Fatal(err)
+	}
+	h, err := loadHistory(tree)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(h) != 1 || !slices.Equal(h["go/new.md"], []string{"go/old/"}) {
+		t.Errorf("history = %v", h)
+	}
+	s, _ := build(t, tree, Options{})
+	if !slices.Contains(s.Redirects, Redirect{"/go/old/", "/go/new/"}) {
+		t.Errorf("redirects = %v", s.Redirects)
+	}
+}
@@ -0,0 +1,106 @@
+package site
+
+import (
+	_ "embed"
+	"encoding/json"
+
+	"github.com/canhta/til/go/internal/related"
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+// Search files, written to the site root. The search page, from the
+// theme's optional search.html, is at search/.
+const (
+	SearchIndex  = "search.json"
+	SearchScript = "search.js"
+)
+
+// Bounds on the size of each entry's record in the search index.
+const (
+	// SearchExcerptWords is the length of the excerpt shown in results.
+	SearchExcerptWords = 30
+	// SearchTerms is the most distinct terms of the body indexed, in the
+	// order they first appear.
+	SearchTerms = 200
+)
+
+//go:embed search.js
+var searchJS []byte
+
+// searchRecord is an entry in the search index, with short keys as the
+// whole index is downloaded by the search page.
+type searchRecord struct {
+	Title    string   `json:"t"`
+	URL      string   `json:"u"`
+	Category string   `json:"c"`
+	Tags     []string `json:"g,omitempty"`
+	Date     string   `json:"d,omitempty"`
+	Excerpt  string   `json:"x,omitempty"`
+	// Terms are the body's terms, stopwords dropped, not already in the
+	// title, tags or excerpt, space-separated.
+	Terms string `json:"k,omitempty"`
+}
+
+// SearchURL returns the URL path of the search page.
+func (s *Site) SearchURL() string { return s.Base + "search/" }
+
+// searchIndex returns the search index of the site's pages, newest first.
+func (s *Site) searchIndex() ([]byte, error) {
+	recs := make([]searchRecord, 0, len(s.Pages))
+	for _, p := range s.Pages {
+		r := searchRecord{
+			Title:    p.Title,
+			URL:      p.URL,
+			Category: p.Category.Name,
+			Tags:     p.Tags,
+			Excerpt:  entry.Excerpt(p.Entry.Body, SearchExcerptWords),
+		}
+		if !p.Date.IsZero() {
+			r.Date = p.Date.Format(entry.DateLayout)
+		}
+		seen := map[string]bool{}
+		for _, t := range related.Tokens(r.Title + " " + r.Excerpt) {
+			seen[t] = true
+		}
+		for _, t := range p.Tags {
+			for _, t := range related.Tokens(t) {
+				seen[t] = true
+			}
+		}
+		var terms []byte
+		n := 0
+		for _, t := range related.Tokens(string(p.Entry.Body)) {
+			if seen[t] || n == SearchTerms {
+				continue
+			}
+			seen[t] = true
+			if n > 0 {
+				terms = append(terms, ' ')
+			}
+			terms = append(terms, t...)
+			n++
+		}
+		r.Terms = string(terms)
+		recs = append(recs, r)
+	}
+	return json.Marshal(recs)
+}
+
+// writeSearch writes the search index and script, and the search page when
+// the theme has one.
+func (s *Site) writeSearch(w *Writer, t *Templates) error {
+	idx, err := s.searchIndex()
+	if err != nil {
+		return err
+	}
+	if err := w.Write(SearchIndex, idx); err != nil {
+		return err
+	}
+	if err := w.Write(SearchScript, searchJS); err != nil {
+		return err
+	}
+	if _, ok := t.pages["search.html"]; !ok {
+		return nil
+	}
+	return s.writePage(w, t, "search.html", s.outPath(s.SearchURL()), templateData{Site: s})
+}
@@ -0,0 +1,106 @@
+// Searches the entries of a til site in the browser, from search.json.
+//
+// The search page has an input#search-input and a list#search-results; the
+// query is kept in ?q= so searches can be linked to. Every word of the
+// query must start a word of an entry, which is scored by where: title,
+// tags, then category, excerpt and the other terms of the body.
+(function () {
+  "use strict";
+  var input = document.getElementById("search-input");
+  var list = document.getElementById("search-results");
+  if (!input || !list) return;
+  var script = document.currentScript;
+  var url = (script && script.dataset.index) || "search.json";
+  var records = null;
+
+  function words(s) {
+    return s.toLowerCase().split(/[^\p{L}\p{N}]+/u).filter(Boolean);
+  }
+
+  function prepare(r) {
+    return {
+      r: r,
+      fields: [
+        [words(r.t), 8],
+        [words((r.g || []).join(" ")), 5],
+        [words(r.c), 3],
+        [words(r.x || ""), 2],
+        [words(r.k || ""), 1],
+      ],
+    };
+  }
+
+  function score(p, terms) {
+    var total = 0;
+    for (var i = 0; i < terms.length; i++) {
+      var best = 0;
+      for (var j = 0; j < p.fields.length; j++) {
+        var f = p.fields[j];
+        for (var k = 0; k < f[0].length; k++) {
+          var w = f[0][k];
+          if (w.lastIndexOf(terms[i], 0) === 0) {
+            best = Math.max(best, w === terms[i] ? f[1] * 2 : f[1]);
+          }
+        }
+      }
+      if (best === 0) return 0;
+      total += best;
+    }
+    return total;
+  }
+
+  function el(tag, cls, text) {
+    var e = document.createElement(tag);
+    if (cls) e.className = cls;
+    if (text) e.textContent = text;
+    return e;
+  }
+
+  function render() {
+    var q = input.value.trim();
+    var terms = words(q);
+    list.textContent = "";
+    if (!records || terms.length === 0) return;
+    var hits = [];
+    records.forEach(function (p) {
+      var s = score(p, terms);
+      if (s > 0) hits.push([s, p.r]);
+    });
+    hits.sort(function (a, b) {
+      return b[0] - a[0];
+    });
+    if (hits.length === 0) {
+      list.appendChild(el("li", "none", "No entries match."));
+      return;
+    }
+    hits.slice(0, 50).forEach(function (h) {
+      var r = h[1];
+      var li = el("li");
+      var a = el("a", "", r.t);
+      a.href = r.u;
+      li.appendChild(a);
+      if (r.d) li.appendChild(el("time", "", " " + r.d));
+      li.appendChild(el("span", "category", " " + r.c));
+      if (r.x) li.appendChild(el("p", "excerpt", r.x));
+      list.appendChild(li);
+    });
+  }
+
+  var params = new URLSearchParams(location.search);
+  if (params.get("q")) input.value = params.get("q");
+  input.addEventListener("input", function () {
+    var u = new URL(location.href);
+    if (input.value) u.searchParams.set("q", input.value);
+    else u.searchParams.delete("q");
+    history.replaceState(null, "", u);
+    render();
+  });
+  fetch(url)
+    .then(function (res) {
+      return res.json();
+    })
+    .then(function (rs) {
+      records = rs.map(prepare);
+      render();
+    });
+})();
@@ -0,0 +1,53 @@
+package site
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestBuildSearch(t *testing.T) {
+	files := maps.Clone(siteFiles)
+	var long strings.Builder
+	for i := range SearchTerms + 50 {
+		fmt.Fprintf(&long, "word%d ", i)
+	}
+	files["go/long.md"] = "---\ntitle: Long\ntags: [go, data-structures]\n---\n\n" + long.String() + "\n"
+	_, out := build(t, newTree(t, files), Options{})
+
+	var recs []searchRecord
+	if err := json.Unmarshal([]byte(readOut(t, out, SearchIndex)), &recs); err != nil {
+		t.Fatal(err)
+	}
+	var urls []string
+	for _, r := range recs {
+		urls = append(urls, r.URL)
+	}
+	if want := []string{"/go/slices/", "/go/maps/", "/git/rebase/", "/go/long/"}; !slices.Equal(urls, want) {
+		t.Errorf("search index URLs = %q, want %q", urls, want)
+	}
+	if r := recs[0]; r.Title != "Slices share arrays" || r.Category != "go" || !slices.Equal(r.Tags, []string{"go"}) || r.Date != "2024-06-01" {
+		t.Errorf("record = %+v", r)
+	}
+	if r := recs[1]; r.Excerpt != "Maps are *unordered*." || r.Terms != "" {
+		t.Errorf("record = %+v, want the body in the excerpt only", r)
+	}
+	long3 := recs[3]
+	if long3.Date != "" || len(strings.Fields(long3.Excerpt)) != SearchExcerptWords {
+		t.Errorf("record = %+v", long3)
+	}
+	terms := strings.Fields(long3.Terms)
+	if len(terms) != SearchTerms || terms[0] != fmt.Sprintf("word%d", SearchExcerptWords) || slices.Contains(terms, "word0") {
+		t.Errorf("terms = %q..., %d of them", terms[:3], len(terms))
+	}
+
+	if !exists(out, SearchScript) {
+		t.Errorf("%s not written", SearchScript)
+	}
+	if got := readOut(t, out, "search/index.html"); !strings.Contains(got, SearchScript) {
+		t.Errorf("search/index.html does not load the script:\n%s", got)
+	}
+}
@@ -0,0 +1,1122 @@
+// Package site generates a static website from a notes tree.
+//
+// The generated site has an index page listing every entry, one page per
+// entry, and listings of the entries of each category and each month,
+// paginated, laid out as:
+//
+//	index.html
+//	<category>/<slug>/index.html
+//	<category>/<slug>/og.png, or og.svg without a converter
+//	categories/<category>/index.html
+//	categories/<category>/page/<n>/index.html
+//	<yyyy>/<mm>/index.html
+//	<yyyy>/<mm>/page/<n>/index.html
+//	<category>/<slug>/history/index.html, with Options.History
+//	changes/index.html, with Options.Changelog
+//	search/index.html
+//	search.json
+//	sitemap.xml
+//	robots.txt
+//
+// where entry pages follow Options.Permalink, <category>/<slug>/ by
+// default. Pages the site no longer has, such as <category>/index.html where
+// category pages used to be and the URLs entries were published at before
+// their slug or the pattern changed, redirect to where they moved, as listed
+// in _redirects.
+package site
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/url"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/canhta/til/go/internal/assets"
+	"github.com/canhta/til/go/internal/diagram"
+	"github.com/canhta/til/go/internal/git"
+	"github.com/canhta/til/go/internal/gitdates"
+	"github.com/canhta/til/go/internal/heatmap"
+	"github.com/canhta/til/go/internal/include"
+	"github.com/canhta/til/go/internal/katex"
+	"github.com/canhta/til/go/internal/links"
+	"github.com/canhta/til/go/internal/logging"
+	"github.com/canhta/til/go/internal/notes"
+	"github.com/canhta/til/go/internal/ogimage"
+	"github.com/canhta/til/go/internal/pool"
+	"github.com/canhta/til/go/internal/query"
+	"github.com/canhta/til/go/internal/related"
+	"github.com/canhta/til/go/internal/webmention"
+	"github.com/canhta/til/go/pkg/entry"
+	"github.com/canhta/til/go/pkg/render"
+)
+
+// Options configures a build.
+type Options struct {
+	// Out is the output directory.
+	Out string
+	// Permalink is the pattern of entry URLs, as "/:year/:month/:slug/";
+	// see CheckPermalink. Defaults to DefaultPermalink.
+	Permalink string
+	// Title is the site title.
+	Title string
+	// Lang is the language of entries that name none and of the site's
+	// own pages. Defaults to "en".
+	Lang string
+	// BaseURL is where the site is published: either an absolute URL such
+	// as "https://example.com/til/" or just a path. Feeds need an absolute
+	// URL, as does the sitemap, and are skipped otherwise. Defaults to "/".
+	BaseURL string
+	// Robots is the content of robots.txt. Defaults to DefaultRobots; a
+	// Sitemap line is added when there is a sitemap and it has none.
+	Robots string
+	// FeedLimit is the number of entries in each feed. Defaults to 20.
+	FeedLimit int
+	// Theme names the theme loaded with LoadTheme when Templates is nil.
+	Theme string
+	// Templates, if set, are used instead of a theme.
+	Templates *Templates
+	// Highlight names the chroma style code is highlighted with. Defaults
+	// to the theme's "highlight-style" template, else DefaultStyle.
+	Highlight string
+	// LineNumbers numbers the lines of every code block.
+	LineNumbers bool
+	// Mermaid is the command rendering mermaid fences to SVG, as described
+	// in package diagram. Diagrams it cannot render are drawn in the browser.
+	Mermaid []string
+	// Math typesets TeX between $ and $$, pre-rendered to HTML with the
+	// KaTeX command when it is installed and in the browser otherwise.
+	Math bool
+	// KaTeX is the command pre-rendering math, as described in package
+	// katex.
+	KaTeX []string
+	// OGImage is the command converting preview cards to PNG, as described
+	// in package ogimage. Cards stay SVG without one.
+	OGImage []string
+	// GitDates dates entries by their commit history; see package gitdates.
+	GitDates bool
+	// History links each entry page to a page listing the commits that
+	// changed the entry, when the theme has a history.html template and
+	// the tree is in a git repository.
+	History bool
+	// Changelog publishes a page summarizing the entries added and updated
+	// in each of the last ChangelogMonths months, when the theme has a
+	// changelog.html template.
+	Changelog bool
+	// Related is the number of similar entries listed on each entry page;
+	// zero lists none.
+	Related int
+	// Collections are saved searches keyed by name.
+	Collections map[string]query.Expr
+	// CollectionPages renders a page listing the entries of each
+	// collection.
+	CollectionPages bool
+	// PerPage is the number of entries on each page of a category,
+	// collection or month listing. Defaults to DefaultPerPage.
+	PerPage int
+	// Drafts includes draft entries, for previewing them.
+	Drafts bool
+	// Force renders every entry anew instead of reusing those rendered by
+	// the last build, which are kept in the state directory.
+	Force bool
+	// Jobs bounds the entries rendered and the files written at once.
+	// Defaults to the number of CPUs.
+	Jobs int
+	// Webmention is the endpoint pages advertise for receiving
+	// webmentions.
+	Webmention string
+	// Mentions are the webmentions received, listed under the pages they
+	// mention, including at URLs the pages moved from. They need an
+	// absolute BaseURL to be matched to pages.
+	Mentions []webmention.Received
+}
+
+// TOCMin is the fewest headings for which an entry page lists them in a
+// table of contents.
+const TOCMin = 3
+
+// DefaultPerPage is the number of entries on each page of a listing by
+// default.
+const DefaultPerPage = 20
+
+// Site is the model rendered by the page templates.
+type Site struct {
+	Title string
+	// Base is the URL path prefix, always ending in a slash.
+	Base string
+	// Origin is the scheme and host of BaseURL, or "" when it is a path.
+	Origin string
+	// Pages holds every entry page, newest first.
+	Pages      []*Page
+	Categories []*Category
+	// Collections are the pages of saved searches, sorted by name, when
+	// Options.CollectionPages is set.
+	Collections []*Category
+	// Months are the archives of the months with dated entries, newest
+	// first.
+	Months []*Category
+	// Authors are the pages of the entries of each author, sorted by name,
+	// when any entry names one.
+	Authors []*Category
+	// Lang is the language of the site's own pages.
+	Lang string
+	// Langs are the listings of the entries in each language, sorted by
+	// tag, when entries are in more than one.
+	Langs []*Category
+	// Rendered lists the entries whose markdown was rendered by the build
+	// that produced this site, as opposed to reused from a previous build
+	// of unchanged sources with links resolving the same.
+	Rendered []string
+	// Heatmap is an SVG calendar of the entries created in the last year,
+	// also published as heatmap.svg.
+	Heatmap template.HTML
+	// Dangling lists the [[links]] that name no entry.
+	Dangling []links.Dangling
+	// DiagramErrors lists the diagrams and math that failed to render
+	// during this build and were left to the browser.
+	DiagramErrors []error
+	// IncludeErrors lists the include directives that could not be
+	// expanded and were left as written.
+	IncludeErrors []error
+	// TranslationErrors lists the translation_of references that name no
+	// single entry.
+	TranslationErrors []error
+	// ImageErrors lists the preview cards that failed to convert to PNG
+	// and were published as SVG.
+	ImageErrors []error
+	// Collisions lists the URLs more than one entry maps to, which Build
+	// refuses to publish.
+	Collisions []Collision
+	// Redirects are the URLs pages moved from, set by Build.
+	Redirects []Redirect
+	// Webmention is the endpoint pages advertise for webmentions.
+	Webmention string
+	// Changelog lists the entries added and updated in each month, newest
+	// first, skipping months without any, with Options.Changelog, and
+	// ChangelogURL is the page listing them then.
+	Changelog    []*Changes
+	ChangelogURL string
+
+	byPath map[string]*Page
+	links  *links.Index
+	// assets maps the paths of entry assets to their published copies.
+	assets map[string]asset
+}
+
+// Collision is a URL several entries map to.
+type Collision struct {
+	URL   string
+	Paths []string
+}
+
+func (c Collision) Error() string {
+	return fmt.Sprintf("%s map to the same URL %s; give them distinct slugs", strings.Join(c.Paths, ", "), c.URL)
+}
+
+// asset is an entry asset published under a fingerprinted name.
+type asset struct {
+	// path is the asset's path in the notes tree.
+	path string
+	// out is the output path, relative to the site root.
+	out string
+}
+
+// Category groups the pages of one category directory, collection, month,
+// author or language.
+type Category struct {
+	Name string
+	URL  string
+	// Lang is the language tag of a language listing, and empty for the
+	// other kinds.
+	Lang string
+	// Month is the first day of the month of a month archive, and zero
+	// for categories and collections.
+	Month time.Time
+	Pages []*Page
+	// Pagers are the pages of the listing, of Options.PerPage entries
+	// each; there is always at least one.
+	Pagers []*Pager
+}
+
+// Pager is one page of a paginated listing.
+type Pager struct {
+	// Number is the page's 1-based number, of Total.
+	Number, Total int
+	URL           string
+	Pages         []*Page
+	// Prev and Next are the neighbouring pages of the listing, or nil.
+	Prev, Next *Pager
+}
+
+// Page is a rendered entry.
+type Page struct {
+	Entry    *entry.Entry
+	Title    string
+	Date     time.Time
+	Tags     []string
+	URL      string
+	Category *Category
+	// Author is the listing of the entry's author, nil when it names none.
+	Author *Category
+	// Lang is the language the entry is written in.
+	Lang string
+	// Translations are the other versions of the entry, in other
+	// languages, sorted by language.
+	Translations []*Page
+	// Archive is the archive of the entry's month, nil when it is undated.
+	Archive *Category
+	// Prev and Next are the entries created just before and after this
+	// one, nil at either end. Undated entries are in neither chain.
+	Prev, Next *Page
+	Content    template.HTML
+	// Words, CodeLines and ReadingMinutes measure the entry, as
+	// entry.Counts does.
+	Words, CodeLines, ReadingMinutes int
+	// Mermaid is set when Content has diagrams left for mermaid.js to
+	// draw, so the page needs to load it.
+	Mermaid bool
+	// Math is set when Content has math, which needs KaTeX's stylesheet,
+	// and MathScript when some of it is left for KaTeX to typeset.
+	Math, MathScript bool
+	// TOC lists the headings of Content when it has at least TOCMin.
+	TOC []render.Heading
+	// Backlinks are the pages linking to this one, newest first.
+	Backlinks []*Page
+	// Related are the most similar pages, best first.
+	Related []*Page
+	// Image is the URL path of the entry's preview card, shown by sites
+	// that link to it.
+	Image string
+	// History lists the commits that changed the entry, newest first, with
+	// Options.History, and HistoryURL is the page listing them then.
+	History    []git.Revision
+	HistoryURL string
+	// Mentions are the webmentions of the page, newest first.
+	Mentions []webmention.Received
+
+	// card is the content of Image.
+	card []byte
+}
+
+// templateData is passed to every page template.
+type templateData struct {
+	Site     *Site
+	Page     *Page
+	Category *Category
+	// Pager is the page of Category's listing being rendered.
+	Pager *Pager
+	// URL is the URL path of the page being rendered, its canonical URL.
+	URL string
+	// Lang is the language of the page being rendered.
+	Lang string
+	// Alternates are the versions of the page in other languages, for
+	// hreflang links.
+	Alternates []Alternate
+}
+
+// New builds the site model for entries without rendering anything.
+func New(entries []*entry.Entry, opts Options) *Site {
+	var origin, base string
+	if u, err := url.Parse(opts.BaseURL); err == nil {
+		if u.Scheme != "" && u.Host != "" {
+			origin = u.Scheme + "://" + u.Host
+		}
+		base = u.Path
+	}
+	if !strings.HasPrefix(base, "/") {
+		base = "/" + base
+	}
+	if !strings.HasSuffix(base, "/") {
+		base += "/"
+	}
+	perPage := opts.PerPage
+	if perPage <= 0 {
+		perPage = DefaultPerPage
+	}
+	lang := opts.Lang
+	if lang == "" {
+		lang = "en"
+	}
+	s := &Site{Title: opts.Title, Base: base, Origin: origin, Lang: lang, Webmention: opts.Webmention, byPath: map[string]*Page{}}
+	cats := map[string]*Category{}
+	months := map[string]*Category{}
+	authors := map[string]*Category{}
+	for _, e := range entries {
+		cat := cats[e.Meta.Category]
+		if cat == nil {
+			cat = &Category{Name: e.Meta.Category, URL: s.CategoryURL(e.Meta.Category)}
+			cats[cat.Name] = cat
+			s.Categories = append(s.Categories, cat)
+		}
+		p := &Page{
+			Entry:    e,
+			Title:    e.Meta.Title,
+			Date:     e.Meta.Date,
+			Tags:     e.Meta.Tags,
+			URL:      permalink(base, opts.Permalink, e),
+			Category: cat,
+			Lang:     cmp.Or(e.Meta.Lang, lang),
+
+			Words:          e.Counts.Words,
+			CodeLines:      e.Counts.CodeLines,
+			ReadingMinutes: e.Counts.ReadingMinutes(),
+		}
+		cat.Pages = append(cat.Pages, p)
+		s.Pages = append(s.Pages, p)
+		s.byPath[e.Path] = p
+		if name := e.Meta.Author; name != "" {
+			a := authors[name]
+			if a == nil {
+				a = &Category{Name: name, URL: s.AuthorURL(name)}
+				authors[name] = a
+				s.Authors = append(s.Authors, a)
+			}
+			a.Pages = append(a.Pages, p)
+			p.Author = a
+		}
+		if !p.Date.IsZero() {
+			key := p.Date.Format("2006/01")
+			m := months[key]
+			if m == nil {
+				m = &Category{
+					Name:  p.Date.Format("January 2006"),
+					URL:   base + key + "/",
+					Month: time.Date(p.Date.Year(), p.Date.Month(), 1, 0, 0, 0, 0, time.UTC),
+				}
+				months[key] = m
+				s.Months = append(s.Months, m)
+			}
+			m.Pages = append(m.Pages, p)
+			p.Archive = m
+		}
+	}
+	byURL := map[string][]string{}
+	for _, p := range s.Pages {
+		byURL[p.URL] = append(byURL[p.URL], p.Entry.Path)
+	}
+	for u, paths := range byURL {
+		if len(paths) > 1 {
+			s.Collisions = append(s.Collisions, Collision{URL: u, Paths: paths})
+		}
+	}
+	sort.Slice(s.Collisions, func(i, j int) bool { return s.Collisions[i].URL < s.Collisions[j].URL })
+	s.links = links.NewIndex(entries)
+	s.languages()
+	g := links.NewGraph(entries)
+	s.Dangling = g.Dangling
+	for to, froms := range g.In {
+		p := s.byPath[to]
+		for _, from := range froms {
+			p.Backlinks = append(p.Backlinks, s.byPath[from])
+		}
+		sortPages(p.Backlinks)
+	}
+	sortPages(s.Pages)
+	for _, c := range s.Categories {
+		sortPages(c.Pages)
+	}
+	for _, cs := range [][]*Category{s.Months, s.Authors, s.Langs} {
+		for _, c := range cs {
+			sortPages(c.Pages)
+		}
+	}
+	sort.Slice(s.Categories, func(i, j int) bool { return s.Categories[i].Name < s.Categories[j].Name })
+	sort.Slice(s.Authors, func(i, j int) bool { return s.Authors[i].Name < s.Authors[j].Name })
+	sort.Slice(s.Months, func(i, j int) bool { return s.Months[i].Month.After(s.Months[j].Month) })
+	// s.Pages is newest first, undated entries last.
+	var newer *Page
+	for _, p := range s.Pages {
+		if p.Date.IsZero() {
+			break
+		}
+		if newer != nil {
+			newer.Prev, p.Next = p, newer
+		}
+		newer = p
+	}
+	if opts.CollectionPages {
+		for name, x := range opts.Collections {
+			c := &Category{Name: name, URL: s.CollectionURL(name)}
+			for _, p := range s.Pages {
+				if x.Match(p.Entry) {
+					c.Pages = append(c.Pages, p)
+				}
+			}
+			s.Collections = append(s.Collections, c)
+		}
+		sort.Slice(s.Collections, func(i, j int) bool { return s.Collections[i].Name < s.Collections[j].Name })
+	}
+	for _, cs := range [][]*Category{s.Categories, s.Collections, s.Months, s.Authors, s.Langs} {
+		for _, c := range cs {
+			c.paginate(perPage)
+		}
+	}
+	return s
+}
+
+// paginate splits the pages of c into Pagers of n pages each. The first
+// is at c.URL and the others at page/<number>/ below it.
+func (c *Category) paginate(n int) {
+	total := max(1, (len(c.Pages)+n-1)/n)
+	c.Pagers = make([]*Pager, total)
+	for i := range c.Pagers {
+		pg := &Pager{Number: i + 1, Total: total, URL: c.URL, Pages: c.Pages[i*n : min((i+1)*n, len(c.Pages))]}
+		if i > 0 {
+			pg.URL = fmt.Sprintf("%spage/%d/", c.URL, i+1)
+			pg.Prev, c.Pagers[i-1].Next = c.Pagers[i-1], pg
+		}
+		c.Pagers[i] = pg
+	}
+}
+
+// CategoryURL returns the URL path of the listing of the named category.
+func (s *Site) CategoryURL(name string) string {
+	slug := entry.Slugify(name)
+	if slug == "" {
+		slug = url.PathEscape(strings.ToLower(name))
+	}
+	return s.Base + "categories/" + slug + "/"
+}
+
+// CollectionURL returns the URL path of the page of the named collection.
+func (s *Site) CollectionURL(name string) string {
+	slug := entry.Slugify(name)
+	if slug == "" {
+		slug = url.PathEscape(strings.ToLower(name))
+	}
+	return s.Base + "collections/" + slug + "/"
+}
+
+// AuthorURL returns the URL path of the listing of the named author.
+func (s *Site) AuthorURL(name string) string {
+	slug := entry.Slugify(name)
+	if slug == "" {
+		slug = url.PathEscape(strings.ToLower(name))
+	}
+	return s.Base + "authors/" + slug + "/"
+}
+
+// sortPages orders pages newest first, then by title.
+func sortPages(pages []*Page) {
+	sort.SliceStable(pages, func(i, j int) bool {
+		if !pages[i].Date.Equal(pages[j].Date) {
+			return pages[i].Date.After(pages[j].Date)
+		}
+		return pages[i].Title < pages[j].Title
+	})
+}
+
+// urlFingerprint identifies what links in other entries render as: each
+// page's URL, and its title, which labels [[links]] to it, and where each
+// asset is published.
+func (s *Site) urlFingerprint() string {
+	var sb strings.Builder
+	for _, p := range s.Pages {
+		sb.WriteString(p.Entry.Path)
+		sb.WriteByte('=')
+		sb.WriteString(p.URL)
+		sb.WriteByte(' ')
+		sb.WriteString(p.Title)
+		sb.WriteByte('\n')
+	}
+	paths := make([]string, 0, len(s.assets))
+	for p := range s.assets {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	for _, p := range paths {
+		sb.WriteString(p + "=" + s.assets[p].out + "\n")
+	}
+	return sb.String()
+}
+
+// AbsURL returns the absolute form of the site URL path u. It returns u
+// unchanged when the site has no origin.
+func (s *Site) AbsURL(u string) string {
+	return s.Origin + u
+}
+
+// Page returns the page generated for the entry at the relative path p.
+func (s *Site) Page(p string) *Page {
+	return s.byPath[p]
+}
+
+// ResolveWiki resolves the target of a [[target]] reference in the entry at
+// from to the URL and title of the page it names.
+func (s *Site) ResolveWiki(from, target string) (string, string, bool) {
+	e, res := s.links.Resolve(from, links.Link{Target: target, Wiki: true})
+	if res != links.Resolved {
+		return "", "", false
+	}
+	p := s.byPath[e.Path]
+	return p.URL, p.Title, true
+}
+
+// ResolveLink rewrites a relative link to another entry's markdown file into
+// the URL of its generated page.
+func (s *Site) ResolveLink(from, dest string) (string, bool) {
+	u, err := url.Parse(dest)
+	if err != nil || u.Scheme != "" || u.Host != "" || u.Path == "" {
+		return "", false
+	}
+	target := path.Clean(path.Join(path.Dir(from), u.Path))
+	if strings.HasPrefix(u.Path, "/") {
+		target = strings.TrimPrefix(path.Clean(u.Path), "/")
+	}
+	p := s.byPath[target]
+	if p == nil {
+		if a, ok := s.assets[target]; ok {
+			return s.Base + a.out, true
+		}
+		return "", false
+	}
+	if u.Fragment != "" {
+		return p.URL + "#" + u.Fragment, true
+	}
+	return p.URL, true
+}
+
+// AbsLinks rewrites the links of body, from the entry at from, to other
+// entries and to assets into absolute site URLs, for copies of the entry
+// published elsewhere. It returns the links it could not resolve, which
+// are left as written.
+func (s *Site) AbsLinks(from string, body []byte) ([]byte, []string) {
+	var unresolved []string
+	out := links.Rewrite(body, func(l links.Link) (string, bool) {
+		if l.Wiki {
+			u, title, ok := s.ResolveWiki(from, l.Target)
+			if !ok {
+				unresolved = append(unresolved, "[["+l.Target+"]]")
+				return "", false
+			}
+			if l.Label != "" {
+				title = l.Label
+			}
+			return "[" + title + "](" + s.AbsURL(u) + ")", true
+		}
+		dest := l.Target
+		if l.Fragment != "" {
+			dest += "#" + l.Fragment
+		}
+		u, ok := s.ResolveLink(from, dest)
+		if !ok {
+			unresolved = append(unresolved, l.Target)
+			return "", false
+		}
+		return "[" + l.Label + "](" + s.AbsURL(u) + ")", true
+	})
+	return out, unresolved
+}
+
+// Build renders the entries of tree into opts.Out.
+func Build(ctx context.Context, tree *notes.Tree, opts Options) (*Site, error) {
+	b, err := NewBuilder(tree, opts)
+	if err != nil {
+		return nil, err
+	}
+	return b.Build(ctx)
+}
+
+// Builder builds a site repeatedly, re-rendering only the entries that
+// changed since the previous build. Rendered entries are addressed by
+// their source and the options rendering depends on, and kept in the state
+// directory between builds.
+//
+// Entries are rendered and pages written on up to Options.Jobs goroutines.
+// Pages are listed in the same order however the work interleaves, and the
+// errors of every entry failing to render are returned together.
+type Builder struct {
+	tree     *notes.Tree
+	opts     Options
+	diagrams *diagram.Renderer
+	tex      *katex.Renderer
+	cards    *ogimage.Converter
+	// workers each render entries on one goroutine.
+	workers []*worker
+	site    *Site
+	// cache holds the rendered entries by renderKey; nil until loaded.
+	cache map[string]rendered
+	// version fingerprints what rendering depends on besides the source.
+	version string
+	// css styles the highlighted code.
+	css []byte
+	// ctx is that of the build in progress.
+	ctx context.Context
+}
+
+// worker renders entries, recording the links the entry being rendered
+// resolves and the diagrams and math it fails to render.
+type worker struct {
+	renderer *render.Renderer
+	links    []lookup
+	errs     []error
+}
+
+// NewBuilder returns a Builder for tree.
+func NewBuilder(tree *notes.Tree, opts Options) (*Builder, error) {
+	if opts.Templates == nil {
+		var err error
+		if opts.Templates, err = LoadTheme(tree, opts.Theme); err != nil {
+			return nil, err
+		}
+	}
+	if opts.Highlight == "" {
+		opts.Highlight = opts.Templates.style
+	}
+	if opts.Permalink != "" {
+		if err := CheckPermalink(opts.Permalink); err != nil {
+			return nil, err
+		}
+	}
+	css, err := render.CSS(opts.Highlight)
+	if err != nil {
+		return nil, err
+	}
+	b := &Builder{
+		tree:     tree,
+		opts:     opts,
+		diagrams: diagram.New(tree, opts.Mermaid),
+		tex:      katex.New(tree, opts.KaTeX),
+		cards:    ogimage.NewConverter(tree, opts.OGImage),
+		css:      css,
+	}
+	b.version = b.fingerprint(b.diagrams.Command(), b.tex.Command())
+	for range pool.Size(opts.Jobs) {
+		b.workers = append(b.workers, b.newWorker())
+	}
+	return b, nil
+}
+
+func (b *Builder) newWorker() *worker {
+	w := &worker{}
+	// The link resolver consults whichever site model is current, and
+	// records the resolutions the rendered HTML depends on.
+	w.renderer = render.New(render.Options{
+		ResolveLink: func(from, dest string) (string, bool) {
+			u, ok := b.site.ResolveLink(from, dest)
+			w.links = append(w.links, lookup{Target: dest, URL: u, OK: ok})
+			return u, ok
+		},
+		ResolveWiki: func(from, target string) (string, string, bool) {
+			u, title, ok := b.site.ResolveWiki(from, target)
+			w.links = append(w.links, lookup{Wiki: true, Target: target, URL: u, Title: title, OK: ok})
+			return u, title, ok
+		},
+		Highlight:   b.opts.Highlight,
+		Classes:     true,
+		LineNumbers: b.opts.LineNumbers,
+		Permalinks:  true,
+		Diagram: func(from, _ string, src []byte) ([]byte, error) {
+			svg, err := b.diagrams.Render(b.ctx, src)
+			if err != nil && !errors.Is(err, diagram.ErrUnavailable) {
+				w.errs = append(w.errs, fmt.Errorf("%s: %w", from, err))
+			}
+			return svg, err
+		},
+		Math: b.opts.Math,
+		RenderMath: func(src []byte, display bool) ([]byte, error) {
+			html, err := b.tex.Render(b.ctx, src, display)
+			if err != nil && !errors.Is(err, katex.ErrUnavailable) {
+				w.errs = append(w.errs, fmt.Errorf("math %q: %w", src, err))
+			}
+			return html, err
+		},
+	})
+	return w
+}
+
+// result is what rendering one entry came to.
+type result struct {
+	key string
+	r   rendered
+	// fresh is set when the entry was rendered rather than taken from the
+	// cache.
+	fresh bool
+	// includeErrors and diagramErrors are for the Site fields so named.
+	includeErrors, diagramErrors []error
+}
+
+// Build renders the tree and writes the site.
+func (b *Builder) Build(ctx context.Context) (*Site, error) {
+	b.ctx = ctx
+	log := logging.From(ctx)
+	_, end := logging.Start(ctx, "load")
+	entries, err := b.tree.Entries()
+	end()
+	if err != nil {
+		return nil, err
+	}
+	entries = slices.DeleteFunc(entries, func(e *entry.Entry) bool {
+		return e.Meta.Private || e.Meta.Archived || e.Meta.Draft && !b.opts.Drafts
+	})
+	log.Debug("loaded entries", "entries", len(entries))
+	if b.opts.GitDates {
+		gctx, end := logging.Start(ctx, "git dates")
+		err := gitdates.Apply(gctx, b.tree, entries)
+		end()
+		if err != nil {
+			return nil, err
+		}
+	}
+	_, end = logging.Start(ctx, "index")
+	b.site = New(entries, b.opts)
+	if len(b.site.Collisions) > 0 {
+		errs := make([]error, len(b.site.Collisions))
+		for i, c := range b.site.Collisions {
+			errs[i] = c
+		}
+		return nil, errors.Join(errs...)
+	}
+	if err := b.site.trackPermalinks(b.tree); err != nil {
+		return nil, err
+	}
+	if err := b.site.LoadAssets(b.tree); err != nil {
+		return nil, err
+	}
+	b.site.matchMentions(b.opts.Mentions)
+	b.site.Heatmap = template.HTML(heatmap.LastYear(entries, time.Now()).SVG())
+	end()
+	if b.opts.Related > 0 {
+		rctx, end := logging.Start(ctx, "related")
+		m, err := related.Compute(rctx, b.tree, entries)
+		end()
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range b.site.Pages {
+			for _, r := range m.Related(p.Entry.Path, b.opts.Related) {
+				p.Related = append(p.Related, b.site.byPath[r.Path])
+			}
+		}
+	}
+	_, end = logging.Start(ctx, "render", "pages", len(b.site.Pages))
+	if b.cache == nil {
+		b.cache = map[string]rendered{}
+		if !b.opts.Force {
+			b.cache = b.loadCache()
+		}
+	}
+	results := make([]result, len(b.site.Pages))
+	err = pool.Run(ctx, len(b.workers), len(b.site.Pages), func(w, i int) error {
+		var err error
+		results[i], err = b.renderPage(b.workers[w], b.site.Pages[i])
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	next := make(map[string]rendered, len(results))
+	for i, res := range results {
+		p := b.site.Pages[i]
+		p.Content = res.r.HTML
+		p.Mermaid = res.r.Mermaid
+		p.Math, p.MathScript = res.r.Math, res.r.MathScript
+		p.TOC = res.r.TOC
+		b.site.IncludeErrors = append(b.site.IncludeErrors, res.includeErrors...)
+		b.site.DiagramErrors = append(b.site.DiagramErrors, res.diagramErrors...)
+		if res.fresh {
+			b.site.Rendered = append(b.site.Rendered, p.Entry.Path)
+		}
+		// Rendered again next time, in case the failure passed.
+		if len(res.diagramErrors) == 0 {
+			next[res.key] = res.r
+		}
+	}
+	changed := len(next) != len(b.cache) || len(b.site.Rendered) > 0
+	b.cache = next
+	if changed {
+		if err := b.saveCache(); err != nil {
+			return nil, err
+		}
+	}
+	end()
+	log.Debug("rendered entries", "rendered", len(b.site.Rendered), "cached", len(b.site.Pages)-len(b.site.Rendered))
+
+	for _, phase := range []struct {
+		name string
+		on   bool
+		run  func(context.Context) error
+	}{
+		{"cards", true, b.drawCards},
+		{"history", b.opts.History, b.loadHistory},
+		{"changelog", b.opts.Changelog, b.loadChangelog},
+	} {
+		if !phase.on {
+			continue
+		}
+		pctx, end := logging.Start(ctx, phase.name)
+		err := phase.run(pctx)
+		end()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	_, end = logging.Start(ctx, "write")
+	defer end()
+	w, err := NewWriter(b.opts.Out)
+	if err != nil {
+		return nil, err
+	}
+	if err := b.site.write(ctx, w, b.tree, b.opts.Templates, len(b.workers)); err != nil {
+		return nil, err
+	}
+	if err := w.Write(HighlightCSS, b.css); err != nil {
+		return nil, err
+	}
+	if err := b.site.writeSearch(w, b.opts.Templates); err != nil {
+		return nil, err
+	}
+	if err := w.Write(RobotsFile, b.site.robots(b.opts.Robots)); err != nil {
+		return nil, err
+	}
+	if b.site.Origin != "" {
+		if err := b.site.writeFeeds(w, b.opts.FeedLimit); err != nil {
+			return nil, err
+		}
+		sitemap, err := b.site.sitemap()
+		if err != nil {
+			return nil, err
+		}
+		if err := w.Write(SitemapFile, sitemap); err != nil {
+			return nil, err
+		}
+	}
+	stale, err := w.Finish()
+	if err != nil {
+		return nil, err
+	}
+	log.Debug("wrote site", "out", b.opts.Out, "files", len(w.written), "removed", len(stale))
+	return b.site, nil
+}
+
+// loadHistory sets the History of every page from the commits that changed
+// its entry, when the theme can show them.
+func (b *Builder) loadHistory(ctx context.Context) error {
+	if _, ok := b.opts.Templates.pages["history.html"]; !ok {
+		return nil
+	}
+	repo, err := git.Open(ctx, b.tree.Root)
+	if errors.Is(err, git.ErrNotRepo) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return pool.Run(ctx, len(b.workers), len(b.site.Pages), func(_, i int) error {
+		p := b.site.Pages[i]
+		revs, err := repo.Log(ctx, b.tree.Abs(p.Entry.Path))
+		if err != nil {
+			return fmt.Errorf("%s: %w", p.Entry.Path, err)
+		}
+		if len(revs) > 0 {
+			p.History, p.HistoryURL = revs, p.URL+"history/"
+		}
+		return nil
+	})
+}
+
+// drawCards draws the preview card of every page, converting them to PNG
+// when there is a converter.
+func (b *Builder) drawCards(ctx context.Context) error {
+	errs := make([]error, len(b.site.Pages))
+	err := pool.Run(ctx, len(b.workers), len(b.site.Pages), func(_, i int) error {
+		p := b.site.Pages[i]
+		var date string
+		if !p.Date.IsZero() {
+			date = p.Date.Format(entry.DateLayout)
+		}
+		svg, err := ogimage.SVG(b.opts.Templates.card, ogimage.NewCard(p.Title, p.Category.Name, b.site.Title, date))
+		if err != nil {
+			return fmt.Errorf("%s: preview card: %w", p.Entry.Path, err)
+		}
+		png, err := b.cards.Convert(ctx, svg)
+		switch {
+		case err == nil:
+			p.Image, p.card = p.URL+"og.png", png
+			return nil
+		case !errors.Is(err, ogimage.ErrUnavailable):
+			errs[i] = fmt.Errorf("%s: preview card: %w", p.Entry.Path, err)
+		}
+		p.Image, p.card = p.URL+"og.svg", svg
+		return nil
+	})
+	for _, err := range errs {
+		if err != nil {
+			b.site.ImageErrors = append(b.site.ImageErrors, err)
+		}
+	}
+	return err
+}
+
+// renderPage renders the entry of p on w, or takes it from the cache when
+// its source is unchanged and its links resolve as they did.
+func (b *Builder) renderPage(w *worker, p *Page) (result, error) {
+	x := include.Expand(p.Entry, b.site.links)
+	src := render.StripTitle(x.Body)
+	res := result{key: b.renderKey(p.Entry.Path, src)}
+	for _, err := range x.Errors {
+		res.includeErrors = append(res.includeErrors, err)
+	}
+	if r, ok := b.cache[res.key]; ok && b.site.current(p.Entry.Path, r) {
+		res.r = r
+		return res, nil
+	}
+	w.links, w.errs = nil, nil
+	html, err := w.renderer.RenderFrom(src, p.Entry.Path)
+	if err != nil {
+		return res, fmt.Errorf("%s: %w", p.Entry.Path, err)
+	}
+	res.r = rendered{
+		HTML:       template.HTML(html),
+		Mermaid:    bytes.Contains(html, render.ClientDiagram("mermaid")),
+		MathScript: bytes.Contains(html, render.ClientMath),
+		Links:      w.links,
+	}
+	res.r.Math = res.r.MathScript || bytes.Contains(html, []byte(`class="katex`))
+	if toc := render.Headings(src); len(toc) >= TOCMin {
+		res.r.TOC = toc
+	}
+	res.fresh = true
+	res.diagramErrors = w.errs
+	return res, nil
+}
+
+// write writes the pages, heatmap, assets and static files of the site on
+// up to jobs goroutines.
+func (s *Site) write(ctx context.Context, w *Writer, tree *notes.Tree, t *Templates, jobs int) error {
+	var tasks []func() error
+	page := func(name, out string, data templateData) {
+		tasks = append(tasks, func() error { return s.writePage(w, t, name, out, data) })
+	}
+	page("index.html", "index.html", templateData{Site: s, Alternates: s.langAlternates()})
+	listing := func(name string, c *Category) {
+		for _, pg := range c.Pagers {
+			page(name, s.outPath(pg.URL), templateData{Site: s, Category: c, Pager: pg})
+		}
+	}
+	for _, c := range s.Categories {
+		listing("category.html", c)
+	}
+	for _, r := range s.Redirects {
+		tasks = append(tasks, func() error { return w.Write(s.outPath(r.From), redirect(r.To)) })
+	}
+	if len(s.Redirects) > 0 {
+		tasks = append(tasks, func() error { return w.Write(RedirectsFile, s.redirectsFile()) })
+	}
+	for _, c := range s.Collections {
+		listing("category.html", c)
+	}
+	for _, m := range s.Months {
+		listing("archive.html", m)
+	}
+	for _, c := range s.Authors {
+		listing("category.html", c)
+	}
+	for _, c := range s.Langs {
+		for _, pg := range c.Pagers {
+			data := templateData{Site: s, Category: c, Pager: pg}
+			if pg.Number == 1 {
+				data.Alternates = s.langAlternates()
+			}
+			page("category.html", s.outPath(pg.URL), data)
+		}
+	}
+	for _, p := range s.Pages {
+		page("entry.html", s.outPath(p.URL), templateData{Site: s, Page: p, Category: p.Category, Alternates: p.alternates()})
+		if p.HistoryURL != "" {
+			page("history.html", s.outPath(p.HistoryURL), templateData{Site: s, Page: p, Category: p.Category})
+		}
+		if p.card != nil {
+			tasks = append(tasks, func() error { return w.Write(strings.TrimPrefix(p.Image, s.Base), p.card) })
+		}
+	}
+	if s.ChangelogURL != "" {
+		page("changelog.html", s.outPath(s.ChangelogURL), templateData{Site: s})
+	}
+	if s.Heatmap != "" {
+		tasks = append(tasks, func() error { return w.Write("heatmap.svg", []byte(s.Heatmap)) })
+	}
+	for _, a := range s.assets {
+		tasks = append(tasks, func() error {
+			data, err := tree.Read(a.path)
+			if err != nil {
+				return err
+			}
+			return w.Write(a.out, data)
+		})
+	}
+	tasks = append(tasks, func() error { return w.CopyFS("", t.static) })
+	return pool.Run(ctx, jobs, len(tasks), func(_, i int) error { return tasks[i]() })
+}
+
+// LoadAssets finds the entry assets of tree, so that ResolveLink resolves
+// links to them. Build does so itself.
+func (s *Site) LoadAssets(tree *notes.Tree) error {
+	var err error
+	s.assets, err = loadAssets(tree)
+	return err
+}
+
+// loadAssets finds the entry assets of tree and names their published
+// copies after their content.
+func loadAssets(tree *notes.Tree) (map[string]asset, error) {
+	paths, err := assets.List(tree)
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string]asset, len(paths))
+	for _, p := range paths {
+		data, err := tree.Read(p)
+		if err != nil {
+			return nil, err
+		}
+		out[p] = asset{path: p, out: assets.Fingerprinted(p, data)}
+	}
+	return out, nil
+}
+
+// redirect returns a page sending browsers on to the URL path to.
+func redirect(to string) []byte {
+	u := template.HTMLEscapeString(to)
+	return []byte(`<!doctype html>
+<meta charset="utf-8">
+<title>Moved</title>
+<link rel="canonical" href="` + u + `">
+<meta http-equiv="refresh" content="0; url=` + u + `">
+<p>Moved to <a href="` + u + `">` + u + `</a>.</p>
+`)
+}
+
+// outPath maps a page URL to the file that serves it.
+func (s *Site) outPath(u string) string {
+	return strings.TrimPrefix(u, s.Base) + "index.html"
+}
+
+func (s *Site) writePage(w *Writer, t *Templates, name, out string, data templateData) error {
+	tmpl, err := t.lookup(name)
+	if err != nil {
+		return err
+	}
+	data.URL = s.Base + strings.TrimSuffix(out, "index.html")
+	switch {
+	case data.Page != nil:
+		data.Lang = data.Page.Lang
+	case data.Category != nil && data.Category.Lang != "":
+		data.Lang = data.Category.Lang
+	default:
+		data.Lang = s.Lang
+	}
+	var buf bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&buf, "layout", data); err != nil {
+		return fmt.Errorf("%s: %w", out, err)
+	}
+	return w.Write(out, buf.Bytes())
+}
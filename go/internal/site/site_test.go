@@ -0,0 +1,553 @@
+package site
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/canhta/til/go/internal/notes"
+	"github.com/canhta/til/go/internal/query"
+	"github.com/canhta/til/go/pkg/render"
+)
+
+func newTree(t *testing.T, files map[string]string) *notes.Tree {
+	t.Helper()
+	tree := notes.Open(t.TempDir())
+	for p, data := range files {
+		if err := tree.Write(p, []byte(data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return tree
+}
+
+// build builds the site of tree into a new directory, returning it too.
+func build(t *testing.T, tree *notes.Tree, opts Options) (*Site, string) {
+	t.Helper()
+	if opts.Out == "" {
+		opts.Out = filepath.Join(t.TempDir(), "public")
+	}
+	s, err := Build(context.Background(), tree, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, opts.Out
+}
+
+func readOut(t *testing.T, out, p string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(out, filepath.FromSlash(p)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func exists(out, p string) bool {
+	_, err := os.Stat(filepath.Join(out, filepath.FromSlash(p)))
+	return err == nil
+}
+
+var siteFiles = map[string]string{
+	"go/slices.md":  "---\ntitle: Slices share arrays\ndate: 2024-06-01\ntags: [go]\n---\n\n# Slices share arrays\n\nSee [maps](maps.md) and [[git/rebase]].\n",
+	"go/maps.md":    "---\ntitle: Maps\ndate: 2024-05-01\n---\n\nMaps are *unordered*.\n",
+	"git/rebase.md": "---\ntitle: Rebase onto\ndate: 2023-01-10\n---\n\nUse `--onto`.\n",
+	"git/draft.md":  "---\ntitle: Unfinished\ndraft: true\n---\n\nTODO\n",
+	"git/secret.md": "---\ntitle: Secret\nprivate: true\n---\n\nHush\n",
+}
+
+func TestBuild(t *testing.T) {
+	s, out := build(t, newTree(t, siteFiles), Options{Title: "My TIL"})
+	var titles []string
+	for _, p := range s.Pages {
+		titles = append(titles, p.Title)
+	}
+	if got := strings.Join(titles, ", "); got != "Slices share arrays, Maps, Rebase onto" {
+		t.Errorf("pages = %s", got)
+	}
+
+	index := readOut(t, out, "index.html")
+	for _, want := range []string{"My TIL", `href="/go/slices/"`, `href="/go/maps/"`, `href="/git/rebase/"`} {
+		if !strings.Contains(index, want) {
+			t.Errorf("index.html lacks %s:\n%s", want, index)
+		}
+	}
+	for _, leak := range []string{"Unfinished", "Secret"} {
+		if strings.Contains(index, leak) {
+			t.Errorf("index.html lists %s", leak)
+		}
+	}
+	if exists(out, "git/draft/index.html") || exists(out, "git/secret/index.html") {
+		t.Error("draft or private entry published")
+	}
+
+	page := readOut(t, out, "go/slices/index.html")
+	for _, want := range []string{`<a href="/go/maps/">maps</a>`, `href="/git/rebase/">Rebase onto</a>`} {
+		if !strings.Contains(page, want) {
+			t.Errorf("go/slices lacks %s:\n%s", want, page)
+		}
+	}
+	// The title heading is the page's own, not part of the content.
+	if strings.Count(page, "Slices share arrays</h1>") != 1 {
+		t.Errorf("go/slices repeats its title:\n%s", page)
+	}
+	if got := readOut(t, out, "go/maps/index.html"); !strings.Contains(got, "<em>unordered</em>") {
+		t.Errorf("go/maps:\n%s", got)
+	}
+	if got := readOut(t, out, "categories/git/index.html"); !strings.Contains(got, `href="/git/rebase/"`) || strings.Contains(got, "/go/") {
+		t.Errorf("categories/git:\n%s", got)
+	}
+	for _, p := range []string{"style.css", HighlightCSS, RobotsFile} {
+		if !exists(out, p) {
+			t.Errorf("%s not written", p)
+		}
+	}
+}
+
+func TestBuildDrafts(t *testing.T) {
+	_, out := build(t, newTree(t, siteFiles), Options{Drafts: true})
+	if !exists(out, "git/draft/index.html") {
+		t.Error("draft not previewed with Drafts")
+	}
+	if exists(out, "git/secret/index.html") {
+		t.Error("private entry published with Drafts")
+	}
+}
+
+func TestBuildRemovesStalePages(t *testing.T) {
+	tree := newTree(t, siteFiles)
+	_, out := build(t, tree, Options{})
+	if err := tree.Remove("go/maps.md"); err != nil {
+		t.Fatal(err)
+	}
+	build(t, tree, Options{Out: out})
+	if exists(out, "go/maps/index.html") {
+		t.Error("page of a removed entry kept")
+	}
+	if !exists(out, "go/slices/index.html") {
+		t.Error("page of a kept entry removed")
+	}
+}
+
+func TestBuildBase(t *testing.T) {
+	_, out := build(t, newTree(t, siteFiles), Options{BaseURL: "https://example.com/til"})
+	if got := readOut(t, out, "index.html"); !strings.Contains(got, `href="/til/go/slices/"`) {
+		t.Errorf("index.html does not link under the base path:\n%s", got)
+	}
+}
+
+func TestBuildRefusesCollisions(t *testing.T) {
+	tree := newTree(t, map[string]string{
+		"go/a.md": "---\ntitle: A\nslug: same\n---\n",
+		"go/b.md": "---\ntitle: B\nslug: same\n---\n",
+	})
+	_, err := Build(context.Background(), tree, Options{Out: filepath.Join(t.TempDir(), "public")})
+	if err == nil || !strings.Contains(err.Error(), "go/a.md, go/b.md map to the same URL /go/same/") {
+		t.Errorf("Build = %v, want a collision", err)
+	}
+}
+
+func TestBuildBacklinks(t *testing.T) {
+	s, out := build(t, newTree(t, map[string]string{
+		"go/slices.md":  "---\ntitle: Slices\ndate: 2024-06-01\n---\n\nSee [[git/rebase]], [[nowhere]] and [[secret]].\n",
+		"go/maps.md":    "---\ntitle: Maps\ndate: 2024-05-01\n---\n\nLike [[rebase|rebasing]].\n",
+		"git/rebase.md": "---\ntitle: Rebase onto\n---\n\nNo links.\n",
+		"git/secret.md": "---\ntitle: Secret\nprivate: true\n---\n\nSee [[rebase]].\n",
+	}), Options{})
+	page := readOut(t, out, "git/rebase/index.html")
+	_, aside, ok := strings.Cut(page, `<aside class="backlinks">`)
+	if !ok {
+		t.Fatalf("git/rebase has no backlinks:\n%s", page)
+	}
+	aside, _, _ = strings.Cut(aside, "</aside>")
+	if i, j := strings.Index(aside, `href="/go/slices/"`), strings.Index(aside, `href="/go/maps/"`); i < 0 || j < i {
+		t.Errorf("backlinks not newest first:\n%s", aside)
+	}
+	if strings.Contains(aside, "Secret") {
+		t.Errorf("a private entry is listed as a backlink:\n%s", aside)
+	}
+	if strings.Contains(readOut(t, out, "go/maps/index.html"), `class="backlinks"`) {
+		t.Error("go/maps lists backlinks without any")
+	}
+	var dangling []string
+	for _, d := range s.Dangling {
+		dangling = append(dangling, d.From+" "+d.Link.Target)
+	}
+	if got := strings.Join(dangling, ", "); got != "go/slices.md nowhere, go/slices.md secret" {
+		t.Errorf("Dangling = %s", got)
+	}
+}
+
+func TestBuildCollections(t *testing.T) {
+	ps := query.Parser{Now: time.Now(), Collections: map[string]string{"Golang": "tag:go", "old": "created:<2024"}}
+	colls := map[string]query.Expr{}
+	for _, name := range []string{"Golang", "old"} {
+		x, err := ps.Parse("@" + name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		colls[name] = x
+	}
+	tree := newTree(t, siteFiles)
+	_, out := build(t, tree, Options{Collections: colls})
+	if exists(out, "collections") {
+		t.Error("collection pages rendered without CollectionPages")
+	}
+
+	s, out := build(t, tree, Options{Collections: colls, CollectionPages: true})
+	var names []string
+	for _, c := range s.Collections {
+		names = append(names, c.Name)
+	}
+	if got := strings.Join(names, ", "); got != "Golang, old" {
+		t.Errorf("Collections = %s", got)
+	}
+	page := readOut(t, out, "collections/golang/index.html")
+	if !strings.Contains(page, `href="/go/slices/"`) || strings.Contains(page, `href="/go/maps/"`) {
+		t.Errorf("collections/golang:\n%s", page)
+	}
+	if page := readOut(t, out, "collections/old/index.html"); !strings.Contains(page, `href="/git/rebase/"`) || strings.Contains(page, `href="/go/slices/"`) {
+		t.Errorf("collections/old:\n%s", page)
+	}
+	if index := readOut(t, out, "index.html"); !strings.Contains(index, `href="/collections/golang/"`) {
+		t.Errorf("index.html does not link the collections:\n%s", index)
+	}
+}
+
+func TestBuildHighlight(t *testing.T) {
+	tree := newTree(t, map[string]string{"go/a.md": "---\ntitle: A\n---\n\n```go {hl_lines=[1]}\nx := 1\n```\n"})
+	css := func(opts Options) string {
+		t.Helper()
+		_, out := build(t, tree, opts)
+		if page := readOut(t, out, "go/a/index.html"); !strings.Contains(page, `<span class="line hl">`) || strings.Contains(page, "<pre style=") {
+			t.Errorf("go/a with %+v:\n%s", opts, page)
+		}
+		return readOut(t, out, HighlightCSS)
+	}
+	want := func(style string) string {
+		t.Helper()
+		data, err := render.CSS(style)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+	if got := css(Options{}); got != want(DefaultStyle) {
+		t.Errorf("default %s =\n%s", HighlightCSS, got)
+	}
+	if got := css(Options{Theme: "terminal"}); got != want("monokai") {
+		t.Errorf("terminal theme's %s =\n%s", HighlightCSS, got)
+	}
+	if got := css(Options{Theme: "terminal", Highlight: "dracula"}); got != want("dracula") {
+		t.Errorf("%s with dracula =\n%s", HighlightCSS, got)
+	}
+	if _, err := Build(context.Background(), tree, Options{Out: filepath.Join(t.TempDir(), "public"), Highlight: "no-such-style"}); err == nil || !strings.Contains(err.Error(), "unknown highlight style") {
+		t.Error("Build with an unknown style succeeded")
+	}
+}
+
+func TestBuildDiagrams(t *testing.T) {
+	tree := newTree(t, map[string]string{"net/tcp.md": "---\ntitle: TCP\n---\n\n```mermaid\nsequenceDiagram\n```\n"})
+
+	draw := []string{"sh", "-c", `printf '<svg id="%s"></svg>' "$2" > "$1"`, "sh", "{out}", "{id}"}
+	s, out := build(t, tree, Options{Mermaid: draw})
+	page := readOut(t, out, "net/tcp/index.html")
+	if !strings.Contains(page, `<figure class="diagram diagram-mermaid"><svg id="diagram-`) || strings.Contains(page, "mermaid.esm") {
+		t.Errorf("net/tcp with a renderer:\n%s", page)
+	}
+	if len(s.DiagramErrors) != 0 {
+		t.Errorf("DiagramErrors = %v", s.DiagramErrors)
+	}
+
+	s, out = build(t, tree, Options{Mermaid: []string{"sh", "-c", "echo broken >&2; exit 1"}})
+	page = readOut(t, out, "net/tcp/index.html")
+	if !strings.Contains(page, `<pre class="mermaid">sequenceDiagram`) || !strings.Contains(page, "mermaid.esm.min.mjs") {
+		t.Errorf("net/tcp with a failing renderer:\n%s", page)
+	}
+	if len(s.DiagramErrors) != 1 || !strings.Contains(s.DiagramErrors[0].Error(), "net/tcp.md: sh: exit status 1: broken") {
+		t.Errorf("DiagramErrors = %v", s.DiagramErrors)
+	}
+}
+
+func TestBuildMath(t *testing.T) {
+	tree := newTree(t, map[string]string{
+		"stats/mean.md": "---\ntitle: Mean\n---\n\nThe mean $\\mu$.\n",
+		"go/maps.md":    "---\ntitle: Maps\n---\n\nCosts $5.\n",
+	})
+	const css, script = "katex.min.css", "auto-render.min.js"
+
+	katex := []string{"sh", "-c", `printf '<span class="katex">%s</span>' "$(cat)"`}
+	_, out := build(t, tree, Options{Math: true, KaTeX: katex})
+	page := readOut(t, out, "stats/mean/index.html")
+	if !strings.Contains(page, `<span class="katex">\mu</span>`) || !strings.Contains(page, css) || strings.Contains(page, script) {
+		t.Errorf("stats/mean pre-rendered:\n%s", page)
+	}
+	if page := readOut(t, out, "go/maps/index.html"); strings.Contains(page, css) {
+		t.Errorf("go/maps without math loads KaTeX:\n%s", page)
+	}
+
+	_, out = build(t, tree, Options{Math: true, KaTeX: []string{"sh", "-c", "exit 1"}})
+	page = readOut(t, out, "stats/mean/index.html")
+	if !strings.Contains(page, `<span class="math math-inline">\(\mu\)</span>`) || !strings.Contains(page, script) {
+		t.Errorf("stats/mean left to the browser:\n%s", page)
+	}
+
+	_, out = build(t, tree, Options{})
+	if page := readOut(t, out, "stats/mean/index.html"); strings.Contains(page, "math-inline") || strings.Contains(page, css) {
+		t.Errorf("stats/mean without Math:\n%s", page)
+	}
+}
+
+func TestBuildIncludes(t *testing.T) {
+	tree := newTree(t, map[string]string{
+		"go/slices.md": "---\ntitle: Slices\n---\n\n## Pitfalls\n\nAppend *aliases*.\n",
+		"git/notes.md": "---\ntitle: Notes\n---\n\n{{include \"go/slices#pitfalls\"}}\n\n{{include \"go/nowhere\"}}\n",
+	})
+	b, err := NewBuilder(tree, Options{Out: filepath.Join(t.TempDir(), "public")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := b.Build(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := b.opts.Out
+	if page := readOut(t, out, "git/notes/index.html"); !strings.Contains(page, "<p>Append <em>aliases</em>.</p>") {
+		t.Errorf("git/notes:\n%s", page)
+	}
+	if len(s.IncludeErrors) != 1 || !strings.Contains(s.IncludeErrors[0].Error(), `git/notes.md:7: include "go/nowhere": not found`) {
+		t.Errorf("IncludeErrors = %v", s.IncludeErrors)
+	}
+
+	// Pages are rendered again when what they include changes.
+	if err := tree.Write("go/slices.md", []byte("---\ntitle: Slices\n---\n\n## Pitfalls\n\nAppend copies.\n")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.Build(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if page := readOut(t, out, "git/notes/index.html"); !strings.Contains(page, "<p>Append copies.</p>") {
+		t.Errorf("git/notes after go/slices changed:\n%s", page)
+	}
+}
+
+func TestBuildTOC(t *testing.T) {
+	_, out := build(t, newTree(t, map[string]string{
+		"go/long.md":  "---\ntitle: Long\n---\n# Long\n\n## One\n\n### Two\n\n## Three\n",
+		"go/short.md": "---\ntitle: Short\n---\n\n## One\n\n## Two\n",
+	}), Options{})
+	page := readOut(t, out, "go/long/index.html")
+	for _, want := range []string{`<nav class="toc">`, `<li class="toc-3"><a href="#two">Two</a></li>`, `<h2 id="one">One<a href="#one" title="Link to this section" class="anchor">#</a></h2>`} {
+		if !strings.Contains(page, want) {
+			t.Errorf("go/long lacks %s:\n%s", want, page)
+		}
+	}
+	if page := readOut(t, out, "go/short/index.html"); strings.Contains(page, `class="toc"`) {
+		t.Errorf("go/short has a TOC with fewer than %d headings:\n%s", TOCMin, page)
+	}
+}
+
+// TestBuildJobs checks that building on many goroutines writes the same
+// site as building on one.
+func TestBuildJobs(t *testing.T) {
+	files := maps.Clone(siteFiles)
+	for i := range 30 {
+		files[fmt.Sprintf("go/e%02d.md", i)] = fmt.Sprintf("---\ntitle: Entry %d\ndate: 2024-01-%02d\ntags: [go, t%d]\n---\n\nSee [[go/slices]].\n", i, i%28+1, i%3)
+	}
+	tree := newTree(t, files)
+	read := func(out string) map[string]string {
+		t.Helper()
+		got := map[string]string{}
+		err := filepath.WalkDir(out, func(p string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return err
+			}
+			data, err := os.ReadFile(p)
+			rel, _ := filepath.Rel(out, p)
+			got[rel] = string(data)
+			return err
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return got
+	}
+	s1, out1 := build(t, tree, Options{Jobs: 1, Force: true, BaseURL: "https://til.example.com/"})
+	s8, out8 := build(t, tree, Options{Jobs: 8, Force: true, BaseURL: "https://til.example.com/"})
+	if !slices.Equal(s1.Rendered, s8.Rendered) {
+		t.Errorf("Rendered = %q on 8 jobs, %q on 1", s8.Rendered, s1.Rendered)
+	}
+	one, eight := read(out1), read(out8)
+	if len(one) != len(eight) {
+		t.Errorf("%d files on 8 jobs, %d on 1", len(eight), len(one))
+	}
+	for p, data := range one {
+		if eight[p] != data {
+			t.Errorf("%s differs between 1 and 8 jobs", p)
+		}
+	}
+}
+
+func TestBuildArchives(t *testing.T) {
+	files := maps.Clone(siteFiles)
+	files["go/iota.md"] = "---\ntitle: Iota\ndate: 2024-06-20\n---\n\nConstants.\n"
+	files["go/undated.md"] = "# Undated\n\nNo date.\n"
+	s, out := build(t, newTree(t, files), Options{PerPage: 2})
+
+	var months []string
+	for _, m := range s.Months {
+		months = append(months, fmt.Sprintf("%s %s %d", m.Name, m.URL, len(m.Pages)))
+	}
+	if want := []string{"June 2024 /2024/06/ 2", "May 2024 /2024/05/ 1", "January 2023 /2023/01/ 1"}; !slices.Equal(months, want) {
+		t.Errorf("months = %q, want %q", months, want)
+	}
+	june := readOut(t, out, "2024/06/index.html")
+	if !strings.Contains(june, "June 2024") || !strings.Contains(june, `href="/go/iota/"`) || !strings.Contains(june, `href="/go/slices/"`) || strings.Contains(june, `href="/go/maps/"`) {
+		t.Errorf("2024/06:\n%s", june)
+	}
+
+	// go has four entries, so two pages of two.
+	cat := s.Categories[slices.IndexFunc(s.Categories, func(c *Category) bool { return c.Name == "go" })]
+	if len(cat.Pagers) != 2 || cat.Pagers[0].URL != "/categories/go/" || cat.Pagers[1].URL != "/categories/go/page/2/" ||
+		cat.Pagers[0].Next != cat.Pagers[1] || cat.Pagers[1].Prev != cat.Pagers[0] || cat.Pagers[0].Prev != nil {
+		t.Errorf("go pagers = %+v", cat.Pagers)
+	}
+	first, second := readOut(t, out, "categories/go/index.html"), readOut(t, out, "categories/go/page/2/index.html")
+	if !strings.Contains(first, "Page 1 of 2") || !strings.Contains(first, `rel="next" href="/categories/go/page/2/"`) || strings.Contains(first, `href="/go/maps/"`) {
+		t.Errorf("categories/go:\n%s", first)
+	}
+	if !strings.Contains(second, `rel="prev" href="/categories/go/"`) || !strings.Contains(second, `href="/go/maps/"`) || !strings.Contains(second, `href="/go/undated/"`) {
+		t.Errorf("categories/go/page/2:\n%s", second)
+	}
+	if got := readOut(t, out, "categories/git/index.html"); strings.Contains(got, "Page 1 of") {
+		t.Errorf("categories/git is paginated:\n%s", got)
+	}
+	if got := readOut(t, out, "go/index.html"); !strings.Contains(got, `url=/categories/go/"`) {
+		t.Errorf("go/index.html does not redirect:\n%s", got)
+	}
+
+	// Dated entries chain from newest to oldest across categories.
+	var chain []string
+	for p := s.Pages[0]; p != nil; p = p.Prev {
+		chain = append(chain, p.Title)
+		if p.Prev != nil && p.Prev.Next != p {
+			t.Errorf("%s.Prev.Next = %v", p.Title, p.Prev.Next)
+		}
+	}
+	if want := []string{"Iota", "Slices share arrays", "Maps", "Rebase onto"}; !slices.Equal(chain, want) {
+		t.Errorf("chain = %q, want %q", chain, want)
+	}
+	undated := s.byPath["go/undated.md"]
+	if undated.Prev != nil || undated.Next != nil || undated.Archive != nil {
+		t.Errorf("undated entry = %+v, want out of the chain and archives", undated)
+	}
+	slicesPage := readOut(t, out, "go/slices/index.html")
+	if !strings.Contains(slicesPage, `rel="prev" href="/go/maps/">← Maps</a>`) || !strings.Contains(slicesPage, `rel="next" href="/go/iota/">Iota →</a>`) {
+		t.Errorf("go/slices lacks prev and next links:\n%s", slicesPage)
+	}
+}
+
+func TestBuildCards(t *testing.T) {
+	dir := t.TempDir()
+	script := func(name, body string) []string {
+		p := filepath.Join(dir, name)
+		if err := os.WriteFile(p, []byte("#!/bin/sh\n"+body+"\n"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		return []string{p, "{file}", "{out}"}
+	}
+	tree := newTree(t, siteFiles)
+
+	s, out := build(t, tree, Options{Title: "My TIL", BaseURL: "https://til.example/", OGImage: script("png", `printf '\211PNG' > "$2"`)})
+	if len(s.ImageErrors) != 0 || s.Page("go/slices.md").Image != "/go/slices/og.png" {
+		t.Errorf("image = %q, errors %v", s.Page("go/slices.md").Image, s.ImageErrors)
+	}
+	if got := readOut(t, out, "go/slices/og.png"); got != "\x89PNG" {
+		t.Errorf("go/slices/og.png = %q", got)
+	}
+	page := readOut(t, out, "go/slices/index.html")
+	for _, want := range []string{
+		`<meta property="og:image" content="https://til.example/go/slices/og.png">`,
+		`<meta name="twitter:card" content="summary_large_image">`,
+		`<meta property="og:title" content="Slices share arrays">`,
+	} {
+		if !strings.Contains(page, want) {
+			t.Errorf("go/slices lacks %s:\n%s", want, page)
+		}
+	}
+
+	s, out = build(t, tree, Options{Title: "My TIL", OGImage: script("fail", "exit 3")})
+	if len(s.ImageErrors) != len(s.Pages) || !strings.Contains(s.ImageErrors[0].Error(), "preview card: ") {
+		t.Errorf("errors = %v", s.ImageErrors)
+	}
+	if svg := readOut(t, out, "git/rebase/og.svg"); !strings.Contains(svg, ">Rebase onto</tspan>") || !strings.Contains(svg, "My TIL · 2023-01-10") {
+		t.Errorf("git/rebase/og.svg =\n%s", svg)
+	}
+	if exists(out, "git/rebase/og.png") {
+		t.Error("og.png written without a converter")
+	}
+
+	// A theme may draw cards its own way.
+	theme := maps.Clone(minimal)
+	theme["category.html"] = &fstest.MapFile{Data: []byte(`{{define "content"}}category{{end}}`)}
+	theme[CardTemplate] = &fstest.MapFile{Data: []byte(`<svg>{{html .Title}}</svg>`)}
+	tmpl, err := LoadTemplates(theme)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, out = build(t, tree, Options{Templates: tmpl, OGImage: script("fail", "exit 3")})
+	if got := readOut(t, out, "go/maps/og.svg"); got != "<svg>Maps</svg>" {
+		t.Errorf("go/maps/og.svg = %q", got)
+	}
+	theme[CardTemplate] = &fstest.MapFile{Data: []byte(`{{if}}`)}
+	if _, err := LoadTemplates(theme); err == nil || !strings.Contains(err.Error(), CardTemplate) {
+		t.Errorf("LoadTemplates with a bad card = %v", err)
+	}
+}
+
+func TestBuildAuthors(t *testing.T) {
+	tree := newTree(t, map[string]string{
+		"go/slices.md":  "---\ntitle: Slices\ndate: 2024-06-01\nauthor: Jane Doe\n---\n",
+		"go/maps.md":    "---\ntitle: Maps\ndate: 2024-05-01\nauthor: Bob\n---\n",
+		"git/rebase.md": "---\ntitle: Rebase\ndate: 2024-04-01\nauthor: Jane Doe\n---\n",
+		"git/bisect.md": "---\ntitle: Bisect\ndate: 2024-03-01\n---\n",
+	})
+	s, out := build(t, tree, Options{Title: "Team TIL", BaseURL: "https://example.com/"})
+	var names []string
+	for _, a := range s.Authors {
+		names = append(names, fmt.Sprintf("%s %s %d", a.Name, a.URL, len(a.Pages)))
+	}
+	if want := []string{"Bob /authors/bob/ 1", "Jane Doe /authors/jane-doe/ 2"}; !slices.Equal(names, want) {
+		t.Errorf("Authors = %q, want %q", names, want)
+	}
+	if s.Page("git/bisect.md").Author != nil {
+		t.Error("entry without an author has one")
+	}
+
+	jane := readOut(t, out, "authors/jane-doe/index.html")
+	if i, j := strings.Index(jane, `href="/go/slices/"`), strings.Index(jane, `href="/git/rebase/"`); i < 0 || j < i || strings.Contains(jane, `href="/go/maps/"`) {
+		t.Errorf("authors/jane-doe =\n%s", jane)
+	}
+	if page := readOut(t, out, "go/slices/index.html"); !strings.Contains(page, `by <a class="author" href="/authors/jane-doe/">Jane Doe</a>`) {
+		t.Errorf("go/slices does not credit its author:\n%s", page)
+	}
+	if index := readOut(t, out, "index.html"); !strings.Contains(index, `href="/authors/bob/"`) {
+		t.Errorf("index.html does not list the authors:\n%s", index)
+	}
+	if atom := readOut(t, out, "atom.xml"); !strings.Contains(atom, "<author>\n      <name>Jane Doe</name>\n      <uri>https://example.com/authors/jane-doe/</uri>\n    </author>") {
+		t.Errorf("atom.xml lacks the author:\n%s", atom)
+	}
+
+	s, _ = build(t, newTree(t, map[string]string{"go/slices.md": "# Slices\n"}), Options{})
+	if len(s.Authors) != 0 {
+		t.Errorf("Authors = %v, want none", s.Authors)
+	}
+}
@@ -0,0 +1,112 @@
+package site
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"html/template"
+	"strings"
+	"time"
+
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+// Files written to the site root for search engines. The sitemap needs
+// absolute URLs and is skipped, like feeds, without an origin.
+const (
+	SitemapFile = "sitemap.xml"
+	RobotsFile  = "robots.txt"
+)
+
+// DefaultRobots is robots.txt unless configured: every crawler may read
+// everything.
+const DefaultRobots = "User-agent: *\nAllow: /\n"
+
+type sitemapDoc struct {
+	XMLName xml.Name     `xml:"urlset"`
+	NS      string       `xml:"xmlns,attr"`
+	URLs    []sitemapURL `xml:"url"`
+}
+
+type sitemapURL struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+// sitemap lists the index, every entry with the date it was last updated,
+// and the first page of each listing.
+func (s *Site) sitemap() ([]byte, error) {
+	doc := sitemapDoc{NS: "http://www.sitemaps.org/schemas/sitemap/0.9"}
+	add := func(u string, t time.Time) {
+		su := sitemapURL{Loc: s.AbsURL(u)}
+		if !t.IsZero() {
+			su.LastMod = t.UTC().Format(entry.DateLayout)
+		}
+		doc.URLs = append(doc.URLs, su)
+	}
+	add(s.Base, s.updated(s.Pages))
+	for _, p := range s.Pages {
+		add(p.URL, p.Entry.LastUpdated())
+	}
+	for _, cs := range [][]*Category{s.Categories, s.Collections, s.Months, s.Authors, s.Langs} {
+		for _, c := range cs {
+			add(c.URL, s.updated(c.Pages))
+		}
+	}
+	out, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), append(out, '\n')...), nil
+}
+
+// robots returns robots.txt: text, or DefaultRobots when empty, pointing
+// to the sitemap when there is one.
+func (s *Site) robots(text string) []byte {
+	if text == "" {
+		text = DefaultRobots
+	}
+	if !strings.HasSuffix(text, "\n") {
+		text += "\n"
+	}
+	if s.Origin != "" && !strings.Contains(strings.ToLower(text), "sitemap:") {
+		text += "\nSitemap: " + s.AbsURL(s.Base+SitemapFile) + "\n"
+	}
+	return []byte(text)
+}
+
+// ArticleLD returns the JSON-LD structured data of p, a schema.org
+// Article, for templates to embed in a script of type
+// application/ld+json.
+func (s *Site) ArticleLD(p *Page) (template.JS, error) {
+	ld := map[string]any{
+		"@context":         "https://schema.org",
+		"@type":            "Article",
+		"headline":         p.Title,
+		"url":              s.AbsURL(p.URL),
+		"mainEntityOfPage": s.AbsURL(p.URL),
+		"articleSection":   p.Category.Name,
+		"wordCount":        p.Words,
+		"isPartOf":         map[string]any{"@type": "WebSite", "name": s.Title, "url": s.AbsURL(s.Base)},
+	}
+	if !p.Date.IsZero() {
+		ld["datePublished"] = p.Date.Format(entry.DateLayout)
+	}
+	if u := p.Entry.LastUpdated(); !u.IsZero() {
+		ld["dateModified"] = u.UTC().Format(time.RFC3339)
+	}
+	if len(p.Tags) > 0 {
+		ld["keywords"] = p.Tags
+	}
+	if p.Image != "" {
+		ld["image"] = s.AbsURL(p.Image)
+	}
+	if p.Author != nil {
+		ld["author"] = map[string]any{"@type": "Person", "name": p.Author.Name, "url": s.AbsURL(p.Author.URL)}
+	}
+	data, err := json.Marshal(ld)
+	if err != nil {
+		return "", err
+	}
+	// Marshal escapes <, > and &, so the data cannot end the script.
+	return template.JS(data), nil
+}
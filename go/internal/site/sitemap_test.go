@@ -0,0 +1,75 @@
+package site
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestBuildSitemap(t *testing.T) {
+	tree := newTree(t, map[string]string{
+		"go/slices.md":  "---\ntitle: Slices share arrays\ndate: 2024-06-01\nupdated: 2024-07-02\ntags: [go]\n---\n\nSlices.\n",
+		"go/maps.md":    "---\ntitle: Maps\ndate: 2024-05-01\nupdated: 2024-05-03\n---\n\nMaps.\n",
+		"git/rebase.md": "---\ntitle: Rebase onto\ndate: 2023-01-10\nupdated: 2023-01-10\n---\n\nRebase.\n",
+		"git/draft.md":  "---\ntitle: Unfinished\ndraft: true\n---\n\nTODO\n",
+	})
+	_, out := build(t, tree, Options{Title: "My TIL", BaseURL: "https://til.example/til/"})
+	var doc sitemapDoc
+	if err := xml.Unmarshal([]byte(readOut(t, out, SitemapFile)), &doc); err != nil {
+		t.Fatal(err)
+	}
+	var locs []string
+	for _, u := range doc.URLs {
+		locs = append(locs, u.Loc+" "+u.LastMod)
+	}
+	want := []string{
+		"https://til.example/til/ 2024-07-02",
+		"https://til.example/til/go/slices/ 2024-07-02",
+		"https://til.example/til/go/maps/ 2024-05-03",
+		"https://til.example/til/git/rebase/ 2023-01-10",
+		"https://til.example/til/categories/git/ 2023-01-10",
+		"https://til.example/til/categories/go/ 2024-07-02",
+		"https://til.example/til/2024/06/ 2024-07-02",
+		"https://til.example/til/2024/05/ 2024-05-03",
+		"https://til.example/til/2023/01/ 2023-01-10",
+	}
+	if !slices.Equal(locs, want) {
+		t.Errorf("sitemap =\n%s\nwant\n%s", strings.Join(locs, "\n"), strings.Join(want, "\n"))
+	}
+	if got := readOut(t, out, RobotsFile); got != DefaultRobots+"\nSitemap: https://til.example/til/sitemap.xml\n" {
+		t.Errorf("%s =\n%s", RobotsFile, got)
+	}
+
+	page := readOut(t, out, "go/slices/index.html")
+	if !strings.Contains(page, `<link rel="canonical" href="https://til.example/til/go/slices/">`) {
+		t.Errorf("go/slices has no canonical link:\n%s", page)
+	}
+	_, script, _ := strings.Cut(page, `<script type="application/ld+json">`)
+	script, _, _ = strings.Cut(script, "</script>")
+	var ld map[string]any
+	if err := json.Unmarshal([]byte(script), &ld); err != nil {
+		t.Fatalf("JSON-LD %q: %v", script, err)
+	}
+	if ld["@type"] != "Article" || ld["headline"] != "Slices share arrays" || ld["url"] != "https://til.example/til/go/slices/" ||
+		ld["datePublished"] != "2024-06-01" || ld["dateModified"] != "2024-07-02T00:00:00Z" || ld["articleSection"] != "go" || !slices.Equal(ld["keywords"].([]any), []any{"go"}) {
+		t.Errorf("JSON-LD = %v", ld)
+	}
+	if got := readOut(t, out, "categories/go/index.html"); !strings.Contains(got, `<link rel="canonical" href="https://til.example/til/categories/go/">`) {
+		t.Errorf("categories/go has no canonical link:\n%s", got)
+	}
+
+	// Without an origin, there is no sitemap to point to.
+	_, out = build(t, tree, Options{Robots: "User-agent: *\nDisallow: /drafts/"})
+	if exists(out, SitemapFile) {
+		t.Error("sitemap written without an absolute base URL")
+	}
+	if got := readOut(t, out, RobotsFile); got != "User-agent: *\nDisallow: /drafts/\n" {
+		t.Errorf("%s =\n%s", RobotsFile, got)
+	}
+	_, out = build(t, tree, Options{BaseURL: "https://til.example/", Robots: "Sitemap: https://elsewhere.example/map.xml\n"})
+	if got := readOut(t, out, RobotsFile); got != "Sitemap: https://elsewhere.example/map.xml\n" {
+		t.Errorf("%s with its own sitemap =\n%s", RobotsFile, got)
+	}
+}
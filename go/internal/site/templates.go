@@ -0,0 +1,225 @@
+package site
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	texttemplate "text/template"
+
+	"github.com/canhta/til/go/internal/notes"
+	"github.com/canhta/til/go/internal/ogimage"
+)
+
+//go:embed themes
+var builtinFS embed.FS
+
+// DefaultTheme is the theme used when none is configured.
+const DefaultTheme = "default"
+
+// ThemeDir is the directory, inside the notes state directory, whose files
+// shadow those of the theme in use.
+const ThemeDir = "theme"
+
+// DefaultStyle is the chroma style code is highlighted with when neither
+// the options nor the theme name one.
+const DefaultStyle = "github"
+
+// HighlightCSS is the stylesheet, written to the site root, that colours
+// highlighted code.
+const HighlightCSS = "chroma.css"
+
+// Page template names. Each is parsed together with the layout and partials.
+var pageTemplates = []string{"index.html", "category.html", "entry.html", "archive.html"}
+
+// fallbacks name the page templates a theme may leave out, and the
+// template used instead, so that themes written before them still load.
+var fallbacks = map[string]string{"archive.html": "category.html"}
+
+// optionalTemplates are page templates whose pages are only written when
+// the theme has them.
+var optionalTemplates = []string{"search.html", "history.html", "changelog.html"}
+
+// CardTemplate is the optional theme file drawing preview cards.
+const CardTemplate = "og-image.svg"
+
+// Templates holds the parsed page templates and the static assets that
+// accompany them.
+type Templates struct {
+	pages  map[string]*template.Template
+	static fs.FS
+	// style is the output of the optional "highlight-style" template, the
+	// theme's choice of chroma style.
+	style string
+	// card draws the preview cards of entries: the theme's og-image.svg,
+	// else ogimage.Default.
+	card *texttemplate.Template
+}
+
+// Themes lists the names of the builtin themes.
+func Themes() []string {
+	des, _ := builtinFS.ReadDir("themes")
+	var names []string
+	for _, d := range des {
+		names = append(names, d.Name())
+	}
+	return names
+}
+
+// LoadTheme loads the theme name for tree: a builtin theme, or else a
+// directory, relative to the notes root, laid out like one. Files in the
+// tree's override directory (.til/theme) shadow the theme's files of the
+// same path, so a single partial or the stylesheet can be replaced without
+// copying the whole theme.
+func LoadTheme(tree *notes.Tree, name string) (*Templates, error) {
+	if name == "" {
+		name = DefaultTheme
+	}
+	var theme fs.FS
+	if slices.Contains(Themes(), name) {
+		sub, err := fs.Sub(builtinFS, "themes/"+name)
+		if err != nil {
+			return nil, err
+		}
+		theme = sub
+	} else {
+		dir := name
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(tree.Root, dir)
+		}
+		if fi, err := os.Stat(dir); err != nil || !fi.IsDir() {
+			return nil, fmt.Errorf("unknown theme %q: not a builtin theme (%s) nor a directory", name, strings.Join(Themes(), ", "))
+		}
+		theme = os.DirFS(dir)
+	}
+	fsys := theme
+	override := tree.StatePath(ThemeDir)
+	if fi, err := os.Stat(override); err == nil && fi.IsDir() {
+		fsys = overlay{os.DirFS(override), theme}
+	}
+	t, err := LoadTemplates(fsys)
+	if err != nil {
+		return nil, fmt.Errorf("theme %s: %w", name, err)
+	}
+	return t, nil
+}
+
+// LoadTemplates parses the templates in fsys. It must contain layout.html,
+// the partials/*.html templates they share, one file per page template, of
+// which archive.html falls back to category.html, and a static directory.
+// It may contain search.html, the search page, history.html, listing the
+// commits that changed an entry, changelog.html, summarizing the entries
+// added and updated lately, and CardTemplate, drawing the preview
+// cards of entries as package ogimage describes. A partial may define
+// "highlight-style" as the name of the chroma style that suits the theme.
+func LoadTemplates(fsys fs.FS) (*Templates, error) {
+	base, err := template.New("layout").Funcs(funcs).ParseFS(fsys, "layout.html", "partials/*.html")
+	if err != nil {
+		return nil, err
+	}
+	t := &Templates{pages: map[string]*template.Template{}, style: DefaultStyle}
+	for _, name := range pageTemplates {
+		clone, err := base.Clone()
+		if err != nil {
+			return nil, err
+		}
+		file := name
+		if alt, ok := fallbacks[name]; ok {
+			if _, err := fs.Stat(fsys, name); errors.Is(err, fs.ErrNotExist) {
+				file = alt
+			}
+		}
+		if t.pages[name], err = clone.ParseFS(fsys, file); err != nil {
+			return nil, err
+		}
+	}
+	for _, name := range optionalTemplates {
+		if _, err := fs.Stat(fsys, name); errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		clone, err := base.Clone()
+		if err != nil {
+			return nil, err
+		}
+		if t.pages[name], err = clone.ParseFS(fsys, name); err != nil {
+			return nil, err
+		}
+	}
+	t.card = ogimage.Default
+	if text, err := fs.ReadFile(fsys, CardTemplate); err == nil {
+		if t.card, err = ogimage.Parse(string(text)); err != nil {
+			return nil, fmt.Errorf("%s: %w", CardTemplate, err)
+		}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	if t.static, err = fs.Sub(fsys, "static"); err != nil {
+		return nil, err
+	}
+	// Executed templates can no longer be cloned, hence last.
+	if base.Lookup("highlight-style") != nil {
+		var buf strings.Builder
+		if err := base.ExecuteTemplate(&buf, "highlight-style", nil); err != nil {
+			return nil, err
+		}
+		t.style = strings.TrimSpace(buf.String())
+	}
+	return t, nil
+}
+
+var funcs = template.FuncMap{}
+
+func (t *Templates) lookup(name string) (*template.Template, error) {
+	tmpl, ok := t.pages[name]
+	if !ok {
+		return nil, fmt.Errorf("no template %q", name)
+	}
+	return tmpl, nil
+}
+
+// overlay is a file system made of layers, each file coming from the first
+// layer that has it. Directories list the files of every layer.
+type overlay []fs.FS
+
+func (o overlay) Open(name string) (fs.File, error) {
+	for _, l := range o {
+		f, err := l.Open(name)
+		if !errors.Is(err, fs.ErrNotExist) {
+			return f, err
+		}
+	}
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
+
+func (o overlay) ReadDir(name string) ([]fs.DirEntry, error) {
+	seen := map[string]bool{}
+	var out []fs.DirEntry
+	found := false
+	for _, l := range o {
+		des, err := fs.ReadDir(l, name)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		found = true
+		for _, d := range des {
+			if !seen[d.Name()] {
+				seen[d.Name()] = true
+				out = append(out, d)
+			}
+		}
+	}
+	if !found {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name() < out[j].Name() })
+	return out, nil
+}
@@ -0,0 +1,135 @@
+package site
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestThemes(t *testing.T) {
+	if got := Themes(); !slices.Equal(got, []string{"default", "terminal"}) {
+		t.Errorf("Themes() = %q", got)
+	}
+}
+
+func TestLoadTheme(t *testing.T) {
+	tree := newTree(t, siteFiles)
+	for _, name := range append(Themes(), "") {
+		tmpl, err := LoadTheme(tree, name)
+		if err != nil {
+			t.Fatalf("LoadTheme(%q): %v", name, err)
+		}
+		if _, err := fs.Stat(tmpl.static, "style.css"); err != nil {
+			t.Errorf("theme %q has no style.css: %v", name, err)
+		}
+	}
+	if tmpl, _ := LoadTheme(tree, "terminal"); tmpl.style != "monokai" {
+		t.Errorf("terminal highlight style = %q, want monokai", tmpl.style)
+	}
+	if _, err := LoadTheme(tree, "nope"); err == nil || !strings.Contains(err.Error(), "default, terminal") {
+		t.Errorf("LoadTheme(nope) = %v", err)
+	}
+
+	_, out := build(t, tree, Options{Title: "notes", Theme: "terminal"})
+	if got := readOut(t, out, "index.html"); !strings.Contains(got, "~/notes") {
+		t.Errorf("terminal index.html:\n%s", got)
+	}
+}
+
+func TestLoadThemeOverrides(t *testing.T) {
+	tree := newTree(t, siteFiles)
+	footer := tree.StatePath(ThemeDir, "partials", "footer.html")
+	if err := os.MkdirAll(filepath.Dir(footer), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(footer, []byte(`{{define "footer"}}<footer>overridden</footer>{{end}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(tree.StatePath(ThemeDir, "partials", "extra.html"), []byte(`{{define "extra"}}{{end}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	_, out := build(t, tree, Options{})
+	index := readOut(t, out, "index.html")
+	if !strings.Contains(index, "<footer>overridden</footer>") || !strings.Contains(index, `href="/go/slices/"`) {
+		t.Errorf("index.html with an overridden footer:\n%s", index)
+	}
+	if !exists(out, "style.css") {
+		t.Error("the theme's style.css is not written under the override")
+	}
+}
+
+// minimal is a theme with only the required files.
+var minimal = fstest.MapFS{
+	"layout.html":           {Data: []byte(`{{define "layout"}}[{{template "content" .}}]{{end}}`)},
+	"partials/nothing.html": {Data: []byte(`{{define "highlight-style"}} dracula {{end}}`)},
+	"index.html":            {Data: []byte(`{{define "content"}}index{{end}}`)},
+	"category.html":         {Data: []byte(`{{define "content"}}{{.Title}}{{end}}`)},
+	"entry.html":            {Data: []byte(`{{define "content"}}entry{{end}}`)},
+	"static/style.css":      {Data: []byte("body{}")},
+}
+
+func TestLoadTemplates(t *testing.T) {
+	tmpl, err := LoadTemplates(minimal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tmpl.style != "dracula" {
+		t.Errorf("style = %q", tmpl.style)
+	}
+	// Pages a theme lacks fall back, or are left out when optional.
+	if _, err := tmpl.lookup("archive.html"); err != nil {
+		t.Errorf("archive.html did not fall back to category.html: %v", err)
+	}
+	if _, err := tmpl.lookup("search.html"); err == nil {
+		t.Error("theme without search.html has a search page")
+	}
+
+	dir := t.TempDir()
+	if err := os.CopyFS(dir, minimal); err != nil {
+		t.Fatal(err)
+	}
+	tree := newTree(t, map[string]string{"go/a.md": "# A\n"})
+	if _, err := LoadTheme(tree, dir); err != nil {
+		t.Errorf("LoadTheme(directory): %v", err)
+	}
+
+	broken := fstest.MapFS{}
+	for k, v := range minimal {
+		broken[k] = v
+	}
+	delete(broken, "entry.html")
+	if _, err := LoadTemplates(broken); err == nil {
+		t.Error("LoadTemplates without entry.html succeeded")
+	}
+}
+
+func TestOverlay(t *testing.T) {
+	o := overlay{
+		fstest.MapFS{"a.txt": {Data: []byte("top")}, "d/x": {}},
+		fstest.MapFS{"a.txt": {Data: []byte("bottom")}, "b.txt": {}, "d/y": {}},
+	}
+	if data, _ := fs.ReadFile(o, "a.txt"); string(data) != "top" {
+		t.Errorf("a.txt = %q, want the top layer's", data)
+	}
+	if _, err := fs.ReadFile(o, "b.txt"); err != nil {
+		t.Errorf("b.txt: %v", err)
+	}
+	var names []string
+	des, err := fs.ReadDir(o, "d")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range des {
+		names = append(names, d.Name())
+	}
+	if !slices.Equal(names, []string{"x", "y"}) {
+		t.Errorf("ReadDir(d) = %q", names)
+	}
+	if _, err := fs.ReadDir(o, "none"); err == nil {
+		t.Error("ReadDir of a missing directory succeeded")
+	}
+}
@@ -0,0 +1,114 @@
+package site
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/canhta/til/go/internal/fsutil"
+)
+
+// marker identifies a directory as generated by til, making it safe to
+// remove stale files from.
+const marker = ".til-site"
+
+// Writer writes generated files below Dir and, on Finish, removes files
+// left over from previous builds.
+type Writer struct {
+	Dir string
+	// mu guards written, as files may be written concurrently.
+	mu      sync.Mutex
+	written map[string]bool
+}
+
+// NewWriter prepares dir for output. It refuses to write into an existing,
+// non-empty directory that was not created by a previous build.
+func NewWriter(dir string) (*Writer, error) {
+	des, err := os.ReadDir(dir)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+	case err != nil:
+		return nil, err
+	case len(des) > 0:
+		if _, err := os.Stat(filepath.Join(dir, marker)); err != nil {
+			return nil, fmt.Errorf("%s is not empty and was not generated by til build", dir)
+		}
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	w := &Writer{Dir: dir, written: map[string]bool{}}
+	return w, w.Write(marker, nil)
+}
+
+// Write writes data to the slash-separated path rel below the output
+// directory, replacing any existing file atomically. Files whose content
+// is unchanged are left untouched so their modification times stay stable.
+// It is safe to call from several goroutines.
+func (w *Writer) Write(rel string, data []byte) error {
+	file := filepath.Join(w.Dir, filepath.FromSlash(rel))
+	w.mu.Lock()
+	w.written[filepath.Clean(file)] = true
+	w.mu.Unlock()
+	if old, err := os.ReadFile(file); err == nil && bytes.Equal(old, data) {
+		return nil
+	}
+	return fsutil.WriteFile(file, data, 0o644)
+}
+
+// CopyFS copies every file of fsys below the directory prefix.
+func (w *Writer) CopyFS(prefix string, fsys fs.FS) error {
+	return fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		data, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return err
+		}
+		return w.Write(filepath.ToSlash(filepath.Join(prefix, p)), data)
+	})
+}
+
+// Finish removes files and empty directories that were not written during
+// this build. It returns the removed paths relative to the output directory.
+func (w *Writer) Finish() ([]string, error) {
+	var stale, dirs []string
+	err := filepath.WalkDir(w.Dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if p != w.Dir {
+				dirs = append(dirs, p)
+			}
+			return nil
+		}
+		if !w.written[filepath.Clean(p)] {
+			stale = append(stale, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	removed := make([]string, 0, len(stale))
+	for _, p := range stale {
+		if err := os.Remove(p); err != nil {
+			return removed, err
+		}
+		rel, _ := filepath.Rel(w.Dir, p)
+		removed = append(removed, filepath.ToSlash(rel))
+	}
+	// Deepest directories first so parents become empty before we reach them.
+	sort.Sort(sort.Reverse(sort.StringSlice(dirs)))
+	for _, d := range dirs {
+		os.Remove(d) // fails harmlessly when not empty
+	}
+	return removed, nil
+}
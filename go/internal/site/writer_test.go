@@ -0,0 +1,113 @@
+package site
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestNewWriterRefusesForeignDir(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "index.html"), []byte("mine"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewWriter(dir); err == nil {
+		t.Fatal("NewWriter succeeded on a directory til did not generate")
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "index.html")); string(data) != "mine" {
+		t.Errorf("index.html = %q after refusing", data)
+	}
+}
+
+func TestNewWriterCreatesDir(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "public", "site")
+	if _, err := NewWriter(dir); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, marker)); err != nil {
+		t.Errorf("no marker: %v", err)
+	}
+	// A directory generated before is written into again.
+	if err := os.WriteFile(filepath.Join(dir, "old.html"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewWriter(dir); err != nil {
+		t.Errorf("NewWriter on a generated directory: %v", err)
+	}
+}
+
+func TestWriteKeepsUnchangedFiles(t *testing.T) {
+	dir := t.TempDir()
+	w, err := NewWriter(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Write("go/slices/index.html", []byte("a")); err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(dir, "go", "slices", "index.html")
+	old := time.Now().Add(-time.Hour).Truncate(time.Second)
+	if err := os.Chtimes(file, old, old); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Write("go/slices/index.html", []byte("a")); err != nil {
+		t.Fatal(err)
+	}
+	if fi, _ := os.Stat(file); !fi.ModTime().Equal(old) {
+		t.Errorf("unchanged file rewritten: modified %v, want %v", fi.ModTime(), old)
+	}
+	if err := w.Write("go/slices/index.html", []byte("b")); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(file); string(data) != "b" {
+		t.Errorf("file = %q, want b", data)
+	}
+}
+
+func TestFinishRemovesStaleFiles(t *testing.T) {
+	dir := t.TempDir()
+	w, err := NewWriter(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, rel := range []string{"index.html", "go/a/index.html", "git/b/index.html"} {
+		if err := w.Write(rel, []byte(rel)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := w.Finish(); err != nil {
+		t.Fatal(err)
+	}
+
+	w, err = NewWriter(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Write("index.html", []byte("index.html")); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.CopyFS("static", fstest.MapFS{"style.css": {Data: []byte("body{}")}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Write("go/a/index.html", []byte("go/a/index.html")); err != nil {
+		t.Fatal(err)
+	}
+	removed, err := w.Finish()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(removed, []string{"git/b/index.html"}) {
+		t.Errorf("removed %q, want [git/b/index.html]", removed)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "git")); !os.IsNotExist(err) {
+		t.Errorf("empty directory git left: %v", err)
+	}
+	for _, rel := range []string{marker, "index.html", "static/style.css", "go/a/index.html"} {
+		if _, err := os.Stat(filepath.Join(dir, filepath.FromSlash(rel))); err != nil {
+			t.Errorf("%s: %v", rel, err)
+		}
+	}
+}
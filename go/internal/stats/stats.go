@@ -0,0 +1,178 @@
+// Package stats summarises a notes tree: how many entries were written
+// when, in which categories, under which tags and by whom, and writing
+// streaks.
+// Journal files are summarised apart, by the bullets logged in them.
+package stats
+
+import (
+	"sort"
+	"time"
+
+	"github.com/canhta/til/go/internal/goals"
+	"github.com/canhta/til/go/internal/journal"
+	"github.com/canhta/til/go/internal/tags"
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+// Stats summarises a set of entries.
+type Stats struct {
+	Entries int `json:"entries"`
+	// Undated counts entries without a creation date; they are left out
+	// of the per-month counts and streaks.
+	Undated     int          `json:"undated"`
+	PerMonth    []Count      `json:"per_month"`
+	PerCategory []Count      `json:"per_category"`
+	PerTag      []tags.Count `json:"per_tag"`
+	// PerAuthor counts the entries of each author, and is empty when no
+	// entry names one.
+	PerAuthor []Count `json:"per_author,omitempty"`
+	// CurrentStreak is the number of consecutive days, ending today or
+	// yesterday, on which at least one entry was created.
+	CurrentStreak int `json:"current_streak"`
+	// LongestStreak is the longest run of such days.
+	LongestStreak int `json:"longest_streak"`
+	// LongestStreakEnd is the last day of the longest streak.
+	LongestStreakEnd string `json:"longest_streak_end,omitempty"`
+	// Words, CodeLines and ReadingMinutes total the entry.Counts of the
+	// entries and their reading times.
+	Words          int `json:"words"`
+	CodeLines      int `json:"code_lines"`
+	ReadingMinutes int `json:"reading_minutes"`
+	// AverageWords is the mean number of words in entry bodies.
+	AverageWords int `json:"average_words"`
+	// Goals is the progress on the configured goals, which Compute leaves
+	// to the caller as goals count every entry, not just those summarised.
+	Goals []goals.Progress `json:"goals,omitempty"`
+	// Journal summarises the journal files, which the other fields leave
+	// out; it is nil when there are none.
+	Journal *Journal `json:"journal,omitempty"`
+}
+
+// Journal counts the days written to in the journal and the bullets
+// logged, in all and per month.
+type Journal struct {
+	Days     int     `json:"days"`
+	Items    int     `json:"items"`
+	PerMonth []Count `json:"per_month"`
+}
+
+// Count is the number of entries in a month, category or by an author.
+type Count struct {
+	Key   string `json:"key"`
+	Count int    `json:"count"`
+}
+
+// MonthLayout formats the per-month keys.
+const MonthLayout = "2006-01"
+
+// Compute summarises entries as of now.
+func Compute(entries []*entry.Entry, now time.Time) *Stats {
+	entries, logs := split(entries)
+	s := &Stats{Entries: len(entries), PerTag: tags.Counts(entries)}
+	if len(logs) > 0 {
+		s.Journal = &Journal{Days: len(logs)}
+		months := map[string]int{}
+		for _, e := range logs {
+			for _, it := range journal.Items(e) {
+				s.Journal.Items++
+				months[it.Time.Format(MonthLayout)]++
+			}
+		}
+		s.Journal.PerMonth = sorted(months, func(a, b Count) bool { return a.Key < b.Key })
+	}
+	months := map[string]int{}
+	cats := map[string]int{}
+	authors := map[string]int{}
+	var days []time.Time
+	for _, e := range entries {
+		cats[e.Meta.Category]++
+		if e.Meta.Author != "" {
+			authors[e.Meta.Author]++
+		}
+		s.Words += e.Counts.Words
+		s.CodeLines += e.Counts.CodeLines
+		s.ReadingMinutes += e.Counts.ReadingMinutes()
+		d := e.Created()
+		if d.IsZero() {
+			s.Undated++
+			continue
+		}
+		months[d.Format(MonthLayout)]++
+		days = append(days, d)
+	}
+	if len(entries) > 0 {
+		s.AverageWords = s.Words / len(entries)
+	}
+	s.PerMonth = sorted(months, func(a, b Count) bool { return a.Key < b.Key })
+	byCount := func(a, b Count) bool {
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		return a.Key < b.Key
+	}
+	s.PerCategory = sorted(cats, byCount)
+	if len(authors) > 0 {
+		s.PerAuthor = sorted(authors, byCount)
+	}
+	s.CurrentStreak, s.LongestStreak, s.LongestStreakEnd = streaks(days, now)
+	return s
+}
+
+// split separates journal files from the other entries.
+func split(all []*entry.Entry) (entries, logs []*entry.Entry) {
+	for _, e := range all {
+		if journal.Is(e) {
+			logs = append(logs, e)
+		} else {
+			entries = append(entries, e)
+		}
+	}
+	return entries, logs
+}
+
+func sorted(m map[string]int, less func(a, b Count) bool) []Count {
+	out := make([]Count, 0, len(m))
+	for k, n := range m {
+		out = append(out, Count{Key: k, Count: n})
+	}
+	sort.Slice(out, func(i, j int) bool { return less(out[i], out[j]) })
+	return out
+}
+
+// day truncates t to its calendar day as a comparable value.
+func day(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func streaks(dates []time.Time, now time.Time) (current, longest int, longestEnd string) {
+	set := map[time.Time]bool{}
+	for _, d := range dates {
+		set[day(d)] = true
+	}
+	days := make([]time.Time, 0, len(set))
+	for d := range set {
+		days = append(days, d)
+	}
+	sort.Slice(days, func(i, j int) bool { return days[i].Before(days[j]) })
+	run := 0
+	for i, d := range days {
+		if i > 0 && days[i-1].AddDate(0, 0, 1).Equal(d) {
+			run++
+		} else {
+			run = 1
+		}
+		if run >= longest {
+			longest, longestEnd = run, d.Format(entry.DateLayout)
+		}
+	}
+	// A streak is still current until a whole day passes without an entry.
+	d := day(now)
+	if !set[d] {
+		d = d.AddDate(0, 0, -1)
+	}
+	for set[d] {
+		current++
+		d = d.AddDate(0, 0, -1)
+	}
+	return current, longest, longestEnd
+}
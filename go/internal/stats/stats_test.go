@@ -0,0 +1,112 @@
+package stats
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/canhta/til/go/internal/tags"
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+func parse(t *testing.T, p, data string) *entry.Entry {
+	t.Helper()
+	e, err := entry.Parse(p, []byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+func TestCompute(t *testing.T) {
+	entries := []*entry.Entry{
+		parse(t, "go/a.md", "---\ndate: 2024-05-30\ntags: [go]\nauthor: Ann\n---\none two three four\n"),
+		parse(t, "go/b.md", "---\ndate: 2024-05-31\ntags: [go, slices]\nauthor: Ann\n---\none two\n\n```go\nx := 1\ny := 2\n```\n"),
+		parse(t, "go/c.md", "---\ndate: 2024-06-01\ntags: [go]\nauthor: Bob\n---\none two three four five six\n"),
+		parse(t, "git/d.md", "---\ndate: 2024-04-01\n---\nwords\n"),
+		parse(t, "git/e.md", "# Undated\n"),
+	}
+	now := time.Date(2024, 6, 2, 9, 0, 0, 0, time.UTC)
+	s := Compute(entries, now)
+	if s.Entries != 5 || s.Undated != 1 {
+		t.Errorf("entries %d, undated %d", s.Entries, s.Undated)
+	}
+	if want := []Count{{"2024-04", 1}, {"2024-05", 2}, {"2024-06", 1}}; !reflect.DeepEqual(s.PerMonth, want) {
+		t.Errorf("PerMonth = %v, want %v", s.PerMonth, want)
+	}
+	if want := []Count{{"go", 3}, {"git", 2}}; !reflect.DeepEqual(s.PerCategory, want) {
+		t.Errorf("PerCategory = %v, want %v", s.PerCategory, want)
+	}
+	if want := []tags.Count{{Tag: "go", Count: 3}, {Tag: "slices", Count: 1}}; !reflect.DeepEqual(s.PerTag, want) {
+		t.Errorf("PerTag = %v, want %v", s.PerTag, want)
+	}
+	if want := []Count{{"Ann", 2}, {"Bob", 1}}; !reflect.DeepEqual(s.PerAuthor, want) {
+		t.Errorf("PerAuthor = %v, want %v", s.PerAuthor, want)
+	}
+	if s.CurrentStreak != 3 || s.LongestStreak != 3 || s.LongestStreakEnd != "2024-06-01" {
+		t.Errorf("streaks: current %d, longest %d to %s", s.CurrentStreak, s.LongestStreak, s.LongestStreakEnd)
+	}
+	if s.Words != 14 || s.AverageWords != 2 || s.CodeLines != 2 {
+		t.Errorf("words %d, average %d, code lines %d", s.Words, s.AverageWords, s.CodeLines)
+	}
+	if s.Journal != nil {
+		t.Errorf("Journal = %+v without journal files", s.Journal)
+	}
+	if s := Compute(nil, now); s.Entries != 0 || s.AverageWords != 0 || s.PerAuthor != nil {
+		t.Errorf("Compute(nil) = %+v", s)
+	}
+}
+
+func TestStreaks(t *testing.T) {
+	days := func(ds ...string) []time.Time {
+		var out []time.Time
+		for _, d := range ds {
+			t, _ := time.Parse(time.DateOnly, d)
+			out = append(out, t.Add(15*time.Hour))
+		}
+		return out
+	}
+	now := time.Date(2024, 6, 10, 8, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name          string
+		dates         []time.Time
+		current, long int
+		end           string
+	}{
+		{"none", nil, 0, 0, ""},
+		{"today", days("2024-06-10"), 1, 1, "2024-06-10"},
+		{"through yesterday", days("2024-06-08", "2024-06-09", "2024-06-09"), 2, 2, "2024-06-09"},
+		{"broken", days("2024-06-07", "2024-06-08"), 0, 2, "2024-06-08"},
+		{"longest earlier", days("2024-05-01", "2024-05-02", "2024-05-03", "2024-06-09", "2024-06-10"), 2, 3, "2024-05-03"},
+		{"tie keeps the latest", days("2024-05-01", "2024-05-02", "2024-06-01", "2024-06-02"), 0, 2, "2024-06-02"},
+		{"across months", days("2024-05-31", "2024-06-01"), 0, 2, "2024-06-01"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			current, long, end := streaks(tt.dates, now)
+			if current != tt.current || long != tt.long || end != tt.end {
+				t.Errorf("streaks = %d, %d, %q; want %d, %d, %q", current, long, end, tt.current, tt.long, tt.end)
+			}
+		})
+	}
+}
+
+func TestComputeJournal(t *testing.T) {
+	entries := []*entry.Entry{
+		parse(t, "go/a.md", "---\ndate: 2024-05-30\n---\none\n"),
+		parse(t, "journal/2024-05-31.md", "---\ndate: 2024-05-31\n---\n\n- 09:00 one\n- 10:00 two\n  still two\n"),
+		parse(t, "journal/2024-06-01.md", "- 09:00 three\n"),
+		parse(t, "journal/2024-06-02.md", "nothing logged\n"),
+	}
+	s := Compute(entries, time.Date(2024, 6, 2, 9, 0, 0, 0, time.UTC))
+	if s.Entries != 1 || !reflect.DeepEqual(s.PerCategory, []Count{{"go", 1}}) {
+		t.Errorf("entries %d, PerCategory %v, want the journal left out", s.Entries, s.PerCategory)
+	}
+	want := &Journal{Days: 3, Items: 3, PerMonth: []Count{{"2024-05", 2}, {"2024-06", 1}}}
+	if !reflect.DeepEqual(s.Journal, want) {
+		t.Errorf("Journal = %+v, want %+v", s.Journal, want)
+	}
+	if s := Compute(entries[:1], time.Now()); s.Journal != nil {
+		t.Errorf("Journal without journal files = %+v", s.Journal)
+	}
+}
@@ -0,0 +1,201 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/canhta/til/go/internal/fsutil"
+)
+
+// FS stores files in a local directory.
+type FS struct {
+	Root string
+	// Skip reports whether a directory with the given name is ignored by
+	// List and Watch.
+	Skip func(name string) bool
+}
+
+var (
+	_ Store   = (*FS)(nil)
+	_ Batcher = (*FS)(nil)
+	_ Local   = (*FS)(nil)
+)
+
+// File returns the local file for the slash-separated path p.
+func (s *FS) File(p string) string {
+	return filepath.Join(s.Root, filepath.FromSlash(p))
+}
+
+func (s *FS) skip(name string) bool {
+	return s.Skip != nil && s.Skip(name)
+}
+
+// List implements Store.
+func (s *FS) List(ctx context.Context) ([]Info, error) {
+	var out []Info
+	err := filepath.WalkDir(s.Root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if p == s.Root {
+			return nil
+		}
+		if d.IsDir() {
+			if s.skip(d.Name()) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, err := s.rel(p)
+		if err != nil {
+			return err
+		}
+		// Files at the root (README.md and friends) are not entries, and
+		// dot files are editor and temporary files.
+		if !strings.Contains(rel, "/") || strings.HasPrefix(d.Name(), ".") {
+			return nil
+		}
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+		out = append(out, Info{Path: rel, ModTime: fi.ModTime(), Size: fi.Size()})
+		return nil
+	})
+	sort.Slice(out, func(i, j int) bool { return out[i].Path < out[j].Path })
+	return out, err
+}
+
+func (s *FS) rel(file string) (string, error) {
+	rel, err := filepath.Rel(s.Root, file)
+	if err != nil {
+		return "", err
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// Get implements Store.
+func (s *FS) Get(_ context.Context, p string) ([]byte, Info, error) {
+	file := s.File(p)
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, Info{}, err
+	}
+	fi, err := os.Stat(file)
+	if err != nil {
+		return nil, Info{}, err
+	}
+	return data, Info{Path: p, ModTime: fi.ModTime(), Size: fi.Size()}, nil
+}
+
+// Put implements Store.
+func (s *FS) Put(_ context.Context, p string, data []byte) error {
+	return fsutil.WriteFile(s.File(p), data, 0o644)
+}
+
+// PutAll implements Batcher.
+func (s *FS) PutAll(_ context.Context, files map[string][]byte) error {
+	abs := make(map[string][]byte, len(files))
+	for p, data := range files {
+		abs[s.File(p)] = data
+	}
+	return fsutil.WriteFiles(abs)
+}
+
+// Delete implements Store. Directories left empty are removed too, up to
+// the category.
+func (s *FS) Delete(_ context.Context, p string) error {
+	file := s.File(p)
+	if err := os.Remove(file); err != nil {
+		return err
+	}
+	for d := filepath.Dir(file); d != s.Root && filepath.Dir(d) != s.Root; d = filepath.Dir(d) {
+		if os.Remove(d) != nil {
+			break
+		}
+	}
+	return nil
+}
+
+// Watch implements Store.
+func (s *FS) Watch(ctx context.Context) (<-chan Event, error) {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.watchDir(w, s.Root); err != nil {
+		w.Close()
+		return nil, err
+	}
+	ch := make(chan Event)
+	go func() {
+		defer close(ch)
+		defer w.Close()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-w.Errors:
+			case ev := <-w.Events:
+				rel, err := s.rel(ev.Name)
+				if err != nil || s.ignored(rel) {
+					continue
+				}
+				fi, err := os.Stat(ev.Name)
+				if err == nil && fi.IsDir() {
+					if ev.Has(fsnotify.Create) {
+						s.watchDir(w, ev.Name)
+					}
+					continue
+				}
+				if !strings.Contains(rel, "/") || strings.HasPrefix(filepath.Base(rel), ".") {
+					continue
+				}
+				select {
+				case ch <- Event{Path: rel, Removed: errors.Is(err, fs.ErrNotExist)}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return ch, nil
+}
+
+// watchDir adds dir and its subdirectories to w, leaving out ignored ones.
+func (s *FS) watchDir(w *fsnotify.Watcher, dir string) error {
+	return filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if p != s.Root && s.skip(d.Name()) {
+			return filepath.SkipDir
+		}
+		return w.Add(p)
+	})
+}
+
+// ignored reports whether the slash-separated path rel lies in an ignored
+// directory.
+func (s *FS) ignored(rel string) bool {
+	parts := strings.Split(rel, "/")
+	for _, part := range parts[:len(parts)-1] {
+		if s.skip(part) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,134 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newFS(t *testing.T, files map[string]string) *FS {
+	t.Helper()
+	s := &FS{Root: t.TempDir(), Skip: func(name string) bool { return strings.HasPrefix(name, ".") || name == "public" }}
+	for p, data := range files {
+		file := s.File(p)
+		if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return s
+}
+
+func TestFSList(t *testing.T) {
+	s := newFS(t, map[string]string{
+		"go/slices.md":           "slices",
+		"go/assets/slices/a.png": "png",
+		"git/rebase.md":          "rebase",
+		"README.md":              "readme",
+		".til/state.db":          "",
+		"public/go/index.html":   "",
+		"go/.slices.md.swp":      "",
+	})
+	got, err := s.List(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	for _, fi := range got {
+		paths = append(paths, fi.Path)
+	}
+	if want := "git/rebase.md go/assets/slices/a.png go/slices.md"; strings.Join(paths, " ") != want {
+		t.Errorf("List = %q, want %s", paths, want)
+	}
+	if got[2].Size != int64(len("slices")) || got[2].ModTime.IsZero() {
+		t.Errorf("List info = %+v", got[2])
+	}
+}
+
+func TestFSGetPutDelete(t *testing.T) {
+	ctx := context.Background()
+	s := newFS(t, nil)
+	if _, _, err := s.Get(ctx, "go/a.md"); !errors.Is(err, ErrNotExist) {
+		t.Errorf("Get of a missing file = %v, want ErrNotExist", err)
+	}
+	if err := s.Put(ctx, "go/deep/er/a.md", []byte("a")); err != nil {
+		t.Fatal(err)
+	}
+	data, fi, err := s.Get(ctx, "go/deep/er/a.md")
+	if err != nil || string(data) != "a" || fi.Path != "go/deep/er/a.md" || fi.Size != 1 {
+		t.Errorf("Get = %q, %+v, %v", data, fi, err)
+	}
+	if err := s.PutAll(ctx, map[string][]byte{"go/b.md": []byte("b"), "go/deep/er/a.md": []byte("a2")}); err != nil {
+		t.Fatal(err)
+	}
+	if data, _, _ := s.Get(ctx, "go/deep/er/a.md"); string(data) != "a2" {
+		t.Errorf("after PutAll go/deep/er/a.md = %q", data)
+	}
+
+	// Emptied directories go, up to the category.
+	if err := s.Delete(ctx, "go/deep/er/a.md"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(s.File("go/deep")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("go/deep kept: %v", err)
+	}
+	if err := s.Delete(ctx, "go/b.md"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(s.File("go")); err != nil {
+		t.Errorf("the category was removed: %v", err)
+	}
+	if err := s.Delete(ctx, "go/b.md"); !errors.Is(err, ErrNotExist) {
+		t.Errorf("Delete of a missing file = %v, want ErrNotExist", err)
+	}
+}
+
+func TestFSWatch(t *testing.T) {
+	s := newFS(t, map[string]string{"go/a.md": "a", ".til/x": ""})
+	ctx, cancel := context.WithCancel(context.Background())
+	ch, err := s.Watch(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	next := func(what string) Event {
+		t.Helper()
+		select {
+		case ev := <-ch:
+			return ev
+		case <-time.After(5 * time.Second):
+			t.Fatalf("no event for %s", what)
+		}
+		return Event{}
+	}
+
+	os.WriteFile(s.File(".til/x"), []byte("ignored"), 0o644)
+	os.WriteFile(s.File("README.md"), []byte("ignored"), 0o644)
+	if err := os.Remove(s.File("go/a.md")); err != nil {
+		t.Fatal(err)
+	}
+	if ev := next("the removal"); ev != (Event{Path: "go/a.md", Removed: true}) {
+		t.Errorf("event = %+v, want the removal of go/a.md", ev)
+	}
+
+	// Files in new directories are watched too.
+	if err := os.MkdirAll(s.File("rust/new"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if err := os.WriteFile(s.File("rust/new/b.md"), []byte("b"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if ev := next("the new file"); ev.Path != "rust/new/b.md" || ev.Removed {
+		t.Errorf("event = %+v, want rust/new/b.md", ev)
+	}
+
+	cancel()
+	for range ch {
+	}
+}
@@ -0,0 +1,106 @@
+// Package store abstracts where the files of a notes tree are kept.
+//
+// A Store holds files under slash-separated paths relative to the notes
+// root, such as "go/basic_syntax.md" or "go/assets/basic_syntax/a.png".
+// The filesystem store keeps them in the notes directory itself; other
+// backends, such as a SQLite file or an S3 bucket, can implement the same
+// interface. Local state (indexes, caches, templates) stays in the notes
+// directory whatever the backend.
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"time"
+)
+
+// ErrNotExist is returned, possibly wrapped, for paths that hold no file.
+var ErrNotExist = fs.ErrNotExist
+
+// Info describes a stored file.
+type Info struct {
+	Path    string
+	ModTime time.Time
+	Size    int64
+}
+
+// Event reports a change to the file at Path.
+type Event struct {
+	Path string
+	// Removed is set when the file no longer exists.
+	Removed bool
+}
+
+// Store holds the entry files of a notes tree.
+type Store interface {
+	// List returns every file inside a category directory, sorted by path.
+	// Files at the root and in ignored directories are left out.
+	List(ctx context.Context) ([]Info, error)
+	// Get returns the content of the file at p.
+	Get(ctx context.Context, p string) ([]byte, Info, error)
+	// Put creates or replaces the file at p, so readers never observe a
+	// partial write.
+	Put(ctx context.Context, p string, data []byte) error
+	// Delete removes the file at p.
+	Delete(ctx context.Context, p string) error
+	// Watch reports changes to files until ctx is cancelled, when the
+	// channel is closed.
+	Watch(ctx context.Context) (<-chan Event, error)
+}
+
+// Batcher is implemented by stores that can replace several files as one
+// unit.
+type Batcher interface {
+	PutAll(ctx context.Context, files map[string][]byte) error
+}
+
+// PutAll writes files to s, as one unit if s is a Batcher and one by one
+// otherwise.
+func PutAll(ctx context.Context, s Store, files map[string][]byte) error {
+	if b, ok := s.(Batcher); ok {
+		return b.PutAll(ctx, files)
+	}
+	for p, data := range files {
+		if err := s.Put(ctx, p, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Local is implemented by stores whose files are local files, which
+// editors and other programs can open directly.
+type Local interface {
+	File(p string) string
+}
+
+// Exists reports whether s holds a file at p.
+func Exists(ctx context.Context, s Store, p string) (bool, error) {
+	_, _, err := s.Get(ctx, p)
+	switch {
+	case err == nil:
+		return true, nil
+	case errors.Is(err, ErrNotExist):
+		return false, nil
+	}
+	return false, err
+}
+
+// Backends lists the names accepted by Check.
+var Backends = []string{"fs"}
+
+// Check reports an error unless backend names a supported store; "" means
+// the filesystem.
+func Check(backend string) error {
+	if backend == "" {
+		return nil
+	}
+	for _, b := range Backends {
+		if b == backend {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown store backend %q; supported: %v", backend, Backends)
+}
@@ -0,0 +1,85 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"slices"
+	"testing"
+)
+
+// mem is a Store keeping files in a map, failing Get with err when set.
+type mem struct {
+	files map[string][]byte
+	err   error
+}
+
+func (m *mem) List(ctx context.Context) ([]Info, error) {
+	var out []Info
+	for _, p := range slices.Sorted(maps.Keys(m.files)) {
+		out = append(out, Info{Path: p, Size: int64(len(m.files[p]))})
+	}
+	return out, nil
+}
+
+func (m *mem) Get(ctx context.Context, p string) ([]byte, Info, error) {
+	if m.err != nil {
+		return nil, Info{}, m.err
+	}
+	data, ok := m.files[p]
+	if !ok {
+		return nil, Info{}, ErrNotExist
+	}
+	return data, Info{Path: p, Size: int64(len(data))}, nil
+}
+
+func (m *mem) Put(ctx context.Context, p string, data []byte) error {
+	m.files[p] = data
+	return nil
+}
+
+func (m *mem) Delete(ctx context.Context, p string) error {
+	delete(m.files, p)
+	return nil
+}
+
+func (m *mem) Watch(ctx context.Context) (<-chan Event, error) {
+	return nil, errors.ErrUnsupported
+}
+
+func TestPutAllOneByOne(t *testing.T) {
+	m := &mem{files: map[string][]byte{}}
+	if err := PutAll(context.Background(), m, map[string][]byte{"go/a.md": []byte("a"), "go/b.md": []byte("b")}); err != nil {
+		t.Fatal(err)
+	}
+	got := slices.Sorted(maps.Keys(m.files))
+	if !slices.Equal(got, []string{"go/a.md", "go/b.md"}) || string(m.files["go/b.md"]) != "b" {
+		t.Errorf("files = %q", got)
+	}
+}
+
+func TestExists(t *testing.T) {
+	ctx := context.Background()
+	m := &mem{files: map[string][]byte{"go/a.md": nil}}
+	if ok, err := Exists(ctx, m, "go/a.md"); !ok || err != nil {
+		t.Errorf("Exists(go/a.md) = %v, %v", ok, err)
+	}
+	if ok, err := Exists(ctx, m, "go/b.md"); ok || err != nil {
+		t.Errorf("Exists(go/b.md) = %v, %v", ok, err)
+	}
+	m.err = errors.New("offline")
+	if _, err := Exists(ctx, m, "go/a.md"); err == nil {
+		t.Error("Exists hid a store error")
+	}
+}
+
+func TestCheck(t *testing.T) {
+	for _, b := range append([]string{""}, Backends...) {
+		if err := Check(b); err != nil {
+			t.Errorf("Check(%q) = %v", b, err)
+		}
+	}
+	if err := Check("floppy"); err == nil {
+		t.Error(`Check("floppy") succeeded`)
+	}
+}
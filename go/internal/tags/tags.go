@@ -0,0 +1,164 @@
+// Package tags implements tag bookkeeping across a notes tree: counting,
+// renaming and merging tags, and validating them against an allowlist.
+package tags
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/canhta/til/go/internal/notes"
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+// AllowlistFile is the allowlist location inside the notes state directory.
+const AllowlistFile = "tags.txt"
+
+// Count is the number of entries carrying a tag.
+type Count struct {
+	Tag   string `json:"tag"`
+	Count int    `json:"count"`
+}
+
+// Counts tallies the tags of entries, most used first.
+func Counts(entries []*entry.Entry) []Count {
+	n := map[string]int{}
+	for _, e := range entries {
+		for _, t := range e.Meta.Tags {
+			n[t]++
+		}
+	}
+	counts := make([]Count, 0, len(n))
+	for t, c := range n {
+		counts = append(counts, Count{Tag: t, Count: c})
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Count != counts[j].Count {
+			return counts[i].Count > counts[j].Count
+		}
+		return counts[i].Tag < counts[j].Tag
+	})
+	return counts
+}
+
+// Allowlist is the set of permitted tags. A nil Allowlist permits all.
+type Allowlist map[string]bool
+
+// LoadAllowlist reads the tree's allowlist: one tag per line, with blank
+// lines and "#" comments ignored. It returns nil when the file is missing.
+func LoadAllowlist(tree *notes.Tree) (Allowlist, error) {
+	data, err := os.ReadFile(tree.StatePath(AllowlistFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	allow := Allowlist{}
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		line := sc.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		if t := strings.TrimSpace(line); t != "" {
+			allow[t] = true
+		}
+	}
+	return allow, sc.Err()
+}
+
+// Unknown returns the tags not in the allowlist.
+func (a Allowlist) Unknown(tags []string) []string {
+	if a == nil {
+		return nil
+	}
+	var unknown []string
+	for _, t := range tags {
+		if !a[t] {
+			unknown = append(unknown, t)
+		}
+	}
+	return unknown
+}
+
+// Violation is an entry using tags outside the allowlist.
+type Violation struct {
+	Path string
+	Tags []string
+}
+
+// Check lists entries whose tags are not all allowed.
+func (a Allowlist) Check(entries []*entry.Entry) []Violation {
+	var vs []Violation
+	for _, e := range entries {
+		if u := a.Unknown(e.Meta.Tags); len(u) > 0 {
+			vs = append(vs, Violation{Path: e.Path, Tags: u})
+		}
+	}
+	return vs
+}
+
+// Replace returns tags with every tag in from replaced by to, keeping the
+// first position of each resulting tag and dropping duplicates.
+func Replace(tags []string, from []string, to string) []string {
+	src := map[string]bool{}
+	for _, f := range from {
+		src[f] = true
+	}
+	seen := map[string]bool{}
+	out := make([]string, 0, len(tags))
+	for _, t := range tags {
+		if src[t] {
+			t = to
+		}
+		if !seen[t] {
+			seen[t] = true
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// Change is the tag rewrite of one entry.
+type Change struct {
+	Path string   `json:"path"`
+	Old  []string `json:"old"`
+	New  []string `json:"new"`
+}
+
+// Rewrite applies fn to the tags of every entry and replaces the changed
+// files as one unit: either every file is rewritten or none is. Only the
+// tags field of each frontmatter is touched.
+func Rewrite(tree *notes.Tree, entries []*entry.Entry, fn func([]string) []string) ([]Change, error) {
+	var changes []Change
+	files := map[string][]byte{}
+	for _, e := range entries {
+		next := fn(e.Meta.Tags)
+		if slices.Equal(next, e.Meta.Tags) {
+			continue
+		}
+		data, err := tree.Read(e.Path)
+		if err != nil {
+			return nil, err
+		}
+		out, err := entry.Rewrite(data, func(f *entry.Front) error {
+			return f.Set("tags", next)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", e.Path, err)
+		}
+		files[e.Path] = out
+		changes = append(changes, Change{Path: e.Path, Old: e.Meta.Tags, New: next})
+	}
+	if err := tree.WriteAll(files); err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
@@ -0,0 +1,144 @@
+package tags
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/canhta/til/go/internal/notes"
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+// newTree writes files to a new tree and loads its entries.
+func newTree(t *testing.T, files map[string]string) (*notes.Tree, []*entry.Entry) {
+	t.Helper()
+	tree := notes.Open(t.TempDir())
+	for p, data := range files {
+		file := tree.Abs(p)
+		if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	entries, err := tree.Entries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tree, entries
+}
+
+func read(t *testing.T, tree *notes.Tree, p string) string {
+	t.Helper()
+	data, err := tree.Read(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestCounts(t *testing.T) {
+	_, entries := newTree(t, map[string]string{
+		"go/a.md": "---\ntags: [go, concurrency]\n---\n",
+		"go/b.md": "---\ntags: [go, slices]\n---\n",
+		"go/c.md": "---\ntags: [go, concurrency, apple]\n---\n",
+		"go/d.md": "# Untagged\n",
+	})
+	want := []Count{{"go", 3}, {"concurrency", 2}, {"apple", 1}, {"slices", 1}}
+	if got := Counts(entries); !reflect.DeepEqual(got, want) {
+		t.Errorf("Counts = %v, want %v", got, want)
+	}
+}
+
+func TestAllowlist(t *testing.T) {
+	tree, entries := newTree(t, map[string]string{
+		"go/a.md": "---\ntags: [go, golang]\n---\n",
+		"go/b.md": "---\ntags: [go]\n---\n",
+	})
+	allow, err := LoadAllowlist(tree)
+	if allow != nil || err != nil {
+		t.Fatalf("LoadAllowlist without a file = %v, %v", allow, err)
+	}
+	if u := allow.Unknown([]string{"anything"}); u != nil {
+		t.Errorf("nil allowlist rejected %q", u)
+	}
+	if err := os.WriteFile(tree.StatePath(AllowlistFile), []byte("# Languages\ngo  # the language\n\n  rust\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if allow, err = LoadAllowlist(tree); err != nil {
+		t.Fatal(err)
+	}
+	if want := (Allowlist{"go": true, "rust": true}); !reflect.DeepEqual(allow, want) {
+		t.Errorf("LoadAllowlist = %v, want %v", allow, want)
+	}
+	if got, want := allow.Check(entries), []Violation{{Path: "go/a.md", Tags: []string{"golang"}}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Check = %v, want %v", got, want)
+	}
+}
+
+func TestReplace(t *testing.T) {
+	tests := []struct {
+		tags, from []string
+		to         string
+		want       []string
+	}{
+		{[]string{"golang", "web"}, []string{"golang"}, "go", []string{"go", "web"}},
+		{[]string{"web", "golang", "go"}, []string{"golang"}, "go", []string{"web", "go"}},
+		{[]string{"js", "db", "ts"}, []string{"js", "ts"}, "web", []string{"web", "db"}},
+		{[]string{"db"}, []string{"js"}, "web", []string{"db"}},
+	}
+	for _, tt := range tests {
+		if got := Replace(tt.tags, tt.from, tt.to); !slices.Equal(got, tt.want) {
+			t.Errorf("Replace(%q, %q, %s) = %q, want %q", tt.tags, tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+func TestRewrite(t *testing.T) {
+	tree, entries := newTree(t, map[string]string{
+		"go/a.md": "---\n# Keep me.\ntitle: A\ntags: [golang, web]  # topics\ndate: 2024-01-01\n---\n\nBody.\n",
+		"go/b.md": "---\ntitle: B\ntags:\n  - golang\n---\n",
+		"go/c.md": "---\ntitle: C\ntags: [rust]\n---\n",
+	})
+	changes, err := Rewrite(tree, entries, func(tags []string) []string { return Replace(tags, []string{"golang"}, "go") })
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Change{
+		{Path: "go/a.md", Old: []string{"golang", "web"}, New: []string{"go", "web"}},
+		{Path: "go/b.md", Old: []string{"golang"}, New: []string{"go"}},
+	}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("Rewrite = %v, want %v", changes, want)
+	}
+	if got := read(t, tree, "go/a.md"); got != "---\n# Keep me.\ntitle: A\ntags: [go, web]  # topics\ndate: 2024-01-01\n---\n\nBody.\n" {
+		t.Errorf("go/a.md = %q", got)
+	}
+	if got := read(t, tree, "go/c.md"); got != "---\ntitle: C\ntags: [rust]\n---\n" {
+		t.Errorf("go/c.md = %q", got)
+	}
+	e, err := tree.Load("go/b.md")
+	if err != nil || !slices.Equal(e.Meta.Tags, []string{"go"}) {
+		t.Errorf("go/b.md tags = %v, %v", e, err)
+	}
+}
+
+func TestRewriteAllOrNothing(t *testing.T) {
+	tree, entries := newTree(t, map[string]string{
+		"go/a.md": "---\ntags: [golang]\n---\n",
+		"go/b.md": "---\ntags: [golang]\n---\n",
+	})
+	// go/b.md goes missing after the entries were loaded.
+	if err := os.Remove(tree.Abs("go/b.md")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Rewrite(tree, entries, func([]string) []string { return []string{"go"} }); err == nil {
+		t.Fatal("Rewrite succeeded")
+	}
+	if got := read(t, tree, "go/a.md"); got != "---\ntags: [golang]\n---\n" {
+		t.Errorf("go/a.md was rewritten: %q", got)
+	}
+}
@@ -0,0 +1,246 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf16"
+
+	"github.com/canhta/til/go/internal/capture"
+	"github.com/canhta/til/go/internal/importer"
+)
+
+// Options configures Convert.
+type Options struct {
+	// Category holds the entries. Defaults to importer.DefaultCategory.
+	Category string
+}
+
+// Convert converts m into a note keyed by its chat and message ID, so a
+// message redelivered is only saved once. The text becomes markdown with
+// code blocks kept and hashtags as tags, and a photo or document sent is
+// downloaded as an asset.
+func (c Client) Convert(ctx context.Context, m *Message, opts Options) (*importer.Note, error) {
+	text, entities := m.Text, m.Entities
+	if text == "" {
+		text, entities = m.Caption, m.CaptionEntities
+	}
+	body, tags := Markdown(text, entities)
+	n := &importer.Note{
+		Key:      fmt.Sprintf("telegram:%d/%d", m.Chat.ID, m.ID),
+		Title:    title(body),
+		Category: opts.Category,
+		Tags:     tags,
+		Date:     time.Unix(m.Date, 0),
+		Body:     body,
+	}
+	if n.Category == "" {
+		n.Category = importer.DefaultCategory
+	}
+	var fileID, name string
+	switch {
+	case len(m.Photo) > 0:
+		// Sizes come smallest first.
+		fileID, name = m.Photo[len(m.Photo)-1].FileID, "photo.jpg"
+	case m.Document != nil:
+		fileID, name = m.Document.FileID, m.Document.FileName
+		if name == "" {
+			name = "file"
+			if exts, _ := mime.ExtensionsByType(m.Document.MimeType); len(exts) > 0 {
+				name += exts[0]
+			}
+		}
+	}
+	if fileID != "" {
+		data, err := c.Download(ctx, fileID)
+		if err != nil {
+			return nil, err
+		}
+		n.Assets = append(n.Assets, importer.Asset{Ref: "telegram:" + fileID, Name: name, Data: data})
+		if n.Title == "" {
+			n.Title = strings.TrimSuffix(name, ".jpg")
+		}
+	}
+	if strings.TrimSpace(n.Title) == "" {
+		n.Title = "Telegram message"
+	}
+	return n, nil
+}
+
+// Markdown converts text with its entities to markdown, returning it with
+// the hashtags in it. Code blocks are fenced, inline code, emphasis and
+// text links kept, and hashtags ending a line dropped from the text, or
+// otherwise left as plain words.
+func Markdown(text string, entities []Entity) (string, []string) {
+	u := utf16.Encode([]rune(text))
+	// opens and closes hold the markup inserted at each offset, closes in
+	// the order they are written.
+	opens, closes := map[int][]mark{}, map[int][]mark{}
+	add := func(start, end int, open, close mark) {
+		opens[start] = append(opens[start], open)
+		closes[end] = append([]mark{close}, closes[end]...)
+	}
+	drop := map[int]bool{}
+	var tags []string
+	for _, e := range entities {
+		start, end := e.Offset, e.Offset+e.Length
+		if start < 0 || end > len(u) || start >= end {
+			continue
+		}
+		switch e.Type {
+		case "pre":
+			f := fence(string(utf16.Decode(u[start:end])))
+			add(start, end, mark{f + strings.ToLower(e.Language) + "\n", true}, mark{f, true})
+		case "code":
+			add(start, end, mark{"`", false}, mark{"`", false})
+		case "bold":
+			add(start, end, mark{"**", false}, mark{"**", false})
+		case "italic":
+			add(start, end, mark{"*", false}, mark{"*", false})
+		case "strikethrough":
+			add(start, end, mark{"~~", false}, mark{"~~", false})
+		case "text_link":
+			add(start, end, mark{"[", false}, mark{"](" + e.URL + ")", false})
+		case "hashtag":
+			tag := importer.Tag(string(utf16.Decode(u[start:end])))
+			if tag == "" {
+				continue
+			}
+			tags = append(tags, tag)
+			if endsLine(u, end, entities) {
+				for i := start; i < end; i++ {
+					drop[i] = true
+				}
+			} else {
+				drop[start] = true
+			}
+		}
+	}
+	var b strings.Builder
+	// breakNext is set after a fence, which the text must not continue on
+	// the same line.
+	breakNext := false
+	lineBreak := func() {
+		if s := b.String(); s != "" && !strings.HasSuffix(s, "\n") {
+			b.WriteByte('\n')
+		}
+	}
+	write := func(s string) {
+		if s == "" {
+			return
+		}
+		if breakNext && s[0] != '\n' {
+			lineBreak()
+		}
+		breakNext = false
+		b.WriteString(s)
+	}
+	var run []uint16
+	for i := 0; i <= len(u); i++ {
+		if marks := append(closes[i], opens[i]...); len(marks) > 0 {
+			write(string(utf16.Decode(run)))
+			run = run[:0]
+			for _, m := range marks {
+				if m.fence {
+					lineBreak()
+				}
+				write(m.text)
+				if m.fence && !strings.HasSuffix(m.text, "\n") {
+					breakNext = true
+				}
+			}
+		}
+		if i < len(u) && !drop[i] {
+			run = append(run, u[i])
+		}
+	}
+	write(string(utf16.Decode(run)))
+	return tidy(b.String()), slices.Compact(slices.Sorted(slices.Values(tags)))
+}
+
+// fence returns a backtick fence longer than any backtick run in code.
+func fence(code string) string {
+	n, run := 3, 0
+	for _, r := range code {
+		if r == '`' {
+			run++
+			n = max(n, run+1)
+		} else {
+			run = 0
+		}
+	}
+	return strings.Repeat("`", n)
+}
+
+// title derives a title from the first line of body outside code blocks,
+// or from the code if there is nothing else.
+func title(body string) string {
+	var prose []string
+	inFence := false
+	for _, l := range strings.Split(body, "\n") {
+		if strings.HasPrefix(l, "```") {
+			inFence = !inFence
+			continue
+		}
+		if !inFence {
+			prose = append(prose, l)
+		}
+	}
+	t := capture.Title(strings.Join(prose, "\n"))
+	if t == "" {
+		t = capture.Title(strings.Join(strings.Split(body, "\n")[1:], "\n"))
+	}
+	return strings.TrimSpace(strings.NewReplacer("**", "", "~~", "", "`", "").Replace(t))
+}
+
+// mark is markup inserted into a message's text; fence marks go on lines
+// of their own.
+type mark struct {
+	text  string
+	fence bool
+}
+
+// endsLine reports whether only spaces and hashtags follow offset end on
+// its line of u.
+func endsLine(u []uint16, end int, entities []Entity) bool {
+	for i := end; i < len(u) && u[i] != '\n'; i++ {
+		if u[i] == ' ' || u[i] == '\t' {
+			continue
+		}
+		in := false
+		for _, e := range entities {
+			if e.Type == "hashtag" && e.Offset <= i && i < e.Offset+e.Length {
+				in = true
+				break
+			}
+		}
+		if !in {
+			return false
+		}
+	}
+	return true
+}
+
+// tidy removes the trailing spaces and runs of blank lines that dropped
+// hashtags leave outside code blocks.
+func tidy(s string) string {
+	var out []string
+	inFence := false
+	for _, l := range strings.Split(s, "\n") {
+		fence := strings.HasPrefix(l, "```")
+		if !inFence {
+			l = strings.TrimRight(l, " \t")
+			if l == "" && (len(out) == 0 || out[len(out)-1] == "") {
+				continue
+			}
+		}
+		if fence {
+			inFence = !inFence
+		}
+		out = append(out, l)
+	}
+	return strings.TrimSpace(strings.Join(out, "\n"))
+}
@@ -0,0 +1,116 @@
+package telegram
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf16"
+)
+
+// span returns the entity of type typ covering the nth sub in text,
+// counting from zero.
+func span(text, sub string, nth int, typ string) Entity {
+	i := -len(sub)
+	for range nth + 1 {
+		i += len(sub) + strings.Index(text[i+len(sub):], sub)
+	}
+	return Entity{Type: typ, Offset: len(utf16.Encode([]rune(text[:i]))), Length: len(utf16.Encode([]rune(sub)))}
+}
+
+func TestMarkdown(t *testing.T) {
+	text := "Slices 🍕 share arrays #go\nUse copy or append, see docs. #go #slices\n#tip in a sentence\ns = append(s, 1)\nend"
+	pre := span(text, "s = append(s, 1)", 0, "pre")
+	pre.Language = "Go"
+	link := span(text, "docs", 0, "text_link")
+	link.URL = "https://go.dev/ref/spec"
+	entities := []Entity{
+		span(text, "🍕", 0, "bold"),
+		span(text, "#go", 0, "hashtag"),
+		span(text, "copy", 0, "code"),
+		span(text, "append", 0, "italic"),
+		link,
+		span(text, "#go", 1, "hashtag"),
+		span(text, "#slices", 0, "hashtag"),
+		span(text, "#tip", 0, "hashtag"),
+		pre,
+		{Type: "bold", Offset: 500, Length: 3},
+	}
+
+	got, tags := Markdown(text, entities)
+	want := "Slices **🍕** share arrays\nUse `copy` or *append*, see [docs](https://go.dev/ref/spec).\ntip in a sentence\n```go\ns = append(s, 1)\n```\nend"
+	if got != want {
+		t.Errorf("Markdown =\n%s\nwant\n%s", got, want)
+	}
+	if want := []string{"go", "slices", "tip"}; !slices.Equal(tags, want) {
+		t.Errorf("tags = %q, want %q", tags, want)
+	}
+
+	code := "x := \"```\""
+	got, _ = Markdown(code, []Entity{span(code, code, 0, "pre")})
+	if want := "````\nx := \"```\"\n````"; got != want {
+		t.Errorf("Markdown(backticks) = %q, want %q", got, want)
+	}
+}
+
+func TestTitle(t *testing.T) {
+	tests := []struct{ body, want string }{
+		{"**Slices** share arrays\nMore.", "Slices share arrays"},
+		{"```go\nx := 1\n```\nAbout x", "About x"},
+		{"```go\nx := 1\n```", "x := 1"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := title(tt.body); got != tt.want {
+			t.Errorf("title(%q) = %q, want %q", tt.body, got, tt.want)
+		}
+	}
+}
+
+func TestConvert(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/botTOKEN/getFile":
+			w.Write([]byte(`{"ok":true,"result":{"file_path":"photos/big.jpg","file_size":3}}`))
+		case "/file/botTOKEN/photos/big.jpg":
+			w.Write([]byte("jpg"))
+		default:
+			t.Errorf("unexpected request %s", r.URL.Path)
+		}
+	}))
+	defer srv.Close()
+	c := Client{Token: "TOKEN", API: srv.URL}
+	ctx := context.Background()
+
+	m := &Message{ID: 7, Chat: Chat{ID: 42}, Date: 1709251200, Text: "Slices #go", Entities: []Entity{{Type: "hashtag", Offset: 7, Length: 3}}}
+	n, err := c.Convert(ctx, m, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n.Key != "telegram:42/7" || n.Title != "Slices" || n.Category != "inbox" || !slices.Equal(n.Tags, []string{"go"}) || n.Body != "Slices" || !n.Date.Equal(time.Unix(1709251200, 0)) {
+		t.Errorf("note = %+v", n)
+	}
+
+	photo := &Message{ID: 8, Chat: Chat{ID: 42}, Photo: []PhotoSize{{FileID: "small"}, {FileID: "big"}}}
+	n, err = c.Convert(ctx, photo, Options{Category: "pics"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n.Title != "photo" || n.Category != "pics" || len(n.Assets) != 1 || n.Assets[0].Name != "photo.jpg" || n.Assets[0].Ref != "telegram:big" || string(n.Assets[0].Data) != "jpg" {
+		t.Errorf("photo note = %+v", n)
+	}
+
+	doc := &Message{ID: 9, Chat: Chat{ID: 42}, Caption: "The spec", Document: &Document{FileID: "big", MimeType: "application/pdf"}}
+	if n, err = c.Convert(ctx, doc, Options{}); err != nil {
+		t.Fatal(err)
+	}
+	if n.Title != "The spec" || n.Body != "The spec" || n.Assets[0].Name != "file.pdf" {
+		t.Errorf("document note = %+v", n)
+	}
+	if n, _ := c.Convert(ctx, &Message{}, Options{}); n.Title != "Telegram message" {
+		t.Errorf("title of an empty message = %q", n.Title)
+	}
+}
@@ -0,0 +1,205 @@
+// Package telegram talks to the Telegram Bot API, reading the messages sent
+// to a bot and turning them into entries.
+package telegram
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// API is the Bot API endpoint.
+const API = "https://api.telegram.org"
+
+// maxFile is the largest file the Bot API lets bots download.
+const maxFile = 20 << 20
+
+// Update is an incoming update. Only messages are asked for.
+type Update struct {
+	ID      int64    `json:"update_id"`
+	Message *Message `json:"message"`
+}
+
+// Message is a message sent to the bot.
+type Message struct {
+	ID   int64 `json:"message_id"`
+	From *User `json:"from"`
+	Chat Chat  `json:"chat"`
+	// Date is in seconds since the epoch.
+	Date     int64    `json:"date"`
+	Text     string   `json:"text"`
+	Entities []Entity `json:"entities"`
+	// Caption and CaptionEntities are the text of a photo or document.
+	Caption         string      `json:"caption"`
+	CaptionEntities []Entity    `json:"caption_entities"`
+	Photo           []PhotoSize `json:"photo"`
+	Document        *Document   `json:"document"`
+}
+
+// User is a Telegram user.
+type User struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username"`
+}
+
+// Chat is the chat a message was sent in.
+type Chat struct {
+	ID int64 `json:"id"`
+}
+
+// Entity marks a span of a message's text, such as a code block or a
+// hashtag. Offset and Length count UTF-16 code units.
+type Entity struct {
+	Type     string `json:"type"`
+	Offset   int    `json:"offset"`
+	Length   int    `json:"length"`
+	URL      string `json:"url"`
+	Language string `json:"language"`
+}
+
+// PhotoSize is one size of a photo.
+type PhotoSize struct {
+	FileID   string `json:"file_id"`
+	Width    int    `json:"width"`
+	Height   int    `json:"height"`
+	FileSize int    `json:"file_size"`
+}
+
+// Document is a file sent as such.
+type Document struct {
+	FileID   string `json:"file_id"`
+	FileName string `json:"file_name"`
+	MimeType string `json:"mime_type"`
+	FileSize int    `json:"file_size"`
+}
+
+// Client calls the Bot API as the bot whose token it holds.
+type Client struct {
+	Token string
+	// API defaults to API.
+	API    string
+	Client *http.Client
+}
+
+// Updates waits up to wait for updates after the one before offset and
+// returns them. Updates before offset are confirmed: Telegram drops them.
+func (c Client) Updates(ctx context.Context, offset int64, wait time.Duration) ([]Update, error) {
+	// Give up on a connection that stalls past the wait.
+	ctx, cancel := context.WithTimeout(ctx, wait+30*time.Second)
+	defer cancel()
+	var out []Update
+	err := c.call(ctx, "getUpdates", map[string]any{
+		"offset":          offset,
+		"timeout":         int(wait.Seconds()),
+		"allowed_updates": []string{"message"},
+	}, &out)
+	return out, err
+}
+
+// Reply sends text to the chat of m, as a reply to it.
+func (c Client) Reply(ctx context.Context, m *Message, text string) error {
+	return c.call(ctx, "sendMessage", map[string]any{
+		"chat_id":              m.Chat.ID,
+		"text":                 text,
+		"reply_parameters":     map[string]any{"message_id": m.ID, "allow_sending_without_reply": true},
+		"link_preview_options": map[string]any{"is_disabled": true},
+	}, nil)
+}
+
+// Download returns the content of the file fileID.
+func (c Client) Download(ctx context.Context, fileID string) ([]byte, error) {
+	var f struct {
+		Path string `json:"file_path"`
+		Size int    `json:"file_size"`
+	}
+	if err := c.call(ctx, "getFile", map[string]any{"file_id": fileID}, &f); err != nil {
+		return nil, err
+	}
+	if f.Size > maxFile {
+		return nil, fmt.Errorf("telegram: file of %d bytes is too large", f.Size)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.api()+"/file/bot"+c.Token+"/"+f.Path, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.client().Do(req)
+	if err != nil {
+		return nil, c.redact(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return nil, fmt.Errorf("telegram: file: %s", resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, maxFile))
+}
+
+// call calls method with params and decodes its result into out.
+func (c Client) call(ctx context.Context, method string, params map[string]any, out any) error {
+	data, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.api()+"/bot"+c.Token+"/"+method, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.client().Do(req)
+	if err != nil {
+		return c.redact(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 10<<20))
+	if err != nil {
+		return c.redact(err)
+	}
+	var r struct {
+		OK          bool            `json:"ok"`
+		Description string          `json:"description"`
+		Result      json.RawMessage `json:"result"`
+	}
+	if err := json.Unmarshal(body, &r); err != nil {
+		return fmt.Errorf("telegram: %s: %s", method, resp.Status)
+	}
+	if !r.OK {
+		return fmt.Errorf("telegram: %s: %s", method, r.Description)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(r.Result, out); err != nil {
+		return fmt.Errorf("telegram: %s: %w", method, err)
+	}
+	return nil
+}
+
+func (c Client) api() string {
+	if c.API == "" {
+		return API
+	}
+	return strings.TrimSuffix(c.API, "/")
+}
+
+func (c Client) client() *http.Client {
+	if c.Client != nil {
+		return c.Client
+	}
+	return http.DefaultClient
+}
+
+// redact removes the token, which the Bot API takes in the URL path, from
+// the URL in a request error.
+func (c Client) redact(err error) error {
+	var ue *url.Error
+	if errors.As(err, &ue) && c.Token != "" {
+		ue.URL = strings.ReplaceAll(ue.URL, c.Token, "<token>")
+	}
+	return err
+}
@@ -0,0 +1,77 @@
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClient(t *testing.T) {
+	var calls []map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var params map[string]any
+		json.NewDecoder(r.Body).Decode(&params)
+		params["method"] = r.URL.Path
+		calls = append(calls, params)
+		switch r.URL.Path {
+		case "/bot123:abc/getUpdates":
+			w.Write([]byte(`{"ok":true,"result":[{"update_id":5,"message":{"message_id":7,"from":{"id":1,"username":"me"},"chat":{"id":42},"text":"hi"}}]}`))
+		case "/bot123:abc/sendMessage":
+			w.Write([]byte(`{"ok":true,"result":{}}`))
+		case "/bot123:abc/getFile":
+			w.Write([]byte(`{"ok":true,"result":{"file_path":"big","file_size":99999999}}`))
+		default:
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"ok":false,"description":"Unauthorized"}`))
+		}
+	}))
+	defer srv.Close()
+	c := Client{Token: "123:abc", API: srv.URL + "/"}
+	ctx := context.Background()
+
+	updates, err := c.Updates(ctx, 5, 30*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(updates) != 1 || updates[0].ID != 5 || updates[0].Message.From.Username != "me" || updates[0].Message.Chat.ID != 42 {
+		t.Errorf("Updates = %+v", updates)
+	}
+	if p := calls[0]; p["offset"] != 5.0 || p["timeout"] != 30.0 {
+		t.Errorf("getUpdates params = %v", p)
+	}
+	if err := c.Reply(ctx, updates[0].Message, "Saved"); err != nil {
+		t.Fatal(err)
+	}
+	if p := calls[1]; p["chat_id"] != 42.0 || p["text"] != "Saved" || p["reply_parameters"].(map[string]any)["message_id"] != 7.0 {
+		t.Errorf("sendMessage params = %v", p)
+	}
+	if _, err := c.Download(ctx, "big"); err == nil || !strings.Contains(err.Error(), "too large") {
+		t.Errorf("Download of a large file = %v", err)
+	}
+
+	bad := Client{Token: "wrong", API: srv.URL}
+	if _, err := bad.Updates(ctx, 0, 0); err == nil || err.Error() != "telegram: getUpdates: Unauthorized" {
+		t.Errorf("Updates with a wrong token = %v", err)
+	}
+}
+
+func TestClientRedactsToken(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	srv.Close()
+	c := Client{Token: "123:secret", API: srv.URL}
+	_, err := c.Updates(context.Background(), 0, 0)
+	if err == nil || strings.Contains(err.Error(), "secret") || !strings.Contains(err.Error(), "<token>") {
+		t.Errorf("Updates on a closed server = %v, want the token redacted", err)
+	}
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+	if err := (Client{API: srv.URL}).Reply(context.Background(), &Message{}, "x"); err == nil || err.Error() != "telegram: sendMessage: 502 Bad Gateway" {
+		t.Errorf("Reply with no JSON = %v", err)
+	}
+}
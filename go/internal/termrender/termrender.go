@@ -0,0 +1,495 @@
+// Package termrender renders entries for the terminal: the markdown body
+// laid out to a width, with headings, emphasis and links styled, code
+// highlighted, tables aligned, and links between entries shown with the
+// entry they point to.
+package termrender
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/alecthomas/chroma/v2/quick"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/muesli/termenv"
+	"github.com/yuin/goldmark/ast"
+	east "github.com/yuin/goldmark/extension/ast"
+
+	"github.com/canhta/til/go/internal/include"
+	"github.com/canhta/til/go/internal/links"
+	"github.com/canhta/til/go/pkg/entry"
+	"github.com/canhta/til/go/pkg/render"
+)
+
+// DefaultWidth is the width text is wrapped at unless set.
+const DefaultWidth = 80
+
+// Options configures Render.
+type Options struct {
+	// Width is the column paragraphs wrap at; code blocks and tables keep
+	// their own width. Zero means DefaultWidth.
+	Width int
+	// Style is the chroma style of code blocks.
+	Style string
+	// Color styles the output with ANSI escapes. Without it the output is
+	// plain text laid out the same.
+	Color bool
+	// Index resolves links to other entries, which are shown followed by
+	// the path of the entry they point to, and the include directives of
+	// the entry. Without it links show their label only.
+	Index *links.Index
+}
+
+// calloutColors are the ANSI colors of callout types, as the browser's
+// preview shows them.
+var calloutColors = map[string]string{
+	"note":      "12",
+	"tip":       "10",
+	"important": "13",
+	"warning":   "11",
+	"caution":   "9",
+}
+
+// Render renders e: its title, a line of metadata, then the body without
+// the title heading. Code blocks are numbered from 1 in their labels.
+func Render(e *entry.Entry, opts Options) string {
+	if opts.Width <= 0 {
+		opts.Width = DefaultWidth
+	}
+	if opts.Style == "" {
+		opts.Style = "monokai"
+	}
+	profile := termenv.Ascii
+	if opts.Color {
+		profile = termenv.ANSI256
+	}
+	body := e.Body
+	if opts.Index != nil {
+		body = include.Expand(e, opts.Index).Body
+	}
+	body = render.StripTitle(body)
+	r := &renderer{
+		src:  body,
+		from: e.Path,
+		opts: opts,
+	}
+	r.styles(profile)
+	md := render.New(render.Options{ResolveWiki: r.resolveWiki})
+	doc := md.Parse(body, e.Path)
+
+	var b strings.Builder
+	b.WriteString(r.title.Render(e.Meta.Title) + "\n")
+	meta := e.Path
+	if d := e.Created(); !d.IsZero() {
+		meta += "  " + d.Format(entry.DateLayout)
+	}
+	if len(e.Meta.Tags) > 0 {
+		meta += "  #" + strings.Join(e.Meta.Tags, " #")
+	}
+	b.WriteString(r.dim.Render(meta) + "\n")
+	if lines := r.blocks(doc, opts.Width); len(lines) > 0 {
+		b.WriteString("\n" + strings.Join(lines, "\n") + "\n")
+	}
+	return b.String()
+}
+
+type renderer struct {
+	src  []byte
+	from string
+	opts Options
+	// codeBlocks counts the code blocks rendered so far.
+	codeBlocks int
+
+	title, heading, dim, codeSpan, link, dangling style
+	bold, italic, strike                          style
+}
+
+func (r *renderer) styles(p termenv.Profile) {
+	r.title = style{p: p, color: "12", bold: true}
+	r.heading = style{p: p, color: "13", bold: true}
+	r.dim = style{p: p, color: "8"}
+	r.codeSpan = style{p: p, color: "11"}
+	r.link = style{p: p, color: "14", underline: true}
+	r.dangling = style{p: p, color: "9"}
+	r.bold = style{p: p, bold: true}
+	r.italic = style{p: p, italic: true}
+	r.strike = style{p: p, strike: true}
+}
+
+// style is how text of one kind is shown, in ANSI colors. Text is left
+// plain under the Ascii profile.
+type style struct {
+	p                               termenv.Profile
+	color                           string
+	bold, italic, underline, strike bool
+}
+
+// Render returns text in the style.
+func (s style) Render(text string) string {
+	t := s.p.String(text)
+	if s.color != "" {
+		t = t.Foreground(s.p.Color(s.color))
+	}
+	if s.bold {
+		t = t.Bold()
+	}
+	if s.italic {
+		t = t.Italic()
+	}
+	if s.underline {
+		t = t.Underline()
+	}
+	if s.strike {
+		t = t.CrossOut()
+	}
+	return t.String()
+}
+
+// resolveWiki resolves [[target]] references to the path of the entry
+// they name, kept as the link's URL.
+func (r *renderer) resolveWiki(from, target string) (string, string, bool) {
+	if r.opts.Index == nil {
+		return "", "", true
+	}
+	to, res := r.opts.Index.Resolve(from, links.Link{Target: target, Wiki: true})
+	if res != links.Resolved {
+		return "", "", false
+	}
+	return to.Path, to.Meta.Title, true
+}
+
+// blocks renders the block children of n to lines at most width wide,
+// separated by blank lines unless n is an item of a tight list.
+func (r *renderer) blocks(n ast.Node, width int) []string {
+	tight := false
+	if _, ok := n.(*ast.ListItem); ok {
+		if l, ok := n.Parent().(*ast.List); ok {
+			tight = l.IsTight
+		}
+	}
+	var out []string
+	for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+		lines := r.block(c, width)
+		if len(lines) == 0 {
+			continue
+		}
+		if len(out) > 0 && !tight {
+			out = append(out, "")
+		}
+		out = append(out, lines...)
+	}
+	return out
+}
+
+func (r *renderer) block(n ast.Node, width int) []string {
+	switch n := n.(type) {
+	case *ast.Heading:
+		return wrap(r.heading.Render(strings.Repeat("#", n.Level)+" "+r.inline(n)), width)
+	case *ast.Paragraph, *ast.TextBlock:
+		return wrap(r.inline(n), width)
+	case *ast.ThematicBreak:
+		return []string{r.dim.Render(strings.Repeat("─", width))}
+	case *ast.FencedCodeBlock:
+		return r.codeBlock(string(n.Language(r.src)), r.lines(n))
+	case *ast.CodeBlock:
+		return r.codeBlock("", r.lines(n))
+	case *render.MathBlock:
+		return r.codeBlock("tex", r.lines(n))
+	case *ast.HTMLBlock:
+		return strings.Split(r.dim.Render(strings.TrimRight(r.lines(n), "\n")), "\n")
+	case *ast.Blockquote:
+		return prefix(r.blocks(n, width-2), r.dim.Render("│ "))
+	case *render.Callout:
+		return r.callout(n, width)
+	case *ast.List:
+		return r.list(n, width)
+	case *east.Table:
+		return r.table(n)
+	case *render.PlayLink:
+		return []string{r.dim.Render("▶ " + render.PlayURL(n.ID))}
+	}
+	if n.HasChildren() {
+		return r.blocks(n, width)
+	}
+	return nil
+}
+
+// lines returns the source lines of a block, as of code.
+func (r *renderer) lines(n ast.Node) string {
+	var b strings.Builder
+	for i := 0; i < n.Lines().Len(); i++ {
+		seg := n.Lines().At(i)
+		b.Write(seg.Value(r.src))
+	}
+	return b.String()
+}
+
+// codeBlock renders code between rules, highlighted for lang, the first
+// rule labeled with the block's number and language. Code is not
+// wrapped, so that it can be copied as is.
+func (r *renderer) codeBlock(lang, code string) []string {
+	r.codeBlocks++
+	label := "[" + strconv.Itoa(r.codeBlocks) + "]"
+	if lang != "" {
+		label += " " + lang
+	}
+	out := []string{r.dim.Render("── " + label)}
+	code = strings.TrimRight(code, "\n")
+	if r.opts.Color {
+		if lang == "" {
+			lang = "text"
+		}
+		var hl strings.Builder
+		if err := quick.Highlight(&hl, code, lang, "terminal256", r.opts.Style); err == nil {
+			code = strings.TrimRight(hl.String(), "\n")
+		}
+	}
+	out = append(out, strings.Split(code, "\n")...)
+	return append(out, r.dim.Render("──"))
+}
+
+// callout renders a callout as its title and blocks behind a bar in the
+// color of its type.
+func (r *renderer) callout(n *render.Callout, width int) []string {
+	color := r.dim
+	color.color = calloutColors[n.Variant]
+	title := color
+	title.bold = true
+	var out []string
+	for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+		if _, ok := c.(*render.CalloutTitle); ok {
+			out = append(out, wrap(title.Render(r.inline(c)), width-2)...)
+			continue
+		}
+		out = append(out, r.block(c, width-2)...)
+	}
+	return prefix(out, color.Render("┃ "))
+}
+
+// list renders the items of a list behind their bullet or number, the
+// lines after the first indented to line up with it.
+func (r *renderer) list(n *ast.List, width int) []string {
+	markers := []string{}
+	num := n.Start
+	for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+		if n.IsOrdered() {
+			markers = append(markers, strconv.Itoa(num)+".")
+			num++
+		} else {
+			markers = append(markers, "•")
+		}
+	}
+	indent := 0
+	for _, m := range markers {
+		indent = max(indent, ansi.StringWidth(m)+1)
+	}
+	var out []string
+	i := 0
+	for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+		if i > 0 && !n.IsTight {
+			out = append(out, "")
+		}
+		lines := r.blocks(c, width-indent)
+		if len(lines) == 0 {
+			lines = []string{""}
+		}
+		marker := markers[i]
+		if n.IsOrdered() {
+			marker = strings.Repeat(" ", indent-1-len(marker)) + marker
+		}
+		out = append(out, r.dim.Render(marker)+" "+lines[0])
+		out = append(out, prefix(lines[1:], strings.Repeat(" ", indent))...)
+		i++
+	}
+	return out
+}
+
+// table renders a table with its columns aligned and the header row bold
+// above a rule. Cells are not wrapped.
+func (r *renderer) table(n *east.Table) []string {
+	var rows [][]string
+	var widths []int
+	for row := n.FirstChild(); row != nil; row = row.NextSibling() {
+		var cells []string
+		for cell := row.FirstChild(); cell != nil; cell = cell.NextSibling() {
+			s := r.inline(cell)
+			if _, ok := row.(*east.TableHeader); ok {
+				s = r.bold.Render(s)
+			}
+			if len(widths) <= len(cells) {
+				widths = append(widths, 0)
+			}
+			widths[len(cells)] = max(widths[len(cells)], ansi.StringWidth(s))
+			cells = append(cells, s)
+		}
+		rows = append(rows, cells)
+	}
+	sep := r.dim.Render(" │ ")
+	var out []string
+	for i, cells := range rows {
+		parts := make([]string, len(cells))
+		for j, s := range cells {
+			align := east.AlignNone
+			if j < len(n.Alignments) {
+				align = n.Alignments[j]
+			}
+			parts[j] = pad(s, widths[j], align)
+		}
+		out = append(out, strings.TrimRight(strings.Join(parts, sep), " "))
+		if i == 0 {
+			rules := make([]string, len(widths))
+			for j, w := range widths {
+				rules[j] = strings.Repeat("─", w)
+			}
+			out = append(out, r.dim.Render(strings.Join(rules, "─┼─")))
+		}
+	}
+	return out
+}
+
+// pad pads s to width w as align says.
+func pad(s string, w int, align east.Alignment) string {
+	gap := w - ansi.StringWidth(s)
+	switch align {
+	case east.AlignRight:
+		return strings.Repeat(" ", gap) + s
+	case east.AlignCenter:
+		return strings.Repeat(" ", gap/2) + s + strings.Repeat(" ", gap-gap/2)
+	}
+	return s + strings.Repeat(" ", gap)
+}
+
+// inline renders the inline children of n to one string, with hard line
+// breaks as newlines.
+func (r *renderer) inline(n ast.Node) string {
+	var b strings.Builder
+	for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+		r.inlineNode(&b, c)
+	}
+	return b.String()
+}
+
+func (r *renderer) inlineNode(b *strings.Builder, n ast.Node) {
+	switch n := n.(type) {
+	case *ast.Text:
+		b.Write(n.Segment.Value(r.src))
+		switch {
+		case n.HardLineBreak():
+			b.WriteString("\n")
+		case n.SoftLineBreak():
+			b.WriteString(" ")
+		}
+	case *ast.String:
+		b.Write(n.Value)
+	case *ast.CodeSpan:
+		b.WriteString(r.codeSpan.Render(r.plain(n)))
+	case *ast.Emphasis:
+		if n.Level >= 2 {
+			b.WriteString(r.bold.Render(r.inline(n)))
+		} else {
+			b.WriteString(r.italic.Render(r.inline(n)))
+		}
+	case *east.Strikethrough:
+		b.WriteString(r.strike.Render(r.inline(n)))
+	case *east.TaskCheckBox:
+		if n.IsChecked {
+			b.WriteString("☑ ")
+		} else {
+			b.WriteString("☐ ")
+		}
+	case *ast.Link:
+		b.WriteString(r.linkText(r.inline(n), string(n.Destination)))
+	case *ast.AutoLink:
+		b.WriteString(r.link.Render(string(n.URL(r.src))))
+	case *ast.Image:
+		alt := r.plain(n)
+		if alt == "" {
+			alt = path.Base(string(n.Destination))
+		}
+		b.WriteString(r.dim.Render("[image: " + alt + "]"))
+	case *render.WikiLink:
+		switch {
+		case n.Dangling:
+			b.WriteString(r.dangling.Render(n.Label))
+		case n.URL == "":
+			b.WriteString(r.link.Render(n.Label))
+		default:
+			b.WriteString(r.link.Render(n.Label) + r.dim.Render(" → "+n.URL))
+		}
+	case *render.Math:
+		b.WriteString(r.codeSpan.Render("$" + string(n.TeX) + "$"))
+	case *ast.RawHTML:
+		for i := 0; i < n.Segments.Len(); i++ {
+			seg := n.Segments.At(i)
+			b.WriteString(r.dim.Render(string(seg.Value(r.src))))
+		}
+	default:
+		for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+			r.inlineNode(b, c)
+		}
+	}
+}
+
+// linkText renders a markdown link labeled label: one to another entry's
+// file followed by that entry's path, or a dangling label when there is
+// none, and one to the web followed by its URL unless the label is the
+// URL.
+func (r *renderer) linkText(label, dest string) string {
+	u, err := url.Parse(dest)
+	switch {
+	case err != nil || dest == "":
+		return r.link.Render(label)
+	case u.Scheme != "" || u.Host != "":
+		if ansi.Strip(label) == dest || strings.TrimPrefix(dest, "mailto:") == ansi.Strip(label) {
+			return r.link.Render(label)
+		}
+		return r.link.Render(label) + r.dim.Render(" <"+dest+">")
+	case u.Path == "":
+		// A heading of this entry.
+		return r.link.Render(label)
+	case r.opts.Index != nil && strings.HasSuffix(u.Path, ".md"):
+		to, res := r.opts.Index.Resolve(r.from, links.Link{Target: u.Path})
+		if res != links.Resolved {
+			return r.dangling.Render(label)
+		}
+		return r.link.Render(label) + r.dim.Render(" → "+to.Path)
+	}
+	return r.link.Render(label) + r.dim.Render(fmt.Sprintf(" (%s)", dest))
+}
+
+// plain returns the text of the inline children of n, unstyled.
+func (r *renderer) plain(n ast.Node) string {
+	var b strings.Builder
+	_ = ast.Walk(n, func(c ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch c := c.(type) {
+		case *ast.Text:
+			b.Write(c.Segment.Value(r.src))
+			if c.SoftLineBreak() || c.HardLineBreak() {
+				b.WriteString(" ")
+			}
+		case *ast.String:
+			b.Write(c.Value)
+		}
+		return ast.WalkContinue, nil
+	})
+	return b.String()
+}
+
+// wrap word-wraps s to width, breaking words longer than a line.
+func wrap(s string, width int) []string {
+	return strings.Split(ansi.Wrap(s, max(width, 20), ""), "\n")
+}
+
+// prefix puts p before each of lines.
+func prefix(lines []string, p string) []string {
+	out := make([]string, len(lines))
+	for i, l := range lines {
+		out[i] = p + l
+	}
+	return out
+}
@@ -0,0 +1,106 @@
+package termrender
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/canhta/til/go/internal/links"
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+const slicesEntry = "---\ntitle: Slices\ndate: 2024-06-01\ntags: [go, memory]\n---\n\n# Slices\n\n## Sharing\n\n" +
+	"Slices **share** their *backing* arrays, so `append` may ~~not~~ copy. See [maps](maps.md), [[maps]], [[nowhere]], [gone](gone.md), [Go](https://go.dev), <https://go.dev/x> and [top](#sharing).\n\n" +
+	"```go\nfunc main() {}\n```\n\n    indented\n\n> quoted text\n\n> [!TIP]\n> Use copy.\n\n" +
+	"- one\n- two\n  more\n- [x] done\n\n3. three\n4. four\n\n" +
+	"| a | long header | c |\n|:--|:-:|--:|\n| 1 | 2 | 3333 |\n\n---\n\n![diagram](assets/slices/d.svg)\n"
+
+func parse(t *testing.T, p, data string) *entry.Entry {
+	t.Helper()
+	e, err := entry.Parse(p, []byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+func TestRender(t *testing.T) {
+	e := parse(t, "go/slices.md", slicesEntry)
+	maps := parse(t, "go/maps.md", "# Maps\n")
+	got := Render(e, Options{Width: 40, Index: links.NewIndex([]*entry.Entry{e, maps})})
+	want := `Slices
+go/slices.md  2024-06-01  #go #memory
+
+## Sharing
+
+Slices share their backing arrays, so
+append may not copy. See maps →
+go/maps.md, Maps → go/maps.md, nowhere,
+gone, Go <https://go.dev>,
+https://go.dev/x and top.
+
+── [1] go
+func main() {}
+──
+
+── [2]
+indented
+──
+
+│ quoted text
+
+┃ Tip
+┃ Use copy.
+
+• one
+• two more
+• ☑ done
+
+3. three
+4. four
+
+a │ long header │    c
+──┼─────────────┼─────
+1 │      2      │ 3333
+
+────────────────────────────────────────
+
+[image: diagram]
+`
+	if got != want {
+		t.Errorf("Render =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestRenderWithoutIndex(t *testing.T) {
+	e := parse(t, "go/slices.md", "# Slices\n\nSee [maps](maps.md) and [[maps]].\n")
+	if got, want := Render(e, Options{}), "Slices\ngo/slices.md\n\nSee maps (maps.md) and maps.\n"; got != want {
+		t.Errorf("Render =\n%q\nwant\n%q", got, want)
+	}
+	if got := Render(parse(t, "go/empty.md", "---\ntitle: Empty\n---\n"), Options{}); got != "Empty\ngo/empty.md\n" {
+		t.Errorf("Render of an empty entry = %q", got)
+	}
+}
+
+func TestRenderColor(t *testing.T) {
+	e := parse(t, "go/slices.md", slicesEntry)
+	out := Render(e, Options{Width: 40, Color: true})
+	for _, want := range []string{"\x1b[94;1mSlices\x1b[0m\n", "\x1b[1mshare\x1b[0m", "\x1b[3mbacking\x1b[0m", "\x1b[93mappend\x1b[0m", "\x1b[9mnot\x1b[0m", "\x1b[90m── [1] go\x1b[0m"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Render lacks %q:\n%q", want, out)
+		}
+	}
+	// The code is highlighted.
+	if strings.Contains(out, "\nfunc main() {}\n") {
+		t.Errorf("code not highlighted:\n%q", out)
+	}
+}
+
+// TestWrap checks that lines are at least 20 columns wide, and words
+// longer than a line broken.
+func TestWrap(t *testing.T) {
+	got := wrap("a "+strings.Repeat("x", 30), 10)
+	if want := []string{"a", strings.Repeat("x", 20), strings.Repeat("x", 10)}; !slices.Equal(got, want) {
+		t.Errorf("wrap = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,246 @@
+// Package tmpl renders the skeletons used to scaffold new entries.
+//
+// Templates are looked up in the notes tree's ".til/templates" directory,
+// first as "<category>.md", then as "default.md". When neither exists the
+// default template embedded in the binary is used. A template can also be
+// chosen by name, as "<name>.md".
+//
+// Besides the fields of Data, a template can use variables of its own,
+// such as {{.Topic}} or {{.SourceURL}}: every other field it names is one,
+// and is given a value when the entry is created.
+package tmpl
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/template"
+	tparse "text/template/parse"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed templates/default.md.tmpl
+var builtin embed.FS
+
+// Dir is the template directory relative to the notes state directory.
+const Dir = "templates"
+
+// Data is the data available to entry templates.
+type Data struct {
+	Title    string
+	Date     string
+	Category string
+	Slug     string
+	Tags     []string
+	// Author is the user.name of git, if set.
+	Author string
+	// Vars are the values of the template's variables, by name; those
+	// missing are empty.
+	Vars map[string]string
+}
+
+// Template is a loaded entry template.
+type Template struct {
+	// Source is the file the template was loaded from, or "builtin".
+	Source string
+	tmpl   *template.Template
+}
+
+// Lookup loads the template for category from dir, falling back to
+// dir/default.md and finally to the builtin template.
+func Lookup(dir, category string) (*Template, error) {
+	for _, name := range []string{category + ".md", "default.md"} {
+		if name == ".md" {
+			continue
+		}
+		file := filepath.Join(dir, name)
+		src, err := os.ReadFile(file)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return parse(file, string(src))
+	}
+	src, err := builtin.ReadFile("templates/default.md.tmpl")
+	if err != nil {
+		return nil, err
+	}
+	return parse("builtin", string(src))
+}
+
+// Named loads the template called name from dir. The name default falls
+// back to the builtin template.
+func Named(dir, name string) (*Template, error) {
+	if name == "" || strings.ContainsAny(name, `/\`) {
+		return nil, fmt.Errorf("invalid template name %q", name)
+	}
+	file := filepath.Join(dir, name+".md")
+	src, err := os.ReadFile(file)
+	if errors.Is(err, fs.ErrNotExist) {
+		if name == "default" {
+			return Lookup(dir, "")
+		}
+		return nil, fmt.Errorf("no template %q in %s", name, dir)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return parse(file, string(src))
+}
+
+// Names returns the names of the templates in dir, sorted.
+func Names(dir string) ([]string, error) {
+	files, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, f := range files {
+		if name, ok := strings.CutSuffix(f.Name(), ".md"); ok && !f.IsDir() {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+func parse(source, src string) (*Template, error) {
+	t, err := template.New(filepath.Base(source)).Funcs(funcs).Parse(src)
+	if err != nil {
+		return nil, err
+	}
+	return &Template{Source: source, tmpl: t}, nil
+}
+
+var funcs = template.FuncMap{
+	"join": strings.Join,
+	"yaml": yamlScalar,
+}
+
+// yamlScalar encodes s as a YAML scalar, quoting it only when needed.
+func yamlScalar(s string) (string, error) {
+	out, err := yaml.Marshal(s)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(string(out), "\n"), nil
+}
+
+// Render executes the template with data.
+func (t *Template) Render(data Data) ([]byte, error) {
+	v := map[string]any{}
+	for _, name := range t.Vars() {
+		v[name] = data.Vars[name]
+	}
+	maps.Copy(v, map[string]any{
+		"Title":    data.Title,
+		"Date":     data.Date,
+		"Category": data.Category,
+		"Slug":     data.Slug,
+		"Tags":     data.Tags,
+		"Author":   data.Author,
+	})
+	var buf bytes.Buffer
+	if err := t.tmpl.Execute(&buf, v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Vars returns the names of the template's variables, in the order they
+// first appear.
+func (t *Template) Vars() []string {
+	var names []string
+	var walk func(n tparse.Node, top bool)
+	walk = func(n tparse.Node, top bool) {
+		switch n := n.(type) {
+		case *tparse.ListNode:
+			if n == nil {
+				return
+			}
+			for _, c := range n.Nodes {
+				walk(c, top)
+			}
+		case *tparse.ActionNode:
+			walk(n.Pipe, top)
+		case *tparse.IfNode:
+			walk(n.Pipe, top)
+			walk(n.List, top)
+			walk(n.ElseList, top)
+		case *tparse.WithNode:
+			// Inside, dot is the value of the pipeline.
+			walk(n.Pipe, top)
+			walk(n.List, false)
+			walk(n.ElseList, top)
+		case *tparse.RangeNode:
+			walk(n.Pipe, top)
+			walk(n.List, false)
+			walk(n.ElseList, top)
+		case *tparse.TemplateNode:
+			walk(n.Pipe, top)
+		case *tparse.PipeNode:
+			if n == nil {
+				return
+			}
+			for _, c := range n.Cmds {
+				walk(c, top)
+			}
+		case *tparse.CommandNode:
+			for _, c := range n.Args {
+				walk(c, top)
+			}
+		case *tparse.ChainNode:
+			walk(n.Node, top)
+		case *tparse.FieldNode:
+			if top {
+				names = addVar(names, n.Ident[0])
+			}
+		case *tparse.VariableNode:
+			if len(n.Ident) > 1 && n.Ident[0] == "$" {
+				names = addVar(names, n.Ident[1])
+			}
+		}
+	}
+	walk(t.tmpl.Tree.Root, true)
+	return names
+}
+
+// dataFields are the names of the fields of Data other than Vars.
+var dataFields = []string{"Title", "Date", "Category", "Slug", "Tags", "Author"}
+
+func addVar(names []string, name string) []string {
+	if slices.Contains(dataFields, name) || slices.Contains(names, name) {
+		return names
+	}
+	return append(names, name)
+}
+
+// Label returns the name of a variable as words, as "Source URL" for
+// SourceURL.
+func Label(name string) string {
+	rs := []rune(name)
+	var b strings.Builder
+	for i, r := range rs {
+		if i > 0 && unicode.IsUpper(r) && (unicode.IsLower(rs[i-1]) || i+1 < len(rs) && unicode.IsLower(rs[i+1]) && unicode.IsUpper(rs[i-1])) {
+			b.WriteByte(' ')
+		} else if r == '_' {
+			b.WriteByte(' ')
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,155 @@
+package tmpl
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func writeTemplate(t *testing.T, dir, name, src string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLookup(t *testing.T) {
+	dir := t.TempDir()
+	check := func(category, want string) {
+		t.Helper()
+		tp, err := Lookup(dir, category)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tp.Source != want {
+			t.Errorf("Lookup(%s) = %s, want %s", category, tp.Source, want)
+		}
+	}
+	check("go", "builtin")
+	writeTemplate(t, dir, "default.md", "# {{.Title}}\n")
+	check("go", filepath.Join(dir, "default.md"))
+	writeTemplate(t, dir, "go.md", "# Go: {{.Title}}\n")
+	check("go", filepath.Join(dir, "go.md"))
+	check("git", filepath.Join(dir, "default.md"))
+	check("", filepath.Join(dir, "default.md"))
+}
+
+func TestLookupMissingDir(t *testing.T) {
+	tp, err := Lookup(filepath.Join(t.TempDir(), "none"), "go")
+	if err != nil || tp.Source != "builtin" {
+		t.Errorf("Lookup = %v, %v; want the builtin template", tp, err)
+	}
+}
+
+func TestRenderBuiltin(t *testing.T) {
+	tp, err := Lookup(t.TempDir(), "go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := tp.Render(Data{
+		Title:    "Errors: wrap, don't panic",
+		Date:     "2024-06-01",
+		Category: "go",
+		Slug:     "errors-wrap-dont-panic",
+		Tags:     []string{"go", "errors"},
+		Author:   "Jane Doe",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `---
+title: 'Errors: wrap, don''t panic'
+date: 2024-06-01
+category: go
+slug: errors-wrap-dont-panic
+tags: [go, errors]
+author: Jane Doe
+---
+
+# Errors: wrap, don't panic
+
+`
+	if string(got) != want {
+		t.Errorf("Render =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestRenderBuiltinWithoutAuthor(t *testing.T) {
+	tp, err := Lookup(t.TempDir(), "go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := tp.Render(Data{Title: "A", Date: "2024-06-01", Category: "go", Slug: "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "---\ntitle: A\ndate: 2024-06-01\ncategory: go\nslug: a\ntags: []\n---\n\n# A\n\n"; string(got) != want {
+		t.Errorf("Render = %q, want %q", got, want)
+	}
+}
+
+func TestNamed(t *testing.T) {
+	dir := t.TempDir()
+	writeTemplate(t, dir, "postmortem.md", "# {{.Title}}\n")
+	writeTemplate(t, dir, "go.md", "# {{.Title}}\n")
+	if err := os.Mkdir(filepath.Join(dir, "old.md"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if tp, err := Named(dir, "postmortem"); err != nil || tp.Source != filepath.Join(dir, "postmortem.md") {
+		t.Errorf("Named(postmortem) = %v, %v", tp, err)
+	}
+	if tp, err := Named(dir, "default"); err != nil || tp.Source != "builtin" {
+		t.Errorf("Named(default) = %v, %v; want the builtin template", tp, err)
+	}
+	for _, name := range []string{"", "../go", "missing"} {
+		if _, err := Named(dir, name); err == nil {
+			t.Errorf("Named(%q) succeeded", name)
+		}
+	}
+	if names, err := Names(dir); err != nil || !slices.Equal(names, []string{"go", "postmortem"}) {
+		t.Errorf("Names = %q, %v", names, err)
+	}
+	if names, err := Names(filepath.Join(dir, "none")); names != nil || err != nil {
+		t.Errorf("Names of a missing dir = %q, %v", names, err)
+	}
+}
+
+func TestVars(t *testing.T) {
+	dir := t.TempDir()
+	src := "---\ntitle: {{.Title}}\nservice: {{.Service | yaml}}\n---\n\n" +
+		"{{if .Severity}}Severity {{.Severity}}.{{else}}{{.Impact}}{{end}}\n" +
+		"{{range .Tags}}{{.Name}}{{end}}{{with .Owner}}{{.Email}}{{end}}\n" +
+		"{{range $t := .Tags}}{{$.SourceURL}}{{end}} {{.Service}} {{.Date}}\n"
+	writeTemplate(t, dir, "pm.md", src)
+	tp, err := Named(dir, "pm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Fields of dot inside range and with are not variables.
+	if got, want := tp.Vars(), []string{"Service", "Severity", "Impact", "Owner", "SourceURL"}; !slices.Equal(got, want) {
+		t.Errorf("Vars = %q, want %q", got, want)
+	}
+	out, err := tp.Render(Data{Title: "Disk full", Date: "2024-06-01", Vars: map[string]string{"Service": "db: primary", "Severity": "2", "SourceURL": "u"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "---\ntitle: Disk full\nservice: 'db: primary'\n---\n\nSeverity 2.\n\n db: primary 2024-06-01\n"
+	if string(out) != want {
+		t.Errorf("Render =\n%s\nwant\n%s", out, want)
+	}
+}
+
+func TestLabel(t *testing.T) {
+	for name, want := range map[string]string{
+		"Topic":      "Topic",
+		"SourceURL":  "Source URL",
+		"HTTPServer": "HTTP Server",
+		"on_call":    "on call",
+		"RootCause":  "Root Cause",
+	} {
+		if got := Label(name); got != want {
+			t.Errorf("Label(%s) = %q, want %q", name, got, want)
+		}
+	}
+}
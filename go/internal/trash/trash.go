@@ -0,0 +1,250 @@
+// Package trash keeps the entries removed with til rm for a while, so that
+// til restore can bring them back.
+//
+// Each removal is a directory of .trash at the notes root, named for the
+// time it was made and holding the removed files under their paths in the
+// tree:
+//
+//	.trash/20261014-153000.000/go/slices.md
+//	.trash/20261014-153000.000/go/assets/slices/diagram.png
+//
+// The trash is kept in the notes directory whatever the store, and ignored
+// by git. Removals older than the retention period are purged.
+package trash
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/canhta/til/go/internal/assets"
+	"github.com/canhta/til/go/internal/notes"
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+// Dir is the trash directory, relative to the notes root.
+const Dir = ".trash"
+
+// Retention is how long removals are kept unless configured otherwise.
+const Retention = 30 * 24 * time.Hour
+
+// stampLayout names the directory of a removal.
+const stampLayout = "20060102-150405.000"
+
+// Item is an entry in the trash.
+type Item struct {
+	// Path is where the entry was in the tree.
+	Path    string    `json:"path"`
+	Title   string    `json:"title,omitempty"`
+	Removed time.Time `json:"removed"`
+	// Files are the entry file and its assets, by their paths in the tree.
+	Files []string `json:"files"`
+	// dir is the directory of the removal.
+	dir string
+}
+
+// Put moves the files at paths, entries and their assets, out of the tree
+// and into the trash as one removal made at now. The files are copied to
+// the trash before being removed from the tree, which they are as one unit.
+func Put(tree *notes.Tree, paths []string, now time.Time) error {
+	root := filepath.Join(tree.Root, Dir)
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return err
+	}
+	// Git keeps out of the trash, whatever the repository ignores.
+	ignore := filepath.Join(root, ".gitignore")
+	if _, err := os.Stat(ignore); errors.Is(err, fs.ErrNotExist) {
+		if err := os.WriteFile(ignore, []byte("*\n"), 0o644); err != nil {
+			return err
+		}
+	}
+	dir := filepath.Join(root, now.UTC().Format(stampLayout))
+	if _, err := os.Stat(dir); err == nil {
+		return fmt.Errorf("trash: %s already exists", dir)
+	}
+	for _, p := range paths {
+		data, err := tree.Read(p)
+		if err != nil {
+			os.RemoveAll(dir)
+			return err
+		}
+		file := filepath.Join(dir, filepath.FromSlash(p))
+		if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+			os.RemoveAll(dir)
+			return err
+		}
+		if err := os.WriteFile(file, data, 0o644); err != nil {
+			os.RemoveAll(dir)
+			return err
+		}
+	}
+	if err := tree.Replace(nil, paths); err != nil {
+		os.RemoveAll(dir)
+		return err
+	}
+	return nil
+}
+
+// List returns the entries in the trash of tree, most recently removed
+// first.
+func List(tree *notes.Tree) ([]Item, error) {
+	root := filepath.Join(tree.Root, Dir)
+	dirs, err := os.ReadDir(root)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var items []Item
+	for _, d := range dirs {
+		removed, err := time.Parse(stampLayout, d.Name())
+		if !d.IsDir() || err != nil {
+			continue
+		}
+		dir := filepath.Join(root, d.Name())
+		var files []string
+		err = filepath.WalkDir(dir, func(file string, de fs.DirEntry, err error) error {
+			if err != nil || de.IsDir() {
+				return err
+			}
+			rel, err := filepath.Rel(dir, file)
+			if err != nil {
+				return err
+			}
+			files = append(files, filepath.ToSlash(rel))
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range files {
+			if !strings.HasSuffix(p, ".md") || owner(files, p) != "" {
+				continue
+			}
+			it := Item{Path: p, Removed: removed, Files: []string{p}, dir: dir}
+			for _, f := range files {
+				if owner(files, f) == p {
+					it.Files = append(it.Files, f)
+				}
+			}
+			if data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(p))); err == nil {
+				if e, err := entry.Parse(p, data); err == nil {
+					it.Title = e.Meta.Title
+				}
+			}
+			items = append(items, it)
+		}
+	}
+	slices.SortStableFunc(items, func(x, y Item) int { return y.Removed.Compare(x.Removed) })
+	return items, nil
+}
+
+// owner returns the entry among files whose assets hold the file at p, or
+// "".
+func owner(files []string, p string) string {
+	for _, f := range files {
+		if f != p && strings.HasSuffix(f, ".md") && strings.HasPrefix(p, assets.DirFor(f)+"/") {
+			return f
+		}
+	}
+	return ""
+}
+
+// Find returns the most recently removed entry in items that ref names: by
+// its path, with or without the .md extension, or by its file name alone.
+func Find(items []Item, ref string) (Item, bool) {
+	ref = strings.TrimSuffix(ref, ".md")
+	for _, it := range items {
+		p := strings.TrimSuffix(it.Path, ".md")
+		if p == ref || path.Base(p) == ref {
+			return it, true
+		}
+	}
+	return Item{}, false
+}
+
+// Restore puts the files of it back into tree, as one unit, and takes them
+// out of the trash. It fails when a file is in the way.
+func Restore(tree *notes.Tree, it Item) error {
+	files := map[string][]byte{}
+	for _, p := range it.Files {
+		if ok, err := tree.Exists(p); err != nil {
+			return err
+		} else if ok {
+			return fmt.Errorf("cannot restore %s: %s exists", it.Path, p)
+		}
+		data, err := os.ReadFile(filepath.Join(it.dir, filepath.FromSlash(p)))
+		if err != nil {
+			return err
+		}
+		files[p] = data
+	}
+	if err := tree.WriteAll(files); err != nil {
+		return err
+	}
+	for _, p := range it.Files {
+		if err := os.Remove(filepath.Join(it.dir, filepath.FromSlash(p))); err != nil {
+			return err
+		}
+	}
+	return prune(it.dir)
+}
+
+// prune removes the empty directories under dir, and dir itself if it is
+// left empty.
+func prune(dir string) error {
+	var dirs []string
+	err := filepath.WalkDir(dir, func(file string, de fs.DirEntry, err error) error {
+		if err == nil && de.IsDir() {
+			dirs = append(dirs, file)
+		}
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	// Deepest first, so that parents are empty by the time they come.
+	for _, d := range slices.Backward(dirs) {
+		if err := os.Remove(d); err != nil && !isNotEmpty(d) {
+			return err
+		}
+	}
+	return nil
+}
+
+func isNotEmpty(dir string) bool {
+	des, err := os.ReadDir(dir)
+	return err == nil && len(des) > 0
+}
+
+// Purge deletes the removals made longer than retention before now,
+// returning the number of entries deleted with them.
+func Purge(tree *notes.Tree, retention time.Duration, now time.Time) (int, error) {
+	items, err := List(tree)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	purged := map[string]bool{}
+	for _, it := range items {
+		if now.Sub(it.Removed) <= retention {
+			continue
+		}
+		n++
+		if purged[it.dir] {
+			continue
+		}
+		purged[it.dir] = true
+		if err := os.RemoveAll(it.dir); err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
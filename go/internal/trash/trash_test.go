@@ -0,0 +1,195 @@
+package trash
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/canhta/til/go/internal/notes"
+)
+
+func newTree(t *testing.T, files map[string]string) *notes.Tree {
+	t.Helper()
+	tree := notes.Open(t.TempDir())
+	for p, data := range files {
+		if err := tree.Write(p, []byte(data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return tree
+}
+
+func exists(tree *notes.Tree, p string) bool {
+	ok, err := tree.Exists(p)
+	return err == nil && ok
+}
+
+func TestPutAndRestore(t *testing.T) {
+	tree := newTree(t, map[string]string{
+		"go/slices.md":               "---\ntitle: Slices share arrays\n---\n",
+		"go/assets/slices/d.svg":     "<svg/>",
+		"go/assets/slices/notes.md":  "asset, not an entry",
+		"go/maps.md":                 "# Maps\n",
+		"git/rebase.md":              "# Rebase\n",
+		"go/assets/slicesmore/x.png": "png",
+	})
+	day := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	if err := Put(tree, []string{"go/slices.md", "go/assets/slices/d.svg", "go/assets/slices/notes.md", "go/maps.md"}, day); err != nil {
+		t.Fatal(err)
+	}
+	if err := Put(tree, []string{"git/rebase.md"}, day.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []string{"go/slices.md", "go/assets/slices/d.svg", "go/maps.md", "git/rebase.md"} {
+		if exists(tree, p) {
+			t.Errorf("%s still in the tree", p)
+		}
+	}
+	if !exists(tree, "go/assets/slicesmore/x.png") {
+		t.Error("the assets of another entry were removed")
+	}
+	if data, err := os.ReadFile(filepath.Join(tree.Root, Dir, ".gitignore")); err != nil || string(data) != "*\n" {
+		t.Errorf(".trash/.gitignore = %q, %v", data, err)
+	}
+	if _, err := os.Stat(filepath.Join(tree.Root, Dir, "20240601-120000.000", "go", "slices.md")); err != nil {
+		t.Errorf("removal not kept under its time: %v", err)
+	}
+	// Removed entries are no longer entries of the tree.
+	if es, err := tree.Entries(); err != nil || len(es) != 0 {
+		t.Errorf("Entries after Put = %v, %v", es, err)
+	}
+
+	items, err := List(tree)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, it := range items {
+		got = append(got, it.Path+" "+it.Title+" "+strings.Join(it.Files, ","))
+	}
+	want := []string{
+		"git/rebase.md Rebase git/rebase.md",
+		"go/maps.md Maps go/maps.md",
+		"go/slices.md Slices share arrays go/slices.md,go/assets/slices/d.svg,go/assets/slices/notes.md",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("List =\n%q\nwant\n%q", got, want)
+	}
+	if !items[0].Removed.Equal(day.Add(time.Hour)) {
+		t.Errorf("removed at %v", items[0].Removed)
+	}
+
+	for _, ref := range []string{"go/slices.md", "go/slices", "slices"} {
+		if it, ok := Find(items, ref); !ok || it.Path != "go/slices.md" {
+			t.Errorf("Find(%s) = %v, %v", ref, it, ok)
+		}
+	}
+	if _, ok := Find(items, "go/gone"); ok {
+		t.Error("Find(go/gone) found an entry")
+	}
+
+	it, _ := Find(items, "slices")
+	if err := Restore(tree, it); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := tree.Read("go/assets/slices/d.svg"); string(data) != "<svg/>" || !exists(tree, "go/slices.md") {
+		t.Error("entry not restored with its assets")
+	}
+	items, _ = List(tree)
+	if len(items) != 2 || items[1].Path != "go/maps.md" {
+		t.Errorf("List after Restore = %v", items)
+	}
+	// The removal keeps go/maps.md, but not the directories emptied.
+	if _, err := os.Stat(filepath.Join(tree.Root, Dir, "20240601-120000.000", "go", "assets")); err == nil {
+		t.Error("emptied directories left in the trash")
+	}
+
+	it, _ = Find(items, "rebase")
+	if err := Restore(tree, it); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(tree.Root, Dir, "20240601-130000.000")); err == nil {
+		t.Error("emptied removal left in the trash")
+	}
+}
+
+func TestRestoreConflict(t *testing.T) {
+	tree := newTree(t, map[string]string{"go/slices.md": "old\n", "go/assets/slices/d.svg": "old"})
+	if err := Put(tree, []string{"go/slices.md", "go/assets/slices/d.svg"}, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if err := tree.Write("go/assets/slices/d.svg", []byte("new")); err != nil {
+		t.Fatal(err)
+	}
+	items, _ := List(tree)
+	if err := Restore(tree, items[0]); err == nil || err.Error() != "cannot restore go/slices.md: go/assets/slices/d.svg exists" {
+		t.Errorf("Restore over a file = %v", err)
+	}
+	// Nothing was restored, and the entry stays in the trash.
+	if exists(tree, "go/slices.md") {
+		t.Error("go/slices.md restored in part")
+	}
+	if items, _ := List(tree); len(items) != 1 {
+		t.Errorf("List = %v", items)
+	}
+}
+
+func TestRemovedTwice(t *testing.T) {
+	tree := newTree(t, map[string]string{"go/slices.md": "first\n"})
+	day := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	if err := Put(tree, []string{"go/slices.md"}, day); err != nil {
+		t.Fatal(err)
+	}
+	if err := tree.Write("go/slices.md", []byte("second\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := Put(tree, []string{"go/slices.md"}, day.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	items, _ := List(tree)
+	it, _ := Find(items, "slices")
+	if err := Restore(tree, it); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := tree.Read("go/slices.md"); string(data) != "second\n" {
+		t.Errorf("restored %q, want the entry as it last was", data)
+	}
+}
+
+func TestPutMissing(t *testing.T) {
+	tree := newTree(t, map[string]string{"go/slices.md": "x\n"})
+	now := time.Now()
+	if err := Put(tree, []string{"go/slices.md", "go/nowhere.md"}, now); err == nil {
+		t.Fatal("Put of a missing file succeeded")
+	}
+	if !exists(tree, "go/slices.md") {
+		t.Error("go/slices.md removed by a failed Put")
+	}
+	if items, _ := List(tree); len(items) != 0 {
+		t.Errorf("List after a failed Put = %v", items)
+	}
+}
+
+func TestPurge(t *testing.T) {
+	tree := newTree(t, map[string]string{"go/a.md": "a\n", "go/b.md": "b\n", "go/c.md": "c\n"})
+	now := time.Date(2024, 6, 30, 0, 0, 0, 0, time.UTC)
+	if err := Put(tree, []string{"go/a.md", "go/b.md"}, now.Add(-31*24*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if err := Put(tree, []string{"go/c.md"}, now.Add(-29*24*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	n, err := Purge(tree, Retention, now)
+	if err != nil || n != 2 {
+		t.Errorf("Purge = %d, %v, want 2", n, err)
+	}
+	if items, _ := List(tree); len(items) != 1 || items[0].Path != "go/c.md" {
+		t.Errorf("List after Purge = %v", items)
+	}
+	if n, err := Purge(tree, Retention, now); n != 0 || err != nil {
+		t.Errorf("Purge again = %d, %v", n, err)
+	}
+}
@@ -0,0 +1,164 @@
+package tui
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/canhta/til/go/internal/fuzzy"
+)
+
+// ErrCanceled is returned by Pick when the user quits without choosing.
+var ErrCanceled = errors.New("canceled")
+
+var matchStyle = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("3"))
+
+// Item is a choice offered by Pick. Label is matched against, Detail only
+// shown after it.
+type Item struct {
+	Label  string
+	Detail string
+}
+
+// Pick shows items in a fuzzy finder on the terminal, filtered as the user
+// types starting from query, and returns the index of the one chosen. It
+// draws on stderr, so it can be used while stdout is piped.
+func Pick(items []Item, query string) (int, error) {
+	labels := make([]string, len(items))
+	for i, it := range items {
+		labels[i] = it.Label + " " + it.Detail
+	}
+	input := textinput.New()
+	input.Prompt = "> "
+	input.SetValue(query)
+	input.Focus()
+	m := &picker{items: items, labels: labels, input: input, chosen: -1}
+	m.refilter()
+	p := tea.NewProgram(m, tea.WithAltScreen(), tea.WithOutput(os.Stderr), tea.WithInputTTY())
+	if _, err := p.Run(); err != nil {
+		return -1, err
+	}
+	if m.chosen < 0 {
+		return -1, ErrCanceled
+	}
+	return m.chosen, nil
+}
+
+type picker struct {
+	items   []Item
+	labels  []string
+	matches []fuzzy.Match
+	input   textinput.Model
+	cursor  int
+	top     int
+	width   int
+	height  int
+	chosen  int
+}
+
+func (m *picker) refilter() {
+	m.matches = fuzzy.Find(m.input.Value(), m.labels)
+	m.cursor, m.top = 0, 0
+}
+
+func (m *picker) Init() tea.Cmd {
+	return textinput.Blink
+}
+
+func (m *picker) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+		m.input.Width = msg.Width - 3
+		return m, nil
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "ctrl+c", "esc":
+			return m, tea.Quit
+		case "enter":
+			if len(m.matches) > 0 {
+				m.chosen = m.matches[m.cursor].Index
+			}
+			return m, tea.Quit
+		case "up", "ctrl+p", "ctrl+k":
+			m.cursor = max(0, m.cursor-1)
+			return m, nil
+		case "down", "ctrl+n", "ctrl+j", "tab":
+			m.cursor = min(max(0, len(m.matches)-1), m.cursor+1)
+			return m, nil
+		case "pgup":
+			m.cursor = max(0, m.cursor-m.listHeight())
+			return m, nil
+		case "pgdown":
+			m.cursor = min(max(0, len(m.matches)-1), m.cursor+m.listHeight())
+			return m, nil
+		}
+	}
+	before := m.input.Value()
+	var cmd tea.Cmd
+	m.input, cmd = m.input.Update(msg)
+	if m.input.Value() != before {
+		m.refilter()
+	}
+	return m, cmd
+}
+
+// listHeight is the number of matches shown, leaving room for the input
+// and the count.
+func (m *picker) listHeight() int {
+	return max(1, m.height-2)
+}
+
+func (m *picker) View() string {
+	if m.width == 0 {
+		return ""
+	}
+	h := m.listHeight()
+	if m.cursor < m.top {
+		m.top = m.cursor
+	} else if m.cursor >= m.top+h {
+		m.top = m.cursor - h + 1
+	}
+	var rows []string
+	for i := m.top; i < len(m.matches) && i < m.top+h; i++ {
+		rows = append(rows, m.row(m.matches[i], i == m.cursor))
+	}
+	count := statusStyle.Render(fmt.Sprintf("  %d/%d", len(m.matches), len(m.items)))
+	return m.input.View() + "\n" + count + "\n" + strings.Join(rows, "\n")
+}
+
+// row renders a match with the matched characters highlighted.
+func (m *picker) row(mt fuzzy.Match, selected bool) string {
+	it := m.items[mt.Index]
+	label := []rune(it.Label)
+	width := m.width - 2
+	hit := map[int]bool{}
+	for _, p := range mt.Positions {
+		hit[p] = true
+	}
+	var b strings.Builder
+	n := 0
+	for i, r := range label {
+		if n == width {
+			break
+		}
+		if hit[i] {
+			b.WriteString(matchStyle.Render(string(r)))
+		} else {
+			b.WriteRune(r)
+		}
+		n++
+	}
+	if rest := width - n - 1; rest > 0 && it.Detail != "" {
+		b.WriteString(" " + statusStyle.Render(truncate(it.Detail, rest)))
+	}
+	if selected {
+		return selectedStyle.Render(">") + " " + b.String()
+	}
+	return "  " + b.String()
+}
@@ -0,0 +1,73 @@
+package tui
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func newPicker(items []Item, query string) *picker {
+	labels := make([]string, len(items))
+	for i, it := range items {
+		labels[i] = it.Label + " " + it.Detail
+	}
+	input := textinput.New()
+	input.SetValue(query)
+	input.Focus()
+	m := &picker{items: items, labels: labels, input: input, chosen: -1}
+	m.refilter()
+	m.Update(tea.WindowSizeMsg{Width: 60, Height: 4})
+	return m
+}
+
+var pickItems = []Item{
+	{Label: "Slices", Detail: "go/slices.md"},
+	{Label: "Maps", Detail: "go/maps.md"},
+	{Label: "Rebase onto", Detail: "git/rebase.md"},
+}
+
+func TestPick(t *testing.T) {
+	m := newPicker(pickItems, "go")
+	if len(m.matches) != 2 {
+		t.Fatalf("matches of go = %v", m.matches)
+	}
+	m.Update(tea.KeyMsg{Type: tea.KeyDown})
+	m.Update(tea.KeyMsg{Type: tea.KeyDown})
+	if m.cursor != 1 {
+		t.Errorf("cursor = %d, want it kept on the last match", m.cursor)
+	}
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("/ma")})
+	if len(m.matches) != 1 || m.cursor != 0 {
+		t.Errorf("after typing: matches %v, cursor %d", m.matches, m.cursor)
+	}
+	if _, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter}); cmd == nil || m.chosen != 1 {
+		t.Errorf("enter chose %d", m.chosen)
+	}
+
+	m = newPicker(pickItems, "zzz")
+	m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if m.chosen != -1 {
+		t.Errorf("enter with no match chose %d", m.chosen)
+	}
+	m = newPicker(pickItems, "")
+	m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if m.chosen != -1 {
+		t.Errorf("esc chose %d", m.chosen)
+	}
+}
+
+func TestPickView(t *testing.T) {
+	m := newPicker(pickItems, "")
+	view := m.View()
+	lines := strings.Split(view, "\n")
+	// The input, the count and as many matches as fit.
+	if len(lines) != 4 || !strings.Contains(lines[1], "3/3") || !strings.Contains(lines[2], "Slices") || !strings.Contains(lines[3], "Maps") {
+		t.Errorf("view =\n%s", view)
+	}
+	m.Update(tea.KeyMsg{Type: tea.KeyPgDown})
+	if lines := strings.Split(m.View(), "\n"); !strings.Contains(lines[3], "Rebase onto") {
+		t.Errorf("view after pgdown =\n%s", m.View())
+	}
+}
@@ -0,0 +1,131 @@
+package tui
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/alecthomas/chroma/v2/quick"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/canhta/til/go/pkg/entry"
+	"github.com/canhta/til/go/pkg/render"
+)
+
+var (
+	titleStyle   = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("12"))
+	headingStyle = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("13"))
+	metaStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("8"))
+	fenceStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("8"))
+	// calloutColors are the ANSI colors of callout types.
+	calloutColors = map[string]lipgloss.Color{
+		"note":      "12",
+		"tip":       "10",
+		"important": "13",
+		"warning":   "11",
+		"caution":   "9",
+	}
+)
+
+// preview renders an entry for the terminal: metadata, then the body with
+// headings styled and fenced code highlighted. Code blocks are numbered as
+// the copy key expects them.
+func preview(e *entry.Entry, style string) string {
+	var b strings.Builder
+	b.WriteString(titleStyle.Render(e.Meta.Title) + "\n")
+	meta := e.Path
+	if d := e.Created(); !d.IsZero() {
+		meta += "  " + d.Format(entry.DateLayout)
+	}
+	if len(e.Meta.Tags) > 0 {
+		meta += "  #" + strings.Join(e.Meta.Tags, " #")
+	}
+	b.WriteString(metaStyle.Render(meta) + "\n\n")
+
+	lines := strings.Split(string(e.Body), "\n")
+	blocks := entry.CodeBlocks(e.Body)
+	next := 0
+	title := e.Meta.Title
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSuffix(lines[i], "\r")
+		if next < len(blocks) && i+1 == blocks[next].Line {
+			cb := blocks[next]
+			label := "[" + strconv.Itoa(cb.Index+1) + "]"
+			if cb.Lang != "" {
+				label += " " + cb.Lang
+			}
+			b.WriteString(fenceStyle.Render("── "+label) + "\n")
+			var code strings.Builder
+			lang := cb.Lang
+			if lang == "" {
+				lang = "text"
+			}
+			if err := quick.Highlight(&code, cb.Code, lang, "terminal256", style); err != nil {
+				code.Reset()
+				code.WriteString(cb.Code)
+			}
+			b.WriteString(strings.TrimRight(code.String(), "\n") + "\n")
+			b.WriteString(fenceStyle.Render("──") + "\n")
+			i = cb.EndLine - 1
+			next++
+			continue
+		}
+		if typ, head, ok := calloutStart(line); ok {
+			var body []string
+			for i+1 < len(lines) && strings.HasPrefix(strings.TrimLeft(lines[i+1], " "), ">") {
+				i++
+				body = append(body, quoted(lines[i]))
+			}
+			writeCallout(&b, typ, head, body)
+			continue
+		}
+		switch {
+		case strings.HasPrefix(line, "# ") && strings.TrimSpace(line[2:]) == title:
+			// Shown above already.
+		case strings.HasPrefix(line, "#"):
+			b.WriteString(headingStyle.Render(line) + "\n")
+		default:
+			b.WriteString(line + "\n")
+		}
+	}
+	return b.String()
+}
+
+// calloutStart reports whether line opens a callout, "> [!TYPE] title",
+// returning its type and title.
+func calloutStart(line string) (typ, title string, ok bool) {
+	rest := strings.TrimLeft(line, " ")
+	if !strings.HasPrefix(rest, ">") {
+		return "", "", false
+	}
+	m := render.CalloutRE.FindStringSubmatch(quoted(rest))
+	if m == nil {
+		return "", "", false
+	}
+	typ = strings.ToLower(m[1])
+	name, known := render.CalloutTypes[typ]
+	if !known {
+		return "", "", false
+	}
+	if title = strings.TrimSpace(m[2]); title == "" {
+		title = name
+	}
+	return typ, title, true
+}
+
+// quoted strips the blockquote marker from line.
+func quoted(line string) string {
+	line = strings.TrimSuffix(strings.TrimLeft(line, " "), "\r")
+	line = strings.TrimPrefix(line, ">")
+	return strings.TrimPrefix(line, " ")
+}
+
+// writeCallout writes a callout as its lines behind a bar in the color of
+// its type, under the title.
+func writeCallout(b *strings.Builder, typ, title string, body []string) {
+	color := lipgloss.NewStyle().Foreground(calloutColors[typ])
+	bar := color.Render("┃ ")
+	b.WriteString(bar + color.Bold(true).Render(title) + "\n")
+	for _, l := range body {
+		b.WriteString(bar + l + "\n")
+	}
+}
@@ -0,0 +1,510 @@
+// Package tui implements an interactive full-screen browser for a notes
+// tree: a filterable entry list beside a highlighted preview.
+package tui
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/canhta/til/go/internal/clipboard"
+	"github.com/canhta/til/go/internal/editor"
+	"github.com/canhta/til/go/internal/notes"
+	"github.com/canhta/til/go/internal/tags"
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+// Options configures the browser.
+type Options struct {
+	// Style is the chroma style for code in the preview.
+	Style string
+}
+
+// Run opens the browser on tree and blocks until the user quits.
+func Run(tree *notes.Tree, opts Options) error {
+	m, err := newModel(tree, opts)
+	if err != nil {
+		return err
+	}
+	_, err = tea.NewProgram(m, tea.WithAltScreen(), tea.WithMouseCellMotion()).Run()
+	return err
+}
+
+type mode int
+
+const (
+	browsing mode = iota
+	filtering
+	editingTags
+	confirmingDelete
+	choosingBlock
+)
+
+const help = "↑/↓ move · / filter · e edit · t tags · d delete · y copy snippet · pgup/pgdn scroll · q quit"
+
+var (
+	selectedStyle = lipgloss.NewStyle().Reverse(true)
+	statusStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("8"))
+	errorStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("9"))
+	paneStyle     = lipgloss.NewStyle().Border(lipgloss.NormalBorder(), false, true, false, false).BorderForeground(lipgloss.Color("8"))
+)
+
+type model struct {
+	tree  *notes.Tree
+	opts  Options
+	allow tags.Allowlist
+
+	entries []*entry.Entry
+	// haystack holds the lowercased searchable text of each entry.
+	haystack []string
+	// visible indexes the entries matching the filter.
+	visible []int
+	cursor  int
+	top     int
+
+	mode    mode
+	filter  textinput.Model
+	input   textinput.Model
+	preview viewport.Model
+	width   int
+	height  int
+	status  string
+	failed  bool
+}
+
+func newModel(tree *notes.Tree, opts Options) (*model, error) {
+	if opts.Style == "" {
+		opts.Style = "monokai"
+	}
+	entries, err := tree.Entries()
+	if err != nil {
+		return nil, err
+	}
+	allow, err := tags.LoadAllowlist(tree)
+	if err != nil {
+		return nil, err
+	}
+	filter := textinput.New()
+	filter.Prompt = "/"
+	filter.Placeholder = "filter by title, tag, path or text"
+	input := textinput.New()
+	m := &model{tree: tree, opts: opts, allow: allow, filter: filter, input: input}
+	m.setEntries(entries)
+	return m, nil
+}
+
+func (m *model) setEntries(entries []*entry.Entry) {
+	m.entries = entries
+	m.haystack = make([]string, len(entries))
+	for i, e := range entries {
+		m.haystack[i] = strings.ToLower(strings.Join([]string{e.Meta.Title, e.Path, strings.Join(e.Meta.Tags, " "), string(e.Body)}, "\n"))
+	}
+	m.applyFilter()
+}
+
+// applyFilter keeps the entries containing every word of the filter.
+func (m *model) applyFilter() {
+	words := strings.Fields(strings.ToLower(m.filter.Value()))
+	m.visible = m.visible[:0]
+	for i, h := range m.haystack {
+		ok := true
+		for _, w := range words {
+			if !strings.Contains(h, w) {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			m.visible = append(m.visible, i)
+		}
+	}
+	m.cursor = min(m.cursor, max(0, len(m.visible)-1))
+	m.refreshPreview()
+}
+
+func (m *model) current() *entry.Entry {
+	if len(m.visible) == 0 {
+		return nil
+	}
+	return m.entries[m.visible[m.cursor]]
+}
+
+func (m *model) refreshPreview() {
+	e := m.current()
+	if e == nil {
+		m.preview.SetContent("No matching entries.")
+		return
+	}
+	m.preview.SetContent(lipgloss.NewStyle().Width(m.preview.Width).Render(preview(e, m.opts.Style)))
+	m.preview.GotoTop()
+}
+
+func (m *model) setStatus(err error, format string, args ...any) {
+	m.failed = err != nil
+	if err != nil {
+		m.status = err.Error()
+		return
+	}
+	m.status = fmt.Sprintf(format, args...)
+}
+
+func (m *model) Init() tea.Cmd {
+	return nil
+}
+
+// editedMsg reports that the editor exited.
+type editedMsg struct {
+	path string
+	// before is the content the entry had, to write the edited one
+	// through the store, which runs save hooks, when it changed.
+	before []byte
+	err    error
+}
+
+func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+		m.layout()
+		m.refreshPreview()
+		return m, nil
+	case editedMsg:
+		if msg.err == nil {
+			var data []byte
+			if data, msg.err = m.tree.Read(msg.path); msg.err == nil && !bytes.Equal(data, msg.before) {
+				msg.err = m.tree.Write(msg.path, data)
+			}
+		}
+		if msg.err != nil {
+			m.setStatus(msg.err, "")
+			return m, nil
+		}
+		m.reload(msg.path)
+		m.setStatus(nil, "saved %s", msg.path)
+		return m, nil
+	case tea.MouseMsg:
+		var cmd tea.Cmd
+		m.preview, cmd = m.preview.Update(msg)
+		return m, cmd
+	case tea.KeyMsg:
+		if msg.String() == "ctrl+c" {
+			return m, tea.Quit
+		}
+		switch m.mode {
+		case filtering:
+			return m.updateFilter(msg)
+		case editingTags:
+			return m.updateTags(msg)
+		case confirmingDelete:
+			return m.updateDelete(msg)
+		case choosingBlock:
+			return m.updateBlock(msg)
+		}
+		return m.updateBrowse(msg)
+	}
+	return m, nil
+}
+
+func (m *model) updateBrowse(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	e := m.current()
+	m.status = ""
+	switch msg.String() {
+	case "q":
+		return m, tea.Quit
+	case "up", "k":
+		m.move(-1)
+	case "down", "j":
+		m.move(1)
+	case "home", "g":
+		m.move(-len(m.visible))
+	case "end", "G":
+		m.move(len(m.visible))
+	case "/":
+		m.mode = filtering
+		return m, m.filter.Focus()
+	case "esc":
+		if m.filter.Value() != "" {
+			m.filter.SetValue("")
+			m.applyFilter()
+		}
+	case "pgup", "ctrl+u":
+		m.preview.HalfPageUp()
+	case "pgdown", "ctrl+d", " ":
+		m.preview.HalfPageDown()
+	case "e", "enter":
+		if e == nil {
+			break
+		}
+		file, ok := m.tree.File(e.Path)
+		if !ok {
+			m.setStatus(fmt.Errorf("%s is not a local file; use til edit", e.Path), "")
+			break
+		}
+		before, err := m.tree.Read(e.Path)
+		if err != nil {
+			m.setStatus(err, "")
+			break
+		}
+		argv := append(editor.Command(), file)
+		path := e.Path
+		return m, tea.ExecProcess(exec.Command(argv[0], argv[1:]...), func(err error) tea.Msg {
+			return editedMsg{path: path, before: before, err: err}
+		})
+	case "t":
+		if e == nil {
+			break
+		}
+		m.mode = editingTags
+		m.input.Prompt = "tags: "
+		m.input.SetValue(strings.Join(e.Meta.Tags, ", "))
+		m.input.CursorEnd()
+		return m, m.input.Focus()
+	case "d":
+		if e != nil {
+			m.mode = confirmingDelete
+		}
+	case "y":
+		if e == nil {
+			break
+		}
+		blocks := entry.CodeBlocks(e.Body)
+		switch len(blocks) {
+		case 0:
+			m.setStatus(nil, "no code blocks in %s", e.Path)
+		case 1:
+			m.copyBlock(blocks[0])
+		default:
+			m.mode = choosingBlock
+			m.input.Prompt = fmt.Sprintf("copy block (1-%d): ", len(blocks))
+			m.input.SetValue("")
+			return m, m.input.Focus()
+		}
+	}
+	return m, nil
+}
+
+func (m *model) updateFilter(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "enter", "esc", "up", "down":
+		if msg.String() == "esc" {
+			m.filter.SetValue("")
+			m.applyFilter()
+		}
+		m.mode = browsing
+		m.filter.Blur()
+		return m, nil
+	}
+	var cmd tea.Cmd
+	m.filter, cmd = m.filter.Update(msg)
+	m.applyFilter()
+	return m, cmd
+}
+
+func (m *model) updateTags(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		m.mode = browsing
+		m.input.Blur()
+		return m, nil
+	case "enter":
+		m.mode = browsing
+		m.input.Blur()
+		m.saveTags(m.current(), splitTags(m.input.Value()))
+		return m, nil
+	}
+	var cmd tea.Cmd
+	m.input, cmd = m.input.Update(msg)
+	return m, cmd
+}
+
+func (m *model) updateDelete(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	m.mode = browsing
+	e := m.current()
+	if msg.String() != "y" || e == nil {
+		m.setStatus(nil, "")
+		return m, nil
+	}
+	if err := m.tree.Remove(e.Path); err != nil {
+		m.setStatus(err, "")
+		return m, nil
+	}
+	i := m.visible[m.cursor]
+	entries := slices.Delete(slices.Clone(m.entries), i, i+1)
+	m.setEntries(entries)
+	m.setStatus(nil, "deleted %s", e.Path)
+	return m, nil
+}
+
+func (m *model) updateBlock(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		m.mode = browsing
+		m.input.Blur()
+		return m, nil
+	case "enter":
+		m.mode = browsing
+		m.input.Blur()
+		blocks := entry.CodeBlocks(m.current().Body)
+		n, err := strconv.Atoi(strings.TrimSpace(m.input.Value()))
+		if err != nil || n < 1 || n > len(blocks) {
+			m.setStatus(fmt.Errorf("no block %q", m.input.Value()), "")
+			return m, nil
+		}
+		m.copyBlock(blocks[n-1])
+		return m, nil
+	}
+	var cmd tea.Cmd
+	m.input, cmd = m.input.Update(msg)
+	return m, cmd
+}
+
+func (m *model) copyBlock(b entry.CodeBlock) {
+	if err := clipboard.Copy(b.Code); err != nil {
+		m.setStatus(err, "")
+		return
+	}
+	m.setStatus(nil, "copied block %d", b.Index+1)
+}
+
+func (m *model) saveTags(e *entry.Entry, next []string) {
+	if e == nil || slices.Equal(next, e.Meta.Tags) {
+		return
+	}
+	if u := m.allow.Unknown(next); len(u) > 0 {
+		m.setStatus(fmt.Errorf("tags not in the allowlist: %s", strings.Join(u, ", ")), "")
+		return
+	}
+	data, err := m.tree.Read(e.Path)
+	if err == nil {
+		data, err = entry.Rewrite(data, func(f *entry.Front) error {
+			return f.Set("tags", next)
+		})
+	}
+	if err == nil {
+		err = m.tree.Write(e.Path, data)
+	}
+	if err != nil {
+		m.setStatus(fmt.Errorf("%s: %w", e.Path, err), "")
+		return
+	}
+	m.reload(e.Path)
+	m.setStatus(nil, "updated tags of %s", e.Path)
+}
+
+// reload re-reads the entry at p after it changed on disk.
+func (m *model) reload(p string) {
+	e, err := m.tree.Load(p)
+	if err != nil {
+		m.setStatus(err, "")
+		return
+	}
+	entries := slices.Clone(m.entries)
+	for i := range entries {
+		if entries[i].Path == p {
+			entries[i] = e
+		}
+	}
+	m.setEntries(entries)
+}
+
+func splitTags(s string) []string {
+	var out []string
+	for _, t := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' }) {
+		if t = strings.TrimSpace(t); t != "" && !slices.Contains(out, t) {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+func (m *model) move(delta int) {
+	if len(m.visible) == 0 {
+		return
+	}
+	m.cursor = max(0, min(len(m.visible)-1, m.cursor+delta))
+	m.refreshPreview()
+}
+
+func (m *model) listWidth() int {
+	return max(24, min(50, m.width*2/5))
+}
+
+// bodyHeight is the height of the panes, leaving room for the filter line
+// and the status line.
+func (m *model) bodyHeight() int {
+	return max(1, m.height-2)
+}
+
+func (m *model) layout() {
+	m.preview.Width = max(10, m.width-m.listWidth()-2)
+	m.preview.Height = m.bodyHeight()
+	m.filter.Width = m.width - 2
+	m.input.Width = m.width - 8
+}
+
+func (m *model) View() string {
+	if m.width == 0 {
+		return ""
+	}
+	h := m.bodyHeight()
+	if m.cursor < m.top {
+		m.top = m.cursor
+	} else if m.cursor >= m.top+h {
+		m.top = m.cursor - h + 1
+	}
+	lw := m.listWidth()
+	var rows []string
+	for i := m.top; i < len(m.visible) && i < m.top+h; i++ {
+		e := m.entries[m.visible[i]]
+		row := truncate(e.Meta.Title, lw-1)
+		if i == m.cursor {
+			row = selectedStyle.Render(fmt.Sprintf("%-*s", lw-1, row))
+		}
+		rows = append(rows, row)
+	}
+	list := paneStyle.Width(lw).Height(h).Render(strings.Join(rows, "\n"))
+	body := lipgloss.JoinHorizontal(lipgloss.Top, list, " ", m.preview.View())
+
+	var top string
+	if m.mode == filtering || m.filter.Value() != "" {
+		top = m.filter.View()
+	} else {
+		top = statusStyle.Render(fmt.Sprintf("%d entries", len(m.visible)))
+	}
+	return top + "\n" + body + "\n" + m.statusLine()
+}
+
+func (m *model) statusLine() string {
+	switch m.mode {
+	case editingTags, choosingBlock:
+		return m.input.View()
+	case confirmingDelete:
+		return errorStyle.Render(fmt.Sprintf("delete %s? (y/N)", m.current().Path))
+	}
+	if m.status != "" {
+		if m.failed {
+			return errorStyle.Render(m.status)
+		}
+		return statusStyle.Render(m.status)
+	}
+	return statusStyle.Render(truncate(help, m.width))
+}
+
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if n <= 0 || len(r) <= n {
+		return s
+	}
+	if n == 1 {
+		return "…"
+	}
+	return string(r[:n-1]) + "…"
+}
@@ -0,0 +1,197 @@
+package tui
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/canhta/til/go/internal/notes"
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+func newTree(t *testing.T, files map[string]string) *notes.Tree {
+	t.Helper()
+	tree := notes.Open(t.TempDir())
+	for p, data := range files {
+		if err := tree.Write(p, []byte(data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return tree
+}
+
+func key(s string) tea.KeyMsg {
+	switch s {
+	case "enter":
+		return tea.KeyMsg{Type: tea.KeyEnter}
+	case "esc":
+		return tea.KeyMsg{Type: tea.KeyEsc}
+	case "down":
+		return tea.KeyMsg{Type: tea.KeyDown}
+	}
+	return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(s)}
+}
+
+// press sends the keys to m, typing runes one at a time.
+func press(m *model, keys ...string) {
+	for _, k := range keys {
+		if msg := key(k); msg.Type == tea.KeyRunes && len(msg.Runes) > 1 {
+			for _, r := range msg.Runes {
+				m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+			}
+			continue
+		}
+		m.Update(key(k))
+	}
+}
+
+func titles(m *model) []string {
+	var out []string
+	for _, i := range m.visible {
+		out = append(out, m.entries[i].Meta.Title)
+	}
+	return out
+}
+
+var tuiFiles = map[string]string{
+	"git/rebase.md": "---\ntitle: Rebase onto\ntags: [git]\n---\n\nMove a branch.\n",
+	"go/maps.md":    "---\ntitle: Maps\ntags: [go]\n---\n\nIteration order is random.\n",
+	"go/slices.md":  "---\ntitle: Slices\ntags: [go, arrays]\n---\n\nAppend may reallocate.\n\n```go\ns = append(s, 1)\n```\n",
+}
+
+func TestFilter(t *testing.T) {
+	m, err := newModel(newTree(t, tuiFiles), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Update(tea.WindowSizeMsg{Width: 100, Height: 20})
+	if got := titles(m); !slices.Equal(got, []string{"Rebase onto", "Maps", "Slices"}) {
+		t.Fatalf("entries = %q", got)
+	}
+	press(m, "/", "go ", "random")
+	if got := titles(m); !slices.Equal(got, []string{"Maps"}) {
+		t.Errorf("filtered by tag and text = %q", got)
+	}
+	press(m, "enter")
+	if m.mode != browsing || !strings.Contains(m.View(), "/go random") {
+		t.Errorf("mode %v after enter, view:\n%s", m.mode, m.View())
+	}
+	press(m, "esc")
+	if len(m.visible) != 3 {
+		t.Errorf("esc kept the filter: %q", titles(m))
+	}
+	press(m, "down", "j")
+	if e := m.current(); e.Path != "go/slices.md" || !strings.Contains(m.preview.View(), "reallocate") {
+		t.Errorf("current = %s, preview:\n%s", e.Path, m.preview.View())
+	}
+	press(m, "j")
+	if m.current().Path != "go/slices.md" {
+		t.Errorf("moved past the end to %s", m.current().Path)
+	}
+	press(m, "/", "nothing matches")
+	if m.current() != nil || !strings.Contains(m.preview.View(), "No matching entries.") {
+		t.Errorf("preview without matches:\n%s", m.preview.View())
+	}
+}
+
+func TestEditTags(t *testing.T) {
+	tree := newTree(t, tuiFiles)
+	m, err := newModel(tree, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Update(tea.WindowSizeMsg{Width: 100, Height: 20})
+	press(m, "j", "t")
+	if m.mode != editingTags || m.input.Value() != "go" {
+		t.Fatalf("mode %v, input %q", m.mode, m.input.Value())
+	}
+	press(m, ", maps, go,", "enter")
+	data, _ := tree.Read("go/maps.md")
+	if !strings.Contains(string(data), "tags: [go, maps]\n") || m.status != "updated tags of go/maps.md" {
+		t.Errorf("go/maps.md = %q, status %q", data, m.status)
+	}
+	if e := m.current(); !slices.Equal(e.Meta.Tags, []string{"go", "maps"}) {
+		t.Errorf("tags of the reloaded entry = %q", e.Meta.Tags)
+	}
+
+	if err := os.WriteFile(tree.StatePath("tags.txt"), []byte("go\nmaps\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if m, err = newModel(tree, Options{}); err != nil {
+		t.Fatal(err)
+	}
+	press(m, "j", "t", ", hash", "enter")
+	if !m.failed || !strings.Contains(m.status, "not in the allowlist: hash") {
+		t.Errorf("status %q", m.status)
+	}
+	press(m, "t", "x", "esc")
+	if data, _ := tree.Read("go/maps.md"); !strings.Contains(string(data), "tags: [go, maps]\n") {
+		t.Errorf("go/maps.md = %q", data)
+	}
+}
+
+func TestDelete(t *testing.T) {
+	tree := newTree(t, tuiFiles)
+	m, err := newModel(tree, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Update(tea.WindowSizeMsg{Width: 100, Height: 20})
+	press(m, "d", "n")
+	if _, err := tree.Read("git/rebase.md"); err != nil {
+		t.Errorf("declining deleted the entry: %v", err)
+	}
+	press(m, "d")
+	if !strings.Contains(m.View(), "delete git/rebase.md? (y/N)") {
+		t.Errorf("no confirmation:\n%s", m.View())
+	}
+	press(m, "y")
+	if _, err := os.Stat(filepath.Join(tree.Root, "git", "rebase.md")); !os.IsNotExist(err) {
+		t.Errorf("git/rebase.md not deleted: %v", err)
+	}
+	if got := titles(m); !slices.Equal(got, []string{"Maps", "Slices"}) || m.status != "deleted git/rebase.md" {
+		t.Errorf("entries %q, status %q", got, m.status)
+	}
+}
+
+func TestSplitTags(t *testing.T) {
+	if got := splitTags(" go, ,maps,go , data structures"); !slices.Equal(got, []string{"go", "maps", "data structures"}) {
+		t.Errorf("splitTags = %q", got)
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	for _, tt := range []struct {
+		s    string
+		n    int
+		want string
+	}{
+		{"Slices", 10, "Slices"},
+		{"Slices", 6, "Slices"},
+		{"Slices", 4, "Sli…"},
+		{"Lát cắt", 4, "Lát…"},
+		{"Slices", 1, "…"},
+		{"Slices", 0, "Slices"},
+	} {
+		if got := truncate(tt.s, tt.n); got != tt.want {
+			t.Errorf("truncate(%q, %d) = %q, want %q", tt.s, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestPreviewCallouts(t *testing.T) {
+	e, err := entry.Parse("go/slices.md", []byte("---\ntitle: Slices\n---\n\nBefore.\n\n> [!WARNING] Slices alias\n> Appending may write\n> into another slice.\n\n> [!tip]\n>  Indented.\n\n> [!BOGUS] plain\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := preview(e, "monokai")
+	for _, want := range []string{"Before.\n", "┃ Slices alias\n┃ Appending may write\n┃ into another slice.\n", "┃ Tip\n┃  Indented.\n", "> [!BOGUS] plain\n"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("preview lacks %q:\n%s", want, got)
+		}
+	}
+}
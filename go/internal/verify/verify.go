@@ -0,0 +1,196 @@
+// Package verify runs every runnable code block in a notes tree and checks
+// it against its recorded output.
+package verify
+
+import (
+	"context"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/canhta/til/go/internal/notes"
+	"github.com/canhta/til/go/internal/runner"
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+// Status is the verdict for one code block.
+type Status string
+
+const (
+	Pass Status = "pass"
+	// Fail means the block did not build or exited unsuccessfully.
+	Fail Status = "fail"
+	// Mismatch means the block ran but its output differs from the
+	// recorded output block.
+	Mismatch Status = "mismatch"
+)
+
+// Case is the verification of one code block.
+type Case struct {
+	Entry    *entry.Entry
+	Snippet  entry.Snippet
+	Result   *runner.Result
+	Status   Status
+	Expected string
+	Actual   string
+}
+
+// Report summarizes a verification run.
+type Report struct {
+	Cases []*Case
+	// Entries is the number of entries scanned.
+	Entries int
+	// Skipped counts code blocks without a runner or marked {norun} or {bench}.
+	Skipped int
+}
+
+// Count returns the number of cases with status st.
+func (r *Report) Count(st Status) int {
+	n := 0
+	for _, c := range r.Cases {
+		if c.Status == st {
+			n++
+		}
+	}
+	return n
+}
+
+// OK reports whether every case passed.
+func (r *Report) OK() bool {
+	return r.Count(Pass) == len(r.Cases)
+}
+
+// Options configures a run.
+type Options struct {
+	Registry *runner.Registry
+	// Tree, if set, reads the companion programs of entries, verified
+	// after their code blocks.
+	Tree *notes.Tree
+	// Jobs is the number of blocks run concurrently. Defaults to the
+	// number of CPUs.
+	Jobs int
+	// Progress, if set, is called as each case completes.
+	Progress func(*Case)
+}
+
+// Run verifies the code blocks of entries. Cases are reported in entry and
+// block order regardless of completion order.
+func Run(ctx context.Context, entries []*entry.Entry, opts Options) *Report {
+	rep := &Report{Entries: len(entries)}
+	for _, e := range entries {
+		snippets := entry.Snippets(e.Body)
+		if opts.Tree != nil {
+			var err error
+			if snippets, err = opts.Tree.Snippets(e); err != nil {
+				block := entry.CodeBlock{Index: -1, File: e.SourcePath()}
+				rep.Cases = append(rep.Cases, &Case{Entry: e, Snippet: entry.Snippet{Code: block}, Status: Fail,
+					Result: &runner.Result{Block: block, Phase: runner.PhaseSetup, Err: err}})
+			}
+		}
+		for _, s := range snippets {
+			if !opts.Registry.Runnable(s.Code) {
+				rep.Skipped++
+				continue
+			}
+			rep.Cases = append(rep.Cases, &Case{Entry: e, Snippet: s})
+		}
+	}
+
+	jobs := opts.Jobs
+	if jobs <= 0 {
+		jobs = runtime.NumCPU()
+	}
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		work = make(chan *Case)
+	)
+	for i := 0; i < jobs; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for c := range work {
+				if c.Result == nil {
+					check(ctx, opts.Registry, c)
+				}
+				if opts.Progress != nil {
+					mu.Lock()
+					opts.Progress(c)
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	for _, c := range rep.Cases {
+		work <- c
+	}
+	close(work)
+	wg.Wait()
+	return rep
+}
+
+func check(ctx context.Context, reg *runner.Registry, c *Case) {
+	c.Result = reg.Run(ctx, c.Entry, c.Snippet)
+	c.Actual = string(c.Result.Output)
+	switch {
+	case c.Result.Failed():
+		c.Status = Fail
+	case c.Snippet.Output == nil:
+		c.Status = Pass
+	default:
+		c.Expected = c.Snippet.Output.Code
+		if normalize(c.Expected) == normalize(c.Actual) {
+			c.Status = Pass
+		} else {
+			c.Status = Mismatch
+		}
+	}
+}
+
+// normalize ignores trailing whitespace on lines and trailing blank lines.
+func normalize(s string) string {
+	lines := strings.Split(s, "\n")
+	for i, l := range lines {
+		lines[i] = strings.TrimRight(l, " \t\r")
+	}
+	return strings.TrimRight(strings.Join(lines, "\n"), "\n")
+}
+
+// Diff returns a line diff turning expected into actual, as compared: lines
+// only in expected start with "-", lines only in actual with "+", and
+// lines in both with a space.
+func Diff(expected, actual string) string {
+	a := strings.Split(normalize(expected), "\n")
+	b := strings.Split(normalize(actual), "\n")
+	// lcs[i][j] is the length of the longest common subsequence of a[i:]
+	// and b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	var d strings.Builder
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			d.WriteString(" " + a[i] + "\n")
+			i, j = i+1, j+1
+		case j == len(b) || i < len(a) && lcs[i+1][j] >= lcs[i][j+1]:
+			d.WriteString("-" + a[i] + "\n")
+			i++
+		default:
+			d.WriteString("+" + b[j] + "\n")
+			j++
+		}
+	}
+	return d.String()
+}
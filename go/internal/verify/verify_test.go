@@ -0,0 +1,72 @@
+package verify
+
+import (
+	"context"
+	"os/exec"
+	"slices"
+	"testing"
+
+	"github.com/canhta/til/go/internal/runner"
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+func parse(t *testing.T, p, data string) *entry.Entry {
+	t.Helper()
+	e, err := entry.Parse(p, []byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+func TestRun(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not installed")
+	}
+	entries := []*entry.Entry{
+		parse(t, "sh/a.md", "# A\n\n```sh\necho one  \n```\n\n```output\none\n\n```\n\n```sh\necho two\n```\n\n```output\nthree\n```\n"),
+		parse(t, "sh/b.md", "# B\n\n```sh\nexit 1\n```\n\n```sh {norun}\nrm -rf /\n```\n\n```text\nprose\n```\n\n```sh\necho unchecked\n```\n"),
+	}
+	var progress []string
+	rep := Run(context.Background(), entries, Options{
+		Registry: runner.NewRegistry(nil, 0),
+		Jobs:     3,
+		Progress: func(c *Case) { progress = append(progress, c.Entry.Path) },
+	})
+	var got []Status
+	for _, c := range rep.Cases {
+		got = append(got, c.Status)
+	}
+	if want := []Status{Pass, Mismatch, Fail, Pass}; !slices.Equal(got, want) {
+		t.Errorf("statuses = %v, want %v", got, want)
+	}
+	if c := rep.Cases[1]; c.Expected != "three\n" || c.Actual != "two\n" {
+		t.Errorf("mismatch: expected %q, actual %q", c.Expected, c.Actual)
+	}
+	if rep.Entries != 2 || rep.Skipped != 2 || len(progress) != 4 {
+		t.Errorf("entries %d, skipped %d, progress %q", rep.Entries, rep.Skipped, progress)
+	}
+	if rep.OK() || rep.Count(Pass) != 2 || rep.Count(Fail) != 1 || rep.Count(Mismatch) != 1 {
+		t.Errorf("report: ok %v, %d passed", rep.OK(), rep.Count(Pass))
+	}
+	if rep := Run(context.Background(), entries[:1], Options{Registry: runner.NewRegistry(nil, 0)}); rep.OK() {
+		t.Error("OK with a mismatch")
+	}
+	if rep := Run(context.Background(), nil, Options{Registry: runner.NewRegistry(nil, 0)}); !rep.OK() {
+		t.Error("not OK without blocks")
+	}
+}
+
+func TestDiff(t *testing.T) {
+	tests := []struct{ expected, actual, want string }{
+		{"a\nb\n", "a\nb\n", " a\n b\n"},
+		{"a\nb\nc\n", "a\nc\nd\n", " a\n-b\n c\n+d\n"},
+		{"x  \n\n\n", "x\n", " x\n"},
+		{"", "new\n", "-\n+new\n"},
+	}
+	for _, tt := range tests {
+		if got := Diff(tt.expected, tt.actual); got != tt.want {
+			t.Errorf("Diff(%q, %q) = %q, want %q", tt.expected, tt.actual, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+// Package walk splits a code block into steps for a guided tour.
+//
+// Lines holding only a // --- or # --- comment mark the step boundaries.
+// Each step runs as the program made of the code up to its end, so a step
+// sees everything the steps before it declared, and shows what it printed
+// beyond what they did.
+package walk
+
+import "strings"
+
+// Step is a step of a code block.
+type Step struct {
+	// Code holds the step's own lines, from the first that is not blank.
+	Code string
+	// Program is the code of the block up to the end of the step, without
+	// the markers.
+	Program string
+	// Line is the 1-based line of the step's first line within the block.
+	Line int
+}
+
+// IsMarker reports whether line marks a step boundary.
+func IsMarker(line string) bool {
+	switch strings.TrimSpace(line) {
+	case "// ---", "# ---", "-- ---":
+		return true
+	}
+	return false
+}
+
+// Steps splits code at the marker lines. Steps without code are dropped;
+// code without markers is a single step.
+func Steps(code string) []Step {
+	var (
+		steps   []Step
+		program strings.Builder
+		cur     strings.Builder
+		start   = 1
+	)
+	flush := func() {
+		code := cur.String()
+		cur.Reset()
+		if strings.TrimSpace(code) == "" {
+			return
+		}
+		line := start
+		for {
+			i := strings.IndexByte(code, '\n')
+			if i < 0 || strings.TrimSpace(code[:i]) != "" {
+				break
+			}
+			code = code[i+1:]
+			line++
+		}
+		steps = append(steps, Step{Code: code, Program: program.String(), Line: line})
+	}
+	lines := strings.SplitAfter(code, "\n")
+	for i, line := range lines {
+		if IsMarker(line) {
+			flush()
+			start = i + 2
+			continue
+		}
+		cur.WriteString(line)
+		program.WriteString(line)
+	}
+	flush()
+	return steps
+}
+
+// Since returns the part of out not already printed as prev: what follows
+// prev if out starts with it, or all of out otherwise.
+func Since(prev, out string) string {
+	if rest, ok := strings.CutPrefix(out, prev); ok {
+		return rest
+	}
+	return out
+}
@@ -0,0 +1,55 @@
+package walk
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSteps(t *testing.T) {
+	code := "s := []int{1}\n// ---\n\ns = append(s, 2)\nfmt.Println(s)\n  // ---  \n// ---\nfmt.Println(len(s))\n"
+	got := Steps(code)
+	want := []Step{
+		{Code: "s := []int{1}\n", Program: "s := []int{1}\n", Line: 1},
+		{Code: "s = append(s, 2)\nfmt.Println(s)\n", Program: "s := []int{1}\n\ns = append(s, 2)\nfmt.Println(s)\n", Line: 4},
+		{Code: "fmt.Println(len(s))\n", Program: "s := []int{1}\n\ns = append(s, 2)\nfmt.Println(s)\nfmt.Println(len(s))\n", Line: 8},
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("Steps =\n%+v\nwant\n%+v", got, want)
+	}
+
+	if got := Steps("echo hi\n"); len(got) != 1 || got[0].Code != "echo hi\n" || got[0].Line != 1 {
+		t.Errorf("Steps without markers = %+v", got)
+	}
+	if got := Steps("# ---\n\n# ---\n"); len(got) != 0 {
+		t.Errorf("Steps of markers only = %+v", got)
+	}
+}
+
+func TestIsMarker(t *testing.T) {
+	for line, want := range map[string]bool{
+		"// ---":       true,
+		"  # ---\n":    true,
+		"-- ---":       true,
+		"// --- setup": false,
+		"---":          false,
+		"// ----":      false,
+	} {
+		if got := IsMarker(line); got != want {
+			t.Errorf("IsMarker(%q) = %v, want %v", line, got, want)
+		}
+	}
+}
+
+func TestSince(t *testing.T) {
+	tests := []struct{ prev, out, want string }{
+		{"", "a\n", "a\n"},
+		{"a\n", "a\nb\n", "b\n"},
+		{"a\n", "a\n", ""},
+		{"a\n", "changed\n", "changed\n"},
+	}
+	for _, tt := range tests {
+		if got := Since(tt.prev, tt.out); got != tt.want {
+			t.Errorf("Since(%q, %q) = %q, want %q", tt.prev, tt.out, got, tt.want)
+		}
+	}
+}
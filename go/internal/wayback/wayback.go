@@ -0,0 +1,175 @@
+// Package wayback finds and requests snapshots of web pages on the
+// Internet Archive's Wayback Machine, and links entries to them in place
+// of dead links.
+package wayback
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/canhta/til/go/internal/links"
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+const (
+	// AvailableAPI is the availability API, finding the snapshot of a URL
+	// closest to a time.
+	AvailableAPI = "https://archive.org/wayback/available"
+	// SaveAPI is Save Page Now, taking a snapshot of the URL appended to
+	// it.
+	SaveAPI = "https://web.archive.org/save/"
+)
+
+// Client talks to the Wayback Machine.
+type Client struct {
+	// HTTP defaults to a client with a two minute timeout, as saving a
+	// page takes a while.
+	HTTP *http.Client
+	// Available and Save default to AvailableAPI and SaveAPI.
+	Available, Save string
+}
+
+func (c *Client) http() *http.Client {
+	if c.HTTP != nil {
+		return c.HTTP
+	}
+	return &http.Client{Timeout: 2 * time.Minute}
+}
+
+// Snapshot returns the URL of the snapshot of u closest to near, the page
+// as it was when linked to, or "" when the archive has none that loaded.
+func (c *Client) Snapshot(ctx context.Context, u string, near time.Time) (string, error) {
+	api := c.Available
+	if api == "" {
+		api = AvailableAPI
+	}
+	q := url.Values{"url": {u}}
+	if !near.IsZero() {
+		q.Set("timestamp", near.UTC().Format("20060102"))
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, api+"?"+q.Encode(), nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := c.http().Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("wayback availability: %s", resp.Status)
+	}
+	var v struct {
+		Snapshots struct {
+			Closest *struct {
+				Available bool   `json:"available"`
+				URL       string `json:"url"`
+				Status    string `json:"status"`
+			} `json:"closest"`
+		} `json:"archived_snapshots"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&v); err != nil {
+		return "", fmt.Errorf("wayback availability: %w", err)
+	}
+	s := v.Snapshots.Closest
+	if s == nil || !s.Available || s.URL == "" || !strings.HasPrefix(s.Status, "2") && !strings.HasPrefix(s.Status, "3") {
+		return "", nil
+	}
+	return strings.Replace(s.URL, "http://", "https://", 1), nil
+}
+
+// snapshotPath matches the path of a snapshot, /web/<timestamp>/<url>.
+var snapshotPath = regexp.MustCompile(`^/web/\d{14}/`)
+
+// IsSnapshot reports whether u is the URL of a snapshot on the Wayback
+// Machine, as Snapshot and Request return them.
+func IsSnapshot(u string) bool {
+	p, err := url.Parse(u)
+	return err == nil && p.Host == "web.archive.org" && snapshotPath.MatchString(p.Path)
+}
+
+// Request asks the archive to take a snapshot of u now and returns its
+// URL. It fails when the page does not load for the archive either.
+func (c *Client) Request(ctx context.Context, u string) (string, error) {
+	api := c.Save
+	if api == "" {
+		api = SaveAPI
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, api+u, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := c.http().Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("wayback save: %s", resp.Status)
+	}
+	base := resp.Request.URL
+	if loc := resp.Header.Get("Content-Location"); loc != "" {
+		if l, err := base.Parse(loc); err == nil {
+			base = l
+		}
+	}
+	if !snapshotPath.MatchString(base.Path) {
+		return "", errors.New("wayback save: no snapshot taken")
+	}
+	return "https://web.archive.org" + base.Path, nil
+}
+
+// Mode is how entries are linked to snapshots.
+type Mode string
+
+const (
+	// Replace links to the snapshot instead of the dead URL.
+	Replace Mode = "replace"
+	// Append keeps the dead link and follows it with one to the snapshot,
+	// as "[docs](u) ([archived](snapshot))".
+	Append Mode = "append"
+)
+
+// archivedLink starts the link Append adds.
+const archivedLink = " ([archived]("
+
+// Rewrite returns the entry file data with the links to u outside code
+// and frontmatter pointing to snapshot as mode says. Places already
+// followed by an archived link are kept.
+func Rewrite(data []byte, u, snapshot string, mode Mode) []byte {
+	_, body, _ := entry.SplitFrontmatter(data)
+	head := data[:len(data)-len(body)]
+	body = links.RewriteURL(body, u, func(written, rest string) (string, bool) {
+		if strings.HasPrefix(rest, archivedLink) {
+			return "", false
+		}
+		if mode == Append {
+			return written + archivedLink + snapshot + "))", true
+		}
+		// The destination of a markdown link follows any label naming u.
+		i := strings.LastIndex(written, u)
+		return written[:i] + snapshot + written[i+len(u):], true
+	})
+	return append(head[:len(head):len(head)], body...)
+}
+
+// Archived reports whether every place body links to u outside code is
+// followed by a link to a snapshot, as Append leaves it.
+func Archived(body []byte, u string) bool {
+	all, seen := true, false
+	links.RewriteURL(body, u, func(_, rest string) (string, bool) {
+		seen = true
+		all = all && strings.HasPrefix(rest, archivedLink)
+		return "", false
+	})
+	return seen && all
+}
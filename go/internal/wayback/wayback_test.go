@@ -0,0 +1,133 @@
+package wayback
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+func TestSnapshot(t *testing.T) {
+	var query string
+	respond := ""
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.RawQuery
+		io.WriteString(w, respond)
+	}))
+	defer srv.Close()
+	c := &Client{HTTP: srv.Client(), Available: srv.URL}
+	ctx := context.Background()
+
+	respond = `{"archived_snapshots": {"closest": {"available": true, "status": "200", "url": "http://web.archive.org/web/20240601000000/https://go.dev/x", "timestamp": "20240601000000"}}}`
+	s, err := c.Snapshot(ctx, "https://go.dev/x", time.Date(2024, 6, 1, 23, 0, 0, 0, time.FixedZone("", -3*3600)))
+	if err != nil || s != "https://web.archive.org/web/20240601000000/https://go.dev/x" {
+		t.Errorf("Snapshot = %q, %v", s, err)
+	}
+	if query != "timestamp=20240602&url=https%3A%2F%2Fgo.dev%2Fx" {
+		t.Errorf("query = %s", query)
+	}
+	for _, none := range []string{
+		`{"archived_snapshots": {}}`,
+		`{"archived_snapshots": {"closest": {"available": true, "status": "404", "url": "http://web.archive.org/web/1/x"}}}`,
+		`{"archived_snapshots": {"closest": {"available": false, "status": "200", "url": "http://web.archive.org/web/1/x"}}}`,
+	} {
+		respond = none
+		if s, err := c.Snapshot(ctx, "https://go.dev/x", time.Time{}); s != "" || err != nil {
+			t.Errorf("Snapshot of %s = %q, %v", none, s, err)
+		}
+	}
+	if query != "url=https%3A%2F%2Fgo.dev%2Fx" {
+		t.Errorf("query without a time = %s", query)
+	}
+	respond = "<html>"
+	if _, err := c.Snapshot(ctx, "https://go.dev/x", time.Time{}); err == nil {
+		t.Error("Snapshot of a response not JSON succeeded")
+	}
+}
+
+func TestRequest(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/save/https://go.dev/redirect":
+			w.Header().Set("Location", "/web/20240601120000/https://go.dev/redirect")
+			w.WriteHeader(http.StatusFound)
+		case "/save/https://go.dev/located":
+			w.Header().Set("Content-Location", "/web/20240601120001/https://go.dev/located")
+		case "/save/https://go.dev/down":
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer srv.Close()
+	c := &Client{HTTP: srv.Client(), Save: srv.URL + "/save/"}
+	ctx := context.Background()
+	tests := []struct {
+		u, want, err string
+	}{
+		{"https://go.dev/redirect", "https://web.archive.org/web/20240601120000/https://go.dev/redirect", ""},
+		{"https://go.dev/located", "https://web.archive.org/web/20240601120001/https://go.dev/located", ""},
+		{"https://go.dev/down", "", "wayback save: 502 Bad Gateway"},
+		{"https://go.dev/nothing", "", "wayback save: no snapshot taken"},
+	}
+	for _, tt := range tests {
+		s, err := c.Request(ctx, tt.u)
+		if s != tt.want || (err == nil) != (tt.err == "") || err != nil && err.Error() != tt.err {
+			t.Errorf("Request(%s) = %q, %v, want %q, %s", tt.u, s, err, tt.want, tt.err)
+		}
+	}
+}
+
+func TestRewrite(t *testing.T) {
+	const u, snap = "https://go.dev/x", "https://web.archive.org/web/20240601000000/https://go.dev/x"
+	in := "---\nsource: " + u + "\n---\n\nSee [docs](" + u + "), <" + u + "> and " + u + ".\n\n" +
+		"Not `" + u + "` nor " + u + "/y.\n\n```\n" + u + "\n```\n"
+	tests := []struct {
+		mode Mode
+		want string
+	}{
+		{Replace, "---\nsource: " + u + "\n---\n\nSee [docs](" + snap + "), <" + snap + "> and " + snap + ".\n\n" +
+			"Not `" + u + "` nor " + u + "/y.\n\n```\n" + u + "\n```\n"},
+		{Append, "---\nsource: " + u + "\n---\n\nSee [docs](" + u + ") ([archived](" + snap + ")), <" + u + "> ([archived](" + snap + ")) and " + u + " ([archived](" + snap + ")).\n\n" +
+			"Not `" + u + "` nor " + u + "/y.\n\n```\n" + u + "\n```\n"},
+	}
+	for _, tt := range tests {
+		got := string(Rewrite([]byte(in), u, snap, tt.mode))
+		if got != tt.want {
+			t.Errorf("Rewrite %s =\n%s\nwant\n%s", tt.mode, got, tt.want)
+		}
+		// Rewriting again changes nothing more.
+		if tt.mode == Append {
+			if again := string(Rewrite([]byte(got), u, snap, Append)); again != got {
+				t.Errorf("Rewrite again =\n%s", again)
+			}
+			_, body, _ := entry.SplitFrontmatter([]byte(got))
+			if !Archived(body, u) {
+				t.Error("Archived = false after Append")
+			}
+		}
+	}
+	if _, body, _ := entry.SplitFrontmatter([]byte(in)); Archived(body, u) || Archived([]byte("No links.\n"), u) {
+		t.Error("Archived = true without snapshot links")
+	}
+	// A label naming the URL is kept.
+	if got := string(Rewrite([]byte("["+u+"]("+u+")\n"), u, snap, Replace)); got != "["+u+"]("+snap+")\n" {
+		t.Errorf("Rewrite of a link labeled with its URL = %q", got)
+	}
+}
+
+func TestIsSnapshot(t *testing.T) {
+	for u, want := range map[string]bool{
+		"https://web.archive.org/web/20240601000000/https://go.dev/x": true,
+		"http://web.archive.org/web/20240601000000/https://go.dev/x":  true,
+		"https://web.archive.org/save/https://go.dev/x":               false,
+		"https://web.archive.org/web/2024/https://go.dev/x":           false,
+		"https://go.dev/web/20240601000000/x":                         false,
+	} {
+		if got := IsSnapshot(u); got != want {
+			t.Errorf("IsSnapshot(%s) = %v, want %v", u, got, want)
+		}
+	}
+}
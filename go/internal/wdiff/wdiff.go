@@ -0,0 +1,177 @@
+// Package wdiff compares two texts word by word, as git diff --word-diff
+// does, and prints the result with the changed words marked.
+package wdiff
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+
+	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+// Op is the kind of a Chunk.
+type Op int
+
+const (
+	Equal Op = iota
+	Delete
+	Insert
+)
+
+// Chunk is a run of text kept, deleted or inserted.
+type Chunk struct {
+	Op   Op
+	Text string
+}
+
+// Diff returns the chunks turning old into new. Words and the whitespace
+// between them are compared whole, never in part.
+func Diff(old, new string) []Chunk {
+	index := map[string]rune{}
+	var tokens []string
+	encode := func(s string) []rune {
+		var rs []rune
+		for _, t := range split(s) {
+			r, ok := index[t]
+			if !ok {
+				// Each token is one rune to the diff, skipping surrogates,
+				// which do not survive the round trip through a string.
+				r = rune(len(tokens) + 1)
+				if r >= 0xd800 {
+					r += 0x800
+				}
+				index[t] = r
+				tokens = append(tokens, t)
+			}
+			rs = append(rs, r)
+		}
+		return rs
+	}
+	decode := func(s string) string {
+		var b strings.Builder
+		for _, r := range s {
+			if r >= 0xe000 {
+				r -= 0x800
+			}
+			b.WriteString(tokens[r-1])
+		}
+		return b.String()
+	}
+	a, b := encode(old), encode(new)
+	var chunks []Chunk
+	for _, d := range diffmatchpatch.New().DiffMainRunes(a, b, false) {
+		op := Equal
+		switch d.Type {
+		case diffmatchpatch.DiffDelete:
+			op = Delete
+		case diffmatchpatch.DiffInsert:
+			op = Insert
+		}
+		chunks = append(chunks, Chunk{Op: op, Text: decode(d.Text)})
+	}
+	return chunks
+}
+
+// split cuts s into runs of whitespace and of anything else.
+func split(s string) []string {
+	var out []string
+	start, space := 0, false
+	for i, r := range s {
+		sp := unicode.IsSpace(r)
+		if i > 0 && sp != space {
+			out = append(out, s[start:i])
+			start = i
+		}
+		space = sp
+	}
+	if start < len(s) {
+		out = append(out, s[start:])
+	}
+	return out
+}
+
+// Changed reports whether chunks insert or delete anything.
+func Changed(chunks []Chunk) bool {
+	for _, c := range chunks {
+		if c.Op != Equal {
+			return true
+		}
+	}
+	return false
+}
+
+// Write prints chunks, marking deletions [-so-] and insertions {+so+}, or
+// in red and green when color is set. Only the lines with changes are
+// printed, with context lines around them; a negative context prints every
+// line.
+func Write(w io.Writer, chunks []Chunk, context int, color bool) error {
+	// lines holds the text printed for each line and whether it changed.
+	type line struct {
+		text    strings.Builder
+		changed bool
+	}
+	lines := []*line{{}}
+	for _, c := range chunks {
+		open, closing := "", ""
+		switch {
+		case c.Op == Delete && color:
+			open, closing = "\x1b[31m", "\x1b[0m"
+		case c.Op == Delete:
+			open, closing = "[-", "-]"
+		case c.Op == Insert && color:
+			open, closing = "\x1b[32m", "\x1b[0m"
+		case c.Op == Insert:
+			open, closing = "{+", "+}"
+		}
+		first := len(lines) - 1
+		for i, part := range strings.Split(c.Text, "\n") {
+			if i > 0 {
+				lines = append(lines, &line{})
+			}
+			if part == "" {
+				continue
+			}
+			l := lines[len(lines)-1]
+			l.text.WriteString(open + part + closing)
+			l.changed = l.changed || c.Op != Equal
+		}
+		// A deleted or inserted line break changes the lines it joins.
+		if c.Op != Equal && strings.Contains(c.Text, "\n") {
+			lines[first].changed = true
+			lines[len(lines)-1].changed = true
+		}
+	}
+	if n := len(lines); n > 1 && lines[n-1].text.Len() == 0 {
+		lines = lines[:n-1]
+	}
+	show := make([]bool, len(lines))
+	for i, l := range lines {
+		if context < 0 {
+			show[i] = true
+			continue
+		}
+		if l.changed {
+			for j := max(i-context, 0); j <= min(i+context, len(lines)-1); j++ {
+				show[j] = true
+			}
+		}
+	}
+	last := -1
+	for i, l := range lines {
+		if !show[i] {
+			continue
+		}
+		if last >= 0 && i > last+1 || last < 0 && i > 0 {
+			if _, err := fmt.Fprintf(w, "@@ line %d @@\n", i+1); err != nil {
+				return err
+			}
+		}
+		if _, err := fmt.Fprintln(w, l.text.String()); err != nil {
+			return err
+		}
+		last = i
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package wdiff
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	got := Diff("append may copy the array\n", "append always copies the array\n")
+	want := []Chunk{
+		{Equal, "append "},
+		{Delete, "may"},
+		{Insert, "always"},
+		{Equal, " "},
+		{Delete, "copy"},
+		{Insert, "copies"},
+		{Equal, " the array\n"},
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("Diff = %q, want %q", got, want)
+	}
+	// Words are compared whole: "slices" is not "slice" plus "s".
+	if got := Diff("slice", "slices"); !slices.Equal(got, []Chunk{{Delete, "slice"}, {Insert, "slices"}}) {
+		t.Errorf("Diff(slice, slices) = %q", got)
+	}
+	if got := Diff("a b", "a b"); Changed(got) {
+		t.Errorf("Diff of equal texts changed: %q", got)
+	}
+	if !Changed(Diff("a", "a b")) {
+		t.Error("Diff(a, a b) did not change")
+	}
+}
+
+func TestWrite(t *testing.T) {
+	var old, new strings.Builder
+	for i := 1; i <= 10; i++ {
+		line := "line " + string(rune('0'+i%10)) + "\n"
+		old.WriteString(line)
+		if i == 5 {
+			line = "line five\n"
+		}
+		new.WriteString(line)
+	}
+	old.WriteString("the end\n")
+	new.WriteString("the very end\n")
+	chunks := Diff(old.String(), new.String())
+
+	tests := []struct {
+		context int
+		color   bool
+		want    string
+	}{
+		{0, false, "@@ line 5 @@\nline [-5-]{+five+}\n@@ line 11 @@\nthe{+ very+} end\n"},
+		{1, false, "@@ line 4 @@\nline 4\nline [-5-]{+five+}\nline 6\n@@ line 10 @@\nline 0\nthe{+ very+} end\n"},
+		{0, true, "@@ line 5 @@\nline \x1b[31m5\x1b[0m\x1b[32mfive\x1b[0m\n@@ line 11 @@\nthe\x1b[32m very\x1b[0m end\n"},
+	}
+	for _, tt := range tests {
+		var b strings.Builder
+		if err := Write(&b, chunks, tt.context, tt.color); err != nil {
+			t.Fatal(err)
+		}
+		if b.String() != tt.want {
+			t.Errorf("Write(context %d, color %v) =\n%q\nwant\n%q", tt.context, tt.color, b.String(), tt.want)
+		}
+	}
+
+	var b strings.Builder
+	if err := Write(&b, chunks, -1, false); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Count(b.String(), "\n"); got != 11 || strings.Contains(b.String(), "@@") {
+		t.Errorf("Write(context -1) =\n%s", b.String())
+	}
+}
+
+func TestWriteLineBreaks(t *testing.T) {
+	var b strings.Builder
+	if err := Write(&b, Diff("one\ntwo\nthree\n", "one two\nthree\n"), 0, false); err != nil {
+		t.Fatal(err)
+	}
+	if want := "one\n{+ +}two\n"; b.String() != want {
+		t.Errorf("joined lines = %q, want %q", b.String(), want)
+	}
+}
@@ -0,0 +1,236 @@
+package webmention
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/canhta/til/go/internal/fsutil"
+	"github.com/canhta/til/go/internal/notes"
+)
+
+// DefaultAPI is the webmention.io API listing the mentions of a domain.
+const DefaultAPI = "https://webmention.io/api/mentions.jf2"
+
+// ReceivedFile is the file in the state directory caching the mentions
+// last fetched, for builds that do not fetch them.
+const ReceivedFile = "webmentions.json"
+
+// Kinds of mention, after the property of the source linking to the
+// target.
+const (
+	Reply    = "reply"
+	Like     = "like"
+	Repost   = "repost"
+	Bookmark = "bookmark"
+	Mention  = "mention"
+)
+
+// kinds maps the wm-property of webmention.io to a kind.
+var kinds = map[string]string{
+	"in-reply-to": Reply,
+	"like-of":     Like,
+	"repost-of":   Repost,
+	"bookmark-of": Bookmark,
+	"mention-of":  Mention,
+}
+
+// Received is a mention of a page of the site.
+type Received struct {
+	ID     string `json:"id"`
+	Kind   string `json:"kind"`
+	Target string `json:"target"`
+	// Source is the page mentioning the target.
+	Source    string    `json:"source"`
+	Author    Author    `json:"author"`
+	Published time.Time `json:"published"`
+	// Text is the content of replies and mentions, as plain text.
+	Text string `json:"text,omitempty"`
+}
+
+// Author is who wrote a mention.
+type Author struct {
+	Name  string `json:"name"`
+	URL   string `json:"url,omitempty"`
+	Photo string `json:"photo,omitempty"`
+}
+
+// Reaction reports whether m is a like, repost or bookmark, shown as a
+// face rather than a comment.
+func (m Received) Reaction() bool { return m.Kind == Like || m.Kind == Repost || m.Kind == Bookmark }
+
+// perPage is how many mentions are asked for at a time.
+const perPage = 100
+
+// maxMentions caps the mentions fetched, in case a server pages forever.
+const maxMentions = 100 * perPage
+
+// jf2 is the feed of mentions webmention.io answers with.
+type jf2 struct {
+	Children []struct {
+		ID        json.Number `json:"wm-id"`
+		Property  string      `json:"wm-property"`
+		Source    string      `json:"wm-source"`
+		Target    string      `json:"wm-target"`
+		URL       string      `json:"url"`
+		Published string      `json:"published"`
+		Received  string      `json:"wm-received"`
+		Author    Author      `json:"author"`
+		Content   struct {
+			Text string `json:"text"`
+		} `json:"content"`
+	} `json:"children"`
+}
+
+// Fetch lists the mentions of the pages of domain from the jf2 API at api,
+// such as DefaultAPI, authenticating with token, newest first.
+func (c *Client) Fetch(ctx context.Context, api, domain, token string) ([]Received, error) {
+	u, err := url.Parse(api)
+	if err != nil {
+		return nil, fmt.Errorf("webmention API %q: %w", api, err)
+	}
+	var out []Received
+	for page := 0; len(out) < maxMentions; page++ {
+		q := u.Query()
+		q.Set("domain", domain)
+		q.Set("per-page", strconv.Itoa(perPage))
+		q.Set("page", strconv.Itoa(page))
+		if token != "" {
+			q.Set("token", token)
+		}
+		u.RawQuery = q.Encode()
+		feed, err := c.fetchPage(ctx, u.String())
+		if err != nil {
+			// The token is in the URL; keep it out of the error.
+			return nil, fmt.Errorf("webmention API %s: %w", api, err)
+		}
+		for _, ch := range feed.Children {
+			m := Received{
+				ID:     ch.ID.String(),
+				Kind:   kinds[ch.Property],
+				Target: ch.Target,
+				Source: ch.URL,
+				Author: ch.Author,
+				Text:   strings.TrimSpace(ch.Content.Text),
+			}
+			if m.Kind == "" {
+				m.Kind = Mention
+			}
+			if m.Source == "" {
+				m.Source = ch.Source
+			}
+			for _, t := range []string{ch.Published, ch.Received} {
+				if p, err := parseTime(t); err == nil {
+					m.Published = p
+					break
+				}
+			}
+			if m.Author.Name == "" {
+				m.Author.Name = hostOf(m.Source)
+			}
+			out = append(out, m)
+		}
+		if len(feed.Children) < perPage {
+			break
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Published.After(out[j].Published) })
+	return out, nil
+}
+
+func (c *Client) fetchPage(ctx context.Context, u string) (*jf2, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Accept", "application/json")
+	resp, err := c.http().Do(req)
+	if err != nil {
+		var ue *url.Error
+		if errors.As(err, &ue) {
+			err = ue.Err
+		}
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return nil, errors.New(resp.Status)
+	}
+	var feed jf2
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 16<<20)).Decode(&feed); err != nil {
+		return nil, err
+	}
+	return &feed, nil
+}
+
+func parseTime(s string) (time.Time, error) {
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04:05-0700", "2006-01-02"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q", s)
+}
+
+func hostOf(s string) string {
+	if u, err := url.Parse(s); err == nil && u.Host != "" {
+		return strings.TrimPrefix(u.Host, "www.")
+	}
+	return s
+}
+
+// ByTarget groups mentions by the page they mention, keyed by Key of its
+// URL.
+func ByTarget(ms []Received) map[string][]Received {
+	out := map[string][]Received{}
+	for _, m := range ms {
+		k := Key(m.Target)
+		out[k] = append(out[k], m)
+	}
+	return out
+}
+
+// Key normalizes a page URL so that mentions of it match however the
+// source wrote it: without fragment, query, trailing slash or www.
+func Key(s string) string {
+	u, err := url.Parse(s)
+	if err != nil {
+		return s
+	}
+	return strings.TrimPrefix(strings.ToLower(u.Host), "www.") + strings.TrimSuffix(u.Path, "/")
+}
+
+// LoadReceived reads the mentions cached in tree's state directory.
+func LoadReceived(tree *notes.Tree) ([]Received, error) {
+	data, err := os.ReadFile(tree.StatePath(ReceivedFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var ms []Received
+	if err := json.Unmarshal(data, &ms); err != nil {
+		return nil, fmt.Errorf("%s: %w", ReceivedFile, err)
+	}
+	return ms, nil
+}
+
+// SaveReceived caches ms in tree's state directory.
+func SaveReceived(tree *notes.Tree, ms []Received) error {
+	data, err := json.MarshalIndent(ms, "", "  ")
+	if err != nil {
+		return err
+	}
+	return fsutil.WriteFile(tree.StatePath(ReceivedFile), append(data, '\n'), 0o644)
+}
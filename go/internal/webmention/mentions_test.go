@@ -0,0 +1,141 @@
+package webmention
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/canhta/til/go/internal/notes"
+)
+
+func TestFetch(t *testing.T) {
+	var pages []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("domain") != "til.example" || q.Get("token") != "tok" || q.Get("per-page") != "100" {
+			t.Errorf("query = %s", r.URL.RawQuery)
+		}
+		pages = append(pages, q.Get("page"))
+		w.Header().Set("Content-Type", "application/json")
+		if q.Get("page") == "0" {
+			// A full page, so the next one is asked for.
+			var ch []string
+			for i := range perPage {
+				ch = append(ch, fmt.Sprintf(`{"wm-id":%d,"wm-property":"like-of","wm-source":"https://a.example/%d","wm-target":"https://til.example/go/slices/","published":"2024-01-0%dT10:00:00Z","author":{"name":"Ann"}}`, 1000+i, i, 1+i%9))
+			}
+			fmt.Fprintf(w, `{"children":[%s]}`, strings.Join(ch, ","))
+			return
+		}
+		w.Write([]byte(`{"children":[
+			{"wm-id":1,"wm-property":"in-reply-to","wm-source":"https://bob.example/r","url":"https://bob.example/replies/1","wm-target":"https://til.example/go/slices/#c","published":"2024-05-01T09:00:00+0200","author":{"name":"Bob","url":"https://bob.example/"},"content":{"text":"  Nice find.\n"}},
+			{"wm-id":2,"wm-property":"rsvp","wm-source":"https://www.carol.example/x","wm-target":"https://til.example/go/maps/","wm-received":"2024-06-01T00:00:00Z"}
+		]}`))
+	}))
+	defer srv.Close()
+
+	var c Client
+	ms, err := c.Fetch(context.Background(), srv.URL+"/api/mentions.jf2", "til.example", "tok")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(pages, []string{"0", "1"}) {
+		t.Errorf("pages fetched = %q", pages)
+	}
+	if len(ms) != perPage+2 {
+		t.Fatalf("fetched %d mentions, want %d", len(ms), perPage+2)
+	}
+	want := Received{
+		ID:     "2",
+		Kind:   Mention,
+		Target: "https://til.example/go/maps/",
+		Source: "https://www.carol.example/x",
+		// Named after the source's host when the mention has no author.
+		Author:    Author{Name: "carol.example"},
+		Published: time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC),
+	}
+	if !ms[0].Published.Equal(want.Published) {
+		t.Errorf("newest = %+v", ms[0])
+	}
+	ms[0].Published = want.Published
+	if ms[0] != want {
+		t.Errorf("mention = %+v, want %+v", ms[0], want)
+	}
+	reply := ms[1]
+	if reply.Kind != Reply || reply.Source != "https://bob.example/replies/1" || reply.Text != "Nice find." ||
+		reply.Author.URL != "https://bob.example/" || !reply.Published.Equal(time.Date(2024, 5, 1, 7, 0, 0, 0, time.UTC)) {
+		t.Errorf("reply = %+v", reply)
+	}
+	if reply.Reaction() || !ms[2].Reaction() || ms[2].Kind != Like {
+		t.Errorf("Reaction of %s, %s = %v, %v", reply.Kind, ms[2].Kind, reply.Reaction(), ms[2].Reaction())
+	}
+	for i := 3; i < len(ms); i++ {
+		if ms[i].Published.After(ms[i-1].Published) {
+			t.Fatalf("mentions not newest first at %d", i)
+		}
+	}
+}
+
+func TestFetchError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "bad token", http.StatusForbidden)
+	}))
+	defer srv.Close()
+	var c Client
+	_, err := c.Fetch(context.Background(), srv.URL, "til.example", "secret-token")
+	if err == nil || !strings.Contains(err.Error(), "403") {
+		t.Fatalf("Fetch = %v, want the status", err)
+	}
+	if strings.Contains(err.Error(), "secret-token") {
+		t.Errorf("error %q shows the token", err)
+	}
+
+	srv.Close()
+	if _, err := c.Fetch(context.Background(), srv.URL+"?x=1", "til.example", "secret-token"); err == nil || strings.Contains(err.Error(), "secret-token") {
+		t.Errorf("Fetch from a closed server = %v", err)
+	}
+}
+
+func TestKey(t *testing.T) {
+	want := Key("https://til.example/go/slices")
+	for _, u := range []string{
+		"https://til.example/go/slices/",
+		"http://www.til.example/go/slices/#comments",
+		"https://TIL.example/go/slices?utm_source=feed",
+	} {
+		if got := Key(u); got != want {
+			t.Errorf("Key(%s) = %q, want %q", u, got, want)
+		}
+	}
+	if Key("https://til.example/go/maps/") == want {
+		t.Error("Key of another page matches")
+	}
+	ms := []Received{
+		{ID: "1", Target: "https://til.example/go/slices/"},
+		{ID: "2", Target: "https://til.example/go/maps/"},
+		{ID: "3", Target: "https://www.til.example/go/slices#x"},
+	}
+	by := ByTarget(ms)
+	if got := by[want]; len(got) != 2 || got[0].ID != "1" || got[1].ID != "3" {
+		t.Errorf("ByTarget = %v", by)
+	}
+}
+
+func TestReceived(t *testing.T) {
+	tree := notes.Open(t.TempDir())
+	if ms, err := LoadReceived(tree); ms != nil || err != nil {
+		t.Fatalf("LoadReceived of a new tree = %v, %v", ms, err)
+	}
+	ms := []Received{{ID: "1", Kind: Reply, Target: "https://til.example/a/", Source: "https://b.example/", Author: Author{Name: "Bob"}, Published: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), Text: "Hi"}}
+	if err := SaveReceived(tree, ms); err != nil {
+		t.Fatal(err)
+	}
+	got, err := LoadReceived(tree)
+	if err != nil || !slices.Equal(got, ms) {
+		t.Errorf("LoadReceived = %v, %v, want %v", got, err, ms)
+	}
+}
@@ -0,0 +1,254 @@
+// Package webmention sends Webmentions (https://www.w3.org/TR/webmention/)
+// to the pages entries link to, and fetches those the site received from a
+// webmention.io-style API, to show under each entry.
+package webmention
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+
+	"github.com/canhta/til/go/internal/fsutil"
+	"github.com/canhta/til/go/internal/links"
+	"github.com/canhta/til/go/internal/notes"
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+// SentFile is the file in the state directory recording the mentions sent
+// from each page, so that only links new since are mentioned again.
+const SentFile = "webmentions-sent.json"
+
+const userAgent = "til (+https://github.com/canhta/til)"
+
+// maxPage caps how much of a target page is read looking for its endpoint.
+const maxPage = 1 << 20
+
+// Client sends webmentions.
+type Client struct {
+	HTTP *http.Client
+}
+
+func (c *Client) http() *http.Client {
+	if c.HTTP != nil {
+		return c.HTTP
+	}
+	return &http.Client{Timeout: 30 * time.Second}
+}
+
+// ErrNoEndpoint is returned by Discover for pages that accept no
+// webmentions.
+var ErrNoEndpoint = errors.New("no webmention endpoint")
+
+// Discover returns the webmention endpoint of the page at target, from its
+// Link header or else the first <link> or <a> with rel="webmention".
+func (c *Client) Discover(ctx context.Context, target string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml;q=0.9,*/*;q=0.1")
+	resp, err := c.http().Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return "", errors.New(resp.Status)
+	}
+	base := resp.Request.URL
+	for _, h := range resp.Header.Values("Link") {
+		if href, ok := linkHeader(h); ok {
+			return resolve(base, href)
+		}
+	}
+	if mt, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); mt != "text/html" && mt != "application/xhtml+xml" {
+		return "", ErrNoEndpoint
+	}
+	doc, err := html.Parse(io.LimitReader(resp.Body, maxPage))
+	if err != nil {
+		return "", err
+	}
+	href, ok := findRel(doc)
+	if !ok {
+		return "", ErrNoEndpoint
+	}
+	return resolve(base, href)
+}
+
+// linkHeader finds the webmention endpoint in a Link header, which may
+// list several links.
+func linkHeader(h string) (string, bool) {
+	for _, link := range strings.Split(h, ",") {
+		parts := strings.Split(link, ";")
+		href := strings.TrimSpace(parts[0])
+		if !strings.HasPrefix(href, "<") || !strings.HasSuffix(href, ">") {
+			continue
+		}
+		for _, p := range parts[1:] {
+			k, v, _ := strings.Cut(strings.TrimSpace(p), "=")
+			if strings.EqualFold(k, "rel") && hasRel(strings.Trim(v, `"`)) {
+				return href[1 : len(href)-1], true
+			}
+		}
+	}
+	return "", false
+}
+
+func hasRel(rel string) bool {
+	return slices.ContainsFunc(strings.Fields(rel), func(r string) bool { return strings.EqualFold(r, "webmention") })
+}
+
+func findRel(n *html.Node) (string, bool) {
+	if n.Type == html.ElementNode && (n.DataAtom == atom.Link || n.DataAtom == atom.A) {
+		var rel, href string
+		hasHref := false
+		for _, a := range n.Attr {
+			switch a.Key {
+			case "rel":
+				rel = a.Val
+			case "href":
+				href, hasHref = a.Val, true
+			}
+		}
+		if hasHref && hasRel(rel) {
+			return href, true
+		}
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if href, ok := findRel(c); ok {
+			return href, true
+		}
+	}
+	return "", false
+}
+
+// resolve resolves href against base; an empty href is the page itself.
+func resolve(base *url.URL, href string) (string, error) {
+	u, err := base.Parse(href)
+	if err != nil {
+		return "", fmt.Errorf("webmention endpoint %q: %w", href, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", fmt.Errorf("webmention endpoint %q: not a web address", href)
+	}
+	return u.String(), nil
+}
+
+// Send tells the page at target that source links to it, through the
+// page's endpoint.
+func (c *Client) Send(ctx context.Context, source, target string) error {
+	endpoint, err := c.Discover(ctx, target)
+	if err != nil {
+		return err
+	}
+	form := url.Values{"source": {source}, "target": {target}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("User-Agent", userAgent)
+	resp, err := c.http().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s: %s %s", endpoint, resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// Targets returns the web pages e links to, leaving out those on the site
+// at origin.
+func Targets(e *entry.Entry, origin string) []string {
+	var out []string
+	for _, l := range links.URLs(e.Body) {
+		u, err := url.Parse(l.URL)
+		if err != nil || u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+			continue
+		}
+		u.Fragment = ""
+		if t := u.String(); !strings.HasPrefix(t+"/", strings.TrimSuffix(origin, "/")+"/") && !slices.Contains(out, t) {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// Sent records the targets mentioned from each source URL, and when.
+type Sent map[string]map[string]time.Time
+
+// LoadSent reads the mentions sent from tree's site.
+func LoadSent(tree *notes.Tree) (Sent, error) {
+	sent := Sent{}
+	data, err := os.ReadFile(tree.StatePath(SentFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return sent, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &sent); err != nil {
+		return nil, fmt.Errorf("%s: %w", SentFile, err)
+	}
+	return sent, nil
+}
+
+// Save writes the record to tree's state directory.
+func (s Sent) Save(tree *notes.Tree) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return fsutil.WriteFile(tree.StatePath(SentFile), append(data, '\n'), 0o644)
+}
+
+// Pending returns the targets source should mention: those it links to
+// and has not mentioned yet, or all of them with all set, and those it
+// mentioned before but no longer links to, which are told of the change.
+func (s Sent) Pending(source string, targets []string, all bool) []string {
+	var out []string
+	for _, t := range targets {
+		if _, ok := s[source][t]; all || !ok {
+			out = append(out, t)
+		}
+	}
+	var gone []string
+	for t := range s[source] {
+		if !slices.Contains(targets, t) {
+			gone = append(gone, t)
+		}
+	}
+	slices.Sort(gone)
+	return append(out, gone...)
+}
+
+// Record notes that source mentioned target, or no longer links to it.
+func (s Sent) Record(source, target string, linked bool, at time.Time) {
+	if !linked {
+		delete(s[source], target)
+		if len(s[source]) == 0 {
+			delete(s, source)
+		}
+		return
+	}
+	if s[source] == nil {
+		s[source] = map[string]time.Time{}
+	}
+	s[source][target] = at.UTC()
+}
@@ -0,0 +1,215 @@
+package webmention
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/canhta/til/go/internal/notes"
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+func TestLinkHeader(t *testing.T) {
+	tests := []struct {
+		h    string
+		want string
+		ok   bool
+	}{
+		{`<https://ex.com/wm>; rel="webmention"`, "https://ex.com/wm", true},
+		{`<https://ex.com/wm>; rel=webmention`, "https://ex.com/wm", true},
+		{`<https://ex.com/a>; rel="me", </wm>; rel="authorization_endpoint webmention"`, "/wm", true},
+		{`<>; rel="webmention"`, "", true},
+		{`<https://ex.com/a>; rel="webmentions"`, "", false},
+		{`https://ex.com/wm; rel="webmention"`, "", false},
+	}
+	for _, tt := range tests {
+		if got, ok := linkHeader(tt.h); got != tt.want || ok != tt.ok {
+			t.Errorf("linkHeader(%s) = %q, %v, want %q, %v", tt.h, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestDiscover(t *testing.T) {
+	pages := map[string]struct {
+		header, ctype, body string
+	}{
+		"/header":   {`</wm?a=1>; rel="webmention"`, "text/html", `<link rel="webmention" href="/other">`},
+		"/link":     {"", "text/html; charset=utf-8", `<html><head><link rel="stylesheet" href="/s.css"><link rel="webmention" href="wm"></head></html>`},
+		"/a":        {"", "text/html", `<p><a href="https://wm.example/in" rel="nofollow webmention">send</a></p>`},
+		"/empty":    {"", "text/html", `<link rel="webmention" href="">`},
+		"/relative": {"", "text/html", `<link rel="webmention" href="../wm">`},
+		"/nohref":   {"", "text/html", `<a rel="webmention">no</a><link rel="webmention" href="/later">`},
+		"/none":     {"", "text/html", `<link rel="me" href="/me">`},
+		"/text":     {"", "text/plain", `<link rel="webmention" href="/wm">`},
+		"/mailto":   {"", "text/html", `<link rel="webmention" href="mailto:me@example.com">`},
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/moved" {
+			http.Redirect(w, r, "/posts/link", http.StatusFound)
+			return
+		}
+		p, ok := pages[strings.TrimPrefix(r.URL.Path, "/posts")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		if p.header != "" {
+			w.Header().Set("Link", p.header)
+		}
+		w.Header().Set("Content-Type", p.ctype)
+		w.Write([]byte(p.body))
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		path string
+		want string
+		err  error
+	}{
+		{"/header", "/wm?a=1", nil},
+		{"/link", "/wm", nil},
+		{"/posts/link", "/posts/wm", nil},
+		{"/moved", "/posts/wm", nil},
+		{"/a", "https://wm.example/in", nil},
+		{"/empty", "/empty", nil},
+		{"/posts/relative", "/wm", nil},
+		{"/nohref", "/later", nil},
+		{"/none", "", ErrNoEndpoint},
+		{"/text", "", ErrNoEndpoint},
+	}
+	var c Client
+	for _, tt := range tests {
+		got, err := c.Discover(context.Background(), srv.URL+tt.path)
+		if tt.err != nil {
+			if !errors.Is(err, tt.err) {
+				t.Errorf("Discover(%s) = %q, %v, want %v", tt.path, got, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Discover(%s): %v", tt.path, err)
+			continue
+		}
+		want := tt.want
+		if !strings.HasPrefix(want, "https:") {
+			want = srv.URL + want
+		}
+		if got != want {
+			t.Errorf("Discover(%s) = %q, want %q", tt.path, got, want)
+		}
+	}
+	for _, p := range []string{"/mailto", "/missing"} {
+		if got, err := c.Discover(context.Background(), srv.URL+p); err == nil || errors.Is(err, ErrNoEndpoint) {
+			t.Errorf("Discover(%s) = %q, %v, want an error", p, got, err)
+		}
+	}
+}
+
+func TestSend(t *testing.T) {
+	var mu sync.Mutex
+	var got []url.Values
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/post":
+			w.Header().Set("Link", `</wm>; rel="webmention"`)
+		case "/broken":
+			w.Header().Set("Link", `</fail>; rel="webmention"`)
+		case "/wm":
+			if ct := r.Header.Get("Content-Type"); ct != "application/x-www-form-urlencoded" {
+				t.Errorf("Content-Type = %q", ct)
+			}
+			r.ParseForm()
+			mu.Lock()
+			got = append(got, r.PostForm)
+			mu.Unlock()
+			w.WriteHeader(http.StatusAccepted)
+		case "/fail":
+			http.Error(w, "source does not link to target", http.StatusBadRequest)
+		}
+	}))
+	defer srv.Close()
+
+	var c Client
+	ctx := context.Background()
+	if err := c.Send(ctx, "https://til.example/go/slices/", srv.URL+"/post"); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].Get("source") != "https://til.example/go/slices/" || got[0].Get("target") != srv.URL+"/post" {
+		t.Errorf("endpoint got %v", got)
+	}
+	err := c.Send(ctx, "https://til.example/go/slices/", srv.URL+"/broken")
+	if err == nil || !strings.Contains(err.Error(), "400 Bad Request source does not link to target") {
+		t.Errorf("Send to a failing endpoint = %v", err)
+	}
+	if err := c.Send(ctx, "https://til.example/go/slices/", srv.URL+"/plain"); !errors.Is(err, ErrNoEndpoint) {
+		t.Errorf("Send to a page without endpoint = %v, want ErrNoEndpoint", err)
+	}
+}
+
+func TestTargets(t *testing.T) {
+	e, err := entry.Parse("go/slices.md", []byte("# Slices\n\n"+
+		"See [the blog](https://go.dev/blog/slices#intro), https://go.dev/blog/slices and <https://example.com/a>.\n\n"+
+		"Also [my maps](https://til.example/go/maps/) and https://til.example.org/x, not https://til.example.\n\n"+
+		"```\nhttps://code.example/skip\n```\n\n`https://inline.example/skip` and ftp://files.example/x.\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := Targets(e, "https://til.example/")
+	want := []string{"https://go.dev/blog/slices", "https://example.com/a", "https://til.example.org/x"}
+	if !slices.Equal(got, want) {
+		t.Errorf("Targets = %q, want %q", got, want)
+	}
+}
+
+func TestSent(t *testing.T) {
+	tree := notes.Open(t.TempDir())
+	sent, err := LoadSent(tree)
+	if err != nil || len(sent) != 0 {
+		t.Fatalf("LoadSent of a new tree = %v, %v", sent, err)
+	}
+	const src = "https://til.example/go/slices/"
+	at := time.Date(2024, 3, 1, 12, 0, 0, 0, time.FixedZone("", 3600))
+
+	if got := sent.Pending(src, []string{"https://a.example/", "https://b.example/"}, false); len(got) != 2 {
+		t.Errorf("Pending before any send = %q", got)
+	}
+	sent.Record(src, "https://a.example/", true, at)
+	sent.Record(src, "https://c.example/", true, at)
+	if err := sent.Save(tree); err != nil {
+		t.Fatal(err)
+	}
+	sent, err = LoadSent(tree)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := sent[src]["https://a.example/"]; !got.Equal(at) || got.Location() != time.UTC {
+		t.Errorf("recorded %v, want %v in UTC", got, at)
+	}
+
+	// b is new and c is no longer linked; a was mentioned already.
+	targets := []string{"https://a.example/", "https://b.example/"}
+	if got, want := sent.Pending(src, targets, false), []string{"https://b.example/", "https://c.example/"}; !slices.Equal(got, want) {
+		t.Errorf("Pending = %q, want %q", got, want)
+	}
+	if got, want := sent.Pending(src, targets, true), []string{"https://a.example/", "https://b.example/", "https://c.example/"}; !slices.Equal(got, want) {
+		t.Errorf("Pending(all) = %q, want %q", got, want)
+	}
+
+	sent.Record(src, "https://c.example/", false, at)
+	sent.Record(src, "https://a.example/", false, at)
+	if _, ok := sent[src]; ok {
+		t.Errorf("source with no mentions left kept: %v", sent)
+	}
+	// Unlinking a target never mentioned is harmless.
+	sent.Record("https://til.example/other/", "https://a.example/", false, at)
+	if len(sent) != 0 {
+		t.Errorf("sent = %v, want empty", sent)
+	}
+}
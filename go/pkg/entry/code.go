@@ -0,0 +1,370 @@
+package entry
+
+import (
+	"cmp"
+	"path"
+	"slices"
+	"strings"
+)
+
+// CodeBlock is a fenced code block in an entry body.
+type CodeBlock struct {
+	// Index is the zero-based position of the block among all fenced
+	// blocks in the body.
+	Index int
+	// Lang is the first word of the info string, e.g. "go".
+	Lang string
+	// Info is the full info string following the opening fence.
+	Info string
+	// Attrs holds the attributes given in braces after the language, as in
+	// ```go {norun label="append" hl_lines=[3]}. Flags map to "".
+	Attrs map[string]string
+	Code  string
+	// Line is the 1-based line of the opening fence within the body.
+	Line int
+	// EndLine is the 1-based line of the closing fence within the body.
+	EndLine int
+	// File is the slash-separated path, relative to the notes root, of the
+	// companion program the code was read from; empty for fenced blocks.
+	File string
+}
+
+// Has reports whether the attribute key is set.
+func (b CodeBlock) Has(key string) bool {
+	_, ok := b.Attrs[key]
+	return ok
+}
+
+// CodeBlocks returns the fenced code blocks of body in order.
+func CodeBlocks(body []byte) []CodeBlock {
+	var (
+		blocks []CodeBlock
+		cur    *CodeBlock
+		fence  string
+		indent int
+		code   strings.Builder
+	)
+	lines := strings.Split(string(body), "\n")
+	for i, line := range lines {
+		trimmed := strings.TrimLeft(line, " ")
+		lead := len(line) - len(trimmed)
+		if cur == nil {
+			if lead > 3 {
+				continue
+			}
+			f := fenceOf(trimmed)
+			if f == "" || (f[0] == '`' && strings.Contains(trimmed[len(f):], "`")) {
+				continue
+			}
+			info := strings.TrimSpace(trimmed[len(f):])
+			lang, attrs := ParseInfo(info)
+			cur = &CodeBlock{Index: len(blocks), Lang: lang, Info: info, Attrs: attrs, Line: i + 1}
+			fence, indent = f, lead
+			code.Reset()
+			continue
+		}
+		if f := fenceOf(trimmed); lead <= 3 && f != "" && f[0] == fence[0] && len(f) >= len(fence) && strings.TrimSpace(trimmed[len(f):]) == "" {
+			cur.Code = code.String()
+			cur.EndLine = i + 1
+			blocks = append(blocks, *cur)
+			cur = nil
+			continue
+		}
+		// Strip up to the opening fence's indentation from content lines.
+		n := 0
+		for n < indent && n < len(line) && line[n] == ' ' {
+			n++
+		}
+		code.WriteString(strings.TrimSuffix(line[n:], "\r"))
+		code.WriteByte('\n')
+	}
+	if cur != nil {
+		// An unclosed fence runs to the end of the document.
+		cur.Code = code.String()
+		cur.EndLine = len(lines)
+		blocks = append(blocks, *cur)
+	}
+	return blocks
+}
+
+// fenceOf returns the run of three or more backticks or tildes that starts
+// s, or "".
+func fenceOf(s string) string {
+	if len(s) < 3 || (s[0] != '`' && s[0] != '~') {
+		return ""
+	}
+	n := 0
+	for n < len(s) && s[n] == s[0] {
+		n++
+	}
+	if n < 3 {
+		return ""
+	}
+	return s[:n]
+}
+
+// ParseInfo splits a fence info string into the language and the attributes
+// given in braces.
+func ParseInfo(info string) (lang string, attrs map[string]string) {
+	attrs = map[string]string{}
+	rest := info
+	if i := strings.IndexAny(info, " \t{"); i >= 0 {
+		lang, rest = info[:i], info[i:]
+	} else {
+		lang, rest = info, ""
+	}
+	rest = strings.TrimSpace(rest)
+	if !strings.HasPrefix(rest, "{") {
+		return lang, attrs
+	}
+	rest = strings.TrimSuffix(strings.TrimPrefix(rest, "{"), "}")
+	for len(rest) > 0 {
+		rest = strings.TrimLeft(rest, " \t,")
+		if rest == "" {
+			break
+		}
+		end := strings.IndexAny(rest, " \t,=")
+		if end < 0 {
+			end = len(rest)
+		}
+		key := rest[:end]
+		rest = rest[end:]
+		if !strings.HasPrefix(rest, "=") {
+			attrs[key] = ""
+			continue
+		}
+		rest = rest[1:]
+		var val string
+		val, rest = attrValue(rest)
+		attrs[key] = val
+	}
+	return lang, attrs
+}
+
+// attrValue reads a quoted, bracketed or bare value from the start of s.
+func attrValue(s string) (val, rest string) {
+	if s == "" {
+		return "", ""
+	}
+	switch s[0] {
+	case '"', '\'':
+		if i := strings.IndexByte(s[1:], s[0]); i >= 0 {
+			return s[1 : i+1], s[i+2:]
+		}
+		return s[1:], ""
+	case '[':
+		if i := strings.IndexByte(s, ']'); i >= 0 {
+			return s[:i+1], s[i+1:]
+		}
+		return s, ""
+	}
+	end := strings.IndexAny(s, " \t,")
+	if end < 0 {
+		return s, ""
+	}
+	return s[:end], s[end:]
+}
+
+// OutputLang is the fence language of a block recording the expected
+// output of the code block right before it.
+const OutputLang = "output"
+
+// ModuleLang is the fence language of a block declaring the module
+// requirements of the Go code block right after it.
+const ModuleLang = "go.mod"
+
+// Snippet is a code block together with its expected output, if recorded,
+// and, for Go code, the go.mod block before it.
+type Snippet struct {
+	Code   CodeBlock
+	Output *CodeBlock
+	Module *CodeBlock
+}
+
+// Snippets pairs each code block in body with an immediately following
+// output block and, for Go blocks, an immediately preceding go.mod block.
+// Only blank lines may separate them. A go.mod block so paired is not a
+// snippet of its own, and the output block marked {source} belongs to the
+// SourceSnippet.
+func Snippets(body []byte) []Snippet {
+	blocks := CodeBlocks(body)
+	lines := strings.Split(string(body), "\n")
+	adjacent := func(i int) bool {
+		return i+1 < len(blocks) && blank(lines[blocks[i].EndLine:blocks[i+1].Line-1])
+	}
+	var snippets []Snippet
+	for i := 0; i < len(blocks); i++ {
+		b := blocks[i]
+		if b.Lang == OutputLang {
+			continue
+		}
+		var s Snippet
+		if b.Lang == ModuleLang && adjacent(i) && blocks[i+1].Lang == "go" {
+			mod := b
+			s.Module = &mod
+			i++
+			b = blocks[i]
+		}
+		s.Code = b
+		if adjacent(i) && blocks[i+1].Lang == OutputLang && !blocks[i+1].Has(SourceAttr) {
+			out := blocks[i+1]
+			s.Output = &out
+			i++
+		}
+		snippets = append(snippets, s)
+	}
+	return snippets
+}
+
+func blank(lines []string) bool {
+	for _, l := range lines {
+		if strings.TrimSpace(l) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// SourceAttr marks the output block recording the output of an entry's
+// companion program, ```output {source}.
+const SourceAttr = "source"
+
+// SourceSnippet returns the snippet of the companion program of e, whose
+// content is code: a code block of the language named by the file
+// extension, with Index -1, paired with the entry's output block marked
+// {source}, if any.
+func SourceSnippet(e *Entry, code []byte) Snippet {
+	p := e.SourcePath()
+	s := Snippet{Code: CodeBlock{
+		Index: -1,
+		Lang:  strings.TrimPrefix(path.Ext(p), "."),
+		Attrs: map[string]string{},
+		Code:  string(code),
+		File:  p,
+	}}
+	s.Output = sourceOutput(e.Body)
+	return s
+}
+
+// sourceOutput returns the output block of body marked {source}, if any.
+func sourceOutput(body []byte) *CodeBlock {
+	for _, b := range CodeBlocks(body) {
+		if b.Lang == OutputLang && b.Has(SourceAttr) {
+			return &b
+		}
+	}
+	return nil
+}
+
+// SetOutputs returns body with the output blocks recorded after code
+// blocks replaced by outputs, keyed by the code block's Index, and the
+// output block marked {source} by outputs[-1]. Code blocks without an
+// output block are left alone. Fences are lengthened where the output
+// would close them early.
+func SetOutputs(body []byte, outputs map[int]string) []byte {
+	lines := strings.Split(string(body), "\n")
+	snippets := Snippets(body)
+	if out := sourceOutput(body); out != nil {
+		snippets = append(snippets, Snippet{Code: CodeBlock{Index: -1}, Output: out})
+		pos := func(s Snippet) int {
+			if s.Output != nil {
+				return s.Output.Line
+			}
+			return s.Code.Line
+		}
+		slices.SortFunc(snippets, func(a, b Snippet) int { return cmp.Compare(pos(a), pos(b)) })
+	}
+	for i := len(snippets) - 1; i >= 0; i-- {
+		s := snippets[i]
+		out, ok := outputs[s.Code.Index]
+		if !ok || s.Output == nil {
+			continue
+		}
+		start, end := s.Output.Line-1, s.Output.EndLine
+		opening := lines[start]
+		trimmed := strings.TrimLeft(opening, " ")
+		indent := opening[:len(opening)-len(trimmed)]
+		old := fenceOf(trimmed)
+		var content []string
+		if out != "" {
+			content = strings.Split(strings.TrimSuffix(out, "\n"), "\n")
+		}
+		fence := old
+		for slices.ContainsFunc(content, func(l string) bool { return strings.HasPrefix(strings.TrimLeft(l, " "), fence) }) {
+			fence += fence[:1]
+		}
+		block := []string{indent + fence + trimmed[len(old):]}
+		for _, l := range content {
+			if l != "" {
+				l = indent + l
+			}
+			block = append(block, l)
+		}
+		block = append(block, indent+fence)
+		lines = slices.Concat(lines[:start], block, lines[end:])
+	}
+	out := strings.Join(lines, "\n")
+	if strings.HasSuffix(string(body), "\n") && !strings.HasSuffix(out, "\n") {
+		// An unclosed block ending the body took its last newline along.
+		out += "\n"
+	}
+	return []byte(out)
+}
+
+// SetAttr returns body with the attribute key of the code block at index
+// set to value, keeping the block's other attributes in place. Values are
+// quoted, as Markdown renderers would read some bare values as numbers.
+func SetAttr(body []byte, index int, key, value string) []byte {
+	lines := strings.Split(string(body), "\n")
+	for _, b := range CodeBlocks(body) {
+		if b.Index != index {
+			continue
+		}
+		opening := lines[b.Line-1]
+		trimmed := strings.TrimLeft(opening, " ")
+		f := fenceOf(trimmed)
+		lines[b.Line-1] = opening[:len(opening)-len(trimmed)] + f + setAttr(strings.TrimSuffix(strings.TrimSpace(trimmed[len(f):]), "\r"), key, value)
+		break
+	}
+	return []byte(strings.Join(lines, "\n"))
+}
+
+// setAttr sets the attribute key in the fence info string info, leaving
+// the text of the other attributes as written.
+func setAttr(info, key, value string) string {
+	attr := key
+	if value != "" {
+		attr += `="` + strings.ReplaceAll(value, `"`, `'`) + `"`
+	}
+	lang, rest := info, ""
+	if i := strings.IndexAny(info, " \t{"); i >= 0 {
+		lang, rest = info[:i], strings.TrimSpace(info[i:])
+	}
+	if !strings.HasPrefix(rest, "{") {
+		return strings.TrimSpace(lang + " {" + attr + "} " + rest)
+	}
+	inner := strings.TrimSuffix(rest[1:], "}")
+	for pos := 0; pos < len(inner); {
+		start := pos + len(inner[pos:]) - len(strings.TrimLeft(inner[pos:], " \t,"))
+		if start == len(inner) {
+			break
+		}
+		end := start + len(inner[start:])
+		if i := strings.IndexAny(inner[start:], " \t,="); i >= 0 {
+			end = start + i
+		}
+		next := end
+		if strings.HasPrefix(inner[end:], "=") {
+			_, after := attrValue(inner[end+1:])
+			next = len(inner) - len(after)
+		}
+		if inner[start:end] == key {
+			return lang + " {" + inner[:start] + attr + inner[next:] + "}"
+		}
+		pos = next
+	}
+	if strings.TrimSpace(inner) == "" {
+		return lang + " {" + attr + "}"
+	}
+	return lang + " {" + strings.TrimRight(inner, " \t") + " " + attr + "}"
+}
@@ -0,0 +1,173 @@
+package entry
+
+import (
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestCodeBlocks(t *testing.T) {
+	body := "# Title\n\n```go {norun label=\"append\" hl_lines=[3]}\nx := 1\n```\n\n  ~~~sh\n  echo hi\n  ~~~\n\n    ```indented\n    not a fence\n    ```\n\n````md\n```go\nnested\n```\n````\n\nInline ```code``` is not a fence.\n"
+	blocks := CodeBlocks([]byte(body))
+	if len(blocks) != 3 {
+		t.Fatalf("CodeBlocks = %+v, want 3", blocks)
+	}
+	b := blocks[0]
+	if b.Index != 0 || b.Lang != "go" || b.Code != "x := 1\n" || b.Line != 3 || b.EndLine != 5 {
+		t.Errorf("block 0 = %+v", b)
+	}
+	if want := map[string]string{"norun": "", "label": "append", "hl_lines": "[3]"}; !maps.Equal(b.Attrs, want) || !b.Has("norun") || b.Has("run") {
+		t.Errorf("block 0 attrs = %q, want %q", b.Attrs, want)
+	}
+	if b := blocks[1]; b.Lang != "sh" || b.Code != "echo hi\n" {
+		t.Errorf("block 1 = %+v, want its indent stripped", b)
+	}
+	if b := blocks[2]; b.Lang != "md" || b.Code != "```go\nnested\n```\n" {
+		t.Errorf("block 2 = %+v, want the shorter fence inside", b)
+	}
+}
+
+func TestSnippets(t *testing.T) {
+	body := "```sh\necho hi\n```\n\n```output\nhi\n```\n\n```sh\necho no output\n```\n\nProse between.\n\n```output\nnot paired\n```\n\n```py\nprint(1)\n```\n"
+	snippets := Snippets([]byte(body))
+	if len(snippets) != 3 {
+		t.Fatalf("Snippets = %+v, want 3", snippets)
+	}
+	if s := snippets[0]; s.Output == nil || s.Output.Code != "hi\n" || s.Output.Index != 1 {
+		t.Errorf("snippet 0 = %+v, want paired with the next block", s)
+	}
+	if s := snippets[1]; s.Code.Index != 2 || s.Output != nil {
+		t.Errorf("snippet 1 = %+v, want no output past the prose", s)
+	}
+	if s := snippets[2]; s.Code.Lang != "py" || s.Output != nil {
+		t.Errorf("snippet 2 = %+v", s)
+	}
+}
+
+func TestSnippetsModule(t *testing.T) {
+	body := "```go.mod\nrequire example.com/a v1.0.0\n```\n\n```go\nfmt.Println(a.X)\n```\n```output\n1\n```\n\n" +
+		"```go.mod\nrequire example.com/b v1.0.0\n```\n\n```sh\necho b\n```\n\n" +
+		"```go.mod\nrequire example.com/c v1.0.0\n```\n\nProse between.\n\n```go\nfmt.Println(2)\n```\n"
+	snippets := Snippets([]byte(body))
+	var langs []string
+	for _, s := range snippets {
+		langs = append(langs, s.Code.Lang)
+	}
+	if want := []string{"go", ModuleLang, "sh", ModuleLang, "go"}; !slices.Equal(langs, want) {
+		t.Fatalf("Snippets = %q, want %q", langs, want)
+	}
+	if s := snippets[0]; s.Module == nil || s.Module.Code != "require example.com/a v1.0.0\n" || s.Output == nil || s.Output.Code != "1\n" {
+		t.Errorf("snippet 0 = %+v, want the go.mod block and the output", s)
+	}
+	for _, i := range []int{1, 2, 3, 4} {
+		if snippets[i].Module != nil {
+			t.Errorf("snippet %d = %+v, want no module", i, snippets[i])
+		}
+	}
+}
+
+func TestSourceSnippet(t *testing.T) {
+	e, err := Parse("go/slices.md", []byte("---\ntitle: Slices\nsource: slices/main.go\n---\n\n```go\nfmt.Println(1)\n```\n```output {source}\nfrom the program\n```\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := e.SourcePath(); p != "go/slices/main.go" {
+		t.Errorf("SourcePath = %q", p)
+	}
+	if s := Snippets(e.Body); len(s) != 1 || s[0].Output != nil {
+		t.Errorf("Snippets = %+v, want the {source} output left to the program", s)
+	}
+	s := SourceSnippet(e, []byte("package main\n"))
+	if b := s.Code; b.Index != -1 || b.Lang != "go" || b.File != "go/slices/main.go" || b.Code != "package main\n" {
+		t.Errorf("SourceSnippet code = %+v", b)
+	}
+	if s.Output == nil || s.Output.Code != "from the program\n" {
+		t.Errorf("SourceSnippet output = %+v", s.Output)
+	}
+
+	e, _ = Parse("go/plain.md", []byte("# Plain\n"))
+	if p := e.SourcePath(); p != "" {
+		t.Errorf("SourcePath without source: = %q", p)
+	}
+}
+
+func TestSetOutputs(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		outputs map[int]string
+		want    string
+	}{
+		{
+			"replaced",
+			"```sh\necho hi\n```\n\n```output\nold\n```\n\nAfter.\n",
+			map[int]string{0: "hi\nthere\n"},
+			"```sh\necho hi\n```\n\n```output\nhi\nthere\n```\n\nAfter.\n",
+		},
+		{
+			"emptied and filled",
+			"```sh\ntrue\n```\n```output\nstale\n```\n```sh\necho a\n```\n\n```output\n```\n",
+			map[int]string{0: "", 2: "a"},
+			"```sh\ntrue\n```\n```output\n```\n```sh\necho a\n```\n\n```output\na\n```\n",
+		},
+		{
+			"no output block",
+			"```sh\necho hi\n```\n",
+			map[int]string{0: "hi\n"},
+			"```sh\necho hi\n```\n",
+		},
+		{
+			"fence lengthened",
+			"```sh\necho '```'\n```\n\n```output {label=x}\n```\n",
+			map[int]string{0: "```\n"},
+			"```sh\necho '```'\n```\n\n````output {label=x}\n```\n````\n",
+		},
+		{
+			"indented",
+			"- item\n\n  ```sh\n  printf 'a\\n\\nb'\n  ```\n  ```output\n  ```\n",
+			map[int]string{0: "a\n\nb"},
+			"- item\n\n  ```sh\n  printf 'a\\n\\nb'\n  ```\n  ```output\n  a\n\n  b\n  ```\n",
+		},
+		{
+			"unclosed",
+			"```sh\necho hi\n```\n\n```output\nold\n",
+			map[int]string{0: "hi\n"},
+			"```sh\necho hi\n```\n\n```output\nhi\n```\n",
+		},
+		{
+			"source",
+			"```sh\necho a\n```\n```output {source}\nold\n```\n\n```sh\necho b\n```\n```output\n```\n",
+			map[int]string{-1: "src\n", 0: "a\n", 2: "b\n"},
+			"```sh\necho a\n```\n```output {source}\nsrc\n```\n\n```sh\necho b\n```\n```output\nb\n```\n",
+		},
+	}
+	for _, tt := range tests {
+		if got := string(SetOutputs([]byte(tt.body), tt.outputs)); got != tt.want {
+			t.Errorf("%s: SetOutputs =\n%q\nwant\n%q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSetAttr(t *testing.T) {
+	tests := []struct {
+		info, key, value string
+		want             string
+	}{
+		{"go", "play", "abc", `go {play="abc"}`},
+		{"go {norun}", "play", "abc", `go {norun play="abc"}`},
+		{`go {label="x" play=old hl_lines=[2]}`, "play", "new", `go {label="x" play="new" hl_lines=[2]}`},
+		{"go {play}", "play", `a"b`, `go {play="a'b"}`},
+		{"go title", "norun", "", "go {norun} title"},
+	}
+	for _, tt := range tests {
+		body := "Text.\n\n```" + tt.info + "\nx := 1\n```\n\n```go\ny := 2\n```\n"
+		want := "Text.\n\n```" + tt.want + "\nx := 1\n```\n\n```go\ny := 2\n```\n"
+		if got := string(SetAttr([]byte(body), 0, tt.key, tt.value)); got != want {
+			t.Errorf("SetAttr(%q, %s=%q) =\n%s\nwant\n%s", tt.info, tt.key, tt.value, got, want)
+		}
+		if _, attrs := ParseInfo(tt.want); attrs[tt.key] != strings.ReplaceAll(tt.value, `"`, `'`) {
+			t.Errorf("ParseInfo(%q)[%s] = %q", tt.want, tt.key, attrs[tt.key])
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package entry
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Reading speeds behind Counts.ReadingMinutes.
+const (
+	WordsPerMinute     = 200
+	CodeLinesPerMinute = 20
+)
+
+var (
+	// linkDestRE matches the destination of a markdown link or image,
+	// which is not read as words.
+	linkDestRE = regexp.MustCompile(`\]\([^)]*\)`)
+	commentRE  = regexp.MustCompile(`(?s)<!--.*?-->`)
+)
+
+// Counts measures the body of an entry.
+type Counts struct {
+	// Words counts the words of the prose, outside code blocks, link
+	// destinations and HTML comments.
+	Words int `json:"words"`
+	// CodeLines counts the non-blank lines of code blocks, output blocks
+	// excluded.
+	CodeLines int `json:"code_lines"`
+}
+
+// Count measures body.
+func Count(body []byte) Counts {
+	var c Counts
+	lines := strings.Split(string(body), "\n")
+	prose := make([]bool, len(lines))
+	for i := range prose {
+		prose[i] = true
+	}
+	for _, b := range CodeBlocks(body) {
+		for l := b.Line - 1; l < b.EndLine && l < len(lines); l++ {
+			prose[l] = false
+		}
+		if b.Lang == OutputLang {
+			continue
+		}
+		for _, l := range strings.Split(b.Code, "\n") {
+			if strings.TrimSpace(l) != "" {
+				c.CodeLines++
+			}
+		}
+	}
+	var text strings.Builder
+	for i, l := range lines {
+		if prose[i] {
+			text.WriteString(l)
+			text.WriteByte('\n')
+		}
+	}
+	s := commentRE.ReplaceAllString(text.String(), " ")
+	s = linkDestRE.ReplaceAllString(s, "]")
+	for _, f := range strings.Fields(s) {
+		if strings.IndexFunc(f, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }) >= 0 {
+			c.Words++
+		}
+	}
+	return c
+}
+
+// ReadingMinutes estimates the time to read the entry, in whole minutes
+// rounded up: prose at WordsPerMinute and code at CodeLinesPerMinute. It
+// is at least one for entries with any content.
+func (c Counts) ReadingMinutes() int {
+	if c.Words == 0 && c.CodeLines == 0 {
+		return 0
+	}
+	// In fractions of a minute both speeds divide, to round once.
+	const per = WordsPerMinute * CodeLinesPerMinute
+	n := c.Words*CodeLinesPerMinute + c.CodeLines*WordsPerMinute
+	return (n + per - 1) / per
+}
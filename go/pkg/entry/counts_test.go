@@ -0,0 +1,42 @@
+package entry
+
+import "testing"
+
+func TestCount(t *testing.T) {
+	tests := []struct {
+		body string
+		want Counts
+	}{
+		{"", Counts{}},
+		{"# Slices share arrays\n\nAppend may - or may not - copy.\n", Counts{Words: 9}},
+		{"See [the spec](https://go.dev/ref/spec#Appending) and ![a chart](chart.png).\n", Counts{Words: 6}},
+		{"Before <!-- a note\nto self --> after.\n", Counts{Words: 2}},
+		{"Run it:\n\n```go\ns := []int{1}\n\ns = append(s, 2)\n```\n\n```output\n[1 2]\n```\n", Counts{Words: 2, CodeLines: 2}},
+		{"```sh\necho unclosed\necho block\n", Counts{CodeLines: 2}},
+	}
+	for _, tt := range tests {
+		if got := Count([]byte(tt.body)); got != tt.want {
+			t.Errorf("Count(%q) = %+v, want %+v", tt.body, got, tt.want)
+		}
+	}
+}
+
+func TestReadingMinutes(t *testing.T) {
+	tests := []struct {
+		c    Counts
+		want int
+	}{
+		{Counts{}, 0},
+		{Counts{Words: 1}, 1},
+		{Counts{Words: WordsPerMinute}, 1},
+		{Counts{Words: WordsPerMinute + 1}, 2},
+		{Counts{CodeLines: CodeLinesPerMinute}, 1},
+		{Counts{Words: WordsPerMinute, CodeLines: CodeLinesPerMinute * 2}, 3},
+		{Counts{Words: WordsPerMinute / 2, CodeLines: CodeLinesPerMinute / 2}, 1},
+	}
+	for _, tt := range tests {
+		if got := tt.c.ReadingMinutes(); got != tt.want {
+			t.Errorf("%+v.ReadingMinutes() = %d, want %d", tt.c, got, tt.want)
+		}
+	}
+}
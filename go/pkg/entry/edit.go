@@ -0,0 +1,354 @@
+package entry
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Front is editable frontmatter. Edits replace only the lines of the fields
+// they touch, so comments, ordering and the formatting of other fields are
+// preserved.
+type Front struct {
+	lines []string
+}
+
+// ParseFront parses raw frontmatter as returned by SplitFrontmatter.
+func ParseFront(front []byte) (*Front, error) {
+	f := &Front{}
+	s := strings.TrimSuffix(string(front), "\n")
+	if s != "" {
+		f.lines = strings.Split(s, "\n")
+	}
+	if _, err := f.root(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Bytes returns the edited frontmatter, newline terminated.
+func (f *Front) Bytes() []byte {
+	if len(f.lines) == 0 {
+		return nil
+	}
+	return []byte(strings.Join(f.lines, "\n") + "\n")
+}
+
+// Has reports whether key is a top-level field.
+func (f *Front) Has(key string) bool {
+	_, _, ok, _ := f.span(key)
+	return ok
+}
+
+// Get decodes the value of the top-level field key into v. It reports
+// whether the field exists.
+func (f *Front) Get(key string, v any) (bool, error) {
+	root, err := f.root()
+	if err != nil || root == nil {
+		return false, err
+	}
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value == key {
+			return true, root.Content[i+1].Decode(v)
+		}
+	}
+	return false, nil
+}
+
+// Set sets the top-level field key to value, replacing the existing field
+// in place or appending a new one. Lists keep the flow ([a, b]) or block
+// ("- a") style of the field they replace.
+func (f *Front) Set(key string, value any) error {
+	start, end, ok, err := f.span(key)
+	if err != nil {
+		return err
+	}
+	// New lists use the flow style of the entry templates.
+	style, indent := flowStyle, "  "
+	if ok {
+		style, indent = f.listStyle(start, end)
+	}
+	lines, err := renderField(key, value, style, indent)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		f.lines = append(f.lines, lines...)
+		return nil
+	}
+	if err := f.keepComments(key, value, lines); err != nil {
+		return err
+	}
+	f.lines = append(f.lines[:start:start], append(lines, f.lines[end:]...)...)
+	return nil
+}
+
+// keepComments carries the comments ending the lines of the field key over
+// to lines, the field rewritten: that of the line of the key, and those of
+// the items of a block list, each kept with its item or, for an item
+// replaced, with the item taking its place.
+func (f *Front) keepComments(key string, value any, lines []string) error {
+	root, err := f.root()
+	if err != nil || root == nil {
+		return err
+	}
+	var k, v *yaml.Node
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value == key {
+			k, v = root.Content[i], root.Content[i+1]
+			break
+		}
+	}
+	if k == nil {
+		return nil
+	}
+	first := k.LineComment
+	if first == "" && v.Line == k.Line {
+		first = v.LineComment
+	}
+	if first != "" {
+		lines[0] += f.comment(k.Line, first)
+	}
+	list, ok := value.([]string)
+	if !ok || v.Kind != yaml.SequenceNode || v.Line == k.Line || len(lines) != len(list)+1 {
+		return nil
+	}
+	// The items gone take turns giving their comments to the items new.
+	comments := map[string]string{}
+	var gone []string
+	for _, item := range v.Content {
+		if item.LineComment != "" {
+			comments[item.Value] = f.comment(item.Line, item.LineComment)
+		}
+		if !slices.Contains(list, item.Value) {
+			gone = append(gone, item.Value)
+		}
+	}
+	for i, item := range list {
+		c, ok := comments[item]
+		if !ok && !slices.ContainsFunc(v.Content, func(n *yaml.Node) bool { return n.Value == item }) && len(gone) > 0 {
+			c, gone = comments[gone[0]], gone[1:]
+		}
+		lines[i+1] += c
+	}
+	return nil
+}
+
+// comment returns the comment c ending the nth line, 1-based, with the
+// space before it.
+func (f *Front) comment(n int, c string) string {
+	line := f.lines[n-1]
+	i := strings.LastIndex(line, c)
+	if i < 0 {
+		return " " + c
+	}
+	j := i
+	for j > 0 && (line[j-1] == ' ' || line[j-1] == '\t') {
+		j--
+	}
+	return line[j:]
+}
+
+// Delete removes the top-level field key. It reports whether it existed.
+func (f *Front) Delete(key string) (bool, error) {
+	start, end, ok, err := f.span(key)
+	if err != nil || !ok {
+		return false, err
+	}
+	f.lines = append(f.lines[:start:start], f.lines[end:]...)
+	return true, nil
+}
+
+// Rename renames the top-level field from to to, keeping its value and
+// formatting.
+func (f *Front) Rename(from, to string) (bool, error) {
+	start, _, ok, err := f.span(from)
+	if err != nil || !ok {
+		return false, err
+	}
+	line := f.lines[start]
+	i := strings.Index(line, from)
+	if i < 0 {
+		return false, fmt.Errorf("frontmatter: cannot locate key %q", from)
+	}
+	f.lines[start] = line[:i] + yamlKey(to) + line[i+len(from):]
+	return true, nil
+}
+
+// Keys returns the top-level field names in order.
+func (f *Front) Keys() []string {
+	root, _ := f.root()
+	if root == nil {
+		return nil
+	}
+	keys := make([]string, 0, len(root.Content)/2)
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		keys = append(keys, root.Content[i].Value)
+	}
+	return keys
+}
+
+func (f *Front) text() string {
+	return strings.Join(f.lines, "\n")
+}
+
+func (f *Front) root() (*yaml.Node, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(f.text()), &doc); err != nil {
+		return nil, fmt.Errorf("frontmatter: %w", err)
+	}
+	if len(doc.Content) == 0 {
+		return nil, nil
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("frontmatter: expected a mapping, got %s", kindName(root.Kind))
+	}
+	return root, nil
+}
+
+// span returns the half-open range of line indexes holding the field key.
+// Trailing blank and comment lines are left to whatever follows.
+func (f *Front) span(key string) (start, end int, ok bool, err error) {
+	root, err := f.root()
+	if err != nil || root == nil {
+		return 0, 0, false, err
+	}
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value != key {
+			continue
+		}
+		start = root.Content[i].Line - 1
+		end = len(f.lines)
+		if i+2 < len(root.Content) {
+			end = root.Content[i+2].Line - 1
+		}
+		for end > start+1 {
+			l := strings.TrimSpace(f.lines[end-1])
+			if l != "" && !strings.HasPrefix(l, "#") {
+				break
+			}
+			end--
+		}
+		return start, end, true, nil
+	}
+	return 0, 0, false, nil
+}
+
+type listStyle int
+
+const (
+	blockStyle listStyle = iota
+	flowStyle
+)
+
+// listStyle inspects the existing field lines to pick the style and item
+// indentation for a replacement list.
+func (f *Front) listStyle(start, end int) (listStyle, string) {
+	if end-start == 1 {
+		return flowStyle, "  "
+	}
+	for _, l := range f.lines[start+1 : end] {
+		trimmed := strings.TrimLeft(l, " ")
+		if strings.HasPrefix(trimmed, "- ") || trimmed == "-" {
+			return blockStyle, l[:len(l)-len(trimmed)]
+		}
+	}
+	return blockStyle, "  "
+}
+
+// renderField formats key: value as frontmatter lines.
+func renderField(key string, value any, style listStyle, indent string) ([]string, error) {
+	k := yamlKey(key)
+	if list, ok := value.([]string); ok {
+		items := make([]string, len(list))
+		for i, v := range list {
+			s, err := yamlScalar(v)
+			if err != nil {
+				return nil, err
+			}
+			items[i] = s
+		}
+		if style == flowStyle || len(list) == 0 {
+			return []string{k + ": [" + strings.Join(items, ", ") + "]"}, nil
+		}
+		lines := []string{k + ":"}
+		for _, it := range items {
+			lines = append(lines, indent+"- "+it)
+		}
+		return lines, nil
+	}
+	if t, ok := value.(time.Time); ok && t.Equal(time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())) {
+		// Plain dates stay unquoted so they read back as timestamps.
+		return []string{k + ": " + t.Format(DateLayout)}, nil
+	}
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(map[string]any{key: value}); err != nil {
+		return nil, err
+	}
+	enc.Close()
+	return strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n"), nil
+}
+
+func yamlScalar(v any) (string, error) {
+	out, err := yaml.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	s := strings.TrimSuffix(string(out), "\n")
+	if strings.Contains(s, "\n") {
+		return "", fmt.Errorf("frontmatter: value %q does not fit on one line", v)
+	}
+	return s, nil
+}
+
+func yamlKey(k string) string {
+	s, err := yamlScalar(k)
+	if err != nil {
+		return k
+	}
+	return s
+}
+
+func kindName(k yaml.Kind) string {
+	switch k {
+	case yaml.SequenceNode:
+		return "a list"
+	case yaml.ScalarNode:
+		return "a scalar"
+	}
+	return "a document"
+}
+
+// Rewrite applies edit to the frontmatter of the entry file data and
+// returns the new file contents. The body is kept byte for byte. A file
+// without frontmatter gets a new block.
+func Rewrite(data []byte, edit func(*Front) error) ([]byte, error) {
+	front, body, ok := SplitFrontmatter(data)
+	f, err := ParseFront(front)
+	if err != nil {
+		return nil, err
+	}
+	if err := edit(f); err != nil {
+		return nil, err
+	}
+	if !ok {
+		return JoinFrontmatter(f.Bytes(), append([]byte("\n"), data...)), nil
+	}
+	// Keep the original delimiter lines (e.g. "..." or CRLF endings).
+	head := data[:len(data)-len(body)]
+	open := head[:bytes.IndexByte(head, '\n')+1]
+	closing := head[len(open)+len(front):]
+	var b bytes.Buffer
+	b.Write(open)
+	b.Write(f.Bytes())
+	b.Write(closing)
+	b.Write(body)
+	return b.Bytes(), nil
+}
@@ -0,0 +1,159 @@
+package entry
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRewrite(t *testing.T) {
+	tests := []struct {
+		name, data, want string
+		edit             func(*Front) error
+	}{
+		{
+			name: "flow list",
+			data: "---\ntitle: A\ntags: [go, git]\n---\nbody\n",
+			edit: func(f *Front) error { return f.Set("tags", []string{"go", "rust"}) },
+			want: "---\ntitle: A\ntags: [go, rust]\n---\nbody\n",
+		},
+		{
+			name: "block list",
+			data: "---\ntags:\n    - go\n    - git\ntitle: A\n---\nbody\n",
+			edit: func(f *Front) error { return f.Set("tags", []string{"rust"}) },
+			want: "---\ntags:\n    - rust\ntitle: A\n---\nbody\n",
+		},
+		{
+			name: "new field",
+			data: "---\ntitle: A\n---\nbody\n",
+			edit: func(f *Front) error { return f.Set("draft", true) },
+			want: "---\ntitle: A\ndraft: true\n---\nbody\n",
+		},
+		{
+			name: "comments and order",
+			data: "---\n# about\ntitle: A\n\n# the tags\ntags: [go]\nslug: a\n---\nbody\n",
+			edit: func(f *Front) error { return f.Set("title", "B") },
+			want: "---\n# about\ntitle: B\n\n# the tags\ntags: [go]\nslug: a\n---\nbody\n",
+		},
+		{
+			name: "delete",
+			data: "---\ntitle: A\ndraft: true\nslug: a\n---\nbody\n",
+			edit: func(f *Front) error { _, err := f.Delete("draft"); return err },
+			want: "---\ntitle: A\nslug: a\n---\nbody\n",
+		},
+		{
+			name: "rename",
+			data: "---\ntitle: A\ntag: [go] # old\n---\nbody\n",
+			edit: func(f *Front) error { _, err := f.Rename("tag", "tags"); return err },
+			want: "---\ntitle: A\ntags: [go] # old\n---\nbody\n",
+		},
+		{
+			name: "no frontmatter",
+			data: "# A\n",
+			edit: func(f *Front) error { return f.Set("title", "A") },
+			want: "---\ntitle: A\n---\n\n# A\n",
+		},
+		{
+			name: "delimiters kept",
+			data: "---\ntitle: A\n...\n\n  body kept  \n",
+			edit: func(f *Front) error { return f.Set("title", "B") },
+			want: "---\ntitle: B\n...\n\n  body kept  \n",
+		},
+		{
+			name: "date",
+			data: "---\ntitle: A\n---\n",
+			edit: func(f *Front) error { return f.Set("updated", time.Date(2024, 5, 6, 0, 0, 0, 0, time.UTC)) },
+			want: "---\ntitle: A\nupdated: 2024-05-06\n---\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Rewrite([]byte(tt.data), tt.edit)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Rewrite(%q) =\n%s\nwant\n%s", tt.data, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRewriteKeepsLineComments(t *testing.T) {
+	data := strings.Join([]string{
+		"---",
+		"title: A",
+		"tags:   # the tags",
+		"  - go   # inline",
+		"  - golang # same",
+		"  - rust\t# ferris",
+		"  # last",
+		"draft: false # wip",
+		"---",
+		"body",
+		"",
+	}, "\n")
+	tests := []struct {
+		name string
+		tags []string
+		want []string
+	}{
+		{"rename", []string{"go", "golang", "crab"}, []string{"  - go   # inline", "  - golang # same", "  - crab\t# ferris"}},
+		{"merge", []string{"go", "rust"}, []string{"  - go   # inline", "  - rust\t# ferris"}},
+		{"add", []string{"go", "golang", "rust", "new"}, []string{"  - go   # inline", "  - golang # same", "  - rust\t# ferris", "  - new"}},
+		{"reorder", []string{"rust", "go"}, []string{"  - rust\t# ferris", "  - go   # inline"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Rewrite([]byte(data), func(f *Front) error { return f.Set("tags", tt.tags) })
+			if err != nil {
+				t.Fatal(err)
+			}
+			lines := append([]string{"---", "title: A", "tags:   # the tags"}, tt.want...)
+			want := strings.Join(append(lines, "  # last", "draft: false # wip", "---", "body", ""), "\n")
+			if string(got) != want {
+				t.Errorf("got\n%s\nwant\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestRewriteKeepsScalarComment(t *testing.T) {
+	got, err := Rewrite([]byte("---\ndraft: false  # wip\ntags: [a] # flow\n---\n"), func(f *Front) error {
+		if err := f.Set("draft", true); err != nil {
+			return err
+		}
+		return f.Set("tags", []string{"b"})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "---\ndraft: true  # wip\ntags: [b] # flow\n---\n"; string(got) != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestFrontGet(t *testing.T) {
+	f, err := ParseFront([]byte("title: A\ntags: [go, git]\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tags []string
+	if ok, err := f.Get("tags", &tags); !ok || err != nil || len(tags) != 2 {
+		t.Errorf("Get(tags) = %v, %v, %q", ok, err, tags)
+	}
+	if f.Has("slug") {
+		t.Error("Has(slug) on frontmatter without it")
+	}
+	if got := strings.Join(f.Keys(), ","); got != "title,tags" {
+		t.Errorf("Keys() = %s", got)
+	}
+}
+
+func TestParseFrontErrors(t *testing.T) {
+	for _, front := range []string{"- a\n- b\n", "title: [a\n"} {
+		if _, err := ParseFront([]byte(front)); err == nil {
+			t.Errorf("ParseFront(%q) succeeded", front)
+		}
+	}
+}
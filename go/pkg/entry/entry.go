@@ -0,0 +1,243 @@
+// Package entry parses and serializes TIL entries: markdown files with an
+// optional YAML frontmatter block.
+package entry
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DateLayout is the layout used for dates written to frontmatter.
+const DateLayout = "2006-01-02"
+
+// Meta is the frontmatter of an entry.
+type Meta struct {
+	Title string `yaml:"title"`
+	// Date is the creation date.
+	Date     time.Time `yaml:"date"`
+	Updated  time.Time `yaml:"updated"`
+	Category string    `yaml:"category"`
+	Slug     string    `yaml:"slug"`
+	Tags     []string  `yaml:"tags"`
+	// Author is who wrote the entry, in repositories shared by a team.
+	Author string `yaml:"author"`
+	// Lang is the language the entry is written in, as a BCP 47 tag such
+	// as "vi" or "en-US". Empty is the configured default language.
+	Lang string `yaml:"lang"`
+	// TranslationOf names the entry this one translates, by slug or any
+	// reference a [[link]] takes.
+	TranslationOf string `yaml:"translation_of"`
+	// Draft keeps the entry out of the site, feeds and README index.
+	Draft bool `yaml:"draft"`
+	// Private marks an entry to be encrypted at rest; see package private.
+	// Unencrypted private entries are kept out of the site like drafts.
+	Private bool `yaml:"private"`
+	// Archived keeps the entry out of the site and of til list and til
+	// search unless they are given --archived; see til archive.
+	Archived bool `yaml:"archived"`
+	// Sandbox runs the entry's code blocks in the sandbox of package
+	// runner, with limits of its own if given.
+	Sandbox Sandbox `yaml:"sandbox"`
+	// Source is the path, relative to the entry's directory, of a companion
+	// program run and verified along with the entry's code blocks.
+	Source string `yaml:"source"`
+}
+
+// Sandbox is the sandbox frontmatter of an entry: either true, or the
+// limits to run its code blocks with.
+//
+//	sandbox:
+//	  timeout: 2m
+//	  memory: 512M
+//	  network: true
+type Sandbox struct {
+	// On is set when the entry asks for the sandbox.
+	On      bool
+	Timeout time.Duration
+	// Memory is a size such as "256M"; empty keeps the configured cap.
+	Memory string
+	// Network is nil unless the entry says whether runs reach the network.
+	Network *bool
+}
+
+// UnmarshalYAML decodes a boolean or a mapping of limits, which turns the
+// sandbox on.
+func (s *Sandbox) UnmarshalYAML(n *yaml.Node) error {
+	if n.Kind == yaml.ScalarNode {
+		*s = Sandbox{}
+		return n.Decode(&s.On)
+	}
+	var v struct {
+		Timeout time.Duration `yaml:"timeout"`
+		Memory  string        `yaml:"memory"`
+		Network *bool         `yaml:"network"`
+	}
+	if err := n.Decode(&v); err != nil {
+		return err
+	}
+	*s = Sandbox{On: true, Timeout: v.Timeout, Memory: v.Memory, Network: v.Network}
+	return nil
+}
+
+// Entry is a single TIL note.
+type Entry struct {
+	// Path is the slash-separated path relative to the notes root,
+	// e.g. "go/basic_syntax.md".
+	Path string
+	Meta Meta
+	// Front is the raw frontmatter, without the delimiters. It is nil when
+	// the file has no frontmatter.
+	Front []byte
+	// Body is the markdown content following the frontmatter.
+	Body []byte
+	// BodyLine is the 1-based line of the file on which Body starts.
+	BodyLine int
+	// Counts measures Body, as parsed.
+	Counts  Counts
+	ModTime time.Time
+}
+
+// Parse parses the contents of the entry file at p. Fields missing from the
+// frontmatter are derived from the path and the first heading.
+func Parse(p string, data []byte) (*Entry, error) {
+	e := &Entry{Path: p, BodyLine: 1}
+	front, body, ok := SplitFrontmatter(data)
+	e.Body = body
+	e.Counts = Count(body)
+	e.BodyLine += bytes.Count(data[:len(data)-len(body)], []byte("\n"))
+	if ok {
+		e.Front = front
+		if err := yaml.Unmarshal(front, &e.Meta); err != nil {
+			return nil, fmt.Errorf("%s: frontmatter: %w", p, err)
+		}
+	}
+	if e.Meta.Title == "" {
+		e.Meta.Title = Heading(body)
+	}
+	if e.Meta.Title == "" {
+		e.Meta.Title = Humanize(e.Stem())
+	}
+	if e.Meta.Category == "" {
+		e.Meta.Category = CategoryOf(p)
+	}
+	if e.Meta.Slug == "" {
+		e.Meta.Slug = Slugify(e.Stem())
+	}
+	return e, nil
+}
+
+// Created returns the creation date from the frontmatter, or the zero time.
+func (e *Entry) Created() time.Time {
+	return e.Meta.Date
+}
+
+// LastUpdated returns the frontmatter update date, falling back to the
+// file's modification time and then to the creation date.
+func (e *Entry) LastUpdated() time.Time {
+	switch {
+	case !e.Meta.Updated.IsZero():
+		return e.Meta.Updated
+	case !e.ModTime.IsZero():
+		return e.ModTime
+	}
+	return e.Meta.Date
+}
+
+// Public returns the entries that are neither drafts, private nor
+// archived, in order.
+func Public(entries []*Entry) []*Entry {
+	out := make([]*Entry, 0, len(entries))
+	for _, e := range entries {
+		if !e.Meta.Draft && !e.Meta.Private && !e.Meta.Archived {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// FileLine converts a 1-based line within Body to a line within the file.
+func (e *Entry) FileLine(bodyLine int) int {
+	return e.BodyLine + bodyLine - 1
+}
+
+// SourcePath returns the slash-separated path, relative to the notes root,
+// of the entry's companion program, or "" if it has none.
+func (e *Entry) SourcePath() string {
+	if e.Meta.Source == "" {
+		return ""
+	}
+	return path.Join(path.Dir(e.Path), e.Meta.Source)
+}
+
+// Stem returns the file name without directory and extension.
+func (e *Entry) Stem() string {
+	return strings.TrimSuffix(path.Base(e.Path), path.Ext(e.Path))
+}
+
+// ID returns the path without the ".md" extension, e.g. "go/basic_syntax".
+func (e *Entry) ID() string {
+	return strings.TrimSuffix(e.Path, path.Ext(e.Path))
+}
+
+// CategoryOf returns the top-level directory of the slash-separated path p.
+func CategoryOf(p string) string {
+	if i := strings.IndexByte(p, '/'); i > 0 {
+		return p[:i]
+	}
+	return ""
+}
+
+// Heading returns the text of the first level-one ATX heading in body.
+func Heading(body []byte) string {
+	inFence := false
+	for _, line := range bytes.Split(body, []byte("\n")) {
+		trimmed := bytes.TrimSpace(line)
+		if bytes.HasPrefix(trimmed, []byte("```")) || bytes.HasPrefix(trimmed, []byte("~~~")) {
+			inFence = !inFence
+			continue
+		}
+		if !inFence && bytes.HasPrefix(trimmed, []byte("# ")) {
+			return string(bytes.TrimSpace(trimmed[2:]))
+		}
+	}
+	return ""
+}
+
+// Excerpt returns the first n words of the prose in body, skipping headings
+// and fenced code, with "…" appended when the prose is longer.
+func Excerpt(body []byte, n int) string {
+	var words []string
+	inFence := false
+	for _, line := range bytes.Split(body, []byte("\n")) {
+		trimmed := bytes.TrimSpace(line)
+		if bytes.HasPrefix(trimmed, []byte("```")) || bytes.HasPrefix(trimmed, []byte("~~~")) {
+			inFence = !inFence
+			continue
+		}
+		if inFence || bytes.HasPrefix(trimmed, []byte("#")) {
+			continue
+		}
+		words = append(words, strings.Fields(string(trimmed))...)
+		if len(words) > n {
+			return strings.Join(words[:n], " ") + "…"
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// Humanize turns a file stem such as "basic_syntax" into "Basic syntax".
+func Humanize(stem string) string {
+	s := strings.NewReplacer("_", " ", "-", " ").Replace(stem)
+	r, n := utf8.DecodeRuneInString(s)
+	if n == 0 {
+		return s
+	}
+	return string(unicode.ToUpper(r)) + s[n:]
+}
@@ -0,0 +1,90 @@
+package entry
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestSplitFrontmatter(t *testing.T) {
+	tests := []struct {
+		name, data, front, body string
+		ok                      bool
+	}{
+		{"none", "# Title\n", "", "# Title\n", false},
+		{"block", "---\ntitle: A\n---\nbody\n", "title: A\n", "body\n", true},
+		{"dots", "---\ntitle: A\n...\nbody\n", "title: A\n", "body\n", true},
+		{"crlf", "---\r\ntitle: A\r\n---\r\nbody\r\n", "title: A\r\n", "body\r\n", true},
+		{"empty", "---\n---\nbody\n", "", "body\n", true},
+		{"no end", "---\ntitle: A\n", "", "---\ntitle: A\n", false},
+		{"at end", "---\ntitle: A\n---", "title: A\n", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			front, body, ok := SplitFrontmatter([]byte(tt.data))
+			if string(front) != tt.front || string(body) != tt.body || ok != tt.ok {
+				t.Errorf("SplitFrontmatter(%q) = %q, %q, %v; want %q, %q, %v", tt.data, front, body, ok, tt.front, tt.body, tt.ok)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	data := "---\ntitle: Slices\ndate: 2024-03-01\ntags: [go, slices]\ndraft: true\n---\n\nSlices share their backing array.\n"
+	e, err := Parse("go/slices_share.md", []byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Meta.Title != "Slices" || e.Meta.Category != "go" || e.Meta.Slug != "slices-share" || !e.Meta.Draft {
+		t.Errorf("Meta = %+v", e.Meta)
+	}
+	if want := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC); !e.Created().Equal(want) {
+		t.Errorf("Created() = %v, want %v", e.Created(), want)
+	}
+	if !slices.Equal(e.Meta.Tags, []string{"go", "slices"}) {
+		t.Errorf("Tags = %q", e.Meta.Tags)
+	}
+	if e.ID() != "go/slices_share" || e.BodyLine != 7 {
+		t.Errorf("ID() = %q, BodyLine = %d", e.ID(), e.BodyLine)
+	}
+}
+
+func TestParseTitle(t *testing.T) {
+	tests := []struct{ path, data, want string }{
+		{"go/x.md", "---\ntitle: Front\n---\n# Heading\n", "Front"},
+		{"go/x.md", "# Heading\n\ntext\n", "Heading"},
+		{"go/basic_syntax.md", "text\n", "Basic syntax"},
+		{"vi/đường_ống.md", "text\n", "Đường ống"},
+	}
+	for _, tt := range tests {
+		e, err := Parse(tt.path, []byte(tt.data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if e.Meta.Title != tt.want {
+			t.Errorf("title of %s %q = %q, want %q", tt.path, tt.data, e.Meta.Title, tt.want)
+		}
+	}
+}
+
+func TestParseBadFrontmatter(t *testing.T) {
+	if _, err := Parse("go/x.md", []byte("---\ntitle: [a\n---\n")); err == nil {
+		t.Error("Parse succeeded on broken frontmatter")
+	}
+}
+
+func TestHumanize(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"", ""},
+		{"basic_syntax", "Basic syntax"},
+		{"two-words", "Two words"},
+		{"đường_ống", "Đường ống"},
+		{"ánh", "Ánh"},
+		{"1st", "1st"},
+	}
+	for _, tt := range tests {
+		if got := Humanize(tt.in); got != tt.want {
+			t.Errorf("Humanize(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
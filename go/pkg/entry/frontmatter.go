@@ -0,0 +1,43 @@
+package entry
+
+import "bytes"
+
+// SplitFrontmatter splits data into the YAML frontmatter (without the "---"
+// delimiter lines) and the remaining body. ok is false when data does not
+// start with a frontmatter block, in which case body is data.
+func SplitFrontmatter(data []byte) (front, body []byte, ok bool) {
+	first, _, found := bytes.Cut(data, []byte("\n"))
+	if !found || string(bytes.TrimRight(first, "\r")) != "---" {
+		return nil, data, false
+	}
+	start := len(first) + 1
+	for off := start; off < len(data); {
+		line, _, more := bytes.Cut(data[off:], []byte("\n"))
+		switch string(bytes.TrimRight(line, "\r")) {
+		case "---", "...":
+			end := off + len(line)
+			if more {
+				end++
+			}
+			return data[start:off], data[end:], true
+		}
+		if !more {
+			break
+		}
+		off += len(line) + 1
+	}
+	return nil, data, false
+}
+
+// JoinFrontmatter assembles a file from raw frontmatter and body.
+func JoinFrontmatter(front, body []byte) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("---\n")
+	buf.Write(front)
+	if len(front) > 0 && front[len(front)-1] != '\n' {
+		buf.WriteByte('\n')
+	}
+	buf.WriteString("---\n")
+	buf.Write(body)
+	return buf.Bytes()
+}
@@ -0,0 +1,59 @@
+package entry
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Slugify returns a lowercase, hyphen-separated slug for s. Latin letters
+// are transliterated to ASCII, as é to e, đ to d and ß to ss, and runs of
+// other characters than ASCII letters and digits collapse into one hyphen.
+// Slugs of scripts without a transliteration may be empty.
+func Slugify(s string) string {
+	var b strings.Builder
+	pendingDash := false
+	write := func(r rune) {
+		if pendingDash && b.Len() > 0 {
+			b.WriteByte('-')
+		}
+		pendingDash = false
+		b.WriteRune(r)
+	}
+	// Decomposed, accented letters are a base letter and combining marks,
+	// which are dropped.
+	for _, r := range norm.NFD.String(strings.ToLower(s)) {
+		switch {
+		case unicode.Is(unicode.Mn, r):
+		case r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			write(r)
+		case translit[r] != "":
+			for _, t := range translit[r] {
+				write(t)
+			}
+		default:
+			pendingDash = true
+		}
+	}
+	return b.String()
+}
+
+// translit spells the lowercase Latin letters that do not decompose into an
+// ASCII letter and marks.
+var translit = map[rune]string{
+	'ß': "ss", 'æ': "ae", 'œ': "oe", 'ø': "o", 'đ': "d", 'ð': "d", 'þ': "th",
+	'ł': "l", 'ı': "i", 'ħ': "h", 'ŧ': "t", 'ŋ': "ng", 'ĸ': "k",
+}
+
+// FileName returns the file name used for an entry with the given slug,
+// following the repository's snake_case naming.
+func FileName(slug string) string {
+	return strings.ReplaceAll(slug, "-", "_") + ".md"
+}
+
+// ValidSlug reports whether slug can name the file of an entry in its
+// category: it is not empty, hidden or a path.
+func ValidSlug(slug string) bool {
+	return slug != "" && !strings.HasPrefix(slug, ".") && !strings.Contains(slug, "..") && !strings.ContainsAny(slug, `/\`)
+}
@@ -0,0 +1,47 @@
+package entry
+
+import "testing"
+
+func TestSlugify(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"Slices share arrays", "slices-share-arrays"},
+		{"  Leading and trailing  ", "leading-and-trailing"},
+		{"Go 1.22: range over int!", "go-1-22-range-over-int"},
+		{"Café déjà vu", "cafe-deja-vu"},
+		{"Đường ống", "duong-ong"},
+		{"Straße", "strasse"},
+		{"C++ & C#", "c-c"},
+		{"日本語", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := Slugify(tt.in); got != tt.want {
+			t.Errorf("Slugify(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestFileName(t *testing.T) {
+	if got := FileName("slices-share-arrays"); got != "slices_share_arrays.md" {
+		t.Errorf("FileName = %s", got)
+	}
+}
+
+func TestValidSlug(t *testing.T) {
+	for slug, want := range map[string]bool{
+		"slices":       true,
+		"my_slug-2":    true,
+		"a.b":          true,
+		"":             false,
+		".hidden":      false,
+		"..":           false,
+		"../x":         false,
+		"a/b":          false,
+		`a\b`:          false,
+		"go/../../etc": false,
+	} {
+		if got := ValidSlug(slug); got != want {
+			t.Errorf("ValidSlug(%q) = %v, want %v", slug, got, want)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package entry
+
+import "time"
+
+// Summary is the JSON form of an entry in listings.
+type Summary struct {
+	Path           string   `json:"path"`
+	Title          string   `json:"title"`
+	Category       string   `json:"category"`
+	Slug           string   `json:"slug"`
+	Tags           []string `json:"tags"`
+	Author         string   `json:"author,omitempty"`
+	Lang           string   `json:"lang,omitempty"`
+	TranslationOf  string   `json:"translation_of,omitempty"`
+	Created        string   `json:"created,omitempty"`
+	Updated        string   `json:"updated,omitempty"`
+	Words          int      `json:"words"`
+	CodeLines      int      `json:"code_lines"`
+	ReadingMinutes int      `json:"reading_minutes"`
+}
+
+// Summarize returns the listing form of e.
+func Summarize(e *Entry) Summary {
+	s := Summary{
+		Path:     e.Path,
+		Title:    e.Meta.Title,
+		Category: e.Meta.Category,
+		Slug:     e.Meta.Slug,
+		Tags:     e.Meta.Tags,
+		Author:   e.Meta.Author,
+
+		Lang:          e.Meta.Lang,
+		TranslationOf: e.Meta.TranslationOf,
+
+		Words:          e.Counts.Words,
+		CodeLines:      e.Counts.CodeLines,
+		ReadingMinutes: e.Counts.ReadingMinutes(),
+	}
+	if s.Tags == nil {
+		s.Tags = []string{}
+	}
+	if d := e.Created(); !d.IsZero() {
+		s.Created = d.Format(DateLayout)
+	}
+	if d := e.LastUpdated(); !d.IsZero() {
+		s.Updated = d.Format(time.RFC3339)
+	}
+	return s
+}
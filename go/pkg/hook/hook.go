@@ -0,0 +1,61 @@
+// Package hook defines the lifecycle events til runs hooks on, and the
+// interface of plugins handling them in process.
+//
+// Hooks are configured under [hooks] in the config file, as commands run
+// with the entry's path as their last argument and the event's Payload as
+// JSON on stdin, or as Go plugins: shared objects built with
+// go build -buildmode=plugin whose main package exports
+//
+//	func New() hook.Plugin
+//
+// A plugin must be built with the same Go version and versions of this
+// module and its dependencies as til itself.
+package hook
+
+import "context"
+
+// Event names a point in til's lifecycle.
+type Event string
+
+// Events hooks run on.
+const (
+	// PreSave runs before an entry is written. Hooks may replace the
+	// content written: commands by printing it, plugins by setting
+	// Payload.Content. A failure aborts the write.
+	PreSave Event = "pre-save"
+	// PostSave runs after an entry is written.
+	PostSave Event = "post-save"
+	// PreBuild runs before til build generates the site. A failure aborts
+	// the build.
+	PreBuild Event = "pre-build"
+	// PostPublish runs after til publish marks a draft published.
+	PostPublish Event = "post-publish"
+)
+
+// Events lists every event in lifecycle order.
+var Events = []Event{PreSave, PostSave, PreBuild, PostPublish}
+
+// Payload describes an occurrence of an event.
+type Payload struct {
+	Event Event `json:"event"`
+	// Root is the notes root, the working directory of hook commands.
+	Root string `json:"root"`
+	// Path is the entry's slash-separated path relative to Root.
+	Path string `json:"path,omitempty"`
+	// Content is the entry's content, on save events.
+	Content string `json:"content,omitempty"`
+	// Out is the directory the site is built into, on PreBuild.
+	Out string `json:"out,omitempty"`
+	// URL is the entry's page on the site, on PostPublish when [site]
+	// base_url is absolute.
+	URL string `json:"url,omitempty"`
+}
+
+// Plugin is an in-process extension.
+type Plugin interface {
+	// Name identifies the plugin in messages.
+	Name() string
+	// Handle is called on every event, before hook commands run; plugins
+	// ignore the events they do not handle.
+	Handle(ctx context.Context, p *Payload) error
+}
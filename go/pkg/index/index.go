@@ -0,0 +1,101 @@
+// Package index searches the entries of a notes tree, for programs that
+// embed til's search instead of running til search.
+//
+// It keeps the same SQLite full-text index as the command, in the tree's
+// state directory, so the two share their work: hits found by one are
+// current for the other after a Sync.
+//
+//	ix, err := index.Open(root)
+//	if err != nil {
+//		return err
+//	}
+//	defer ix.Close()
+//	if _, err := ix.Sync(ctx); err != nil {
+//		return err
+//	}
+//	hits, err := ix.Query(ctx, "tag:go generics", index.Options{Limit: 5})
+package index
+
+import (
+	"context"
+	"time"
+
+	"github.com/canhta/til/go/internal/notes"
+	"github.com/canhta/til/go/internal/query"
+	"github.com/canhta/til/go/internal/search"
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+type (
+	// Result is a single search hit.
+	Result = search.Result
+	// Options controls a search.
+	Options = search.Options
+	// SyncStats reports what a Sync changed.
+	SyncStats = search.SyncStats
+)
+
+// Index is an open search index over a notes tree.
+type Index struct {
+	tree *notes.Tree
+	ix   *search.Index
+}
+
+// FindRoot returns the notes root dir lies in: the nearest directory,
+// starting at dir, with a .til or .git directory.
+func FindRoot(dir string) (string, error) {
+	return notes.FindRoot(dir)
+}
+
+// Open opens, creating if needed, the search index of the notes tree at
+// root. The index reflects the entries as of the last Sync, by any
+// program.
+func Open(root string) (*Index, error) {
+	tree := notes.Open(root)
+	ix, err := search.Open(tree)
+	if err != nil {
+		return nil, err
+	}
+	return &Index{tree: tree, ix: ix}, nil
+}
+
+// Close closes the index.
+func (ix *Index) Close() error {
+	return ix.ix.Close()
+}
+
+// Sync brings the index up to date with the entry files, re-reading only
+// those changed since the last sync.
+func (ix *Index) Sync(ctx context.Context) (SyncStats, error) {
+	return ix.ix.Sync(ctx)
+}
+
+// Search returns the entries matching all the words of text, best match
+// first. The last word matches as a prefix.
+func (ix *Index) Search(ctx context.Context, text string, opts Options) ([]Result, error) {
+	return ix.ix.Search(ctx, text, opts)
+}
+
+// Query returns the entries matching q, written in the query language of
+// til search: words, "phrases", tag:, category:, title:, created: and
+// updated: filters, combined with AND, OR, NOT and parentheses. Saved
+// searches (@name) are not available, as they live in the user's config
+// file.
+func (ix *Index) Query(ctx context.Context, q string, opts Options) ([]Result, error) {
+	x, err := query.Parse(q, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	return ix.ix.Query(ctx, x, opts)
+}
+
+// Entry loads the entry at the slash-separated path p, as a Result names
+// it, relative to the notes root.
+func (ix *Index) Entry(p string) (*entry.Entry, error) {
+	return ix.tree.Load(p)
+}
+
+// Entries loads every entry of the tree.
+func (ix *Index) Entries() ([]*entry.Entry, error) {
+	return ix.tree.Entries()
+}
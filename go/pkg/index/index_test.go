@@ -0,0 +1,85 @@
+package index
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeFile(t *testing.T, root, p, data string) {
+	t.Helper()
+	file := filepath.Join(root, filepath.FromSlash(p))
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(file, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func paths(results []Result) []string {
+	var out []string
+	for _, r := range results {
+		out = append(out, r.Path)
+	}
+	return out
+}
+
+func TestIndex(t *testing.T) {
+	root := t.TempDir()
+	if err := os.Mkdir(filepath.Join(root, ".til"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, root, "go/generics.md", "---\ntitle: Generics\ntags: [go]\n---\n\nType parameters constrain generics.\n")
+	writeFile(t, root, "go/slices.md", "---\ntitle: Slices\ntags: [go]\n---\n\nAppend may reallocate.\n")
+	writeFile(t, root, "rust/generics.md", "---\ntitle: Rust generics\ntags: [rust]\n---\n\nTraits bound generics.\n")
+
+	found, err := FindRoot(filepath.Join(root, "go"))
+	if err != nil || found != root {
+		t.Fatalf("FindRoot = %q, %v, want %q", found, err, root)
+	}
+	ctx := context.Background()
+	ix, err := Open(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ix.Close()
+	if st, err := ix.Sync(ctx); err != nil || st.Added != 3 {
+		t.Fatalf("Sync = %+v, %v", st, err)
+	}
+
+	hits, err := ix.Search(ctx, "gener", Options{})
+	if err != nil || len(hits) != 2 {
+		t.Errorf("Search(gener) = %q, %v", paths(hits), err)
+	}
+	hits, err = ix.Query(ctx, "tag:go generics", Options{Limit: 5})
+	if err != nil || len(hits) != 1 || hits[0].Path != "go/generics.md" || hits[0].Title != "Generics" {
+		t.Errorf("Query(tag:go generics) = %+v, %v", hits, err)
+	}
+	if _, err := ix.Query(ctx, "(unclosed", Options{}); err == nil {
+		t.Error("Query of a malformed query succeeded")
+	}
+
+	e, err := ix.Entry(hits[0].Path)
+	if err != nil || e.Meta.Title != "Generics" {
+		t.Errorf("Entry = %+v, %v", e, err)
+	}
+	if all, err := ix.Entries(); err != nil || len(all) != 3 {
+		t.Errorf("Entries = %d, %v", len(all), err)
+	}
+
+	// The index is shared, here with a second one, as with til search.
+	writeFile(t, root, "go/maps.md", "---\ntitle: Maps\n---\n\nUnordered generics-free maps.\n")
+	other, err := Open(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.Close()
+	if st, err := other.Sync(ctx); err != nil || st.Added != 1 {
+		t.Errorf("second Sync = %+v, %v, want only the new entry", st, err)
+	}
+	if hits, _ := ix.Search(ctx, "unordered", Options{}); len(hits) != 1 {
+		t.Errorf("Search after another index synced = %q", paths(hits))
+	}
+}
@@ -0,0 +1,166 @@
+package render
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// CalloutTypes are the callout types, as GitHub names them, with their
+// default titles.
+var CalloutTypes = map[string]string{
+	"note":      "Note",
+	"tip":       "Tip",
+	"important": "Important",
+	"warning":   "Warning",
+	"caution":   "Caution",
+}
+
+// CalloutRE matches the first line of a callout inside a blockquote, as in
+// "[!WARNING] Slices alias", capturing the type and an optional title.
+var CalloutRE = regexp.MustCompile(`^\[!([A-Za-z]+)\][ \t]*(.*)$`)
+
+// KindCallout is the node kind of callouts, KindCalloutTitle that of
+// their titles.
+var (
+	KindCallout      = ast.NewNodeKind("Callout")
+	KindCalloutTitle = ast.NewNodeKind("CalloutTitle")
+)
+
+// Callout is a blockquote opening with [!TYPE], GitHub's alert syntax:
+//
+//	> [!WARNING] Optional title
+//	> Appending may write into the array another slice shares.
+//
+// Its first child is a CalloutTitle.
+type Callout struct {
+	ast.BaseBlock
+	// Variant is the lower-cased type, one of CalloutTypes.
+	Variant string
+}
+
+// Kind implements ast.Node.
+func (n *Callout) Kind() ast.NodeKind { return KindCallout }
+
+// Dump implements ast.Node.
+func (n *Callout) Dump(src []byte, level int) {
+	ast.DumpHelper(n, src, level, map[string]string{"Variant": n.Variant}, nil)
+}
+
+// CalloutTitle holds the inline title of a callout: the text after the
+// marker, or the type's default title.
+type CalloutTitle struct {
+	ast.BaseBlock
+}
+
+// Kind implements ast.Node.
+func (n *CalloutTitle) Kind() ast.NodeKind { return KindCalloutTitle }
+
+// Dump implements ast.Node.
+func (n *CalloutTitle) Dump(src []byte, level int) { ast.DumpHelper(n, src, level, nil, nil) }
+
+type callouts struct{}
+
+func (callouts) Extend(m goldmark.Markdown) {
+	m.Parser().AddOptions(parser.WithASTTransformers(util.Prioritized(callouts{}, 300)))
+	m.Renderer().AddOptions(renderer.WithNodeRenderers(util.Prioritized(callouts{}, 100)))
+}
+
+// Transform replaces blockquotes opening with [!TYPE] by Callout nodes,
+// moving the rest of the marker line into the title.
+func (callouts) Transform(doc *ast.Document, reader text.Reader, _ parser.Context) {
+	src := reader.Source()
+	var quotes []*ast.Blockquote
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if q, ok := n.(*ast.Blockquote); ok && entering {
+			quotes = append(quotes, q)
+		}
+		return ast.WalkContinue, nil
+	})
+	for _, q := range quotes {
+		p, ok := q.FirstChild().(*ast.Paragraph)
+		if !ok || p.Lines().Len() == 0 {
+			continue
+		}
+		first := p.Lines().At(0)
+		line := bytes.TrimRight(first.Value(src), " \t\r\n")
+		m := CalloutRE.FindSubmatch(line)
+		if m == nil {
+			continue
+		}
+		typ := strings.ToLower(string(m[1]))
+		name, known := CalloutTypes[typ]
+		if !known {
+			continue
+		}
+		title := &CalloutTitle{}
+		takeTitle(p, title, first.Start+len(line)-len(m[2]), first.Start+len(line))
+		if !title.HasChildren() {
+			title.AppendChild(title, ast.NewString([]byte(name)))
+		}
+		c := &Callout{Variant: typ}
+		c.AppendChild(c, title)
+		for child := q.FirstChild(); child != nil; {
+			next := child.NextSibling()
+			if child != p || p.HasChildren() {
+				c.AppendChild(c, child)
+			}
+			child = next
+		}
+		q.Parent().ReplaceChild(q.Parent(), q, c)
+	}
+}
+
+// takeTitle moves the inline nodes of p's first line, which ends at end,
+// into title, dropping the marker before the offset start.
+func takeTitle(p *ast.Paragraph, title *CalloutTitle, start, end int) {
+	for n := p.FirstChild(); n != nil; {
+		next := n.NextSibling()
+		t, isText := n.(*ast.Text)
+		last := isText && (t.Segment.Stop >= end || t.SoftLineBreak() || t.HardLineBreak())
+		p.RemoveChild(p, n)
+		switch {
+		case isText && t.Segment.Stop <= start:
+			// Part of the marker.
+		case isText:
+			if t.Segment.Start < start {
+				t.Segment = t.Segment.WithStart(start)
+			}
+			t.SetSoftLineBreak(false)
+			t.SetHardLineBreak(false)
+			title.AppendChild(title, t)
+		default:
+			title.AppendChild(title, n)
+		}
+		if last {
+			return
+		}
+		n = next
+	}
+}
+
+func (callouts) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(KindCallout, func(out util.BufWriter, _ []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+		if entering {
+			out.WriteString(`<div class="callout callout-` + node.(*Callout).Variant + `">` + "\n")
+		} else {
+			out.WriteString("</div>\n")
+		}
+		return ast.WalkContinue, nil
+	})
+	reg.Register(KindCalloutTitle, func(out util.BufWriter, _ []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+		if entering {
+			out.WriteString(`<p class="callout-title">`)
+		} else {
+			out.WriteString("</p>\n")
+		}
+		return ast.WalkContinue, nil
+	})
+}
@@ -0,0 +1,98 @@
+package render
+
+import (
+	"bytes"
+	"html"
+	"slices"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// DiagramLangs are the fence languages rendered as diagrams.
+var DiagramLangs = []string{"mermaid"}
+
+// KindDiagram is the node kind of diagram code blocks.
+var KindDiagram = ast.NewNodeKind("Diagram")
+
+// Diagram is a fenced code block holding the source of a diagram.
+type Diagram struct {
+	ast.BaseBlock
+	Lang   string
+	Source []byte
+	// SVG is the rendered diagram; empty leaves rendering to a script in
+	// the browser.
+	SVG []byte
+}
+
+// Kind implements ast.Node.
+func (n *Diagram) Kind() ast.NodeKind { return KindDiagram }
+
+// Dump implements ast.Node.
+func (n *Diagram) Dump(src []byte, level int) {
+	ast.DumpHelper(n, src, level, map[string]string{"Lang": n.Lang}, nil)
+}
+
+// ClientDiagram marks, in rendered HTML, a diagram left for a script in the
+// browser to draw: <pre class="mermaid">, as mermaid.js expects.
+func ClientDiagram(lang string) []byte {
+	return []byte(`<pre class="` + lang + `">`)
+}
+
+type diagrams struct {
+	render func(from, lang string, src []byte) ([]byte, error)
+}
+
+func (d *diagrams) Extend(m goldmark.Markdown) {
+	m.Parser().AddOptions(parser.WithASTTransformers(util.Prioritized(d, 200)))
+	m.Renderer().AddOptions(renderer.WithNodeRenderers(util.Prioritized(d, 100)))
+}
+
+// Transform replaces diagram code blocks by Diagram nodes, rendering them
+// when possible.
+func (d *diagrams) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	from, _ := pc.Get(fromKey).(string)
+	src := reader.Source()
+	var blocks []*ast.FencedCodeBlock
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if cb, ok := n.(*ast.FencedCodeBlock); ok && entering && slices.Contains(DiagramLangs, string(cb.Language(src))) {
+			blocks = append(blocks, cb)
+		}
+		return ast.WalkContinue, nil
+	})
+	for _, cb := range blocks {
+		var code bytes.Buffer
+		for i := 0; i < cb.Lines().Len(); i++ {
+			line := cb.Lines().At(i)
+			code.Write(line.Value(src))
+		}
+		n := &Diagram{Lang: string(cb.Language(src)), Source: code.Bytes()}
+		if svg, err := d.render(from, n.Lang, n.Source); err == nil {
+			n.SVG = svg
+		}
+		cb.Parent().ReplaceChild(cb.Parent(), cb, n)
+	}
+}
+
+func (d *diagrams) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(KindDiagram, func(out util.BufWriter, _ []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		n := node.(*Diagram)
+		if len(n.SVG) > 0 {
+			out.WriteString(`<figure class="diagram diagram-` + n.Lang + `">`)
+			out.Write(n.SVG)
+			out.WriteString("</figure>\n")
+		} else {
+			out.Write(ClientDiagram(n.Lang))
+			out.WriteString(html.EscapeString(string(n.Source)))
+			out.WriteString("</pre>\n")
+		}
+		return ast.WalkSkipChildren, nil
+	})
+}
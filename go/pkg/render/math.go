@@ -0,0 +1,189 @@
+package render
+
+import (
+	"bytes"
+	"html"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// KindMath is the node kind of $inline$ math, KindMathBlock that of
+// $$display$$ math on lines of its own.
+var (
+	KindMath      = ast.NewNodeKind("Math")
+	KindMathBlock = ast.NewNodeKind("MathBlock")
+)
+
+// Math is TeX written inline, as $x$ or $$x$$.
+type Math struct {
+	ast.BaseInline
+	TeX     []byte
+	Display bool
+}
+
+// Kind implements ast.Node.
+func (n *Math) Kind() ast.NodeKind { return KindMath }
+
+// Dump implements ast.Node.
+func (n *Math) Dump(src []byte, level int) {
+	ast.DumpHelper(n, src, level, map[string]string{"TeX": string(n.TeX)}, nil)
+}
+
+// MathBlock is display math between lines starting and ending with $$.
+type MathBlock struct {
+	ast.BaseBlock
+	TeX    []byte
+	closed bool
+}
+
+// Kind implements ast.Node.
+func (n *MathBlock) Kind() ast.NodeKind { return KindMathBlock }
+
+// IsRaw implements ast.Node.
+func (n *MathBlock) IsRaw() bool { return true }
+
+// Dump implements ast.Node.
+func (n *MathBlock) Dump(src []byte, level int) {
+	ast.DumpHelper(n, src, level, map[string]string{"TeX": string(n.TeX)}, nil)
+}
+
+// ClientMath marks, in rendered HTML, math left for KaTeX's auto-render
+// script to typeset in the browser.
+var ClientMath = []byte(`class="math `)
+
+type math struct {
+	render func(tex []byte, display bool) ([]byte, error)
+}
+
+func (m *math) Extend(md goldmark.Markdown) {
+	md.Parser().AddOptions(
+		parser.WithBlockParsers(util.Prioritized(mathBlockParser{}, 90)),
+		parser.WithInlineParsers(util.Prioritized(mathInlineParser{}, 150)),
+	)
+	md.Renderer().AddOptions(renderer.WithNodeRenderers(util.Prioritized(m, 500)))
+}
+
+type mathBlockParser struct{}
+
+func (mathBlockParser) Trigger() []byte { return []byte{'$'} }
+
+func (mathBlockParser) Open(parent ast.Node, reader text.Reader, pc parser.Context) (ast.Node, parser.State) {
+	line, _ := reader.PeekLine()
+	pos := pc.BlockOffset()
+	if pos < 0 || !bytes.HasPrefix(line[pos:], []byte("$$")) {
+		return nil, parser.NoChildren
+	}
+	rest := bytes.TrimSpace(line[pos+2:])
+	n := &MathBlock{}
+	if tex, ok := bytes.CutSuffix(rest, []byte("$$")); ok {
+		// $$x$$ alone on a line.
+		n.TeX, n.closed = append([]byte(nil), bytes.TrimSpace(tex)...), true
+	} else if bytes.Contains(rest, []byte("$$")) {
+		// $$x$$ followed by text is inline math in a paragraph.
+		return nil, parser.NoChildren
+	} else if len(rest) > 0 {
+		n.TeX = append(append(n.TeX, rest...), '\n')
+	}
+	reader.AdvanceToEOL()
+	return n, parser.NoChildren
+}
+
+func (mathBlockParser) Continue(node ast.Node, reader text.Reader, pc parser.Context) parser.State {
+	n := node.(*MathBlock)
+	if n.closed {
+		return parser.Close
+	}
+	line, _ := reader.PeekLine()
+	if line == nil {
+		return parser.Close
+	}
+	reader.AdvanceToEOL()
+	if tex, ok := bytes.CutSuffix(bytes.TrimSpace(line), []byte("$$")); ok {
+		n.TeX = append(n.TeX, tex...)
+		n.closed = true
+		return parser.Continue | parser.NoChildren
+	}
+	n.TeX = append(n.TeX, line...)
+	return parser.Continue | parser.NoChildren
+}
+
+func (mathBlockParser) Close(node ast.Node, reader text.Reader, pc parser.Context) {
+	n := node.(*MathBlock)
+	n.TeX = bytes.TrimSpace(n.TeX)
+}
+
+func (mathBlockParser) CanInterruptParagraph() bool { return true }
+func (mathBlockParser) CanAcceptIndentedLine() bool { return false }
+
+type mathInlineParser struct{}
+
+func (mathInlineParser) Trigger() []byte { return []byte{'$'} }
+
+// Parse reads $x$ the way pandoc does, so prices stay prose: the opening $
+// must be followed by a non-space, the closing one preceded by a non-space
+// and not followed by a digit.
+func (mathInlineParser) Parse(parent ast.Node, block text.Reader, pc parser.Context) ast.Node {
+	line, _ := block.PeekLine()
+	if bytes.HasPrefix(line, []byte("$$")) {
+		end := bytes.Index(line[2:], []byte("$$"))
+		if end <= 0 {
+			return nil
+		}
+		block.Advance(end + 4)
+		return &Math{TeX: append([]byte(nil), bytes.TrimSpace(line[2:2+end])...), Display: true}
+	}
+	if len(line) < 3 || isSpace(line[1]) {
+		return nil
+	}
+	for i := 1; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			i++
+		case '$':
+			if isSpace(line[i-1]) || i+1 < len(line) && line[i+1] >= '0' && line[i+1] <= '9' {
+				return nil
+			}
+			block.Advance(i + 1)
+			return &Math{TeX: append([]byte(nil), line[1:i]...)}
+		}
+	}
+	return nil
+}
+
+func isSpace(c byte) bool { return c == ' ' || c == '\t' || c == '\n' || c == '\r' }
+
+func (m *math) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(KindMath, func(out util.BufWriter, _ []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+		if entering {
+			n := node.(*Math)
+			m.write(out, n.TeX, n.Display, "span")
+		}
+		return ast.WalkSkipChildren, nil
+	})
+	reg.Register(KindMathBlock, func(out util.BufWriter, _ []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+		if entering {
+			m.write(out, node.(*MathBlock).TeX, true, "div")
+			out.WriteByte('\n')
+		}
+		return ast.WalkSkipChildren, nil
+	})
+}
+
+func (m *math) write(out util.BufWriter, tex []byte, display bool, tag string) {
+	if m.render != nil {
+		if h, err := m.render(tex, display); err == nil {
+			out.Write(h)
+			return
+		}
+	}
+	open, end, class := `\(`, `\)`, "math-inline"
+	if display {
+		open, end, class = `\[`, `\]`, "math-display"
+	}
+	out.WriteString("<" + tag + " " + string(ClientMath) + class + `">` + open + html.EscapeString(string(tex)) + end + "</" + tag + ">")
+}
@@ -0,0 +1,71 @@
+package render
+
+import (
+	"html"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+// KindPlayLink is the node kind of Go Playground links.
+var KindPlayLink = ast.NewNodeKind("PlayLink")
+
+// PlayLink links a Go code block shared on the Go Playground, as til share
+// records in its play attribute, to the shared snippet.
+type PlayLink struct {
+	ast.BaseBlock
+	ID string
+}
+
+// Kind implements ast.Node.
+func (n *PlayLink) Kind() ast.NodeKind { return KindPlayLink }
+
+// Dump implements ast.Node.
+func (n *PlayLink) Dump(src []byte, level int) {
+	ast.DumpHelper(n, src, level, map[string]string{"ID": n.ID}, nil)
+}
+
+// PlayURL returns the address of the Go Playground snippet id.
+func PlayURL(id string) string {
+	return "https://go.dev/play/p/" + id
+}
+
+type playLinks struct{}
+
+func (p playLinks) Extend(m goldmark.Markdown) {
+	m.Parser().AddOptions(parser.WithASTTransformers(util.Prioritized(p, 300)))
+	m.Renderer().AddOptions(renderer.WithNodeRenderers(util.Prioritized(p, 100)))
+}
+
+// Transform adds a PlayLink after each Go code block with a play attribute.
+func (playLinks) Transform(doc *ast.Document, reader text.Reader, _ parser.Context) {
+	src := reader.Source()
+	var blocks []*ast.FencedCodeBlock
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if cb, ok := n.(*ast.FencedCodeBlock); ok && entering && cb.Info != nil {
+			blocks = append(blocks, cb)
+		}
+		return ast.WalkContinue, nil
+	})
+	for _, cb := range blocks {
+		lang, attrs := entry.ParseInfo(string(cb.Info.Segment.Value(src)))
+		if id := attrs["play"]; lang == "go" && id != "" {
+			cb.Parent().InsertAfter(cb.Parent(), cb, &PlayLink{ID: id})
+		}
+	}
+}
+
+func (playLinks) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(KindPlayLink, func(out util.BufWriter, _ []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+		if entering {
+			out.WriteString(`<p class="play"><a href="` + html.EscapeString(PlayURL(node.(*PlayLink).ID)) + `">Run in the Go Playground</a></p>` + "\n")
+		}
+		return ast.WalkSkipChildren, nil
+	})
+}
@@ -0,0 +1,184 @@
+// Package render converts entry markdown to HTML.
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	chromahtml "github.com/alecthomas/chroma/v2/formatters/html"
+	"github.com/alecthomas/chroma/v2/styles"
+	"github.com/yuin/goldmark"
+	highlighting "github.com/yuin/goldmark-highlighting/v2"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// Options configures a Renderer.
+type Options struct {
+	// ResolveLink maps a link destination found in the entry at the
+	// slash-separated path from to the URL it should point to in the output.
+	// It returns ok=false to leave the destination unchanged.
+	ResolveLink func(from, dest string) (url string, ok bool)
+	// ResolveWiki maps the target of a [[target]] reference in the entry at
+	// from to the URL and title of the entry it names. Unresolved targets
+	// are rendered as dangling links. Without it, references render as
+	// their label.
+	ResolveWiki func(from, target string) (url, title string, ok bool)
+	// Highlight names a chroma style used to highlight fenced code with
+	// inline styles. Empty leaves code blocks plain.
+	//
+	// Fences take Hugo's attributes: ```go {hl_lines=[3,"5-7"], linenos=true}
+	// highlights the given lines and numbers them, linenostart=n numbers
+	// from n and linenos=false turns numbering off for one block.
+	Highlight string
+	// Classes marks highlighted code with CSS classes instead of inline
+	// styles, for use with the stylesheet from CSS.
+	Classes bool
+	// LineNumbers numbers the lines of every highlighted block.
+	LineNumbers bool
+	// Permalinks appends to each heading a link to its anchor. Headings get
+	// anchors, as Headings lists them, either way.
+	Permalinks bool
+	// XHTML renders void elements self-closed, as EPUB requires.
+	XHTML bool
+	// Diagram renders the source of a fence in one of DiagramLangs to SVG,
+	// which is inlined. When it fails, the source is left in a
+	// ClientDiagram element for a script to draw. Without it, diagram
+	// fences are ordinary code blocks.
+	Diagram func(from, lang string, src []byte) ([]byte, error)
+	// Math parses TeX between $ for inline and $$ for display math.
+	Math bool
+	// RenderMath pre-renders math to HTML. Without it, or when it fails,
+	// math is left in ClientMath elements for a script to typeset.
+	RenderMath func(tex []byte, display bool) ([]byte, error)
+}
+
+// Renderer renders markdown to HTML.
+type Renderer struct {
+	md goldmark.Markdown
+}
+
+// New returns a Renderer configured by opts.
+func New(opts Options) *Renderer {
+	var transformers []util.PrioritizedValue
+	if opts.ResolveLink != nil {
+		transformers = append(transformers, util.Prioritized(&linkTransformer{resolve: opts.ResolveLink}, 100))
+	}
+	extensions := []goldmark.Extender{extension.GFM, &wikiLinks{resolve: opts.ResolveWiki}, callouts{}, headingIDs{opts.Permalinks}, playLinks{}}
+	if opts.Diagram != nil {
+		extensions = append(extensions, &diagrams{render: opts.Diagram})
+	}
+	if opts.Math {
+		extensions = append(extensions, &math{render: opts.RenderMath})
+	}
+	if opts.Highlight != "" {
+		extensions = append(extensions, highlighting.NewHighlighting(
+			highlighting.WithStyle(opts.Highlight),
+			highlighting.WithFormatOptions(
+				chromahtml.WithClasses(opts.Classes),
+				chromahtml.WithLineNumbers(opts.LineNumbers),
+			),
+		))
+	}
+	rendererOpts := []renderer.Option{html.WithUnsafe()}
+	if opts.XHTML {
+		rendererOpts = append(rendererOpts, html.WithXHTML())
+	}
+	md := goldmark.New(
+		goldmark.WithExtensions(extensions...),
+		goldmark.WithParserOptions(parser.WithASTTransformers(transformers...)),
+		goldmark.WithRendererOptions(rendererOpts...),
+	)
+	return &Renderer{md: md}
+}
+
+// CheckStyle reports whether style names a chroma style.
+func CheckStyle(style string) error {
+	if _, ok := styles.Registry[strings.ToLower(style)]; !ok {
+		return fmt.Errorf("unknown highlight style %q", style)
+	}
+	return nil
+}
+
+// CSS returns the stylesheet for code highlighted with Classes in the
+// given style.
+func CSS(style string) ([]byte, error) {
+	if err := CheckStyle(style); err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := chromahtml.New(chromahtml.WithClasses(true)).WriteCSS(&buf, styles.Get(style)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+var fromKey = parser.NewContextKey()
+
+// Render renders the markdown src to HTML.
+func (r *Renderer) Render(src []byte) ([]byte, error) {
+	return r.RenderFrom(src, "")
+}
+
+// RenderFrom renders the markdown src of the entry at the slash-separated
+// path from, against which relative links are resolved.
+func (r *Renderer) RenderFrom(src []byte, from string) ([]byte, error) {
+	pc := parser.NewContext()
+	pc.Set(fromKey, from)
+	var buf bytes.Buffer
+	if err := r.md.Convert(src, &buf, parser.WithContext(pc)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Parse parses src as RenderFrom does, with links resolved and callouts
+// recognized, returning the document for renderers of other formats.
+func (r *Renderer) Parse(src []byte, from string) ast.Node {
+	pc := parser.NewContext()
+	pc.Set(fromKey, from)
+	return r.md.Parser().Parse(text.NewReader(src), parser.WithContext(pc))
+}
+
+// StripTitle removes a leading level-one heading from src. Entry pages show
+// the title from the frontmatter, so the heading would otherwise repeat.
+func StripTitle(src []byte) []byte {
+	rest := bytes.TrimLeft(src, " \t\r\n")
+	if !bytes.HasPrefix(rest, []byte("# ")) {
+		return src
+	}
+	if i := bytes.IndexByte(rest, '\n'); i >= 0 {
+		return rest[i+1:]
+	}
+	return nil
+}
+
+type linkTransformer struct {
+	resolve func(from, dest string) (string, bool)
+}
+
+func (t *linkTransformer) Transform(doc *ast.Document, _ text.Reader, pc parser.Context) {
+	from, _ := pc.Get(fromKey).(string)
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch n := n.(type) {
+		case *ast.Link:
+			if url, ok := t.resolve(from, string(n.Destination)); ok {
+				n.Destination = []byte(url)
+			}
+		case *ast.Image:
+			if url, ok := t.resolve(from, string(n.Destination)); ok {
+				n.Destination = []byte(url)
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+}
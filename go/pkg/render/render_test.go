@@ -0,0 +1,321 @@
+package render
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	r := New(Options{
+		ResolveWiki: func(from, target string) (string, string, bool) {
+			if target == "go/slices" {
+				return "/go/slices/", "Slices", true
+			}
+			return "", "", false
+		},
+		ResolveLink: func(from, dest string) (string, bool) {
+			if from == "go/x.md" && dest == "other.md" {
+				return "/go/other/", true
+			}
+			return "", false
+		},
+		Math: true,
+	})
+	tests := []struct{ name, src, want string }{
+		{"paragraph", "Hello *there*", "<p>Hello <em>there</em></p>\n"},
+		{"heading", "## A heading", `<h2 id="a-heading">A heading</h2>` + "\n"},
+		{"wikilink", "[[go/slices]]", `<p><a class="wikilink" href="/go/slices/">Slices</a></p>` + "\n"},
+		{"wikilink label", "[[go/slices|the slices]]", `<p><a class="wikilink" href="/go/slices/">the slices</a></p>` + "\n"},
+		{"dangling", "[[nowhere]]", `<p><span class="wikilink dangling" title="no entry named nowhere">nowhere</span></p>` + "\n"},
+		{"link", "[x](other.md) [y](https://e.com)", `<p><a href="/go/other/">x</a> <a href="https://e.com">y</a></p>` + "\n"},
+		{"callout", "> [!NOTE] Heads up\n> body", "<div class=\"callout callout-note\">\n<p class=\"callout-title\">Heads up</p>\n<p>body</p>\n</div>\n"},
+		{"math", "$x^2$", `<p><span class="math math-inline">\(x^2\)</span></p>` + "\n"},
+		{"code", "```go\nx := 1\n```", "<pre><code class=\"language-go\">x := 1\n</code></pre>\n"},
+		{"raw html", "<b>raw</b>", "<p><b>raw</b></p>\n"},
+		{"play link", "```go {play=\"a&b\"}\nx := 1\n```", "<pre><code class=\"language-go\">x := 1\n</code></pre>\n<p class=\"play\"><a href=\"https://go.dev/play/p/a&amp;b\">Run in the Go Playground</a></p>\n"},
+		{"play not go", "```py {play=abc}\nx = 1\n```", "<pre><code class=\"language-py\">x = 1\n</code></pre>\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := r.RenderFrom([]byte(tt.src), "go/x.md")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("RenderFrom(%q) = %q, want %q", tt.src, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderWithoutResolvers(t *testing.T) {
+	got, err := New(Options{}).Render([]byte("[[go/slices|label]] and $x$"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `<p><span class="wikilink">label</span> and $x$</p>` + "\n"; string(got) != want {
+		t.Errorf("Render = %q, want %q", got, want)
+	}
+}
+
+func TestRenderOptions(t *testing.T) {
+	got, err := New(Options{Permalinks: true, XHTML: true}).Render([]byte("## Hi there\n\n---"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`<a href="#hi-there" title="Link to this section" class="anchor">#</a>`, "<hr />"} {
+		if !strings.Contains(string(got), want) {
+			t.Errorf("Render = %q, missing %q", got, want)
+		}
+	}
+	got, err = New(Options{Highlight: "github", Classes: true}).Render([]byte("```go\nx := 1\n```"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(got), `class="chroma"`) || strings.Contains(string(got), "style=") {
+		t.Errorf("highlighted with classes = %q", got)
+	}
+}
+
+func TestHeadings(t *testing.T) {
+	src := "# Title\n\ntext\n\n## Setup\n\n```\n# not a heading\n```\n\n## Setup\n\n### C++ & [[go/x|links]]\n\n## !!!\n"
+	want := []Heading{
+		{1, "Title", "title", 1},
+		{2, "Setup", "setup", 5},
+		{2, "Setup", "setup-1", 11},
+		{3, "C++ & links", "c-links", 13},
+		{2, "!!!", "section", 15},
+	}
+	got := Headings([]byte(src))
+	if len(got) != len(want) {
+		t.Fatalf("Headings = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("heading %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestStripTitle(t *testing.T) {
+	tests := []struct{ src, want string }{
+		{"# Title\nbody\n", "body\n"},
+		{"\n\n# Title\nbody\n", "body\n"},
+		{"## Not a title\nbody\n", "## Not a title\nbody\n"},
+		{"body\n# Later\n", "body\n# Later\n"},
+		{"# Only", ""},
+	}
+	for _, tt := range tests {
+		if got := string(StripTitle([]byte(tt.src))); got != tt.want {
+			t.Errorf("StripTitle(%q) = %q, want %q", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestCheckStyle(t *testing.T) {
+	if err := CheckStyle("GitHub"); err != nil {
+		t.Error(err)
+	}
+	if err := CheckStyle("no-such-style"); err == nil {
+		t.Error("CheckStyle accepted an unknown style")
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	tests := []struct {
+		name string
+		opts Options
+		src  string
+		want []string
+		not  []string
+	}{
+		{
+			"hl_lines", Options{Highlight: "github", Classes: true},
+			"```go {hl_lines=[2]}\na := 1\nb := 2\nc := 3\n```",
+			[]string{`<span class="line hl"><span class="cl"><span class="nx">b</span>`},
+			[]string{`class="ln"`},
+		},
+		{
+			"linenostart", Options{Highlight: "github", Classes: true, LineNumbers: true},
+			"```go {linenostart=5}\na := 1\nb := 2\n```",
+			[]string{`<span class="ln">5</span>`, `<span class="ln">6</span>`},
+			[]string{`<span class="ln">1</span>`},
+		},
+		{
+			"linenos=false", Options{Highlight: "github", Classes: true, LineNumbers: true},
+			"```go {linenos=false}\nz\n```",
+			[]string{`<span class="nx">z</span>`},
+			[]string{`class="ln"`},
+		},
+		{
+			"linenos=true", Options{Highlight: "github", Classes: true},
+			"```go {linenos=true}\nz\n```",
+			[]string{`<span class="ln">1</span>`},
+			nil,
+		},
+		{
+			"inline styles", Options{Highlight: "github"},
+			"```go\na\n```",
+			[]string{`<pre style="background-color:#f7f7f7;`},
+			[]string{`class="chroma"`},
+		},
+		{
+			"plain", Options{},
+			"```go\na\n```",
+			[]string{`<pre><code class="language-go">a`},
+			[]string{"style=", "chroma"},
+		},
+	}
+	for _, tt := range tests {
+		got, err := New(tt.opts).Render([]byte(tt.src))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, want := range tt.want {
+			if !strings.Contains(string(got), want) {
+				t.Errorf("%s: Render = %s, missing %s", tt.name, got, want)
+			}
+		}
+		for _, not := range tt.not {
+			if strings.Contains(string(got), not) {
+				t.Errorf("%s: Render = %s, has %s", tt.name, got, not)
+			}
+		}
+	}
+}
+
+func TestCSS(t *testing.T) {
+	css, err := CSS("monokai")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(css), ".chroma {") || !strings.Contains(string(css), ".chroma .hl {") {
+		t.Errorf("CSS(monokai) =\n%s", css)
+	}
+	if _, err := CSS("no-such-style"); err == nil {
+		t.Error("CSS accepted an unknown style")
+	}
+}
+
+func TestDiagram(t *testing.T) {
+	src := []byte("```mermaid\nA --> B<C>\n```\n\n```go\nmermaid\n```\n")
+	var from string
+	draw := func(f, lang string, src []byte) ([]byte, error) {
+		from = f
+		if lang != "mermaid" {
+			t.Errorf("lang = %q", lang)
+		}
+		return []byte("<svg>" + strings.TrimSpace(string(src)) + "</svg>"), nil
+	}
+	got, err := New(Options{Diagram: draw}).RenderFrom(src, "net/tcp.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if from != "net/tcp.md" || !strings.Contains(string(got), `<figure class="diagram diagram-mermaid"><svg>A --> B<C></svg></figure>`) {
+		t.Errorf("RenderFrom(%s) = %s", from, got)
+	}
+	if !strings.Contains(string(got), `<code class="language-go">mermaid`) {
+		t.Errorf("other fences were rendered as diagrams: %s", got)
+	}
+
+	failing := func(string, string, []byte) ([]byte, error) { return nil, errors.New("no mmdc") }
+	got, err = New(Options{Diagram: failing}).Render(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := string(ClientDiagram("mermaid")) + "A --&gt; B&lt;C&gt;\n</pre>"; !strings.Contains(string(got), want) {
+		t.Errorf("Render with a failing renderer = %s, want %s", got, want)
+	}
+
+	got, _ = New(Options{}).Render(src)
+	if !strings.Contains(string(got), `<code class="language-mermaid">`) {
+		t.Errorf("Render without Diagram = %s", got)
+	}
+}
+
+func TestMath(t *testing.T) {
+	tests := []struct{ src, want string }{
+		{`Mean $\mu$ here.`, `<p>Mean <span class="math math-inline">\(\mu\)</span> here.</p>` + "\n"},
+		{"Costs $5 and $10.", "<p>Costs $5 and $10.</p>\n"},
+		{"$ x$ no", "<p>$ x$ no</p>\n"},
+		{"$a<b$", `<p><span class="math math-inline">\(a&lt;b\)</span></p>` + "\n"},
+		{"a $$E=mc^2$$ b", `<p>a <span class="math math-display">\[E=mc^2\]</span> b</p>` + "\n"},
+		{"$$\n\\sum_i x_i\n$$\n", `<div class="math math-display">\[\sum_i x_i\]</div>` + "\n"},
+		{"$$x$$\n", `<div class="math math-display">\[x\]</div>` + "\n"},
+		{"$$ a\nb $$\n\npara", "<div class=\"math math-display\">\\[a\nb\\]</div>\n<p>para</p>\n"},
+		{"`$x$`", "<p><code>$x$</code></p>\n"},
+	}
+	r := New(Options{Math: true})
+	for _, tt := range tests {
+		got, err := r.Render([]byte(tt.src))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != tt.want {
+			t.Errorf("Render(%q) = %q, want %q", tt.src, got, tt.want)
+		}
+	}
+	if got, _ := New(Options{}).Render([]byte(`$\mu$`)); strings.Contains(string(got), "math") {
+		t.Errorf("Render without Math = %q", got)
+	}
+
+	pre := func(tex []byte, display bool) ([]byte, error) {
+		if string(tex) == "bad" {
+			return nil, errors.New("parse error")
+		}
+		return []byte(fmt.Sprintf("<katex display=%v>%s</katex>", display, tex)), nil
+	}
+	got, _ := New(Options{Math: true, RenderMath: pre}).Render([]byte("$x$ and $bad$\n\n$$\ny\n$$\n"))
+	want := "<p><katex display=false>x</katex> and <span class=\"math math-inline\">\\(bad\\)</span></p>\n<katex display=true>y</katex>\n"
+	if string(got) != want {
+		t.Errorf("Render with RenderMath = %q, want %q", got, want)
+	}
+}
+
+func TestCallouts(t *testing.T) {
+	tests := []struct{ src, want string }{
+		{
+			"> [!WARNING] Slices *alias*\n> Appending may write.",
+			"<div class=\"callout callout-warning\">\n<p class=\"callout-title\">Slices <em>alias</em></p>\n<p>Appending may write.</p>\n</div>\n",
+		},
+		{
+			"> [!note]\n> Body.\n>\n> More.",
+			"<div class=\"callout callout-note\">\n<p class=\"callout-title\">Note</p>\n<p>Body.</p>\n<p>More.</p>\n</div>\n",
+		},
+		{"> [!TIP]", "<div class=\"callout callout-tip\">\n<p class=\"callout-title\">Tip</p>\n</div>\n"},
+		{"> [!BOGUS] x\n> y", "<blockquote>\n<p>[!BOGUS] x\ny</p>\n</blockquote>\n"},
+		{"> plain\n> quote", "<blockquote>\n<p>plain\nquote</p>\n</blockquote>\n"},
+	}
+	r := New(Options{})
+	for _, tt := range tests {
+		got, err := r.Render([]byte(tt.src))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != tt.want {
+			t.Errorf("Render(%q) = %q, want %q", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestHeadingAnchors(t *testing.T) {
+	src := []byte("## Setup\n\n## Setup\n\n### C++ & [[go/x|links]]\n\n## !!!\n")
+	r := New(Options{Permalinks: true})
+	first, err := r.Render(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, h := range Headings(src) {
+		if !strings.Contains(string(first), `id="`+h.ID+`"`) || !strings.Contains(string(first), `href="#`+h.ID+`"`) {
+			t.Errorf("rendered HTML lacks the anchor %q of %q:\n%s", h.ID, h.Text, first)
+		}
+	}
+	if again, _ := r.Render(src); string(again) != string(first) {
+		t.Errorf("anchors differ between renders:\n%s\n%s", first, again)
+	}
+	if got, _ := New(Options{}).Render([]byte("## Setup")); string(got) != "<h2 id=\"setup\">Setup</h2>\n" {
+		t.Errorf("Render without Permalinks = %q", got)
+	}
+}
@@ -0,0 +1,135 @@
+package render
+
+import (
+	"bytes"
+	"strconv"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+
+	"github.com/canhta/til/go/pkg/entry"
+)
+
+// Heading is a section heading, as listed in a table of contents.
+type Heading struct {
+	Level int    `json:"level"`
+	Text  string `json:"text"`
+	// ID is the heading's anchor in rendered HTML.
+	ID string `json:"id"`
+	// Line is the 1-based line of the heading in the markdown.
+	Line int `json:"line"`
+}
+
+// Anchor returns the anchor of a heading with the given plain text: its
+// slug, or "section" when the text has no letters or digits to slug. The
+// renderer appends -1, -2 and so on to anchors repeated within a page.
+func Anchor(text string) string {
+	if s := entry.Slugify(text); s != "" {
+		return s
+	}
+	return "section"
+}
+
+// anchors hands out the anchors of one document's headings in order.
+type anchors map[string]int
+
+func (a anchors) next(text string) string {
+	id := Anchor(text)
+	n, used := a[id]
+	a[id] = n + 1
+	if !used {
+		return id
+	}
+	return id + "-" + strconv.Itoa(n)
+}
+
+var tocParser = goldmark.New(goldmark.WithExtensions(extension.GFM, &wikiLinks{}, callouts{})).Parser()
+
+// Headings returns the headings of the markdown src with the anchors the
+// renderer gives them.
+func Headings(src []byte) []Heading {
+	doc := tocParser.Parse(text.NewReader(src))
+	var out []Heading
+	ids := anchors{}
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		h, ok := n.(*ast.Heading)
+		if !ok || !entering {
+			return ast.WalkContinue, nil
+		}
+		t := plainText(h, src)
+		hd := Heading{Level: h.Level, Text: t, ID: ids.next(t), Line: 1}
+		if h.Lines().Len() > 0 {
+			hd.Line += bytes.Count(src[:h.Lines().At(0).Start], []byte("\n"))
+		}
+		out = append(out, hd)
+		return ast.WalkSkipChildren, nil
+	})
+	return out
+}
+
+// plainText returns the text of n's inline content without markup.
+func plainText(n ast.Node, src []byte) string {
+	var b bytes.Buffer
+	_ = ast.Walk(n, func(c ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch c := c.(type) {
+		case *ast.Text:
+			b.Write(c.Segment.Value(src))
+			if c.SoftLineBreak() {
+				b.WriteByte(' ')
+			}
+		case *ast.String:
+			b.Write(c.Value)
+		case *WikiLink:
+			if c.Label != "" {
+				b.WriteString(c.Label)
+			} else {
+				b.WriteString(c.Target)
+			}
+		case *Math:
+			b.Write(c.TeX)
+		case *ast.RawHTML:
+			return ast.WalkSkipChildren, nil
+		}
+		return ast.WalkContinue, nil
+	})
+	return string(bytes.TrimSpace(b.Bytes()))
+}
+
+// headingIDs gives every heading its anchor as id and, with permalinks,
+// appends a link to it.
+type headingIDs struct {
+	permalinks bool
+}
+
+func (h headingIDs) Extend(m goldmark.Markdown) {
+	m.Parser().AddOptions(parser.WithASTTransformers(util.Prioritized(h, 400)))
+}
+
+func (h headingIDs) Transform(doc *ast.Document, reader text.Reader, _ parser.Context) {
+	src := reader.Source()
+	ids := anchors{}
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		hd, ok := n.(*ast.Heading)
+		if !ok || !entering {
+			return ast.WalkContinue, nil
+		}
+		id := ids.next(plainText(hd, src))
+		hd.SetAttributeString("id", []byte(id))
+		if h.permalinks {
+			a := ast.NewLink()
+			a.Destination = []byte("#" + id)
+			a.SetAttributeString("class", []byte("anchor"))
+			a.Title = []byte("Link to this section")
+			a.AppendChild(a, ast.NewString([]byte("#")))
+			hd.AppendChild(hd, a)
+		}
+		return ast.WalkSkipChildren, nil
+	})
+}
@@ -0,0 +1,94 @@
+package render
+
+import (
+	"bytes"
+	"html"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// KindWikiLink is the node kind of [[target]] references.
+var KindWikiLink = ast.NewNodeKind("WikiLink")
+
+// WikiLink is a [[target]] or [[target|label]] reference to another entry.
+type WikiLink struct {
+	ast.BaseInline
+	Target string
+	Label  string
+	// URL is where the target resolved to.
+	URL string
+	// Dangling is set when the target did not resolve.
+	Dangling bool
+}
+
+// Kind implements ast.Node.
+func (n *WikiLink) Kind() ast.NodeKind { return KindWikiLink }
+
+// Dump implements ast.Node.
+func (n *WikiLink) Dump(src []byte, level int) {
+	ast.DumpHelper(n, src, level, map[string]string{"Target": n.Target, "URL": n.URL}, nil)
+}
+
+type wikiLinks struct {
+	resolve func(from, target string) (url, title string, ok bool)
+}
+
+func (w *wikiLinks) Extend(m goldmark.Markdown) {
+	m.Parser().AddOptions(parser.WithInlineParsers(util.Prioritized(w, 199)))
+	m.Renderer().AddOptions(renderer.WithNodeRenderers(util.Prioritized(w, 500)))
+}
+
+func (w *wikiLinks) Trigger() []byte { return []byte{'['} }
+
+func (w *wikiLinks) Parse(parent ast.Node, block text.Reader, pc parser.Context) ast.Node {
+	line, _ := block.PeekLine()
+	if !bytes.HasPrefix(line, []byte("[[")) {
+		return nil
+	}
+	end := bytes.Index(line, []byte("]]"))
+	if end < 0 {
+		return nil
+	}
+	inner := line[2:end]
+	if len(bytes.TrimSpace(inner)) == 0 || bytes.ContainsAny(inner, "[]\n") {
+		return nil
+	}
+	block.Advance(end + 2)
+	target, label, _ := bytes.Cut(inner, []byte("|"))
+	n := &WikiLink{Target: string(bytes.TrimSpace(target)), Label: string(bytes.TrimSpace(label))}
+	from, _ := pc.Get(fromKey).(string)
+	if w.resolve != nil {
+		url, title, ok := w.resolve(from, n.Target)
+		n.URL, n.Dangling = url, !ok
+		if ok && n.Label == "" {
+			n.Label = title
+		}
+	}
+	if n.Label == "" {
+		n.Label = n.Target
+	}
+	return n
+}
+
+func (w *wikiLinks) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(KindWikiLink, func(out util.BufWriter, src []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		n := node.(*WikiLink)
+		switch {
+		case n.Dangling:
+			out.WriteString(`<span class="wikilink dangling" title="no entry named ` + html.EscapeString(n.Target) + `">` + html.EscapeString(n.Label) + `</span>`)
+		case n.URL == "":
+			out.WriteString(`<span class="wikilink">` + html.EscapeString(n.Label) + `</span>`)
+		default:
+			out.WriteString(`<a class="wikilink" href="` + html.EscapeString(n.URL) + `">` + html.EscapeString(n.Label) + `</a>`)
+		}
+		return ast.WalkSkipChildren, nil
+	})
+}